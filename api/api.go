@@ -0,0 +1,11 @@
+// Package api embeds the flagz API definitions so the server can publish
+// exactly the version it was built with. The protobuf definitions live in
+// the proto/v1 subpackage.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the HTTP API, in YAML.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
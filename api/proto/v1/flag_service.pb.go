@@ -20,12 +20,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type EvaluationReason int32
+
+const (
+	EvaluationReason_EVALUATION_REASON_UNSPECIFIED EvaluationReason = 0
+	EvaluationReason_RULE_MATCH                    EvaluationReason = 1
+	EvaluationReason_DEFAULT                       EvaluationReason = 2
+	EvaluationReason_DISABLED                      EvaluationReason = 3
+	EvaluationReason_FLAG_NOT_FOUND                EvaluationReason = 4
+	EvaluationReason_TARGET_MATCH                  EvaluationReason = 5
+)
+
+// Enum value maps for EvaluationReason.
+var (
+	EvaluationReason_name = map[int32]string{
+		0: "EVALUATION_REASON_UNSPECIFIED",
+		1: "RULE_MATCH",
+		2: "DEFAULT",
+		3: "DISABLED",
+		4: "FLAG_NOT_FOUND",
+		5: "TARGET_MATCH",
+	}
+	EvaluationReason_value = map[string]int32{
+		"EVALUATION_REASON_UNSPECIFIED": 0,
+		"RULE_MATCH":                    1,
+		"DEFAULT":                       2,
+		"DISABLED":                      3,
+		"FLAG_NOT_FOUND":                4,
+		"TARGET_MATCH":                  5,
+	}
+)
+
+func (x EvaluationReason) Enum() *EvaluationReason {
+	p := new(EvaluationReason)
+	*p = x
+	return p
+}
+
+func (x EvaluationReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EvaluationReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_v1_flag_service_proto_enumTypes[0].Descriptor()
+}
+
+func (EvaluationReason) Type() protoreflect.EnumType {
+	return &file_api_proto_v1_flag_service_proto_enumTypes[0]
+}
+
+func (x EvaluationReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EvaluationReason.Descriptor instead.
+func (EvaluationReason) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{0}
+}
+
 type WatchFlagEventType int32
 
 const (
 	WatchFlagEventType_WATCH_FLAG_EVENT_TYPE_UNSPECIFIED WatchFlagEventType = 0
 	WatchFlagEventType_FLAG_UPDATED                      WatchFlagEventType = 1
 	WatchFlagEventType_FLAG_DELETED                      WatchFlagEventType = 2
+	WatchFlagEventType_RECONNECT                         WatchFlagEventType = 3
 )
 
 // Enum value maps for WatchFlagEventType.
@@ -34,11 +93,13 @@ var (
 		0: "WATCH_FLAG_EVENT_TYPE_UNSPECIFIED",
 		1: "FLAG_UPDATED",
 		2: "FLAG_DELETED",
+		3: "RECONNECT",
 	}
 	WatchFlagEventType_value = map[string]int32{
 		"WATCH_FLAG_EVENT_TYPE_UNSPECIFIED": 0,
 		"FLAG_UPDATED":                      1,
 		"FLAG_DELETED":                      2,
+		"RECONNECT":                         3,
 	}
 )
 
@@ -53,11 +114,11 @@ func (x WatchFlagEventType) String() string {
 }
 
 func (WatchFlagEventType) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_proto_v1_flag_service_proto_enumTypes[0].Descriptor()
+	return file_api_proto_v1_flag_service_proto_enumTypes[1].Descriptor()
 }
 
 func (WatchFlagEventType) Type() protoreflect.EnumType {
-	return &file_api_proto_v1_flag_service_proto_enumTypes[0]
+	return &file_api_proto_v1_flag_service_proto_enumTypes[1]
 }
 
 func (x WatchFlagEventType) Number() protoreflect.EnumNumber {
@@ -66,7 +127,7 @@ func (x WatchFlagEventType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use WatchFlagEventType.Descriptor instead.
 func (WatchFlagEventType) EnumDescriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{0}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{1}
 }
 
 type Flag struct {
@@ -74,11 +135,14 @@ type Flag struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Key          string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Description  string `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	Enabled      bool   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
-	VariantsJson []byte `protobuf:"bytes,4,opt,name=variants_json,json=variantsJson,proto3" json:"variants_json,omitempty"`
-	RulesJson    []byte `protobuf:"bytes,5,opt,name=rules_json,json=rulesJson,proto3" json:"rules_json,omitempty"`
+	Key          string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Description  string   `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Enabled      bool     `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	VariantsJson []byte   `protobuf:"bytes,4,opt,name=variants_json,json=variantsJson,proto3" json:"variants_json,omitempty"`
+	RulesJson    []byte   `protobuf:"bytes,5,opt,name=rules_json,json=rulesJson,proto3" json:"rules_json,omitempty"`
+	Tags         []string `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Owner        string   `protobuf:"bytes,7,opt,name=owner,proto3" json:"owner,omitempty"`
+	ExpiresAt    string   `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 }
 
 func (x *Flag) Reset() {
@@ -148,6 +212,27 @@ func (x *Flag) GetRulesJson() []byte {
 	return nil
 }
 
+func (x *Flag) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Flag) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Flag) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
 type CreateFlagRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -435,8 +520,9 @@ type ListFlagsRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	PageSize  int32  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
-	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	PageSize  int32    `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Tags      []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
 }
 
 func (x *ListFlagsRequest) Reset() {
@@ -485,6 +571,13 @@ func (x *ListFlagsRequest) GetPageToken() string {
 	return ""
 }
 
+func (x *ListFlagsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 type ListFlagsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -693,8 +786,12 @@ type ResolveBooleanResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value bool   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Key       string            `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value     bool              `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Reason    EvaluationReason  `protobuf:"varint,3,opt,name=reason,proto3,enum=flagz.v1.EvaluationReason" json:"reason,omitempty"`
+	RuleIndex *int32            `protobuf:"varint,4,opt,name=rule_index,json=ruleIndex,proto3,oneof" json:"rule_index,omitempty"`
+	Variant   string            `protobuf:"bytes,5,opt,name=variant,proto3" json:"variant,omitempty"`
+	Warnings  []*ContextWarning `protobuf:"bytes,6,rep,name=warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (x *ResolveBooleanResponse) Reset() {
@@ -743,6 +840,97 @@ func (x *ResolveBooleanResponse) GetValue() bool {
 	return false
 }
 
+func (x *ResolveBooleanResponse) GetReason() EvaluationReason {
+	if x != nil {
+		return x.Reason
+	}
+	return EvaluationReason_EVALUATION_REASON_UNSPECIFIED
+}
+
+func (x *ResolveBooleanResponse) GetRuleIndex() int32 {
+	if x != nil && x.RuleIndex != nil {
+		return *x.RuleIndex
+	}
+	return 0
+}
+
+func (x *ResolveBooleanResponse) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *ResolveBooleanResponse) GetWarnings() []*ContextWarning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type ContextWarning struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Attribute string `protobuf:"bytes,1,opt,name=attribute,proto3" json:"attribute,omitempty"`
+	Kind      string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Message   string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *ContextWarning) Reset() {
+	*x = ContextWarning{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContextWarning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContextWarning) ProtoMessage() {}
+
+func (x *ContextWarning) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContextWarning.ProtoReflect.Descriptor instead.
+func (*ContextWarning) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ContextWarning) GetAttribute() string {
+	if x != nil {
+		return x.Attribute
+	}
+	return ""
+}
+
+func (x *ContextWarning) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ContextWarning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 type ResolveBatchRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -754,7 +942,7 @@ type ResolveBatchRequest struct {
 func (x *ResolveBatchRequest) Reset() {
 	*x = ResolveBatchRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_v1_flag_service_proto_msgTypes[13]
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -767,7 +955,7 @@ func (x *ResolveBatchRequest) String() string {
 func (*ResolveBatchRequest) ProtoMessage() {}
 
 func (x *ResolveBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_v1_flag_service_proto_msgTypes[13]
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -780,7 +968,7 @@ func (x *ResolveBatchRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResolveBatchRequest.ProtoReflect.Descriptor instead.
 func (*ResolveBatchRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{13}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *ResolveBatchRequest) GetRequests() []*ResolveBooleanRequest {
@@ -795,14 +983,18 @@ type ResolveBatchResult struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value bool   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Key       string            `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value     bool              `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Reason    EvaluationReason  `protobuf:"varint,3,opt,name=reason,proto3,enum=flagz.v1.EvaluationReason" json:"reason,omitempty"`
+	RuleIndex *int32            `protobuf:"varint,4,opt,name=rule_index,json=ruleIndex,proto3,oneof" json:"rule_index,omitempty"`
+	Variant   string            `protobuf:"bytes,5,opt,name=variant,proto3" json:"variant,omitempty"`
+	Warnings  []*ContextWarning `protobuf:"bytes,6,rep,name=warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (x *ResolveBatchResult) Reset() {
 	*x = ResolveBatchResult{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_v1_flag_service_proto_msgTypes[14]
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -815,7 +1007,7 @@ func (x *ResolveBatchResult) String() string {
 func (*ResolveBatchResult) ProtoMessage() {}
 
 func (x *ResolveBatchResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_v1_flag_service_proto_msgTypes[14]
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -828,7 +1020,7 @@ func (x *ResolveBatchResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResolveBatchResult.ProtoReflect.Descriptor instead.
 func (*ResolveBatchResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{14}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ResolveBatchResult) GetKey() string {
@@ -845,6 +1037,34 @@ func (x *ResolveBatchResult) GetValue() bool {
 	return false
 }
 
+func (x *ResolveBatchResult) GetReason() EvaluationReason {
+	if x != nil {
+		return x.Reason
+	}
+	return EvaluationReason_EVALUATION_REASON_UNSPECIFIED
+}
+
+func (x *ResolveBatchResult) GetRuleIndex() int32 {
+	if x != nil && x.RuleIndex != nil {
+		return *x.RuleIndex
+	}
+	return 0
+}
+
+func (x *ResolveBatchResult) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *ResolveBatchResult) GetWarnings() []*ContextWarning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
 type ResolveBatchResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -856,7 +1076,7 @@ type ResolveBatchResponse struct {
 func (x *ResolveBatchResponse) Reset() {
 	*x = ResolveBatchResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_v1_flag_service_proto_msgTypes[15]
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -869,7 +1089,7 @@ func (x *ResolveBatchResponse) String() string {
 func (*ResolveBatchResponse) ProtoMessage() {}
 
 func (x *ResolveBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_v1_flag_service_proto_msgTypes[15]
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -882,7 +1102,7 @@ func (x *ResolveBatchResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResolveBatchResponse.ProtoReflect.Descriptor instead.
 func (*ResolveBatchResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{15}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *ResolveBatchResponse) GetResults() []*ResolveBatchResult {
@@ -892,6 +1112,108 @@ func (x *ResolveBatchResponse) GetResults() []*ResolveBatchResult {
 	return nil
 }
 
+type ResolveAllRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ContextJson []byte `protobuf:"bytes,1,opt,name=context_json,json=contextJson,proto3" json:"context_json,omitempty"`
+}
+
+func (x *ResolveAllRequest) Reset() {
+	*x = ResolveAllRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResolveAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveAllRequest) ProtoMessage() {}
+
+func (x *ResolveAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveAllRequest.ProtoReflect.Descriptor instead.
+func (*ResolveAllRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ResolveAllRequest) GetContextJson() []byte {
+	if x != nil {
+		return x.ContextJson
+	}
+	return nil
+}
+
+type ResolveAllResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values   map[string]bool   `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Warnings []*ContextWarning `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"`
+}
+
+func (x *ResolveAllResponse) Reset() {
+	*x = ResolveAllResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResolveAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveAllResponse) ProtoMessage() {}
+
+func (x *ResolveAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveAllResponse.ProtoReflect.Descriptor instead.
+func (*ResolveAllResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ResolveAllResponse) GetValues() map[string]bool {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *ResolveAllResponse) GetWarnings() []*ContextWarning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
 type WatchFlagRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -904,7 +1226,7 @@ type WatchFlagRequest struct {
 func (x *WatchFlagRequest) Reset() {
 	*x = WatchFlagRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_v1_flag_service_proto_msgTypes[16]
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -917,7 +1239,7 @@ func (x *WatchFlagRequest) String() string {
 func (*WatchFlagRequest) ProtoMessage() {}
 
 func (x *WatchFlagRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_v1_flag_service_proto_msgTypes[16]
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -930,7 +1252,7 @@ func (x *WatchFlagRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WatchFlagRequest.ProtoReflect.Descriptor instead.
 func (*WatchFlagRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{16}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *WatchFlagRequest) GetKey() string {
@@ -961,7 +1283,7 @@ type WatchFlagEvent struct {
 func (x *WatchFlagEvent) Reset() {
 	*x = WatchFlagEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_v1_flag_service_proto_msgTypes[17]
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -974,7 +1296,7 @@ func (x *WatchFlagEvent) String() string {
 func (*WatchFlagEvent) ProtoMessage() {}
 
 func (x *WatchFlagEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_v1_flag_service_proto_msgTypes[17]
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -987,7 +1309,7 @@ func (x *WatchFlagEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WatchFlagEvent.ProtoReflect.Descriptor instead.
 func (*WatchFlagEvent) Descriptor() ([]byte, []int) {
-	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{17}
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *WatchFlagEvent) GetType() WatchFlagEventType {
@@ -1018,147 +1340,563 @@ func (x *WatchFlagEvent) GetEventId() int64 {
 	return 0
 }
 
-var File_api_proto_v1_flag_service_proto protoreflect.FileDescriptor
+type WatchAllProjectsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_api_proto_v1_flag_service_proto_rawDesc = []byte{
-	0x0a, 0x1f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x31, 0x2f, 0x66,
-	0x6c, 0x61, 0x67, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x12, 0x08, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x22, 0x98, 0x01, 0x0a, 0x04,
-	0x46, 0x6c, 0x61, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69,
-	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73,
-	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x61, 0x62,
-	0x6c, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c,
-	0x65, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x73, 0x5f, 0x6a,
-	0x73, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x76, 0x61, 0x72, 0x69, 0x61,
-	0x6e, 0x74, 0x73, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x75, 0x6c, 0x65, 0x73,
-	0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x72, 0x75, 0x6c,
-	0x65, 0x73, 0x4a, 0x73, 0x6f, 0x6e, 0x22, 0x37, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
-	0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x66,
-	0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67,
-	0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22,
-	0x38, 0x0a, 0x12, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46,
-	0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x37, 0x0a, 0x11, 0x55, 0x70, 0x64,
-	0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22,
-	0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66,
-	0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c,
-	0x61, 0x67, 0x22, 0x38, 0x0a, 0x12, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
-	0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x22, 0x0a, 0x0e,
-	0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10,
-	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
-	0x22, 0x35, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61,
-	0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x4e, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x46,
-	0x6c, 0x61, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x70,
-	0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08,
-	0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65,
-	0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61,
-	0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x61, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x46,
-	0x6c, 0x61, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x24, 0x0a, 0x05,
-	0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c,
-	0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x05, 0x66, 0x6c, 0x61,
-	0x67, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f,
-	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78,
-	0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x25, 0x0a, 0x11, 0x44, 0x65,
-	0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
-	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
-	0x79, 0x22, 0x14, 0x0a, 0x12, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x71, 0x0a, 0x15, 0x52, 0x65, 0x73, 0x6f, 0x6c,
-	0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
-	0x65, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x5f, 0x6a, 0x73,
-	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78,
-	0x74, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
-	0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x64, 0x65,
-	0x66, 0x61, 0x75, 0x6c, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x40, 0x0a, 0x16, 0x52, 0x65,
-	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x52, 0x0a, 0x13,
-	0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x3b, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31,
-	0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73,
-	0x22, 0x3c, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68,
-	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x4e,
-	0x0a, 0x14, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e,
-	0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52,
-	0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x48,
-	0x0a, 0x10, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x03, 0x6b, 0x65, 0x79, 0x12, 0x22, 0x0a, 0x0d, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65, 0x76, 0x65,
-	0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c, 0x61, 0x73,
-	0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x93, 0x01, 0x0a, 0x0e, 0x57, 0x61, 0x74,
-	0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x30, 0x0a, 0x04, 0x74,
-	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67,
-	0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76,
-	0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x10, 0x0a,
-	0x03, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
-	0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
-	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66,
-	0x6c, 0x61, 0x67, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x2a, 0x5f,
-	0x0a, 0x12, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74,
-	0x54, 0x79, 0x70, 0x65, 0x12, 0x25, 0x0a, 0x21, 0x57, 0x41, 0x54, 0x43, 0x48, 0x5f, 0x46, 0x4c,
-	0x41, 0x47, 0x5f, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e,
-	0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x46,
-	0x4c, 0x41, 0x47, 0x5f, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x10, 0x0a,
-	0x0c, 0x46, 0x4c, 0x41, 0x47, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x44, 0x10, 0x02, 0x32,
-	0xd7, 0x04, 0x0a, 0x0b, 0x46, 0x6c, 0x61, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
-	0x47, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1b, 0x2e,
-	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46,
-	0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61,
-	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x47, 0x0a, 0x0a, 0x55, 0x70, 0x64, 0x61,
-	0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
-	0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x55,
-	0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x3e, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x18, 0x2e, 0x66,
-	0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
-	0x31, 0x2e, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x44, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x1a,
-	0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c,
-	0x61, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x66, 0x6c, 0x61,
-	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x47, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31,
-	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65,
-	0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x53, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65,
-	0x61, 0x6e, 0x12, 0x1f, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65,
-	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52,
-	0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65,
-	0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1d, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31,
-	0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
-	0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x43, 0x0a, 0x09, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61,
-	0x67, 0x12, 0x1a, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74,
-	0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e,
-	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c,
-	0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x74, 0x74, 0x72, 0x69, 0x6c, 0x65,
-	0x79, 0x2f, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2f, 0x76, 0x31, 0x3b, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	LastEventId int64 `protobuf:"varint,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
 }
 
-var (
+func (x *WatchAllProjectsRequest) Reset() {
+	*x = WatchAllProjectsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchAllProjectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchAllProjectsRequest) ProtoMessage() {}
+
+func (x *WatchAllProjectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchAllProjectsRequest.ProtoReflect.Descriptor instead.
+func (*WatchAllProjectsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *WatchAllProjectsRequest) GetLastEventId() int64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+type ProjectFlagEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProjectId string          `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	Event     *WatchFlagEvent `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+}
+
+func (x *ProjectFlagEvent) Reset() {
+	*x = ProjectFlagEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProjectFlagEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProjectFlagEvent) ProtoMessage() {}
+
+func (x *ProjectFlagEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProjectFlagEvent.ProtoReflect.Descriptor instead.
+func (*ProjectFlagEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ProjectFlagEvent) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *ProjectFlagEvent) GetEvent() *WatchFlagEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+type WatchProjectRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ResumeToken string `protobuf:"bytes,1,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+}
+
+func (x *WatchProjectRequest) Reset() {
+	*x = WatchProjectRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchProjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchProjectRequest) ProtoMessage() {}
+
+func (x *WatchProjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchProjectRequest.ProtoReflect.Descriptor instead.
+func (*WatchProjectRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WatchProjectRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type ProjectSnapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Flags []*Flag `protobuf:"bytes,1,rep,name=flags,proto3" json:"flags,omitempty"`
+}
+
+func (x *ProjectSnapshot) Reset() {
+	*x = ProjectSnapshot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProjectSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProjectSnapshot) ProtoMessage() {}
+
+func (x *ProjectSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProjectSnapshot.ProtoReflect.Descriptor instead.
+func (*ProjectSnapshot) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ProjectSnapshot) GetFlags() []*Flag {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+type WatchProjectEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Snapshot    *ProjectSnapshot `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	Change      *WatchFlagEvent  `protobuf:"bytes,2,opt,name=change,proto3" json:"change,omitempty"`
+	ResumeToken string           `protobuf:"bytes,3,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+}
+
+func (x *WatchProjectEvent) Reset() {
+	*x = WatchProjectEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchProjectEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchProjectEvent) ProtoMessage() {}
+
+func (x *WatchProjectEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchProjectEvent.ProtoReflect.Descriptor instead.
+func (*WatchProjectEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *WatchProjectEvent) GetSnapshot() *ProjectSnapshot {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *WatchProjectEvent) GetChange() *WatchFlagEvent {
+	if x != nil {
+		return x.Change
+	}
+	return nil
+}
+
+func (x *WatchProjectEvent) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type ListFlagsStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tags []string `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *ListFlagsStreamRequest) Reset() {
+	*x = ListFlagsStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_v1_flag_service_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListFlagsStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFlagsStreamRequest) ProtoMessage() {}
+
+func (x *ListFlagsStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_v1_flag_service_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFlagsStreamRequest.ProtoReflect.Descriptor instead.
+func (*ListFlagsStreamRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_v1_flag_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListFlagsStreamRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+var File_api_proto_v1_flag_service_proto protoreflect.FileDescriptor
+
+var file_api_proto_v1_flag_service_proto_rawDesc = []byte{
+	0x0a, 0x1f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x31, 0x2f, 0x66,
+	0x6c, 0x61, 0x67, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x08, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x22, 0xe1, 0x01, 0x0a, 0x04,
+	0x46, 0x6c, 0x61, 0x67, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x61, 0x62,
+	0x6c, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c,
+	0x65, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x73, 0x5f, 0x6a,
+	0x73, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x76, 0x61, 0x72, 0x69, 0x61,
+	0x6e, 0x74, 0x73, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x75, 0x6c, 0x65, 0x73,
+	0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x72, 0x75, 0x6c,
+	0x65, 0x73, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x06,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77,
+	0x6e, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72,
+	0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x22,
+	0x37, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c,
+	0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x38, 0x0a, 0x12, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22,
+	0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66,
+	0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c,
+	0x61, 0x67, 0x22, 0x37, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31,
+	0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x38, 0x0a, 0x12, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x22, 0x0a, 0x04, 0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52,
+	0x04, 0x66, 0x6c, 0x61, 0x67, 0x22, 0x22, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x22, 0x35, 0x0a, 0x0f, 0x47, 0x65, 0x74,
+	0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04,
+	0x66, 0x6c, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61,
+	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67,
+	0x22, 0x62, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04,
+	0x74, 0x61, 0x67, 0x73, 0x22, 0x61, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x24, 0x0a, 0x05, 0x66, 0x6c, 0x61,
+	0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x05, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x12,
+	0x26, 0x0a, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x70, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x65, 0x78, 0x74, 0x50, 0x61,
+	0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x25, 0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x22, 0x14,
+	0x0a, 0x12, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x71, 0x0a, 0x15, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42,
+	0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x4a, 0x73,
+	0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x64, 0x65, 0x66, 0x61, 0x75,
+	0x6c, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xf7, 0x01, 0x0a, 0x16, 0x52, 0x65, 0x73, 0x6f,
+	0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x32, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x66, 0x6c, 0x61,
+	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76, 0x61, 0x6c, 0x75, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x22,
+	0x0a, 0x0a, 0x72, 0x75, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x05, 0x48, 0x00, 0x52, 0x09, 0x72, 0x75, 0x6c, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x88,
+	0x01, 0x01, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x61, 0x72, 0x69, 0x61, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x08,
+	0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18,
+	0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78,
+	0x74, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e,
+	0x67, 0x73, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x22, 0x5c, 0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x57, 0x61, 0x72, 0x6e,
+	0x69, 0x6e, 0x67, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22,
+	0x52, 0x0a, 0x13, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x3b, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65,
+	0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x73, 0x22, 0xf3, 0x01, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x12, 0x32, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x1a, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x76,
+	0x61, 0x6c, 0x75, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x52, 0x06,
+	0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0a, 0x72, 0x75, 0x6c, 0x65, 0x5f, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x09, 0x72, 0x75,
+	0x6c, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x88, 0x01, 0x01, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x61,
+	0x72, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x61, 0x72,
+	0x69, 0x61, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73,
+	0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72,
+	0x75, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x4e, 0x0a, 0x14, 0x52, 0x65, 0x73,
+	0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x36, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65,
+	0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x36, 0x0a, 0x11, 0x52, 0x65, 0x73,
+	0x6f, 0x6c, 0x76, 0x65, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21,
+	0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x4a, 0x73, 0x6f,
+	0x6e, 0x22, 0xc7, 0x01, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x41, 0x6c, 0x6c,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x40, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x41, 0x6c, 0x6c, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x08, 0x77, 0x61,
+	0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x66,
+	0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x57,
+	0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73,
+	0x1a, 0x39, 0x0a, 0x0b, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x48, 0x0a, 0x10, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x22, 0x0a, 0x0d, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c, 0x61, 0x73, 0x74, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x93, 0x01, 0x0a, 0x0e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46,
+	0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x30, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x22, 0x0a, 0x04,
+	0x66, 0x6c, 0x61, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61,
+	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x04, 0x66, 0x6c, 0x61, 0x67,
+	0x12, 0x19, 0x0a, 0x08, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x3d, 0x0a, 0x17, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x41, 0x6c, 0x6c, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0d, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65,
+	0x76, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c,
+	0x61, 0x73, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x61, 0x0a, 0x10, 0x50, 0x72,
+	0x6f, 0x6a, 0x65, 0x63, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1d,
+	0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x49, 0x64, 0x12, 0x2e, 0x0a,
+	0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x66,
+	0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61,
+	0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x38, 0x0a,
+	0x13, 0x57, 0x61, 0x74, 0x63, 0x68, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f, 0x74,
+	0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75,
+	0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x37, 0x0a, 0x0f, 0x50, 0x72, 0x6f, 0x6a, 0x65,
+	0x63, 0x74, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x24, 0x0a, 0x05, 0x66, 0x6c,
+	0x61, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67,
+	0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x05, 0x66, 0x6c, 0x61, 0x67, 0x73,
+	0x22, 0x9f, 0x01, 0x0a, 0x11, 0x57, 0x61, 0x74, 0x63, 0x68, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63,
+	0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x35, 0x0a, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68,
+	0x6f, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x53, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x52, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x30, 0x0a,
+	0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e,
+	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c,
+	0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12,
+	0x21, 0x0a, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x22, 0x2c, 0x0a, 0x16, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x61, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73,
+	0x2a, 0x86, 0x01, 0x0a, 0x10, 0x45, 0x76, 0x61, 0x6c, 0x75, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x1d, 0x45, 0x56, 0x41, 0x4c, 0x55, 0x41, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x52, 0x45, 0x41, 0x53, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x52, 0x55, 0x4c, 0x45,
+	0x5f, 0x4d, 0x41, 0x54, 0x43, 0x48, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x46, 0x41,
+	0x55, 0x4c, 0x54, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x44, 0x49, 0x53, 0x41, 0x42, 0x4c, 0x45,
+	0x44, 0x10, 0x03, 0x12, 0x12, 0x0a, 0x0e, 0x46, 0x4c, 0x41, 0x47, 0x5f, 0x4e, 0x4f, 0x54, 0x5f,
+	0x46, 0x4f, 0x55, 0x4e, 0x44, 0x10, 0x04, 0x12, 0x10, 0x0a, 0x0c, 0x54, 0x41, 0x52, 0x47, 0x45,
+	0x54, 0x5f, 0x4d, 0x41, 0x54, 0x43, 0x48, 0x10, 0x05, 0x2a, 0x6e, 0x0a, 0x12, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12,
+	0x25, 0x0a, 0x21, 0x57, 0x41, 0x54, 0x43, 0x48, 0x5f, 0x46, 0x4c, 0x41, 0x47, 0x5f, 0x45, 0x56,
+	0x45, 0x4e, 0x54, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49,
+	0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x46, 0x4c, 0x41, 0x47, 0x5f, 0x55,
+	0x50, 0x44, 0x41, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x46, 0x4c, 0x41, 0x47,
+	0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x44, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45,
+	0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x10, 0x03, 0x32, 0x8a, 0x07, 0x0a, 0x0b, 0x46, 0x6c,
+	0x61, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x47, 0x0a, 0x0a, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x47, 0x0a, 0x0a, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67,
+	0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e,
+	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x46,
+	0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x07, 0x47,
+	0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x18, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x65, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x19, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x46,
+	0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x44, 0x0a, 0x09, 0x4c,
+	0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x1a, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x45, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x12, 0x20, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x46, 0x6c, 0x61, 0x67, 0x30, 0x01, 0x12, 0x47, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x44,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x6c, 0x61, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x53, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c,
+	0x65, 0x61, 0x6e, 0x12, 0x1f, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e,
+	0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x6f, 0x6f, 0x6c, 0x65, 0x61, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4d, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76,
+	0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1d, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76,
+	0x31, 0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31,
+	0x2e, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x47, 0x0a, 0x0a, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65,
+	0x41, 0x6c, 0x6c, 0x12, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1c, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x6f,
+	0x6c, 0x76, 0x65, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x43,
+	0x0a, 0x09, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x1a, 0x2e, 0x66, 0x6c,
+	0x61, 0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x46, 0x6c, 0x61, 0x67, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x30, 0x01, 0x12, 0x53, 0x0a, 0x10, 0x57, 0x61, 0x74, 0x63, 0x68, 0x41, 0x6c, 0x6c, 0x50,
+	0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x12, 0x21, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x41, 0x6c, 0x6c, 0x50, 0x72, 0x6f, 0x6a, 0x65,
+	0x63, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x66, 0x6c, 0x61,
+	0x67, 0x7a, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x46, 0x6c, 0x61,
+	0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x4c, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x1d, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a,
+	0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x50, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x32, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x74, 0x74, 0x2d, 0x72, 0x69, 0x6c, 0x65, 0x79, 0x2f,
+	0x66, 0x6c, 0x61, 0x67, 0x7a, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x76, 0x31, 0x3b, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
 	file_api_proto_v1_flag_service_proto_rawDescOnce sync.Once
 	file_api_proto_v1_flag_service_proto_rawDescData = file_api_proto_v1_flag_service_proto_rawDesc
 )
@@ -1170,61 +1908,90 @@ func file_api_proto_v1_flag_service_proto_rawDescGZIP() []byte {
 	return file_api_proto_v1_flag_service_proto_rawDescData
 }
 
-var file_api_proto_v1_flag_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_api_proto_v1_flag_service_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_api_proto_v1_flag_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_api_proto_v1_flag_service_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_api_proto_v1_flag_service_proto_goTypes = []any{
-	(WatchFlagEventType)(0),        // 0: flagz.v1.WatchFlagEventType
-	(*Flag)(nil),                   // 1: flagz.v1.Flag
-	(*CreateFlagRequest)(nil),      // 2: flagz.v1.CreateFlagRequest
-	(*CreateFlagResponse)(nil),     // 3: flagz.v1.CreateFlagResponse
-	(*UpdateFlagRequest)(nil),      // 4: flagz.v1.UpdateFlagRequest
-	(*UpdateFlagResponse)(nil),     // 5: flagz.v1.UpdateFlagResponse
-	(*GetFlagRequest)(nil),         // 6: flagz.v1.GetFlagRequest
-	(*GetFlagResponse)(nil),        // 7: flagz.v1.GetFlagResponse
-	(*ListFlagsRequest)(nil),       // 8: flagz.v1.ListFlagsRequest
-	(*ListFlagsResponse)(nil),      // 9: flagz.v1.ListFlagsResponse
-	(*DeleteFlagRequest)(nil),      // 10: flagz.v1.DeleteFlagRequest
-	(*DeleteFlagResponse)(nil),     // 11: flagz.v1.DeleteFlagResponse
-	(*ResolveBooleanRequest)(nil),  // 12: flagz.v1.ResolveBooleanRequest
-	(*ResolveBooleanResponse)(nil), // 13: flagz.v1.ResolveBooleanResponse
-	(*ResolveBatchRequest)(nil),    // 14: flagz.v1.ResolveBatchRequest
-	(*ResolveBatchResult)(nil),     // 15: flagz.v1.ResolveBatchResult
-	(*ResolveBatchResponse)(nil),   // 16: flagz.v1.ResolveBatchResponse
-	(*WatchFlagRequest)(nil),       // 17: flagz.v1.WatchFlagRequest
-	(*WatchFlagEvent)(nil),         // 18: flagz.v1.WatchFlagEvent
+	(EvaluationReason)(0),           // 0: flagz.v1.EvaluationReason
+	(WatchFlagEventType)(0),         // 1: flagz.v1.WatchFlagEventType
+	(*Flag)(nil),                    // 2: flagz.v1.Flag
+	(*CreateFlagRequest)(nil),       // 3: flagz.v1.CreateFlagRequest
+	(*CreateFlagResponse)(nil),      // 4: flagz.v1.CreateFlagResponse
+	(*UpdateFlagRequest)(nil),       // 5: flagz.v1.UpdateFlagRequest
+	(*UpdateFlagResponse)(nil),      // 6: flagz.v1.UpdateFlagResponse
+	(*GetFlagRequest)(nil),          // 7: flagz.v1.GetFlagRequest
+	(*GetFlagResponse)(nil),         // 8: flagz.v1.GetFlagResponse
+	(*ListFlagsRequest)(nil),        // 9: flagz.v1.ListFlagsRequest
+	(*ListFlagsResponse)(nil),       // 10: flagz.v1.ListFlagsResponse
+	(*DeleteFlagRequest)(nil),       // 11: flagz.v1.DeleteFlagRequest
+	(*DeleteFlagResponse)(nil),      // 12: flagz.v1.DeleteFlagResponse
+	(*ResolveBooleanRequest)(nil),   // 13: flagz.v1.ResolveBooleanRequest
+	(*ResolveBooleanResponse)(nil),  // 14: flagz.v1.ResolveBooleanResponse
+	(*ContextWarning)(nil),          // 15: flagz.v1.ContextWarning
+	(*ResolveBatchRequest)(nil),     // 16: flagz.v1.ResolveBatchRequest
+	(*ResolveBatchResult)(nil),      // 17: flagz.v1.ResolveBatchResult
+	(*ResolveBatchResponse)(nil),    // 18: flagz.v1.ResolveBatchResponse
+	(*ResolveAllRequest)(nil),       // 19: flagz.v1.ResolveAllRequest
+	(*ResolveAllResponse)(nil),      // 20: flagz.v1.ResolveAllResponse
+	(*WatchFlagRequest)(nil),        // 21: flagz.v1.WatchFlagRequest
+	(*WatchFlagEvent)(nil),          // 22: flagz.v1.WatchFlagEvent
+	(*WatchAllProjectsRequest)(nil), // 23: flagz.v1.WatchAllProjectsRequest
+	(*ProjectFlagEvent)(nil),        // 24: flagz.v1.ProjectFlagEvent
+	(*WatchProjectRequest)(nil),     // 25: flagz.v1.WatchProjectRequest
+	(*ProjectSnapshot)(nil),         // 26: flagz.v1.ProjectSnapshot
+	(*WatchProjectEvent)(nil),       // 27: flagz.v1.WatchProjectEvent
+	(*ListFlagsStreamRequest)(nil),  // 28: flagz.v1.ListFlagsStreamRequest
+	nil,                             // 29: flagz.v1.ResolveAllResponse.ValuesEntry
 }
 var file_api_proto_v1_flag_service_proto_depIdxs = []int32{
-	1,  // 0: flagz.v1.CreateFlagRequest.flag:type_name -> flagz.v1.Flag
-	1,  // 1: flagz.v1.CreateFlagResponse.flag:type_name -> flagz.v1.Flag
-	1,  // 2: flagz.v1.UpdateFlagRequest.flag:type_name -> flagz.v1.Flag
-	1,  // 3: flagz.v1.UpdateFlagResponse.flag:type_name -> flagz.v1.Flag
-	1,  // 4: flagz.v1.GetFlagResponse.flag:type_name -> flagz.v1.Flag
-	1,  // 5: flagz.v1.ListFlagsResponse.flags:type_name -> flagz.v1.Flag
-	12, // 6: flagz.v1.ResolveBatchRequest.requests:type_name -> flagz.v1.ResolveBooleanRequest
-	15, // 7: flagz.v1.ResolveBatchResponse.results:type_name -> flagz.v1.ResolveBatchResult
-	0,  // 8: flagz.v1.WatchFlagEvent.type:type_name -> flagz.v1.WatchFlagEventType
-	1,  // 9: flagz.v1.WatchFlagEvent.flag:type_name -> flagz.v1.Flag
-	2,  // 10: flagz.v1.FlagService.CreateFlag:input_type -> flagz.v1.CreateFlagRequest
-	4,  // 11: flagz.v1.FlagService.UpdateFlag:input_type -> flagz.v1.UpdateFlagRequest
-	6,  // 12: flagz.v1.FlagService.GetFlag:input_type -> flagz.v1.GetFlagRequest
-	8,  // 13: flagz.v1.FlagService.ListFlags:input_type -> flagz.v1.ListFlagsRequest
-	10, // 14: flagz.v1.FlagService.DeleteFlag:input_type -> flagz.v1.DeleteFlagRequest
-	12, // 15: flagz.v1.FlagService.ResolveBoolean:input_type -> flagz.v1.ResolveBooleanRequest
-	14, // 16: flagz.v1.FlagService.ResolveBatch:input_type -> flagz.v1.ResolveBatchRequest
-	17, // 17: flagz.v1.FlagService.WatchFlag:input_type -> flagz.v1.WatchFlagRequest
-	3,  // 18: flagz.v1.FlagService.CreateFlag:output_type -> flagz.v1.CreateFlagResponse
-	5,  // 19: flagz.v1.FlagService.UpdateFlag:output_type -> flagz.v1.UpdateFlagResponse
-	7,  // 20: flagz.v1.FlagService.GetFlag:output_type -> flagz.v1.GetFlagResponse
-	9,  // 21: flagz.v1.FlagService.ListFlags:output_type -> flagz.v1.ListFlagsResponse
-	11, // 22: flagz.v1.FlagService.DeleteFlag:output_type -> flagz.v1.DeleteFlagResponse
-	13, // 23: flagz.v1.FlagService.ResolveBoolean:output_type -> flagz.v1.ResolveBooleanResponse
-	16, // 24: flagz.v1.FlagService.ResolveBatch:output_type -> flagz.v1.ResolveBatchResponse
-	18, // 25: flagz.v1.FlagService.WatchFlag:output_type -> flagz.v1.WatchFlagEvent
-	18, // [18:26] is the sub-list for method output_type
-	10, // [10:18] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	2,  // 0: flagz.v1.CreateFlagRequest.flag:type_name -> flagz.v1.Flag
+	2,  // 1: flagz.v1.CreateFlagResponse.flag:type_name -> flagz.v1.Flag
+	2,  // 2: flagz.v1.UpdateFlagRequest.flag:type_name -> flagz.v1.Flag
+	2,  // 3: flagz.v1.UpdateFlagResponse.flag:type_name -> flagz.v1.Flag
+	2,  // 4: flagz.v1.GetFlagResponse.flag:type_name -> flagz.v1.Flag
+	2,  // 5: flagz.v1.ListFlagsResponse.flags:type_name -> flagz.v1.Flag
+	0,  // 6: flagz.v1.ResolveBooleanResponse.reason:type_name -> flagz.v1.EvaluationReason
+	15, // 7: flagz.v1.ResolveBooleanResponse.warnings:type_name -> flagz.v1.ContextWarning
+	13, // 8: flagz.v1.ResolveBatchRequest.requests:type_name -> flagz.v1.ResolveBooleanRequest
+	0,  // 9: flagz.v1.ResolveBatchResult.reason:type_name -> flagz.v1.EvaluationReason
+	15, // 10: flagz.v1.ResolveBatchResult.warnings:type_name -> flagz.v1.ContextWarning
+	17, // 11: flagz.v1.ResolveBatchResponse.results:type_name -> flagz.v1.ResolveBatchResult
+	29, // 12: flagz.v1.ResolveAllResponse.values:type_name -> flagz.v1.ResolveAllResponse.ValuesEntry
+	15, // 13: flagz.v1.ResolveAllResponse.warnings:type_name -> flagz.v1.ContextWarning
+	1,  // 14: flagz.v1.WatchFlagEvent.type:type_name -> flagz.v1.WatchFlagEventType
+	2,  // 15: flagz.v1.WatchFlagEvent.flag:type_name -> flagz.v1.Flag
+	22, // 16: flagz.v1.ProjectFlagEvent.event:type_name -> flagz.v1.WatchFlagEvent
+	2,  // 17: flagz.v1.ProjectSnapshot.flags:type_name -> flagz.v1.Flag
+	26, // 18: flagz.v1.WatchProjectEvent.snapshot:type_name -> flagz.v1.ProjectSnapshot
+	22, // 19: flagz.v1.WatchProjectEvent.change:type_name -> flagz.v1.WatchFlagEvent
+	3,  // 20: flagz.v1.FlagService.CreateFlag:input_type -> flagz.v1.CreateFlagRequest
+	5,  // 21: flagz.v1.FlagService.UpdateFlag:input_type -> flagz.v1.UpdateFlagRequest
+	7,  // 22: flagz.v1.FlagService.GetFlag:input_type -> flagz.v1.GetFlagRequest
+	9,  // 23: flagz.v1.FlagService.ListFlags:input_type -> flagz.v1.ListFlagsRequest
+	28, // 24: flagz.v1.FlagService.ListFlagsStream:input_type -> flagz.v1.ListFlagsStreamRequest
+	11, // 25: flagz.v1.FlagService.DeleteFlag:input_type -> flagz.v1.DeleteFlagRequest
+	13, // 26: flagz.v1.FlagService.ResolveBoolean:input_type -> flagz.v1.ResolveBooleanRequest
+	16, // 27: flagz.v1.FlagService.ResolveBatch:input_type -> flagz.v1.ResolveBatchRequest
+	19, // 28: flagz.v1.FlagService.ResolveAll:input_type -> flagz.v1.ResolveAllRequest
+	21, // 29: flagz.v1.FlagService.WatchFlag:input_type -> flagz.v1.WatchFlagRequest
+	23, // 30: flagz.v1.FlagService.WatchAllProjects:input_type -> flagz.v1.WatchAllProjectsRequest
+	25, // 31: flagz.v1.FlagService.WatchProject:input_type -> flagz.v1.WatchProjectRequest
+	4,  // 32: flagz.v1.FlagService.CreateFlag:output_type -> flagz.v1.CreateFlagResponse
+	6,  // 33: flagz.v1.FlagService.UpdateFlag:output_type -> flagz.v1.UpdateFlagResponse
+	8,  // 34: flagz.v1.FlagService.GetFlag:output_type -> flagz.v1.GetFlagResponse
+	10, // 35: flagz.v1.FlagService.ListFlags:output_type -> flagz.v1.ListFlagsResponse
+	2,  // 36: flagz.v1.FlagService.ListFlagsStream:output_type -> flagz.v1.Flag
+	12, // 37: flagz.v1.FlagService.DeleteFlag:output_type -> flagz.v1.DeleteFlagResponse
+	14, // 38: flagz.v1.FlagService.ResolveBoolean:output_type -> flagz.v1.ResolveBooleanResponse
+	18, // 39: flagz.v1.FlagService.ResolveBatch:output_type -> flagz.v1.ResolveBatchResponse
+	20, // 40: flagz.v1.FlagService.ResolveAll:output_type -> flagz.v1.ResolveAllResponse
+	22, // 41: flagz.v1.FlagService.WatchFlag:output_type -> flagz.v1.WatchFlagEvent
+	24, // 42: flagz.v1.FlagService.WatchAllProjects:output_type -> flagz.v1.ProjectFlagEvent
+	27, // 43: flagz.v1.FlagService.WatchProject:output_type -> flagz.v1.WatchProjectEvent
+	32, // [32:44] is the sub-list for method output_type
+	20, // [20:32] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_v1_flag_service_proto_init() }
@@ -1390,7 +2157,7 @@ func file_api_proto_v1_flag_service_proto_init() {
 			}
 		}
 		file_api_proto_v1_flag_service_proto_msgTypes[13].Exporter = func(v any, i int) any {
-			switch v := v.(*ResolveBatchRequest); i {
+			switch v := v.(*ContextWarning); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1402,7 +2169,7 @@ func file_api_proto_v1_flag_service_proto_init() {
 			}
 		}
 		file_api_proto_v1_flag_service_proto_msgTypes[14].Exporter = func(v any, i int) any {
-			switch v := v.(*ResolveBatchResult); i {
+			switch v := v.(*ResolveBatchRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1414,7 +2181,7 @@ func file_api_proto_v1_flag_service_proto_init() {
 			}
 		}
 		file_api_proto_v1_flag_service_proto_msgTypes[15].Exporter = func(v any, i int) any {
-			switch v := v.(*ResolveBatchResponse); i {
+			switch v := v.(*ResolveBatchResult); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1426,7 +2193,7 @@ func file_api_proto_v1_flag_service_proto_init() {
 			}
 		}
 		file_api_proto_v1_flag_service_proto_msgTypes[16].Exporter = func(v any, i int) any {
-			switch v := v.(*WatchFlagRequest); i {
+			switch v := v.(*ResolveBatchResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1438,6 +2205,42 @@ func file_api_proto_v1_flag_service_proto_init() {
 			}
 		}
 		file_api_proto_v1_flag_service_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*ResolveAllRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*ResolveAllResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*WatchFlagRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[20].Exporter = func(v any, i int) any {
 			switch v := v.(*WatchFlagEvent); i {
 			case 0:
 				return &v.state
@@ -1449,14 +2252,88 @@ func file_api_proto_v1_flag_service_proto_init() {
 				return nil
 			}
 		}
+		file_api_proto_v1_flag_service_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*WatchAllProjectsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*ProjectFlagEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*WatchProjectRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*ProjectSnapshot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*WatchProjectEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_v1_flag_service_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*ListFlagsStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
+	file_api_proto_v1_flag_service_proto_msgTypes[12].OneofWrappers = []any{}
+	file_api_proto_v1_flag_service_proto_msgTypes[15].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_proto_v1_flag_service_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   18,
+			NumEnums:      2,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,14 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	FlagService_CreateFlag_FullMethodName     = "/flagz.v1.FlagService/CreateFlag"
-	FlagService_UpdateFlag_FullMethodName     = "/flagz.v1.FlagService/UpdateFlag"
-	FlagService_GetFlag_FullMethodName        = "/flagz.v1.FlagService/GetFlag"
-	FlagService_ListFlags_FullMethodName      = "/flagz.v1.FlagService/ListFlags"
-	FlagService_DeleteFlag_FullMethodName     = "/flagz.v1.FlagService/DeleteFlag"
-	FlagService_ResolveBoolean_FullMethodName = "/flagz.v1.FlagService/ResolveBoolean"
-	FlagService_ResolveBatch_FullMethodName   = "/flagz.v1.FlagService/ResolveBatch"
-	FlagService_WatchFlag_FullMethodName      = "/flagz.v1.FlagService/WatchFlag"
+	FlagService_CreateFlag_FullMethodName       = "/flagz.v1.FlagService/CreateFlag"
+	FlagService_UpdateFlag_FullMethodName       = "/flagz.v1.FlagService/UpdateFlag"
+	FlagService_GetFlag_FullMethodName          = "/flagz.v1.FlagService/GetFlag"
+	FlagService_ListFlags_FullMethodName        = "/flagz.v1.FlagService/ListFlags"
+	FlagService_ListFlagsStream_FullMethodName  = "/flagz.v1.FlagService/ListFlagsStream"
+	FlagService_DeleteFlag_FullMethodName       = "/flagz.v1.FlagService/DeleteFlag"
+	FlagService_ResolveBoolean_FullMethodName   = "/flagz.v1.FlagService/ResolveBoolean"
+	FlagService_ResolveBatch_FullMethodName     = "/flagz.v1.FlagService/ResolveBatch"
+	FlagService_ResolveAll_FullMethodName       = "/flagz.v1.FlagService/ResolveAll"
+	FlagService_WatchFlag_FullMethodName        = "/flagz.v1.FlagService/WatchFlag"
+	FlagService_WatchAllProjects_FullMethodName = "/flagz.v1.FlagService/WatchAllProjects"
+	FlagService_WatchProject_FullMethodName     = "/flagz.v1.FlagService/WatchProject"
 )
 
 // FlagServiceClient is the client API for FlagService service.
@@ -37,10 +41,14 @@ type FlagServiceClient interface {
 	UpdateFlag(ctx context.Context, in *UpdateFlagRequest, opts ...grpc.CallOption) (*UpdateFlagResponse, error)
 	GetFlag(ctx context.Context, in *GetFlagRequest, opts ...grpc.CallOption) (*GetFlagResponse, error)
 	ListFlags(ctx context.Context, in *ListFlagsRequest, opts ...grpc.CallOption) (*ListFlagsResponse, error)
+	ListFlagsStream(ctx context.Context, in *ListFlagsStreamRequest, opts ...grpc.CallOption) (FlagService_ListFlagsStreamClient, error)
 	DeleteFlag(ctx context.Context, in *DeleteFlagRequest, opts ...grpc.CallOption) (*DeleteFlagResponse, error)
 	ResolveBoolean(ctx context.Context, in *ResolveBooleanRequest, opts ...grpc.CallOption) (*ResolveBooleanResponse, error)
 	ResolveBatch(ctx context.Context, in *ResolveBatchRequest, opts ...grpc.CallOption) (*ResolveBatchResponse, error)
+	ResolveAll(ctx context.Context, in *ResolveAllRequest, opts ...grpc.CallOption) (*ResolveAllResponse, error)
 	WatchFlag(ctx context.Context, in *WatchFlagRequest, opts ...grpc.CallOption) (FlagService_WatchFlagClient, error)
+	WatchAllProjects(ctx context.Context, in *WatchAllProjectsRequest, opts ...grpc.CallOption) (FlagService_WatchAllProjectsClient, error)
+	WatchProject(ctx context.Context, in *WatchProjectRequest, opts ...grpc.CallOption) (FlagService_WatchProjectClient, error)
 }
 
 type flagServiceClient struct {
@@ -91,6 +99,39 @@ func (c *flagServiceClient) ListFlags(ctx context.Context, in *ListFlagsRequest,
 	return out, nil
 }
 
+func (c *flagServiceClient) ListFlagsStream(ctx context.Context, in *ListFlagsStreamRequest, opts ...grpc.CallOption) (FlagService_ListFlagsStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FlagService_ServiceDesc.Streams[0], FlagService_ListFlagsStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &flagServiceListFlagsStreamClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FlagService_ListFlagsStreamClient interface {
+	Recv() (*Flag, error)
+	grpc.ClientStream
+}
+
+type flagServiceListFlagsStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *flagServiceListFlagsStreamClient) Recv() (*Flag, error) {
+	m := new(Flag)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *flagServiceClient) DeleteFlag(ctx context.Context, in *DeleteFlagRequest, opts ...grpc.CallOption) (*DeleteFlagResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(DeleteFlagResponse)
@@ -121,9 +162,19 @@ func (c *flagServiceClient) ResolveBatch(ctx context.Context, in *ResolveBatchRe
 	return out, nil
 }
 
+func (c *flagServiceClient) ResolveAll(ctx context.Context, in *ResolveAllRequest, opts ...grpc.CallOption) (*ResolveAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResolveAllResponse)
+	err := c.cc.Invoke(ctx, FlagService_ResolveAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *flagServiceClient) WatchFlag(ctx context.Context, in *WatchFlagRequest, opts ...grpc.CallOption) (FlagService_WatchFlagClient, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &FlagService_ServiceDesc.Streams[0], FlagService_WatchFlag_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &FlagService_ServiceDesc.Streams[1], FlagService_WatchFlag_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,6 +205,72 @@ func (x *flagServiceWatchFlagClient) Recv() (*WatchFlagEvent, error) {
 	return m, nil
 }
 
+func (c *flagServiceClient) WatchAllProjects(ctx context.Context, in *WatchAllProjectsRequest, opts ...grpc.CallOption) (FlagService_WatchAllProjectsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FlagService_ServiceDesc.Streams[2], FlagService_WatchAllProjects_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &flagServiceWatchAllProjectsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FlagService_WatchAllProjectsClient interface {
+	Recv() (*ProjectFlagEvent, error)
+	grpc.ClientStream
+}
+
+type flagServiceWatchAllProjectsClient struct {
+	grpc.ClientStream
+}
+
+func (x *flagServiceWatchAllProjectsClient) Recv() (*ProjectFlagEvent, error) {
+	m := new(ProjectFlagEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *flagServiceClient) WatchProject(ctx context.Context, in *WatchProjectRequest, opts ...grpc.CallOption) (FlagService_WatchProjectClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FlagService_ServiceDesc.Streams[3], FlagService_WatchProject_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &flagServiceWatchProjectClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FlagService_WatchProjectClient interface {
+	Recv() (*WatchProjectEvent, error)
+	grpc.ClientStream
+}
+
+type flagServiceWatchProjectClient struct {
+	grpc.ClientStream
+}
+
+func (x *flagServiceWatchProjectClient) Recv() (*WatchProjectEvent, error) {
+	m := new(WatchProjectEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // FlagServiceServer is the server API for FlagService service.
 // All implementations must embed UnimplementedFlagServiceServer
 // for forward compatibility
@@ -162,10 +279,14 @@ type FlagServiceServer interface {
 	UpdateFlag(context.Context, *UpdateFlagRequest) (*UpdateFlagResponse, error)
 	GetFlag(context.Context, *GetFlagRequest) (*GetFlagResponse, error)
 	ListFlags(context.Context, *ListFlagsRequest) (*ListFlagsResponse, error)
+	ListFlagsStream(*ListFlagsStreamRequest, FlagService_ListFlagsStreamServer) error
 	DeleteFlag(context.Context, *DeleteFlagRequest) (*DeleteFlagResponse, error)
 	ResolveBoolean(context.Context, *ResolveBooleanRequest) (*ResolveBooleanResponse, error)
 	ResolveBatch(context.Context, *ResolveBatchRequest) (*ResolveBatchResponse, error)
+	ResolveAll(context.Context, *ResolveAllRequest) (*ResolveAllResponse, error)
 	WatchFlag(*WatchFlagRequest, FlagService_WatchFlagServer) error
+	WatchAllProjects(*WatchAllProjectsRequest, FlagService_WatchAllProjectsServer) error
+	WatchProject(*WatchProjectRequest, FlagService_WatchProjectServer) error
 	mustEmbedUnimplementedFlagServiceServer()
 }
 
@@ -185,6 +306,9 @@ func (UnimplementedFlagServiceServer) GetFlag(context.Context, *GetFlagRequest)
 func (UnimplementedFlagServiceServer) ListFlags(context.Context, *ListFlagsRequest) (*ListFlagsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListFlags not implemented")
 }
+func (UnimplementedFlagServiceServer) ListFlagsStream(*ListFlagsStreamRequest, FlagService_ListFlagsStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListFlagsStream not implemented")
+}
 func (UnimplementedFlagServiceServer) DeleteFlag(context.Context, *DeleteFlagRequest) (*DeleteFlagResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteFlag not implemented")
 }
@@ -194,9 +318,18 @@ func (UnimplementedFlagServiceServer) ResolveBoolean(context.Context, *ResolveBo
 func (UnimplementedFlagServiceServer) ResolveBatch(context.Context, *ResolveBatchRequest) (*ResolveBatchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ResolveBatch not implemented")
 }
+func (UnimplementedFlagServiceServer) ResolveAll(context.Context, *ResolveAllRequest) (*ResolveAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveAll not implemented")
+}
 func (UnimplementedFlagServiceServer) WatchFlag(*WatchFlagRequest, FlagService_WatchFlagServer) error {
 	return status.Errorf(codes.Unimplemented, "method WatchFlag not implemented")
 }
+func (UnimplementedFlagServiceServer) WatchAllProjects(*WatchAllProjectsRequest, FlagService_WatchAllProjectsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAllProjects not implemented")
+}
+func (UnimplementedFlagServiceServer) WatchProject(*WatchProjectRequest, FlagService_WatchProjectServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProject not implemented")
+}
 func (UnimplementedFlagServiceServer) mustEmbedUnimplementedFlagServiceServer() {}
 
 // UnsafeFlagServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -282,6 +415,27 @@ func _FlagService_ListFlags_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FlagService_ListFlagsStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListFlagsStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FlagServiceServer).ListFlagsStream(m, &flagServiceListFlagsStreamServer{ServerStream: stream})
+}
+
+type FlagService_ListFlagsStreamServer interface {
+	Send(*Flag) error
+	grpc.ServerStream
+}
+
+type flagServiceListFlagsStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *flagServiceListFlagsStreamServer) Send(m *Flag) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _FlagService_DeleteFlag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteFlagRequest)
 	if err := dec(in); err != nil {
@@ -336,6 +490,24 @@ func _FlagService_ResolveBatch_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FlagService_ResolveAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FlagServiceServer).ResolveAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FlagService_ResolveAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FlagServiceServer).ResolveAll(ctx, req.(*ResolveAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _FlagService_WatchFlag_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(WatchFlagRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -357,6 +529,48 @@ func (x *flagServiceWatchFlagServer) Send(m *WatchFlagEvent) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _FlagService_WatchAllProjects_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAllProjectsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FlagServiceServer).WatchAllProjects(m, &flagServiceWatchAllProjectsServer{ServerStream: stream})
+}
+
+type FlagService_WatchAllProjectsServer interface {
+	Send(*ProjectFlagEvent) error
+	grpc.ServerStream
+}
+
+type flagServiceWatchAllProjectsServer struct {
+	grpc.ServerStream
+}
+
+func (x *flagServiceWatchAllProjectsServer) Send(m *ProjectFlagEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FlagService_WatchProject_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProjectRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FlagServiceServer).WatchProject(m, &flagServiceWatchProjectServer{ServerStream: stream})
+}
+
+type FlagService_WatchProjectServer interface {
+	Send(*WatchProjectEvent) error
+	grpc.ServerStream
+}
+
+type flagServiceWatchProjectServer struct {
+	grpc.ServerStream
+}
+
+func (x *flagServiceWatchProjectServer) Send(m *WatchProjectEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // FlagService_ServiceDesc is the grpc.ServiceDesc for FlagService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -392,13 +606,32 @@ var FlagService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ResolveBatch",
 			Handler:    _FlagService_ResolveBatch_Handler,
 		},
+		{
+			MethodName: "ResolveAll",
+			Handler:    _FlagService_ResolveAll_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListFlagsStream",
+			Handler:       _FlagService_ListFlagsStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "WatchFlag",
 			Handler:       _FlagService_WatchFlag_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchAllProjects",
+			Handler:       _FlagService_WatchAllProjects_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchProject",
+			Handler:       _FlagService_WatchProject_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "api/proto/v1/flag_service.proto",
 }
@@ -0,0 +1,20 @@
+package flagz
+
+import "errors"
+
+// Errors both clients report for common failures, so callers can test for
+// them with errors.Is whichever transport they use. The error returned is
+// the transport's own error type, with the server's status and message,
+// wrapping one of these.
+var (
+	// ErrNotFound means the flag, or whatever else was asked for, does not
+	// exist.
+	ErrNotFound = errors.New("flagz: not found")
+	// ErrInvalidRules means the server rejected a flag's rules.
+	ErrInvalidRules = errors.New("flagz: invalid rules")
+	// ErrUnauthorized means the API key is missing, unknown or revoked.
+	ErrUnauthorized = errors.New("flagz: unauthorized")
+	// ErrRateLimited means the server turned the request away to protect
+	// itself, and it may succeed if sent again later.
+	ErrRateLimited = errors.New("flagz: rate limited")
+)
@@ -8,6 +8,7 @@ package flagz
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
@@ -41,6 +42,20 @@ type Flag struct {
 	Rules       []Rule          // may be nil
 	CreatedAt   time.Time       // zero on gRPC (not on wire)
 	UpdatedAt   time.Time       // zero on gRPC (not on wire)
+	// BucketingSalt seeds percentage rollout bucketing. Read-only; empty on
+	// gRPC (not on wire).
+	BucketingSalt string
+	// Targets are the identifiers the flag is individually switched on or
+	// off for. Read-only; empty on gRPC (not on wire).
+	Targets Targets
+}
+
+// Targets pins individual identifiers to a flag value ahead of its rules.
+// Attribute names the evaluation-context attribute holding the identifier.
+type Targets struct {
+	Attribute string
+	Allow     []string
+	Deny      []string
 }
 
 // Rule is a targeting rule that determines flag evaluation.
@@ -66,6 +81,13 @@ type EvaluateRequest struct {
 type EvaluateResult struct {
 	Key   string
 	Value bool
+	// Reason explains the value: "TARGET_MATCH", "RULE_MATCH", "DEFAULT",
+	// "DISABLED" or "FLAG_NOT_FOUND". Empty when talking to a server that predates reasons.
+	Reason string
+	// RuleIndex is the index of the matching rule when Reason is "RULE_MATCH".
+	RuleIndex *int
+	// Variant is "default" when the value came from the flag's variants default.
+	Variant string
 }
 
 // FlagEvent is a real-time notification of a flag change.
@@ -75,3 +97,84 @@ type FlagEvent struct {
 	Flag    *Flag // nil on delete/error
 	EventID int64
 }
+
+// SDKConfig holds the behaviour hints the server sends when a client fetches
+// a flag snapshot or opens a stream. Clients start from [DefaultSDKConfig]
+// and adopt each hint the server sends, so operators can tune a whole fleet
+// centrally.
+type SDKConfig struct {
+	// PollInterval is how often [Poll] refreshes flags.
+	PollInterval time.Duration
+	// MaxBatchSize caps the evaluations sent in one request; EvaluateBatch
+	// splits larger batches.
+	MaxBatchSize int
+	// HeartbeatInterval is how often the server sends stream heartbeats.
+	// The HTTP client drops a stream that stays silent for twice this long.
+	// Zero disables the check.
+	HeartbeatInterval time.Duration
+}
+
+// DefaultSDKConfig returns the configuration used until the server sends
+// its own.
+func DefaultSDKConfig() SDKConfig {
+	return SDKConfig{
+		PollInterval: 30 * time.Second,
+		MaxBatchSize: 100,
+	}
+}
+
+// ParseSDKConfig overlays the JSON hint block sent by the server onto base.
+// Hints that are missing or not positive leave the base value in place. It
+// returns base unchanged and false if raw is empty or malformed.
+func ParseSDKConfig(raw string, base SDKConfig) (SDKConfig, bool) {
+	if raw == "" {
+		return base, false
+	}
+	var wire struct {
+		PollIntervalMS      int64 `json:"poll_interval_ms"`
+		MaxBatchSize        int   `json:"max_batch_size"`
+		HeartbeatIntervalMS int64 `json:"heartbeat_interval_ms"`
+	}
+	if err := json.Unmarshal([]byte(raw), &wire); err != nil {
+		return base, false
+	}
+	cfg := base
+	if wire.PollIntervalMS > 0 {
+		cfg.PollInterval = time.Duration(wire.PollIntervalMS) * time.Millisecond
+	}
+	if wire.MaxBatchSize > 0 {
+		cfg.MaxBatchSize = wire.MaxBatchSize
+	}
+	if wire.HeartbeatIntervalMS > 0 {
+		cfg.HeartbeatInterval = time.Duration(wire.HeartbeatIntervalMS) * time.Millisecond
+	}
+	return cfg, true
+}
+
+// Poller is implemented by clients that can be refreshed with [Poll].
+type Poller interface {
+	ListFlags(ctx context.Context) ([]Flag, error)
+	SDKConfig() SDKConfig
+}
+
+// Poll lists flags immediately and then once per the client's current
+// PollInterval, passing each result to fn, until ctx is cancelled. Because
+// the interval is re-read after every fetch, a new interval sent by the
+// server takes effect on the next tick. Poll returns ctx.Err().
+func Poll(ctx context.Context, client Poller, fn func([]Flag, error)) error {
+	for {
+		fn(client.ListFlags(ctx))
+
+		interval := client.SDKConfig().PollInterval
+		if interval <= 0 {
+			interval = DefaultSDKConfig().PollInterval
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
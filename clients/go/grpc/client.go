@@ -3,13 +3,21 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
 
-	flagz "github.com/matt-riley/flagz/clients/go"
 	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagz "github.com/matt-riley/flagz/clients/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -20,31 +28,114 @@ type Config struct {
 	// APIKey is the bearer token in "id.secret" format.
 	APIKey string
 	// DialOpts are additional gRPC dial options (e.g. TLS credentials).
-	// If empty, insecure credentials are used.
+	// If empty and TLSConfig is nil, insecure credentials are used.
 	DialOpts []grpc.DialOption
+	// TLSConfig, if set, connects over TLS with it, for example to trust a
+	// private CA or present a client certificate.
+	TLSConfig *tls.Config
+	// Proxy is an http:// proxy to tunnel connections through with HTTP
+	// CONNECT, overriding the HTTPS_PROXY and NO_PROXY environment
+	// variables gRPC otherwise follows. A username and password in the URL
+	// are sent as basic Proxy-Authorization. Address is resolved by the
+	// proxy, not locally.
+	Proxy *url.URL
+	// DialContext opens the client's network connections, to Proxy if it
+	// is set and to Address otherwise. Setting it without Proxy stops gRPC
+	// from following the proxy environment variables.
+	DialContext func(ctx context.Context, addr string) (net.Conn, error)
 }
 
+// sdkConfigKey is the header metadata key carrying the server's SDK
+// configuration on ListFlags and WatchFlag.
+const sdkConfigKey = "flagz-sdk-config"
+
 // Client implements flagz.FlagManager, flagz.Evaluator, and flagz.Streamer over gRPC.
 type Client struct {
-	cfg    Config
-	stub   flagspb.FlagServiceClient
-	conn   *grpc.ClientConn
+	cfg  Config
+	stub flagspb.FlagServiceClient
+	conn *grpc.ClientConn
+
+	sdkMu  sync.RWMutex
+	sdkCfg flagz.SDKConfig
 }
 
 // NewGRPCClient dials the flagz gRPC server and returns a new client.
 // Call Close() when done.
 func NewGRPCClient(cfg Config) (*Client, error) {
 	opts := []grpc.DialOption{}
-	if len(cfg.DialOpts) > 0 {
-		opts = append(opts, cfg.DialOpts...)
-	} else {
+	switch {
+	case cfg.TLSConfig != nil:
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg.TLSConfig)))
+	case len(cfg.DialOpts) == 0:
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
-	conn, err := grpc.NewClient(cfg.Address, opts...)
+
+	target := cfg.Address
+	dial := dialFunc(cfg.DialContext)
+	if cfg.Proxy != nil {
+		if dial == nil {
+			dial = dialTCP
+		}
+		var err error
+		if dial, err = proxyDialer(cfg.Proxy, dial); err != nil {
+			return nil, fmt.Errorf("flagz: grpc proxy: %w", err)
+		}
+		// Hand the dialer the host name, for the proxy to resolve.
+		if !strings.Contains(target, "://") {
+			target = "passthrough:///" + target
+		}
+	}
+	if dial != nil {
+		opts = append(opts, grpc.WithContextDialer(dial))
+	}
+
+	opts = append(opts, cfg.DialOpts...)
+	conn, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("flagz: grpc dial: %w", err)
 	}
-	return &Client{cfg: cfg, stub: flagspb.NewFlagServiceClient(conn), conn: conn}, nil
+	return &Client{
+		cfg:    cfg,
+		stub:   flagspb.NewFlagServiceClient(conn),
+		conn:   conn,
+		sdkCfg: flagz.DefaultSDKConfig(),
+	}, nil
+}
+
+// SDKConfig returns the SDK configuration last sent by the server, or
+// flagz.DefaultSDKConfig if it has not sent one yet.
+func (c *Client) SDKConfig() flagz.SDKConfig {
+	c.sdkMu.RLock()
+	defer c.sdkMu.RUnlock()
+	return c.sdkCfg
+}
+
+// updateSDKConfig adopts the SDK configuration carried by header metadata,
+// if any.
+func (c *Client) updateSDKConfig(md metadata.MD) {
+	vals := md.Get(sdkConfigKey)
+	if len(vals) == 0 {
+		return
+	}
+	c.sdkMu.Lock()
+	defer c.sdkMu.Unlock()
+	if cfg, ok := flagz.ParseSDKConfig(vals[0], c.sdkCfg); ok {
+		c.sdkCfg = cfg
+	}
+}
+
+// Ping checks that the server can be reached and accepts the client's API
+// key by calling the standard gRPC health service once, so an application
+// can fail fast at startup when the network, proxy, TLS or key is wrong.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(c.conn).Check(c.authCtx(ctx), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return rpcError("Ping", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("flagz: Ping: server is %s", resp.GetStatus())
+	}
+	return nil
 }
 
 // Close closes the underlying gRPC connection.
@@ -129,7 +220,7 @@ func (c *Client) CreateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, e
 	}
 	resp, err := c.stub.CreateFlag(c.authCtx(ctx), &flagspb.CreateFlagRequest{Flag: p})
 	if err != nil {
-		return flagz.Flag{}, fmt.Errorf("flagz: CreateFlag: %w", err)
+		return flagz.Flag{}, rpcError("CreateFlag", err)
 	}
 	return protoToFlag(resp.Flag)
 }
@@ -137,25 +228,94 @@ func (c *Client) CreateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, e
 func (c *Client) GetFlag(ctx context.Context, key string) (flagz.Flag, error) {
 	resp, err := c.stub.GetFlag(c.authCtx(ctx), &flagspb.GetFlagRequest{Key: key})
 	if err != nil {
-		return flagz.Flag{}, fmt.Errorf("flagz: GetFlag: %w", err)
+		return flagz.Flag{}, rpcError("GetFlag", err)
 	}
 	return protoToFlag(resp.Flag)
 }
 
 func (c *Client) ListFlags(ctx context.Context) ([]flagz.Flag, error) {
-	resp, err := c.stub.ListFlags(c.authCtx(ctx), &flagspb.ListFlagsRequest{})
+	flags, _, err := c.ListFlagsPage(ctx, ListFlagsOptions{})
+	return flags, err
+}
+
+// ListFlagsOptions selects a page of flags for [Client.ListFlagsPage] and
+// [Client.ListFlagsAll].
+type ListFlagsOptions struct {
+	// PageSize is the maximum number of flags per page. Zero returns every
+	// flag in one page.
+	PageSize int32
+	// PageToken is the next page token of the previous page. Empty starts
+	// from the first flag.
+	PageToken string
+}
+
+// ListFlagsPage returns one page of flags, sorted by key, and the token of
+// the next page, which is empty after the last page.
+func (c *Client) ListFlagsPage(ctx context.Context, opts ListFlagsOptions) ([]flagz.Flag, string, error) {
+	var header metadata.MD
+	resp, err := c.stub.ListFlags(c.authCtx(ctx), &flagspb.ListFlagsRequest{
+		PageSize:  opts.PageSize,
+		PageToken: opts.PageToken,
+	}, grpc.Header(&header))
 	if err != nil {
-		return nil, fmt.Errorf("flagz: ListFlags: %w", err)
+		return nil, "", rpcError("ListFlags", err)
 	}
+	c.updateSDKConfig(header)
 	flags := make([]flagz.Flag, 0, len(resp.Flags))
 	for _, p := range resp.Flags {
 		f, err := protoToFlag(p)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		flags = append(flags, f)
 	}
-	return flags, nil
+	return flags, resp.NextPageToken, nil
+}
+
+// ListFlagsAll returns every flag from opts.PageToken on, fetching
+// opts.PageSize flags per call, so large projects are listed without one
+// oversized response.
+func (c *Client) ListFlagsAll(ctx context.Context, opts ListFlagsOptions) ([]flagz.Flag, error) {
+	var all []flagz.Flag
+	for {
+		flags, next, err := c.ListFlagsPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, flags...)
+		if next == "" {
+			return all, nil
+		}
+		opts.PageToken = next
+	}
+}
+
+// ListFlagsStream calls fn with every flag, sorted by key, as the server
+// streams them, so a very large project is exported without holding all
+// of its flags at once. It stops at the first error fn returns.
+func (c *Client) ListFlagsStream(ctx context.Context, fn func(flagz.Flag) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.stub.ListFlagsStream(c.authCtx(ctx), &flagspb.ListFlagsStreamRequest{})
+	if err != nil {
+		return rpcError("ListFlagsStream", err)
+	}
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return rpcError("ListFlagsStream", err)
+		}
+		f, err := protoToFlag(p)
+		if err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
 }
 
 func (c *Client) UpdateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, error) {
@@ -165,7 +325,7 @@ func (c *Client) UpdateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, e
 	}
 	resp, err := c.stub.UpdateFlag(c.authCtx(ctx), &flagspb.UpdateFlagRequest{Flag: p})
 	if err != nil {
-		return flagz.Flag{}, fmt.Errorf("flagz: UpdateFlag: %w", err)
+		return flagz.Flag{}, rpcError("UpdateFlag", err)
 	}
 	return protoToFlag(resp.Flag)
 }
@@ -173,7 +333,7 @@ func (c *Client) UpdateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, e
 func (c *Client) DeleteFlag(ctx context.Context, key string) error {
 	_, err := c.stub.DeleteFlag(c.authCtx(ctx), &flagspb.DeleteFlagRequest{Key: key})
 	if err != nil {
-		return fmt.Errorf("flagz: DeleteFlag: %w", err)
+		return rpcError("DeleteFlag", err)
 	}
 	return nil
 }
@@ -191,12 +351,30 @@ func (c *Client) Evaluate(ctx context.Context, key string, evalCtx flagz.Evaluat
 		DefaultValue: defaultValue,
 	})
 	if err != nil {
-		return defaultValue, fmt.Errorf("flagz: ResolveBoolean: %w", err)
+		return defaultValue, rpcError("ResolveBoolean", err)
 	}
 	return resp.Value, nil
 }
 
+// EvaluateBatch evaluates reqs, splitting them into calls of at most the
+// server's MaxBatchSize. Results are returned in request order.
 func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest) ([]flagz.EvaluateResult, error) {
+	size := c.SDKConfig().MaxBatchSize
+	if size <= 0 || len(reqs) <= size {
+		return c.evaluateBatch(ctx, reqs)
+	}
+	results := make([]flagz.EvaluateResult, 0, len(reqs))
+	for start := 0; start < len(reqs); start += size {
+		chunk, err := c.evaluateBatch(ctx, reqs[start:min(start+size, len(reqs))])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+func (c *Client) evaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest) ([]flagz.EvaluateResult, error) {
 	pbReqs := make([]*flagspb.ResolveBooleanRequest, len(reqs))
 	for i, r := range reqs {
 		ctxJSON, err := json.Marshal(r.Context)
@@ -211,11 +389,18 @@ func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest
 	}
 	resp, err := c.stub.ResolveBatch(c.authCtx(ctx), &flagspb.ResolveBatchRequest{Requests: pbReqs})
 	if err != nil {
-		return nil, fmt.Errorf("flagz: ResolveBatch: %w", err)
+		return nil, rpcError("ResolveBatch", err)
 	}
 	results := make([]flagz.EvaluateResult, len(resp.Results))
 	for i, r := range resp.Results {
-		results[i] = flagz.EvaluateResult{Key: r.Key, Value: r.Value}
+		results[i] = flagz.EvaluateResult{Key: r.Key, Value: r.Value, Variant: r.GetVariant()}
+		if r.GetReason() != flagspb.EvaluationReason_EVALUATION_REASON_UNSPECIFIED {
+			results[i].Reason = r.GetReason().String()
+		}
+		if r.RuleIndex != nil {
+			ruleIndex := int(r.GetRuleIndex())
+			results[i].RuleIndex = &ruleIndex
+		}
 	}
 	return results, nil
 }
@@ -223,44 +408,90 @@ func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest
 // -- Streamer ----------------------------------------------------------------
 
 // Stream connects to the WatchFlag gRPC stream and emits FlagEvents on the returned channel.
-// The channel is closed when ctx is cancelled or the stream ends.
+// The channel is closed when ctx is cancelled or the stream ends. See
+// [Client.StreamWithReconnect] for a stream that survives disconnects.
 func (c *Client) Stream(ctx context.Context, lastEventID int64) (<-chan flagz.FlagEvent, error) {
-	stream, err := c.stub.WatchFlag(c.authCtx(ctx), &flagspb.WatchFlagRequest{
-		LastEventId: lastEventID,
-	})
+	return c.WatchFlag(ctx, "", lastEventID)
+}
+
+// WatchFlag is like [Client.Stream], but emits only the events for the flag
+// key, resuming after lastEventID. An empty key watches every flag.
+func (c *Client) WatchFlag(ctx context.Context, key string, lastEventID int64) (<-chan flagz.FlagEvent, error) {
+	stream, err := c.watchFlag(ctx, key, lastEventID)
 	if err != nil {
-		return nil, fmt.Errorf("flagz: WatchFlag: %w", err)
+		return nil, err
 	}
 
 	ch := make(chan flagz.FlagEvent, 16)
 	go func() {
 		defer close(ch)
-		for {
-			ev, err := stream.Recv()
-			if err != nil {
-				return
-			}
-			fe := flagz.FlagEvent{EventID: ev.EventId, Key: ev.Key}
-			switch ev.Type {
-			case flagspb.WatchFlagEventType_FLAG_UPDATED:
-				fe.Type = "update"
-			case flagspb.WatchFlagEventType_FLAG_DELETED:
-				fe.Type = "delete"
-			default:
-				fe.Type = "unknown"
-			}
-			if ev.Flag != nil {
-				f, err := protoToFlag(ev.Flag)
-				if err == nil {
-					fe.Flag = &f
-				}
-			}
+		_ = c.recvEvents(ctx, stream, nil, func(fe flagz.FlagEvent) bool {
 			select {
 			case ch <- fe:
+				return true
 			case <-ctx.Done():
-				return
+				return false
 			}
-		}
+		})
 	}()
 	return ch, nil
 }
+
+func (c *Client) watchFlag(ctx context.Context, key string, lastEventID int64) (flagspb.FlagService_WatchFlagClient, error) {
+	stream, err := c.stub.WatchFlag(c.authCtx(ctx), &flagspb.WatchFlagRequest{
+		Key:         key,
+		LastEventId: lastEventID,
+	})
+	if err != nil {
+		return nil, rpcError("WatchFlag", err)
+	}
+	return stream, nil
+}
+
+// recvEvents passes each event on stream to emit until the stream ends, or
+// emit returns false, and returns the error that ended it; a stream the
+// server closed cleanly, or ended with a RECONNECT event as it shut down,
+// returns io.EOF. connected, if not nil, is called once the server has
+// answered.
+func (c *Client) recvEvents(ctx context.Context, stream flagspb.FlagService_WatchFlagClient, connected func(), emit func(flagz.FlagEvent) bool) error {
+	// Header blocks until the server sends it, so it is read here rather
+	// than delaying Stream's return. A nil header means the server ended
+	// the stream without accepting it; Recv then returns the status.
+	if header, err := stream.Header(); err == nil && header != nil {
+		c.updateSDKConfig(header)
+		if connected != nil {
+			connected()
+		}
+	}
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if ev.GetType() == flagspb.WatchFlagEventType_RECONNECT {
+			return io.EOF
+		}
+		if !emit(eventFromProto(ev)) {
+			return ctx.Err()
+		}
+	}
+}
+
+func eventFromProto(ev *flagspb.WatchFlagEvent) flagz.FlagEvent {
+	fe := flagz.FlagEvent{EventID: ev.EventId, Key: ev.Key}
+	switch ev.Type {
+	case flagspb.WatchFlagEventType_FLAG_UPDATED:
+		fe.Type = "update"
+	case flagspb.WatchFlagEventType_FLAG_DELETED:
+		fe.Type = "delete"
+	default:
+		fe.Type = "unknown"
+	}
+	if ev.Flag != nil {
+		f, err := protoToFlag(ev.Flag)
+		if err == nil {
+			fe.Flag = &f
+		}
+	}
+	return fe
+}
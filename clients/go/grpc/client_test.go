@@ -3,17 +3,23 @@ package grpc_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
 	flagz "github.com/matt-riley/flagz/clients/go"
 	flagzgrpc "github.com/matt-riley/flagz/clients/go/grpc"
-	flagspb "github.com/matt-riley/flagz/api/proto/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -22,8 +28,18 @@ const bufSize = 1 << 20 // 1 MiB
 // testServer is a minimal in-process FlagService gRPC server.
 type testServer struct {
 	flagspb.UnimplementedFlagServiceServer
-	flags    map[string]*flagspb.Flag
+	flags      map[string]*flagspb.Flag
 	capturedMD metadata.MD
+	// sdkConfig, when set, is sent as flagz-sdk-config header metadata.
+	sdkConfig  string
+	batchSizes []int
+	// pageTokens records the page token of each ListFlags call.
+	pageTokens []string
+	// draining, when set, ends WatchFlag with a RECONNECT event, as a
+	// server that is shutting down does.
+	draining bool
+	// updateErr, when set, is returned by UpdateFlag.
+	updateErr error
 }
 
 func newTestServer() *testServer {
@@ -59,17 +75,41 @@ func (s *testServer) GetFlag(ctx context.Context, req *flagspb.GetFlagRequest) (
 	return &flagspb.GetFlagResponse{Flag: f}, nil
 }
 
-func (s *testServer) ListFlags(ctx context.Context, _ *flagspb.ListFlagsRequest) (*flagspb.ListFlagsResponse, error) {
+func (s *testServer) ListFlags(ctx context.Context, req *flagspb.ListFlagsRequest) (*flagspb.ListFlagsResponse, error) {
 	s.captureAuth(ctx)
-	flags := make([]*flagspb.Flag, 0, len(s.flags))
-	for _, f := range s.flags {
-		flags = append(flags, f)
+	s.pageTokens = append(s.pageTokens, req.PageToken)
+	if s.sdkConfig != "" {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("flagz-sdk-config", s.sdkConfig))
+	}
+	keys := slices.Sorted(maps.Keys(s.flags))
+	offset, _ := strconv.Atoi(req.PageToken)
+	keys = keys[min(offset, len(keys)):]
+	resp := &flagspb.ListFlagsResponse{}
+	if req.PageSize > 0 && len(keys) > int(req.PageSize) {
+		keys = keys[:req.PageSize]
+		resp.NextPageToken = strconv.Itoa(offset + len(keys))
+	}
+	for _, k := range keys {
+		resp.Flags = append(resp.Flags, s.flags[k])
+	}
+	return resp, nil
+}
+
+func (s *testServer) ListFlagsStream(req *flagspb.ListFlagsStreamRequest, stream flagspb.FlagService_ListFlagsStreamServer) error {
+	s.captureAuth(stream.Context())
+	for _, k := range slices.Sorted(maps.Keys(s.flags)) {
+		if err := stream.Send(s.flags[k]); err != nil {
+			return err
+		}
 	}
-	return &flagspb.ListFlagsResponse{Flags: flags}, nil
+	return nil
 }
 
 func (s *testServer) UpdateFlag(ctx context.Context, req *flagspb.UpdateFlagRequest) (*flagspb.UpdateFlagResponse, error) {
 	s.captureAuth(ctx)
+	if s.updateErr != nil {
+		return nil, s.updateErr
+	}
 	s.flags[req.Flag.Key] = req.Flag
 	return &flagspb.UpdateFlagResponse{Flag: req.Flag}, nil
 }
@@ -91,6 +131,7 @@ func (s *testServer) ResolveBoolean(ctx context.Context, req *flagspb.ResolveBoo
 
 func (s *testServer) ResolveBatch(ctx context.Context, req *flagspb.ResolveBatchRequest) (*flagspb.ResolveBatchResponse, error) {
 	s.captureAuth(ctx)
+	s.batchSizes = append(s.batchSizes, len(req.Requests))
 	results := make([]*flagspb.ResolveBatchResult, len(req.Requests))
 	for i, r := range req.Requests {
 		f, ok := s.flags[r.Key]
@@ -105,16 +146,32 @@ func (s *testServer) ResolveBatch(ctx context.Context, req *flagspb.ResolveBatch
 
 func (s *testServer) WatchFlag(req *flagspb.WatchFlagRequest, stream flagspb.FlagService_WatchFlagServer) error {
 	s.captureAuth(stream.Context())
-	// Emit two events then return.
+	if s.sdkConfig != "" {
+		if err := stream.SendHeader(metadata.Pairs("flagz-sdk-config", s.sdkConfig)); err != nil {
+			return err
+		}
+	}
+	// Emit the events the request asks for then return.
 	events := []*flagspb.WatchFlagEvent{
 		{Type: flagspb.WatchFlagEventType_FLAG_UPDATED, Key: "flag-a", EventId: 1, Flag: &flagspb.Flag{Key: "flag-a", Enabled: true}},
 		{Type: flagspb.WatchFlagEventType_FLAG_DELETED, Key: "flag-b", EventId: 2},
+		{Type: flagspb.WatchFlagEventType_FLAG_UPDATED, Key: "flag-a", EventId: 3, Flag: &flagspb.Flag{Key: "flag-a"}},
 	}
 	for _, ev := range events {
+		if ev.EventId <= req.LastEventId || (req.Key != "" && ev.Key != req.Key) {
+			continue
+		}
 		if err := stream.Send(ev); err != nil {
 			return err
 		}
 	}
+	if s.draining {
+		if err := stream.Send(&flagspb.WatchFlagEvent{Type: flagspb.WatchFlagEventType_RECONNECT, EventId: 3}); err != nil {
+			return err
+		}
+		// A client that stops at the RECONNECT event never sees this.
+		return stream.Send(events[0])
+	}
 	return nil
 }
 
@@ -199,6 +256,76 @@ func TestGRPCListFlags(t *testing.T) {
 	}
 }
 
+func TestGRPCListFlagsPage(t *testing.T) {
+	ts, c := startTestServer(t)
+	for _, k := range []string{"a", "b", "c"} {
+		ts.flags[k] = &flagspb.Flag{Key: k}
+	}
+
+	flags, next, err := c.ListFlagsPage(context.Background(), flagzgrpc.ListFlagsOptions{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 2 || flags[0].Key != "a" || flags[1].Key != "b" || next != "2" {
+		t.Fatalf("first page = %+v, next %q", flags, next)
+	}
+	flags, next, err = c.ListFlagsPage(context.Background(), flagzgrpc.ListFlagsOptions{PageSize: 2, PageToken: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 1 || flags[0].Key != "c" || next != "" {
+		t.Fatalf("last page = %+v, next %q", flags, next)
+	}
+}
+
+func TestGRPCListFlagsAll(t *testing.T) {
+	ts, c := startTestServer(t)
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		ts.flags[k] = &flagspb.Flag{Key: k}
+	}
+
+	flags, err := c.ListFlagsAll(context.Background(), flagzgrpc.ListFlagsOptions{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, f := range flags {
+		keys = append(keys, f.Key)
+	}
+	if !slices.Equal(keys, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("keys = %v, want all five in order", keys)
+	}
+	if !slices.Equal(ts.pageTokens, []string{"", "2", "4"}) {
+		t.Errorf("page tokens = %q, want one call per page", ts.pageTokens)
+	}
+}
+
+func TestGRPCListFlagsStream(t *testing.T) {
+	ts, c := startTestServer(t)
+	for _, k := range []string{"c", "a", "b"} {
+		ts.flags[k] = &flagspb.Flag{Key: k}
+	}
+
+	var keys []string
+	err := c.ListFlagsStream(context.Background(), func(f flagz.Flag) error {
+		keys = append(keys, f.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"a", "b", "c"}) {
+		t.Errorf("keys = %v, want all three in order", keys)
+	}
+	ts.assertAuth(t)
+
+	stop := errors.New("stop")
+	err = c.ListFlagsStream(context.Background(), func(flagz.Flag) error { return stop })
+	if err != stop {
+		t.Fatalf("ListFlagsStream() error = %v, want fn's error", err)
+	}
+}
+
 func TestGRPCUpdateFlag(t *testing.T) {
 	ts, c := startTestServer(t)
 	ts.flags["x"] = &flagspb.Flag{Key: "x", Enabled: true}
@@ -212,6 +339,36 @@ func TestGRPCUpdateFlag(t *testing.T) {
 	}
 }
 
+func TestGRPCErrors(t *testing.T) {
+	ts, c := startTestServer(t)
+
+	for _, tc := range []struct {
+		err  error
+		want error
+	}{
+		{status.Error(codes.NotFound, "flag not found"), flagz.ErrNotFound},
+		{status.Error(codes.InvalidArgument, "invalid rules: rule 0: unknown operator"), flagz.ErrInvalidRules},
+		{status.Error(codes.Unauthenticated, "unauthorized"), flagz.ErrUnauthorized},
+		{status.Error(codes.ResourceExhausted, "too many evaluations in progress"), flagz.ErrRateLimited},
+		{status.Error(codes.InvalidArgument, "invalid tags"), nil},
+	} {
+		ts.updateErr = tc.err
+		_, err := c.UpdateFlag(context.Background(), flagz.Flag{Key: "x"})
+		var apiErr *flagzgrpc.APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != status.Code(tc.err) {
+			t.Fatalf("UpdateFlag() error = %v, want an APIError with code %v", err, status.Code(tc.err))
+		}
+		if status.Code(err) != status.Code(tc.err) {
+			t.Errorf("status.Code(%v) = %v, want %v", err, status.Code(err), status.Code(tc.err))
+		}
+		for _, sentinel := range []error{flagz.ErrNotFound, flagz.ErrInvalidRules, flagz.ErrUnauthorized, flagz.ErrRateLimited} {
+			if got := errors.Is(err, sentinel); got != (sentinel == tc.want) {
+				t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+			}
+		}
+	}
+}
+
 func TestGRPCDeleteFlag(t *testing.T) {
 	ts, c := startTestServer(t)
 	ts.flags["x"] = &flagspb.Flag{Key: "x", Enabled: true}
@@ -289,8 +446,8 @@ func TestGRPCStream(t *testing.T) {
 		received = append(received, ev)
 	}
 
-	if len(received) != 2 {
-		t.Fatalf("want 2 events, got %d", len(received))
+	if len(received) != 3 {
+		t.Fatalf("want 3 events, got %d", len(received))
 	}
 	if received[0].Type != "update" || received[0].EventID != 1 {
 		t.Errorf("event 0: %+v", received[0])
@@ -301,6 +458,88 @@ func TestGRPCStream(t *testing.T) {
 	ts.assertAuth(t)
 }
 
+func TestGRPCStreamEndsAtReconnectEvent(t *testing.T) {
+	ts, c := startTestServer(t)
+	ts.draining = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := c.Stream(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []flagz.FlagEvent
+	for ev := range ch {
+		received = append(received, ev)
+	}
+	if len(received) != 1 || received[0].EventID != 3 {
+		t.Fatalf("events = %+v, want only event 3 and nothing after the RECONNECT", received)
+	}
+}
+
+func TestGRPCWatchFlag(t *testing.T) {
+	_, c := startTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := c.WatchFlag(ctx, "flag-a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []flagz.FlagEvent
+	for ev := range ch {
+		received = append(received, ev)
+	}
+	if len(received) != 1 || received[0].Key != "flag-a" || received[0].EventID != 3 {
+		t.Fatalf("events = %+v, want only flag-a's event after 1", received)
+	}
+}
+
+func TestGRPCSDKConfigFromListFlags(t *testing.T) {
+	ts, c := startTestServer(t)
+	ts.sdkConfig = `{"poll_interval_ms":5000,"max_batch_size":2}`
+
+	if _, err := c.ListFlags(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := flagz.SDKConfig{PollInterval: 5 * time.Second, MaxBatchSize: 2}
+	if got := c.SDKConfig(); got != want {
+		t.Fatalf("SDKConfig = %+v, want %+v", got, want)
+	}
+
+	reqs := make([]flagz.EvaluateRequest, 5)
+	for i := range reqs {
+		reqs[i].Key = fmt.Sprintf("flag-%d", i)
+	}
+	results, err := c.EvaluateBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ts.batchSizes) != "[2 2 1]" {
+		t.Errorf("batch sizes = %v, want [2 2 1]", ts.batchSizes)
+	}
+	if len(results) != 5 || results[4].Key != "flag-4" {
+		t.Errorf("results = %+v, want 5 in request order", results)
+	}
+}
+
+func TestGRPCSDKConfigFromStream(t *testing.T) {
+	ts, c := startTestServer(t)
+	ts.sdkConfig = `{"heartbeat_interval_ms":15000}`
+
+	ch, err := c.Stream(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if got := c.SDKConfig().HeartbeatInterval; got != 15*time.Second {
+		t.Errorf("HeartbeatInterval = %v, want 15s", got)
+	}
+}
+
 func TestGRPCStreamContextCancel(t *testing.T) {
 	// Override WatchFlag to hold open until context cancels.
 	lis := bufconn.Listen(bufSize)
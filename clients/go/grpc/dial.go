@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dialFunc opens a connection to addr, as grpc.WithContextDialer expects.
+type dialFunc func(ctx context.Context, addr string) (net.Conn, error)
+
+func dialTCP(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// proxyDialer returns a dialFunc that tunnels each connection through the
+// HTTP proxy at proxy with a CONNECT request, opening the connection to the
+// proxy with dial.
+func proxyDialer(proxy *url.URL, dial dialFunc) (dialFunc, error) {
+	if proxy.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q; only http is supported", proxy.Scheme)
+	}
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("dial proxy: %w", err)
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+			defer conn.SetDeadline(time.Time{})
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Host: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if user := proxy.User; user != nil {
+			password, _ := user.Password()
+			credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("send CONNECT to proxy: %w", err)
+		}
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("read CONNECT response from proxy: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+		}
+		if br.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+		return conn, nil
+	}, nil
+}
+
+// bufferedConn is a connection whose first bytes were already read into r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
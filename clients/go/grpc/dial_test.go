@@ -0,0 +1,157 @@
+package grpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagzgrpc "github.com/matt-riley/flagz/clients/go/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startTCPServer serves ts and the health service on a loopback port.
+func startTCPServer(t *testing.T) (string, *health.Server) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	flagspb.RegisterFlagServiceServer(gs, newTestServer())
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(gs, hs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String(), hs
+}
+
+// connectProxy is a minimal HTTP CONNECT proxy that records the targets and
+// Proxy-Authorization headers it is sent.
+type connectProxy struct {
+	mu      sync.Mutex
+	targets []string
+	auths   []string
+}
+
+func (p *connectProxy) start(t *testing.T) *url.URL {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return &url.URL{Scheme: "http", Host: lis.Addr().String()}
+}
+
+func (p *connectProxy) serve(client net.Conn) {
+	defer client.Close()
+	br := bufio.NewReader(client)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, req.Host)
+	p.auths = append(p.auths, req.Header.Get("Proxy-Authorization"))
+	p.mu.Unlock()
+
+	// The test's target is named "localhost"; the proxy resolves it.
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		_, _ = io.WriteString(client, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	_, _ = io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() { _, _ = io.Copy(upstream, br) }()
+	_, _ = io.Copy(client, upstream)
+}
+
+func TestGRPCPing(t *testing.T) {
+	addr, hs := startTCPServer(t)
+	c, err := flagzgrpc.NewGRPCClient(flagzgrpc.Config{Address: addr, APIKey: "test-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := c.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Fatalf("Ping() error = %v, want NOT_SERVING", err)
+	}
+}
+
+func TestGRPCProxy(t *testing.T) {
+	addr, _ := startTCPServer(t)
+	_, port, _ := net.SplitHostPort(addr)
+	proxy := &connectProxy{}
+	proxyURL := proxy.start(t)
+	proxyURL.User = url.UserPassword("alice", "s3cret")
+
+	var dials int
+	c, err := flagzgrpc.NewGRPCClient(flagzgrpc.Config{
+		Address: net.JoinHostPort("localhost", port),
+		APIKey:  "test-key",
+		Proxy:   proxyURL,
+		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
+			dials++
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if _, err := c.ListFlags(context.Background()); err != nil {
+		t.Fatalf("ListFlags() error = %v", err)
+	}
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if len(proxy.targets) != 1 || proxy.targets[0] != net.JoinHostPort("localhost", port) {
+		t.Fatalf("proxy targets = %v, want one tunnel to the unresolved address", proxy.targets)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret")); proxy.auths[0] != want {
+		t.Fatalf("Proxy-Authorization = %q, want %q", proxy.auths[0], want)
+	}
+	if dials != 1 {
+		t.Fatalf("dials = %d, want the custom dialer to reach the proxy once", dials)
+	}
+}
+
+func TestGRPCProxyRejectsUnsupportedScheme(t *testing.T) {
+	_, err := flagzgrpc.NewGRPCClient(flagzgrpc.Config{
+		Address: "localhost:9090",
+		Proxy:   &url.URL{Scheme: "socks5", Host: "proxy:1080"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "socks5") {
+		t.Fatalf("NewGRPCClient() error = %v, want an unsupported scheme error", err)
+	}
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	flagz "github.com/matt-riley/flagz/clients/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is returned when the server answers a call with an error status.
+// It wraps the flagz error matching the status, if any, so
+//
+//	errors.Is(err, flagz.ErrNotFound)
+//
+// reports a missing flag, and status.FromError and status.Code still see
+// the status itself.
+type APIError struct {
+	Code    codes.Code
+	Message string
+
+	status *status.Status
+}
+
+func (e *APIError) Error() string {
+	return e.status.Err().Error()
+}
+
+// GRPCStatus returns the status the server answered with.
+func (e *APIError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// Unwrap returns the flagz error matching the status, or nil.
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case codes.NotFound:
+		return flagz.ErrNotFound
+	case codes.InvalidArgument:
+		if strings.HasPrefix(e.Message, "invalid rules") {
+			return flagz.ErrInvalidRules
+		}
+	case codes.Unauthenticated:
+		return flagz.ErrUnauthorized
+	case codes.ResourceExhausted:
+		return flagz.ErrRateLimited
+	}
+	return nil
+}
+
+// rpcError reports err, returned by the call to method, as an [APIError]
+// if it is a status.
+func rpcError(method string, err error) error {
+	if st, ok := status.FromError(err); ok {
+		err = &APIError{Code: st.Code(), Message: st.Message(), status: st}
+	}
+	return fmt.Errorf("flagz: %s: %w", method, err)
+}
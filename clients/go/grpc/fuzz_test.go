@@ -6,8 +6,8 @@ import (
 	"encoding/json"
 	"testing"
 
-	flagz "github.com/matt-riley/flagz/clients/go"
 	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagz "github.com/matt-riley/flagz/clients/go"
 )
 
 // FuzzProtoToFlag ensures protoToFlag never panics on arbitrary JSON bytes
@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	flagz "github.com/matt-riley/flagz/clients/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConnState is the connection state of a stream opened with
+// [Client.StreamWithReconnect].
+type ConnState int
+
+const (
+	// StateConnecting means a WatchFlag call is being made.
+	StateConnecting ConnState = iota
+	// StateConnected means the server has accepted the stream.
+	StateConnected
+	// StateDisconnected means the stream dropped and will be reopened
+	// after a backoff.
+	StateDisconnected
+	// StateClosed means the stream will not be reopened, because ctx was
+	// cancelled or the server rejected it for good; the channel is closed.
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// ReconnectConfig configures [Client.StreamWithReconnect]. The zero value
+// is ready to use.
+type ReconnectConfig struct {
+	// MinBackoff is the delay before the first reconnect after a drop.
+	// It doubles on each failed attempt up to MaxBackoff and resets once a
+	// stream is connected. Defaults to 500ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Key, if set, limits the stream to events for that flag, as
+	// [Client.WatchFlag] does.
+	Key string
+	// OnStateChange, if set, is called on every state change with the
+	// error that caused it, if any. It is called from the goroutine that
+	// feeds the channel, so it should return promptly.
+	OnStateChange func(state ConnState, err error)
+}
+
+// StreamWithReconnect is like [Client.Stream], but reopens the stream with
+// exponential backoff and jitter whenever it drops, resuming after the last
+// event received. Events the server replays that were already delivered are
+// skipped, so each event ID is emitted at most once.
+//
+// The channel is closed when ctx is cancelled or when the server rejects
+// the stream with an error that retrying cannot fix (Unauthenticated,
+// PermissionDenied, InvalidArgument or Unimplemented).
+func (c *Client) StreamWithReconnect(ctx context.Context, lastEventID int64, cfg ReconnectConfig) <-chan flagz.FlagEvent {
+	minBackoff, maxBackoff := cfg.MinBackoff, cfg.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(defaultMaxBackoff, minBackoff)
+	}
+	notify := func(state ConnState, err error) {
+		if cfg.OnStateChange != nil {
+			cfg.OnStateChange(state, err)
+		}
+	}
+
+	ch := make(chan flagz.FlagEvent, 16)
+	go func() {
+		defer close(ch)
+		backoff := minBackoff
+		for {
+			notify(StateConnecting, nil)
+			err := c.watchOnce(ctx, cfg.Key, &lastEventID, ch, func() {
+				backoff = minBackoff
+				notify(StateConnected, nil)
+			})
+			if ctx.Err() != nil {
+				notify(StateClosed, ctx.Err())
+				return
+			}
+			if permanentStreamError(err) {
+				notify(StateClosed, err)
+				return
+			}
+			notify(StateDisconnected, err)
+
+			// Full jitter in the upper half of the window, so clients that
+			// dropped together do not reconnect together.
+			delay := backoff/2 + rand.N(backoff/2+1)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				notify(StateClosed, ctx.Err())
+				return
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}()
+	return ch
+}
+
+// watchOnce runs one WatchFlag stream for key from *lastEventID, forwarding
+// new events to ch and advancing *lastEventID, until the stream ends.
+func (c *Client) watchOnce(ctx context.Context, key string, lastEventID *int64, ch chan<- flagz.FlagEvent, connected func()) error {
+	stream, err := c.watchFlag(ctx, key, *lastEventID)
+	if err != nil {
+		return err
+	}
+	return c.recvEvents(ctx, stream, connected, func(fe flagz.FlagEvent) bool {
+		if fe.EventID > 0 && fe.EventID <= *lastEventID {
+			return true
+		}
+		select {
+		case ch <- fe:
+		case <-ctx.Done():
+			return false
+		}
+		if fe.EventID > 0 {
+			*lastEventID = fe.EventID
+		}
+		return true
+	})
+}
+
+// permanentStreamError reports whether err means reconnecting would only
+// fail the same way.
+func permanentStreamError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,222 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagzgrpc "github.com/matt-riley/flagz/clients/go/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// flakyWatchServer replays every event from the start on each WatchFlag
+// call, ignoring the requested LastEventId, and drops the stream after
+// dropAfter[i] events on call i. Calls past the end of dropAfter send all
+// events and then hold the stream open.
+type flakyWatchServer struct {
+	*testServer
+	events    []*flagspb.WatchFlagEvent
+	dropAfter []int
+	err       error
+
+	mu          sync.Mutex
+	lastEventID []int64
+	keys        []string
+}
+
+func (f *flakyWatchServer) WatchFlag(req *flagspb.WatchFlagRequest, stream flagspb.FlagService_WatchFlagServer) error {
+	f.mu.Lock()
+	call := len(f.lastEventID)
+	f.lastEventID = append(f.lastEventID, req.LastEventId)
+	f.keys = append(f.keys, req.Key)
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	limit := len(f.events)
+	if call < len(f.dropAfter) {
+		limit = f.dropAfter[call]
+	}
+	for _, ev := range f.events[:limit] {
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+	}
+	if call < len(f.dropAfter) {
+		return status.Error(codes.Unavailable, "dropped")
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func startFlakyServer(t *testing.T, srv *flakyWatchServer) *flagzgrpc.Client {
+	t.Helper()
+	srv.testServer = newTestServer()
+	lis := bufconn.Listen(bufSize)
+	gs := grpc.NewServer()
+	flagspb.RegisterFlagServiceServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(func() { gs.Stop(); lis.Close() })
+
+	c, err := flagzgrpc.NewGRPCClient(flagzgrpc.Config{
+		Address: "passthrough:///bufnet",
+		APIKey:  "k",
+		DialOpts: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []flagzgrpc.ConnState
+	errs   []error
+}
+
+func (r *stateRecorder) record(state flagzgrpc.ConnState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+	r.errs = append(r.errs, err)
+}
+
+func (r *stateRecorder) snapshot() ([]flagzgrpc.ConnState, []error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.states), slices.Clone(r.errs)
+}
+
+func TestGRPCStreamWithReconnectResumesAndDeduplicates(t *testing.T) {
+	srv := &flakyWatchServer{
+		events: []*flagspb.WatchFlagEvent{
+			{Type: flagspb.WatchFlagEventType_FLAG_UPDATED, Key: "a", EventId: 1},
+			{Type: flagspb.WatchFlagEventType_FLAG_UPDATED, Key: "b", EventId: 2},
+			{Type: flagspb.WatchFlagEventType_FLAG_DELETED, Key: "a", EventId: 3},
+		},
+		dropAfter: []int{2, 1},
+	}
+	c := startFlakyServer(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var rec stateRecorder
+	ch := c.StreamWithReconnect(ctx, 0, flagzgrpc.ReconnectConfig{
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    5 * time.Millisecond,
+		OnStateChange: rec.record,
+	})
+
+	var ids []int64
+	for len(ids) < 3 {
+		select {
+		case ev := <-ch:
+			ids = append(ids, ev.EventID)
+		case <-ctx.Done():
+			t.Fatalf("timed out after events %v", ids)
+		}
+	}
+	if !slices.Equal(ids, []int64{1, 2, 3}) {
+		t.Fatalf("event IDs = %v, want [1 2 3]", ids)
+	}
+
+	srv.mu.Lock()
+	resumedFrom := slices.Clone(srv.lastEventID)
+	srv.mu.Unlock()
+	if !slices.Equal(resumedFrom, []int64{0, 2, 2}) {
+		t.Errorf("LastEventId per call = %v, want [0 2 2]", resumedFrom)
+	}
+
+	cancel()
+	for range ch {
+	}
+	states, errs := rec.snapshot()
+	want := []flagzgrpc.ConnState{
+		flagzgrpc.StateConnecting, flagzgrpc.StateConnected, flagzgrpc.StateDisconnected,
+		flagzgrpc.StateConnecting, flagzgrpc.StateConnected, flagzgrpc.StateDisconnected,
+		flagzgrpc.StateConnecting, flagzgrpc.StateConnected, flagzgrpc.StateClosed,
+	}
+	if !slices.Equal(states, want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	if status.Code(errs[2]) != codes.Unavailable {
+		t.Errorf("disconnect error = %v, want Unavailable", errs[2])
+	}
+	if !errors.Is(errs[len(errs)-1], context.Canceled) {
+		t.Errorf("close error = %v, want context.Canceled", errs[len(errs)-1])
+	}
+}
+
+func TestGRPCStreamWithReconnectKeepsKey(t *testing.T) {
+	srv := &flakyWatchServer{
+		events:    []*flagspb.WatchFlagEvent{{Type: flagspb.WatchFlagEventType_FLAG_UPDATED, Key: "a", EventId: 1}},
+		dropAfter: []int{0},
+	}
+	c := startFlakyServer(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch := c.StreamWithReconnect(ctx, 0, flagzgrpc.ReconnectConfig{MinBackoff: time.Millisecond, Key: "a"})
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for an event")
+	}
+	cancel()
+	for range ch {
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !slices.Equal(srv.keys, []string{"a", "a"}) {
+		t.Errorf("Key per call = %q, want the key on every reconnect", srv.keys)
+	}
+}
+
+func TestGRPCStreamWithReconnectStopsOnPermanentError(t *testing.T) {
+	srv := &flakyWatchServer{err: status.Error(codes.Unauthenticated, "bad key")}
+	c := startFlakyServer(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var rec stateRecorder
+	ch := c.StreamWithReconnect(ctx, 0, flagzgrpc.ReconnectConfig{
+		MinBackoff:    time.Millisecond,
+		OnStateChange: rec.record,
+	})
+
+	for range ch {
+		t.Fatal("no events expected")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("channel should close without waiting for ctx")
+	}
+	states, errs := rec.snapshot()
+	if !slices.Equal(states, []flagzgrpc.ConnState{flagzgrpc.StateConnecting, flagzgrpc.StateClosed}) {
+		t.Fatalf("states = %v, want [connecting closed]", states)
+	}
+	if status.Code(errs[1]) != codes.Unauthenticated {
+		t.Errorf("close error = %v, want Unauthenticated", errs[1])
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	flagz "github.com/matt-riley/flagz/clients/go"
@@ -24,21 +28,106 @@ type Config struct {
 	APIKey string
 	// HTTPClient is optional; defaults to http.DefaultClient.
 	HTTPClient *http.Client
+	// Retry is optional; the zero value sends each request once.
+	Retry RetryPolicy
+
+	// Proxy, DialContext and TLSConfig configure the transport of the
+	// client NewHTTPClient builds when HTTPClient is nil. They are ignored
+	// when HTTPClient is set; configure its transport instead.
+	//
+	// Proxy chooses the proxy for each request, overriding the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables; use http.ProxyURL
+	// for a fixed proxy. A nil result sends the request directly.
+	Proxy func(*http.Request) (*url.URL, error)
+	// DialContext opens the client's network connections, to the proxy if
+	// there is one.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig is used for https:// base URLs, for example to trust a
+	// private CA or present a client certificate.
+	TLSConfig *tls.Config
 }
 
+// sdkConfigHeader carries the server's SDK configuration on flag snapshot
+// and stream responses.
+const sdkConfigHeader = "Flagz-SDK-Config"
+
 // Client implements flagz.FlagManager, flagz.Evaluator, and flagz.Streamer over HTTP.
 type Client struct {
 	cfg        Config
 	httpClient *http.Client
+
+	sdkMu  sync.RWMutex
+	sdkCfg flagz.SDKConfig
+
+	lastSeenEventID atomic.Int64
 }
 
 // NewHTTPClient returns a new HTTP client for the flagz service.
 func NewHTTPClient(cfg Config) *Client {
 	hc := cfg.HTTPClient
-	if hc == nil {
+	switch {
+	case hc != nil:
+	case cfg.Proxy != nil || cfg.DialContext != nil || cfg.TLSConfig != nil:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.Proxy != nil {
+			transport.Proxy = cfg.Proxy
+		}
+		if cfg.DialContext != nil {
+			transport.DialContext = cfg.DialContext
+		}
+		if cfg.TLSConfig != nil {
+			transport.TLSClientConfig = cfg.TLSConfig
+		}
+		hc = &http.Client{Transport: transport}
+	default:
 		hc = http.DefaultClient
 	}
-	return &Client{cfg: cfg, httpClient: hc}
+	return &Client{cfg: cfg, httpClient: hc, sdkCfg: flagz.DefaultSDKConfig()}
+}
+
+// Ping checks that the server can be reached by requesting /healthz once,
+// without retries, so an application can fail fast at startup when the
+// network, proxy or TLS configuration is broken. It returns an [*APIError]
+// if the server answers with an error status.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.roundTrip(ctx, http.MethodGet, "/healthz", nil, "")
+	if err != nil {
+		return fmt.Errorf("flagz: http: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp)
+	}
+	return nil
+}
+
+// SDKConfig returns the SDK configuration last sent by the server, or
+// flagz.DefaultSDKConfig if it has not sent one yet.
+func (c *Client) SDKConfig() flagz.SDKConfig {
+	c.sdkMu.RLock()
+	defer c.sdkMu.RUnlock()
+	return c.sdkCfg
+}
+
+// LastSeenEventID returns the ID of the latest event a stream opened by this
+// client has seen, either delivered on its channel or reported by a server
+// heartbeat, or zero if there has been none. Heartbeats are not delivered to
+// the channel, but they keep this current while the stream is idle, so it is
+// the position to resume from after a reconnect, and comparing it with
+// [flagz.FlagEvent.EventID] of the last event processed shows how far a
+// consumer lags behind the stream.
+func (c *Client) LastSeenEventID() int64 {
+	return c.lastSeenEventID.Load()
+}
+
+// updateSDKConfig adopts the SDK configuration carried by resp, if any.
+func (c *Client) updateSDKConfig(resp *http.Response) flagz.SDKConfig {
+	c.sdkMu.Lock()
+	defer c.sdkMu.Unlock()
+	if cfg, ok := flagz.ParseSDKConfig(resp.Header.Get(sdkConfigHeader), c.sdkCfg); ok {
+		c.sdkCfg = cfg
+	}
+	return c.sdkCfg
 }
 
 // -- wire types --------------------------------------------------------------
@@ -51,6 +140,16 @@ type wireFlag struct {
 	Rules       json.RawMessage `json:"rules"`
 	CreatedAt   string          `json:"created_at"`
 	UpdatedAt   string          `json:"updated_at"`
+	// BucketingSalt is set by the server and ignored on writes.
+	BucketingSalt string `json:"bucketing_salt,omitempty"`
+	// Targets is set by the server and ignored on writes.
+	Targets *wireTargets `json:"targets,omitempty"`
+}
+
+type wireTargets struct {
+	Attribute string   `json:"attribute"`
+	Allow     []string `json:"allow"`
+	Deny      []string `json:"deny"`
 }
 
 type wireRule struct {
@@ -60,9 +159,9 @@ type wireRule struct {
 }
 
 type wireEvaluateReq struct {
-	Key          string          `json:"key,omitempty"`
-	Context      json.RawMessage `json:"context,omitempty"`
-	DefaultValue bool            `json:"default_value"`
+	Key          string            `json:"key,omitempty"`
+	Context      json.RawMessage   `json:"context,omitempty"`
+	DefaultValue bool              `json:"default_value"`
 	Requests     []wireEvalReqItem `json:"requests,omitempty"`
 }
 
@@ -76,57 +175,104 @@ type wireEvaluateResp struct {
 	Key     string `json:"key"`
 	Value   bool   `json:"value"`
 	Results []struct {
-		Key   string `json:"key"`
-		Value bool   `json:"value"`
+		Key       string `json:"key"`
+		Value     bool   `json:"value"`
+		Reason    string `json:"reason"`
+		RuleIndex *int   `json:"rule_index"`
+		Variant   string `json:"variant"`
 	} `json:"results"`
 }
 
 // -- helpers -----------------------------------------------------------------
 
 func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
-	var bodyReader io.Reader
+	return c.send(ctx, method, path, body, "")
+}
+
+// send makes a request, retrying it under the client's RetryPolicy if it is
+// safe to repeat. A non-empty idempotencyKey is sent as the Idempotency-Key
+// header, which makes any request safe to repeat.
+func (c *Client) send(ctx context.Context, method, path string, body any, idempotencyKey string) (*http.Response, error) {
+	var payload []byte
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("flagz: marshal request: %w", err)
 		}
-		bodyReader = bytes.NewReader(b)
+		payload = b
+	}
+
+	attempts := 1
+	if retry := c.cfg.Retry; retry.MaxAttempts > 1 && (idempotencyKey != "" || repeatable(method, path)) {
+		attempts = retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.roundTrip(ctx, method, path, payload, idempotencyKey)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		delay := c.cfg.Retry.backoff(attempt - 1)
+		if err != nil {
+			err = fmt.Errorf("flagz: http: %w", err)
+		} else {
+			if d, ok := retryAfter(resp.Header, time.Now()); ok {
+				delay = d
+			}
+			apiErr := newAPIError(resp)
+			resp.Body.Close()
+			if !retryableStatus(resp.StatusCode) {
+				return nil, apiErr
+			}
+			err = apiErr
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			return nil, err
+		}
+		if sleep(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, path string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	var bodyReader io.Reader
+	if payload != nil {
+		bodyReader = bytes.NewReader(payload)
 	}
 	req, err := http.NewRequestWithContext(ctx, method, c.cfg.BaseURL+path, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("flagz: create request: %w", err)
+		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
-	if body != nil {
+	if payload != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("flagz: http: %w", err)
-	}
-	if resp.StatusCode >= 400 {
-		defer resp.Body.Close()
-		msg, _ := io.ReadAll(resp.Body)
-		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	if idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
 	}
-	return resp, nil
-}
-
-// APIError is returned when the server responds with an HTTP error status.
-type APIError struct {
-	StatusCode int
-	Message    string
+	return c.httpClient.Do(req)
 }
 
-func (e *APIError) Error() string {
-	return fmt.Sprintf("flagz: HTTP %d: %s", e.StatusCode, e.Message)
+// repeatable reports whether sending the request twice has the same effect
+// as sending it once. Evaluations are POSTs but only read.
+func repeatable(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return method == http.MethodPost && path == "/v1/evaluate"
 }
 
 func decodeFlag(wf wireFlag) (flagz.Flag, error) {
 	f := flagz.Flag{
-		Key:         wf.Key,
-		Description: wf.Description,
-		Enabled:     wf.Enabled,
+		Key:           wf.Key,
+		Description:   wf.Description,
+		Enabled:       wf.Enabled,
+		BucketingSalt: wf.BucketingSalt,
+	}
+	if wf.Targets != nil {
+		f.Targets = flagz.Targets{Attribute: wf.Targets.Attribute, Allow: wf.Targets.Allow, Deny: wf.Targets.Deny}
 	}
 	if wf.CreatedAt != "" {
 		t, err := time.Parse(time.RFC3339, wf.CreatedAt)
@@ -192,7 +338,11 @@ func (c *Client) CreateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, e
 	if err != nil {
 		return flagz.Flag{}, err
 	}
-	resp, err := c.do(ctx, http.MethodPost, "/v1/flags", map[string]any{"flag": wf})
+	var idempotencyKey string
+	if c.cfg.Retry.IdempotencyKeys {
+		idempotencyKey = newIdempotencyKey()
+	}
+	resp, err := c.send(ctx, http.MethodPost, "/v1/flags", map[string]any{"flag": wf}, idempotencyKey)
 	if err != nil {
 		return flagz.Flag{}, err
 	}
@@ -227,6 +377,7 @@ func (c *Client) ListFlags(ctx context.Context) ([]flagz.Flag, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.updateSDKConfig(resp)
 	var out struct {
 		Flags []wireFlag `json:"flags"`
 	}
@@ -244,6 +395,35 @@ func (c *Client) ListFlags(ctx context.Context) ([]flagz.Flag, error) {
 	return flags, nil
 }
 
+// ListFlagsStream calls fn with every flag, sorted by key, as the server
+// writes them one per line, so a very large project is exported without
+// building or parsing one large response. It stops at the first error fn
+// returns.
+func (c *Client) ListFlagsStream(ctx context.Context, fn func(flagz.Flag) error) error {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/flags?format=ndjson", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	c.updateSDKConfig(resp)
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var wf wireFlag
+		if err := dec.Decode(&wf); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("flagz: decode response: %w", err)
+		}
+		f, err := decodeFlag(wf)
+		if err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+}
+
 func (c *Client) UpdateFlag(ctx context.Context, flag flagz.Flag) (flagz.Flag, error) {
 	wf, err := encodeFlag(flag)
 	if err != nil {
@@ -299,7 +479,25 @@ func (c *Client) Evaluate(ctx context.Context, key string, evalCtx flagz.Evaluat
 	return out.Results[0].Value, nil
 }
 
+// EvaluateBatch evaluates reqs, splitting them into requests of at most the
+// server's MaxBatchSize. Results are returned in request order.
 func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest) ([]flagz.EvaluateResult, error) {
+	size := c.SDKConfig().MaxBatchSize
+	if size <= 0 || len(reqs) <= size {
+		return c.evaluateBatch(ctx, reqs)
+	}
+	results := make([]flagz.EvaluateResult, 0, len(reqs))
+	for start := 0; start < len(reqs); start += size {
+		chunk, err := c.evaluateBatch(ctx, reqs[start:min(start+size, len(reqs))])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+func (c *Client) evaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest) ([]flagz.EvaluateResult, error) {
 	items := make([]wireEvalReqItem, len(reqs))
 	for i, r := range reqs {
 		ctxJSON, err := json.Marshal(r.Context)
@@ -320,7 +518,13 @@ func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest
 	}
 	results := make([]flagz.EvaluateResult, len(out.Results))
 	for i, r := range out.Results {
-		results[i] = flagz.EvaluateResult{Key: r.Key, Value: r.Value}
+		results[i] = flagz.EvaluateResult{
+			Key:       r.Key,
+			Value:     r.Value,
+			Reason:    r.Reason,
+			RuleIndex: r.RuleIndex,
+			Variant:   r.Variant,
+		}
 	}
 	return results, nil
 }
@@ -328,7 +532,13 @@ func (c *Client) EvaluateBatch(ctx context.Context, reqs []flagz.EvaluateRequest
 // -- Streamer ----------------------------------------------------------------
 
 // Stream connects to the SSE stream and emits FlagEvents on the returned channel.
-// The channel is closed when ctx is cancelled or the connection drops.
+// The channel is closed when ctx is cancelled or the connection drops. When the
+// server advertises a heartbeat interval, a stream that stays silent for twice
+// that long is treated as dropped; keepalive comments count as traffic.
+// Heartbeat events are not emitted; they update [Client.LastSeenEventID]. Nor
+// is the reconnect event that ends the stream when the server shuts down; it
+// updates [Client.LastSeenEventID] too, so resume from that after the channel
+// closes.
 func (c *Client) Stream(ctx context.Context, lastEventID int64) (<-chan flagz.FlagEvent, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/v1/stream", nil)
 	if err != nil {
@@ -345,8 +555,12 @@ func (c *Client) Stream(ctx context.Context, lastEventID int64) (<-chan flagz.Fl
 	}
 	if resp.StatusCode >= 400 {
 		defer resp.Body.Close()
-		msg, _ := io.ReadAll(resp.Body)
-		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+		return nil, newAPIError(resp)
+	}
+
+	var body io.Reader = resp.Body
+	if heartbeat := c.updateSDKConfig(resp).HeartbeatInterval; heartbeat > 0 {
+		body = newIdleTimeoutReader(resp.Body, 2*heartbeat)
 	}
 
 	ch := make(chan flagz.FlagEvent, 16)
@@ -354,16 +568,53 @@ func (c *Client) Stream(ctx context.Context, lastEventID int64) (<-chan flagz.Fl
 		defer close(ch)
 		defer resp.Body.Close()
 		// Use a buffered reader with a 1 MiB buffer to handle large SSE data lines.
-		br := bufio.NewReaderSize(resp.Body, 1<<20)
-		parseSSE(ctx, br, ch)
+		br := bufio.NewReaderSize(body, 1<<20)
+		parseSSE(ctx, br, ch, c.lastSeenEventID.Store)
 	}()
 	return ch, nil
 }
 
+// idleTimeoutReader closes the underlying body when no data arrives within
+// timeout, which unblocks the pending Read with an error.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func newIdleTimeoutReader(rc io.ReadCloser, timeout time.Duration) *idleTimeoutReader {
+	return &idleTimeoutReader{
+		r:       rc,
+		timer:   time.AfterFunc(timeout, func() { rc.Close() }),
+		timeout: timeout,
+	}
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	if err != nil {
+		r.timer.Stop()
+	}
+	return n, err
+}
+
+// wireHeartbeat is the payload of the server's "heartbeat" SSE event, and of
+// the "reconnect" event that ends a stream when the server shuts down.
+type wireHeartbeat struct {
+	EventID int64 `json:"event_id"`
+}
+
 // parseSSE reads SSE lines from r and sends parsed FlagEvents to ch.
 // It implements the subset of the SSE spec used by the flagz server:
 // id, event, data fields; blank-line flush; multi-line data concatenation.
-func parseSSE(ctx context.Context, r *bufio.Reader, ch chan<- flagz.FlagEvent) {
+// Comment lines, such as the server's keepalives, and the retry field are
+// skipped without disturbing an event being read. Heartbeat and reconnect
+// events are not sent to ch. seen, if not nil, is called with the ID of each
+// event sent and the event ID each heartbeat or reconnect event reports.
+func parseSSE(ctx context.Context, r *bufio.Reader, ch chan<- flagz.FlagEvent, seen func(int64)) {
 	var (
 		eventType string
 		dataLines []string
@@ -379,14 +630,21 @@ func parseSSE(ctx context.Context, r *bufio.Reader, ch chan<- flagz.FlagEvent) {
 
 		if line == "" {
 			// Blank line: dispatch event if we have data.
-			if len(dataLines) > 0 {
+			if len(dataLines) > 0 && (eventType == "heartbeat" || eventType == "reconnect") {
+				var hb wireHeartbeat
+				if jsonErr := json.Unmarshal([]byte(strings.Join(dataLines, "\n")), &hb); jsonErr == nil && seen != nil {
+					seen(hb.EventID)
+				}
+			} else if len(dataLines) > 0 {
 				data := strings.Join(dataLines, "\n")
 				ev := flagz.FlagEvent{Type: eventType, EventID: eventID}
 				if eventType == "update" || eventType == "delete" {
-					var f flagz.Flag
-					if jsonErr := json.Unmarshal([]byte(data), &f); jsonErr == nil {
-						ev.Flag = &f
-						ev.Key = f.Key
+					var wf wireFlag
+					if jsonErr := json.Unmarshal([]byte(data), &wf); jsonErr == nil {
+						if f, decodeErr := decodeFlag(wf); decodeErr == nil {
+							ev.Flag = &f
+							ev.Key = f.Key
+						}
 					}
 				}
 				select {
@@ -394,10 +652,15 @@ func parseSSE(ctx context.Context, r *bufio.Reader, ch chan<- flagz.FlagEvent) {
 				case <-ctx.Done():
 					return
 				}
+				if seen != nil {
+					seen(eventID)
+				}
 			}
 			// Reset for next event.
 			eventType = ""
 			dataLines = nil
+		} else if strings.HasPrefix(line, ":") || strings.HasPrefix(line, "retry:") {
+			// Keepalive or reconnect hint: nothing to emit.
 		} else if strings.HasPrefix(line, "id:") {
 			fmt.Sscanf(strings.TrimSpace(strings.TrimPrefix(line, "id:")), "%d", &eventID)
 		} else if strings.HasPrefix(line, "event:") {
@@ -2,10 +2,15 @@ package http_test
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -110,6 +115,78 @@ func TestGetFlagUnauthorized(t *testing.T) {
 	}
 }
 
+func TestAPIErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		retryAfter  string
+		want        error
+		wantType    string
+		wantMessage string
+	}{
+		{
+			name: "not found", status: http.StatusNotFound, contentType: "application/problem+json",
+			body: `{"type":"urn:flagz:problem:flag-not-found","title":"Not Found","status":404,"detail":"flag not found"}`,
+			want: flagz.ErrNotFound, wantType: "flag-not-found", wantMessage: "flag not found",
+		},
+		{
+			name: "invalid rules", status: http.StatusBadRequest, contentType: "application/problem+json",
+			body: `{"type":"urn:flagz:problem:invalid-rules","title":"Bad Request","status":400,"detail":"invalid rules: rule 0: unknown operator"}`,
+			want: flagz.ErrInvalidRules, wantType: "invalid-rules", wantMessage: "invalid rules: rule 0: unknown operator",
+		},
+		{
+			name: "legacy invalid rules", status: http.StatusBadRequest, contentType: "application/json",
+			body: `{"error":"invalid rules: rule 0: unknown operator"}`,
+			want: flagz.ErrInvalidRules, wantMessage: "invalid rules: rule 0: unknown operator",
+		},
+		{
+			name: "unauthorized", status: http.StatusUnauthorized, contentType: "text/plain",
+			body: "unauthorized",
+			want: flagz.ErrUnauthorized, wantMessage: "unauthorized",
+		},
+		{
+			name: "rate limited", status: http.StatusTooManyRequests, contentType: "application/problem+json", retryAfter: "7",
+			body: `{"type":"urn:flagz:problem:rate-limited","title":"Too Many Requests","status":429}`,
+			want: flagz.ErrRateLimited, wantType: "rate-limited", wantMessage: "Too Many Requests",
+		},
+		{
+			name: "invalid tags", status: http.StatusBadRequest, contentType: "application/problem+json",
+			body:     `{"type":"urn:flagz:problem:invalid-tags","title":"Bad Request","status":400,"detail":"invalid tags"}`,
+			wantType: "invalid-tags", wantMessage: "invalid tags",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+			_, err := c.GetFlag(context.Background(), "x")
+
+			var apiErr *flagzhttp.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.status {
+				t.Fatalf("GetFlag() error = %v, want an APIError with status %d", err, tc.status)
+			}
+			if apiErr.Type != tc.wantType || apiErr.Message != tc.wantMessage {
+				t.Errorf("APIError type, message = %q, %q, want %q, %q", apiErr.Type, apiErr.Message, tc.wantType, tc.wantMessage)
+			}
+			for _, sentinel := range []error{flagz.ErrNotFound, flagz.ErrInvalidRules, flagz.ErrUnauthorized, flagz.ErrRateLimited} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tc.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+				}
+			}
+			if tc.retryAfter != "" && apiErr.RetryAfter != 7*time.Second {
+				t.Errorf("RetryAfter = %v, want 7s", apiErr.RetryAfter)
+			}
+		})
+	}
+}
+
 func TestListFlags(t *testing.T) {
 	// Use a simpler server for list
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +204,38 @@ func TestListFlags(t *testing.T) {
 	}
 }
 
+func TestListFlagsStream(t *testing.T) {
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assertAuth(t, r)
+		if r.URL.Path != "/v1/flags" || r.URL.Query().Get("format") != "ndjson" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"key\":\"a\",\"enabled\":true}\n{\"key\":\"b\",\"enabled\":false}\n")
+	})
+	var keys []string
+	err := c.ListFlagsStream(context.Background(), func(f flagz.Flag) error {
+		keys = append(keys, f.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("keys = %v, want [a b]", keys)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = c.ListFlagsStream(context.Background(), func(flagz.Flag) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("ListFlagsStream() = %v after %d calls, want fn's error after the first flag", err, calls)
+	}
+}
+
 func TestUpdateFlag(t *testing.T) {
 	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		assertAuth(t, r)
@@ -197,7 +306,7 @@ func TestEvaluateBatch(t *testing.T) {
 			t.Errorf("expected 2 requests, got %v", body["requests"])
 		}
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, `{"results":[{"key":"a","value":true},{"key":"b","value":false}]}`)
+		fmt.Fprint(w, `{"results":[{"key":"a","value":true,"reason":"RULE_MATCH","rule_index":1},{"key":"b","value":false,"reason":"DEFAULT","variant":"default"}]}`)
 	})
 	results, err := c.EvaluateBatch(context.Background(), []flagz.EvaluateRequest{
 		{Key: "a", DefaultValue: false},
@@ -209,13 +318,19 @@ func TestEvaluateBatch(t *testing.T) {
 	if len(results) != 2 || results[0].Key != "a" || !results[0].Value {
 		t.Errorf("unexpected results: %+v", results)
 	}
+	if results[0].Reason != "RULE_MATCH" || results[0].RuleIndex == nil || *results[0].RuleIndex != 1 {
+		t.Errorf("results[0] reason = %q rule_index = %v, want RULE_MATCH at 1", results[0].Reason, results[0].RuleIndex)
+	}
+	if results[1].Reason != "DEFAULT" || results[1].Variant != "default" || results[1].RuleIndex != nil {
+		t.Errorf("results[1] = %+v, want DEFAULT from variant default", results[1])
+	}
 }
 
 // -- SSE streaming tests -----------------------------------------------------
 
 func TestStream(t *testing.T) {
 	events := []string{
-		"id:1\nevent:update\ndata:{\"key\":\"flag-a\",\"enabled\":true}\n\n",
+		"id:1\nevent:update\ndata:{\"key\":\"flag-a\",\"enabled\":true,\"bucketing_salt\":\"s1\",\"targets\":{\"attribute\":\"user_id\",\"allow\":[\"alice\"],\"deny\":[]}}\n\n",
 		"id:2\nevent:delete\ndata:{\"key\":\"flag-b\"}\n\n",
 	}
 
@@ -254,11 +369,106 @@ func TestStream(t *testing.T) {
 	if received[0].Type != "update" || received[0].EventID != 1 {
 		t.Errorf("event 0: %+v", received[0])
 	}
+	if f := received[0].Flag; f == nil || f.Key != "flag-a" || !f.Enabled || f.BucketingSalt != "s1" || f.Targets.Attribute != "user_id" || len(f.Targets.Allow) != 1 {
+		t.Errorf("event 0 flag: %+v", f)
+	}
 	if received[1].Type != "delete" || received[1].EventID != 2 {
 		t.Errorf("event 1: %+v", received[1])
 	}
 }
 
+func TestStreamSkipsKeepalivesAndRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 2500\n\n")
+		fmt.Fprint(w, ": keepalive\n\n")
+		fmt.Fprint(w, "id: 7\nevent: update\n: keepalive\nretry: 1000\ndata: {\"key\":\"flag-a\",\"enabled\":true}\n\n")
+		fmt.Fprint(w, ": keepalive\r\n\r\n")
+	}))
+	defer srv.Close()
+
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{BaseURL: srv.URL, APIKey: "test-key"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := c.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []flagz.FlagEvent
+	for ev := range ch {
+		received = append(received, ev)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("want 1 event, got %d: %+v", len(received), received)
+	}
+	if ev := received[0]; ev.Type != "update" || ev.EventID != 7 || ev.Flag == nil || ev.Flag.Key != "flag-a" {
+		t.Errorf("event: %+v", ev)
+	}
+}
+
+func TestStreamHeartbeatsUpdateLastSeenEventID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 7\nevent: update\ndata: {\"key\":\"flag-a\",\"enabled\":true}\n\n")
+		fmt.Fprint(w, "event: heartbeat\ndata: {\"event_id\":9,\"server_time\":\"2024-05-01T10:00:00Z\"}\n\n")
+	}))
+	defer srv.Close()
+
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{BaseURL: srv.URL, APIKey: "test-key"})
+	if got := c.LastSeenEventID(); got != 0 {
+		t.Fatalf("LastSeenEventID before streaming = %d, want 0", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := c.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []flagz.FlagEvent
+	for ev := range ch {
+		received = append(received, ev)
+	}
+
+	if len(received) != 1 || received[0].EventID != 7 {
+		t.Fatalf("want only event 7, got %+v", received)
+	}
+	if got := c.LastSeenEventID(); got != 9 {
+		t.Errorf("LastSeenEventID = %d, want 9 from the heartbeat", got)
+	}
+}
+
+func TestStreamReconnectEventUpdatesLastSeenEventID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 7\nevent: update\ndata: {\"key\":\"flag-a\",\"enabled\":true}\n\n")
+		fmt.Fprint(w, "event: reconnect\ndata: {\"event_id\":8}\n\n")
+	}))
+	defer srv.Close()
+
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{BaseURL: srv.URL, APIKey: "test-key"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ch, err := c.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []flagz.FlagEvent
+	for ev := range ch {
+		received = append(received, ev)
+	}
+
+	if len(received) != 1 || received[0].EventID != 7 {
+		t.Fatalf("want only event 7, got %+v", received)
+	}
+	if got := c.LastSeenEventID(); got != 8 {
+		t.Errorf("LastSeenEventID = %d, want 8 from the reconnect event", got)
+	}
+}
+
 func TestStreamLastEventIDHeader(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		got := r.Header.Get("Last-Event-ID")
@@ -318,6 +528,117 @@ func TestStreamContextCancellation(t *testing.T) {
 	}
 }
 
+// -- SDK configuration tests -------------------------------------------------
+
+func TestSDKConfigFromListFlags(t *testing.T) {
+	var batchSizes []int
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/flags":
+			w.Header().Set("Flagz-SDK-Config", `{"poll_interval_ms":5000,"max_batch_size":2}`)
+			fmt.Fprint(w, `{"flags":[]}`)
+		case "/v1/evaluate":
+			var body struct {
+				Requests []struct {
+					Key string `json:"key"`
+				} `json:"requests"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			batchSizes = append(batchSizes, len(body.Requests))
+			results := make([]map[string]any, len(body.Requests))
+			for i, req := range body.Requests {
+				results[i] = map[string]any{"key": req.Key, "value": true}
+			}
+			w.Write(mustMarshal(map[string]any{"results": results}))
+		}
+	})
+
+	if got := c.SDKConfig(); got != flagz.DefaultSDKConfig() {
+		t.Fatalf("initial SDKConfig = %+v, want defaults", got)
+	}
+	if _, err := c.ListFlags(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := flagz.SDKConfig{PollInterval: 5 * time.Second, MaxBatchSize: 2}
+	if got := c.SDKConfig(); got != want {
+		t.Fatalf("SDKConfig = %+v, want %+v", got, want)
+	}
+
+	reqs := make([]flagz.EvaluateRequest, 5)
+	for i := range reqs {
+		reqs[i].Key = fmt.Sprintf("flag-%d", i)
+	}
+	results, err := c.EvaluateBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(batchSizes) != "[2 2 1]" {
+		t.Errorf("batch sizes = %v, want [2 2 1]", batchSizes)
+	}
+	if len(results) != 5 || results[4].Key != "flag-4" {
+		t.Errorf("results = %+v, want 5 in request order", results)
+	}
+}
+
+func TestStreamClosesAfterMissedHeartbeats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Flagz-SDK-Config", `{"heartbeat_interval_ms":20}`)
+		w.(http.Flusher).Flush()
+		// Go silent: no events and no heartbeats.
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{BaseURL: srv.URL, APIKey: "k"})
+	ch, err := c.Stream(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.SDKConfig().HeartbeatInterval; got != 20*time.Millisecond {
+		t.Errorf("HeartbeatInterval = %v, want 20ms", got)
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("unexpected event on silent stream")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream was not closed after missed heartbeats")
+	}
+}
+
+func TestPollUsesServerInterval(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Flagz-SDK-Config", `{"poll_interval_ms":10}`)
+		fmt.Fprint(w, `{"flags":[]}`)
+		calls <- struct{}{}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	polls := 0
+	err := flagz.Poll(ctx, c, func(_ []flagz.Flag, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		if polls++; polls == 3 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("Poll() error = %v, want context.Canceled", err)
+	}
+	if len(calls) != 3 {
+		t.Errorf("server saw %d polls, want 3", len(calls))
+	}
+}
+
 // -- helpers -----------------------------------------------------------------
 
 func isAPIError(err error, target **flagzhttp.APIError) bool {
@@ -338,3 +659,82 @@ var _ flagz.Streamer = (*flagzhttp.Client)(nil)
 
 // Ensure types are usable.
 var _ = strings.TrimSpace
+
+func TestPing(t *testing.T) {
+	healthy := true
+	srv, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("path = %q, want /healthz", r.URL.Path)
+		}
+		if !healthy {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	defer srv.Close()
+
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	healthy = false
+	var apiErr *flagzhttp.APIError
+	if err := c.Ping(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Ping() error = %v, want a 503 APIError", err)
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	var dials int
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{
+		BaseURL:   srv.URL,
+		APIKey:    "k",
+		TLSConfig: &tls.Config{RootCAs: roots},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials++
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	})
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if dials != 1 {
+		t.Fatalf("dials = %d, want the custom dialer used once", dials)
+	}
+
+	// Without the server's CA, the default transport refuses the certificate.
+	if err := flagzhttp.NewHTTPClient(flagzhttp.Config{BaseURL: srv.URL}).Ping(context.Background()); err == nil {
+		t.Fatal("Ping() without TLSConfig succeeded, want a certificate error")
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the request.
+		proxied = r.URL.String()
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	c := flagzhttp.NewHTTPClient(flagzhttp.Config{
+		BaseURL: "http://flagz.internal:8080",
+		APIKey:  "k",
+		Proxy:   http.ProxyURL(proxyURL),
+	})
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if proxied != "http://flagz.internal:8080/healthz" {
+		t.Fatalf("proxy received %q, want the absolute /healthz URL", proxied)
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	flagz "github.com/matt-riley/flagz/clients/go"
+)
+
+// problemTypePrefix starts the type URI of every problem the server returns.
+const problemTypePrefix = "urn:flagz:problem:"
+
+// APIError is returned when the server responds with an HTTP error status.
+// It wraps the flagz error matching the response, if any, so
+//
+//	errors.Is(err, flagz.ErrNotFound)
+//
+// reports a missing flag.
+type APIError struct {
+	StatusCode int
+	// Message is the server's explanation: the problem's detail, or the
+	// response body if it is not a problem.
+	Message string
+	// Type names the kind of problem, such as "flag-not-found" or
+	// "invalid-rules". It is empty if the response is not a problem.
+	Type string
+	// RetryAfter is how long the server asked the client to wait before
+	// trying again, or zero if it did not say.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("flagz: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the flagz error matching the response, or nil.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Type == "invalid-rules":
+		return flagz.ErrInvalidRules
+	case e.Type == "" && e.StatusCode == http.StatusBadRequest && strings.HasPrefix(e.Message, "invalid rules"):
+		// The legacy error format names no type.
+		return flagz.ErrInvalidRules
+	case e.StatusCode == http.StatusNotFound:
+		return flagz.ErrNotFound
+	case e.StatusCode == http.StatusUnauthorized:
+		return flagz.ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests, e.Type == "overloaded":
+		return flagz.ErrRateLimited
+	}
+	return nil
+}
+
+// problem is the part of an RFC 7807 problem, or of the legacy
+// {"error": "..."} body, that APIError reports.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	Error  string `json:"error"`
+}
+
+// newAPIError reads resp's error body into an APIError. It does not close
+// the body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	if d, ok := retryAfter(resp.Header, time.Now()); ok {
+		apiErr.RetryAfter = d
+	}
+
+	var p problem
+	if json.Unmarshal(body, &p) != nil {
+		return apiErr
+	}
+	switch {
+	case strings.HasPrefix(p.Type, problemTypePrefix):
+		apiErr.Type = strings.TrimPrefix(p.Type, problemTypePrefix)
+		if p.Detail != "" {
+			apiErr.Message = p.Detail
+		} else if p.Title != "" {
+			apiErr.Message = p.Title
+		}
+	case p.Error != "":
+		apiErr.Message = p.Error
+	}
+	return apiErr
+}
@@ -23,7 +23,7 @@ func runParseSSE(b []byte) []flagz.FlagEvent {
 	go func() {
 		defer close(ch)
 		br := bufio.NewReaderSize(bytes.NewReader(b), 1<<20)
-		parseSSE(ctx, br, ch)
+		parseSSE(ctx, br, ch, nil)
 	}()
 	var evs []flagz.FlagEvent
 	for e := range ch {
@@ -39,6 +39,7 @@ func FuzzParseSSE(f *testing.F) {
 	f.Add([]byte("id:2\nevent:delete\ndata:{\"key\":\"x\"}\n\n"))
 	f.Add([]byte("event:update\ndata:first\ndata:second\n\n"))
 	f.Add([]byte(":comment\ndata:hello\n\n"))
+	f.Add([]byte("event:heartbeat\ndata:{\"event_id\":5,\"server_time\":\"2024-01-01T00:00:00Z\"}\n\n"))
 	f.Add([]byte("\n\n"))
 	f.Add([]byte(""))
 	f.Add([]byte("id:9999999999\nevent:update\ndata:{}\n\n"))
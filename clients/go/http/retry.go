@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader carries the key that lets the server recognise a
+// retried create and answer it with the original result.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	defaultRetryMinBackoff = 200 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy configures automatic retries of failed requests. The zero
+// value disables them.
+//
+// Only requests that are safe to repeat are retried: GET, PUT and DELETE,
+// evaluations, and CreateFlag when IdempotencyKeys is set. They are retried
+// after network errors and 429, 500, 502, 503 and 504 responses, but not
+// once ctx is done. Streams are never retried; see the README for
+// reconnecting them.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, counting the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles for each
+	// further retry, up to MaxBackoff, and each delay is jittered. A
+	// Retry-After header on the response overrides it. Defaults to 200ms
+	// and 5s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// IdempotencyKeys sends a random Idempotency-Key with each CreateFlag
+	// call, the same on every attempt, so a server that supports them
+	// creates the flag once however often the request is retried.
+	IdempotencyKeys bool
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	minBackoff, maxBackoff := p.MinBackoff, p.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultRetryMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(defaultRetryMaxBackoff, minBackoff)
+	}
+	d := minBackoff
+	for range retry {
+		d = min(d*2, maxBackoff)
+	}
+	// Jitter over the upper half, so clients that failed together do not
+	// retry together.
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryableStatus reports whether a response with code may succeed if the
+// request is sent again.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns false if the header is missing or unusable.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error.
+	_, _ = cryptorand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	flagz "github.com/matt-riley/flagz/clients/go"
+	flagzhttp "github.com/matt-riley/flagz/clients/go/http"
+)
+
+func newRetryClient(t *testing.T, policy flagzhttp.RetryPolicy, handler http.HandlerFunc) *flagzhttp.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return flagzhttp.NewHTTPClient(flagzhttp.Config{
+		BaseURL: srv.URL,
+		APIKey:  "test-key",
+		Retry:   policy,
+	})
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, flagJSON("my-flag", true))
+	})
+
+	if _, err := c.GetFlag(context.Background(), "my-flag"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("calls = %d, want 3", got)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "boom", http.StatusBadGateway)
+	})
+
+	_, err := c.GetFlag(context.Background(), "my-flag")
+	var apiErr *flagzhttp.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want HTTP 502", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls = %d, want 2", got)
+	}
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	if _, err := c.GetFlag(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var first time.Time
+	var waited time.Duration
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		waited = time.Since(first)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := c.DeleteFlag(context.Background(), "my-flag"); err != nil {
+		t.Fatal(err)
+	}
+	if waited < time.Second {
+		t.Fatalf("retried after %v, want at least the 1s Retry-After", waited)
+	}
+}
+
+func TestRetryCreateFlagOnlyWithIdempotencyKey(t *testing.T) {
+	var calls atomic.Int32
+	var keys []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(flagzhttp.IdempotencyKeyHeader))
+		if calls.Add(1)%2 == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, flagJSON("my-flag", true))
+	}
+
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}, handler)
+	if _, err := c.CreateFlag(context.Background(), flagz.Flag{Key: "my-flag"}); err == nil {
+		t.Fatal("CreateFlag without idempotency keys should not be retried")
+	}
+	if len(keys) != 1 || keys[0] != "" {
+		t.Fatalf("Idempotency-Key headers = %q, want one empty", keys)
+	}
+
+	keys = nil
+	calls.Store(0)
+	c = newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, IdempotencyKeys: true}, handler)
+	if _, err := c.CreateFlag(context.Background(), flagz.Flag{Key: "my-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("Idempotency-Key headers = %q, want the same key on both attempts", keys)
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	var calls atomic.Int32
+	c := newRetryClient(t, flagzhttp.RetryPolicy{MaxAttempts: 5, MinBackoff: time.Hour}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.ListFlags(ctx); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+}
@@ -0,0 +1,124 @@
+// Package webhook verifies the signed deliveries flagz sends to webhook
+// notification channels.
+//
+// Each delivery carries a [SignatureHeader] of the form
+//
+//	t=<unix seconds>,v1=<signature>[,v1=<signature>…]
+//
+// where each signature is the hex HMAC-SHA256, keyed with one of the
+// project's signing secrets, of the timestamp, a "." and the request body.
+// While a rotated secret is still valid there is a signature for it as well
+// as for its replacement. [Verify] accepts a delivery signed with the given
+// secret whose timestamp is within a tolerance of the local clock, so that
+// a captured delivery cannot be replayed later.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header that carries a delivery's signatures.
+const SignatureHeader = "Flagz-Signature"
+
+// DefaultTolerance is how far a delivery's timestamp may be from the local
+// clock, in either direction, by default.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when the signature header is missing or
+	// malformed, or none of its signatures match the secret.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrTimestampOutOfTolerance is returned when a delivery was signed too
+	// long ago, or too far in the future, to be accepted.
+	ErrTimestampOutOfTolerance = errors.New("webhook: timestamp outside tolerance")
+)
+
+// Event is the body of a delivery.
+type Event struct {
+	// Type is "flag.updated" or "flag.deleted".
+	Type      string    `json:"type"`
+	EventID   int64     `json:"event_id"`
+	ProjectID string    `json:"project_id"`
+	FlagKey   string    `json:"flag_key"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Flag is the flag as it was after the change, as returned by the
+	// flagz API.
+	Flag json.RawMessage `json:"flag"`
+}
+
+// Verify checks that header, the value of a delivery's [SignatureHeader],
+// signs body with secret and was created within tolerance of now. A
+// tolerance of zero or less means [DefaultTolerance].
+func Verify(body []byte, header, secret string, tolerance time.Duration) error {
+	return verify(body, header, secret, tolerance, time.Now())
+}
+
+func verify(body []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	matched := false
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			matched = true
+		}
+	}
+	if !matched {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signed %s ago", ErrTimestampOutOfTolerance, age.Round(time.Second))
+	}
+	return nil
+}
+
+// VerifyRequest reads the body of r and verifies it as [Verify] does,
+// returning the body if it is genuine.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: read body: %w", err)
+	}
+	if err := Verify(body, r.Header.Get(SignatureHeader), secret, tolerance); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
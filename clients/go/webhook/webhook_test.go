@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sign builds a signature header the way the flagz server does.
+func sign(body []byte, t time.Time, secrets ...string) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	header := "t=" + timestamp
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		header += ",v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"flag.updated","flag_key":"checkout"}`)
+	now := time.Unix(1760520600, 0)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header string
+		want   error
+	}{
+		{"valid", body, sign(body, now, "whsec_new"), nil},
+		{"rotated secret", body, sign(body, now, "whsec_other", "whsec_new"), nil},
+		{"slightly old", body, sign(body, now.Add(-4*time.Minute), "whsec_new"), nil},
+		{"wrong secret", body, sign(body, now, "whsec_other"), ErrInvalidSignature},
+		{"tampered body", []byte(`{"type":"flag.deleted","flag_key":"checkout"}`), sign(body, now, "whsec_new"), ErrInvalidSignature},
+		{"replayed", body, sign(body, now.Add(-6*time.Minute), "whsec_new"), ErrTimestampOutOfTolerance},
+		{"future", body, sign(body, now.Add(6*time.Minute), "whsec_new"), ErrTimestampOutOfTolerance},
+		{"missing header", body, "", ErrInvalidSignature},
+		{"no signature", body, "t=1760520600", ErrInvalidSignature},
+		{"no timestamp", body, strings.TrimPrefix(sign(body, now, "whsec_new"), "t=1760520600,"), ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.body, tt.header, "whsec_new", 0, now); !errors.Is(err, tt.want) {
+				t.Errorf("verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"type":"flag.updated"}`
+	req := httptest.NewRequest("POST", "/hooks/flagz", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, sign([]byte(body), time.Now(), "whsec_new"))
+
+	got, err := VerifyRequest(req, "whsec_new", DefaultTolerance)
+	if err != nil {
+		t.Fatalf("VerifyRequest() error = %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("VerifyRequest() body = %q, want %q", got, body)
+	}
+
+	req = httptest.NewRequest("POST", "/hooks/flagz", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, sign([]byte(body), time.Now(), "whsec_old"))
+	if _, err := VerifyRequest(req, "whsec_new", DefaultTolerance); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyRequest() with the wrong secret error = %v, want ErrInvalidSignature", err)
+	}
+}
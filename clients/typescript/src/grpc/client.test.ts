@@ -86,6 +86,8 @@ async function startTestServer() {
       receivedMeta.push(call.metadata)
       call.write({ type: 'FLAG_UPDATED', key: 'flag-a', event_id: '1', flag: { key: 'flag-a', enabled: true, variants_json: Buffer.alloc(0), rules_json: Buffer.alloc(0) } })
       call.write({ type: 'FLAG_DELETED', key: 'flag-b', event_id: '2' })
+      call.write({ type: 'RECONNECT', event_id: '2' })
+      call.write({ type: 'FLAG_DELETED', key: 'flag-c', event_id: '3' })
       call.end()
     },
   }
@@ -190,7 +192,7 @@ describe('createGRPCClient', () => {
 
   // -- Streamer tests --------------------------------------------------------
 
-  it('stream yields update and delete events', async () => {
+  it('stream yields update and delete events and ends on reconnect', async () => {
     await getServer()
     const client = await createGRPCClient({ address: `127.0.0.1:${TEST_PORT}`, apiKey: 'k' })
     const events = []
@@ -176,6 +176,9 @@ export async function createGRPCClient(config: GRPCConfig): Promise<FlagClient>
     }>
 
     for await (const ev of grpcStream) {
+      // The server is draining: it ends the stream after this event, and the
+      // caller reconnects from the last eventId it saw.
+      if (ev.type === 'RECONNECT') return
       const fe: FlagEvent = {
         type: ev.type === 'FLAG_UPDATED' ? 'update' : ev.type === 'FLAG_DELETED' ? 'delete' : 'error',
         key: ev.key,
@@ -208,6 +208,26 @@ describe('createHTTPClient', () => {
       expect(events[1]).toMatchObject({ type: 'delete', key: 'flag-b', eventId: '2' })
     })
 
+    it('skips heartbeats and ends the stream on a reconnect event', async () => {
+      const sseData = [
+        'id:1\nevent:update\ndata:{"key":"flag-a","enabled":true}\n\n',
+        'event:heartbeat\ndata:{"event_id":1,"server_time":"2026-01-01T00:00:00Z"}\n\n',
+        'event:reconnect\ndata:{"event_id":1}\n\n',
+        'id:2\nevent:delete\ndata:{"key":"flag-b"}\n\n',
+      ].join('')
+
+      const fetch = vi.fn().mockResolvedValue(makeSSEResponse(sseData))
+      const client = createHTTPClient({ ...baseConfig, fetch })
+
+      const events = []
+      for await (const ev of client.stream()) {
+        events.push(ev)
+      }
+
+      expect(events).toHaveLength(1)
+      expect(events[0]).toMatchObject({ type: 'update', key: 'flag-a', eventId: '1' })
+    })
+
     it('sends Last-Event-ID header on reconnect', async () => {
       const fetch = vi.fn().mockResolvedValue(makeSSEResponse(''))
       const client = createHTTPClient({ ...baseConfig, fetch })
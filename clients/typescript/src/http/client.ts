@@ -167,7 +167,12 @@ export function createHTTPClient(config: HTTPConfig): FlagClient {
           const line = rawLine.replace(/\r$/, '')
           if (line === '') {
             // Blank line: dispatch if we have data
-            if (dataLines.length > 0) {
+            if (eventType === 'reconnect') {
+              // The server is draining: it closes the stream after this event,
+              // and the caller reconnects from the last eventId it saw.
+              return
+            }
+            if (dataLines.length > 0 && eventType !== 'heartbeat') {
               const data = dataLines.join('\n')
               const ev: FlagEvent = {
                 type: (eventType as FlagEvent['type']) || 'update',
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/matt-riley/flagz/internal/backup"
+	"github.com/matt-riley/flagz/internal/config"
+	"github.com/matt-riley/flagz/internal/logging"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	backupUsage  = "usage: server backup <file>"
+	restoreUsage = "usage: server restore [-rename] <file>"
+)
+
+// openBackupRepository connects to DATABASE_URL for the backup and restore
+// subcommands, decrypting and encrypting variants with VARIANTS_KEY_FILE
+// when it is set. The caller must close the returned pool.
+func openBackupRepository(ctx context.Context) (*repository.PostgresRepository, *pgxpool.Pool, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	slog.SetDefault(logging.New(cfg.LogLevel))
+	if cfg.DatabaseDriver == config.DatabaseDriverSQLite {
+		return nil, nil, errors.New("backups are not supported with SQLite")
+	}
+
+	var opts []repository.RepoOption
+	if cfg.VariantsKeyFile != "" {
+		keys, err := repository.LoadLocalKeyFile(cfg.VariantsKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load VARIANTS_KEY_FILE: %w", err)
+		}
+		opts = append(opts, repository.WithVariantsEncryption(keys))
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect postgres: %w", err)
+	}
+	return repository.NewPostgresRepository(pool, opts...), pool, nil
+}
+
+// runBackupCommand implements the "backup <file>" subcommand, which writes
+// a backup archive of DATABASE_URL to file and exits.
+func runBackupCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New(backupUsage)
+	}
+
+	ctx := context.Background()
+	repo, pool, err := openBackupRepository(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+	summary, err := backup.Write(ctx, file, repo)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(args[0])
+		return err
+	}
+
+	slog.Info("backup written", "file", args[0],
+		"projects", summary[repository.BackupKindProject],
+		"api_keys", summary[repository.BackupKindAPIKey],
+		"segments", summary[repository.BackupKindSegment],
+		"flags", summary[repository.BackupKindFlag],
+		"audit_entries", summary[repository.BackupKindAudit],
+	)
+	return nil
+}
+
+// runRestoreCommand implements the "restore [-rename] <file>" subcommand,
+// which restores a backup archive into DATABASE_URL and exits. Running
+// servers pick up the restored flags at their next cache resync.
+func runRestoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	rename := flags.Bool("rename", false, "restore projects whose names are taken under new names")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errors.New(restoreUsage)
+	}
+	path := flags.Arg(0)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup file: %w", err)
+	}
+	defer file.Close()
+	reader, err := backup.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	repo, pool, err := openBackupRepository(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	result, err := repo.Restore(ctx, repository.RestoreOptions{RenameConflicts: *rename}, reader.Next)
+	if err != nil {
+		return err
+	}
+	for oldID, newID := range result.Projects {
+		slog.Info("project restored", "backup_id", oldID, "id", newID)
+	}
+	slog.Info("backup restored", "file", path,
+		"projects", len(result.Projects),
+		"api_keys", len(result.APIKeys),
+		"segments", result.Segments,
+		"flags", result.Flags,
+		"audit_entries", result.AuditEntries,
+	)
+	return nil
+}
@@ -1,17 +1,41 @@
 // Package main is the entry point for the flagz server.
 //
 // The bootstrap sequence is:
-//  1. Load configuration from environment variables.
-//  2. Connect to PostgreSQL via pgxpool.
-//  3. Create the repository and service (eagerly loading the flag cache).
-//  4. Wire up the API key token validator.
-//  5. Start the HTTP server (:8080) and gRPC server (:9090) concurrently.
-//  6. Wait for SIGINT/SIGTERM, then gracefully shut down both servers.
+//  1. Load configuration from the optional FLAGZ_CONFIG file and environment
+//     variables.
+//  2. Connect to PostgreSQL via pgxpool and, unless RUN_MIGRATIONS=false,
+//     apply pending migrations under an advisory lock.
+//  3. Create the repository and service (eagerly loading the flag cache),
+//     routing reads to DATABASE_READ_URL, or hedging cache-miss reads across
+//     DATABASE_REPLICA_URL, when either is set.
+//  4. Wire up the API key token validator and, when KUBERNETES_SYNC is set,
+//     start syncing flags from Kubernetes resources. When EXPORT_S3_BUCKET is
+//     set, start the scheduled Parquet export. Start the flag expiry
+//     reminder, which posts to FLAG_EXPIRY_WEBHOOK_URL when it is set.
+//  5. Start the HTTP server (:8080) and gRPC server (:9090, with server
+//     reflection) concurrently, over TLS when TLS_CERT_FILE is set.
+//  6. Wait for SIGINT/SIGTERM, then gracefully shut down both servers. On
+//     SIGHUP, reload the log level, stream poll interval, rate limits and
+//     CORS policy from configuration without a restart.
+//
+// When DATABASE_URL names a SQLite database, step 2 opens it and brings its
+// schema up to date instead, and step 3 skips the replica; the flag cache is
+// then refreshed only by this server's own writes and the periodic resync.
+//
+// When UPSTREAM_URL is set, steps 2-4 are replaced by proxy mode: flags are
+// mirrored in memory from the upstream server, tokens are checked against
+// it, and only read and evaluation endpoints are served.
+//
+// "server migrate up" and "server migrate down" apply or roll back
+// migrations and exit without starting the servers. "server backup <file>"
+// and "server restore [-rename] <file>" likewise write or restore a backup
+// archive of DATABASE_URL and exit.
 package main
 
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
@@ -19,34 +43,89 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"github.com/jackc/pgx/v5/pgxpool"
 	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagzhttp "github.com/matt-riley/flagz/clients/go/http"
 	"github.com/matt-riley/flagz/internal/admin"
 	"github.com/matt-riley/flagz/internal/config"
+	"github.com/matt-riley/flagz/internal/export"
+	"github.com/matt-riley/flagz/internal/instance"
+	"github.com/matt-riley/flagz/internal/kubesync"
 	"github.com/matt-riley/flagz/internal/logging"
 	"github.com/matt-riley/flagz/internal/metrics"
 	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/notify"
+	"github.com/matt-riley/flagz/internal/oidc"
+	"github.com/matt-riley/flagz/internal/proxy"
 	"github.com/matt-riley/flagz/internal/repository"
 	"github.com/matt-riley/flagz/internal/server"
 	"github.com/matt-riley/flagz/internal/service"
+	"github.com/matt-riley/flagz/internal/tlsreload"
 	"github.com/matt-riley/flagz/internal/tracing"
+	"github.com/matt-riley/flagz/internal/webhook"
+	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"tailscale.com/tsnet"
 )
 
 const (
-	shutdownTimeout       = 10 * time.Second
-	httpReadHeaderTimeout = 5 * time.Second
-	httpReadTimeout       = 30 * time.Second
-	httpIdleTimeout       = 2 * time.Minute
+	shutdownTimeout          = 10 * time.Second
+	httpReadHeaderTimeout    = 5 * time.Second
+	httpReadTimeout          = 30 * time.Second
+	httpIdleTimeout          = 2 * time.Minute
+	projectPurgeInterval     = time.Hour
+	idempotencyPurgeInterval = time.Hour
+	keyRotationInterval      = 5 * time.Minute
+	flagExpiryInterval       = 5 * time.Minute
+	proxyBootstrapTimeout    = 30 * time.Second
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "load and validate configuration, then exit")
+	flag.Parse()
+
+	if *validateConfig {
+		if _, err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("config OK")
+		return
+	}
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrateCommand(args[1:]); err != nil {
+			slog.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "backup" {
+		if err := runBackupCommand(args[1:]); err != nil {
+			slog.Error("backup failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "restore" {
+		if err := runRestoreCommand(args[1:]); err != nil {
+			slog.Error("restore failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		slog.Error("server failed", "error", err)
 		os.Exit(1)
@@ -54,16 +133,28 @@ func main() {
 }
 
 func run() error {
-	// ... (no change needed here, just context)
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
-	log := logging.New(cfg.LogLevel)
+	identity := instance.New(cfg.Region)
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(logging.ParseLevel(cfg.LogLevel))
+	log, shutdownLogExport, err := logging.Init(context.Background(), logLevel, identity)
+	if err != nil {
+		return fmt.Errorf("init logging: %w", err)
+	}
 	slog.SetDefault(log)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownLogExport(ctx); err != nil {
+			log.Error("log export shutdown error", "err", err)
+		}
+	}()
 
-	shutdownTracer, err := tracing.Init(context.Background())
+	shutdownTracer, err := tracing.Init(context.Background(), identity)
 	if err != nil {
 		return fmt.Errorf("init tracing: %w", err)
 	}
@@ -78,35 +169,288 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
-	if err != nil {
-		return fmt.Errorf("connect postgres: %w", err)
+	m := metrics.New(metrics.WithFlagLabelLimit(cfg.MetricsFlagLabelLimit), metrics.WithInstance(identity))
+	svcOpts := []service.Option{
+		service.WithLogger(log),
+		service.WithCacheMetrics(m.IncCacheLoads, m.IncCacheInvalidations, m.ResetCacheSize, m.SetCacheSize),
+		service.WithCacheReloadMetrics(m.ObserveCacheReload, m.IncCacheReloadFailures, m.SetCacheSizeDelta),
+		service.WithEventMetrics(m.IncEventsPublished),
+		service.WithContextWarningMetrics(m.IncContextWarnings),
+		service.WithShadowEvaluationMetrics(m.RecordShadowEvaluation),
+		service.WithEvaluationCache(cfg.EvaluationCacheTTL, cfg.EvaluationCacheSize),
+		service.WithEvaluationCacheMetrics(m.RecordEvaluationCacheLookup),
+		service.WithCacheResyncInterval(cfg.CacheResyncInterval),
+		service.WithEventPollInterval(cfg.StreamPollInterval),
+		service.WithWarmupTimeout(cfg.WarmupTimeout),
+		service.WithProjectRetention(cfg.ProjectRetention),
+		service.WithIdempotencyTTL(cfg.IdempotencyKeyTTL),
+		service.WithStatsFlushInterval(cfg.StatsFlushInterval),
+		service.WithStaleThresholds(service.StaleThresholds{
+			NotEvaluatedFor: cfg.StaleFlagNotEvaluatedFor,
+			NotModifiedFor:  cfg.StaleFlagNotModifiedFor,
+		}),
 	}
-	defer pool.Close()
 
-	if err := runMigrations(pool); err != nil {
-		return fmt.Errorf("migrate: %w", err)
+	// Notifications only go out where the repository stores channels.
+	var smtpConfig *notify.SMTPConfig
+	if cfg.SMTPAddr != "" {
+		smtpConfig = &notify.SMTPConfig{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword}
 	}
+	svcOpts = append(svcOpts, service.WithNotificationSender(notify.NewRouter(nil, smtpConfig)))
 
-	repo := repository.NewPostgresRepository(pool, repository.WithEventBatchSize(cfg.EventBatchSize))
-	m := metrics.New()
-	metrics.RegisterPoolMetrics(m.Registry, pool)
-	svc, err := service.New(ctx, repo,
-		service.WithLogger(log),
-		service.WithCacheMetrics(m.IncCacheLoads, m.IncCacheInvalidations, m.ResetCacheSize, m.SetCacheSize),
-		service.WithCacheResyncInterval(cfg.CacheResyncInterval),
+	var expiryWebhook *webhook.Sender
+	if cfg.FlagExpiryWebhookURL != "" {
+		expiryWebhook, err = webhook.NewSender(cfg.FlagExpiryWebhookURL, nil)
+		if err != nil {
+			return fmt.Errorf("flag expiry webhook: %w", err)
+		}
+	}
+
+	var (
+		svc            *service.Service
+		repo           *repository.PostgresRepository
+		tokenValidator middleware.TokenValidator
 	)
-	if err != nil {
-		return fmt.Errorf("init service: %w", err)
+	if cfg.UpstreamURL != "" {
+		svc, tokenValidator, err = newProxyService(ctx, cfg, log, svcOpts)
+		if err != nil {
+			return err
+		}
+	} else if cfg.DatabaseDriver == config.DatabaseDriverSQLite {
+		sqliteRepo, err := repository.OpenSQLite(ctx, cfg.DatabaseURL, cfg.EventBatchSize)
+		if err != nil {
+			return err
+		}
+		defer sqliteRepo.Close()
+
+		svc, err = service.New(ctx, sqliteRepo, svcOpts...)
+		if err != nil {
+			return fmt.Errorf("init service: %w", err)
+		}
+		go remindExpiredFlags(ctx, svc, expiryWebhook, log)
+		go purgeIdempotencyKeys(ctx, svc, log)
+
+		if cfg.KubernetesSync {
+			if err := startKubernetesSync(ctx, cfg, svc, log); err != nil {
+				return err
+			}
+		}
+		tokenValidator = &apiKeyTokenValidator{lookup: sqliteRepo}
+		log.Info("using sqlite storage; changes made outside this server are picked up by the cache resync", "interval", cfg.CacheResyncInterval)
+	} else {
+		if cfg.RunMigrations {
+			// Migrations get a pool of their own, without the statement
+			// timeout, since rewriting a large table can take a while.
+			migrationPool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+			if err != nil {
+				return fmt.Errorf("connect postgres: %w", err)
+			}
+			err = runMigrations(ctx, migrationPool)
+			migrationPool.Close()
+			if err != nil {
+				return fmt.Errorf("migrate: %w", err)
+			}
+		}
+
+		poolConfig := repository.PoolConfig{
+			MaxConns:          cfg.DatabaseMaxConns,
+			MinConns:          cfg.DatabaseMinConns,
+			MaxConnLifetime:   cfg.DatabaseMaxConnLifetime,
+			MaxConnIdleTime:   cfg.DatabaseMaxConnIdleTime,
+			HealthCheckPeriod: cfg.DatabaseHealthCheckPeriod,
+			StatementTimeout:  cfg.DatabaseStatementTimeout,
+		}
+		pool, err := repository.NewPool(ctx, cfg.DatabaseURL, poolConfig)
+		if err != nil {
+			return fmt.Errorf("connect postgres: %w", err)
+		}
+		defer pool.Close()
+
+		repoOpts := []repository.RepoOption{
+			repository.WithEventBatchSize(cfg.EventBatchSize),
+			repository.WithListenReconnectHook(m.IncListenReconnects),
+		}
+		if cfg.DatabaseReadURL != "" {
+			readPool, err := repository.NewPool(ctx, cfg.DatabaseReadURL, poolConfig)
+			if err != nil {
+				return fmt.Errorf("connect postgres read replica: %w", err)
+			}
+			defer readPool.Close()
+			repoOpts = append(repoOpts,
+				repository.WithReadPool(readPool),
+				repository.WithReadFallbackHook(m.IncReplicaReadFallbacks),
+			)
+			log.Info("routing flag and event reads to the read replica")
+		}
+		// Both the primary and a hedging replica must decrypt variants.
+		var encryptionOpts []repository.RepoOption
+		if cfg.VariantsKeyFile != "" {
+			keys, err := repository.LoadLocalKeyFile(cfg.VariantsKeyFile)
+			if err != nil {
+				return fmt.Errorf("load VARIANTS_KEY_FILE: %w", err)
+			}
+			encryptionOpts = append(encryptionOpts, repository.WithVariantsEncryption(keys))
+			repoOpts = append(repoOpts, encryptionOpts...)
+			log.Info("encrypting flag variants at rest")
+		}
+		repo = repository.NewPostgresRepository(pool, repoOpts...)
+		metrics.RegisterPoolMetrics(m.Registry, pool)
+		if cfg.DatabaseReplicaURL != "" {
+			replicaPool, err := repository.NewPool(ctx, cfg.DatabaseReplicaURL, poolConfig)
+			if err != nil {
+				return fmt.Errorf("connect postgres replica: %w", err)
+			}
+			defer replicaPool.Close()
+			replica := repository.NewPostgresRepository(replicaPool, encryptionOpts...)
+			svcOpts = append(svcOpts, service.WithHedgedReads(replica, cfg.HedgeDelay, m.RecordHedgedRead))
+			log.Info("hedging flag reads across primary and replica", "delay", cfg.HedgeDelay)
+		}
+		if cfg.CacheInvalidation == config.CacheInvalidationRedis {
+			redisOpts, err := redis.ParseURL(cfg.RedisURL)
+			if err != nil {
+				return fmt.Errorf("parse REDIS_URL: %w", err)
+			}
+			redisClient := redis.NewClient(redisOpts)
+			defer redisClient.Close()
+			svcOpts = append(svcOpts, service.WithInvalidationTransport(repository.NewRedisNotifier(redisClient, "")))
+			log.Info("using redis for cache invalidation")
+		}
+		svc, err = service.New(ctx, repo, svcOpts...)
+		if err != nil {
+			return fmt.Errorf("init service: %w", err)
+		}
+
+		go purgeDeletedProjects(ctx, svc, log)
+		go enforceKeyRotation(ctx, svc, log)
+		go remindExpiredFlags(ctx, svc, expiryWebhook, log)
+		go purgeIdempotencyKeys(ctx, svc, log)
+
+		if cfg.KubernetesSync {
+			if err := startKubernetesSync(ctx, cfg, svc, log); err != nil {
+				return err
+			}
+		}
+
+		if cfg.ExportS3Bucket != "" {
+			store, err := export.NewS3Store(export.S3Config{
+				Endpoint:        cfg.ExportS3Endpoint,
+				Region:          cfg.ExportS3Region,
+				Bucket:          cfg.ExportS3Bucket,
+				AccessKeyID:     cfg.ExportS3AccessKeyID,
+				SecretAccessKey: cfg.ExportS3SecretAccessKey,
+			}, nil)
+			if err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+			scheduler := export.NewScheduler(svc, store,
+				export.WithInterval(cfg.ExportInterval),
+				export.WithPrefix(cfg.ExportS3Prefix),
+				export.WithLogger(log),
+			)
+			go scheduler.Run(ctx)
+			log.Info("exporting audit log and flag events to s3", "bucket", cfg.ExportS3Bucket, "interval", cfg.ExportInterval)
+		}
+
+		tokenValidator = &apiKeyTokenValidator{lookup: repo}
 	}
 
 	authFailure := middleware.WithOnAuthFailure(func() { m.AuthFailuresTotal.Inc() })
-	tokenValidator := &apiKeyTokenValidator{lookup: repo}
 	rateLimiter := middleware.NewRateLimiter(ctx, cfg.AuthRateLimit)
 	defer rateLimiter.Stop()
 	authRL := middleware.WithRateLimiter(rateLimiter)
-	apiHandler := server.NewHTTPHandlerWithOptions(svc, cfg.StreamPollInterval, m, server.WithMaxJSONBodySize(cfg.MaxJSONBodySize))
+	sdkConfig := server.SDKConfig{
+		PollInterval:      cfg.SDKPollInterval,
+		MaxBatchSize:      cfg.SDKMaxBatchSize,
+		HeartbeatInterval: cfg.SDKHeartbeatInterval,
+	}
+	// One limiter, so SSE streams and gRPC watches share each key's allowance.
+	streamLimiter := server.NewStreamLimiter(cfg.MaxStreamsPerAPIKey)
+	streamDrainer := server.NewStreamDrainer()
+	evaluationLimiter := server.NewEvaluationLimiter(cfg.MaxEvaluationsInFlight, cfg.MaxQueuedEvaluations, m)
+	// Shared, like the limiter, so that a reload reaches both servers.
+	pollInterval := server.NewPollInterval(cfg.StreamPollInterval)
+	apiHandler := server.NewHTTPHandlerWithOptions(svc, cfg.StreamPollInterval, m,
+		server.WithStreamPollInterval(pollInterval),
+		server.WithMaxJSONBodySize(cfg.MaxJSONBodySize),
+		server.WithMaxEvaluateBodySize(cfg.MaxEvaluateBodySize),
+		server.WithMaxImportBodySize(cfg.MaxImportBodySize),
+		server.WithEvaluateTimeout(cfg.EvaluateTimeout),
+		server.WithImportTimeout(cfg.ImportTimeout),
+		server.WithReadinessCheck(svc.Readiness),
+		server.WithSDKConfig(sdkConfig),
+		server.WithStreamKeepalive(cfg.StreamKeepaliveInterval),
+		server.WithStreamWriteTimeout(cfg.StreamWriteTimeout),
+		server.WithStreamLimiter(streamLimiter),
+		server.WithStreamDrainer(streamDrainer),
+		server.WithEvaluationLimiter(evaluationLimiter),
+		server.WithInstance(identity),
+	)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		middleware.UnaryRequestLoggingInterceptor(log),
+		middleware.UnaryBearerAuthInterceptor(tokenValidator, authFailure, authRL),
+		m.UnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		middleware.StreamRequestLoggingInterceptor(log),
+		middleware.StreamBearerAuthInterceptor(tokenValidator, authFailure, authRL),
+		m.StreamServerInterceptor(),
+	}
+	if cfg.UpstreamURL != "" {
+		apiHandler = proxy.ReadOnlyHandler(apiHandler)
+		unaryInterceptors = append(unaryInterceptors, proxy.UnaryReadOnlyInterceptor())
+		streamInterceptors = append(streamInterceptors, proxy.StreamReadOnlyInterceptor())
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.MaxConcurrentStreams(cfg.HTTP2MaxConcurrentStreams),
+	}
+	var certs *tlsreload.Reloader
+	if cfg.TLSCertFile != "" {
+		certs, err = tlsreload.New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, tlsreload.WithLogger(log))
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		go certs.Run(ctx, cfg.TLSReloadInterval)
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(certs.Config())))
+		log.Info("serving TLS", "mutual_tls", certs.MutualTLS(), "reload_interval", cfg.TLSReloadInterval)
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
+	flagspb.RegisterFlagServiceServer(grpcServer, server.NewGRPCServerWithOptions(svc, cfg.StreamPollInterval, m,
+		server.WithGRPCSDKConfig(sdkConfig),
+		server.WithGRPCStreamLimiter(streamLimiter),
+		server.WithGRPCStreamDrainer(streamDrainer),
+		server.WithGRPCEvaluationLimiter(evaluationLimiter),
+		server.WithGRPCStreamPollInterval(pollInterval),
+	))
+	reflection.Register(grpcServer)
+	// Like /healthz, the health service reports the process is up; clients
+	// use it to check connectivity and their API key before serving.
+	grpcHealth := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, grpcHealth)
+
 	httpHandler := newHTTPHandler(apiHandler, tokenValidator, authFailure, authRL)
+	if cfg.GRPCWeb {
+		// gRPC-Web calls are authenticated by the gRPC interceptors.
+		httpHandler = grpcWebHandler(grpcServer, httpHandler)
+	}
+	// The throttle and CORS policy are installed even while they are off,
+	// so that a reload can turn them on.
+	ipThrottle := middleware.NewIPThrottle(ctx, cfg.HTTPRateLimitPerIP, cfg.HTTPRateLimitBurst)
+	defer ipThrottle.Stop()
+	httpHandler = middleware.IPThrottle(ipThrottle)(httpHandler)
+	if cfg.ErrorFormat == config.ErrorFormatLegacy {
+		httpHandler = middleware.LegacyErrors(httpHandler)
+	}
+	corsPolicy := middleware.NewCORSPolicy(corsConfig(cfg))
+	httpHandler = corsPolicy.Middleware(httpHandler)
+	// Outermost, so every error response, including authentication
+	// failures, carries the request ID it was logged under.
+	httpHandler = middleware.HTTPRequestLogging(log,
+		middleware.WithRouteLevels(cfg.LogRouteLevels),
+		middleware.WithRouteSampleRates(cfg.LogSampleRates),
+	)(httpHandler)
 
 	httpServer := &http.Server{
 		Addr:              cfg.HTTPAddr,
@@ -114,20 +458,13 @@ func run() error {
 		ReadHeaderTimeout: httpReadHeaderTimeout,
 		ReadTimeout:       httpReadTimeout,
 		IdleTimeout:       httpIdleTimeout,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: int(cfg.HTTP2MaxConcurrentStreams),
+		},
+	}
+	if certs != nil {
+		httpServer.TLSConfig = certs.Config("h2", "http/1.1")
 	}
-
-	grpcServer := grpc.NewServer(
-		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-		grpc.ChainUnaryInterceptor(
-			middleware.UnaryBearerAuthInterceptor(tokenValidator, authFailure, authRL),
-			m.UnaryServerInterceptor(),
-		),
-		grpc.ChainStreamInterceptor(
-			middleware.StreamBearerAuthInterceptor(tokenValidator, authFailure, authRL),
-			m.StreamServerInterceptor(),
-		),
-	)
-	flagspb.RegisterFlagServiceServer(grpcServer, server.NewGRPCServerWithOptions(svc, cfg.StreamPollInterval, m))
 
 	// -------------------------------------------------------------------------
 	// Admin Portal (Tailscale)
@@ -135,6 +472,9 @@ func run() error {
 	var tsServer *tsnet.Server
 	var adminLis net.Listener
 
+	if !cfg.AdminPortalEnabled {
+		log.Info("admin portal disabled")
+	}
 	if cfg.AdminHostname != "" {
 		if cfg.TSAuthKey == "" {
 			return errors.New("ADMIN_HOSTNAME is set but TS_AUTH_KEY is missing")
@@ -153,10 +493,37 @@ func run() error {
 		}
 
 		// Create admin session manager
-		sessionMgr := admin.NewSessionManager(ctx, repo, cfg.SessionSecret)
+		sessionMgr := admin.NewSessionManager(ctx, repo, cfg.SessionSecret, admin.WithIdleTimeout(cfg.AdminSessionIdleTimeout))
 
 		// Create admin handler
 		adminHandler := admin.NewHandler(repo, svc, sessionMgr, cfg.AdminHostname, log)
+		if cfg.OIDCIssuerURL != "" {
+			adminHandler.OIDC = &admin.OIDCLogin{
+				Provider:             oidc.New(cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, oidc.WithScopes(cfg.OIDCScopes...)),
+				RolesClaim:           cfg.OIDCRolesClaim,
+				AdminGroups:          cfg.OIDCAdminGroups,
+				ViewerGroups:         cfg.OIDCViewerGroups,
+				DisablePasswordLogin: !cfg.OIDCPasswordLogin,
+			}
+		}
+		adminHandler.PasswordHasher = admin.PasswordHasher{
+			Algorithm:  cfg.AdminPasswordHash,
+			BcryptCost: cfg.AdminPasswordBcryptCost,
+			Argon2: admin.Argon2Params{
+				Memory:      cfg.AdminPasswordArgon2Memory,
+				Iterations:  cfg.AdminPasswordArgon2Iterations,
+				Parallelism: cfg.AdminPasswordArgon2Parallelism,
+			},
+		}
+		adminHandler.PasswordPolicy = admin.PasswordPolicy{MinLength: cfg.AdminPasswordMinLength}
+		if cfg.AdminPasswordBreachList != "" {
+			breached, err := admin.LoadBreachList(cfg.AdminPasswordBreachList)
+			if err != nil {
+				return fmt.Errorf("load admin password breach list: %w", err)
+			}
+			adminHandler.PasswordPolicy.Breached = breached
+			log.Info("loaded admin password breach list", "entries", len(breached))
+		}
 
 		// Listen on tailnet
 		var err error
@@ -196,7 +563,12 @@ func run() error {
 
 	serveErrCh := make(chan error, 2)
 	go func() {
-		if err := httpServer.Serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		serve := httpServer.Serve
+		if certs != nil {
+			// The certificate comes from TLSConfig, not from files named here.
+			serve = func(l net.Listener) error { return httpServer.ServeTLS(l, "", "") }
+		}
+		if err := serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serveErrCh <- fmt.Errorf("serve HTTP: %w", err)
 		}
 	}()
@@ -206,6 +578,16 @@ func run() error {
 		}
 	}()
 
+	reloadOnSIGHUP(ctx, &reloadable{
+		logLevel:     logLevel,
+		svc:          svc,
+		pollInterval: pollInterval,
+		authLimiter:  rateLimiter,
+		ipThrottle:   ipThrottle,
+		cors:         corsPolicy,
+		current:      cfg,
+	}, log)
+
 	log.Info("server started", "http_addr", cfg.HTTPAddr, "grpc_addr", cfg.GRPCAddr)
 
 	var serveErr error
@@ -215,8 +597,26 @@ func run() error {
 	}
 	stop()
 
+	// Fail readiness first, so load balancers stop routing here while the
+	// listeners still accept the requests already on their way.
+	svc.BeginShutdown()
+	if serveErr == nil && cfg.ShutdownDrainDelay > 0 {
+		log.Info("server draining", "delay", cfg.ShutdownDrainDelay)
+		time.Sleep(cfg.ShutdownDrainDelay)
+	}
+
 	log.Info("server shutting down")
 
+	// Streams never finish on their own, so end them with a reconnect event
+	// before closing the listeners; their clients resume on another replica.
+	if cfg.StreamDrainTimeout > 0 {
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.StreamDrainTimeout)
+		if err := streamDrainer.Drain(drainCtx); err != nil {
+			log.Warn("streams still open after drain timeout", "timeout", cfg.StreamDrainTimeout)
+		}
+		cancelDrain()
+	}
+
 	httpShutdownCtx, cancelHTTP := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancelHTTP()
 	if err := httpServer.Shutdown(httpShutdownCtx); err != nil && !errors.Is(err, context.Canceled) {
@@ -226,6 +626,7 @@ func run() error {
 		return fmt.Errorf("shutdown HTTP: %w", err)
 	}
 
+	grpcHealth.Shutdown()
 	stopped := make(chan struct{})
 	go func() {
 		grpcServer.GracefulStop()
@@ -241,45 +642,333 @@ func run() error {
 		tsServer.Close()
 	}
 
+	// Evaluations and API key usage counted since the last periodic flush
+	// would otherwise be lost.
+	statsCtx, cancelStats := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelStats()
+	if err := svc.FlushStats(statsCtx); err != nil {
+		log.Warn("final flag stats flush failed", "error", err)
+	}
+	if err := svc.FlushUsage(statsCtx); err != nil {
+		log.Warn("final api key usage flush failed", "error", err)
+	}
+
 	return serveErr
 }
 
+// newProxyService builds the service for read-only proxy mode: it loads the
+// upstream project's flags from cfg.UpstreamURL, then keeps following its
+// event stream in the background for as long as ctx lives.
+func newProxyService(ctx context.Context, cfg config.Config, log *slog.Logger, svcOpts []service.Option) (*service.Service, middleware.TokenValidator, error) {
+	upstream := flagzhttp.NewHTTPClient(flagzhttp.Config{
+		BaseURL: cfg.UpstreamURL,
+		APIKey:  cfg.UpstreamAPIKey,
+	})
+	repo := proxy.NewRepository()
+	syncer := proxy.NewSyncer(repo, upstream, proxy.WithLogger(log))
+
+	bootstrapCtx, cancel := context.WithTimeout(ctx, proxyBootstrapTimeout)
+	defer cancel()
+	if err := syncer.Bootstrap(bootstrapCtx); err != nil {
+		return nil, nil, fmt.Errorf("bootstrap from upstream: %w", err)
+	}
+
+	svc, err := service.New(ctx, repo, svcOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("init service: %w", err)
+	}
+	go syncer.Run(ctx)
+	log.Info("serving as read-only proxy", "upstream", cfg.UpstreamURL)
+
+	return svc, proxy.NewTokenValidator(cfg.UpstreamURL, cfg.UpstreamAPIKey, nil), nil
+}
+
+// startKubernetesSync starts syncing flags from the Kubernetes cluster the
+// server runs in until ctx is cancelled.
+func startKubernetesSync(ctx context.Context, cfg config.Config, svc *service.Service, log *slog.Logger) error {
+	kube, err := kubesync.NewInClusterClient()
+	if err != nil {
+		return fmt.Errorf("kubernetes sync: %w", err)
+	}
+	syncer := kubesync.New(svc, kube,
+		kubesync.WithNamespace(cfg.KubernetesNamespace),
+		kubesync.WithInterval(cfg.KubernetesSyncInterval),
+		kubesync.WithLogger(log),
+	)
+	go syncer.Run(ctx)
+	log.Info("syncing flags from kubernetes", "namespace", cfg.KubernetesNamespace)
+	return nil
+}
+
+// purgeDeletedProjects removes projects past their restore window every
+// projectPurgeInterval until ctx is cancelled. Every replica runs it; the
+// delete is idempotent.
+func purgeDeletedProjects(ctx context.Context, svc *service.Service, log *slog.Logger) {
+	ticker := time.NewTicker(projectPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		purged, err := svc.PurgeDeletedProjects(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("purge deleted projects failed", "error", err)
+			}
+			continue
+		}
+		if purged > 0 {
+			log.Info("purged deleted projects", "count", purged)
+		}
+	}
+}
+
+// purgeIdempotencyKeys removes stored Idempotency-Key responses past their
+// TTL every idempotencyPurgeInterval until ctx is cancelled. Expired records
+// are already ignored when a key is claimed; this only reclaims the space.
+func purgeIdempotencyKeys(ctx context.Context, svc *service.Service, log *slog.Logger) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		purged, err := svc.PurgeIdempotencyKeys(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("purge idempotency keys failed", "error", err)
+			}
+			continue
+		}
+		if purged > 0 {
+			log.Info("purged expired idempotency keys", "count", purged)
+		}
+	}
+}
+
+// enforceKeyRotation applies project API key rotation policies every
+// keyRotationInterval until ctx is cancelled. Like the project purge it runs
+// on every replica; each overdue key is marked and revoked only once.
+func enforceKeyRotation(ctx context.Context, svc *service.Service, log *slog.Logger) {
+	ticker := time.NewTicker(keyRotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := svc.EnforceKeyRotation(ctx); err != nil && ctx.Err() == nil {
+			log.Warn("enforce api key rotation failed", "error", err)
+		}
+	}
+}
+
+// flagExpiredEvent is the JSON body posted to FLAG_EXPIRY_WEBHOOK_URL for
+// each expired flag.
+type flagExpiredEvent struct {
+	Type      string          `json:"type"`
+	ProjectID string          `json:"project_id"`
+	Flag      repository.Flag `json:"flag"`
+}
+
+// remindExpiredFlags reports flags whose expiry has passed every
+// flagExpiryInterval. The service logs each one; when sender is non-nil it is
+// also posted to the webhook. A failed delivery is logged and not retried,
+// since the flag has already been claimed.
+func remindExpiredFlags(ctx context.Context, svc *service.Service, sender *webhook.Sender, log *slog.Logger) {
+	ticker := time.NewTicker(flagExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		expired, err := svc.RemindExpiredFlags(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("flag expiry reminder failed", "error", err)
+			}
+			continue
+		}
+		if sender == nil {
+			continue
+		}
+		for _, flag := range expired {
+			event := flagExpiredEvent{Type: "flag.expired", ProjectID: flag.ProjectID, Flag: flag}
+			if err := sender.Send(ctx, event); err != nil && ctx.Err() == nil {
+				log.Warn("flag expiry webhook failed", "project_id", flag.ProjectID, "flag_key", flag.Key, "error", err)
+			}
+		}
+	}
+}
+
 func newHTTPHandler(apiHandler http.Handler, tokenValidator middleware.TokenValidator, opts ...middleware.AuthOption) http.Handler {
 	protectedAPIHandler := middleware.HTTPBearerAuthMiddleware(tokenValidator, opts...)(apiHandler)
 
 	mux := http.NewServeMux()
 	mux.Handle("/v1/", protectedAPIHandler)
 	mux.Handle("GET /healthz", apiHandler)
+	mux.Handle("GET /readyz", apiHandler)
 	mux.Handle("GET /metrics", apiHandler)
+	// API descriptions are not secret: the same files ship with the source.
+	mux.Handle("GET /v1/openapi.json", apiHandler)
+	mux.Handle("GET /v1/schema", apiHandler)
+	mux.Handle("GET /v1/proto/descriptor", apiHandler)
 
 	return mux
 }
 
+// grpcWebHandler serves gRPC-Web requests from browsers with grpcServer and
+// passes every other request to next.
+func grpcWebHandler(grpcServer *grpc.Server, next http.Handler) http.Handler {
+	web := grpcweb.WrapServer(grpcServer)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if web.IsGrpcWebRequest(r) {
+			web.HandleGrpcWebRequest(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 type apiKeyHashLookup interface {
 	ValidateAPIKey(ctx context.Context, id string) (string, string, error)
 }
 
+// apiKeyCredentialLookup is implemented by lookups that also return a key's
+// age and its project's rotation policy, letting the validator flag overdue
+// keys without a second query.
+type apiKeyCredentialLookup interface {
+	LookupAPIKey(ctx context.Context, id string) (repository.APIKeyCredential, error)
+}
+
+// apiKeyUsageRecorder is implemented by lookups that can record when a key
+// last authenticated a request.
+type apiKeyUsageRecorder interface {
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// dashboardTokenLookup is implemented by lookups that can authenticate the
+// short-lived read-only tokens minted in the admin portal.
+type dashboardTokenLookup interface {
+	LookupDashboardToken(ctx context.Context, id string) (repository.DashboardToken, error)
+}
+
+// apiKeyTouchInterval is the least time between recorded uses of one key,
+// so a busy key costs a write every few minutes rather than every request.
+const apiKeyTouchInterval = 5 * time.Minute
+
 type apiKeyTokenValidator struct {
 	lookup apiKeyHashLookup
+
+	touchMu sync.Mutex
+	touched map[string]time.Time
 }
 
 func (v *apiKeyTokenValidator) ValidateToken(ctx context.Context, token string) (string, error) {
+	projectID, _, err := v.ValidateTokenRotation(ctx, token)
+	return projectID, err
+}
+
+func (v *apiKeyTokenValidator) ValidateTokenRotation(ctx context.Context, token string) (string, middleware.KeyRotation, error) {
 	if v == nil || v.lookup == nil {
-		return "", errors.New("api key validator is nil")
+		return "", middleware.KeyRotation{}, errors.New("api key validator is nil")
 	}
 
 	keyID, rawSecret, found := strings.Cut(token, ".")
 	if !found || strings.TrimSpace(keyID) == "" || rawSecret == "" {
-		return "", errors.New("invalid token format")
+		return "", middleware.KeyRotation{}, errors.New("invalid token format")
 	}
 
-	keyHash, projectID, err := v.lookup.ValidateAPIKey(ctx, keyID)
-	if err != nil {
-		return "", fmt.Errorf("lookup key hash: %w", err)
+	if strings.HasPrefix(keyID, middleware.DashboardTokenPrefix) {
+		return v.validateDashboardToken(ctx, keyID, rawSecret)
+	}
+
+	var keyHash, projectID string
+	var rotation middleware.KeyRotation
+	if lookup, ok := v.lookup.(apiKeyCredentialLookup); ok {
+		cred, err := lookup.LookupAPIKey(ctx, keyID)
+		if err != nil {
+			return "", middleware.KeyRotation{}, fmt.Errorf("lookup key hash: %w", err)
+		}
+		keyHash, projectID = cred.KeyHash, cred.ProjectID
+		rotation = keyRotation(cred, time.Now())
+	} else {
+		var err error
+		keyHash, projectID, err = v.lookup.ValidateAPIKey(ctx, keyID)
+		if err != nil {
+			return "", middleware.KeyRotation{}, fmt.Errorf("lookup key hash: %w", err)
+		}
 	}
 	if !middleware.APIKeyMatchesHash(keyHash, rawSecret) {
-		return "", errors.New("invalid token")
+		return "", middleware.KeyRotation{}, errors.New("invalid token")
 	}
+	v.touch(ctx, keyID, time.Now())
 
-	return projectID, nil
+	return projectID, rotation, nil
+}
+
+// validateDashboardToken authenticates a dashboard token. The auth
+// middleware limits what it may do; dashboard tokens are never overdue for
+// rotation since they expire on their own.
+func (v *apiKeyTokenValidator) validateDashboardToken(ctx context.Context, id, rawSecret string) (string, middleware.KeyRotation, error) {
+	lookup, ok := v.lookup.(dashboardTokenLookup)
+	if !ok {
+		return "", middleware.KeyRotation{}, errors.New("dashboard tokens not supported")
+	}
+	token, err := lookup.LookupDashboardToken(ctx, id)
+	if err != nil {
+		return "", middleware.KeyRotation{}, fmt.Errorf("lookup dashboard token: %w", err)
+	}
+	if !middleware.APIKeyMatchesHash(token.TokenHash, rawSecret) {
+		return "", middleware.KeyRotation{}, errors.New("invalid token")
+	}
+	return token.ProjectID, middleware.KeyRotation{}, nil
+}
+
+// touch records the use of keyID if the lookup supports it and the key's
+// last recorded use in this process is more than apiKeyTouchInterval ago.
+// Recording is best effort: a failed write does not fail authentication and
+// is not retried until the interval has passed again.
+func (v *apiKeyTokenValidator) touch(ctx context.Context, keyID string, now time.Time) {
+	recorder, ok := v.lookup.(apiKeyUsageRecorder)
+	if !ok {
+		return
+	}
+
+	v.touchMu.Lock()
+	if last, ok := v.touched[keyID]; ok && now.Sub(last) < apiKeyTouchInterval {
+		v.touchMu.Unlock()
+		return
+	}
+	if v.touched == nil {
+		v.touched = make(map[string]time.Time)
+	}
+	v.touched[keyID] = now
+	v.touchMu.Unlock()
+
+	_ = recorder.TouchAPIKey(ctx, keyID, now)
+}
+
+// keyRotation reports cred as overdue once now reaches its rotation deadline.
+func keyRotation(cred repository.APIKeyCredential, now time.Time) middleware.KeyRotation {
+	rotateBy, ok := cred.Policy.RotateBy(cred.CreatedAt)
+	if !ok || now.Before(rotateBy) {
+		return middleware.KeyRotation{}
+	}
+	rotation := middleware.KeyRotation{RotateBy: rotateBy}
+	if revokeAt, ok := cred.Policy.RevokeAt(cred.CreatedAt); ok {
+		rotation.RevokeAt = revokeAt
+	}
+	return rotation
 }
@@ -3,12 +3,21 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/matt-riley/flagz/internal/config"
 	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/server"
+	"google.golang.org/grpc"
 )
 
 func mustHashAPIKey(t *testing.T, apiKey string) string {
@@ -66,16 +75,28 @@ func TestNewHTTPHandlerKeepsPublicEndpointsAccessible(t *testing.T) {
 	apiHandler.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	apiHandler.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
 	apiHandler.HandleFunc("GET /metrics", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	apiHandler.HandleFunc("GET /v1/openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	apiHandler.HandleFunc("GET /v1/schema", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	apiHandler.HandleFunc("GET /v1/proto/descriptor", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
 	apiHandler.HandleFunc("GET /debug", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
 	handler := newHTTPHandler(apiHandler, &fakeHTTPTokenValidator{err: errors.New("invalid token")})
 
-	for _, path := range []string{"/healthz", "/metrics"} {
+	for _, path := range []string{"/healthz", "/readyz", "/metrics", "/v1/openapi.json", "/v1/schema", "/v1/proto/descriptor"} {
 		path := path
 		t.Run(path, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, path, nil)
@@ -102,6 +123,37 @@ func TestNewHTTPHandlerKeepsPublicEndpointsAccessible(t *testing.T) {
 	})
 }
 
+func TestGRPCWebHandlerDispatchesGRPCWebRequests(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	defer grpcServer.Stop()
+	var nextCalled bool
+	handler := grpcWebHandler(grpcServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !nextCalled || rec.Code != http.StatusTeapot {
+		t.Fatalf("JSON request: next called = %v, status = %d, want the HTTP API", nextCalled, rec.Code)
+	}
+
+	nextCalled = false
+	req = httptest.NewRequest(http.MethodPost, "/flagz.v1.FlagService/ResolveBoolean", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if nextCalled {
+		t.Fatal("gRPC-Web request should not reach the HTTP API")
+	}
+	// No service is registered, so the gRPC server answers Unimplemented.
+	if got := rec.Header().Get("Grpc-Status"); got != "12" {
+		t.Fatalf("grpc-status = %q, want 12 (Unimplemented)", got)
+	}
+}
+
 func TestAPIKeyTokenValidatorValidateToken(t *testing.T) {
 	t.Run("nil validator", func(t *testing.T) {
 		var validator *apiKeyTokenValidator
@@ -186,6 +238,131 @@ func TestAPIKeyTokenValidatorValidateToken(t *testing.T) {
 	})
 }
 
+func TestAPIKeyTokenValidatorValidateTokenRotation(t *testing.T) {
+	now := time.Now()
+	policy := repository.KeyRotationPolicy{MaxAge: 24 * time.Hour, RevokeAfter: time.Hour}
+
+	tests := []struct {
+		name        string
+		createdAt   time.Time
+		wantOverdue bool
+	}{
+		{name: "current key", createdAt: now.Add(-time.Hour)},
+		{name: "overdue key", createdAt: now.Add(-25 * time.Hour), wantOverdue: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &apiKeyTokenValidator{lookup: &fakeAPIKeyCredentialLookup{cred: repository.APIKeyCredential{
+				KeyHash:   mustHashAPIKey(t, "good-secret"),
+				ProjectID: "proj-123",
+				CreatedAt: tt.createdAt,
+				Policy:    policy,
+			}}}
+
+			pid, rotation, err := validator.ValidateTokenRotation(context.Background(), "my-key.good-secret")
+			if err != nil || pid != "proj-123" {
+				t.Fatalf("ValidateTokenRotation() = %q, %v, want proj-123", pid, err)
+			}
+			if rotation.Overdue() != tt.wantOverdue {
+				t.Fatalf("Overdue() = %v, want %v", rotation.Overdue(), tt.wantOverdue)
+			}
+			if tt.wantOverdue {
+				if want := tt.createdAt.Add(policy.MaxAge); !rotation.RotateBy.Equal(want) {
+					t.Errorf("RotateBy = %v, want %v", rotation.RotateBy, want)
+				}
+				if want := tt.createdAt.Add(policy.MaxAge + policy.RevokeAfter); !rotation.RevokeAt.Equal(want) {
+					t.Errorf("RevokeAt = %v, want %v", rotation.RevokeAt, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyTokenValidatorTouchesKeys(t *testing.T) {
+	lookup := &fakeAPIKeyUsageLookup{fakeAPIKeyHashLookup: fakeAPIKeyHashLookup{
+		hash:      mustHashAPIKey(t, "good-secret"),
+		projectID: "proj-123",
+	}}
+	validator := &apiKeyTokenValidator{lookup: lookup}
+
+	for range 3 {
+		if _, err := validator.ValidateToken(context.Background(), "my-key.good-secret"); err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+	}
+	if _, err := validator.ValidateToken(context.Background(), "my-key.bad-secret"); err == nil {
+		t.Fatal("ValidateToken() with bad secret should fail")
+	}
+	if len(lookup.touched) != 1 || lookup.touched[0] != "my-key" {
+		t.Fatalf("touched = %v, want one write for my-key", lookup.touched)
+	}
+
+	// Once the interval has passed the next use is recorded again.
+	validator.touch(context.Background(), "my-key", time.Now().Add(apiKeyTouchInterval))
+	if len(lookup.touched) != 2 {
+		t.Fatalf("touched = %v, want a second write after the interval", lookup.touched)
+	}
+}
+
+func TestAPIKeyTokenValidatorDashboardTokens(t *testing.T) {
+	lookup := &fakeDashboardTokenLookup{token: repository.DashboardToken{
+		ID:        "dash_abc",
+		ProjectID: "proj-123",
+		TokenHash: mustHashAPIKey(t, "good-secret"),
+	}}
+	validator := &apiKeyTokenValidator{lookup: lookup}
+
+	pid, err := validator.ValidateToken(context.Background(), "dash_abc.good-secret")
+	if err != nil || pid != "proj-123" {
+		t.Fatalf("ValidateToken() = %q, %v, want proj-123", pid, err)
+	}
+	if lookup.calls != 0 {
+		t.Fatalf("ValidateAPIKey calls = %d, want dashboard tokens looked up on their own", lookup.calls)
+	}
+	if _, err := validator.ValidateToken(context.Background(), "dash_abc.bad-secret"); err == nil {
+		t.Fatal("ValidateToken() with bad secret should fail")
+	}
+	if _, err := validator.ValidateToken(context.Background(), "dash_expired.good-secret"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("ValidateToken(expired) error = %v, want wrapped pgx.ErrNoRows", err)
+	}
+
+	plain := &apiKeyTokenValidator{lookup: &fakeAPIKeyHashLookup{hash: mustHashAPIKey(t, "good-secret"), projectID: "proj-123"}}
+	if _, err := plain.ValidateToken(context.Background(), "dash_abc.good-secret"); err == nil {
+		t.Fatal("ValidateToken() without dashboard token support should fail")
+	}
+}
+
+type fakeDashboardTokenLookup struct {
+	fakeAPIKeyHashLookup
+	token repository.DashboardToken
+}
+
+func (f *fakeDashboardTokenLookup) LookupDashboardToken(_ context.Context, id string) (repository.DashboardToken, error) {
+	if id != f.token.ID {
+		return repository.DashboardToken{}, fmt.Errorf("lookup dashboard token: %w", pgx.ErrNoRows)
+	}
+	return f.token, nil
+}
+
+type fakeAPIKeyUsageLookup struct {
+	fakeAPIKeyHashLookup
+	touched []string
+}
+
+func (f *fakeAPIKeyUsageLookup) TouchAPIKey(_ context.Context, id string, _ time.Time) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+type fakeAPIKeyCredentialLookup struct {
+	fakeAPIKeyHashLookup
+	cred repository.APIKeyCredential
+}
+
+func (f *fakeAPIKeyCredentialLookup) LookupAPIKey(_ context.Context, _ string) (repository.APIKeyCredential, error) {
+	return f.cred, nil
+}
+
 type fakeAPIKeyHashLookup struct {
 	hash      string
 	projectID string
@@ -213,3 +390,99 @@ func (f *fakeHTTPTokenValidator) ValidateToken(_ context.Context, _ string) (str
 	f.calls++
 	return f.projectID, f.err
 }
+
+func TestRunMigrateCommandRejectsBadArguments(t *testing.T) {
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "extra"}} {
+		err := runMigrateCommand(args)
+		if err == nil || !strings.Contains(err.Error(), migrateUsage) {
+			t.Fatalf("runMigrateCommand(%q) error = %v, want usage error", args, err)
+		}
+	}
+}
+
+func TestRunBackupCommandsRejectBadArguments(t *testing.T) {
+	for _, args := range [][]string{nil, {"a.jsonl.gz", "extra"}} {
+		if err := runBackupCommand(args); err == nil || !strings.Contains(err.Error(), backupUsage) {
+			t.Fatalf("runBackupCommand(%q) error = %v, want usage error", args, err)
+		}
+	}
+	for _, args := range [][]string{nil, {"-rename"}, {"-force", "a.jsonl.gz"}} {
+		if err := runRestoreCommand(args); err == nil || !strings.Contains(err.Error(), restoreUsage) {
+			t.Fatalf("runRestoreCommand(%q) error = %v, want usage error", args, err)
+		}
+	}
+}
+
+type fakeEventPollIntervalSetter struct {
+	interval time.Duration
+}
+
+func (f *fakeEventPollIntervalSetter) SetEventPollInterval(interval time.Duration) {
+	f.interval = interval
+}
+
+func TestReloadableApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial := config.Config{
+		LogLevel:           "info",
+		StreamPollInterval: time.Second,
+		AuthRateLimit:      10,
+		HTTPRateLimitBurst: 20,
+	}
+	svc := &fakeEventPollIntervalSetter{}
+	r := &reloadable{
+		logLevel:     new(slog.LevelVar),
+		svc:          svc,
+		pollInterval: server.NewPollInterval(initial.StreamPollInterval),
+		authLimiter:  middleware.NewRateLimiter(ctx, initial.AuthRateLimit),
+		ipThrottle:   middleware.NewIPThrottle(ctx, initial.HTTPRateLimitPerIP, initial.HTTPRateLimitBurst),
+		cors:         middleware.NewCORSPolicy(corsConfig(initial)),
+		current:      initial,
+	}
+	defer r.authLimiter.Stop()
+	defer r.ipThrottle.Stop()
+
+	if changed := r.apply(initial); len(changed) != 0 {
+		t.Fatalf("apply(unchanged) = %v, want nothing changed", changed)
+	}
+
+	reloaded := initial
+	reloaded.LogLevel = "debug"
+	reloaded.StreamPollInterval = 5 * time.Second
+	reloaded.HTTPRateLimitPerIP = 0.001
+	reloaded.HTTPRateLimitBurst = 1
+	reloaded.CORSAllowedOrigins = []string{"https://dash.example.com"}
+	changed := r.apply(reloaded)
+	want := []string{"LOG_LEVEL", "STREAM_POLL_INTERVAL", "HTTP_RATE_LIMIT_PER_IP", "CORS_ALLOWED_ORIGINS"}
+	if !slices.Equal(changed, want) {
+		t.Fatalf("apply() = %v, want %v", changed, want)
+	}
+	if got := r.logLevel.Level(); got != slog.LevelDebug {
+		t.Errorf("log level = %v, want debug", got)
+	}
+	if got := r.pollInterval.Get(); got != 5*time.Second {
+		t.Errorf("stream poll interval = %v, want 5s", got)
+	}
+	if svc.interval != 5*time.Second {
+		t.Errorf("event poll interval = %v, want 5s", svc.interval)
+	}
+
+	handler := r.cors.Middleware(middleware.IPThrottle(r.ipThrottle)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+		req.Header.Set("Origin", "https://dash.example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do(); rec.Header().Get("Access-Control-Allow-Origin") != "https://dash.example.com" {
+		t.Errorf("Allow-Origin = %q, want the reloaded origin", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rec := do(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second request: status = %d, want %d once throttling is on", rec.Code, http.StatusTooManyRequests)
+	}
+}
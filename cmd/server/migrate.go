@@ -1,28 +1,116 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/matt-riley/flagz/internal/config"
+	"github.com/matt-riley/flagz/internal/logging"
 	"github.com/matt-riley/flagz/migrations"
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
 )
 
-func runMigrations(pool *pgxpool.Pool) error {
+const migrateUsage = "usage: server migrate up|down"
+
+// newMigrationProvider returns a goose provider for the embedded migrations.
+// It holds a PostgreSQL session-level advisory lock for the duration of each
+// operation, so replicas that start at the same time apply migrations one at
+// a time instead of racing each other.
+func newMigrationProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("create migration lock: %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create migration provider: %w", err)
+	}
+	return provider, nil
+}
+
+// runMigrations applies all pending migrations.
+func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 	db := stdlib.OpenDBFromPool(pool)
 	defer db.Close()
 
-	goose.SetBaseFS(migrations.FS)
-	if err := goose.SetDialect("postgres"); err != nil {
-		return fmt.Errorf("set goose dialect: %w", err)
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		return err
 	}
 
-	if err := goose.Up(db, "."); err != nil {
+	results, err := provider.Up(ctx)
+	if err != nil {
 		return fmt.Errorf("run migrations: %w", err)
 	}
+	for _, r := range results {
+		slog.Info("migration applied", "version", r.Source.Version, "duration", r.Duration)
+	}
+
+	slog.Info("migrations up to date", "applied", len(results))
+	return nil
+}
+
+// rollbackMigration rolls back the most recently applied migration.
+func rollbackMigration(ctx context.Context, pool *pgxpool.Pool) error {
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Down(ctx)
+	if err != nil {
+		return fmt.Errorf("roll back migration: %w", err)
+	}
 
-	slog.Info("migrations applied")
+	slog.Info("migration rolled back", "version", result.Source.Version, "duration", result.Duration)
 	return nil
 }
+
+// runMigrateCommand implements the "migrate up|down" subcommand, which
+// applies or rolls back migrations against DATABASE_URL and exits without
+// starting the servers.
+func runMigrateCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	var migrate func(context.Context, *pgxpool.Pool) error
+	switch args[0] {
+	case "up":
+		migrate = runMigrations
+	case "down":
+		migrate = rollbackMigration
+	default:
+		return fmt.Errorf("unknown migrate direction %q; %s", args[0], migrateUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	slog.SetDefault(logging.New(cfg.LogLevel))
+	if cfg.DatabaseDriver == config.DatabaseDriverSQLite {
+		return errors.New("SQLite databases are migrated when the server starts")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	defer pool.Close()
+
+	return migrate(ctx, pool)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/config"
+	"github.com/matt-riley/flagz/internal/logging"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/server"
+)
+
+// reloadable holds what a SIGHUP reconfigures: the log level, the stream
+// poll interval, the auth and per-IP rate limits and the CORS policy. The
+// handlers and service read them on every use rather than capturing them
+// when they are built. Every other setting needs a restart.
+type reloadable struct {
+	logLevel     *slog.LevelVar
+	svc          eventPollIntervalSetter
+	pollInterval *server.PollInterval
+	authLimiter  *middleware.RateLimiter
+	ipThrottle   *middleware.RateLimiter
+	cors         *middleware.CORSPolicy
+
+	// current is the configuration last applied.
+	current config.Config
+}
+
+// eventPollIntervalSetter is the part of [service.Service] a reload changes.
+type eventPollIntervalSetter interface {
+	SetEventPollInterval(interval time.Duration)
+}
+
+// apply swaps in cfg's reloadable settings and returns the environment
+// variable names of those that changed.
+func (r *reloadable) apply(cfg config.Config) []string {
+	var changed []string
+	if cfg.LogLevel != r.current.LogLevel {
+		r.logLevel.Set(logging.ParseLevel(cfg.LogLevel))
+		changed = append(changed, "LOG_LEVEL")
+	}
+	if cfg.StreamPollInterval != r.current.StreamPollInterval {
+		r.pollInterval.Set(cfg.StreamPollInterval)
+		r.svc.SetEventPollInterval(cfg.StreamPollInterval)
+		changed = append(changed, "STREAM_POLL_INTERVAL")
+	}
+	if cfg.AuthRateLimit != r.current.AuthRateLimit {
+		r.authLimiter.SetMaxPerMinute(cfg.AuthRateLimit)
+		changed = append(changed, "AUTH_RATE_LIMIT")
+	}
+	if cfg.HTTPRateLimitPerIP != r.current.HTTPRateLimitPerIP || cfg.HTTPRateLimitBurst != r.current.HTTPRateLimitBurst {
+		r.ipThrottle.SetPerSecond(cfg.HTTPRateLimitPerIP, cfg.HTTPRateLimitBurst)
+		changed = append(changed, "HTTP_RATE_LIMIT_PER_IP")
+	}
+	if !slices.Equal(cfg.CORSAllowedOrigins, r.current.CORSAllowedOrigins) ||
+		!slices.Equal(cfg.CORSAllowedHeaders, r.current.CORSAllowedHeaders) ||
+		cfg.CORSMaxAge != r.current.CORSMaxAge {
+		r.cors.Set(corsConfig(cfg))
+		changed = append(changed, "CORS_ALLOWED_ORIGINS")
+	}
+	r.current = cfg
+	return changed
+}
+
+// corsConfig returns the CORS policy cfg describes. It allows no origins,
+// and so leaves requests untouched, unless CORS_ALLOWED_ORIGINS is set.
+func corsConfig(cfg config.Config) middleware.CORSConfig {
+	return middleware.CORSConfig{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+		ExposedHeaders: []string{"ETag", "Retry-After", middleware.RequestIDHeader, server.SDKConfigHeader, server.SchemaVersionHeader},
+		MaxAge:         cfg.CORSMaxAge,
+	}
+}
+
+// reloadOnSIGHUP reloads configuration from FLAGZ_CONFIG and the environment
+// on every SIGHUP until ctx is done. A configuration that fails to load is
+// logged and leaves the running settings as they were.
+func reloadOnSIGHUP(ctx context.Context, r *reloadable, log *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				log.Error("configuration reload failed", "error", err)
+				continue
+			}
+			log.Info("configuration reloaded", "changed", r.apply(cfg))
+		}
+	}()
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// handleChangePassword lets the signed-in user change their own password.
+// They must enter their current password first. Changing it signs out all of
+// their sessions, so this browser is given a fresh one. Users who sign in
+// with single sign-on have no password to change.
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value(sessionContextKey).(repository.AdminSession)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	user, err := h.Repo.GetAdminUserByID(r.Context(), session.AdminUserID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if msg := h.changePassword(r, user); msg != "" {
+			h.renderChangePassword(w, r, user, session, msg)
+			return
+		}
+		token, err := h.SessionMgr.GenerateSession(r.Context(), user, r.UserAgent(), clientIP(r))
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		h.SessionMgr.SetSessionCookie(w, token)
+		http.Redirect(w, r, "/account/password?changed=1", http.StatusFound)
+		return
+	}
+
+	h.renderChangePassword(w, r, user, session, "")
+}
+
+// changePassword sets user's password from the POSTed form and returns a
+// message for the user if it was refused.
+func (h *Handler) changePassword(r *http.Request, user repository.AdminUser) string {
+	ctx := r.Context()
+	if user.PasswordHash == "" {
+		return "You sign in with single sign-on and have no password to change"
+	}
+	match, err := VerifyPassword(r.FormValue("current_password"), user.PasswordHash)
+	if err != nil || !match {
+		return "Current password is incorrect"
+	}
+	hash, msg := h.newPasswordHash(r.FormValue("password"), r.FormValue("confirm_password"))
+	if msg != "" {
+		return msg
+	}
+	if err := h.Repo.SetAdminUserPassword(ctx, user.ID, hash); err != nil {
+		h.log.ErrorContext(ctx, "failed to change admin password", "error", err)
+		return "Failed to change password"
+	}
+	h.logAudit(ctx, user.ID, "admin_password_change", defaultProjectID, "", nil)
+	return ""
+}
+
+func (h *Handler) renderChangePassword(w http.ResponseWriter, r *http.Request, user repository.AdminUser, session repository.AdminSession, errMsg string) {
+	if err := Render(w, "account_password.html", map[string]any{
+		"User":              user,
+		"CSRFToken":         session.CSRFToken,
+		"Error":             errMsg,
+		"Changed":           errMsg == "" && r.URL.Query().Get("changed") == "1",
+		"MinPasswordLength": h.PasswordPolicy.minLength(),
+	}); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
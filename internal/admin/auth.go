@@ -1,13 +1,20 @@
 package admin
 
 import (
+	"bufio"
 	"crypto/rand"
+	"crypto/sha1"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -16,68 +23,256 @@ const (
 	argonParallelism = 4
 	argonSaltLength  = 16
 	argonKeyLength   = 32
+
+	defaultBcryptCost        = 12
+	defaultPasswordMinLength = 12
+)
+
+// Password hashing algorithms supported by [PasswordHasher].
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
 )
 
-// HashPassword hashes a password using Argon2id.
+// Argon2Params are the argon2id cost settings of a [PasswordHasher].
+type Argon2Params struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// PasswordHasher hashes new admin passwords. [VerifyPassword] accepts hashes
+// made by either algorithm with any settings, so they can be changed at any
+// time; [PasswordHasher.NeedsRehash] spots hashes made with other settings
+// so they can be replaced when their user next signs in.
+//
+// The zero value hashes with argon2id at the default cost.
+type PasswordHasher struct {
+	// Algorithm is [PasswordHashArgon2id] or [PasswordHashBcrypt].
+	Algorithm string
+	// BcryptCost is the bcrypt work factor. Defaults to 12.
+	BcryptCost int
+	// Argon2 holds the argon2id settings. Defaults to 64MB of memory, 4
+	// iterations and 4 lanes.
+	Argon2 Argon2Params
+}
+
+func (p PasswordHasher) withDefaults() PasswordHasher {
+	if p.Algorithm == "" {
+		p.Algorithm = PasswordHashArgon2id
+	}
+	if p.BcryptCost == 0 {
+		p.BcryptCost = defaultBcryptCost
+	}
+	if p.Argon2 == (Argon2Params{}) {
+		p.Argon2 = Argon2Params{Memory: argonMemory, Iterations: argonIterations, Parallelism: argonParallelism}
+	}
+	return p
+}
+
+// Hash hashes password with the configured algorithm. bcrypt only reads the
+// first 72 bytes of a password, so longer ones are refused with
+// [bcrypt.ErrPasswordTooLong] rather than silently truncated.
+func (p PasswordHasher) Hash(password string) (string, error) {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case PasswordHashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %w", err)
+		}
+		return string(hash), nil
+	case PasswordHashArgon2id:
+		return hashArgon2id(password, p.Argon2)
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm %q", p.Algorithm)
+	}
+}
+
+// NeedsRehash reports whether encodedHash was made by another algorithm or
+// with other settings than p would use now.
+func (p PasswordHasher) NeedsRehash(encodedHash string) bool {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case PasswordHashBcrypt:
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost != p.BcryptCost
+	case PasswordHashArgon2id:
+		params, _, _, err := decodeArgon2id(encodedHash)
+		return err != nil || params != p.Argon2
+	default:
+		return false
+	}
+}
+
+// HashPassword hashes a password using Argon2id at the default cost.
 // Returns format compatible with PHC string format: $argon2id$v=19$m=65536,t=4,p=4$<salt>$<hash>
 func HashPassword(password string) (string, error) {
+	return PasswordHasher{}.Hash(password)
+}
+
+func hashArgon2id(password string, params Argon2Params) (string, error) {
 	salt := make([]byte, argonSaltLength)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("generate salt: %w", err)
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, argonIterations, argonMemory, argonParallelism, argonKeyLength)
+	hash := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argonKeyLength)
 
 	// Encode to base64 without padding
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
 	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version, argonMemory, argonIterations, argonParallelism, b64Salt, b64Hash), nil
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism, b64Salt, b64Hash), nil
 }
 
-// VerifyPassword checks if the provided password matches the encoded Argon2id hash.
+// VerifyPassword checks if the provided password matches the encoded
+// Argon2id or bcrypt hash.
 func VerifyPassword(password, encodedHash string) (bool, error) {
+	if strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$") {
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, decodedHash, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	hashToCompare := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(decodedHash)))
+
+	if subtle.ConstantTimeCompare(decodedHash, hashToCompare) == 1 {
+		return true, nil
+	}
+	return false, nil
+}
+
+func decodeArgon2id(encodedHash string) (params Argon2Params, salt, hash []byte, err error) {
 	parts := strings.Split(encodedHash, "$")
 	if len(parts) != 6 {
-		return false, fmt.Errorf("invalid hash format")
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid hash format")
 	}
 
 	if parts[1] != "argon2id" {
-		return false, fmt.Errorf("incompatible variant")
+		return Argon2Params{}, nil, nil, fmt.Errorf("incompatible variant")
 	}
 
 	var version int
-	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
 	if err != nil {
-		return false, fmt.Errorf("incompatible version")
+		return Argon2Params{}, nil, nil, fmt.Errorf("incompatible version")
 	}
 	if version != argon2.Version {
-		return false, fmt.Errorf("incompatible version: %d", version)
+		return Argon2Params{}, nil, nil, fmt.Errorf("incompatible version: %d", version)
 	}
 
-	var memory uint32
-	var iterations uint32
-	var parallelism uint8
-	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
 	if err != nil {
-		return false, fmt.Errorf("invalid params")
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid params")
 	}
 
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, fmt.Errorf("decode salt: %w", err)
+		return Argon2Params{}, nil, nil, fmt.Errorf("decode salt: %w", err)
 	}
 
-	decodedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	hash, err = base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, fmt.Errorf("decode hash: %w", err)
+		return Argon2Params{}, nil, nil, fmt.Errorf("decode hash: %w", err)
 	}
+	return params, salt, hash, nil
+}
 
-	hashToCompare := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(decodedHash)))
+// PasswordPolicy is what new admin passwords must satisfy. The zero value
+// requires 12 characters and checks no breach list.
+type PasswordPolicy struct {
+	// MinLength is the minimum length in characters. Defaults to 12.
+	MinLength int
+	// Breached lists passwords known from data breaches, which are refused
+	// whatever their length.
+	Breached BreachList
+}
 
-	if subtle.ConstantTimeCompare(decodedHash, hashToCompare) == 1 {
-		return true, nil
+// Validate returns why password, entered twice as password and confirm,
+// cannot be set, or "" if it can.
+func (p PasswordPolicy) Validate(password, confirm string) string {
+	minLength := p.minLength()
+	if password != confirm {
+		return "Passwords do not match"
 	}
-	return false, nil
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Sprintf("Password must be at least %d characters", minLength)
+	}
+	if p.Breached.Contains(password) {
+		return "This password has appeared in a data breach; choose another"
+	}
+	return ""
+}
+
+func (p PasswordPolicy) minLength() int {
+	if p.MinLength <= 0 {
+		return defaultPasswordMinLength
+	}
+	return p.MinLength
+}
+
+// BreachList is a set of passwords known from data breaches, held as SHA-1
+// hashes.
+type BreachList map[[sha1.Size]byte]struct{}
+
+// Contains reports whether password is on the list.
+func (b BreachList) Contains(password string) bool {
+	if len(b) == 0 {
+		return false
+	}
+	_, ok := b[sha1.Sum([]byte(password))]
+	return ok
+}
+
+// LoadBreachList reads a breach list with one entry per line. An entry is
+// either the password itself or, as in the Have I Been Pwned downloads, its
+// hex SHA-1 hash, optionally followed by ":" and a count. Blank lines are
+// skipped.
+func LoadBreachList(path string) (BreachList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breach list: %w", err)
+	}
+	defer f.Close()
+
+	list := make(BreachList)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if digest, ok := parseSHA1Entry(line); ok {
+			list[digest] = struct{}{}
+			continue
+		}
+		list[sha1.Sum([]byte(line))] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breach list: %w", err)
+	}
+	return list, nil
+}
+
+// parseSHA1Entry decodes a "HASH" or "HASH:COUNT" breach list line.
+func parseSHA1Entry(line string) ([sha1.Size]byte, bool) {
+	var digest [sha1.Size]byte
+	hexHash, _, _ := strings.Cut(line, ":")
+	if len(hexHash) != 2*sha1.Size {
+		return digest, false
+	}
+	if _, err := hex.Decode(digest[:], []byte(hexHash)); err != nil {
+		return digest, false
+	}
+	return digest, true
 }
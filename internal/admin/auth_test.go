@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheapArgon2 keeps argon2id tests fast.
+var cheapArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestPasswordHasher_HashAndVerify(t *testing.T) {
+	for _, hasher := range []PasswordHasher{
+		{Algorithm: PasswordHashArgon2id, Argon2: cheapArgon2},
+		{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost},
+	} {
+		t.Run(hasher.Algorithm, func(t *testing.T) {
+			hash, err := hasher.Hash("correct horse battery")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if ok, err := VerifyPassword("correct horse battery", hash); err != nil || !ok {
+				t.Fatalf("VerifyPassword(right) = %v, %v; want true", ok, err)
+			}
+			if ok, err := VerifyPassword("wrong horse battery", hash); err != nil || ok {
+				t.Fatalf("VerifyPassword(wrong) = %v, %v; want false", ok, err)
+			}
+			if hasher.NeedsRehash(hash) {
+				t.Fatal("NeedsRehash() = true for a hash it just made")
+			}
+		})
+	}
+}
+
+func TestPasswordHasher_DefaultsMatchHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=4,p=4$") {
+		t.Fatalf("HashPassword() = %q, want default argon2id settings", hash)
+	}
+	if (PasswordHasher{}).NeedsRehash(hash) {
+		t.Fatal("zero PasswordHasher wants to rehash a default hash")
+	}
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	argonHash, err := PasswordHasher{Argon2: cheapArgon2}.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := PasswordHasher{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{"argon2id to bcrypt", PasswordHasher{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}, argonHash, true},
+		{"bcrypt to argon2id", PasswordHasher{Argon2: cheapArgon2}, bcryptHash, true},
+		{"stronger argon2id", PasswordHasher{Argon2: Argon2Params{Memory: 128, Iterations: 1, Parallelism: 1}}, argonHash, true},
+		{"higher bcrypt cost", PasswordHasher{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost + 1}, bcryptHash, true},
+		{"same bcrypt cost", PasswordHasher{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}, bcryptHash, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Fatalf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordHasher_BcryptRejectsLongPasswords(t *testing.T) {
+	h := &Handler{PasswordHasher: PasswordHasher{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}}
+	long := strings.Repeat("a", 73)
+	if _, msg := h.newPasswordHash(long, long); msg != "Password must be at most 72 bytes" {
+		t.Fatalf("newPasswordHash() message = %q", msg)
+	}
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength: 14,
+		Breached:  BreachList{sha1.Sum([]byte("password123456")): {}},
+	}
+	tests := []struct {
+		password, confirm, want string
+	}{
+		{"correct horse battery", "correct horse battery", ""},
+		{"correct horse battery", "correct horse", "Passwords do not match"},
+		{"thirteen char", "thirteen char", "Password must be at least 14 characters"},
+		// Length is counted in characters, not bytes.
+		{"ééééééééééééé", "ééééééééééééé", "Password must be at least 14 characters"},
+		{"password123456", "password123456", "This password has appeared in a data breach; choose another"},
+	}
+	for _, tt := range tests {
+		if got := policy.Validate(tt.password, tt.confirm); got != tt.want {
+			t.Errorf("Validate(%q, %q) = %q, want %q", tt.password, tt.confirm, got, tt.want)
+		}
+	}
+
+	if got := (PasswordPolicy{}).Validate("short", "short"); got != "Password must be at least 12 characters" {
+		t.Errorf("zero policy Validate(short) = %q", got)
+	}
+}
+
+func TestLoadBreachList(t *testing.T) {
+	sum := sha1.Sum([]byte("hunter2hunter2"))
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "letmein12345\r\n\n" + strings.ToUpper(hex.EncodeToString(sum[:])) + ":4021\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachList(path)
+	if err != nil {
+		t.Fatalf("LoadBreachList() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("len(list) = %d, want 2", len(list))
+	}
+	for _, password := range []string{"letmein12345", "hunter2hunter2"} {
+		if !list.Contains(password) {
+			t.Errorf("Contains(%q) = false, want true", password)
+		}
+	}
+	if list.Contains("correct horse battery") {
+		t.Error("Contains(correct horse battery) = true, want false")
+	}
+
+	if _, err := LoadBreachList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachList(missing) error = nil, want an error")
+	}
+}
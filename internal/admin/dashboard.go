@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	// dashboardWindow is how far back the dashboard's "recent" counts reach.
+	dashboardWindow = 7 * 24 * time.Hour
+	// defaultActivityDays and maxActivityDays bound the days of activity
+	// returned for a project's sparkline.
+	defaultActivityDays = 14
+	maxActivityDays     = 90
+)
+
+// projectActivityJSON is the body of GET /projects/{id}/activity.
+type projectActivityJSON struct {
+	Days []repository.DailyActivity `json:"days"`
+}
+
+// handleProjectActivity returns a project's daily flag events and audit
+// actions as JSON, for the dashboard's sparklines. Every day of the range is
+// present, oldest first, with zero counts for days without activity.
+//
+//	GET /projects/{id}/activity[?days=N]
+func (h *Handler) handleProjectActivity(w http.ResponseWriter, r *http.Request, project *repository.Project) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultActivityDays
+	if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n > 0 {
+		days = min(n, maxActivityDays)
+	}
+	now := time.Now().UTC()
+	activity, err := h.Repo.ListProjectActivity(r.Context(), project.ID, activityStart(now, days))
+	if err != nil {
+		http.Error(w, "Failed to load project activity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(projectActivityJSON{Days: fillActivity(activity, days, now)}); err != nil {
+		h.log.ErrorContext(r.Context(), "encode project activity", "error", err)
+	}
+}
+
+// activityStart returns midnight UTC at the start of the first of the days
+// ending with the one containing now.
+func activityStart(now time.Time, days int) time.Time {
+	return now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+}
+
+// fillActivity returns one entry for each of the days ending with the one
+// containing now, taking the counts from activity and zero for days it
+// leaves out.
+func fillActivity(activity []repository.DailyActivity, days int, now time.Time) []repository.DailyActivity {
+	byDay := make(map[time.Time]repository.DailyActivity, len(activity))
+	for _, d := range activity {
+		byDay[d.Day.UTC()] = d
+	}
+
+	filled := make([]repository.DailyActivity, days)
+	day := activityStart(now, days)
+	for i := range filled {
+		filled[i] = byDay[day]
+		filled[i].Day = day
+		day = day.AddDate(0, 0, 1)
+	}
+	return filled
+}
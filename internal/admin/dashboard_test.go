@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+func TestRenderDashboardTemplate_ProjectOverview(t *testing.T) {
+	lastAudit := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := Render(&buf, "dashboard.html", map[string]any{
+		"User": repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Projects": []repository.ProjectOverview{
+			{
+				Project:              repository.Project{ID: "proj-1", Name: "Checkout", Description: "Payments"},
+				Flags:                8,
+				EnabledFlags:         6,
+				RecentlyChangedFlags: 3,
+				ActiveAPIKeys:        2,
+				RecentAuditActions:   11,
+				LastAuditAction:      "update_flag",
+				LastAuditAt:          &lastAudit,
+			},
+			{Project: repository.Project{ID: "proj-2", Name: "Empty"}},
+		},
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		">8</td>", ">75%</td>", ">3</td>", ">2</td>", ">11</a>",
+		"Last: update_flag at 2026-03-01T12:00:00Z",
+		`data-activity-url="/projects/proj-1/activity"`,
+		">0%</td>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+}
+
+func TestFillActivity(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	got := fillActivity([]repository.DailyActivity{
+		{Day: day(8), FlagEvents: 4, AuditActions: 1},
+		{Day: day(10), AuditActions: 2},
+	}, 4, now)
+
+	want := []repository.DailyActivity{
+		{Day: day(7)},
+		{Day: day(8), FlagEvents: 4, AuditActions: 1},
+		{Day: day(9)},
+		{Day: day(10), AuditActions: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("fillActivity() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Day.Equal(want[i].Day) || got[i].FlagEvents != want[i].FlagEvents || got[i].AuditActions != want[i].AuditActions {
+			t.Errorf("day %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHandleProjectActivity_MethodNotAllowed(t *testing.T) {
+	h := &Handler{}
+	req := httptest.NewRequest(http.MethodPost, "/projects/proj-1/activity", nil)
+	rr := httptest.NewRecorder()
+
+	h.handleProjectActivity(rr, req, &repository.Project{ID: "proj-1"})
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	// defaultDashboardTokenTTL and maxDashboardTokenTTL bound how long a
+	// dashboard token lives.
+	defaultDashboardTokenTTL = time.Hour
+	maxDashboardTokenTTL     = 24 * time.Hour
+)
+
+// dashboardTokenJSON is the body of the response to POST /admin/tokens.
+type dashboardTokenJSON struct {
+	repository.DashboardToken
+	// Token is the bearer token, "<id>.<secret>". It is not shown again.
+	Token string `json:"token"`
+}
+
+// handleCreateDashboardToken mints a short-lived, read-only HTTP API token
+// for a project, so internal dashboards can read it without a long-lived
+// API key. Any signed-in user may mint one: the token can do no more than
+// read, which every role can. The form or query takes project_id and an
+// optional ttl, a Go duration of at most 24 hours.
+//
+//	POST /admin/tokens
+func (h *Handler) handleCreateDashboardToken(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value(sessionContextKey).(repository.AdminSession)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimSpace(r.FormValue("project_id")))
+	if err != nil {
+		http.Error(w, "project_id must be a project ID", http.StatusBadRequest)
+		return
+	}
+	ttl, errMsg := parseDashboardTokenTTL(r.FormValue("ttl"))
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.Repo.GetProject(r.Context(), projectID.String())
+	if err != nil || project.DeletedAt != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	token, secret, err := h.Repo.CreateDashboardToken(r.Context(), project.ID, session.AdminUserID, ttl)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "create dashboard token failed", "error", err, "project_id", project.ID)
+		http.Error(w, "Failed to create dashboard token", http.StatusInternalServerError)
+		return
+	}
+	h.logAudit(r.Context(), session.AdminUserID, "dashboard_token_create", project.ID, "", map[string]string{
+		"dashboard_token_id": token.ID,
+		"expires_at":         token.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(dashboardTokenJSON{DashboardToken: token, Token: token.ID + "." + secret}); err != nil {
+		h.log.ErrorContext(r.Context(), "encode dashboard token", "error", err)
+	}
+}
+
+// parseDashboardTokenTTL parses the ttl of a dashboard token, defaulting to
+// an hour. It returns why value is unacceptable, or "" if it is fine.
+func parseDashboardTokenTTL(value string) (time.Duration, string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultDashboardTokenTTL, ""
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < time.Minute || ttl > maxDashboardTokenTTL {
+		return 0, "ttl must be a duration between 1m and 24h"
+	}
+	return ttl.Truncate(time.Second), ""
+}
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+func TestParseDashboardTokenTTL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: time.Hour},
+		{value: "15m", want: 15 * time.Minute},
+		{value: " 24h ", want: 24 * time.Hour},
+		{value: "90.5s", want: 90 * time.Second},
+		{value: "30s", wantErr: true},
+		{value: "25h", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, errMsg := parseDashboardTokenTTL(tt.value)
+		if (errMsg != "") != tt.wantErr || got != tt.want {
+			t.Errorf("parseDashboardTokenTTL(%q) = %v, %q, want %v (error %v)", tt.value, got, errMsg, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHandleCreateDashboardToken_InvalidRequest(t *testing.T) {
+	h := &Handler{}
+	for _, target := range []string{
+		"/admin/tokens",
+		"/admin/tokens?project_id=not-a-uuid",
+		"/admin/tokens?project_id=11111111-1111-1111-1111-111111111111&ttl=48h",
+	} {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionContextKey, repository.AdminSession{}))
+		rr := httptest.NewRecorder()
+
+		h.handleCreateDashboardToken(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("POST %s status = %d, want %d", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
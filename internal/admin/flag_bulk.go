@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// bulkFlagUpdate reads the project page's bulk action forms: the flags
+// ticked as "key" fields, the action to take on them and, for "tag", the
+// comma-separated tags to add. "restore" comes from the archived flags list.
+func bulkFlagUpdate(r *http.Request, projectID string) (service.BulkFlagUpdate, error) {
+	update := service.BulkFlagUpdate{ProjectID: projectID, Keys: r.PostForm["key"]}
+	switch action := r.PostFormValue("action"); action {
+	case "enable", "disable":
+		enabled := action == "enable"
+		update.Enabled = &enabled
+	case "tag":
+		update.AddTags = splitTags(r.PostFormValue("tags"))
+	case "archive":
+		update.Archive = true
+	case "restore":
+		update.Restore = true
+	default:
+		return service.BulkFlagUpdate{}, fmt.Errorf("unknown bulk action %q", action)
+	}
+	return update, nil
+}
+
+// handleFlagBulk serves POST /projects/{id}/flags/bulk, applying one action
+// to every flag ticked on the project page, or restoring the archived flags
+// ticked there, in a single transaction, and returns to the page with its
+// tag filter kept.
+func (h *Handler) handleFlagBulk(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser) {
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	update, err := bulkFlagUpdate(r, project.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = h.Service.BulkUpdateFlags(r.Context(), update)
+	switch {
+	case errors.Is(err, service.ErrInvalidBulkFlagUpdate), errors.Is(err, service.ErrInvalidTags):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrFlagNotFound):
+		http.Error(w, "A selected flag no longer exists or was already archived or restored; reload the page and try again", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to update flags", http.StatusInternalServerError)
+		return
+	}
+
+	target := fmt.Sprintf("/projects/%s", project.ID)
+	if tag := r.PostFormValue("tag"); tag != "" {
+		target += "?tag=" + url.QueryEscape(tag)
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
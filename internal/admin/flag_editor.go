@@ -0,0 +1,422 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// flagDraft is the state of the flag editor form. Variants, Rules and
+// Context hold the raw JSON typed by the user, Tags the comma-separated tags
+// and ExpiresAt a YYYY-MM-DD date, so a rejected edit can be shown again
+// exactly as entered.
+type flagDraft struct {
+	Variants  string
+	Rules     string
+	Tags      string
+	Owner     string
+	ExpiresAt string
+	Context   string
+	Preset    string
+	UpdatedAt string
+
+	// Attribute, Operator and Value are the structured "add rule" fields.
+	Attribute string
+	Operator  string
+	Value     string
+}
+
+// handleFlagEditor serves the rules and variants editor of a single flag.
+// Nothing is written until the save action, so viewers may change the draft
+// and preview it against a sample context; saving requires the admin role.
+//
+//	GET  /projects/{id}/flags/{key}/edit
+//	POST /projects/{id}/flags/{key}/edit (action=add_rule|preview|save)
+//
+// The page also holds the form for [Handler.handleFlagCopy].
+func (h *Handler) handleFlagEditor(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, csrfToken, flagKey string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flag, err := h.Service.GetFlag(r.Context(), project.ID, flagKey)
+	if errors.Is(err, service.ErrFlagNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load flag", http.StatusInternalServerError)
+		return
+	}
+	presets, err := h.Service.ListContextPresets(r.Context(), project.ID)
+	if err != nil {
+		http.Error(w, "Failed to list context presets", http.StatusInternalServerError)
+		return
+	}
+	projects, err := h.Repo.ListProjects(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list projects", http.StatusInternalServerError)
+		return
+	}
+	stats, err := h.Service.GetFlagStats(r.Context(), project.ID, flagKey)
+	if err != nil {
+		http.Error(w, "Failed to load flag stats", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"User":      user,
+		"Project":   project,
+		"Flag":      flag,
+		"Stats":     stats,
+		"Presets":   presets,
+		"Projects":  projects,
+		"CSRFToken": csrfToken,
+		"Saved":     r.URL.Query().Get("saved") == "1",
+	}
+	render := func(status int, draft flagDraft) {
+		data["Draft"] = draft
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		if err := Render(w, "flag_editor.html", data); err != nil {
+			h.log.ErrorContext(r.Context(), "render error", "error", err)
+		}
+	}
+
+	if r.Method == http.MethodGet {
+		render(http.StatusOK, flagDraft{
+			Variants:  prettyJSON(flag.Variants),
+			Rules:     prettyJSON(flag.Rules),
+			Tags:      strings.Join(flag.Tags, ", "),
+			Owner:     flag.Owner,
+			ExpiresAt: expiryDate(flag.ExpiresAt),
+			UpdatedAt: flag.UpdatedAt.Format(time.RFC3339Nano),
+			Operator:  string(core.OperatorEquals),
+		})
+		return
+	}
+
+	draft := flagDraft{
+		Variants:  r.FormValue("variants"),
+		Rules:     r.FormValue("rules"),
+		Tags:      r.FormValue("tags"),
+		Owner:     r.FormValue("owner"),
+		ExpiresAt: r.FormValue("expires_at"),
+		Context:   r.FormValue("context"),
+		Preset:    r.FormValue("preset"),
+		UpdatedAt: r.FormValue("updated_at"),
+		Attribute: r.FormValue("attribute"),
+		Operator:  r.FormValue("operator"),
+		Value:     r.FormValue("value"),
+	}
+	edited := flag
+	edited.Variants = draftJSON(draft.Variants)
+	edited.Rules = draftJSON(draft.Rules)
+	edited.Tags = splitTags(draft.Tags)
+	edited.Owner = draft.Owner
+
+	switch r.FormValue("action") {
+	case "add_rule":
+		rule, err := ruleFromForm(draft.Attribute, draft.Operator, draft.Value)
+		if err == nil {
+			draft.Rules, err = appendRule(draft.Rules, rule)
+		}
+		if err != nil {
+			data["Errors"] = []string{err.Error()}
+			render(http.StatusBadRequest, draft)
+			return
+		}
+		draft.Attribute, draft.Value = "", ""
+		render(http.StatusOK, draft)
+
+	case "preview":
+		evalContext, err := h.sampleContext(r, project.ID, draft)
+		if err != nil {
+			data["Errors"] = []string{err.Error()}
+			render(http.StatusBadRequest, draft)
+			return
+		}
+		result, err := h.Service.PreviewFlag(r.Context(), edited, evalContext)
+		if messages := flagValidationMessages(err); messages != nil {
+			data["Errors"] = messages
+			render(http.StatusBadRequest, draft)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to evaluate flag", http.StatusInternalServerError)
+			return
+		}
+		data["Preview"] = result
+		render(http.StatusOK, draft)
+
+	case "save":
+		if !isAdminRole(user.Role) {
+			http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+			return
+		}
+		// The editor may have been open for a while; refuse to overwrite a
+		// change someone else saved in the meantime.
+		if draft.UpdatedAt != flag.UpdatedAt.Format(time.RFC3339Nano) {
+			data["Errors"] = []string{"This flag was changed by someone else after you opened the editor. Reload the page to see their changes before saving."}
+			render(http.StatusConflict, draft)
+			return
+		}
+		edited.ExpiresAt, err = parseExpiryDate(draft.ExpiresAt, flag.ExpiresAt)
+		if err != nil {
+			data["Errors"] = []string{err.Error()}
+			render(http.StatusBadRequest, draft)
+			return
+		}
+		_, err = h.Service.UpdateFlag(r.Context(), edited)
+		if messages := flagValidationMessages(err); messages != nil {
+			data["Errors"] = messages
+			render(http.StatusBadRequest, draft)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to update flag", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/projects/%s/flags/%s/edit?saved=1", project.ID, flag.Key), http.StatusFound)
+
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+	}
+}
+
+// handleFlagCopy copies a flag from the editor to another project or key and
+// shows the copy in its own editor. Admin only.
+//
+//	POST /projects/{id}/flags/{key}/copy
+func (h *Handler) handleFlagCopy(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, flagKey string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	flag, _, err := h.Service.CopyFlag(r.Context(), service.CopyFlagRequest{
+		SourceProjectID: project.ID,
+		SourceKey:       flagKey,
+		TargetProjectID: r.FormValue("target_project"),
+		TargetKey:       strings.TrimSpace(r.FormValue("target_key")),
+		IncludeRules:    r.FormValue("include_rules") == "on",
+		IncludeTargets:  r.FormValue("include_targets") == "on",
+		Overwrite:       r.FormValue("overwrite") == "on",
+	})
+	switch {
+	case errors.Is(err, service.ErrFlagNotFound), errors.Is(err, service.ErrProjectNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, service.ErrFlagExists):
+		http.Error(w, "The target project already has this flag; tick overwrite to update it", http.StatusConflict)
+		return
+	case errors.Is(err, service.ErrInvalidFlagCopy), errors.Is(err, service.ErrFlagKeyPolicyViolation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Failed to copy flag", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s/flags/%s/edit?saved=1", flag.ProjectID, flag.Key), http.StatusFound)
+}
+
+// splitTags splits the editor's comma-separated tags. It never returns nil,
+// so saving with the field emptied removes the flag's tags.
+func splitTags(raw string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// expiryDate formats an expiry for the editor's date field.
+func expiryDate(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.UTC().Format(time.DateOnly)
+}
+
+// parseExpiryDate reads the editor's date field as the start of that day in
+// UTC. An unchanged date keeps current, time of day included, so saving
+// other edits does not re-arm the expiry reminder.
+func parseExpiryDate(raw string, current *time.Time) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if raw == expiryDate(current) {
+		return current, nil
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return nil, errors.New("expiry date must be in YYYY-MM-DD format")
+	}
+	return &day, nil
+}
+
+// sampleContext builds the preview context from the selected preset, if any,
+// with the attributes typed into the editor layered on top.
+func (h *Handler) sampleContext(r *http.Request, projectID string, draft flagDraft) (core.EvaluationContext, error) {
+	var attributes map[string]any
+	if raw := strings.TrimSpace(draft.Context); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &attributes); err != nil {
+			return core.EvaluationContext{}, errors.New("sample context must be a JSON object")
+		}
+	}
+	if draft.Preset == "" {
+		return core.EvaluationContext{Attributes: attributes}, nil
+	}
+
+	preset, err := h.Service.GetContextPreset(r.Context(), projectID, draft.Preset)
+	if errors.Is(err, service.ErrContextPresetNotFound) {
+		return core.EvaluationContext{}, errors.New("context preset not found")
+	}
+	if err != nil {
+		return core.EvaluationContext{}, err
+	}
+	merged := make(map[string]any, len(preset.Context.Attributes)+len(attributes))
+	for name, value := range preset.Context.Attributes {
+		merged[name] = value
+	}
+	for name, value := range attributes {
+		merged[name] = value
+	}
+	return core.EvaluationContext{Attributes: merged}, nil
+}
+
+// flagValidationMessages turns a rules or variants validation error from the
+// service into messages for the editor, one per invalid rule when the rules
+// parse at all and one per variants schema violation. It returns nil for any
+// other error.
+func flagValidationMessages(err error) []string {
+	var rulesErr *service.RulesError
+	var variantsErr *service.VariantsError
+	switch {
+	case errors.As(err, &rulesErr):
+		messages := make([]string, len(rulesErr.Rules))
+		for i, ruleErr := range rulesErr.Rules {
+			messages[i] = fmt.Sprintf("Rule %d: %s", ruleErr.Index, ruleErr.Reason)
+		}
+		return messages
+	case errors.As(err, &variantsErr):
+		messages := make([]string, len(variantsErr.Errors))
+		for i, schemaErr := range variantsErr.Errors {
+			messages[i] = "Variants: " + schemaErr.Error()
+		}
+		return messages
+	case errors.Is(err, service.ErrInvalidRules), errors.Is(err, service.ErrInvalidVariants), errors.Is(err, service.ErrInvalidVariantsSchema),
+		errors.Is(err, service.ErrInvalidTags), errors.Is(err, service.ErrInvalidOwner):
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// ruleFromForm builds a rule from the editor's structured form. Values are
+// read the way they would be written in JSON, so 42 and true stay a number
+// and a boolean while anything that is not valid JSON is taken as a string.
+// An "in" value is a comma-separated list and a percentage is a number.
+func ruleFromForm(attribute, operator, value string) (core.Rule, error) {
+	rule := core.Rule{
+		Attribute: strings.TrimSpace(attribute),
+		Operator:  core.Operator(operator),
+	}
+	switch rule.Operator {
+	case core.OperatorEquals:
+		rule.Value = formScalar(value)
+	case core.OperatorIn:
+		var values []any
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, formScalar(item))
+			}
+		}
+		rule.Value = values
+	case core.OperatorPercentage:
+		percentage, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return core.Rule{}, errors.New("percentage must be a number between 0 and 100")
+		}
+		rule.Value = percentage
+	}
+	if errs := core.ValidateRules([]core.Rule{rule}); len(errs) > 0 {
+		return core.Rule{}, errors.New(errs[0].Reason)
+	}
+	return rule, nil
+}
+
+func formScalar(value string) any {
+	value = strings.TrimSpace(value)
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+		switch decoded.(type) {
+		case string, float64, bool:
+			return decoded
+		}
+	}
+	return value
+}
+
+// appendRule adds rule to the end of the rules JSON array in rulesJSON and
+// returns the array indented for the editor. A blank rulesJSON is treated as
+// an empty array.
+func appendRule(rulesJSON string, rule core.Rule) (string, error) {
+	var rules []json.RawMessage
+	if raw := strings.TrimSpace(rulesJSON); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return "", errors.New("fix the rules JSON before adding a rule: it must be an array")
+		}
+	}
+	encoded, err := json.Marshal(rule)
+	if err != nil {
+		return "", fmt.Errorf("encode rule: %w", err)
+	}
+	rules = append(rules, encoded)
+	indented, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode rules: %w", err)
+	}
+	return string(indented), nil
+}
+
+// draftJSON returns the editor text as a JSON payload, with blank text
+// meaning no value.
+func draftJSON(text string) json.RawMessage {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	return json.RawMessage(text)
+}
+
+// prettyJSON indents a stored JSON payload for editing. Empty and null
+// payloads are shown as blank.
+func prettyJSON(payload json.RawMessage) string {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, trimmed, "", "  "); err != nil {
+		return string(payload)
+	}
+	return buf.String()
+}
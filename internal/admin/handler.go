@@ -12,14 +12,17 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
 	"github.com/matt-riley/flagz/internal/service"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type adminContextKey string
@@ -40,6 +43,15 @@ type Handler struct {
 	AdminHostname string
 	log           *slog.Logger
 	mux           *http.ServeMux
+
+	// OIDC enables single sign-on when set.
+	OIDC *OIDCLogin
+
+	// PasswordHasher hashes new passwords. Passwords hashed some other way
+	// are rehashed with it when their user next signs in.
+	PasswordHasher PasswordHasher
+	// PasswordPolicy is what new passwords must satisfy.
+	PasswordPolicy PasswordPolicy
 }
 
 type TemplateManager struct {
@@ -72,6 +84,8 @@ func (h *Handler) buildMux() *http.ServeMux {
 	mux.HandleFunc("/login", h.handleLogin)
 	mux.HandleFunc("/setup", h.handleSetup)
 	mux.HandleFunc("/logout", h.handleLogout)
+	mux.HandleFunc("GET /oidc/login", h.handleOIDCLogin)
+	mux.HandleFunc("GET /oidc/callback", h.handleOIDCCallback)
 
 	// Protected routes
 	mux.HandleFunc("/", h.requireAuth(h.handleDashboard))
@@ -79,7 +93,12 @@ func (h *Handler) buildMux() *http.ServeMux {
 	mux.HandleFunc("/projects/", h.requireAuth(h.handleProjectDetail))
 	mux.HandleFunc("/api-keys/", h.requireAuth(h.handleAPIKeys))
 	mux.HandleFunc("/api-keys/delete/", h.requireAuth(h.requireAdmin(h.handleDeleteAPIKey)))
+	mux.HandleFunc("/api-keys/policy/", h.requireAuth(h.requireAdmin(h.handleKeyRotationPolicy)))
 	mux.HandleFunc("/audit-log/", h.requireAuth(h.handleAuditLog))
+	mux.HandleFunc("/sessions", h.requireAuth(h.handleSessions))
+	mux.HandleFunc("/users", h.requireAuth(h.requireAdmin(h.handleUsers)))
+	mux.HandleFunc("/account/password", h.requireAuth(h.handleChangePassword))
+	mux.HandleFunc("POST /admin/tokens", h.requireAuth(h.handleCreateDashboardToken))
 
 	// Static assets
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(content))))
@@ -115,6 +134,18 @@ func (h *Handler) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 
+		// A role change since login gets a fresh session ID, so a token
+		// captured before the change cannot carry the new privileges.
+		if NeedsRotation(session) {
+			token, rotated, err := h.SessionMgr.RotateSession(r.Context(), session)
+			if err != nil {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			h.SessionMgr.SetSessionCookie(w, token)
+			session = rotated
+		}
+
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, sessionContextKey, session)
 		ctx = middleware.NewContextWithAdminUserID(ctx, session.AdminUserID)
@@ -135,7 +166,7 @@ func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		if !isAdminRole(user.Role) {
+		if !isAdminRole(user.Role) || user.DeactivatedAt != nil {
 			http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
 			return
 		}
@@ -148,6 +179,42 @@ func isAdminRole(role string) bool {
 	return role == "admin"
 }
 
+// validRole reports whether role is one admin users can be given.
+func validRole(role string) bool {
+	return role == "admin" || role == "viewer"
+}
+
+// validateUsername returns why username cannot be used for a new admin user,
+// or "" if it can.
+func validateUsername(username string) string {
+	if len(username) < 3 || len(username) > 50 {
+		return "Username must be between 3 and 50 characters"
+	}
+	for _, c := range username {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.') {
+			return "Username may only contain letters, digits, underscores, hyphens, and dots"
+		}
+	}
+	return ""
+}
+
+// newPasswordHash checks password, entered twice as password and confirm,
+// against the password policy and hashes it. It returns either the hash or
+// why the password cannot be set.
+func (h *Handler) newPasswordHash(password, confirm string) (hash, msg string) {
+	if msg := h.PasswordPolicy.Validate(password, confirm); msg != "" {
+		return "", msg
+	}
+	hash, err := h.PasswordHasher.Hash(password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", "Password must be at most 72 bytes"
+	}
+	if err != nil {
+		return "", "Failed to hash password"
+	}
+	return hash, ""
+}
+
 func canManageAPIKeys(method, role string) bool {
 	if method == http.MethodPost {
 		return isAdminRole(role)
@@ -165,6 +232,11 @@ func canMutateFlags(method, role string) bool {
 }
 
 func (h *Handler) handleSetup(w http.ResponseWriter, r *http.Request) {
+	if !h.passwordLoginEnabled() {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
 	// Check if admin user exists
 	exists, err := h.Repo.HasAdminUsers(r.Context())
 	if err != nil {
@@ -190,7 +262,7 @@ func (h *Handler) handleSetup(w http.ResponseWriter, r *http.Request) {
 		if err := Render(w, "setup.html", map[string]any{
 			"CSRFToken": csrfToken,
 		}); err != nil {
-			h.log.Error("render error", "error", err)
+			h.log.ErrorContext(r.Context(), "render error", "error", err)
 		}
 		return
 	}
@@ -204,41 +276,20 @@ func (h *Handler) handleSetup(w http.ResponseWriter, r *http.Request) {
 		password := r.FormValue("password")
 		confirm := r.FormValue("confirm_password")
 
-		if len(username) < 3 || len(username) > 50 {
-			if err := Render(w, "setup.html", map[string]any{"Error": "Username must be between 3 and 50 characters"}); err != nil {
-				h.log.Error("render error", "error", err)
+		if msg := validateUsername(username); msg != "" {
+			if err := Render(w, "setup.html", map[string]any{"Error": msg}); err != nil {
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
-		for _, c := range username {
-			if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.') {
-				if err := Render(w, "setup.html", map[string]any{"Error": "Username may only contain letters, digits, underscores, hyphens, and dots"}); err != nil {
-					h.log.Error("render error", "error", err)
-				}
-				return
-			}
-		}
-
-		if password != confirm {
-			if err := Render(w, "setup.html", map[string]any{"Error": "Passwords do not match"}); err != nil {
-				h.log.Error("render error", "error", err)
+		hash, msg := h.newPasswordHash(password, confirm)
+		if msg != "" {
+			if err := Render(w, "setup.html", map[string]any{"Error": msg}); err != nil {
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
 
-		if len(password) < 12 {
-			if err := Render(w, "setup.html", map[string]any{"Error": "Password must be at least 12 characters"}); err != nil {
-				h.log.Error("render error", "error", err)
-			}
-			return
-		}
-
-		hash, err := HashPassword(password)
-		if err != nil {
-			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-			return
-		}
-
 		user, err := h.Repo.CreateAdminUser(r.Context(), username, hash, "admin")
 		if err != nil {
 			var pgErr *pgconn.PgError
@@ -246,9 +297,9 @@ func (h *Handler) handleSetup(w http.ResponseWriter, r *http.Request) {
 				http.Redirect(w, r, "/login", http.StatusFound)
 				return
 			}
-			h.log.Error("failed to create admin user", "error", err)
+			h.log.ErrorContext(r.Context(), "failed to create admin user", "error", err)
 			if err := Render(w, "setup.html", map[string]any{"Error": "Failed to create user"}); err != nil {
-				h.log.Error("render error", "error", err)
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
@@ -272,14 +323,20 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 			Secure:   isSecure,
 		})
 		if err := Render(w, "login.html", map[string]any{
-			"CSRFToken": csrfToken,
+			"CSRFToken":             csrfToken,
+			"SSO":                   h.OIDC != nil,
+			"PasswordLoginDisabled": !h.passwordLoginEnabled(),
 		}); err != nil {
-			h.log.Error("render error", "error", err)
+			h.log.ErrorContext(r.Context(), "render error", "error", err)
 		}
 		return
 	}
 
 	if r.Method == "POST" {
+		if !h.passwordLoginEnabled() {
+			http.Error(w, "Forbidden: password login is disabled", http.StatusForbidden)
+			return
+		}
 		if !h.validateDoubleSubmitCSRF(r) {
 			http.Error(w, "Forbidden: invalid CSRF token", http.StatusForbidden)
 			return
@@ -287,24 +344,11 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		// Only trust proxy headers when the request comes from a
-		// loopback or private address (i.e., a trusted reverse proxy).
-		remoteAddr := r.RemoteAddr
-		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
-			remoteAddr = host
-		}
-		if ip := net.ParseIP(remoteAddr); ip != nil && (ip.IsLoopback() || ip.IsPrivate()) {
-			if xri := r.Header.Get("X-Real-IP"); xri != "" {
-				remoteAddr = xri
-			} else if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-				first, _, _ := strings.Cut(xff, ",")
-				remoteAddr = strings.TrimSpace(first)
-			}
-		}
+		remoteAddr := clientIP(r)
 
 		if allowed := h.SessionMgr.CheckLoginRateLimit(remoteAddr); !allowed {
 			if err := Render(w, "login.html", map[string]any{"Error": "Too many attempts. Please try again later."}); err != nil {
-				h.log.Error("render error", "error", err)
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
@@ -315,7 +359,7 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 			// Don't reveal if user exists vs db error, generally
 			// For admin portal, generic error is fine
 			if err := Render(w, "login.html", map[string]any{"Error": "Invalid credentials"}); err != nil {
-				h.log.Error("render error", "error", err)
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
@@ -324,12 +368,19 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		if err != nil || !match {
 			h.SessionMgr.RecordLoginAttempt(remoteAddr)
 			if err := Render(w, "login.html", map[string]any{"Error": "Invalid credentials"}); err != nil {
-				h.log.Error("render error", "error", err)
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
+			}
+			return
+		}
+		if user.DeactivatedAt != nil {
+			if err := Render(w, "login.html", map[string]any{"Error": "This account has been deactivated"}); err != nil {
+				h.log.ErrorContext(r.Context(), "render error", "error", err)
 			}
 			return
 		}
+		h.upgradePasswordHash(r.Context(), user, password)
 
-		token, err := h.SessionMgr.GenerateSession(r.Context(), user.ID)
+		token, err := h.SessionMgr.GenerateSession(r.Context(), user, r.UserAgent(), remoteAddr)
 		if err != nil {
 			http.Error(w, "Failed to create session", http.StatusInternalServerError)
 			return
@@ -342,6 +393,42 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// upgradePasswordHash rehashes the password user has just signed in with if
+// its stored hash was made with other settings than h.PasswordHasher's.
+// Failing to do so does not stop the sign-in; the next one tries again.
+func (h *Handler) upgradePasswordHash(ctx context.Context, user repository.AdminUser, password string) {
+	if !h.PasswordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := h.PasswordHasher.Hash(password)
+	if err != nil {
+		h.log.WarnContext(ctx, "failed to rehash admin password", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := h.Repo.RehashAdminUserPassword(ctx, user.ID, user.PasswordHash, hash); err != nil {
+		h.log.WarnContext(ctx, "failed to store rehashed admin password", "user_id", user.ID, "error", err)
+	}
+}
+
+// clientIP returns the address of the client making r. Proxy headers are
+// only trusted when the request comes from a loopback or private address
+// (i.e., a trusted reverse proxy).
+func clientIP(r *http.Request) string {
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+	if ip := net.ParseIP(remoteAddr); ip != nil && (ip.IsLoopback() || ip.IsPrivate()) {
+		if xri := r.Header.Get("X-Real-IP"); xri != "" {
+			remoteAddr = xri
+		} else if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			remoteAddr = strings.TrimSpace(first)
+		}
+	}
+	return remoteAddr
+}
+
 func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		cookie, err := r.Cookie("flagz_admin_session")
@@ -369,18 +456,29 @@ func (h *Handler) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projects, err := h.Repo.ListProjects(r.Context())
+	projects, err := h.Repo.ListProjectOverviews(r.Context(), time.Now().Add(-dashboardWindow))
 	if err != nil {
 		http.Error(w, "Failed to list projects", http.StatusInternalServerError)
 		return
 	}
 
+	var deleted []deletedProject
+	if isAdminRole(user.Role) {
+		deletedProjects, err := h.Repo.ListDeletedProjects(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to list deleted projects", http.StatusInternalServerError)
+			return
+		}
+		deleted = restorableProjects(deletedProjects, h.Service.ProjectRetention(), time.Now())
+	}
+
 	if err := Render(w, "dashboard.html", map[string]any{
-		"User":      user,
-		"Projects":  projects,
-		"CSRFToken": session.CSRFToken,
+		"User":            user,
+		"Projects":        projects,
+		"DeletedProjects": deleted,
+		"CSRFToken":       session.CSRFToken,
 	}); err != nil {
-		h.log.Error("render error", "error", err)
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
 	}
 }
 
@@ -440,12 +538,62 @@ func (h *Handler) handleProjectDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(pathParts) == 2 && (pathParts[1] == "delete" || pathParts[1] == "restore") {
+		h.handleProjectLifecycle(w, r, &project, user, pathParts[1])
+		return
+	}
+	// A deleted project is only visible on the dashboard, for restoring.
+	if project.DeletedAt != nil {
+		http.NotFound(w, r)
+		return
+	}
+
 	// Handle sub-resources
 	if len(pathParts) > 1 {
+		if pathParts[1] == "flags" && len(pathParts) == 3 && pathParts[2] == "import" && r.Method != http.MethodDelete {
+			h.handleFlagImport(w, r, &project, user, session.CSRFToken)
+			return
+		}
+		if pathParts[1] == "flags" && len(pathParts) == 3 && pathParts[2] == "bulk" && r.Method == http.MethodPost {
+			h.handleFlagBulk(w, r, &project, user)
+			return
+		}
+		if pathParts[1] == "flags" && len(pathParts) == 4 && pathParts[3] == "edit" {
+			h.handleFlagEditor(w, r, &project, user, session.CSRFToken, pathParts[2])
+			return
+		}
+		if pathParts[1] == "flags" && len(pathParts) == 4 && pathParts[3] == "copy" {
+			h.handleFlagCopy(w, r, &project, user, pathParts[2])
+			return
+		}
 		if pathParts[1] == "flags" {
 			h.handleFlags(w, r, &project, pathParts[2:])
 			return
 		}
+		if pathParts[1] == "proposals" {
+			h.handleProposals(w, r, &project, user, session.CSRFToken, pathParts[2:])
+			return
+		}
+		if pathParts[1] == "flag-defaults" && len(pathParts) == 2 {
+			h.handleFlagDefaults(w, r, &project, user)
+			return
+		}
+		if pathParts[1] == "flag-key-policy" && len(pathParts) == 2 {
+			h.handleFlagKeyPolicy(w, r, &project, user)
+			return
+		}
+		if pathParts[1] == "activity" && len(pathParts) == 2 {
+			h.handleProjectActivity(w, r, &project)
+			return
+		}
+		if pathParts[1] == "stale" && len(pathParts) == 2 {
+			h.handleStaleFlags(w, r, &project, user)
+			return
+		}
+		if pathParts[1] == "context-presets" && len(pathParts) == 2 {
+			h.handleContextPresets(w, r, &project, user, session.CSRFToken)
+			return
+		}
 	}
 
 	// GET /projects/{id} -> Show detail
@@ -454,15 +602,66 @@ func (h *Handler) handleProjectDetail(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to list flags", http.StatusInternalServerError)
 		return
 	}
+	tag := service.NormalizeTag(r.URL.Query().Get("tag"))
+	if tag != "" {
+		flags = service.FilterFlagsByTags(flags, []string{tag})
+	}
+
+	defaults, err := h.Service.GetFlagDefaults(r.Context(), projectID.String())
+	if err != nil {
+		http.Error(w, "Failed to load flag defaults", http.StatusInternalServerError)
+		return
+	}
+
+	keyPolicy, err := h.Service.GetFlagKeyPolicy(r.Context(), projectID.String())
+	if err != nil {
+		http.Error(w, "Failed to load flag key policy", http.StatusInternalServerError)
+		return
+	}
+
+	archived, err := h.Repo.ListArchivedFlags(r.Context(), projectID.String())
+	if err != nil {
+		http.Error(w, "Failed to list archived flags", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := h.Service.ListFlagStats(r.Context(), projectID.String())
+	if err != nil {
+		http.Error(w, "Failed to load flag stats", http.StatusInternalServerError)
+		return
+	}
 
 	if err := Render(w, "project.html", map[string]any{
-		"User":      user,
-		"Project":   project,
-		"Flags":     flags,
-		"CSRFToken": session.CSRFToken,
+		"User":          user,
+		"Project":       project,
+		"Flags":         flags,
+		"ArchivedFlags": archived,
+		"Tag":           tag,
+		"FlagStats":     stats,
+		"FlagDefaults":  defaults,
+		"DefaultRules":  string(defaults.Rules),
+		"FlagKeyPolicy": keyPolicy,
+		"FlagTemplates": h.Service.FlagTemplates(),
+		"CSRFToken":     session.CSRFToken,
 	}); err != nil {
-		h.log.Error("render error", "error", err)
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
+
+// templateParameters returns the parameters of the flag template named
+// templateName submitted with the create-flag form, as
+// "param.<template>.<name>" fields. Blank fields are left out so the
+// template's defaults apply.
+func templateParameters(r *http.Request, templateName string) map[string]any {
+	prefix := "param." + templateName + "."
+	params := make(map[string]any)
+	for field, values := range r.PostForm {
+		name, ok := strings.CutPrefix(field, prefix)
+		if ok && len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+			params[name] = values[0]
+		}
 	}
+	return params
 }
 
 func (h *Handler) handleFlags(w http.ResponseWriter, r *http.Request, project *repository.Project, subPath []string) {
@@ -490,17 +689,32 @@ func (h *Handler) handleFlags(w http.ResponseWriter, r *http.Request, project *r
 		desc := r.FormValue("description")
 		enabled := r.FormValue("enabled") == "on"
 
-		flag := repository.Flag{
-			ProjectID:   project.ID,
-			Key:         key,
-			Description: desc,
-			Enabled:     enabled,
-			Variants:    []byte("null"), // Default null variants
-			Rules:       []byte("[]"),   // Default empty rules
+		var err error
+		if templateName := r.FormValue("template"); templateName != "" {
+			_, err = h.Service.CreateFlagFromTemplate(r.Context(), service.FlagFromTemplateRequest{
+				ProjectID:   project.ID,
+				Template:    templateName,
+				Key:         key,
+				Description: desc,
+				Enabled:     &enabled,
+				Parameters:  templateParameters(r, templateName),
+			})
+		} else {
+			_, err = h.Service.CreateFlag(r.Context(), repository.Flag{
+				ProjectID:   project.ID,
+				Key:         key,
+				Description: desc,
+				Enabled:     enabled,
+				Variants:    []byte("null"), // Default null variants
+				Rules:       []byte("[]"),   // Default empty rules
+			})
 		}
-
-		_, err := h.Service.CreateFlag(r.Context(), flag)
 		if err != nil {
+			if errors.Is(err, service.ErrFlagKeyPolicyViolation) || errors.Is(err, service.ErrInvalidFlagTemplateParameters) ||
+				errors.Is(err, service.ErrFlagTemplateNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, "Failed to create flag: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -575,6 +789,343 @@ func (h *Handler) handleFlags(w http.ResponseWriter, r *http.Request, project *r
 	}
 }
 
+// handleFlagDefaults saves the project's flag defaults from the project page
+// form. A blank rules field clears the rules skeleton.
+func (h *Handler) handleFlagDefaults(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	defaults := repository.FlagDefaults{
+		DescriptionTemplate: strings.TrimSpace(r.FormValue("description_template")),
+	}
+	if rules := strings.TrimSpace(r.FormValue("rules")); rules != "" {
+		defaults.Rules = json.RawMessage(rules)
+	}
+
+	if _, err := h.Service.SetFlagDefaults(r.Context(), project.ID, defaults); err != nil {
+		if errors.Is(err, service.ErrInvalidRules) {
+			http.Error(w, "Invalid rules: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to save flag defaults", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s", project.ID), http.StatusFound)
+}
+
+// handleFlagKeyPolicy saves the project's flag key policy from the project
+// page form. Blank fields remove that requirement.
+func (h *Handler) handleFlagKeyPolicy(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	policy := repository.FlagKeyPolicy{
+		Pattern: strings.TrimSpace(r.FormValue("pattern")),
+		Prefix:  strings.TrimSpace(r.FormValue("prefix")),
+	}
+	if maxLength := strings.TrimSpace(r.FormValue("max_length")); maxLength != "" {
+		n, err := strconv.Atoi(maxLength)
+		if err != nil {
+			http.Error(w, "Invalid maximum length", http.StatusBadRequest)
+			return
+		}
+		policy.MaxLength = n
+	}
+
+	if _, err := h.Service.SetFlagKeyPolicy(r.Context(), project.ID, policy); err != nil {
+		if errors.Is(err, service.ErrInvalidFlagKeyPolicy) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to save flag key policy", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s", project.ID), http.StatusFound)
+}
+
+// handleProjectLifecycle soft-deletes or restores a project. Both are admin
+// only and redirect to the dashboard, where deleted projects are listed.
+//
+//	POST /projects/{id}/delete
+//	POST /projects/{id}/restore
+func (h *Handler) handleProjectLifecycle(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, action string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	if action == "delete" {
+		if err := h.Service.DeleteProject(r.Context(), project.ID); err != nil {
+			if errors.Is(err, service.ErrProjectNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "Failed to delete project", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.Service.RestoreProject(r.Context(), project.ID); err != nil {
+			switch {
+			case errors.Is(err, service.ErrProjectNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, service.ErrProjectNotDeleted), errors.Is(err, service.ErrProjectRestoreExpired):
+				http.Error(w, "Cannot restore project: "+err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Failed to restore project", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// deletedProject is a soft-deleted project as listed on the dashboard.
+type deletedProject struct {
+	repository.Project
+	// RestoreBy is when the project leaves the retention window.
+	RestoreBy time.Time
+	// Restorable reports whether RestoreBy is still in the future. Expired
+	// projects stay listed until the next purge removes them.
+	Restorable bool
+}
+
+func restorableProjects(projects []repository.Project, retention time.Duration, now time.Time) []deletedProject {
+	deleted := make([]deletedProject, 0, len(projects))
+	for _, p := range projects {
+		if p.DeletedAt == nil {
+			continue
+		}
+		restoreBy := p.DeletedAt.Add(retention)
+		deleted = append(deleted, deletedProject{Project: p, RestoreBy: restoreBy, Restorable: restoreBy.After(now)})
+	}
+	return deleted
+}
+
+// handleProposals lists a project's flag change proposals and lets admins
+// approve or reject pending ones. Approval is attributed to the signed-in
+// admin, so an admin cannot approve a change they proposed themselves.
+//
+//	GET  /projects/{id}/proposals
+//	POST /projects/{id}/proposals/{proposalID}/approve
+//	POST /projects/{id}/proposals/{proposalID}/reject
+func (h *Handler) handleProposals(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, csrfToken string, subPath []string) {
+	if len(subPath) == 0 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		proposals, err := h.Service.ListFlagProposals(r.Context(), project.ID, "", "")
+		if err != nil {
+			http.Error(w, "Failed to list proposals", http.StatusInternalServerError)
+			return
+		}
+
+		if err := Render(w, "proposals.html", map[string]any{
+			"User":      user,
+			"Project":   project,
+			"Proposals": proposals,
+			"CSRFToken": csrfToken,
+		}); err != nil {
+			h.log.ErrorContext(r.Context(), "render error", "error", err)
+		}
+		return
+	}
+
+	if len(subPath) != 2 || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	var err error
+	switch subPath[1] {
+	case "approve":
+		_, err = h.Service.ApproveFlagProposal(r.Context(), project.ID, subPath[0])
+	case "reject":
+		_, err = h.Service.RejectFlagProposal(r.Context(), project.ID, subPath[0])
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrProposalNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, service.ErrSelfApproval):
+		http.Error(w, "You cannot approve a change you proposed", http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrProposalNotPending):
+		http.Error(w, "Proposal has already been reviewed", http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to review proposal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s/proposals", project.ID), http.StatusFound)
+}
+
+// handleStaleFlags shows the project's stale flag report. Thresholds can be
+// narrowed or widened in days through the not_evaluated_days and
+// not_modified_days query parameters.
+func (h *Handler) handleStaleFlags(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var thresholds service.StaleThresholds
+	if days, err := strconv.Atoi(r.URL.Query().Get("not_evaluated_days")); err == nil && days > 0 {
+		thresholds.NotEvaluatedFor = time.Duration(days) * 24 * time.Hour
+	}
+	if days, err := strconv.Atoi(r.URL.Query().Get("not_modified_days")); err == nil && days > 0 {
+		thresholds.NotModifiedFor = time.Duration(days) * 24 * time.Hour
+	}
+
+	report, err := h.Service.StaleFlags(r.Context(), project.ID, thresholds)
+	if err != nil {
+		http.Error(w, "Failed to build stale flag report", http.StatusInternalServerError)
+		return
+	}
+
+	if err := Render(w, "stale.html", map[string]any{
+		"User":             user,
+		"Project":          project,
+		"Flags":            report.Flags,
+		"NotEvaluatedDays": int(report.Thresholds.NotEvaluatedFor / (24 * time.Hour)),
+		"NotModifiedDays":  int(report.Thresholds.NotModifiedFor / (24 * time.Hour)),
+	}); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
+
+// handleContextPresets lists a project's context presets and evaluates a
+// flag against one of them. Admins can also save and delete presets.
+//
+//	GET  /projects/{id}/context-presets[?flag=&preset=]
+//	POST /projects/{id}/context-presets (action=save|delete)
+func (h *Handler) handleContextPresets(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, csrfToken string) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		h.saveContextPreset(w, r, project, user)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	presets, err := h.Service.ListContextPresets(r.Context(), project.ID)
+	if err != nil {
+		http.Error(w, "Failed to list context presets", http.StatusInternalServerError)
+		return
+	}
+	flags, err := h.Repo.ListFlagsByProject(r.Context(), project.ID)
+	if err != nil {
+		http.Error(w, "Failed to list flags", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"User":      user,
+		"Project":   project,
+		"Presets":   presets,
+		"Flags":     flags,
+		"CSRFToken": csrfToken,
+	}
+	flagKey := r.URL.Query().Get("flag")
+	presetName := r.URL.Query().Get("preset")
+	if flagKey != "" && presetName != "" {
+		data["TestFlag"], data["TestPreset"] = flagKey, presetName
+		explanation, err := h.Service.ExplainFlag(r.Context(), project.ID, flagKey, core.EvaluationContext{}, presetName)
+		switch {
+		case errors.Is(err, service.ErrContextPresetNotFound):
+			data["TestError"] = "Context preset not found"
+		case errors.Is(err, service.ErrFlagNotFound):
+			data["TestError"] = "Flag not found"
+		case err != nil:
+			http.Error(w, "Failed to evaluate flag", http.StatusInternalServerError)
+			return
+		default:
+			data["TestResult"], data["TestSteps"] = explanation.ResolveResult, explanation.Steps
+		}
+	}
+
+	if err := Render(w, "context_presets.html", data); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
+
+// saveContextPreset handles the save and delete forms of the context presets
+// page. Attributes are entered as a JSON object.
+func (h *Handler) saveContextPreset(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser) {
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	var err error
+	switch r.FormValue("action") {
+	case "delete":
+		err = h.Service.DeleteContextPreset(r.Context(), project.ID, name)
+	case "save":
+		var attributes map[string]any
+		if raw := strings.TrimSpace(r.FormValue("attributes")); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &attributes); err != nil {
+				http.Error(w, "Attributes must be a JSON object", http.StatusBadRequest)
+				return
+			}
+		}
+		_, err = h.Service.PutContextPreset(r.Context(), repository.ContextPreset{
+			ProjectID:   project.ID,
+			Name:        name,
+			Description: strings.TrimSpace(r.FormValue("description")),
+			Context:     core.EvaluationContext{Attributes: attributes},
+		})
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidContextPresetName):
+		http.Error(w, "Preset names use letters, digits, spaces, '-', '_' and '.' (at most 100 characters)", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrContextPresetNotFound):
+		http.Error(w, "Context preset not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Failed to save context preset", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s/context-presets", project.ID), http.StatusFound)
+}
+
 func (h *Handler) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -599,7 +1150,7 @@ func (h *Handler) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
 	}
 
 	project, err := h.Repo.GetProject(r.Context(), projectID.String())
-	if err != nil {
+	if err != nil || project.DeletedAt != nil {
 		http.NotFound(w, r)
 		return
 	}
@@ -616,16 +1167,29 @@ func (h *Handler) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "POST" {
-		keyID, rawSecret, createErr := h.Repo.CreateAPIKeyForProject(r.Context(), projectID.String())
+		// Admin-scoped keys may also follow the all-projects event stream.
+		scope := repository.APIKeyScopeProject
+		createKey := h.Repo.CreateAPIKeyForProject
+		if r.FormValue("scope") == repository.APIKeyScopeAdmin {
+			scope = repository.APIKeyScopeAdmin
+			createKey = h.Repo.CreateAdminAPIKeyForProject
+		}
+		keyID, rawSecret, createErr := createKey(r.Context(), projectID.String())
 		if createErr != nil {
 			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
 			return
 		}
-		h.logAudit(r.Context(), session.AdminUserID, "api_key_create", projectID.String(), "", map[string]string{"api_key_id": keyID})
+		h.logAudit(r.Context(), session.AdminUserID, "api_key_create", projectID.String(), "", map[string]string{"api_key_id": keyID, "scope": scope})
+		sealed, sealKey, sealErr := sealAPIKeyToken(keyID + "." + rawSecret)
+		if sealErr != nil {
+			h.log.ErrorContext(r.Context(), "seal api key failed", "error", sealErr, "api_key_id", keyID)
+			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			return
+		}
 		if h.SessionMgr != nil {
-			h.SessionMgr.SetAPIKeyFlash(session.IDHash, projectID.String(), keyID, rawSecret)
+			h.SessionMgr.SetAPIKeyFlash(session.IDHash, projectID.String(), keyID, sealed)
 		}
-		http.Redirect(w, r, fmt.Sprintf("/api-keys/%s", projectID.String()), http.StatusFound)
+		http.Redirect(w, r, fmt.Sprintf("/api-keys/%s#key=%s", projectID.String(), sealKey), http.StatusFound)
 		return
 	}
 
@@ -634,26 +1198,33 @@ func (h *Handler) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
 		return
 	}
+	policy, err := h.Service.GetKeyRotationPolicy(r.Context(), projectID.String())
+	if err != nil {
+		http.Error(w, "Failed to load key rotation policy", http.StatusInternalServerError)
+		return
+	}
 
-	var newKeyID, newSecret string
+	var newKeyID, sealedToken string
 	if h.SessionMgr != nil {
-		if keyID, secret, ok := h.SessionMgr.PopAPIKeyFlash(session.IDHash, projectID.String()); ok {
+		if keyID, sealed, ok := h.SessionMgr.PopAPIKeyFlash(session.IDHash, projectID.String()); ok {
 			newKeyID = keyID
-			newSecret = secret
+			sealedToken = sealed
 			w.Header().Set("Cache-Control", "no-store")
 			w.Header().Set("Pragma", "no-cache")
 		}
 	}
 
 	if renderErr := Render(w, "api_keys.html", map[string]any{
-		"User":      user,
-		"Project":   project,
-		"APIKeys":   keys,
-		"NewKeyID":  newKeyID,
-		"NewSecret": newSecret,
-		"CSRFToken": session.CSRFToken,
+		"User":        user,
+		"Project":     project,
+		"APIKeys":     keys,
+		"NewKeyID":    newKeyID,
+		"SealedToken": sealedToken,
+		"CSRFToken":   session.CSRFToken,
+		"MaxAgeDays":  int(policy.MaxAge / (24 * time.Hour)),
+		"GraceDays":   int(policy.RevokeAfter / (24 * time.Hour)),
 	}); renderErr != nil {
-		h.log.Error("render error", "error", renderErr)
+		h.log.ErrorContext(r.Context(), "render error", "error", renderErr)
 	}
 }
 
@@ -694,6 +1265,52 @@ func (h *Handler) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("/api-keys/%s", projectID.String()), http.StatusFound)
 }
 
+// handleKeyRotationPolicy saves the project's API key rotation policy from
+// the API keys page. Durations are entered in whole days; a blank or zero
+// maximum age disables the policy.
+func (h *Handler) handleKeyRotationPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/api-keys/policy/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var days [2]int
+	for i, field := range []string{"max_age_days", "revoke_after_days"} {
+		value := strings.TrimSpace(r.FormValue(field))
+		if value == "" {
+			continue
+		}
+		days[i], err = strconv.Atoi(value)
+		if err != nil || days[i] < 0 {
+			http.Error(w, "Invalid "+strings.ReplaceAll(field, "_", " "), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.Service.SetKeyRotationPolicy(r.Context(), projectID.String(), repository.KeyRotationPolicy{
+		MaxAge:      time.Duration(days[0]) * 24 * time.Hour,
+		RevokeAfter: time.Duration(days[1]) * 24 * time.Hour,
+	}); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidKeyRotationPolicy):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrProjectNotFound):
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "Failed to save key rotation policy", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/api-keys/%s", projectID.String()), http.StatusFound)
+}
+
 func (h *Handler) handleAuditLog(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -741,7 +1358,7 @@ func (h *Handler) handleAuditLog(w http.ResponseWriter, r *http.Request) {
 		"Entries":   entries,
 		"CSRFToken": session.CSRFToken,
 	}); renderErr != nil {
-		h.log.Error("render error", "error", renderErr)
+		h.log.ErrorContext(r.Context(), "render error", "error", renderErr)
 	}
 }
 
@@ -773,7 +1390,7 @@ func (h *Handler) validateDoubleSubmitCSRF(r *http.Request) bool {
 func (h *Handler) logAudit(ctx context.Context, adminUserID, action, projectID, flagKey string, details any) {
 	entry, err := buildAuditEntry(adminUserID, action, projectID, flagKey, details)
 	if err != nil {
-		h.log.Error("audit log: marshal details",
+		h.log.ErrorContext(ctx, "audit log: marshal details",
 			"error", err,
 			"action", action,
 			"project_id", projectID,
@@ -787,7 +1404,7 @@ func (h *Handler) logAudit(ctx context.Context, adminUserID, action, projectID,
 	defer cancel()
 
 	if err := h.Repo.InsertAuditLog(writeCtx, entry); err != nil {
-		h.log.Error("audit log write failed",
+		h.log.ErrorContext(ctx, "audit log write failed",
 			"error", err,
 			"action", action,
 			"project_id", projectID,
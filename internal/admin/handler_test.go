@@ -3,14 +3,18 @@ package admin
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
 )
 
 func TestRenderAPIKeysTemplate(t *testing.T) {
@@ -56,25 +60,90 @@ func TestRenderAPIKeysTemplate_ViewerRole(t *testing.T) {
 func TestRenderAPIKeysTemplate_NewSecret(t *testing.T) {
 	var buf bytes.Buffer
 	err := Render(&buf, "api_keys.html", map[string]any{
-		"User":      repository.AdminUser{Username: "admin", Role: "admin"},
-		"Project":   repository.Project{ID: "proj-1", Name: "Test Project"},
-		"APIKeys":   []repository.APIKeyMeta{},
-		"NewKeyID":  "abc123",
-		"NewSecret": "secret456",
-		"CSRFToken": "token123",
+		"User":        repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project":     repository.Project{ID: "proj-1", Name: "Test Project"},
+		"APIKeys":     []repository.APIKeyMeta{},
+		"NewKeyID":    "abc123",
+		"SealedToken": "c2VhbGVk",
+		"CSRFToken":   "token123",
 	})
 	if err != nil {
 		t.Fatalf("Render() error = %v", err)
 	}
 	out := buf.String()
-	if !strings.Contains(out, "abc123.secret456") {
-		t.Error("expected full token in output")
+	if !strings.Contains(out, `data-sealed="c2VhbGVk"`) {
+		t.Error("expected sealed token in output")
+	}
+	if !strings.Contains(out, "crypto.subtle.decrypt") {
+		t.Error("expected client-side decryption script")
 	}
 	if !strings.Contains(out, "will not be shown again") {
 		t.Error("expected warning about secret visibility")
 	}
 }
 
+func TestRenderAPIKeysTemplate_RotationPolicy(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rotateBy := created.Add(90 * 24 * time.Hour)
+	revokeAt := rotateBy.Add(7 * 24 * time.Hour)
+	lastUsed := created.Add(24 * time.Hour)
+	var buf bytes.Buffer
+	err := Render(&buf, "api_keys.html", map[string]any{
+		"User":    repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"APIKeys": []repository.APIKeyMeta{
+			{ID: "key-1", CreatedAt: created, RotateBy: &rotateBy, RevokeAt: &revokeAt, Overdue: true, LastUsedAt: &lastUsed},
+			{ID: "key-2", CreatedAt: created},
+		},
+		"CSRFToken":  "token123",
+		"MaxAgeDays": 90,
+		"GraceDays":  7,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"due for rotation 90 days after creation",
+		"revoked automatically 7 days later",
+		`name="max_age_days" value="90"`,
+		rotateBy.Format(time.RFC3339),
+		"Overdue",
+		"Revoked " + revokeAt.Format(time.RFC3339),
+		lastUsed.Format(time.RFC3339),
+		"Never",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in api keys page", want)
+		}
+	}
+}
+
+func TestRenderAPIKeysTemplate_Scope(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "api_keys.html", map[string]any{
+		"User":    repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"APIKeys": []repository.APIKeyMeta{
+			{ID: "key-1", Scope: repository.APIKeyScopeAdmin, CreatedAt: time.Now()},
+		},
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`name="scope" value="admin"`,
+		"Create Admin Key",
+		">Admin</span>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in api keys page", want)
+		}
+	}
+}
+
 func TestRenderAuditLogTemplate(t *testing.T) {
 	var buf bytes.Buffer
 	err := Render(&buf, "audit_log.html", map[string]any{
@@ -140,6 +209,371 @@ func TestRenderProjectTemplate_ViewerRole(t *testing.T) {
 	}
 }
 
+func TestRenderProjectTemplate_Tags(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":      repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Project":   repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":     []repository.Flag{{Key: "checkout-v2", Tags: []string{"checkout", "team:payments"}}},
+		"Tag":       "checkout",
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `href="/projects/proj-1?tag=team%3apayments"`) {
+		t.Error("expected a chip linking to the team:payments filter")
+	}
+	if !strings.Contains(out, "Showing flags tagged") || !strings.Contains(out, "Show all flags") {
+		t.Error("expected the active tag filter with a way to clear it")
+	}
+}
+
+func TestRenderProjectTemplate_Ownership(t *testing.T) {
+	expiresAt := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":      repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Project":   repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":     []repository.Flag{{Key: "spring-sale", Owner: "growth@example.com", ExpiresAt: &expiresAt}},
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Owner: growth@example.com") || !strings.Contains(out, "Expires 2026-03-31") {
+		t.Errorf("expected owner and expiry date in the flag row, got %s", out)
+	}
+}
+
+func TestParseExpiryDate(t *testing.T) {
+	current := time.Date(2026, 3, 31, 12, 30, 0, 0, time.UTC)
+
+	got, err := parseExpiryDate("2026-03-31", &current)
+	if err != nil || got != &current {
+		t.Fatalf("unchanged date = %v, %v; want the current expiry kept", got, err)
+	}
+	got, err = parseExpiryDate("2026-04-30", &current)
+	if err != nil || !got.Equal(time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("new date = %v, %v; want start of 2026-04-30 UTC", got, err)
+	}
+	if got, err := parseExpiryDate("  ", &current); err != nil || got != nil {
+		t.Fatalf("empty date = %v, %v; want no expiry", got, err)
+	}
+	if _, err := parseExpiryDate("31/03/2026", nil); err == nil {
+		t.Fatal("expected an error for a malformed date")
+	}
+}
+
+func TestRenderProjectTemplate_FlagDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":         repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project":      repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":        []repository.Flag{},
+		"FlagDefaults": repository.FlagDefaults{DescriptionTemplate: "Owned by checkout: {{key}}"},
+		"DefaultRules": `[{"attribute":"user_id","operator":"percentage","value":0}]`,
+		"CSRFToken":    "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `action="/projects/proj-1/flag-defaults"`) {
+		t.Error("expected flag defaults form")
+	}
+	if !strings.Contains(out, `value="Owned by checkout: {{key}}"`) {
+		t.Error("expected current description template in form")
+	}
+	if !strings.Contains(out, "&#34;percentage&#34;") {
+		t.Error("expected current rules skeleton in form")
+	}
+}
+
+func TestRenderProjectTemplate_FlagTemplates(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":          repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project":       repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":         []repository.Flag{},
+		"FlagTemplates": (&service.Service{}).FlagTemplates(),
+		"CSRFToken":     "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<option value="kill-switch" data-enabled="true">Ops kill switch</option>`) {
+		t.Error("expected the kill switch in the template picker")
+	}
+	if !strings.Contains(out, `name="param.percentage-rollout.percentage" type="text" placeholder="10"`) {
+		t.Error("expected the rollout percentage field with its default")
+	}
+}
+
+func TestRenderProjectTemplate_FlagKeyPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":          repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project":       repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":         []repository.Flag{},
+		"FlagKeyPolicy": repository.FlagKeyPolicy{Prefix: "checkout-", MaxLength: 40},
+		"CSRFToken":     "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `action="/projects/proj-1/flag-key-policy"`) {
+		t.Error("expected flag key policy form")
+	}
+	if !strings.Contains(out, `name="max_length" type="number" min="0" value="40"`) {
+		t.Error("expected current maximum length in form")
+	}
+	if !strings.Contains(out, "Project policy: starts with <code>checkout-</code>; at most 40 characters.") {
+		t.Error("expected policy hint on the create flag form")
+	}
+}
+
+func TestRenderContextPresetsTemplate(t *testing.T) {
+	ruleIndex := 0
+	steps := []core.TraceStep{{
+		Kind:      core.StepRule,
+		RuleIndex: &ruleIndex,
+		Rule:      &core.Rule{Operator: core.OperatorSegment, Value: "europe"},
+		Matched:   true,
+		Detail:    `the context is in segment "europe"`,
+		Steps: []core.TraceStep{{
+			Kind:      core.StepRule,
+			RuleIndex: &ruleIndex,
+			Rule:      &core.Rule{Attribute: "country", Operator: core.OperatorIn, Value: []any{"FR", "DE"}},
+			Matched:   true,
+			Detail:    `"FR" is in ["FR" "DE"]`,
+		}},
+	}}
+	var buf bytes.Buffer
+	err := Render(&buf, "context_presets.html", map[string]any{
+		"User":    repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Presets": []repository.ContextPreset{
+			{Name: "EU free-tier user", Context: core.EvaluationContext{Attributes: map[string]any{"country": "FR"}}},
+		},
+		"Flags":      []repository.Flag{{Key: "checkout"}},
+		"TestFlag":   "checkout",
+		"TestPreset": "EU free-tier user",
+		"TestResult": service.ResolveResult{Key: "checkout", Value: true, Reason: core.ReasonRuleMatch, RuleIndex: &ruleIndex},
+		"TestSteps":  steps,
+		"CSRFToken":  "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "{&#34;country&#34;:&#34;FR&#34;}") {
+		t.Error("expected preset attributes as JSON")
+	}
+	if !strings.Contains(out, `<option value="EU free-tier user" selected>`) {
+		t.Error("expected tested preset to stay selected")
+	}
+	if !strings.Contains(out, "RULE_MATCH, rule 0") {
+		t.Error("expected evaluation reason and matched rule")
+	}
+	if !strings.Contains(out, "segment &#34;europe&#34;</span>: the context is in segment &#34;europe&#34;") {
+		t.Error("expected the matched segment rule in the trace")
+	}
+	if !strings.Contains(out, "country in [&#34;FR&#34;,&#34;DE&#34;]") {
+		t.Error("expected the segment's rules nested in the trace")
+	}
+	if !strings.Contains(out, `name="action" value="delete"`) {
+		t.Error("expected delete control for admin")
+	}
+}
+
+func TestRenderProposalsTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "proposals.html", map[string]any{
+		"User":    repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Proposals": []repository.FlagProposal{
+			{ID: "p1", FlagKey: "checkout", Action: "update", Flag: []byte(`{"enabled":true}`), Status: "pending", ProposedByAPIKeyID: "ci"},
+			{ID: "p2", FlagKey: "legacy", Action: "delete", Status: "rejected", ProposedByAdminUserID: "u1"},
+		},
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `action="/projects/proj-1/proposals/p1/approve"`) {
+		t.Error("expected approve control for pending proposal")
+	}
+	if strings.Contains(out, `/proposals/p2/approve`) {
+		t.Error("reviewed proposal should not be approvable")
+	}
+	if !strings.Contains(out, "{&#34;enabled&#34;:true}") {
+		t.Error("expected proposed flag body")
+	}
+}
+
+func TestRenderDashboardTemplate_DeletedProjects(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-48 * time.Hour)
+	deleted := restorableProjects([]repository.Project{
+		{ID: "proj-1", Name: "Recent", DeletedAt: &recent},
+		{ID: "proj-2", Name: "Old", DeletedAt: &old},
+	}, 24*time.Hour, now)
+	if len(deleted) != 2 || !deleted[0].Restorable || deleted[1].Restorable {
+		t.Fatalf("restorableProjects() = %+v", deleted)
+	}
+
+	var buf bytes.Buffer
+	err := Render(&buf, "dashboard.html", map[string]any{
+		"User":            repository.AdminUser{Username: "admin", Role: "admin"},
+		"Projects":        []repository.Project{},
+		"DeletedProjects": deleted,
+		"CSRFToken":       "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `action="/projects/proj-1/restore"`) {
+		t.Error("expected restore control for project within retention")
+	}
+	if strings.Contains(out, `action="/projects/proj-2/restore"`) {
+		t.Error("expired project should not be restorable")
+	}
+	if !strings.Contains(out, "2026-03-02T11:00:00Z") {
+		t.Error("expected restore deadline")
+	}
+}
+
+func TestRenderProjectTemplate_FlagStats(t *testing.T) {
+	lastEvaluated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := Render(&buf, "project.html", map[string]any{
+		"User":    repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags":   []repository.Flag{{Key: "dark-mode"}, {Key: "stale"}},
+		"FlagStats": map[string]repository.FlagStats{
+			"dark-mode": {FlagKey: "dark-mode", Evaluations: 1234, LastEvaluatedAt: &lastEvaluated},
+		},
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, ">1234</td>") || !strings.Contains(out, "2026-03-01T12:00:00Z") {
+		t.Error("expected evaluation count and last evaluated time")
+	}
+	if !strings.Contains(out, "Never") {
+		t.Error("expected never-evaluated flag to be marked")
+	}
+}
+
+func TestRenderProjectTemplate_DeleteControl(t *testing.T) {
+	for _, role := range []string{"admin", "viewer"} {
+		var buf bytes.Buffer
+		err := Render(&buf, "project.html", map[string]any{
+			"User":      repository.AdminUser{Username: role, Role: role},
+			"Project":   repository.Project{ID: "proj-1", Name: "Test Project"},
+			"Flags":     []repository.Flag{},
+			"CSRFToken": "token123",
+		})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		hasControl := strings.Contains(buf.String(), `action="/projects/proj-1/delete"`)
+		if hasControl != (role == "admin") {
+			t.Errorf("role %s: delete control shown = %v", role, hasControl)
+		}
+	}
+}
+
+func TestRenderProjectTemplate_BulkActions(t *testing.T) {
+	for _, role := range []string{"admin", "viewer"} {
+		var buf bytes.Buffer
+		err := Render(&buf, "project.html", map[string]any{
+			"User":      repository.AdminUser{Username: role, Role: role},
+			"Project":   repository.Project{ID: "proj-1", Name: "Test Project"},
+			"Flags":     []repository.Flag{{Key: "dark-mode"}},
+			"Tag":       "beta",
+			"CSRFToken": "token123",
+		})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		out := buf.String()
+		hasForm := strings.Contains(out, `action="/projects/proj-1/flags/bulk"`)
+		hasCheckbox := strings.Contains(out, `name="key" value="dark-mode" form="bulk-flags-form"`)
+		if hasForm != (role == "admin") || hasCheckbox != (role == "admin") {
+			t.Errorf("role %s: bulk form shown = %v, flag checkbox shown = %v", role, hasForm, hasCheckbox)
+		}
+		if role == "admin" && !strings.Contains(out, `name="tag" value="beta"`) {
+			t.Error("expected the bulk form to keep the tag filter")
+		}
+	}
+}
+
+func TestRenderProjectTemplate_ArchivedFlags(t *testing.T) {
+	archivedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, role := range []string{"admin", "viewer"} {
+		var buf bytes.Buffer
+		err := Render(&buf, "project.html", map[string]any{
+			"User":          repository.AdminUser{Username: role, Role: role},
+			"Project":       repository.Project{ID: "proj-1", Name: "Test Project"},
+			"ArchivedFlags": []repository.Flag{{Key: "old-banner", ArchivedAt: &archivedAt}},
+			"CSRFToken":     "token123",
+		})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, "old-banner") || !strings.Contains(out, "2026-01-02T03:04:05Z") {
+			t.Errorf("role %s: expected the archived flag to be listed with when it was archived", role)
+		}
+		hasRestore := strings.Contains(out, `name="key" value="old-banner" form="restore-flags-form"`)
+		if hasRestore != (role == "admin") {
+			t.Errorf("role %s: restore control shown = %v", role, hasRestore)
+		}
+	}
+}
+
+func TestBulkFlagUpdate(t *testing.T) {
+	form := func(values url.Values) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/projects/proj-1/flags/bulk", strings.NewReader(values.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm() error = %v", err)
+		}
+		return r
+	}
+
+	update, err := bulkFlagUpdate(form(url.Values{"key": {"a", "b"}, "action": {"disable"}}), "proj-1")
+	if err != nil || update.ProjectID != "proj-1" || !reflect.DeepEqual(update.Keys, []string{"a", "b"}) || update.Enabled == nil || *update.Enabled {
+		t.Fatalf("bulkFlagUpdate(disable) = %+v, %v, want a and b disabled", update, err)
+	}
+	update, err = bulkFlagUpdate(form(url.Values{"key": {"a"}, "action": {"tag"}, "tags": {"beta, ops"}}), "proj-1")
+	if err != nil || !reflect.DeepEqual(update.AddTags, []string{"beta", "ops"}) || update.Enabled != nil {
+		t.Fatalf("bulkFlagUpdate(tag) = %+v, %v, want beta and ops added", update, err)
+	}
+	update, err = bulkFlagUpdate(form(url.Values{"key": {"a"}, "action": {"archive"}}), "proj-1")
+	if err != nil || !update.Archive {
+		t.Fatalf("bulkFlagUpdate(archive) = %+v, %v, want a archived", update, err)
+	}
+	update, err = bulkFlagUpdate(form(url.Values{"key": {"a"}, "action": {"restore"}}), "proj-1")
+	if err != nil || !update.Restore {
+		t.Fatalf("bulkFlagUpdate(restore) = %+v, %v, want a restored", update, err)
+	}
+	if _, err := bulkFlagUpdate(form(url.Values{"key": {"a"}, "action": {"delete"}}), "proj-1"); err == nil {
+		t.Fatal("bulkFlagUpdate(delete) should reject an unknown action")
+	}
+}
+
 func TestIsAdminRole(t *testing.T) {
 	tests := []struct {
 		name string
@@ -313,3 +747,210 @@ func TestHandleAuditLog_InvalidProjectID(t *testing.T) {
 		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNotFound)
 	}
 }
+
+func TestRenderStaleTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "stale.html", map[string]any{
+		"User":    repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flags": []service.StaleFlag{
+			{
+				Key:              "legacy-banner",
+				Reasons:          []string{service.StaleReasonNotModified, service.StaleReasonAlwaysOn},
+				Evaluations:      42,
+				UpdatedAt:        time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				CleanupCandidate: true,
+				Suggestion:       "Always true and unchanged for 90 days; replace checks with true and delete it.",
+			},
+		},
+		"NotEvaluatedDays": 30,
+		"NotModifiedDays":  90,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"legacy-banner", "always_on", "cleanup candidate", "replace checks with true", `name="not_modified_days" value="90"`, "Never"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in stale report", want)
+		}
+	}
+}
+
+func TestRenderFlagEditorTemplate_Stats(t *testing.T) {
+	lastEvaluated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := map[string]any{
+		"User":    repository.AdminUser{Username: "viewer", Role: "viewer"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flag":    repository.Flag{Key: "checkout", Enabled: true},
+		"Stats":   repository.FlagStats{FlagKey: "checkout", Evaluations: 800, TrueEvaluations: 203, LastEvaluatedAt: &lastEvaluated},
+		"Draft":   flagDraft{},
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, "flag_editor.html", data); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "25.4% of 800 evaluations") || !strings.Contains(out, "2026-03-01T12:00:00Z") {
+		t.Error("expected true ratio, evaluation count and last evaluated time")
+	}
+
+	data["Stats"] = repository.FlagStats{FlagKey: "checkout"}
+	buf.Reset()
+	if err := Render(&buf, "flag_editor.html", data); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Not evaluated yet.") {
+		t.Error("expected never-evaluated flag to be marked")
+	}
+}
+
+func TestRenderFlagEditorTemplate(t *testing.T) {
+	ruleIndex := 1
+	data := map[string]any{
+		"User":    repository.AdminUser{Username: "admin", Role: "admin"},
+		"Project": repository.Project{ID: "proj-1", Name: "Test Project"},
+		"Flag":    repository.Flag{Key: "checkout", Enabled: true},
+		"Presets": []repository.ContextPreset{{Name: "EU user"}},
+		"Projects": []repository.Project{
+			{ID: "proj-1", Name: "Test Project"},
+			{ID: "proj-2", Name: "Production"},
+		},
+		"Draft": flagDraft{
+			Rules:    `[{"attribute": "country", "operator": "equals", "value": "FR"}]`,
+			Preset:   "EU user",
+			Operator: "in",
+		},
+		"Errors":    []string{"Rule 0: unknown operator \"like\""},
+		"Preview":   service.ResolveResult{Key: "checkout", Value: true, Reason: core.ReasonRuleMatch, RuleIndex: &ruleIndex},
+		"CSRFToken": "token123",
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, "flag_editor.html", data); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"&#34;attribute&#34;: &#34;country&#34;",
+		"Rule 0: unknown operator &#34;like&#34;",
+		`<option value="in" selected>`,
+		`<option value="EU user" selected>`,
+		"RULE_MATCH, rule 1",
+		`name="action" value="save"`,
+		`action="/projects/proj-1/flags/checkout/copy"`,
+		`<option value="proj-2">Production</option>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in editor output", want)
+		}
+	}
+
+	data["User"] = repository.AdminUser{Username: "viewer", Role: "viewer"}
+	buf.Reset()
+	if err := Render(&buf, "flag_editor.html", data); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `value="save"`) {
+		t.Error("viewer should not see the save control")
+	}
+	if strings.Contains(buf.String(), "/copy") {
+		t.Error("viewer should not see the copy form")
+	}
+	if !strings.Contains(buf.String(), `value="preview"`) {
+		t.Error("viewer should still be able to preview")
+	}
+}
+
+func TestRuleFromForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		value    string
+		want     any
+		wantErr  string
+	}{
+		{name: "equals string", operator: "equals", value: " FR ", want: "FR"},
+		{name: "equals number", operator: "equals", value: "42", want: float64(42)},
+		{name: "equals quoted number", operator: "equals", value: `"42"`, want: "42"},
+		{name: "equals boolean", operator: "equals", value: "true", want: true},
+		{name: "in list", operator: "in", value: "FR, DE,,3", want: []any{"FR", "DE", float64(3)}},
+		{name: "in empty", operator: "in", value: " , ", wantErr: "non-empty list"},
+		{name: "percentage", operator: "percentage", value: "25.5", want: 25.5},
+		{name: "percentage out of range", operator: "percentage", value: "101", wantErr: "between 0 and 100"},
+		{name: "percentage not a number", operator: "percentage", value: "half", wantErr: "between 0 and 100"},
+		{name: "unknown operator", operator: "like", value: "x", wantErr: "unknown operator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ruleFromForm(" country ", tt.operator, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ruleFromForm() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ruleFromForm() error = %v", err)
+			}
+			if rule.Attribute != "country" {
+				t.Errorf("Attribute = %q, want trimmed", rule.Attribute)
+			}
+			if !reflect.DeepEqual(rule.Value, tt.want) {
+				t.Errorf("Value = %#v, want %#v", rule.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendRule(t *testing.T) {
+	rule := core.Rule{Attribute: "plan", Operator: core.OperatorEquals, Value: "pro"}
+
+	got, err := appendRule(`[{"attribute":"country","operator":"equals","value":"FR"}]`, rule)
+	if err != nil {
+		t.Fatalf("appendRule() error = %v", err)
+	}
+	var rules []core.Rule
+	if err := json.Unmarshal([]byte(got), &rules); err != nil {
+		t.Fatalf("appendRule() returned invalid JSON: %v", err)
+	}
+	if len(rules) != 2 || rules[1].Attribute != "plan" {
+		t.Fatalf("rules = %+v, want the new rule appended", rules)
+	}
+	if !strings.Contains(got, "\n  {") {
+		t.Errorf("appendRule() = %q, want indented output", got)
+	}
+
+	if got, err := appendRule("  ", rule); err != nil || !strings.Contains(got, `"plan"`) {
+		t.Fatalf("appendRule(blank) = %q, %v", got, err)
+	}
+	if _, err := appendRule(`{"not": "an array"}`, rule); err == nil {
+		t.Fatal("appendRule() accepted rules that are not an array")
+	}
+}
+
+func TestRenderSessionsTemplate(t *testing.T) {
+	now := time.Now()
+	var buf bytes.Buffer
+	err := Render(&buf, "sessions.html", map[string]any{
+		"User": repository.AdminUser{Username: "alice", Role: "viewer"},
+		"Sessions": []repository.AdminSession{
+			{ID: "current-id", UserAgent: "Firefox", RemoteAddr: "100.64.0.1", CreatedAt: now, LastSeenAt: now},
+			{ID: "other-id", UserAgent: "Safari", RemoteAddr: "100.64.0.2", CreatedAt: now, LastSeenAt: now},
+		},
+		"CurrentID": "current-id",
+		"CSRFToken": "token123",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Firefox", "100.64.0.2", "This session", `name="session_id" value="other-id"`, "Sign out all other sessions"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(out, `value="current-id"`) {
+		t.Error("current session should not have a sign-out button")
+	}
+}
@@ -0,0 +1,333 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	// maxImportBytes caps the size of an uploaded or pasted import file.
+	maxImportBytes = 1 << 20
+	// maxImportRows caps the number of flags created by a single import.
+	maxImportRows = 1000
+)
+
+var (
+	importKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	importColumns = []string{"key", "description", "enabled", "tags"}
+)
+
+// importRow is a single parsed line of a flag import file together with any
+// validation problems found for it. Line is the 1-based line number in the
+// source file so errors can be matched back to the spreadsheet.
+type importRow struct {
+	Line        int
+	Key         string
+	Description string
+	Enabled     bool
+	Tags        []string
+	Errors      []string
+}
+
+// importResult is the outcome of parsing and validating an import file.
+type importResult struct {
+	Rows      []importRow
+	ErrorRows int
+}
+
+// HasErrors reports whether any row failed validation.
+func (r importResult) HasErrors() bool {
+	return r.ErrorRows > 0
+}
+
+// parseFlagImport reads a CSV or TSV file with a header row naming the
+// key, description, enabled and tags columns (in any order; only key is
+// required). The delimiter is detected from the header line. Rows are
+// validated against each other and against existingKeys; per-row problems
+// are recorded on the row rather than returned as an error. An error is only
+// returned when the file as a whole cannot be read.
+func parseFlagImport(r io.Reader, existingKeys map[string]bool) (importResult, error) {
+	br := bufio.NewReader(r)
+	peek, err := br.Peek(br.Size())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return importResult{}, fmt.Errorf("read import: %w", err)
+	}
+	firstLine, _, _ := strings.Cut(string(peek), "\n")
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if strings.Contains(firstLine, "\t") {
+		reader.Comma = '\t'
+	}
+
+	columns, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return importResult{}, errors.New("import file is empty")
+	}
+	if err != nil {
+		return importResult{}, fmt.Errorf("read header: %w", err)
+	}
+	index, err := importColumnIndex(columns)
+	if err != nil {
+		return importResult{}, err
+	}
+
+	var result importResult
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return importResult{}, fmt.Errorf("read import: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(result.Rows) == maxImportRows {
+			return importResult{}, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		row := importRow{Line: line}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row.Key = field("key")
+		row.Description = field("description")
+		switch {
+		case row.Key == "":
+			row.Errors = append(row.Errors, "key is required")
+		case !importKeyPattern.MatchString(row.Key):
+			row.Errors = append(row.Errors, "key may only contain letters, digits, underscores, and dashes")
+		case existingKeys[row.Key]:
+			row.Errors = append(row.Errors, "flag already exists in this project")
+		default:
+			if first, dup := seen[row.Key]; dup {
+				row.Errors = append(row.Errors, fmt.Sprintf("duplicate of line %d", first))
+			} else {
+				seen[row.Key] = line
+			}
+		}
+
+		enabled, ok := parseImportBool(field("enabled"))
+		if !ok {
+			row.Errors = append(row.Errors, fmt.Sprintf("enabled must be true or false, got %q", field("enabled")))
+		}
+		row.Enabled = enabled
+		row.Tags = parseImportTags(field("tags"))
+
+		if len(row.Errors) > 0 {
+			result.ErrorRows++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if len(result.Rows) == 0 {
+		return importResult{}, errors.New("import file has no flag rows")
+	}
+	return result, nil
+}
+
+func importColumnIndex(columns []string) (map[string]int, error) {
+	index := make(map[string]int, len(columns))
+	for i, c := range columns {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(c, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, want := range importColumns {
+			if name == want {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q (expected %s)", name, strings.Join(importColumns, ", "))
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		index[name] = i
+	}
+	if _, ok := index["key"]; !ok {
+		return nil, errors.New(`header row must include a "key" column`)
+	}
+	return index, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportBool accepts the spellings spreadsheets commonly produce. An
+// empty cell means disabled.
+func parseImportBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "", "false", "no", "n", "off", "0":
+		return false, true
+	case "true", "yes", "y", "on", "1":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// parseImportTags splits a tags cell on commas, semicolons or pipes so the
+// column survives being exported from tools that use commas as the delimiter.
+func parseImportTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	})
+	tags := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if t := strings.TrimSpace(f); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// flags converts validated rows into repository flags for projectID.
+func (r importResult) flags(projectID string) []repository.Flag {
+	flags := make([]repository.Flag, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		flags = append(flags, repository.Flag{
+			ProjectID:   projectID,
+			Key:         row.Key,
+			Description: row.Description,
+			Enabled:     row.Enabled,
+			Tags:        row.Tags,
+			Variants:    []byte("null"),
+			Rules:       []byte("[]"),
+		})
+	}
+	return flags
+}
+
+// readImportPayload returns the import file contents from either the
+// uploaded "file" field or the pasted "data" field. The pasted field is also
+// how the preview page round-trips the data back for confirmation.
+func readImportPayload(r *http.Request) (string, error) {
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		b, err := io.ReadAll(io.LimitReader(file, maxImportBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("read upload: %w", err)
+		}
+		if len(b) > maxImportBytes {
+			return "", fmt.Errorf("import file exceeds %d bytes", maxImportBytes)
+		}
+		if len(strings.TrimSpace(string(b))) > 0 {
+			return string(b), nil
+		}
+	}
+	data := r.FormValue("data")
+	if len(data) > maxImportBytes {
+		return "", fmt.Errorf("import data exceeds %d bytes", maxImportBytes)
+	}
+	if strings.TrimSpace(data) == "" {
+		return "", errors.New("choose a file or paste CSV data to import")
+	}
+	return data, nil
+}
+
+// handleFlagImport serves GET/POST /projects/{id}/flags/import. A POST with
+// step=preview validates the file and renders every row with its errors;
+// step=confirm re-validates the same data and creates all flags in one
+// transaction, so nothing is written unless every row is valid.
+func (h *Handler) handleFlagImport(w http.ResponseWriter, r *http.Request, project *repository.Project, user repository.AdminUser, csrfToken string) {
+	if !isAdminRole(user.Role) {
+		http.Error(w, "Forbidden: admin role required", http.StatusForbidden)
+		return
+	}
+
+	render := func(data map[string]any) {
+		data["User"] = user
+		data["Project"] = project
+		data["CSRFToken"] = csrfToken
+		if err := Render(w, "import.html", data); err != nil {
+			h.log.ErrorContext(r.Context(), "render error", "error", err)
+		}
+	}
+
+	if r.Method == http.MethodGet {
+		render(map[string]any{})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := readImportPayload(r)
+	if err != nil {
+		render(map[string]any{"Error": err.Error()})
+		return
+	}
+
+	existing, err := h.Repo.ListFlagsByProject(r.Context(), project.ID)
+	if err != nil {
+		http.Error(w, "Failed to list flags", http.StatusInternalServerError)
+		return
+	}
+	// Archived flags keep their keys, so importing one would fail too.
+	archived, err := h.Repo.ListArchivedFlags(r.Context(), project.ID)
+	if err != nil {
+		http.Error(w, "Failed to list flags", http.StatusInternalServerError)
+		return
+	}
+	existing = append(existing, archived...)
+	existingKeys := make(map[string]bool, len(existing))
+	for _, f := range existing {
+		existingKeys[f.Key] = true
+	}
+
+	result, err := parseFlagImport(strings.NewReader(payload), existingKeys)
+	if err != nil {
+		render(map[string]any{"Error": err.Error(), "Data": payload})
+		return
+	}
+
+	if r.FormValue("step") != "confirm" || result.HasErrors() {
+		render(map[string]any{"Result": result, "Data": payload})
+		return
+	}
+
+	created, err := h.Service.CreateFlags(r.Context(), result.flags(project.ID))
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "flag import failed", "error", err, "project_id", project.ID)
+		render(map[string]any{
+			"Error":  "Import failed; no flags were created: " + err.Error(),
+			"Result": result,
+			"Data":   payload,
+		})
+		return
+	}
+
+	h.logAudit(r.Context(), user.ID, "flag_import", project.ID, "", map[string]int{"count": len(created)})
+
+	http.Redirect(w, r, fmt.Sprintf("/projects/%s", project.ID), http.StatusFound)
+}
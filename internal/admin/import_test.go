@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+func TestParseFlagImport_CSV(t *testing.T) {
+	input := "key,description,enabled,tags\n" +
+		"new-ui,New UI rollout,true,\"web,beta\"\n" +
+		"\n" +
+		"dark-mode,,no,\n"
+
+	result, err := parseFlagImport(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("parseFlagImport() error = %v", err)
+	}
+	if result.HasErrors() {
+		t.Fatalf("parseFlagImport() rows have errors: %+v", result.Rows)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("len(Rows) = %d, want 2", len(result.Rows))
+	}
+
+	first := result.Rows[0]
+	if first.Line != 2 || first.Key != "new-ui" || first.Description != "New UI rollout" || !first.Enabled {
+		t.Fatalf("Rows[0] = %+v", first)
+	}
+	if len(first.Tags) != 2 || first.Tags[0] != "web" || first.Tags[1] != "beta" {
+		t.Fatalf("Rows[0].Tags = %v, want [web beta]", first.Tags)
+	}
+	if second := result.Rows[1]; second.Line != 4 || second.Key != "dark-mode" || second.Enabled {
+		t.Fatalf("Rows[1] = %+v", second)
+	}
+
+	flags := result.flags("proj-1")
+	if len(flags) != 2 || flags[0].ProjectID != "proj-1" || string(flags[0].Rules) != "[]" || !slices.Equal(flags[0].Tags, first.Tags) {
+		t.Fatalf("flags() = %+v", flags)
+	}
+}
+
+func TestFlagImportStoresTags(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo, err := repository.OpenSQLite(ctx, "sqlite:"+filepath.Join(t.TempDir(), "flagz.db"), 2)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer repo.Close()
+	svc, err := service.New(ctx, repo)
+	if err != nil {
+		t.Fatalf("service.New() error = %v", err)
+	}
+
+	input := "key,tags\nnew-ui,Web;beta|web\ndark-mode,\n"
+	result, err := parseFlagImport(strings.NewReader(input), nil)
+	if err != nil || result.HasErrors() {
+		t.Fatalf("parseFlagImport() = %+v, %v", result.Rows, err)
+	}
+	const projectID = "11111111-1111-1111-1111-111111111111"
+	if _, err := svc.CreateFlags(ctx, result.flags(projectID)); err != nil {
+		t.Fatalf("CreateFlags() error = %v", err)
+	}
+
+	for key, want := range map[string][]string{"new-ui": {"beta", "web"}, "dark-mode": nil} {
+		flag, err := repo.GetFlag(ctx, projectID, key)
+		if err != nil {
+			t.Fatalf("GetFlag(%s) error = %v", key, err)
+		}
+		if !slices.Equal(flag.Tags, want) {
+			t.Errorf("stored tags of %s = %v, want %v", key, flag.Tags, want)
+		}
+	}
+}
+
+func TestParseFlagImport_TSVWithReorderedColumns(t *testing.T) {
+	input := "enabled\tkey\n1\tcheckout-v2\n"
+
+	result, err := parseFlagImport(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("parseFlagImport() error = %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0].Key != "checkout-v2" || !result.Rows[0].Enabled {
+		t.Fatalf("Rows = %+v", result.Rows)
+	}
+}
+
+func TestParseFlagImport_RowErrors(t *testing.T) {
+	input := "key,enabled\n" +
+		"ok-flag,true\n" +
+		",true\n" +
+		"bad key,true\n" +
+		"existing,false\n" +
+		"ok-flag,false\n" +
+		"other,maybe\n"
+
+	result, err := parseFlagImport(strings.NewReader(input), map[string]bool{"existing": true})
+	if err != nil {
+		t.Fatalf("parseFlagImport() error = %v", err)
+	}
+	if result.ErrorRows != 5 {
+		t.Fatalf("ErrorRows = %d, want 5", result.ErrorRows)
+	}
+
+	wantErrors := []string{
+		"",
+		"key is required",
+		"key may only contain",
+		"already exists",
+		"duplicate of line 2",
+		"enabled must be true or false",
+	}
+	for i, want := range wantErrors {
+		row := result.Rows[i]
+		if want == "" {
+			if len(row.Errors) != 0 {
+				t.Errorf("Rows[%d].Errors = %v, want none", i, row.Errors)
+			}
+			continue
+		}
+		if len(row.Errors) == 0 || !strings.Contains(row.Errors[0], want) {
+			t.Errorf("Rows[%d].Errors = %v, want %q", i, row.Errors, want)
+		}
+	}
+}
+
+func TestParseFlagImport_FileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "empty", input: "", want: "empty"},
+		{name: "missing key column", input: "description,enabled\nx,true\n", want: `"key" column`},
+		{name: "unknown column", input: "key,owner\nx,alice\n", want: "unknown column"},
+		{name: "header only", input: "key,description\n", want: "no flag rows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFlagImport(strings.NewReader(tt.input), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parseFlagImport() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/matt-riley/flagz/internal/oidc"
+)
+
+const (
+	oidcStateCookieName = "flagz_oidc_state"
+	oidcLoginTTL        = 10 * time.Minute
+	maxPendingOIDCLogin = 1000
+)
+
+// OIDCLogin signs admin portal users in through an OpenID Connect provider.
+// Users are matched on the ID token's subject and get the admin role when the
+// roles claim lists one of AdminGroups, or the viewer role for one of
+// ViewerGroups. The role is refreshed at every sign-in, so removing someone
+// from a group takes effect at their next login.
+type OIDCLogin struct {
+	Provider     *oidc.Provider
+	RolesClaim   string
+	AdminGroups  []string
+	ViewerGroups []string
+	// DisablePasswordLogin hides the username and password form and the
+	// first-run setup page, making single sign-on the only way in.
+	DisablePasswordLogin bool
+
+	mu      sync.Mutex
+	pending map[string]pendingOIDCLogin
+}
+
+type pendingOIDCLogin struct {
+	req       oidc.AuthRequest
+	expiresAt time.Time
+}
+
+// role maps claims to a portal role. ok is false when the user is in none of
+// the configured groups.
+func (o *OIDCLogin) role(claims oidc.Claims) (role string, ok bool) {
+	groups := claims.Strings(o.RolesClaim)
+	for _, group := range groups {
+		if slices.Contains(o.AdminGroups, group) {
+			return "admin", true
+		}
+	}
+	for _, group := range groups {
+		if slices.Contains(o.ViewerGroups, group) {
+			return "viewer", true
+		}
+	}
+	return "", false
+}
+
+// begin records a new login attempt and returns its parameters.
+func (o *OIDCLogin) begin() (oidc.AuthRequest, error) {
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		return oidc.AuthRequest{}, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.pending == nil {
+		o.pending = make(map[string]pendingOIDCLogin)
+	}
+	now := time.Now()
+	for state, p := range o.pending {
+		if now.After(p.expiresAt) {
+			delete(o.pending, state)
+		}
+	}
+	// Abandoned logins expire; beyond the cap, the oldest attempts simply
+	// cannot complete, which bounds memory for a portal with few users.
+	if len(o.pending) >= maxPendingOIDCLogin {
+		return oidc.AuthRequest{}, errors.New("too many pending OIDC logins")
+	}
+	o.pending[req.State] = pendingOIDCLogin{req: req, expiresAt: now.Add(oidcLoginTTL)}
+	return req, nil
+}
+
+// finish removes and returns the login attempt for state.
+func (o *OIDCLogin) finish(state string) (oidc.AuthRequest, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p, ok := o.pending[state]
+	delete(o.pending, state)
+	if !ok || time.Now().After(p.expiresAt) {
+		return oidc.AuthRequest{}, false
+	}
+	return p.req, true
+}
+
+func (h *Handler) passwordLoginEnabled() bool {
+	return h.OIDC == nil || !h.OIDC.DisablePasswordLogin
+}
+
+// renderLoginError shows the login page with an error message.
+func (h *Handler) renderLoginError(w http.ResponseWriter, r *http.Request, message string) {
+	if err := Render(w, "login.html", map[string]any{
+		"Error":                 message,
+		"SSO":                   h.OIDC != nil,
+		"PasswordLoginDisabled": !h.passwordLoginEnabled(),
+	}); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
+
+// handleOIDCLogin redirects to the identity provider. The state is also kept
+// in a cookie so the callback only completes in the browser that started it.
+func (h *Handler) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	req, err := h.OIDC.begin()
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "oidc login", "error", err)
+		h.renderLoginError(w, r, "Single sign-on is unavailable. Please try again later.")
+		return
+	}
+	authURL, err := h.OIDC.Provider.AuthCodeURL(r.Context(), req)
+	if err != nil {
+		h.OIDC.finish(req.State)
+		h.log.ErrorContext(r.Context(), "oidc login", "error", err)
+		h.renderLoginError(w, r, "Single sign-on is unavailable. Please try again later.")
+		return
+	}
+	isSecure := r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    req.State,
+		Path:     "/oidc/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		// Lax, because the provider's redirect back is a cross-site navigation.
+		SameSite: http.SameSiteLaxMode,
+		Secure:   isSecure,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback completes a login: it redeems the code, maps the user's
+// groups to a role, links or creates their admin user and starts a session.
+func (h *Handler) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Path: "/oidc/", MaxAge: -1, HttpOnly: true})
+
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		h.renderLoginError(w, r, "Single sign-on was cancelled or denied.")
+		return
+	}
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.renderLoginError(w, r, "Single sign-on expired. Please try again.")
+		return
+	}
+	req, ok := h.OIDC.finish(state)
+	if !ok {
+		h.renderLoginError(w, r, "Single sign-on expired. Please try again.")
+		return
+	}
+
+	claims, err := h.OIDC.Provider.Exchange(r.Context(), q.Get("code"), req)
+	if err != nil {
+		h.log.WarnContext(r.Context(), "oidc callback", "error", err)
+		h.renderLoginError(w, r, "Single sign-on failed.")
+		return
+	}
+	role, ok := h.OIDC.role(claims)
+	if !ok {
+		h.log.InfoContext(r.Context(), "oidc login denied: no matching group", "subject", claims.Subject())
+		h.renderLoginError(w, r, "Your account is not in a group allowed to use flagz.")
+		return
+	}
+
+	username := claims.Username()
+	user, err := h.Repo.UpsertOIDCAdminUser(r.Context(), claims.Subject(), username, role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			h.renderLoginError(w, r, "Another account is already named "+username+".")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "oidc login: upsert admin user", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if user.DeactivatedAt != nil {
+		h.log.InfoContext(r.Context(), "oidc login denied: user deactivated", "subject", claims.Subject())
+		h.renderLoginError(w, r, "This account has been deactivated.")
+		return
+	}
+
+	token, err := h.SessionMgr.GenerateSession(r.Context(), user, r.UserAgent(), clientIP(r))
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	h.SessionMgr.SetSessionCookie(w, token)
+
+	h.logAudit(r.Context(), user.ID, "admin_login", defaultProjectID, "", map[string]string{"method": "oidc", "role": role})
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/oidc"
+)
+
+func TestOIDCLoginRole(t *testing.T) {
+	o := &OIDCLogin{RolesClaim: "groups", AdminGroups: []string{"flagz-admins"}, ViewerGroups: []string{"engineering"}}
+	tests := []struct {
+		name     string
+		groups   []any
+		wantRole string
+		wantOK   bool
+	}{
+		{name: "admin group", groups: []any{"flagz-admins"}, wantRole: "admin", wantOK: true},
+		{name: "admin wins over viewer", groups: []any{"engineering", "flagz-admins"}, wantRole: "admin", wantOK: true},
+		{name: "viewer group", groups: []any{"engineering"}, wantRole: "viewer", wantOK: true},
+		{name: "no matching group", groups: []any{"sales"}},
+		{name: "no groups claim"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := oidc.Claims{"sub": "u1"}
+			if tt.groups != nil {
+				claims["groups"] = tt.groups
+			}
+			role, ok := o.role(claims)
+			if role != tt.wantRole || ok != tt.wantOK {
+				t.Fatalf("role() = %q, %v; want %q, %v", role, ok, tt.wantRole, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHandleOIDCLogin(t *testing.T) {
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	}))
+	defer issuer.Close()
+
+	h := &Handler{OIDC: &OIDCLogin{
+		Provider: oidc.New(issuer.URL, "flagz", "secret", "http://flagz-admin/oidc/callback"),
+	}}
+	rr := httptest.NewRecorder()
+	h.handleOIDCLogin(rr, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusFound)
+	}
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), issuer.URL+"/authorize?") {
+		t.Fatalf("Location = %q", rr.Header().Get("Location"))
+	}
+	state := location.Query().Get("state")
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == oidcStateCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != state || !cookie.HttpOnly {
+		t.Fatalf("state cookie = %+v, want HttpOnly cookie with state %q", cookie, state)
+	}
+
+	// A callback from a browser without the state cookie, as in a login CSRF
+	// attempt, is rejected before the code is redeemed and leaves the
+	// attempt pending for the browser that started it.
+	rr = httptest.NewRecorder()
+	h.handleOIDCCallback(rr, httptest.NewRequest(http.MethodGet, "/oidc/callback?code=c&state="+url.QueryEscape(state), nil))
+	if !strings.Contains(rr.Body.String(), "Single sign-on expired") {
+		t.Fatalf("callback without cookie body = %q", rr.Body.String())
+	}
+	if _, ok := h.OIDC.finish(state); !ok {
+		t.Fatal("pending login was consumed by a callback without the state cookie")
+	}
+	if _, ok := h.OIDC.finish(state); ok {
+		t.Fatal("finish() returned the same login twice")
+	}
+}
+
+func TestPasswordLoginDisabled(t *testing.T) {
+	h := &Handler{OIDC: &OIDCLogin{DisablePasswordLogin: true}}
+	form := url.Values{"username": {"admin"}, "password": {"password1234"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.handleLogin(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("POST /login status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	rr = httptest.NewRecorder()
+	h.handleSetup(rr, httptest.NewRequest(http.MethodGet, "/setup", nil))
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/login" {
+		t.Fatalf("GET /setup = %d %q, want redirect to /login", rr.Code, rr.Header().Get("Location"))
+	}
+}
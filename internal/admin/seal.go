@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// sealAPIKeyToken encrypts a newly created API key token with a fresh
+// single-use AES-256-GCM key. The ciphertext is rendered into the API keys
+// page while the key travels only in the redirect's URL fragment, which
+// browsers never send to the server; the page decrypts the token with Web
+// Crypto. Proxies and HTML caches therefore never see the token in
+// plaintext. Both values are unpadded base64url; sealed is the 12-byte nonce
+// followed by the GCM ciphertext and tag.
+func sealAPIKeyToken(token string) (sealed, key string, err error) {
+	rawKey := make([]byte, 32)
+	if _, err := rand.Read(rawKey); err != nil {
+		return "", "", fmt.Errorf("generate seal key: %w", err)
+	}
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return "", "", fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", "", fmt.Errorf("create gcm: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(token), nil)
+	return base64.RawURLEncoding.EncodeToString(ciphertext), base64.RawURLEncoding.EncodeToString(rawKey), nil
+}
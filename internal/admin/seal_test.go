@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSealAPIKeyTokenRoundTrip(t *testing.T) {
+	sealed, key, err := sealAPIKeyToken("key-1.super-secret")
+	if err != nil {
+		t.Fatalf("sealAPIKeyToken() error = %v", err)
+	}
+	if strings.Contains(sealed, "super-secret") {
+		t.Fatal("sealed token contains the plaintext secret")
+	}
+
+	rawKey, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != 32 {
+		t.Fatalf("key = %q, want 32 base64url bytes (err %v)", key, err)
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		t.Fatalf("decode sealed: %v", err)
+	}
+
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("NewGCM() error = %v", err)
+	}
+	plain, err := gcm.Open(nil, ciphertext[:12], ciphertext[12:], nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if string(plain) != "key-1.super-secret" {
+		t.Fatalf("plaintext = %q, want key-1.super-secret", plain)
+	}
+
+	again, againKey, err := sealAPIKeyToken("key-1.super-secret")
+	if err != nil {
+		t.Fatalf("sealAPIKeyToken() error = %v", err)
+	}
+	if again == sealed || againKey == key {
+		t.Fatal("sealing twice reused a key or nonce")
+	}
+}
@@ -25,6 +25,8 @@ const (
 	maxLoginAttempts   = 5
 	loginWindow        = 15 * time.Minute
 	maxTrackedIPs      = 10000
+	// sessionTouchInterval limits how often a session's last use is written.
+	sessionTouchInterval = time.Minute
 )
 
 var (
@@ -39,6 +41,20 @@ type SessionManager struct {
 	loginAttempts map[string][]time.Time
 	apiKeyFlashes map[string]apiKeyFlash
 	mu            sync.Mutex
+
+	// idleTimeout ends sessions unused for longer; zero disables it.
+	idleTimeout time.Duration
+}
+
+// SessionOption configures a [SessionManager].
+type SessionOption func(*SessionManager)
+
+// WithIdleTimeout ends sessions that have not been used for d, in addition
+// to the fixed 24-hour lifetime. Zero, the default, disables the idle check.
+func WithIdleTimeout(d time.Duration) SessionOption {
+	return func(m *SessionManager) {
+		m.idleTimeout = d
+	}
 }
 
 type apiKeyFlash struct {
@@ -47,13 +63,16 @@ type apiKeyFlash struct {
 	expiresAt time.Time
 }
 
-func NewSessionManager(ctx context.Context, repo *repository.PostgresRepository, sessionSecret string) *SessionManager {
+func NewSessionManager(ctx context.Context, repo *repository.PostgresRepository, sessionSecret string, opts ...SessionOption) *SessionManager {
 	mgr := &SessionManager{
 		repo:          repo,
 		sessionSecret: []byte(sessionSecret),
 		loginAttempts: make(map[string][]time.Time),
 		apiKeyFlashes: make(map[string]apiKeyFlash),
 	}
+	for _, opt := range opts {
+		opt(mgr)
+	}
 	// Periodically clean up old rate limit entries to prevent unbounded memory growth
 	// and purge expired sessions from the database.
 	go func() {
@@ -79,7 +98,7 @@ func NewSessionManager(ctx context.Context, repo *repository.PostgresRepository,
 				mgr.mu.Unlock()
 
 				cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-				_ = repo.DeleteExpiredAdminSessions(cleanupCtx)
+				_ = repo.DeleteExpiredAdminSessions(cleanupCtx, mgr.idleTimeout)
 				cancel()
 			}
 		}
@@ -92,6 +111,7 @@ func apiKeyFlashKey(sessionIDHash, projectID string) string {
 }
 
 // SetAPIKeyFlash stores a one-time API key secret for PRG flow after creation.
+// Callers store the token sealed with [sealAPIKeyToken], never the plaintext.
 func (m *SessionManager) SetAPIKeyFlash(sessionIDHash, projectID, keyID, secret string) {
 	if sessionIDHash == "" || projectID == "" || keyID == "" || secret == "" {
 		return
@@ -124,41 +144,54 @@ func (m *SessionManager) PopAPIKeyFlash(sessionIDHash, projectID string) (keyID
 	return flash.keyID, flash.secret, true
 }
 
-// GenerateSession creates a new session for the user, returning the raw token to be set in the cookie.
-func (m *SessionManager) GenerateSession(ctx context.Context, userID string) (string, error) {
+// GenerateSession creates a new session for the user, returning the raw token
+// to be set in the cookie. userAgent and remoteAddr describe the browser on
+// the user's list of active sessions.
+func (m *SessionManager) GenerateSession(ctx context.Context, user repository.AdminUser, userAgent, remoteAddr string) (string, error) {
+	rawToken, session, err := m.newSession(user.ID, user.Role)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	session.CreatedAt = now
+	session.ExpiresAt = now.Add(sessionDuration)
+	session.UserAgent = userAgent
+	session.RemoteAddr = remoteAddr
+
+	if err := m.repo.CreateAdminSession(ctx, session); err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// newSession returns a fresh raw token and a session holding its hash and a
+// new CSRF token.
+func (m *SessionManager) newSession(userID, role string) (string, repository.AdminSession, error) {
 	// Generate raw session token
 	tokenBytes := make([]byte, sessionTokenLength)
 	if _, err := rand.Read(tokenBytes); err != nil {
-		return "", fmt.Errorf("generate session token: %w", err)
+		return "", repository.AdminSession{}, fmt.Errorf("generate session token: %w", err)
 	}
 	rawToken := base64.RawURLEncoding.EncodeToString(tokenBytes)
 
-	// Hash token for storage
-	idHash := m.hashToken(rawToken)
-
 	// Generate CSRF token
 	csrfBytes := make([]byte, csrfTokenLength)
 	if _, err := rand.Read(csrfBytes); err != nil {
-		return "", fmt.Errorf("generate csrf token: %w", err)
+		return "", repository.AdminSession{}, fmt.Errorf("generate csrf token: %w", err)
 	}
-	csrfToken := base64.RawURLEncoding.EncodeToString(csrfBytes)
 
-	session := repository.AdminSession{
-		IDHash:      idHash,
+	return rawToken, repository.AdminSession{
+		IDHash:      m.hashToken(rawToken),
 		AdminUserID: userID,
-		CSRFToken:   csrfToken,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(sessionDuration),
-	}
-
-	if err := m.repo.CreateAdminSession(ctx, session); err != nil {
-		return "", err
-	}
-
-	return rawToken, nil
+		CSRFToken:   base64.RawURLEncoding.EncodeToString(csrfBytes),
+		Role:        role,
+	}, nil
 }
 
-// ValidateSession checks the cookie token against the DB and returns the session if valid.
+// ValidateSession checks the cookie token against the DB and returns the
+// session if valid. A session idle for longer than the idle timeout is
+// deleted and rejected.
 func (m *SessionManager) ValidateSession(ctx context.Context, rawToken string) (repository.AdminSession, error) {
 	if rawToken == "" {
 		return repository.AdminSession{}, ErrUnauthorized
@@ -170,9 +203,80 @@ func (m *SessionManager) ValidateSession(ctx context.Context, rawToken string) (
 		return repository.AdminSession{}, ErrUnauthorized
 	}
 
+	now := time.Now()
+	if m.idle(session, now) {
+		_ = m.repo.DeleteAdminSession(ctx, idHash)
+		return repository.AdminSession{}, ErrUnauthorized
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		// Best effort: a failed write only makes the session look idle sooner.
+		if err := m.repo.TouchAdminSession(ctx, idHash); err == nil {
+			session.LastSeenAt = now
+		}
+	}
+
 	return session, nil
 }
 
+func (m *SessionManager) idle(session repository.AdminSession, now time.Time) bool {
+	return m.idleTimeout > 0 && now.Sub(session.LastSeenAt) > m.idleTimeout
+}
+
+// NeedsRotation reports whether the user's role has changed since the
+// session was issued, in which case it should be replaced by [RotateSession].
+func NeedsRotation(session repository.AdminSession) bool {
+	return session.Role != session.UserRole
+}
+
+// RotateSession replaces session with one under a new token and CSRF token,
+// issued for the user's current role, and returns the new raw token. The
+// replacement keeps the original expiry, so rotating never extends a
+// session's lifetime.
+func (m *SessionManager) RotateSession(ctx context.Context, session repository.AdminSession) (string, repository.AdminSession, error) {
+	rawToken, next, err := m.newSession(session.AdminUserID, session.UserRole)
+	if err != nil {
+		return "", repository.AdminSession{}, err
+	}
+	next.UserRole = session.UserRole
+	next.CreatedAt = time.Now()
+	next.ExpiresAt = session.ExpiresAt
+	next.LastSeenAt = next.CreatedAt
+	next.UserAgent = session.UserAgent
+	next.RemoteAddr = session.RemoteAddr
+
+	if err := m.repo.RotateAdminSession(ctx, session.IDHash, next); err != nil {
+		return "", repository.AdminSession{}, err
+	}
+	return rawToken, next, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+func (m *SessionManager) ListSessions(ctx context.Context, userID string) ([]repository.AdminSession, error) {
+	sessions, err := m.repo.ListAdminSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if !m.idle(s, now) {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession ends one of the user's sessions by its public ID.
+func (m *SessionManager) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	return m.repo.DeleteAdminSessionByID(ctx, userID, sessionID)
+}
+
+// RevokeOtherSessions ends all of the user's sessions except current and
+// returns how many were ended.
+func (m *SessionManager) RevokeOtherSessions(ctx context.Context, current repository.AdminSession) (int64, error) {
+	return m.repo.DeleteOtherAdminSessions(ctx, current.AdminUserID, current.IDHash)
+}
+
 // InvalidateSession removes the session from the DB.
 func (m *SessionManager) InvalidateSession(ctx context.Context, rawToken string) error {
 	idHash := m.hashToken(rawToken)
@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
 )
 
 func TestCheckLoginRateLimit(t *testing.T) {
@@ -216,3 +218,42 @@ func TestAPIKeyFlashRoundTrip(t *testing.T) {
 		t.Fatal("expected flash to be consumed after pop")
 	}
 }
+
+func TestSessionIdle(t *testing.T) {
+	now := time.Now()
+	session := repository.AdminSession{LastSeenAt: now.Add(-90 * time.Minute)}
+
+	if (&SessionManager{}).idle(session, now) {
+		t.Fatal("session should never be idle without an idle timeout")
+	}
+	mgr := &SessionManager{idleTimeout: time.Hour}
+	if !mgr.idle(session, now) {
+		t.Fatal("session unused for 90m should be idle with a 1h timeout")
+	}
+	session.LastSeenAt = now.Add(-30 * time.Minute)
+	if mgr.idle(session, now) {
+		t.Fatal("session used 30m ago should not be idle with a 1h timeout")
+	}
+}
+
+func TestNeedsRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		userRole string
+		want     bool
+	}{
+		{name: "unchanged", role: "viewer", userRole: "viewer"},
+		{name: "promoted", role: "viewer", userRole: "admin", want: true},
+		{name: "demoted", role: "admin", userRole: "viewer", want: true},
+		{name: "issued before roles were recorded", role: "", userRole: "admin", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NeedsRotation(repository.AdminSession{Role: tt.role, UserRole: tt.userRole})
+			if got != tt.want {
+				t.Fatalf("NeedsRotation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
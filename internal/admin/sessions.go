@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// handleSessions lists the signed-in user's active sessions and, on POST,
+// signs out one of them ("revoke" with session_id) or all but the current one
+// ("revoke_others"). Users can only see and end their own sessions.
+func (h *Handler) handleSessions(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value(sessionContextKey).(repository.AdminSession)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	user, err := h.Repo.GetAdminUserByID(r.Context(), session.AdminUserID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		switch r.FormValue("action") {
+		case "revoke":
+			id := r.FormValue("session_id")
+			if _, err := uuid.Parse(id); err != nil || id == session.ID {
+				http.Error(w, "Invalid session", http.StatusBadRequest)
+				return
+			}
+			if err := h.SessionMgr.RevokeSession(r.Context(), user.ID, id); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					http.Error(w, "Session not found", http.StatusNotFound)
+					return
+				}
+				http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+				return
+			}
+			h.logAudit(r.Context(), user.ID, "admin_session_revoke", defaultProjectID, "", map[string]string{"session_id": id})
+		case "revoke_others":
+			n, err := h.SessionMgr.RevokeOtherSessions(r.Context(), session)
+			if err != nil {
+				http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+				return
+			}
+			h.logAudit(r.Context(), user.ID, "admin_session_revoke", defaultProjectID, "", map[string]int64{"revoked": n})
+		default:
+			http.Error(w, "Unknown action", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, "/sessions", http.StatusFound)
+		return
+	}
+
+	sessions, err := h.SessionMgr.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	if err := Render(w, "sessions.html", map[string]any{
+		"User":      user,
+		"Sessions":  sessions,
+		"CurrentID": session.ID,
+		"CSRFToken": session.CSRFToken,
+	}); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
@@ -2,6 +2,7 @@ package admin
 
 import (
 	"embed"
+	"encoding/json"
 	"html/template"
 	"io"
 	"time"
@@ -16,6 +17,10 @@ func Render(w io.Writer, name string, data any) error {
 		"formatTime": func(t time.Time) string {
 			return t.Format(time.RFC3339)
 		},
+		"toJSON": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
 	}).ParseFS(content, "templates/base.html", "templates/"+name)
 	if err != nil {
 		return err
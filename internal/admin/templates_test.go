@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/matt-riley/flagz/internal/repository"
 )
 
 func TestRender(t *testing.T) {
@@ -19,12 +21,45 @@ func TestRender(t *testing.T) {
 			data:         map[string]any{"Error": "invalid credentials"},
 			wantContent:  "Login",
 		},
+		{
+			name:         "login template with single sign-on only",
+			templateName: "login.html",
+			data:         map[string]any{"SSO": true, "PasswordLoginDisabled": true},
+			wantContent:  `href="/oidc/login"`,
+		},
 		{
 			name:         "setup template",
 			templateName: "setup.html",
 			data:         nil,
 			wantContent:  "Setup Admin",
 		},
+		{
+			name:         "import template with preview",
+			templateName: "import.html",
+			data: map[string]any{
+				"Project": map[string]string{"ID": "p1", "Name": "Demo"},
+				"Result": importResult{
+					Rows:      []importRow{{Line: 2, Key: "bad key", Errors: []string{"key may only contain letters"}}},
+					ErrorRows: 1,
+				},
+			},
+			wantContent: "1 of 1 rows have errors",
+		},
+		{
+			name:         "change password template",
+			templateName: "account_password.html",
+			data: map[string]any{
+				"User":              repository.AdminUser{Username: "alice", Role: "viewer", PasswordHash: "hash"},
+				"MinPasswordLength": 16,
+			},
+			wantContent: `name="current_password"`,
+		},
+		{
+			name:         "change password template for single sign-on users",
+			templateName: "account_password.html",
+			data:         map[string]any{"User": repository.AdminUser{Username: "carol", Role: "viewer"}},
+			wantContent:  "managed by your identity provider",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,171 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// adminUserRow is one user on the users page.
+type adminUserRow struct {
+	repository.AdminUser
+	// SSO marks users who sign in through OIDC. Their role is set from their
+	// groups at every login and they have no password, so neither can be
+	// changed here.
+	SSO bool
+	// Self marks the signed-in user, who cannot demote or deactivate
+	// themselves.
+	Self bool
+}
+
+// handleUsers lists admin users and, on POST, creates a user ("create") or
+// changes one selected by user_id: its role ("role"), its password
+// ("password"), or whether it can sign in ("deactivate", "reactivate").
+// Changing a password or deactivating a user ends all of that user's
+// sessions. Only admins reach this page.
+func (h *Handler) handleUsers(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value(sessionContextKey).(repository.AdminSession)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	actor, ok := r.Context().Value(adminUserContextKey).(repository.AdminUser)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if msg := h.applyUserAction(r, actor); msg != "" {
+			h.renderUsers(w, r, actor, session, msg)
+			return
+		}
+		http.Redirect(w, r, "/users", http.StatusFound)
+		return
+	}
+
+	h.renderUsers(w, r, actor, session, "")
+}
+
+// applyUserAction carries out the POSTed action and returns a message for
+// the user if it was refused.
+func (h *Handler) applyUserAction(r *http.Request, actor repository.AdminUser) string {
+	ctx := r.Context()
+	action := r.FormValue("action")
+
+	if action == "create" {
+		username := strings.TrimSpace(r.FormValue("username"))
+		role := r.FormValue("role")
+		if msg := validateUsername(username); msg != "" {
+			return msg
+		}
+		if !validRole(role) {
+			return "Choose a role for the new user"
+		}
+		hash, msg := h.newPasswordHash(r.FormValue("password"), r.FormValue("confirm_password"))
+		if msg != "" {
+			return msg
+		}
+		user, err := h.Repo.CreateAdminUser(ctx, username, hash, role)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return "A user named " + username + " already exists"
+			}
+			h.log.ErrorContext(ctx, "failed to create admin user", "error", err)
+			return "Failed to create user"
+		}
+		h.logAudit(ctx, actor.ID, "admin_user_create", defaultProjectID, "", map[string]string{"user_id": user.ID, "username": user.Username, "role": role})
+		return ""
+	}
+
+	userID := r.FormValue("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		return "Invalid user"
+	}
+	target, err := h.Repo.GetAdminUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "User not found"
+		}
+		return "Failed to load user"
+	}
+	sso := target.PasswordHash == ""
+	details := map[string]string{"user_id": target.ID, "username": target.Username}
+
+	switch action {
+	case "role":
+		role := r.FormValue("role")
+		switch {
+		case !validRole(role):
+			return "Invalid role"
+		case target.ID == actor.ID:
+			return "You cannot change your own role"
+		case sso:
+			return target.Username + " signs in with single sign-on; their role comes from their groups"
+		}
+		if err := h.Repo.SetAdminUserRole(ctx, target.ID, role); err != nil {
+			return "Failed to change role"
+		}
+		details["role"] = role
+		h.logAudit(ctx, actor.ID, "admin_user_role_change", defaultProjectID, "", details)
+	case "password":
+		if sso {
+			return target.Username + " signs in with single sign-on and has no password"
+		}
+		hash, msg := h.newPasswordHash(r.FormValue("password"), r.FormValue("confirm_password"))
+		if msg != "" {
+			return msg
+		}
+		if err := h.Repo.SetAdminUserPassword(ctx, target.ID, hash); err != nil {
+			return "Failed to reset password"
+		}
+		h.logAudit(ctx, actor.ID, "admin_user_password_reset", defaultProjectID, "", details)
+	case "deactivate":
+		if target.ID == actor.ID {
+			return "You cannot deactivate yourself"
+		}
+		if err := h.Repo.DeactivateAdminUser(ctx, target.ID); err != nil {
+			return "Failed to deactivate user"
+		}
+		h.logAudit(ctx, actor.ID, "admin_user_deactivate", defaultProjectID, "", details)
+	case "reactivate":
+		if err := h.Repo.ReactivateAdminUser(ctx, target.ID); err != nil {
+			return "Failed to reactivate user"
+		}
+		h.logAudit(ctx, actor.ID, "admin_user_reactivate", defaultProjectID, "", details)
+	default:
+		return "Unknown action"
+	}
+	return ""
+}
+
+func (h *Handler) renderUsers(w http.ResponseWriter, r *http.Request, actor repository.AdminUser, session repository.AdminSession, errMsg string) {
+	users, err := h.Repo.ListAdminUsers(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+	if err := Render(w, "users.html", map[string]any{
+		"User":              actor,
+		"Users":             adminUserRows(users, actor.ID),
+		"CSRFToken":         session.CSRFToken,
+		"Error":             errMsg,
+		"MinPasswordLength": h.PasswordPolicy.minLength(),
+	}); err != nil {
+		h.log.ErrorContext(r.Context(), "render error", "error", err)
+	}
+}
+
+func adminUserRows(users []repository.AdminUser, actorID string) []adminUserRow {
+	rows := make([]adminUserRow, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, adminUserRow{AdminUser: u, SSO: u.PasswordHash == "", Self: u.ID == actorID})
+	}
+	return rows
+}
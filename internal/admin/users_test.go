@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+func TestRenderUsersTemplate(t *testing.T) {
+	now := time.Now()
+	actor := repository.AdminUser{ID: "id-alice", Username: "alice", Role: "admin", PasswordHash: "hash", CreatedAt: now}
+	users := []repository.AdminUser{
+		actor,
+		{ID: "id-bob", Username: "bob", Role: "viewer", PasswordHash: "hash", CreatedAt: now, DeactivatedAt: &now},
+		{ID: "id-carol", Username: "carol", Role: "viewer", CreatedAt: now},
+	}
+
+	var buf bytes.Buffer
+	err := Render(&buf, "users.html", map[string]any{
+		"User":      actor,
+		"Users":     adminUserRows(users, actor.ID),
+		"CSRFToken": "token123",
+		"Error":     "Passwords do not match",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Passwords do not match",
+		`href="/users"`,
+		`value="create"`,
+		"You",
+		"SSO",
+		`value="reactivate"`,
+		`value="deactivate"`,
+		"Deactivated " + now.Format(time.RFC3339),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	// Only bob can have his role changed: alice is signed in and carol's
+	// role comes from single sign-on.
+	if got := strings.Count(out, `name="action" value="role"`); got != 1 {
+		t.Errorf("role forms = %d, want 1", got)
+	}
+	// Carol has no password to reset.
+	if got := strings.Count(out, `name="action" value="password"`); got != 2 {
+		t.Errorf("password forms = %d, want 2", got)
+	}
+	// Alice cannot deactivate herself.
+	if got := strings.Count(out, `value="deactivate"`); got != 1 {
+		t.Errorf("deactivate buttons = %d, want 1", got)
+	}
+}
+
+func TestRenderBaseTemplate_UsersLinkForAdmins(t *testing.T) {
+	for role, want := range map[string]bool{"admin": true, "viewer": false} {
+		var buf bytes.Buffer
+		if err := Render(&buf, "sessions.html", map[string]any{
+			"User":     repository.AdminUser{Username: "alice", Role: role},
+			"Sessions": []repository.AdminSession{},
+		}); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if got := strings.Contains(buf.String(), `href="/users"`); got != want {
+			t.Errorf("role %s: users link shown = %v, want %v", role, got, want)
+		}
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for username, wantOK := range map[string]bool{
+		"alice":                 true,
+		"a.b-c_d":               true,
+		"ab":                    false,
+		strings.Repeat("a", 51): false,
+		"alice smith":           false,
+	} {
+		if got := validateUsername(username) == ""; got != wantOK {
+			t.Errorf("validateUsername(%q) ok = %v, want %v", username, got, wantOK)
+		}
+	}
+}
+
+func TestApplyUserAction_RejectsBadInput(t *testing.T) {
+	h := &Handler{}
+	actor := repository.AdminUser{ID: "11111111-1111-1111-1111-111111111111", Role: "admin"}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{"short username", url.Values{"action": {"create"}, "username": {"al"}, "role": {"viewer"}}, "Username must be between 3 and 50 characters"},
+		{"missing role", url.Values{"action": {"create"}, "username": {"alice"}}, "Choose a role for the new user"},
+		{"weak password", url.Values{"action": {"create"}, "username": {"alice"}, "role": {"admin"}, "password": {"short"}, "confirm_password": {"short"}}, "Password must be at least 12 characters"},
+		{"invalid user id", url.Values{"action": {"deactivate"}, "user_id": {"not-a-uuid"}}, "Invalid user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if got := h.applyUserAction(req, actor); got != tt.want {
+				t.Fatalf("applyUserAction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Package backup reads and writes backup archives: a logical copy of every
+// project with its API key metadata, segments, flags and audit log, for
+// moving them between flagz instances.
+//
+// An archive is gzip-compressed JSON Lines. The first line is a [Header];
+// each line after it is one [repository.BackupRecord], in the order
+// [repository.PostgresRepository.Backup] emits them. The HTTP API serves
+// archives from GET /v1/admin/backup and restores them with
+// POST /v1/admin/restore; "server backup" and "server restore" do the same
+// directly against DATABASE_URL.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// FormatVersion is the version of the archive format written by [Write].
+// [NewReader] refuses archives of any other version.
+const FormatVersion = 1
+
+// ContentType is the media type of archives.
+const ContentType = "application/gzip"
+
+// maxLineSize bounds one record, which holds at most one flag.
+const maxLineSize = 16 << 20
+
+// ErrInvalidArchive is returned for a file that is not a backup archive this
+// version can read.
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// Header is the first line of an archive.
+type Header struct {
+	Format    int       `json:"flagz_backup"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Summary counts the records written to an archive, by kind.
+type Summary map[string]int
+
+// Source is the subset of [service.Service] backups read from.
+type Source interface {
+	Backup(ctx context.Context, emit func(repository.BackupRecord) error) error
+}
+
+// Write writes an archive of everything src backs up to w and returns how
+// many records of each kind it holds. An error may leave a partial archive
+// in w; it will fail to restore rather than restore incompletely, since the
+// gzip stream is not closed.
+func Write(ctx context.Context, w io.Writer, src Source) (Summary, error) {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(Header{Format: FormatVersion, CreatedAt: time.Now().UTC()}); err != nil {
+		return nil, fmt.Errorf("write backup header: %w", err)
+	}
+
+	summary := make(Summary)
+	if err := src.Backup(ctx, func(record repository.BackupRecord) error {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("write backup record: %w", err)
+		}
+		summary[record.Kind]++
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("finish backup archive: %w", err)
+	}
+	return summary, nil
+}
+
+// Reader reads the records of an archive.
+type Reader struct {
+	header  Header
+	scanner *bufio.Scanner
+}
+
+// NewReader reads the header of the archive in r and returns a Reader
+// positioned at its first record.
+func NewReader(r io.Reader) (*Reader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	var header Header
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%w: no header", ErrInvalidArchive)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format == 0 {
+		return nil, fmt.Errorf("%w: no header", ErrInvalidArchive)
+	}
+	if header.Format != FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d, want %d", ErrInvalidArchive, header.Format, FormatVersion)
+	}
+	return &Reader{header: header, scanner: scanner}, nil
+}
+
+// Header returns the archive's header.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next record, or io.EOF once every record has been read.
+// A truncated or corrupt archive is an [ErrInvalidArchive].
+func (r *Reader) Next() (repository.BackupRecord, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return repository.BackupRecord{}, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		return repository.BackupRecord{}, io.EOF
+	}
+	var record repository.BackupRecord
+	if err := json.Unmarshal(r.scanner.Bytes(), &record); err != nil {
+		return repository.BackupRecord{}, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return record, nil
+}
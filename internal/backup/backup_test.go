@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+type recordsSource []repository.BackupRecord
+
+func (s recordsSource) Backup(_ context.Context, emit func(repository.BackupRecord) error) error {
+	for _, record := range s {
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWriteAndRead(t *testing.T) {
+	records := recordsSource{
+		{Kind: repository.BackupKindProject, Project: &repository.Project{ID: "p1", Name: "Checkout"}},
+		{Kind: repository.BackupKindAPIKey, ProjectID: "p1", APIKey: &repository.BackupAPIKey{ID: "k1", Name: "ci", Scope: repository.APIKeyScopeProject}},
+		{Kind: repository.BackupKindFlag, ProjectID: "p1", Flag: &repository.Flag{Key: "new-cart", Enabled: true, Variants: []byte(`{"a":1}`), BucketingSalt: "salt"}},
+		{Kind: repository.BackupKindFlag, ProjectID: "p1", Flag: &repository.Flag{Key: "dark-mode"}},
+	}
+
+	var buf bytes.Buffer
+	summary, err := Write(context.Background(), &buf, records)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if summary[repository.BackupKindFlag] != 2 || summary[repository.BackupKindProject] != 1 {
+		t.Fatalf("summary = %v, want 1 project and 2 flags", summary)
+	}
+
+	reader, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if reader.Header().Format != FormatVersion || reader.Header().CreatedAt.IsZero() {
+		t.Fatalf("header = %+v", reader.Header())
+	}
+	var got []repository.BackupRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, record)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("read %d records, want %d", len(got), len(records))
+	}
+	flag := got[2]
+	if flag.ProjectID != "p1" || flag.Flag.Key != "new-cart" || string(flag.Flag.Variants) != `{"a":1}` || flag.Flag.BucketingSalt != "salt" {
+		t.Fatalf("flag record = %+v, flag %+v", flag, flag.Flag)
+	}
+	if got[1].APIKey.ID != "k1" || got[0].Project.Name != "Checkout" {
+		t.Fatalf("records = %+v", got)
+	}
+}
+
+func TestWriteReturnsSourceError(t *testing.T) {
+	failing := errors.New("snapshot failed")
+	_, err := Write(context.Background(), io.Discard, sourceFunc(func(context.Context, func(repository.BackupRecord) error) error {
+		return failing
+	}))
+	if !errors.Is(err, failing) {
+		t.Fatalf("Write() error = %v, want %v", err, failing)
+	}
+}
+
+type sourceFunc func(context.Context, func(repository.BackupRecord) error) error
+
+func (f sourceFunc) Backup(ctx context.Context, emit func(repository.BackupRecord) error) error {
+	return f(ctx, emit)
+}
+
+func TestNewReaderRejectsInvalidArchives(t *testing.T) {
+	gzipped := func(body string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(body))
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	for name, body := range map[string][]byte{
+		"not gzip":       []byte(`{"flagz_backup":1}`),
+		"empty":          gzipped(""),
+		"no header":      gzipped(`{"kind":"project"}` + "\n"),
+		"future version": gzipped(`{"flagz_backup":2}` + "\n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(body)); !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("NewReader() error = %v, want ErrInvalidArchive", err)
+			}
+		})
+	}
+}
+
+func TestReaderRejectsTruncatedArchive(t *testing.T) {
+	var buf bytes.Buffer
+	records := recordsSource{
+		{Kind: repository.BackupKindProject, Project: &repository.Project{ID: "p1", Name: "Checkout"}},
+		{Kind: repository.BackupKindFlag, ProjectID: "p1", Flag: &repository.Flag{Key: "new-cart"}},
+	}
+	if _, err := Write(context.Background(), &buf, records); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	reader, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-8]))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	for {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			t.Fatal("Next() reached io.EOF, want ErrInvalidArchive for the truncated archive")
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("Next() error = %v, want ErrInvalidArchive", err)
+			}
+			return
+		}
+	}
+}
@@ -1,24 +1,233 @@
-// Package config loads server configuration from environment variables.
+// Package config loads server configuration from environment variables and
+// an optional YAML config file.
+//
+// When FLAGZ_CONFIG names a file, it is read first. Its keys are the
+// lower-cased variable names below (for example "database_url" or
+// "stream_poll_interval"); any environment variable that is set and non-empty
+// overrides the file's value. Unknown keys in the file are rejected so typos
+// fail fast instead of being silently ignored.
 //
 // Required variables:
-//   - DATABASE_URL: PostgreSQL connection string.
+//   - DATABASE_URL: PostgreSQL connection string, or the path of a SQLite
+//     database as "sqlite:<path>" or a "file:" URI. Not used, and not
+//     required, when UPSTREAM_URL is set. SQLite suits a single server only:
+//     ADMIN_HOSTNAME, DATABASE_READ_URL, DATABASE_REPLICA_URL,
+//     CACHE_INVALIDATION=redis, EXPORT_S3_BUCKET and VARIANTS_KEY_FILE
+//     cannot be used with it.
 //
 // Optional variables:
 //   - HTTP_ADDR: listen address for the HTTP server (default ":8080").
 //   - GRPC_ADDR: listen address for the gRPC server (default ":9090").
-//   - STREAM_POLL_INTERVAL: polling interval for SSE and gRPC streaming
-//     (default "1s", must be > 0 if set).
+//   - REGION: region the server runs in, reported by GET /v1/info, SSE
+//     heartbeats, flagz_instance_info and exported telemetry (default: none).
+//   - STREAM_POLL_INTERVAL: how often to check for new stream events when
+//     no change notification arrives (default "1s", must be > 0 if set).
+//   - STREAM_KEEPALIVE_INTERVAL: how long an SSE stream may stay silent
+//     before a keepalive comment is written, so idle connections are not
+//     dropped by proxies (default "30s", must be >= 0; "0" disables them).
+//   - STREAM_WRITE_TIMEOUT: how long one write to an SSE stream may take
+//     before the stream is closed, so clients that stop reading cannot hold
+//     connections open (default "10s", must be >= 0; "0" disables it).
+//   - MAX_STREAMS_PER_API_KEY: how many SSE and gRPC watch streams one API
+//     key may hold open at once (default "0", no limit; must be >= 0).
+//   - MAX_EVALUATIONS_IN_FLIGHT: how many HTTP and gRPC evaluation requests
+//     are handled at once before further ones queue (default "0", no limit;
+//     must be >= 0).
+//   - MAX_QUEUED_EVALUATIONS: how many evaluation requests may wait for one
+//     of MAX_EVALUATIONS_IN_FLIGHT before the rest are rejected with 503 or
+//     RESOURCE_EXHAUSTED (default "0", rejected at once; must be >= 0).
+//   - HTTP_RATE_LIMIT_PER_IP: requests per second each client IP may make
+//     to the HTTP API before it is answered with 429 (default "0", no limit;
+//     must be >= 0).
+//   - HTTP_RATE_LIMIT_BURST: how many requests above HTTP_RATE_LIMIT_PER_IP
+//     a client IP may make in a burst (default "20", must be > 0 if set).
+//   - HTTP2_MAX_CONCURRENT_STREAMS: how many concurrent HTTP/2 streams one
+//     connection may open, on both the HTTP and gRPC listeners (default
+//     "250", must be > 0 if set).
+//   - LOG_ROUTE_LEVELS: levels HTTP requests are logged at by path prefix,
+//     as "<prefix>=<level>,..." with levels "debug", "info", "warn" or
+//     "error" (default: "/v1/evaluate=debug", everything else at info).
+//   - LOG_SAMPLE_RATES: fractions of HTTP requests logged by path prefix,
+//     as "<prefix>=<rate>,..." with rates between 0 and 1 (default: every
+//     request). Responses with a 5xx status are always logged.
+//   - ADMIN_PORTAL_ENABLED: serve the admin portal when ADMIN_HOSTNAME is
+//     set (default "true"). "false" disables it and ignores ADMIN_HOSTNAME.
 //   - MAX_JSON_BODY_SIZE: max HTTP JSON request body size in bytes
 //     (default "1048576", must be > 0 if set).
+//   - MAX_EVALUATE_BODY_SIZE: max POST /v1/evaluate request body size in
+//     bytes (default "262144", must be > 0 if set).
+//   - MAX_IMPORT_BODY_SIZE: max POST /v1/flags/import request body size in
+//     bytes (default "33554432", must be > 0 if set).
+//   - EVALUATE_TIMEOUT: how long POST /v1/evaluate may take, including
+//     reading the body (default "5s", must be >= 0; "0" disables it).
+//   - IMPORT_TIMEOUT: how long POST /v1/flags/import and POST /v1/flags:batch
+//     may take (default "5m", must be >= 0; "0" disables it).
 //   - EVENT_BATCH_SIZE: max number of events returned per stream poll query
 //     (default "1000", must be > 0 if set).
 //   - CACHE_RESYNC_INTERVAL: safety-net cache refresh interval
 //     (default "1m", must be > 0 if set).
+//   - WARMUP_TIMEOUT: how long /readyz waits for the cache invalidation
+//     subscription before reporting ready anyway (default "30s", must be
+//     >= 0; "0" stops it waiting).
+//   - SHUTDOWN_DRAIN_DELAY: how long /readyz reports not ready on shutdown
+//     before the listeners stop accepting requests (default "5s", must be
+//     >= 0; "0" stops them at once).
+//   - STREAM_DRAIN_TIMEOUT: how long shutdown waits for open SSE and gRPC
+//     streams to send a final reconnect event and close (default "10s",
+//     must be >= 0; "0" cuts them without one).
+//   - CACHE_INVALIDATION: transport used to tell replicas to reload their
+//     flag cache, "postgres" (LISTEN/NOTIFY, the default) or "redis".
+//   - REDIS_URL: Redis connection URL, required when CACHE_INVALIDATION is
+//     "redis".
+//   - RUN_MIGRATIONS: apply pending database migrations on startup
+//     (default "true"; set to "false" when migrations are run separately).
+//   - SDK_POLL_INTERVAL: poll interval recommended to SDKs that refresh
+//     flags by polling (default "30s", must be > 0 if set).
+//   - SDK_MAX_BATCH_SIZE: max number of evaluations SDKs should send in one
+//     batch request (default "100", must be > 0 if set).
+//   - SDK_HEARTBEAT_INTERVAL: how often the SSE stream sends a heartbeat
+//     comment, advertised to SDKs so they can detect dead connections
+//     (default "15s", must be >= 0; "0" disables heartbeats).
+//   - KUBERNETES_SYNC: sync flags declared in labelled ConfigMaps and
+//     FeatureFlag resources of the cluster the server runs in (default
+//     "false").
+//   - KUBERNETES_NAMESPACE: namespace to sync from (default: all namespaces
+//     the service account can list).
+//   - KUBERNETES_SYNC_INTERVAL: how often Kubernetes resources are synced
+//     (default "30s", must be > 0 if set).
+//   - PROJECT_RETENTION: how long a deleted project can be restored before
+//     it is purged (default "720h", must be > 0 if set).
+//   - IDEMPOTENCY_KEY_TTL: how long the response to a request made with an
+//     Idempotency-Key header is replayed to retries (default "24h", must be
+//     > 0 if set).
+//   - STATS_FLUSH_INTERVAL: how often flag evaluation counters are written
+//     to the database (default "10s", must be > 0 if set).
+//   - EVALUATION_CACHE_TTL: how long a flag evaluation result is reused
+//     for an identical context; results are dropped as soon as the flag
+//     changes (default "0", must be >= 0; "0" disables the cache).
+//   - EVALUATION_CACHE_SIZE: max number of evaluation results cached
+//     (default "10000", must be > 0 if set).
+//   - METRICS_FLAG_LABEL_LIMIT: how many distinct project and flag key pairs
+//     get their own flagz_flag_evaluations_total series; evaluations of
+//     further flags are counted under flag_key "__other__" (default "1000",
+//     must be >= 0).
+//   - STALE_FLAG_NOT_EVALUATED_FOR: how long a flag can go unevaluated before
+//     the stale flag report lists it (default "720h", must be > 0 if set).
+//   - STALE_FLAG_NOT_MODIFIED_FOR: how long a flag can go unmodified before
+//     the stale flag report lists it (default "2160h", must be > 0 if set).
+//   - DATABASE_REPLICA_URL: PostgreSQL read replica connection string. When
+//     set, flag reads that miss the cache are hedged across the primary and
+//     the replica.
+//   - DATABASE_READ_URL: PostgreSQL read replica connection string. When
+//     set, flag and event reads go to the replica and fall back to the
+//     primary when it fails them; writes and LISTEN stay on the primary.
+//     Cannot be combined with DATABASE_REPLICA_URL.
+//   - VARIANTS_KEY_FILE: JSON file of the keys that encrypt flag variants at
+//     rest; see repository.LoadLocalKeyFile. Unset, variants are stored in
+//     plaintext. Cannot be used when UPSTREAM_URL is set.
+//   - DATABASE_MAX_CONNS, DATABASE_MIN_CONNS: size limits of each PostgreSQL
+//     connection pool (must be > 0 if set, and min <= max when both are).
+//     Unset, they keep the connection string's pool_max_conns and
+//     pool_min_conns, or the pgxpool defaults.
+//   - DATABASE_MAX_CONN_LIFETIME, DATABASE_MAX_CONN_IDLE_TIME,
+//     DATABASE_HEALTH_CHECK_PERIOD: how long a pooled connection is kept,
+//     how long it may sit idle, and how often idle connections are checked
+//     (must be > 0 if set; unset keeps the pgxpool defaults).
+//   - DATABASE_STATEMENT_TIMEOUT: statement_timeout of every pooled
+//     connection, after which PostgreSQL cancels a query (default "30s",
+//     must be >= 0; "0" disables it).
+//   - HEDGE_DELAY: how long a hedged read waits for the first source before
+//     also asking the other (default "10ms", must be > 0 if set).
+//   - UPSTREAM_URL: base URL of an upstream flagz server. When set, the
+//     server runs as a read-only evaluation proxy without a database,
+//     mirroring the upstream project's flags in memory. ADMIN_HOSTNAME and
+//     KUBERNETES_SYNC cannot be used in this mode.
+//   - UPSTREAM_API_KEY: API key the proxy uses to read from the upstream
+//     server, required when UPSTREAM_URL is set. It also selects the
+//     project the proxy serves.
+//   - ERROR_FORMAT: body of HTTP error responses, "problem" (RFC 7807
+//     application/problem+json, the default) or "legacy" ({"error": "..."}
+//     JSON, with plain-text authentication failures).
+//   - EXPORT_S3_BUCKET: bucket that scheduled Parquet exports of the audit
+//     log and flag events are uploaded to. Setting it enables the export job,
+//     which cannot be used when UPSTREAM_URL is set.
+//   - EXPORT_S3_ENDPOINT: base URL of an S3-compatible store (default: the
+//     AWS S3 endpoint of EXPORT_S3_REGION).
+//   - EXPORT_S3_REGION: region used to sign uploads (default "us-east-1").
+//   - EXPORT_S3_PREFIX: key prefix of uploaded objects, such as "flagz/".
+//   - EXPORT_S3_ACCESS_KEY_ID, EXPORT_S3_SECRET_ACCESS_KEY: credentials for
+//     uploads, required when EXPORT_S3_BUCKET is set.
+//   - EXPORT_INTERVAL: length of each exported window and how often the job
+//     runs (default "24h", must be > 0 if set).
+//   - CORS_ALLOWED_ORIGINS: comma-separated origins, such as
+//     "https://dash.example.com", whose browser pages may call the HTTP API
+//     (default: none, CORS disabled). "*" allows any origin.
+//   - CORS_ALLOWED_HEADERS: comma-separated request headers browsers may
+//     send (default: Authorization, Content-Type, If-None-Match,
+//     Last-Event-ID, X-Request-ID and the gRPC-Web headers).
+//   - CORS_MAX_AGE: how long browsers may cache a preflight response
+//     (default "10m", must be >= 0).
+//   - GRPC_WEB: also serve the gRPC API to browsers as gRPC-Web on the HTTP
+//     port (default "false").
+//   - TLS_CERT_FILE, TLS_KEY_FILE: PEM certificate chain and private key.
+//     Setting both serves HTTP and gRPC over TLS; setting only one is an
+//     error.
+//   - TLS_CLIENT_CA_FILE: PEM bundle of CAs whose client certificates are
+//     accepted. Setting it requires every client to present one (mutual
+//     TLS), and requires TLS_CERT_FILE.
+//   - TLS_RELOAD_INTERVAL: how often the TLS files are checked for changes
+//     and reloaded (default "1m", must be > 0 if set). They are also
+//     reloaded on SIGHUP.
+//   - ADMIN_SESSION_IDLE_TIMEOUT: how long an admin portal session may go
+//     unused before it ends (default "1h", must be >= 0; "0" disables it).
+//     Sessions also end 24 hours after sign-in regardless.
+//   - ADMIN_PASSWORD_HASH: how new admin portal passwords are hashed,
+//     "argon2id" (default) or "bcrypt". Existing passwords are rehashed when
+//     their users next sign in.
+//   - ADMIN_PASSWORD_BCRYPT_COST: bcrypt work factor (default 12, 4 to 31).
+//   - ADMIN_PASSWORD_ARGON2: argon2id settings as "m=<KiB>,t=<iterations>,
+//     p=<lanes>" (default "m=65536,t=4,p=4").
+//   - ADMIN_PASSWORD_MIN_LENGTH: shortest admin portal password accepted, in
+//     characters (default 12, must be > 0).
+//   - ADMIN_PASSWORD_BREACH_LIST: file of breached passwords that are
+//     refused, one per line, either in plain text or as hex SHA-1 hashes
+//     (optionally followed by ":<count>", as in the Have I Been Pwned
+//     downloads).
+//   - OIDC_ISSUER_URL: OpenID Connect issuer the admin portal offers single
+//     sign-on through. Requires ADMIN_HOSTNAME, OIDC_CLIENT_ID,
+//     OIDC_CLIENT_SECRET and at least one of OIDC_ADMIN_GROUPS and
+//     OIDC_VIEWER_GROUPS.
+//   - OIDC_CLIENT_ID, OIDC_CLIENT_SECRET: the portal's client credentials.
+//   - OIDC_REDIRECT_URL: callback URL registered with the provider (default
+//     "http://<ADMIN_HOSTNAME>/oidc/callback").
+//   - OIDC_SCOPES: comma-separated scopes to request besides "openid",
+//     "profile" and "email", such as "groups".
+//   - OIDC_ROLES_CLAIM: ID token claim listing the user's groups or roles
+//     (default "groups"). A dotted path reaches into nested objects, such as
+//     "realm_access.roles".
+//   - OIDC_ADMIN_GROUPS, OIDC_VIEWER_GROUPS: comma-separated claim values
+//     granting the admin and viewer roles. Users in neither cannot sign in.
+//   - OIDC_PASSWORD_LOGIN: keep username and password login alongside
+//     single sign-on (default "true"). "false" makes SSO the only way in.
+//   - FLAG_EXPIRY_WEBHOOK_URL: http(s) URL that a JSON event is POSTed to
+//     when a flag passes its expires_at. Expiries are logged and audited
+//     whether or not it is set. Cannot be used when UPSTREAM_URL is set.
+//   - SMTP_ADDR: host:port of the mail server that email notification
+//     channels send through. Without it, email deliveries fail and are
+//     recorded as failed; Slack channels work either way.
+//   - SMTP_FROM: sender address of notification emails, required when
+//     SMTP_ADDR is set.
+//   - SMTP_USERNAME, SMTP_PASSWORD: PLAIN auth credentials, used when
+//     SMTP_USERNAME is set. Go only sends them over TLS or to localhost.
 package config
 
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -26,21 +235,97 @@ import (
 )
 
 const (
-	defaultHTTPAddr                  = ":8080"
-	defaultGRPCAddr                  = ":9090"
-	defaultStreamPollInterval        = time.Second
-	defaultTSStateDir                = "tsnet-state"
-	defaultAuthRateLimit             = 10
-	defaultMaxJSONBodySize     int64 = 1 << 20 // 1MB
-	defaultEventBatchSize            = 1000
-	defaultCacheResyncInterval       = time.Minute
+	defaultHTTPAddr                       = ":8080"
+	defaultGRPCAddr                       = ":9090"
+	defaultStreamPollInterval             = time.Second
+	defaultStreamKeepaliveInterval        = 30 * time.Second
+	defaultTSStateDir                     = "tsnet-state"
+	defaultAuthRateLimit                  = 10
+	defaultStreamWriteTimeout             = 10 * time.Second
+	defaultHTTPRateLimitBurst             = 20
+	defaultHTTP2MaxStreams                = 250
+	defaultMaxJSONBodySize          int64 = 1 << 20   // 1MB
+	defaultMaxEvaluateBodySize      int64 = 256 << 10 // 256KB
+	defaultMaxImportBodySize        int64 = 32 << 20  // 32MB
+	defaultEvaluateTimeout                = 5 * time.Second
+	defaultImportTimeout                  = 5 * time.Minute
+	defaultEventBatchSize                 = 1000
+	defaultCacheResyncInterval            = time.Minute
+	defaultWarmupTimeout                  = 30 * time.Second
+	defaultShutdownDrainDelay             = 5 * time.Second
+	defaultStreamDrainTimeout             = 10 * time.Second
+	defaultDatabaseStatementTimeout       = 30 * time.Second
+	defaultSDKPollInterval                = 30 * time.Second
+	defaultSDKMaxBatchSize                = 100
+	defaultSDKHeartbeatInterval           = 15 * time.Second
+	defaultKubernetesSyncInterval         = 30 * time.Second
+	defaultProjectRetention               = 30 * 24 * time.Hour
+	defaultIdempotencyKeyTTL              = 24 * time.Hour
+	defaultStatsFlushInterval             = 10 * time.Second
+	defaultMetricsFlagLabelLimit          = 1000
+	defaultEvaluationCacheSize            = 10000
+	defaultStaleFlagNotEvaluatedFor       = 30 * 24 * time.Hour
+	defaultStaleFlagNotModifiedFor        = 90 * 24 * time.Hour
+	defaultHedgeDelay                     = 10 * time.Millisecond
+	defaultExportInterval                 = 24 * time.Hour
+	defaultCORSMaxAge                     = 10 * time.Minute
+	defaultTLSReloadInterval              = time.Minute
+	defaultAdminSessionIdleTimeout        = time.Hour
+	defaultAdminPasswordBcryptCost        = 12
+	defaultAdminPasswordMinLength         = 12
+)
+
+// Default argon2id settings for ADMIN_PASSWORD_ARGON2.
+const (
+	defaultAdminPasswordArgon2Memory      = 64 * 1024
+	defaultAdminPasswordArgon2Iterations  = 4
+	defaultAdminPasswordArgon2Parallelism = 4
+)
+
+// Admin password hashing algorithms accepted by ADMIN_PASSWORD_HASH.
+const (
+	AdminPasswordHashArgon2id = "argon2id"
+	AdminPasswordHashBcrypt   = "bcrypt"
+)
+
+// defaultCORSAllowedHeaders covers the headers sent by the flagz clients and
+// by gRPC-Web.
+var defaultCORSAllowedHeaders = []string{
+	"Authorization",
+	"Content-Type",
+	"If-None-Match",
+	"Last-Event-ID",
+	"X-Request-ID",
+	"X-Grpc-Web",
+	"X-User-Agent",
+	"Grpc-Timeout",
+}
+
+// Cache invalidation transports accepted by CACHE_INVALIDATION.
+const (
+	CacheInvalidationPostgres = "postgres"
+	CacheInvalidationRedis    = "redis"
+)
+
+// Storage backends selected by the DATABASE_URL scheme.
+const (
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
+// HTTP error response formats accepted by ERROR_FORMAT.
+const (
+	ErrorFormatProblem = "problem"
+	ErrorFormatLegacy  = "legacy"
 )
 
 // Config holds the runtime configuration for the flagz server.
 type Config struct {
 	DatabaseURL         string
+	DatabaseDriver      string
 	HTTPAddr            string
 	GRPCAddr            string
+	Region              string
 	StreamPollInterval  time.Duration
 	LogLevel            string
 	AuthRateLimit       int
@@ -49,23 +334,199 @@ type Config struct {
 	TSStateDir          string
 	SessionSecret       string
 	MaxJSONBodySize     int64
+	MaxEvaluateBodySize int64
+	MaxImportBodySize   int64
+	EvaluateTimeout     time.Duration
+	ImportTimeout       time.Duration
 	EventBatchSize      int
 	CacheResyncInterval time.Duration
+	WarmupTimeout       time.Duration
+	ShutdownDrainDelay  time.Duration
+	StreamDrainTimeout  time.Duration
+	CacheInvalidation   string
+	RedisURL            string
+	RunMigrations       bool
+
+	// StreamKeepaliveInterval is the longest an SSE stream stays silent.
+	StreamKeepaliveInterval time.Duration
+
+	// Protections for servers exposed to the internet; see
+	// server.WithStreamWriteTimeout, server.NewStreamLimiter,
+	// server.NewEvaluationLimiter and middleware.IPThrottle. Zero disables
+	// each limit.
+	StreamWriteTimeout        time.Duration
+	MaxStreamsPerAPIKey       int
+	MaxEvaluationsInFlight    int
+	MaxQueuedEvaluations      int
+	HTTPRateLimitPerIP        float64
+	HTTPRateLimitBurst        int
+	HTTP2MaxConcurrentStreams uint32
+	AdminPortalEnabled        bool
+
+	// LogRouteLevels and LogSampleRates are keyed by path prefix; see
+	// middleware.WithRouteLevels and middleware.WithRouteSampleRates.
+	LogRouteLevels map[string]slog.Level
+	LogSampleRates map[string]float64
+
+	// SDK hints sent to clients on connect; see server.SDKConfig.
+	SDKPollInterval      time.Duration
+	SDKMaxBatchSize      int
+	SDKHeartbeatInterval time.Duration
+
+	KubernetesSync         bool
+	KubernetesNamespace    string
+	KubernetesSyncInterval time.Duration
+	ProjectRetention       time.Duration
+	IdempotencyKeyTTL      time.Duration
+	StatsFlushInterval     time.Duration
+
+	// Evaluation result cache; see service.WithEvaluationCache. A zero TTL
+	// disables it.
+	EvaluationCacheTTL  time.Duration
+	EvaluationCacheSize int
+
+	// MetricsFlagLabelLimit; see metrics.WithFlagLabelLimit.
+	MetricsFlagLabelLimit int
+
+	// Stale flag report thresholds; see service.StaleThresholds.
+	StaleFlagNotEvaluatedFor time.Duration
+	StaleFlagNotModifiedFor  time.Duration
+
+	// DatabaseReplicaURL enables hedged flag reads; see service.WithHedgedReads.
+	DatabaseReplicaURL string
+	HedgeDelay         time.Duration
+
+	// DatabaseReadURL routes reads to a replica; see repository.WithReadPool.
+	DatabaseReadURL string
+
+	// VariantsKeyFile enables encryption of flag variants at rest; see
+	// repository.WithVariantsEncryption.
+	VariantsKeyFile string
+
+	// Connection pool tuning applied to every PostgreSQL pool; zero keeps
+	// the default. See repository.PoolConfig.
+	DatabaseMaxConns          int32
+	DatabaseMinConns          int32
+	DatabaseMaxConnLifetime   time.Duration
+	DatabaseMaxConnIdleTime   time.Duration
+	DatabaseHealthCheckPeriod time.Duration
+	DatabaseStatementTimeout  time.Duration
+
+	// UpstreamURL enables read-only proxy mode; see package proxy.
+	UpstreamURL    string
+	UpstreamAPIKey string
+
+	// ErrorFormat is ErrorFormatProblem or ErrorFormatLegacy.
+	ErrorFormat string
+
+	// ExportS3Bucket enables scheduled exports; see export.Scheduler.
+	ExportS3Bucket          string
+	ExportS3Endpoint        string
+	ExportS3Region          string
+	ExportS3Prefix          string
+	ExportS3AccessKeyID     string
+	ExportS3SecretAccessKey string
+	ExportInterval          time.Duration
+
+	// CORSAllowedOrigins enables CORS; see middleware.CORS.
+	CORSAllowedOrigins []string
+	CORSAllowedHeaders []string
+	CORSMaxAge         time.Duration
+	// GRPCWeb serves gRPC-Web on the HTTP listener.
+	GRPCWeb bool
+
+	// TLSCertFile enables TLS on both listeners; see package tlsreload.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSReloadInterval time.Duration
+
+	// OIDCIssuerURL enables admin portal single sign-on; see admin.OIDCLogin.
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCRolesClaim    string
+	OIDCAdminGroups   []string
+	OIDCViewerGroups  []string
+	OIDCPasswordLogin bool
+
+	// AdminSessionIdleTimeout; see admin.WithIdleTimeout.
+	AdminSessionIdleTimeout time.Duration
+
+	// Admin password hashing and policy; see admin.PasswordHasher and
+	// admin.PasswordPolicy.
+	AdminPasswordHash              string
+	AdminPasswordBcryptCost        int
+	AdminPasswordArgon2Memory      uint32
+	AdminPasswordArgon2Iterations  uint32
+	AdminPasswordArgon2Parallelism uint8
+	AdminPasswordMinLength         int
+	AdminPasswordBreachList        string
+
+	// FlagExpiryWebhookURL receives flag expiry reminders; see
+	// service.Service.RemindExpiredFlags.
+	FlagExpiryWebhookURL string
+
+	// SMTPAddr is the mail server of email notifications, or empty when
+	// email is not configured.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
-// Load reads configuration from environment variables, applying defaults where
-// appropriate. It returns an error if required variables are missing or if
-// optional values fail validation.
+// Load reads configuration from the optional FLAGZ_CONFIG file and environment
+// variables, applying defaults where appropriate. It returns an error if the
+// file cannot be read, required variables are missing, or optional values
+// fail validation.
 func Load() (Config, error) {
-	databaseURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
-	if databaseURL == "" {
+	file := map[string]string{}
+	if path := strings.TrimSpace(os.Getenv("FLAGZ_CONFIG")); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		file = values
+	}
+
+	getenv := func(key string) string {
+		if value := os.Getenv(key); strings.TrimSpace(value) != "" {
+			return value
+		}
+		return file[key]
+	}
+	orDefault := func(key, fallback string) string {
+		if value := strings.TrimSpace(getenv(key)); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	upstreamURL := strings.TrimSpace(getenv("UPSTREAM_URL"))
+	upstreamAPIKey := strings.TrimSpace(getenv("UPSTREAM_API_KEY"))
+	if upstreamURL != "" && upstreamAPIKey == "" {
+		return Config{}, errors.New("UPSTREAM_API_KEY is required when UPSTREAM_URL is set")
+	}
+
+	databaseURL := strings.TrimSpace(getenv("DATABASE_URL"))
+	if databaseURL == "" && upstreamURL == "" {
 		return Config{}, errors.New("DATABASE_URL is required")
 	}
+	databaseReadURL := strings.TrimSpace(getenv("DATABASE_READ_URL"))
+	if databaseReadURL != "" && strings.TrimSpace(getenv("DATABASE_REPLICA_URL")) != "" {
+		return Config{}, errors.New("DATABASE_READ_URL and DATABASE_REPLICA_URL cannot both be set")
+	}
+	databaseDriver := DatabaseDriverPostgres
+	if lower := strings.ToLower(databaseURL); strings.HasPrefix(lower, "sqlite:") || strings.HasPrefix(lower, "file:") {
+		databaseDriver = DatabaseDriverSQLite
+	}
 
-	sessionSecret := strings.TrimSpace(os.Getenv("SESSION_SECRET"))
+	sessionSecret := strings.TrimSpace(getenv("SESSION_SECRET"))
 
 	streamPollInterval := defaultStreamPollInterval
-	if value := strings.TrimSpace(os.Getenv("STREAM_POLL_INTERVAL")); value != "" {
+	if value := strings.TrimSpace(getenv("STREAM_POLL_INTERVAL")); value != "" {
 		parsed, err := time.ParseDuration(value)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse STREAM_POLL_INTERVAL: %w", err)
@@ -76,8 +537,20 @@ func Load() (Config, error) {
 		streamPollInterval = parsed
 	}
 
+	streamKeepaliveInterval := defaultStreamKeepaliveInterval
+	if value := strings.TrimSpace(getenv("STREAM_KEEPALIVE_INTERVAL")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STREAM_KEEPALIVE_INTERVAL: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("STREAM_KEEPALIVE_INTERVAL must be >= 0")
+		}
+		streamKeepaliveInterval = parsed
+	}
+
 	authRateLimit := defaultAuthRateLimit
-	if value := strings.TrimSpace(os.Getenv("AUTH_RATE_LIMIT")); value != "" {
+	if value := strings.TrimSpace(getenv("AUTH_RATE_LIMIT")); value != "" {
 		parsed, err := strconv.Atoi(value)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse AUTH_RATE_LIMIT: %w", err)
@@ -88,8 +561,113 @@ func Load() (Config, error) {
 		authRateLimit = parsed
 	}
 
+	streamWriteTimeout := defaultStreamWriteTimeout
+	if value := strings.TrimSpace(getenv("STREAM_WRITE_TIMEOUT")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STREAM_WRITE_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("STREAM_WRITE_TIMEOUT must be >= 0")
+		}
+		streamWriteTimeout = parsed
+	}
+
+	var maxStreamsPerAPIKey int
+	if value := strings.TrimSpace(getenv("MAX_STREAMS_PER_API_KEY")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse MAX_STREAMS_PER_API_KEY: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("MAX_STREAMS_PER_API_KEY must be >= 0")
+		}
+		maxStreamsPerAPIKey = parsed
+	}
+
+	var maxEvaluationsInFlight int
+	if value := strings.TrimSpace(getenv("MAX_EVALUATIONS_IN_FLIGHT")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse MAX_EVALUATIONS_IN_FLIGHT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("MAX_EVALUATIONS_IN_FLIGHT must be >= 0")
+		}
+		maxEvaluationsInFlight = parsed
+	}
+
+	var maxQueuedEvaluations int
+	if value := strings.TrimSpace(getenv("MAX_QUEUED_EVALUATIONS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse MAX_QUEUED_EVALUATIONS: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("MAX_QUEUED_EVALUATIONS must be >= 0")
+		}
+		maxQueuedEvaluations = parsed
+	}
+
+	var httpRateLimitPerIP float64
+	if value := strings.TrimSpace(getenv("HTTP_RATE_LIMIT_PER_IP")); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse HTTP_RATE_LIMIT_PER_IP: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("HTTP_RATE_LIMIT_PER_IP must be >= 0")
+		}
+		httpRateLimitPerIP = parsed
+	}
+
+	httpRateLimitBurst := defaultHTTPRateLimitBurst
+	if value := strings.TrimSpace(getenv("HTTP_RATE_LIMIT_BURST")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse HTTP_RATE_LIMIT_BURST: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("HTTP_RATE_LIMIT_BURST must be > 0")
+		}
+		httpRateLimitBurst = parsed
+	}
+
+	var http2MaxConcurrentStreams uint32 = defaultHTTP2MaxStreams
+	if value := strings.TrimSpace(getenv("HTTP2_MAX_CONCURRENT_STREAMS")); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse HTTP2_MAX_CONCURRENT_STREAMS: %w", err)
+		}
+		if parsed == 0 {
+			return Config{}, errors.New("HTTP2_MAX_CONCURRENT_STREAMS must be > 0")
+		}
+		http2MaxConcurrentStreams = uint32(parsed)
+	}
+
+	logRouteLevels, err := parseRouteValues(getenv("LOG_ROUTE_LEVELS"), parseLogLevel)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse LOG_ROUTE_LEVELS: %w", err)
+	}
+	logSampleRates, err := parseRouteValues(getenv("LOG_SAMPLE_RATES"), parseSampleRate)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse LOG_SAMPLE_RATES: %w", err)
+	}
+
+	adminPortalEnabled := true
+	if v := strings.TrimSpace(getenv("ADMIN_PORTAL_ENABLED")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse ADMIN_PORTAL_ENABLED: %w", err)
+		}
+		adminPortalEnabled = parsed
+	}
+
 	// Admin Portal Config
-	adminHostname := strings.TrimSpace(os.Getenv("ADMIN_HOSTNAME"))
+	adminHostname := strings.TrimSpace(getenv("ADMIN_HOSTNAME"))
+	if !adminPortalEnabled {
+		adminHostname = ""
+	}
 	if adminHostname != "" && sessionSecret == "" {
 		return Config{}, errors.New("SESSION_SECRET is required when ADMIN_HOSTNAME is set")
 	}
@@ -98,7 +676,7 @@ func Load() (Config, error) {
 	}
 
 	maxJSONBodySize := defaultMaxJSONBodySize
-	if v := strings.TrimSpace(os.Getenv("MAX_JSON_BODY_SIZE")); v != "" {
+	if v := strings.TrimSpace(getenv("MAX_JSON_BODY_SIZE")); v != "" {
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil || n < 1 {
 			return Config{}, errors.New("MAX_JSON_BODY_SIZE must be a positive integer (bytes)")
@@ -106,8 +684,50 @@ func Load() (Config, error) {
 		maxJSONBodySize = n
 	}
 
+	maxEvaluateBodySize := defaultMaxEvaluateBodySize
+	if v := strings.TrimSpace(getenv("MAX_EVALUATE_BODY_SIZE")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return Config{}, errors.New("MAX_EVALUATE_BODY_SIZE must be a positive integer (bytes)")
+		}
+		maxEvaluateBodySize = n
+	}
+
+	maxImportBodySize := defaultMaxImportBodySize
+	if v := strings.TrimSpace(getenv("MAX_IMPORT_BODY_SIZE")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return Config{}, errors.New("MAX_IMPORT_BODY_SIZE must be a positive integer (bytes)")
+		}
+		maxImportBodySize = n
+	}
+
+	evaluateTimeout := defaultEvaluateTimeout
+	if v := strings.TrimSpace(getenv("EVALUATE_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse EVALUATE_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("EVALUATE_TIMEOUT must be >= 0")
+		}
+		evaluateTimeout = parsed
+	}
+
+	importTimeout := defaultImportTimeout
+	if v := strings.TrimSpace(getenv("IMPORT_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse IMPORT_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("IMPORT_TIMEOUT must be >= 0")
+		}
+		importTimeout = parsed
+	}
+
 	eventBatchSize := defaultEventBatchSize
-	if v := strings.TrimSpace(os.Getenv("EVENT_BATCH_SIZE")); v != "" {
+	if v := strings.TrimSpace(getenv("EVENT_BATCH_SIZE")); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil || n < 1 {
 			return Config{}, errors.New("EVENT_BATCH_SIZE must be a positive integer")
@@ -116,7 +736,7 @@ func Load() (Config, error) {
 	}
 
 	cacheResyncInterval := defaultCacheResyncInterval
-	if v := strings.TrimSpace(os.Getenv("CACHE_RESYNC_INTERVAL")); v != "" {
+	if v := strings.TrimSpace(getenv("CACHE_RESYNC_INTERVAL")); v != "" {
 		parsed, err := time.ParseDuration(v)
 		if err != nil {
 			return Config{}, fmt.Errorf("parse CACHE_RESYNC_INTERVAL: %w", err)
@@ -127,23 +747,744 @@ func Load() (Config, error) {
 		cacheResyncInterval = parsed
 	}
 
+	warmupTimeout := defaultWarmupTimeout
+	if v := strings.TrimSpace(getenv("WARMUP_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse WARMUP_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("WARMUP_TIMEOUT must be >= 0")
+		}
+		warmupTimeout = parsed
+	}
+
+	shutdownDrainDelay := defaultShutdownDrainDelay
+	if v := strings.TrimSpace(getenv("SHUTDOWN_DRAIN_DELAY")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SHUTDOWN_DRAIN_DELAY: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("SHUTDOWN_DRAIN_DELAY must be >= 0")
+		}
+		shutdownDrainDelay = parsed
+	}
+
+	streamDrainTimeout := defaultStreamDrainTimeout
+	if v := strings.TrimSpace(getenv("STREAM_DRAIN_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STREAM_DRAIN_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("STREAM_DRAIN_TIMEOUT must be >= 0")
+		}
+		streamDrainTimeout = parsed
+	}
+
+	cacheInvalidation := strings.ToLower(orDefault("CACHE_INVALIDATION", CacheInvalidationPostgres))
+	redisURL := strings.TrimSpace(getenv("REDIS_URL"))
+	switch cacheInvalidation {
+	case CacheInvalidationPostgres:
+	case CacheInvalidationRedis:
+		if redisURL == "" {
+			return Config{}, errors.New("REDIS_URL is required when CACHE_INVALIDATION is redis")
+		}
+	default:
+		return Config{}, fmt.Errorf("CACHE_INVALIDATION must be %q or %q", CacheInvalidationPostgres, CacheInvalidationRedis)
+	}
+
+	runMigrations := true
+	if v := strings.TrimSpace(getenv("RUN_MIGRATIONS")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse RUN_MIGRATIONS: %w", err)
+		}
+		runMigrations = parsed
+	}
+
+	sdkPollInterval := defaultSDKPollInterval
+	if v := strings.TrimSpace(getenv("SDK_POLL_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SDK_POLL_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("SDK_POLL_INTERVAL must be > 0")
+		}
+		sdkPollInterval = parsed
+	}
+
+	sdkMaxBatchSize := defaultSDKMaxBatchSize
+	if v := strings.TrimSpace(getenv("SDK_MAX_BATCH_SIZE")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Config{}, errors.New("SDK_MAX_BATCH_SIZE must be a positive integer")
+		}
+		sdkMaxBatchSize = n
+	}
+
+	sdkHeartbeatInterval := defaultSDKHeartbeatInterval
+	if v := strings.TrimSpace(getenv("SDK_HEARTBEAT_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse SDK_HEARTBEAT_INTERVAL: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("SDK_HEARTBEAT_INTERVAL must be >= 0")
+		}
+		sdkHeartbeatInterval = parsed
+	}
+
+	kubernetesSync := false
+	if v := strings.TrimSpace(getenv("KUBERNETES_SYNC")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse KUBERNETES_SYNC: %w", err)
+		}
+		kubernetesSync = parsed
+	}
+
+	kubernetesSyncInterval := defaultKubernetesSyncInterval
+	if v := strings.TrimSpace(getenv("KUBERNETES_SYNC_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse KUBERNETES_SYNC_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("KUBERNETES_SYNC_INTERVAL must be > 0")
+		}
+		kubernetesSyncInterval = parsed
+	}
+
+	projectRetention := defaultProjectRetention
+	if v := strings.TrimSpace(getenv("PROJECT_RETENTION")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROJECT_RETENTION: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("PROJECT_RETENTION must be > 0")
+		}
+		projectRetention = parsed
+	}
+
+	idempotencyKeyTTL := defaultIdempotencyKeyTTL
+	if v := strings.TrimSpace(getenv("IDEMPOTENCY_KEY_TTL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse IDEMPOTENCY_KEY_TTL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("IDEMPOTENCY_KEY_TTL must be > 0")
+		}
+		idempotencyKeyTTL = parsed
+	}
+
+	statsFlushInterval := defaultStatsFlushInterval
+	if v := strings.TrimSpace(getenv("STATS_FLUSH_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STATS_FLUSH_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("STATS_FLUSH_INTERVAL must be > 0")
+		}
+		statsFlushInterval = parsed
+	}
+
+	var evaluationCacheTTL time.Duration
+	if v := strings.TrimSpace(getenv("EVALUATION_CACHE_TTL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse EVALUATION_CACHE_TTL: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("EVALUATION_CACHE_TTL must be >= 0")
+		}
+		evaluationCacheTTL = parsed
+	}
+
+	evaluationCacheSize := defaultEvaluationCacheSize
+	if v := strings.TrimSpace(getenv("EVALUATION_CACHE_SIZE")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Config{}, errors.New("EVALUATION_CACHE_SIZE must be a positive integer")
+		}
+		evaluationCacheSize = n
+	}
+
+	metricsFlagLabelLimit := defaultMetricsFlagLabelLimit
+	if v := strings.TrimSpace(getenv("METRICS_FLAG_LABEL_LIMIT")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, errors.New("METRICS_FLAG_LABEL_LIMIT must be a non-negative integer")
+		}
+		metricsFlagLabelLimit = n
+	}
+
+	staleFlagNotEvaluatedFor := defaultStaleFlagNotEvaluatedFor
+	if v := strings.TrimSpace(getenv("STALE_FLAG_NOT_EVALUATED_FOR")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STALE_FLAG_NOT_EVALUATED_FOR: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("STALE_FLAG_NOT_EVALUATED_FOR must be > 0")
+		}
+		staleFlagNotEvaluatedFor = parsed
+	}
+
+	staleFlagNotModifiedFor := defaultStaleFlagNotModifiedFor
+	if v := strings.TrimSpace(getenv("STALE_FLAG_NOT_MODIFIED_FOR")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse STALE_FLAG_NOT_MODIFIED_FOR: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("STALE_FLAG_NOT_MODIFIED_FOR must be > 0")
+		}
+		staleFlagNotModifiedFor = parsed
+	}
+
+	hedgeDelay := defaultHedgeDelay
+	if v := strings.TrimSpace(getenv("HEDGE_DELAY")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse HEDGE_DELAY: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("HEDGE_DELAY must be > 0")
+		}
+		hedgeDelay = parsed
+	}
+
+	var databaseMaxConns, databaseMinConns int32
+	for _, setting := range []struct {
+		key string
+		dst *int32
+	}{
+		{"DATABASE_MAX_CONNS", &databaseMaxConns},
+		{"DATABASE_MIN_CONNS", &databaseMinConns},
+	} {
+		if v := strings.TrimSpace(getenv(setting.key)); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || n < 1 {
+				return Config{}, fmt.Errorf("%s must be a positive integer", setting.key)
+			}
+			*setting.dst = int32(n)
+		}
+	}
+	if databaseMaxConns > 0 && databaseMinConns > databaseMaxConns {
+		return Config{}, errors.New("DATABASE_MIN_CONNS must be <= DATABASE_MAX_CONNS")
+	}
+
+	var databaseMaxConnLifetime, databaseMaxConnIdleTime, databaseHealthCheckPeriod time.Duration
+	for _, setting := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"DATABASE_MAX_CONN_LIFETIME", &databaseMaxConnLifetime},
+		{"DATABASE_MAX_CONN_IDLE_TIME", &databaseMaxConnIdleTime},
+		{"DATABASE_HEALTH_CHECK_PERIOD", &databaseHealthCheckPeriod},
+	} {
+		if v := strings.TrimSpace(getenv(setting.key)); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("parse %s: %w", setting.key, err)
+			}
+			if parsed <= 0 {
+				return Config{}, fmt.Errorf("%s must be > 0", setting.key)
+			}
+			*setting.dst = parsed
+		}
+	}
+
+	databaseStatementTimeout := defaultDatabaseStatementTimeout
+	if v := strings.TrimSpace(getenv("DATABASE_STATEMENT_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse DATABASE_STATEMENT_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("DATABASE_STATEMENT_TIMEOUT must be >= 0")
+		}
+		databaseStatementTimeout = parsed
+	}
+
+	errorFormat := strings.ToLower(orDefault("ERROR_FORMAT", ErrorFormatProblem))
+	if errorFormat != ErrorFormatProblem && errorFormat != ErrorFormatLegacy {
+		return Config{}, fmt.Errorf("ERROR_FORMAT must be %q or %q", ErrorFormatProblem, ErrorFormatLegacy)
+	}
+
+	variantsKeyFile := strings.TrimSpace(getenv("VARIANTS_KEY_FILE"))
+
+	exportS3Bucket := strings.TrimSpace(getenv("EXPORT_S3_BUCKET"))
+	exportS3AccessKeyID := strings.TrimSpace(getenv("EXPORT_S3_ACCESS_KEY_ID"))
+	exportS3SecretAccessKey := getenv("EXPORT_S3_SECRET_ACCESS_KEY")
+	if exportS3Bucket != "" && (exportS3AccessKeyID == "" || exportS3SecretAccessKey == "") {
+		return Config{}, errors.New("EXPORT_S3_ACCESS_KEY_ID and EXPORT_S3_SECRET_ACCESS_KEY are required when EXPORT_S3_BUCKET is set")
+	}
+
+	exportInterval := defaultExportInterval
+	if v := strings.TrimSpace(getenv("EXPORT_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse EXPORT_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("EXPORT_INTERVAL must be > 0")
+		}
+		exportInterval = parsed
+	}
+
+	corsAllowedOrigins, err := parseCORSOrigins(getenv("CORS_ALLOWED_ORIGINS"))
+	if err != nil {
+		return Config{}, err
+	}
+	corsAllowedHeaders := defaultCORSAllowedHeaders
+	if v := splitList(getenv("CORS_ALLOWED_HEADERS")); len(v) > 0 {
+		corsAllowedHeaders = v
+	}
+
+	corsMaxAge := defaultCORSMaxAge
+	if v := strings.TrimSpace(getenv("CORS_MAX_AGE")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse CORS_MAX_AGE: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("CORS_MAX_AGE must be >= 0")
+		}
+		corsMaxAge = parsed
+	}
+
+	grpcWeb := false
+	if v := strings.TrimSpace(getenv("GRPC_WEB")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse GRPC_WEB: %w", err)
+		}
+		grpcWeb = parsed
+	}
+
+	tlsCertFile := strings.TrimSpace(getenv("TLS_CERT_FILE"))
+	tlsKeyFile := strings.TrimSpace(getenv("TLS_KEY_FILE"))
+	tlsClientCAFile := strings.TrimSpace(getenv("TLS_CLIENT_CA_FILE"))
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return Config{}, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if tlsClientCAFile != "" && tlsCertFile == "" {
+		return Config{}, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+
+	tlsReloadInterval := defaultTLSReloadInterval
+	if v := strings.TrimSpace(getenv("TLS_RELOAD_INTERVAL")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse TLS_RELOAD_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("TLS_RELOAD_INTERVAL must be > 0")
+		}
+		tlsReloadInterval = parsed
+	}
+
+	adminSessionIdleTimeout := defaultAdminSessionIdleTimeout
+	if v := strings.TrimSpace(getenv("ADMIN_SESSION_IDLE_TIMEOUT")); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse ADMIN_SESSION_IDLE_TIMEOUT: %w", err)
+		}
+		if parsed < 0 {
+			return Config{}, errors.New("ADMIN_SESSION_IDLE_TIMEOUT must be >= 0")
+		}
+		adminSessionIdleTimeout = parsed
+	}
+
+	adminPasswordHash := AdminPasswordHashArgon2id
+	if v := strings.TrimSpace(getenv("ADMIN_PASSWORD_HASH")); v != "" {
+		switch v {
+		case AdminPasswordHashArgon2id, AdminPasswordHashBcrypt:
+			adminPasswordHash = v
+		default:
+			return Config{}, fmt.Errorf("ADMIN_PASSWORD_HASH must be %q or %q", AdminPasswordHashArgon2id, AdminPasswordHashBcrypt)
+		}
+	}
+	adminPasswordBcryptCost := defaultAdminPasswordBcryptCost
+	if v := strings.TrimSpace(getenv("ADMIN_PASSWORD_BCRYPT_COST")); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse ADMIN_PASSWORD_BCRYPT_COST: %w", err)
+		}
+		if parsed < 4 || parsed > 31 {
+			return Config{}, errors.New("ADMIN_PASSWORD_BCRYPT_COST must be between 4 and 31")
+		}
+		adminPasswordBcryptCost = parsed
+	}
+	var adminPasswordArgon2Memory, adminPasswordArgon2Iterations uint32 = defaultAdminPasswordArgon2Memory, defaultAdminPasswordArgon2Iterations
+	var adminPasswordArgon2Parallelism uint8 = defaultAdminPasswordArgon2Parallelism
+	if v := strings.TrimSpace(getenv("ADMIN_PASSWORD_ARGON2")); v != "" {
+		var err error
+		adminPasswordArgon2Memory, adminPasswordArgon2Iterations, adminPasswordArgon2Parallelism, err = parseArgon2Params(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse ADMIN_PASSWORD_ARGON2: %w", err)
+		}
+	}
+	adminPasswordMinLength := defaultAdminPasswordMinLength
+	if v := strings.TrimSpace(getenv("ADMIN_PASSWORD_MIN_LENGTH")); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse ADMIN_PASSWORD_MIN_LENGTH: %w", err)
+		}
+		if parsed <= 0 {
+			return Config{}, errors.New("ADMIN_PASSWORD_MIN_LENGTH must be > 0")
+		}
+		adminPasswordMinLength = parsed
+	}
+	adminPasswordBreachList := strings.TrimSpace(getenv("ADMIN_PASSWORD_BREACH_LIST"))
+
+	oidcIssuerURL := strings.TrimSpace(getenv("OIDC_ISSUER_URL"))
+	oidcClientID := strings.TrimSpace(getenv("OIDC_CLIENT_ID"))
+	oidcClientSecret := strings.TrimSpace(getenv("OIDC_CLIENT_SECRET"))
+	oidcRedirectURL := strings.TrimSpace(getenv("OIDC_REDIRECT_URL"))
+	oidcRolesClaim := strings.TrimSpace(getenv("OIDC_ROLES_CLAIM"))
+	oidcAdminGroups := splitList(getenv("OIDC_ADMIN_GROUPS"))
+	oidcViewerGroups := splitList(getenv("OIDC_VIEWER_GROUPS"))
+	oidcPasswordLogin := true
+	if v := strings.TrimSpace(getenv("OIDC_PASSWORD_LOGIN")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse OIDC_PASSWORD_LOGIN: %w", err)
+		}
+		oidcPasswordLogin = parsed
+	}
+	if oidcIssuerURL != "" {
+		if u, err := url.Parse(oidcIssuerURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return Config{}, errors.New("OIDC_ISSUER_URL must be an http(s) URL")
+		}
+		if adminHostname == "" {
+			return Config{}, errors.New("OIDC_ISSUER_URL requires ADMIN_HOSTNAME")
+		}
+		if oidcClientID == "" || oidcClientSecret == "" {
+			return Config{}, errors.New("OIDC_CLIENT_ID and OIDC_CLIENT_SECRET are required when OIDC_ISSUER_URL is set")
+		}
+		if len(oidcAdminGroups) == 0 && len(oidcViewerGroups) == 0 {
+			return Config{}, errors.New("OIDC_ADMIN_GROUPS or OIDC_VIEWER_GROUPS is required when OIDC_ISSUER_URL is set")
+		}
+		if oidcRedirectURL == "" {
+			oidcRedirectURL = "http://" + adminHostname + "/oidc/callback"
+		}
+		if oidcRolesClaim == "" {
+			oidcRolesClaim = "groups"
+		}
+	} else if !oidcPasswordLogin {
+		return Config{}, errors.New("OIDC_PASSWORD_LOGIN can only be disabled when OIDC_ISSUER_URL is set")
+	}
+
+	flagExpiryWebhookURL := strings.TrimSpace(getenv("FLAG_EXPIRY_WEBHOOK_URL"))
+	if flagExpiryWebhookURL != "" {
+		if u, err := url.Parse(flagExpiryWebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return Config{}, errors.New("FLAG_EXPIRY_WEBHOOK_URL must be an http(s) URL")
+		}
+	}
+
+	smtpAddr := strings.TrimSpace(getenv("SMTP_ADDR"))
+	smtpFrom := strings.TrimSpace(getenv("SMTP_FROM"))
+	smtpUsername := getenv("SMTP_USERNAME")
+	smtpPassword := getenv("SMTP_PASSWORD")
+	if smtpAddr != "" {
+		if host, port, err := net.SplitHostPort(smtpAddr); err != nil || host == "" || port == "" {
+			return Config{}, errors.New("SMTP_ADDR must be host:port")
+		}
+		if smtpFrom == "" {
+			return Config{}, errors.New("SMTP_FROM is required when SMTP_ADDR is set")
+		}
+		if _, err := mail.ParseAddress(smtpFrom); err != nil {
+			return Config{}, fmt.Errorf("SMTP_FROM must be an email address: %w", err)
+		}
+	} else if smtpFrom != "" || smtpUsername != "" || smtpPassword != "" {
+		return Config{}, errors.New("SMTP_FROM, SMTP_USERNAME and SMTP_PASSWORD require SMTP_ADDR")
+	}
+
+	if upstreamURL != "" {
+		if flagExpiryWebhookURL != "" {
+			return Config{}, errors.New("FLAG_EXPIRY_WEBHOOK_URL cannot be set when UPSTREAM_URL is set")
+		}
+		if adminHostname != "" {
+			return Config{}, errors.New("ADMIN_HOSTNAME cannot be set when UPSTREAM_URL is set")
+		}
+		if kubernetesSync {
+			return Config{}, errors.New("KUBERNETES_SYNC cannot be enabled when UPSTREAM_URL is set")
+		}
+		if exportS3Bucket != "" {
+			return Config{}, errors.New("EXPORT_S3_BUCKET cannot be set when UPSTREAM_URL is set")
+		}
+		if variantsKeyFile != "" {
+			return Config{}, errors.New("VARIANTS_KEY_FILE cannot be set when UPSTREAM_URL is set")
+		}
+	}
+
+	if databaseDriver == DatabaseDriverSQLite && upstreamURL == "" {
+		if adminHostname != "" {
+			return Config{}, errors.New("ADMIN_HOSTNAME cannot be set with a SQLite DATABASE_URL")
+		}
+		if strings.TrimSpace(getenv("DATABASE_REPLICA_URL")) != "" {
+			return Config{}, errors.New("DATABASE_REPLICA_URL cannot be set with a SQLite DATABASE_URL")
+		}
+		if databaseReadURL != "" {
+			return Config{}, errors.New("DATABASE_READ_URL cannot be set with a SQLite DATABASE_URL")
+		}
+		if cacheInvalidation == CacheInvalidationRedis {
+			return Config{}, errors.New("CACHE_INVALIDATION cannot be redis with a SQLite DATABASE_URL")
+		}
+		if exportS3Bucket != "" {
+			return Config{}, errors.New("EXPORT_S3_BUCKET cannot be set with a SQLite DATABASE_URL")
+		}
+		if variantsKeyFile != "" {
+			return Config{}, errors.New("VARIANTS_KEY_FILE cannot be set with a SQLite DATABASE_URL")
+		}
+	}
+
 	return Config{
 		DatabaseURL:         databaseURL,
-		HTTPAddr:            envOrDefault("HTTP_ADDR", defaultHTTPAddr),
-		GRPCAddr:            envOrDefault("GRPC_ADDR", defaultGRPCAddr),
+		DatabaseDriver:      databaseDriver,
+		HTTPAddr:            orDefault("HTTP_ADDR", defaultHTTPAddr),
+		GRPCAddr:            orDefault("GRPC_ADDR", defaultGRPCAddr),
+		Region:              strings.TrimSpace(getenv("REGION")),
 		StreamPollInterval:  streamPollInterval,
-		LogLevel:            envOrDefault("LOG_LEVEL", "info"),
+		LogLevel:            orDefault("LOG_LEVEL", "info"),
 		AuthRateLimit:       authRateLimit,
 		AdminHostname:       adminHostname,
-		TSAuthKey:           os.Getenv("TS_AUTH_KEY"),
-		TSStateDir:          envOrDefault("TS_STATE_DIR", defaultTSStateDir),
+		TSAuthKey:           getenv("TS_AUTH_KEY"),
+		TSStateDir:          orDefault("TS_STATE_DIR", defaultTSStateDir),
 		SessionSecret:       sessionSecret,
 		MaxJSONBodySize:     maxJSONBodySize,
+		MaxEvaluateBodySize: maxEvaluateBodySize,
+		MaxImportBodySize:   maxImportBodySize,
+		EvaluateTimeout:     evaluateTimeout,
+		ImportTimeout:       importTimeout,
 		EventBatchSize:      eventBatchSize,
 		CacheResyncInterval: cacheResyncInterval,
+		WarmupTimeout:       warmupTimeout,
+		ShutdownDrainDelay:  shutdownDrainDelay,
+		StreamDrainTimeout:  streamDrainTimeout,
+		CacheInvalidation:   cacheInvalidation,
+		RedisURL:            redisURL,
+		RunMigrations:       runMigrations,
+
+		StreamKeepaliveInterval: streamKeepaliveInterval,
+
+		StreamWriteTimeout:        streamWriteTimeout,
+		MaxStreamsPerAPIKey:       maxStreamsPerAPIKey,
+		MaxEvaluationsInFlight:    maxEvaluationsInFlight,
+		MaxQueuedEvaluations:      maxQueuedEvaluations,
+		HTTPRateLimitPerIP:        httpRateLimitPerIP,
+		HTTPRateLimitBurst:        httpRateLimitBurst,
+		HTTP2MaxConcurrentStreams: http2MaxConcurrentStreams,
+		AdminPortalEnabled:        adminPortalEnabled,
+
+		LogRouteLevels: logRouteLevels,
+		LogSampleRates: logSampleRates,
+
+		SDKPollInterval:      sdkPollInterval,
+		SDKMaxBatchSize:      sdkMaxBatchSize,
+		SDKHeartbeatInterval: sdkHeartbeatInterval,
+
+		KubernetesSync:         kubernetesSync,
+		KubernetesNamespace:    strings.TrimSpace(getenv("KUBERNETES_NAMESPACE")),
+		KubernetesSyncInterval: kubernetesSyncInterval,
+		ProjectRetention:       projectRetention,
+		IdempotencyKeyTTL:      idempotencyKeyTTL,
+		StatsFlushInterval:     statsFlushInterval,
+
+		EvaluationCacheTTL:  evaluationCacheTTL,
+		EvaluationCacheSize: evaluationCacheSize,
+
+		MetricsFlagLabelLimit: metricsFlagLabelLimit,
+
+		StaleFlagNotEvaluatedFor: staleFlagNotEvaluatedFor,
+		StaleFlagNotModifiedFor:  staleFlagNotModifiedFor,
+
+		DatabaseReplicaURL: strings.TrimSpace(getenv("DATABASE_REPLICA_URL")),
+		HedgeDelay:         hedgeDelay,
+
+		DatabaseReadURL: databaseReadURL,
+		VariantsKeyFile: variantsKeyFile,
+
+		DatabaseMaxConns:          databaseMaxConns,
+		DatabaseMinConns:          databaseMinConns,
+		DatabaseMaxConnLifetime:   databaseMaxConnLifetime,
+		DatabaseMaxConnIdleTime:   databaseMaxConnIdleTime,
+		DatabaseHealthCheckPeriod: databaseHealthCheckPeriod,
+		DatabaseStatementTimeout:  databaseStatementTimeout,
+
+		UpstreamURL:    upstreamURL,
+		UpstreamAPIKey: upstreamAPIKey,
+
+		ErrorFormat: errorFormat,
+
+		ExportS3Bucket:          exportS3Bucket,
+		ExportS3Endpoint:        strings.TrimSpace(getenv("EXPORT_S3_ENDPOINT")),
+		ExportS3Region:          strings.TrimSpace(getenv("EXPORT_S3_REGION")),
+		ExportS3Prefix:          strings.TrimSpace(getenv("EXPORT_S3_PREFIX")),
+		ExportS3AccessKeyID:     exportS3AccessKeyID,
+		ExportS3SecretAccessKey: exportS3SecretAccessKey,
+		ExportInterval:          exportInterval,
+
+		CORSAllowedOrigins: corsAllowedOrigins,
+		CORSAllowedHeaders: corsAllowedHeaders,
+		CORSMaxAge:         corsMaxAge,
+		GRPCWeb:            grpcWeb,
+
+		TLSCertFile:       tlsCertFile,
+		TLSKeyFile:        tlsKeyFile,
+		TLSClientCAFile:   tlsClientCAFile,
+		TLSReloadInterval: tlsReloadInterval,
+
+		OIDCIssuerURL:     oidcIssuerURL,
+		OIDCClientID:      oidcClientID,
+		OIDCClientSecret:  oidcClientSecret,
+		OIDCRedirectURL:   oidcRedirectURL,
+		OIDCScopes:        splitList(getenv("OIDC_SCOPES")),
+		OIDCRolesClaim:    oidcRolesClaim,
+		OIDCAdminGroups:   oidcAdminGroups,
+		OIDCViewerGroups:  oidcViewerGroups,
+		OIDCPasswordLogin: oidcPasswordLogin,
+
+		AdminSessionIdleTimeout: adminSessionIdleTimeout,
+
+		AdminPasswordHash:              adminPasswordHash,
+		AdminPasswordBcryptCost:        adminPasswordBcryptCost,
+		AdminPasswordArgon2Memory:      adminPasswordArgon2Memory,
+		AdminPasswordArgon2Iterations:  adminPasswordArgon2Iterations,
+		AdminPasswordArgon2Parallelism: adminPasswordArgon2Parallelism,
+		AdminPasswordMinLength:         adminPasswordMinLength,
+		AdminPasswordBreachList:        adminPasswordBreachList,
+
+		FlagExpiryWebhookURL: flagExpiryWebhookURL,
+
+		SMTPAddr:     smtpAddr,
+		SMTPFrom:     smtpFrom,
+		SMTPUsername: smtpUsername,
+		SMTPPassword: smtpPassword,
 	}, nil
 }
 
+// parseArgon2Params parses argon2id settings written as in a PHC hash,
+// "m=65536,t=4,p=4". Each must be at least 1, and the memory at least 8 KiB
+// per lane.
+func parseArgon2Params(value string) (memory, iterations uint32, parallelism uint8, err error) {
+	var seen [3]bool
+	for _, part := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return 0, 0, 0, fmt.Errorf("%q is not name=value", part)
+		}
+		switch name {
+		case "m":
+			n, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				return 0, 0, 0, fmt.Errorf("m: %w", err)
+			}
+			memory, seen[0] = uint32(n), true
+		case "t":
+			n, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				return 0, 0, 0, fmt.Errorf("t: %w", err)
+			}
+			iterations, seen[1] = uint32(n), true
+		case "p":
+			n, err := strconv.ParseUint(raw, 10, 8)
+			if err != nil {
+				return 0, 0, 0, fmt.Errorf("p: %w", err)
+			}
+			parallelism, seen[2] = uint8(n), true
+		default:
+			return 0, 0, 0, fmt.Errorf("unknown setting %q", name)
+		}
+	}
+	if seen != [3]bool{true, true, true} {
+		return 0, 0, 0, errors.New("m, t and p are all required")
+	}
+	if iterations < 1 || parallelism < 1 || memory < 8*uint32(parallelism) {
+		return 0, 0, 0, errors.New("t and p must be >= 1 and m >= 8*p")
+	}
+	return memory, iterations, parallelism, nil
+}
+
+// parseRouteValues parses "<path prefix>=<value>,..." with parse, returning
+// nil for an empty list.
+func parseRouteValues[V any](value string, parse func(string) (V, error)) (map[string]V, error) {
+	var values map[string]V
+	for _, item := range splitList(value) {
+		prefix, raw, ok := strings.Cut(item, "=")
+		prefix, raw = strings.TrimSpace(prefix), strings.TrimSpace(raw)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%q is not <path prefix>=<value>", item)
+		}
+		parsed, err := parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", prefix, err)
+		}
+		if values == nil {
+			values = make(map[string]V)
+		}
+		values[prefix] = parsed
+	}
+	return values, nil
+}
+
+// parseLogLevel parses "debug", "info", "warn" or "error".
+func parseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, err
+	}
+	return level, nil
+}
+
+// parseSampleRate parses a fraction between 0 and 1.
+func parseSampleRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v is not between 0 and 1", rate)
+	}
+	return rate, nil
+}
+
+// parseCORSOrigins splits a CORS_ALLOWED_ORIGINS value and checks that each
+// entry is "*" or a bare http(s) origin, lower-cased as browsers send it.
+func parseCORSOrigins(value string) ([]string, error) {
+	origins := splitList(value)
+	for i, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS: %q is not an origin such as https://dash.example.com", origin)
+		}
+		origins[i] = strings.ToLower(u.Scheme + "://" + u.Host)
+	}
+	return origins, nil
+}
+
+// splitList splits a comma-separated value, dropping blank entries.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func envOrDefault(key, fallback string) string {
 	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 		return value
@@ -1,6 +1,12 @@
 package config
 
 import (
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,8 +29,18 @@ func TestLoad_Defaults(t *testing.T) {
 	t.Setenv("TS_AUTH_KEY", "")
 	t.Setenv("TS_STATE_DIR", "")
 	t.Setenv("MAX_JSON_BODY_SIZE", "")
+	t.Setenv("MAX_EVALUATE_BODY_SIZE", "")
+	t.Setenv("MAX_IMPORT_BODY_SIZE", "")
+	t.Setenv("EVALUATE_TIMEOUT", "")
+	t.Setenv("IMPORT_TIMEOUT", "")
 	t.Setenv("EVENT_BATCH_SIZE", "")
 	t.Setenv("CACHE_RESYNC_INTERVAL", "")
+	t.Setenv("WARMUP_TIMEOUT", "")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "")
+	t.Setenv("STREAM_DRAIN_TIMEOUT", "")
+	t.Setenv("CACHE_INVALIDATION", "")
+	t.Setenv("REDIS_URL", "")
+	t.Setenv("RUN_MIGRATIONS", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -48,12 +64,36 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.MaxJSONBodySize != defaultMaxJSONBodySize {
 		t.Errorf("MaxJSONBodySize = %d, want %d", cfg.MaxJSONBodySize, defaultMaxJSONBodySize)
 	}
+	if cfg.MaxEvaluateBodySize != defaultMaxEvaluateBodySize {
+		t.Errorf("MaxEvaluateBodySize = %d, want %d", cfg.MaxEvaluateBodySize, defaultMaxEvaluateBodySize)
+	}
+	if cfg.MaxImportBodySize != defaultMaxImportBodySize {
+		t.Errorf("MaxImportBodySize = %d, want %d", cfg.MaxImportBodySize, defaultMaxImportBodySize)
+	}
+	if cfg.EvaluateTimeout != defaultEvaluateTimeout || cfg.ImportTimeout != defaultImportTimeout {
+		t.Errorf("timeouts = %v/%v, want %v/%v", cfg.EvaluateTimeout, cfg.ImportTimeout, defaultEvaluateTimeout, defaultImportTimeout)
+	}
 	if cfg.EventBatchSize != defaultEventBatchSize {
 		t.Errorf("EventBatchSize = %d, want %d", cfg.EventBatchSize, defaultEventBatchSize)
 	}
 	if cfg.CacheResyncInterval != defaultCacheResyncInterval {
 		t.Errorf("CacheResyncInterval = %v, want %v", cfg.CacheResyncInterval, defaultCacheResyncInterval)
 	}
+	if cfg.WarmupTimeout != defaultWarmupTimeout {
+		t.Errorf("WarmupTimeout = %v, want %v", cfg.WarmupTimeout, defaultWarmupTimeout)
+	}
+	if cfg.ShutdownDrainDelay != defaultShutdownDrainDelay {
+		t.Errorf("ShutdownDrainDelay = %v, want %v", cfg.ShutdownDrainDelay, defaultShutdownDrainDelay)
+	}
+	if cfg.StreamDrainTimeout != defaultStreamDrainTimeout {
+		t.Errorf("StreamDrainTimeout = %v, want %v", cfg.StreamDrainTimeout, defaultStreamDrainTimeout)
+	}
+	if cfg.CacheInvalidation != CacheInvalidationPostgres {
+		t.Errorf("CacheInvalidation = %q, want %q", cfg.CacheInvalidation, CacheInvalidationPostgres)
+	}
+	if !cfg.RunMigrations {
+		t.Error("RunMigrations = false, want true")
+	}
 }
 
 func TestLoad_StreamPollInterval_Invalid(t *testing.T) {
@@ -107,6 +147,7 @@ func TestLoad_CustomAddrs(t *testing.T) {
 	t.Setenv("DATABASE_URL", "postgres://localhost/test")
 	t.Setenv("HTTP_ADDR", ":3000")
 	t.Setenv("GRPC_ADDR", ":4000")
+	t.Setenv("REGION", "eu-west-1")
 	t.Setenv("ADMIN_HOSTNAME", "")
 	t.Setenv("SESSION_SECRET", "")
 	t.Setenv("STREAM_POLL_INTERVAL", "")
@@ -121,6 +162,9 @@ func TestLoad_CustomAddrs(t *testing.T) {
 	if cfg.GRPCAddr != ":4000" {
 		t.Errorf("GRPCAddr = %q, want :4000", cfg.GRPCAddr)
 	}
+	if cfg.Region != "eu-west-1" {
+		t.Errorf("Region = %q, want eu-west-1", cfg.Region)
+	}
 }
 
 func TestLoad_CustomStreamPollInterval(t *testing.T) {
@@ -186,6 +230,10 @@ func TestLoad_CustomConfigurableLimits(t *testing.T) {
 	t.Setenv("ADMIN_HOSTNAME", "")
 	t.Setenv("SESSION_SECRET", "")
 	t.Setenv("MAX_JSON_BODY_SIZE", "2048")
+	t.Setenv("MAX_EVALUATE_BODY_SIZE", "512")
+	t.Setenv("MAX_IMPORT_BODY_SIZE", "4096")
+	t.Setenv("EVALUATE_TIMEOUT", "250ms")
+	t.Setenv("IMPORT_TIMEOUT", "0")
 	t.Setenv("EVENT_BATCH_SIZE", "250")
 	t.Setenv("CACHE_RESYNC_INTERVAL", "30s")
 
@@ -196,6 +244,18 @@ func TestLoad_CustomConfigurableLimits(t *testing.T) {
 	if cfg.MaxJSONBodySize != 2048 {
 		t.Errorf("MaxJSONBodySize = %d, want 2048", cfg.MaxJSONBodySize)
 	}
+	if cfg.MaxEvaluateBodySize != 512 {
+		t.Errorf("MaxEvaluateBodySize = %d, want 512", cfg.MaxEvaluateBodySize)
+	}
+	if cfg.MaxImportBodySize != 4096 {
+		t.Errorf("MaxImportBodySize = %d, want 4096", cfg.MaxImportBodySize)
+	}
+	if cfg.EvaluateTimeout != 250*time.Millisecond {
+		t.Errorf("EvaluateTimeout = %v, want 250ms", cfg.EvaluateTimeout)
+	}
+	if cfg.ImportTimeout != 0 {
+		t.Errorf("ImportTimeout = %v, want 0", cfg.ImportTimeout)
+	}
 	if cfg.EventBatchSize != 250 {
 		t.Errorf("EventBatchSize = %d, want 250", cfg.EventBatchSize)
 	}
@@ -220,6 +280,40 @@ func TestLoad_MaxJSONBodySize_Invalid(t *testing.T) {
 	}
 }
 
+func TestLoad_RouteBodySizes_Invalid(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	for _, key := range []string{"MAX_EVALUATE_BODY_SIZE", "MAX_IMPORT_BODY_SIZE"} {
+		for _, tc := range []string{"not-a-number", "0"} {
+			t.Run(key+"="+tc, func(t *testing.T) {
+				t.Setenv(key, tc)
+				if _, err := Load(); err == nil {
+					t.Fatalf("Load() should fail for %s=%q", key, tc)
+				}
+			})
+		}
+	}
+}
+
+func TestLoad_RouteTimeouts_Invalid(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	for _, key := range []string{"EVALUATE_TIMEOUT", "IMPORT_TIMEOUT"} {
+		for _, tc := range []string{"soon", "-1s"} {
+			t.Run(key+"="+tc, func(t *testing.T) {
+				t.Setenv(key, tc)
+				if _, err := Load(); err == nil {
+					t.Fatalf("Load() should fail for %s=%q", key, tc)
+				}
+			})
+		}
+	}
+}
+
 func TestLoad_EventBatchSize_Invalid(t *testing.T) {
 	t.Setenv("DATABASE_URL", "postgres://localhost/test")
 	t.Setenv("ADMIN_HOSTNAME", "")
@@ -236,6 +330,158 @@ func TestLoad_EventBatchSize_Invalid(t *testing.T) {
 	}
 }
 
+func TestLoad_WarmupTimeout(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("WARMUP_TIMEOUT", "0")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.WarmupTimeout != 0 {
+		t.Errorf("WarmupTimeout = %v, want 0", cfg.WarmupTimeout)
+	}
+
+	for _, tc := range []string{"soon", "-1s"} {
+		t.Setenv("WARMUP_TIMEOUT", tc)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for WARMUP_TIMEOUT=%q", tc)
+		}
+	}
+}
+
+func TestLoad_ShutdownDrainDelay(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "0")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ShutdownDrainDelay != 0 {
+		t.Errorf("ShutdownDrainDelay = %v, want 0", cfg.ShutdownDrainDelay)
+	}
+
+	for _, tc := range []string{"later", "-1s"} {
+		t.Setenv("SHUTDOWN_DRAIN_DELAY", tc)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for SHUTDOWN_DRAIN_DELAY=%q", tc)
+		}
+	}
+}
+
+func TestLoad_StreamDrainTimeout(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("STREAM_DRAIN_TIMEOUT", "30s")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StreamDrainTimeout != 30*time.Second {
+		t.Errorf("StreamDrainTimeout = %v, want 30s", cfg.StreamDrainTimeout)
+	}
+
+	for _, tc := range []string{"soon", "-1s"} {
+		t.Setenv("STREAM_DRAIN_TIMEOUT", tc)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for STREAM_DRAIN_TIMEOUT=%q", tc)
+		}
+	}
+}
+
+func TestLoad_CacheInvalidationRedis(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("CACHE_INVALIDATION", "redis")
+	t.Setenv("REDIS_URL", "")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail when CACHE_INVALIDATION=redis without REDIS_URL")
+	}
+
+	t.Setenv("REDIS_URL", "redis://localhost:6379/0")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CacheInvalidation != CacheInvalidationRedis || cfg.RedisURL != "redis://localhost:6379/0" {
+		t.Errorf("CacheInvalidation = %q, RedisURL = %q", cfg.CacheInvalidation, cfg.RedisURL)
+	}
+
+	t.Setenv("CACHE_INVALIDATION", "carrier-pigeon")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for an unknown CACHE_INVALIDATION")
+	}
+}
+
+func TestLoad_RunMigrations(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("RUN_MIGRATIONS", "false")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.RunMigrations {
+		t.Error("RunMigrations = true, want false")
+	}
+
+	t.Setenv("RUN_MIGRATIONS", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for RUN_MIGRATIONS=sometimes")
+	}
+}
+
+func TestLoad_SDKConfig(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("SDK_POLL_INTERVAL", "")
+	t.Setenv("SDK_MAX_BATCH_SIZE", "")
+	t.Setenv("SDK_HEARTBEAT_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SDKPollInterval != defaultSDKPollInterval || cfg.SDKMaxBatchSize != defaultSDKMaxBatchSize || cfg.SDKHeartbeatInterval != defaultSDKHeartbeatInterval {
+		t.Errorf("SDK config = %v/%d/%v, want defaults", cfg.SDKPollInterval, cfg.SDKMaxBatchSize, cfg.SDKHeartbeatInterval)
+	}
+
+	t.Setenv("SDK_POLL_INTERVAL", "2m")
+	t.Setenv("SDK_MAX_BATCH_SIZE", "25")
+	t.Setenv("SDK_HEARTBEAT_INTERVAL", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SDKPollInterval != 2*time.Minute || cfg.SDKMaxBatchSize != 25 || cfg.SDKHeartbeatInterval != 0 {
+		t.Errorf("SDK config = %v/%d/%v, want 2m/25/0s", cfg.SDKPollInterval, cfg.SDKMaxBatchSize, cfg.SDKHeartbeatInterval)
+	}
+
+	for key, value := range map[string]string{
+		"SDK_POLL_INTERVAL":      "0",
+		"SDK_MAX_BATCH_SIZE":     "0",
+		"SDK_HEARTBEAT_INTERVAL": "-1s",
+	} {
+		t.Setenv(key, value)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for %s=%q", key, value)
+		}
+		t.Setenv(key, "")
+	}
+}
+
 func TestLoad_CacheResyncInterval_Invalid(t *testing.T) {
 	t.Setenv("DATABASE_URL", "postgres://localhost/test")
 	t.Setenv("ADMIN_HOSTNAME", "")
@@ -292,3 +538,1101 @@ func TestEnvOrDefault_ValueReturnsValue(t *testing.T) {
 		t.Errorf("envOrDefault() = %q, want %q", got, "value")
 	}
 }
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flagz.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("HTTP_ADDR", "")
+	t.Setenv("STREAM_POLL_INTERVAL", "")
+	t.Setenv("EVENT_BATCH_SIZE", "")
+	t.Setenv("FLAGZ_CONFIG", writeConfigFile(t, `
+database_url: postgres://file/db
+http_addr: ":8181"
+stream_poll_interval: 250ms
+event_batch_size: 50
+`))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://file/db" {
+		t.Errorf("DatabaseURL = %q, want value from file", cfg.DatabaseURL)
+	}
+	if cfg.HTTPAddr != ":8181" {
+		t.Errorf("HTTPAddr = %q, want :8181", cfg.HTTPAddr)
+	}
+	if cfg.StreamPollInterval != 250*time.Millisecond {
+		t.Errorf("StreamPollInterval = %v, want 250ms", cfg.StreamPollInterval)
+	}
+	if cfg.EventBatchSize != 50 {
+		t.Errorf("EventBatchSize = %d, want 50", cfg.EventBatchSize)
+	}
+}
+
+func TestLoad_ConfigFile_EnvOverrides(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://env/db")
+	t.Setenv("HTTP_ADDR", "")
+	t.Setenv("FLAGZ_CONFIG", writeConfigFile(t, "database_url: postgres://file/db\nhttp_addr: \":8181\"\n"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://env/db" {
+		t.Errorf("DatabaseURL = %q, want env override", cfg.DatabaseURL)
+	}
+	if cfg.HTTPAddr != ":8181" {
+		t.Errorf("HTTPAddr = %q, want file value when env is empty", cfg.HTTPAddr)
+	}
+}
+
+func TestLoad_ConfigFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{name: "unknown key", contents: "database_url: x\nhttp_adr: \":1\"\n", want: `unknown key "http_adr"`},
+		{name: "upper-case key", contents: "DATABASE_URL: x\n", want: "unknown key"},
+		{name: "non-scalar", contents: "database_url: [a, b]\n", want: "must be a scalar"},
+		{name: "malformed", contents: "database_url: [\n", want: "parse config file"},
+		{name: "validation still applies", contents: "database_url: x\nevent_batch_size: 0\n", want: "EVENT_BATCH_SIZE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "")
+			t.Setenv("EVENT_BATCH_SIZE", "")
+			t.Setenv("FLAGZ_CONFIG", writeConfigFile(t, tt.contents))
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ConfigFile_Missing(t *testing.T) {
+	t.Setenv("FLAGZ_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail when FLAGZ_CONFIG points at a missing file")
+	}
+}
+
+func TestLoad_KubernetesSync(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("KUBERNETES_SYNC", "")
+	t.Setenv("KUBERNETES_NAMESPACE", "")
+	t.Setenv("KUBERNETES_SYNC_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.KubernetesSync || cfg.KubernetesSyncInterval != defaultKubernetesSyncInterval {
+		t.Errorf("KubernetesSync = %v, interval = %v, want off and default", cfg.KubernetesSync, cfg.KubernetesSyncInterval)
+	}
+
+	t.Setenv("KUBERNETES_SYNC", "true")
+	t.Setenv("KUBERNETES_NAMESPACE", " apps ")
+	t.Setenv("KUBERNETES_SYNC_INTERVAL", "10s")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.KubernetesSync || cfg.KubernetesNamespace != "apps" || cfg.KubernetesSyncInterval != 10*time.Second {
+		t.Errorf("Kubernetes config = %v/%q/%v, want true/apps/10s", cfg.KubernetesSync, cfg.KubernetesNamespace, cfg.KubernetesSyncInterval)
+	}
+
+	for key, value := range map[string]string{
+		"KUBERNETES_SYNC":          "maybe",
+		"KUBERNETES_SYNC_INTERVAL": "0",
+	} {
+		t.Setenv(key, value)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for %s=%q", key, value)
+		}
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_ProjectRetention(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("PROJECT_RETENTION", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ProjectRetention != defaultProjectRetention {
+		t.Errorf("ProjectRetention = %v, want %v", cfg.ProjectRetention, defaultProjectRetention)
+	}
+
+	t.Setenv("PROJECT_RETENTION", "168h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ProjectRetention != 168*time.Hour {
+		t.Errorf("ProjectRetention = %v, want 168h", cfg.ProjectRetention)
+	}
+
+	for _, value := range []string{"0", "-1h", "week"} {
+		t.Setenv("PROJECT_RETENTION", value)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for PROJECT_RETENTION=%q", value)
+		}
+	}
+}
+
+func TestLoad_IdempotencyKeyTTL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.IdempotencyKeyTTL != defaultIdempotencyKeyTTL {
+		t.Errorf("IdempotencyKeyTTL = %v, want %v", cfg.IdempotencyKeyTTL, defaultIdempotencyKeyTTL)
+	}
+
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "1h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.IdempotencyKeyTTL != time.Hour {
+		t.Errorf("IdempotencyKeyTTL = %v, want 1h", cfg.IdempotencyKeyTTL)
+	}
+
+	for _, value := range []string{"0", "-1h", "day"} {
+		t.Setenv("IDEMPOTENCY_KEY_TTL", value)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for IDEMPOTENCY_KEY_TTL=%q", value)
+		}
+	}
+}
+
+func TestLoad_StatsFlushInterval(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("STATS_FLUSH_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StatsFlushInterval != defaultStatsFlushInterval {
+		t.Errorf("StatsFlushInterval = %v, want %v", cfg.StatsFlushInterval, defaultStatsFlushInterval)
+	}
+
+	t.Setenv("STATS_FLUSH_INTERVAL", "1m")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StatsFlushInterval != time.Minute {
+		t.Errorf("StatsFlushInterval = %v, want 1m", cfg.StatsFlushInterval)
+	}
+
+	t.Setenv("STATS_FLUSH_INTERVAL", "0s")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for STATS_FLUSH_INTERVAL=0s")
+	}
+}
+
+func TestLoad_EvaluationCache(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("EVALUATION_CACHE_TTL", "")
+	t.Setenv("EVALUATION_CACHE_SIZE", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.EvaluationCacheTTL != 0 || cfg.EvaluationCacheSize != defaultEvaluationCacheSize {
+		t.Errorf("EvaluationCacheTTL, EvaluationCacheSize = %v, %d, want 0, %d", cfg.EvaluationCacheTTL, cfg.EvaluationCacheSize, defaultEvaluationCacheSize)
+	}
+
+	t.Setenv("EVALUATION_CACHE_TTL", "500ms")
+	t.Setenv("EVALUATION_CACHE_SIZE", "50")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.EvaluationCacheTTL != 500*time.Millisecond || cfg.EvaluationCacheSize != 50 {
+		t.Errorf("EvaluationCacheTTL, EvaluationCacheSize = %v, %d, want 500ms, 50", cfg.EvaluationCacheTTL, cfg.EvaluationCacheSize)
+	}
+
+	for key, value := range map[string]string{
+		"EVALUATION_CACHE_TTL":  "-1s",
+		"EVALUATION_CACHE_SIZE": "0",
+	} {
+		t.Setenv(key, value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() should fail for %s=%q", key, value)
+		}
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_MetricsFlagLabelLimit(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("METRICS_FLAG_LABEL_LIMIT", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.MetricsFlagLabelLimit != defaultMetricsFlagLabelLimit {
+		t.Errorf("MetricsFlagLabelLimit = %d, want %d", cfg.MetricsFlagLabelLimit, defaultMetricsFlagLabelLimit)
+	}
+
+	t.Setenv("METRICS_FLAG_LABEL_LIMIT", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.MetricsFlagLabelLimit != 0 {
+		t.Errorf("MetricsFlagLabelLimit = %d, want 0", cfg.MetricsFlagLabelLimit)
+	}
+
+	for _, value := range []string{"-1", "lots"} {
+		t.Setenv("METRICS_FLAG_LABEL_LIMIT", value)
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for METRICS_FLAG_LABEL_LIMIT=%q", value)
+		}
+	}
+}
+
+func TestLoad_StaleFlagThresholds(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("STALE_FLAG_NOT_EVALUATED_FOR", "")
+	t.Setenv("STALE_FLAG_NOT_MODIFIED_FOR", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StaleFlagNotEvaluatedFor != defaultStaleFlagNotEvaluatedFor || cfg.StaleFlagNotModifiedFor != defaultStaleFlagNotModifiedFor {
+		t.Errorf("stale thresholds = %v, %v, want defaults", cfg.StaleFlagNotEvaluatedFor, cfg.StaleFlagNotModifiedFor)
+	}
+
+	t.Setenv("STALE_FLAG_NOT_EVALUATED_FOR", "168h")
+	t.Setenv("STALE_FLAG_NOT_MODIFIED_FOR", "336h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StaleFlagNotEvaluatedFor != 7*24*time.Hour || cfg.StaleFlagNotModifiedFor != 14*24*time.Hour {
+		t.Errorf("stale thresholds = %v, %v, want 168h and 336h", cfg.StaleFlagNotEvaluatedFor, cfg.StaleFlagNotModifiedFor)
+	}
+
+	for _, key := range []string{"STALE_FLAG_NOT_EVALUATED_FOR", "STALE_FLAG_NOT_MODIFIED_FOR"} {
+		t.Setenv(key, "0s")
+		if _, err := Load(); err == nil {
+			t.Fatalf("Load() should fail for %s=0s", key)
+		}
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoad_HedgedReads(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("DATABASE_REPLICA_URL", "")
+	t.Setenv("HEDGE_DELAY", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseReplicaURL != "" || cfg.HedgeDelay != defaultHedgeDelay {
+		t.Errorf("replica = %q, hedge delay = %v, want none and %v", cfg.DatabaseReplicaURL, cfg.HedgeDelay, defaultHedgeDelay)
+	}
+
+	t.Setenv("DATABASE_REPLICA_URL", "postgres://replica/test")
+	t.Setenv("HEDGE_DELAY", "25ms")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseReplicaURL != "postgres://replica/test" || cfg.HedgeDelay != 25*time.Millisecond {
+		t.Errorf("replica = %q, hedge delay = %v, want postgres://replica/test and 25ms", cfg.DatabaseReplicaURL, cfg.HedgeDelay)
+	}
+
+	t.Setenv("HEDGE_DELAY", "0s")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for HEDGE_DELAY=0s")
+	}
+}
+
+func TestLoad_DatabasePool(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	keys := []string{
+		"DATABASE_MAX_CONNS", "DATABASE_MIN_CONNS", "DATABASE_MAX_CONN_LIFETIME",
+		"DATABASE_MAX_CONN_IDLE_TIME", "DATABASE_HEALTH_CHECK_PERIOD", "DATABASE_STATEMENT_TIMEOUT",
+	}
+	for _, key := range keys {
+		t.Setenv(key, "")
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseMaxConns != 0 || cfg.DatabaseMinConns != 0 || cfg.DatabaseMaxConnLifetime != 0 {
+		t.Errorf("pool settings = %d, %d, %v, want pgxpool defaults", cfg.DatabaseMaxConns, cfg.DatabaseMinConns, cfg.DatabaseMaxConnLifetime)
+	}
+	if cfg.DatabaseStatementTimeout != defaultDatabaseStatementTimeout {
+		t.Errorf("DatabaseStatementTimeout = %v, want %v", cfg.DatabaseStatementTimeout, defaultDatabaseStatementTimeout)
+	}
+
+	t.Setenv("DATABASE_MAX_CONNS", "40")
+	t.Setenv("DATABASE_MIN_CONNS", "4")
+	t.Setenv("DATABASE_MAX_CONN_LIFETIME", "30m")
+	t.Setenv("DATABASE_MAX_CONN_IDLE_TIME", "5m")
+	t.Setenv("DATABASE_HEALTH_CHECK_PERIOD", "15s")
+	t.Setenv("DATABASE_STATEMENT_TIMEOUT", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseMaxConns != 40 || cfg.DatabaseMinConns != 4 {
+		t.Errorf("conns = %d..%d, want 4..40", cfg.DatabaseMinConns, cfg.DatabaseMaxConns)
+	}
+	if cfg.DatabaseMaxConnLifetime != 30*time.Minute || cfg.DatabaseMaxConnIdleTime != 5*time.Minute || cfg.DatabaseHealthCheckPeriod != 15*time.Second {
+		t.Errorf("durations = %v, %v, %v, want 30m, 5m, 15s", cfg.DatabaseMaxConnLifetime, cfg.DatabaseMaxConnIdleTime, cfg.DatabaseHealthCheckPeriod)
+	}
+	if cfg.DatabaseStatementTimeout != 0 {
+		t.Errorf("DatabaseStatementTimeout = %v, want 0", cfg.DatabaseStatementTimeout)
+	}
+
+	for _, tc := range []struct{ key, value string }{
+		{"DATABASE_MAX_CONNS", "0"},
+		{"DATABASE_MIN_CONNS", "41"},
+		{"DATABASE_MAX_CONN_LIFETIME", "0s"},
+		{"DATABASE_HEALTH_CHECK_PERIOD", "often"},
+		{"DATABASE_STATEMENT_TIMEOUT", "-1s"},
+	} {
+		t.Run(tc.key+"="+tc.value, func(t *testing.T) {
+			t.Setenv(tc.key, tc.value)
+			if _, err := Load(); err == nil {
+				t.Fatalf("Load() should fail for %s=%q", tc.key, tc.value)
+			}
+		})
+	}
+}
+
+func TestLoad_DatabaseReadURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("DATABASE_REPLICA_URL", "")
+
+	t.Setenv("DATABASE_READ_URL", " postgres://replica/test ")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DatabaseReadURL != "postgres://replica/test" {
+		t.Errorf("DatabaseReadURL = %q, want postgres://replica/test", cfg.DatabaseReadURL)
+	}
+
+	t.Setenv("DATABASE_REPLICA_URL", "postgres://replica/test")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail when DATABASE_READ_URL and DATABASE_REPLICA_URL are both set")
+	}
+	t.Setenv("DATABASE_REPLICA_URL", "")
+
+	t.Setenv("DATABASE_URL", "sqlite:flagz.db")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for DATABASE_READ_URL with SQLite")
+	}
+}
+
+func TestLoad_VariantsKeyFile(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("VARIANTS_KEY_FILE", " /etc/flagz/keys.json ")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VariantsKeyFile != "/etc/flagz/keys.json" {
+		t.Errorf("VariantsKeyFile = %q, want /etc/flagz/keys.json", cfg.VariantsKeyFile)
+	}
+
+	t.Setenv("DATABASE_URL", "sqlite:flagz.db")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for VARIANTS_KEY_FILE with SQLite")
+	}
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("UPSTREAM_URL", "https://flagz.example.com")
+	t.Setenv("UPSTREAM_API_KEY", "id.secret")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for VARIANTS_KEY_FILE with UPSTREAM_URL")
+	}
+}
+
+func TestLoad_FlagExpiryWebhookURL(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("FLAG_EXPIRY_WEBHOOK_URL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.FlagExpiryWebhookURL != "" {
+		t.Errorf("FlagExpiryWebhookURL = %q, want empty by default", cfg.FlagExpiryWebhookURL)
+	}
+
+	t.Setenv("FLAG_EXPIRY_WEBHOOK_URL", "https://hooks.example.com/flagz")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.FlagExpiryWebhookURL != "https://hooks.example.com/flagz" {
+		t.Errorf("FlagExpiryWebhookURL = %q", cfg.FlagExpiryWebhookURL)
+	}
+
+	t.Setenv("FLAG_EXPIRY_WEBHOOK_URL", "hooks.example.com/flagz")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for a webhook URL without a scheme")
+	}
+}
+
+func TestLoad_SMTP(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("SMTP_USERNAME", "")
+	t.Setenv("SMTP_PASSWORD", "")
+
+	t.Setenv("SMTP_ADDR", "")
+	t.Setenv("SMTP_FROM", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SMTPAddr != "" {
+		t.Errorf("SMTPAddr = %q, want empty by default", cfg.SMTPAddr)
+	}
+
+	t.Setenv("SMTP_ADDR", "smtp.example.com:587")
+	t.Setenv("SMTP_FROM", "flagz@example.com")
+	t.Setenv("SMTP_USERNAME", "flagz")
+	t.Setenv("SMTP_PASSWORD", "secret")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SMTPAddr != "smtp.example.com:587" || cfg.SMTPFrom != "flagz@example.com" || cfg.SMTPUsername != "flagz" || cfg.SMTPPassword != "secret" {
+		t.Errorf("SMTP config = %q %q %q %q", cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+
+	for name, env := range map[string][2]string{
+		"missing port":      {"smtp.example.com", "flagz@example.com"},
+		"missing from":      {"smtp.example.com:587", ""},
+		"invalid from":      {"smtp.example.com:587", "flagz"},
+		"from without addr": {"", "flagz@example.com"},
+	} {
+		t.Setenv("SMTP_ADDR", env[0])
+		t.Setenv("SMTP_FROM", env[1])
+		if _, err := Load(); err == nil {
+			t.Errorf("%s: Load() should fail", name)
+		}
+	}
+}
+
+func TestLoad_UpstreamProxy(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("KUBERNETES_SYNC", "")
+
+	t.Setenv("UPSTREAM_URL", "https://flagz.example.com")
+	t.Setenv("UPSTREAM_API_KEY", "")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail without UPSTREAM_API_KEY")
+	}
+
+	t.Setenv("UPSTREAM_API_KEY", "key.secret")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v; DATABASE_URL must not be required in proxy mode", err)
+	}
+	if cfg.UpstreamURL != "https://flagz.example.com" || cfg.UpstreamAPIKey != "key.secret" {
+		t.Errorf("upstream = %q, %q, want https://flagz.example.com, key.secret", cfg.UpstreamURL, cfg.UpstreamAPIKey)
+	}
+
+	t.Setenv("KUBERNETES_SYNC", "true")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for KUBERNETES_SYNC in proxy mode")
+	}
+	t.Setenv("KUBERNETES_SYNC", "")
+
+	t.Setenv("ADMIN_HOSTNAME", "flagz-admin")
+	t.Setenv("SESSION_SECRET", strings.Repeat("s", 32))
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for ADMIN_HOSTNAME in proxy mode")
+	}
+}
+
+func TestLoad_SQLiteDatabase(t *testing.T) {
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("UPSTREAM_URL", "")
+	t.Setenv("DATABASE_REPLICA_URL", "")
+	t.Setenv("CACHE_INVALIDATION", "")
+	t.Setenv("EXPORT_S3_BUCKET", "")
+
+	for url, want := range map[string]string{
+		"postgres://localhost/test":      DatabaseDriverPostgres,
+		"sqlite:/var/lib/flagz/flagz.db": DatabaseDriverSQLite,
+		"file:flagz.db?cache=shared":     DatabaseDriverSQLite,
+	} {
+		t.Setenv("DATABASE_URL", url)
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", url, err)
+		}
+		if cfg.DatabaseDriver != want {
+			t.Errorf("DatabaseDriver for %s = %q, want %q", url, cfg.DatabaseDriver, want)
+		}
+	}
+
+	t.Setenv("DATABASE_URL", "sqlite:flagz.db")
+	t.Setenv("REDIS_URL", "redis://localhost:6379")
+	t.Setenv("EXPORT_S3_ACCESS_KEY_ID", "key")
+	t.Setenv("EXPORT_S3_SECRET_ACCESS_KEY", "secret")
+	for key, value := range map[string]string{
+		"DATABASE_REPLICA_URL": "postgres://replica/test",
+		"CACHE_INVALIDATION":   "redis",
+		"EXPORT_S3_BUCKET":     "flagz-exports",
+	} {
+		t.Setenv(key, value)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "SQLite") {
+			t.Errorf("Load() error = %v, want it to reject %s with SQLite", err, key)
+		}
+		t.Setenv(key, "")
+	}
+
+	t.Setenv("ADMIN_HOSTNAME", "flagz-admin")
+	t.Setenv("SESSION_SECRET", strings.Repeat("s", 32))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "SQLite") {
+		t.Fatalf("Load() error = %v, want it to reject ADMIN_HOSTNAME with SQLite", err)
+	}
+}
+
+func TestLoad_ErrorFormat(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("ERROR_FORMAT", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ErrorFormat != ErrorFormatProblem {
+		t.Errorf("ErrorFormat = %q, want %q", cfg.ErrorFormat, ErrorFormatProblem)
+	}
+
+	t.Setenv("ERROR_FORMAT", "Legacy")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ErrorFormat != ErrorFormatLegacy {
+		t.Errorf("ErrorFormat = %q, want %q", cfg.ErrorFormat, ErrorFormatLegacy)
+	}
+
+	t.Setenv("ERROR_FORMAT", "xml")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for ERROR_FORMAT=xml")
+	}
+}
+
+func TestLoad_Export(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("EXPORT_S3_BUCKET", "")
+	t.Setenv("EXPORT_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ExportS3Bucket != "" || cfg.ExportInterval != defaultExportInterval {
+		t.Errorf("bucket = %q, interval = %v, want none and %v", cfg.ExportS3Bucket, cfg.ExportInterval, defaultExportInterval)
+	}
+
+	t.Setenv("EXPORT_S3_BUCKET", "analytics")
+	t.Setenv("EXPORT_S3_ACCESS_KEY_ID", "")
+	t.Setenv("EXPORT_S3_SECRET_ACCESS_KEY", "")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for EXPORT_S3_BUCKET without credentials")
+	}
+
+	t.Setenv("EXPORT_S3_ACCESS_KEY_ID", "AKID")
+	t.Setenv("EXPORT_S3_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("EXPORT_S3_ENDPOINT", "http://minio:9000")
+	t.Setenv("EXPORT_S3_PREFIX", "flagz/")
+	t.Setenv("EXPORT_INTERVAL", "1h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ExportS3Bucket != "analytics" || cfg.ExportS3Endpoint != "http://minio:9000" || cfg.ExportS3Prefix != "flagz/" || cfg.ExportInterval != time.Hour {
+		t.Errorf("export config = %q, %q, %q, %v, want analytics, http://minio:9000, flagz/, 1h", cfg.ExportS3Bucket, cfg.ExportS3Endpoint, cfg.ExportS3Prefix, cfg.ExportInterval)
+	}
+
+	t.Setenv("EXPORT_INTERVAL", "0s")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for EXPORT_INTERVAL=0s")
+	}
+	t.Setenv("EXPORT_INTERVAL", "")
+
+	t.Setenv("UPSTREAM_URL", "https://flagz.example.com")
+	t.Setenv("UPSTREAM_API_KEY", "key.secret")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for EXPORT_S3_BUCKET in proxy mode")
+	}
+}
+
+func TestLoad_CORSAndGRPCWeb(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("CORS_ALLOWED_HEADERS", "")
+	t.Setenv("CORS_MAX_AGE", "")
+	t.Setenv("GRPC_WEB", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CORSAllowedOrigins != nil || cfg.CORSMaxAge != defaultCORSMaxAge || cfg.GRPCWeb {
+		t.Errorf("origins = %v, max age = %v, grpc-web = %v, want none, %v, false", cfg.CORSAllowedOrigins, cfg.CORSMaxAge, cfg.GRPCWeb, defaultCORSMaxAge)
+	}
+	if len(cfg.CORSAllowedHeaders) != len(defaultCORSAllowedHeaders) {
+		t.Errorf("allowed headers = %v, want defaults", cfg.CORSAllowedHeaders)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", " https://Dash.Example.com/ , http://localhost:5173,")
+	t.Setenv("CORS_ALLOWED_HEADERS", "Authorization, X-Custom")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	t.Setenv("GRPC_WEB", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := strings.Join(cfg.CORSAllowedOrigins, " "); got != "https://dash.example.com http://localhost:5173" {
+		t.Errorf("origins = %q", got)
+	}
+	if got := strings.Join(cfg.CORSAllowedHeaders, " "); got != "Authorization X-Custom" {
+		t.Errorf("allowed headers = %q", got)
+	}
+	if cfg.CORSMaxAge != time.Hour || !cfg.GRPCWeb {
+		t.Errorf("max age = %v, grpc-web = %v, want 1h, true", cfg.CORSMaxAge, cfg.GRPCWeb)
+	}
+
+	for _, origin := range []string{"dash.example.com", "https://dash.example.com/app", "ftp://dash.example.com", "https://"} {
+		t.Setenv("CORS_ALLOWED_ORIGINS", origin)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() should fail for CORS_ALLOWED_ORIGINS=%q", origin)
+		}
+	}
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+
+	t.Setenv("CORS_MAX_AGE", "-1s")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for negative CORS_MAX_AGE")
+	}
+	t.Setenv("CORS_MAX_AGE", "")
+
+	t.Setenv("GRPC_WEB", "maybe")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() should fail for GRPC_WEB=maybe")
+	}
+}
+
+func TestLoad_StreamKeepaliveInterval(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("STREAM_KEEPALIVE_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StreamKeepaliveInterval != defaultStreamKeepaliveInterval {
+		t.Errorf("StreamKeepaliveInterval = %v, want %v", cfg.StreamKeepaliveInterval, defaultStreamKeepaliveInterval)
+	}
+
+	t.Setenv("STREAM_KEEPALIVE_INTERVAL", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StreamKeepaliveInterval != 0 {
+		t.Errorf("StreamKeepaliveInterval = %v, want 0 (disabled)", cfg.StreamKeepaliveInterval)
+	}
+
+	for _, value := range []string{"-1s", "soon"} {
+		t.Setenv("STREAM_KEEPALIVE_INTERVAL", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() should fail for STREAM_KEEPALIVE_INTERVAL=%q", value)
+		}
+	}
+}
+
+func TestLoad_PublicExposureLimits(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	for _, key := range []string{"STREAM_WRITE_TIMEOUT", "MAX_STREAMS_PER_API_KEY", "MAX_EVALUATIONS_IN_FLIGHT", "MAX_QUEUED_EVALUATIONS", "HTTP_RATE_LIMIT_PER_IP", "HTTP_RATE_LIMIT_BURST", "HTTP2_MAX_CONCURRENT_STREAMS"} {
+		t.Setenv(key, "")
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StreamWriteTimeout != defaultStreamWriteTimeout {
+		t.Errorf("StreamWriteTimeout = %v, want %v", cfg.StreamWriteTimeout, defaultStreamWriteTimeout)
+	}
+	if cfg.MaxStreamsPerAPIKey != 0 || cfg.HTTPRateLimitPerIP != 0 {
+		t.Errorf("MaxStreamsPerAPIKey, HTTPRateLimitPerIP = %d, %v, want 0, 0", cfg.MaxStreamsPerAPIKey, cfg.HTTPRateLimitPerIP)
+	}
+	if cfg.MaxEvaluationsInFlight != 0 || cfg.MaxQueuedEvaluations != 0 {
+		t.Errorf("MaxEvaluationsInFlight, MaxQueuedEvaluations = %d, %d, want 0, 0", cfg.MaxEvaluationsInFlight, cfg.MaxQueuedEvaluations)
+	}
+	if cfg.HTTPRateLimitBurst != defaultHTTPRateLimitBurst {
+		t.Errorf("HTTPRateLimitBurst = %d, want %d", cfg.HTTPRateLimitBurst, defaultHTTPRateLimitBurst)
+	}
+	if cfg.HTTP2MaxConcurrentStreams != defaultHTTP2MaxStreams {
+		t.Errorf("HTTP2MaxConcurrentStreams = %d, want %d", cfg.HTTP2MaxConcurrentStreams, defaultHTTP2MaxStreams)
+	}
+
+	t.Setenv("STREAM_WRITE_TIMEOUT", "0")
+	t.Setenv("MAX_STREAMS_PER_API_KEY", "5")
+	t.Setenv("MAX_EVALUATIONS_IN_FLIGHT", "64")
+	t.Setenv("MAX_QUEUED_EVALUATIONS", "256")
+	t.Setenv("HTTP_RATE_LIMIT_PER_IP", "2.5")
+	t.Setenv("HTTP_RATE_LIMIT_BURST", "10")
+	t.Setenv("HTTP2_MAX_CONCURRENT_STREAMS", "100")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StreamWriteTimeout != 0 {
+		t.Errorf("StreamWriteTimeout = %v, want 0 (disabled)", cfg.StreamWriteTimeout)
+	}
+	if cfg.MaxStreamsPerAPIKey != 5 {
+		t.Errorf("MaxStreamsPerAPIKey = %d, want 5", cfg.MaxStreamsPerAPIKey)
+	}
+	if cfg.MaxEvaluationsInFlight != 64 || cfg.MaxQueuedEvaluations != 256 {
+		t.Errorf("MaxEvaluationsInFlight, MaxQueuedEvaluations = %d, %d, want 64, 256", cfg.MaxEvaluationsInFlight, cfg.MaxQueuedEvaluations)
+	}
+	if cfg.HTTPRateLimitPerIP != 2.5 || cfg.HTTPRateLimitBurst != 10 {
+		t.Errorf("HTTPRateLimitPerIP, HTTPRateLimitBurst = %v, %d, want 2.5, 10", cfg.HTTPRateLimitPerIP, cfg.HTTPRateLimitBurst)
+	}
+	if cfg.HTTP2MaxConcurrentStreams != 100 {
+		t.Errorf("HTTP2MaxConcurrentStreams = %d, want 100", cfg.HTTP2MaxConcurrentStreams)
+	}
+
+	for key, value := range map[string]string{
+		"STREAM_WRITE_TIMEOUT":         "-1s",
+		"MAX_STREAMS_PER_API_KEY":      "-1",
+		"MAX_EVALUATIONS_IN_FLIGHT":    "-1",
+		"MAX_QUEUED_EVALUATIONS":       "many",
+		"HTTP_RATE_LIMIT_PER_IP":       "fast",
+		"HTTP_RATE_LIMIT_BURST":        "0",
+		"HTTP2_MAX_CONCURRENT_STREAMS": "0",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should fail for %s=%q", key, value)
+			}
+		})
+	}
+}
+
+func TestLoad_LogRouteSettings(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("LOG_ROUTE_LEVELS", "")
+	t.Setenv("LOG_SAMPLE_RATES", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.LogRouteLevels != nil || cfg.LogSampleRates != nil {
+		t.Errorf("LogRouteLevels, LogSampleRates = %v, %v, want nil", cfg.LogRouteLevels, cfg.LogSampleRates)
+	}
+
+	t.Setenv("LOG_ROUTE_LEVELS", "/v1/evaluate=info, /v1/stream=WARN")
+	t.Setenv("LOG_SAMPLE_RATES", "/v1/flags=0.1")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := map[string]slog.Level{"/v1/evaluate": slog.LevelInfo, "/v1/stream": slog.LevelWarn}; !maps.Equal(cfg.LogRouteLevels, want) {
+		t.Errorf("LogRouteLevels = %v, want %v", cfg.LogRouteLevels, want)
+	}
+	if want := map[string]float64{"/v1/flags": 0.1}; !maps.Equal(cfg.LogSampleRates, want) {
+		t.Errorf("LogSampleRates = %v, want %v", cfg.LogSampleRates, want)
+	}
+
+	for key, value := range map[string]string{
+		"LOG_ROUTE_LEVELS": "/v1/evaluate=loud",
+		"LOG_SAMPLE_RATES": "/v1/flags=1.5",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should fail for %s=%q", key, value)
+			}
+		})
+	}
+	t.Setenv("LOG_SAMPLE_RATES", "v1/flags=0.5")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should fail for a prefix without a leading slash")
+	}
+}
+
+func TestLoad_AdminPortalDisabled(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "flagz-admin")
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("ADMIN_PORTAL_ENABLED", "false")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AdminPortalEnabled || cfg.AdminHostname != "" {
+		t.Errorf("AdminPortalEnabled, AdminHostname = %v, %q, want false, \"\"", cfg.AdminPortalEnabled, cfg.AdminHostname)
+	}
+
+	t.Setenv("ADMIN_PORTAL_ENABLED", "maybe")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should fail for ADMIN_PORTAL_ENABLED=\"maybe\"")
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "")
+	t.Setenv("SESSION_SECRET", "")
+
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+	t.Setenv("TLS_CLIENT_CA_FILE", "")
+	t.Setenv("TLS_RELOAD_INTERVAL", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSReloadInterval != defaultTLSReloadInterval {
+		t.Errorf("cert = %q, reload interval = %v, want none, %v", cfg.TLSCertFile, cfg.TLSReloadInterval, defaultTLSReloadInterval)
+	}
+
+	t.Setenv("TLS_CERT_FILE", "/etc/flagz/tls.crt")
+	t.Setenv("TLS_KEY_FILE", "/etc/flagz/tls.key")
+	t.Setenv("TLS_CLIENT_CA_FILE", "/etc/flagz/ca.crt")
+	t.Setenv("TLS_RELOAD_INTERVAL", "10s")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.TLSCertFile != "/etc/flagz/tls.crt" || cfg.TLSKeyFile != "/etc/flagz/tls.key" || cfg.TLSClientCAFile != "/etc/flagz/ca.crt" || cfg.TLSReloadInterval != 10*time.Second {
+		t.Errorf("TLS config = %q %q %q %v", cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, cfg.TLSReloadInterval)
+	}
+
+	tests := []struct {
+		name, cert, key, ca, interval string
+	}{
+		{name: "cert without key", cert: "/etc/flagz/tls.crt"},
+		{name: "key without cert", key: "/etc/flagz/tls.key"},
+		{name: "client CA without cert", ca: "/etc/flagz/ca.crt"},
+		{name: "zero reload interval", cert: "/etc/flagz/tls.crt", key: "/etc/flagz/tls.key", interval: "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TLS_CERT_FILE", tt.cert)
+			t.Setenv("TLS_KEY_FILE", tt.key)
+			t.Setenv("TLS_CLIENT_CA_FILE", tt.ca)
+			t.Setenv("TLS_RELOAD_INTERVAL", tt.interval)
+			if _, err := Load(); err == nil {
+				t.Error("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoad_OIDC(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("ADMIN_HOSTNAME", "flagz-admin")
+	t.Setenv("SESSION_SECRET", strings.Repeat("s", 32))
+	t.Setenv("OIDC_ISSUER_URL", "https://sso.example.com/realms/eng")
+	t.Setenv("OIDC_CLIENT_ID", "flagz")
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "")
+	t.Setenv("OIDC_SCOPES", "groups")
+	t.Setenv("OIDC_ROLES_CLAIM", "")
+	t.Setenv("OIDC_ADMIN_GROUPS", "flagz-admins, sre")
+	t.Setenv("OIDC_VIEWER_GROUPS", "")
+	t.Setenv("OIDC_PASSWORD_LOGIN", "false")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.OIDCRedirectURL != "http://flagz-admin/oidc/callback" || cfg.OIDCRolesClaim != "groups" {
+		t.Errorf("redirect URL = %q, roles claim = %q", cfg.OIDCRedirectURL, cfg.OIDCRolesClaim)
+	}
+	if !slices.Equal(cfg.OIDCAdminGroups, []string{"flagz-admins", "sre"}) || !slices.Equal(cfg.OIDCScopes, []string{"groups"}) || cfg.OIDCPasswordLogin {
+		t.Errorf("admin groups = %v, scopes = %v, password login = %v", cfg.OIDCAdminGroups, cfg.OIDCScopes, cfg.OIDCPasswordLogin)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "missing client secret", env: map[string]string{"OIDC_CLIENT_SECRET": ""}},
+		{name: "no groups", env: map[string]string{"OIDC_ADMIN_GROUPS": ""}},
+		{name: "non-http issuer", env: map[string]string{"OIDC_ISSUER_URL": "sso.example.com"}},
+		{name: "without admin portal", env: map[string]string{"ADMIN_HOSTNAME": "", "SESSION_SECRET": ""}},
+		{name: "password login disabled without issuer", env: map[string]string{"OIDC_ISSUER_URL": ""}},
+		{name: "invalid password login", env: map[string]string{"OIDC_PASSWORD_LOGIN": "sometimes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Load(); err == nil {
+				t.Error("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoad_AdminSessionIdleTimeout(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+
+	t.Setenv("ADMIN_SESSION_IDLE_TIMEOUT", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AdminSessionIdleTimeout != defaultAdminSessionIdleTimeout {
+		t.Errorf("AdminSessionIdleTimeout = %v, want %v", cfg.AdminSessionIdleTimeout, defaultAdminSessionIdleTimeout)
+	}
+
+	t.Setenv("ADMIN_SESSION_IDLE_TIMEOUT", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AdminSessionIdleTimeout != 0 {
+		t.Errorf("AdminSessionIdleTimeout = %v, want 0", cfg.AdminSessionIdleTimeout)
+	}
+
+	for _, v := range []string{"-1m", "soon"} {
+		t.Setenv("ADMIN_SESSION_IDLE_TIMEOUT", v)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() with ADMIN_SESSION_IDLE_TIMEOUT=%q error = nil, want an error", v)
+		}
+	}
+}
+
+func TestLoad_AdminPassword(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	for _, name := range []string{"ADMIN_PASSWORD_HASH", "ADMIN_PASSWORD_BCRYPT_COST", "ADMIN_PASSWORD_ARGON2", "ADMIN_PASSWORD_MIN_LENGTH", "ADMIN_PASSWORD_BREACH_LIST"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AdminPasswordHash != AdminPasswordHashArgon2id || cfg.AdminPasswordBcryptCost != defaultAdminPasswordBcryptCost || cfg.AdminPasswordMinLength != defaultAdminPasswordMinLength {
+		t.Errorf("defaults = %q, cost %d, min length %d", cfg.AdminPasswordHash, cfg.AdminPasswordBcryptCost, cfg.AdminPasswordMinLength)
+	}
+	if cfg.AdminPasswordArgon2Memory != 65536 || cfg.AdminPasswordArgon2Iterations != 4 || cfg.AdminPasswordArgon2Parallelism != 4 {
+		t.Errorf("argon2 defaults = m=%d,t=%d,p=%d", cfg.AdminPasswordArgon2Memory, cfg.AdminPasswordArgon2Iterations, cfg.AdminPasswordArgon2Parallelism)
+	}
+
+	t.Setenv("ADMIN_PASSWORD_HASH", "bcrypt")
+	t.Setenv("ADMIN_PASSWORD_BCRYPT_COST", "14")
+	t.Setenv("ADMIN_PASSWORD_ARGON2", "m=19456, t=2, p=1")
+	t.Setenv("ADMIN_PASSWORD_MIN_LENGTH", "16")
+	t.Setenv("ADMIN_PASSWORD_BREACH_LIST", "/etc/flagz/pwned.txt")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AdminPasswordHash != AdminPasswordHashBcrypt || cfg.AdminPasswordBcryptCost != 14 || cfg.AdminPasswordMinLength != 16 || cfg.AdminPasswordBreachList != "/etc/flagz/pwned.txt" {
+		t.Errorf("cfg = %q, cost %d, min length %d, breach list %q", cfg.AdminPasswordHash, cfg.AdminPasswordBcryptCost, cfg.AdminPasswordMinLength, cfg.AdminPasswordBreachList)
+	}
+	if cfg.AdminPasswordArgon2Memory != 19456 || cfg.AdminPasswordArgon2Iterations != 2 || cfg.AdminPasswordArgon2Parallelism != 1 {
+		t.Errorf("argon2 = m=%d,t=%d,p=%d", cfg.AdminPasswordArgon2Memory, cfg.AdminPasswordArgon2Iterations, cfg.AdminPasswordArgon2Parallelism)
+	}
+
+	for name, values := range map[string][]string{
+		"ADMIN_PASSWORD_HASH":        {"scrypt"},
+		"ADMIN_PASSWORD_BCRYPT_COST": {"3", "32", "high"},
+		"ADMIN_PASSWORD_ARGON2":      {"m=65536,t=4", "m=65536,t=0,p=4", "m=4,t=1,p=1", "x=1,m=65536,t=4,p=4", "m=65536,t=4,p=256"},
+		"ADMIN_PASSWORD_MIN_LENGTH":  {"0", "twelve"},
+	} {
+		for _, v := range values {
+			t.Run(name+"="+v, func(t *testing.T) {
+				t.Setenv(name, v)
+				if _, err := Load(); err == nil {
+					t.Errorf("Load() with %s=%q error = nil, want an error", name, v)
+				}
+			})
+		}
+	}
+}
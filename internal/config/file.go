@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys lists the variables that may be set from a config file.
+var fileKeys = []string{
+	"DATABASE_URL",
+	"HTTP_ADDR",
+	"GRPC_ADDR",
+	"REGION",
+	"STREAM_POLL_INTERVAL",
+	"STREAM_KEEPALIVE_INTERVAL",
+	"STREAM_WRITE_TIMEOUT",
+	"MAX_STREAMS_PER_API_KEY",
+	"MAX_EVALUATIONS_IN_FLIGHT",
+	"MAX_QUEUED_EVALUATIONS",
+	"HTTP_RATE_LIMIT_PER_IP",
+	"HTTP_RATE_LIMIT_BURST",
+	"HTTP2_MAX_CONCURRENT_STREAMS",
+	"LOG_LEVEL",
+	"LOG_ROUTE_LEVELS",
+	"LOG_SAMPLE_RATES",
+	"AUTH_RATE_LIMIT",
+	"ADMIN_HOSTNAME",
+	"ADMIN_PORTAL_ENABLED",
+	"TS_AUTH_KEY",
+	"TS_STATE_DIR",
+	"SESSION_SECRET",
+	"MAX_JSON_BODY_SIZE",
+	"MAX_EVALUATE_BODY_SIZE",
+	"MAX_IMPORT_BODY_SIZE",
+	"EVALUATE_TIMEOUT",
+	"IMPORT_TIMEOUT",
+	"EVENT_BATCH_SIZE",
+	"CACHE_RESYNC_INTERVAL",
+	"WARMUP_TIMEOUT",
+	"SHUTDOWN_DRAIN_DELAY",
+	"STREAM_DRAIN_TIMEOUT",
+	"CACHE_INVALIDATION",
+	"REDIS_URL",
+	"RUN_MIGRATIONS",
+	"SDK_POLL_INTERVAL",
+	"SDK_MAX_BATCH_SIZE",
+	"SDK_HEARTBEAT_INTERVAL",
+	"KUBERNETES_SYNC",
+	"KUBERNETES_NAMESPACE",
+	"KUBERNETES_SYNC_INTERVAL",
+	"PROJECT_RETENTION",
+	"IDEMPOTENCY_KEY_TTL",
+	"STATS_FLUSH_INTERVAL",
+	"EVALUATION_CACHE_TTL",
+	"EVALUATION_CACHE_SIZE",
+	"METRICS_FLAG_LABEL_LIMIT",
+	"STALE_FLAG_NOT_EVALUATED_FOR",
+	"STALE_FLAG_NOT_MODIFIED_FOR",
+	"DATABASE_REPLICA_URL",
+	"DATABASE_READ_URL",
+	"VARIANTS_KEY_FILE",
+	"DATABASE_MAX_CONNS",
+	"DATABASE_MIN_CONNS",
+	"DATABASE_MAX_CONN_LIFETIME",
+	"DATABASE_MAX_CONN_IDLE_TIME",
+	"DATABASE_HEALTH_CHECK_PERIOD",
+	"DATABASE_STATEMENT_TIMEOUT",
+	"HEDGE_DELAY",
+	"UPSTREAM_URL",
+	"UPSTREAM_API_KEY",
+	"ERROR_FORMAT",
+	"EXPORT_S3_BUCKET",
+	"EXPORT_S3_ENDPOINT",
+	"EXPORT_S3_REGION",
+	"EXPORT_S3_PREFIX",
+	"EXPORT_S3_ACCESS_KEY_ID",
+	"EXPORT_S3_SECRET_ACCESS_KEY",
+	"EXPORT_INTERVAL",
+	"CORS_ALLOWED_ORIGINS",
+	"CORS_ALLOWED_HEADERS",
+	"CORS_MAX_AGE",
+	"GRPC_WEB",
+	"TLS_CERT_FILE",
+	"TLS_KEY_FILE",
+	"TLS_CLIENT_CA_FILE",
+	"TLS_RELOAD_INTERVAL",
+	"ADMIN_SESSION_IDLE_TIMEOUT",
+	"ADMIN_PASSWORD_HASH",
+	"ADMIN_PASSWORD_BCRYPT_COST",
+	"ADMIN_PASSWORD_ARGON2",
+	"ADMIN_PASSWORD_MIN_LENGTH",
+	"ADMIN_PASSWORD_BREACH_LIST",
+	"OIDC_ISSUER_URL",
+	"OIDC_CLIENT_ID",
+	"OIDC_CLIENT_SECRET",
+	"OIDC_REDIRECT_URL",
+	"OIDC_SCOPES",
+	"OIDC_ROLES_CLAIM",
+	"OIDC_ADMIN_GROUPS",
+	"OIDC_VIEWER_GROUPS",
+	"OIDC_PASSWORD_LOGIN",
+	"FLAG_EXPIRY_WEBHOOK_URL",
+	"SMTP_ADDR",
+	"SMTP_FROM",
+	"SMTP_USERNAME",
+	"SMTP_PASSWORD",
+}
+
+// readConfigFile parses a flat YAML mapping of lower-cased variable names to
+// scalar values and returns it keyed by the upper-case variable name, so it
+// can be consulted exactly like the environment.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(fileKeys))
+	for _, key := range fileKeys {
+		known[key] = true
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]string, len(raw))
+	for _, name := range names {
+		key := strings.ToUpper(name)
+		if name != strings.ToLower(name) || !known[key] {
+			return nil, fmt.Errorf("config file %s: unknown key %q", path, name)
+		}
+		node := raw[name]
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("config file %s: %s must be a scalar value", path, name)
+		}
+		values[key] = node.Value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// BucketCount is the number of buckets targeting keys are distributed
+// across for percentage rollouts. Using basis points rather than whole
+// percentages lets rollouts be tuned to fractions such as 0.5%.
+const BucketCount = 10000
+
+// segmentBucketPrefix precedes a segment's name in the key its percentage
+// rules bucket on.
+const segmentBucketPrefix = "segment:"
+
+// Bucket returns the rollout bucket in [0, BucketCount) that targetingKey
+// falls into for flag. The result depends only on the flag key, its
+// bucketing salt and the targeting key, so it is stable across processes
+// and restarts and changes only when the salt is rotated.
+//
+// The three are hashed, NUL-separated, with 64-bit xxHash, which spreads
+// similar keys such as "user-1" and "user-2" evenly and is cheap enough for
+// every evaluation. SDKs that evaluate locally must hash the same way.
+func Bucket(flag Flag, targetingKey string) int {
+	h := xxhash.New()
+	h.WriteString(flag.Key)
+	h.Write([]byte{0})
+	h.WriteString(flag.BucketingSalt)
+	h.Write([]byte{0})
+	h.WriteString(targetingKey)
+
+	return int(h.Sum64() % BucketCount)
+}
+
+// RolloutPercentage returns the percentage configured on an
+// [OperatorPercentage] rule. It reports false when the rule uses a different
+// operator or its value is not a number between 0 and 100.
+func RolloutPercentage(rule Rule) (float64, bool) {
+	if rule.Operator != OperatorPercentage {
+		return 0, false
+	}
+	return percentageValue(rule.Value)
+}
+
+// BucketInRollout reports whether bucket lies inside a rollout of the given
+// percentage.
+func BucketInRollout(bucket int, percentage float64) bool {
+	return float64(bucket) < percentage*BucketCount/100
+}
+
+// TargetingKey converts an evaluation-context attribute value into the
+// string that is hashed by [Bucket]. Strings are used as-is and numbers are
+// formatted in their shortest decimal form, so 42 and 42.0 bucket the same.
+// Other types cannot be bucketed.
+func TargetingKey(value any) (string, bool) {
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	if n, ok := asInt64(value); ok {
+		return strconv.FormatInt(n, 10), true
+	}
+	if n, ok := asUint64(value); ok {
+		return strconv.FormatUint(n, 10), true
+	}
+	if f, ok := asFloat64(value); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func inRollout(flag Flag, attributeValue any, ruleValue any) bool {
+	percentage, ok := percentageValue(ruleValue)
+	if !ok {
+		return false
+	}
+	key, ok := TargetingKey(attributeValue)
+	if !ok {
+		return false
+	}
+	return BucketInRollout(Bucket(flag, key), percentage)
+}
+
+func percentageValue(value any) (float64, bool) {
+	var percentage float64
+	if n, ok := asInt64(value); ok {
+		percentage = float64(n)
+	} else if n, ok := asUint64(value); ok {
+		percentage = float64(n)
+	} else if f, ok := asFloat64(value); ok {
+		percentage = f
+	} else {
+		return 0, false
+	}
+	if math.IsNaN(percentage) || percentage < 0 || percentage > 100 {
+		return 0, false
+	}
+	return percentage, true
+}
@@ -0,0 +1,95 @@
+package core
+
+import "testing"
+
+func TestBucketIsDeterministicAndSaltDependent(t *testing.T) {
+	flag := Flag{Key: "checkout", BucketingSalt: "salt-a"}
+
+	first := Bucket(flag, "user-1")
+	if first < 0 || first >= BucketCount {
+		t.Fatalf("Bucket() = %d, want within [0, %d)", first, BucketCount)
+	}
+	if again := Bucket(flag, "user-1"); again != first {
+		t.Fatalf("Bucket() not deterministic: %d != %d", again, first)
+	}
+
+	moved := 0
+	rotated := Flag{Key: "checkout", BucketingSalt: "salt-b"}
+	for _, key := range []string{"user-1", "user-2", "user-3", "user-4", "user-5", "user-6", "user-7", "user-8"} {
+		if Bucket(flag, key) != Bucket(rotated, key) {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("rotating the salt did not move any targeting key")
+	}
+}
+
+// TestBucketIsPinned guards the hash itself: changing it would move users
+// between buckets in every running rollout.
+func TestBucketIsPinned(t *testing.T) {
+	flag := Flag{Key: "checkout", BucketingSalt: "3f8a1c"}
+	for key, want := range map[string]int{"user-1": 8106, "user-2": 1646, "42": 4711} {
+		if got := Bucket(flag, key); got != want {
+			t.Errorf("Bucket(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestBucketDistribution(t *testing.T) {
+	flag := Flag{Key: "checkout", BucketingSalt: "salt"}
+	const n = 20000
+	in := 0
+	for i := 0; i < n; i++ {
+		key, _ := TargetingKey(i)
+		if BucketInRollout(Bucket(flag, key), 25) {
+			in++
+		}
+	}
+	if got := float64(in) / n; got < 0.23 || got > 0.27 {
+		t.Fatalf("25%% rollout admitted %.3f of keys", got)
+	}
+}
+
+func TestEvaluateFlagPercentageRule(t *testing.T) {
+	rule := func(value any) Flag {
+		return Flag{
+			Key:           "checkout",
+			BucketingSalt: "salt",
+			DefaultValue:  boolPtr(false),
+			Rules:         []Rule{{Attribute: "user_id", Operator: OperatorPercentage, Value: value}},
+		}
+	}
+	ctx := EvaluationContext{Attributes: map[string]any{"user_id": "user-1"}}
+
+	if !EvaluateFlag(rule(100), ctx) {
+		t.Fatal("100% rollout should include every key")
+	}
+	if EvaluateFlag(rule(0), ctx) {
+		t.Fatal("0% rollout should exclude every key")
+	}
+	if EvaluateFlag(rule("50"), ctx) {
+		t.Fatal("non-numeric percentage should never match")
+	}
+	if EvaluateFlag(rule(100), EvaluationContext{Attributes: map[string]any{"user_id": true}}) {
+		t.Fatal("non-string, non-numeric targeting key should never match")
+	}
+
+	flag := rule(50.0)
+	want := BucketInRollout(Bucket(flag, "user-1"), 50)
+	if got := EvaluateFlag(flag, ctx); got != want {
+		t.Fatalf("EvaluateFlag() = %v, want %v from bucket", got, want)
+	}
+}
+
+func TestTargetingKeyNormalizesNumbers(t *testing.T) {
+	for _, value := range []any{42, int64(42), uint8(42), 42.0} {
+		got, ok := TargetingKey(value)
+		if !ok || got != "42" {
+			t.Fatalf("TargetingKey(%T %v) = %q, %v, want \"42\", true", value, value, got, ok)
+		}
+	}
+	if _, ok := TargetingKey([]string{"a"}); ok {
+		t.Fatal("TargetingKey(slice) ok = true, want false")
+	}
+}
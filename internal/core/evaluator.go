@@ -3,33 +3,90 @@ package core
 import (
 	"math"
 	"reflect"
+	"slices"
 )
 
 // EvaluateFlag evaluates a single flag against the given context and returns
-// the boolean result. A disabled flag always returns false. When no rules are
-// defined, the flag's default value is used (true if unset). If rules are
-// present, any matching rule yields true; otherwise the default applies.
+// the boolean result. A disabled flag always returns false. Identifiers on
+// the flag's deny or allow list get false or true before any rule is
+// considered. When no rules are defined, the flag's default value is used
+// (true if unset). If rules are present, any matching rule yields true;
+// otherwise the default applies.
 func EvaluateFlag(flag Flag, context EvaluationContext) bool {
+	return EvaluateFlagDetail(flag, context).Value
+}
+
+// EvaluateFlagDetail evaluates a flag exactly like [EvaluateFlag] but also
+// reports why the value was chosen, for debugging and SDK diagnostics.
+func EvaluateFlagDetail(flag Flag, context EvaluationContext) Evaluation {
 	if flag.Disabled {
-		return false
+		return Evaluation{Value: false, Reason: ReasonDisabled, RuleIndex: -1}
+	}
+	if value, ok := evaluateTargets(flag.Targets, context.Attributes); ok {
+		return Evaluation{Value: value, Reason: ReasonTargetMatch, RuleIndex: -1}
 	}
 
-	fallbackValue := true
-	if flag.DefaultValue != nil {
-		fallbackValue = *flag.DefaultValue
+	for i, rule := range flag.Rules {
+		if evaluateRule(flag, rule, context.Attributes, 0) {
+			return Evaluation{Value: true, Reason: ReasonRuleMatch, RuleIndex: i}
+		}
 	}
 
-	if len(flag.Rules) == 0 {
-		return fallbackValue
+	result := Evaluation{Value: true, Reason: ReasonDefault, RuleIndex: -1}
+	if flag.DefaultValue != nil {
+		result.Value = *flag.DefaultValue
+		result.Variant = VariantDefault
 	}
+	return result
+}
 
+// ConstantValue reports whether flag evaluates to the same value for every
+// evaluation context and, if so, which value. It inspects configuration only:
+// a disabled flag is always false, a flag whose default is true is always
+// true (rules can only yield true), and a flag defaulting to false is always
+// false when it has no rules that can match. A 100% rollout does not count as
+// constant because it still only matches contexts carrying its attribute.
+// Targets count against constancy only when they pin some identifier to
+// the opposite value.
+func ConstantValue(flag Flag) (value bool, ok bool) {
+	if flag.Disabled {
+		return false, true
+	}
+	if flag.DefaultValue == nil || *flag.DefaultValue {
+		if flag.Targets.Attribute != "" && len(flag.Targets.Deny) > 0 {
+			return false, false
+		}
+		return true, true
+	}
+	if flag.Targets.Attribute != "" && len(flag.Targets.Allow) > 0 {
+		return false, false
+	}
 	for _, rule := range flag.Rules {
-		if evaluateRule(rule, context.Attributes) {
-			return true
+		if percentage, ok := RolloutPercentage(rule); ok && percentage == 0 {
+			continue
 		}
+		return false, false
 	}
+	return false, true
+}
 
-	return fallbackValue
+// evaluateTargets reports whether the identifier in attributes is on one of
+// targets' lists and, if so, the value it is pinned to.
+func evaluateTargets(targets Targets, attributes map[string]any) (value bool, ok bool) {
+	if targets.Attribute == "" || (len(targets.Allow) == 0 && len(targets.Deny) == 0) {
+		return false, false
+	}
+	id, ok := TargetingKey(attributes[targets.Attribute])
+	if !ok {
+		return false, false
+	}
+	if slices.Contains(targets.Deny, id) {
+		return false, true
+	}
+	if slices.Contains(targets.Allow, id) {
+		return true, true
+	}
+	return false, false
 }
 
 // EvaluateFlags evaluates multiple flags against the same context, returning a
@@ -45,10 +102,15 @@ func EvaluateFlags(flags []Flag, context EvaluationContext) map[string]bool {
 	return results
 }
 
-func evaluateRule(rule Rule, attributes map[string]any) bool {
+// evaluateRule reports whether rule matches attributes. depth is how many
+// segments deep the rule is.
+func evaluateRule(flag Flag, rule Rule, attributes map[string]any, depth int) bool {
 	if attributes == nil {
 		return false
 	}
+	if rule.Operator == OperatorSegment {
+		return inSegment(flag.Segments, rule.Value, attributes, depth)
+	}
 
 	attributeValue, ok := attributes[rule.Attribute]
 	if !ok {
@@ -60,11 +122,35 @@ func evaluateRule(rule Rule, attributes map[string]any) bool {
 		return valuesEqual(attributeValue, rule.Value)
 	case OperatorIn:
 		return valueIn(attributeValue, rule.Value)
+	case OperatorPercentage:
+		return inRollout(flag, attributeValue, rule.Value)
 	default:
 		return false
 	}
 }
 
+// inSegment reports whether attributes match a rule of the segment named by
+// name. Unknown segments, and segments deeper than [MaxSegmentDepth], never
+// match.
+func inSegment(segments map[string]Segment, name any, attributes map[string]any, depth int) bool {
+	segmentName, ok := name.(string)
+	if !ok || depth >= MaxSegmentDepth {
+		return false
+	}
+	segment, ok := segments[segmentName]
+	if !ok {
+		return false
+	}
+
+	member := Flag{Key: segmentBucketPrefix + segmentName, Segments: segments}
+	for _, rule := range segment.Rules {
+		if evaluateRule(member, rule, attributes, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
 func valueIn(value any, ruleValue any) bool {
 	values := reflect.ValueOf(ruleValue)
 	if !values.IsValid() {
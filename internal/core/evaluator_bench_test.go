@@ -131,3 +131,72 @@ for b.Loop() {
 EvaluateFlags(flags, ctx)
 }
 }
+
+func BenchmarkEvaluateFlag_PercentageRollout(b *testing.B) {
+	defaultVal := false
+	flag := Flag{
+		Key:           "feature-rollout",
+		DefaultValue:  &defaultVal,
+		BucketingSalt: "salt",
+		Rules: []Rule{
+			{Attribute: "user_id", Operator: OperatorPercentage, Value: float64(25)},
+		},
+	}
+	ctx := EvaluationContext{
+		Attributes: map[string]any{"user_id": "user-42"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		EvaluateFlag(flag, ctx)
+	}
+}
+
+func BenchmarkEvaluateFlag_Segment(b *testing.B) {
+	defaultVal := false
+	flag := Flag{
+		Key:          "feature-segment",
+		DefaultValue: &defaultVal,
+		Rules: []Rule{
+			{Operator: OperatorSegment, Value: "beta-testers"},
+		},
+		Segments: map[string]Segment{
+			"beta-testers": {
+				Name: "beta-testers",
+				Rules: []Rule{
+					{Attribute: "country", Operator: OperatorIn, Value: []any{"US", "CA"}},
+					{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+				},
+			},
+		},
+	}
+	ctx := EvaluationContext{
+		Attributes: map[string]any{"country": "CA", "plan": "pro"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		EvaluateFlag(flag, ctx)
+	}
+}
+
+func BenchmarkEvaluateFlagDetail_Targets(b *testing.B) {
+	defaultVal := false
+	allow := make([]string, 100)
+	for i := range allow {
+		allow[i] = fmt.Sprintf("user-%d", i)
+	}
+	flag := Flag{
+		Key:          "feature-targets",
+		DefaultValue: &defaultVal,
+		Targets:      Targets{Attribute: "user_id", Allow: allow},
+	}
+	ctx := EvaluationContext{
+		Attributes: map[string]any{"user_id": "user-99"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		EvaluateFlagDetail(flag, ctx)
+	}
+}
@@ -56,6 +56,9 @@ func FuzzValuesEqualSymmetry(f *testing.F) {
 			},
 		}
 
-		_ = EvaluateFlag(flag, context)
+		evaluation := EvaluateFlagDetail(flag, context)
+		if explained, _ := ExplainFlag(flag, context); explained != evaluation {
+			t.Fatalf("ExplainFlag() = %+v, EvaluateFlagDetail() = %+v", explained, evaluation)
+		}
 	})
 }
@@ -321,3 +321,160 @@ func TestEvaluateFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateFlagDetail(t *testing.T) {
+	rules := []Rule{
+		{Attribute: "country", Operator: OperatorEquals, Value: "US"},
+		{Attribute: "plan", Operator: OperatorIn, Value: []string{"pro", "team"}},
+	}
+
+	tests := []struct {
+		name    string
+		flag    Flag
+		context EvaluationContext
+		want    Evaluation
+	}{
+		{
+			name: "disabled",
+			flag: Flag{Disabled: true, Rules: rules},
+			want: Evaluation{Value: false, Reason: ReasonDisabled, RuleIndex: -1},
+		},
+		{
+			name:    "second rule matches",
+			flag:    Flag{Rules: rules},
+			context: EvaluationContext{Attributes: map[string]any{"plan": "team"}},
+			want:    Evaluation{Value: true, Reason: ReasonRuleMatch, RuleIndex: 1},
+		},
+		{
+			name:    "allowed identifier beats default",
+			flag:    Flag{DefaultValue: boolPtr(false), Targets: Targets{Attribute: "user_id", Allow: []string{"42"}}},
+			context: EvaluationContext{Attributes: map[string]any{"user_id": 42.0}},
+			want:    Evaluation{Value: true, Reason: ReasonTargetMatch, RuleIndex: -1},
+		},
+		{
+			name:    "denied identifier beats rules",
+			flag:    Flag{Rules: rules, Targets: Targets{Attribute: "email", Allow: []string{"a@example.com"}, Deny: []string{"a@example.com"}}},
+			context: EvaluationContext{Attributes: map[string]any{"email": "a@example.com", "country": "US"}},
+			want:    Evaluation{Value: false, Reason: ReasonTargetMatch, RuleIndex: -1},
+		},
+		{
+			name:    "unlisted identifier falls through to rules",
+			flag:    Flag{Rules: rules, Targets: Targets{Attribute: "email", Deny: []string{"a@example.com"}}},
+			context: EvaluationContext{Attributes: map[string]any{"email": "b@example.com", "country": "US"}},
+			want:    Evaluation{Value: true, Reason: ReasonRuleMatch, RuleIndex: 0},
+		},
+		{
+			name:    "disabled beats allow list",
+			flag:    Flag{Disabled: true, Targets: Targets{Attribute: "user_id", Allow: []string{"42"}}},
+			context: EvaluationContext{Attributes: map[string]any{"user_id": "42"}},
+			want:    Evaluation{Value: false, Reason: ReasonDisabled, RuleIndex: -1},
+		},
+		{
+			name: "implicit default",
+			flag: Flag{Rules: rules},
+			want: Evaluation{Value: true, Reason: ReasonDefault, RuleIndex: -1},
+		},
+		{
+			name: "variants default",
+			flag: Flag{DefaultValue: boolPtr(false)},
+			want: Evaluation{Value: false, Reason: ReasonDefault, RuleIndex: -1, Variant: VariantDefault},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := EvaluateFlagDetail(test.flag, test.context)
+			if got != test.want {
+				t.Fatalf("EvaluateFlagDetail() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConstantValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      Flag
+		wantValue bool
+		wantOK    bool
+	}{
+		{name: "disabled", flag: Flag{Disabled: true, Rules: []Rule{{Attribute: "plan", Operator: OperatorEquals, Value: "pro"}}}, wantValue: false, wantOK: true},
+		{name: "enabled without rules", flag: Flag{}, wantValue: true, wantOK: true},
+		{name: "default true with rules", flag: Flag{Rules: []Rule{{Attribute: "plan", Operator: OperatorEquals, Value: "pro"}}}, wantValue: true, wantOK: true},
+		{name: "default false without rules", flag: Flag{DefaultValue: boolPtr(false)}, wantValue: false, wantOK: true},
+		{name: "default false with zero rollout", flag: Flag{DefaultValue: boolPtr(false), Rules: []Rule{{Attribute: "user_id", Operator: OperatorPercentage, Value: 0}}}, wantValue: false, wantOK: true},
+		{name: "default false with full rollout", flag: Flag{DefaultValue: boolPtr(false), Rules: []Rule{{Attribute: "user_id", Operator: OperatorPercentage, Value: 100}}}, wantOK: false},
+		{name: "default true with deny list", flag: Flag{Targets: Targets{Attribute: "user_id", Deny: []string{"42"}}}, wantOK: false},
+		{name: "default true with allow list", flag: Flag{Targets: Targets{Attribute: "user_id", Allow: []string{"42"}}}, wantValue: true, wantOK: true},
+		{name: "default false with allow list", flag: Flag{DefaultValue: boolPtr(false), Targets: Targets{Attribute: "user_id", Allow: []string{"42"}}}, wantOK: false},
+		{name: "default false with targeting", flag: Flag{DefaultValue: boolPtr(false), Rules: []Rule{{Attribute: "plan", Operator: OperatorEquals, Value: "pro"}}}, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := ConstantValue(tt.flag)
+			if ok != tt.wantOK || (ok && value != tt.wantValue) {
+				t.Fatalf("ConstantValue() = (%v, %v), want (%v, %v)", value, ok, tt.wantValue, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEvaluateFlagSegments(t *testing.T) {
+	segments := map[string]Segment{
+		"staff": {Name: "staff", Rules: []Rule{{Attribute: "email", Operator: OperatorIn, Value: []any{"a@example.com"}}}},
+		"beta": {Name: "beta", Rules: []Rule{
+			{Operator: OperatorSegment, Value: "staff"},
+			{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+		}},
+		"everyone": {Name: "everyone", Rules: []Rule{{Attribute: "userId", Operator: OperatorPercentage, Value: 100.0}}},
+	}
+	flag := Flag{
+		Key:          "checkout",
+		DefaultValue: boolPtr(false),
+		Rules:        []Rule{{Operator: OperatorSegment, Value: "beta"}},
+		Segments:     segments,
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]any
+		want       bool
+	}{
+		{name: "direct rule", attributes: map[string]any{"plan": "pro"}, want: true},
+		{name: "nested segment", attributes: map[string]any{"email": "a@example.com"}, want: true},
+		{name: "not a member", attributes: map[string]any{"plan": "free", "email": "b@example.com"}, want: false},
+		{name: "no attributes", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateFlagDetail(flag, EvaluationContext{Attributes: tt.attributes})
+			if got.Value != tt.want {
+				t.Fatalf("EvaluateFlagDetail() = %+v, want value %v", got, tt.want)
+			}
+			if tt.want && (got.Reason != ReasonRuleMatch || got.RuleIndex != 0) {
+				t.Fatalf("EvaluateFlagDetail() = %+v, want rule 0 to match", got)
+			}
+		})
+	}
+
+	unknown := flag
+	unknown.Rules = []Rule{{Operator: OperatorSegment, Value: "missing"}}
+	if EvaluateFlag(unknown, EvaluationContext{Attributes: map[string]any{"plan": "pro"}}) {
+		t.Error("rule naming an unknown segment matched")
+	}
+
+	cyclic := map[string]Segment{
+		"a": {Name: "a", Rules: []Rule{{Operator: OperatorSegment, Value: "b"}}},
+		"b": {Name: "b", Rules: []Rule{{Operator: OperatorSegment, Value: "a"}}},
+	}
+	loop := Flag{Key: "loop", DefaultValue: boolPtr(false), Rules: []Rule{{Operator: OperatorSegment, Value: "a"}}, Segments: cyclic}
+	if EvaluateFlag(loop, EvaluationContext{Attributes: map[string]any{"plan": "pro"}}) {
+		t.Error("cyclic segments matched")
+	}
+
+	everyone := flag
+	everyone.Rules = []Rule{{Operator: OperatorSegment, Value: "everyone"}}
+	if !EvaluateFlag(everyone, EvaluationContext{Attributes: map[string]any{"userId": "user-1"}}) {
+		t.Error("100% segment rollout did not match")
+	}
+}
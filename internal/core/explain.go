@@ -0,0 +1,232 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// StepKind names the stage of an evaluation a [TraceStep] describes.
+type StepKind string
+
+const (
+	// StepDisabled is the check that the flag is enabled.
+	StepDisabled StepKind = "disabled"
+	// StepTargets is the lookup of the context's identifier on the flag's
+	// allow and deny lists.
+	StepTargets StepKind = "targets"
+	// StepRule is the evaluation of one rule.
+	StepRule StepKind = "rule"
+	// StepDefault is the fallback to the flag's default value.
+	StepDefault StepKind = "default"
+)
+
+// TraceStep is one step of an [ExplainFlag] trace. Matched reports whether
+// the step decided the value: a disabled flag, a listed identifier, a
+// matching rule, or the default. For rules and targets, Attribute and Value
+// are the context attribute considered and its value, and Present is false
+// when the context does not have it. Steps holds the rules of a segment a
+// rule references, as far as they were evaluated.
+type TraceStep struct {
+	Kind      StepKind    `json:"kind"`
+	RuleIndex *int        `json:"rule_index,omitempty"`
+	Rule      *Rule       `json:"rule,omitempty"`
+	Attribute string      `json:"attribute,omitempty"`
+	Value     any         `json:"value,omitempty"`
+	Present   bool        `json:"present,omitempty"`
+	Matched   bool        `json:"matched"`
+	Detail    string      `json:"detail"`
+	Steps     []TraceStep `json:"steps,omitempty"`
+}
+
+// ExplainFlag evaluates a flag exactly like [EvaluateFlagDetail] and also
+// returns a step-by-step trace of how it got there: each stage considered,
+// in order, up to and including the one that decided the value. It is
+// slower than [EvaluateFlagDetail] and meant for debugging, not serving.
+func ExplainFlag(flag Flag, context EvaluationContext) (Evaluation, []TraceStep) {
+	if flag.Disabled {
+		return Evaluation{Value: false, Reason: ReasonDisabled, RuleIndex: -1}, []TraceStep{{
+			Kind:    StepDisabled,
+			Matched: true,
+			Detail:  "the flag is disabled, so it is false for every context",
+		}}
+	}
+
+	var steps []TraceStep
+	if step, value, ok := explainTargets(flag.Targets, context.Attributes); ok {
+		steps = append(steps, step)
+		if step.Matched {
+			return Evaluation{Value: value, Reason: ReasonTargetMatch, RuleIndex: -1}, steps
+		}
+	}
+
+	for i, rule := range flag.Rules {
+		step := explainRule(flag, rule, context.Attributes, 0)
+		step.RuleIndex = &i
+		steps = append(steps, step)
+		if step.Matched {
+			return Evaluation{Value: true, Reason: ReasonRuleMatch, RuleIndex: i}, steps
+		}
+	}
+
+	result := Evaluation{Value: true, Reason: ReasonDefault, RuleIndex: -1}
+	step := TraceStep{Kind: StepDefault, Matched: true}
+	switch {
+	case flag.DefaultValue != nil:
+		result.Value = *flag.DefaultValue
+		result.Variant = VariantDefault
+		step.Detail = fmt.Sprintf("serving the flag's default value, %t", result.Value)
+	default:
+		step.Detail = "the flag has no default value, so it is true"
+	}
+	if len(flag.Rules) > 0 {
+		step.Detail = "no rule matched; " + step.Detail
+	}
+	return result, append(steps, step)
+}
+
+// explainTargets mirrors [evaluateTargets]. ok is false when the flag has
+// no targets to consult, in which case there is no step either.
+func explainTargets(targets Targets, attributes map[string]any) (step TraceStep, value bool, ok bool) {
+	if targets.Attribute == "" || (len(targets.Allow) == 0 && len(targets.Deny) == 0) {
+		return TraceStep{}, false, false
+	}
+	step = TraceStep{Kind: StepTargets, Attribute: targets.Attribute}
+	raw, present := attributes[targets.Attribute]
+	if !present {
+		step.Detail = fmt.Sprintf("attribute %q is not in the context", targets.Attribute)
+		return step, false, true
+	}
+	step.Value, step.Present = raw, true
+	id, bucketable := TargetingKey(raw)
+	switch {
+	case !bucketable:
+		step.Detail = fmt.Sprintf("%s is not a string or number, so it cannot be on the lists", formatValue(raw))
+	case slices.Contains(targets.Deny, id):
+		step.Matched = true
+		step.Detail = fmt.Sprintf("%q is on the deny list, so the flag is false", id)
+	case slices.Contains(targets.Allow, id):
+		step.Matched, value = true, true
+		step.Detail = fmt.Sprintf("%q is on the allow list, so the flag is true", id)
+	default:
+		step.Detail = fmt.Sprintf("%q is on neither list", id)
+	}
+	return step, value, true
+}
+
+// explainRule mirrors [evaluateRule]. depth is how many segments deep the
+// rule is.
+func explainRule(flag Flag, rule Rule, attributes map[string]any, depth int) TraceStep {
+	step := TraceStep{Kind: StepRule, Rule: &rule}
+	if rule.Operator != OperatorSegment {
+		step.Attribute = rule.Attribute
+	}
+	if attributes == nil {
+		step.Detail = "the context has no attributes"
+		return step
+	}
+	if rule.Operator == OperatorSegment {
+		step.Matched, step.Steps, step.Detail = explainSegment(flag.Segments, rule.Value, attributes, depth)
+		return step
+	}
+
+	attributeValue, ok := attributes[rule.Attribute]
+	if !ok {
+		step.Detail = fmt.Sprintf("attribute %q is not in the context", rule.Attribute)
+		return step
+	}
+	step.Value, step.Present = attributeValue, true
+
+	switch rule.Operator {
+	case OperatorEquals:
+		step.Matched = valuesEqual(attributeValue, rule.Value)
+		if step.Matched {
+			step.Detail = fmt.Sprintf("%s equals %s", formatValue(attributeValue), formatValue(rule.Value))
+		} else {
+			step.Detail = fmt.Sprintf("%s does not equal %s", formatValue(attributeValue), formatValue(rule.Value))
+		}
+	case OperatorIn:
+		values := reflect.ValueOf(rule.Value)
+		if !values.IsValid() || (values.Kind() != reflect.Slice && values.Kind() != reflect.Array) {
+			step.Detail = "the rule value is not a list"
+			break
+		}
+		step.Matched = valueIn(attributeValue, rule.Value)
+		if step.Matched {
+			step.Detail = fmt.Sprintf("%s is in %s", formatValue(attributeValue), formatValue(rule.Value))
+		} else {
+			step.Detail = fmt.Sprintf("%s is not in %s", formatValue(attributeValue), formatValue(rule.Value))
+		}
+	case OperatorPercentage:
+		percentage, ok := percentageValue(rule.Value)
+		if !ok {
+			step.Detail = "the rule value is not a percentage between 0 and 100"
+			break
+		}
+		key, ok := TargetingKey(attributeValue)
+		if !ok {
+			step.Detail = fmt.Sprintf("%s is not a string or number, so it cannot be bucketed", formatValue(attributeValue))
+			break
+		}
+		bucket := Bucket(flag, key)
+		step.Matched = BucketInRollout(bucket, percentage)
+		if step.Matched {
+			step.Detail = fmt.Sprintf("%q falls in bucket %d of %d, inside the %s%% rollout", key, bucket, BucketCount, formatPercentage(percentage))
+		} else {
+			step.Detail = fmt.Sprintf("%q falls in bucket %d of %d, outside the %s%% rollout", key, bucket, BucketCount, formatPercentage(percentage))
+		}
+	default:
+		step.Detail = fmt.Sprintf("unknown operator %q never matches", rule.Operator)
+	}
+	return step
+}
+
+// explainSegment mirrors [inSegment], returning whether attributes are a
+// member, the steps of the segment's rules that were evaluated, and why.
+func explainSegment(segments map[string]Segment, name any, attributes map[string]any, depth int) (bool, []TraceStep, string) {
+	segmentName, ok := name.(string)
+	if !ok {
+		return false, nil, "the rule value is not a segment name"
+	}
+	if depth >= MaxSegmentDepth {
+		return false, nil, fmt.Sprintf("segment %q is nested more than %d segments deep, so it never matches", segmentName, MaxSegmentDepth)
+	}
+	segment, ok := segments[segmentName]
+	if !ok {
+		return false, nil, fmt.Sprintf("segment %q does not exist", segmentName)
+	}
+
+	member := Flag{Key: segmentBucketPrefix + segmentName, Segments: segments}
+	var steps []TraceStep
+	for i, rule := range segment.Rules {
+		step := explainRule(member, rule, attributes, depth+1)
+		step.RuleIndex = &i
+		steps = append(steps, step)
+		if step.Matched {
+			return true, steps, fmt.Sprintf("the context is in segment %q", segmentName)
+		}
+	}
+	return false, steps, fmt.Sprintf("the context is not in segment %q", segmentName)
+}
+
+// formatValue formats a rule or attribute value for a trace: strings are
+// quoted, and everything else is printed as Go formats it.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		formatted := make([]string, len(v))
+		for i, item := range v {
+			formatted[i] = formatValue(item)
+		}
+		return fmt.Sprintf("%v", formatted)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func formatPercentage(percentage float64) string {
+	return strconv.FormatFloat(percentage, 'f', -1, 64)
+}
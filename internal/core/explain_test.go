@@ -0,0 +1,111 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainFlag(t *testing.T) {
+	segments := map[string]Segment{
+		"beta": {Name: "beta", Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorEquals, Value: "free"},
+			{Attribute: "email", Operator: OperatorIn, Value: []any{"a@example.com", "b@example.com"}},
+		}},
+	}
+	flag := Flag{
+		Key:           "checkout",
+		DefaultValue:  boolPtr(false),
+		BucketingSalt: "salt",
+		Targets:       Targets{Attribute: "user_id", Deny: []string{"blocked"}},
+		Rules: []Rule{
+			{Attribute: "country", Operator: OperatorEquals, Value: "US"},
+			{Operator: OperatorSegment, Value: "beta"},
+			{Attribute: "user_id", Operator: OperatorPercentage, Value: 0},
+		},
+		Segments: segments,
+	}
+
+	tests := []struct {
+		name       string
+		flag       Flag
+		attributes map[string]any
+		kinds      []StepKind
+		matched    []bool
+		details    []string
+	}{
+		{
+			name:    "disabled",
+			flag:    Flag{Disabled: true, Rules: flag.Rules},
+			kinds:   []StepKind{StepDisabled},
+			matched: []bool{true},
+			details: []string{"disabled"},
+		},
+		{
+			name:       "denied identifier",
+			flag:       flag,
+			attributes: map[string]any{"user_id": "blocked", "country": "US"},
+			kinds:      []StepKind{StepTargets},
+			matched:    []bool{true},
+			details:    []string{`"blocked" is on the deny list`},
+		},
+		{
+			name:       "segment rule matches",
+			flag:       flag,
+			attributes: map[string]any{"user_id": "u1", "country": "CA", "email": "b@example.com"},
+			kinds:      []StepKind{StepTargets, StepRule, StepRule},
+			matched:    []bool{false, false, true},
+			details:    []string{`"u1" is on neither list`, `"CA" does not equal "US"`, `in segment "beta"`},
+		},
+		{
+			name:       "falls back to the default",
+			flag:       flag,
+			attributes: map[string]any{"user_id": "u1"},
+			kinds:      []StepKind{StepTargets, StepRule, StepRule, StepRule, StepDefault},
+			matched:    []bool{false, false, false, false, true},
+			details:    []string{"neither list", `"country" is not in the context`, `not in segment "beta"`, "outside the 0% rollout", "no rule matched; serving the flag's default value, false"},
+		},
+		{
+			name:    "no rules and no default",
+			flag:    Flag{},
+			kinds:   []StepKind{StepDefault},
+			matched: []bool{true},
+			details: []string{"no default value, so it is true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := EvaluationContext{Attributes: tt.attributes}
+			evaluation, steps := ExplainFlag(tt.flag, context)
+			if want := EvaluateFlagDetail(tt.flag, context); evaluation != want {
+				t.Fatalf("evaluation = %+v, want %+v as EvaluateFlagDetail returns", evaluation, want)
+			}
+			if len(steps) != len(tt.kinds) {
+				t.Fatalf("steps = %+v, want kinds %v", steps, tt.kinds)
+			}
+			for i, step := range steps {
+				if step.Kind != tt.kinds[i] || step.Matched != tt.matched[i] || !strings.Contains(step.Detail, tt.details[i]) {
+					t.Errorf("step %d = %+v, want kind %s, matched %t, detail containing %q", i, step, tt.kinds[i], tt.matched[i], tt.details[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExplainFlagSegmentSteps(t *testing.T) {
+	flag := Flag{
+		DefaultValue: boolPtr(false),
+		Rules:        []Rule{{Operator: OperatorSegment, Value: "beta"}},
+		Segments: map[string]Segment{"beta": {Name: "beta", Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorEquals, Value: "free"},
+			{Attribute: "plan", Operator: OperatorIn, Value: []any{"pro", "team"}},
+		}}},
+	}
+	_, steps := ExplainFlag(flag, EvaluationContext{Attributes: map[string]any{"plan": "pro"}})
+	nested := steps[0].Steps
+	if len(nested) != 2 || nested[0].Matched || !nested[1].Matched || *nested[1].RuleIndex != 1 {
+		t.Fatalf("segment steps = %+v, want rule 0 failing and rule 1 matching", nested)
+	}
+	if nested[1].Value != "pro" || !nested[1].Present || nested[1].Detail != `"pro" is in ["pro" "team"]` {
+		t.Fatalf("segment step 1 = %+v", nested[1])
+	}
+}
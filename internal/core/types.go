@@ -14,8 +14,47 @@ const (
 	OperatorEquals Operator = "equals"
 	// OperatorIn matches when an attribute value is contained in the rule value list.
 	OperatorIn Operator = "in"
+	// OperatorPercentage matches when the attribute value, used as a
+	// targeting key, hashes into a bucket below the rule value (a percentage
+	// between 0 and 100). See [Bucket].
+	OperatorPercentage Operator = "percentage"
+	// OperatorSegment matches when the evaluation context is a member of
+	// the [Segment] named by the rule value. The rule's attribute is unused.
+	OperatorSegment Operator = "segment"
 )
 
+// MaxSegmentDepth is how many segments deep a rule may reach through
+// segments that reference other segments. Deeper segments never match.
+const MaxSegmentDepth = 5
+
+// Reason explains why an evaluation produced its value.
+type Reason string
+
+const (
+	// ReasonRuleMatch means a targeting rule matched the evaluation context.
+	ReasonRuleMatch Reason = "RULE_MATCH"
+	// ReasonTargetMatch means the evaluation context's identifier is on the
+	// flag's allow or deny list.
+	ReasonTargetMatch Reason = "TARGET_MATCH"
+	// ReasonDefault means no rule matched (or none exist) and the flag's
+	// default value was used.
+	ReasonDefault Reason = "DEFAULT"
+	// ReasonDisabled means the flag is disabled and evaluated to false.
+	ReasonDisabled Reason = "DISABLED"
+	// ReasonFlagNotFound means the flag does not exist and the caller's
+	// default value was returned. The evaluator never produces this itself;
+	// it is set by callers that look flags up.
+	ReasonFlagNotFound Reason = "FLAG_NOT_FOUND"
+	// ReasonTypeMismatch means a typed evaluation found a value of another
+	// type than the caller's default, so the default was returned. Like
+	// [ReasonFlagNotFound], it is set by callers.
+	ReasonTypeMismatch Reason = "TYPE_MISMATCH"
+)
+
+// VariantDefault names the variant used when the value comes from the
+// "default" entry of a flag's variants.
+const VariantDefault = "default"
+
 // Rule defines a single targeting condition. When evaluated, it checks whether
 // the named attribute in the evaluation context satisfies the operator and value.
 type Rule struct {
@@ -24,15 +63,42 @@ type Rule struct {
 	Value     any      `json:"value"`
 }
 
+// Targets pins individual identifiers, such as user IDs or emails, to a
+// flag value ahead of its rules. Attribute names the evaluation-context
+// attribute holding the identifier; values are compared as [TargetingKey]
+// strings. An identifier on both lists is denied.
+type Targets struct {
+	Attribute string   `json:"attribute,omitempty"`
+	Allow     []string `json:"allow,omitempty"`
+	Deny      []string `json:"deny,omitempty"`
+}
+
+// Segment is a named, reusable set of rules, such as "beta-testers". A
+// context is a member when any of its rules match. Percentage rules inside
+// a segment bucket on the key "segment:" followed by the segment's name, with
+// no salt, so membership is the same for every flag referencing it.
+type Segment struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
 // Flag is the core representation of a feature flag used during evaluation.
 // Note that Disabled uses inverted polarity compared to [repository.Flag].Enabled;
 // the mapping layer handles the conversion so you don't have to think about it
 // (most of the time).
+//
+// BucketingSalt seeds percentage-rollout bucketing; changing it reassigns
+// every targeting key to a new bucket. Segments holds the segments its
+// rules may reference, by name.
 type Flag struct {
-	Key          string `json:"key"`
-	Disabled     bool   `json:"disabled,omitempty"`
-	DefaultValue *bool  `json:"default_value,omitempty"`
-	Rules        []Rule `json:"rules,omitempty"`
+	Key           string  `json:"key"`
+	Disabled      bool    `json:"disabled,omitempty"`
+	DefaultValue  *bool   `json:"default_value,omitempty"`
+	Rules         []Rule  `json:"rules,omitempty"`
+	BucketingSalt string  `json:"bucketing_salt,omitempty"`
+	Targets       Targets `json:"targets,omitzero"`
+
+	Segments map[string]Segment `json:"-"`
 }
 
 // EvaluationContext carries the attribute map provided by a caller at evaluation
@@ -40,3 +106,14 @@ type Flag struct {
 type EvaluationContext struct {
 	Attributes map[string]any `json:"attributes,omitempty"`
 }
+
+// Evaluation is the detailed outcome of evaluating a flag. RuleIndex is the
+// zero-based index of the matching rule when Reason is [ReasonRuleMatch] and
+// -1 otherwise. Variant is [VariantDefault] when the value came from the
+// flag's configured default, and empty otherwise.
+type Evaluation struct {
+	Value     bool
+	Reason    Reason
+	RuleIndex int
+	Variant   string
+}
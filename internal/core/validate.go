@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// RuleError describes why the rule at Index is invalid.
+type RuleError struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func (e RuleError) Error() string {
+	return fmt.Sprintf("rule %d: %s", e.Index, e.Reason)
+}
+
+// ValidateRules checks every rule and returns one [RuleError] per invalid
+// rule, in order, or nil if all are valid. Attribute names are not checked
+// against any schema, since evaluation contexts are free-form, but each rule
+// must name one, use a known operator, and carry a value of the type that
+// operator compares against:
+//
+//   - equals: a string, number or boolean
+//   - in: a non-empty list of strings, numbers or booleans
+//   - percentage: a number between 0 and 100
+//   - segment: the name of a segment; the attribute may be omitted
+//
+// Whether a named segment exists is not checked; see [ValidateSegments].
+//
+// The evaluator tolerates rules that fail these checks by never matching
+// them; validation exists so such rules are rejected when written instead of
+// silently doing nothing.
+func ValidateRules(rules []Rule) []RuleError {
+	var errs []RuleError
+	for i, rule := range rules {
+		if reason := validateRule(rule); reason != "" {
+			errs = append(errs, RuleError{Index: i, Reason: reason})
+		}
+	}
+	return errs
+}
+
+func validateRule(rule Rule) string {
+	if rule.Operator == OperatorSegment {
+		if _, ok := SegmentName(rule); !ok {
+			return "segment value must be a segment name"
+		}
+		return ""
+	}
+	if strings.TrimSpace(rule.Attribute) == "" {
+		return "attribute is required"
+	}
+
+	switch rule.Operator {
+	case OperatorEquals:
+		if !isScalar(rule.Value) {
+			return "equals value must be a string, number or boolean"
+		}
+	case OperatorIn:
+		values := reflect.ValueOf(rule.Value)
+		if !values.IsValid() || (values.Kind() != reflect.Slice && values.Kind() != reflect.Array) || values.Len() == 0 {
+			return "in value must be a non-empty list of strings, numbers or booleans"
+		}
+		for j := 0; j < values.Len(); j++ {
+			if !isScalar(values.Index(j).Interface()) {
+				return fmt.Sprintf("in value[%d] must be a string, number or boolean", j)
+			}
+		}
+	case OperatorPercentage:
+		if _, ok := percentageValue(rule.Value); !ok {
+			return "percentage must be a number between 0 and 100"
+		}
+	case "":
+		return "operator is required"
+	default:
+		return fmt.Sprintf("unknown operator %q", rule.Operator)
+	}
+	return ""
+}
+
+// SegmentName returns the segment referenced by an [OperatorSegment] rule.
+// It reports false when the rule uses a different operator or its value is
+// not a non-empty string.
+func SegmentName(rule Rule) (string, bool) {
+	if rule.Operator != OperatorSegment {
+		return "", false
+	}
+	name, ok := rule.Value.(string)
+	return name, ok && name != ""
+}
+
+// ValidateSegments checks that every segment reference among segments'
+// rules names one of segments, that no segment references itself, directly
+// or through others, and that none nests more than [MaxSegmentDepth]
+// segments deep. The rules themselves are checked by [ValidateRules].
+func ValidateSegments(segments map[string]Segment) error {
+	depths := make(map[string]int, len(segments))
+	for _, name := range slices.Sorted(maps.Keys(segments)) {
+		if _, err := segmentDepth(segments, name, depths, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segmentDepth returns how many segments deep name nests, counting itself,
+// memoising results in depths. path is the chain of segments being
+// resolved, for detecting cycles.
+func segmentDepth(segments map[string]Segment, name string, depths map[string]int, path []string) (int, error) {
+	if depth, ok := depths[name]; ok {
+		return depth, nil
+	}
+	if i := slices.Index(path, name); i >= 0 {
+		return 0, fmt.Errorf("segment cycle: %s", strings.Join(append(path[i:], name), " -> "))
+	}
+
+	path = append(path, name)
+	depth := 1
+	for _, rule := range segments[name].Rules {
+		ref, ok := SegmentName(rule)
+		if !ok {
+			continue
+		}
+		if _, exists := segments[ref]; !exists {
+			return 0, fmt.Errorf("segment %q references unknown segment %q", name, ref)
+		}
+		refDepth, err := segmentDepth(segments, ref, depths, path)
+		if err != nil {
+			return 0, err
+		}
+		depth = max(depth, refDepth+1)
+	}
+	if depth > MaxSegmentDepth {
+		return 0, fmt.Errorf("segment %q nests %d segments deep, more than %d", name, depth, MaxSegmentDepth)
+	}
+	depths[name] = depth
+	return depth, nil
+}
+
+func isScalar(value any) bool {
+	switch value.(type) {
+	case string, bool:
+		return true
+	}
+	if _, ok := asFloat64(value); ok {
+		return true
+	}
+	if _, ok := asInt64(value); ok {
+		return true
+	}
+	_, ok := asUint64(value)
+	return ok
+}
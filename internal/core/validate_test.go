@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRules(t *testing.T) {
+	rules := []Rule{
+		{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+		{Attribute: "country", Operator: OperatorIn, Value: []any{"GB", "IE", 3.0}},
+		{Attribute: "userId", Operator: OperatorPercentage, Value: 25.0},
+		{Attribute: "region", Operator: OperatorIn, Value: []string{"eu"}},
+		{Attribute: "", Operator: OperatorEquals, Value: "x"},
+		{Attribute: "plan", Operator: "regex", Value: ".*"},
+		{Attribute: "plan", Value: "pro"},
+		{Attribute: "plan", Operator: OperatorEquals, Value: map[string]any{"a": 1.0}},
+		{Attribute: "plan", Operator: OperatorEquals},
+		{Attribute: "country", Operator: OperatorIn, Value: "GB"},
+		{Attribute: "country", Operator: OperatorIn, Value: []any{}},
+		{Attribute: "country", Operator: OperatorIn, Value: []any{"GB", []any{"IE"}}},
+		{Attribute: "userId", Operator: OperatorPercentage, Value: 101.0},
+		{Attribute: "userId", Operator: OperatorPercentage, Value: "50"},
+		{Operator: OperatorSegment, Value: "beta-testers"},
+		{Operator: OperatorSegment, Value: 7.0},
+	}
+
+	want := []RuleError{
+		{Index: 4, Reason: "attribute is required"},
+		{Index: 5, Reason: `unknown operator "regex"`},
+		{Index: 6, Reason: "operator is required"},
+		{Index: 7, Reason: "equals value must be a string, number or boolean"},
+		{Index: 8, Reason: "equals value must be a string, number or boolean"},
+		{Index: 9, Reason: "in value must be a non-empty list of strings, numbers or booleans"},
+		{Index: 10, Reason: "in value must be a non-empty list of strings, numbers or booleans"},
+		{Index: 11, Reason: "in value[1] must be a string, number or boolean"},
+		{Index: 12, Reason: "percentage must be a number between 0 and 100"},
+		{Index: 13, Reason: "percentage must be a number between 0 and 100"},
+		{Index: 15, Reason: "segment value must be a segment name"},
+	}
+	if got := ValidateRules(rules); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ValidateRules() = %+v\nwant %+v", got, want)
+	}
+
+	if got := ValidateRules(rules[:4]); got != nil {
+		t.Fatalf("ValidateRules(valid) = %+v, want nil", got)
+	}
+}
+
+func TestValidateSegments(t *testing.T) {
+	ref := func(name string) Rule { return Rule{Operator: OperatorSegment, Value: name} }
+	staff := Rule{Attribute: "email", Operator: OperatorEquals, Value: "a@example.com"}
+
+	valid := map[string]Segment{
+		"staff": {Name: "staff", Rules: []Rule{staff}},
+		"beta":  {Name: "beta", Rules: []Rule{ref("staff"), {Attribute: "plan", Operator: OperatorEquals, Value: "pro"}}},
+	}
+	if err := ValidateSegments(valid); err != nil {
+		t.Fatalf("ValidateSegments(valid) error = %v", err)
+	}
+
+	chain := make(map[string]Segment)
+	for i := range MaxSegmentDepth + 1 {
+		name := fmt.Sprintf("s%d", i)
+		segment := Segment{Name: name, Rules: []Rule{staff}}
+		if i > 0 {
+			segment.Rules = []Rule{ref(fmt.Sprintf("s%d", i-1))}
+		}
+		chain[name] = segment
+	}
+
+	tests := []struct {
+		name     string
+		segments map[string]Segment
+		want     string
+	}{
+		{
+			name:     "unknown reference",
+			segments: map[string]Segment{"beta": {Name: "beta", Rules: []Rule{ref("missing")}}},
+			want:     `segment "beta" references unknown segment "missing"`,
+		},
+		{
+			name: "cycle",
+			segments: map[string]Segment{
+				"a": {Name: "a", Rules: []Rule{ref("b")}},
+				"b": {Name: "b", Rules: []Rule{ref("a")}},
+			},
+			want: "segment cycle: a -> b -> a",
+		},
+		{
+			name:     "self reference",
+			segments: map[string]Segment{"a": {Name: "a", Rules: []Rule{ref("a")}}},
+			want:     "segment cycle: a -> a",
+		},
+		{
+			name:     "too deep",
+			segments: chain,
+			want:     fmt.Sprintf(`segment "s%d" nests %d segments deep, more than %d`, MaxSegmentDepth, MaxSegmentDepth+1, MaxSegmentDepth),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSegments(tt.segments)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("ValidateSegments() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package export writes the audit log and flag event history of every
+// project as Parquet files, for loading into data warehouses.
+//
+// [Write] streams one dataset over a time range to any writer; the HTTP API
+// uses it for GET /v1/admin/export/{dataset}. A [Scheduler] runs the same
+// export on an interval and uploads each completed window to S3-compatible
+// object storage.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Datasets that can be exported.
+const (
+	DatasetAudit  = "audit"
+	DatasetEvents = "events"
+)
+
+// ContentType is the media type of exported files.
+const ContentType = "application/vnd.apache.parquet"
+
+// pageSize is how many rows are read from the source, and written as one
+// Parquet row group, at a time.
+const pageSize = 1000
+
+// ErrUnknownDataset is returned for a dataset other than [DatasetAudit] and
+// [DatasetEvents].
+var ErrUnknownDataset = errors.New("unknown export dataset")
+
+// Source is the subset of [service.Service] exports read from.
+type Source interface {
+	ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error)
+	ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error)
+}
+
+// Range is the half-open interval [From, To) of creation times to export.
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseRange parses the range query parameter of an export request. It is
+// either two RFC 3339 times separated by "/", either of which may be left
+// out ("2026-01-01T00:00:00Z/", "/2026-02-01T00:00:00Z"), or a duration such
+// as "24h" meaning that long before now. An empty string is everything up to
+// now.
+func ParseRange(s string, now time.Time) (Range, error) {
+	rng := Range{From: time.Unix(0, 0).UTC(), To: now}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return rng, nil
+	}
+
+	start, end, isInterval := strings.Cut(s, "/")
+	if !isInterval {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return Range{}, fmt.Errorf("range %q is neither an interval nor a positive duration", s)
+		}
+		rng.From = now.Add(-d)
+		return rng, nil
+	}
+
+	var err error
+	if start != "" {
+		if rng.From, err = time.Parse(time.RFC3339, start); err != nil {
+			return Range{}, fmt.Errorf("range start: %w", err)
+		}
+	}
+	if end != "" {
+		if rng.To, err = time.Parse(time.RFC3339, end); err != nil {
+			return Range{}, fmt.Errorf("range end: %w", err)
+		}
+	}
+	if !rng.From.Before(rng.To) {
+		return Range{}, fmt.Errorf("range start %s is not before its end %s", rng.From.Format(time.RFC3339), rng.To.Format(time.RFC3339))
+	}
+	return rng, nil
+}
+
+// auditRow is the Parquet schema of the audit dataset. Details holds the
+// entry's JSON details as a string, null when there are none.
+type auditRow struct {
+	ID          int64     `parquet:"id"`
+	ProjectID   string    `parquet:"project_id,dict"`
+	APIKeyID    string    `parquet:"api_key_id,dict"`
+	AdminUserID string    `parquet:"admin_user_id,dict"`
+	Action      string    `parquet:"action,dict"`
+	FlagKey     string    `parquet:"flag_key,dict"`
+	Details     string    `parquet:"details,optional"`
+	RequestID   string    `parquet:"request_id"`
+	CreatedAt   time.Time `parquet:"created_at,timestamp(microsecond)"`
+}
+
+// eventRow is the Parquet schema of the events dataset. Payload is the flag
+// as stored with the event.
+type eventRow struct {
+	EventID   int64     `parquet:"event_id"`
+	ProjectID string    `parquet:"project_id,dict"`
+	FlagKey   string    `parquet:"flag_key,dict"`
+	EventType string    `parquet:"event_type,dict"`
+	Payload   string    `parquet:"payload,json"`
+	CreatedAt time.Time `parquet:"created_at,timestamp(microsecond)"`
+}
+
+// Write exports the rows of dataset created within rng to w as a single
+// Parquet file and returns how many rows it wrote. Rows are read and written
+// a page at a time, so memory use does not grow with the range. If an error
+// occurs after writing has started, w holds an incomplete file.
+func Write(ctx context.Context, w io.Writer, src Source, dataset string, rng Range) (int, error) {
+	switch dataset {
+	case DatasetAudit:
+		return writePages(ctx, w, func(after int64) ([]repository.AuditLogEntry, error) {
+			return src.ListAuditLogRange(ctx, after, rng.From, rng.To, pageSize)
+		}, func(e repository.AuditLogEntry) (auditRow, int64) {
+			return auditRow{
+				ID:          e.ID,
+				ProjectID:   e.ProjectID,
+				APIKeyID:    e.APIKeyID,
+				AdminUserID: e.AdminUserID,
+				Action:      e.Action,
+				FlagKey:     e.FlagKey,
+				Details:     string(e.Details),
+				RequestID:   e.RequestID,
+				CreatedAt:   e.CreatedAt,
+			}, e.ID
+		})
+	case DatasetEvents:
+		return writePages(ctx, w, func(after int64) ([]repository.FlagEvent, error) {
+			return src.ListEventsRange(ctx, after, rng.From, rng.To, pageSize)
+		}, func(e repository.FlagEvent) (eventRow, int64) {
+			return eventRow{
+				EventID:   e.EventID,
+				ProjectID: e.ProjectID,
+				FlagKey:   e.FlagKey,
+				EventType: e.EventType,
+				Payload:   string(e.Payload),
+				CreatedAt: e.CreatedAt,
+			}, e.EventID
+		})
+	default:
+		return 0, ErrUnknownDataset
+	}
+}
+
+// writePages pages through list, starting after ID 0, and writes each page
+// as a row group until a short page marks the end.
+func writePages[T, R any](ctx context.Context, w io.Writer, list func(after int64) ([]T, error), toRow func(T) (R, int64)) (int, error) {
+	pw := parquet.NewGenericWriter[R](w, parquet.Compression(&parquet.Zstd))
+	total := 0
+	var after int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		page, err := list(after)
+		if err != nil {
+			return total, err
+		}
+
+		rows := make([]R, len(page))
+		for i, item := range page {
+			rows[i], after = toRow(item)
+		}
+		if _, err := pw.Write(rows); err != nil {
+			return total, fmt.Errorf("write parquet rows: %w", err)
+		}
+		if err := pw.Flush(); err != nil {
+			return total, fmt.Errorf("flush parquet row group: %w", err)
+		}
+		total += len(rows)
+
+		if len(page) < pageSize {
+			break
+		}
+	}
+	if err := pw.Close(); err != nil {
+		return total, fmt.Errorf("close parquet file: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,197 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// fakeSource serves one audit entry and events events, one per second from
+// base, ignoring the range.
+type fakeSource struct {
+	base   time.Time
+	events int
+	pages  int
+}
+
+func (s *fakeSource) ListAuditLogRange(_ context.Context, afterID int64, _, _ time.Time, _ int) ([]repository.AuditLogEntry, error) {
+	if afterID > 0 {
+		return nil, nil
+	}
+	return []repository.AuditLogEntry{{ID: 1, ProjectID: "proj-a", Action: "create_flag", FlagKey: "checkout", CreatedAt: s.base}}, nil
+}
+
+func (s *fakeSource) ListEventsRange(_ context.Context, afterEventID int64, _, _ time.Time, limit int) ([]repository.FlagEvent, error) {
+	s.pages++
+	var page []repository.FlagEvent
+	for id := afterEventID + 1; id <= int64(s.events) && len(page) < limit; id++ {
+		page = append(page, repository.FlagEvent{
+			EventID:   id,
+			ProjectID: "proj-a",
+			FlagKey:   "checkout",
+			EventType: "updated",
+			Payload:   json.RawMessage(`{"key":"checkout"}`),
+			CreatedAt: s.base.Add(time.Duration(id) * time.Second),
+		})
+	}
+	return page, nil
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in      string
+		want    Range
+		wantErr bool
+	}{
+		{in: "", want: Range{From: time.Unix(0, 0).UTC(), To: now}},
+		{in: "24h", want: Range{From: now.Add(-24 * time.Hour), To: now}},
+		{in: "2026-01-01T00:00:00Z/2026-02-01T00:00:00Z", want: Range{From: jan, To: feb}},
+		{in: "2026-01-01T00:00:00Z/", want: Range{From: jan, To: now}},
+		{in: "/2026-02-01T00:00:00Z", want: Range{From: time.Unix(0, 0).UTC(), To: feb}},
+		{in: "-1h", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "2026-01-01/", wantErr: true},
+		{in: "2026-02-01T00:00:00Z/2026-01-01T00:00:00Z", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRange(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (!got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To)) {
+				t.Errorf("ParseRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEventsPagesIntoRowGroups(t *testing.T) {
+	src := &fakeSource{base: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), events: pageSize + 5}
+	var buf bytes.Buffer
+	n, err := Write(context.Background(), &buf, src, DatasetEvents, Range{})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if n != pageSize+5 || src.pages != 2 {
+		t.Errorf("Write() = %d rows in %d pages, want %d rows in 2 pages", n, src.pages, pageSize+5)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	if got := len(f.RowGroups()); got != 2 {
+		t.Errorf("row groups = %d, want 2", got)
+	}
+	rows, err := parquet.Read[eventRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	last := rows[len(rows)-1]
+	if last.EventID != pageSize+5 || last.Payload != `{"key":"checkout"}` || !last.CreatedAt.Equal(src.base.Add((pageSize+5)*time.Second)) {
+		t.Errorf("last row = %+v", last)
+	}
+}
+
+func TestWriteUnknownDataset(t *testing.T) {
+	if _, err := Write(context.Background(), io.Discard, &fakeSource{}, "flags", Range{}); err != ErrUnknownDataset {
+		t.Errorf("Write() error = %v, want ErrUnknownDataset", err)
+	}
+}
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) PutObject(_ context.Context, key string, body io.ReadSeeker) error {
+	b, err := io.ReadAll(body)
+	m[key] = b
+	return err
+}
+
+func TestSchedulerExportWindow(t *testing.T) {
+	store := memoryStore{}
+	s := NewScheduler(&fakeSource{base: time.Now(), events: 3}, store, WithPrefix("flagz/"), WithInterval(time.Hour))
+	from := time.Date(2026, 1, 2, 13, 0, 0, 0, time.UTC)
+	if err := s.ExportWindow(context.Background(), Range{From: from, To: from.Add(time.Hour)}); err != nil {
+		t.Fatalf("ExportWindow() error = %v", err)
+	}
+
+	for key, want := range map[string]int64{
+		"flagz/audit/dt=2026-01-02/audit-20260102T130000Z.parquet":   1,
+		"flagz/events/dt=2026-01-02/events-20260102T130000Z.parquet": 3,
+	} {
+		body, ok := store[key]
+		if !ok {
+			t.Errorf("object %q not uploaded; have %v", key, store)
+			continue
+		}
+		f, err := parquet.OpenFile(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			t.Fatalf("open %s: %v", key, err)
+		}
+		if got := f.NumRows(); got != want {
+			t.Errorf("%s has %d rows, want %d", key, got, want)
+		}
+	}
+}
+
+func TestS3StorePutObject(t *testing.T) {
+	var gotPath, gotAuth, gotHash, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotAuth, gotHash, gotBody = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Amz-Content-Sha256"), string(body)
+		if gotPath == "/exports/denied.parquet" {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        srv.URL,
+		Region:          "eu-west-1",
+		Bucket:          "exports",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("NewS3Store() error = %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := store.PutObject(context.Background(), "audit/dt=2026-01-02/a.parquet", strings.NewReader("PAR1")); err != nil {
+		t.Fatalf("PutObject() error = %v", err)
+	}
+	if gotPath != "/exports/audit/dt=2026-01-02/a.parquet" || gotBody != "PAR1" {
+		t.Errorf("PUT %s with body %q, want /exports/audit/dt=2026-01-02/a.parquet with PAR1", gotPath, gotBody)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/20260102/eu-west-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for AKID in eu-west-1", gotAuth)
+	}
+	if want := "fbc62d3b511368ee275ddc74117d8689b430e1427220e25d30816201d89ca7b6"; gotHash != want {
+		t.Errorf("X-Amz-Content-Sha256 = %q, want %q", gotHash, want)
+	}
+
+	if err := store.PutObject(context.Background(), "denied.parquet", strings.NewReader("PAR1")); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("PutObject() error = %v, want the store's rejection", err)
+	}
+	if _, err := NewS3Store(S3Config{}, nil); err == nil {
+		t.Error("NewS3Store() accepted a config without a bucket")
+	}
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// ObjectStore stores exported files.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body io.ReadSeeker) error
+}
+
+// S3Config locates a bucket in S3 or an S3-compatible store such as MinIO.
+type S3Config struct {
+	// Endpoint is the base URL of the store. Defaults to the AWS endpoint of
+	// Region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Store uploads objects with SigV4-signed, path-style PUT requests, which
+// every S3-compatible store accepts.
+type S3Store struct {
+	cfg        S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	signer     *v4.Signer
+	now        func() time.Time
+}
+
+// NewS3Store returns an S3Store for cfg. A nil httpClient uses
+// http.DefaultClient.
+func NewS3Store(cfg S3Config, httpClient *http.Client) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &S3Store{
+		cfg:        cfg,
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 signs the path as sent rather than escaping it again.
+			o.DisableURIPathEscaping = true
+		}),
+		now: time.Now,
+	}, nil
+}
+
+// PutObject uploads body as key, replacing any existing object.
+func (s *S3Store) PutObject(ctx context.Context, key string, body io.ReadSeeker) error {
+	hash := sha256.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return fmt.Errorf("hash object: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind object: %w", err)
+	}
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+
+	target := *s.endpoint
+	target.Path = target.Path + "/" + s.cfg.Bucket + "/" + strings.TrimLeft(key, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), io.NopCloser(body))
+	if err != nil {
+		return fmt.Errorf("create s3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds := aws.Credentials{AccessKeyID: s.cfg.AccessKeyID, SecretAccessKey: s.cfg.SecretAccessKey}
+	if err := s.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.cfg.Region, s.now()); err != nil {
+		return fmt.Errorf("sign s3 request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 put %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 put %s: unexpected status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
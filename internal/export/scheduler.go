@@ -0,0 +1,139 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const defaultInterval = 24 * time.Hour
+
+// Scheduler exports every dataset to an [ObjectStore] once per interval.
+//
+// Each run exports the most recent complete window, aligned to multiples of
+// the interval since the Unix epoch, to
+//
+//	<prefix><dataset>/dt=<YYYY-MM-DD>/<dataset>-<window start>.parquet
+//
+// Object keys depend only on the window, so replicas that run the same
+// export overwrite each other with identical files.
+type Scheduler struct {
+	src      Source
+	store    ObjectStore
+	interval time.Duration
+	prefix   string
+	log      *slog.Logger
+	now      func() time.Time
+}
+
+// SchedulerOption configures a [Scheduler].
+type SchedulerOption func(*Scheduler)
+
+// WithInterval sets the export window length and how often exports run.
+// Defaults to 24 hours if not set or if interval <= 0.
+func WithInterval(interval time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithPrefix sets the key prefix of uploaded objects, such as "flagz/".
+func WithPrefix(prefix string) SchedulerOption {
+	return func(s *Scheduler) {
+		s.prefix = prefix
+	}
+}
+
+// WithLogger sets the logger used to report export results.
+func WithLogger(log *slog.Logger) SchedulerOption {
+	return func(s *Scheduler) {
+		if log != nil {
+			s.log = log
+		}
+	}
+}
+
+// NewScheduler returns a Scheduler that exports from src to store.
+func NewScheduler(src Source, store ObjectStore, opts ...SchedulerOption) *Scheduler {
+	if src == nil {
+		panic("source is nil")
+	}
+	if store == nil {
+		panic("object store is nil")
+	}
+
+	s := &Scheduler{
+		src:      src,
+		store:    store,
+		interval: defaultInterval,
+		log:      slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run exports the last complete window immediately and then each window as
+// it completes, until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		end := s.now().UTC().Truncate(s.interval)
+		if err := s.ExportWindow(ctx, Range{From: end.Add(-s.interval), To: end}); err != nil && ctx.Err() == nil {
+			s.log.Warn("scheduled export failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(end.Add(s.interval))):
+		}
+	}
+}
+
+// ExportWindow exports every dataset created within rng and uploads them.
+func (s *Scheduler) ExportWindow(ctx context.Context, rng Range) error {
+	for _, dataset := range []string{DatasetAudit, DatasetEvents} {
+		key := s.objectKey(dataset, rng.From)
+		rows, err := s.exportDataset(ctx, dataset, rng, key)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", dataset, err)
+		}
+		s.log.Info("exported dataset", "dataset", dataset, "key", key, "rows", rows)
+	}
+	return nil
+}
+
+// exportDataset writes the dataset to a temporary file, since the store
+// needs the size and hash of an object before uploading it.
+func (s *Scheduler) exportDataset(ctx context.Context, dataset string, rng Range, key string) (int, error) {
+	f, err := os.CreateTemp("", "flagz-export-*.parquet")
+	if err != nil {
+		return 0, fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	rows, err := Write(ctx, f, s.src, dataset, rng)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("rewind temp file: %w", err)
+	}
+	if err := s.store.PutObject(ctx, key, f); err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+func (s *Scheduler) objectKey(dataset string, start time.Time) string {
+	start = start.UTC()
+	return fmt.Sprintf("%s%s/dt=%s/%s-%s.parquet", s.prefix, dataset, start.Format(time.DateOnly), dataset, start.Format("20060102T150405Z"))
+}
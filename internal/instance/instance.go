@@ -0,0 +1,84 @@
+// Package instance identifies the running flagz server, so that clients and
+// dashboards can tell which replica, in which region and on which build,
+// answered them. The identity is served by GET /v1/info, sent in SSE
+// heartbeats and attached to exported traces, logs and metrics.
+package instance
+
+import (
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time with, for example,
+//
+//	-ldflags "-X github.com/matt-riley/flagz/internal/instance.version=v1.2.3 -X github.com/matt-riley/flagz/internal/instance.gitSHA=$(git rev-parse HEAD)"
+//
+// Without them, [New] falls back to the build information the Go toolchain
+// embeds.
+var (
+	version string
+	gitSHA  string
+)
+
+// unknown is reported for any part of the identity that cannot be found.
+const unknown = "unknown"
+
+// Identity describes one server process.
+type Identity struct {
+	// Region is where the server runs, as set by REGION. It is empty when
+	// unset.
+	Region   string `json:"region,omitempty"`
+	Hostname string `json:"hostname"`
+	Version  string `json:"version"`
+	GitSHA   string `json:"git_sha"`
+}
+
+// New returns the identity of this process in region.
+func New(region string) Identity {
+	id := Identity{
+		Region:   strings.TrimSpace(region),
+		Hostname: unknown,
+		Version:  version,
+		GitSHA:   gitSHA,
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		id.Hostname = hostname
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		id.fillFromBuildInfo(info)
+	}
+	if id.Version == "" {
+		id.Version = unknown
+	}
+	if id.GitSHA == "" {
+		id.GitSHA = unknown
+	}
+	return id
+}
+
+// fillFromBuildInfo fills in the version and revision not set at build time
+// from info. "go build" of a module checkout reports its version as
+// "(devel)", which is no more use than unknown.
+func (id *Identity) fillFromBuildInfo(info *debug.BuildInfo) {
+	if id.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		id.Version = info.Main.Version
+	}
+	if id.GitSHA != "" {
+		return
+	}
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	id.GitSHA = revision
+}
@@ -0,0 +1,43 @@
+package instance
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	id := New("  eu-west-1 ")
+	if id.Region != "eu-west-1" {
+		t.Errorf("Region = %q, want eu-west-1", id.Region)
+	}
+	if id.Hostname == "" || id.Version == "" || id.GitSHA == "" {
+		t.Errorf("New() = %+v, want every field but Region set", id)
+	}
+}
+
+func TestFillFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abc123"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	var id Identity
+	id.fillFromBuildInfo(info)
+	if id.Version != "v1.4.0" || id.GitSHA != "abc123-dirty" {
+		t.Errorf("fillFromBuildInfo() = %+v, want v1.4.0 and abc123-dirty", id)
+	}
+
+	id = Identity{Version: "v2.0.0", GitSHA: "def456"}
+	id.fillFromBuildInfo(info)
+	if id.Version != "v2.0.0" || id.GitSHA != "def456" {
+		t.Errorf("fillFromBuildInfo() = %+v, want the values set at build time kept", id)
+	}
+
+	id = Identity{}
+	id.fillFromBuildInfo(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}})
+	if id.Version != "" {
+		t.Errorf("Version = %q, want (devel) ignored", id.Version)
+	}
+}
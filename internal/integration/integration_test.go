@@ -26,9 +26,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,7 +46,10 @@ import (
 	"github.com/docker/go-connections/nat"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
 )
 
 var testPool *pgxpool.Pool
@@ -409,10 +416,485 @@ func TestFlagCRUD(t *testing.T) {
 	})
 }
 
+func TestFlagRevisions(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-revisions")
+
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "history", Enabled: true}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if _, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "history", Description: "off for now"}); err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if _, err := repo.RotateBucketingSalt(ctx, project.ID, "history"); err != nil {
+		t.Fatalf("RotateBucketingSalt: %v", err)
+	}
+
+	revisions, err := repo.ListFlagRevisions(ctx, project.ID, "history", 10)
+	if err != nil {
+		t.Fatalf("ListFlagRevisions: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Revision != 3 || revisions[2].Revision != 1 {
+		t.Fatalf("ListFlagRevisions = %+v, want revisions 3, 2, 1", revisions)
+	}
+	if revisions[0].Flag.BucketingSalt == revisions[1].Flag.BucketingSalt {
+		t.Error("revision 3 does not record the rotated salt")
+	}
+
+	first, err := repo.GetFlagRevision(ctx, project.ID, "history", 1)
+	if err != nil {
+		t.Fatalf("GetFlagRevision: %v", err)
+	}
+	if !first.Flag.Enabled || first.Flag.Description != "" || first.Flag.ProjectID != project.ID {
+		t.Fatalf("revision 1 = %+v, want the flag as created", first.Flag)
+	}
+
+	if err := repo.DeleteFlag(ctx, project.ID, "history"); err != nil {
+		t.Fatalf("DeleteFlag: %v", err)
+	}
+	if _, err := repo.GetFlagRevision(ctx, project.ID, "history", 1); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlagRevision after delete error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestFlagVariantsEncryption(t *testing.T) {
+	keys, err := repository.NewLocalKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	repo := repository.NewPostgresRepository(testPool, repository.WithVariantsEncryption(keys))
+	ctx := context.Background()
+	project := createTestProject(t, repo, "variants-encryption")
+	variants := json.RawMessage(`{"on": {"token": "s3cret"}, "off": {}}`)
+
+	created, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "secret", Enabled: true, Variants: variants})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if !strings.Contains(string(created.Variants), "s3cret") {
+		t.Fatalf("created variants = %s, want plaintext", created.Variants)
+	}
+
+	var stored, revision string
+	if err := testPool.QueryRow(ctx, `SELECT variants::text FROM flags WHERE project_id = $1 AND key = 'secret'`, project.ID).Scan(&stored); err != nil {
+		t.Fatalf("select variants: %v", err)
+	}
+	if err := testPool.QueryRow(ctx, `SELECT flag::text FROM flag_revisions WHERE project_id = $1 AND flag_key = 'secret'`, project.ID).Scan(&revision); err != nil {
+		t.Fatalf("select revision: %v", err)
+	}
+	if strings.Contains(stored, "s3cret") || !strings.Contains(stored, "$encrypted") || strings.Contains(revision, "s3cret") {
+		t.Fatalf("stored variants = %s, revision = %s, want both encrypted", stored, revision)
+	}
+
+	got, err := repo.GetFlag(ctx, project.ID, "secret")
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	rev, err := repo.GetFlagRevision(ctx, project.ID, "secret", 1)
+	if err != nil {
+		t.Fatalf("GetFlagRevision: %v", err)
+	}
+	if !strings.Contains(string(got.Variants), "s3cret") || !strings.Contains(string(rev.Flag.Variants), "s3cret") {
+		t.Fatalf("read variants = %s and %s, want plaintext", got.Variants, rev.Flag.Variants)
+	}
+
+	if _, err := newRepo().GetFlag(ctx, project.ID, "secret"); !errors.Is(err, repository.ErrVariantsEncrypted) {
+		t.Fatalf("GetFlag without keys error = %v, want ErrVariantsEncrypted", err)
+	}
+}
+
+func TestVariantsEncryptionCoversAuditLogAndProposals(t *testing.T) {
+	keys, err := repository.NewLocalKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	repo := repository.NewPostgresRepository(testPool, repository.WithVariantsEncryption(keys))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	project := createTestProject(t, repo, "variants-encryption-history")
+	svc, err := service.New(ctx, repo)
+	if err != nil {
+		t.Fatalf("service.New: %v", err)
+	}
+	keyCtx := middleware.NewContextWithAPIKeyID(ctx, "key-1")
+
+	flag := repository.Flag{ProjectID: project.ID, Key: "secret", Variants: json.RawMessage(`{"on": {"token": "s3cret-1"}}`)}
+	if _, err := svc.CreateFlag(keyCtx, flag); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	flag.Variants = json.RawMessage(`{"on": {"token": "s3cret-2"}}`)
+	if _, err := svc.UpdateFlag(keyCtx, flag); err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	flag.Variants = json.RawMessage(`{"on": {"token": "s3cret-3"}}`)
+	body, err := json.Marshal(flag)
+	if err != nil {
+		t.Fatalf("marshal proposed flag: %v", err)
+	}
+	proposal, err := svc.ProposeFlagChange(keyCtx, repository.FlagProposal{
+		ProjectID: project.ID,
+		FlagKey:   "secret",
+		Action:    repository.ProposalActionUpdate,
+		Flag:      body,
+	})
+	if err != nil {
+		t.Fatalf("ProposeFlagChange: %v", err)
+	}
+
+	for _, query := range []string{
+		`SELECT COALESCE(string_agg(details::text, ''), '') FROM audit_log WHERE project_id = $1`,
+		`SELECT COALESCE(string_agg(flag::text, ''), '') FROM flag_proposals WHERE project_id = $1`,
+		`SELECT COALESCE(string_agg(payload::text, ''), '') FROM flag_events WHERE project_id = $1`,
+	} {
+		var stored string
+		if err := testPool.QueryRow(ctx, query, project.ID).Scan(&stored); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if strings.Contains(stored, "s3cret") {
+			t.Errorf("%s = %s, want no plaintext variants", query, stored)
+		}
+	}
+
+	got, err := repo.GetFlagProposal(ctx, project.ID, proposal.ID)
+	if err != nil {
+		t.Fatalf("GetFlagProposal: %v", err)
+	}
+	listed, err := repo.ListFlagProposals(ctx, project.ID, "secret", "")
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("ListFlagProposals = %+v, %v, want one proposal", listed, err)
+	}
+	if !strings.Contains(string(got.Flag), "s3cret-3") || !strings.Contains(string(listed[0].Flag), "s3cret-3") {
+		t.Fatalf("read proposals = %s and %s, want plaintext variants", got.Flag, listed[0].Flag)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "backup")
+	keyID, _ := insertAPIKey(t, project.ID)
+
+	if _, err := repo.PutSegment(ctx, repository.Segment{ProjectID: project.ID, Name: "beta", Rules: []core.Rule{{Attribute: "plan", Operator: core.OperatorEquals, Value: "pro"}}}); err != nil {
+		t.Fatalf("PutSegment: %v", err)
+	}
+	created, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout", Enabled: true, Variants: json.RawMessage(`{"on":1}`), CreatedBy: "api_key:" + keyID})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if err := repo.InsertAuditLog(ctx, repository.AuditLogEntry{ProjectID: project.ID, APIKeyID: keyID, Action: "create_flag", FlagKey: "checkout"}); err != nil {
+		t.Fatalf("InsertAuditLog: %v", err)
+	}
+
+	// Keep only this test's project; the database is shared with other tests.
+	var records []repository.BackupRecord
+	if err := repo.Backup(ctx, func(record repository.BackupRecord) error {
+		if record.ProjectID == project.ID || (record.Project != nil && record.Project.ID == project.ID) {
+			records = append(records, record)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	kinds := make([]string, len(records))
+	for i, record := range records {
+		kinds[i] = record.Kind
+	}
+	want := []string{repository.BackupKindProject, repository.BackupKindAPIKey, repository.BackupKindSegment, repository.BackupKindFlag, repository.BackupKindAudit}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("backup kinds = %v, want %v", kinds, want)
+	}
+	next := func() func() (repository.BackupRecord, error) {
+		remaining := slices.Clone(records)
+		return func() (repository.BackupRecord, error) {
+			if len(remaining) == 0 {
+				return repository.BackupRecord{}, io.EOF
+			}
+			record := remaining[0]
+			remaining = remaining[1:]
+			return record, nil
+		}
+	}
+
+	if _, err := repo.Restore(ctx, repository.RestoreOptions{}, next()); !errors.Is(err, repository.ErrProjectNameTaken) {
+		t.Fatalf("Restore without renaming error = %v, want ErrProjectNameTaken", err)
+	}
+	result, err := repo.Restore(ctx, repository.RestoreOptions{RenameConflicts: true}, next())
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	restoredID, restoredKey := result.Projects[project.ID], result.APIKeys[keyID]
+	if restoredID == "" || restoredID == project.ID || restoredKey == "" || restoredKey == keyID {
+		t.Fatalf("result = %+v, want new project and key IDs", result)
+	}
+	if result.Segments != 1 || result.Flags != 1 || result.AuditEntries != 1 {
+		t.Fatalf("result = %+v, want one segment, flag and audit entry", result)
+	}
+
+	restored, err := repo.GetProject(ctx, restoredID)
+	if err != nil || restored.Name != project.Name+" (restored)" {
+		t.Fatalf("restored project = %+v, %v, want it renamed", restored, err)
+	}
+	flag, err := repo.GetFlag(ctx, restoredID, "checkout")
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	if flag.BucketingSalt != created.BucketingSalt || string(flag.Variants) != `{"on": 1}` || flag.CreatedBy != "api_key:"+restoredKey {
+		t.Fatalf("restored flag = %+v, want the original salt and variants, created by the restored key", flag)
+	}
+	var revoked bool
+	if err := testPool.QueryRow(ctx, `SELECT revoked_at IS NOT NULL FROM api_keys WHERE id = $1 AND project_id = $2`, restoredKey, restoredID).Scan(&revoked); err != nil || !revoked {
+		t.Fatalf("restored key revoked = %t, %v, want it revoked", revoked, err)
+	}
+	audit, err := repo.ListAuditLog(ctx, restoredID, 10, 0)
+	if err != nil || len(audit) != 1 || audit[0].APIKeyID != restoredKey {
+		t.Fatalf("restored audit log = %+v, %v, want the entry attributed to the restored key", audit, err)
+	}
+}
+
+func TestFlagTargets(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-targets")
+
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "beta", Enabled: true}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	set, err := repo.SetFlagTargets(ctx, project.ID, "beta", repository.FlagTargets{
+		Attribute: "user_id",
+		Allow:     []string{"alice", "bob"},
+	})
+	if err != nil {
+		t.Fatalf("SetFlagTargets: %v", err)
+	}
+	if set.Targets.Attribute != "user_id" || len(set.Targets.Allow) != 2 || len(set.Targets.Deny) != 0 {
+		t.Fatalf("SetFlagTargets = %+v, want two allowed users", set.Targets)
+	}
+
+	// Updates leave targets alone.
+	if _, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "beta", Description: "rules only"}); err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	got, err := repo.GetFlag(ctx, project.ID, "beta")
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	if got.Targets.Attribute != "user_id" || len(got.Targets.Allow) != 2 {
+		t.Fatalf("GetFlag targets = %+v, want them kept across UpdateFlag", got.Targets)
+	}
+
+	revisions, err := repo.ListFlagRevisions(ctx, project.ID, "beta", 10)
+	if err != nil {
+		t.Fatalf("ListFlagRevisions: %v", err)
+	}
+	if len(revisions) != 3 || len(revisions[1].Flag.Targets.Allow) != 2 {
+		t.Fatalf("ListFlagRevisions = %+v, want the targets change recorded as revision 2", revisions)
+	}
+
+	if _, err := repo.SetFlagTargets(ctx, project.ID, "missing", repository.FlagTargets{}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("SetFlagTargets(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Flag events
 // ---------------------------------------------------------------------------
 
+func TestFlagVariantsSchema(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-variants-schema")
+
+	created, err := repo.CreateFlag(ctx, repository.Flag{
+		ProjectID:      project.ID,
+		Key:            "theme",
+		Variants:       json.RawMessage(`{"red":{"color":"red"}}`),
+		VariantsSchema: json.RawMessage(`{"type":"object","required":["color"]}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if len(created.VariantsSchema) == 0 {
+		t.Fatal("CreateFlag did not return the variants schema")
+	}
+	got, err := repo.GetFlag(ctx, project.ID, "theme")
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(got.VariantsSchema, &schema); err != nil || schema["type"] != "object" {
+		t.Fatalf("GetFlag variants schema = %s, want the stored schema", got.VariantsSchema)
+	}
+
+	// The service resolves an omitted schema, so the repository stores what
+	// it is given: nil clears the column.
+	updated, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "theme"})
+	if err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if updated.VariantsSchema != nil {
+		t.Fatalf("UpdateFlag variants schema = %s, want none", updated.VariantsSchema)
+	}
+}
+
+func TestFlagShadowRules(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-shadow-rules")
+
+	shadow := json.RawMessage(`[{"attribute":"country","operator":"in","value":["FR","DE"]}]`)
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "eu-launch", ShadowRules: shadow}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	got, err := repo.GetFlag(ctx, project.ID, "eu-launch")
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	var rules []map[string]any
+	if err := json.Unmarshal(got.ShadowRules, &rules); err != nil || len(rules) != 1 || rules[0]["attribute"] != "country" {
+		t.Fatalf("GetFlag shadow rules = %s, want the stored rules", got.ShadowRules)
+	}
+
+	updated, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "eu-launch"})
+	if err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if updated.ShadowRules != nil {
+		t.Fatalf("UpdateFlag shadow rules = %s, want none", updated.ShadowRules)
+	}
+}
+
+func TestFlagActors(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-actors")
+
+	created, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout", CreatedBy: "api_key:key-1"})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if created.CreatedBy != "api_key:key-1" || created.UpdatedBy != "api_key:key-1" {
+		t.Fatalf("CreateFlag actors = %q, %q, want api_key:key-1 for both", created.CreatedBy, created.UpdatedBy)
+	}
+
+	updated, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout", UpdatedBy: "admin_user:user-1"})
+	if err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if updated.CreatedBy != "api_key:key-1" || updated.UpdatedBy != "admin_user:user-1" {
+		t.Fatalf("UpdateFlag actors = %q, %q, want api_key:key-1 and admin_user:user-1", updated.CreatedBy, updated.UpdatedBy)
+	}
+
+	event, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: project.ID, FlagKey: "checkout", EventType: "updated", Actor: "admin_user:user-1"})
+	if err != nil {
+		t.Fatalf("PublishFlagEvent: %v", err)
+	}
+	if event.Actor != "admin_user:user-1" {
+		t.Fatalf("PublishFlagEvent actor = %q, want admin_user:user-1", event.Actor)
+	}
+}
+
+func TestFlagTags(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-tags")
+
+	created, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "untagged"})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if len(created.Tags) != 0 {
+		t.Fatalf("CreateFlag tags = %v, want none", created.Tags)
+	}
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout", Tags: []string{"checkout", "team:payments"}}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	flags, err := repo.ListFlagsByProject(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListFlagsByProject: %v", err)
+	}
+	if len(flags) != 2 || !slices.Equal(flags[0].Tags, []string{"checkout", "team:payments"}) {
+		t.Fatalf("ListFlagsByProject = %+v, want checkout's tags", flags)
+	}
+
+	page, err := repo.ListFlagsPage(ctx, repository.FlagPageQuery{ProjectID: project.ID, Tags: []string{"team:payments"}, Limit: 10})
+	if err != nil || len(page) != 1 || page[0].Key != "checkout" {
+		t.Fatalf("ListFlagsPage(team:payments) = %+v, %v, want checkout", page, err)
+	}
+	page, err = repo.ListFlagsPage(ctx, repository.FlagPageQuery{ProjectID: project.ID, Limit: 1})
+	if err != nil || len(page) != 1 || page[0].Key != "checkout" {
+		t.Fatalf("ListFlagsPage(limit 1) = %+v, %v, want checkout", page, err)
+	}
+	page, err = repo.ListFlagsPage(ctx, repository.FlagPageQuery{ProjectID: project.ID, AfterKey: "checkout", Limit: 10})
+	if err != nil || len(page) != 1 || page[0].Key != "untagged" {
+		t.Fatalf("ListFlagsPage(after checkout) = %+v, %v, want untagged", page, err)
+	}
+
+	// Nil tags keep the stored ones; an empty slice clears them.
+	updated, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout"})
+	if err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if len(updated.Tags) != 2 {
+		t.Fatalf("UpdateFlag(nil tags) tags = %v, want them kept", updated.Tags)
+	}
+	updated, err = repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "checkout", Tags: []string{}})
+	if err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if len(updated.Tags) != 0 {
+		t.Fatalf("UpdateFlag(no tags) tags = %v, want none", updated.Tags)
+	}
+}
+
+func TestClaimExpiredFlags(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "flag-expiry")
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	expiring, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "spring-sale", Owner: "growth@example.com", ExpiresAt: &past})
+	if err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "summer-sale", ExpiresAt: &future}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	// Other tests share the database, so only this project's claims count.
+	claim := func() []string {
+		t.Helper()
+		claimed, err := repo.ClaimExpiredFlags(ctx, now)
+		if err != nil {
+			t.Fatalf("ClaimExpiredFlags: %v", err)
+		}
+		var keys []string
+		for _, flag := range claimed {
+			if flag.ProjectID == project.ID {
+				keys = append(keys, flag.Key)
+			}
+		}
+		return keys
+	}
+
+	if got := claim(); !slices.Equal(got, []string{"spring-sale"}) {
+		t.Fatalf("first claim = %v, want [spring-sale]", got)
+	}
+	if got := claim(); len(got) != 0 {
+		t.Fatalf("second claim = %v, want none", got)
+	}
+
+	// Moving the expiry re-arms the reminder.
+	extended := past.Add(time.Minute)
+	expiring.ExpiresAt = &extended
+	if _, err := repo.UpdateFlag(ctx, expiring); err != nil {
+		t.Fatalf("UpdateFlag: %v", err)
+	}
+	if got := claim(); !slices.Equal(got, []string{"spring-sale"}) {
+		t.Fatalf("claim after moving the expiry = %v, want [spring-sale]", got)
+	}
+}
+
 func TestFlagEvents(t *testing.T) {
 	repo := newRepo()
 	ctx := context.Background()
@@ -455,6 +937,43 @@ func TestFlagEvents(t *testing.T) {
 		}
 	})
 
+	t.Run("latest event ID", func(t *testing.T) {
+		project := createTestProject(t, repo, "events-latest")
+
+		if latest, err := repo.LatestEventID(ctx, project.ID); err != nil || latest != 0 {
+			t.Fatalf("LatestEventID(empty) = %d, %v, want 0", latest, err)
+		}
+		published, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+			ProjectID: project.ID,
+			FlagKey:   "flag-a",
+			EventType: "updated",
+			Payload:   json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("PublishFlagEvent: %v", err)
+		}
+		if latest, err := repo.LatestEventID(ctx, project.ID); err != nil || latest != published.EventID {
+			t.Fatalf("LatestEventID = %d, %v, want %d", latest, err, published.EventID)
+		}
+	})
+
+	t.Run("max event ID", func(t *testing.T) {
+		project := createTestProject(t, repo, "events-max")
+
+		published, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+			ProjectID: project.ID,
+			FlagKey:   "flag-a",
+			EventType: "updated",
+			Payload:   json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("PublishFlagEvent: %v", err)
+		}
+		if max, err := repo.MaxEventID(ctx); err != nil || max < published.EventID {
+			t.Fatalf("MaxEventID = %d, %v, want >= %d", max, err, published.EventID)
+		}
+	})
+
 	t.Run("list events since filters by event ID", func(t *testing.T) {
 		project := createTestProject(t, repo, "events-filter")
 
@@ -524,36 +1043,121 @@ func TestFlagEvents(t *testing.T) {
 			t.Errorf("EventID = %d, want %d", events[0].EventID, keyBEvent.EventID)
 		}
 	})
-}
 
-// ---------------------------------------------------------------------------
-// API key validation
-// ---------------------------------------------------------------------------
+	t.Run("list events since matching", func(t *testing.T) {
+		project := createTestProject(t, repo, "events-matching")
 
-func TestAPIKeyValidation(t *testing.T) {
-	repo := newRepo()
-	ctx := context.Background()
+		var published []repository.FlagEvent
+		for _, event := range []struct{ key, eventType string }{
+			{"key-a", "updated"}, {"key-b", "updated"}, {"key-c", "updated"}, {"key-b", "deleted"},
+		} {
+			created, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+				ProjectID: project.ID,
+				FlagKey:   event.key,
+				EventType: event.eventType,
+				Payload:   json.RawMessage(`{}`),
+			})
+			if err != nil {
+				t.Fatalf("PublishFlagEvent %s: %v", event.key, err)
+			}
+			published = append(published, created)
+		}
 
-	t.Run("validate correct secret", func(t *testing.T) {
-		project := createTestProject(t, repo, "apikey-valid")
-		keyID, rawSecret := insertAPIKey(t, project.ID)
+		events, err := repo.ListEventsSinceMatching(ctx, project.ID, 0, repository.EventFilter{Keys: []string{"key-a", "key-b"}})
+		if err != nil {
+			t.Fatalf("ListEventsSinceMatching keys: %v", err)
+		}
+		if len(events) != 3 || events[0].EventID != published[0].EventID || events[2].EventID != published[3].EventID {
+			t.Fatalf("ListEventsSinceMatching keys = %+v, want the key-a and key-b events", events)
+		}
 
-		keyHash, projectID, err := repo.ValidateAPIKey(ctx, keyID)
+		events, err = repo.ListEventsSinceMatching(ctx, project.ID, 0, repository.EventFilter{Keys: []string{"key-b"}, Types: []string{"deleted"}})
 		if err != nil {
-			t.Fatalf("ValidateAPIKey: %v", err)
+			t.Fatalf("ListEventsSinceMatching keys and types: %v", err)
 		}
-		if projectID != project.ID {
-			t.Errorf("projectID = %q, want %q", projectID, project.ID)
+		if len(events) != 1 || events[0].EventID != published[3].EventID {
+			t.Fatalf("ListEventsSinceMatching keys and types = %+v, want the key-b delete", events)
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(keyHash), []byte(rawSecret)); err != nil {
-			t.Errorf("bcrypt hash mismatch: %v", err)
+		events, err = repo.ListEventsSinceMatching(ctx, project.ID, 0, repository.EventFilter{})
+		if err != nil || len(events) != 4 {
+			t.Fatalf("ListEventsSinceMatching empty filter = %+v, %v, want all 4 events", events, err)
 		}
 	})
 
-	t.Run("validate nonexistent key returns error", func(t *testing.T) {
-		_, _, err := repo.ValidateAPIKey(ctx, "nonexistent-key-id")
-		if err == nil {
+	t.Run("query events", func(t *testing.T) {
+		project := createTestProject(t, repo, "events-query")
+
+		var published []repository.FlagEvent
+		for _, event := range []struct{ key, actor string }{
+			{"key-a", "api_key:k1"}, {"key-b", "api_key:k1"}, {"key-a", "admin_user:u1"},
+		} {
+			created, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+				ProjectID: project.ID,
+				FlagKey:   event.key,
+				EventType: "updated",
+				Payload:   json.RawMessage(`{}`),
+				Actor:     event.actor,
+			})
+			if err != nil {
+				t.Fatalf("PublishFlagEvent %s: %v", event.key, err)
+			}
+			published = append(published, created)
+		}
+
+		events, err := repo.QueryEvents(ctx, repository.EventQuery{ProjectID: project.ID, Keys: []string{"key-a"}, Actor: "admin_user:u1", Limit: 10})
+		if err != nil {
+			t.Fatalf("QueryEvents key and actor: %v", err)
+		}
+		if len(events) != 1 || events[0].EventID != published[2].EventID || events[0].Actor != "admin_user:u1" {
+			t.Fatalf("QueryEvents key and actor = %+v, want the admin's key-a event", events)
+		}
+
+		events, err = repo.QueryEvents(ctx, repository.EventQuery{ProjectID: project.ID, AfterID: published[0].EventID, Limit: 1})
+		if err != nil || len(events) != 1 || events[0].EventID != published[1].EventID {
+			t.Fatalf("QueryEvents after the first = %+v, %v, want the second event", events, err)
+		}
+
+		now := time.Now()
+		events, err = repo.QueryEvents(ctx, repository.EventQuery{ProjectID: project.ID, Since: now.Add(-time.Hour), Until: now.Add(time.Hour), Limit: 10})
+		if err != nil || len(events) != 3 {
+			t.Fatalf("QueryEvents last hour = %+v, %v, want all 3 events", events, err)
+		}
+		events, err = repo.QueryEvents(ctx, repository.EventQuery{ProjectID: project.ID, Until: now.Add(-time.Hour), Limit: 10})
+		if err != nil || len(events) != 0 {
+			t.Fatalf("QueryEvents before the last hour = %+v, %v, want none", events, err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// API key validation
+// ---------------------------------------------------------------------------
+
+func TestAPIKeyValidation(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	t.Run("validate correct secret", func(t *testing.T) {
+		project := createTestProject(t, repo, "apikey-valid")
+		keyID, rawSecret := insertAPIKey(t, project.ID)
+
+		keyHash, projectID, err := repo.ValidateAPIKey(ctx, keyID)
+		if err != nil {
+			t.Fatalf("ValidateAPIKey: %v", err)
+		}
+		if projectID != project.ID {
+			t.Errorf("projectID = %q, want %q", projectID, project.ID)
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(keyHash), []byte(rawSecret)); err != nil {
+			t.Errorf("bcrypt hash mismatch: %v", err)
+		}
+	})
+
+	t.Run("validate nonexistent key returns error", func(t *testing.T) {
+		_, _, err := repo.ValidateAPIKey(ctx, "nonexistent-key-id")
+		if err == nil {
 			t.Fatal("expected error for nonexistent key, got nil")
 		}
 	})
@@ -571,10 +1175,991 @@ func TestAPIKeyValidation(t *testing.T) {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// API key rotation
+// ---------------------------------------------------------------------------
+
+func TestAPIKeyRotation(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "apikey-rotation")
+	freshID, _ := insertAPIKey(t, project.ID)
+	overdueID, _ := insertAPIKey(t, project.ID)
+	expiredID, _ := insertAPIKey(t, project.ID)
+	for id, age := range map[string]string{overdueID: "2 days", expiredID: "10 days"} {
+		if _, err := testPool.Exec(ctx, `UPDATE api_keys SET created_at = NOW() - $2::interval WHERE id = $1`, id, age); err != nil {
+			t.Fatalf("backdate api key: %v", err)
+		}
+	}
+
+	policy := repository.KeyRotationPolicy{MaxAge: 24 * time.Hour, RevokeAfter: 3 * 24 * time.Hour}
+	if _, err := repo.SetKeyRotationPolicy(ctx, project.ID, policy); err != nil {
+		t.Fatalf("SetKeyRotationPolicy: %v", err)
+	}
+	if got, err := repo.GetKeyRotationPolicy(ctx, project.ID); err != nil || got != policy {
+		t.Fatalf("GetKeyRotationPolicy = %+v, %v, want %+v", got, err, policy)
+	}
+
+	cred, err := repo.LookupAPIKey(ctx, overdueID)
+	if err != nil {
+		t.Fatalf("LookupAPIKey: %v", err)
+	}
+	if cred.ProjectID != project.ID || cred.Policy != policy {
+		t.Errorf("LookupAPIKey = %+v, want project %s with policy %+v", cred, project.ID, policy)
+	}
+
+	inProject := func(keys []repository.APIKeyMeta) []string {
+		var ids []string
+		for _, key := range keys {
+			if key.ProjectID == project.ID {
+				ids = append(ids, key.ID)
+			}
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	now := time.Now()
+	marked, err := repo.MarkOverdueAPIKeys(ctx, now)
+	if err != nil {
+		t.Fatalf("MarkOverdueAPIKeys: %v", err)
+	}
+	if got, want := inProject(marked), slices.Sorted(slices.Values([]string{overdueID, expiredID})); !slices.Equal(got, want) {
+		t.Fatalf("marked = %v, want %v", got, want)
+	}
+	if marked, err = repo.MarkOverdueAPIKeys(ctx, now); err != nil || len(inProject(marked)) != 0 {
+		t.Fatalf("second MarkOverdueAPIKeys = %v, %v, want none", inProject(marked), err)
+	}
+
+	revoked, err := repo.RevokeOverdueAPIKeys(ctx, now)
+	if err != nil {
+		t.Fatalf("RevokeOverdueAPIKeys: %v", err)
+	}
+	if got := inProject(revoked); !slices.Equal(got, []string{expiredID}) {
+		t.Fatalf("revoked = %v, want [%s]", got, expiredID)
+	}
+
+	if err := repo.TouchAPIKey(ctx, freshID, now); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	overdue := map[string]bool{}
+	for _, key := range keys {
+		overdue[key.ID] = key.Overdue
+		if key.RotateBy == nil || key.RevokeAt == nil {
+			t.Errorf("key %s RotateBy/RevokeAt not set", key.ID)
+		}
+		if used := key.LastUsedAt != nil; used != (key.ID == freshID) {
+			t.Errorf("key %s LastUsedAt = %v", key.ID, key.LastUsedAt)
+		}
+	}
+	if want := map[string]bool{freshID: false, overdueID: true}; !maps.Equal(overdue, want) {
+		t.Fatalf("listed keys overdue = %v, want %v", overdue, want)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Admin-scoped keys and the all-projects stream
+// ---------------------------------------------------------------------------
+
+func TestAdminStream(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	first := createTestProject(t, repo, "admin-stream-a")
+	second := createTestProject(t, repo, "admin-stream-b")
+
+	adminID, _, err := repo.CreateAdminAPIKeyForProject(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("CreateAdminAPIKeyForProject: %v", err)
+	}
+	projectKeyID, _ := insertAPIKey(t, first.ID)
+
+	if scope, err := repo.GetAPIKeyScope(ctx, adminID); err != nil || scope != repository.APIKeyScopeAdmin {
+		t.Fatalf("GetAPIKeyScope(admin) = %q, %v, want %q", scope, err, repository.APIKeyScopeAdmin)
+	}
+	if scope, err := repo.GetAPIKeyScope(ctx, projectKeyID); err != nil || scope != repository.APIKeyScopeProject {
+		t.Fatalf("GetAPIKeyScope(project) = %q, %v, want %q", scope, err, repository.APIKeyScopeProject)
+	}
+	revokeAPIKey(t, adminID)
+	if _, err := repo.GetAPIKeyScope(ctx, adminID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetAPIKeyScope(revoked) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	var start int64
+	for _, project := range []repository.Project{first, second} {
+		event, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+			ProjectID: project.ID,
+			FlagKey:   "shared-key",
+			EventType: "updated",
+			Payload:   json.RawMessage(`{"key":"shared-key"}`),
+		})
+		if err != nil {
+			t.Fatalf("PublishFlagEvent: %v", err)
+		}
+		if start == 0 {
+			start = event.EventID - 1
+		}
+	}
+
+	events, err := repo.ListAllEventsSince(ctx, start)
+	if err != nil {
+		t.Fatalf("ListAllEventsSince: %v", err)
+	}
+	var projects []string
+	for _, event := range events {
+		if event.ProjectID == first.ID || event.ProjectID == second.ID {
+			projects = append(projects, event.ProjectID)
+		}
+	}
+	if want := []string{first.ID, second.ID}; !slices.Equal(projects, want) {
+		t.Fatalf("ListAllEventsSince projects = %v, want %v", projects, want)
+	}
+}
+
+func TestExportRanges(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "export-ranges")
+	before := time.Now().Add(-time.Minute)
+
+	for _, key := range []string{"export-a", "export-b", "export-c"} {
+		if err := repo.InsertAuditLog(ctx, repository.AuditLogEntry{ProjectID: project.ID, Action: "create_flag", FlagKey: key}); err != nil {
+			t.Fatalf("InsertAuditLog: %v", err)
+		}
+		if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{
+			ProjectID: project.ID,
+			FlagKey:   key,
+			EventType: "updated",
+			Payload:   json.RawMessage(`{"key":"` + key + `"}`),
+		}); err != nil {
+			t.Fatalf("PublishFlagEvent: %v", err)
+		}
+	}
+	after := time.Now().Add(time.Minute)
+
+	// Page through with a limit of 2, as an export would.
+	var keys []string
+	var afterID int64
+	for {
+		entries, err := repo.ListAuditLogRange(ctx, afterID, before, after, 2)
+		if err != nil {
+			t.Fatalf("ListAuditLogRange: %v", err)
+		}
+		for _, e := range entries {
+			if e.ProjectID == project.ID {
+				keys = append(keys, e.FlagKey)
+			}
+			afterID = e.ID
+		}
+		if len(entries) < 2 {
+			break
+		}
+	}
+	if want := []string{"export-a", "export-b", "export-c"}; !slices.Equal(keys, want) {
+		t.Fatalf("ListAuditLogRange keys = %v, want %v", keys, want)
+	}
+
+	events, err := repo.ListEventsRange(ctx, 0, before, after, 1000)
+	if err != nil {
+		t.Fatalf("ListEventsRange: %v", err)
+	}
+	keys = keys[:0]
+	for _, event := range events {
+		if event.ProjectID == project.ID {
+			keys = append(keys, event.FlagKey)
+		}
+	}
+	if want := []string{"export-a", "export-b", "export-c"}; !slices.Equal(keys, want) {
+		t.Fatalf("ListEventsRange keys = %v, want %v", keys, want)
+	}
+
+	if events, err := repo.ListEventsRange(ctx, 0, after, after.Add(time.Hour), 1000); err != nil || len(events) != 0 {
+		t.Fatalf("ListEventsRange(future) = %d events, %v, want none", len(events), err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Context presets
+// ---------------------------------------------------------------------------
+
+func TestContextPresets(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "context-presets")
+
+	put := repository.ContextPreset{
+		ProjectID: project.ID,
+		Name:      "EU free-tier user",
+		Context:   core.EvaluationContext{Attributes: map[string]any{"country": "FR", "plan": "free"}},
+	}
+	if _, err := repo.PutContextPreset(ctx, put); err != nil {
+		t.Fatalf("PutContextPreset: %v", err)
+	}
+	put.Description = "updated"
+	put.Context.Attributes["plan"] = "pro"
+	replaced, err := repo.PutContextPreset(ctx, put)
+	if err != nil {
+		t.Fatalf("PutContextPreset (replace): %v", err)
+	}
+	if replaced.Description != "updated" || replaced.Context.Attributes["plan"] != "pro" {
+		t.Fatalf("replaced preset = %+v", replaced)
+	}
+
+	got, err := repo.GetContextPreset(ctx, project.ID, "EU free-tier user")
+	if err != nil {
+		t.Fatalf("GetContextPreset: %v", err)
+	}
+	if got.Context.Attributes["country"] != "FR" {
+		t.Fatalf("GetContextPreset attributes = %v", got.Context.Attributes)
+	}
+	presets, err := repo.ListContextPresets(ctx, project.ID)
+	if err != nil || len(presets) != 1 {
+		t.Fatalf("ListContextPresets = %+v, %v, want one preset", presets, err)
+	}
+
+	if err := repo.DeleteContextPreset(ctx, project.ID, "EU free-tier user"); err != nil {
+		t.Fatalf("DeleteContextPreset: %v", err)
+	}
+	if _, err := repo.GetContextPreset(ctx, project.ID, "EU free-tier user"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetContextPreset after delete error = %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.DeleteContextPreset(ctx, project.ID, "EU free-tier user"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("second DeleteContextPreset error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestUpdateFlagFunc(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "patch")
+
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "patched", Description: "kept"}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	before, after, err := repo.UpdateFlagFunc(ctx, project.ID, "patched", func(flag repository.Flag) (repository.Flag, error) {
+		flag.Enabled = true
+		return flag, nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateFlagFunc: %v", err)
+	}
+	if before.Enabled || !after.Enabled || after.Description != "kept" {
+		t.Fatalf("UpdateFlagFunc = %+v, %+v, want only enabled changed", before, after)
+	}
+
+	errRejected := errors.New("rejected")
+	if _, _, err := repo.UpdateFlagFunc(ctx, project.ID, "patched", func(flag repository.Flag) (repository.Flag, error) {
+		return repository.Flag{}, errRejected
+	}); !errors.Is(err, errRejected) {
+		t.Fatalf("UpdateFlagFunc (rejected) error = %v, want errRejected", err)
+	}
+	if _, _, err := repo.UpdateFlagFunc(ctx, project.ID, "missing", func(flag repository.Flag) (repository.Flag, error) {
+		return flag, nil
+	}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("UpdateFlagFunc (missing) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	revisions, err := repo.ListFlagRevisions(ctx, project.ID, "patched", 10)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("ListFlagRevisions = %d revisions, %v, want 2", len(revisions), err)
+	}
+}
+
+func TestFlagArchive(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "archive")
+
+	for _, key := range []string{"kept", "archived"} {
+		if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: key}); err != nil {
+			t.Fatalf("CreateFlag(%s): %v", key, err)
+		}
+	}
+	if _, err := repo.BulkUpdateFlags(ctx, project.ID, nil, []string{"archived"}); err != nil {
+		t.Fatalf("BulkUpdateFlags (archive): %v", err)
+	}
+	if _, err := repo.BulkUpdateFlags(ctx, project.ID, nil, []string{"archived"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("BulkUpdateFlags (archive twice) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	if _, err := repo.GetFlag(ctx, project.ID, "archived"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlag (archived) error = %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := repo.UpdateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "archived", Enabled: true}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("UpdateFlag (archived) error = %v, want pgx.ErrNoRows", err)
+	}
+	flags, err := repo.ListFlagsByProject(ctx, project.ID)
+	if err != nil || len(flags) != 1 || flags[0].Key != "kept" {
+		t.Fatalf("ListFlagsByProject = %+v, %v, want only kept", flags, err)
+	}
+	archived, err := repo.ListArchivedFlags(ctx, project.ID)
+	if err != nil || len(archived) != 1 || archived[0].Key != "archived" || archived[0].ArchivedAt == nil {
+		t.Fatalf("ListArchivedFlags = %+v, %v, want archived", archived, err)
+	}
+
+	restored, err := repo.RestoreFlags(ctx, project.ID, []string{"archived"})
+	if err != nil || len(restored) != 1 || restored[0].Key != "archived" {
+		t.Fatalf("RestoreFlags = %+v, %v, want archived", restored, err)
+	}
+	if _, err := repo.GetFlag(ctx, project.ID, "archived"); err != nil {
+		t.Fatalf("GetFlag (restored): %v", err)
+	}
+	if _, err := repo.RestoreFlags(ctx, project.ID, []string{"kept"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("RestoreFlags (live flag) error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "segments")
+
+	put := repository.Segment{
+		ProjectID: project.ID,
+		Name:      "beta-testers",
+		Rules:     []core.Rule{{Attribute: "plan", Operator: core.OperatorEquals, Value: "pro"}},
+	}
+	if _, err := repo.PutSegment(ctx, put); err != nil {
+		t.Fatalf("PutSegment: %v", err)
+	}
+	put.Description = "updated"
+	put.Rules = append(put.Rules, core.Rule{Operator: core.OperatorSegment, Value: "staff"})
+	replaced, err := repo.PutSegment(ctx, put)
+	if err != nil {
+		t.Fatalf("PutSegment (replace): %v", err)
+	}
+	if replaced.Description != "updated" || len(replaced.Rules) != 2 || replaced.Rules[1].Value != "staff" {
+		t.Fatalf("replaced segment = %+v", replaced)
+	}
+
+	segments, err := repo.ListAllSegments(ctx)
+	if err != nil {
+		t.Fatalf("ListAllSegments: %v", err)
+	}
+	found := false
+	for _, segment := range segments {
+		found = found || (segment.ProjectID == project.ID && segment.Name == "beta-testers")
+	}
+	if !found {
+		t.Fatalf("ListAllSegments = %+v, want beta-testers", segments)
+	}
+
+	if err := repo.DeleteSegment(ctx, project.ID, "beta-testers"); err != nil {
+		t.Fatalf("DeleteSegment: %v", err)
+	}
+	if err := repo.DeleteSegment(ctx, project.ID, "beta-testers"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("second DeleteSegment error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Project soft-delete
+// ---------------------------------------------------------------------------
+
+func TestProjectSoftDelete(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	project := createTestProject(t, repo, "soft-delete")
+	keyID, _ := insertAPIKey(t, project.ID)
+	dashboardToken, _, err := repo.CreateDashboardToken(ctx, project.ID, "", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateDashboardToken: %v", err)
+	}
+	if _, err := repo.CreateFlag(ctx, repository.Flag{Key: "checkout", ProjectID: project.ID, Enabled: true}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+
+	if err := repo.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if err := repo.DeleteProject(ctx, project.ID); err == nil {
+		t.Fatal("second DeleteProject should fail")
+	}
+
+	if _, _, err := repo.ValidateAPIKey(ctx, keyID); err == nil {
+		t.Fatal("API key should be revoked by project deletion")
+	}
+	if _, err := repo.LookupDashboardToken(ctx, dashboardToken.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("LookupDashboardToken error = %v, want pgx.ErrNoRows after project deletion", err)
+	}
+	if _, err := repo.GetFlag(ctx, project.ID, "checkout"); err == nil {
+		t.Fatal("GetFlag should not return flags of a deleted project")
+	}
+	flags, err := repo.ListFlags(ctx)
+	if err != nil {
+		t.Fatalf("ListFlags: %v", err)
+	}
+	for _, flag := range flags {
+		if flag.ProjectID == project.ID {
+			t.Fatalf("ListFlags returned flag %q of a deleted project", flag.Key)
+		}
+	}
+	got, err := repo.GetProject(ctx, project.ID)
+	if err != nil || got.DeletedAt == nil {
+		t.Fatalf("GetProject = %+v, %v, want DeletedAt set", got, err)
+	}
+
+	if err := repo.RestoreProject(ctx, project.ID, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("RestoreProject should fail outside the retention window")
+	}
+	if err := repo.RestoreProject(ctx, project.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("RestoreProject: %v", err)
+	}
+	if _, err := repo.GetFlag(ctx, project.ID, "checkout"); err != nil {
+		t.Fatalf("GetFlag after restore: %v", err)
+	}
+	if _, _, err := repo.ValidateAPIKey(ctx, keyID); err == nil {
+		t.Fatal("API key should stay revoked after restore")
+	}
+	if _, err := repo.LookupDashboardToken(ctx, dashboardToken.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("LookupDashboardToken error = %v, want the token gone after restore", err)
+	}
+
+	if err := repo.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("DeleteProject again: %v", err)
+	}
+	if _, err := repo.PurgeDeletedProjects(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeDeletedProjects: %v", err)
+	}
+	if _, err := repo.GetProject(ctx, project.ID); err == nil {
+		t.Fatal("GetProject should fail after purge")
+	}
+}
+
+func TestProjectOverviewsAndActivity(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	project := createTestProject(t, repo, "overview")
+	keyID, _ := insertAPIKey(t, project.ID)
+	insertAPIKey(t, project.ID)
+	revokeAPIKey(t, keyID)
+	for _, flag := range []repository.Flag{
+		{Key: "checkout", ProjectID: project.ID, Enabled: true},
+		{Key: "search", ProjectID: project.ID},
+	} {
+		if _, err := repo.CreateFlag(ctx, flag); err != nil {
+			t.Fatalf("CreateFlag(%s): %v", flag.Key, err)
+		}
+	}
+	if _, err := testPool.Exec(ctx, `UPDATE flags SET updated_at = NOW() - INTERVAL '30 days' WHERE project_id = $1 AND key = 'search'`, project.ID); err != nil {
+		t.Fatalf("age flag: %v", err)
+	}
+	if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: project.ID, FlagKey: "checkout", EventType: "updated", Payload: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("PublishFlagEvent: %v", err)
+	}
+	for _, action := range []string{"create_flag", "update_flag"} {
+		if err := repo.InsertAuditLog(ctx, repository.AuditLogEntry{ProjectID: project.ID, Action: action, FlagKey: "checkout"}); err != nil {
+			t.Fatalf("InsertAuditLog: %v", err)
+		}
+	}
+
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	overviews, err := repo.ListProjectOverviews(ctx, since)
+	if err != nil {
+		t.Fatalf("ListProjectOverviews: %v", err)
+	}
+	var got *repository.ProjectOverview
+	for i := range overviews {
+		if overviews[i].ID == project.ID {
+			got = &overviews[i]
+		}
+	}
+	if got == nil {
+		t.Fatal("ListProjectOverviews did not include the project")
+	}
+	if got.Flags != 2 || got.EnabledFlags != 1 || got.RecentlyChangedFlags != 1 || got.ActiveAPIKeys != 1 || got.RecentAuditActions != 2 {
+		t.Fatalf("overview = %+v, want 2 flags, 1 enabled, 1 changed, 1 active key, 2 audit actions", got)
+	}
+	if got.LastAuditAction != "update_flag" || got.LastAuditAt == nil {
+		t.Fatalf("last audit action = %q at %v, want update_flag", got.LastAuditAction, got.LastAuditAt)
+	}
+
+	activity, err := repo.ListProjectActivity(ctx, project.ID, since)
+	if err != nil {
+		t.Fatalf("ListProjectActivity: %v", err)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if len(activity) != 1 || !activity[0].Day.Equal(today) || activity[0].FlagEvents != 1 || activity[0].AuditActions != 2 {
+		t.Fatalf("ListProjectActivity = %+v, want today with 1 event and 2 audit actions", activity)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Admin sessions
+// ---------------------------------------------------------------------------
+
+func TestAdminSessions(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	user, err := repo.CreateAdminUser(ctx, "sessions-"+randID(), "hash", "viewer")
+	if err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	now := time.Now()
+	newSession := func(idHash string) repository.AdminSession {
+		return repository.AdminSession{
+			IDHash:      idHash,
+			AdminUserID: user.ID,
+			CSRFToken:   "csrf-" + idHash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(time.Hour),
+			Role:        user.Role,
+			UserAgent:   "test agent",
+			RemoteAddr:  "100.64.0.1",
+		}
+	}
+	first, second := newSession("first-"+randID()), newSession("second-"+randID())
+	for _, s := range []repository.AdminSession{first, second} {
+		if err := repo.CreateAdminSession(ctx, s); err != nil {
+			t.Fatalf("CreateAdminSession: %v", err)
+		}
+	}
+
+	got, err := repo.GetAdminSession(ctx, first.IDHash)
+	if err != nil {
+		t.Fatalf("GetAdminSession: %v", err)
+	}
+	if got.ID == "" || got.Role != "viewer" || got.UserRole != "viewer" || got.UserAgent != "test agent" {
+		t.Fatalf("GetAdminSession = %+v", got)
+	}
+
+	// Promoting the user shows up as a role mismatch on the session.
+	if _, err := testPool.Exec(ctx, `UPDATE admin_users SET role = 'admin' WHERE id = $1`, user.ID); err != nil {
+		t.Fatalf("promote user: %v", err)
+	}
+	if got, err = repo.GetAdminSession(ctx, first.IDHash); err != nil || got.UserRole != "admin" {
+		t.Fatalf("GetAdminSession after promotion = %+v, %v", got, err)
+	}
+	rotated := newSession("rotated-" + randID())
+	rotated.Role = "admin"
+	if err := repo.RotateAdminSession(ctx, first.IDHash, rotated); err != nil {
+		t.Fatalf("RotateAdminSession: %v", err)
+	}
+	if _, err := repo.GetAdminSession(ctx, first.IDHash); err == nil {
+		t.Fatal("old session should not survive rotation")
+	}
+	if err := repo.RotateAdminSession(ctx, first.IDHash, newSession("again-"+randID())); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("RotateAdminSession of a removed session error = %v, want pgx.ErrNoRows", err)
+	}
+	if got, err = repo.GetAdminSession(ctx, rotated.IDHash); err != nil || got.Role != "admin" {
+		t.Fatalf("GetAdminSession(rotated) = %+v, %v", got, err)
+	}
+
+	sessions, err := repo.ListAdminSessions(ctx, user.ID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListAdminSessions = %d sessions, %v, want 2", len(sessions), err)
+	}
+	secondID := ""
+	for _, s := range sessions {
+		if s.IDHash == second.IDHash {
+			secondID = s.ID
+		}
+	}
+	if err := repo.DeleteAdminSessionByID(ctx, "00000000-0000-0000-0000-000000000000", secondID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("DeleteAdminSessionByID for another user error = %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.DeleteAdminSessionByID(ctx, user.ID, secondID); err != nil {
+		t.Fatalf("DeleteAdminSessionByID: %v", err)
+	}
+
+	third := newSession("third-" + randID())
+	if err := repo.CreateAdminSession(ctx, third); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+	if n, err := repo.DeleteOtherAdminSessions(ctx, user.ID, rotated.IDHash); err != nil || n != 1 {
+		t.Fatalf("DeleteOtherAdminSessions = %d, %v, want 1", n, err)
+	}
+
+	// A session unused for longer than the idle timeout is cleaned up.
+	if _, err := testPool.Exec(ctx, `UPDATE admin_sessions SET last_seen_at = NOW() - INTERVAL '2 hours' WHERE id_hash = $1`, rotated.IDHash); err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+	if err := repo.DeleteExpiredAdminSessions(ctx, 0); err != nil {
+		t.Fatalf("DeleteExpiredAdminSessions: %v", err)
+	}
+	if _, err := repo.GetAdminSession(ctx, rotated.IDHash); err != nil {
+		t.Fatal("session should survive cleanup without an idle timeout")
+	}
+	if err := repo.DeleteExpiredAdminSessions(ctx, time.Hour); err != nil {
+		t.Fatalf("DeleteExpiredAdminSessions: %v", err)
+	}
+	if _, err := repo.GetAdminSession(ctx, rotated.IDHash); err == nil {
+		t.Fatal("idle session should be removed by cleanup")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Flag stats
+// ---------------------------------------------------------------------------
+
+func TestAdminUserManagement(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	user, err := repo.CreateAdminUser(ctx, "managed-"+randID(), "hash", "viewer")
+	if err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	now := time.Now()
+	session := repository.AdminSession{
+		IDHash:      "managed-" + randID(),
+		AdminUserID: user.ID,
+		CSRFToken:   "csrf",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+		Role:        user.Role,
+	}
+	if err := repo.CreateAdminSession(ctx, session); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+
+	users, err := repo.ListAdminUsers(ctx)
+	if err != nil {
+		t.Fatalf("ListAdminUsers: %v", err)
+	}
+	if !slices.ContainsFunc(users, func(u repository.AdminUser) bool { return u.ID == user.ID }) {
+		t.Fatalf("ListAdminUsers = %+v, want %s", users, user.Username)
+	}
+
+	if err := repo.SetAdminUserRole(ctx, user.ID, "admin"); err != nil {
+		t.Fatalf("SetAdminUserRole: %v", err)
+	}
+	if got, err := repo.GetAdminSession(ctx, session.IDHash); err != nil || got.UserRole != "admin" {
+		t.Fatalf("GetAdminSession after role change = %+v, %v", got, err)
+	}
+
+	if err := repo.SetAdminUserPassword(ctx, user.ID, "new-hash"); err != nil {
+		t.Fatalf("SetAdminUserPassword: %v", err)
+	}
+	if got, err := repo.GetAdminUserByID(ctx, user.ID); err != nil || got.PasswordHash != "new-hash" {
+		t.Fatalf("GetAdminUserByID after password reset = %+v, %v", got, err)
+	}
+	if _, err := repo.GetAdminSession(ctx, session.IDHash); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetAdminSession after password reset error = %v, want pgx.ErrNoRows", err)
+	}
+
+	session.IDHash = "managed-" + randID()
+	if err := repo.CreateAdminSession(ctx, session); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+	if err := repo.DeactivateAdminUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeactivateAdminUser: %v", err)
+	}
+	if got, err := repo.GetAdminUserByUsername(ctx, user.Username); err != nil || got.DeactivatedAt == nil {
+		t.Fatalf("GetAdminUserByUsername after deactivation = %+v, %v", got, err)
+	}
+	if _, err := repo.GetAdminSession(ctx, session.IDHash); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetAdminSession after deactivation error = %v, want pgx.ErrNoRows", err)
+	}
+
+	if err := repo.ReactivateAdminUser(ctx, user.ID); err != nil {
+		t.Fatalf("ReactivateAdminUser: %v", err)
+	}
+	if got, err := repo.GetAdminUserByID(ctx, user.ID); err != nil || got.DeactivatedAt != nil {
+		t.Fatalf("GetAdminUserByID after reactivation = %+v, %v", got, err)
+	}
+
+	missing := "00000000-0000-0000-0000-000000000000"
+	if err := repo.SetAdminUserRole(ctx, missing, "admin"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("SetAdminUserRole(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.DeactivateAdminUser(ctx, missing); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("DeactivateAdminUser(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestRehashAdminUserPassword(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	user, err := repo.CreateAdminUser(ctx, "rehash-"+randID(), "old-hash", "viewer")
+	if err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	now := time.Now()
+	session := repository.AdminSession{
+		IDHash:      "rehash-" + randID(),
+		AdminUserID: user.ID,
+		CSRFToken:   "csrf",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+		Role:        user.Role,
+	}
+	if err := repo.CreateAdminSession(ctx, session); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+
+	if err := repo.RehashAdminUserPassword(ctx, user.ID, "old-hash", "new-hash"); err != nil {
+		t.Fatalf("RehashAdminUserPassword: %v", err)
+	}
+	if got, err := repo.GetAdminUserByID(ctx, user.ID); err != nil || got.PasswordHash != "new-hash" {
+		t.Fatalf("GetAdminUserByID after rehash = %+v, %v", got, err)
+	}
+	if _, err := repo.GetAdminSession(ctx, session.IDHash); err != nil {
+		t.Fatalf("GetAdminSession after rehash error = %v, want the session kept", err)
+	}
+
+	// A rehash based on a hash that has since changed is ignored.
+	if err := repo.RehashAdminUserPassword(ctx, user.ID, "old-hash", "stale-hash"); err != nil {
+		t.Fatalf("RehashAdminUserPassword(stale): %v", err)
+	}
+	if got, err := repo.GetAdminUserByID(ctx, user.ID); err != nil || got.PasswordHash != "new-hash" {
+		t.Fatalf("GetAdminUserByID after stale rehash = %+v, %v", got, err)
+	}
+}
+
+func TestFlagStats(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	project := createTestProject(t, repo, "stats")
+	if _, err := repo.CreateFlag(ctx, repository.Flag{Key: "checkout", ProjectID: project.ID}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+
+	first := time.Now().UTC().Truncate(time.Second)
+	second := first.Add(time.Minute)
+	if err := repo.AddFlagStats(ctx, []repository.FlagStats{
+		{ProjectID: project.ID, FlagKey: "checkout", Evaluations: 3, TrueEvaluations: 1, LastEvaluatedAt: &second},
+		{ProjectID: project.ID, FlagKey: "missing", Evaluations: 1, LastEvaluatedAt: &first},
+	}); err != nil {
+		t.Fatalf("AddFlagStats: %v", err)
+	}
+	// An older flush arriving late must not move last_evaluated_at back.
+	if err := repo.AddFlagStats(ctx, []repository.FlagStats{
+		{ProjectID: project.ID, FlagKey: "checkout", Evaluations: 2, TrueEvaluations: 2, LastEvaluatedAt: &first},
+	}); err != nil {
+		t.Fatalf("AddFlagStats again: %v", err)
+	}
+
+	stats, err := repo.GetFlagStats(ctx, project.ID, "checkout")
+	if err != nil {
+		t.Fatalf("GetFlagStats: %v", err)
+	}
+	if stats.Evaluations != 5 || stats.LastEvaluatedAt == nil || !stats.LastEvaluatedAt.Equal(second) {
+		t.Errorf("stats = %d at %v, want 5 at %v", stats.Evaluations, stats.LastEvaluatedAt, second)
+	}
+	if stats.TrueEvaluations != 3 {
+		t.Errorf("true evaluations = %d, want 3", stats.TrueEvaluations)
+	}
+
+	all, err := repo.ListFlagStats(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListFlagStats: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("ListFlagStats returned %d entries, want 1 (missing flag dropped)", len(all))
+	}
+
+	if err := repo.DeleteFlag(ctx, project.ID, "checkout"); err != nil {
+		t.Fatalf("DeleteFlag: %v", err)
+	}
+	if _, err := repo.GetFlagStats(ctx, project.ID, "checkout"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetFlagStats after delete error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestAPIKeyUsage(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "usage")
+
+	hour := time.Now().UTC().Truncate(time.Hour)
+	totals, err := repo.AddAPIKeyUsage(ctx, []repository.APIKeyUsage{
+		{ProjectID: project.ID, APIKeyID: "key-1", Hour: hour, Evaluations: 3, StreamSeconds: 60},
+		{ProjectID: project.ID, APIKeyID: "key-2", Hour: hour.Add(-time.Hour), Mutations: 1},
+	})
+	if err != nil || len(totals) != 2 {
+		t.Fatalf("AddAPIKeyUsage = %v, %v, want 2 totals", totals, err)
+	}
+	totals, err = repo.AddAPIKeyUsage(ctx, []repository.APIKeyUsage{
+		{ProjectID: project.ID, APIKeyID: "key-1", Hour: hour, Evaluations: 2, Mutations: 1},
+	})
+	if err != nil {
+		t.Fatalf("AddAPIKeyUsage again: %v", err)
+	}
+	if len(totals) != 1 || totals[0].Evaluations != 5 || totals[0].Mutations != 1 || totals[0].StreamSeconds != 60 {
+		t.Fatalf("totals = %+v, want 5 evaluations, 1 mutation, 60s", totals)
+	}
+
+	usage, err := repo.ListAPIKeyUsage(ctx, project.ID, hour.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("ListAPIKeyUsage: %v", err)
+	}
+	if len(usage) != 1 || usage[0].APIKeyID != "key-1" || !usage[0].Hour.Equal(hour) {
+		t.Fatalf("ListAPIKeyUsage = %+v, want key-1 in the current hour only", usage)
+	}
+
+	quota := repository.APIKeyQuota{HourlyEvaluations: 1000, HourlyMutations: 10}
+	if _, err := repo.SetAPIKeyQuota(ctx, project.ID, quota); err != nil {
+		t.Fatalf("SetAPIKeyQuota: %v", err)
+	}
+	if got, err := repo.GetAPIKeyQuota(ctx, project.ID); err != nil || got != quota {
+		t.Fatalf("GetAPIKeyQuota = %+v, %v, want %+v", got, err, quota)
+	}
+	if _, err := repo.GetAPIKeyQuota(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetAPIKeyQuota(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+	project := createTestProject(t, repo, "notifications")
+
+	slack, err := repo.CreateNotificationChannel(ctx, repository.NotificationChannel{
+		ProjectID: project.ID, Kind: "slack", Target: "https://hooks.slack.com/services/x",
+	})
+	if err != nil {
+		t.Fatalf("CreateNotificationChannel(slack): %v", err)
+	}
+	email, err := repo.CreateNotificationChannel(ctx, repository.NotificationChannel{
+		ProjectID: project.ID, Kind: "email", Target: "ops@example.com", EventTypes: []string{"deleted"},
+	})
+	if err != nil {
+		t.Fatalf("CreateNotificationChannel(email): %v", err)
+	}
+	if slack.ID == "" || slack.EventTypes == nil || len(slack.EventTypes) != 0 {
+		t.Errorf("slack channel = %+v, want an ID and no event types", slack)
+	}
+
+	channels, err := repo.ListNotificationChannels(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("ListNotificationChannels: %v", err)
+	}
+	if len(channels) != 2 || channels[0].ID != slack.ID || !slices.Equal(channels[1].EventTypes, []string{"deleted"}) {
+		t.Fatalf("ListNotificationChannels = %+v, want slack then email", channels)
+	}
+
+	for _, n := range []repository.Notification{
+		{ProjectID: project.ID, ChannelID: slack.ID, EventID: 1, FlagKey: "checkout", EventType: "updated", Status: "sent", Attempts: 1},
+		{ProjectID: project.ID, ChannelID: email.ID, EventID: 2, FlagKey: "checkout", EventType: "deleted", Status: "failed", Attempts: 3, Error: "connection refused"},
+	} {
+		if err := repo.InsertNotification(ctx, n); err != nil {
+			t.Fatalf("InsertNotification: %v", err)
+		}
+	}
+
+	if err := repo.DeleteNotificationChannel(ctx, project.ID, email.ID); err != nil {
+		t.Fatalf("DeleteNotificationChannel: %v", err)
+	}
+	if err := repo.DeleteNotificationChannel(ctx, project.ID, email.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second DeleteNotificationChannel error = %v, want pgx.ErrNoRows", err)
+	}
+
+	history, err := repo.ListNotifications(ctx, project.ID, 10)
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+	if len(history) != 2 || history[0].ChannelID != email.ID || history[0].Status != "failed" || history[0].Error != "connection refused" {
+		t.Fatalf("ListNotifications = %+v, want the failed email delivery first, kept after its channel was deleted", history)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	repo := newRepo()
+	ctx := context.Background()
+
+	apiKeyID := t.Name()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	cutoff := now.Add(-24 * time.Hour)
+
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, apiKeyID, "k1", "fp-1", now, cutoff); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey: claimed = %v, err = %v, want claimed", claimed, err)
+	}
+	record, claimed, err := repo.ClaimIdempotencyKey(ctx, apiKeyID, "k1", "fp-1", now, cutoff)
+	if err != nil || claimed || record.StatusCode != 0 {
+		t.Fatalf("ClaimIdempotencyKey while in progress = %+v, %v, %v, want the in-progress record", record, claimed, err)
+	}
+
+	if err := repo.CompleteIdempotencyKey(ctx, apiKeyID, "k1", repository.IdempotencyRecord{
+		StatusCode: 201, ContentType: "application/json", Body: []byte(`{"key":"checkout"}`),
+	}); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	if err := repo.ReleaseIdempotencyKey(ctx, apiKeyID, "k1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	record, claimed, err = repo.ClaimIdempotencyKey(ctx, apiKeyID, "k1", "fp-2", now, cutoff)
+	if err != nil || claimed || record.Fingerprint != "fp-1" || record.StatusCode != 201 || string(record.Body) != `{"key":"checkout"}` {
+		t.Fatalf("ClaimIdempotencyKey after completion = %+v, %v, %v, want the stored response", record, claimed, err)
+	}
+
+	later := now.Add(25 * time.Hour)
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, apiKeyID, "k1", "fp-3", later, later.Add(-24*time.Hour)); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey after expiry: claimed = %v, err = %v, want claimed", claimed, err)
+	}
+	if purged, err := repo.PurgeIdempotencyKeys(ctx, later.Add(time.Minute)); err != nil || purged < 1 {
+		t.Fatalf("PurgeIdempotencyKeys = %d, %v, want at least 1", purged, err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Project scoping
 // ---------------------------------------------------------------------------
 
+func TestReadPool(t *testing.T) {
+	ctx := context.Background()
+	project := createTestProject(t, newRepo(), "read-pool")
+
+	// A second pool on the same database stands in for a caught-up replica.
+	readPool, err := pgxpool.NewWithConfig(ctx, testPool.Config())
+	if err != nil {
+		t.Fatalf("create read pool: %v", err)
+	}
+	defer readPool.Close()
+	var fallbacks []string
+	repo := repository.NewPostgresRepository(testPool,
+		repository.WithReadPool(readPool),
+		repository.WithReadFallbackHook(func(op string) { fallbacks = append(fallbacks, op) }),
+	)
+
+	if _, err := repo.CreateFlag(ctx, repository.Flag{ProjectID: project.ID, Key: "replicated", Enabled: true}); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+	if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: project.ID, FlagKey: "replicated", EventType: "updated"}); err != nil {
+		t.Fatalf("PublishFlagEvent: %v", err)
+	}
+	if flag, err := repo.GetFlag(ctx, project.ID, "replicated"); err != nil || !flag.Enabled {
+		t.Fatalf("GetFlag = %+v, %v", flag, err)
+	}
+	if events, err := repo.ListEventsSince(ctx, project.ID, 0); err != nil || len(events) != 1 {
+		t.Fatalf("ListEventsSince = %+v, %v", events, err)
+	}
+	if len(fallbacks) != 0 {
+		t.Fatalf("fallbacks = %v, want none while the read pool is healthy", fallbacks)
+	}
+
+	// A flag the replica does not have is looked up on the primary too.
+	if _, err := repo.GetFlag(ctx, project.ID, "missing"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlag(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+	if !slices.Equal(fallbacks, []string{"get_flag"}) {
+		t.Fatalf("fallbacks = %v, want [get_flag]", fallbacks)
+	}
+
+	// Once the replica is gone, every read is served by the primary.
+	readPool.Close()
+	flags, err := repo.ListFlags(ctx)
+	if err != nil || !slices.ContainsFunc(flags, func(f repository.Flag) bool { return f.Key == "replicated" && f.ProjectID == project.ID }) {
+		t.Fatalf("ListFlags after the replica closed = %d flags, %v", len(flags), err)
+	}
+	if events, err := repo.ListEventsSinceForKey(ctx, project.ID, 0, "replicated"); err != nil || len(events) != 1 {
+		t.Fatalf("ListEventsSinceForKey after the replica closed = %+v, %v", events, err)
+	}
+	if !slices.Equal(fallbacks, []string{"get_flag", "list_flags", "list_events_since_for_key"}) {
+		t.Fatalf("fallbacks = %v", fallbacks)
+	}
+}
+
 func TestProjectScoping(t *testing.T) {
 	repo := newRepo()
 	ctx := context.Background()
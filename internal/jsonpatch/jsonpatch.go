@@ -0,0 +1,355 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+//
+// Numbers are carried through as written rather than converted to float64,
+// so applying a patch never changes the precision of values it leaves
+// alone.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for a patch that is malformed or whose
+	// operations cannot be applied to the document.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a JSON Patch "test" operation finds a
+	// different value than it expects.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Merge applies the JSON Merge Patch patch to doc and returns the result.
+// Object members of patch replace or, when null, remove the members of doc
+// with the same name, recursively; any other patch replaces doc entirely.
+func Merge(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergeValue(t[name], value)
+	}
+	return t
+}
+
+// operation is one step of a JSON Patch.
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch patch, an array of operations, to doc and
+// returns the result. The operations are applied in order, and the result
+// is only returned if all of them succeed: a failed "test" operation
+// returns [ErrTestFailed], and any other failure [ErrInvalidPatch].
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+	var ops []operation
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("%w: patch must be an array of operations: %v", ErrInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		target, err = applyOperation(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyOperation(doc any, op operation) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, op.Op)
+		}
+		value, err := decode(*op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if doc, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: value at %q differs", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s requires from", ErrInvalidPatch, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(doc, path, clone(value))
+		}
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move %q into itself", ErrInvalidPatch, *op.From)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must be empty or start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerString formats tokens as a JSON Pointer for error messages.
+func pointerString(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+func get(doc any, path []string) (any, error) {
+	for i, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointerString(path[:i+1]))
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%w: path %q: %v", ErrInvalidPatch, pointerString(path[:i+1]), err)
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointerString(path[:i+1]))
+		}
+	}
+	return doc, nil
+}
+
+// add sets the value at path, inserting into arrays, and returns the
+// updated document.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+		return doc, nil
+	case []any:
+		index := len(node)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(node)); err != nil {
+				return nil, fmt.Errorf("%w: path %q: %v", ErrInvalidPatch, pointerString(path), err)
+			}
+		}
+		return replaceParent(doc, path, slices.Insert(node, index, value))
+	default:
+		return nil, fmt.Errorf("%w: path %q has no parent object or array", ErrInvalidPatch, pointerString(path))
+	}
+}
+
+// remove deletes the value at path and returns the updated document.
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	parent, _ := get(doc, path[:len(path)-1])
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		delete(node, token)
+		return doc, nil
+	default:
+		index, _ := arrayIndex(token, len(node.([]any))-1)
+		return replaceParent(doc, path, slices.Delete(node.([]any), index, index+1))
+	}
+}
+
+// replaceParent stores array, which replaces the array holding path's last
+// token, in the document. Arrays can change length, so unlike objects they
+// have to be stored again after being modified.
+func replaceParent(doc any, path []string, array []any) (any, error) {
+	parentPath := path[:len(path)-1]
+	if len(parentPath) == 0 {
+		return array, nil
+	}
+	grandparent, _ := get(doc, parentPath[:len(parentPath)-1])
+	token := parentPath[len(parentPath)-1]
+	switch node := grandparent.(type) {
+	case map[string]any:
+		node[token] = array
+	case []any:
+		index, _ := arrayIndex(token, len(node)-1)
+		node[index] = array
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token no greater than maxIndex.
+func arrayIndex(token string, maxIndex int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > maxIndex {
+		return 0, fmt.Errorf("index %s is out of range", token)
+	}
+	return index, nil
+}
+
+// equal reports whether two decoded values are the same JSON value.
+// Numbers are equal when they have the same numeric value.
+func equal(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok || bok {
+		if !aok || !bok {
+			return false
+		}
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equal)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func clone(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		cloned := make(map[string]any, len(value))
+		for name, v := range value {
+			cloned[name] = clone(v)
+		}
+		return cloned
+	case []any:
+		cloned := make([]any, len(value))
+		for i, v := range value {
+			cloned[i] = clone(v)
+		}
+		return cloned
+	default:
+		return value
+	}
+}
+
+// decode parses a single JSON value, keeping numbers as [json.Number].
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
@@ -0,0 +1,112 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":1,"b":2}`, `{"a":3}`, `{"a":3,"b":2}`},
+		{"remove member", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"nested objects merge", `{"v":{"on":"x","off":"y"}}`, `{"v":{"off":"z","new":1}}`, `{"v":{"off":"z","on":"x","new":1}}`},
+		{"arrays are replaced", `{"tags":["a","b"]}`, `{"tags":["c"]}`, `{"tags":["c"]}`},
+		{"non-object patch replaces", `{"a":1}`, `[1]`, `[1]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+
+	got, err := Merge([]byte(`{"n":12345678901234567890}`), []byte(`{"m":1}`))
+	if err != nil || string(got) != `{"m":1,"n":12345678901234567890}` {
+		t.Errorf("Merge() = %s, %v, want the number kept as written", got, err)
+	}
+	if _, err := Merge([]byte(`{}`), []byte(`{`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("Merge(malformed) error = %v, want ErrInvalidPatch", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	const doc = `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2}],"variants":{"on":"x"},"a/b":{"~c":1}}`
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"replace", `[{"op":"replace","path":"/enabled","value":true}]`, `{"key":"checkout","enabled":true,"rules":[{"a":1},{"b":2}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"add member", `[{"op":"add","path":"/variants/off","value":"y"}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2}],"variants":{"on":"x","off":"y"},"a/b":{"~c":1}}`},
+		{"insert into array", `[{"op":"add","path":"/rules/1","value":{"c":3}}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"c":3},{"b":2}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"append to array", `[{"op":"add","path":"/rules/-","value":{"c":3}}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2},{"c":3}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"remove array item", `[{"op":"remove","path":"/rules/0"}]`, `{"key":"checkout","enabled":false,"rules":[{"b":2}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"escaped pointer", `[{"op":"replace","path":"/a~1b/~0c","value":2}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2}],"variants":{"on":"x"},"a/b":{"~c":2}}`},
+		{"move", `[{"op":"move","from":"/variants/on","path":"/variants/off"}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2}],"variants":{"off":"x"},"a/b":{"~c":1}}`},
+		{"copy", `[{"op":"copy","from":"/rules/0","path":"/rules/-"}]`, `{"key":"checkout","enabled":false,"rules":[{"a":1},{"b":2},{"a":1}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"test then replace", `[{"op":"test","path":"/enabled","value":false},{"op":"replace","path":"/enabled","value":true}]`, `{"key":"checkout","enabled":true,"rules":[{"a":1},{"b":2}],"variants":{"on":"x"},"a/b":{"~c":1}}`},
+		{"test compares numbers by value", `[{"op":"test","path":"/rules/0/a","value":1.0}]`, doc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	const doc = `{"enabled":false,"rules":[{"a":1}]}`
+	tests := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"not an array", `{"op":"remove","path":"/enabled"}`, ErrInvalidPatch},
+		{"unknown op", `[{"op":"merge","path":"/enabled"}]`, ErrInvalidPatch},
+		{"unknown member", `[{"op":"remove","path":"/enabled","extra":1}]`, ErrInvalidPatch},
+		{"missing path", `[{"op":"remove"}]`, ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/x"}]`, ErrInvalidPatch},
+		{"bad pointer", `[{"op":"remove","path":"enabled"}]`, ErrInvalidPatch},
+		{"remove missing", `[{"op":"remove","path":"/missing"}]`, ErrInvalidPatch},
+		{"replace missing", `[{"op":"replace","path":"/missing","value":1}]`, ErrInvalidPatch},
+		{"index out of range", `[{"op":"add","path":"/rules/5","value":1}]`, ErrInvalidPatch},
+		{"leading zero index", `[{"op":"remove","path":"/rules/00"}]`, ErrInvalidPatch},
+		{"move into itself", `[{"op":"move","from":"/rules","path":"/rules/0"}]`, ErrInvalidPatch},
+		{"failed test", `[{"op":"test","path":"/enabled","value":true}]`, ErrTestFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Apply([]byte(doc), []byte(tt.patch)); !errors.Is(err, tt.want) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s is not JSON: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want %s is not JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("result = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,476 @@
+// Package jsonschema validates JSON values against a subset of JSON Schema
+// (draft 2020-12) that covers the shapes flag variants take.
+//
+// Supported keywords are type, enum, const, properties, required,
+// additionalProperties, minProperties, maxProperties, items, minItems,
+// maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf and
+// not. Annotations such as title, description, default and examples are
+// accepted and ignored. Any other keyword, notably $ref, is rejected when
+// the schema is compiled rather than silently skipped, so a schema never
+// validates less than its author expects.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSchema is returned by [Compile] for a schema it cannot use.
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// Error is one way a value failed validation.
+type Error struct {
+	// Path is a JSON Pointer (RFC 6901) to the offending value; "" is the
+	// value itself.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Schema is a compiled schema.
+type Schema struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	types                []string
+	enum                 []any
+	constant             *any
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+	items                *Schema
+	minItems             *int
+	maxItems             *int
+	uniqueItems          bool
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+	minimum              *float64
+	maximum              *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	multipleOf           *float64
+	allOf                []*Schema
+	anyOf                []*Schema
+	oneOf                []*Schema
+	not                  *Schema
+}
+
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+var types = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Compile parses a JSON schema document.
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	s, err := compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return s, nil
+}
+
+func compile(doc any, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", pathOrRoot(path))
+	}
+
+	s := &Schema{}
+	// Sorted so the problem reported does not depend on map order.
+	for _, key := range slices.Sorted(maps.Keys(obj)) {
+		v := obj[key]
+		at := path + "/" + EscapePointer(key)
+		var err error
+		switch key {
+		case "type":
+			s.types, err = compileTypes(v)
+		case "enum":
+			list, ok := v.([]any)
+			if !ok {
+				err = errors.New("must be an array")
+			}
+			s.enum = list
+		case "const":
+			s.constant = &v
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				err = errors.New("must be an object")
+				break
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for _, name := range slices.Sorted(maps.Keys(props)) {
+				if s.properties[name], err = compile(props[name], at+"/"+EscapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = compileStrings(v)
+		case "additionalProperties":
+			if s.additionalProperties, err = compile(v, at); err != nil {
+				return nil, err
+			}
+		case "items":
+			if s.items, err = compile(v, at); err != nil {
+				return nil, err
+			}
+		case "allOf", "anyOf", "oneOf":
+			list, err := compileList(v, at)
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "allOf":
+				s.allOf = list
+			case "anyOf":
+				s.anyOf = list
+			default:
+				s.oneOf = list
+			}
+		case "not":
+			if s.not, err = compile(v, at); err != nil {
+				return nil, err
+			}
+		case "minProperties":
+			s.minProperties, err = compileCount(v)
+		case "maxProperties":
+			s.maxProperties, err = compileCount(v)
+		case "minItems":
+			s.minItems, err = compileCount(v)
+		case "maxItems":
+			s.maxItems, err = compileCount(v)
+		case "minLength":
+			s.minLength, err = compileCount(v)
+		case "maxLength":
+			s.maxLength, err = compileCount(v)
+		case "uniqueItems":
+			b, ok := v.(bool)
+			if !ok {
+				err = errors.New("must be a boolean")
+			}
+			s.uniqueItems = b
+		case "pattern":
+			str, ok := v.(string)
+			if !ok {
+				err = errors.New("must be a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(str)
+		case "minimum":
+			s.minimum, err = compileNumber(v)
+		case "maximum":
+			s.maximum, err = compileNumber(v)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = compileNumber(v)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = compileNumber(v)
+		case "multipleOf":
+			s.multipleOf, err = compileNumber(v)
+			if err == nil && *s.multipleOf <= 0 {
+				err = errors.New("must be greater than 0")
+			}
+		default:
+			if !annotations[key] {
+				err = errors.New("keyword is not supported")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", at, err)
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(v any) ([]string, error) {
+	var names []string
+	if name, ok := v.(string); ok {
+		names = []string{name}
+	} else {
+		var err error
+		if names, err = compileStrings(v); err != nil {
+			return nil, errors.New("must be a string or an array of strings")
+		}
+	}
+	for _, name := range names {
+		if !slices.Contains(types, name) {
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return names, nil
+}
+
+func compileStrings(v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		if out[i], ok = item.(string); !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+	}
+	return out, nil
+}
+
+func compileList(v any, path string) ([]*Schema, error) {
+	list, ok := v.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array of schemas", path)
+	}
+	out := make([]*Schema, len(list))
+	for i, item := range list {
+		var err error
+		if out[i], err = compile(item, path+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func compileCount(v any) (*int, error) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func compileNumber(v any) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, errors.New("must be a number")
+	}
+	return &f, nil
+}
+
+// Validate checks a decoded JSON value, as produced by [json.Unmarshal] into
+// an any, and returns every error found.
+func (s *Schema) Validate(v any) []Error {
+	return s.validate(v, "")
+}
+
+// ValidateJSON decodes raw and validates it.
+func (s *Schema) ValidateJSON(raw json.RawMessage) []Error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return []Error{{Message: "invalid JSON: " + err.Error()}}
+	}
+	return s.Validate(v)
+}
+
+func (s *Schema) validate(v any, path string) []Error {
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return []Error{{Path: path, Message: "no value is allowed here"}}
+	}
+
+	var errs []Error
+	fail := func(format string, args ...any) {
+		errs = append(errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(v, t) }) {
+		fail("must be %s, not %s", strings.Join(s.types, " or "), typeOf(v))
+		// The remaining keywords would only repeat the type mismatch.
+		return errs
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return equal(e, v) }) {
+		fail("must be one of %s", compact(s.enum))
+	}
+	if s.constant != nil && !equal(*s.constant, v) {
+		fail("must be %s", compact(*s.constant))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		if s.minProperties != nil && len(v) < *s.minProperties {
+			fail("must have at least %d properties", *s.minProperties)
+		}
+		if s.maxProperties != nil && len(v) > *s.maxProperties {
+			fail("must have at most %d properties", *s.maxProperties)
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			at := path + "/" + EscapePointer(name)
+			if sub, ok := s.properties[name]; ok {
+				errs = append(errs, sub.validate(v[name], at)...)
+			} else if s.additionalProperties != nil {
+				if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+					errs = append(errs, Error{Path: at, Message: "property is not allowed"})
+				} else {
+					errs = append(errs, s.additionalProperties.validate(v[name], at)...)
+				}
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+		unique:
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if equal(v[i], v[j]) {
+						fail("items %d and %d are equal", i, j)
+						break unique
+					}
+				}
+			}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				errs = append(errs, s.items.validate(item, path+"/"+strconv.Itoa(i))...)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("must be < %v", *s.exclusiveMaximum)
+		}
+		if s.multipleOf != nil {
+			if q := v / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("must be a multiple of %v", *s.multipleOf)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		errs = append(errs, sub.validate(v, path)...)
+	}
+	if len(s.anyOf) > 0 && !slices.ContainsFunc(s.anyOf, func(sub *Schema) bool { return len(sub.validate(v, path)) == 0 }) {
+		fail("must match at least one schema in anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if len(sub.validate(v, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", matched)
+		}
+	}
+	if s.not != nil && len(s.not.validate(v, path)) == 0 {
+		fail("must not match the schema in not")
+	}
+	return errs
+}
+
+func hasType(v any, name string) bool {
+	switch name {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return typeOf(v) == name
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// equal compares decoded JSON values. Numbers are float64 on both sides, so
+// 1 and 1.0 are equal as JSON Schema requires.
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func compact(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// EscapePointer encodes a property name as a JSON Pointer reference token,
+// for callers building paths like those in [Error].
+func EscapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "schema"
+	}
+	return path
+}
@@ -0,0 +1,139 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string // "path: message" of each error
+	}{
+		{
+			name:   "object with required properties",
+			schema: `{"type":"object","required":["color","size"],"properties":{"color":{"type":"string"},"size":{"enum":["s","m","l"]}}}`,
+			value:  `{"color":"red","size":"m"}`,
+		},
+		{
+			name:   "missing and wrong properties",
+			schema: `{"type":"object","required":["color","size"],"properties":{"color":{"type":"string"},"size":{"enum":["s","m","l"]}}}`,
+			value:  `{"color":1}`,
+			want:   []string{`missing required property "size"`, `/color: must be string, not number`},
+		},
+		{
+			name:   "wrong type stops further checks",
+			schema: `{"type":"object","required":["color"]}`,
+			value:  `"red"`,
+			want:   []string{`must be object, not string`},
+		},
+		{
+			name:   "additional properties",
+			schema: `{"properties":{"a":{}},"additionalProperties":false}`,
+			value:  `{"a":1,"b/c":2}`,
+			want:   []string{`/b~1c: property is not allowed`},
+		},
+		{
+			name:   "additional properties schema",
+			schema: `{"additionalProperties":{"type":"integer"}}`,
+			value:  `{"a":1,"b":1.5}`,
+			want:   []string{`/b: must be integer, not number`},
+		},
+		{
+			name:   "array items",
+			schema: `{"type":"array","items":{"type":"number","minimum":0},"minItems":1,"uniqueItems":true}`,
+			value:  `[1,-1,1]`,
+			want:   []string{`items 0 and 2 are equal`, `/1: must be >= 0`},
+		},
+		{
+			name:   "string constraints",
+			schema: `{"type":"string","minLength":2,"maxLength":3,"pattern":"^[a-z]+$"}`,
+			value:  `"ABCD"`,
+			want:   []string{`must be at most 3 characters`, `must match "^[a-z]+$"`},
+		},
+		{
+			name:   "number constraints",
+			schema: `{"exclusiveMaximum":10,"multipleOf":0.5}`,
+			value:  `10.25`,
+			want:   []string{`must be < 10`, `must be a multiple of 0.5`},
+		},
+		{
+			name:   "integer accepts whole floats",
+			schema: `{"type":"integer"}`,
+			value:  `2.0`,
+		},
+		{
+			name:   "const",
+			schema: `{"const":{"a":[1]}}`,
+			value:  `{"a":[1.0]}`,
+		},
+		{
+			name:   "oneOf",
+			schema: `{"oneOf":[{"type":"number"},{"type":"integer"}]}`,
+			value:  `1`,
+			want:   []string{`must match exactly one schema in oneOf, matched 2`},
+		},
+		{
+			name:   "anyOf and not",
+			schema: `{"anyOf":[{"type":"string"},{"type":"null"}],"not":{"const":"off"}}`,
+			value:  `"off"`,
+			want:   []string{`must not match the schema in not`},
+		},
+		{
+			name:   "false schema",
+			schema: `false`,
+			value:  `1`,
+			want:   []string{`no value is allowed here`},
+		},
+		{
+			name:   "annotations are ignored",
+			schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"Variant","description":"d","default":1}`,
+			value:  `1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Compile(json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var got []string
+			for _, e := range schema.ValidateJSON(json.RawMessage(tt.value)) {
+				got = append(got, e.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate(%s) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsSchemas(t *testing.T) {
+	for _, schema := range []string{
+		`{"$ref":"#/$defs/variant"}`,
+		`{"properties":{"a":{"format":"email"}}}`,
+		`{"type":"colour"}`,
+		`{"required":"color"}`,
+		`{"pattern":"("}`,
+		`{"minLength":-1}`,
+		`{"multipleOf":0}`,
+		`{"anyOf":[]}`,
+		`[]`,
+		`{`,
+	} {
+		if _, err := Compile(json.RawMessage(schema)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("Compile(%s) error = %v, want ErrInvalidSchema", schema, err)
+		}
+	}
+}
+
+func TestCompileErrorNamesKeyword(t *testing.T) {
+	_, err := Compile(json.RawMessage(`{"properties":{"size":{"items":{"$ref":"#"}}}}`))
+	if want := `invalid JSON schema: /properties/size/items/$ref: keyword is not supported`; err == nil || err.Error() != want {
+		t.Fatalf("Compile() error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,255 @@
+package kubesync
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serviceAccountDir is where Kubernetes mounts the pod's service account
+// credentials.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client is a minimal Kubernetes API client covering the calls the syncer
+// needs: listing labelled ConfigMaps and FeatureFlag resources and patching
+// their sync status.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	// token returns the bearer token for each request. Projected service
+	// account tokens rotate, so the in-cluster client re-reads the file.
+	token func() (string, error)
+}
+
+// NewClient returns a Client for the API server at baseURL. token may be
+// nil for unauthenticated access, as in tests.
+func NewClient(baseURL string, httpClient *http.Client, token func() (string, error)) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if token == nil {
+		token = func() (string, error) { return "", nil }
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		token:      token,
+	}
+}
+
+// NewInClusterClient returns a Client authenticated with the pod's service
+// account. It returns an error when not running inside a cluster.
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	caPEM, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("service account CA contains no certificates")
+	}
+
+	tokenPath := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(tokenPath); err != nil {
+		return nil, fmt.Errorf("read service account token: %w", err)
+	}
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	token := func() (string, error) {
+		b, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return "", fmt.Errorf("read service account token: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return NewClient("https://"+net.JoinHostPort(host, port), httpClient, token), nil
+}
+
+// InClusterNamespace returns the namespace the pod runs in, or "" if it
+// cannot be determined.
+func InClusterNamespace() string {
+	b, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// ObjectMeta holds the metadata fields the syncer reads.
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Generation  int64             `json:"generation,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ConfigMap is a Kubernetes ConfigMap.
+type ConfigMap struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// FeatureFlag is a flagz.io/v1alpha1 FeatureFlag custom resource.
+type FeatureFlag struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Spec     FeatureFlagSpec   `json:"spec"`
+	Status   FeatureFlagStatus `json:"status,omitempty"`
+}
+
+// FeatureFlagSpec is the desired state of a flag. Key defaults to the
+// resource name.
+type FeatureFlagSpec struct {
+	Project     string          `json:"project"`
+	Key         string          `json:"key,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Enabled     bool            `json:"enabled"`
+	Variants    json.RawMessage `json:"variants,omitempty"`
+	Rules       json.RawMessage `json:"rules,omitempty"`
+}
+
+// FeatureFlagStatus reports the outcome of the last sync.
+type FeatureFlagStatus struct {
+	Synced             bool   `json:"synced"`
+	Message            string `json:"message,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	LastSyncTime       string `json:"lastSyncTime,omitempty"`
+}
+
+// ListConfigMaps lists ConfigMaps matching labelSelector in namespace, or
+// in every namespace when namespace is empty.
+func (c *Client) ListConfigMaps(ctx context.Context, namespace, labelSelector string) ([]ConfigMap, error) {
+	var list struct {
+		Items []ConfigMap `json:"items"`
+	}
+	path := namespacedPath("/api/v1", namespace, "configmaps") + "?labelSelector=" + url.QueryEscape(labelSelector)
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &list); err != nil {
+		return nil, fmt.Errorf("list configmaps: %w", err)
+	}
+	return list.Items, nil
+}
+
+// ListFeatureFlags lists FeatureFlag resources in namespace, or in every
+// namespace when namespace is empty. It returns [ErrNotFound] if the
+// FeatureFlag CRD is not installed.
+func (c *Client) ListFeatureFlags(ctx context.Context, namespace string) ([]FeatureFlag, error) {
+	var list struct {
+		Items []FeatureFlag `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, namespacedPath("/apis/"+crdGroupVersion, namespace, crdPlural), "", nil, &list); err != nil {
+		return nil, fmt.Errorf("list featureflags: %w", err)
+	}
+	return list.Items, nil
+}
+
+// PatchConfigMapAnnotations merges annotations into a ConfigMap.
+func (c *Client) PatchConfigMapAnnotations(ctx context.Context, namespace, name string, annotations map[string]string) error {
+	patch := map[string]any{"metadata": map[string]any{"annotations": annotations}}
+	path := namespacedPath("/api/v1", namespace, "configmaps") + "/" + url.PathEscape(name)
+	if err := c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", patch, nil); err != nil {
+		return fmt.Errorf("patch configmap %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// PatchFeatureFlagStatus replaces the status of a FeatureFlag through its
+// status subresource.
+func (c *Client) PatchFeatureFlagStatus(ctx context.Context, namespace, name string, status FeatureFlagStatus) error {
+	patch := map[string]any{"status": status}
+	path := namespacedPath("/apis/"+crdGroupVersion, namespace, crdPlural) + "/" + url.PathEscape(name) + "/status"
+	if err := c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", patch, nil); err != nil {
+		return fmt.Errorf("patch featureflag %s/%s status: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// ErrNotFound is returned when the API server responds 404, for example
+// because a CRD is not installed.
+var ErrNotFound = errors.New("kubernetes resource not found")
+
+// APIError is returned for other non-2xx responses from the API server.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kubernetes API: HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func namespacedPath(prefix, namespace, resource string) string {
+	if namespace == "" {
+		return prefix + "/" + resource
+	}
+	return prefix + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+}
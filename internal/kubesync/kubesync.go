@@ -0,0 +1,305 @@
+// Package kubesync mirrors flag definitions declared in Kubernetes into
+// flagz projects.
+//
+// Two sources are supported:
+//
+//   - ConfigMaps labelled "flagz.io/project=<project-id>". Each data entry is
+//     one flag: the entry key is the flag key and the value is the flag as
+//     JSON, in the same shape as the HTTP API's flag body.
+//   - FeatureFlag custom resources (flagz.io/v1alpha1), whose spec names the
+//     project and describes a single flag.
+//
+// A [Syncer] lists both on an interval and creates or updates the matching
+// flags through the service layer, so validation, events, cache invalidation
+// and audit entries behave exactly as for API writes. The outcome is written
+// back onto each resource: as "flagz.io/sync-*" annotations on ConfigMaps and
+// as the status subresource on FeatureFlags. Removing a resource or entry
+// does not delete the flag; deletes stay an explicit action in flagz.
+package kubesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+const (
+	// ProjectLabel selects ConfigMaps to sync and names their project.
+	ProjectLabel = "flagz.io/project"
+
+	// Annotations written back onto synced ConfigMaps.
+	StatusAnnotation  = "flagz.io/sync-status"
+	MessageAnnotation = "flagz.io/sync-message"
+
+	// Values of StatusAnnotation.
+	StatusSynced = "Synced"
+	StatusError  = "Error"
+
+	crdGroupVersion = "flagz.io/v1alpha1"
+	crdPlural       = "featureflags"
+
+	defaultInterval = 30 * time.Second
+)
+
+// FlagService is the subset of [service.Service] used to apply flags.
+type FlagService interface {
+	GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error)
+	CreateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+	UpdateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+}
+
+// Syncer periodically applies flags declared in Kubernetes to flagz.
+type Syncer struct {
+	svc       FlagService
+	kube      *Client
+	namespace string
+	interval  time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// Option configures a [Syncer].
+type Option func(*Syncer)
+
+// WithNamespace limits the syncer to one namespace. By default it watches
+// every namespace its service account can list.
+func WithNamespace(namespace string) Option {
+	return func(s *Syncer) {
+		s.namespace = namespace
+	}
+}
+
+// WithInterval sets how often resources are listed and synced. Defaults to
+// 30 seconds if not set or if interval <= 0.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Syncer) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithLogger sets the logger used to report sync failures.
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Syncer) {
+		if log != nil {
+			s.log = log
+		}
+	}
+}
+
+// New returns a Syncer that applies flags through svc using kube.
+func New(svc FlagService, kube *Client, opts ...Option) *Syncer {
+	if svc == nil {
+		panic("service is nil")
+	}
+	if kube == nil {
+		panic("kubernetes client is nil")
+	}
+
+	s := &Syncer{
+		svc:      svc,
+		kube:     kube,
+		interval: defaultInterval,
+		log:      slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run syncs immediately and then on every interval until ctx is cancelled.
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SyncOnce(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warn("kubernetes flag sync failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce lists ConfigMaps and FeatureFlags and applies each of them. A
+// resource that fails to apply is reported on the resource itself and does
+// not stop the others. The returned error covers only failures to list
+// resources. A missing FeatureFlag CRD is not an error.
+func (s *Syncer) SyncOnce(ctx context.Context) error {
+	var errs []error
+
+	configMaps, err := s.kube.ListConfigMaps(ctx, s.namespace, ProjectLabel)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, cm := range configMaps {
+		s.syncConfigMap(ctx, cm)
+	}
+
+	featureFlags, err := s.kube.ListFeatureFlags(ctx, s.namespace)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		errs = append(errs, err)
+	}
+	for _, ff := range featureFlags {
+		s.syncFeatureFlag(ctx, ff)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Syncer) syncConfigMap(ctx context.Context, cm ConfigMap) {
+	projectID := strings.TrimSpace(cm.Metadata.Labels[ProjectLabel])
+
+	var failures []string
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := s.applyConfigMapEntry(ctx, projectID, key, cm.Data[key]); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	status, message := StatusSynced, fmt.Sprintf("%d flag(s) synced", len(keys))
+	if len(failures) > 0 {
+		status = StatusError
+		message = strings.Join(failures, "; ")
+	}
+
+	// Only patch on change: every patch bumps the resource version, and
+	// rewriting identical annotations each pass would churn the object.
+	if cm.Metadata.Annotations[StatusAnnotation] == status && cm.Metadata.Annotations[MessageAnnotation] == message {
+		return
+	}
+	if err := s.kube.PatchConfigMapAnnotations(ctx, cm.Metadata.Namespace, cm.Metadata.Name, map[string]string{
+		StatusAnnotation:  status,
+		MessageAnnotation: message,
+	}); err != nil {
+		s.log.Warn("report configmap sync status failed", "namespace", cm.Metadata.Namespace, "name", cm.Metadata.Name, "error", err)
+	}
+}
+
+func (s *Syncer) applyConfigMapEntry(ctx context.Context, projectID, key, value string) error {
+	var flag repository.Flag
+	if err := json.Unmarshal([]byte(value), &flag); err != nil {
+		return fmt.Errorf("invalid flag JSON: %w", err)
+	}
+	if flag.Key != "" && flag.Key != key {
+		return fmt.Errorf("flag key %q does not match entry key", flag.Key)
+	}
+	flag.Key = key
+	flag.ProjectID = projectID
+	return s.apply(ctx, flag)
+}
+
+func (s *Syncer) syncFeatureFlag(ctx context.Context, ff FeatureFlag) {
+	key := ff.Spec.Key
+	if key == "" {
+		key = ff.Metadata.Name
+	}
+	err := s.apply(ctx, repository.Flag{
+		Key:         key,
+		ProjectID:   strings.TrimSpace(ff.Spec.Project),
+		Description: ff.Spec.Description,
+		Enabled:     ff.Spec.Enabled,
+		Variants:    ff.Spec.Variants,
+		Rules:       ff.Spec.Rules,
+	})
+
+	status := FeatureFlagStatus{
+		Synced:             err == nil,
+		Message:            "flag synced",
+		ObservedGeneration: ff.Metadata.Generation,
+	}
+	if err != nil {
+		status.Message = err.Error()
+	}
+	if ff.Status.Synced == status.Synced &&
+		ff.Status.Message == status.Message &&
+		ff.Status.ObservedGeneration == status.ObservedGeneration {
+		return
+	}
+	status.LastSyncTime = s.now().UTC().Format(time.RFC3339)
+	if err := s.kube.PatchFeatureFlagStatus(ctx, ff.Metadata.Namespace, ff.Metadata.Name, status); err != nil {
+		s.log.Warn("report featureflag sync status failed", "namespace", ff.Metadata.Namespace, "name", ff.Metadata.Name, "error", err)
+	}
+}
+
+// apply creates flag, or updates it if it exists and differs.
+func (s *Syncer) apply(ctx context.Context, flag repository.Flag) error {
+	if flag.ProjectID == "" {
+		return service.ErrProjectIDRequired
+	}
+
+	existing, err := s.svc.GetFlag(ctx, flag.ProjectID, flag.Key)
+	switch {
+	case errors.Is(err, service.ErrFlagNotFound):
+		_, err = s.svc.CreateFlag(ctx, flag)
+		return err
+	case err != nil:
+		return err
+	}
+
+	if sameFlag(existing, flag) {
+		return nil
+	}
+	_, err = s.svc.UpdateFlag(ctx, flag)
+	return err
+}
+
+// sameFlag reports whether applying desired would leave current unchanged.
+func sameFlag(current, desired repository.Flag) bool {
+	return current.Description == desired.Description &&
+		current.Enabled == desired.Enabled &&
+		sameJSON(current.Variants, desired.Variants) &&
+		sameJSON(current.Rules, desired.Rules)
+}
+
+// sameJSON compares JSON payloads semantically, treating absent, null and
+// empty collections as equal.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb any
+	if len(a) > 0 {
+		if err := json.Unmarshal(a, &va); err != nil {
+			return false
+		}
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &vb); err != nil {
+			return false
+		}
+	}
+	ea, _ := json.Marshal(normalizeEmpty(va))
+	eb, _ := json.Marshal(normalizeEmpty(vb))
+	return string(ea) == string(eb)
+}
+
+func normalizeEmpty(v any) any {
+	switch t := v.(type) {
+	case []any:
+		if len(t) == 0 {
+			return nil
+		}
+	case map[string]any:
+		if len(t) == 0 {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,218 @@
+package kubesync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+type fakeFlagService struct {
+	flags   map[string]repository.Flag
+	updates int
+}
+
+func (f *fakeFlagService) GetFlag(_ context.Context, projectID, key string) (repository.Flag, error) {
+	flag, ok := f.flags[projectID+"/"+key]
+	if !ok {
+		return repository.Flag{}, service.ErrFlagNotFound
+	}
+	return flag, nil
+}
+
+func (f *fakeFlagService) CreateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+	if strings.Contains(string(flag.Rules), "bogus") {
+		return repository.Flag{}, service.ErrInvalidRules
+	}
+	f.flags[flag.ProjectID+"/"+flag.Key] = flag
+	return flag, nil
+}
+
+func (f *fakeFlagService) UpdateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+	f.updates++
+	f.flags[flag.ProjectID+"/"+flag.Key] = flag
+	return flag, nil
+}
+
+// fakeKubeAPI serves fixed ConfigMap and FeatureFlag lists and records
+// patches.
+type fakeKubeAPI struct {
+	mu           sync.Mutex
+	configMaps   []ConfigMap
+	featureFlags []FeatureFlag
+	noCRD        bool
+	patches      map[string]string
+}
+
+func (a *fakeKubeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/apps/configmaps":
+		if got := r.URL.Query().Get("labelSelector"); got != ProjectLabel {
+			http.Error(w, "unexpected selector "+got, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": a.configMaps})
+	case r.Method == http.MethodGet && r.URL.Path == "/apis/flagz.io/v1alpha1/namespaces/apps/featureflags":
+		if a.noCRD {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": a.featureFlags})
+	case r.Method == http.MethodPatch:
+		if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+			http.Error(w, "unexpected content type "+ct, http.StatusUnsupportedMediaType)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		a.patches[r.URL.Path] = string(body)
+		w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestSyncer(t *testing.T, api *fakeKubeAPI, svc *fakeFlagService) *Syncer {
+	t.Helper()
+	api.patches = map[string]string{}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return New(svc, NewClient(srv.URL, srv.Client(), nil), WithNamespace("apps"))
+}
+
+func TestSyncOnceAppliesConfigMapsAndReportsStatus(t *testing.T) {
+	svc := &fakeFlagService{flags: map[string]repository.Flag{
+		"proj-1/dark-mode": {Key: "dark-mode", ProjectID: "proj-1", Enabled: false},
+	}}
+	api := &fakeKubeAPI{
+		noCRD: true,
+		configMaps: []ConfigMap{
+			{
+				Metadata: ObjectMeta{Name: "flags", Namespace: "apps", Labels: map[string]string{ProjectLabel: "proj-1"}},
+				Data: map[string]string{
+					"dark-mode":    `{"description":"Dark theme","enabled":true}`,
+					"new-checkout": `{"enabled":false,"rules":[{"attribute":"plan","operator":"equals","value":"pro"}]}`,
+				},
+			},
+			{
+				Metadata: ObjectMeta{Name: "broken", Namespace: "apps", Labels: map[string]string{ProjectLabel: "proj-1"}},
+				Data:     map[string]string{"bad": `{"enabled":`},
+			},
+		},
+	}
+	syncer := newTestSyncer(t, api, svc)
+
+	if err := syncer.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+
+	if got := svc.flags["proj-1/dark-mode"]; !got.Enabled || got.Description != "Dark theme" {
+		t.Errorf("dark-mode = %+v, want enabled with description", got)
+	}
+	if got, ok := svc.flags["proj-1/new-checkout"]; !ok || len(got.Rules) == 0 {
+		t.Errorf("new-checkout = %+v, want created with rules", got)
+	}
+
+	ok := api.patches["/api/v1/namespaces/apps/configmaps/flags"]
+	if !strings.Contains(ok, `"flagz.io/sync-status":"Synced"`) || !strings.Contains(ok, "2 flag(s) synced") {
+		t.Errorf("flags patch = %s, want Synced", ok)
+	}
+	broken := api.patches["/api/v1/namespaces/apps/configmaps/broken"]
+	if !strings.Contains(broken, `"flagz.io/sync-status":"Error"`) || !strings.Contains(broken, "invalid flag JSON") {
+		t.Errorf("broken patch = %s, want Error", broken)
+	}
+
+	// A second pass with the status already recorded changes nothing.
+	api.configMaps[0].Metadata.Annotations = map[string]string{
+		StatusAnnotation:  StatusSynced,
+		MessageAnnotation: "2 flag(s) synced",
+	}
+	api.configMaps = api.configMaps[:1]
+	api.patches = map[string]string{}
+	updates := svc.updates
+	if err := syncer.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("second SyncOnce() error = %v", err)
+	}
+	if svc.updates != updates {
+		t.Errorf("updates = %d, want %d (flags unchanged)", svc.updates, updates)
+	}
+	if len(api.patches) != 0 {
+		t.Errorf("patches = %v, want none", api.patches)
+	}
+}
+
+func TestSyncOnceAppliesFeatureFlags(t *testing.T) {
+	svc := &fakeFlagService{flags: map[string]repository.Flag{}}
+	api := &fakeKubeAPI{
+		featureFlags: []FeatureFlag{
+			{
+				Metadata: ObjectMeta{Name: "beta-banner", Namespace: "apps", Generation: 3},
+				Spec:     FeatureFlagSpec{Project: "proj-1", Enabled: true},
+			},
+			{
+				Metadata: ObjectMeta{Name: "bad-rules", Namespace: "apps", Generation: 1},
+				Spec:     FeatureFlagSpec{Project: "proj-1", Rules: json.RawMessage(`"bogus"`)},
+			},
+			{
+				Metadata: ObjectMeta{Name: "no-project", Namespace: "apps", Generation: 1},
+			},
+		},
+	}
+	syncer := newTestSyncer(t, api, svc)
+
+	if err := syncer.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+
+	if got, ok := svc.flags["proj-1/beta-banner"]; !ok || !got.Enabled {
+		t.Errorf("beta-banner = %+v, want created and enabled", got)
+	}
+
+	statusOf := func(name string) FeatureFlagStatus {
+		t.Helper()
+		var patch struct {
+			Status FeatureFlagStatus `json:"status"`
+		}
+		body := api.patches["/apis/flagz.io/v1alpha1/namespaces/apps/featureflags/"+name+"/status"]
+		if err := json.Unmarshal([]byte(body), &patch); err != nil {
+			t.Fatalf("decode %s status patch %q: %v", name, body, err)
+		}
+		return patch.Status
+	}
+	if got := statusOf("beta-banner"); !got.Synced || got.ObservedGeneration != 3 || got.LastSyncTime == "" {
+		t.Errorf("beta-banner status = %+v, want synced at generation 3", got)
+	}
+	if got := statusOf("bad-rules"); got.Synced || got.Message != service.ErrInvalidRules.Error() {
+		t.Errorf("bad-rules status = %+v, want invalid rules", got)
+	}
+	if got := statusOf("no-project"); got.Synced || got.Message != service.ErrProjectIDRequired.Error() {
+		t.Errorf("no-project status = %+v, want project required", got)
+	}
+}
+
+func TestSameJSONTreatsEmptyCollectionsAsUnset(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "null", true},
+		{"[]", "", true},
+		{"{}", "null", true},
+		{`{"a":true,"b":false}`, `{"b":false, "a":true}`, true},
+		{`[{"attribute":"x"}]`, "[]", false},
+	}
+	for _, tc := range tests {
+		if got := sameJSON(json.RawMessage(tc.a), json.RawMessage(tc.b)); got != tc.want {
+			t.Errorf("sameJSON(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 // Package logging provides a structured logger factory for the flagz server.
 //
 // It configures [log/slog] with a JSON handler and a configurable minimum
-// level, suitable for production deployments.
+// level, suitable for production deployments. Records logged with a context
+// carrying an active span include its trace_id and span_id, so log lines can
+// be matched to traces; [Init] can additionally export records over OTLP.
 package logging
 
 import (
@@ -20,9 +22,13 @@ func New(level string) *slog.Logger {
 
 // NewWithWriter creates a [slog.Logger] writing JSON to w at the given level.
 func NewWithWriter(level string, w io.Writer) *slog.Logger {
-	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
-		Level: ParseLevel(level),
-	}))
+	return slog.New(NewTraceHandler(newJSONHandler(ParseLevel(level), w)))
+}
+
+func newJSONHandler(level slog.Leveler, w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+	})
 }
 
 // ParseLevel converts a level string to a [slog.Level].
@@ -2,8 +2,17 @@ package logging
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/matt-riley/flagz/internal/instance"
 )
 
 func TestParseLevel(t *testing.T) {
@@ -41,3 +50,114 @@ func TestNewWithWriter(t *testing.T) {
 		t.Errorf("expected JSON msg field, got: %s", buf.String())
 	}
 }
+
+func TestNewWithWriterAddsTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithWriter("info", &buf)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	log.With("component", "test").InfoContext(ctx, "in span")
+	log.Info("no span")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf.String())
+	}
+	var inSpan, noSpan map[string]any
+	if err := json.Unmarshal(lines[0], &inSpan); err != nil {
+		t.Fatalf("decode %s: %v", lines[0], err)
+	}
+	if err := json.Unmarshal(lines[1], &noSpan); err != nil {
+		t.Fatalf("decode %s: %v", lines[1], err)
+	}
+	if inSpan[TraceIDKey] != traceID.String() || inSpan[SpanIDKey] != spanID.String() || inSpan["component"] != "test" {
+		t.Errorf("in-span record = %v, want trace and span IDs", inSpan)
+	}
+	if _, ok := noSpan[TraceIDKey]; ok {
+		t.Errorf("record without span = %v, want no trace_id", noSpan)
+	}
+}
+
+func TestInitExportsLogsOverOTLP(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	t.Setenv("OTEL_LOGS_EXPORTER", "")
+
+	log, shutdown, err := Init(context.Background(), ParseLevel("warn"), instance.Identity{})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	log.Warn("exported")
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/v1/logs" {
+		t.Fatalf("collector requests = %v, want one POST to /v1/logs", paths)
+	}
+}
+
+func TestInitWithoutExportLogsLocally(t *testing.T) {
+	for name, env := range map[string][2]string{
+		"no endpoint":  {"", ""},
+		"exporter off": {"http://127.0.0.1:4318", "none"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", env[0])
+			t.Setenv("OTEL_LOGS_EXPORTER", env[1])
+
+			log, shutdown, err := Init(context.Background(), ParseLevel("info"), instance.Identity{})
+			if err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			if _, ok := log.Handler().(traceHandler); !ok {
+				t.Errorf("handler = %T, want local traceHandler only", log.Handler())
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Fatalf("shutdown() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestInitFollowsLevelVar(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	log, _, err := Init(context.Background(), &level, instance.Identity{})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if log.Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("info enabled at warn level")
+	}
+	level.Set(slog.LevelDebug)
+	if !log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("debug not enabled after lowering level")
+	}
+
+	export := levelHandler{level: &level, next: slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug})}
+	level.Set(slog.LevelError)
+	if export.Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatal("export handler enabled for warn at error level")
+	}
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/matt-riley/flagz/internal/instance"
+	"github.com/matt-riley/flagz/internal/tracing"
+)
+
+// Init creates the server logger. Like [New] it writes JSON to stderr. When
+// OTEL_EXPORTER_OTLP_ENDPOINT is set, records at or above level are also
+// exported over OTLP/HTTP to the collector that receives traces, carrying the
+// trace context of the ctx they were logged with and id as resource
+// attributes. Set OTEL_LOGS_EXPORTER to "none" to keep logs local while still
+// exporting traces.
+//
+// Pass a [*slog.LevelVar] as level to change the level of the returned
+// logger while it is in use. The returned function flushes pending log
+// exports and should be called on server shutdown.
+func Init(ctx context.Context, level slog.Leveler, id instance.Identity) (*slog.Logger, func(context.Context) error, error) {
+	local := NewTraceHandler(newJSONHandler(level, os.Stderr))
+	if !otlpLogExportEnabled() {
+		return slog.New(local), func(context.Context) error { return nil }, nil
+	}
+
+	res, err := tracing.NewResource(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exporter, err := otlploghttp.New(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create OTLP log exporter: %w", err)
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+	export := levelHandler{
+		level: level,
+		next:  otelslog.NewHandler("flagz", otelslog.WithLoggerProvider(provider)),
+	}
+
+	return slog.New(fanoutHandler{local, export}), provider.Shutdown, nil
+}
+
+func otlpLogExportEnabled() bool {
+	if strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")) == "" {
+		return false
+	}
+	return !strings.EqualFold(strings.TrimSpace(os.Getenv("OTEL_LOGS_EXPORTER")), "none")
+}
+
+// levelHandler drops records below level. The OTLP bridge has no level of
+// its own and would otherwise export debug records from an info logger.
+type levelHandler struct {
+	level slog.Leveler
+	next  slog.Handler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.next.Handle(ctx, record)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{level: h.level, next: h.next.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{level: h.level, next: h.next.WithGroup(name)}
+}
+
+// fanoutHandler sends each record to every handler that is enabled for it.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, next := range h {
+		if next.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, next := range h {
+		if next.Enabled(ctx, record.Level) {
+			errs = append(errs, next.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanoutHandler, len(h))
+	for i, next := range h {
+		out[i] = next.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	out := make(fanoutHandler, len(h))
+	for i, next := range h {
+		out[i] = next.WithGroup(name)
+	}
+	return out
+}
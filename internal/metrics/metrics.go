@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"path"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/instance"
 )
 
 // Metrics holds all Prometheus collectors used by the flagz server.
@@ -23,22 +27,104 @@ type Metrics struct {
 
 	HTTPRequestsTotal   *prometheus.CounterVec
 	HTTPRequestDuration *prometheus.HistogramVec
+	HTTPBodyTooLarge    *prometheus.CounterVec
 	GRPCRequestsTotal   *prometheus.CounterVec
 	GRPCRequestDuration *prometheus.HistogramVec
 	CacheSize           *prometheus.GaugeVec
 	CacheLoadsTotal     prometheus.Counter
 	CacheInvalidations  prometheus.Counter
+	// CacheReloadDuration, CacheReloadFailures, CacheLastReload, CacheAge and
+	// CacheSizeDelta show whether the cache is being refreshed; a CacheAge
+	// well past the resync interval means it is drifting.
+	CacheReloadDuration prometheus.Histogram
+	CacheReloadFailures prometheus.Counter
+	CacheLastReload     prometheus.Gauge
+	CacheAge            prometheus.GaugeFunc
+	CacheSizeDelta      *prometheus.GaugeVec
 	EvaluationsTotal    *prometheus.CounterVec
 	AuthFailuresTotal   prometheus.Counter
 	ActiveStreams       *prometheus.GaugeVec
+
+	EventsPublishedTotal   *prometheus.CounterVec
+	StreamEventsSentTotal  *prometheus.CounterVec
+	StreamReplayDepth      *prometheus.HistogramVec
+	ProjectActiveStreams   *prometheus.GaugeVec
+	ListenReconnectsTotal  prometheus.Counter
+	HedgedReadsTotal       *prometheus.CounterVec
+	ContextWarningsTotal   *prometheus.CounterVec
+	ShadowEvaluationsTotal *prometheus.CounterVec
+
+	EvaluationCacheLookupsTotal *prometheus.CounterVec
+
+	// EvaluationsInFlight, EvaluationQueueDepth and EvaluationsShedTotal
+	// show the load on the evaluation limiter; a queue that stays non-empty
+	// means evaluations are arriving faster than they complete.
+	EvaluationsInFlight  prometheus.Gauge
+	EvaluationQueueDepth prometheus.Gauge
+	EvaluationsShedTotal *prometheus.CounterVec
+
+	// InstanceInfo is always 1, labelled with the server's identity; see
+	// WithInstance.
+	InstanceInfo *prometheus.GaugeVec
+
+	ReplicaReadFallbacksTotal *prometheus.CounterVec
+
+	// flagLabelLimit caps the distinct project and flag key pairs that
+	// EvaluationsTotal labels; see WithFlagLabelLimit.
+	flagLabelLimit int
+	flagLabelsMu   sync.Mutex
+	flagLabels     map[flagLabel]struct{}
+
+	// lastCacheReload is the Unix time in nanoseconds of the last successful
+	// cache reload, or of New until there is one.
+	lastCacheReload atomic.Int64
+}
+
+// OtherFlagKey is the flag_key label of evaluations of flags past the
+// [WithFlagLabelLimit] cap.
+const OtherFlagKey = "__other__"
+
+// defaultFlagLabelLimit is the default cap on per-flag evaluation series.
+const defaultFlagLabelLimit = 1000
+
+type flagLabel struct {
+	projectID string
+	key       string
+}
+
+// Option configures [New].
+type Option func(*Metrics)
+
+// WithFlagLabelLimit caps how many distinct project and flag key pairs get
+// their own flagz_flag_evaluations_total series. Evaluations of further
+// flags are counted under flag_key [OtherFlagKey] of their project, so a
+// project with thousands of flags cannot blow up the series count. A limit
+// of 0 counts every flag as [OtherFlagKey]. Defaults to 1000; a negative
+// limit is ignored.
+func WithFlagLabelLimit(limit int) Option {
+	return func(m *Metrics) {
+		if limit >= 0 {
+			m.flagLabelLimit = limit
+		}
+	}
+}
+
+// WithInstance labels flagz_instance_info with id, so dashboards can join
+// any series on the replica, region and build that produced it.
+func WithInstance(id instance.Identity) Option {
+	return func(m *Metrics) {
+		m.InstanceInfo.WithLabelValues(id.Region, id.Hostname, id.Version, id.GitSHA).Set(1)
+	}
 }
 
 // New creates and registers all flagz metrics in a fresh registry.
-func New() *Metrics {
+func New(opts ...Option) *Metrics {
 	reg := prometheus.NewRegistry()
 
 	m := &Metrics{
-		Registry: reg,
+		Registry:       reg,
+		flagLabelLimit: defaultFlagLabelLimit,
+		flagLabels:     make(map[flagLabel]struct{}),
 
 		HTTPRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "flagz_http_requests_total",
@@ -51,6 +137,11 @@ func New() *Metrics {
 			Buckets: prometheus.DefBuckets,
 		}, []string{"method", "route", "status"}),
 
+		HTTPBodyTooLarge: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_http_body_too_large_total",
+			Help: "Total number of HTTP requests rejected because the body exceeded the route's size limit.",
+		}, []string{"route"}),
+
 		GRPCRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "flagz_grpc_requests_total",
 			Help: "Total number of gRPC requests.",
@@ -77,10 +168,31 @@ func New() *Metrics {
 			Help: "Total number of NOTIFY-triggered cache invalidations.",
 		}),
 
+		CacheReloadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "flagz_cache_reload_duration_seconds",
+			Help:    "Duration of successful full cache reloads in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}),
+
+		CacheReloadFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "flagz_cache_reload_failures_total",
+			Help: "Total number of full cache reloads that failed.",
+		}),
+
+		CacheLastReload: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "flagz_cache_last_reload_timestamp_seconds",
+			Help: "Unix time of the last successful full cache reload.",
+		}),
+
+		CacheSizeDelta: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "flagz_cache_size_delta",
+			Help: "Change in the number of cached flags at the last full cache reload.",
+		}, []string{"project_id"}),
+
 		EvaluationsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "flagz_flag_evaluations_total",
-			Help: "Total number of flag evaluations.",
-		}, []string{"result"}),
+			Help: "Total number of flag evaluations, by project, flag key and result.",
+		}, []string{"project_id", "flag_key", "result"}),
 
 		AuthFailuresTotal: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "flagz_auth_failures_total",
@@ -91,21 +203,124 @@ func New() *Metrics {
 			Name: "flagz_active_streams",
 			Help: "Number of active streaming connections.",
 		}, []string{"transport"}),
+
+		EventsPublishedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_events_published_total",
+			Help: "Total number of flag events published, by event type.",
+		}, []string{"event_type"}),
+
+		StreamEventsSentTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_stream_events_sent_total",
+			Help: "Total number of flag events delivered to streaming clients.",
+		}, []string{"transport"}),
+
+		StreamReplayDepth: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "flagz_stream_replay_depth",
+			Help:    "Number of events replayed to a resuming stream client (how far behind its last event ID was), capped at the event batch size.",
+			Buckets: []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000},
+		}, []string{"transport"}),
+
+		ProjectActiveStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "flagz_project_active_streams",
+			Help: "Number of active streaming connections per project.",
+		}, []string{"transport", "project_id"}),
+
+		ListenReconnectsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "flagz_listen_reconnects_total",
+			Help: "Total number of times the LISTEN/NOTIFY connection was re-established after a failure.",
+		}),
+
+		HedgedReadsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_hedged_reads_total",
+			Help: "Total number of flag cache-miss reads by the source that answered, and whether the answer came from the hedged request.",
+		}, []string{"source", "hedge_win"}),
+
+		ReplicaReadFallbacksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_replica_read_fallbacks_total",
+			Help: "Total number of reads retried on the primary database after the read replica failed them, by repository operation.",
+		}, []string{"operation"}),
+
+		ContextWarningsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_context_warnings_total",
+			Help: "Total number of evaluation context attributes that broke a strict context schema, by kind (unknown_attribute or type_mismatch).",
+		}, []string{"kind"}),
+
+		ShadowEvaluationsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_shadow_evaluations_total",
+			Help: "Total number of shadow rule evaluations, by whether they agreed with the live rules.",
+		}, []string{"project_id", "flag_key", "result"}),
+
+		EvaluationCacheLookupsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_evaluation_cache_lookups_total",
+			Help: "Total number of evaluation cache lookups, by result (hit or miss).",
+		}, []string{"result"}),
+
+		EvaluationsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "flagz_evaluations_in_flight",
+			Help: "Number of evaluation requests being handled under the evaluation limit.",
+		}),
+
+		EvaluationQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "flagz_evaluation_queue_depth",
+			Help: "Number of evaluation requests waiting for a slot under the evaluation limit.",
+		}),
+
+		EvaluationsShedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "flagz_evaluations_shed_total",
+			Help: "Total number of evaluation requests rejected because the evaluation limit and its queue were full, by transport.",
+		}, []string{"transport"}),
+
+		InstanceInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "flagz_instance_info",
+			Help: "Always 1; labelled with the region, hostname, version and git SHA of this server.",
+		}, []string{"region", "hostname", "version", "git_sha"}),
 	}
 
+	m.lastCacheReload.Store(time.Now().UnixNano())
+	m.CacheAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "flagz_cache_age_seconds",
+		Help: "Seconds since the last successful full cache reload, or since startup if there has been none.",
+	}, func() float64 {
+		return time.Since(time.Unix(0, m.lastCacheReload.Load())).Seconds()
+	})
+
 	reg.MustRegister(
 		m.HTTPRequestsTotal,
 		m.HTTPRequestDuration,
+		m.HTTPBodyTooLarge,
 		m.GRPCRequestsTotal,
 		m.GRPCRequestDuration,
 		m.CacheSize,
 		m.CacheLoadsTotal,
 		m.CacheInvalidations,
+		m.CacheReloadDuration,
+		m.CacheReloadFailures,
+		m.CacheLastReload,
+		m.CacheAge,
+		m.CacheSizeDelta,
 		m.EvaluationsTotal,
 		m.AuthFailuresTotal,
 		m.ActiveStreams,
+		m.EventsPublishedTotal,
+		m.StreamEventsSentTotal,
+		m.StreamReplayDepth,
+		m.ProjectActiveStreams,
+		m.ListenReconnectsTotal,
+		m.HedgedReadsTotal,
+		m.ContextWarningsTotal,
+		m.ShadowEvaluationsTotal,
+		m.ReplicaReadFallbacksTotal,
+		m.EvaluationCacheLookupsTotal,
+		m.EvaluationsInFlight,
+		m.EvaluationQueueDepth,
+		m.EvaluationsShedTotal,
+		m.InstanceInfo,
 	)
 
+	for _, opt := range opts {
+		opt(m)
+	}
+
 	return m
 }
 
@@ -146,9 +361,28 @@ func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
 	}
 }
 
-// RecordEvaluation increments the evaluation counter with the given result.
-func (m *Metrics) RecordEvaluation(result bool) {
-	m.EvaluationsTotal.WithLabelValues(strconv.FormatBool(result)).Inc()
+// RecordEvaluation increments the evaluation counter of a flag with the
+// given result. Flags past the [WithFlagLabelLimit] cap are counted as
+// [OtherFlagKey].
+func (m *Metrics) RecordEvaluation(projectID, key string, result bool) {
+	m.EvaluationsTotal.WithLabelValues(projectID, m.flagKeyLabel(projectID, key), strconv.FormatBool(result)).Inc()
+}
+
+// flagKeyLabel returns key if the flag already has its own series or there
+// is room for one, and [OtherFlagKey] otherwise.
+func (m *Metrics) flagKeyLabel(projectID, key string) string {
+	label := flagLabel{projectID, key}
+
+	m.flagLabelsMu.Lock()
+	defer m.flagLabelsMu.Unlock()
+	if _, ok := m.flagLabels[label]; ok {
+		return key
+	}
+	if len(m.flagLabels) >= m.flagLabelLimit {
+		return OtherFlagKey
+	}
+	m.flagLabels[label] = struct{}{}
+	return key
 }
 
 // SetCacheSize updates the cache size gauge for the given project.
@@ -156,10 +390,32 @@ func (m *Metrics) SetCacheSize(projectID string, size float64) {
 	m.CacheSize.WithLabelValues(projectID).Set(size)
 }
 
-// ResetCacheSize removes all project labels from the cache size gauge,
-// preventing stale values for projects that no longer have flags.
+// ResetCacheSize removes all project labels from the cache size and size
+// delta gauges, preventing stale values for projects that no longer have
+// flags.
 func (m *Metrics) ResetCacheSize() {
 	m.CacheSize.Reset()
+	m.CacheSizeDelta.Reset()
+}
+
+// SetCacheSizeDelta records how much a project's cached flag count changed
+// at the last full cache reload.
+func (m *Metrics) SetCacheSizeDelta(projectID string, delta float64) {
+	m.CacheSizeDelta.WithLabelValues(projectID).Set(delta)
+}
+
+// ObserveCacheReload records a successful full cache reload that took
+// duration, resetting the cache age.
+func (m *Metrics) ObserveCacheReload(duration time.Duration) {
+	now := time.Now()
+	m.lastCacheReload.Store(now.UnixNano())
+	m.CacheLastReload.Set(float64(now.UnixNano()) / 1e9)
+	m.CacheReloadDuration.Observe(duration.Seconds())
+}
+
+// IncCacheReloadFailures increments the failed cache reload counter.
+func (m *Metrics) IncCacheReloadFailures() {
+	m.CacheReloadFailures.Inc()
 }
 
 // IncCacheLoads increments the cache load counter.
@@ -171,3 +427,72 @@ func (m *Metrics) IncCacheLoads() {
 func (m *Metrics) IncCacheInvalidations() {
 	m.CacheInvalidations.Inc()
 }
+
+// IncEventsPublished increments the published event counter for eventType.
+func (m *Metrics) IncEventsPublished(eventType string) {
+	m.EventsPublishedTotal.WithLabelValues(eventType).Inc()
+}
+
+// AddStreamEventsSent adds n to the delivered stream event counter.
+func (m *Metrics) AddStreamEventsSent(transport string, n int) {
+	if n > 0 {
+		m.StreamEventsSentTotal.WithLabelValues(transport).Add(float64(n))
+	}
+}
+
+// ObserveReplayDepth records how many events were replayed to a stream
+// client that resumed from a previous event ID.
+func (m *Metrics) ObserveReplayDepth(transport string, depth int) {
+	m.StreamReplayDepth.WithLabelValues(transport).Observe(float64(depth))
+}
+
+// TrackProjectStream increments the per-project active stream gauge and
+// returns a function that decrements it; call it when the stream ends.
+func (m *Metrics) TrackProjectStream(transport, projectID string) func() {
+	gauge := m.ProjectActiveStreams.WithLabelValues(transport, projectID)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// IncListenReconnects increments the LISTEN/NOTIFY reconnect counter.
+func (m *Metrics) IncListenReconnects() {
+	m.ListenReconnectsTotal.Inc()
+}
+
+// RecordHedgedRead counts a flag read answered by source; hedgeWon reports
+// whether the hedged request beat the first one.
+func (m *Metrics) RecordHedgedRead(source string, hedgeWon bool) {
+	m.HedgedReadsTotal.WithLabelValues(source, strconv.FormatBool(hedgeWon)).Inc()
+}
+
+// IncReplicaReadFallbacks counts a read of the given repository operation
+// that the read replica failed and the primary served instead.
+func (m *Metrics) IncReplicaReadFallbacks(operation string) {
+	m.ReplicaReadFallbacksTotal.WithLabelValues(operation).Inc()
+}
+
+// IncContextWarnings counts an evaluation context warning of the given kind.
+func (m *Metrics) IncContextWarnings(kind string) {
+	m.ContextWarningsTotal.WithLabelValues(kind).Inc()
+}
+
+// RecordEvaluationCacheLookup counts an evaluation cache lookup as a "hit"
+// or a "miss".
+func (m *Metrics) RecordEvaluationCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.EvaluationCacheLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordShadowEvaluation counts an evaluation of a flag's shadow rules as
+// "agree" or "disagree" with its live rules. Flags past the
+// [WithFlagLabelLimit] cap are counted as [OtherFlagKey].
+func (m *Metrics) RecordShadowEvaluation(projectID, key string, agree bool) {
+	result := "disagree"
+	if agree {
+		result = "agree"
+	}
+	m.ShadowEvaluationsTotal.WithLabelValues(projectID, m.flagKeyLabel(projectID, key), result).Inc()
+}
@@ -5,8 +5,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/matt-riley/flagz/internal/instance"
 )
 
 func TestNew(t *testing.T) {
@@ -34,12 +37,12 @@ func TestNew(t *testing.T) {
 func TestRecordEvaluation(t *testing.T) {
 	m := New()
 
-	m.RecordEvaluation(true)
-	m.RecordEvaluation(true)
-	m.RecordEvaluation(false)
+	m.RecordEvaluation("proj1", "dark-mode", true)
+	m.RecordEvaluation("proj1", "dark-mode", true)
+	m.RecordEvaluation("proj1", "dark-mode", false)
 
-	trueCount := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("true"))
-	falseCount := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("false"))
+	trueCount := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("proj1", "dark-mode", "true"))
+	falseCount := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("proj1", "dark-mode", "false"))
 
 	if trueCount != 2 {
 		t.Fatalf("expected true count 2, got %v", trueCount)
@@ -49,6 +52,45 @@ func TestRecordEvaluation(t *testing.T) {
 	}
 }
 
+func TestRecordEvaluation_FlagLabelLimit(t *testing.T) {
+	m := New(WithFlagLabelLimit(2))
+
+	m.RecordEvaluation("proj1", "a", true)
+	m.RecordEvaluation("proj2", "a", true)
+	m.RecordEvaluation("proj1", "b", true)
+	m.RecordEvaluation("proj1", "c", false)
+	m.RecordEvaluation("proj1", "a", false)
+
+	if got := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("proj1", "a", "false")); got != 1 {
+		t.Fatalf("expected known flag to keep its series, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("proj1", OtherFlagKey, "true")); got != 1 {
+		t.Fatalf("expected flag b past the limit to be counted as %s, got %v", OtherFlagKey, got)
+	}
+	if got := testutil.ToFloat64(m.EvaluationsTotal.WithLabelValues("proj1", OtherFlagKey, "false")); got != 1 {
+		t.Fatalf("expected flag c past the limit to be counted as %s, got %v", OtherFlagKey, got)
+	}
+	if got := testutil.CollectAndCount(m.EvaluationsTotal); got != 5 {
+		t.Fatalf("expected 5 series, got %d", got)
+	}
+
+	none := New(WithFlagLabelLimit(0))
+	none.RecordEvaluation("proj1", "a", true)
+	if got := testutil.ToFloat64(none.EvaluationsTotal.WithLabelValues("proj1", OtherFlagKey, "true")); got != 1 {
+		t.Fatalf("expected limit 0 to count every flag as %s, got %v", OtherFlagKey, got)
+	}
+}
+
+func TestWithInstance(t *testing.T) {
+	m := New(WithInstance(instance.Identity{Region: "eu-west-1", Hostname: "flagz-0", Version: "v1.4.0", GitSHA: "abc123"}))
+	if got := testutil.ToFloat64(m.InstanceInfo.WithLabelValues("eu-west-1", "flagz-0", "v1.4.0", "abc123")); got != 1 {
+		t.Fatalf("flagz_instance_info = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(m.InstanceInfo); n != 1 {
+		t.Fatalf("flagz_instance_info has %d series, want 1", n)
+	}
+}
+
 func TestSetCacheSize(t *testing.T) {
 	m := New()
 
@@ -73,6 +115,36 @@ func TestResetCacheSize(t *testing.T) {
 	}
 }
 
+func TestCacheReloadMetrics(t *testing.T) {
+	m := New()
+
+	m.IncCacheReloadFailures()
+	if got := testutil.ToFloat64(m.CacheReloadFailures); got != 1 {
+		t.Fatalf("expected 1 reload failure, got %v", got)
+	}
+
+	before := time.Now()
+	m.ObserveCacheReload(250 * time.Millisecond)
+	if got := testutil.ToFloat64(m.CacheLastReload); got < float64(before.Unix()) {
+		t.Fatalf("expected last reload timestamp >= %d, got %v", before.Unix(), got)
+	}
+	if got := testutil.ToFloat64(m.CacheAge); got < 0 || got > 5 {
+		t.Fatalf("expected cache age just after a reload, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.CacheReloadDuration); got != 1 {
+		t.Fatalf("expected 1 reload duration series, got %d", got)
+	}
+
+	m.SetCacheSizeDelta("proj1", -3)
+	if got := testutil.ToFloat64(m.CacheSizeDelta.WithLabelValues("proj1")); got != -3 {
+		t.Fatalf("expected cache size delta -3, got %v", got)
+	}
+	m.ResetCacheSize()
+	if got := testutil.CollectAndCount(m.CacheSizeDelta); got != 0 {
+		t.Fatalf("expected no cache size delta series after reset, got %d", got)
+	}
+}
+
 func TestHandler(t *testing.T) {
 	m := New()
 	m.CacheLoadsTotal.Inc()
@@ -112,3 +184,110 @@ func TestIncCacheInvalidations(t *testing.T) {
 		t.Fatalf("expected cache invalidations 3, got %v", v)
 	}
 }
+
+func TestStreamMetrics(t *testing.T) {
+	m := New()
+
+	m.IncEventsPublished("updated")
+	m.AddStreamEventsSent("sse", 3)
+	m.AddStreamEventsSent("sse", 0)
+	m.ObserveReplayDepth("grpc", 7)
+	m.IncListenReconnects()
+
+	if got := testutil.ToFloat64(m.EventsPublishedTotal.WithLabelValues("updated")); got != 1 {
+		t.Fatalf("expected 1 published event, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.StreamEventsSentTotal.WithLabelValues("sse")); got != 3 {
+		t.Fatalf("expected 3 sent events, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.StreamReplayDepth); got != 1 {
+		t.Fatalf("expected 1 replay depth series, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ListenReconnectsTotal); got != 1 {
+		t.Fatalf("expected 1 reconnect, got %v", got)
+	}
+
+	done := m.TrackProjectStream("sse", "proj1")
+	if got := testutil.ToFloat64(m.ProjectActiveStreams.WithLabelValues("sse", "proj1")); got != 1 {
+		t.Fatalf("expected 1 active project stream, got %v", got)
+	}
+	done()
+	if got := testutil.ToFloat64(m.ProjectActiveStreams.WithLabelValues("sse", "proj1")); got != 0 {
+		t.Fatalf("expected 0 active project streams after done, got %v", got)
+	}
+}
+
+func TestRecordHedgedRead(t *testing.T) {
+	m := New()
+
+	m.RecordHedgedRead("replica", true)
+	m.RecordHedgedRead("primary", false)
+	m.RecordHedgedRead("replica", true)
+
+	if got := testutil.ToFloat64(m.HedgedReadsTotal.WithLabelValues("replica", "true")); got != 2 {
+		t.Fatalf("expected 2 replica hedge wins, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.HedgedReadsTotal.WithLabelValues("primary", "false")); got != 1 {
+		t.Fatalf("expected 1 unhedged primary read, got %v", got)
+	}
+}
+
+func TestIncReplicaReadFallbacks(t *testing.T) {
+	m := New()
+
+	m.IncReplicaReadFallbacks("list_flags")
+	m.IncReplicaReadFallbacks("list_flags")
+
+	if got := testutil.ToFloat64(m.ReplicaReadFallbacksTotal.WithLabelValues("list_flags")); got != 2 {
+		t.Fatalf("expected 2 list_flags fallbacks, got %v", got)
+	}
+}
+
+func TestIncContextWarnings(t *testing.T) {
+	m := New()
+
+	m.IncContextWarnings("unknown_attribute")
+	m.IncContextWarnings("unknown_attribute")
+	m.IncContextWarnings("type_mismatch")
+
+	if got := testutil.ToFloat64(m.ContextWarningsTotal.WithLabelValues("unknown_attribute")); got != 2 {
+		t.Fatalf("expected 2 unknown attribute warnings, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ContextWarningsTotal.WithLabelValues("type_mismatch")); got != 1 {
+		t.Fatalf("expected 1 type mismatch warning, got %v", got)
+	}
+}
+
+func TestRecordShadowEvaluation(t *testing.T) {
+	m := New(WithFlagLabelLimit(1))
+
+	m.RecordShadowEvaluation("p1", "checkout", true)
+	m.RecordShadowEvaluation("p1", "checkout", false)
+	m.RecordShadowEvaluation("p1", "checkout", true)
+	m.RecordShadowEvaluation("p1", "banner", false)
+
+	if got := testutil.ToFloat64(m.ShadowEvaluationsTotal.WithLabelValues("p1", "checkout", "agree")); got != 2 {
+		t.Fatalf("expected 2 agreeing shadow evaluations, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ShadowEvaluationsTotal.WithLabelValues("p1", "checkout", "disagree")); got != 1 {
+		t.Fatalf("expected 1 disagreeing shadow evaluation, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ShadowEvaluationsTotal.WithLabelValues("p1", OtherFlagKey, "disagree")); got != 1 {
+		t.Fatalf("expected 1 disagreeing shadow evaluation past the label limit, got %v", got)
+	}
+}
+
+func TestRecordEvaluationCacheLookup(t *testing.T) {
+	m := New()
+
+	m.RecordEvaluationCacheLookup(true)
+	m.RecordEvaluationCacheLookup(true)
+	m.RecordEvaluationCacheLookup(false)
+
+	if got := testutil.ToFloat64(m.EvaluationCacheLookupsTotal.WithLabelValues("hit")); got != 2 {
+		t.Fatalf("expected 2 hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.EvaluationCacheLookupsTotal.WithLabelValues("miss")); got != 1 {
+		t.Fatalf("expected 1 miss, got %v", got)
+	}
+}
@@ -50,8 +50,9 @@ func HTTPBearerAuthMiddleware(validator TokenValidator, opts ...AuthOption) func
 		o(&cfg)
 	}
 	return func(next http.Handler) http.Handler {
+		dashboardNext := dashboardTokenHandler(next)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			projectID, err := authorizeHTTP(r.Context(), r.Header.Get("Authorization"), validator)
+			projectID, rotation, err := authorizeHTTP(r.Context(), r.Header.Get("Authorization"), validator)
 			if err != nil {
 				if cfg.onFailure != nil {
 					cfg.onFailure()
@@ -59,19 +60,25 @@ func HTTPBearerAuthMiddleware(validator TokenValidator, opts ...AuthOption) func
 				if cfg.rateLimiter != nil {
 					if ip := ExtractIP(r.RemoteAddr); ip != "" {
 						if !cfg.rateLimiter.RecordFailureAndAllow(ip) {
-							http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+							writeHTTPAuthError(w, r, http.StatusTooManyRequests)
 							return
 						}
 					}
 				}
-				writeHTTPUnauthorized(w)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeHTTPAuthError(w, r, http.StatusUnauthorized)
 				return
 			}
 			ctx := context.WithValue(r.Context(), projectIDKey, projectID)
-			if keyID := apiKeyIDFromBearer(r.Header.Get("Authorization")); keyID != "" {
+			handler := next
+			if tokenID := dashboardTokenIDFromBearer(r.Header.Get("Authorization")); tokenID != "" {
+				ctx = context.WithValue(ctx, dashboardTokenIDKey, tokenID)
+				handler = dashboardNext
+			} else if keyID := apiKeyIDFromBearer(r.Header.Get("Authorization")); keyID != "" {
 				ctx = context.WithValue(ctx, apiKeyIDKey, keyID)
 			}
-			next.ServeHTTP(w, r.WithContext(ctx))
+			setKeyRotationHeaders(w, rotation)
+			handler.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -83,7 +90,7 @@ func UnaryBearerAuthInterceptor(validator TokenValidator, opts ...AuthOption) gr
 		o(&cfg)
 	}
 	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		projectID, err := authorizeGRPC(ctx, validator)
+		projectID, rotation, err := authorizeGRPC(ctx, validator)
 		if err != nil {
 			if cfg.onFailure != nil {
 				cfg.onFailure()
@@ -97,6 +104,9 @@ func UnaryBearerAuthInterceptor(validator TokenValidator, opts ...AuthOption) gr
 			}
 			return nil, status.Error(codes.Unauthenticated, "unauthorized")
 		}
+		if md := keyRotationMetadata(rotation); md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
 		newCtx := context.WithValue(ctx, projectIDKey, projectID)
 		if keyID := apiKeyIDFromGRPCMetadata(ctx); keyID != "" {
 			newCtx = context.WithValue(newCtx, apiKeyIDKey, keyID)
@@ -113,7 +123,7 @@ func StreamBearerAuthInterceptor(validator TokenValidator, opts ...AuthOption) g
 	}
 	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
-		projectID, err := authorizeGRPC(ctx, validator)
+		projectID, rotation, err := authorizeGRPC(ctx, validator)
 		if err != nil {
 			if cfg.onFailure != nil {
 				cfg.onFailure()
@@ -127,6 +137,9 @@ func StreamBearerAuthInterceptor(validator TokenValidator, opts ...AuthOption) g
 			}
 			return status.Error(codes.Unauthenticated, "unauthorized")
 		}
+		if md := keyRotationMetadata(rotation); md != nil {
+			_ = ss.SetHeader(md)
+		}
 
 		ctx = context.WithValue(ctx, projectIDKey, projectID)
 		if keyID := apiKeyIDFromGRPCMetadata(ss.Context()); keyID != "" {
@@ -193,41 +206,75 @@ func NewContextWithAdminUserID(ctx context.Context, userID string) context.Conte
 	return context.WithValue(ctx, adminUserIDKey, userID)
 }
 
-func authorizeHTTP(ctx context.Context, authorizationHeader string, validator TokenValidator) (string, error) {
+// Actor identifies who is making a request: the API key or dashboard token
+// it authenticated with, or the admin user signed in to the admin portal.
+type Actor struct {
+	APIKeyID         string
+	AdminUserID      string
+	DashboardTokenID string
+}
+
+// ActorFromContext returns the actor stored by [NewContextWithAPIKeyID],
+// [NewContextWithAdminUserID] and the auth middleware. All fields are empty
+// for an anonymous or internal caller.
+func ActorFromContext(ctx context.Context) Actor {
+	apiKeyID, _ := APIKeyIDFromContext(ctx)
+	adminUserID, _ := AdminUserIDFromContext(ctx)
+	dashboardTokenID, _ := DashboardTokenIDFromContext(ctx)
+	return Actor{APIKeyID: apiKeyID, AdminUserID: adminUserID, DashboardTokenID: dashboardTokenID}
+}
+
+// String returns "admin_user:<id>", "api_key:<id>" or
+// "dashboard_token:<id>", in that order of preference, or "" when none is
+// set.
+func (a Actor) String() string {
+	switch {
+	case a.AdminUserID != "":
+		return "admin_user:" + a.AdminUserID
+	case a.APIKeyID != "":
+		return "api_key:" + a.APIKeyID
+	case a.DashboardTokenID != "":
+		return "dashboard_token:" + a.DashboardTokenID
+	default:
+		return ""
+	}
+}
+
+func authorizeHTTP(ctx context.Context, authorizationHeader string, validator TokenValidator) (string, KeyRotation, error) {
 	if validator == nil {
-		return "", errors.New("token validator is nil")
+		return "", KeyRotation{}, errors.New("token validator is nil")
 	}
 	if strings.TrimSpace(authorizationHeader) == "" {
-		return "", errMissingAuthorizationHeader
+		return "", KeyRotation{}, errMissingAuthorizationHeader
 	}
 
 	token, err := parseBearerToken(authorizationHeader)
 	if err != nil {
-		return "", err
+		return "", KeyRotation{}, err
 	}
-	projectID, err := validator.ValidateToken(ctx, token)
+	projectID, rotation, err := validateToken(ctx, validator, token)
 	if err != nil {
-		return "", err
+		return "", KeyRotation{}, err
 	}
 	if strings.TrimSpace(projectID) == "" {
-		return "", errInvalidAuthorizationHeader
+		return "", KeyRotation{}, errInvalidAuthorizationHeader
 	}
-	return projectID, nil
+	return projectID, rotation, nil
 }
 
-func authorizeGRPC(ctx context.Context, validator TokenValidator) (string, error) {
+func authorizeGRPC(ctx context.Context, validator TokenValidator) (string, KeyRotation, error) {
 	if validator == nil {
-		return "", errors.New("token validator is nil")
+		return "", KeyRotation{}, errors.New("token validator is nil")
 	}
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return "", errMissingAuthorizationHeader
+		return "", KeyRotation{}, errMissingAuthorizationHeader
 	}
 
 	authorizationHeaders := md.Get("authorization")
 	if len(authorizationHeaders) == 0 {
-		return "", errMissingAuthorizationHeader
+		return "", KeyRotation{}, errMissingAuthorizationHeader
 	}
 
 	for _, authorizationHeader := range authorizationHeaders {
@@ -235,16 +282,20 @@ func authorizeGRPC(ctx context.Context, validator TokenValidator) (string, error
 		if err != nil {
 			continue
 		}
-		projectID, err := validator.ValidateToken(ctx, token)
+		// Dashboard tokens are for the HTTP API's read endpoints only.
+		if _, ok := dashboardTokenID(token); ok {
+			continue
+		}
+		projectID, rotation, err := validateToken(ctx, validator, token)
 		if err == nil {
 			if strings.TrimSpace(projectID) == "" {
 				continue
 			}
-			return projectID, nil
+			return projectID, rotation, nil
 		}
 	}
 
-	return "", errInvalidAuthorizationHeader
+	return "", KeyRotation{}, errInvalidAuthorizationHeader
 }
 
 func parseBearerToken(authorizationHeader string) (string, error) {
@@ -262,9 +313,14 @@ func parseBearerToken(authorizationHeader string) (string, error) {
 	return parts[1], nil
 }
 
-func writeHTTPUnauthorized(w http.ResponseWriter) {
-	w.Header().Set("WWW-Authenticate", "Bearer")
-	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+// writeHTTPAuthError answers a rejected request with a problem, or with the
+// status text as plain text under [LegacyErrors].
+func writeHTTPAuthError(w http.ResponseWriter, r *http.Request, status int) {
+	if LegacyErrorsFromContext(r.Context()) {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	WriteProblem(w, r, Problem{Status: status})
 }
 
 // apiKeyIDFromBearer extracts the API key ID (the part before the dot) from
@@ -275,7 +331,7 @@ func apiKeyIDFromBearer(authHeader string) string {
 		return ""
 	}
 	keyID, _, ok := strings.Cut(token, ".")
-	if !ok || keyID == "" {
+	if !ok || keyID == "" || strings.HasPrefix(keyID, DashboardTokenPrefix) {
 		return ""
 	}
 	return keyID
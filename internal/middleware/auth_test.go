@@ -786,3 +786,24 @@ func (v *testTokenValidator) ValidateToken(_ context.Context, token string) (str
 	}
 	return v.projectID, nil
 }
+
+func TestActorFromContext(t *testing.T) {
+	ctx := context.Background()
+	if actor := ActorFromContext(ctx); actor != (Actor{}) || actor.String() != "" {
+		t.Fatalf("ActorFromContext(empty) = %+v (%q), want zero", actor, actor.String())
+	}
+
+	ctx = NewContextWithAPIKeyID(ctx, "key-1")
+	if got := ActorFromContext(ctx).String(); got != "api_key:key-1" {
+		t.Fatalf("API key actor = %q, want api_key:key-1", got)
+	}
+
+	ctx = NewContextWithAdminUserID(ctx, "user-1")
+	actor := ActorFromContext(ctx)
+	if actor.APIKeyID != "key-1" || actor.AdminUserID != "user-1" {
+		t.Fatalf("ActorFromContext() = %+v, want both IDs", actor)
+	}
+	if got := actor.String(); got != "admin_user:user-1" {
+		t.Fatalf("admin user actor = %q, want admin_user:user-1", got)
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// corsAllowedMethods lists every method the HTTP API routes.
+const corsAllowedMethods = "GET, POST, PUT, DELETE"
+
+// CORSConfig controls which browser origins may call the API.
+type CORSConfig struct {
+	// AllowedOrigins are lower-case origins such as
+	// "https://dash.example.com". "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers a preflight may ask for.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read besides the
+	// CORS-safelisted ones.
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// AllowsOrigin reports whether origin may make cross-origin requests.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	return slices.ContainsFunc(c.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" || allowed == origin
+	})
+}
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// allowed origins. Requests without an Origin header pass through untouched.
+// Credentials are never allowed: browser clients authenticate with a bearer
+// token in the Authorization header rather than cookies.
+//
+// A preflight from an origin that is not allowed is rejected with 403 rather
+// than passed on, so it never reaches authentication.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	return NewCORSPolicy(cfg).Middleware
+}
+
+// CORSPolicy is a [CORSConfig] that can be replaced while its middleware
+// serves requests, so that allowed origins can change without a restart.
+type CORSPolicy struct {
+	current atomic.Pointer[corsPolicy]
+}
+
+// corsPolicy is a CORSConfig with its header values rendered once.
+type corsPolicy struct {
+	CORSConfig
+	wildcard       bool
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+// NewCORSPolicy creates a CORSPolicy that starts out with cfg.
+func NewCORSPolicy(cfg CORSConfig) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.Set(cfg)
+	return p
+}
+
+// Set replaces the policy's config. Requests already past the middleware
+// keep the headers they were given.
+func (p *CORSPolicy) Set(cfg CORSConfig) {
+	p.current.Store(&corsPolicy{
+		CORSConfig:     cfg,
+		wildcard:       slices.Contains(cfg.AllowedOrigins, "*"),
+		allowedHeaders: strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(cfg.ExposedHeaders, ", "),
+		maxAge:         strconv.Itoa(int(cfg.MaxAge / time.Second)),
+	})
+}
+
+// Middleware is [CORS] for the policy's current config. While the config
+// allows no origins every request passes through untouched, as if the
+// middleware were not installed.
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := p.current.Load()
+		origin := r.Header.Get("Origin")
+		if origin == "" || len(cfg.AllowedOrigins) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		allowed := cfg.AllowsOrigin(origin)
+		if allowed {
+			if cfg.wildcard {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+		}
+		if !cfg.wildcard {
+			h.Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			if cfg.allowedHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", cfg.allowedHeaders)
+			}
+			h.Set("Access-Control-Max-Age", cfg.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed && cfg.exposedHeaders != "" {
+			h.Set("Access-Control-Expose-Headers", cfg.exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins: []string{"https://dash.example.com"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+	var reached bool
+	handler := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantReached bool
+		wantOrigin  string
+		wantExpose  string
+		wantMaxAge  string
+	}{
+		{name: "no origin", method: http.MethodGet, wantStatus: http.StatusOK, wantReached: true},
+		{name: "allowed request", method: http.MethodPost, origin: "https://dash.example.com", wantStatus: http.StatusOK, wantReached: true, wantOrigin: "https://dash.example.com", wantExpose: "ETag"},
+		{name: "origin case is ignored", method: http.MethodGet, origin: "https://DASH.example.com", wantStatus: http.StatusOK, wantReached: true, wantOrigin: "https://DASH.example.com", wantExpose: "ETag"},
+		{name: "disallowed request", method: http.MethodGet, origin: "https://evil.example.com", wantStatus: http.StatusOK, wantReached: true},
+		{name: "allowed preflight", method: http.MethodOptions, origin: "https://dash.example.com", preflight: true, wantStatus: http.StatusNoContent, wantOrigin: "https://dash.example.com", wantMaxAge: "600"},
+		{name: "disallowed preflight", method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest(tt.method, "/v1/evaluate", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if reached != tt.wantReached {
+				t.Errorf("next reached = %v, want %v", reached, tt.wantReached)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Expose-Headers"); got != tt.wantExpose {
+				t.Errorf("Expose-Headers = %q, want %q", got, tt.wantExpose)
+			}
+			if got := rec.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if tt.origin != "" && rec.Header().Get("Vary") == "" {
+				t.Error("Vary header missing")
+			}
+			if tt.wantStatus == http.StatusNoContent {
+				if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+					t.Errorf("Allow-Headers = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	handler := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "" {
+		t.Errorf("Vary = %q, want none for a wildcard", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials must not be allowed")
+	}
+}
+
+func TestCORSPolicy_Set(t *testing.T) {
+	policy := NewCORSPolicy(CORSConfig{})
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	preflight := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/flags", nil)
+		req.Header.Set("Origin", "https://dash.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := preflight(); rec.Code != http.StatusOK || rec.Header().Get("Vary") != "" {
+		t.Fatalf("no origins: status = %d, Vary = %q, want request passed through untouched", rec.Code, rec.Header().Get("Vary"))
+	}
+
+	policy.Set(CORSConfig{AllowedOrigins: []string{"https://dash.example.com"}, MaxAge: time.Minute})
+	rec := preflight()
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("allowed origin: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "60" {
+		t.Errorf("Max-Age = %q, want 60", got)
+	}
+
+	policy.Set(CORSConfig{AllowedOrigins: []string{"https://other.example.com"}})
+	if rec := preflight(); rec.Code != http.StatusForbidden {
+		t.Fatalf("removed origin: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
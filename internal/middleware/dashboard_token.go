@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// DashboardTokenPrefix starts the ID of a dashboard token: a short-lived
+// token, minted in the admin portal, that reads a project through the HTTP
+// API. The auth middleware only lets dashboard tokens use
+// [dashboardTokenRoutes], never sets an API key ID for them and rejects them
+// on gRPC.
+const DashboardTokenPrefix = "dash_"
+
+const dashboardTokenIDKey contextKey = "dashboard_token_id"
+
+// DashboardTokenIDFromContext retrieves the ID of the dashboard token a
+// request authenticated with.
+func DashboardTokenIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(dashboardTokenIDKey).(string)
+	return id, ok
+}
+
+// dashboardTokenID returns the ID of token if it is a dashboard token.
+func dashboardTokenID(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok || !strings.HasPrefix(id, DashboardTokenPrefix) {
+		return "", false
+	}
+	return id, true
+}
+
+// dashboardTokenIDFromBearer returns the dashboard token ID of an
+// Authorization header, or "" if it does not carry a dashboard token.
+func dashboardTokenIDFromBearer(authHeader string) string {
+	token, err := parseBearerToken(authHeader)
+	if err != nil {
+		return ""
+	}
+	id, _ := dashboardTokenID(token)
+	return id
+}
+
+// dashboardTokenRoutes are the HTTP routes a dashboard token may use: flag
+// reads and evaluations. Everything else, including reads of API keys,
+// notification channels and the audit log, is refused.
+var dashboardTokenRoutes = []string{
+	"GET /v1/flags",
+	"GET /v1/flags/{key}",
+	"GET /v1/flags/{key}/bucket",
+	"GET /v1/flags/{key}/stats",
+	"GET /v1/flags/{key}/history",
+	"POST /v1/evaluate",
+	"POST /v1/evaluate/all",
+	"POST /v1/evaluate/explain",
+	"GET /v1/stream",
+}
+
+// dashboardTokenHandler passes requests for dashboardTokenRoutes to next
+// and answers every other request with 403 Forbidden.
+func dashboardTokenHandler(next http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range dashboardTokenRoutes {
+		mux.Handle(route, next)
+	}
+	mux.HandleFunc("/", writeReadOnlyTokenError)
+	return mux
+}
+
+func writeReadOnlyTokenError(w http.ResponseWriter, r *http.Request) {
+	if LegacyErrorsFromContext(r.Context()) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	WriteProblem(w, r, Problem{
+		Status: http.StatusForbidden,
+		Type:   ProblemType("read-only-token"),
+		Detail: "dashboard tokens can only read and evaluate flags",
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPBearerAuthMiddlewareDashboardTokens(t *testing.T) {
+	validator := &testTokenValidator{expectedToken: "dash_abc.secret", projectID: "proj-1"}
+	var gotActor Actor
+	var gotKeyID bool
+	handler := HTTPBearerAuthMiddleware(validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotActor = ActorFromContext(r.Context())
+		_, gotKeyID = APIKeyIDFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer dash_abc.secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, route := range []string{
+		"GET /v1/flags",
+		"HEAD /v1/flags",
+		"GET /v1/flags/checkout",
+		"GET /v1/flags/checkout/history",
+		"POST /v1/evaluate",
+		"POST /v1/evaluate/all",
+		"GET /v1/stream",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		if rec := do(method, path); rec.Code != http.StatusNoContent {
+			t.Fatalf("%s status = %d, want %d", route, rec.Code, http.StatusNoContent)
+		}
+	}
+	if gotActor.String() != "dashboard_token:dash_abc" || gotKeyID {
+		t.Errorf("actor = %+v, API key ID set = %v, want the dashboard token and no API key", gotActor, gotKeyID)
+	}
+
+	for _, route := range []string{
+		"POST /v1/flags",
+		"PUT /v1/flags/checkout",
+		"PATCH /v1/flags/checkout",
+		"DELETE /v1/flags/checkout",
+		"GET /v1/api-keys",
+		"GET /v1/notifications/channels",
+		"GET /v1/audit-log",
+		"GET /v1/webhooks/signing-secret",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		rec := do(method, path)
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), ProblemType("read-only-token")) {
+			t.Errorf("%s = %d %s, want 403 read-only-token", route, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestUnaryBearerAuthInterceptorRejectsDashboardTokens(t *testing.T) {
+	validator := &testTokenValidator{projectID: "proj-1"}
+	interceptor := UnaryBearerAuthInterceptor(validator)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer dash_abc.secret"))
+
+	_, err := interceptor(ctx, struct{}{}, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+		t.Fatal("expected handler not to be called")
+		return nil, nil
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated, got %v", status.Code(err))
+	}
+	if validator.called {
+		t.Fatal("expected validator not to be called")
+	}
+}
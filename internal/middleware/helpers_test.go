@@ -4,14 +4,21 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // testServerStream is a minimal grpc.ServerStream for testing interceptors.
 type testServerStream struct {
 	grpc.ServerStream
-	ctx context.Context
+	ctx    context.Context
+	header metadata.MD
 }
 
 func (s *testServerStream) Context() context.Context {
 	return s.ctx
 }
+
+func (s *testServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// KeyRotationDueHeader is set on responses to requests authenticated with
+	// an API key that is overdue for rotation. Its value is the RFC 3339 time
+	// the key became overdue.
+	KeyRotationDueHeader = "Flagz-Key-Rotation-Due"
+	// KeyRotationDueMetadataKey is the gRPC header metadata equivalent of
+	// [KeyRotationDueHeader].
+	KeyRotationDueMetadataKey = "flagz-key-rotation-due"
+	// KeyRevokeAtMetadataKey carries the RFC 3339 time an overdue key will be
+	// revoked, when its project revokes overdue keys. HTTP responses use the
+	// standard Sunset header (RFC 8594) instead.
+	KeyRevokeAtMetadataKey = "flagz-key-revoke-at"
+)
+
+// KeyRotation describes the rotation state of the API key that authenticated
+// a request. The zero value means the key is not overdue.
+type KeyRotation struct {
+	// RotateBy is when the key became overdue for rotation.
+	RotateBy time.Time
+	// RevokeAt is when the key will be revoked automatically; zero if never.
+	RevokeAt time.Time
+}
+
+// Overdue reports whether the key should be rotated.
+func (k KeyRotation) Overdue() bool {
+	return !k.RotateBy.IsZero()
+}
+
+// KeyRotationValidator is a [TokenValidator] that also reports whether the
+// key behind a token is overdue for rotation. The auth middleware checks for
+// it by type assertion and, for overdue keys, warns the client through
+// response headers while still serving the request.
+type KeyRotationValidator interface {
+	TokenValidator
+	ValidateTokenRotation(ctx context.Context, token string) (string, KeyRotation, error)
+}
+
+func validateToken(ctx context.Context, validator TokenValidator, token string) (string, KeyRotation, error) {
+	if rotating, ok := validator.(KeyRotationValidator); ok {
+		return rotating.ValidateTokenRotation(ctx, token)
+	}
+	projectID, err := validator.ValidateToken(ctx, token)
+	return projectID, KeyRotation{}, err
+}
+
+func setKeyRotationHeaders(w http.ResponseWriter, rotation KeyRotation) {
+	if !rotation.Overdue() {
+		return
+	}
+	w.Header().Set(KeyRotationDueHeader, rotation.RotateBy.UTC().Format(time.RFC3339))
+	if !rotation.RevokeAt.IsZero() {
+		w.Header().Set("Sunset", rotation.RevokeAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func keyRotationMetadata(rotation KeyRotation) metadata.MD {
+	if !rotation.Overdue() {
+		return nil
+	}
+	md := metadata.Pairs(KeyRotationDueMetadataKey, rotation.RotateBy.UTC().Format(time.RFC3339))
+	if !rotation.RevokeAt.IsZero() {
+		md.Set(KeyRevokeAtMetadataKey, rotation.RevokeAt.UTC().Format(time.RFC3339))
+	}
+	return md
+}
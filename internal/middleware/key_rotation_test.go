@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testRotationValidator struct {
+	testTokenValidator
+	rotation KeyRotation
+}
+
+func (v *testRotationValidator) ValidateTokenRotation(ctx context.Context, token string) (string, KeyRotation, error) {
+	projectID, err := v.ValidateToken(ctx, token)
+	if err != nil {
+		return "", KeyRotation{}, err
+	}
+	return projectID, v.rotation, nil
+}
+
+type headerCapturingStream struct {
+	testServerStream
+	header metadata.MD
+}
+
+func (s *headerCapturingStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestHTTPBearerAuthMiddlewareKeyRotationHeaders(t *testing.T) {
+	rotateBy := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	revokeAt := rotateBy.Add(7 * 24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		rotation   KeyRotation
+		wantDue    string
+		wantSunset string
+	}{
+		{name: "current key", rotation: KeyRotation{}},
+		{name: "overdue key", rotation: KeyRotation{RotateBy: rotateBy}, wantDue: "2025-03-01T12:00:00Z"},
+		{
+			name:       "overdue key with revocation",
+			rotation:   KeyRotation{RotateBy: rotateBy, RevokeAt: revokeAt},
+			wantDue:    "2025-03-01T12:00:00Z",
+			wantSunset: "Sat, 08 Mar 2025 12:00:00 GMT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &testRotationValidator{
+				testTokenValidator: testTokenValidator{expectedToken: "good", projectID: "proj"},
+				rotation:           tt.rotation,
+			}
+			handlerCalled := false
+			handler := HTTPBearerAuthMiddleware(validator)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				handlerCalled = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+			req.Header.Set("Authorization", "Bearer good")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if !handlerCalled || rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, handler called = %v; overdue keys must still be served", rec.Code, handlerCalled)
+			}
+			if got := rec.Header().Get(KeyRotationDueHeader); got != tt.wantDue {
+				t.Errorf("%s = %q, want %q", KeyRotationDueHeader, got, tt.wantDue)
+			}
+			if got := rec.Header().Get("Sunset"); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+		})
+	}
+}
+
+func TestStreamBearerAuthInterceptorKeyRotationMetadata(t *testing.T) {
+	rotateBy := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	validator := &testRotationValidator{
+		testTokenValidator: testTokenValidator{expectedToken: "good", projectID: "proj"},
+		rotation:           KeyRotation{RotateBy: rotateBy, RevokeAt: rotateBy.Add(time.Hour)},
+	}
+	interceptor := StreamBearerAuthInterceptor(validator)
+	stream := &headerCapturingStream{testServerStream: testServerStream{
+		ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer good")),
+	}}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(any, grpc.ServerStream) error { return nil })
+	if err != nil {
+		t.Fatalf("interceptor() error = %v", err)
+	}
+	if got := stream.header.Get(KeyRotationDueMetadataKey); len(got) != 1 || got[0] != "2025-03-01T12:00:00Z" {
+		t.Errorf("%s = %v, want 2025-03-01T12:00:00Z", KeyRotationDueMetadataKey, got)
+	}
+	if got := stream.header.Get(KeyRevokeAtMetadataKey); len(got) != 1 || got[0] != "2025-03-01T13:00:00Z" {
+		t.Errorf("%s = %v, want 2025-03-01T13:00:00Z", KeyRevokeAtMetadataKey, got)
+	}
+}
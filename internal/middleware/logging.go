@@ -6,13 +6,34 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"maps"
+	mathrand "math/rand/v2"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
+// RequestIDHeader carries the request ID on HTTP requests and responses. A
+// client may set it to correlate a call with its own logs; otherwise the
+// server generates one. gRPC uses the lower-case metadata key
+// [RequestIDMetadataKey].
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMetadataKey is the gRPC metadata key equivalent of
+// [RequestIDHeader], read from request metadata and sent as a response
+// header.
+const RequestIDMetadataKey = "x-request-id"
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
 type logContextKey string
 
 const (
@@ -26,6 +47,11 @@ func RequestIDFromContext(ctx context.Context) (string, bool) {
 	return id, ok
 }
 
+// NewContextWithRequestID returns a new context with the given request ID.
+func NewContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
 // LoggerFromContext retrieves the request-scoped logger from the context.
 // Falls back to slog.Default() if none is set.
 func LoggerFromContext(ctx context.Context) *slog.Logger {
@@ -35,6 +61,39 @@ func LoggerFromContext(ctx context.Context) *slog.Logger {
 	return slog.Default()
 }
 
+// requestID returns incoming if it is a usable request ID, or a new one.
+// Usable IDs are at most 128 printable ASCII characters without spaces, so a
+// client cannot smuggle anything into log lines or response headers.
+func requestID(incoming string) string {
+	if incoming == "" || len(incoming) > maxRequestIDLength {
+		return generateRequestID()
+	}
+	for i := 0; i < len(incoming); i++ {
+		if c := incoming[i]; c <= ' ' || c > '~' {
+			return generateRequestID()
+		}
+	}
+	return incoming
+}
+
+// incomingRequestID returns the request ID a gRPC client sent, if any.
+func incomingRequestID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, RequestIDMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// withRequestID stores reqID and a logger carrying it in ctx, and records it
+// on the current span so traces can be found from a request ID.
+func withRequestID(ctx context.Context, logger *slog.Logger, reqID string) (context.Context, *slog.Logger) {
+	reqLogger := logger.With(slog.String("request_id", reqID))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", reqID))
+	ctx = NewContextWithRequestID(ctx, reqID)
+	ctx = context.WithValue(ctx, loggerKey, reqLogger)
+	return ctx, reqLogger
+}
+
 func generateRequestID() string {
 	var b [8]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -73,53 +132,153 @@ func (rw *responseWriter) Unwrap() http.ResponseWriter {
 	return rw.ResponseWriter
 }
 
+// RedactedHeaderValue replaces the values of credential headers in logs.
+const RedactedHeaderValue = "[REDACTED]"
+
+// redactedHeaders are request headers whose values are never logged.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+}
+
+// defaultRouteLevels are the log levels of routes too busy to log at info.
+var defaultRouteLevels = map[string]slog.Level{
+	"/v1/evaluate": slog.LevelDebug,
+}
+
+// RequestLogOption configures [HTTPRequestLogging].
+type RequestLogOption func(*requestLogConfig)
+
+type requestLogConfig struct {
+	levels      map[string]slog.Level
+	sampleRates map[string]float64
+	sample      func() float64
+}
+
+// WithRouteLevels sets the level requests are logged at by path prefix,
+// overriding the defaults, under which /v1/evaluate and the routes below it
+// are logged at debug and everything else at info. The longest
+// matching prefix applies.
+func WithRouteLevels(levels map[string]slog.Level) RequestLogOption {
+	return func(c *requestLogConfig) {
+		maps.Copy(c.levels, levels)
+	}
+}
+
+// WithRouteSampleRates logs only the given fraction, between 0 and 1, of
+// the requests under each path prefix. The longest matching prefix applies,
+// and requests under no prefix are all logged. Responses with a 5xx status
+// are always logged.
+func WithRouteSampleRates(rates map[string]float64) RequestLogOption {
+	return func(c *requestLogConfig) {
+		c.sampleRates = rates
+	}
+}
+
+// routeValue returns the value of the longest prefix of path in values.
+func routeValue[V any](values map[string]V, path string) (V, bool) {
+	var value V
+	longest, found := -1, false
+	for prefix, v := range values {
+		if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+			value, longest, found = v, len(prefix), true
+		}
+	}
+	return value, found
+}
+
+// loggableHeaders returns the request headers for a debug log line, with
+// credentials replaced by [RedactedHeaderValue].
+func loggableHeaders(header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		value := strings.Join(header[name], ", ")
+		if redactedHeaders[name] {
+			value = RedactedHeaderValue
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
 // HTTPRequestLogging returns middleware that logs each HTTP request with a
-// unique request ID, method, path, status code, and duration.
-func HTTPRequestLogging(logger *slog.Logger) func(http.Handler) http.Handler {
+// request ID, method, path, status code, and duration. The request ID is
+// taken from the [RequestIDHeader] request header when it holds a usable
+// one and generated otherwise; it is returned in the same response header
+// and recorded as the request_id attribute of the current span.
+//
+// Requests are logged at info, or at the level [WithRouteLevels] gives
+// their route; a request logged at debug also has its headers logged, with
+// credentials redacted. [WithRouteSampleRates] logs only some of a route's
+// requests. A response with a 5xx status is always logged, at error.
+func HTTPRequestLogging(logger *slog.Logger, opts ...RequestLogOption) func(http.Handler) http.Handler {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	cfg := requestLogConfig{levels: maps.Clone(defaultRouteLevels), sample: mathrand.Float64}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			reqID := generateRequestID()
-			reqLogger := logger.With(slog.String("request_id", reqID))
+			reqID := requestID(r.Header.Get(RequestIDHeader))
+			ctx, reqLogger := withRequestID(r.Context(), logger, reqID)
+			w.Header().Set(RequestIDHeader, reqID)
 
-			ctx := context.WithValue(r.Context(), requestIDKey, reqID)
-			ctx = context.WithValue(ctx, loggerKey, reqLogger)
+			level, ok := routeValue(cfg.levels, r.URL.Path)
+			if !ok {
+				level = slog.LevelInfo
+			}
+			logged := reqLogger.Enabled(ctx, level)
+			if rate, ok := routeValue(cfg.sampleRates, r.URL.Path); ok && logged {
+				logged = cfg.sample() < rate
+			}
 
-			reqLogger.InfoContext(ctx, "request started",
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-			)
+			if logged {
+				attrs := []any{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("remote_addr", r.RemoteAddr),
+				}
+				if level <= slog.LevelDebug {
+					attrs = append(attrs, loggableHeaders(r.Header))
+				}
+				reqLogger.Log(ctx, level, "request started", attrs...)
+			}
 
 			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 			start := time.Now()
 			next.ServeHTTP(wrapped, r.WithContext(ctx))
 			duration := time.Since(start)
 
-			reqLogger.InfoContext(ctx, "request completed",
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.Int("status_code", wrapped.statusCode),
-				slog.Float64("duration_ms", float64(duration.Nanoseconds())/1e6),
-			)
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				level, logged = slog.LevelError, true
+			}
+			if logged {
+				reqLogger.Log(ctx, level, "request completed",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status_code", wrapped.statusCode),
+					slog.Float64("duration_ms", float64(duration.Nanoseconds())/1e6),
+				)
+			}
 		})
 	}
 }
 
 // UnaryRequestLoggingInterceptor returns a gRPC unary server interceptor that
-// logs each call with a unique request ID, method, status code, and duration.
+// logs each call with a request ID, method, status code, and duration. The
+// request ID is handled as by [HTTPRequestLogging], using the
+// [RequestIDMetadataKey] metadata key.
 func UnaryRequestLoggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
 	if logger == nil {
 		logger = slog.Default()
 	}
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		reqID := generateRequestID()
-		reqLogger := logger.With(slog.String("request_id", reqID))
-
-		ctx = context.WithValue(ctx, requestIDKey, reqID)
-		ctx = context.WithValue(ctx, loggerKey, reqLogger)
+		reqID := requestID(incomingRequestID(ctx))
+		ctx, reqLogger := withRequestID(ctx, logger, reqID)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, reqID))
 
 		reqLogger.InfoContext(ctx, "request started",
 			slog.String("method", info.FullMethod),
@@ -150,18 +309,17 @@ func (l *loggingServerStream) Context() context.Context {
 }
 
 // StreamRequestLoggingInterceptor returns a gRPC stream server interceptor that
-// logs each streaming call with a unique request ID, method, status code, and duration.
+// logs each streaming call with a request ID, method, status code, and
+// duration. The request ID is handled as by [UnaryRequestLoggingInterceptor].
 func StreamRequestLoggingInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		reqID := generateRequestID()
-		reqLogger := logger.With(slog.String("request_id", reqID))
-
-		ctx := context.WithValue(ss.Context(), requestIDKey, reqID)
-		ctx = context.WithValue(ctx, loggerKey, reqLogger)
+		reqID := requestID(incomingRequestID(ss.Context()))
+		ctx, reqLogger := withRequestID(ss.Context(), logger, reqID)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, reqID))
 
 		reqLogger.InfoContext(ctx, "stream started",
 			slog.String("method", info.FullMethod),
@@ -11,6 +11,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -127,6 +128,105 @@ func TestHTTPRequestLogging(t *testing.T) {
 	})
 }
 
+func TestHTTPRequestLogging_RequestIDHeader(t *testing.T) {
+	var seen string
+	handler := HTTPRequestLogging(slog.New(slog.DiscardHandler))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = RequestIDFromContext(r.Context())
+	}))
+
+	for _, tc := range []struct {
+		name, incoming string
+		honored        bool
+	}{
+		{"generated", "", false},
+		{"honored", "client-abc.123", true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"control characters", "abc\r\nInjected: 1", false},
+		{"spaces", "a b", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("%s header = %q, context request ID = %q, want the same non-empty ID", RequestIDHeader, got, seen)
+			}
+			if honored := got == tc.incoming; honored != tc.honored {
+				t.Fatalf("%s header = %q for incoming %q, honored = %v, want %v", RequestIDHeader, got, tc.incoming, honored, tc.honored)
+			}
+		})
+	}
+}
+
+func TestHTTPRequestLogging_RouteLevelsAndSampling(t *testing.T) {
+	status := http.StatusOK
+	inner := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	})
+	serve := func(t *testing.T, level slog.Level, path string, opts ...RequestLogOption) string {
+		t.Helper()
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer key.secret")
+		req.Header.Set("User-Agent", "flagz-test")
+		HTTPRequestLogging(logger, opts...)(inner).ServeHTTP(httptest.NewRecorder(), req)
+		return buf.String()
+	}
+
+	t.Run("evaluations log at debug", func(t *testing.T) {
+		if out := serve(t, slog.LevelInfo, "/v1/evaluate/all"); out != "" {
+			t.Fatalf("evaluation logged at info: %s", out)
+		}
+		out := serve(t, slog.LevelDebug, "/v1/evaluate")
+		if !strings.Contains(out, "level=DEBUG msg=\"request completed\"") {
+			t.Fatalf("expected evaluation logged at debug, got: %s", out)
+		}
+		if !strings.Contains(out, "headers.User-Agent=flagz-test") {
+			t.Fatalf("expected headers in debug log, got: %s", out)
+		}
+		if strings.Contains(out, "key.secret") || !strings.Contains(out, "headers.Authorization=[REDACTED]") {
+			t.Fatalf("expected Authorization redacted, got: %s", out)
+		}
+	})
+
+	t.Run("route levels override defaults", func(t *testing.T) {
+		out := serve(t, slog.LevelInfo, "/v1/evaluate", WithRouteLevels(map[string]slog.Level{"/v1/evaluate": slog.LevelInfo}))
+		if !strings.Contains(out, "level=INFO msg=\"request completed\"") {
+			t.Fatalf("expected evaluation logged at info, got: %s", out)
+		}
+		if strings.Contains(out, "headers.") {
+			t.Fatalf("headers logged at info: %s", out)
+		}
+		out = serve(t, slog.LevelInfo, "/v1/flags/a", WithRouteLevels(map[string]slog.Level{"/v1/flags": slog.LevelWarn, "/v1/flags/a": slog.LevelError}))
+		if !strings.Contains(out, "level=ERROR msg=\"request completed\"") {
+			t.Fatalf("expected longest prefix's level, got: %s", out)
+		}
+	})
+
+	t.Run("sampling skips requests but not server errors", func(t *testing.T) {
+		rates := WithRouteSampleRates(map[string]float64{"/v1/flags": 0})
+		if out := serve(t, slog.LevelInfo, "/v1/flags", rates); out != "" {
+			t.Fatalf("unsampled request logged: %s", out)
+		}
+		if out := serve(t, slog.LevelInfo, "/v1/projects", rates); !strings.Contains(out, "request completed") {
+			t.Fatalf("expected request outside sampled routes logged, got: %s", out)
+		}
+
+		status = http.StatusInternalServerError
+		t.Cleanup(func() { status = http.StatusOK })
+		out := serve(t, slog.LevelInfo, "/v1/flags", rates)
+		if !strings.Contains(out, "level=ERROR msg=\"request completed\"") || strings.Contains(out, "request started") {
+			t.Fatalf("expected only the 5xx completion logged at error, got: %s", out)
+		}
+	})
+}
+
 func TestUnaryRequestLoggingInterceptor(t *testing.T) {
 	t.Run("logs gRPC request with request_id", func(t *testing.T) {
 		var buf bytes.Buffer
@@ -173,6 +273,22 @@ func TestUnaryRequestLoggingInterceptor(t *testing.T) {
 		}
 	})
 
+	t.Run("honors incoming request ID metadata", func(t *testing.T) {
+		interceptor := UnaryRequestLoggingInterceptor(slog.New(slog.DiscardHandler))
+		info := &grpc.UnaryServerInfo{FullMethod: "/flagz.v1.FlagService/GetFlag"}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "client-abc"))
+
+		_, err := interceptor(ctx, "req", info, func(ctx context.Context, req any) (any, error) {
+			if id, _ := RequestIDFromContext(ctx); id != "client-abc" {
+				t.Fatalf("request_id = %q, want client-abc", id)
+			}
+			return "ok", nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
 	t.Run("logs error status code", func(t *testing.T) {
 		var buf bytes.Buffer
 		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
@@ -293,6 +409,9 @@ func TestStreamRequestLoggingInterceptor(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		if got := stream.header.Get(RequestIDMetadataKey); len(got) != 1 || len(got[0]) != 16 {
+			t.Fatalf("expected request_id response header, got %v", got)
+		}
 
 		output := buf.String()
 		if !strings.Contains(output, "stream started") {
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix starts every problem type URI flagz emits. The rest of
+// the URI is a stable slug, such as "not-found" or "invalid-rules", that
+// clients can switch on.
+const ProblemTypePrefix = "urn:flagz:problem:"
+
+const legacyErrorsKey logContextKey = "legacy_errors"
+
+// statusProblemTypes are the generic problem types used when a handler does
+// not name a more specific one.
+var statusProblemTypes = map[int]string{
+	http.StatusBadRequest:            "invalid-request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not-found",
+	http.StatusRequestTimeout:        "request-timeout",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "payload-too-large",
+	http.StatusUnsupportedMediaType:  "unsupported-media-type",
+	http.StatusTooManyRequests:       "rate-limited",
+	http.StatusInternalServerError:   "internal",
+	http.StatusNotImplemented:        "not-implemented",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	// Type is a URI identifying the kind of problem. Defaults to a generic
+	// type for Status.
+	Type string `json:"type"`
+	// Title is a short summary of the problem type. Defaults to the status
+	// text.
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// RequestID is filled in from the request context by [WriteProblem].
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists problems with individual request fields.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a problem with one field of a request. Field is a
+// JSON path such as "key" or "rules[2]".
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// ProblemType returns the problem type URI for slug.
+func ProblemType(slug string) string {
+	return ProblemTypePrefix + slug
+}
+
+// LegacyErrors returns middleware that makes [WriteProblem] write the
+// pre-RFC 7807 {"error": "..."} body, and authentication failures plain
+// text, for every request it serves. It exists for clients that have not
+// moved to problem+json yet.
+func LegacyErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), legacyErrorsKey, true)))
+	})
+}
+
+// LegacyErrorsFromContext reports whether the request is served by
+// [LegacyErrors].
+func LegacyErrorsFromContext(ctx context.Context) bool {
+	legacy, _ := ctx.Value(legacyErrorsKey).(bool)
+	return legacy
+}
+
+// WriteProblem writes p as the response, filling in its type, title and
+// request ID when unset.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.RequestID == "" {
+		p.RequestID, _ = RequestIDFromContext(r.Context())
+	}
+
+	if LegacyErrorsFromContext(r.Context()) {
+		body := map[string]string{"error": p.Detail}
+		if p.RequestID != "" {
+			body["request_id"] = p.RequestID
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(p.Status)
+		_ = json.NewEncoder(w).Encode(body)
+		return
+	}
+
+	if p.Type == "" {
+		if slug, ok := statusProblemTypes[p.Status]; ok {
+			p.Type = ProblemType(slug)
+		} else {
+			p.Type = "about:blank"
+		}
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
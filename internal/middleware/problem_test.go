@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	handler := HTTPRequestLogging(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteProblem(w, r, Problem{
+			Status: http.StatusBadRequest,
+			Detail: "flag key is required",
+			Errors: []FieldError{{Field: "key", Detail: "flag key is required"}},
+		})
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/flags", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q", got, ProblemContentType)
+	}
+	var got Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal problem: %v", err)
+	}
+	if got.Type != "urn:flagz:problem:invalid-request" || got.Title != "Bad Request" || got.Status != http.StatusBadRequest {
+		t.Errorf("problem = %+v, want the generic bad request type and title", got)
+	}
+	if got.RequestID == "" {
+		t.Error("request_id is empty, want the logging middleware's request ID")
+	}
+	if len(got.Errors) != 1 || got.Errors[0].Field != "key" {
+		t.Errorf("errors = %+v, want one for key", got.Errors)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/flags", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	LegacyErrors(handler).ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("legacy Content-Type = %q, want application/json", got)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"error":"flag key is required","request_id":"req-123"}`; got != want {
+		t.Errorf("legacy body = %s, want %s", got, want)
+	}
+}
+
+func TestHTTPBearerAuthMiddleware_ErrorFormat(t *testing.T) {
+	handler := HTTPBearerAuthMiddleware(&testTokenValidator{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("expected next handler not to be called")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+	}
+	if !strings.Contains(rec.Body.String(), `"type":"urn:flagz:problem:unauthorized"`) {
+		t.Errorf("body = %s, want an unauthorized problem", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	LegacyErrors(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != "Unauthorized" {
+		t.Errorf("legacy body = %q, want plain Unauthorized", got)
+	}
+}
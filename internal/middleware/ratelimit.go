@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -25,13 +26,19 @@ type ipEntry struct {
 	lastSeen time.Time
 }
 
-// RateLimiter tracks per-IP failed authentication attempts.
+// RateLimiter tracks per-IP failed authentication attempts, or, as an
+// [IPThrottle], per-IP requests.
 type RateLimiter struct {
 	mu            sync.Mutex
 	entries       map[string]*ipEntry
-	maxPerMinute  int
+	limit         rate.Limit
+	burst         int
 	maxTrackedIPs int
 	cancel        context.CancelFunc
+
+	// limited mirrors limit > 0, so an [IPThrottle] that is turned off
+	// admits requests without taking mu.
+	limited atomic.Bool
 }
 
 // NewRateLimiter creates a new per-IP rate limiter with the given max attempts per minute.
@@ -40,13 +47,19 @@ func NewRateLimiter(ctx context.Context, maxPerMinute int) *RateLimiter {
 	if maxPerMinute <= 0 {
 		maxPerMinute = DefaultMaxAttemptsPerMinute
 	}
+	return newRateLimiter(ctx, rate.Limit(float64(maxPerMinute)/60.0), maxPerMinute)
+}
+
+func newRateLimiter(ctx context.Context, limit rate.Limit, burst int) *RateLimiter {
 	ctx, cancel := context.WithCancel(ctx)
 	rl := &RateLimiter{
 		entries:       make(map[string]*ipEntry),
-		maxPerMinute:  maxPerMinute,
+		limit:         limit,
+		burst:         burst,
 		maxTrackedIPs: DefaultMaxTrackedIPs,
 		cancel:        cancel,
 	}
+	rl.limited.Store(limit > 0)
 	go rl.cleanup(ctx)
 	return rl
 }
@@ -84,15 +97,37 @@ func (rl *RateLimiter) RecordFailureAndAllow(ip string) bool {
 	return e.limiter.Allow()
 }
 
+// SetMaxPerMinute changes the limit given to [NewRateLimiter], for IPs
+// already tracked as well as new ones. Pass 0 to use
+// DefaultMaxAttemptsPerMinute.
+func (rl *RateLimiter) SetMaxPerMinute(maxPerMinute int) {
+	if maxPerMinute <= 0 {
+		maxPerMinute = DefaultMaxAttemptsPerMinute
+	}
+	rl.setLimit(rate.Limit(float64(maxPerMinute)/60.0), maxPerMinute)
+}
+
+func (rl *RateLimiter) setLimit(limit rate.Limit, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limit = limit
+	rl.burst = burst
+	rl.limited.Store(limit > 0)
+	for _, e := range rl.entries {
+		e.limiter.SetLimit(limit)
+		e.limiter.SetBurst(burst)
+	}
+}
+
 func (rl *RateLimiter) getOrCreateEntryLocked(ip string, now time.Time) *ipEntry {
 	e, ok := rl.entries[ip]
 	if !ok {
 		if len(rl.entries) >= rl.maxTrackedIPs {
 			rl.evictOldestLocked()
 		}
-		r := rate.Limit(float64(rl.maxPerMinute) / 60.0)
 		e = &ipEntry{
-			limiter:  rate.NewLimiter(r, rl.maxPerMinute),
+			limiter:  rate.NewLimiter(rl.limit, rl.burst),
 			lastSeen: now,
 		}
 		rl.entries[ip] = e
@@ -46,6 +46,28 @@ func TestRateLimiter_ExceedLimit(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_SetMaxPerMinute(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewRateLimiter(ctx, 1)
+	defer rl.Stop()
+
+	rl.RecordFailure("10.0.0.1")
+	if rl.Allow("10.0.0.1") {
+		t.Fatal("Allow should return false after exceeding limit")
+	}
+
+	rl.SetMaxPerMinute(5)
+	for i := range 4 {
+		if !rl.RecordFailureAndAllow("10.0.0.2") {
+			t.Fatalf("new IP: failure %d not allowed under raised limit", i)
+		}
+	}
+	if got := rl.entries["10.0.0.1"].limiter.Burst(); got != 5 {
+		t.Errorf("tracked IP burst = %d, want 5", got)
+	}
+}
+
 func TestRateLimiter_DifferentIPsIndependent(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// NewIPThrottle creates a per-IP request limiter that admits perSecond
+// requests a second from each client IP, in bursts of up to burst. With
+// perSecond <= 0 it admits every request. Call Stop when done with it.
+func NewIPThrottle(ctx context.Context, perSecond float64, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return newRateLimiter(ctx, rate.Limit(max(perSecond, 0)), burst)
+}
+
+// SetPerSecond changes the limit given to [NewIPThrottle], for IPs already
+// tracked as well as new ones. perSecond <= 0 turns throttling off.
+func (rl *RateLimiter) SetPerSecond(perSecond float64, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+	rl.setLimit(rate.Limit(max(perSecond, 0)), burst)
+}
+
+// throttle records a request from ip and reports whether it is within the
+// limit. Every request is admitted while the limit is 0, without locking.
+func (rl *RateLimiter) throttle(ip string) bool {
+	if !rl.limited.Load() {
+		return true
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.limit <= 0 {
+		return true
+	}
+	return rl.getOrCreateEntryLocked(ip, time.Now()).limiter.Allow()
+}
+
+// IPThrottle answers requests from a client IP over rl's limit with
+// 429 Too Many Requests and a Retry-After header, before they are
+// authenticated or reach a handler. The client IP is the connection's
+// remote address; forwarding headers are not trusted.
+func IPThrottle(rl *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := ExtractIP(r.RemoteAddr); ip != "" && !rl.throttle(ip) {
+				w.Header().Set("Retry-After", "1")
+				writeHTTPAuthError(w, r, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
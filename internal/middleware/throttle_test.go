@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIPThrottle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewIPThrottle(ctx, 0.001, 2)
+	defer rl.Stop()
+
+	handler := IPThrottle(rl)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := do("10.0.0.1:1234"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, http.StatusNoContent)
+		}
+	}
+
+	rec := do("10.0.0.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("Retry-After header missing")
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", got)
+	}
+
+	if rec := do("10.0.0.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("other IP: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestIPThrottle_LegacyErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewIPThrottle(ctx, 0.001, 1)
+	defer rl.Stop()
+
+	handler := LegacyErrors(IPThrottle(rl)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	var rec *httptest.ResponseRecorder
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+}
+
+func TestIPThrottle_SetPerSecond(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewIPThrottle(ctx, 0, 1)
+	defer rl.Stop()
+
+	handler := IPThrottle(rl)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/flags", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := range 3 {
+		if code := do(); code != http.StatusNoContent {
+			t.Fatalf("unthrottled request %d: status = %d, want %d", i, code, http.StatusNoContent)
+		}
+	}
+
+	rl.SetPerSecond(0.001, 1)
+	if code := do(); code != http.StatusNoContent {
+		t.Fatalf("first throttled request: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := do(); code != http.StatusTooManyRequests {
+		t.Fatalf("second throttled request: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	rl.SetPerSecond(0, 0)
+	if code := do(); code != http.StatusNoContent {
+		t.Fatalf("after turning throttling off: status = %d, want %d", code, http.StatusNoContent)
+	}
+}
+
+func TestIPThrottle_OffDoesNotLock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewIPThrottle(ctx, 0.001, 1)
+	defer rl.Stop()
+	rl.SetPerSecond(0, 0)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	admitted := make(chan bool, 1)
+	go func() { admitted <- rl.throttle("10.0.0.1") }()
+	select {
+	case ok := <-admitted:
+		if !ok {
+			t.Fatal("throttle() = false with throttling off")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("throttle() waited for the lock with throttling off")
+	}
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// SMTPConfig is the mail server email notifications are sent through.
+type SMTPConfig struct {
+	// Addr is the server's host:port. STARTTLS is used when the server
+	// offers it.
+	Addr string
+	// From is the sender address.
+	From string
+	// Username and Password authenticate with PLAIN auth when Username is
+	// set. Go refuses PLAIN auth without TLS except to localhost.
+	Username string
+	Password string
+}
+
+var errEmailNotConfigured = errors.New("email notifications are not configured")
+
+func (r *Router) sendEmail(to string, event repository.FlagEvent) error {
+	if r.smtp == nil {
+		return errEmailNotConfigured
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid email recipient %q: %w", to, err)
+	}
+
+	var auth smtp.Auth
+	if r.smtp.Username != "" {
+		host, _, err := net.SplitHostPort(r.smtp.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address %q: %w", r.smtp.Addr, err)
+		}
+		auth = smtp.PlainAuth("", r.smtp.Username, r.smtp.Password, host)
+	}
+
+	msg := emailMessage(r.smtp.From, recipient.Address, event, time.Now())
+	if err := r.sendMail(r.smtp.Addr, auth, r.smtp.From, []string{recipient.Address}, msg); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	return nil
+}
+
+// emailMessage formats event as a plain text email, with the flag as it
+// was after the change below the summary.
+func emailMessage(from, to string, event repository.FlagEvent, now time.Time) []byte {
+	summary := Summary(event)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", encodeHeader("[flagz] "+summary))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(summary + ".\r\n")
+	fmt.Fprintf(&b, "\r\nEvent ID: %d\r\n", event.EventID)
+
+	var payload bytes.Buffer
+	if len(event.Payload) > 0 && json.Indent(&payload, event.Payload, "", "  ") == nil {
+		b.WriteString("\r\n")
+		b.WriteString(strings.ReplaceAll(payload.String(), "\n", "\r\n"))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+// encodeHeader encodes a header value so that non-ASCII text and line
+// breaks cannot corrupt the message.
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(value))
+}
@@ -0,0 +1,76 @@
+// Package notify announces flag changes on the channels a project has
+// configured: Slack incoming webhooks, email over SMTP and signed JSON
+// webhooks.
+//
+// A [Router] delivers one flag event to one channel in a single attempt;
+// the service decides which channels an event goes to, retries failed
+// deliveries and records the outcome.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Channel kinds.
+const (
+	KindSlack = "slack"
+	KindEmail = "email"
+	// KindWebhook posts the event as JSON, signed with the channel's
+	// SigningSecrets.
+	KindWebhook = "webhook"
+)
+
+// Router sends flag events to notification channels of any kind.
+type Router struct {
+	httpClient *http.Client
+	smtp       *SMTPConfig
+	sendMail   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewRouter returns a Router that posts to Slack and webhooks with
+// httpClient, or a client with a 10 second timeout when it is nil, and sends
+// email through smtpConfig. With a nil smtpConfig every email delivery fails.
+func NewRouter(httpClient *http.Client, smtpConfig *SMTPConfig) *Router {
+	return &Router{httpClient: httpClient, smtp: smtpConfig, sendMail: smtp.SendMail}
+}
+
+// SendNotification delivers event to channel once.
+func (r *Router) SendNotification(ctx context.Context, channel repository.NotificationChannel, event repository.FlagEvent) error {
+	switch channel.Kind {
+	case KindSlack:
+		return r.sendSlack(ctx, channel.Target, event)
+	case KindEmail:
+		return r.sendEmail(channel.Target, event)
+	case KindWebhook:
+		return r.sendWebhook(ctx, channel, event)
+	default:
+		return fmt.Errorf("unknown notification channel kind %q", channel.Kind)
+	}
+}
+
+// Summary is a one-line description of event, such as
+//
+//	Flag "checkout" was updated (enabled) by admin:alice in project 1111…
+func Summary(event repository.FlagEvent) string {
+	text := fmt.Sprintf("Flag %q was %s", event.FlagKey, event.EventType)
+	var flag struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if json.Unmarshal(event.Payload, &flag) == nil && flag.Enabled != nil {
+		if *flag.Enabled {
+			text += " (enabled)"
+		} else {
+			text += " (disabled)"
+		}
+	}
+	if event.Actor != "" {
+		text += " by " + event.Actor
+	}
+	return text + " in project " + event.ProjectID
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/webhook"
+)
+
+var testEvent = repository.FlagEvent{
+	EventID:   42,
+	ProjectID: "proj-1",
+	FlagKey:   "checkout",
+	EventType: "updated",
+	Payload:   json.RawMessage(`{"key":"checkout","enabled":true}`),
+	Actor:     "admin:alice",
+}
+
+func TestSummary(t *testing.T) {
+	if got, want := Summary(testEvent), `Flag "checkout" was updated (enabled) by admin:alice in project proj-1`; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	deleted := repository.FlagEvent{ProjectID: "proj-1", FlagKey: "old", EventType: "deleted", Payload: json.RawMessage(`{}`)}
+	if got, want := Summary(deleted), `Flag "old" was deleted in project proj-1`; got != want {
+		t.Errorf("Summary(deleted) = %q, want %q", got, want)
+	}
+}
+
+func TestRouterSlack(t *testing.T) {
+	var got slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	router := NewRouter(srv.Client(), nil)
+	channel := repository.NotificationChannel{Kind: KindSlack, Target: srv.URL + "/services/T000/B000"}
+	if err := router.SendNotification(context.Background(), channel, testEvent); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if got.Text != Summary(testEvent) {
+		t.Errorf("text = %q, want %q", got.Text, Summary(testEvent))
+	}
+}
+
+func TestRouterEmail(t *testing.T) {
+	channel := repository.NotificationChannel{Kind: KindEmail, Target: "Ops <ops@example.com>"}
+	if err := NewRouter(nil, nil).SendNotification(context.Background(), channel, testEvent); !errors.Is(err, errEmailNotConfigured) {
+		t.Fatalf("SendNotification() without SMTP error = %v, want errEmailNotConfigured", err)
+	}
+
+	router := NewRouter(nil, &SMTPConfig{Addr: "smtp.example.com:587", From: "flagz@example.com", Username: "flagz", Password: "secret"})
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+	router.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, msg
+		return nil
+	}
+	if err := router.SendNotification(context.Background(), channel, testEvent); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotFrom != "flagz@example.com" || len(gotTo) != 1 || gotTo[0] != "ops@example.com" || gotAuth == nil {
+		t.Fatalf("sendMail(%q, %v, %q, %v), want the configured server, auth and the bare recipient", gotAddr, gotAuth, gotFrom, gotTo)
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{
+		"To: ops@example.com\r\n",
+		`Subject: [flagz] Flag "checkout" was updated (enabled) by admin:alice in project proj-1` + "\r\n",
+		"Event ID: 42\r\n",
+		`"enabled": true`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+
+	channel.Target = "not an address"
+	if err := router.SendNotification(context.Background(), channel, testEvent); err == nil {
+		t.Error("SendNotification() to an invalid address error = nil")
+	}
+}
+
+func TestRouterWebhook(t *testing.T) {
+	var got webhookEvent
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.SignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	router := NewRouter(srv.Client(), nil)
+	channel := repository.NotificationChannel{Kind: KindWebhook, Target: srv.URL + "/hooks/flagz"}
+	if err := router.SendNotification(context.Background(), channel, testEvent); !errors.Is(err, errWebhookNotSigned) {
+		t.Fatalf("SendNotification() without a signing secret error = %v, want errWebhookNotSigned", err)
+	}
+
+	channel.SigningSecrets = []string{"whsec_test"}
+	if err := router.SendNotification(context.Background(), channel, testEvent); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if got.Type != "flag.updated" || got.EventID != 42 || got.FlagKey != "checkout" || got.Actor != "admin:alice" || string(got.Flag) != string(testEvent.Payload) {
+		t.Errorf("event = %+v, want the flag event", got)
+	}
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		t.Fatalf("%s = %q: %v", webhook.SignatureHeader, signature, err)
+	}
+	if want := webhook.Sign(body, time.Unix(unix, 0), "whsec_test"); signature != want {
+		t.Errorf("%s = %q, want %q", webhook.SignatureHeader, signature, want)
+	}
+}
+
+func TestEmailMessageEncodesSubject(t *testing.T) {
+	event := testEvent
+	event.FlagKey = "café\r\nBcc: victim@example.com"
+	msg := string(emailMessage("flagz@example.com", "ops@example.com", event, time.Unix(0, 0)))
+	headers, _, _ := strings.Cut(msg, "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Fatalf("headers contain an injected Bcc:\n%s", headers)
+	}
+	if !strings.Contains(headers, "Subject: =?utf-8?q?") {
+		t.Errorf("subject not encoded:\n%s", headers)
+	}
+}
@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/webhook"
+)
+
+// slackMessage is the body of a Slack incoming webhook request.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (r *Router) sendSlack(ctx context.Context, webhookURL string, event repository.FlagEvent) error {
+	sender, err := webhook.NewSender(webhookURL, r.httpClient)
+	if err != nil {
+		return err
+	}
+	return sender.Send(ctx, slackMessage{Text: Summary(event)})
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/webhook"
+)
+
+var errWebhookNotSigned = errors.New("webhook channel has no signing secret")
+
+// webhookEvent is the body of a delivery to a webhook channel.
+type webhookEvent struct {
+	Type      string          `json:"type"`
+	EventID   int64           `json:"event_id"`
+	ProjectID string          `json:"project_id"`
+	FlagKey   string          `json:"flag_key"`
+	Actor     string          `json:"actor,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Flag      json.RawMessage `json:"flag"`
+}
+
+// sendWebhook posts event to the channel's URL, signed with its signing
+// secrets. Deliveries are never sent unsigned.
+func (r *Router) sendWebhook(ctx context.Context, channel repository.NotificationChannel, event repository.FlagEvent) error {
+	if len(channel.SigningSecrets) == 0 {
+		return errWebhookNotSigned
+	}
+	sender, err := webhook.NewSender(channel.Target, r.httpClient)
+	if err != nil {
+		return err
+	}
+	return sender.SendSigned(ctx, webhookEvent{
+		Type:      "flag." + event.EventType,
+		EventID:   event.EventID,
+		ProjectID: event.ProjectID,
+		FlagKey:   event.FlagKey,
+		Actor:     event.Actor,
+		CreatedAt: event.CreatedAt,
+		Flag:      event.Payload,
+	}, channel.SigningSecrets...)
+}
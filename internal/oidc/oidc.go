@@ -0,0 +1,495 @@
+// Package oidc implements the parts of OpenID Connect the admin portal needs
+// to sign users in with an external identity provider: discovery, the
+// authorization code flow with PKCE, and ID token verification.
+//
+// Only ID tokens signed with RS256, RS384, RS512 or ES256 are accepted; the
+// signing keys are read from the provider's JWKS document and fetched again
+// when a token names a key that is not cached. Provider metadata is
+// discovered on first use rather than at startup, so an identity provider
+// outage only affects sign-in.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// clockSkew is how far token timestamps may be off from the local clock.
+	clockSkew = time.Minute
+
+	maxResponseSize = 1 << 20
+)
+
+// ErrInvalidToken is returned when an ID token fails verification.
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Provider signs users in with one OpenID Connect issuer.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+	now          func() time.Time
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]crypto.PublicKey
+}
+
+// metadata is the subset of the discovery document the provider uses.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets the client used to reach the provider.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithScopes adds scopes to request besides "openid", such as "groups" for
+// providers that only include group claims when asked.
+func WithScopes(scopes ...string) Option {
+	return func(p *Provider) {
+		p.scopes = append(p.scopes, scopes...)
+	}
+}
+
+// New returns a Provider for issuer. redirectURL is the callback URL
+// registered with the provider for clientID.
+func New(issuer, clientID, clientSecret, redirectURL string, opts ...Option) *Provider {
+	p := &Provider{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       []string{"openid", "profile", "email"},
+		client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// AuthRequest is the per-login state that must survive the redirect to the
+// provider and back.
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewAuthRequest returns random state, nonce and PKCE verifier values.
+func NewAuthRequest() (AuthRequest, error) {
+	var values [3]string
+	for i := range values {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return AuthRequest{}, fmt.Errorf("generate auth request: %w", err)
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return AuthRequest{State: values[0], Nonce: values[1], Verifier: values[2]}, nil
+}
+
+// AuthCodeURL returns the provider URL to send the user to for req.
+func (p *Provider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {strings.Join(p.scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return md.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified claims of
+// the ID token, which must carry req's nonce.
+func (p *Provider) Exchange(ctx context.Context, code string, req AuthRequest) (Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {req.Verifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("build token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(httpReq, &token); err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("exchange code: token response has no id_token")
+	}
+	claims, err := p.Verify(ctx, token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != req.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// Verify checks an ID token's signature, issuer, audience and expiry and
+// returns its claims. The token's issuer must be exactly the one the
+// discovery document names, which may end in a slash, as Auth0's does.
+func (p *Provider) Verify(ctx context.Context, rawToken string) (Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if iss, _ := claims["iss"].(string); iss != md.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+	}
+	if !slices.Contains(claims.Strings("aud"), p.clientID) {
+		return nil, fmt.Errorf("%w: token is not for this client", ErrInvalidToken)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.clientID {
+		return nil, fmt.Errorf("%w: token was issued to another party", ErrInvalidToken)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if p.now().After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if claims.Subject() == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// discover fetches and caches the provider's discovery document. A failed
+// fetch is not cached, so the next sign-in tries again. The document may
+// name the configured issuer with or without a trailing slash.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build discovery request: %w", err)
+	}
+	var md metadata
+	if err := p.do(req, &md); err != nil {
+		return nil, fmt.Errorf("discover OIDC provider: %w", err)
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != strings.TrimSuffix(p.issuer, "/") {
+		return nil, fmt.Errorf("discover OIDC provider: document is for issuer %q", md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("discover OIDC provider: document is missing an endpoint")
+	}
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// key returns the signing key kid, fetching the JWKS document when it is
+// not cached. An empty kid matches the only key of a single-key set.
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build JWKS request: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.do(req, &set); err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the set.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	p.keys = keys
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) do(req *http.Request, dst any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("%s: %s %s", resp.Status, oauthErr.Error, oauthErr.Description)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.Unmarshal(body, dst)
+}
+
+// jwk is a JSON Web Key, limited to the RSA and EC fields.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("unsupported RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed point encoding, which ecdsa.ParseUncompressedPublicKey
+		// validates lies on the curve.
+		point := append([]byte{4}, append(leftPad(x, 32), leftPad(y, 32)...)...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %q does not match an RSA key", alg)
+		}
+		return rsa.VerifyPKCS1v15(key, hash, digest, sig)
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(sig) != 64 {
+			return fmt.Errorf("algorithm %q does not match an EC key", alg)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("bad signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported key")
+	}
+}
+
+func decodeSegment(segment string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// Claims are the decoded claims of a verified ID token.
+type Claims map[string]any
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Username returns the first non-empty of the "preferred_username", "email"
+// and "sub" claims.
+func (c Claims) Username() string {
+	for _, name := range []string{"preferred_username", "email"} {
+		if v, _ := c[name].(string); v != "" {
+			return v
+		}
+	}
+	return c.Subject()
+}
+
+// Strings returns the claim at path as a list of strings. A dotted path
+// reaches into nested objects, such as "realm_access.roles" for Keycloak. A
+// string claim is returned as a one-element list; a space-separated string
+// is not split. Non-string list elements are skipped.
+func (c Claims) Strings(path string) []string {
+	var v any = map[string]any(c)
+	for name := range strings.SplitSeq(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[name]
+	}
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
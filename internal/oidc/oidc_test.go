@@ -0,0 +1,282 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIssuer is an identity provider that issues an ID token carrying claims
+// for any code it is asked to redeem.
+type testIssuer struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	claims map[string]any
+	alg    string
+	form   url.Values
+	// slash makes the issuer identify itself with a trailing slash, as
+	// Auth0 does.
+	slash bool
+}
+
+// issuer is the identifier the issuer publishes in discovery and tokens.
+func (iss *testIssuer) issuer() string {
+	if iss.slash {
+		return iss.URL + "/"
+	}
+	return iss.URL
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey, alg: "RS256"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 iss.issuer(),
+			"authorization_endpoint": iss.URL + "/authorize",
+			"token_endpoint":         iss.URL + "/token",
+			"jwks_uri":               iss.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		ecBytes, _ := ecKey.PublicKey.Bytes()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecBytes[1:33]), "y": b64(ecBytes[33:])},
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		iss.form = r.PostForm
+		if id, secret, _ := r.BasicAuth(); id != "flagz" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": iss.sign(t, iss.claims)})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func (iss *testIssuer) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	kid := map[string]string{"RS256": "rsa", "ES256": "ec"}[iss.alg]
+	header, _ := json.Marshal(map[string]string{"alg": iss.alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	if iss.alg == "ES256" {
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (iss *testIssuer) validClaims(nonce string) map[string]any {
+	return map[string]any{
+		"iss":                iss.issuer(),
+		"aud":                "flagz",
+		"sub":                "user-1",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice",
+		"groups":             []string{"flagz-admins", "everyone"},
+	}
+}
+
+func TestProviderLogin(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := New(iss.URL+"/", "flagz", "s3cret", "https://admin.example/oidc/callback", WithScopes("groups"))
+	ctx := context.Background()
+
+	req, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, req)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != req.State || q.Get("nonce") != req.Nonce || q.Get("client_id") != "flagz" {
+		t.Fatalf("AuthCodeURL() = %s", authURL)
+	}
+	if q.Get("scope") != "openid profile email groups" {
+		t.Errorf("scope = %q", q.Get("scope"))
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+	if q.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("PKCE challenge = %q (%s)", q.Get("code_challenge"), q.Get("code_challenge_method"))
+	}
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			iss.alg = alg
+			iss.claims = iss.validClaims(req.Nonce)
+			claims, err := p.Exchange(ctx, "code-1", req)
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if iss.form.Get("code") != "code-1" || iss.form.Get("code_verifier") != req.Verifier {
+				t.Errorf("token request form = %v", iss.form)
+			}
+			if claims.Subject() != "user-1" || claims.Username() != "alice" {
+				t.Errorf("claims = %v", claims)
+			}
+			if got := claims.Strings("groups"); !slices.Equal(got, []string{"flagz-admins", "everyone"}) {
+				t.Errorf("Strings(groups) = %v", got)
+			}
+		})
+	}
+}
+
+func TestProviderIssuerWithTrailingSlash(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.slash = true
+	p := New(iss.URL+"/", "flagz", "s3cret", "https://admin.example/oidc/callback")
+	req, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iss.claims = iss.validClaims(req.Nonce)
+	if _, err := p.Exchange(context.Background(), "code", req); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	iss.claims = iss.validClaims(req.Nonce)
+	iss.claims["iss"] = iss.URL
+	if _, err := p.Exchange(context.Background(), "code", req); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Exchange() with unslashed iss error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestProviderExchangeRejectsTokens(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := New(iss.URL, "flagz", "s3cret", "https://admin.example/oidc/callback")
+	req, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]any)
+	}{
+		{name: "wrong nonce", modify: func(c map[string]any) { c["nonce"] = "replayed" }},
+		{name: "wrong issuer", modify: func(c map[string]any) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", modify: func(c map[string]any) { c["aud"] = []string{"other-client"} }},
+		{name: "other authorized party", modify: func(c map[string]any) { c["aud"] = []string{"flagz", "other"}; c["azp"] = "other" }},
+		{name: "expired", modify: func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "no subject", modify: func(c map[string]any) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iss.claims = iss.validClaims(req.Nonce)
+			tt.modify(iss.claims)
+			if _, err := p.Exchange(context.Background(), "code", req); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Exchange() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	t.Run("tampered signature", func(t *testing.T) {
+		token := iss.sign(t, iss.validClaims(req.Nonce))
+		parts := strings.Split(token, ".")
+		forged, _ := json.Marshal(map[string]any{"iss": iss.URL, "aud": "flagz", "sub": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+		parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+		if _, err := p.Verify(context.Background(), strings.Join(parts, ".")); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify() error = %v, want ErrInvalidToken", err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+		payload, _ := json.Marshal(iss.validClaims(req.Nonce))
+		token := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		if _, err := p.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify() error = %v, want ErrInvalidToken", err)
+		}
+	})
+
+	t.Run("bad client secret", func(t *testing.T) {
+		iss.claims = iss.validClaims(req.Nonce)
+		bad := New(iss.URL, "flagz", "wrong", "https://admin.example/oidc/callback")
+		_, err := bad.Exchange(context.Background(), "code", req)
+		if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("Exchange() error = %v, want invalid_client", err)
+		}
+	})
+}
+
+func TestClaimsStrings(t *testing.T) {
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{
+		"groups": ["a", 1, "b"],
+		"role": "admin",
+		"realm_access": {"roles": ["flagz-viewer"]}
+	}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "groups", want: []string{"a", "b"}},
+		{path: "role", want: []string{"admin"}},
+		{path: "realm_access.roles", want: []string{"flagz-viewer"}},
+		{path: "realm_access.missing", want: nil},
+		{path: "role.nested", want: nil},
+	}
+	for _, tt := range tests {
+		if got := claims.Strings(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("Strings(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
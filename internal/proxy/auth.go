@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Upstream verdicts on a token are reused for these long, so a revoked
+	// key keeps working on the proxy for at most validTokenTTL.
+	validTokenTTL   = time.Minute
+	invalidTokenTTL = 10 * time.Second
+	// maxCachedTokens bounds the verdict cache; it is cleared when full.
+	maxCachedTokens = 10000
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// TokenValidator implements middleware.TokenValidator by asking the upstream
+// server whether a token is valid. A token is accepted when it authenticates
+// upstream and belongs to the same project as the proxy's own API key;
+// accepted callers are scoped to [ProjectID].
+type TokenValidator struct {
+	baseURL    string
+	ownKeyID   string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu       sync.Mutex
+	verdicts map[[sha256.Size]byte]tokenVerdict
+}
+
+type tokenVerdict struct {
+	valid   bool
+	expires time.Time
+}
+
+// NewTokenValidator returns a TokenValidator for the upstream server at
+// baseURL, where the proxy itself authenticates with apiKey. A nil
+// httpClient uses http.DefaultClient.
+func NewTokenValidator(baseURL, apiKey string, httpClient *http.Client) *TokenValidator {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	ownKeyID, _, _ := strings.Cut(apiKey, ".")
+	return &TokenValidator{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		ownKeyID:   ownKeyID,
+		httpClient: httpClient,
+		now:        time.Now,
+		verdicts:   make(map[[sha256.Size]byte]tokenVerdict),
+	}
+}
+
+// ValidateToken returns [ProjectID] if token is accepted by the upstream
+// server for the proxy's project.
+func (v *TokenValidator) ValidateToken(ctx context.Context, token string) (string, error) {
+	keyID, secret, found := strings.Cut(token, ".")
+	if !found || strings.TrimSpace(keyID) == "" || secret == "" {
+		return "", errors.New("invalid token format")
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	now := v.now()
+	v.mu.Lock()
+	verdict, ok := v.verdicts[sum]
+	v.mu.Unlock()
+	if ok && now.Before(verdict.expires) {
+		if !verdict.valid {
+			return "", errInvalidToken
+		}
+		return ProjectID, nil
+	}
+
+	valid, err := v.checkUpstream(ctx, token)
+	if err != nil {
+		// Not cached: an unreachable upstream is not a verdict on the token.
+		return "", err
+	}
+	ttl := invalidTokenTTL
+	if valid {
+		ttl = validTokenTTL
+	}
+	v.mu.Lock()
+	if len(v.verdicts) >= maxCachedTokens {
+		clear(v.verdicts)
+	}
+	v.verdicts[sum] = tokenVerdict{valid: valid, expires: now.Add(ttl)}
+	v.mu.Unlock()
+
+	if !valid {
+		return "", errInvalidToken
+	}
+	return ProjectID, nil
+}
+
+// checkUpstream lists the API keys of the token's project upstream. The
+// token belongs to the proxy's project if the proxy's own key is among them.
+func (v *TokenValidator) checkUpstream(ctx context.Context, token string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/v1/api-keys", nil)
+	if err != nil {
+		return false, fmt.Errorf("create upstream auth request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("upstream auth request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, fmt.Errorf("upstream auth request: unexpected status %d", resp.StatusCode)
+	}
+
+	var keys []struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return false, fmt.Errorf("decode upstream api keys: %w", err)
+	}
+	for _, key := range keys {
+		if key.ID == v.ownKeyID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
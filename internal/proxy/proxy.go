@@ -0,0 +1,268 @@
+// Package proxy runs flagz as a read-only evaluation proxy in front of an
+// upstream flagz server, without a database of its own.
+//
+// A [Syncer] bootstraps a snapshot of the upstream project's flags with the
+// Go client and then follows its event stream, keeping a [Repository] in
+// memory. The repository satisfies [service.Repository], so the usual
+// service, HTTP and gRPC layers serve flag reads, evaluations and streams
+// from it unchanged. Writes are refused: [ReadOnlyHandler] and the gRPC
+// interceptors reject every mutating endpoint before it reaches the
+// service, and the repository itself returns an error for any write that
+// gets through.
+//
+// A proxy serves exactly one upstream project, the one owning its upstream
+// API key. Callers authenticate with their own upstream API keys, which
+// [TokenValidator] checks against the upstream server.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// ProjectID is the local project that holds the upstream project's flags.
+// Every caller accepted by [TokenValidator] is scoped to it.
+const ProjectID = "upstream"
+
+// defaultMaxEvents bounds the in-memory event log replayed to streaming
+// clients that reconnect with a Last-Event-ID.
+const defaultMaxEvents = 10000
+
+var errReadOnly = errors.New("proxy is read-only")
+
+// Repository is an in-memory, read-only [service.Repository] holding the
+// flags mirrored from the upstream server. It is safe for concurrent use.
+type Repository struct {
+	mu        sync.RWMutex
+	flags     map[string]repository.Flag
+	events    []repository.FlagEvent
+	maxEvents int
+
+	subsMu sync.Mutex
+	subs   map[chan repository.FlagInvalidation]struct{}
+
+	listening atomic.Bool
+}
+
+// NewRepository returns an empty Repository.
+func NewRepository() *Repository {
+	return &Repository{
+		flags:     make(map[string]repository.Flag),
+		maxEvents: defaultMaxEvents,
+		subs:      make(map[chan repository.FlagInvalidation]struct{}),
+	}
+}
+
+// GetFlag returns a mirrored flag. Returns pgx.ErrNoRows (wrapped) if the
+// flag does not exist.
+func (r *Repository) GetFlag(_ context.Context, projectID, key string) (repository.Flag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	flag, ok := r.flags[key]
+	if !ok || projectID != ProjectID {
+		return repository.Flag{}, fmt.Errorf("get flag %q: %w", key, pgx.ErrNoRows)
+	}
+	return flag, nil
+}
+
+// ListFlags returns every mirrored flag.
+func (r *Repository) ListFlags(context.Context) ([]repository.Flag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	flags := make([]repository.Flag, 0, len(r.flags))
+	for _, flag := range r.flags {
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// ListEventsSince returns the retained upstream events after eventID.
+func (r *Repository) ListEventsSince(_ context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
+	return r.eventsSince(projectID, eventID, ""), nil
+}
+
+// ListEventsSinceForKey returns the retained upstream events for key after
+// eventID.
+func (r *Repository) ListEventsSinceForKey(_ context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error) {
+	return r.eventsSince(projectID, eventID, key), nil
+}
+
+// ListAllEventsSince returns the retained upstream events after eventID. All
+// of them belong to [ProjectID].
+func (r *Repository) ListAllEventsSince(_ context.Context, eventID int64) ([]repository.FlagEvent, error) {
+	return r.eventsSince(ProjectID, eventID, ""), nil
+}
+
+// MaxEventID returns [Repository.LastEventID].
+func (r *Repository) MaxEventID(context.Context) (int64, error) {
+	return r.LastEventID(), nil
+}
+
+func (r *Repository) eventsSince(projectID string, eventID int64, key string) []repository.FlagEvent {
+	if projectID != ProjectID {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []repository.FlagEvent
+	for _, event := range r.events {
+		if event.EventID <= eventID || (key != "" && event.FlagKey != key) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// CreateFlag always fails: flags are managed on the upstream server.
+func (r *Repository) CreateFlag(context.Context, repository.Flag) (repository.Flag, error) {
+	return repository.Flag{}, errReadOnly
+}
+
+// UpdateFlag always fails: flags are managed on the upstream server.
+func (r *Repository) UpdateFlag(context.Context, repository.Flag) (repository.Flag, error) {
+	return repository.Flag{}, errReadOnly
+}
+
+// DeleteFlag always fails: flags are managed on the upstream server.
+func (r *Repository) DeleteFlag(context.Context, string, string) error {
+	return errReadOnly
+}
+
+// PublishFlagEvent always fails; events only come from the upstream server.
+func (r *Repository) PublishFlagEvent(context.Context, repository.FlagEvent) (repository.FlagEvent, error) {
+	return repository.FlagEvent{}, errReadOnly
+}
+
+// InsertAuditLog discards entry. The upstream server keeps the audit log.
+func (r *Repository) InsertAuditLog(context.Context, repository.AuditLogEntry) error {
+	return nil
+}
+
+// ListAuditLog always returns an empty log.
+func (r *Repository) ListAuditLog(context.Context, string, int, int) ([]repository.AuditLogEntry, error) {
+	return nil, nil
+}
+
+// SubscribeFlagInvalidation returns a channel that receives a full reload
+// every time the mirrored flags change or an upstream event arrives, so the
+// service reloads its cache and wakes its streams. The channel is closed when
+// ctx is cancelled.
+func (r *Repository) SubscribeFlagInvalidation(ctx context.Context) (<-chan repository.FlagInvalidation, error) {
+	ch := make(chan repository.FlagInvalidation, 1)
+	r.subsMu.Lock()
+	r.subs[ch] = struct{}{}
+	r.subsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.subsMu.Lock()
+		delete(r.subs, ch)
+		r.subsMu.Unlock()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Listening reports whether the upstream event stream is connected. The
+// service uses it to hold readiness during warm-up.
+func (r *Repository) Listening() bool {
+	return r.listening.Load()
+}
+
+func (r *Repository) setListening(listening bool) {
+	r.listening.Store(listening)
+}
+
+// LastEventID returns the ID of the newest upstream event seen, or 0.
+func (r *Repository) LastEventID() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.events) == 0 {
+		return 0
+	}
+	return r.events[len(r.events)-1].EventID
+}
+
+// LatestEventID returns [Repository.LastEventID], so rulesets served by the
+// proxy carry the upstream generation once the stream has caught up.
+func (r *Repository) LatestEventID(_ context.Context, projectID string) (int64, error) {
+	if projectID != ProjectID {
+		return 0, nil
+	}
+	return r.LastEventID(), nil
+}
+
+// replace swaps in a full snapshot of the upstream flags.
+func (r *Repository) replace(flags []repository.Flag) {
+	next := make(map[string]repository.Flag, len(flags))
+	for _, flag := range flags {
+		next[flag.Key] = flag
+	}
+
+	r.mu.Lock()
+	r.flags = next
+	r.mu.Unlock()
+	r.notify()
+}
+
+// apply records an upstream event and applies it to the mirrored flags. An
+// event describing an older version of a flag than the one held, as seen
+// when history is replayed after a snapshot, is logged but not applied.
+func (r *Repository) apply(event repository.FlagEvent, flag repository.Flag) {
+	r.mu.Lock()
+	recorded := false
+	if event.EventID > 0 && (len(r.events) == 0 || event.EventID > r.events[len(r.events)-1].EventID) {
+		recorded = true
+		r.events = append(r.events, event)
+		if excess := len(r.events) - r.maxEvents; excess > 0 {
+			r.events = append(r.events[:0:0], r.events[excess:]...)
+		}
+	}
+
+	current, exists := r.flags[flag.Key]
+	stale := exists && flag.UpdatedAt.Before(current.UpdatedAt)
+	changed := false
+	switch {
+	case stale:
+	case event.EventType == service.EventTypeDeleted:
+		if exists {
+			delete(r.flags, flag.Key)
+			changed = true
+		}
+	default:
+		r.flags[flag.Key] = flag
+		changed = true
+	}
+	r.mu.Unlock()
+
+	// A recorded event is news to streams even if the flag was unchanged.
+	if changed || recorded {
+		r.notify()
+	}
+}
+
+func (r *Repository) notify() {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+
+	for ch := range r.subs {
+		select {
+		case ch <- repository.FlagInvalidation{}:
+		default:
+		}
+	}
+}
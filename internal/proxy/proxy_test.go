@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	flagz "github.com/matt-riley/flagz/clients/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// fakeUpstream serves a fixed snapshot and replays events on every stream.
+type fakeUpstream struct {
+	flags  []flagz.Flag
+	events []flagz.FlagEvent
+	since  chan int64
+}
+
+func (u *fakeUpstream) ListFlags(context.Context) ([]flagz.Flag, error) {
+	return u.flags, nil
+}
+
+func (u *fakeUpstream) Stream(ctx context.Context, lastEventID int64) (<-chan flagz.FlagEvent, error) {
+	if u.since != nil {
+		u.since <- lastEventID
+	}
+	ch := make(chan flagz.FlagEvent, len(u.events))
+	for _, event := range u.events {
+		if event.EventID > lastEventID {
+			ch <- event
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func TestSyncerMirrorsUpstream(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+
+	upstream := &fakeUpstream{
+		flags: []flagz.Flag{
+			{Key: "checkout", Enabled: true, UpdatedAt: t2, BucketingSalt: "salt", Targets: flagz.Targets{Attribute: "user_id", Deny: []string{"mallory"}}, Rules: []flagz.Rule{
+				{Attribute: "country", Operator: "in", Value: []any{"NZ", "AU"}},
+			}},
+			{Key: "legacy", Enabled: true, UpdatedAt: t1},
+		},
+		events: []flagz.FlagEvent{
+			// Predates the snapshot and must not roll checkout back.
+			{Type: "update", EventID: 1, Key: "checkout", Flag: &flagz.Flag{Key: "checkout", UpdatedAt: t1}},
+			{Type: "delete", EventID: 2, Key: "legacy", Flag: &flagz.Flag{Key: "legacy", Enabled: true, UpdatedAt: t1}},
+			{Type: "update", EventID: 3, Key: "beta", Flag: &flagz.Flag{Key: "beta", Enabled: true, UpdatedAt: t3}},
+		},
+		since: make(chan int64, 1),
+	}
+	repo := NewRepository()
+	syncer := NewSyncer(repo, upstream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go syncer.Run(ctx)
+	if got := <-upstream.since; got != 0 {
+		t.Fatalf("first stream from event %d, want 0", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.LastEventID() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("last event ID = %d, want 3", repo.LastEventID())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !repo.Listening() {
+		t.Error("Listening() = false while the stream is connected")
+	}
+	checkout, err := repo.GetFlag(ctx, ProjectID, "checkout")
+	if err != nil {
+		t.Fatalf("GetFlag(checkout) error = %v", err)
+	}
+	if !checkout.Enabled || checkout.BucketingSalt != "salt" || checkout.Targets.Attribute != "user_id" || !checkout.UpdatedAt.Equal(t2) {
+		t.Errorf("checkout = %+v, want the snapshot version", checkout)
+	}
+	if got, want := string(checkout.Rules), `[{"attribute":"country","operator":"in","value":["NZ","AU"]}]`; got != want {
+		t.Errorf("checkout rules = %s, want %s", got, want)
+	}
+	if _, err := repo.GetFlag(ctx, ProjectID, "legacy"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetFlag(legacy) error = %v, want pgx.ErrNoRows after delete", err)
+	}
+	if _, err := repo.GetFlag(ctx, ProjectID, "beta"); err != nil {
+		t.Errorf("GetFlag(beta) error = %v", err)
+	}
+
+	events, _ := repo.ListEventsSince(ctx, ProjectID, 1)
+	if len(events) != 2 || events[0].EventType != service.EventTypeDeleted || events[1].FlagKey != "beta" {
+		t.Errorf("events since 1 = %+v, want the legacy delete and the beta update", events)
+	}
+	events, _ = repo.ListEventsSinceForKey(ctx, ProjectID, 0, "checkout")
+	if len(events) != 1 || events[0].EventID != 1 {
+		t.Errorf("checkout events = %+v, want event 1", events)
+	}
+	if generation, _ := repo.LatestEventID(ctx, ProjectID); generation != 3 {
+		t.Errorf("LatestEventID() = %d, want the upstream's 3", generation)
+	}
+}
+
+func TestRepositoryIsReadOnly(t *testing.T) {
+	repo := NewRepository()
+	svc, err := service.New(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("service.New() error = %v", err)
+	}
+	if _, err := svc.CreateFlag(context.Background(), repository.Flag{Key: "new", ProjectID: ProjectID}); err == nil {
+		t.Error("CreateFlag() succeeded on a read-only proxy")
+	}
+	if err := svc.DeleteFlag(context.Background(), ProjectID, "new"); err == nil {
+		t.Error("DeleteFlag() succeeded on a read-only proxy")
+	}
+}
+
+func TestRepositorySignalsInvalidation(t *testing.T) {
+	repo := NewRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	invalidations, err := repo.SubscribeFlagInvalidation(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeFlagInvalidation() error = %v", err)
+	}
+
+	syncer := NewSyncer(repo, &fakeUpstream{flags: []flagz.Flag{{Key: "a"}}})
+	if err := syncer.Bootstrap(ctx); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	select {
+	case <-invalidations:
+	case <-time.After(time.Second):
+		t.Fatal("no invalidation after bootstrap")
+	}
+
+	cancel()
+	if _, ok := <-invalidations; ok {
+		t.Error("invalidation channel still open after cancel")
+	}
+}
+
+func TestTokenValidator(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/api-keys" {
+			t.Errorf("path = %s, want /v1/api-keys", r.URL.Path)
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer mine.secret", "Bearer sibling.secret":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "mine"}, {"id": "sibling"}})
+		case "Bearer other.secret":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "other"}})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer upstream.Close()
+
+	v := NewTokenValidator(upstream.URL+"/", "mine.proxy-secret", upstream.Client())
+	tests := []struct {
+		token   string
+		wantErr bool
+	}{
+		{token: "sibling.secret"},
+		{token: "other.secret", wantErr: true},
+		{token: "bad.secret", wantErr: true},
+		{token: "malformed", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			projectID, err := v.ValidateToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && projectID != ProjectID {
+				t.Errorf("project = %q, want %q", projectID, ProjectID)
+			}
+		})
+	}
+
+	before := requests.Load()
+	if _, err := v.ValidateToken(context.Background(), "sibling.secret"); err != nil {
+		t.Fatalf("cached ValidateToken() error = %v", err)
+	}
+	if _, err := v.ValidateToken(context.Background(), "bad.secret"); err == nil {
+		t.Fatal("cached ValidateToken() accepted a rejected token")
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("upstream asked %d more times, want verdicts served from cache", got-before)
+	}
+}
+
+func TestReadOnlyHandler(t *testing.T) {
+	handler := ReadOnlyHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/v1/flags", http.StatusTeapot},
+		{http.MethodGet, "/v1/flags/checkout", http.StatusTeapot},
+		{http.MethodGet, "/v1/flags/checkout/bucket", http.StatusTeapot},
+		{http.MethodPost, "/v1/evaluate", http.StatusTeapot},
+		{http.MethodGet, "/v1/sdk/config", http.StatusTeapot},
+		{http.MethodGet, "/v1/stream", http.StatusTeapot},
+		{http.MethodGet, "/readyz", http.StatusTeapot},
+		{http.MethodGet, "/v1/openapi.json", http.StatusTeapot},
+		{http.MethodGet, "/v1/schema", http.StatusTeapot},
+		{http.MethodGet, "/v1/info", http.StatusTeapot},
+		{http.MethodPost, "/v1/flags", http.StatusNotImplemented},
+		{http.MethodPost, "/v1/flags/from-template", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/flag-templates", http.StatusNotImplemented},
+		{http.MethodPut, "/v1/flags/checkout", http.StatusNotImplemented},
+		{http.MethodDelete, "/v1/flags/checkout", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/flags/stale", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/flags/checkout/history", http.StatusNotImplemented},
+		{http.MethodPut, "/v1/flags/checkout/targets", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/api-keys", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/audit-log", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/events", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/admin/stream", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/admin/export/audit", http.StatusNotImplemented},
+		{http.MethodGet, "/v1/admin/backup", http.StatusNotImplemented},
+		{http.MethodPost, "/v1/admin/restore", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOnlyInterceptors(t *testing.T) {
+	unary := UnaryReadOnlyInterceptor()
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	if _, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: flagspb.FlagService_ResolveBoolean_FullMethodName}, handler); err != nil {
+		t.Errorf("ResolveBoolean error = %v", err)
+	}
+	if _, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: healthpb.Health_Check_FullMethodName}, handler); err != nil {
+		t.Errorf("Health/Check error = %v", err)
+	}
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: flagspb.FlagService_CreateFlag_FullMethodName}, handler)
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("CreateFlag code = %v, want Unimplemented", status.Code(err))
+	}
+
+	stream := StreamReadOnlyInterceptor()
+	if err := stream(nil, nil, &grpc.StreamServerInfo{FullMethod: flagspb.FlagService_WatchFlag_FullMethodName}, func(any, grpc.ServerStream) error { return nil }); err != nil {
+		t.Errorf("WatchFlag error = %v", err)
+	}
+	if err := stream(nil, nil, &grpc.StreamServerInfo{FullMethod: reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName}, func(any, grpc.ServerStream) error { return nil }); err != nil {
+		t.Errorf("ServerReflectionInfo error = %v", err)
+	}
+}
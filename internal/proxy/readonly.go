@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// readOnlyRoutes are the HTTP routes a proxy serves. Everything else is
+// either a write or needs data only the upstream server has.
+var readOnlyRoutes = []string{
+	"GET /v1/flags",
+	"GET /v1/flags/{key}",
+	"GET /v1/flags/{key}/bucket",
+	"POST /v1/evaluate",
+	"POST /v1/evaluate/all",
+	"POST /v1/evaluate/explain",
+	"GET /v1/sdk/config",
+	"GET /v1/stream",
+	"GET /v1/openapi.json",
+	"GET /v1/schema",
+	"GET /v1/proto/descriptor",
+	"GET /v1/info",
+	"GET /healthz",
+	"GET /readyz",
+	"GET /metrics",
+}
+
+// readOnlyMethods are the gRPC methods a proxy serves.
+var readOnlyMethods = map[string]bool{
+	flagspb.FlagService_GetFlag_FullMethodName:         true,
+	flagspb.FlagService_ListFlags_FullMethodName:       true,
+	flagspb.FlagService_ListFlagsStream_FullMethodName: true,
+	flagspb.FlagService_ResolveBoolean_FullMethodName:  true,
+	flagspb.FlagService_ResolveBatch_FullMethodName:    true,
+	flagspb.FlagService_ResolveAll_FullMethodName:      true,
+	flagspb.FlagService_WatchFlag_FullMethodName:       true,
+	flagspb.FlagService_WatchProject_FullMethodName:    true,
+	// Reflection describes the API, and health reports on the proxy
+	// itself; neither reads anything from the upstream.
+	healthpb.Health_Check_FullMethodName:                                   true,
+	reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName:      true,
+	reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: true,
+}
+
+const notServedMessage = "not available on a read-only proxy; use the upstream server"
+
+// ReadOnlyHandler passes flag reads, evaluations and streams to next and
+// answers every other request with 501 Not Implemented.
+func ReadOnlyHandler(next http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range readOnlyRoutes {
+		mux.Handle(route, next)
+	}
+	mux.HandleFunc("/", notServed)
+	// More specific than "GET /v1/flags/{key}", so it is not mistaken for a
+	// flag named "stale".
+	mux.HandleFunc("GET /v1/flags/stale", notServed)
+	return mux
+}
+
+func notServed(w http.ResponseWriter, r *http.Request) {
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Type:   middleware.ProblemType("read-only-proxy"),
+		Status: http.StatusNotImplemented,
+		Detail: notServedMessage,
+	})
+}
+
+// UnaryReadOnlyInterceptor rejects unary calls other than flag reads and
+// evaluations with codes.Unimplemented.
+func UnaryReadOnlyInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !readOnlyMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unimplemented, notServedMessage)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamReadOnlyInterceptor rejects streaming calls other than WatchFlag
+// with codes.Unimplemented.
+func StreamReadOnlyInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !readOnlyMethods[info.FullMethod] {
+			return status.Error(codes.Unimplemented, notServedMessage)
+		}
+		return handler(srv, ss)
+	}
+}
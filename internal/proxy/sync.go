@@ -0,0 +1,243 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	flagz "github.com/matt-riley/flagz/clients/go"
+
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// Upstream is the part of the flagz Go client the proxy syncs from. It is
+// satisfied by the HTTP client in clients/go/http.
+type Upstream interface {
+	ListFlags(ctx context.Context) ([]flagz.Flag, error)
+	Stream(ctx context.Context, lastEventID int64) (<-chan flagz.FlagEvent, error)
+}
+
+// Syncer keeps a [Repository] in step with the upstream server.
+type Syncer struct {
+	repo       *Repository
+	upstream   Upstream
+	log        *slog.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a [Syncer].
+type Option func(*Syncer)
+
+// WithLogger sets the logger used to report upstream connection problems.
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Syncer) {
+		if log != nil {
+			s.log = log
+		}
+	}
+}
+
+// WithReconnectBackoff sets the bounds of the exponential backoff between
+// upstream reconnection attempts. Defaults to 1 second doubling up to 30
+// seconds; non-positive values keep the defaults.
+func WithReconnectBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(s *Syncer) {
+		if minDelay > 0 {
+			s.minBackoff = minDelay
+		}
+		if maxDelay > 0 {
+			s.maxBackoff = maxDelay
+		}
+	}
+}
+
+// NewSyncer returns a Syncer that mirrors upstream into repo.
+func NewSyncer(repo *Repository, upstream Upstream, opts ...Option) *Syncer {
+	if repo == nil {
+		panic("repository is nil")
+	}
+	if upstream == nil {
+		panic("upstream is nil")
+	}
+
+	s := &Syncer{
+		repo:       repo,
+		upstream:   upstream,
+		log:        slog.Default(),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Bootstrap loads a snapshot of the upstream flags. Call it before creating
+// the service so the proxy starts serving with a full cache.
+func (s *Syncer) Bootstrap(ctx context.Context) error {
+	flags, err := s.upstream.ListFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("list upstream flags: %w", err)
+	}
+	converted, err := toRepositoryFlags(flags)
+	if err != nil {
+		return err
+	}
+	s.repo.replace(converted)
+	return nil
+}
+
+// Run follows the upstream event stream until ctx is cancelled,
+// reconnecting with backoff whenever the stream drops.
+func (s *Syncer) Run(ctx context.Context) {
+	backoff := s.minBackoff
+	for {
+		start := time.Now()
+		err := s.syncOnce(ctx)
+		s.repo.setListening(false)
+		if ctx.Err() != nil {
+			return
+		}
+		// A stream that stayed up for a while is a fresh failure, not a
+		// continuation of the previous one.
+		if time.Since(start) > s.maxBackoff {
+			backoff = s.minBackoff
+		}
+		s.log.Warn("upstream stream disconnected", "error", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, s.maxBackoff)
+	}
+}
+
+// syncOnce opens the stream, reloads the snapshot and then applies events
+// until the stream ends. The stream is opened first so no change made
+// while the snapshot is taken is missed; events it replays that predate
+// the snapshot are recognised as stale and only logged.
+func (s *Syncer) syncOnce(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := s.upstream.Stream(ctx, s.repo.LastEventID())
+	if err != nil {
+		return fmt.Errorf("open upstream stream: %w", err)
+	}
+	if err := s.Bootstrap(ctx); err != nil {
+		return err
+	}
+	s.repo.setListening(true)
+	s.log.Info("upstream stream connected", "last_event_id", s.repo.LastEventID())
+
+	for event := range events {
+		if err := s.applyEvent(event); err != nil {
+			return err
+		}
+	}
+	return errors.New("upstream stream closed")
+}
+
+func (s *Syncer) applyEvent(event flagz.FlagEvent) error {
+	var eventType string
+	switch event.Type {
+	case "update":
+		eventType = service.EventTypeUpdated
+	case "delete":
+		eventType = service.EventTypeDeleted
+	case "error":
+		return errors.New("upstream stream error")
+	default:
+		return nil
+	}
+	if event.Flag == nil {
+		s.log.Warn("skipping upstream event without flag", "event_id", event.EventID, "event_type", event.Type)
+		return nil
+	}
+
+	flag, err := toRepositoryFlag(*event.Flag)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("marshal event payload: %w", err)
+	}
+	s.repo.apply(repository.FlagEvent{
+		EventID:   event.EventID,
+		ProjectID: ProjectID,
+		FlagKey:   flag.Key,
+		EventType: eventType,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}, flag)
+	return nil
+}
+
+func toRepositoryFlags(flags []flagz.Flag) ([]repository.Flag, error) {
+	converted := make([]repository.Flag, 0, len(flags))
+	for _, flag := range flags {
+		f, err := toRepositoryFlag(flag)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, f)
+	}
+	return converted, nil
+}
+
+// wireRule mirrors the JSON shape of a stored rule.
+type wireRule struct {
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"`
+	Value     any    `json:"value"`
+}
+
+func toRepositoryFlag(f flagz.Flag) (repository.Flag, error) {
+	flag := repository.Flag{
+		Key:           f.Key,
+		ProjectID:     ProjectID,
+		Description:   f.Description,
+		Enabled:       f.Enabled,
+		BucketingSalt: f.BucketingSalt,
+		Targets: repository.FlagTargets{
+			Attribute: f.Targets.Attribute,
+			Allow:     f.Targets.Allow,
+			Deny:      f.Targets.Deny,
+		},
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+	if f.Variants != nil {
+		variants, err := json.Marshal(f.Variants)
+		if err != nil {
+			return repository.Flag{}, fmt.Errorf("flag %q: marshal variants: %w", f.Key, err)
+		}
+		flag.Variants = variants
+	}
+	if f.Rules != nil {
+		rules := make([]wireRule, len(f.Rules))
+		for i, r := range f.Rules {
+			rules[i] = wireRule{Attribute: r.Attribute, Operator: r.Operator, Value: r.Value}
+		}
+		encoded, err := json.Marshal(rules)
+		if err != nil {
+			return repository.Flag{}, fmt.Errorf("flag %q: marshal rules: %w", f.Key, err)
+		}
+		flag.Rules = encoded
+	}
+	return flag, nil
+}
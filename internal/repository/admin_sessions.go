@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const insertAdminSessionSQL = `
+	INSERT INTO admin_sessions (id_hash, admin_user_id, csrf_token, created_at, expires_at, last_seen_at, role, user_agent, remote_addr)
+	VALUES ($1, $2, $3, $4, $5, $4, $6, $7, $8)
+`
+
+// RotateAdminSession replaces the session oldIDHash with next in one
+// transaction, so the old token stops working as the new one starts.
+func (r *PostgresRepository) RotateAdminSession(ctx context.Context, oldIDHash string, next AdminSession) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin rotate admin session tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	commandTag, err := tx.Exec(ctx, `DELETE FROM admin_sessions WHERE id_hash = $1`, oldIDHash)
+	if err != nil {
+		return fmt.Errorf("rotate admin session: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("rotate admin session: %w", pgx.ErrNoRows)
+	}
+	if _, err := tx.Exec(ctx, insertAdminSessionSQL,
+		next.IDHash, next.AdminUserID, next.CSRFToken, next.CreatedAt, next.ExpiresAt,
+		next.Role, next.UserAgent, next.RemoteAddr); err != nil {
+		return fmt.Errorf("rotate admin session: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit rotate admin session tx: %w", err)
+	}
+	return nil
+}
+
+// TouchAdminSession records that a session was just used.
+func (r *PostgresRepository) TouchAdminSession(ctx context.Context, idHash string) error {
+	_, err := r.pool.Exec(ctx, `UPDATE admin_sessions SET last_seen_at = NOW() WHERE id_hash = $1`, idHash)
+	if err != nil {
+		return fmt.Errorf("touch admin session: %w", err)
+	}
+	return nil
+}
+
+// ListAdminSessions returns a user's unexpired sessions, most recently used
+// first.
+func (r *PostgresRepository) ListAdminSessions(ctx context.Context, adminUserID string) ([]AdminSession, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id_hash, admin_user_id, created_at, expires_at, id, role, last_seen_at, user_agent, remote_addr
+		FROM admin_sessions
+		WHERE admin_user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, adminUserID)
+	if err != nil {
+		return nil, fmt.Errorf("list admin sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []AdminSession
+	for rows.Next() {
+		var s AdminSession
+		if err := rows.Scan(
+			&s.IDHash,
+			&s.AdminUserID,
+			&s.CreatedAt,
+			&s.ExpiresAt,
+			&s.ID,
+			&s.Role,
+			&s.LastSeenAt,
+			&s.UserAgent,
+			&s.RemoteAddr,
+		); err != nil {
+			return nil, fmt.Errorf("scan admin session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list admin sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteAdminSessionByID removes one of a user's sessions by its public ID.
+// It returns pgx.ErrNoRows if the user has no such session.
+func (r *PostgresRepository) DeleteAdminSessionByID(ctx context.Context, adminUserID, id string) error {
+	commandTag, err := r.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE admin_user_id = $1 AND id = $2`, adminUserID, id)
+	if err != nil {
+		return fmt.Errorf("delete admin session: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("delete admin session: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// DeleteOtherAdminSessions removes all of a user's sessions except
+// keepIDHash and returns how many were removed.
+func (r *PostgresRepository) DeleteOtherAdminSessions(ctx context.Context, adminUserID, keepIDHash string) (int64, error) {
+	commandTag, err := r.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE admin_user_id = $1 AND id_hash <> $2`, adminUserID, keepIDHash)
+	if err != nil {
+		return 0, fmt.Errorf("delete other admin sessions: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// API key scopes. A project key authenticates requests for its own project
+// only; an admin key additionally may follow events across all projects.
+const (
+	APIKeyScopeProject = "project"
+	APIKeyScopeAdmin   = "admin"
+)
+
+// GetAPIKeyScope returns the scope of a non-revoked key ID. Returns
+// pgx.ErrNoRows (wrapped) if the key does not exist or is revoked.
+func (r *PostgresRepository) GetAPIKeyScope(ctx context.Context, keyID string) (string, error) {
+	var scope string
+	if err := r.pool.QueryRow(ctx, `
+		SELECT scope
+		FROM api_keys
+		WHERE id = $1
+		  AND revoked_at IS NULL
+	`, keyID).Scan(&scope); err != nil {
+		return "", fmt.Errorf("get api key scope: %w", err)
+	}
+	return scope, nil
+}
+
+// ListAllEventsSince returns up to the configured event batch size (default
+// 1000) flag events of every project with IDs greater than eventID, ordered
+// by event ID.
+func (r *PostgresRepository) ListAllEventsSince(ctx context.Context, eventID int64) ([]FlagEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > $1
+		ORDER BY event_id
+		LIMIT $2
+	`, eventID, r.eventBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("list all events since: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]FlagEvent, 0)
+	for rows.Next() {
+		var event FlagEvent
+		if err := rows.Scan(
+			&event.EventID,
+			&event.ProjectID,
+			&event.FlagKey,
+			&event.EventType,
+			&event.Payload,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list all events rows: %w", err)
+	}
+	if err := r.openEvents(ctx, events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
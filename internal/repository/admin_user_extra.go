@@ -3,13 +3,15 @@ package repository
 import (
 	"context"
 	"fmt"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // GetAdminUserByID retrieves an admin user by ID.
 func (r *PostgresRepository) GetAdminUserByID(ctx context.Context, id string) (AdminUser, error) {
 	var u AdminUser
 	err := r.pool.QueryRow(ctx, `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, password_hash, role, created_at, updated_at, deactivated_at
 		FROM admin_users
 		WHERE id = $1
 	`, id).Scan(
@@ -19,9 +21,153 @@ func (r *PostgresRepository) GetAdminUserByID(ctx context.Context, id string) (A
 		&u.Role,
 		&u.CreatedAt,
 		&u.UpdatedAt,
+		&u.DeactivatedAt,
 	)
 	if err != nil {
 		return AdminUser{}, fmt.Errorf("get admin user by id: %w", err)
 	}
 	return u, nil
 }
+
+// UpsertOIDCAdminUser creates or updates the admin user linked to an OIDC
+// subject, setting its username and role to the values from the latest
+// login. OIDC users have an empty password hash, so they cannot sign in with
+// a password.
+func (r *PostgresRepository) UpsertOIDCAdminUser(ctx context.Context, subject, username, role string) (AdminUser, error) {
+	var u AdminUser
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO admin_users (username, password_hash, role, oidc_subject)
+		VALUES ($1, '', $2, $3)
+		ON CONFLICT (oidc_subject) DO UPDATE
+		SET username = EXCLUDED.username, role = EXCLUDED.role, updated_at = NOW()
+		RETURNING id, username, password_hash, role, created_at, updated_at, deactivated_at
+	`, username, role, subject).Scan(
+		&u.ID,
+		&u.Username,
+		&u.PasswordHash,
+		&u.Role,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.DeactivatedAt,
+	)
+	if err != nil {
+		return AdminUser{}, fmt.Errorf("upsert oidc admin user: %w", err)
+	}
+	return u, nil
+}
+
+// ListAdminUsers returns every admin user, deactivated ones included, sorted
+// by username.
+func (r *PostgresRepository) ListAdminUsers(ctx context.Context) ([]AdminUser, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, username, password_hash, role, created_at, updated_at, deactivated_at
+		FROM admin_users
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list admin users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []AdminUser
+	for rows.Next() {
+		var u AdminUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.DeactivatedAt); err != nil {
+			return nil, fmt.Errorf("scan admin user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list admin users rows: %w", err)
+	}
+	return users, nil
+}
+
+// SetAdminUserRole changes a user's role. The user's sessions pick up the
+// new role, under a new session ID, on their next request.
+func (r *PostgresRepository) SetAdminUserRole(ctx context.Context, id, role string) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		UPDATE admin_users SET role = $2, updated_at = NOW() WHERE id = $1
+	`, id, role)
+	if err != nil {
+		return fmt.Errorf("set admin user role: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("set admin user role: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// SetAdminUserPassword replaces a user's password hash and ends all of the
+// user's sessions, so the new password is needed to sign in again.
+func (r *PostgresRepository) SetAdminUserPassword(ctx context.Context, id, passwordHash string) error {
+	return r.updateAdminUserEndingSessions(ctx, "set admin user password", `
+		UPDATE admin_users SET password_hash = $2, updated_at = NOW() WHERE id = $1
+	`, id, passwordHash)
+}
+
+// DeactivateAdminUser marks a user deactivated and ends all of the user's
+// sessions. Deactivating an already deactivated user keeps the original
+// time.
+func (r *PostgresRepository) DeactivateAdminUser(ctx context.Context, id string) error {
+	return r.updateAdminUserEndingSessions(ctx, "deactivate admin user", `
+		UPDATE admin_users
+		SET deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`, id)
+}
+
+// ReactivateAdminUser lets a deactivated user sign in again.
+func (r *PostgresRepository) ReactivateAdminUser(ctx context.Context, id string) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		UPDATE admin_users SET deactivated_at = NULL, updated_at = NOW() WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("reactivate admin user: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("reactivate admin user: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// RehashAdminUserPassword replaces a user's password hash with newHash, a
+// hash of the same password made with different settings, so unlike
+// SetAdminUserPassword it leaves the user's sessions alone. It does nothing
+// if the stored hash is no longer oldHash, so it cannot undo a password
+// change made in the meantime.
+func (r *PostgresRepository) RehashAdminUserPassword(ctx context.Context, id, oldHash, newHash string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE admin_users SET password_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND password_hash = $2
+	`, id, oldHash, newHash)
+	if err != nil {
+		return fmt.Errorf("rehash admin user password: %w", err)
+	}
+	return nil
+}
+
+// updateAdminUserEndingSessions runs update, whose first argument is the
+// user ID, and deletes the user's sessions in the same transaction.
+func (r *PostgresRepository) updateAdminUserEndingSessions(ctx context.Context, op, update string, args ...any) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin %s tx: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	commandTag, err := tx.Exec(ctx, update, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, pgx.ErrNoRows)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_sessions WHERE admin_user_id = $1`, args[0]); err != nil {
+		return fmt.Errorf("%s: end sessions: %w", op, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit %s tx: %w", op, err)
+	}
+	return nil
+}
@@ -7,6 +7,13 @@ func (r *PostgresRepository) CreateAPIKeyForProject(ctx context.Context, project
 	return r.CreateAPIKey(ctx, projectID)
 }
 
+// CreateAdminAPIKeyForProject creates an admin-scoped API key owned by the
+// given project. Besides acting as a key for that project, it may follow the
+// event stream of every project.
+func (r *PostgresRepository) CreateAdminAPIKeyForProject(ctx context.Context, projectID string) (string, string, error) {
+	return r.createAPIKey(ctx, projectID, APIKeyScopeAdmin)
+}
+
 // ListAPIKeysForProject returns API keys newest-first for admin display.
 func (r *PostgresRepository) ListAPIKeysForProject(ctx context.Context, projectID string) ([]APIKeyMeta, error) {
 	return r.listAPIKeys(ctx, projectID, true)
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// APIKeyUsage counts the requests one API key made in one hour.
+type APIKeyUsage struct {
+	ProjectID string `json:"-"`
+	APIKeyID  string `json:"api_key_id"`
+	// Hour is the start of the hour, in UTC.
+	Hour          time.Time `json:"hour"`
+	Evaluations   int64     `json:"evaluations"`
+	Mutations     int64     `json:"mutations"`
+	StreamSeconds int64     `json:"stream_seconds"`
+}
+
+// APIKeyQuota limits the requests each of a project's API keys may make in
+// an hour. Zero means unlimited.
+type APIKeyQuota struct {
+	HourlyEvaluations int64 `json:"hourly_evaluations"`
+	HourlyMutations   int64 `json:"hourly_mutations"`
+}
+
+// AddAPIKeyUsage adds each entry's counts to the stored totals of its key
+// and hour, in a single statement, and returns the totals after the
+// addition. Entries for projects that no longer exist are dropped.
+func (r *PostgresRepository) AddAPIKeyUsage(ctx context.Context, usage []APIKeyUsage) ([]APIKeyUsage, error) {
+	if len(usage) == 0 {
+		return nil, nil
+	}
+
+	projectIDs := make([]string, len(usage))
+	keyIDs := make([]string, len(usage))
+	hours := make([]time.Time, len(usage))
+	evaluations := make([]int64, len(usage))
+	mutations := make([]int64, len(usage))
+	streamSeconds := make([]int64, len(usage))
+	for i, u := range usage {
+		projectIDs[i] = u.ProjectID
+		keyIDs[i] = u.APIKeyID
+		hours[i] = u.Hour
+		evaluations[i] = u.Evaluations
+		mutations[i] = u.Mutations
+		streamSeconds[i] = u.StreamSeconds
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		INSERT INTO api_key_usage (project_id, api_key_id, hour, evaluations, mutations, stream_seconds)
+		SELECT u.project_id, u.api_key_id, u.hour, u.evaluations, u.mutations, u.stream_seconds
+		FROM unnest($1::uuid[], $2::text[], $3::timestamptz[], $4::bigint[], $5::bigint[], $6::bigint[])
+			AS u(project_id, api_key_id, hour, evaluations, mutations, stream_seconds)
+		JOIN projects p ON p.id = u.project_id
+		ON CONFLICT (project_id, hour, api_key_id) DO UPDATE
+		SET evaluations = api_key_usage.evaluations + EXCLUDED.evaluations,
+			mutations = api_key_usage.mutations + EXCLUDED.mutations,
+			stream_seconds = api_key_usage.stream_seconds + EXCLUDED.stream_seconds
+		RETURNING project_id, api_key_id, hour, evaluations, mutations, stream_seconds
+	`, projectIDs, keyIDs, hours, evaluations, mutations, streamSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("add api key usage: %w", err)
+	}
+	return collectAPIKeyUsage(rows)
+}
+
+// ListAPIKeyUsage returns a project's hourly usage from the hour containing
+// since onwards, ordered by hour and then API key ID.
+func (r *PostgresRepository) ListAPIKeyUsage(ctx context.Context, projectID string, since time.Time) ([]APIKeyUsage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT project_id, api_key_id, hour, evaluations, mutations, stream_seconds
+		FROM api_key_usage
+		WHERE project_id = $1 AND hour >= date_trunc('hour', $2::timestamptz)
+		ORDER BY hour, api_key_id
+	`, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("list api key usage: %w", err)
+	}
+	return collectAPIKeyUsage(rows)
+}
+
+// GetAPIKeyQuota returns the hourly API key quota of a project. Returns
+// pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetAPIKeyQuota(ctx context.Context, projectID string) (APIKeyQuota, error) {
+	var quota APIKeyQuota
+	err := r.pool.QueryRow(ctx, `
+		SELECT api_key_hourly_evaluation_quota, api_key_hourly_mutation_quota
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&quota.HourlyEvaluations, &quota.HourlyMutations)
+	if err != nil {
+		return APIKeyQuota{}, fmt.Errorf("get api key quota: %w", err)
+	}
+	return quota, nil
+}
+
+// SetAPIKeyQuota replaces the hourly API key quota of a project. Returns
+// pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) SetAPIKeyQuota(ctx context.Context, projectID string, quota APIKeyQuota) (APIKeyQuota, error) {
+	var stored APIKeyQuota
+	err := r.pool.QueryRow(ctx, `
+		UPDATE projects
+		SET api_key_hourly_evaluation_quota = $2, api_key_hourly_mutation_quota = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING api_key_hourly_evaluation_quota, api_key_hourly_mutation_quota
+	`, projectID, quota.HourlyEvaluations, quota.HourlyMutations).Scan(&stored.HourlyEvaluations, &stored.HourlyMutations)
+	if err != nil {
+		return APIKeyQuota{}, fmt.Errorf("set api key quota: %w", err)
+	}
+	return stored, nil
+}
+
+func collectAPIKeyUsage(rows pgx.Rows) ([]APIKeyUsage, error) {
+	defer rows.Close()
+
+	usage := make([]APIKeyUsage, 0)
+	for rows.Next() {
+		var u APIKeyUsage
+		if err := rows.Scan(&u.ProjectID, &u.APIKeyID, &u.Hour, &u.Evaluations, &u.Mutations, &u.StreamSeconds); err != nil {
+			return nil, fmt.Errorf("scan api key usage: %w", err)
+		}
+		u.Hour = u.Hour.UTC()
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("api key usage rows: %w", err)
+	}
+	return usage, nil
+}
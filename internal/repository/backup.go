@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Kinds of [BackupRecord].
+const (
+	BackupKindProject = "project"
+	BackupKindAPIKey  = "api_key"
+	BackupKindSegment = "segment"
+	BackupKindFlag    = "flag"
+	BackupKindAudit   = "audit"
+)
+
+// ErrProjectNameTaken is returned by [PostgresRepository.Restore] when a
+// project in the backup has the name of a project that already exists and
+// conflicts are not renamed.
+var ErrProjectNameTaken = errors.New("project name is already taken")
+
+// BackupRecord is one entry of a backup: a project, or one thing belonging
+// to the project ProjectID. Exactly one of its pointers is set, as Kind says.
+type BackupRecord struct {
+	Kind      string         `json:"kind"`
+	ProjectID string         `json:"project_id,omitempty"`
+	Project   *Project       `json:"project,omitempty"`
+	APIKey    *BackupAPIKey  `json:"api_key,omitempty"`
+	Segment   *Segment       `json:"segment,omitempty"`
+	Flag      *Flag          `json:"flag,omitempty"`
+	Audit     *AuditLogEntry `json:"audit,omitempty"`
+}
+
+// BackupAPIKey is the metadata of an API key as backed up. Its hash is left
+// out, so restored keys cannot authenticate.
+type BackupAPIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// RestoreOptions control [PostgresRepository.Restore].
+type RestoreOptions struct {
+	// RenameConflicts restores a project whose name is taken under a new
+	// name, "<name> (restored)", instead of failing.
+	RenameConflicts bool
+}
+
+// RestoreResult describes what [PostgresRepository.Restore] restored.
+// Projects and APIKeys map the IDs in the backup to the IDs they were
+// restored under.
+type RestoreResult struct {
+	Projects     map[string]string `json:"projects"`
+	APIKeys      map[string]string `json:"api_keys"`
+	Segments     int               `json:"segments"`
+	Flags        int               `json:"flags"`
+	AuditEntries int               `json:"audit_entries"`
+}
+
+// liveProjectIDs selects the projects a backup covers: those not deleted.
+const liveProjectIDs = `SELECT id FROM projects WHERE deleted_at IS NULL`
+
+// Backup passes every project that is not deleted to emit, followed by its
+// API keys, segments, flags and audit log, all read from one consistent
+// snapshot. Records are emitted in that order, so each project comes before
+// anything belonging to it. Flag variants are decrypted; API key hashes,
+// flag revisions and events are not included.
+func (r *PostgresRepository) Backup(ctx context.Context, emit func(BackupRecord) error) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin backup tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	steps := []struct {
+		name  string
+		query string
+		scan  func(pgx.Rows) (BackupRecord, error)
+	}{
+		{"projects", `
+			SELECT id, name, description, created_at, updated_at
+			FROM projects
+			WHERE deleted_at IS NULL
+			ORDER BY created_at, id
+		`, scanBackupProject},
+		{"api keys", `
+			SELECT project_id, id, name, scope, created_at, revoked_at, last_used_at
+			FROM api_keys
+			WHERE project_id IN (` + liveProjectIDs + `)
+			ORDER BY created_at, id
+		`, scanBackupAPIKey},
+		{"segments", `
+			SELECT ` + segmentColumns + `
+			FROM segments
+			WHERE project_id IN (` + liveProjectIDs + `)
+			ORDER BY project_id, name
+		`, scanBackupSegment},
+		{"flags", `
+			SELECT project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by, archived_at
+			FROM flags
+			WHERE project_id IN (` + liveProjectIDs + `)
+			ORDER BY project_id, key
+		`, r.scanBackupFlag(ctx)},
+		{"audit log", `
+			SELECT id, project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at
+			FROM audit_log
+			WHERE project_id IN (` + liveProjectIDs + `)
+			ORDER BY id
+		`, scanBackupAudit},
+	}
+	for _, step := range steps {
+		if err := backupRows(ctx, tx, step.query, step.scan, emit); err != nil {
+			return fmt.Errorf("back up %s: %w", step.name, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func backupRows(ctx context.Context, tx pgx.Tx, query string, scan func(pgx.Rows) (BackupRecord, error), emit func(BackupRecord) error) error {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanBackupProject(rows pgx.Rows) (BackupRecord, error) {
+	var p Project
+	if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return BackupRecord{}, err
+	}
+	return BackupRecord{Kind: BackupKindProject, Project: &p}, nil
+}
+
+func scanBackupAPIKey(rows pgx.Rows) (BackupRecord, error) {
+	var (
+		projectID string
+		k         BackupAPIKey
+	)
+	if err := rows.Scan(&projectID, &k.ID, &k.Name, &k.Scope, &k.CreatedAt, &k.RevokedAt, &k.LastUsedAt); err != nil {
+		return BackupRecord{}, err
+	}
+	return BackupRecord{Kind: BackupKindAPIKey, ProjectID: projectID, APIKey: &k}, nil
+}
+
+func scanBackupSegment(rows pgx.Rows) (BackupRecord, error) {
+	s, err := scanSegment(rows)
+	if err != nil {
+		return BackupRecord{}, err
+	}
+	return BackupRecord{Kind: BackupKindSegment, ProjectID: s.ProjectID, Segment: &s}, nil
+}
+
+func (r *PostgresRepository) scanBackupFlag(ctx context.Context) func(pgx.Rows) (BackupRecord, error) {
+	return func(rows pgx.Rows) (BackupRecord, error) {
+		var f Flag
+		if err := rows.Scan(
+			&f.ProjectID,
+			&f.Key,
+			&f.Description,
+			&f.Enabled,
+			&f.Variants,
+			&f.Rules,
+			&f.BucketingSalt,
+			&f.VariantsSchema,
+			&f.ShadowRules,
+			&f.Tags,
+			&f.Owner,
+			&f.ExpiresAt,
+			&f.Targets.Attribute,
+			&f.Targets.Allow,
+			&f.Targets.Deny,
+			&f.CreatedAt,
+			&f.UpdatedAt,
+			&f.CreatedBy,
+			&f.UpdatedBy,
+			&f.ArchivedAt,
+		); err != nil {
+			return BackupRecord{}, err
+		}
+		if err := r.openFlag(ctx, &f); err != nil {
+			return BackupRecord{}, err
+		}
+		return BackupRecord{Kind: BackupKindFlag, ProjectID: f.ProjectID, Flag: &f}, nil
+	}
+}
+
+func scanBackupAudit(rows pgx.Rows) (BackupRecord, error) {
+	var e AuditLogEntry
+	if err := rows.Scan(&e.ID, &e.ProjectID, &e.APIKeyID, &e.AdminUserID, &e.Action, &e.FlagKey, &e.Details, &e.RequestID, &e.CreatedAt); err != nil {
+		return BackupRecord{}, err
+	}
+	return BackupRecord{Kind: BackupKindAudit, ProjectID: e.ProjectID, Audit: &e}, nil
+}
+
+// Restore adds the records next returns, until it returns io.EOF, to the
+// database in one transaction, so a failed restore leaves nothing behind.
+// Records must come in the order [PostgresRepository.Backup] emits them.
+//
+// Projects and API keys are given new IDs, and every reference to them is
+// rewritten, so a backup can be restored next to existing data or more than
+// once. API keys are restored revoked, since their secrets are not backed
+// up. Flags keep their bucketing salts, so rollouts assign the same buckets
+// as before, and their variants are encrypted if the repository encrypts
+// variants. Returns [ErrProjectNameTaken] (wrapped) for a project whose name
+// is taken, unless opts.RenameConflicts is set.
+func (r *PostgresRepository) Restore(ctx context.Context, opts RestoreOptions, next func() (BackupRecord, error)) (RestoreResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("begin restore tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result := RestoreResult{Projects: make(map[string]string), APIKeys: make(map[string]string)}
+	for {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return RestoreResult{}, fmt.Errorf("read backup: %w", err)
+		}
+		if err := r.restoreRecord(ctx, tx, opts, record, &result); err != nil {
+			return RestoreResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RestoreResult{}, fmt.Errorf("commit restore tx: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresRepository) restoreRecord(ctx context.Context, tx pgx.Tx, opts RestoreOptions, record BackupRecord, result *RestoreResult) error {
+	if record.Kind == BackupKindProject {
+		if record.Project == nil {
+			return errors.New("project record has no project")
+		}
+		return restoreProject(ctx, tx, opts, *record.Project, result)
+	}
+
+	projectID, ok := result.Projects[record.ProjectID]
+	if !ok {
+		return fmt.Errorf("%s record comes before its project %q", record.Kind, record.ProjectID)
+	}
+	switch {
+	case record.Kind == BackupKindAPIKey && record.APIKey != nil:
+		return restoreAPIKey(ctx, tx, projectID, *record.APIKey, result)
+	case record.Kind == BackupKindSegment && record.Segment != nil:
+		segment := *record.Segment
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO segments (project_id, name, description, rules, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, projectID, segment.Name, segment.Description, segment.Rules, segment.CreatedAt, segment.UpdatedAt); err != nil {
+			return fmt.Errorf("restore segment %q: %w", segment.Name, err)
+		}
+		result.Segments++
+		return nil
+	case record.Kind == BackupKindFlag && record.Flag != nil:
+		flag := *record.Flag
+		flag.ProjectID = projectID
+		flag.CreatedBy = remapActor(flag.CreatedBy, result.APIKeys)
+		flag.UpdatedBy = remapActor(flag.UpdatedBy, result.APIKeys)
+		if err := r.restoreFlag(ctx, tx, flag); err != nil {
+			return fmt.Errorf("restore flag %q: %w", flag.Key, err)
+		}
+		result.Flags++
+		return nil
+	case record.Kind == BackupKindAudit && record.Audit != nil:
+		entry := *record.Audit
+		if mapped, ok := result.APIKeys[entry.APIKeyID]; ok {
+			entry.APIKeyID = mapped
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO audit_log (project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, projectID, entry.APIKeyID, entry.AdminUserID, entry.Action, entry.FlagKey, entry.Details, entry.RequestID, entry.CreatedAt); err != nil {
+			return fmt.Errorf("restore audit log entry %d: %w", entry.ID, err)
+		}
+		result.AuditEntries++
+		return nil
+	default:
+		return fmt.Errorf("invalid %q record", record.Kind)
+	}
+}
+
+func restoreProject(ctx context.Context, tx pgx.Tx, opts RestoreOptions, project Project, result *RestoreResult) error {
+	if _, ok := result.Projects[project.ID]; ok {
+		return fmt.Errorf("project %q appears twice", project.ID)
+	}
+
+	name := project.Name
+	for attempt := 1; ; attempt++ {
+		var taken bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM projects WHERE name = $1)`, name).Scan(&taken); err != nil {
+			return fmt.Errorf("restore project %q: %w", project.Name, err)
+		}
+		if !taken {
+			break
+		}
+		if !opts.RenameConflicts {
+			return fmt.Errorf("restore project %q: %w", project.Name, ErrProjectNameTaken)
+		}
+		name = project.Name + " (restored)"
+		if attempt > 1 {
+			name = fmt.Sprintf("%s (restored %d)", project.Name, attempt)
+		}
+	}
+
+	var id string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO projects (name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, name, project.Description, project.CreatedAt, project.UpdatedAt).Scan(&id); err != nil {
+		return fmt.Errorf("restore project %q: %w", project.Name, err)
+	}
+	result.Projects[project.ID] = id
+	return nil
+}
+
+func restoreAPIKey(ctx context.Context, tx pgx.Tx, projectID string, key BackupAPIKey, result *RestoreResult) error {
+	id, err := generateRandomHex(16)
+	if err != nil {
+		return fmt.Errorf("generate key id: %w", err)
+	}
+	revokedAt := key.RevokedAt
+	if revokedAt == nil {
+		now := time.Now()
+		revokedAt = &now
+	}
+	// An empty hash matches no secret.
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO api_keys (id, project_id, name, key_hash, scope, created_at, revoked_at, last_used_at)
+		VALUES ($1, $2, $3, '', $4, $5, $6, $7)
+	`, id, projectID, key.Name, key.Scope, key.CreatedAt, revokedAt, key.LastUsedAt); err != nil {
+		return fmt.Errorf("restore api key %q: %w", key.ID, err)
+	}
+	result.APIKeys[key.ID] = id
+	return nil
+}
+
+func (r *PostgresRepository) restoreFlag(ctx context.Context, tx pgx.Tx, flag Flag) error {
+	variants, err := r.sealVariants(ctx, flag.ProjectID, flag.Key, flag.Variants)
+	if err != nil {
+		return err
+	}
+	var row Flag
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO flags (project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, ''), replace(gen_random_uuid()::text, '-', '')), $8, $9, COALESCE($10::text[], '{}'), $11, $12, $13, COALESCE($14::text[], '{}'), COALESCE($15::text[], '{}'), $16, $17, $18, $19, $20)
+		RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
+	`,
+		flag.ProjectID,
+		flag.Key,
+		flag.Description,
+		flag.Enabled,
+		variants,
+		ensureJSON(flag.Rules, "[]"),
+		flag.BucketingSalt,
+		flag.VariantsSchema,
+		flag.ShadowRules,
+		flag.Tags,
+		flag.Owner,
+		flag.ExpiresAt,
+		flag.Targets.Attribute,
+		flag.Targets.Allow,
+		flag.Targets.Deny,
+		flag.CreatedAt,
+		flag.UpdatedAt,
+		flag.CreatedBy,
+		flag.UpdatedBy,
+		flag.ArchivedAt,
+	).Scan(
+		&row.ProjectID,
+		&row.Key,
+		&row.Description,
+		&row.Enabled,
+		&row.Variants,
+		&row.Rules,
+		&row.BucketingSalt,
+		&row.VariantsSchema,
+		&row.ShadowRules,
+		&row.Tags,
+		&row.Owner,
+		&row.ExpiresAt,
+		&row.Targets.Attribute,
+		&row.Targets.Allow,
+		&row.Targets.Deny,
+		&row.CreatedAt,
+		&row.UpdatedAt,
+		&row.CreatedBy,
+		&row.UpdatedBy,
+	); err != nil {
+		return err
+	}
+	return insertFlagRevision(ctx, tx, row)
+}
+
+// remapActor rewrites an "api_key:<id>" actor to the ID the key was
+// restored under. Other actors are returned as they are.
+func remapActor(actor string, apiKeys map[string]string) string {
+	id, ok := strings.CutPrefix(actor, "api_key:")
+	if !ok {
+		return actor
+	}
+	if mapped, ok := apiKeys[id]; ok {
+		return "api_key:" + mapped
+	}
+	return actor
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ContextEnrichment lists attributes the server adds to every evaluation
+// context of a project. They go beneath the attributes the client sends, so
+// a client can still override any of them.
+type ContextEnrichment struct {
+	// Attributes are added as they are, such as {"environment": "prod"}.
+	Attributes map[string]any `json:"attributes"`
+	// Headers maps an attribute name to the request header, or gRPC
+	// metadata key, whose value it is set to. Missing headers add nothing.
+	Headers map[string]string `json:"headers"`
+	// APIKeyIDAttribute, when set, names an attribute set to the ID of the
+	// API key making the request.
+	APIKeyIDAttribute string `json:"api_key_id_attribute,omitempty"`
+}
+
+// GetContextEnrichment returns the context enrichment of a project. A
+// project that never configured one returns the zero value. Returns
+// pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetContextEnrichment(ctx context.Context, projectID string) (ContextEnrichment, error) {
+	var payload []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT context_enrichment
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&payload)
+	if err != nil {
+		return ContextEnrichment{}, fmt.Errorf("get context enrichment: %w", err)
+	}
+
+	var enrichment ContextEnrichment
+	if err := json.Unmarshal(payload, &enrichment); err != nil {
+		return ContextEnrichment{}, fmt.Errorf("decode context enrichment: %w", err)
+	}
+	return enrichment, nil
+}
+
+// SetContextEnrichment replaces the context enrichment of a project and
+// returns the stored value. Returns pgx.ErrNoRows (wrapped) if the project
+// does not exist.
+func (r *PostgresRepository) SetContextEnrichment(ctx context.Context, projectID string, enrichment ContextEnrichment) (ContextEnrichment, error) {
+	payload, err := json.Marshal(enrichment)
+	if err != nil {
+		return ContextEnrichment{}, fmt.Errorf("encode context enrichment: %w", err)
+	}
+
+	var stored []byte
+	err = r.pool.QueryRow(ctx, `
+		UPDATE projects
+		SET context_enrichment = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING context_enrichment
+	`, projectID, payload).Scan(&stored)
+	if err != nil {
+		return ContextEnrichment{}, fmt.Errorf("set context enrichment: %w", err)
+	}
+
+	var updated ContextEnrichment
+	if err := json.Unmarshal(stored, &updated); err != nil {
+		return ContextEnrichment{}, fmt.Errorf("decode context enrichment: %w", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// ContextPreset is a named evaluation context saved for a project, so rules
+// can be checked against the same realistic contexts every time.
+type ContextPreset struct {
+	ProjectID   string                 `json:"-"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Context     core.EvaluationContext `json:"context"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+const contextPresetColumns = `project_id, name, description, context, created_at, updated_at`
+
+func scanContextPreset(row pgx.Row) (ContextPreset, error) {
+	var p ContextPreset
+	err := row.Scan(&p.ProjectID, &p.Name, &p.Description, &p.Context, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// ListContextPresets returns a project's context presets ordered by name.
+func (r *PostgresRepository) ListContextPresets(ctx context.Context, projectID string) ([]ContextPreset, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+contextPresetColumns+`
+		FROM context_presets
+		WHERE project_id = $1
+		ORDER BY name
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list context presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := make([]ContextPreset, 0)
+	for rows.Next() {
+		p, err := scanContextPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan context preset: %w", err)
+		}
+		presets = append(presets, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list context presets rows: %w", err)
+	}
+	return presets, nil
+}
+
+// GetContextPreset retrieves a preset by project and name. Returns
+// pgx.ErrNoRows (wrapped) if it does not exist.
+func (r *PostgresRepository) GetContextPreset(ctx context.Context, projectID, name string) (ContextPreset, error) {
+	p, err := scanContextPreset(r.pool.QueryRow(ctx, `
+		SELECT `+contextPresetColumns+`
+		FROM context_presets
+		WHERE project_id = $1 AND name = $2
+	`, projectID, name))
+	if err != nil {
+		return ContextPreset{}, fmt.Errorf("get context preset: %w", err)
+	}
+	return p, nil
+}
+
+// PutContextPreset creates the preset or replaces the one with the same
+// name, and returns the stored preset.
+func (r *PostgresRepository) PutContextPreset(ctx context.Context, preset ContextPreset) (ContextPreset, error) {
+	p, err := scanContextPreset(r.pool.QueryRow(ctx, `
+		INSERT INTO context_presets (project_id, name, description, context)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, name) DO UPDATE
+		SET description = EXCLUDED.description,
+		    context = EXCLUDED.context,
+		    updated_at = NOW()
+		RETURNING `+contextPresetColumns,
+		preset.ProjectID, preset.Name, preset.Description, preset.Context,
+	))
+	if err != nil {
+		return ContextPreset{}, fmt.Errorf("put context preset: %w", err)
+	}
+	return p, nil
+}
+
+// DeleteContextPreset removes a preset. Returns pgx.ErrNoRows (wrapped) if
+// it does not exist.
+func (r *PostgresRepository) DeleteContextPreset(ctx context.Context, projectID, name string) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		DELETE FROM context_presets
+		WHERE project_id = $1 AND name = $2
+	`, projectID, name)
+	if err != nil {
+		return fmt.Errorf("delete context preset: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("delete context preset: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Types a [ContextAttribute] can declare, named after their JSON types.
+const (
+	ContextAttributeString  = "string"
+	ContextAttributeNumber  = "number"
+	ContextAttributeBoolean = "boolean"
+	ContextAttributeArray   = "array"
+)
+
+// ContextSchema is a project's registry of the evaluation context
+// attributes its applications send.
+type ContextSchema struct {
+	// Strict reports evaluation contexts that use attributes missing from
+	// Attributes, or values of the wrong type.
+	Strict     bool               `json:"strict"`
+	Attributes []ContextAttribute `json:"attributes"`
+}
+
+// ContextAttribute describes one evaluation context attribute.
+type ContextAttribute struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Sensitive attributes, such as email addresses, have their values
+	// replaced by "[REDACTED]" wherever evaluation contexts are logged.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// GetContextSchema returns the context schema of a project. A project that
+// never configured one returns the zero value. Returns pgx.ErrNoRows
+// (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetContextSchema(ctx context.Context, projectID string) (ContextSchema, error) {
+	var payload []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT context_schema
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&payload)
+	if err != nil {
+		return ContextSchema{}, fmt.Errorf("get context schema: %w", err)
+	}
+
+	var schema ContextSchema
+	if err := json.Unmarshal(payload, &schema); err != nil {
+		return ContextSchema{}, fmt.Errorf("decode context schema: %w", err)
+	}
+	return schema, nil
+}
+
+// SetContextSchema replaces the context schema of a project and returns the
+// stored value. Returns pgx.ErrNoRows (wrapped) if the project does not
+// exist.
+func (r *PostgresRepository) SetContextSchema(ctx context.Context, projectID string, schema ContextSchema) (ContextSchema, error) {
+	payload, err := json.Marshal(schema)
+	if err != nil {
+		return ContextSchema{}, fmt.Errorf("encode context schema: %w", err)
+	}
+
+	var stored []byte
+	err = r.pool.QueryRow(ctx, `
+		UPDATE projects
+		SET context_schema = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING context_schema
+	`, projectID, payload).Scan(&stored)
+	if err != nil {
+		return ContextSchema{}, fmt.Errorf("set context schema: %w", err)
+	}
+
+	var updated ContextSchema
+	if err := json.Unmarshal(stored, &updated); err != nil {
+		return ContextSchema{}, fmt.Errorf("decode context schema: %w", err)
+	}
+	return updated, nil
+}
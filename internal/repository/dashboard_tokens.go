@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// DashboardToken is a short-lived token that reads one project through the
+// HTTP API, for internal dashboards that should not hold a long-lived API
+// key. See [middleware.DashboardTokenPrefix].
+type DashboardToken struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	TokenHash string    `json:"-"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateDashboardToken mints a dashboard token for projectID that expires
+// after ttl, on behalf of the admin user createdBy. It returns the token's
+// secret, which is not stored, alongside the token. Tokens that expired more
+// than a day ago are deleted on the way.
+func (r *PostgresRepository) CreateDashboardToken(ctx context.Context, projectID, createdBy string, ttl time.Duration) (DashboardToken, string, error) {
+	id, err := generateRandomHex(16)
+	if err != nil {
+		return DashboardToken{}, "", fmt.Errorf("generate dashboard token id: %w", err)
+	}
+	secret, err := generateRandomHex(32)
+	if err != nil {
+		return DashboardToken{}, "", fmt.Errorf("generate dashboard token secret: %w", err)
+	}
+	hash, err := middleware.HashAPIKey(secret)
+	if err != nil {
+		return DashboardToken{}, "", fmt.Errorf("hash dashboard token: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, `DELETE FROM dashboard_tokens WHERE expires_at < NOW() - INTERVAL '1 day'`); err != nil {
+		return DashboardToken{}, "", fmt.Errorf("delete expired dashboard tokens: %w", err)
+	}
+
+	token := DashboardToken{ID: middleware.DashboardTokenPrefix + id, ProjectID: projectID, TokenHash: hash, CreatedBy: createdBy}
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO dashboard_tokens (id, project_id, token_hash, created_by, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NOW() + make_interval(secs => $5::bigint))
+		RETURNING expires_at, created_at
+	`, token.ID, projectID, hash, createdBy, int64(ttl/time.Second)).Scan(&token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		return DashboardToken{}, "", fmt.Errorf("create dashboard token: %w", err)
+	}
+	return token, secret, nil
+}
+
+// LookupDashboardToken returns an unexpired dashboard token. Callers should
+// compare the secret against TokenHash outside this package. Returns
+// pgx.ErrNoRows (wrapped) if the token does not exist or has expired.
+func (r *PostgresRepository) LookupDashboardToken(ctx context.Context, id string) (DashboardToken, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.LookupDashboardToken")
+	defer span.End()
+
+	var token DashboardToken
+	var createdBy *string
+	if err := r.pool.QueryRow(ctx, `
+		SELECT id, project_id, token_hash, created_by::text, expires_at, created_at
+		FROM dashboard_tokens
+		WHERE id = $1
+		  AND expires_at > NOW()
+	`, id).Scan(&token.ID, &token.ProjectID, &token.TokenHash, &createdBy, &token.ExpiresAt, &token.CreatedAt); err != nil {
+		span.RecordError(err)
+		return DashboardToken{}, fmt.Errorf("lookup dashboard token: %w", err)
+	}
+	if createdBy != nil {
+		token.CreatedBy = *createdBy
+	}
+	return token, nil
+}
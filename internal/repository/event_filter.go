@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventFilter narrows the flag events of a project to some flag keys and
+// event types. An empty list matches every key or type.
+type EventFilter struct {
+	Keys  []string
+	Types []string
+}
+
+// Matches reports whether event passes the filter.
+func (f EventFilter) Matches(event FlagEvent) bool {
+	return (len(f.Keys) == 0 || slices.Contains(f.Keys, event.FlagKey)) &&
+		(len(f.Types) == 0 || slices.Contains(f.Types, event.EventType))
+}
+
+// ListEventsSinceMatching returns up to the configured event batch size
+// (default 1000) flag events of a project with IDs greater than eventID that
+// pass filter, ordered by event ID. The filter is applied by the query, so a
+// batch is never padded with events the caller would discard.
+func (r *PostgresRepository) ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter EventFilter) ([]FlagEvent, error) {
+	keys, types := filter.Keys, filter.Types
+	if keys == nil {
+		keys = []string{}
+	}
+	if types == nil {
+		types = []string{}
+	}
+	events, err := readWithFallback(ctx, r, "list_events_since_matching", func(pool *pgxpool.Pool) ([]FlagEvent, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT event_id, project_id, flag_key, event_type, payload, created_at
+			FROM flag_events
+			WHERE event_id > $1 AND project_id = $2
+			  AND (cardinality($3::text[]) = 0 OR flag_key = ANY($3))
+			  AND (cardinality($4::text[]) = 0 OR event_type = ANY($4))
+			ORDER BY event_id
+			LIMIT $5
+		`, eventID, projectID, keys, types, r.eventBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("list events since matching: %w", err)
+		}
+		return collectFlagEvents(rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventQuery selects a page of one project's flag events for browsing its
+// history. Empty fields do not filter.
+type EventQuery struct {
+	ProjectID string
+	Keys      []string
+	Types     []string
+	// Actor matches events made by one actor, in the form of
+	// [Flag.UpdatedBy].
+	Actor string
+	// Since and Until bound the events' creation time to [Since, Until).
+	Since, Until time.Time
+	// AfterID resumes a query after the event with this ID.
+	AfterID int64
+	Limit   int
+}
+
+// QueryEvents returns up to q.Limit flag events that match q, ordered by
+// event ID, with their actors. The filters are applied by the query.
+func (r *PostgresRepository) QueryEvents(ctx context.Context, q EventQuery) ([]FlagEvent, error) {
+	keys, types := q.Keys, q.Types
+	if keys == nil {
+		keys = []string{}
+	}
+	if types == nil {
+		types = []string{}
+	}
+	var since, until *time.Time
+	if !q.Since.IsZero() {
+		since = &q.Since
+	}
+	if !q.Until.IsZero() {
+		until = &q.Until
+	}
+	events, err := readWithFallback(ctx, r, "query_events", func(pool *pgxpool.Pool) ([]FlagEvent, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT event_id, project_id, flag_key, event_type, payload, created_at, actor
+			FROM flag_events
+			WHERE event_id > $1 AND project_id = $2
+			  AND (cardinality($3::text[]) = 0 OR flag_key = ANY($3))
+			  AND (cardinality($4::text[]) = 0 OR event_type = ANY($4))
+			  AND ($5 = '' OR actor = $5)
+			  AND ($6::timestamptz IS NULL OR created_at >= $6)
+			  AND ($7::timestamptz IS NULL OR created_at < $7)
+			ORDER BY event_id
+			LIMIT $8
+		`, q.AfterID, q.ProjectID, keys, types, q.Actor, since, until, q.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("query events: %w", err)
+		}
+		return collectFlagEventsWithActor(rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// collectFlagEventsWithActor is collectFlagEvents for rows that also select
+// the actor.
+func collectFlagEventsWithActor(rows pgx.Rows) ([]FlagEvent, error) {
+	defer rows.Close()
+
+	events := make([]FlagEvent, 0)
+	for rows.Next() {
+		var event FlagEvent
+		if err := rows.Scan(
+			&event.EventID,
+			&event.ProjectID,
+			&event.FlagKey,
+			&event.EventType,
+			&event.Payload,
+			&event.CreatedAt,
+			&event.Actor,
+		); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query events rows: %w", err)
+	}
+	return events, nil
+}
+
+// QueryEvents is [PostgresRepository.QueryEvents] for SQLite.
+func (r *SQLiteRepository) QueryEvents(ctx context.Context, q EventQuery) ([]FlagEvent, error) {
+	query := `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at, actor
+		FROM flag_events
+		WHERE event_id > ? AND project_id = ?`
+	args := []any{q.AfterID, q.ProjectID}
+	for _, in := range []struct {
+		column string
+		values []string
+	}{{"flag_key", q.Keys}, {"event_type", q.Types}} {
+		if len(in.values) == 0 {
+			continue
+		}
+		query += " AND " + in.column + " IN (?" + strings.Repeat(", ?", len(in.values)-1) + ")"
+		for _, value := range in.values {
+			args = append(args, value)
+		}
+	}
+	if q.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, q.Actor)
+	}
+	if !q.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, formatSQLiteTime(q.Since))
+	}
+	if !q.Until.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, formatSQLiteTime(q.Until))
+	}
+	query += `
+		ORDER BY event_id
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, q.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]FlagEvent, 0)
+	for rows.Next() {
+		var event FlagEvent
+		if err := rows.Scan(
+			&event.EventID,
+			&event.ProjectID,
+			&event.FlagKey,
+			&event.EventType,
+			(*[]byte)(&event.Payload),
+			sqliteTime{&event.CreatedAt},
+			&event.Actor,
+		); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query events rows: %w", err)
+	}
+	return events, nil
+}
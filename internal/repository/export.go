@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListAuditLogRange returns up to limit audit log entries of every project
+// with IDs greater than afterID and created in [from, to), ordered by ID.
+// Exports page through the log by passing the last ID seen as afterID.
+func (r *PostgresRepository) ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]AuditLogEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at
+		FROM audit_log
+		WHERE id > $1
+		  AND created_at >= $2
+		  AND created_at < $3
+		ORDER BY id
+		LIMIT $4
+	`, afterID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log range: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditLogEntry, 0)
+	for rows.Next() {
+		var e AuditLogEntry
+		if err := rows.Scan(&e.ID, &e.ProjectID, &e.APIKeyID, &e.AdminUserID, &e.Action, &e.FlagKey, &e.Details, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list audit log range rows: %w", err)
+	}
+	return entries, nil
+}
+
+// ListEventsRange returns up to limit flag events of every project with IDs
+// greater than afterEventID and created in [from, to), ordered by event ID.
+// Payloads are returned as stored, so variants encrypted by
+// [WithVariantsEncryption] stay encrypted.
+func (r *PostgresRepository) ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]FlagEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > $1
+		  AND created_at >= $2
+		  AND created_at < $3
+		ORDER BY event_id
+		LIMIT $4
+	`, afterEventID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list events range: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]FlagEvent, 0)
+	for rows.Next() {
+		var event FlagEvent
+		if err := rows.Scan(
+			&event.EventID,
+			&event.ProjectID,
+			&event.FlagKey,
+			&event.EventType,
+			&event.Payload,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list events range rows: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ClaimExpiredFlags marks every flag whose ExpiresAt is at or before now and
+// whose expiry has not been reported yet as reported, and returns them. The
+// claim is a single UPDATE, so when several replicas look at once each
+// expired flag is returned to exactly one of them. Flags of soft-deleted
+// projects are skipped.
+func (r *PostgresRepository) ClaimExpiredFlags(ctx context.Context, now time.Time) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.ClaimExpiredFlags")
+	defer span.End()
+
+	rows, err := r.pool.Query(ctx, `
+		UPDATE flags f
+		SET expiry_notified_at = $1
+		FROM projects p
+		WHERE p.id = f.project_id
+		  AND p.deleted_at IS NULL
+		  AND f.archived_at IS NULL
+		  AND f.expires_at <= $1
+		  AND f.expiry_notified_at IS NULL
+		RETURNING f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by
+	`, now)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "claim expired flags failed")
+		return nil, fmt.Errorf("claim expired flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []Flag
+	for rows.Next() {
+		var flag Flag
+		if err := rows.Scan(
+			&flag.ProjectID,
+			&flag.Key,
+			&flag.Description,
+			&flag.Enabled,
+			&flag.Variants,
+			&flag.Rules,
+			&flag.BucketingSalt,
+			&flag.VariantsSchema,
+			&flag.ShadowRules,
+			&flag.Tags,
+			&flag.Owner,
+			&flag.ExpiresAt,
+			&flag.Targets.Attribute,
+			&flag.Targets.Allow,
+			&flag.Targets.Deny,
+			&flag.CreatedAt,
+			&flag.UpdatedAt,
+			&flag.CreatedBy,
+			&flag.UpdatedBy,
+		); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "scan expired flag failed")
+			return nil, fmt.Errorf("scan expired flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "iterate expired flags failed")
+		return nil, fmt.Errorf("iterating expired flag rows: %w", err)
+	}
+	if err := r.openFlags(ctx, flags); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "decrypt expired flags failed")
+		return nil, err
+	}
+	return flags, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// FlagKeyPolicy is a project's naming convention for flag keys. Each field
+// is a separate requirement and is ignored when it is zero, so the zero
+// value allows any key.
+type FlagKeyPolicy struct {
+	// Pattern is a regular expression that the whole key must match.
+	Pattern string `json:"pattern,omitempty"`
+	// MaxLength is the maximum key length in characters.
+	MaxLength int `json:"max_length,omitempty"`
+	// Prefix is a string every key must start with.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// GetFlagKeyPolicy returns the flag key policy configured for a project. A
+// project that never configured one returns the zero value. Returns
+// pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetFlagKeyPolicy(ctx context.Context, projectID string) (FlagKeyPolicy, error) {
+	var payload []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT flag_key_policy
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&payload)
+	if err != nil {
+		return FlagKeyPolicy{}, fmt.Errorf("get flag key policy: %w", err)
+	}
+
+	var policy FlagKeyPolicy
+	if err := json.Unmarshal(payload, &policy); err != nil {
+		return FlagKeyPolicy{}, fmt.Errorf("decode flag key policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetFlagKeyPolicy replaces the flag key policy of a project and returns the
+// stored value. Returns pgx.ErrNoRows (wrapped) if the project does not
+// exist.
+func (r *PostgresRepository) SetFlagKeyPolicy(ctx context.Context, projectID string, policy FlagKeyPolicy) (FlagKeyPolicy, error) {
+	payload, err := json.Marshal(policy)
+	if err != nil {
+		return FlagKeyPolicy{}, fmt.Errorf("encode flag key policy: %w", err)
+	}
+
+	var stored []byte
+	err = r.pool.QueryRow(ctx, `
+		UPDATE projects
+		SET flag_key_policy = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING flag_key_policy
+	`, projectID, payload).Scan(&stored)
+	if err != nil {
+		return FlagKeyPolicy{}, fmt.Errorf("set flag key policy: %w", err)
+	}
+
+	var updated FlagKeyPolicy
+	if err := json.Unmarshal(stored, &updated); err != nil {
+		return FlagKeyPolicy{}, fmt.Errorf("decode flag key policy: %w", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FlagRevision is a snapshot of a flag taken when it was created or
+// updated. Revisions are numbered from 1 per flag and are deleted with it.
+type FlagRevision struct {
+	Revision  int       `json:"revision"`
+	Flag      Flag      `json:"flag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// insertFlagRevision records flag as the next revision of its key. It runs in
+// the transaction that wrote the flag, after the write has locked the flag
+// row, so concurrent writers number their revisions in commit order.
+func insertFlagRevision(ctx context.Context, tx pgx.Tx, flag Flag) error {
+	body, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("marshal flag revision: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO flag_revisions (project_id, flag_key, revision, flag, created_at)
+		SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3, $4
+		FROM flag_revisions
+		WHERE project_id = $1 AND flag_key = $2
+	`, flag.ProjectID, flag.Key, body, flag.UpdatedAt); err != nil {
+		return fmt.Errorf("insert flag revision: %w", err)
+	}
+	return nil
+}
+
+func scanFlagRevision(row pgx.Row, projectID string) (FlagRevision, error) {
+	var (
+		rev  FlagRevision
+		body []byte
+	)
+	if err := row.Scan(&rev.Revision, &body, &rev.CreatedAt); err != nil {
+		return FlagRevision{}, err
+	}
+	if err := json.Unmarshal(body, &rev.Flag); err != nil {
+		return FlagRevision{}, fmt.Errorf("decode flag revision %d: %w", rev.Revision, err)
+	}
+	rev.Flag.ProjectID = projectID
+	return rev, nil
+}
+
+// ListFlagRevisions returns up to limit revisions of a flag, newest first.
+func (r *PostgresRepository) ListFlagRevisions(ctx context.Context, projectID, key string, limit int) ([]FlagRevision, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT revision, flag, created_at
+		FROM flag_revisions
+		WHERE project_id = $1 AND flag_key = $2
+		ORDER BY revision DESC
+		LIMIT $3
+	`, projectID, key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list flag revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]FlagRevision, 0)
+	for rows.Next() {
+		rev, err := scanFlagRevision(rows, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("scan flag revision: %w", err)
+		}
+		if err := r.openFlag(ctx, &rev.Flag); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list flag revisions rows: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetFlagRevision retrieves one revision of a flag. Returns pgx.ErrNoRows
+// (wrapped) if it does not exist.
+func (r *PostgresRepository) GetFlagRevision(ctx context.Context, projectID, key string, revision int) (FlagRevision, error) {
+	rev, err := scanFlagRevision(r.pool.QueryRow(ctx, `
+		SELECT revision, flag, created_at
+		FROM flag_revisions
+		WHERE project_id = $1 AND flag_key = $2 AND revision = $3
+	`, projectID, key, revision), projectID)
+	if err != nil {
+		return FlagRevision{}, fmt.Errorf("get flag revision: %w", err)
+	}
+	if err := r.openFlag(ctx, &rev.Flag); err != nil {
+		return FlagRevision{}, err
+	}
+	return rev, nil
+}
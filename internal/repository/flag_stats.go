@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FlagStats holds evaluation counters for a flag. TrueEvaluations is the
+// part of Evaluations that served true. LastEvaluatedAt is nil for a flag
+// that has never been evaluated.
+type FlagStats struct {
+	ProjectID       string     `json:"-"`
+	FlagKey         string     `json:"key"`
+	Evaluations     int64      `json:"evaluations"`
+	TrueEvaluations int64      `json:"true_evaluations"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+}
+
+// TruePercent returns the percentage of evaluations that served true, or 0
+// for a flag that has never been evaluated.
+func (s FlagStats) TruePercent() float64 {
+	if s.Evaluations == 0 {
+		return 0
+	}
+	return 100 * float64(s.TrueEvaluations) / float64(s.Evaluations)
+}
+
+// AddFlagStats adds each entry's evaluation count to the stored totals and
+// advances last_evaluated_at, in a single statement. Entries for flags that
+// no longer exist are dropped.
+func (r *PostgresRepository) AddFlagStats(ctx context.Context, stats []FlagStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	projectIDs := make([]string, len(stats))
+	keys := make([]string, len(stats))
+	counts := make([]int64, len(stats))
+	trueCounts := make([]int64, len(stats))
+	lastEvaluated := make([]*time.Time, len(stats))
+	for i, s := range stats {
+		projectIDs[i] = s.ProjectID
+		keys[i] = s.FlagKey
+		counts[i] = s.Evaluations
+		trueCounts[i] = s.TrueEvaluations
+		lastEvaluated[i] = s.LastEvaluatedAt
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO flag_stats (project_id, flag_key, evaluations, true_evaluations, last_evaluated_at)
+		SELECT s.project_id, s.flag_key, s.evaluations, s.true_evaluations, s.last_evaluated_at
+		FROM unnest($1::uuid[], $2::text[], $3::bigint[], $4::bigint[], $5::timestamptz[])
+			AS s(project_id, flag_key, evaluations, true_evaluations, last_evaluated_at)
+		JOIN flags f ON f.project_id = s.project_id AND f.key = s.flag_key
+		ON CONFLICT (project_id, flag_key) DO UPDATE
+		SET evaluations = flag_stats.evaluations + EXCLUDED.evaluations,
+			true_evaluations = flag_stats.true_evaluations + EXCLUDED.true_evaluations,
+			last_evaluated_at = GREATEST(flag_stats.last_evaluated_at, EXCLUDED.last_evaluated_at)
+	`, projectIDs, keys, counts, trueCounts, lastEvaluated)
+	if err != nil {
+		return fmt.Errorf("add flag stats: %w", err)
+	}
+	return nil
+}
+
+// ListFlagStats returns the stored stats of every evaluated flag in a
+// project. Flags that were never evaluated have no entry.
+func (r *PostgresRepository) ListFlagStats(ctx context.Context, projectID string) ([]FlagStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT project_id, flag_key, evaluations, true_evaluations, last_evaluated_at
+		FROM flag_stats
+		WHERE project_id = $1
+		ORDER BY flag_key
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list flag stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]FlagStats, 0)
+	for rows.Next() {
+		var s FlagStats
+		if err := rows.Scan(&s.ProjectID, &s.FlagKey, &s.Evaluations, &s.TrueEvaluations, &s.LastEvaluatedAt); err != nil {
+			return nil, fmt.Errorf("scan flag stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list flag stats rows: %w", err)
+	}
+	return stats, nil
+}
+
+// GetFlagStats returns the stored stats of a flag. Returns pgx.ErrNoRows
+// (wrapped) if the flag has never been evaluated or does not exist.
+func (r *PostgresRepository) GetFlagStats(ctx context.Context, projectID, key string) (FlagStats, error) {
+	var s FlagStats
+	err := r.pool.QueryRow(ctx, `
+		SELECT project_id, flag_key, evaluations, true_evaluations, last_evaluated_at
+		FROM flag_stats
+		WHERE project_id = $1 AND flag_key = $2
+	`, projectID, key).Scan(&s.ProjectID, &s.FlagKey, &s.Evaluations, &s.TrueEvaluations, &s.LastEvaluatedAt)
+	if err != nil {
+		return FlagStats{}, fmt.Errorf("get flag stats: %w", err)
+	}
+	return s, nil
+}
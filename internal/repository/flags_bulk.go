@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BulkUpdateFlags stores updates and archives the flags of projectID keyed by
+// archiveKeys in a single transaction, and returns the updated records in
+// input order. If any flag does not exist or is already archived, or any
+// write fails, nothing changes, and the returned error names the offending
+// flag key; a missing flag is pgx.ErrNoRows (wrapped). Each update is stored
+// as by [PostgresRepository.UpdateFlag].
+func (r *PostgresRepository) BulkUpdateFlags(ctx context.Context, projectID string, updates []Flag, archiveKeys []string) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.BulkUpdateFlags",
+		trace.WithAttributes(
+			attribute.String("project_id", projectID),
+			attribute.Int("flag_count", len(updates)+len(archiveKeys)),
+		))
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin bulk update flags tx failed")
+		return nil, fmt.Errorf("begin bulk update flags tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	updated := make([]Flag, 0, len(updates))
+	for _, flag := range updates {
+		flag.ProjectID = projectID
+		row, err := r.updateFlagTx(ctx, tx, flag)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "bulk update flags failed")
+			return nil, fmt.Errorf("update flag %q: %w", flag.Key, err)
+		}
+		updated = append(updated, row)
+	}
+	for _, key := range archiveKeys {
+		commandTag, err := tx.Exec(ctx, `
+			UPDATE flags
+			SET archived_at = NOW()
+			WHERE project_id = $1 AND key = $2 AND archived_at IS NULL
+		`, projectID, key)
+		if err == nil && commandTag.RowsAffected() == 0 {
+			err = pgx.ErrNoRows
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "bulk update flags failed")
+			return nil, fmt.Errorf("archive flag %q: %w", key, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit bulk update flags tx failed")
+		return nil, fmt.Errorf("commit bulk update flags tx: %w", err)
+	}
+
+	return updated, nil
+}
+
+// RestoreFlags brings back the archived flags of projectID keyed by keys in
+// a single transaction, and returns them in input order. If any flag does
+// not exist or is not archived nothing changes, and the returned error
+// names the offending flag key as pgx.ErrNoRows (wrapped).
+func (r *PostgresRepository) RestoreFlags(ctx context.Context, projectID string, keys []string) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.RestoreFlags",
+		trace.WithAttributes(
+			attribute.String("project_id", projectID),
+			attribute.Int("flag_count", len(keys)),
+		))
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin restore flags tx failed")
+		return nil, fmt.Errorf("begin restore flags tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	restored := make([]Flag, 0, len(keys))
+	for _, key := range keys {
+		var flag Flag
+		err := tx.QueryRow(ctx, `
+			UPDATE flags
+			SET archived_at = NULL
+			WHERE project_id = $1 AND key = $2 AND archived_at IS NOT NULL
+			RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
+		`, projectID, key).Scan(
+			&flag.ProjectID,
+			&flag.Key,
+			&flag.Description,
+			&flag.Enabled,
+			&flag.Variants,
+			&flag.Rules,
+			&flag.BucketingSalt,
+			&flag.VariantsSchema,
+			&flag.ShadowRules,
+			&flag.Tags,
+			&flag.Owner,
+			&flag.ExpiresAt,
+			&flag.Targets.Attribute,
+			&flag.Targets.Allow,
+			&flag.Targets.Deny,
+			&flag.CreatedAt,
+			&flag.UpdatedAt,
+			&flag.CreatedBy,
+			&flag.UpdatedBy,
+		)
+		if err == nil {
+			err = r.openFlag(ctx, &flag)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "restore flags failed")
+			return nil, fmt.Errorf("restore flag %q: %w", key, err)
+		}
+		restored = append(restored, flag)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit restore flags tx failed")
+		return nil, fmt.Errorf("commit restore flags tx: %w", err)
+	}
+
+	return restored, nil
+}
+
+// ListArchivedFlags returns the archived flags of projectID ordered by key,
+// with ArchivedAt set.
+func (r *PostgresRepository) ListArchivedFlags(ctx context.Context, projectID string) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.ListArchivedFlags",
+		trace.WithAttributes(attribute.String("project_id", projectID)))
+	defer span.End()
+
+	flags, err := readWithFallback(ctx, r, "list_archived_flags", func(pool *pgxpool.Pool) ([]Flag, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by, f.archived_at
+			FROM flags f
+			JOIN projects p ON p.id = f.project_id
+			WHERE f.project_id = $1 AND f.archived_at IS NOT NULL AND p.deleted_at IS NULL
+			ORDER BY f.key
+		`, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("list archived flags: %w", err)
+		}
+		defer rows.Close()
+
+		flags := make([]Flag, 0)
+		for rows.Next() {
+			var flag Flag
+			if err := rows.Scan(
+				&flag.ProjectID,
+				&flag.Key,
+				&flag.Description,
+				&flag.Enabled,
+				&flag.Variants,
+				&flag.Rules,
+				&flag.BucketingSalt,
+				&flag.VariantsSchema,
+				&flag.ShadowRules,
+				&flag.Tags,
+				&flag.Owner,
+				&flag.ExpiresAt,
+				&flag.Targets.Attribute,
+				&flag.Targets.Allow,
+				&flag.Targets.Deny,
+				&flag.CreatedAt,
+				&flag.UpdatedAt,
+				&flag.CreatedBy,
+				&flag.UpdatedBy,
+				&flag.ArchivedAt,
+			); err != nil {
+				return nil, fmt.Errorf("scan archived flag: %w", err)
+			}
+			flags = append(flags, flag)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("list archived flags rows: %w", err)
+		}
+		return flags, nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list archived flags failed")
+		return nil, err
+	}
+	if err := r.openFlags(ctx, flags); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list archived flags failed")
+		return nil, err
+	}
+	return flags, nil
+}
+
+// BulkUpdateFlags is [PostgresRepository.BulkUpdateFlags] for SQLite.
+func (r *SQLiteRepository) BulkUpdateFlags(ctx context.Context, projectID string, updates []Flag, archiveKeys []string) ([]Flag, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin bulk update flags tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated := make([]Flag, 0, len(updates))
+	for _, flag := range updates {
+		flag.ProjectID = projectID
+		row, err := updateSQLiteFlag(ctx, tx, flag)
+		if err != nil {
+			return nil, fmt.Errorf("update flag %q: %w", flag.Key, err)
+		}
+		updated = append(updated, row)
+	}
+	now := formatSQLiteTime(time.Now().UTC())
+	for _, key := range archiveKeys {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE flags
+			SET archived_at = ?
+			WHERE project_id = ? AND key = ? AND archived_at IS NULL
+		`, now, projectID, key)
+		if err == nil {
+			err = sqliteNoRows(result, "archive flag")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive flag %q: %w", key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit bulk update flags tx: %w", err)
+	}
+	return updated, nil
+}
+
+// RestoreFlags is [PostgresRepository.RestoreFlags] for SQLite.
+func (r *SQLiteRepository) RestoreFlags(ctx context.Context, projectID string, keys []string) ([]Flag, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin restore flags tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	restored := make([]Flag, 0, len(keys))
+	for _, key := range keys {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE flags
+			SET archived_at = NULL
+			WHERE project_id = ? AND key = ? AND archived_at IS NOT NULL
+		`, projectID, key)
+		if err == nil {
+			err = sqliteNoRows(result, "restore flag")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("restore flag %q: %w", key, err)
+		}
+		flag, err := getSQLiteFlag(ctx, tx, projectID, key, "restore flag")
+		if err != nil {
+			return nil, fmt.Errorf("restore flag %q: %w", key, err)
+		}
+		restored = append(restored, flag)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit restore flags tx: %w", err)
+	}
+	return restored, nil
+}
+
+// ListArchivedFlags is [PostgresRepository.ListArchivedFlags] for SQLite.
+func (r *SQLiteRepository) ListArchivedFlags(ctx context.Context, projectID string) ([]Flag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sqliteFlagColumns+`, f.archived_at
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id = ? AND f.archived_at IS NOT NULL AND p.deleted_at IS NULL
+		ORDER BY f.key
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list archived flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]Flag, 0)
+	for rows.Next() {
+		var archivedAt *time.Time
+		flag, err := scanSQLiteFlag(sqliteScanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, sqliteNullTime{&archivedAt})...)
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("scan archived flag: %w", err)
+		}
+		flag.ArchivedAt = archivedAt
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list archived flags rows: %w", err)
+	}
+	return flags, nil
+}
+
+// sqliteScanFunc adapts a function to the row interface scanSQLiteFlag
+// reads, so callers can scan extra columns after the flag's.
+type sqliteScanFunc func(dest ...any) error
+
+func (f sqliteScanFunc) Scan(dest ...any) error { return f(dest...) }
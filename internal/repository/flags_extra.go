@@ -3,14 +3,19 @@ package repository
 import (
 	"context"
 	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ListFlagsByProject returns all flags for a specific project.
+// ListFlagsByProject returns all flags for a specific project except
+// archived ones.
 func (r *PostgresRepository) ListFlagsByProject(ctx context.Context, projectID string) ([]Flag, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT project_id, key, description, enabled, variants, rules, created_at, updated_at
+		SELECT project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
 		FROM flags
-		WHERE project_id = $1
+		WHERE project_id = $1 AND archived_at IS NULL
 		ORDER BY key
 	`, projectID)
 	if err != nil {
@@ -28,8 +33,19 @@ func (r *PostgresRepository) ListFlagsByProject(ctx context.Context, projectID s
 			&flag.Enabled,
 			&flag.Variants,
 			&flag.Rules,
+			&flag.BucketingSalt,
+			&flag.VariantsSchema,
+			&flag.ShadowRules,
+			&flag.Tags,
+			&flag.Owner,
+			&flag.ExpiresAt,
+			&flag.Targets.Attribute,
+			&flag.Targets.Allow,
+			&flag.Targets.Deny,
 			&flag.CreatedAt,
 			&flag.UpdatedAt,
+			&flag.CreatedBy,
+			&flag.UpdatedBy,
 		); err != nil {
 			return nil, fmt.Errorf("scan flag: %w", err)
 		}
@@ -40,6 +56,171 @@ func (r *PostgresRepository) ListFlagsByProject(ctx context.Context, projectID s
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("list flags rows: %w", err)
 	}
+	if err := r.openFlags(ctx, flags); err != nil {
+		return nil, err
+	}
 
 	return flags, nil
 }
+
+// CreateFlags inserts flags in a single transaction and returns the created
+// records in input order. If any insert fails the whole batch is rolled back
+// and the returned error names the offending flag key.
+func (r *PostgresRepository) CreateFlags(ctx context.Context, flags []Flag) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.CreateFlags",
+		trace.WithAttributes(attribute.Int("flag_count", len(flags))))
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin create flags tx failed")
+		return nil, fmt.Errorf("begin create flags tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	created := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		variants, err := r.sealVariants(ctx, flag.ProjectID, flag.Key, flag.Variants)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "create flags failed")
+			return nil, fmt.Errorf("create flag %q: %w", flag.Key, err)
+		}
+		var row Flag
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO flags (project_id, key, description, enabled, variants, rules, variants_schema, tags, owner, expires_at, shadow_rules, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::text[], '{}'), $9, $10, $11, $12, $12)
+			RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
+		`,
+			flag.ProjectID,
+			flag.Key,
+			flag.Description,
+			flag.Enabled,
+			variants,
+			ensureJSON(flag.Rules, "[]"),
+			flag.VariantsSchema,
+			flag.Tags,
+			flag.Owner,
+			flag.ExpiresAt,
+			flag.ShadowRules,
+			flag.CreatedBy,
+		).Scan(
+			&row.ProjectID,
+			&row.Key,
+			&row.Description,
+			&row.Enabled,
+			&row.Variants,
+			&row.Rules,
+			&row.BucketingSalt,
+			&row.VariantsSchema,
+			&row.ShadowRules,
+			&row.Tags,
+			&row.Owner,
+			&row.ExpiresAt,
+			&row.Targets.Attribute,
+			&row.Targets.Allow,
+			&row.Targets.Deny,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+			&row.CreatedBy,
+			&row.UpdatedBy,
+		); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "create flags failed")
+			return nil, fmt.Errorf("create flag %q: %w", flag.Key, err)
+		}
+		if err := insertFlagRevision(ctx, tx, row); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "create flags failed")
+			return nil, fmt.Errorf("create flag %q: %w", flag.Key, err)
+		}
+		if err := r.openFlag(ctx, &row); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "create flags failed")
+			return nil, err
+		}
+		created = append(created, row)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit create flags tx failed")
+		return nil, fmt.Errorf("commit create flags tx: %w", err)
+	}
+
+	return created, nil
+}
+
+// RotateBucketingSalt replaces a flag's bucketing salt with a freshly
+// generated one and returns the updated flag. Every targeting key is
+// reassigned to a new percentage-rollout bucket as a result. The change is
+// recorded as a new revision. Returns pgx.ErrNoRows (wrapped) if the flag
+// does not exist.
+func (r *PostgresRepository) RotateBucketingSalt(ctx context.Context, projectID, key string) (Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.RotateBucketingSalt",
+		trace.WithAttributes(
+			attribute.String("flag_key", key),
+			attribute.String("project_id", projectID),
+		))
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin rotate bucketing salt tx failed")
+		return Flag{}, fmt.Errorf("begin rotate bucketing salt tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var flag Flag
+	err = tx.QueryRow(ctx, `
+		UPDATE flags
+		SET bucketing_salt = replace(gen_random_uuid()::text, '-', ''),
+		    updated_at = NOW()
+		WHERE project_id = $1 AND key = $2 AND archived_at IS NULL
+		RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
+	`, projectID, key).Scan(
+		&flag.ProjectID,
+		&flag.Key,
+		&flag.Description,
+		&flag.Enabled,
+		&flag.Variants,
+		&flag.Rules,
+		&flag.BucketingSalt,
+		&flag.VariantsSchema,
+		&flag.ShadowRules,
+		&flag.Tags,
+		&flag.Owner,
+		&flag.ExpiresAt,
+		&flag.Targets.Attribute,
+		&flag.Targets.Allow,
+		&flag.Targets.Deny,
+		&flag.CreatedAt,
+		&flag.UpdatedAt,
+		&flag.CreatedBy,
+		&flag.UpdatedBy,
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "rotate bucketing salt failed")
+		return Flag{}, fmt.Errorf("rotate bucketing salt: %w", err)
+	}
+	if err := insertFlagRevision(ctx, tx, flag); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "rotate bucketing salt failed")
+		return Flag{}, err
+	}
+	if err := r.openFlag(ctx, &flag); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "rotate bucketing salt failed")
+		return Flag{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit rotate bucketing salt tx failed")
+		return Flag{}, fmt.Errorf("commit rotate bucketing salt tx: %w", err)
+	}
+
+	return flag, nil
+}
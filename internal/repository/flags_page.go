@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// FlagPageQuery selects a page of one project's flags, ordered by key.
+type FlagPageQuery struct {
+	ProjectID string
+	// AfterKey resumes a listing after the flag with this key.
+	AfterKey string
+	// Tags narrows the page to flags carrying every one of them. Tags are
+	// compared as stored, so callers normalize them first.
+	Tags  []string
+	Limit int
+}
+
+// ListFlagsPage returns up to q.Limit flags of q.ProjectID with keys after
+// q.AfterKey, ordered by key. Only the page is read, so listing a project
+// with many flags does not load all of them.
+func (r *PostgresRepository) ListFlagsPage(ctx context.Context, q FlagPageQuery) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.ListFlagsPage")
+	defer span.End()
+
+	tags := q.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	flags, err := readWithFallback(ctx, r, "list_flags_page", func(pool *pgxpool.Pool) ([]Flag, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by
+			FROM flags f
+			JOIN projects p ON p.id = f.project_id
+			WHERE f.project_id = $1 AND f.key > $2 AND f.archived_at IS NULL AND p.deleted_at IS NULL
+			  AND (cardinality($3::text[]) = 0 OR f.tags @> $3::text[])
+			ORDER BY f.key
+			LIMIT $4
+		`, q.ProjectID, q.AfterKey, tags, q.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("list flags page: %w", err)
+		}
+		defer rows.Close()
+
+		flags := make([]Flag, 0)
+		for rows.Next() {
+			var flag Flag
+			if err := rows.Scan(
+				&flag.ProjectID,
+				&flag.Key,
+				&flag.Description,
+				&flag.Enabled,
+				&flag.Variants,
+				&flag.Rules,
+				&flag.BucketingSalt,
+				&flag.VariantsSchema,
+				&flag.ShadowRules,
+				&flag.Tags,
+				&flag.Owner,
+				&flag.ExpiresAt,
+				&flag.Targets.Attribute,
+				&flag.Targets.Allow,
+				&flag.Targets.Deny,
+				&flag.CreatedAt,
+				&flag.UpdatedAt,
+				&flag.CreatedBy,
+				&flag.UpdatedBy,
+			); err != nil {
+				return nil, fmt.Errorf("scan flag: %w", err)
+			}
+			flags = append(flags, flag)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("list flags page rows: %w", err)
+		}
+		return flags, nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list flags page failed")
+		return nil, err
+	}
+	if err := r.openFlags(ctx, flags); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list flags page failed")
+		return nil, err
+	}
+	return flags, nil
+}
+
+// ListFlagsPage is [PostgresRepository.ListFlagsPage] for SQLite.
+func (r *SQLiteRepository) ListFlagsPage(ctx context.Context, q FlagPageQuery) ([]Flag, error) {
+	query := `
+		SELECT ` + sqliteFlagColumns + `
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id = ? AND f.key > ? AND f.archived_at IS NULL AND p.deleted_at IS NULL`
+	args := []any{q.ProjectID, q.AfterKey}
+	for _, tag := range q.Tags {
+		query += " AND EXISTS (SELECT 1 FROM json_each(f.tags) WHERE value = ?)"
+		args = append(args, tag)
+	}
+	query += `
+		ORDER BY f.key
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, q.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("list flags page: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]Flag, 0)
+	for rows.Next() {
+		flag, err := scanSQLiteFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list flags page rows: %w", err)
+	}
+	return flags, nil
+}
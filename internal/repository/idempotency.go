@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// Idempotency-Key header.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request (method, path and body) the key was
+	// first used with.
+	Fingerprint string
+	// StatusCode is the response status, or 0 while the first request is
+	// still being handled.
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// claimIdempotencyKeySQL inserts an in-progress record for a key, or takes
+// over one created before $5 (an expired record not yet purged). It affects
+// no rows when a live record already holds the key.
+const claimIdempotencyKeySQL = `
+	INSERT INTO idempotency_keys (api_key_id, key, fingerprint, created_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (api_key_id, key) DO UPDATE
+	SET fingerprint = EXCLUDED.fingerprint,
+	    status_code = NULL,
+	    content_type = '',
+	    body = NULL,
+	    created_at = EXCLUDED.created_at
+	WHERE idempotency_keys.created_at < $5
+`
+
+// ClaimIdempotencyKey reserves key for the API key apiKeyID, recording the
+// request's fingerprint. It reports true if the caller now owns the key and
+// must handle the request; otherwise it returns the record already holding
+// the key. Records created before expiredBefore are treated as absent.
+func (r *PostgresRepository) ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string, now, expiredBefore time.Time) (IdempotencyRecord, bool, error) {
+	commandTag, err := r.pool.Exec(ctx, claimIdempotencyKeySQL, apiKeyID, key, fingerprint, now, expiredBefore)
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if commandTag.RowsAffected() > 0 {
+		return IdempotencyRecord{Fingerprint: fingerprint}, true, nil
+	}
+
+	var (
+		record     IdempotencyRecord
+		statusCode *int32
+	)
+	if err := r.pool.QueryRow(ctx, `
+		SELECT fingerprint, status_code, content_type, body
+		FROM idempotency_keys
+		WHERE api_key_id = $1 AND key = $2
+	`, apiKeyID, key).Scan(&record.Fingerprint, &statusCode, &record.ContentType, &record.Body); err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("get idempotency key: %w", err)
+	}
+	if statusCode != nil {
+		record.StatusCode = int(*statusCode)
+	}
+	return record, false, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request that claimed
+// key, so retries are answered with it.
+func (r *PostgresRepository) CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record IdempotencyRecord) error {
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, body = $5
+		WHERE api_key_id = $1 AND key = $2
+	`, apiKeyID, key, record.StatusCode, record.ContentType, record.Body); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes an in-progress claim on key, letting a retry
+// handle the request afresh.
+func (r *PostgresRepository) ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error {
+	if _, err := r.pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE api_key_id = $1 AND key = $2 AND status_code IS NULL
+	`, apiKeyID, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys removes records created before createdBefore and
+// returns how many were removed.
+func (r *PostgresRepository) PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error) {
+	commandTag, err := r.pool.Exec(ctx, `
+		DELETE FROM idempotency_keys WHERE created_at < $1
+	`, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import "encoding/json"
+
+// invalidationBuffer is how many flag invalidations a subscription holds
+// for a subscriber that has fallen behind before they are folded into a
+// single full reload.
+const invalidationBuffer = 64
+
+// FlagInvalidation is a change announced to cache invalidation subscribers.
+// It names the flag that changed, so the subscriber can refresh just that
+// flag; the zero value, or one without a FlagKey, asks for a full reload.
+type FlagInvalidation struct {
+	ProjectID string `json:"project_id"`
+	FlagKey   string `json:"flag_key"`
+	EventType string `json:"event_type"`
+}
+
+// Targeted reports whether the invalidation names a single flag.
+func (inv FlagInvalidation) Targeted() bool {
+	return inv.ProjectID != "" && inv.FlagKey != ""
+}
+
+// marshalNotifyPayload encodes the LISTEN/NOTIFY, or Redis, message that
+// announces event.
+func marshalNotifyPayload(event FlagEvent) (string, error) {
+	serialized, err := json.Marshal(FlagInvalidation{
+		ProjectID: event.ProjectID,
+		FlagKey:   event.FlagKey,
+		EventType: event.EventType,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(serialized), nil
+}
+
+// parseNotifyPayload decodes a message written by [marshalNotifyPayload]. A
+// message that cannot be decoded yields the zero value, a full reload.
+func parseNotifyPayload(payload string) FlagInvalidation {
+	var inv FlagInvalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		return FlagInvalidation{}
+	}
+	return inv
+}
+
+// sendInvalidation delivers inv without blocking. When the subscriber has
+// fallen invalidationBuffer behind, the invalidations it has not read are
+// replaced by one full reload, which covers them all.
+func sendInvalidation(invalidations chan FlagInvalidation, inv FlagInvalidation) {
+	for {
+		select {
+		case invalidations <- inv:
+			return
+		default:
+		}
+		inv = FlagInvalidation{}
+		for drained := false; !drained; {
+			select {
+			case <-invalidations:
+			default:
+				drained = true
+			}
+		}
+	}
+}
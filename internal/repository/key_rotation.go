@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KeyRotationPolicy limits how long a project's API keys may be used before
+// they must be replaced. A zero MaxAge means keys never become overdue.
+type KeyRotationPolicy struct {
+	// MaxAge is how long after creation a key becomes overdue for rotation.
+	MaxAge time.Duration
+	// RevokeAfter, when positive, is the grace period after a key becomes
+	// overdue before it is revoked automatically. Zero never revokes.
+	RevokeAfter time.Duration
+}
+
+// RotateBy returns when a key created at createdAt becomes overdue, or false
+// if the policy is disabled.
+func (p KeyRotationPolicy) RotateBy(createdAt time.Time) (time.Time, bool) {
+	if p.MaxAge <= 0 {
+		return time.Time{}, false
+	}
+	return createdAt.Add(p.MaxAge), true
+}
+
+// RevokeAt returns when a key created at createdAt is revoked automatically,
+// or false if the policy never revokes keys.
+func (p KeyRotationPolicy) RevokeAt(createdAt time.Time) (time.Time, bool) {
+	rotateBy, ok := p.RotateBy(createdAt)
+	if !ok || p.RevokeAfter <= 0 {
+		return time.Time{}, false
+	}
+	return rotateBy.Add(p.RevokeAfter), true
+}
+
+// APIKeyCredential is what is needed to authenticate a bearer token: the
+// stored hash, the owning project, and enough to tell whether the key is
+// overdue under the project's rotation policy.
+type APIKeyCredential struct {
+	KeyHash   string
+	ProjectID string
+	CreatedAt time.Time
+	Policy    KeyRotationPolicy
+}
+
+// LookupAPIKey returns the credential of a non-revoked key ID. Callers
+// should do constant-time comparison outside this package. Returns
+// pgx.ErrNoRows (wrapped) if the key does not exist or is revoked.
+func (r *PostgresRepository) LookupAPIKey(ctx context.Context, id string) (APIKeyCredential, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.LookupAPIKey")
+	defer span.End()
+
+	var cred APIKeyCredential
+	var maxAge, revokeAfter int64
+	if err := r.pool.QueryRow(ctx, `
+		SELECT k.key_hash, k.project_id, k.created_at, p.api_key_max_age_seconds, p.api_key_revoke_after_seconds
+		FROM api_keys k
+		JOIN projects p ON p.id = k.project_id
+		WHERE k.id = $1
+		  AND k.revoked_at IS NULL
+	`, id).Scan(&cred.KeyHash, &cred.ProjectID, &cred.CreatedAt, &maxAge, &revokeAfter); err != nil {
+		span.RecordError(err)
+		return APIKeyCredential{}, fmt.Errorf("lookup api key: %w", err)
+	}
+
+	cred.Policy = keyRotationPolicyFromSeconds(maxAge, revokeAfter)
+	return cred, nil
+}
+
+// GetKeyRotationPolicy returns the API key rotation policy of a project.
+// Returns pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetKeyRotationPolicy(ctx context.Context, projectID string) (KeyRotationPolicy, error) {
+	var maxAge, revokeAfter int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT api_key_max_age_seconds, api_key_revoke_after_seconds
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&maxAge, &revokeAfter)
+	if err != nil {
+		return KeyRotationPolicy{}, fmt.Errorf("get key rotation policy: %w", err)
+	}
+	return keyRotationPolicyFromSeconds(maxAge, revokeAfter), nil
+}
+
+// SetKeyRotationPolicy replaces the API key rotation policy of a project.
+// Durations are stored with one-second precision. Keys already marked
+// overdue are unmarked if the new policy no longer makes them overdue.
+// Returns pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) SetKeyRotationPolicy(ctx context.Context, projectID string, policy KeyRotationPolicy) (KeyRotationPolicy, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return KeyRotationPolicy{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var maxAge, revokeAfter int64
+	err = tx.QueryRow(ctx, `
+		UPDATE projects
+		SET api_key_max_age_seconds = $2, api_key_revoke_after_seconds = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING api_key_max_age_seconds, api_key_revoke_after_seconds
+	`, projectID, int64(policy.MaxAge/time.Second), int64(policy.RevokeAfter/time.Second)).Scan(&maxAge, &revokeAfter)
+	if err != nil {
+		return KeyRotationPolicy{}, fmt.Errorf("set key rotation policy: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys
+		SET rotation_overdue_at = NULL
+		WHERE project_id = $1
+		  AND rotation_overdue_at IS NOT NULL
+		  AND ($2::bigint = 0 OR created_at + make_interval(secs => $2::bigint) > NOW())
+	`, projectID, maxAge); err != nil {
+		return KeyRotationPolicy{}, fmt.Errorf("reset overdue api keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return KeyRotationPolicy{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return keyRotationPolicyFromSeconds(maxAge, revokeAfter), nil
+}
+
+// MarkOverdueAPIKeys flags every non-revoked key that has passed its
+// project's maximum age as of now and returns the keys that were newly
+// marked. Keys are marked once, so each is returned by only one call.
+func (r *PostgresRepository) MarkOverdueAPIKeys(ctx context.Context, now time.Time) ([]APIKeyMeta, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE api_keys k
+		SET rotation_overdue_at = $1
+		FROM projects p
+		WHERE p.id = k.project_id
+		  AND p.api_key_max_age_seconds > 0
+		  AND k.revoked_at IS NULL
+		  AND k.rotation_overdue_at IS NULL
+		  AND k.created_at + make_interval(secs => p.api_key_max_age_seconds) <= $1
+		RETURNING k.id, k.project_id, k.scope, k.created_at, k.last_used_at, p.api_key_max_age_seconds, p.api_key_revoke_after_seconds
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("mark overdue api keys: %w", err)
+	}
+	return collectAPIKeyMeta(rows)
+}
+
+// RevokeOverdueAPIKeys revokes every key whose project revokes overdue keys
+// and whose grace period ended before now, and returns the revoked keys.
+func (r *PostgresRepository) RevokeOverdueAPIKeys(ctx context.Context, now time.Time) ([]APIKeyMeta, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE api_keys k
+		SET revoked_at = $1
+		FROM projects p
+		WHERE p.id = k.project_id
+		  AND p.api_key_max_age_seconds > 0
+		  AND p.api_key_revoke_after_seconds > 0
+		  AND k.revoked_at IS NULL
+		  AND k.created_at + make_interval(secs => p.api_key_max_age_seconds + p.api_key_revoke_after_seconds) <= $1
+		RETURNING k.id, k.project_id, k.scope, k.created_at, k.last_used_at, p.api_key_max_age_seconds, p.api_key_revoke_after_seconds
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("revoke overdue api keys: %w", err)
+	}
+	return collectAPIKeyMeta(rows)
+}
+
+func keyRotationPolicyFromSeconds(maxAge, revokeAfter int64) KeyRotationPolicy {
+	return KeyRotationPolicy{
+		MaxAge:      time.Duration(maxAge) * time.Second,
+		RevokeAfter: time.Duration(revokeAfter) * time.Second,
+	}
+}
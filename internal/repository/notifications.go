@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NotificationChannel is somewhere a project's flag changes are announced.
+type NotificationChannel struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"-"`
+	// Kind is "slack", "email" or "webhook".
+	Kind string `json:"kind"`
+	// Target is the Slack incoming webhook URL, the email recipient or the
+	// URL webhook deliveries are POSTed to.
+	Target string `json:"target"`
+	// EventTypes limits the channel to these flag event types; empty means
+	// every type.
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+	// SigningSecrets sign deliveries to a webhook channel, newest first.
+	// They are not stored with the channel: the service sets them from the
+	// project's [WebhookSigningSecret] before each delivery.
+	SigningSecrets []string `json:"-"`
+}
+
+// Notification records one delivery of a flag event to a channel, after
+// any retries.
+type Notification struct {
+	ID        int64     `json:"id"`
+	ProjectID string    `json:"-"`
+	ChannelID string    `json:"channel_id"`
+	EventID   int64     `json:"event_id"`
+	FlagKey   string    `json:"flag_key"`
+	EventType string    `json:"event_type"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const notificationChannelColumns = `id::text, project_id, kind, target, event_types, created_at`
+
+func scanNotificationChannel(row pgx.Row) (NotificationChannel, error) {
+	var c NotificationChannel
+	err := row.Scan(&c.ID, &c.ProjectID, &c.Kind, &c.Target, &c.EventTypes, &c.CreatedAt)
+	return c, err
+}
+
+// ListNotificationChannels returns a project's notification channels,
+// oldest first.
+func (r *PostgresRepository) ListNotificationChannels(ctx context.Context, projectID string) ([]NotificationChannel, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+notificationChannelColumns+`
+		FROM notification_channels
+		WHERE project_id = $1
+		ORDER BY created_at, id
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := make([]NotificationChannel, 0)
+	for rows.Next() {
+		c, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan notification channel: %w", err)
+		}
+		channels = append(channels, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list notification channels rows: %w", err)
+	}
+	return channels, nil
+}
+
+// CreateNotificationChannel stores a new channel and returns it with its
+// generated ID.
+func (r *PostgresRepository) CreateNotificationChannel(ctx context.Context, channel NotificationChannel) (NotificationChannel, error) {
+	eventTypes := channel.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	c, err := scanNotificationChannel(r.pool.QueryRow(ctx, `
+		INSERT INTO notification_channels (project_id, kind, target, event_types)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+notificationChannelColumns,
+		channel.ProjectID, channel.Kind, channel.Target, eventTypes,
+	))
+	if err != nil {
+		return NotificationChannel{}, fmt.Errorf("create notification channel: %w", err)
+	}
+	return c, nil
+}
+
+// DeleteNotificationChannel removes a channel. Its delivery history is
+// kept. Returns pgx.ErrNoRows (wrapped) if it does not exist.
+func (r *PostgresRepository) DeleteNotificationChannel(ctx context.Context, projectID, id string) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		DELETE FROM notification_channels
+		WHERE project_id = $1 AND id::text = $2
+	`, projectID, id)
+	if err != nil {
+		return fmt.Errorf("delete notification channel: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("delete notification channel: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// InsertNotification records a delivery. Deliveries for projects that no
+// longer exist are dropped.
+func (r *PostgresRepository) InsertNotification(ctx context.Context, n Notification) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO notifications (project_id, channel_id, event_id, flag_key, event_type, status, attempts, error)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE EXISTS (SELECT 1 FROM projects WHERE id = $1)
+	`, n.ProjectID, n.ChannelID, n.EventID, n.FlagKey, n.EventType, n.Status, n.Attempts, n.Error)
+	if err != nil {
+		return fmt.Errorf("insert notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications returns a project's most recent deliveries, newest
+// first.
+func (r *PostgresRepository) ListNotifications(ctx context.Context, projectID string, limit int) ([]Notification, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, project_id, channel_id::text, event_id, flag_key, event_type, status, attempts, error, created_at
+		FROM notifications
+		WHERE project_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.ProjectID, &n.ChannelID, &n.EventID, &n.FlagKey, &n.EventType, &n.Status, &n.Attempts, &n.Error, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list notifications rows: %w", err)
+	}
+	return notifications, nil
+}
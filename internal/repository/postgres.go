@@ -1,7 +1,8 @@
 // Package repository provides PostgreSQL-backed persistence for feature flags,
 // API keys, and flag events. It also handles LISTEN/NOTIFY-based cache
 // invalidation so the service layer stays fresh without polling the database
-// into submission.
+// into submission. [SQLiteRepository] is a smaller alternative for
+// single-server deployments.
 package repository
 
 import (
@@ -11,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -42,8 +44,43 @@ type Flag struct {
 	Enabled     bool            `json:"enabled"`
 	Variants    json.RawMessage `json:"variants"`
 	Rules       json.RawMessage `json:"rules"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
+	// BucketingSalt seeds the hash that assigns targeting keys to percentage
+	// rollout buckets. It is generated by the database on insert and only
+	// changes when the flag is explicitly reshuffled.
+	BucketingSalt string `json:"bucketing_salt,omitempty"`
+	// VariantsSchema is a JSON Schema every variant value must satisfy, or
+	// nil when the flag has none.
+	VariantsSchema json.RawMessage `json:"variants_schema,omitempty"`
+	// ShadowRules is an alternative rule set evaluated alongside Rules
+	// without affecting the result, so a new targeting configuration can be
+	// compared against live traffic before it replaces Rules. It is nil
+	// when the flag has none.
+	ShadowRules json.RawMessage `json:"shadow_rules,omitempty"`
+	// Tags group flags by team or feature area. UpdateFlag keeps the
+	// current tags when Tags is nil; an empty slice removes them.
+	Tags []string `json:"tags,omitempty"`
+	// Owner is the person or team responsible for the flag, such as an
+	// email address or "team:payments".
+	Owner string `json:"owner,omitempty"`
+	// ExpiresAt marks a temporary flag: once it passes, an expiry reminder
+	// is sent once. Changing it re-arms the reminder.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Targets is only changed by [PostgresRepository.SetFlagTargets];
+	// creating or updating a flag leaves it as it is.
+	Targets   FlagTargets `json:"targets,omitzero"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// CreatedBy and UpdatedBy name the actor who created the flag and who
+	// last created or updated it, as "api_key:<id>" or "admin_user:<id>".
+	// They are empty when the actor is unknown. CreateFlag stores CreatedBy
+	// as both; UpdateFlag stores UpdatedBy.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// ArchivedAt is when the flag was archived, or nil if it is not.
+	// Archived flags are left out of every read except
+	// [PostgresRepository.ListArchivedFlags] and backups, so they are
+	// neither evaluated nor listed until restored.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
 }
 
 // Project represents a tenant or namespace for flags.
@@ -53,6 +90,8 @@ type Project struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// DeletedAt is set while the project is soft-deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // AdminUser represents an administrator account.
@@ -63,6 +102,9 @@ type AdminUser struct {
 	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// DeactivatedAt is set once the user has been deactivated; such users
+	// cannot sign in.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
 }
 
 // AdminSession represents an authenticated admin session.
@@ -72,6 +114,16 @@ type AdminSession struct {
 	CSRFToken   string    `json:"csrf_token"`
 	CreatedAt   time.Time `json:"created_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
+
+	// ID identifies the session to its user without revealing IDHash.
+	ID string `json:"id"`
+	// Role is the user's role when the session was issued; UserRole is
+	// their current role, filled in by GetAdminSession.
+	Role       string    `json:"role"`
+	UserRole   string    `json:"-"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent"`
+	RemoteAddr string    `json:"remote_addr"`
 }
 
 // APIKey represents a stored API key record used for bearer-token authentication.
@@ -87,9 +139,22 @@ type APIKey struct {
 // APIKeyMeta contains non-sensitive metadata for an API key, suitable for
 // listing keys without exposing secrets.
 type APIKeyMeta struct {
-	ID        string    `json:"id"`
-	ProjectID string    `json:"project_id"`
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	// Scope is APIKeyScopeProject or APIKeyScopeAdmin.
+	Scope     string    `json:"scope"`
 	CreatedAt time.Time `json:"created_at"`
+	// RotateBy is when the key becomes overdue under its project's
+	// [KeyRotationPolicy], and RevokeAt when it is then revoked
+	// automatically. Each is nil if the policy does not set it.
+	RotateBy *time.Time `json:"rotate_by,omitempty"`
+	RevokeAt *time.Time `json:"revoke_at,omitempty"`
+	// Overdue reports whether RotateBy had passed when the key was listed.
+	Overdue bool `json:"overdue"`
+	// LastUsedAt is when the key last authenticated a request, or nil if it
+	// never has. Uses are recorded at most every few minutes per key, so it
+	// may lag slightly.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 }
 
 // FlagEvent represents a change event for a flag, stored in the flag_events
@@ -101,6 +166,9 @@ type FlagEvent struct {
 	EventType string          `json:"event_type"`
 	Payload   json.RawMessage `json:"payload"`
 	CreatedAt time.Time       `json:"created_at"`
+	// Actor made the change, in the form of [Flag.UpdatedBy]. It is only
+	// returned by PublishFlagEvent and QueryEvents.
+	Actor string `json:"-"`
 }
 
 // AuditLogEntry records a mutation performed on a flag for audit purposes.
@@ -112,6 +180,7 @@ type AuditLogEntry struct {
 	Action      string          `json:"action"`
 	FlagKey     string          `json:"flag_key"`
 	Details     json.RawMessage `json:"details,omitempty"`
+	RequestID   string          `json:"request_id,omitempty"`
 	CreatedAt   time.Time       `json:"created_at"`
 }
 
@@ -122,6 +191,17 @@ type PostgresRepository struct {
 	pool           *pgxpool.Pool
 	notifyChannel  string
 	eventBatchSize int
+	onReconnect    func()
+	listening      atomic.Bool
+
+	// readPool, when set, serves the flag and event reads that tolerate
+	// replication lag; see WithReadPool.
+	readPool       *pgxpool.Pool
+	onReadFallback func(operation string)
+
+	// variantKeys, when set, encrypts flag variants at rest; see
+	// WithVariantsEncryption.
+	variantKeys KeyProvider
 }
 
 // RepoOption configures optional PostgresRepository parameters.
@@ -137,6 +217,33 @@ func WithEventBatchSize(size int) RepoOption {
 	}
 }
 
+// WithListenReconnectHook registers fn to be called each time the
+// LISTEN/NOTIFY listener re-establishes its connection after a failure.
+func WithListenReconnectHook(fn func()) RepoOption {
+	return func(r *PostgresRepository) {
+		r.onReconnect = fn
+	}
+}
+
+// WithReadPool routes GetFlag, ListFlags, ListEventsSince and
+// ListEventsSinceForKey to pool, typically connected to a read replica.
+// Writes and LISTEN stay on the primary. A read the replica fails, or a flag
+// it does not have yet, is retried on the primary.
+func WithReadPool(pool *pgxpool.Pool) RepoOption {
+	return func(r *PostgresRepository) {
+		r.readPool = pool
+	}
+}
+
+// WithReadFallbackHook registers fn to be called with the operation name,
+// such as "list_flags", each time a read is retried on the primary because
+// the read pool failed it or did not find the flag.
+func WithReadFallbackHook(fn func(operation string)) RepoOption {
+	return func(r *PostgresRepository) {
+		r.onReadFallback = fn
+	}
+}
+
 // NewPostgresRepository creates a [PostgresRepository] using the default
 // "flag_events" notification channel.
 func NewPostgresRepository(pool *pgxpool.Pool, opts ...RepoOption) *PostgresRepository {
@@ -157,8 +264,8 @@ func NewPostgresRepositoryWithChannel(pool *pgxpool.Pool, notifyChannel string,
 	return r
 }
 
-// CreateFlag inserts a new flag row and returns the created record with
-// server-generated timestamps.
+// CreateFlag inserts a new flag row, records it as the flag's first
+// revision, and returns the created record with server-generated timestamps.
 func (r *PostgresRepository) CreateFlag(ctx context.Context, flag Flag) (Flag, error) {
 	ctx, span := repoTracer.Start(ctx, "repo.CreateFlag",
 		trace.WithAttributes(
@@ -167,18 +274,39 @@ func (r *PostgresRepository) CreateFlag(ctx context.Context, flag Flag) (Flag, e
 		))
 	defer span.End()
 
+	variants, err := r.sealVariants(ctx, flag.ProjectID, flag.Key, flag.Variants)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create flag failed")
+		return Flag{}, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin create flag tx failed")
+		return Flag{}, fmt.Errorf("begin create flag tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var created Flag
-	err := r.pool.QueryRow(ctx, `
-		INSERT INTO flags (project_id, key, description, enabled, variants, rules)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING project_id, key, description, enabled, variants, rules, created_at, updated_at
+	err = tx.QueryRow(ctx, `
+		INSERT INTO flags (project_id, key, description, enabled, variants, rules, variants_schema, tags, owner, expires_at, shadow_rules, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::text[], '{}'), $9, $10, $11, $12, $12)
+		RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
 	`,
 		flag.ProjectID,
 		flag.Key,
 		flag.Description,
 		flag.Enabled,
-		ensureJSON(flag.Variants, "{}"),
+		variants,
 		ensureJSON(flag.Rules, "[]"),
+		flag.VariantsSchema,
+		flag.Tags,
+		flag.Owner,
+		flag.ExpiresAt,
+		flag.ShadowRules,
+		flag.CreatedBy,
 	).Scan(
 		&created.ProjectID,
 		&created.Key,
@@ -186,20 +314,47 @@ func (r *PostgresRepository) CreateFlag(ctx context.Context, flag Flag) (Flag, e
 		&created.Enabled,
 		&created.Variants,
 		&created.Rules,
+		&created.BucketingSalt,
+		&created.VariantsSchema,
+		&created.ShadowRules,
+		&created.Tags,
+		&created.Owner,
+		&created.ExpiresAt,
+		&created.Targets.Attribute,
+		&created.Targets.Allow,
+		&created.Targets.Deny,
 		&created.CreatedAt,
 		&created.UpdatedAt,
+		&created.CreatedBy,
+		&created.UpdatedBy,
 	)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "create flag failed")
 		return Flag{}, fmt.Errorf("create flag: %w", err)
 	}
+	if err := insertFlagRevision(ctx, tx, created); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create flag failed")
+		return Flag{}, err
+	}
+	if err := r.openFlag(ctx, &created); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create flag failed")
+		return Flag{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit create flag tx failed")
+		return Flag{}, fmt.Errorf("commit create flag tx: %w", err)
+	}
 
 	return created, nil
 }
 
-// UpdateFlag updates an existing flag row identified by project_id and key and returns the
-// updated record. Returns pgx.ErrNoRows (wrapped) if the flag does not exist.
+// UpdateFlag updates an existing flag row identified by project_id and key, records
+// the result as a new revision, and returns the updated record. Returns
+// pgx.ErrNoRows (wrapped) if the flag does not exist or is archived.
 func (r *PostgresRepository) UpdateFlag(ctx context.Context, flag Flag) (Flag, error) {
 	ctx, span := repoTracer.Start(ctx, "repo.UpdateFlag",
 		trace.WithAttributes(
@@ -208,23 +363,67 @@ func (r *PostgresRepository) UpdateFlag(ctx context.Context, flag Flag) (Flag, e
 		))
 	defer span.End()
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin update flag tx failed")
+		return Flag{}, fmt.Errorf("begin update flag tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	updated, err := r.updateFlagTx(ctx, tx, flag)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update flag failed")
+		return Flag{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit update flag tx failed")
+		return Flag{}, fmt.Errorf("commit update flag tx: %w", err)
+	}
+
+	return updated, nil
+}
+
+// updateFlagTx writes flag's editable fields within tx and records the
+// result as a new revision.
+func (r *PostgresRepository) updateFlagTx(ctx context.Context, tx pgx.Tx, flag Flag) (Flag, error) {
+	variants, err := r.sealVariants(ctx, flag.ProjectID, flag.Key, flag.Variants)
+	if err != nil {
+		return Flag{}, err
+	}
+
 	var updated Flag
-	err := r.pool.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		UPDATE flags
 		SET description = $3,
 		    enabled = $4,
 		    variants = $5,
 		    rules = $6,
+		    variants_schema = $7,
+		    shadow_rules = $11,
+		    tags = COALESCE($8::text[], tags),
+		    owner = $9,
+		    expires_at = $10,
+		    expiry_notified_at = CASE WHEN expires_at IS DISTINCT FROM $10 THEN NULL ELSE expiry_notified_at END,
+		    updated_by = $12,
 		    updated_at = NOW()
-		WHERE project_id = $1 AND key = $2
-		RETURNING project_id, key, description, enabled, variants, rules, created_at, updated_at
+		WHERE project_id = $1 AND key = $2 AND archived_at IS NULL
+		RETURNING project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, target_attribute, allow_targets, deny_targets, created_at, updated_at, created_by, updated_by
 	`,
 		flag.ProjectID,
 		flag.Key,
 		flag.Description,
 		flag.Enabled,
-		ensureJSON(flag.Variants, "{}"),
+		variants,
 		ensureJSON(flag.Rules, "[]"),
+		flag.VariantsSchema,
+		flag.Tags,
+		flag.Owner,
+		flag.ExpiresAt,
+		flag.ShadowRules,
+		flag.UpdatedBy,
 	).Scan(
 		&updated.ProjectID,
 		&updated.Key,
@@ -232,90 +431,232 @@ func (r *PostgresRepository) UpdateFlag(ctx context.Context, flag Flag) (Flag, e
 		&updated.Enabled,
 		&updated.Variants,
 		&updated.Rules,
+		&updated.BucketingSalt,
+		&updated.VariantsSchema,
+		&updated.ShadowRules,
+		&updated.Tags,
+		&updated.Owner,
+		&updated.ExpiresAt,
+		&updated.Targets.Attribute,
+		&updated.Targets.Allow,
+		&updated.Targets.Deny,
 		&updated.CreatedAt,
 		&updated.UpdatedAt,
+		&updated.CreatedBy,
+		&updated.UpdatedBy,
 	)
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "update flag failed")
 		return Flag{}, fmt.Errorf("update flag: %w", err)
 	}
-
+	if err := insertFlagRevision(ctx, tx, updated); err != nil {
+		return Flag{}, err
+	}
+	if err := r.openFlag(ctx, &updated); err != nil {
+		return Flag{}, err
+	}
 	return updated, nil
 }
 
-// GetFlag retrieves a single flag by its project_id and key. Returns pgx.ErrNoRows (wrapped)
-// if not found.
-func (r *PostgresRepository) GetFlag(ctx context.Context, projectID, key string) (Flag, error) {
-	ctx, span := repoTracer.Start(ctx, "repo.GetFlag",
+// UpdateFlagFunc locks a flag, passes it to update and stores the flag update
+// returns, all in one transaction, so no other write to the flag can land
+// between reading and writing it. Only the fields [PostgresRepository.UpdateFlag]
+// writes are stored. It returns the flag as it was before and after the
+// update. Returns pgx.ErrNoRows (wrapped) if the flag does not exist, and
+// update's error (wrapped) if update fails, in which case nothing changes.
+func (r *PostgresRepository) UpdateFlagFunc(ctx context.Context, projectID, key string, update func(Flag) (Flag, error)) (Flag, Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.UpdateFlagFunc",
 		trace.WithAttributes(
 			attribute.String("flag_key", key),
 			attribute.String("project_id", projectID),
 		))
 	defer span.End()
 
-	var flag Flag
-	err := r.pool.QueryRow(ctx, `
-		SELECT project_id, key, description, enabled, variants, rules, created_at, updated_at
-		FROM flags
-		WHERE project_id = $1 AND key = $2
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin update flag tx failed")
+		return Flag{}, Flag{}, fmt.Errorf("begin update flag tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current Flag
+	err = tx.QueryRow(ctx, `
+		SELECT f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id = $1 AND f.key = $2 AND f.archived_at IS NULL AND p.deleted_at IS NULL
+		FOR UPDATE OF f
 	`, projectID, key).Scan(
-		&flag.ProjectID,
-		&flag.Key,
-		&flag.Description,
-		&flag.Enabled,
-		&flag.Variants,
-		&flag.Rules,
-		&flag.CreatedAt,
-		&flag.UpdatedAt,
+		&current.ProjectID,
+		&current.Key,
+		&current.Description,
+		&current.Enabled,
+		&current.Variants,
+		&current.Rules,
+		&current.BucketingSalt,
+		&current.VariantsSchema,
+		&current.ShadowRules,
+		&current.Tags,
+		&current.Owner,
+		&current.ExpiresAt,
+		&current.Targets.Attribute,
+		&current.Targets.Allow,
+		&current.Targets.Deny,
+		&current.CreatedAt,
+		&current.UpdatedAt,
+		&current.CreatedBy,
+		&current.UpdatedBy,
 	)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "get flag failed")
-		return Flag{}, fmt.Errorf("get flag: %w", err)
+		span.SetStatus(codes.Error, "lock flag failed")
+		return Flag{}, Flag{}, fmt.Errorf("lock flag: %w", err)
+	}
+	if err := r.openFlag(ctx, &current); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "lock flag failed")
+		return Flag{}, Flag{}, err
 	}
 
-	return flag, nil
-}
-
-// ListFlags returns all flags across all projects ordered by project_id and key.
-func (r *PostgresRepository) ListFlags(ctx context.Context) ([]Flag, error) {
-	ctx, span := repoTracer.Start(ctx, "repo.ListFlags")
-	defer span.End()
+	flag, err := update(current)
+	if err != nil {
+		return Flag{}, Flag{}, fmt.Errorf("update flag: %w", err)
+	}
+	flag.ProjectID, flag.Key = projectID, key
 
-	rows, err := r.pool.Query(ctx, `
-		SELECT project_id, key, description, enabled, variants, rules, created_at, updated_at
-		FROM flags
-		ORDER BY project_id, key
-	`)
+	updated, err := r.updateFlagTx(ctx, tx, flag)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "list flags failed")
-		return nil, fmt.Errorf("list flags: %w", err)
+		span.SetStatus(codes.Error, "update flag failed")
+		return Flag{}, Flag{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit update flag tx failed")
+		return Flag{}, Flag{}, fmt.Errorf("commit update flag tx: %w", err)
 	}
-	defer rows.Close()
 
-	flags := make([]Flag, 0)
-	for rows.Next() {
+	return current, updated, nil
+}
+
+// GetFlag retrieves a single flag by its project_id and key. Returns pgx.ErrNoRows (wrapped)
+// if not found or archived.
+func (r *PostgresRepository) GetFlag(ctx context.Context, projectID, key string) (Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.GetFlag",
+		trace.WithAttributes(
+			attribute.String("flag_key", key),
+			attribute.String("project_id", projectID),
+		))
+	defer span.End()
+
+	flag, err := readWithFallback(ctx, r, "get_flag", func(pool *pgxpool.Pool) (Flag, error) {
 		var flag Flag
-		if err := rows.Scan(
+		err := pool.QueryRow(ctx, `
+			SELECT f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by
+			FROM flags f
+			JOIN projects p ON p.id = f.project_id
+			WHERE f.project_id = $1 AND f.key = $2 AND f.archived_at IS NULL AND p.deleted_at IS NULL
+		`, projectID, key).Scan(
 			&flag.ProjectID,
 			&flag.Key,
 			&flag.Description,
 			&flag.Enabled,
 			&flag.Variants,
 			&flag.Rules,
+			&flag.BucketingSalt,
+			&flag.VariantsSchema,
+			&flag.ShadowRules,
+			&flag.Tags,
+			&flag.Owner,
+			&flag.ExpiresAt,
+			&flag.Targets.Attribute,
+			&flag.Targets.Allow,
+			&flag.Targets.Deny,
 			&flag.CreatedAt,
 			&flag.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("scan flag: %w", err)
+			&flag.CreatedBy,
+			&flag.UpdatedBy,
+		)
+		return flag, err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "get flag failed")
+		return Flag{}, fmt.Errorf("get flag: %w", err)
+	}
+	if err := r.openFlag(ctx, &flag); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "get flag failed")
+		return Flag{}, err
+	}
+
+	return flag, nil
+}
+
+// ListFlags returns all flags across all projects ordered by project_id and
+// key. Archived flags and flags of soft-deleted projects are excluded.
+func (r *PostgresRepository) ListFlags(ctx context.Context) ([]Flag, error) {
+	ctx, span := repoTracer.Start(ctx, "repo.ListFlags")
+	defer span.End()
+
+	flags, err := readWithFallback(ctx, r, "list_flags", func(pool *pgxpool.Pool) ([]Flag, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.target_attribute, f.allow_targets, f.deny_targets, f.created_at, f.updated_at, f.created_by, f.updated_by
+			FROM flags f
+			JOIN projects p ON p.id = f.project_id
+			WHERE f.archived_at IS NULL AND p.deleted_at IS NULL
+			ORDER BY f.project_id, f.key
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("list flags: %w", err)
+		}
+		defer rows.Close()
+
+		flags := make([]Flag, 0)
+		for rows.Next() {
+			var flag Flag
+			if err := rows.Scan(
+				&flag.ProjectID,
+				&flag.Key,
+				&flag.Description,
+				&flag.Enabled,
+				&flag.Variants,
+				&flag.Rules,
+				&flag.BucketingSalt,
+				&flag.VariantsSchema,
+				&flag.ShadowRules,
+				&flag.Tags,
+				&flag.Owner,
+				&flag.ExpiresAt,
+				&flag.Targets.Attribute,
+				&flag.Targets.Allow,
+				&flag.Targets.Deny,
+				&flag.CreatedAt,
+				&flag.UpdatedAt,
+				&flag.CreatedBy,
+				&flag.UpdatedBy,
+			); err != nil {
+				return nil, fmt.Errorf("scan flag: %w", err)
+			}
+
+			flags = append(flags, flag)
 		}
 
-		flags = append(flags, flag)
-	}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("list flags rows: %w", err)
+		}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("list flags rows: %w", err)
+		return flags, nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list flags failed")
+		return nil, err
+	}
+	if err := r.openFlags(ctx, flags); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list flags failed")
+		return nil, err
 	}
 
 	return flags, nil
@@ -368,10 +709,24 @@ func (r *PostgresRepository) ValidateAPIKey(ctx context.Context, id string) (str
 	return keyHash, projectID, nil
 }
 
+// TouchAPIKey records that the key authenticated a request at usedAt.
+func (r *PostgresRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = $2 WHERE id = $1
+	`, id, usedAt); err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
 // CreateAPIKey generates a new API key for the given project, storing a bcrypt
 // hash of the secret. The raw secret is returned exactly once; it cannot be
 // retrieved later.
 func (r *PostgresRepository) CreateAPIKey(ctx context.Context, projectID string) (string, string, error) {
+	return r.createAPIKey(ctx, projectID, APIKeyScopeProject)
+}
+
+func (r *PostgresRepository) createAPIKey(ctx context.Context, projectID, scope string) (string, string, error) {
 	keyID, err := generateRandomHex(16)
 	if err != nil {
 		return "", "", fmt.Errorf("generate key id: %w", err)
@@ -388,9 +743,9 @@ func (r *PostgresRepository) CreateAPIKey(ctx context.Context, projectID string)
 	}
 
 	_, err = r.pool.Exec(ctx, `
-		INSERT INTO api_keys (id, project_id, name, key_hash)
-		VALUES ($1, $2, $3, $4)
-	`, keyID, projectID, "api-key-"+keyID[:8], hash)
+		INSERT INTO api_keys (id, project_id, name, key_hash, scope)
+		VALUES ($1, $2, $3, $4, $5)
+	`, keyID, projectID, "api-key-"+keyID[:8], hash, scope)
 	if err != nil {
 		return "", "", fmt.Errorf("create api key: %w", err)
 	}
@@ -411,23 +766,41 @@ func (r *PostgresRepository) listAPIKeys(ctx context.Context, projectID string,
 	}
 
 	query := `
-		SELECT id, project_id, created_at
-		FROM api_keys
-		WHERE project_id = $1 AND revoked_at IS NULL
-		ORDER BY created_at` + orderDirection
+		SELECT k.id, k.project_id, k.scope, k.created_at, k.last_used_at, p.api_key_max_age_seconds, p.api_key_revoke_after_seconds
+		FROM api_keys k
+		JOIN projects p ON p.id = k.project_id
+		WHERE k.project_id = $1 AND k.revoked_at IS NULL
+		ORDER BY k.created_at` + orderDirection
 
 	rows, err := r.pool.Query(ctx, query, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("list api keys: %w", err)
 	}
+	return collectAPIKeyMeta(rows)
+}
+
+// collectAPIKeyMeta scans rows of (id, project_id, scope, created_at,
+// last_used_at, api_key_max_age_seconds, api_key_revoke_after_seconds) and
+// closes them.
+func collectAPIKeyMeta(rows pgx.Rows) ([]APIKeyMeta, error) {
 	defer rows.Close()
 
+	now := time.Now()
 	keys := make([]APIKeyMeta, 0)
 	for rows.Next() {
 		var k APIKeyMeta
-		if err := rows.Scan(&k.ID, &k.ProjectID, &k.CreatedAt); err != nil {
+		var maxAge, revokeAfter int64
+		if err := rows.Scan(&k.ID, &k.ProjectID, &k.Scope, &k.CreatedAt, &k.LastUsedAt, &maxAge, &revokeAfter); err != nil {
 			return nil, fmt.Errorf("scan api key: %w", err)
 		}
+		policy := keyRotationPolicyFromSeconds(maxAge, revokeAfter)
+		if rotateBy, ok := policy.RotateBy(k.CreatedAt); ok {
+			k.RotateBy = &rotateBy
+			k.Overdue = !now.Before(rotateBy)
+		}
+		if revokeAt, ok := policy.RevokeAt(k.CreatedAt); ok {
+			k.RevokeAt = &revokeAt
+		}
 		keys = append(keys, k)
 	}
 
@@ -458,39 +831,25 @@ func (r *PostgresRepository) DeleteAPIKey(ctx context.Context, projectID, keyID
 // ListEventsSince returns up to the configured event batch size (default 1000)
 // flag events with IDs greater than eventID, ordered by event ID.
 func (r *PostgresRepository) ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]FlagEvent, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT event_id, project_id, flag_key, event_type, payload, created_at
-		FROM flag_events
-		WHERE event_id > $1 AND project_id = $2
-		ORDER BY event_id
-		LIMIT $3
-	`, eventID, projectID, r.eventBatchSize)
-	if err != nil {
-		return nil, fmt.Errorf("list events since: %w", err)
-	}
-	defer rows.Close()
-
-	events := make([]FlagEvent, 0)
-	for rows.Next() {
-		var event FlagEvent
-		if err := rows.Scan(
-			&event.EventID,
-			&event.ProjectID,
-			&event.FlagKey,
-			&event.EventType,
-			&event.Payload,
-			&event.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("scan event: %w", err)
+	events, err := readWithFallback(ctx, r, "list_events_since", func(pool *pgxpool.Pool) ([]FlagEvent, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT event_id, project_id, flag_key, event_type, payload, created_at
+			FROM flag_events
+			WHERE event_id > $1 AND project_id = $2
+			ORDER BY event_id
+			LIMIT $3
+		`, eventID, projectID, r.eventBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("list events since: %w", err)
 		}
-
-		events = append(events, event)
+		return collectFlagEvents(rows)
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("list events rows: %w", err)
+	if err := r.openEvents(ctx, events); err != nil {
+		return nil, err
 	}
-
 	return events, nil
 }
 
@@ -499,17 +858,32 @@ func (r *PostgresRepository) ListEventsSince(ctx context.Context, projectID stri
 // flag key. Including projectID in the filter ensures that events are correctly
 // scoped when different projects reuse the same flag keys.
 func (r *PostgresRepository) ListEventsSinceForKey(ctx context.Context, projectID string, eventID int64, key string) ([]FlagEvent, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT event_id, project_id, flag_key, event_type, payload, created_at
-		FROM flag_events
-		WHERE event_id > $1
-		  AND project_id = $2 AND flag_key = $3
-		ORDER BY event_id
-		LIMIT $4
-	`, eventID, projectID, key, r.eventBatchSize)
+	events, err := readWithFallback(ctx, r, "list_events_since_for_key", func(pool *pgxpool.Pool) ([]FlagEvent, error) {
+		rows, err := pool.Query(ctx, `
+			SELECT event_id, project_id, flag_key, event_type, payload, created_at
+			FROM flag_events
+			WHERE event_id > $1
+			  AND project_id = $2 AND flag_key = $3
+			ORDER BY event_id
+			LIMIT $4
+		`, eventID, projectID, key, r.eventBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("list events since for key: %w", err)
+		}
+		return collectFlagEvents(rows)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("list events since for key: %w", err)
+		return nil, err
 	}
+	if err := r.openEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// collectFlagEvents scans rows of (event_id, project_id, flag_key,
+// event_type, payload, created_at) and closes them.
+func collectFlagEvents(rows pgx.Rows) ([]FlagEvent, error) {
 	defer rows.Close()
 
 	events := make([]FlagEvent, 0)
@@ -536,6 +910,48 @@ func (r *PostgresRepository) ListEventsSinceForKey(ctx context.Context, projectI
 	return events, nil
 }
 
+// readWithFallback runs read against the read pool when there is one, and
+// against the primary when there is not or when the read pool fails. A
+// pgx.ErrNoRows from the read pool also goes to the primary, since the row
+// may not have replicated yet. Errors caused by ctx are returned as they are.
+func readWithFallback[T any](ctx context.Context, r *PostgresRepository, operation string, read func(pool *pgxpool.Pool) (T, error)) (T, error) {
+	if r.readPool == nil {
+		return read(r.pool)
+	}
+	result, err := read(r.readPool)
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	if r.onReadFallback != nil {
+		r.onReadFallback(operation)
+	}
+	return read(r.pool)
+}
+
+// LatestEventID returns the ID of the newest flag event in a project, or 0
+// if the project has none.
+func (r *PostgresRepository) LatestEventID(ctx context.Context, projectID string) (int64, error) {
+	var eventID int64
+	if err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(MAX(event_id), 0)
+		FROM flag_events
+		WHERE project_id = $1
+	`, projectID).Scan(&eventID); err != nil {
+		return 0, fmt.Errorf("latest event id: %w", err)
+	}
+	return eventID, nil
+}
+
+// MaxEventID returns the ID of the newest flag event in any project, or 0 if
+// there are none.
+func (r *PostgresRepository) MaxEventID(ctx context.Context) (int64, error) {
+	var eventID int64
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(MAX(event_id), 0) FROM flag_events`).Scan(&eventID); err != nil {
+		return 0, fmt.Errorf("max event id: %w", err)
+	}
+	return eventID, nil
+}
+
 // CreateProject inserts a new project.
 func (r *PostgresRepository) CreateProject(ctx context.Context, name, description string) (Project, error) {
 	var p Project
@@ -556,9 +972,9 @@ func (r *PostgresRepository) CreateProject(ctx context.Context, name, descriptio
 	return p, nil
 }
 
-// ListProjects returns all projects.
+// ListProjects returns all projects that are not soft-deleted.
 func (r *PostgresRepository) ListProjects(ctx context.Context) ([]Project, error) {
-	rows, err := r.pool.Query(ctx, `SELECT id, name, description, created_at, updated_at FROM projects ORDER BY name`)
+	rows, err := r.pool.Query(ctx, `SELECT id, name, description, created_at, updated_at FROM projects WHERE deleted_at IS NULL ORDER BY name`)
 	if err != nil {
 		return nil, fmt.Errorf("list projects: %w", err)
 	}
@@ -575,14 +991,15 @@ func (r *PostgresRepository) ListProjects(ctx context.Context) ([]Project, error
 	return projects, nil
 }
 
-// GetProject retrieves a project by ID.
+// GetProject retrieves a project by ID, including a soft-deleted one; check
+// DeletedAt to tell them apart.
 func (r *PostgresRepository) GetProject(ctx context.Context, id string) (Project, error) {
 	var p Project
 	err := r.pool.QueryRow(ctx, `
-		SELECT id, name, description, created_at, updated_at
+		SELECT id, name, description, created_at, updated_at, deleted_at
 		FROM projects
 		WHERE id = $1
-	`, id).Scan(&p.ID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt)
+	`, id).Scan(&p.ID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt)
 	if err != nil {
 		return Project{}, fmt.Errorf("get project: %w", err)
 	}
@@ -613,7 +1030,7 @@ func (r *PostgresRepository) CreateAdminUser(ctx context.Context, username, pass
 func (r *PostgresRepository) GetAdminUserByUsername(ctx context.Context, username string) (AdminUser, error) {
 	var u AdminUser
 	err := r.pool.QueryRow(ctx, `
-		SELECT id, username, password_hash, role, created_at, updated_at
+		SELECT id, username, password_hash, role, created_at, updated_at, deactivated_at
 		FROM admin_users
 		WHERE username = $1
 	`, username).Scan(
@@ -623,6 +1040,7 @@ func (r *PostgresRepository) GetAdminUserByUsername(ctx context.Context, usernam
 		&u.Role,
 		&u.CreatedAt,
 		&u.UpdatedAt,
+		&u.DeactivatedAt,
 	)
 	if err != nil {
 		return AdminUser{}, fmt.Errorf("get admin user: %w", err)
@@ -642,10 +1060,9 @@ func (r *PostgresRepository) HasAdminUsers(ctx context.Context) (bool, error) {
 
 // CreateAdminSession creates a new session.
 func (r *PostgresRepository) CreateAdminSession(ctx context.Context, session AdminSession) error {
-	_, err := r.pool.Exec(ctx, `
-		INSERT INTO admin_sessions (id_hash, admin_user_id, csrf_token, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`, session.IDHash, session.AdminUserID, session.CSRFToken, session.CreatedAt, session.ExpiresAt)
+	_, err := r.pool.Exec(ctx, insertAdminSessionSQL,
+		session.IDHash, session.AdminUserID, session.CSRFToken, session.CreatedAt, session.ExpiresAt,
+		session.Role, session.UserAgent, session.RemoteAddr)
 	if err != nil {
 		return fmt.Errorf("create admin session: %w", err)
 	}
@@ -656,15 +1073,23 @@ func (r *PostgresRepository) CreateAdminSession(ctx context.Context, session Adm
 func (r *PostgresRepository) GetAdminSession(ctx context.Context, idHash string) (AdminSession, error) {
 	var s AdminSession
 	err := r.pool.QueryRow(ctx, `
-		SELECT id_hash, admin_user_id, csrf_token, created_at, expires_at
-		FROM admin_sessions
-		WHERE id_hash = $1 AND expires_at > NOW()
+		SELECT s.id_hash, s.admin_user_id, s.csrf_token, s.created_at, s.expires_at,
+		       s.id, s.role, u.role, s.last_seen_at, s.user_agent, s.remote_addr
+		FROM admin_sessions s
+		JOIN admin_users u ON u.id = s.admin_user_id
+		WHERE s.id_hash = $1 AND s.expires_at > NOW() AND u.deactivated_at IS NULL
 	`, idHash).Scan(
 		&s.IDHash,
 		&s.AdminUserID,
 		&s.CSRFToken,
 		&s.CreatedAt,
 		&s.ExpiresAt,
+		&s.ID,
+		&s.Role,
+		&s.UserRole,
+		&s.LastSeenAt,
+		&s.UserAgent,
+		&s.RemoteAddr,
 	)
 	if err != nil {
 		return AdminSession{}, fmt.Errorf("get admin session: %w", err)
@@ -681,9 +1106,14 @@ func (r *PostgresRepository) DeleteAdminSession(ctx context.Context, idHash stri
 	return nil
 }
 
-// DeleteExpiredAdminSessions removes all sessions that have passed their expiry time.
-func (r *PostgresRepository) DeleteExpiredAdminSessions(ctx context.Context) error {
-	_, err := r.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE expires_at < NOW()`)
+// DeleteExpiredAdminSessions removes all sessions that have passed their
+// expiry time, and when idleTimeout is positive, those unused for longer.
+func (r *PostgresRepository) DeleteExpiredAdminSessions(ctx context.Context, idleTimeout time.Duration) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM admin_sessions
+		WHERE expires_at < NOW()
+		   OR ($1::bigint > 0 AND last_seen_at + make_interval(secs => $1::bigint) < NOW())
+	`, int64(idleTimeout.Seconds()))
 	if err != nil {
 		return fmt.Errorf("delete expired admin sessions: %w", err)
 	}
@@ -693,6 +1123,11 @@ func (r *PostgresRepository) DeleteExpiredAdminSessions(ctx context.Context) err
 // PublishFlagEvent inserts a flag event and sends a PostgreSQL NOTIFY on the
 // configured channel within a single transaction.
 func (r *PostgresRepository) PublishFlagEvent(ctx context.Context, event FlagEvent) (FlagEvent, error) {
+	payload, err := r.sealFlagBody(ctx, event.ProjectID, event.FlagKey, ensureJSON(event.Payload, "{}"))
+	if err != nil {
+		return FlagEvent{}, fmt.Errorf("seal flag event payload: %w", err)
+	}
+
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return FlagEvent{}, fmt.Errorf("begin publish event tx: %w", err)
@@ -701,14 +1136,15 @@ func (r *PostgresRepository) PublishFlagEvent(ctx context.Context, event FlagEve
 
 	var created FlagEvent
 	if err := tx.QueryRow(ctx, `
-		INSERT INTO flag_events (project_id, flag_key, event_type, payload)
-		VALUES ($1, $2, $3, $4)
-		RETURNING event_id, project_id, flag_key, event_type, payload, created_at
+		INSERT INTO flag_events (project_id, flag_key, event_type, payload, actor)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING event_id, project_id, flag_key, event_type, payload, created_at, actor
 	`,
 		event.ProjectID,
 		event.FlagKey,
 		event.EventType,
-		ensureJSON(event.Payload, "{}"),
+		payload,
+		event.Actor,
 	).Scan(
 		&created.EventID,
 		&created.ProjectID,
@@ -716,6 +1152,7 @@ func (r *PostgresRepository) PublishFlagEvent(ctx context.Context, event FlagEve
 		&created.EventType,
 		&created.Payload,
 		&created.CreatedAt,
+		&created.Actor,
 	); err != nil {
 		return FlagEvent{}, fmt.Errorf("insert flag event: %w", err)
 	}
@@ -733,25 +1170,28 @@ func (r *PostgresRepository) PublishFlagEvent(ctx context.Context, event FlagEve
 		return FlagEvent{}, fmt.Errorf("commit publish event tx: %w", err)
 	}
 
+	created.Payload = ensureJSON(event.Payload, "{}")
 	return created, nil
 }
 
-// SubscribeFlagInvalidation returns a channel that receives a signal whenever a
-// flag event notification arrives on the PostgreSQL LISTEN channel. The channel
-// is closed if the underlying connection is lost.
-func (r *PostgresRepository) SubscribeFlagInvalidation(ctx context.Context) (<-chan struct{}, error) {
-	invalidations := make(chan struct{}, 1)
+// SubscribeFlagInvalidation returns a channel that receives the flag named by
+// each event notification arriving on the PostgreSQL LISTEN channel. A full
+// reload is asked for instead when a notification cannot be parsed, and after
+// reconnecting, since notifications sent in between are lost. The channel is
+// closed if ctx is cancelled.
+func (r *PostgresRepository) SubscribeFlagInvalidation(ctx context.Context) (<-chan FlagInvalidation, error) {
+	invalidations := make(chan FlagInvalidation, invalidationBuffer)
 
 	go r.runFlagInvalidationListener(ctx, invalidations)
 
 	return invalidations, nil
 }
 
-func (r *PostgresRepository) runFlagInvalidationListener(ctx context.Context, invalidations chan<- struct{}) {
+func (r *PostgresRepository) runFlagInvalidationListener(ctx context.Context, invalidations chan FlagInvalidation) {
 	defer close(invalidations)
 
-	for {
-		err := r.listenForFlagInvalidation(ctx, invalidations)
+	for reconnected := false; ; reconnected = true {
+		err := r.listenForFlagInvalidation(ctx, invalidations, reconnected)
 		if err == nil || ctx.Err() != nil {
 			return
 		}
@@ -763,10 +1203,17 @@ func (r *PostgresRepository) runFlagInvalidationListener(ctx context.Context, in
 			return
 		case <-retryTimer.C:
 		}
+
+		if r.onReconnect != nil {
+			r.onReconnect()
+		}
 	}
 }
 
-func (r *PostgresRepository) listenForFlagInvalidation(ctx context.Context, invalidations chan<- struct{}) error {
+// listenForFlagInvalidation holds a LISTEN until the connection fails. After
+// a reconnect it first asks for a full reload, since the notifications sent
+// while it was away are lost.
+func (r *PostgresRepository) listenForFlagInvalidation(ctx context.Context, invalidations chan FlagInvalidation, reconnected bool) error {
 	conn, err := r.pool.Acquire(ctx)
 	if err != nil {
 		return fmt.Errorf("acquire listen connection: %w", err)
@@ -776,19 +1223,36 @@ func (r *PostgresRepository) listenForFlagInvalidation(ctx context.Context, inva
 	if _, err := conn.Exec(ctx, listenStatement(r.notifyChannel)); err != nil {
 		return fmt.Errorf("listen on %q: %w", r.notifyChannel, err)
 	}
+	r.listening.Store(true)
+	defer r.listening.Store(false)
+	if reconnected {
+		sendInvalidation(invalidations, FlagInvalidation{})
+	}
 
 	for {
-		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
 			return fmt.Errorf("wait for flag event notification: %w", err)
 		}
 
-		select {
-		case invalidations <- struct{}{}:
-		default:
-		}
+		sendInvalidation(invalidations, parseNotifyPayload(notification.Payload))
 	}
 }
 
+// Ping verifies that the connection pool can reach the database.
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	if err := r.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
+	}
+	return nil
+}
+
+// Listening reports whether the cache invalidation listener currently holds
+// an active LISTEN on the notification channel.
+func (r *PostgresRepository) Listening() bool {
+	return r.listening.Load()
+}
+
 func deleteFlagNoRows(commandTag pgconn.CommandTag) error {
 	if commandTag.RowsAffected() == 0 {
 		return fmt.Errorf("delete flag: %w", pgx.ErrNoRows)
@@ -816,9 +1280,9 @@ func ensureJSON(input json.RawMessage, fallback string) json.RawMessage {
 // InsertAuditLog writes a single audit log entry.
 func (r *PostgresRepository) InsertAuditLog(ctx context.Context, entry AuditLogEntry) error {
 	_, err := r.pool.Exec(ctx,
-		`INSERT INTO audit_log (project_id, api_key_id, admin_user_id, action, flag_key, details)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		entry.ProjectID, entry.APIKeyID, entry.AdminUserID, entry.Action, entry.FlagKey, entry.Details,
+		`INSERT INTO audit_log (project_id, api_key_id, admin_user_id, action, flag_key, details, request_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.ProjectID, entry.APIKeyID, entry.AdminUserID, entry.Action, entry.FlagKey, entry.Details, entry.RequestID,
 	)
 	if err != nil {
 		return fmt.Errorf("insert audit log: %w", err)
@@ -830,7 +1294,7 @@ func (r *PostgresRepository) InsertAuditLog(ctx context.Context, entry AuditLogE
 // ListAuditLog returns audit log entries for a project, newest first.
 func (r *PostgresRepository) ListAuditLog(ctx context.Context, projectID string, limit, offset int) ([]AuditLogEntry, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT id, project_id, api_key_id, admin_user_id, action, flag_key, details, created_at
+		`SELECT id, project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at
 		 FROM audit_log
 		 WHERE project_id = $1
 		 ORDER BY id DESC
@@ -845,7 +1309,7 @@ func (r *PostgresRepository) ListAuditLog(ctx context.Context, projectID string,
 	var entries []AuditLogEntry
 	for rows.Next() {
 		var e AuditLogEntry
-		if err := rows.Scan(&e.ID, &e.ProjectID, &e.APIKeyID, &e.AdminUserID, &e.Action, &e.FlagKey, &e.Details, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.ProjectID, &e.APIKeyID, &e.AdminUserID, &e.Action, &e.FlagKey, &e.Details, &e.RequestID, &e.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scanning audit log entry: %w", err)
 		}
 		entries = append(entries, e)
@@ -866,20 +1330,3 @@ func generateRandomHex(n int) (string, error) {
 	}
 	return hex.EncodeToString(b), nil
 }
-
-func marshalNotifyPayload(event FlagEvent) (string, error) {
-	serialized, err := json.Marshal(struct {
-		ProjectID string `json:"project_id"`
-		FlagKey   string `json:"flag_key"`
-		EventType string `json:"event_type"`
-	}{
-		ProjectID: event.ProjectID,
-		FlagKey:   event.FlagKey,
-		EventType: event.EventType,
-	})
-	if err != nil {
-		return "", err
-	}
-
-	return string(serialized), nil
-}
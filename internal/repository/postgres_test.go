@@ -1,15 +1,47 @@
 package repository
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+func TestNewPoolAppliesConfig(t *testing.T) {
+	pool, err := NewPool(context.Background(), "postgres://flagz@127.0.0.1:1/flagz", PoolConfig{
+		MaxConns:          12,
+		MinConns:          0,
+		MaxConnLifetime:   20 * time.Minute,
+		MaxConnIdleTime:   2 * time.Minute,
+		HealthCheckPeriod: 10 * time.Second,
+		StatementTimeout:  1500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	defer pool.Close()
+
+	cfg := pool.Config()
+	if cfg.MaxConns != 12 || cfg.MaxConnLifetime != 20*time.Minute || cfg.MaxConnIdleTime != 2*time.Minute || cfg.HealthCheckPeriod != 10*time.Second {
+		t.Fatalf("pool config = %d, %v, %v, %v", cfg.MaxConns, cfg.MaxConnLifetime, cfg.MaxConnIdleTime, cfg.HealthCheckPeriod)
+	}
+	if got := cfg.ConnConfig.RuntimeParams["statement_timeout"]; got != "1500" {
+		t.Fatalf("statement_timeout = %q, want 1500", got)
+	}
+
+	if _, err := NewPool(context.Background(), "postgres://flagz@127.0.0.1:1/flagz", PoolConfig{MaxConns: 2, MinConns: 3}); err == nil {
+		t.Fatal("NewPool() with min conns above max conns should fail")
+	}
+}
+
 func TestNormalizeNotifyChannel(t *testing.T) {
 	t.Run("defaults when empty", func(t *testing.T) {
 		if got := normalizeNotifyChannel(""); got != defaultNotifyChannel {
@@ -60,6 +92,37 @@ func TestMarshalNotifyPayload(t *testing.T) {
 	})
 }
 
+func TestParseNotifyPayload(t *testing.T) {
+	payload, err := marshalNotifyPayload(FlagEvent{ProjectID: "p1", FlagKey: "new-ui", EventType: "deleted"})
+	if err != nil {
+		t.Fatalf("marshalNotifyPayload() error = %v", err)
+	}
+	got := parseNotifyPayload(payload)
+	if want := (FlagInvalidation{ProjectID: "p1", FlagKey: "new-ui", EventType: "deleted"}); got != want || !got.Targeted() {
+		t.Fatalf("parseNotifyPayload() = %+v, want targeted %+v", got, want)
+	}
+
+	for _, payload := range []string{"", "not json", `{"project_id":"p1"}`} {
+		if got := parseNotifyPayload(payload); got.Targeted() {
+			t.Errorf("parseNotifyPayload(%q) = %+v, want a full reload", payload, got)
+		}
+	}
+}
+
+func TestSendInvalidationFoldsOverflowIntoFullReload(t *testing.T) {
+	invalidations := make(chan FlagInvalidation, 2)
+	sendInvalidation(invalidations, FlagInvalidation{ProjectID: "p1", FlagKey: "a"})
+	sendInvalidation(invalidations, FlagInvalidation{ProjectID: "p1", FlagKey: "b"})
+	sendInvalidation(invalidations, FlagInvalidation{ProjectID: "p1", FlagKey: "c"})
+
+	if got := len(invalidations); got != 1 {
+		t.Fatalf("buffered invalidations = %d, want 1", got)
+	}
+	if got := <-invalidations; got.Targeted() {
+		t.Fatalf("invalidation after overflow = %+v, want a full reload", got)
+	}
+}
+
 func TestListenStatement(t *testing.T) {
 	if got := listenStatement("flag_events"); got != `LISTEN "flag_events"` {
 		t.Fatalf("listenStatement() = %q, want %q", got, `LISTEN "flag_events"`)
@@ -89,3 +152,67 @@ func TestDeleteFlagNoRows(t *testing.T) {
 		t.Fatalf("deleteFlagNoRows(delete 0) error = %v, want %v", err, pgx.ErrNoRows)
 	}
 }
+
+func TestReadWithFallback(t *testing.T) {
+	ctx := context.Background()
+	// Pools connect lazily, so these never touch a database.
+	primary, err := pgxpool.New(ctx, "postgres://primary.invalid/flagz")
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	defer primary.Close()
+	replica, err := pgxpool.New(ctx, "postgres://replica.invalid/flagz")
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	defer replica.Close()
+
+	var fallbacks []string
+	r := NewPostgresRepository(primary,
+		WithReadPool(replica),
+		WithReadFallbackHook(func(op string) { fallbacks = append(fallbacks, op) }),
+	)
+	source := func(pool *pgxpool.Pool) string {
+		if pool == replica {
+			return "replica"
+		}
+		return "primary"
+	}
+
+	got, err := readWithFallback(ctx, r, "ok", func(pool *pgxpool.Pool) (string, error) {
+		return source(pool), nil
+	})
+	if err != nil || got != "replica" || len(fallbacks) != 0 {
+		t.Fatalf("healthy read = %q, %v with fallbacks %v; want the replica", got, err, fallbacks)
+	}
+
+	for _, replicaErr := range []error{errors.New("connection refused"), fmt.Errorf("get flag: %w", pgx.ErrNoRows)} {
+		got, err = readWithFallback(ctx, r, "failing", func(pool *pgxpool.Pool) (string, error) {
+			if pool == replica {
+				return "", replicaErr
+			}
+			return source(pool), nil
+		})
+		if err != nil || got != "primary" {
+			t.Fatalf("read after replica error %v = %q, %v; want the primary", replicaErr, got, err)
+		}
+	}
+	if want := []string{"failing", "failing"}; !slices.Equal(fallbacks, want) {
+		t.Fatalf("fallbacks = %v, want %v", fallbacks, want)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := readWithFallback(cancelled, r, "cancelled", func(pool *pgxpool.Pool) (string, error) {
+		return source(pool), cancelled.Err()
+	}); !errors.Is(err, context.Canceled) || len(fallbacks) != 2 {
+		t.Fatalf("cancelled read error = %v with fallbacks %v; want no retry", err, fallbacks)
+	}
+
+	got, err = readWithFallback(ctx, NewPostgresRepository(primary), "ok", func(pool *pgxpool.Pool) (string, error) {
+		return source(pool), nil
+	})
+	if err != nil || got != "primary" {
+		t.Fatalf("read without a read pool = %q, %v; want the primary", got, err)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// FlagDefaults holds a project's template for new flags. Each field is
+// applied only when a flag is created without a value for it.
+type FlagDefaults struct {
+	// DescriptionTemplate becomes the description of flags created without
+	// one. Every "{{key}}" in it is replaced with the new flag's key.
+	DescriptionTemplate string `json:"description_template,omitempty"`
+	// Rules is the initial rules skeleton for flags created without rules.
+	Rules json.RawMessage `json:"rules,omitempty"`
+}
+
+// GetFlagDefaults returns the flag defaults configured for a project. A
+// project that never configured any returns the zero value. Returns
+// pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) GetFlagDefaults(ctx context.Context, projectID string) (FlagDefaults, error) {
+	var payload []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT flag_defaults
+		FROM projects
+		WHERE id = $1
+	`, projectID).Scan(&payload)
+	if err != nil {
+		return FlagDefaults{}, fmt.Errorf("get flag defaults: %w", err)
+	}
+
+	var defaults FlagDefaults
+	if err := json.Unmarshal(payload, &defaults); err != nil {
+		return FlagDefaults{}, fmt.Errorf("decode flag defaults: %w", err)
+	}
+	return defaults, nil
+}
+
+// SetFlagDefaults replaces the flag defaults of a project and returns the
+// stored value. Returns pgx.ErrNoRows (wrapped) if the project does not exist.
+func (r *PostgresRepository) SetFlagDefaults(ctx context.Context, projectID string, defaults FlagDefaults) (FlagDefaults, error) {
+	payload, err := json.Marshal(defaults)
+	if err != nil {
+		return FlagDefaults{}, fmt.Errorf("encode flag defaults: %w", err)
+	}
+
+	var stored []byte
+	err = r.pool.QueryRow(ctx, `
+		UPDATE projects
+		SET flag_defaults = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING flag_defaults
+	`, projectID, payload).Scan(&stored)
+	if err != nil {
+		return FlagDefaults{}, fmt.Errorf("set flag defaults: %w", err)
+	}
+
+	var updated FlagDefaults
+	if err := json.Unmarshal(stored, &updated); err != nil {
+		return FlagDefaults{}, fmt.Errorf("decode flag defaults: %w", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProjectOverview summarizes a live project for the admin dashboard. The
+// "recent" counts cover the window since the time passed to
+// [PostgresRepository.ListProjectOverviews].
+type ProjectOverview struct {
+	Project
+	Flags        int64
+	EnabledFlags int64
+	// RecentlyChangedFlags counts the flags updated within the window.
+	RecentlyChangedFlags int64
+	// ActiveAPIKeys counts the project's keys that are not revoked.
+	ActiveAPIKeys int64
+	// RecentAuditActions counts the audit log entries within the window.
+	RecentAuditActions int64
+	// LastAuditAction and LastAuditAt describe the project's latest audit
+	// log entry; LastAuditAt is nil if it has none.
+	LastAuditAction string
+	LastAuditAt     *time.Time
+}
+
+// EnabledPercent returns the percentage of the project's flags that are
+// enabled, or 0 if it has none.
+func (o ProjectOverview) EnabledPercent() float64 {
+	if o.Flags == 0 {
+		return 0
+	}
+	return 100 * float64(o.EnabledFlags) / float64(o.Flags)
+}
+
+// DailyActivity counts a project's flag events and audit log entries on one
+// UTC day.
+type DailyActivity struct {
+	// Day is midnight UTC at the start of the day.
+	Day          time.Time `json:"day"`
+	FlagEvents   int64     `json:"flag_events"`
+	AuditActions int64     `json:"audit_actions"`
+}
+
+// ListProjectOverviews returns an overview of every live project, ordered by
+// name, counting recent changes from since onwards. The counts come from one
+// aggregate query rather than one per project.
+func (r *PostgresRepository) ListProjectOverviews(ctx context.Context, since time.Time) ([]ProjectOverview, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.id, p.name, p.description, p.created_at, p.updated_at,
+			COALESCE(f.total, 0), COALESCE(f.enabled, 0), COALESCE(f.changed, 0),
+			COALESCE(k.active, 0), COALESCE(a.recent, 0),
+			COALESCE(last.action, ''), last.created_at
+		FROM projects p
+		LEFT JOIN (
+			SELECT project_id,
+				count(*) AS total,
+				count(*) FILTER (WHERE enabled) AS enabled,
+				count(*) FILTER (WHERE updated_at >= $1) AS changed
+			FROM flags
+			WHERE archived_at IS NULL
+			GROUP BY project_id
+		) f ON f.project_id = p.id
+		LEFT JOIN (
+			SELECT project_id, count(*) AS active
+			FROM api_keys
+			WHERE revoked_at IS NULL
+			GROUP BY project_id
+		) k ON k.project_id = p.id
+		LEFT JOIN (
+			SELECT project_id, count(*) AS recent
+			FROM audit_log
+			WHERE created_at >= $1
+			GROUP BY project_id
+		) a ON a.project_id = p.id
+		LEFT JOIN LATERAL (
+			SELECT action, created_at
+			FROM audit_log
+			WHERE project_id = p.id
+			ORDER BY id DESC
+			LIMIT 1
+		) last ON true
+		WHERE p.deleted_at IS NULL
+		ORDER BY p.name
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("list project overviews: %w", err)
+	}
+	defer rows.Close()
+
+	overviews := make([]ProjectOverview, 0)
+	for rows.Next() {
+		var o ProjectOverview
+		if err := rows.Scan(
+			&o.ID, &o.Name, &o.Description, &o.CreatedAt, &o.UpdatedAt,
+			&o.Flags, &o.EnabledFlags, &o.RecentlyChangedFlags,
+			&o.ActiveAPIKeys, &o.RecentAuditActions,
+			&o.LastAuditAction, &o.LastAuditAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan project overview: %w", err)
+		}
+		overviews = append(overviews, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list project overviews rows: %w", err)
+	}
+	return overviews, nil
+}
+
+// ListProjectActivity returns a project's daily activity from the UTC day
+// containing since onwards, ordered by day. Days without activity have no
+// entry.
+func (r *PostgresRepository) ListProjectActivity(ctx context.Context, projectID string, since time.Time) ([]DailyActivity, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH events AS (
+			SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, count(*) AS n
+			FROM flag_events
+			WHERE project_id = $1 AND created_at >= date_trunc('day', $2::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+			GROUP BY 1
+		), audits AS (
+			SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, count(*) AS n
+			FROM audit_log
+			WHERE project_id = $1 AND created_at >= date_trunc('day', $2::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+			GROUP BY 1
+		)
+		SELECT COALESCE(e.day, a.day) AT TIME ZONE 'UTC', COALESCE(e.n, 0), COALESCE(a.n, 0)
+		FROM events e
+		FULL JOIN audits a ON a.day = e.day
+		ORDER BY 1
+	`, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("list project activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := make([]DailyActivity, 0)
+	for rows.Next() {
+		var d DailyActivity
+		if err := rows.Scan(&d.Day, &d.FlagEvents, &d.AuditActions); err != nil {
+			return nil, fmt.Errorf("scan project activity: %w", err)
+		}
+		d.Day = d.Day.UTC()
+		activity = append(activity, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list project activity rows: %w", err)
+	}
+	return activity, nil
+}
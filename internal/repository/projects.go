@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DeleteProject soft-deletes a project, revokes all of its API keys and
+// deletes its dashboard tokens in a single transaction, so restoring the
+// project brings back neither. The project's flags stay in place but are no longer
+// returned by ListFlags or GetFlag. Returns pgx.ErrNoRows (wrapped) if the
+// project does not exist or is already deleted.
+func (r *PostgresRepository) DeleteProject(ctx context.Context, id string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin delete project tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	commandTag, err := tx.Exec(ctx, `
+		UPDATE projects SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("delete project: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("delete project: %w", pgx.ErrNoRows)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE project_id = $1 AND revoked_at IS NULL
+	`, id); err != nil {
+		return fmt.Errorf("revoke project api keys: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM dashboard_tokens WHERE project_id = $1`, id); err != nil {
+		return fmt.Errorf("delete project dashboard tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit delete project tx: %w", err)
+	}
+	return nil
+}
+
+// RestoreProject clears the deletion of a project deleted after
+// deletedAfter. API keys revoked by the deletion stay revoked. Returns
+// pgx.ErrNoRows (wrapped) if no such deleted project exists.
+func (r *PostgresRepository) RestoreProject(ctx context.Context, id string, deletedAfter time.Time) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		UPDATE projects SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at > $2
+	`, id, deletedAfter)
+	if err != nil {
+		return fmt.Errorf("restore project: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("restore project: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ListDeletedProjects returns soft-deleted projects, most recently deleted
+// first.
+func (r *PostgresRepository) ListDeletedProjects(ctx context.Context) ([]Project, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, name, description, created_at, updated_at, deleted_at
+		FROM projects
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list deleted projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := make([]Project, 0)
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list deleted projects rows: %w", err)
+	}
+	return projects, nil
+}
+
+// PurgeDeletedProjects permanently removes projects deleted on or before
+// deletedBefore, together with their flags, keys, events and audit log, and
+// returns how many projects were removed.
+func (r *PostgresRepository) PurgeDeletedProjects(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	commandTag, err := r.pool.Exec(ctx, `
+		DELETE FROM projects
+		WHERE deleted_at IS NOT NULL AND deleted_at <= $1
+	`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted projects: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
+}
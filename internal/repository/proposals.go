@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Flag proposal actions and statuses.
+const (
+	ProposalActionUpdate = "update"
+	ProposalActionDelete = "delete"
+
+	ProposalStatusPending  = "pending"
+	ProposalStatusApproved = "approved"
+	ProposalStatusRejected = "rejected"
+)
+
+// FlagProposal is a pending change to a flag that takes effect only once a
+// second actor approves it. Flag holds the proposed flag body for updates
+// and is empty for deletes. The proposer and reviewer are each identified by
+// either an API key ID or an admin user ID.
+type FlagProposal struct {
+	ID                    string          `json:"id"`
+	ProjectID             string          `json:"project_id"`
+	FlagKey               string          `json:"flag_key"`
+	Action                string          `json:"action"`
+	Flag                  json.RawMessage `json:"flag,omitempty"`
+	Status                string          `json:"status"`
+	ProposedByAPIKeyID    string          `json:"proposed_by_api_key_id,omitempty"`
+	ProposedByAdminUserID string          `json:"proposed_by_admin_user_id,omitempty"`
+	ReviewedByAPIKeyID    string          `json:"reviewed_by_api_key_id,omitempty"`
+	ReviewedByAdminUserID string          `json:"reviewed_by_admin_user_id,omitempty"`
+	CreatedAt             time.Time       `json:"created_at"`
+	ReviewedAt            *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+const flagProposalColumns = `id, project_id, flag_key, action, flag, status,
+	proposed_by_api_key_id, proposed_by_admin_user_id,
+	reviewed_by_api_key_id, reviewed_by_admin_user_id,
+	created_at, reviewed_at`
+
+func scanFlagProposal(row pgx.Row) (FlagProposal, error) {
+	var p FlagProposal
+	err := row.Scan(
+		&p.ID,
+		&p.ProjectID,
+		&p.FlagKey,
+		&p.Action,
+		&p.Flag,
+		&p.Status,
+		&p.ProposedByAPIKeyID,
+		&p.ProposedByAdminUserID,
+		&p.ReviewedByAPIKeyID,
+		&p.ReviewedByAdminUserID,
+		&p.CreatedAt,
+		&p.ReviewedAt,
+	)
+	return p, err
+}
+
+// CreateFlagProposal stores a new pending proposal and returns it with its
+// generated ID.
+func (r *PostgresRepository) CreateFlagProposal(ctx context.Context, proposal FlagProposal) (FlagProposal, error) {
+	var flag any
+	if len(proposal.Flag) > 0 {
+		sealed, err := r.sealFlagBody(ctx, proposal.ProjectID, proposal.FlagKey, proposal.Flag)
+		if err != nil {
+			return FlagProposal{}, fmt.Errorf("seal flag proposal: %w", err)
+		}
+		flag = sealed
+	}
+
+	created, err := scanFlagProposal(r.pool.QueryRow(ctx, `
+		INSERT INTO flag_proposals (project_id, flag_key, action, flag, proposed_by_api_key_id, proposed_by_admin_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+flagProposalColumns,
+		proposal.ProjectID,
+		proposal.FlagKey,
+		proposal.Action,
+		flag,
+		proposal.ProposedByAPIKeyID,
+		proposal.ProposedByAdminUserID,
+	))
+	if err != nil {
+		return FlagProposal{}, fmt.Errorf("create flag proposal: %w", err)
+	}
+	created.Flag = proposal.Flag
+	return created, nil
+}
+
+// GetFlagProposal retrieves a proposal by project and ID. Returns
+// pgx.ErrNoRows (wrapped) if it does not exist.
+func (r *PostgresRepository) GetFlagProposal(ctx context.Context, projectID, id string) (FlagProposal, error) {
+	p, err := scanFlagProposal(r.pool.QueryRow(ctx, `
+		SELECT `+flagProposalColumns+`
+		FROM flag_proposals
+		WHERE project_id = $1 AND id::text = $2
+	`, projectID, id))
+	if err != nil {
+		return FlagProposal{}, fmt.Errorf("get flag proposal: %w", err)
+	}
+	if p.Flag, err = r.openFlagBody(ctx, p.ProjectID, p.FlagKey, p.Flag); err != nil {
+		return FlagProposal{}, fmt.Errorf("get flag proposal: %w", err)
+	}
+	return p, nil
+}
+
+// ListFlagProposals returns a project's proposals, newest first. Blank
+// flagKey or status match every proposal.
+func (r *PostgresRepository) ListFlagProposals(ctx context.Context, projectID, flagKey, status string) ([]FlagProposal, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+flagProposalColumns+`
+		FROM flag_proposals
+		WHERE project_id = $1
+		  AND ($2 = '' OR flag_key = $2)
+		  AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC
+	`, projectID, flagKey, status)
+	if err != nil {
+		return nil, fmt.Errorf("list flag proposals: %w", err)
+	}
+	defer rows.Close()
+
+	proposals := make([]FlagProposal, 0)
+	for rows.Next() {
+		p, err := scanFlagProposal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan flag proposal: %w", err)
+		}
+		proposals = append(proposals, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list flag proposals rows: %w", err)
+	}
+	if err := r.openProposals(ctx, proposals); err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+// ReviewFlagProposal moves a pending proposal to status and records the
+// reviewer. Only pending proposals can be reviewed, so of two concurrent
+// reviews exactly one succeeds. Returns pgx.ErrNoRows (wrapped) if the
+// proposal does not exist or is no longer pending.
+func (r *PostgresRepository) ReviewFlagProposal(ctx context.Context, projectID, id, status, reviewerAPIKeyID, reviewerAdminUserID string) (FlagProposal, error) {
+	p, err := scanFlagProposal(r.pool.QueryRow(ctx, `
+		UPDATE flag_proposals
+		SET status = $3,
+		    reviewed_by_api_key_id = $4,
+		    reviewed_by_admin_user_id = $5,
+		    reviewed_at = NOW()
+		WHERE project_id = $1 AND id::text = $2 AND status = 'pending'
+		RETURNING `+flagProposalColumns,
+		projectID, id, status, reviewerAPIKeyID, reviewerAdminUserID,
+	))
+	if err != nil {
+		return FlagProposal{}, fmt.Errorf("review flag proposal: %w", err)
+	}
+	if p.Flag, err = r.openFlagBody(ctx, p.ProjectID, p.FlagKey, p.Flag); err != nil {
+		return FlagProposal{}, fmt.Errorf("review flag proposal: %w", err)
+	}
+	return p, nil
+}
+
+// ReopenFlagProposal returns an approved proposal to pending and clears its
+// reviewer. It is used when an approved change could not be applied.
+func (r *PostgresRepository) ReopenFlagProposal(ctx context.Context, projectID, id string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE flag_proposals
+		SET status = 'pending',
+		    reviewed_by_api_key_id = '',
+		    reviewed_by_admin_user_id = '',
+		    reviewed_at = NULL
+		WHERE project_id = $1 AND id::text = $2 AND status = 'approved'
+	`, projectID, id)
+	if err != nil {
+		return fmt.Errorf("reopen flag proposal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisChannel is the Redis pub/sub channel used for cache
+// invalidation when none is configured.
+const DefaultRedisChannel = "flagz:flag_events"
+
+// RedisNotifier carries cache invalidation signals over Redis pub/sub instead
+// of PostgreSQL LISTEN/NOTIFY. It is intended for deployments where a
+// long-lived LISTEN connection is not available, such as behind PgBouncer in
+// transaction pooling mode. Writers publish through
+// [RedisNotifier.PublishFlagInvalidation]; every replica subscribes through
+// [RedisNotifier.SubscribeFlagInvalidation].
+type RedisNotifier struct {
+	client    *redis.Client
+	channel   string
+	listening atomic.Bool
+}
+
+// NewRedisNotifier returns a [RedisNotifier] publishing and subscribing on
+// channel, or [DefaultRedisChannel] if channel is blank.
+func NewRedisNotifier(client *redis.Client, channel string) *RedisNotifier {
+	if strings.TrimSpace(channel) == "" {
+		channel = DefaultRedisChannel
+	}
+	return &RedisNotifier{client: client, channel: channel}
+}
+
+// SubscribeFlagInvalidation subscribes to the invalidation channel and
+// returns a channel that receives the flag named by every published flag
+// event, buffered the same way as the LISTEN/NOTIFY listener. The Redis
+// client reconnects and resubscribes on its own after network failures; the
+// returned channel is closed only when ctx is cancelled.
+func (n *RedisNotifier) SubscribeFlagInvalidation(ctx context.Context) (<-chan FlagInvalidation, error) {
+	pubsub := n.client.Subscribe(ctx, n.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe redis channel %q: %w", n.channel, err)
+	}
+	n.listening.Store(true)
+
+	invalidations := make(chan FlagInvalidation, invalidationBuffer)
+	go func() {
+		defer close(invalidations)
+		defer pubsub.Close()
+		defer n.listening.Store(false)
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				sendInvalidation(invalidations, parseNotifyPayload(message.Payload))
+			}
+		}
+	}()
+
+	return invalidations, nil
+}
+
+// PublishFlagInvalidation announces event to every subscribed replica. The
+// message body matches the LISTEN/NOTIFY payload.
+func (n *RedisNotifier) PublishFlagInvalidation(ctx context.Context, event FlagEvent) error {
+	payload, err := marshalNotifyPayload(event)
+	if err != nil {
+		return fmt.Errorf("marshal notify payload: %w", err)
+	}
+	if err := n.client.Publish(ctx, n.channel, payload).Err(); err != nil {
+		return fmt.Errorf("publish redis invalidation: %w", err)
+	}
+	return nil
+}
+
+// Listening reports whether the Redis subscription is active.
+func (n *RedisNotifier) Listening() bool {
+	return n.listening.Load()
+}
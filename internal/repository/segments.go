@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// Segment is a named set of rules saved for a project, which flag rules
+// reference by name to target the same group of users everywhere.
+type Segment struct {
+	ProjectID   string      `json:"-"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Rules       []core.Rule `json:"rules"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+const segmentColumns = `project_id, name, description, rules, created_at, updated_at`
+
+func scanSegment(row pgx.Row) (Segment, error) {
+	var s Segment
+	err := row.Scan(&s.ProjectID, &s.Name, &s.Description, &s.Rules, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+func collectSegments(rows pgx.Rows) ([]Segment, error) {
+	defer rows.Close()
+
+	segments := make([]Segment, 0)
+	for rows.Next() {
+		s, err := scanSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan segment: %w", err)
+		}
+		segments = append(segments, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list segments rows: %w", err)
+	}
+	return segments, nil
+}
+
+// ListAllSegments returns every project's segments, ordered by project and
+// name, for loading the service cache.
+func (r *PostgresRepository) ListAllSegments(ctx context.Context) ([]Segment, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+segmentColumns+`
+		FROM segments
+		ORDER BY project_id, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list all segments: %w", err)
+	}
+	return collectSegments(rows)
+}
+
+// PutSegment creates the segment or replaces the one with the same name,
+// and returns the stored segment.
+func (r *PostgresRepository) PutSegment(ctx context.Context, segment Segment) (Segment, error) {
+	s, err := scanSegment(r.pool.QueryRow(ctx, `
+		INSERT INTO segments (project_id, name, description, rules)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, name) DO UPDATE
+		SET description = EXCLUDED.description,
+		    rules = EXCLUDED.rules,
+		    updated_at = NOW()
+		RETURNING `+segmentColumns,
+		segment.ProjectID, segment.Name, segment.Description, segment.Rules,
+	))
+	if err != nil {
+		return Segment{}, fmt.Errorf("put segment: %w", err)
+	}
+	return s, nil
+}
+
+// DeleteSegment removes a segment. Returns pgx.ErrNoRows (wrapped) if it
+// does not exist.
+func (r *PostgresRepository) DeleteSegment(ctx context.Context, projectID, name string) error {
+	commandTag, err := r.pool.Exec(ctx, `
+		DELETE FROM segments
+		WHERE project_id = $1 AND name = $2
+	`, projectID, name)
+	if err != nil {
+		return fmt.Errorf("delete segment: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return fmt.Errorf("delete segment: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
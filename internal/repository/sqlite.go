@@ -0,0 +1,868 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	_ "modernc.org/sqlite"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// SQLiteRepository implements flag, API key, event and audit log persistence
+// in a single SQLite database file, for deployments that run one server and
+// do not want to operate PostgreSQL. It has no cross-process change
+// notifications, so it does not implement cache invalidation subscriptions:
+// the service relies on its own writes and the periodic cache resync.
+//
+// Like [PostgresRepository], it reports missing rows by wrapping
+// [pgx.ErrNoRows], so the service maps them to the same not-found errors.
+// Features backed by other optional repository interfaces, such as projects
+// management, proposals and the admin portal, are not available.
+type SQLiteRepository struct {
+	db             *sql.DB
+	eventBatchSize int
+}
+
+// OpenSQLite opens, creating if needed, the SQLite database named by dsn and
+// brings its schema up to date. dsn is a path, optionally prefixed with
+// "sqlite:" or "sqlite://", or a "file:" URI. eventBatchSize caps the events
+// returned per query, as [WithEventBatchSize] does; <= 0 uses the default.
+func OpenSQLite(ctx context.Context, dsn string, eventBatchSize int) (*SQLiteRepository, error) {
+	path := dsn
+	for _, prefix := range []string{"sqlite://", "sqlite:"} {
+		if len(path) >= len(prefix) && strings.EqualFold(path[:len(prefix)], prefix) {
+			path = path[len(prefix):]
+			break
+		}
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, errors.New("open sqlite: database path is empty")
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// A single connection serializes writers, which SQLite requires anyway,
+	// keeps the pragmas below in effect and lets ":memory:" databases work.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{
+		"PRAGMA foreign_keys = ON",
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err := db.ExecContext(ctx, pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("sqlite %s: %w", pragma, err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("apply sqlite schema: %w", err)
+	}
+	if err := addSQLiteColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if eventBatchSize <= 0 {
+		eventBatchSize = defaultEventBatchSize
+	}
+	return &SQLiteRepository{db: db, eventBatchSize: eventBatchSize}, nil
+}
+
+// Close closes the database.
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// sqliteAddedColumns are columns added to the schema after the table was
+// first created. CREATE TABLE IF NOT EXISTS does not add them to an existing
+// database, so [OpenSQLite] adds any that are missing.
+var sqliteAddedColumns = []struct{ table, column, definition string }{
+	{"flags", "tags", "TEXT NOT NULL DEFAULT '[]'"},
+	{"flags", "owner", "TEXT NOT NULL DEFAULT ''"},
+	{"flags", "expires_at", "TEXT"},
+	{"flags", "expiry_notified_at", "TEXT"},
+	{"flags", "shadow_rules", "TEXT"},
+	{"flags", "created_by", "TEXT NOT NULL DEFAULT ''"},
+	{"flags", "updated_by", "TEXT NOT NULL DEFAULT ''"},
+	{"flag_events", "actor", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_log", "request_id", "TEXT NOT NULL DEFAULT ''"},
+	{"api_keys", "last_used_at", "TEXT"},
+	{"flags", "archived_at", "TEXT"},
+}
+
+func addSQLiteColumns(ctx context.Context, db *sql.DB) error {
+	for _, c := range sqliteAddedColumns {
+		var exists bool
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("inspect sqlite table %s: %w", c.table, err)
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("add sqlite column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+const sqliteFlagColumns = `f.project_id, f.key, f.description, f.enabled, f.variants, f.rules, f.bucketing_salt, f.variants_schema, f.shadow_rules, f.tags, f.owner, f.expires_at, f.created_at, f.updated_at, f.created_by, f.updated_by`
+
+// CreateFlag inserts a new flag with a fresh bucketing salt and returns it.
+func (r *SQLiteRepository) CreateFlag(ctx context.Context, flag Flag) (Flag, error) {
+	created, err := insertSQLiteFlag(ctx, r.db, flag)
+	if err != nil {
+		return Flag{}, fmt.Errorf("create flag: %w", err)
+	}
+	return created, nil
+}
+
+// CreateFlags inserts flags in a single transaction and returns the created
+// records in input order. If any insert fails the whole batch is rolled back
+// and the returned error names the offending flag key.
+func (r *SQLiteRepository) CreateFlags(ctx context.Context, flags []Flag) ([]Flag, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin create flags tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	created := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		row, err := insertSQLiteFlag(ctx, tx, flag)
+		if err != nil {
+			return nil, fmt.Errorf("create flag %q: %w", flag.Key, err)
+		}
+		created = append(created, row)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit create flags tx: %w", err)
+	}
+	return created, nil
+}
+
+func insertSQLiteFlag(ctx context.Context, db interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, flag Flag) (Flag, error) {
+	salt, err := generateRandomHex(16)
+	if err != nil {
+		return Flag{}, fmt.Errorf("generate bucketing salt: %w", err)
+	}
+	now := time.Now().UTC()
+	created := Flag{
+		Key:            flag.Key,
+		ProjectID:      flag.ProjectID,
+		Description:    flag.Description,
+		Enabled:        flag.Enabled,
+		Variants:       ensureJSON(flag.Variants, "{}"),
+		Rules:          ensureJSON(flag.Rules, "[]"),
+		BucketingSalt:  salt,
+		VariantsSchema: flag.VariantsSchema,
+		ShadowRules:    flag.ShadowRules,
+		Tags:           flag.Tags,
+		Owner:          flag.Owner,
+		ExpiresAt:      flag.ExpiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		CreatedBy:      flag.CreatedBy,
+		UpdatedBy:      flag.CreatedBy,
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO flags (project_id, key, description, enabled, variants, rules, bucketing_salt, variants_schema, shadow_rules, tags, owner, expires_at, created_at, updated_at, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		created.ProjectID,
+		created.Key,
+		created.Description,
+		created.Enabled,
+		string(created.Variants),
+		string(created.Rules),
+		created.BucketingSalt,
+		sqliteNullableJSON(created.VariantsSchema),
+		sqliteNullableJSON(created.ShadowRules),
+		sqliteTagsJSON(created.Tags),
+		created.Owner,
+		sqliteNullableTime(created.ExpiresAt),
+		formatSQLiteTime(now),
+		formatSQLiteTime(now),
+		created.CreatedBy,
+		created.UpdatedBy,
+	); err != nil {
+		return Flag{}, err
+	}
+	return created, nil
+}
+
+// UpdateFlag updates an existing flag and returns it. Returns pgx.ErrNoRows
+// (wrapped) if the flag does not exist or is archived.
+func (r *SQLiteRepository) UpdateFlag(ctx context.Context, flag Flag) (Flag, error) {
+	return updateSQLiteFlag(ctx, r.db, flag)
+}
+
+// updateSQLiteFlag is [SQLiteRepository.UpdateFlag] on db, which may be a
+// transaction.
+func updateSQLiteFlag(ctx context.Context, db sqliteConn, flag Flag) (Flag, error) {
+	result, err := db.ExecContext(ctx, `
+		UPDATE flags
+		SET description = ?,
+		    enabled = ?,
+		    variants = ?,
+		    rules = ?,
+		    variants_schema = ?,
+		    shadow_rules = ?,
+		    tags = COALESCE(?, tags),
+		    owner = ?,
+		    expiry_notified_at = CASE WHEN expires_at IS ? THEN expiry_notified_at END,
+		    expires_at = ?,
+		    updated_by = ?,
+		    updated_at = ?
+		WHERE project_id = ? AND key = ? AND archived_at IS NULL
+	`,
+		flag.Description,
+		flag.Enabled,
+		string(ensureJSON(flag.Variants, "{}")),
+		string(ensureJSON(flag.Rules, "[]")),
+		sqliteNullableJSON(flag.VariantsSchema),
+		sqliteNullableJSON(flag.ShadowRules),
+		sqliteNullableTags(flag.Tags),
+		flag.Owner,
+		sqliteNullableTime(flag.ExpiresAt),
+		sqliteNullableTime(flag.ExpiresAt),
+		flag.UpdatedBy,
+		formatSQLiteTime(time.Now().UTC()),
+		flag.ProjectID,
+		flag.Key,
+	)
+	if err != nil {
+		return Flag{}, fmt.Errorf("update flag: %w", err)
+	}
+	if err := sqliteNoRows(result, "update flag"); err != nil {
+		return Flag{}, err
+	}
+
+	return getSQLiteFlag(ctx, db, flag.ProjectID, flag.Key, "update flag")
+}
+
+// GetFlag retrieves a single flag of a project that is not soft-deleted.
+// Returns pgx.ErrNoRows (wrapped) if not found or archived.
+func (r *SQLiteRepository) GetFlag(ctx context.Context, projectID, key string) (Flag, error) {
+	return r.getFlag(ctx, projectID, key, "get flag")
+}
+
+func (r *SQLiteRepository) getFlag(ctx context.Context, projectID, key, op string) (Flag, error) {
+	return getSQLiteFlag(ctx, r.db, projectID, key, op)
+}
+
+func getSQLiteFlag(ctx context.Context, db sqliteConn, projectID, key, op string) (Flag, error) {
+	row := db.QueryRowContext(ctx, `
+		SELECT `+sqliteFlagColumns+`
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id = ? AND f.key = ? AND f.archived_at IS NULL AND p.deleted_at IS NULL
+	`, projectID, key)
+	flag, err := scanSQLiteFlag(row)
+	if err != nil {
+		return Flag{}, fmt.Errorf("%s: %w", op, sqliteErr(err))
+	}
+	return flag, nil
+}
+
+// ListFlags returns all flags across all projects ordered by project_id and
+// key. Archived flags and flags of soft-deleted projects are excluded.
+func (r *SQLiteRepository) ListFlags(ctx context.Context) ([]Flag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sqliteFlagColumns+`
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE f.archived_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY f.project_id, f.key
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]Flag, 0)
+	for rows.Next() {
+		flag, err := scanSQLiteFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list flags rows: %w", err)
+	}
+
+	return flags, nil
+}
+
+// ClaimExpiredFlags marks the flags whose expiry has passed and was not
+// reported yet as reported, and returns them, like
+// [PostgresRepository.ClaimExpiredFlags]. The flags are read and marked in
+// one transaction.
+func (r *SQLiteRepository) ClaimExpiredFlags(ctx context.Context, now time.Time) ([]Flag, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin claim expired flags tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+sqliteFlagColumns+`
+		FROM flags f
+		JOIN projects p ON p.id = f.project_id
+		WHERE p.deleted_at IS NULL
+		  AND f.archived_at IS NULL
+		  AND f.expires_at IS NOT NULL
+		  AND julianday(f.expires_at) <= julianday(?)
+		  AND f.expiry_notified_at IS NULL
+		ORDER BY f.project_id, f.key
+	`, formatSQLiteTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("claim expired flags: %w", err)
+	}
+	var flags []Flag
+	for rows.Next() {
+		flag, err := scanSQLiteFlag(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan expired flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating expired flag rows: %w", err)
+	}
+
+	for _, flag := range flags {
+		if _, err := tx.ExecContext(ctx, `UPDATE flags SET expiry_notified_at = ? WHERE project_id = ? AND key = ?`,
+			formatSQLiteTime(now), flag.ProjectID, flag.Key); err != nil {
+			return nil, fmt.Errorf("mark expired flag %q: %w", flag.Key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit claim expired flags tx: %w", err)
+	}
+	return flags, nil
+}
+
+// DeleteFlag removes a flag. Returns pgx.ErrNoRows (wrapped) if the flag does
+// not exist.
+func (r *SQLiteRepository) DeleteFlag(ctx context.Context, projectID, key string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM flags WHERE project_id = ? AND key = ?`, projectID, key)
+	if err != nil {
+		return fmt.Errorf("delete flag: %w", err)
+	}
+	return sqliteNoRows(result, "delete flag")
+}
+
+// ValidateAPIKey returns the stored hash and project ID for a non-revoked key ID.
+// Callers should do constant-time comparison outside this package.
+func (r *SQLiteRepository) ValidateAPIKey(ctx context.Context, id string) (string, string, error) {
+	var keyHash, projectID string
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT key_hash, project_id
+		FROM api_keys
+		WHERE id = ? AND revoked_at IS NULL
+	`, id).Scan(&keyHash, &projectID); err != nil {
+		return "", "", fmt.Errorf("validate api key: %w", sqliteErr(err))
+	}
+	return keyHash, projectID, nil
+}
+
+// TouchAPIKey records that the key authenticated a request at usedAt.
+func (r *SQLiteRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = ? WHERE id = ?
+	`, formatSQLiteTime(usedAt), id); err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+// CreateAPIKey generates a new API key for the given project, storing a bcrypt
+// hash of the secret. The raw secret is returned exactly once.
+func (r *SQLiteRepository) CreateAPIKey(ctx context.Context, projectID string) (string, string, error) {
+	keyID, err := generateRandomHex(16)
+	if err != nil {
+		return "", "", fmt.Errorf("generate key id: %w", err)
+	}
+	secret, err := generateRandomHex(32)
+	if err != nil {
+		return "", "", fmt.Errorf("generate secret: %w", err)
+	}
+	hash, err := middleware.HashAPIKey(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("hash api key: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, project_id, name, key_hash, scope, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, keyID, projectID, "api-key-"+keyID[:8], hash, APIKeyScopeProject, formatSQLiteTime(time.Now().UTC())); err != nil {
+		return "", "", fmt.Errorf("create api key: %w", err)
+	}
+
+	return keyID, secret, nil
+}
+
+// ListAPIKeys returns metadata for all non-revoked API keys belonging to the
+// given project, oldest first. Key rotation policies are not supported, so
+// no key has a rotation deadline.
+func (r *SQLiteRepository) ListAPIKeys(ctx context.Context, projectID string) ([]APIKeyMeta, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, project_id, scope, created_at, last_used_at
+		FROM api_keys
+		WHERE project_id = ? AND revoked_at IS NULL
+		ORDER BY created_at, id
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]APIKeyMeta, 0)
+	for rows.Next() {
+		var k APIKeyMeta
+		if err := rows.Scan(&k.ID, &k.ProjectID, &k.Scope, sqliteTime{&k.CreatedAt}, sqliteNullTime{&k.LastUsedAt}); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes an API key. Returns pgx.ErrNoRows (wrapped) if the
+// key does not exist or is already revoked.
+func (r *SQLiteRepository) DeleteAPIKey(ctx context.Context, projectID, keyID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND project_id = ? AND revoked_at IS NULL
+	`, formatSQLiteTime(time.Now().UTC()), keyID, projectID)
+	if err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+	return sqliteNoRows(result, "delete api key")
+}
+
+// PublishFlagEvent stores a flag event and returns it with its ID and
+// creation time.
+func (r *SQLiteRepository) PublishFlagEvent(ctx context.Context, event FlagEvent) (FlagEvent, error) {
+	created := event
+	created.Payload = ensureJSON(event.Payload, "{}")
+	created.CreatedAt = time.Now().UTC()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO flag_events (project_id, flag_key, event_type, payload, created_at, actor)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, created.ProjectID, created.FlagKey, created.EventType, string(created.Payload), formatSQLiteTime(created.CreatedAt), created.Actor)
+	if err != nil {
+		return FlagEvent{}, fmt.Errorf("insert flag event: %w", err)
+	}
+	if created.EventID, err = result.LastInsertId(); err != nil {
+		return FlagEvent{}, fmt.Errorf("insert flag event: %w", err)
+	}
+
+	return created, nil
+}
+
+// ListEventsSince returns up to the configured event batch size flag events
+// of a project with IDs greater than eventID, ordered by event ID.
+func (r *SQLiteRepository) ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]FlagEvent, error) {
+	return r.listEvents(ctx, `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > ? AND project_id = ?
+		ORDER BY event_id
+		LIMIT ?
+	`, eventID, projectID, r.eventBatchSize)
+}
+
+// ListEventsSinceForKey is [SQLiteRepository.ListEventsSince] restricted to
+// the events of one flag key.
+func (r *SQLiteRepository) ListEventsSinceForKey(ctx context.Context, projectID string, eventID int64, key string) ([]FlagEvent, error) {
+	return r.listEvents(ctx, `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > ? AND project_id = ? AND flag_key = ?
+		ORDER BY event_id
+		LIMIT ?
+	`, eventID, projectID, key, r.eventBatchSize)
+}
+
+// ListEventsSinceMatching is [SQLiteRepository.ListEventsSince] restricted to
+// the events that pass filter.
+func (r *SQLiteRepository) ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter EventFilter) ([]FlagEvent, error) {
+	query := `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > ? AND project_id = ?`
+	args := []any{eventID, projectID}
+	for _, in := range []struct {
+		column string
+		values []string
+	}{{"flag_key", filter.Keys}, {"event_type", filter.Types}} {
+		if len(in.values) == 0 {
+			continue
+		}
+		query += " AND " + in.column + " IN (?" + strings.Repeat(", ?", len(in.values)-1) + ")"
+		for _, value := range in.values {
+			args = append(args, value)
+		}
+	}
+	query += `
+		ORDER BY event_id
+		LIMIT ?`
+	return r.listEvents(ctx, query, append(args, r.eventBatchSize)...)
+}
+
+// ListAllEventsSince returns up to the configured event batch size flag
+// events of every project with IDs greater than eventID, ordered by event ID.
+func (r *SQLiteRepository) ListAllEventsSince(ctx context.Context, eventID int64) ([]FlagEvent, error) {
+	return r.listEvents(ctx, `
+		SELECT event_id, project_id, flag_key, event_type, payload, created_at
+		FROM flag_events
+		WHERE event_id > ?
+		ORDER BY event_id
+		LIMIT ?
+	`, eventID, r.eventBatchSize)
+}
+
+func (r *SQLiteRepository) listEvents(ctx context.Context, query string, args ...any) ([]FlagEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]FlagEvent, 0)
+	for rows.Next() {
+		var event FlagEvent
+		if err := rows.Scan(
+			&event.EventID,
+			&event.ProjectID,
+			&event.FlagKey,
+			&event.EventType,
+			(*[]byte)(&event.Payload),
+			sqliteTime{&event.CreatedAt},
+		); err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list events rows: %w", err)
+	}
+
+	return events, nil
+}
+
+// LatestEventID returns the ID of the newest flag event in a project, or 0
+// if the project has none.
+func (r *SQLiteRepository) LatestEventID(ctx context.Context, projectID string) (int64, error) {
+	var eventID int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(event_id), 0) FROM flag_events WHERE project_id = ?`, projectID).Scan(&eventID); err != nil {
+		return 0, fmt.Errorf("latest event id: %w", err)
+	}
+	return eventID, nil
+}
+
+// MaxEventID returns the ID of the newest flag event in any project, or 0 if
+// there are none.
+func (r *SQLiteRepository) MaxEventID(ctx context.Context) (int64, error) {
+	var eventID int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(event_id), 0) FROM flag_events`).Scan(&eventID); err != nil {
+		return 0, fmt.Errorf("max event id: %w", err)
+	}
+	return eventID, nil
+}
+
+// InsertAuditLog records an audit log entry.
+func (r *SQLiteRepository) InsertAuditLog(ctx context.Context, entry AuditLogEntry) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_log (project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.ProjectID, entry.APIKeyID, entry.AdminUserID, entry.Action, entry.FlagKey, sqliteNullableJSON(entry.Details), entry.RequestID, formatSQLiteTime(time.Now().UTC())); err != nil {
+		return fmt.Errorf("insert audit log: %w", err)
+	}
+	return nil
+}
+
+// ListAuditLog returns audit log entries for a project, newest first.
+func (r *SQLiteRepository) ListAuditLog(ctx context.Context, projectID string, limit, offset int) ([]AuditLogEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, project_id, api_key_id, admin_user_id, action, flag_key, details, request_id, created_at
+		FROM audit_log
+		WHERE project_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, projectID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditLogEntry
+	for rows.Next() {
+		var e AuditLogEntry
+		if err := rows.Scan(&e.ID, &e.ProjectID, &e.APIKeyID, &e.AdminUserID, &e.Action, &e.FlagKey, (*[]byte)(&e.Details), &e.RequestID, sqliteTime{&e.CreatedAt}); err != nil {
+			return nil, fmt.Errorf("scanning audit log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating audit log rows: %w", err)
+	}
+	return entries, nil
+}
+
+// ClaimIdempotencyKey reserves key for the API key apiKeyID, or returns the
+// live record already holding it.
+func (r *SQLiteRepository) ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string, now, expiredBefore time.Time) (IdempotencyRecord, bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (api_key_id, key, fingerprint, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (api_key_id, key) DO UPDATE
+		SET fingerprint = excluded.fingerprint,
+		    status_code = NULL,
+		    content_type = '',
+		    body = NULL,
+		    created_at = excluded.created_at
+		WHERE julianday(idempotency_keys.created_at) < julianday(?)
+	`, apiKeyID, key, fingerprint, formatSQLiteTime(now), formatSQLiteTime(expiredBefore))
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key: %w", err)
+	} else if affected > 0 {
+		return IdempotencyRecord{Fingerprint: fingerprint}, true, nil
+	}
+
+	var (
+		record     IdempotencyRecord
+		statusCode sql.NullInt64
+	)
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, content_type, body
+		FROM idempotency_keys
+		WHERE api_key_id = ? AND key = ?
+	`, apiKeyID, key).Scan(&record.Fingerprint, &statusCode, &record.ContentType, &record.Body); err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("get idempotency key: %w", sqliteErr(err))
+	}
+	record.StatusCode = int(statusCode.Int64)
+	return record, false, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request that claimed key.
+func (r *SQLiteRepository) CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record IdempotencyRecord) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, body = ?
+		WHERE api_key_id = ? AND key = ?
+	`, record.StatusCode, record.ContentType, record.Body, apiKeyID, key); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes an in-progress claim on key.
+func (r *SQLiteRepository) ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE api_key_id = ? AND key = ? AND status_code IS NULL
+	`, apiKeyID, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys removes records created before createdBefore.
+func (r *SQLiteRepository) PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE julianday(created_at) < julianday(?)
+	`, formatSQLiteTime(createdBefore))
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return purged, nil
+}
+
+func scanSQLiteFlag(row interface{ Scan(...any) error }) (Flag, error) {
+	var flag Flag
+	err := row.Scan(
+		&flag.ProjectID,
+		&flag.Key,
+		&flag.Description,
+		&flag.Enabled,
+		(*[]byte)(&flag.Variants),
+		(*[]byte)(&flag.Rules),
+		&flag.BucketingSalt,
+		(*[]byte)(&flag.VariantsSchema),
+		(*[]byte)(&flag.ShadowRules),
+		sqliteTags{&flag.Tags},
+		&flag.Owner,
+		sqliteNullTime{&flag.ExpiresAt},
+		sqliteTime{&flag.CreatedAt},
+		sqliteTime{&flag.UpdatedAt},
+		&flag.CreatedBy,
+		&flag.UpdatedBy,
+	)
+	return flag, err
+}
+
+// sqliteErr translates sql.ErrNoRows to pgx.ErrNoRows, the error the service
+// layer checks for.
+func sqliteErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+	return err
+}
+
+// sqliteConn is the part of a *sql.DB or *sql.Tx that flag writes use.
+type sqliteConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func sqliteNoRows(result sql.Result, op string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// sqliteNullableJSON stores empty JSON as NULL and anything else as text.
+func sqliteNullableJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// sqliteTagsJSON stores tags as a JSON array, never NULL.
+func sqliteTagsJSON(tags []string) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	encoded, _ := json.Marshal(tags)
+	return string(encoded)
+}
+
+// sqliteNullableTags is [sqliteTagsJSON] for UpdateFlag, where nil tags are
+// NULL so the current ones are kept.
+func sqliteNullableTags(tags []string) any {
+	if tags == nil {
+		return nil
+	}
+	return sqliteTagsJSON(tags)
+}
+
+// sqliteTags scans tags stored by sqliteTagsJSON, leaving none as nil like
+// the PostgreSQL repository's empty array.
+type sqliteTags struct{ tags *[]string }
+
+func (s sqliteTags) Scan(src any) error {
+	var text []byte
+	switch v := src.(type) {
+	case string:
+		text = []byte(v)
+	case []byte:
+		text = v
+	default:
+		return fmt.Errorf("scan tags: unsupported type %T", src)
+	}
+	var tags []string
+	if err := json.Unmarshal(text, &tags); err != nil {
+		return fmt.Errorf("scan tags: %w", err)
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	*s.tags = tags
+	return nil
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// sqliteNullableTime stores a nil time as NULL.
+func sqliteNullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatSQLiteTime(*t)
+}
+
+// sqliteNullTime scans a timestamp stored by sqliteNullableTime.
+type sqliteNullTime struct{ t **time.Time }
+
+func (s sqliteNullTime) Scan(src any) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (sqliteTime{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.t = &t
+	return nil
+}
+
+// sqliteTime scans a timestamp stored by formatSQLiteTime.
+type sqliteTime struct{ t *time.Time }
+
+func (s sqliteTime) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case time.Time:
+		*s.t = v.UTC()
+		return nil
+	default:
+		return fmt.Errorf("scan timestamp: unsupported type %T", src)
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return fmt.Errorf("scan timestamp: %w", err)
+	}
+	*s.t = parsed
+	return nil
+}
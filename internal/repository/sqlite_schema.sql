@@ -0,0 +1,91 @@
+-- Schema of the SQLite backend. Every statement is idempotent; it is applied
+-- each time the database is opened. Timestamps are RFC 3339 text in UTC and
+-- JSON columns are text.
+
+CREATE TABLE IF NOT EXISTS projects (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    deleted_at TEXT
+);
+
+INSERT OR IGNORE INTO projects (id, name, description, created_at, updated_at) VALUES
+    ('11111111-1111-1111-1111-111111111111', 'Default', 'Auto-created default project',
+     strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), strftime('%Y-%m-%dT%H:%M:%fZ', 'now'));
+
+CREATE TABLE IF NOT EXISTS flags (
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    enabled INTEGER NOT NULL DEFAULT 0,
+    variants TEXT NOT NULL DEFAULT '{}',
+    rules TEXT NOT NULL DEFAULT '[]',
+    bucketing_salt TEXT NOT NULL,
+    variants_schema TEXT,
+    shadow_rules TEXT,
+    tags TEXT NOT NULL DEFAULT '[]',
+    owner TEXT NOT NULL DEFAULT '',
+    expires_at TEXT,
+    expiry_notified_at TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    created_by TEXT NOT NULL DEFAULT '',
+    updated_by TEXT NOT NULL DEFAULT '',
+    archived_at TEXT,
+    PRIMARY KEY (project_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'project' CHECK (scope IN ('project', 'admin')),
+    created_at TEXT NOT NULL,
+    revoked_at TEXT,
+    last_used_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_project_id ON api_keys (project_id);
+
+CREATE TABLE IF NOT EXISTS flag_events (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    flag_key TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '{}',
+    created_at TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_flag_events_project_event ON flag_events (project_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_flag_events_project_key_event ON flag_events (project_id, flag_key, event_id);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    api_key_id TEXT NOT NULL DEFAULT '',
+    admin_user_id TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    flag_key TEXT NOT NULL DEFAULT '',
+    details TEXT,
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_project ON audit_log (project_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    api_key_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL DEFAULT '',
+    status_code INTEGER,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BLOB,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (api_key_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys (created_at);
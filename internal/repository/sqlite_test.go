@@ -0,0 +1,540 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+const sqliteTestProject = "11111111-1111-1111-1111-111111111111"
+
+func openTestSQLite(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	repo, err := OpenSQLite(context.Background(), "sqlite:"+filepath.Join(t.TempDir(), "flagz.db"), 2)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSQLiteRepositoryFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	created, err := repo.CreateFlag(ctx, Flag{
+		ProjectID:      sqliteTestProject,
+		Key:            "checkout",
+		Description:    "new checkout",
+		Enabled:        true,
+		Variants:       json.RawMessage(`{"default":true}`),
+		VariantsSchema: json.RawMessage(`{"type":"boolean"}`),
+		ShadowRules:    json.RawMessage(`[]`),
+		Tags:           []string{"checkout", "team:payments"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if len(created.BucketingSalt) != 32 || string(created.Rules) != "[]" || created.CreatedAt.IsZero() {
+		t.Fatalf("CreateFlag() = %+v, want a salt, empty rules and timestamps", created)
+	}
+
+	got, err := repo.GetFlag(ctx, sqliteTestProject, "checkout")
+	if err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if !got.Enabled || got.Description != "new checkout" || string(got.Variants) != `{"default":true}` ||
+		string(got.VariantsSchema) != `{"type":"boolean"}` || string(got.ShadowRules) != "[]" || !slices.Equal(got.Tags, []string{"checkout", "team:payments"}) ||
+		got.BucketingSalt != created.BucketingSalt ||
+		!got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("GetFlag() = %+v, want %+v", got, created)
+	}
+
+	got.Enabled = false
+	got.Rules = json.RawMessage(`[{"attribute":"country","operator":"equals","value":"GB"}]`)
+	got.VariantsSchema = nil
+	got.ShadowRules = nil
+	got.BucketingSalt = "from-the-client"
+	updated, err := repo.UpdateFlag(ctx, got)
+	if err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if updated.Enabled || string(updated.Rules) != string(got.Rules) || updated.VariantsSchema != nil || updated.ShadowRules != nil || updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Fatalf("UpdateFlag() = %+v", updated)
+	}
+	// Only a reshuffle changes the salt, so edits keep users in their buckets.
+	if updated.BucketingSalt != created.BucketingSalt {
+		t.Fatalf("UpdateFlag() salt = %q, want %q kept", updated.BucketingSalt, created.BucketingSalt)
+	}
+
+	// Nil tags keep the current ones; an empty slice removes them.
+	got.Tags = nil
+	if updated, err = repo.UpdateFlag(ctx, got); err != nil || len(updated.Tags) != 2 {
+		t.Fatalf("UpdateFlag(nil tags) = %v, %v; want the tags kept", updated.Tags, err)
+	}
+	got.Tags = []string{}
+	if updated, err = repo.UpdateFlag(ctx, got); err != nil || updated.Tags != nil {
+		t.Fatalf("UpdateFlag(no tags) = %v, %v; want none", updated.Tags, err)
+	}
+
+	if _, err := repo.UpdateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "missing"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("UpdateFlag(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := repo.GetFlag(ctx, sqliteTestProject, "missing"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlag(missing) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "banner"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "banner"}); err == nil {
+		t.Fatal("CreateFlag() of an existing key should fail")
+	}
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: "unknown-project", Key: "banner"}); err == nil {
+		t.Fatal("CreateFlag() in an unknown project should fail")
+	}
+	flags, err := repo.ListFlags(ctx)
+	if err != nil {
+		t.Fatalf("ListFlags() error = %v", err)
+	}
+	if len(flags) != 2 || flags[0].Key != "banner" || flags[1].Key != "checkout" {
+		t.Fatalf("ListFlags() = %+v, want banner and checkout", flags)
+	}
+
+	if _, err := repo.CreateFlags(ctx, []Flag{
+		{ProjectID: sqliteTestProject, Key: "footer"},
+		{ProjectID: sqliteTestProject, Key: "checkout"},
+	}); err == nil || !strings.Contains(err.Error(), `"checkout"`) {
+		t.Fatalf("CreateFlags() with a taken key error = %v, want it to name checkout", err)
+	}
+	if _, err := repo.GetFlag(ctx, sqliteTestProject, "footer"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlag(footer) error = %v; a failed batch must create nothing", err)
+	}
+	batch, err := repo.CreateFlags(ctx, []Flag{{ProjectID: sqliteTestProject, Key: "footer"}})
+	if err != nil || len(batch) != 1 || batch[0].BucketingSalt == "" {
+		t.Fatalf("CreateFlags() = %+v, %v", batch, err)
+	}
+
+	if err := repo.DeleteFlag(ctx, sqliteTestProject, "banner"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
+	}
+	if err := repo.DeleteFlag(ctx, sqliteTestProject, "banner"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("DeleteFlag() twice error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteRepositoryEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	for _, key := range []string{"a", "b", "a"} {
+		if _, err := repo.PublishFlagEvent(ctx, FlagEvent{ProjectID: sqliteTestProject, FlagKey: key, EventType: "updated"}); err != nil {
+			t.Fatalf("PublishFlagEvent() error = %v", err)
+		}
+	}
+
+	// The batch size of 2 caps every listing.
+	events, err := repo.ListEventsSince(ctx, sqliteTestProject, 0)
+	if err != nil {
+		t.Fatalf("ListEventsSince() error = %v", err)
+	}
+	if len(events) != 2 || events[0].EventID != 1 || events[1].EventID != 2 || string(events[0].Payload) != "{}" || events[0].CreatedAt.IsZero() {
+		t.Fatalf("ListEventsSince() = %+v, want events 1 and 2", events)
+	}
+	events, err = repo.ListEventsSinceForKey(ctx, sqliteTestProject, 1, "a")
+	if err != nil {
+		t.Fatalf("ListEventsSinceForKey() error = %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != 3 {
+		t.Fatalf("ListEventsSinceForKey() = %+v, want event 3", events)
+	}
+	events, err = repo.ListEventsSinceMatching(ctx, sqliteTestProject, 0, EventFilter{Keys: []string{"a", "c"}, Types: []string{"updated"}})
+	if err != nil {
+		t.Fatalf("ListEventsSinceMatching() error = %v", err)
+	}
+	if len(events) != 2 || events[0].EventID != 1 || events[1].EventID != 3 {
+		t.Fatalf("ListEventsSinceMatching() = %+v, want events 1 and 3", events)
+	}
+	if events, err = repo.ListEventsSinceMatching(ctx, sqliteTestProject, 0, EventFilter{Types: []string{"deleted"}}); err != nil || len(events) != 0 {
+		t.Fatalf("ListEventsSinceMatching(deleted) = %+v, %v, want none", events, err)
+	}
+	events, err = repo.ListAllEventsSince(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListAllEventsSince() error = %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != 3 {
+		t.Fatalf("ListAllEventsSince() = %+v, want event 3", events)
+	}
+	if id, err := repo.MaxEventID(ctx); err != nil || id != 3 {
+		t.Fatalf("MaxEventID() = %d, %v, want 3", id, err)
+	}
+	if id, err := repo.LatestEventID(ctx, "other"); err != nil || id != 0 {
+		t.Fatalf("LatestEventID(other) = %d, %v, want 0", id, err)
+	}
+}
+
+func TestSQLiteRepositoryQueryEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	for _, event := range []struct{ key, actor string }{
+		{"a", "api_key:k1"}, {"b", "api_key:k1"}, {"a", "admin_user:u1"}, {"a", "api_key:k1"},
+	} {
+		if _, err := repo.PublishFlagEvent(ctx, FlagEvent{ProjectID: sqliteTestProject, FlagKey: event.key, EventType: "updated", Actor: event.actor}); err != nil {
+			t.Fatalf("PublishFlagEvent() error = %v", err)
+		}
+	}
+
+	// Unlike the stream listings, queries are not capped by the batch size.
+	events, err := repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Limit: 10})
+	if err != nil || len(events) != 4 || events[2].Actor != "admin_user:u1" {
+		t.Fatalf("QueryEvents() = %+v, %v, want all 4 events with actors", events, err)
+	}
+	events, err = repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Keys: []string{"a"}, Actor: "api_key:k1", AfterID: 1, Limit: 10})
+	if err != nil || len(events) != 1 || events[0].EventID != 4 {
+		t.Fatalf("QueryEvents(a, api_key:k1, after 1) = %+v, %v, want event 4", events, err)
+	}
+	if events, err = repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Limit: 2}); err != nil || len(events) != 2 {
+		t.Fatalf("QueryEvents(limit 2) = %+v, %v, want 2 events", events, err)
+	}
+	if events, err = repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Types: []string{"deleted"}, Limit: 10}); err != nil || len(events) != 0 {
+		t.Fatalf("QueryEvents(deleted) = %+v, %v, want none", events, err)
+	}
+
+	now := time.Now()
+	if events, err = repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Since: now.Add(-time.Hour), Until: now.Add(time.Hour), Limit: 10}); err != nil || len(events) != 4 {
+		t.Fatalf("QueryEvents(last hour) = %+v, %v, want all 4 events", events, err)
+	}
+	if events, err = repo.QueryEvents(ctx, EventQuery{ProjectID: sqliteTestProject, Since: now.Add(time.Hour), Limit: 10}); err != nil || len(events) != 0 {
+		t.Fatalf("QueryEvents(future) = %+v, %v, want none", events, err)
+	}
+}
+
+func TestSQLiteRepositoryListFlagsPage(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	for _, flag := range []Flag{
+		{Key: "d", Tags: []string{"beta"}},
+		{Key: "a", Tags: []string{"beta", "ops"}},
+		{Key: "c"},
+		{Key: "b", Tags: []string{"ops"}},
+	} {
+		flag.ProjectID = sqliteTestProject
+		if _, err := repo.CreateFlag(ctx, flag); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", flag.Key, err)
+		}
+	}
+
+	keys := func(flags []Flag) []string {
+		out := make([]string, 0, len(flags))
+		for _, flag := range flags {
+			out = append(out, flag.Key)
+		}
+		return out
+	}
+	flags, err := repo.ListFlagsPage(ctx, FlagPageQuery{ProjectID: sqliteTestProject, Limit: 2})
+	if err != nil || !slices.Equal(keys(flags), []string{"a", "b"}) {
+		t.Fatalf("ListFlagsPage(limit 2) = %v, %v, want a and b", keys(flags), err)
+	}
+	flags, err = repo.ListFlagsPage(ctx, FlagPageQuery{ProjectID: sqliteTestProject, AfterKey: "b", Limit: 10})
+	if err != nil || !slices.Equal(keys(flags), []string{"c", "d"}) {
+		t.Fatalf("ListFlagsPage(after b) = %v, %v, want c and d", keys(flags), err)
+	}
+	flags, err = repo.ListFlagsPage(ctx, FlagPageQuery{ProjectID: sqliteTestProject, Tags: []string{"beta", "ops"}, Limit: 10})
+	if err != nil || !slices.Equal(keys(flags), []string{"a"}) {
+		t.Fatalf("ListFlagsPage(beta, ops) = %v, %v, want a", keys(flags), err)
+	}
+	flags, err = repo.ListFlagsPage(ctx, FlagPageQuery{ProjectID: "other", Limit: 10})
+	if err != nil || len(flags) != 0 {
+		t.Fatalf("ListFlagsPage(other project) = %v, %v, want none", keys(flags), err)
+	}
+}
+
+func TestSQLiteRepositoryBulkUpdateFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: key}); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", key, err)
+		}
+	}
+
+	// A missing flag rolls back the whole batch.
+	_, err := repo.BulkUpdateFlags(ctx, sqliteTestProject, []Flag{{Key: "a", Enabled: true}}, []string{"missing"})
+	if !errors.Is(err, pgx.ErrNoRows) || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("BulkUpdateFlags(missing) error = %v, want no rows naming the key", err)
+	}
+	if flag, err := repo.GetFlag(ctx, sqliteTestProject, "a"); err != nil || flag.Enabled {
+		t.Fatalf("GetFlag(a) = %+v, %v, want it left disabled", flag, err)
+	}
+
+	updated, err := repo.BulkUpdateFlags(ctx, sqliteTestProject, []Flag{
+		{Key: "a", Enabled: true, Tags: []string{"beta"}, UpdatedBy: "admin:1"},
+		{Key: "b", Enabled: true},
+	}, []string{"c"})
+	if err != nil {
+		t.Fatalf("BulkUpdateFlags() error = %v", err)
+	}
+	if len(updated) != 2 || updated[0].Key != "a" || !updated[0].Enabled || !slices.Equal(updated[0].Tags, []string{"beta"}) || updated[0].UpdatedBy != "admin:1" {
+		t.Fatalf("BulkUpdateFlags() = %+v, want a and b enabled, a tagged beta", updated)
+	}
+	if _, err := repo.GetFlag(ctx, sqliteTestProject, "c"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetFlag(c) error = %v, want it archived", err)
+	}
+	if flags, err := repo.ListFlags(ctx); err != nil || len(flags) != 2 {
+		t.Fatalf("ListFlags() = %+v, %v, want the archived flag left out", flags, err)
+	}
+	archived, err := repo.ListArchivedFlags(ctx, sqliteTestProject)
+	if err != nil || len(archived) != 1 || archived[0].Key != "c" || archived[0].ArchivedAt == nil {
+		t.Fatalf("ListArchivedFlags() = %+v, %v, want c", archived, err)
+	}
+
+	// Restoring a flag that is not archived rolls back the whole batch.
+	if _, err := repo.RestoreFlags(ctx, sqliteTestProject, []string{"c", "a"}); !errors.Is(err, pgx.ErrNoRows) || !strings.Contains(err.Error(), `"a"`) {
+		t.Fatalf("RestoreFlags(a) error = %v, want no rows naming the key", err)
+	}
+	restored, err := repo.RestoreFlags(ctx, sqliteTestProject, []string{"c"})
+	if err != nil || len(restored) != 1 || restored[0].Key != "c" {
+		t.Fatalf("RestoreFlags(c) = %+v, %v, want c", restored, err)
+	}
+	if _, err := repo.GetFlag(ctx, sqliteTestProject, "c"); err != nil {
+		t.Fatalf("GetFlag(c) error = %v, want it restored", err)
+	}
+}
+
+func TestSQLiteRepositoryAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	keyID, secret, err := repo.CreateAPIKey(ctx, sqliteTestProject)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	hash, projectID, err := repo.ValidateAPIKey(ctx, keyID)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+	if projectID != sqliteTestProject || !middleware.APIKeyMatchesHash(hash, secret) {
+		t.Fatalf("ValidateAPIKey() = %q, %q; want the key's hash and project", hash, projectID)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, sqliteTestProject)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != keyID || keys[0].Scope != APIKeyScopeProject || keys[0].CreatedAt.IsZero() || keys[0].LastUsedAt != nil {
+		t.Fatalf("ListAPIKeys() = %+v", keys)
+	}
+
+	usedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.TouchAPIKey(ctx, keyID, usedAt); err != nil {
+		t.Fatalf("TouchAPIKey() error = %v", err)
+	}
+	keys, err = repo.ListAPIKeys(ctx, sqliteTestProject)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(usedAt) {
+		t.Fatalf("LastUsedAt = %v, want %v", keys[0].LastUsedAt, usedAt)
+	}
+
+	if err := repo.DeleteAPIKey(ctx, sqliteTestProject, keyID); err != nil {
+		t.Fatalf("DeleteAPIKey() error = %v", err)
+	}
+	if err := repo.DeleteAPIKey(ctx, sqliteTestProject, keyID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("DeleteAPIKey() twice error = %v, want pgx.ErrNoRows", err)
+	}
+	if _, _, err := repo.ValidateAPIKey(ctx, keyID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("ValidateAPIKey(revoked) error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteRepositoryAuditLog(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+
+	for _, entry := range []AuditLogEntry{
+		{ProjectID: sqliteTestProject, APIKeyID: "key", Action: "create", FlagKey: "a", RequestID: "req-1"},
+		{ProjectID: sqliteTestProject, Action: "update", FlagKey: "a", Details: json.RawMessage(`{"enabled":true}`)},
+	} {
+		if err := repo.InsertAuditLog(ctx, entry); err != nil {
+			t.Fatalf("InsertAuditLog() error = %v", err)
+		}
+	}
+
+	entries, err := repo.ListAuditLog(ctx, sqliteTestProject, 10, 0)
+	if err != nil {
+		t.Fatalf("ListAuditLog() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "update" || string(entries[0].Details) != `{"enabled":true}` ||
+		entries[1].APIKeyID != "key" || entries[1].Details != nil || entries[1].RequestID != "req-1" {
+		t.Fatalf("ListAuditLog() = %+v, want the update then the create", entries)
+	}
+}
+
+func TestSQLiteRepositoryIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-24 * time.Hour)
+
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, "key", "k1", "fp-1", now, cutoff); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey() claimed = %v, error = %v, want claimed", claimed, err)
+	}
+	record, claimed, err := repo.ClaimIdempotencyKey(ctx, "key", "k1", "fp-2", now, cutoff)
+	if err != nil || claimed || record.Fingerprint != "fp-1" || record.StatusCode != 0 {
+		t.Fatalf("ClaimIdempotencyKey(held) = %+v, %v, %v, want the in-progress record", record, claimed, err)
+	}
+
+	if err := repo.CompleteIdempotencyKey(ctx, "key", "k1", IdempotencyRecord{StatusCode: 201, ContentType: "application/json", Body: []byte(`{"key":"a"}`)}); err != nil {
+		t.Fatalf("CompleteIdempotencyKey() error = %v", err)
+	}
+	// Releasing only drops claims still in progress.
+	if err := repo.ReleaseIdempotencyKey(ctx, "key", "k1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey() error = %v", err)
+	}
+	record, claimed, err = repo.ClaimIdempotencyKey(ctx, "key", "k1", "fp-1", now, cutoff)
+	if err != nil || claimed || record.StatusCode != 201 || record.ContentType != "application/json" || string(record.Body) != `{"key":"a"}` {
+		t.Fatalf("ClaimIdempotencyKey(completed) = %+v, %v, %v, want the stored response", record, claimed, err)
+	}
+
+	// The same key from another API key is independent.
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, "other", "k1", "fp-1", now, cutoff); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey(other api key) claimed = %v, error = %v, want claimed", claimed, err)
+	}
+	if err := repo.ReleaseIdempotencyKey(ctx, "other", "k1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey() error = %v", err)
+	}
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, "other", "k1", "fp-1", now, cutoff); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey(released) claimed = %v, error = %v, want claimed", claimed, err)
+	}
+
+	// Once expired, a record is taken over by the next claim.
+	later := now.Add(25 * time.Hour)
+	if _, claimed, err := repo.ClaimIdempotencyKey(ctx, "key", "k1", "fp-3", later, later.Add(-24*time.Hour)); err != nil || !claimed {
+		t.Fatalf("ClaimIdempotencyKey(expired) claimed = %v, error = %v, want claimed", claimed, err)
+	}
+
+	purged, err := repo.PurgeIdempotencyKeys(ctx, later.Add(-24*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeIdempotencyKeys() = %d, %v, want 1 (the other API key's record)", purged, err)
+	}
+}
+
+func TestOpenSQLiteKeepsData(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "flagz.db")
+
+	repo, err := OpenSQLite(ctx, "sqlite://"+path, 0)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "kept"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	repo.Close()
+
+	// Reopening reapplies the schema without touching existing rows.
+	repo, err = OpenSQLite(ctx, "file:"+path, 0)
+	if err != nil {
+		t.Fatalf("OpenSQLite() again error = %v", err)
+	}
+	defer repo.Close()
+	if _, err := repo.GetFlag(ctx, sqliteTestProject, "kept"); err != nil {
+		t.Fatalf("GetFlag() after reopening error = %v", err)
+	}
+
+	if _, err := OpenSQLite(ctx, "sqlite:", 0); err == nil {
+		t.Fatal("OpenSQLite() without a path should fail")
+	}
+}
+
+func TestSQLiteRepositoryClaimExpiredFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t)
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	created, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "spring-sale", Owner: "growth@example.com", ExpiresAt: &past})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if created.Owner != "growth@example.com" || created.ExpiresAt == nil || !created.ExpiresAt.Equal(past) {
+		t.Fatalf("CreateFlag() = %+v, want owner and expiry", created)
+	}
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "summer-sale", ExpiresAt: &future}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+
+	claimed, err := repo.ClaimExpiredFlags(ctx, now)
+	if err != nil {
+		t.Fatalf("ClaimExpiredFlags() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Key != "spring-sale" || claimed[0].Owner != "growth@example.com" {
+		t.Fatalf("ClaimExpiredFlags() = %+v, want only spring-sale", claimed)
+	}
+	if claimed, err := repo.ClaimExpiredFlags(ctx, now); err != nil || len(claimed) != 0 {
+		t.Fatalf("second ClaimExpiredFlags() = %+v, %v; want nothing", claimed, err)
+	}
+
+	// Updating a flag without moving its expiry does not re-arm it; moving
+	// the expiry does.
+	created.Description = "spring sale banner"
+	if _, err := repo.UpdateFlag(ctx, created); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if claimed, err := repo.ClaimExpiredFlags(ctx, now); err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimExpiredFlags() after description change = %+v, %v; want nothing", claimed, err)
+	}
+	extended := past.Add(time.Minute)
+	created.ExpiresAt = &extended
+	if _, err := repo.UpdateFlag(ctx, created); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if claimed, err := repo.ClaimExpiredFlags(ctx, now); err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimExpiredFlags() after moving the expiry = %+v, %v; want spring-sale again", claimed, err)
+	}
+}
+
+func TestOpenSQLiteAddsColumns(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "flagz.db")
+
+	// A flags table from before the tags column existed.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE flags (
+		project_id TEXT NOT NULL, key TEXT NOT NULL, description TEXT NOT NULL DEFAULT '',
+		enabled INTEGER NOT NULL DEFAULT 0, variants TEXT NOT NULL DEFAULT '{}', rules TEXT NOT NULL DEFAULT '[]',
+		bucketing_salt TEXT NOT NULL, variants_schema TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL,
+		PRIMARY KEY (project_id, key))`); err != nil {
+		t.Fatalf("create old flags table: %v", err)
+	}
+	db.Close()
+
+	repo, err := OpenSQLite(ctx, path, 0)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer repo.Close()
+	if _, err := repo.CreateFlag(ctx, Flag{ProjectID: sqliteTestProject, Key: "tagged", Tags: []string{"checkout"}}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if flag, err := repo.GetFlag(ctx, sqliteTestProject, "tagged"); err != nil || !slices.Equal(flag.Tags, []string{"checkout"}) {
+		t.Fatalf("GetFlag() = %v, %v; want the checkout tag", flag.Tags, err)
+	}
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// encryptedVariantsKey is the only key of the JSON object a flag's variants
+// are stored as once encrypted.
+const encryptedVariantsKey = "$encrypted"
+
+// ErrVariantsEncrypted is returned when a flag's variants are encrypted but
+// the repository has no [KeyProvider] to decrypt them with.
+var ErrVariantsEncrypted = errors.New("flag variants are encrypted but no key provider is configured")
+
+// KeyProvider wraps and unwraps the data keys that encrypt flag variants at
+// rest, in the manner of a KMS: each flag's variants are encrypted with a
+// fresh data key, and only the wrapped data key is stored beside them.
+// UnwrapKey is called for every encrypted flag read, so a provider backed by
+// a remote KMS should cache the keys it unwraps.
+type KeyProvider interface {
+	// WrapKey encrypts dataKey for projectID with the current
+	// key-encryption key and returns that key's ID with the result.
+	WrapKey(ctx context.Context, projectID string, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key WrapKey wrapped for projectID.
+	UnwrapKey(ctx context.Context, projectID, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// WithVariantsEncryption encrypts the variants of every flag written from
+// then on with AES-256-GCM under data keys wrapped by keys. Reads decrypt
+// them again, so callers only ever see plaintext. Variants written without
+// encryption are read as they are and encrypted the next time the flag is
+// written.
+func WithVariantsEncryption(keys KeyProvider) RepoOption {
+	return func(r *PostgresRepository) {
+		r.variantKeys = keys
+	}
+}
+
+// variantsEnvelope is an encrypted variants value as stored.
+type variantsEnvelope struct {
+	KeyID      string `json:"kid"`
+	WrappedKey []byte `json:"key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"data"`
+}
+
+// variantsAAD binds encrypted variants to the flag they belong to, so they
+// cannot be copied to another flag's row.
+func variantsAAD(projectID, key string) []byte {
+	return []byte(projectID + "\x00" + key)
+}
+
+// sealVariants returns the variants of the flag projectID/key as they are to
+// be stored: encrypted when the repository has a [KeyProvider], and as they
+// are otherwise.
+func (r *PostgresRepository) sealVariants(ctx context.Context, projectID, key string, variants json.RawMessage) (json.RawMessage, error) {
+	variants = ensureJSON(variants, "{}")
+	if r.variantKeys == nil {
+		return variants, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("generate variants data key: %w", err)
+	}
+	keyID, wrapped, err := r.variantKeys.WrapKey(ctx, projectID, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("wrap variants data key: %w", err)
+	}
+	nonce, ciphertext, err := gcmSeal(dataKey, variants, variantsAAD(projectID, key))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt variants: %w", err)
+	}
+	return json.Marshal(map[string]variantsEnvelope{encryptedVariantsKey: {
+		KeyID:      keyID,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}})
+}
+
+// openFlag decrypts flag's variants in place if they are encrypted.
+func (r *PostgresRepository) openFlag(ctx context.Context, flag *Flag) error {
+	envelope, ok := parseVariantsEnvelope(flag.Variants)
+	if !ok {
+		return nil
+	}
+	if r.variantKeys == nil {
+		return fmt.Errorf("flag %q: %w", flag.Key, ErrVariantsEncrypted)
+	}
+	dataKey, err := r.variantKeys.UnwrapKey(ctx, flag.ProjectID, envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		return fmt.Errorf("unwrap variants data key of flag %q: %w", flag.Key, err)
+	}
+	plaintext, err := gcmOpen(dataKey, envelope.Nonce, envelope.Ciphertext, variantsAAD(flag.ProjectID, flag.Key))
+	if err != nil {
+		return fmt.Errorf("decrypt variants of flag %q: %w", flag.Key, err)
+	}
+	flag.Variants = plaintext
+	return nil
+}
+
+// openFlags decrypts the variants of each of flags in place.
+func (r *PostgresRepository) openFlags(ctx context.Context, flags []Flag) error {
+	for i := range flags {
+		if err := r.openFlag(ctx, &flags[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealFlagBody returns body, a flag encoded as JSON, as it is to be stored:
+// with its variants sealed as [sealVariants] seals the flag's own. Flag
+// events and proposals carry such bodies.
+func (r *PostgresRepository) sealFlagBody(ctx context.Context, projectID, key string, body json.RawMessage) (json.RawMessage, error) {
+	if r.variantKeys == nil || len(body) == 0 {
+		return body, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("decode flag body: %w", err)
+	}
+	variants, ok := fields["variants"]
+	if !ok {
+		return body, nil
+	}
+	sealed, err := r.sealVariants(ctx, projectID, key, variants)
+	if err != nil {
+		return nil, err
+	}
+	fields["variants"] = sealed
+	return json.Marshal(fields)
+}
+
+// openFlagBody decrypts the variants of body, a flag encoded as JSON, if
+// they are encrypted.
+func (r *PostgresRepository) openFlagBody(ctx context.Context, projectID, key string, body json.RawMessage) (json.RawMessage, error) {
+	if !bytes.Contains(body, []byte(encryptedVariantsKey)) {
+		return body, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, nil
+	}
+	flag := Flag{ProjectID: projectID, Key: key, Variants: fields["variants"]}
+	if _, ok := parseVariantsEnvelope(flag.Variants); !ok {
+		return body, nil
+	}
+	if err := r.openFlag(ctx, &flag); err != nil {
+		return nil, err
+	}
+	fields["variants"] = flag.Variants
+	return json.Marshal(fields)
+}
+
+// openEvents decrypts the variants in the payload of each of events in
+// place if they are encrypted.
+func (r *PostgresRepository) openEvents(ctx context.Context, events []FlagEvent) error {
+	for i := range events {
+		payload, err := r.openFlagBody(ctx, events[i].ProjectID, events[i].FlagKey, events[i].Payload)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", events[i].EventID, err)
+		}
+		events[i].Payload = payload
+	}
+	return nil
+}
+
+// openProposals decrypts the variants of the flag body of each of
+// proposals in place if they are encrypted.
+func (r *PostgresRepository) openProposals(ctx context.Context, proposals []FlagProposal) error {
+	for i := range proposals {
+		flag, err := r.openFlagBody(ctx, proposals[i].ProjectID, proposals[i].FlagKey, proposals[i].Flag)
+		if err != nil {
+			return fmt.Errorf("proposal %s: %w", proposals[i].ID, err)
+		}
+		proposals[i].Flag = flag
+	}
+	return nil
+}
+
+// parseVariantsEnvelope reports whether variants are encrypted and returns
+// the envelope if so.
+func parseVariantsEnvelope(variants json.RawMessage) (variantsEnvelope, bool) {
+	if !bytes.Contains(variants, []byte(encryptedVariantsKey)) {
+		return variantsEnvelope{}, false
+	}
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(variants, &stored); err != nil || len(stored) != 1 {
+		return variantsEnvelope{}, false
+	}
+	raw, ok := stored[encryptedVariantsKey]
+	if !ok {
+		return variantsEnvelope{}, false
+	}
+	var envelope variantsEnvelope
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return variantsEnvelope{}, false
+	}
+	return envelope, true
+}
+
+// gcmSeal encrypts plaintext with AES-GCM under key and a random nonce.
+func gcmSeal(key, plaintext, aad []byte) (nonce, ciphertext []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// gcmOpen decrypts what gcmSeal encrypted.
+func gcmOpen(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LocalKeyProvider is a [KeyProvider] holding its key-encryption keys in
+// memory, typically loaded from a file with [LoadLocalKeyFile]. It wraps
+// data keys with AES-256-GCM, bound to the project they belong to.
+type LocalKeyProvider struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// localKeyFile is the format of the file LoadLocalKeyFile reads.
+type localKeyFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// NewLocalKeyProvider returns a provider that wraps new data keys with
+// keys[primary] and unwraps them with whichever key wrapped them. Each key
+// must be 32 bytes.
+func NewLocalKeyProvider(primary string, keys map[string][]byte) (*LocalKeyProvider, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not one of the keys", primary)
+	}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q is %d bytes, want 32", id, len(key))
+		}
+	}
+	return &LocalKeyProvider{primary: primary, keys: keys}, nil
+}
+
+// LoadLocalKeyFile reads a [LocalKeyProvider] from a JSON file naming the
+// primary key and mapping each key ID to a base64-encoded 32-byte key:
+//
+//	{"primary": "2026-10", "keys": {"2026-10": "…", "2025-04": "…"}}
+//
+// Keys other than the primary only unwrap data keys they wrapped before, so
+// a new key can be made primary while older flags stay readable.
+func LoadLocalKeyFile(path string) (*LocalKeyProvider, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	var file localKeyFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, fmt.Errorf("parse key file: %w", err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	provider, err := NewLocalKeyProvider(file.Primary, keys)
+	if err != nil {
+		return nil, fmt.Errorf("key file: %w", err)
+	}
+	return provider, nil
+}
+
+// WrapKey encrypts dataKey with the primary key.
+func (p *LocalKeyProvider) WrapKey(_ context.Context, projectID string, dataKey []byte) (string, []byte, error) {
+	nonce, ciphertext, err := gcmSeal(p.keys[p.primary], dataKey, []byte(projectID))
+	if err != nil {
+		return "", nil, err
+	}
+	return p.primary, append(nonce, ciphertext...), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by the key keyID.
+func (p *LocalKeyProvider) UnwrapKey(_ context.Context, projectID, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(projectID))
+}
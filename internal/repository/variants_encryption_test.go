@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeyProvider(t *testing.T) *LocalKeyProvider {
+	t.Helper()
+	keys, err := NewLocalKeyProvider("k2", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider() error = %v", err)
+	}
+	return keys
+}
+
+func TestSealAndOpenVariants(t *testing.T) {
+	ctx := context.Background()
+	r := &PostgresRepository{variantKeys: testKeyProvider(t)}
+	variants := json.RawMessage(`{"default":false,"on":{"api_token":"s3cret"}}`)
+
+	sealed, err := r.sealVariants(ctx, "proj-1", "checkout", variants)
+	if err != nil {
+		t.Fatalf("sealVariants() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) || !json.Valid(sealed) {
+		t.Fatalf("sealed variants = %s, want valid JSON without the plaintext", sealed)
+	}
+	envelope, ok := parseVariantsEnvelope(sealed)
+	if !ok || envelope.KeyID != "k2" {
+		t.Fatalf("envelope = %+v, %t, want one wrapped with the primary key", envelope, ok)
+	}
+
+	flag := Flag{ProjectID: "proj-1", Key: "checkout", Variants: sealed}
+	if err := r.openFlag(ctx, &flag); err != nil {
+		t.Fatalf("openFlag() error = %v", err)
+	}
+	if !bytes.Equal(flag.Variants, variants) {
+		t.Fatalf("opened variants = %s, want %s", flag.Variants, variants)
+	}
+
+	moved := Flag{ProjectID: "proj-1", Key: "search", Variants: sealed}
+	if err := r.openFlag(ctx, &moved); err == nil {
+		t.Fatal("openFlag() of variants copied to another flag should fail")
+	}
+	if err := (&PostgresRepository{}).openFlag(ctx, &Flag{Key: "checkout", Variants: sealed}); !errors.Is(err, ErrVariantsEncrypted) {
+		t.Fatalf("openFlag() without keys error = %v, want ErrVariantsEncrypted", err)
+	}
+}
+
+func TestOpenFlagLeavesPlaintextVariants(t *testing.T) {
+	r := &PostgresRepository{variantKeys: testKeyProvider(t)}
+	for _, variants := range []string{`{}`, `{"default":true}`, `{"$encrypted":true,"on":false}`} {
+		flag := Flag{ProjectID: "proj-1", Key: "checkout", Variants: json.RawMessage(variants)}
+		if err := r.openFlag(context.Background(), &flag); err != nil {
+			t.Fatalf("openFlag(%s) error = %v", variants, err)
+		}
+		if string(flag.Variants) != variants {
+			t.Errorf("openFlag(%s) = %s, want it unchanged", variants, flag.Variants)
+		}
+	}
+
+	sealed, err := (&PostgresRepository{}).sealVariants(context.Background(), "proj-1", "checkout", nil)
+	if err != nil || string(sealed) != "{}" {
+		t.Fatalf("sealVariants() without keys = %s, %v, want {}", sealed, err)
+	}
+}
+
+func TestSealAndOpenFlagBody(t *testing.T) {
+	ctx := context.Background()
+	r := &PostgresRepository{variantKeys: testKeyProvider(t)}
+	payload := json.RawMessage(`{"key":"checkout","enabled":true,"variants":{"on":{"api_token":"s3cret"}}}`)
+	event := FlagEvent{EventID: 7, ProjectID: "proj-1", FlagKey: "checkout", EventType: "updated", Payload: payload}
+
+	sealed, err := r.sealFlagBody(ctx, event.ProjectID, event.FlagKey, payload)
+	if err != nil {
+		t.Fatalf("sealFlagBody() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) || !bytes.Contains(sealed, []byte(`"enabled":true`)) {
+		t.Fatalf("sealed payload = %s, want the flag without plaintext variants", sealed)
+	}
+
+	events := []FlagEvent{event, event}
+	events[0].Payload = sealed
+	if err := r.openEvents(ctx, events); err != nil {
+		t.Fatalf("openEvents() error = %v", err)
+	}
+	var opened struct {
+		Enabled  bool            `json:"enabled"`
+		Variants json.RawMessage `json:"variants"`
+	}
+	if err := json.Unmarshal(events[0].Payload, &opened); err != nil {
+		t.Fatalf("decode opened payload: %v", err)
+	}
+	if !opened.Enabled || string(opened.Variants) != `{"on":{"api_token":"s3cret"}}` {
+		t.Fatalf("opened payload = %s, want the variants decrypted", events[0].Payload)
+	}
+	if !bytes.Equal(events[1].Payload, payload) {
+		t.Fatalf("plaintext payload = %s, want it unchanged", events[1].Payload)
+	}
+
+	proposals := []FlagProposal{{ID: "p1", ProjectID: "proj-1", FlagKey: "checkout", Flag: sealed}, {ID: "p2", ProjectID: "proj-1", FlagKey: "checkout"}}
+	if err := r.openProposals(ctx, proposals); err != nil {
+		t.Fatalf("openProposals() error = %v", err)
+	}
+	if !bytes.Contains(proposals[0].Flag, []byte("s3cret")) || proposals[1].Flag != nil {
+		t.Fatalf("opened proposals = %s and %s, want the first decrypted and the second without a flag", proposals[0].Flag, proposals[1].Flag)
+	}
+
+	unsealed, err := (&PostgresRepository{}).sealFlagBody(ctx, event.ProjectID, event.FlagKey, payload)
+	if err != nil || !bytes.Equal(unsealed, payload) {
+		t.Fatalf("sealFlagBody() without keys = %s, %v, want it unchanged", unsealed, err)
+	}
+}
+
+func TestLocalKeyProviderUnwrapsOlderKeys(t *testing.T) {
+	ctx := context.Background()
+	old, err := NewLocalKeyProvider("k1", testKeyProvider(t).keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, wrapped, err := old.WrapKey(ctx, "proj-1", []byte("data key"))
+	if err != nil || keyID != "k1" {
+		t.Fatalf("WrapKey() = %q, %v", keyID, err)
+	}
+
+	rotated := testKeyProvider(t)
+	if got, err := rotated.UnwrapKey(ctx, "proj-1", keyID, wrapped); err != nil || string(got) != "data key" {
+		t.Fatalf("UnwrapKey() = %q, %v, want the data key", got, err)
+	}
+	if _, err := rotated.UnwrapKey(ctx, "proj-2", keyID, wrapped); err == nil {
+		t.Fatal("UnwrapKey() for another project should fail")
+	}
+	if _, err := rotated.UnwrapKey(ctx, "proj-1", "k3", wrapped); err == nil {
+		t.Fatal("UnwrapKey() with an unknown key should fail")
+	}
+}
+
+func TestLoadLocalKeyFile(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	keys, err := LoadLocalKeyFile(write("keys.json", `{"primary":"2026-10","keys":{"2026-10":"`+key+`"}}`))
+	if err != nil {
+		t.Fatalf("LoadLocalKeyFile() error = %v", err)
+	}
+	if keys.primary != "2026-10" || len(keys.keys["2026-10"]) != 32 {
+		t.Fatalf("provider = %+v", keys)
+	}
+
+	for name, body := range map[string]string{
+		"missing-primary.json": `{"primary":"2026-11","keys":{"2026-10":"` + key + `"}}`,
+		"short-key.json":       `{"primary":"a","keys":{"a":"c2hvcnQ="}}`,
+		"bad-base64.json":      `{"primary":"a","keys":{"a":"!!"}}`,
+		"not-json.json":        `primary: a`,
+	} {
+		if _, err := LoadLocalKeyFile(write(name, body)); err == nil || !strings.Contains(err.Error(), "key") {
+			t.Errorf("LoadLocalKeyFile(%s) error = %v, want a key file error", name, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WebhookSigningSecret signs the deliveries of a project's webhook
+// notification channels.
+type WebhookSigningSecret struct {
+	ProjectID string `json:"-"`
+	// Secret is only returned to API clients when it is created.
+	Secret string `json:"secret,omitempty"`
+	// PreviousSecret is the secret a rotation replaced. Deliveries are
+	// signed with it as well until PreviousExpiresAt, so receivers can
+	// switch over without dropping any. It is empty when there is none.
+	PreviousSecret    string     `json:"-"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// GetWebhookSigningSecret returns a project's webhook signing secret.
+// Returns pgx.ErrNoRows (wrapped) if it has none.
+func (r *PostgresRepository) GetWebhookSigningSecret(ctx context.Context, projectID string) (WebhookSigningSecret, error) {
+	s := WebhookSigningSecret{ProjectID: projectID}
+	err := r.pool.QueryRow(ctx, `
+		SELECT secret, previous_secret, previous_expires_at, created_at
+		FROM webhook_signing_secrets
+		WHERE project_id = $1
+	`, projectID).Scan(&s.Secret, &s.PreviousSecret, &s.PreviousExpiresAt, &s.CreatedAt)
+	if err != nil {
+		return WebhookSigningSecret{}, fmt.Errorf("get webhook signing secret: %w", err)
+	}
+	return s, nil
+}
+
+// RotateWebhookSigningSecret makes secret the project's webhook signing
+// secret. The secret it replaces, if any, stays valid for overlap.
+func (r *PostgresRepository) RotateWebhookSigningSecret(ctx context.Context, projectID, secret string, overlap time.Duration) (WebhookSigningSecret, error) {
+	s := WebhookSigningSecret{ProjectID: projectID}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_signing_secrets (project_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (project_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			previous_secret = webhook_signing_secrets.secret,
+			previous_expires_at = NOW() + make_interval(secs => $3::bigint),
+			created_at = NOW()
+		RETURNING secret, previous_secret, previous_expires_at, created_at
+	`, projectID, secret, int64(overlap/time.Second)).Scan(&s.Secret, &s.PreviousSecret, &s.PreviousExpiresAt, &s.CreatedAt)
+	if err != nil {
+		return WebhookSigningSecret{}, fmt.Errorf("rotate webhook signing secret: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/backup"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// authorizeAdminRequest reports whether the request carries an admin-scoped
+// API key, writing the error response if it does not.
+func (s *HTTPServer) authorizeAdminRequest(w http.ResponseWriter, r *http.Request) bool {
+	keyID, ok := middleware.APIKeyIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	if err := s.service.AuthorizeAdminAPIKey(r.Context(), keyID); err != nil {
+		writeServiceError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// handleAdminBackup streams a backup archive of every project to callers
+// holding an admin-scoped API key. Like exports, a failure after the body
+// has started can only be reported by cutting the response short.
+func (s *HTTPServer) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdminRequest(w, r) {
+		return
+	}
+
+	filename := "flagz-backup-" + time.Now().UTC().Format("20060102T150405Z") + ".jsonl.gz"
+	w.Header().Set("Content-Type", backup.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := backup.Write(r.Context(), w, s.service); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// handleAdminRestore restores a backup archive sent as the request body, up
+// to the import body limit, and responds with the IDs its projects and API
+// keys were restored under. ?rename=true restores projects whose names are
+// taken under new names instead of failing.
+func (s *HTTPServer) handleAdminRestore(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdminRequest(w, r) {
+		return
+	}
+
+	var opts repository.RestoreOptions
+	if raw := r.URL.Query().Get("rename"); raw != "" {
+		rename, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "rename must be true or false")
+			return
+		}
+		opts.RenameConflicts = rename
+	}
+
+	reader, err := backup.NewReader(http.MaxBytesReader(w, r.Body, s.maxImportBytes))
+	if err != nil {
+		writeRestoreError(w, r, err)
+		return
+	}
+	result, err := s.service.RestoreBackup(r.Context(), opts, reader.Next)
+	if err != nil {
+		writeRestoreError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func writeRestoreError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+	case errors.Is(err, backup.ErrInvalidArchive):
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+	default:
+		writeServiceError(w, r, err)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/backup"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+func TestHTTPHandlerAdminBackupAndRestore(t *testing.T) {
+	records := []repository.BackupRecord{
+		{Kind: repository.BackupKindProject, Project: &repository.Project{ID: "p1", Name: "Checkout"}},
+		{Kind: repository.BackupKindFlag, ProjectID: "p1", Flag: &repository.Flag{Key: "new-cart", Enabled: true}},
+	}
+	var (
+		restored []repository.BackupRecord
+		gotOpts  repository.RestoreOptions
+	)
+	svc := &fakeService{
+		authorizeAdminAPIKeyFunc: func(_ context.Context, keyID string) error {
+			if keyID != "admin-key" {
+				return service.ErrAdminKeyRequired
+			}
+			return nil
+		},
+		backupFunc: func(_ context.Context, emit func(repository.BackupRecord) error) error {
+			for _, record := range records {
+				if err := emit(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		restoreBackupFunc: func(_ context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error) {
+			gotOpts = opts
+			restored = nil
+			for {
+				record, err := next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return repository.RestoreResult{}, err
+				}
+				restored = append(restored, record)
+			}
+			if !opts.RenameConflicts {
+				return repository.RestoreResult{}, fmt.Errorf("%w: Checkout", service.ErrProjectNameTaken)
+			}
+			return repository.RestoreResult{Projects: map[string]string{"p1": "p2"}, Flags: 1}, nil
+		},
+	}
+	handler := NewHTTPHandler(svc)
+
+	request := func(keyID, method, target string, body []byte) *httptest.ResponseRecorder {
+		ctx := middleware.NewContextWithAPIKeyID(middleware.NewContextWithProjectID(context.Background(), "default"), keyID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, bytes.NewReader(body)).WithContext(ctx))
+		return rec
+	}
+
+	rec := request("admin-key", http.MethodGet, "/v1/admin/backup", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("backup status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != backup.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, backup.ContentType)
+	}
+	archive := rec.Body.Bytes()
+
+	rec = request("admin-key", http.MethodPost, "/v1/admin/restore?rename=true", archive)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("restore status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if !gotOpts.RenameConflicts || len(restored) != 2 || restored[1].Flag.Key != "new-cart" {
+		t.Fatalf("restored %+v with %+v, want both records renaming conflicts", restored, gotOpts)
+	}
+	if want := `"projects":{"p1":"p2"}`; !bytes.Contains(rec.Body.Bytes(), []byte(want)) {
+		t.Errorf("restore body = %s, want it to contain %s", rec.Body.String(), want)
+	}
+
+	tests := []struct {
+		name, keyID, method, target string
+		body                        []byte
+		want                        int
+	}{
+		{"backup with project key", "project-key", http.MethodGet, "/v1/admin/backup", nil, http.StatusForbidden},
+		{"restore with project key", "project-key", http.MethodPost, "/v1/admin/restore", archive, http.StatusForbidden},
+		{"name taken", "admin-key", http.MethodPost, "/v1/admin/restore", archive, http.StatusConflict},
+		{"not an archive", "admin-key", http.MethodPost, "/v1/admin/restore", []byte(`{"flags":[]}`), http.StatusBadRequest},
+		{"invalid rename", "admin-key", http.MethodPost, "/v1/admin/restore?rename=maybe", archive, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(tt.keyID, tt.method, tt.target, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
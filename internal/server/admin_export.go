@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/export"
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// handleAdminExport streams the audit log or flag events of every project as a
+// Parquet file to callers holding an admin-scoped API key. Once the body has
+// started, a failure can only be reported by cutting the response short.
+func (s *HTTPServer) handleAdminExport(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := middleware.APIKeyIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if err := s.service.AuthorizeAdminAPIKey(r.Context(), keyID); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	dataset := r.PathValue("dataset")
+	if dataset != export.DatasetAudit && dataset != export.DatasetEvents {
+		writeJSONError(w, r, http.StatusNotFound, "unknown export dataset")
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" && format != "parquet" {
+		writeJSONError(w, r, http.StatusBadRequest, "unsupported export format; only parquet is available")
+		return
+	}
+	rng, err := export.ParseRange(r.URL.Query().Get("range"), time.Now())
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="flagz-`+dataset+`.parquet"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := export.Write(r.Context(), w, s.service, dataset, rng); err != nil {
+		// The status is already sent; abort so the client sees a truncated
+		// response instead of a corrupt but complete-looking file.
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/matt-riley/flagz/internal/export"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+func TestHTTPHandlerAdminExport(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	svc := &fakeService{
+		authorizeAdminAPIKeyFunc: func(_ context.Context, keyID string) error {
+			if keyID != "admin-key" {
+				return service.ErrAdminKeyRequired
+			}
+			return nil
+		},
+		listAuditLogRangeFunc: func(_ context.Context, afterID int64, from, to time.Time, _ int) ([]repository.AuditLogEntry, error) {
+			gotFrom, gotTo = from, to
+			if afterID != 0 {
+				return nil, nil
+			}
+			return []repository.AuditLogEntry{
+				{ID: 1, ProjectID: "proj-a", APIKeyID: "key-a", Action: "create_flag", FlagKey: "checkout", CreatedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+				{ID: 2, ProjectID: "proj-b", AdminUserID: "7", Action: "delete_flag", FlagKey: "legacy", Details: []byte(`{"reason":"cleanup"}`), CreatedAt: time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandler(svc)
+
+	request := func(keyID, target string) *httptest.ResponseRecorder {
+		ctx := middleware.NewContextWithAPIKeyID(middleware.NewContextWithProjectID(context.Background(), "default"), keyID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+		return rec
+	}
+
+	t.Run("audit", func(t *testing.T) {
+		rec := request("admin-key", "/v1/admin/export/audit?format=parquet&range=2026-01-01T00:00:00Z/2026-01-02T00:00:00Z")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != export.ContentType {
+			t.Errorf("Content-Type = %q, want %q", got, export.ContentType)
+		}
+		if !gotFrom.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotTo.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("range = %s to %s, want 2026-01-01 to 2026-01-02", gotFrom, gotTo)
+		}
+
+		type row struct {
+			ID      int64   `parquet:"id"`
+			Action  string  `parquet:"action"`
+			Details *string `parquet:"details,optional"`
+		}
+		rows, err := parquet.Read[row](bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatalf("read parquet: %v", err)
+		}
+		if len(rows) != 2 || rows[0].Action != "create_flag" || rows[0].Details != nil || rows[1].Details == nil || *rows[1].Details != `{"reason":"cleanup"}` {
+			t.Errorf("rows = %+v, want both audit entries", rows)
+		}
+	})
+
+	tests := []struct {
+		name, keyID, target string
+		want                int
+	}{
+		{"project key", "project-key", "/v1/admin/export/audit", http.StatusForbidden},
+		{"unknown dataset", "admin-key", "/v1/admin/export/flags", http.StatusNotFound},
+		{"unsupported format", "admin-key", "/v1/admin/export/events?format=csv", http.StatusBadRequest},
+		{"invalid range", "admin-key", "/v1/admin/export/events?range=yesterday", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(tt.keyID, tt.target); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
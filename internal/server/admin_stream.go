@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// adminStreamEventJSON is the SSE data of an all-projects stream event.
+// Flag holds the stored event payload unchanged.
+type adminStreamEventJSON struct {
+	ProjectID string          `json:"project_id"`
+	Key       string          `json:"key"`
+	Flag      json.RawMessage `json:"flag"`
+}
+
+// handleAdminStream streams flag events from every project to callers
+// holding an admin-scoped API key. It mirrors handleStream, with each event
+// wrapped to say which project it came from.
+func (s *HTTPServer) handleAdminStream(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := middleware.APIKeyIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if err := s.service.AuthorizeAdminAPIKey(r.Context(), keyID); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "invalid Last-Event-ID")
+		return
+	}
+
+	release, ok := s.streamLimiter.acquire(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusTooManyRequests, "too many open streams for this API key")
+		return
+	}
+	defer release()
+
+	drain, leave, ok := s.streamDrainer.join()
+	if !ok {
+		writeShuttingDown(w, r)
+		return
+	}
+	defer leave()
+
+	w, rc := s.streamWriter(w)
+
+	keepalive := newSSEKeepalive(s.keepaliveInterval)
+	defer keepalive.stop()
+
+	currentEventID := lastEventID
+	writeEvents := func(events []repository.FlagEvent) error {
+		sent := 0
+		defer func() { s.metrics.AddStreamEventsSent("sse", sent) }()
+		for _, event := range events {
+			currentEventID = event.EventID
+			eventName := toSSEEventName(event.EventType)
+			if eventName == "" {
+				continue
+			}
+
+			flag := json.RawMessage(event.Payload)
+			if len(flag) == 0 {
+				flag = json.RawMessage(`{}`)
+			}
+			payload, err := json.Marshal(adminStreamEventJSON{
+				ProjectID: event.ProjectID,
+				Key:       event.FlagKey,
+				Flag:      flag,
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := writeSSEEvent(w, event.EventID, eventName, payload); err != nil {
+				return err
+			}
+			sent++
+			_ = rc.Flush()
+			keepalive.reset()
+		}
+
+		return nil
+	}
+
+	follow := func() bool {
+		for {
+			events, err := s.service.ListAllEventsSince(r.Context(), currentEventID)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+					writeSSEError(w, rc, serviceErrorMessage(err))
+				}
+				return false
+			}
+			previous := currentEventID
+			if err := writeEvents(events); err != nil {
+				return false
+			}
+			if currentEventID == previous {
+				return true
+			}
+		}
+	}
+
+	waiter := newEventWaiter(s.service, s.streamPollInterval)
+	defer waiter.stop()
+
+	initialEvents, err := s.service.ListAllEventsSince(r.Context(), currentEventID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	headers := w.Header()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_ = writeSSERetry(w)
+	_ = rc.Flush()
+
+	s.metrics.ActiveStreams.WithLabelValues("sse").Inc()
+	defer s.metrics.ActiveStreams.WithLabelValues("sse").Dec()
+	if lastEventID > 0 {
+		s.metrics.ObserveReplayDepth("sse", len(initialEvents))
+	}
+
+	if err := writeEvents(initialEvents); err != nil {
+		return
+	}
+	if len(initialEvents) > 0 && !follow() {
+		return
+	}
+
+	var heartbeat <-chan time.Time
+	if s.heartbeatInterval > 0 {
+		heartbeatTicker := time.NewTicker(s.heartbeatInterval)
+		defer heartbeatTicker.Stop()
+		heartbeat = heartbeatTicker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-drain:
+			_ = writeSSEReconnect(w, currentEventID)
+			_ = rc.Flush()
+			return
+		case now := <-heartbeat:
+			if err := writeSSEHeartbeat(w, currentEventID, now, s.identity); err != nil {
+				return
+			}
+			_ = rc.Flush()
+			keepalive.reset()
+		case <-keepalive.C():
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			_ = rc.Flush()
+			keepalive.reset()
+		case <-waiter.C():
+			waiter.rearm()
+			if !follow() {
+				return
+			}
+		}
+	}
+}
+
+// WatchAllProjects streams flag events from every project to callers
+// holding an admin-scoped API key.
+func (s *GRPCServer) WatchAllProjects(req *flagspb.WatchAllProjectsRequest, stream flagspb.FlagService_WatchAllProjectsServer) error {
+	keyID, ok := middleware.APIKeyIDFromContext(stream.Context())
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if err := s.service.AuthorizeAdminAPIKey(stream.Context(), keyID); err != nil {
+		return toGRPCError(err)
+	}
+
+	lastEventID := req.GetLastEventId()
+	if lastEventID < 0 {
+		return status.Error(codes.InvalidArgument, "last_event_id must be non-negative")
+	}
+	drain, leave, ok := s.streamDrainer.join()
+	if !ok {
+		return errStreamShuttingDown
+	}
+	defer leave()
+
+	sendBatch := func(ctx context.Context, replay bool) error {
+		events, err := s.service.ListAllEventsSince(ctx, lastEventID)
+		if err != nil {
+			return toGRPCError(err)
+		}
+		if replay {
+			s.metrics.ObserveReplayDepth("grpc", len(events))
+		}
+
+		sent := 0
+		defer func() { s.metrics.AddStreamEventsSent("grpc", sent) }()
+		for _, event := range events {
+			lastEventID = event.EventID
+			watchEvent, ok := repositoryEventToProto(event)
+			if !ok {
+				continue
+			}
+
+			if err := stream.Send(&flagspb.ProjectFlagEvent{ProjectId: event.ProjectID, Event: watchEvent}); err != nil {
+				return err
+			}
+			sent++
+		}
+
+		return nil
+	}
+
+	// sendEvents sends batches until there are none left to catch up on.
+	sendEvents := func(ctx context.Context, replay bool) error {
+		for {
+			previous := lastEventID
+			if err := sendBatch(ctx, replay); err != nil || lastEventID == previous {
+				return err
+			}
+			replay = false
+		}
+	}
+
+	waiter := newEventWaiter(s.service, s.streamPollInterval)
+	defer waiter.stop()
+
+	if err := sendEvents(stream.Context(), lastEventID > 0); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-drain:
+			return stream.Send(&flagspb.ProjectFlagEvent{Event: reconnectEvent(lastEventID)})
+		case <-waiter.C():
+			waiter.rearm()
+			if err := sendEvents(stream.Context(), false); err != nil {
+				return err
+			}
+		}
+	}
+}
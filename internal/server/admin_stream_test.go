@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+func adminStreamService(t *testing.T) *fakeService {
+	t.Helper()
+	return &fakeService{
+		authorizeAdminAPIKeyFunc: func(_ context.Context, keyID string) error {
+			if keyID != "admin-key" {
+				return service.ErrAdminKeyRequired
+			}
+			return nil
+		},
+		listAllEventsSinceFunc: func(_ context.Context, since int64) ([]repository.FlagEvent, error) {
+			if since != 1 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{
+				{
+					EventID:   2,
+					ProjectID: "proj-a",
+					FlagKey:   "checkout",
+					EventType: service.EventTypeUpdated,
+					Payload:   json.RawMessage(`{"key":"checkout","enabled":true}`),
+				},
+				{
+					EventID:   3,
+					ProjectID: "proj-b",
+					FlagKey:   "legacy",
+					EventType: service.EventTypeDeleted,
+					Payload:   json.RawMessage(`{"key":"legacy"}`),
+				},
+			}, nil
+		},
+	}
+}
+
+func TestHTTPHandlerAdminStream(t *testing.T) {
+	handler := NewHTTPHandlerWithStreamPollInterval(adminStreamService(t), 5*time.Millisecond)
+
+	t.Run("admin key", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		ctx = middleware.NewContextWithAPIKeyID(middleware.NewContextWithProjectID(ctx, "default"), "admin-key")
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/stream", nil).WithContext(ctx)
+		req.Header.Set("Last-Event-ID", "1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{
+			"id: 2\nevent: update\ndata: " + `{"project_id":"proj-a","key":"checkout","flag":{"key":"checkout","enabled":true}}`,
+			"id: 3\nevent: delete\ndata: " + `{"project_id":"proj-b","key":"legacy","flag":{"key":"legacy"}}`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("stream body = %q, want it to contain %q", body, want)
+			}
+		}
+	})
+
+	t.Run("project key", func(t *testing.T) {
+		ctx := middleware.NewContextWithAPIKeyID(middleware.NewContextWithProjectID(context.Background(), "default"), "project-key")
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/stream", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+type fakeWatchAllProjectsServer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	events []*flagspb.ProjectFlagEvent
+}
+
+func (f *fakeWatchAllProjectsServer) Send(event *flagspb.ProjectFlagEvent) error {
+	f.events = append(f.events, event)
+	if len(f.events) == 2 {
+		f.cancel()
+	}
+	return nil
+}
+
+func (f *fakeWatchAllProjectsServer) SetHeader(metadata.MD) error  { return nil }
+func (f *fakeWatchAllProjectsServer) SendHeader(metadata.MD) error { return nil }
+func (f *fakeWatchAllProjectsServer) SetTrailer(metadata.MD)       {}
+func (f *fakeWatchAllProjectsServer) Context() context.Context     { return f.ctx }
+func (f *fakeWatchAllProjectsServer) SendMsg(any) error            { return nil }
+func (f *fakeWatchAllProjectsServer) RecvMsg(any) error            { return io.EOF }
+
+func TestGRPCServerWatchAllProjects(t *testing.T) {
+	grpcServer := NewGRPCServerWithStreamPollInterval(adminStreamService(t), time.Hour)
+
+	ctx, cancel := context.WithCancel(middleware.NewContextWithAPIKeyID(ctxWithProject(), "admin-key"))
+	stream := &fakeWatchAllProjectsServer{ctx: ctx, cancel: cancel}
+	if err := grpcServer.WatchAllProjects(&flagspb.WatchAllProjectsRequest{LastEventId: 1}, stream); err != nil {
+		t.Fatalf("WatchAllProjects() error = %v", err)
+	}
+	if len(stream.events) != 2 {
+		t.Fatalf("WatchAllProjects() sent %d events, want 2", len(stream.events))
+	}
+	if got := stream.events[0]; got.GetProjectId() != "proj-a" || got.GetEvent().GetKey() != "checkout" || got.GetEvent().GetType() != flagspb.WatchFlagEventType_FLAG_UPDATED {
+		t.Errorf("first event = %v, want the proj-a checkout update", got)
+	}
+	if got := stream.events[1]; got.GetProjectId() != "proj-b" || got.GetEvent().GetEventId() != 3 || got.GetEvent().GetType() != flagspb.WatchFlagEventType_FLAG_DELETED {
+		t.Errorf("second event = %v, want the proj-b legacy delete", got)
+	}
+
+	projectCtx := middleware.NewContextWithAPIKeyID(ctxWithProject(), "project-key")
+	err := grpcServer.WatchAllProjects(&flagspb.WatchAllProjectsRequest{}, &fakeWatchAllProjectsServer{ctx: projectCtx})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("WatchAllProjects() with a project key code = %v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/matt-riley/flagz/api"
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+)
+
+// protoDescriptorContentType is the media type of a serialized
+// google.protobuf.FileDescriptorSet.
+const protoDescriptorContentType = "application/x-protobuf; messageType=google.protobuf.FileDescriptorSet"
+
+// openAPIJSON converts the embedded OpenAPI document to JSON once.
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(api.OpenAPI, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	return json.Marshal(doc)
+})
+
+// schemaDocument describes the wire formats of flags and stream events,
+// for SDKs to check their compatibility with the server. Its schemas are
+// taken from the OpenAPI document, and references between them resolve
+// within it.
+type schemaDocument struct {
+	// EventSchemaVersion is the newest event schema version the server
+	// writes; EventSchemaVersions lists every version a stream may ask for.
+	EventSchemaVersion  int   `json:"event_schema_version"`
+	EventSchemaVersions []int `json:"event_schema_versions"`
+	Flag                any   `json:"flag"`
+	// Events holds the schema of update and delete event data by version.
+	Events     map[string]any `json:"events"`
+	Components struct {
+		Schemas map[string]any `json:"schemas"`
+	} `json:"components"`
+}
+
+// schemaJSON builds the schema document from the embedded OpenAPI document
+// once.
+var schemaJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]any `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(api.OpenAPI, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+
+	schema := schemaDocument{
+		EventSchemaVersion:  latestEventSchemaVersion,
+		EventSchemaVersions: eventSchemaVersions,
+		Flag:                schemaRef("Flag"),
+		Events: map[string]any{
+			strconv.Itoa(eventSchemaV1): schemaRef("Flag"),
+			strconv.Itoa(eventSchemaV2): schemaRef("StreamEvent"),
+		},
+	}
+	schema.Components.Schemas = make(map[string]any)
+	var add func(name string) error
+	add = func(name string) error {
+		if _, ok := schema.Components.Schemas[name]; ok {
+			return nil
+		}
+		component, ok := doc.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("openapi document has no schema %q", name)
+		}
+		schema.Components.Schemas[name] = component
+		for _, ref := range schemaRefs(component) {
+			if err := add(ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range []string{"Flag", "StreamEvent"} {
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(schema)
+})
+
+const schemaRefPrefix = "#/components/schemas/"
+
+func schemaRef(name string) map[string]string {
+	return map[string]string{"$ref": schemaRefPrefix + name}
+}
+
+// schemaRefs returns the names of the component schemas v refers to.
+func schemaRefs(v any) []string {
+	var refs []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" && strings.HasPrefix(ref, schemaRefPrefix) {
+				refs = append(refs, strings.TrimPrefix(ref, schemaRefPrefix))
+				continue
+			}
+			refs = append(refs, schemaRefs(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			refs = append(refs, schemaRefs(value)...)
+		}
+	}
+	return refs
+}
+
+// protoDescriptorSet serializes the descriptors of the gRPC API and
+// everything it imports, dependencies first, as protoc -o would.
+var protoDescriptorSet = sync.OnceValues(func() ([]byte, error) {
+	var set descriptorpb.FileDescriptorSet
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(flagspb.File_api_proto_v1_flag_service_proto)
+	return proto.Marshal(&set)
+})
+
+// handleOpenAPI serves the OpenAPI document the server was built with.
+func (s *HTTPServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := openAPIJSON()
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(doc)
+}
+
+// handleSchema serves the schemas of flags and stream events, and the event
+// schema versions the server supports.
+func (s *HTTPServer) handleSchema(w http.ResponseWriter, r *http.Request) {
+	doc, err := schemaJSON()
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(doc)
+}
+
+// handleProtoDescriptor serves the FileDescriptorSet of the gRPC API the
+// server was built with, for code generators and tools such as grpcurl.
+func (s *HTTPServer) handleProtoDescriptor(w http.ResponseWriter, r *http.Request) {
+	set, err := protoDescriptorSet()
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", protoDescriptorContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="flagz.protoset"`)
+	_, _ = w.Write(set)
+}
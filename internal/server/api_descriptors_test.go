@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+func TestHTTPHandlerServesAPIDescriptors(t *testing.T) {
+	handler := NewHTTPHandler(&fakeService{})
+
+	t.Run("openapi", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var doc struct {
+			OpenAPI string                     `json:"openapi"`
+			Paths   map[string]json.RawMessage `json:"paths"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("decode document: %v", err)
+		}
+		if doc.OpenAPI == "" {
+			t.Error("document has no openapi version")
+		}
+		for _, path := range []string{"/v1/flags", "/v1/openapi.json", "/v1/proto/descriptor"} {
+			if _, ok := doc.Paths[path]; !ok {
+				t.Errorf("document does not describe %s", path)
+			}
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/schema", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var doc struct {
+			EventSchemaVersion  int                          `json:"event_schema_version"`
+			EventSchemaVersions []int                        `json:"event_schema_versions"`
+			Events              map[string]map[string]string `json:"events"`
+			Components          struct {
+				Schemas map[string]struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("decode document: %v", err)
+		}
+		if doc.EventSchemaVersion != latestEventSchemaVersion || !slices.Equal(doc.EventSchemaVersions, eventSchemaVersions) {
+			t.Errorf("versions = %d of %v, want %d of %v", doc.EventSchemaVersion, doc.EventSchemaVersions, latestEventSchemaVersion, eventSchemaVersions)
+		}
+		for version, schema := range doc.Events {
+			name := strings.TrimPrefix(schema["$ref"], "#/components/schemas/")
+			if _, ok := doc.Components.Schemas[name]; !ok {
+				t.Errorf("event schema %s refers to missing component %q", version, schema["$ref"])
+			}
+		}
+		// Referenced schemas come along, so references resolve.
+		if _, ok := doc.Components.Schemas["FlagTargets"]; !ok {
+			t.Error("components do not include FlagTargets, which Flag refers to")
+		}
+
+		flag := doc.Components.Schemas["Flag"].Properties
+		flagType := reflect.TypeFor[repository.Flag]()
+		for i := range flagType.NumField() {
+			name, _, _ := strings.Cut(flagType.Field(i).Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if _, ok := flag[name]; !ok {
+				t.Errorf("Flag schema does not describe %q", name)
+			}
+		}
+		event := doc.Components.Schemas["StreamEvent"].Properties
+		eventType := reflect.TypeFor[streamEventJSON]()
+		for i := range eventType.NumField() {
+			name, _, _ := strings.Cut(eventType.Field(i).Tag.Get("json"), ",")
+			if _, ok := event[name]; !ok {
+				t.Errorf("StreamEvent schema does not describe %q", name)
+			}
+		}
+	})
+
+	t.Run("proto descriptor", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/proto/descriptor", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+			t.Fatalf("decode descriptor set: %v", err)
+		}
+		files, err := protodesc.NewFiles(&set)
+		if err != nil {
+			t.Fatalf("descriptor set is not self-contained: %v", err)
+		}
+		if _, err := files.FindDescriptorByName("flagz.v1.FlagService"); err != nil {
+			t.Errorf("FindDescriptorByName(flagz.v1.FlagService) error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/jsonschema"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// maxBatchCreateFlags caps the number of flags in one POST /v1/flags:batch
+// request, keeping the repository transaction reasonably short.
+const maxBatchCreateFlags = 500
+
+type batchCreateRequest struct {
+	Flags []repository.Flag `json:"flags"`
+}
+
+// batchCreateResult reports the outcome for one flag of a batch, in request
+// order. Flag is set when the batch succeeded; Error is set on the flags
+// that caused it to be rejected.
+type batchCreateResult struct {
+	Index      int              `json:"index"`
+	Key        string           `json:"key"`
+	Flag       *repository.Flag `json:"flag,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	RuleErrors []core.RuleError `json:"rule_errors,omitempty"`
+
+	VariantErrors []jsonschema.Error `json:"variant_errors,omitempty"`
+}
+
+type batchCreateResponse struct {
+	Results []batchCreateResult `json:"results"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// handleBatchCreateFlags creates up to maxBatchCreateFlags flags in a single
+// transaction. Either every flag is created, or none are and the response
+// marks each flag that was rejected. The body shares the import size limit.
+func (s *HTTPServer) handleBatchCreateFlags(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req batchCreateRequest
+	if err := s.decodeJSONBodyLimit(w, r, &req, s.maxImportBytes); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+	if len(req.Flags) == 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "flags are required")
+		return
+	}
+	if len(req.Flags) > maxBatchCreateFlags {
+		writeJSONError(w, r, http.StatusBadRequest, fmt.Sprintf("a batch may create at most %d flags", maxBatchCreateFlags))
+		return
+	}
+
+	results := make([]batchCreateResult, len(req.Flags))
+	for i := range req.Flags {
+		// Force project ID from context
+		req.Flags[i].ProjectID = projectID
+		results[i] = batchCreateResult{Index: i, Key: req.Flags[i].Key}
+	}
+
+	created, err := s.service.CreateFlags(r.Context(), req.Flags)
+	if err != nil {
+		var batchErr *service.BatchError
+		if !errors.As(err, &batchErr) {
+			writeServiceError(w, r, err)
+			return
+		}
+		for _, item := range batchErr.Items {
+			results[item.Index].Error = serviceErrorMessage(item.Err)
+			var rulesErr *service.RulesError
+			if errors.As(item.Err, &rulesErr) {
+				results[item.Index].RuleErrors = rulesErr.Rules
+			}
+			var variantsErr *service.VariantsError
+			if errors.As(item.Err, &variantsErr) {
+				results[item.Index].VariantErrors = variantsErr.Errors
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, batchCreateResponse{
+			Results: results,
+			Error:   "batch rejected; no flags were created",
+		})
+		return
+	}
+
+	for i := range created {
+		results[i].Flag = &created[i]
+	}
+	writeJSON(w, http.StatusCreated, batchCreateResponse{Results: results})
+}
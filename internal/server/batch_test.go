@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+func TestHTTPHandlerBatchCreateFlags(t *testing.T) {
+	var gotFlags []repository.Flag
+	svc := &fakeService{
+		createFlagsFunc: func(_ context.Context, flags []repository.Flag) ([]repository.Flag, error) {
+			gotFlags = flags
+			return flags, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	body := `{"flags":[{"key":"a","enabled":true},{"key":"b"}]}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags:batch", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if len(gotFlags) != 2 || gotFlags[1].ProjectID != "default" {
+		t.Fatalf("CreateFlags() flags = %+v, want 2 flags in the authenticated project", gotFlags)
+	}
+
+	var resp batchCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(resp.Results))
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Flag == nil || result.Flag.Key != result.Key || result.Error != "" {
+			t.Errorf("results[%d] = %+v, want created flag", i, result)
+		}
+	}
+}
+
+func TestHTTPHandlerBatchCreateFlagsReportsRejectedFlags(t *testing.T) {
+	svc := &fakeService{
+		createFlagsFunc: func(_ context.Context, _ []repository.Flag) ([]repository.Flag, error) {
+			return nil, &service.BatchError{Items: []service.BatchItemError{
+				{Index: 1, Key: "b", Err: &service.RulesError{Rules: []core.RuleError{{Index: 0, Reason: "attribute is required"}}}},
+				{Index: 2, Key: "a", Err: service.ErrFlagExists},
+			}}
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	body := `{"flags":[{"key":"a"},{"key":"b"},{"key":"a"}]}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags:batch", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var resp batchCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Error == "" || len(resp.Results) != 3 {
+		t.Fatalf("response = %+v, want an error and 3 results", resp)
+	}
+	if r := resp.Results[0]; r.Error != "" || r.Flag != nil {
+		t.Errorf("results[0] = %+v, want neither error nor flag", r)
+	}
+	if r := resp.Results[1]; r.Error != "invalid rules" || len(r.RuleErrors) != 1 {
+		t.Errorf("results[1] = %+v, want invalid rules with rule errors", r)
+	}
+	if r := resp.Results[2]; r.Error != "flag already exists" {
+		t.Errorf("results[2] = %+v, want flag already exists", r)
+	}
+}
+
+func TestHTTPHandlerBatchCreateFlagsLimits(t *testing.T) {
+	svc := &fakeService{
+		createFlagsFunc: func(_ context.Context, _ []repository.Flag) ([]repository.Flag, error) {
+			t.Fatal("CreateFlags should not be called")
+			return nil, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	tooMany := `{"flags":[` + strings.Repeat(`{"key":"k"},`, maxBatchCreateFlags) + `{"key":"k"}]}`
+	for name, body := range map[string]string{
+		"empty":    `{"flags":[]}`,
+		"too many": tooMany,
+		"unknown":  `{"flags":[{"key":"a","colour":"red"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags:batch", strings.NewReader(body)))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// contextPresetJSONRequest is the body of PUT /v1/context-presets/{name};
+// the name comes from the path.
+type contextPresetJSONRequest struct {
+	Description string                 `json:"description"`
+	Context     core.EvaluationContext `json:"context"`
+}
+
+func (s *HTTPServer) handleListContextPresets(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	presets, err := s.service.ListContextPresets(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presets)
+}
+
+func (s *HTTPServer) handleGetContextPreset(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	preset, err := s.service.GetContextPreset(r.Context(), projectID, r.PathValue("name"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preset)
+}
+
+func (s *HTTPServer) handlePutContextPreset(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request contextPresetJSONRequest
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.PutContextPreset(r.Context(), repository.ContextPreset{
+		ProjectID:   projectID,
+		Name:        r.PathValue("name"),
+		Description: request.Description,
+		Context:     request.Context,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *HTTPServer) handleDeleteContextPreset(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := s.service.DeleteContextPreset(r.Context(), projectID, r.PathValue("name")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+func (s *HTTPServer) handleGetContextSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	schema, err := s.service.GetContextSchema(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schema)
+}
+
+func (s *HTTPServer) handleSetContextSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var schema repository.ContextSchema
+	if err := s.decodeJSONBody(w, r, &schema); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetContextSchema(r.Context(), projectID, schema)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *HTTPServer) handleGetContextEnrichment(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	enrichment, err := s.service.GetContextEnrichment(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, enrichment)
+}
+
+func (s *HTTPServer) handleSetContextEnrichment(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var enrichment repository.ContextEnrichment
+	if err := s.decodeJSONBody(w, r, &enrichment); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetContextEnrichment(r.Context(), projectID, enrichment)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
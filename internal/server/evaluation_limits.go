@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/matt-riley/flagz/internal/metrics"
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// EvaluationLimiter caps how many evaluation requests are handled at once,
+// so that a burst of them, each missing the cache, cannot queue up on the
+// database pool. Requests over the cap wait for a slot in a bounded queue;
+// once that is full they are rejected straight away. Share one limiter
+// between the HTTP and gRPC servers so both transports count against it.
+type EvaluationLimiter struct {
+	slots     chan struct{}
+	maxQueued int64
+	queued    atomic.Int64
+	metrics   *metrics.Metrics
+}
+
+// NewEvaluationLimiter returns an [EvaluationLimiter] allowing maxInFlight
+// concurrent evaluation requests, with up to maxQueued more waiting for a
+// slot, recording its load in m. It returns nil, which allows every
+// request, when maxInFlight is zero or negative.
+func NewEvaluationLimiter(maxInFlight, maxQueued int, m *metrics.Metrics) *EvaluationLimiter {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	if m == nil {
+		m = metrics.New()
+	}
+	return &EvaluationLimiter{
+		slots:     make(chan struct{}, maxInFlight),
+		maxQueued: int64(max(maxQueued, 0)),
+		metrics:   m,
+	}
+}
+
+// acquire claims a slot for an evaluation request over transport ("http" or
+// "grpc"), waiting in the queue while there is room in it and ctx is live.
+// It reports false if the request is shed; otherwise the caller must call
+// release once the evaluation is done.
+func (l *EvaluationLimiter) acquire(ctx context.Context, transport string) (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		if !l.wait(ctx) {
+			l.metrics.EvaluationsShedTotal.WithLabelValues(transport).Inc()
+			return nil, false
+		}
+	}
+	l.metrics.EvaluationsInFlight.Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.metrics.EvaluationsInFlight.Dec()
+			<-l.slots
+		})
+	}, true
+}
+
+// wait queues for a slot, reporting false if the queue is full or ctx ends
+// first.
+func (l *EvaluationLimiter) wait(ctx context.Context) bool {
+	if l.queued.Add(1) > l.maxQueued {
+		l.queued.Add(-1)
+		return false
+	}
+	l.metrics.EvaluationQueueDepth.Inc()
+	defer func() {
+		l.queued.Add(-1)
+		l.metrics.EvaluationQueueDepth.Dec()
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// WithEvaluationLimiter limits the POST /v1/evaluate, POST /v1/evaluate/all
+// and POST /v1/evaluate/explain requests handled at once to the limiter's
+// maximum. Requests it sheds are answered with 503 and a Retry-After
+// header.
+func WithEvaluationLimiter(l *EvaluationLimiter) HTTPOption {
+	return func(s *HTTPServer) {
+		s.evaluationLimiter = l
+	}
+}
+
+// WithGRPCEvaluationLimiter limits the ResolveBoolean, ResolveBatch and
+// ResolveAll calls handled at once to the limiter's maximum. Calls it sheds
+// fail with ResourceExhausted.
+func WithGRPCEvaluationLimiter(l *EvaluationLimiter) GRPCOption {
+	return func(s *GRPCServer) {
+		s.evaluationLimiter = l
+	}
+}
+
+// limitEvaluations runs next under the server's evaluation limiter. It goes
+// inside withTimeout, so time spent queued counts against the evaluation
+// timeout.
+func (s *HTTPServer) limitEvaluations(next http.HandlerFunc) http.HandlerFunc {
+	if s.evaluationLimiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, ok := s.evaluationLimiter.acquire(r.Context(), "http")
+		if !ok {
+			w.Header().Set("Retry-After", "1")
+			middleware.WriteProblem(w, r, middleware.Problem{
+				Status: http.StatusServiceUnavailable,
+				Type:   middleware.ProblemType("overloaded"),
+				Detail: "too many evaluations in progress; retry shortly",
+			})
+			return
+		}
+		defer release()
+		next(w, r)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/metrics"
+	"github.com/matt-riley/flagz/internal/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEvaluationLimiter(t *testing.T) {
+	if NewEvaluationLimiter(0, 10, nil) != nil {
+		t.Fatal("NewEvaluationLimiter(0) should return nil")
+	}
+	var unlimited *EvaluationLimiter
+	if _, ok := unlimited.acquire(context.Background(), "http"); !ok {
+		t.Fatal("nil limiter should allow every evaluation")
+	}
+
+	m := metrics.New()
+	l := NewEvaluationLimiter(1, 1, m)
+	release, ok := l.acquire(context.Background(), "http")
+	if !ok {
+		t.Fatal("first evaluation should be allowed")
+	}
+	if got := testutil.ToFloat64(m.EvaluationsInFlight); got != 1 {
+		t.Fatalf("in flight = %v, want 1", got)
+	}
+
+	// The second evaluation queues until the first is released.
+	queued := make(chan bool)
+	go func() {
+		release, ok := l.acquire(context.Background(), "grpc")
+		if ok {
+			release()
+		}
+		queued <- ok
+	}()
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(m.EvaluationQueueDepth) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("second evaluation did not queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// With the queue full, a third is shed without waiting.
+	if _, ok := l.acquire(context.Background(), "http"); ok {
+		t.Fatal("evaluation over the queue should be shed")
+	}
+	if got := testutil.ToFloat64(m.EvaluationsShedTotal.WithLabelValues("http")); got != 1 {
+		t.Fatalf("shed = %v, want 1", got)
+	}
+
+	release()
+	release()
+	if ok := <-queued; !ok {
+		t.Fatal("queued evaluation should get the released slot")
+	}
+	if depth, inFlight := testutil.ToFloat64(m.EvaluationQueueDepth), testutil.ToFloat64(m.EvaluationsInFlight); depth != 0 || inFlight != 0 {
+		t.Fatalf("queue depth, in flight = %v, %v, want 0, 0", depth, inFlight)
+	}
+
+	// A queued evaluation gives up when its context ends.
+	release, _ = l.acquire(context.Background(), "http")
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := l.acquire(ctx, "grpc"); ok {
+		t.Fatal("evaluation should be shed once its context ends")
+	}
+	if got := testutil.ToFloat64(m.EvaluationsShedTotal.WithLabelValues("grpc")); got != 1 {
+		t.Fatalf("grpc shed = %v, want 1", got)
+	}
+}
+
+func TestHTTPHandlerEvaluationLimit(t *testing.T) {
+	svc := &fakeService{
+		resolveAllFunc: func(context.Context, string, core.EvaluationContext, string) ([]service.ResolveResult, error) {
+			return nil, nil
+		},
+	}
+	limiter := NewEvaluationLimiter(1, 0, nil)
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithEvaluationLimiter(limiter))
+	evaluate := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate/all", strings.NewReader(`{}`))))
+		return rec
+	}
+
+	release, ok := limiter.acquire(context.Background(), "http")
+	if !ok {
+		t.Fatal("acquire() refused the first evaluation")
+	}
+	rec := evaluate()
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" || !strings.Contains(rec.Body.String(), "overloaded") {
+		t.Fatalf("evaluation over the limit = %d %q %s, want 503 overloaded with Retry-After", rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+
+	release()
+	if rec := evaluate(); rec.Code != http.StatusOK {
+		t.Fatalf("evaluation after release status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
+
+func TestGRPCServerEvaluationLimit(t *testing.T) {
+	svc := &fakeService{
+		resolveAllFunc: func(context.Context, string, core.EvaluationContext, string) ([]service.ResolveResult, error) {
+			t.Fatal("ResolveAll should not be called")
+			return nil, nil
+		},
+	}
+	limiter := NewEvaluationLimiter(1, 0, nil)
+	grpcServer := NewGRPCServerWithOptions(svc, time.Hour, nil, WithGRPCEvaluationLimiter(limiter))
+
+	release, ok := limiter.acquire(context.Background(), "grpc")
+	if !ok {
+		t.Fatal("acquire() refused the first evaluation")
+	}
+	defer release()
+
+	if _, err := grpcServer.ResolveAll(ctxWithProject(), &flagspb.ResolveAllRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("ResolveAll() code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+	if _, err := grpcServer.ResolveBoolean(ctxWithProject(), &flagspb.ResolveBooleanRequest{Key: "f"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("ResolveBoolean() code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+}
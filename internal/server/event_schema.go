@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// SchemaVersionHeader is the response header of GET /v1/stream naming the
+// event schema version its update and delete events are written in.
+const SchemaVersionHeader = "Flagz-Schema-Version"
+
+// Event schema versions of the data of update and delete SSE events.
+const (
+	// eventSchemaV1 data is the flag itself, as it was before events were
+	// versioned. Streams default to it so that older clients keep working.
+	eventSchemaV1 = 1
+	// eventSchemaV2 data is a [streamEventJSON], which names its version
+	// and project alongside the flag.
+	eventSchemaV2 = 2
+
+	latestEventSchemaVersion = eventSchemaV2
+)
+
+// eventSchemaVersions are the versions a stream may ask for, oldest first.
+var eventSchemaVersions = []int{eventSchemaV1, eventSchemaV2}
+
+// streamEventJSON is the data of an update or delete event in
+// [eventSchemaV2].
+type streamEventJSON struct {
+	SchemaVersion int             `json:"schema_version"`
+	ProjectID     string          `json:"project_id"`
+	Key           string          `json:"key"`
+	Flag          json.RawMessage `json:"flag"`
+}
+
+// parseEventSchemaVersion reads the schema_version parameter of a stream
+// request, defaulting to [eventSchemaV1].
+func parseEventSchemaVersion(query url.Values) (int, error) {
+	raw := strings.TrimSpace(query.Get("schema_version"))
+	if raw == "" {
+		return eventSchemaV1, nil
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil || !slices.Contains(eventSchemaVersions, version) {
+		return 0, fmt.Errorf("unsupported schema_version %q, want one of %v", raw, eventSchemaVersions)
+	}
+	return version, nil
+}
+
+// eventData returns the SSE data of event in the given schema version.
+func eventData(event repository.FlagEvent, version int) ([]byte, error) {
+	flag := json.RawMessage(event.Payload)
+	if len(flag) == 0 {
+		flag = json.RawMessage(`{}`)
+	}
+	if version == eventSchemaV1 {
+		return flag, nil
+	}
+	return json.Marshal(streamEventJSON{
+		SchemaVersion: version,
+		ProjectID:     event.ProjectID,
+		Key:           event.FlagKey,
+		Flag:          flag,
+	})
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PollInterval is a stream poll interval that can be changed while streams
+// are open. Share one between the HTTP and gRPC servers with
+// [WithStreamPollInterval] and [WithGRPCStreamPollInterval]; open streams
+// pick up a new interval the next time they wait.
+type PollInterval struct {
+	d atomic.Int64
+}
+
+// NewPollInterval creates a PollInterval set to d, or to 1 second if
+// d <= 0.
+func NewPollInterval(d time.Duration) *PollInterval {
+	p := &PollInterval{}
+	p.d.Store(int64(defaultStreamPollInterval))
+	p.Set(d)
+	return p
+}
+
+// Set changes the interval. Ignored if d <= 0.
+func (p *PollInterval) Set(d time.Duration) {
+	if d > 0 {
+		p.d.Store(int64(d))
+	}
+}
+
+// Get returns the current interval.
+func (p *PollInterval) Get() time.Duration {
+	return time.Duration(p.d.Load())
+}
+
+// WithStreamPollInterval makes SSE streams poll at p's interval instead of
+// the one passed to the constructor.
+func WithStreamPollInterval(p *PollInterval) HTTPOption {
+	return func(s *HTTPServer) {
+		s.streamPollInterval = p
+	}
+}
+
+// WithGRPCStreamPollInterval makes WatchFlag and WatchProject streams poll
+// at p's interval instead of the one passed to the constructor.
+func WithGRPCStreamPollInterval(p *PollInterval) GRPCOption {
+	return func(s *GRPCServer) {
+		s.streamPollInterval = p
+	}
+}
+
+// eventWaiter tells a stream when to list events again: as soon as the
+// service announces new ones or, when it cannot, after the poll interval.
+type eventWaiter struct {
+	service      Service
+	pollInterval *PollInterval
+	ready        <-chan struct{}
+	timer        *time.Timer
+}
+
+// newEventWaiter must be called before a stream first lists events, so that
+// events arriving while it does still wake it.
+func newEventWaiter(svc Service, pollInterval *PollInterval) *eventWaiter {
+	w := &eventWaiter{service: svc, pollInterval: pollInterval}
+	w.rearm()
+	return w
+}
+
+// C is closed when the stream should list events. Call rearm before listing.
+func (w *eventWaiter) C() <-chan struct{} {
+	return w.ready
+}
+
+func (w *eventWaiter) rearm() {
+	if changed := w.service.EventsChanged(); changed != nil {
+		w.ready = changed
+		return
+	}
+	ready := make(chan struct{})
+	w.timer = time.AfterFunc(w.pollInterval.Get(), func() { close(ready) })
+	w.ready = ready
+}
+
+func (w *eventWaiter) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
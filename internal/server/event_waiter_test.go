@@ -0,0 +1,26 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventWaiterFollowsPollInterval(t *testing.T) {
+	interval := NewPollInterval(time.Hour)
+	waiter := newEventWaiter(&fakeService{}, interval)
+	defer waiter.stop()
+
+	interval.Set(time.Millisecond)
+	interval.Set(0)
+	if got := interval.Get(); got != time.Millisecond {
+		t.Fatalf("Get() = %v, want %v", got, time.Millisecond)
+	}
+
+	waiter.stop()
+	waiter.rearm()
+	select {
+	case <-waiter.C():
+	case <-time.After(time.Second):
+		t.Fatal("waiter did not fire at the changed interval")
+	}
+}
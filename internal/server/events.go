@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// eventJSON is a flag event as listed by GET /v1/events, which unlike the
+// streams shows who made each change.
+type eventJSON struct {
+	repository.FlagEvent
+	Actor string `json:"actor,omitempty"`
+}
+
+type eventsResponse struct {
+	Events     []eventJSON `json:"events"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// handleListEvents pages through the caller's project's flag events, oldest
+// first. It takes the stream's key and types filters, an actor, a since and
+// until range and the cursor of a previous page.
+func (s *HTTPServer) handleListEvents(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := parseStreamFilter(query)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := repository.EventQuery{
+		ProjectID: projectID,
+		Keys:      filter.Keys,
+		Types:     filter.Types,
+		Actor:     strings.TrimSpace(query.Get("actor")),
+	}
+	for _, bound := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		raw := strings.TrimSpace(query.Get(bound.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, bound.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*bound.t = parsed
+	}
+	if q.AfterID, err = parseLastEventID(query.Get("cursor")); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "invalid cursor parameter")
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			writeJSONError(w, r, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		q.Limit = parsed
+	}
+
+	events, next, err := s.service.QueryEvents(r.Context(), q)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := eventsResponse{Events: make([]eventJSON, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, eventJSON{FlagEvent: event, Actor: event.Actor})
+	}
+	if next > 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
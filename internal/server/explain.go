@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+type explainJSONRequest struct {
+	Key     string                 `json:"key"`
+	Context core.EvaluationContext `json:"context,omitempty"`
+	Preset  string                 `json:"preset,omitempty"`
+}
+
+// handleExplain evaluates one flag and returns the result with a trace of
+// every step the evaluator took, for debugging rules. Unlike
+// POST /v1/evaluate, a missing flag is an error rather than a default, and
+// the evaluation is not counted.
+func (s *HTTPServer) handleExplain(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request explainJSONRequest
+	if err := s.decodeJSONBodyLimit(w, r, &request, s.maxEvaluateBytes); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+	if strings.TrimSpace(request.Key) == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	explanation, err := s.service.ExplainFlag(service.NewContextWithRequestHeaders(r.Context(), r.Header.Get), projectID, request.Key, request.Context, request.Preset)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, explanation)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+type copyFlagRequest struct {
+	TargetProject  string `json:"target_project"`
+	TargetKey      string `json:"target_key"`
+	IncludeRules   bool   `json:"include_rules"`
+	IncludeTargets bool   `json:"include_targets"`
+	Overwrite      bool   `json:"overwrite"`
+}
+
+// handleCopyFlag copies a flag to another key or project and returns the
+// copy: 201 if it was created, 200 if an existing flag was overwritten. A
+// caller's API key only reaches its own project, so copying to a different
+// project requires an admin-scoped key.
+func (s *HTTPServer) handleCopyFlag(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	var req copyFlagRequest
+	if err := s.decodeJSONBody(w, r, &req); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+	target := strings.TrimSpace(req.TargetProject)
+	if target == "" {
+		target = projectID
+	}
+	if target != projectID {
+		keyID, _ := middleware.APIKeyIDFromContext(r.Context())
+		if err := s.service.AuthorizeAdminAPIKey(r.Context(), keyID); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+	}
+
+	flag, created, err := s.service.CopyFlag(r.Context(), service.CopyFlagRequest{
+		SourceProjectID: projectID,
+		SourceKey:       key,
+		TargetProjectID: target,
+		TargetKey:       strings.TrimSpace(req.TargetKey),
+		IncludeRules:    req.IncludeRules,
+		IncludeTargets:  req.IncludeTargets,
+		Overwrite:       req.Overwrite,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, flag)
+}
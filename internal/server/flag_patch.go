@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// handlePatchFlag changes some of a flag's fields, leaving the rest as they
+// are. The body is a JSON Merge Patch or a JSON Patch, told apart by its
+// content type, applied to the flag as GET /v1/flags/{key} returns it.
+func (s *HTTPServer) handlePatchFlag(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != service.PatchTypeMerge && mediaType != service.PatchTypeJSON) {
+		writeJSONError(w, r, http.StatusUnsupportedMediaType, "content type must be "+service.PatchTypeMerge+" or "+service.PatchTypeJSON)
+		return
+	}
+
+	var patch json.RawMessage
+	if err := s.decodeJSONBody(w, r, &patch); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	flag, err := s.service.PatchFlag(r.Context(), projectID, key, mediaType, patch)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// handleFlagHistory lists a flag's revisions, newest first, each holding
+// the flag as it was stored by that create or update.
+func (s *HTTPServer) handleFlagHistory(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			writeJSONError(w, r, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = parsed
+	}
+
+	revisions, err := s.service.ListFlagRevisions(r.Context(), projectID, key, limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// handleRevertFlag restores a flag to an earlier revision and returns the
+// flag as updated.
+func (s *HTTPServer) handleRevertFlag(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+	revision, err := strconv.Atoi(r.PathValue("revision"))
+	if err != nil || revision <= 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "invalid revision")
+		return
+	}
+
+	flag, err := s.service.RevertFlag(r.Context(), projectID, key, revision)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// flagStreamPageSize is how many flags a flag stream reads from the service
+// at a time; the largest page it serves.
+const flagStreamPageSize = 1000
+
+// eachFlagPage passes the flags q selects to fn a page at a time, from
+// q.AfterKey on, so that only one page is held at once. It stops at the
+// first error.
+func eachFlagPage(ctx context.Context, svc Service, q repository.FlagPageQuery, fn func([]repository.Flag) error) error {
+	q.Limit = flagStreamPageSize
+	for {
+		flags, next, err := svc.ListFlagsPage(ctx, q)
+		if err != nil {
+			return err
+		}
+		if err := fn(flags); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		q.AfterKey = next
+	}
+}
+
+// writeFlagsNDJSON answers GET /v1/flags?format=ndjson with one flag per
+// line, flushing after each page, so exporting a very large project builds
+// neither one large response on the server nor one large document to parse
+// on the client. It honours cursor and tag like the paginated listing.
+func (s *HTTPServer) writeFlagsNDJSON(w http.ResponseWriter, r *http.Request, projectID string, query url.Values) {
+	if _, filtered := query["references_attribute"]; filtered {
+		writeJSONError(w, r, http.StatusBadRequest, "references_attribute cannot be combined with format=ndjson")
+		return
+	}
+
+	started := false
+	encoder := json.NewEncoder(w)
+	rc := http.NewResponseController(w)
+	err := eachFlagPage(r.Context(), s.service, repository.FlagPageQuery{
+		ProjectID: projectID,
+		AfterKey:  strings.TrimSpace(query.Get("cursor")),
+		Tags:      query["tag"],
+	}, func(flags []repository.Flag) error {
+		if !started {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		for _, flag := range flags {
+			if err := encoder.Encode(flag); err != nil {
+				return err
+			}
+		}
+		_ = rc.Flush()
+		return nil
+	})
+	if err == nil {
+		return
+	}
+	if !started {
+		writeServiceError(w, r, err)
+		return
+	}
+	// The status is already sent; abort so the client sees a truncated
+	// response rather than a complete-looking list with flags missing.
+	panic(http.ErrAbortHandler)
+}
+
+// ListFlagsStream sends every flag in the caller's project, sorted by key,
+// one message per flag. It reads them a page at a time, so exporting a very
+// large project never holds all of its flags at once.
+func (s *GRPCServer) ListFlagsStream(req *flagspb.ListFlagsStreamRequest, stream flagspb.FlagService_ListFlagsStreamServer) error {
+	projectID, err := projectIDFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	err = eachFlagPage(stream.Context(), s.service, repository.FlagPageQuery{
+		ProjectID: projectID,
+		Tags:      req.GetTags(),
+	}, func(flags []repository.Flag) error {
+		for _, flag := range flags {
+			if sendErr = stream.Send(repositoryFlagToProto(flag)); sendErr != nil {
+				return sendErr
+			}
+		}
+		return nil
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case err != nil:
+		return toGRPCError(err)
+	}
+	return nil
+}
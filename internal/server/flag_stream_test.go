@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// pagedFlagService serves n flags named flag-0000 onwards from
+// ListFlagsPage, recording each page it is asked for.
+func pagedFlagService(n int) (*fakeService, *[]repository.FlagPageQuery) {
+	flags := make([]repository.Flag, n)
+	for i := range flags {
+		flags[i] = repository.Flag{Key: fmt.Sprintf("flag-%04d", i), Tags: []string{"beta"}}
+	}
+	var queries []repository.FlagPageQuery
+	svc := &fakeService{
+		listFlagsPageFunc: func(_ context.Context, q repository.FlagPageQuery) ([]repository.Flag, string, error) {
+			queries = append(queries, q)
+			page, next := pageFlags(flags, q)
+			return page, next, nil
+		},
+	}
+	return svc, &queries
+}
+
+func TestHTTPHandlerListFlagsNDJSON(t *testing.T) {
+	svc, queries := pagedFlagService(2500)
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags?format=ndjson&cursor=flag-0099&tag=beta", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != ndjsonContentType {
+		t.Fatalf("Content-Type = %q, want %q", got, ndjsonContentType)
+	}
+
+	var keys []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var flag repository.Flag
+		if err := json.Unmarshal(scanner.Bytes(), &flag); err != nil {
+			t.Fatalf("line %d: %v", len(keys)+1, err)
+		}
+		keys = append(keys, flag.Key)
+	}
+	if len(keys) != 2400 || keys[0] != "flag-0100" || keys[len(keys)-1] != "flag-2499" {
+		t.Fatalf("got %d flags from %v to %v, want flag-0100 to flag-2499", len(keys), keys[0], keys[len(keys)-1])
+	}
+	if len(*queries) != 3 {
+		t.Fatalf("pages read = %d, want 3", len(*queries))
+	}
+	for _, q := range *queries {
+		if q.Limit != flagStreamPageSize || len(q.Tags) != 1 || q.Tags[0] != "beta" {
+			t.Fatalf("page query = %+v, want pages of %d flags tagged beta", q, flagStreamPageSize)
+		}
+	}
+}
+
+func TestHTTPHandlerListFlagsNDJSONErrors(t *testing.T) {
+	svc := &fakeService{
+		listFlagsPageFunc: func(context.Context, repository.FlagPageQuery) ([]repository.Flag, string, error) {
+			return nil, "", errors.New("database is down")
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"format=ndjson", http.StatusInternalServerError},
+		{"format=ndjson&references_attribute=country", http.StatusBadRequest},
+		{"format=xml", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags?"+tc.query, nil)))
+		if rec.Code != tc.want {
+			t.Errorf("GET /v1/flags?%s status = %d, want %d", tc.query, rec.Code, tc.want)
+		}
+	}
+}
+
+type fakeListFlagsStreamServer struct {
+	ctx   context.Context
+	flags []*flagspb.Flag
+}
+
+func (f *fakeListFlagsStreamServer) Send(flag *flagspb.Flag) error {
+	f.flags = append(f.flags, flag)
+	return nil
+}
+
+func (f *fakeListFlagsStreamServer) SetHeader(metadata.MD) error  { return nil }
+func (f *fakeListFlagsStreamServer) SendHeader(metadata.MD) error { return nil }
+func (f *fakeListFlagsStreamServer) SetTrailer(metadata.MD)       {}
+func (f *fakeListFlagsStreamServer) Context() context.Context     { return f.ctx }
+func (f *fakeListFlagsStreamServer) SendMsg(any) error            { return nil }
+func (f *fakeListFlagsStreamServer) RecvMsg(any) error            { return io.EOF }
+
+func TestGRPCServerListFlagsStream(t *testing.T) {
+	svc, queries := pagedFlagService(1500)
+	grpcServer := NewGRPCServer(svc)
+
+	stream := &fakeListFlagsStreamServer{ctx: ctxWithProject()}
+	if err := grpcServer.ListFlagsStream(&flagspb.ListFlagsStreamRequest{Tags: []string{"beta"}}, stream); err != nil {
+		t.Fatalf("ListFlagsStream() error = %v", err)
+	}
+	if len(stream.flags) != 1500 || stream.flags[0].GetKey() != "flag-0000" || stream.flags[1499].GetKey() != "flag-1499" {
+		t.Fatalf("sent %d flags, want flag-0000 to flag-1499", len(stream.flags))
+	}
+	if len(*queries) != 2 || (*queries)[1].AfterKey != "flag-0999" {
+		t.Fatalf("page queries = %+v, want a second page after flag-0999", *queries)
+	}
+
+	svc.listFlagsPageFunc = func(context.Context, repository.FlagPageQuery) ([]repository.Flag, string, error) {
+		return nil, "", errors.New("database is down")
+	}
+	err := grpcServer.ListFlagsStream(&flagspb.ListFlagsStreamRequest{}, &fakeListFlagsStreamServer{ctx: ctxWithProject()})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("ListFlagsStream() code = %v, want %v", status.Code(err), codes.Internal)
+	}
+	if strings.Contains(err.Error(), "database") {
+		t.Errorf("ListFlagsStream() error = %v, should not leak internal details", err)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// handleSetFlagTargets replaces a flag's allow and deny lists and returns the
+// flag as updated. Its rules are left untouched.
+func (s *HTTPServer) handleSetFlagTargets(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	var targets repository.FlagTargets
+	if err := s.decodeJSONBody(w, r, &targets); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	flag, err := s.service.SetFlagTargets(r.Context(), projectID, key, targets)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+type flagFromTemplateRequest struct {
+	Template    string         `json:"template"`
+	Key         string         `json:"key"`
+	Description string         `json:"description"`
+	Enabled     *bool          `json:"enabled"`
+	Owner       string         `json:"owner"`
+	Tags        []string       `json:"tags"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+func (s *HTTPServer) handleListFlagTemplates(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.ProjectIDFromContext(r.Context()); !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.service.FlagTemplates())
+}
+
+// handleCreateFlagFromTemplate creates a flag in the caller's project from a
+// built-in template and returns it with 201.
+func (s *HTTPServer) handleCreateFlagFromTemplate(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req flagFromTemplateRequest
+	if err := s.decodeJSONBody(w, r, &req); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+	if strings.TrimSpace(req.Key) == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	flag, err := s.service.CreateFlagFromTemplate(r.Context(), service.FlagFromTemplateRequest{
+		ProjectID:   projectID,
+		Template:    strings.TrimSpace(req.Template),
+		Key:         req.Key,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		Owner:       req.Owner,
+		Tags:        req.Tags,
+		Parameters:  req.Parameters,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, flag)
+}
@@ -14,7 +14,9 @@ import (
 	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
 	"github.com/matt-riley/flagz/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -26,7 +28,11 @@ type GRPCServer struct {
 	flagspb.UnimplementedFlagServiceServer
 	service            Service
 	metrics            *metrics.Metrics
-	streamPollInterval time.Duration
+	streamPollInterval *PollInterval
+	sdkConfigHeader    string
+	streamLimiter      *StreamLimiter
+	streamDrainer      *StreamDrainer
+	evaluationLimiter  *EvaluationLimiter
 }
 
 // NewGRPCServer creates a [GRPCServer] with a default stream poll interval of
@@ -46,7 +52,7 @@ func NewGRPCServerWithStreamPollInterval(svc Service, streamPollInterval time.Du
 
 // NewGRPCServerWithOptions creates a [GRPCServer] with the specified poll
 // interval and metrics. If m is nil, a default [metrics.Metrics] is created.
-func NewGRPCServerWithOptions(svc Service, streamPollInterval time.Duration, m *metrics.Metrics) *GRPCServer {
+func NewGRPCServerWithOptions(svc Service, streamPollInterval time.Duration, m *metrics.Metrics, opts ...GRPCOption) *GRPCServer {
 	if svc == nil {
 		panic("service is nil")
 	}
@@ -59,17 +65,38 @@ func NewGRPCServerWithOptions(svc Service, streamPollInterval time.Duration, m *
 		m = metrics.New()
 	}
 
-	return &GRPCServer{
+	server := &GRPCServer{
 		service:            svc,
 		metrics:            m,
-		streamPollInterval: streamPollInterval,
+		streamPollInterval: NewPollInterval(streamPollInterval),
 	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	return server
 }
 
-func (s *GRPCServer) CreateFlag(ctx context.Context, req *flagspb.CreateFlagRequest) (*flagspb.CreateFlagResponse, error) {
+// projectIDFromContext returns the project the auth interceptor scoped the
+// caller's API key to. Every project-scoped RPC goes through it, so a call
+// that somehow bypassed the interceptor fails with Unauthenticated instead
+// of reaching the service without a project.
+func projectIDFromContext(ctx context.Context) (string, error) {
 	projectID, ok := middleware.ProjectIDFromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	if !ok || projectID == "" {
+		return "", status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return projectID, nil
+}
+
+func (s *GRPCServer) CreateFlag(ctx context.Context, req *flagspb.CreateFlagRequest) (*flagspb.CreateFlagResponse, error) {
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageMutation); err != nil {
+		return nil, err
 	}
 
 	if req == nil || req.GetFlag() == nil {
@@ -79,7 +106,10 @@ func (s *GRPCServer) CreateFlag(ctx context.Context, req *flagspb.CreateFlagRequ
 		return nil, status.Error(codes.InvalidArgument, "key is required")
 	}
 
-	flag := protoFlagToRepository(req.GetFlag())
+	flag, err := protoFlagToRepository(req.GetFlag())
+	if err != nil {
+		return nil, err
+	}
 	flag.ProjectID = projectID
 
 	created, err := s.service.CreateFlag(ctx, flag)
@@ -91,9 +121,12 @@ func (s *GRPCServer) CreateFlag(ctx context.Context, req *flagspb.CreateFlagRequ
 }
 
 func (s *GRPCServer) UpdateFlag(ctx context.Context, req *flagspb.UpdateFlagRequest) (*flagspb.UpdateFlagResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageMutation); err != nil {
+		return nil, err
 	}
 
 	if req == nil || req.GetFlag() == nil {
@@ -103,7 +136,10 @@ func (s *GRPCServer) UpdateFlag(ctx context.Context, req *flagspb.UpdateFlagRequ
 		return nil, status.Error(codes.InvalidArgument, "key is required")
 	}
 
-	flag := protoFlagToRepository(req.GetFlag())
+	flag, err := protoFlagToRepository(req.GetFlag())
+	if err != nil {
+		return nil, err
+	}
 	flag.ProjectID = projectID
 
 	updated, err := s.service.UpdateFlag(ctx, flag)
@@ -115,9 +151,9 @@ func (s *GRPCServer) UpdateFlag(ctx context.Context, req *flagspb.UpdateFlagRequ
 }
 
 func (s *GRPCServer) GetFlag(ctx context.Context, req *flagspb.GetFlagRequest) (*flagspb.GetFlagResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	if req == nil || strings.TrimSpace(req.GetKey()) == "" {
@@ -133,26 +169,53 @@ func (s *GRPCServer) GetFlag(ctx context.Context, req *flagspb.GetFlagRequest) (
 }
 
 func (s *GRPCServer) ListFlags(ctx context.Context, req *flagspb.ListFlagsRequest) (*flagspb.ListFlagsResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
-	}
-
-	flags, err := s.service.ListFlags(ctx, projectID)
+	projectID, err := projectIDFromContext(ctx)
 	if err != nil {
-		return nil, toGRPCError(err)
+		return nil, err
 	}
 
 	pageSize := 0
 	pageToken := ""
 	if req != nil {
 		pageSize = int(req.GetPageSize())
-		pageToken = req.GetPageToken()
+		pageToken = strings.TrimSpace(req.GetPageToken())
 	}
 	if pageSize < 0 {
 		return nil, status.Error(codes.InvalidArgument, "page_size must be non-negative")
 	}
 
+	// Pages are read from the repository after the previous page's last
+	// key. Offset tokens from older servers are still served from the cache.
+	afterKey, keyset := strings.CutPrefix(pageToken, keysetPageTokenPrefix)
+	if pageSize > 0 && (keyset || pageToken == "") {
+		flags, next, err := s.service.ListFlagsPage(ctx, repository.FlagPageQuery{
+			ProjectID: projectID,
+			AfterKey:  afterKey,
+			Tags:      req.GetTags(),
+			Limit:     pageSize,
+		})
+		if err != nil {
+			return nil, toGRPCError(err)
+		}
+		s.setUnarySDKConfigHeader(ctx)
+
+		nextPageToken := ""
+		if next != "" {
+			nextPageToken = keysetPageTokenPrefix + next
+		}
+		return &flagspb.ListFlagsResponse{
+			Flags:         flagsToProto(flags),
+			NextPageToken: nextPageToken,
+		}, nil
+	}
+
+	flags, err := s.service.ListFlags(ctx, projectID)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	flags = service.FilterFlagsByTags(flags, req.GetTags())
+	s.setUnarySDKConfigHeader(ctx)
+
 	pageStart, err := parseListPageToken(pageToken, len(flags))
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
@@ -166,27 +229,31 @@ func (s *GRPCServer) ListFlags(ctx context.Context, req *flagspb.ListFlagsReques
 			pageEnd = len(flags)
 		}
 		if pageEnd < len(flags) {
-			nextPageToken = strconv.Itoa(pageEnd)
+			nextPageToken = keysetPageTokenPrefix + flags[pageEnd-1].Key
 		}
 	}
 
-	flags = flags[pageStart:pageEnd]
+	return &flagspb.ListFlagsResponse{
+		Flags:         flagsToProto(flags[pageStart:pageEnd]),
+		NextPageToken: nextPageToken,
+	}, nil
+}
 
+func flagsToProto(flags []repository.Flag) []*flagspb.Flag {
 	protoFlags := make([]*flagspb.Flag, 0, len(flags))
 	for _, flag := range flags {
 		protoFlags = append(protoFlags, repositoryFlagToProto(flag))
 	}
-
-	return &flagspb.ListFlagsResponse{
-		Flags:         protoFlags,
-		NextPageToken: nextPageToken,
-	}, nil
+	return protoFlags
 }
 
 func (s *GRPCServer) DeleteFlag(ctx context.Context, req *flagspb.DeleteFlagRequest) (*flagspb.DeleteFlagResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageMutation); err != nil {
+		return nil, err
 	}
 
 	if req == nil || strings.TrimSpace(req.GetKey()) == "" {
@@ -200,11 +267,31 @@ func (s *GRPCServer) DeleteFlag(ctx context.Context, req *flagspb.DeleteFlagRequ
 	return &flagspb.DeleteFlagResponse{}, nil
 }
 
+// withMetadataHeaders exposes the request's metadata to project context
+// enrichment as its headers.
+func withMetadataHeaders(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return service.NewContextWithRequestHeaders(ctx, func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	})
+}
+
 func (s *GRPCServer) ResolveBoolean(ctx context.Context, req *flagspb.ResolveBooleanRequest) (*flagspb.ResolveBooleanResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageEvaluation); err != nil {
+		return nil, err
+	}
+	release, ok := s.evaluationLimiter.acquire(ctx, "grpc")
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+		return nil, status.Error(codes.ResourceExhausted, "too many evaluations in progress")
 	}
+	defer release()
 
 	if req == nil || strings.TrimSpace(req.GetKey()) == "" {
 		return nil, status.Error(codes.InvalidArgument, "key is required")
@@ -215,24 +302,36 @@ func (s *GRPCServer) ResolveBoolean(ctx context.Context, req *flagspb.ResolveBoo
 		return nil, status.Error(codes.InvalidArgument, "invalid context_json")
 	}
 
-	value, err := s.service.ResolveBoolean(ctx, projectID, req.GetKey(), evalContext, req.GetDefaultValue())
+	result, err := s.service.ResolveBooleanDetail(withMetadataHeaders(ctx), projectID, req.GetKey(), evalContext, req.GetDefaultValue())
 	if err != nil {
 		return nil, toGRPCError(err)
 	}
 
-	s.metrics.RecordEvaluation(value)
+	s.metrics.RecordEvaluation(projectID, result.Key, result.Value)
 
 	return &flagspb.ResolveBooleanResponse{
-		Key:   req.GetKey(),
-		Value: value,
+		Key:       req.GetKey(),
+		Value:     result.Value,
+		Reason:    reasonToProto(result.Reason),
+		RuleIndex: ruleIndexToProto(result.RuleIndex),
+		Variant:   result.Variant,
+		Warnings:  contextWarningsToProto(result.Warnings),
 	}, nil
 }
 
 func (s *GRPCServer) ResolveBatch(ctx context.Context, req *flagspb.ResolveBatchRequest) (*flagspb.ResolveBatchResponse, error) {
-	projectID, ok := middleware.ProjectIDFromContext(ctx)
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageEvaluation); err != nil {
+		return nil, err
+	}
+	release, ok := s.evaluationLimiter.acquire(ctx, "grpc")
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+		return nil, status.Error(codes.ResourceExhausted, "too many evaluations in progress")
 	}
+	defer release()
 
 	if req == nil || len(req.GetRequests()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "requests are required")
@@ -257,28 +356,78 @@ func (s *GRPCServer) ResolveBatch(ctx context.Context, req *flagspb.ResolveBatch
 		})
 	}
 
-	results, err := s.service.ResolveBatch(ctx, requests)
+	results, err := s.service.ResolveBatch(withMetadataHeaders(ctx), requests)
 	if err != nil {
 		return nil, toGRPCError(err)
 	}
 
 	protoResults := make([]*flagspb.ResolveBatchResult, 0, len(results))
 	for _, result := range results {
-		s.metrics.RecordEvaluation(result.Value)
+		s.metrics.RecordEvaluation(projectID, result.Key, result.Value)
 		protoResults = append(protoResults, &flagspb.ResolveBatchResult{
-			Key:   result.Key,
-			Value: result.Value,
+			Key:       result.Key,
+			Value:     result.Value,
+			Reason:    reasonToProto(result.Reason),
+			RuleIndex: ruleIndexToProto(result.RuleIndex),
+			Variant:   result.Variant,
+			Warnings:  contextWarningsToProto(result.Warnings),
 		})
 	}
 
 	return &flagspb.ResolveBatchResponse{Results: protoResults}, nil
 }
 
+func (s *GRPCServer) ResolveAll(ctx context.Context, req *flagspb.ResolveAllRequest) (*flagspb.ResolveAllResponse, error) {
+	projectID, err := projectIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.allowUsage(ctx, projectID, service.UsageEvaluation); err != nil {
+		return nil, err
+	}
+	release, ok := s.evaluationLimiter.acquire(ctx, "grpc")
+	if !ok {
+		return nil, status.Error(codes.ResourceExhausted, "too many evaluations in progress")
+	}
+	defer release()
+
+	evalContext, err := decodeEvaluationContext(req.GetContextJson())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid context_json")
+	}
+
+	results, err := s.service.ResolveAll(ctx, projectID, evalContext, "")
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+
+	resp := &flagspb.ResolveAllResponse{Values: make(map[string]bool, len(results))}
+	for _, result := range results {
+		s.metrics.RecordEvaluation(projectID, result.Key, result.Value)
+		resp.Values[result.Key] = result.Value
+	}
+	if len(results) > 0 {
+		// Every result shares one context, and so its warnings.
+		resp.Warnings = contextWarningsToProto(results[0].Warnings)
+	}
+	return resp, nil
+}
+
 func (s *GRPCServer) WatchFlag(req *flagspb.WatchFlagRequest, stream flagspb.FlagService_WatchFlagServer) error {
-	projectID, ok := middleware.ProjectIDFromContext(stream.Context())
+	projectID, err := projectIDFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	release, ok := s.streamLimiter.acquire(stream.Context())
 	if !ok {
-		return status.Error(codes.Unauthenticated, "unauthenticated")
+		return status.Error(codes.ResourceExhausted, "too many open streams for this API key")
 	}
+	defer release()
+	drain, leave, ok := s.streamDrainer.join()
+	if !ok {
+		return errStreamShuttingDown
+	}
+	defer leave()
 
 	filterKey := ""
 	var lastEventID int64
@@ -300,12 +449,102 @@ func (s *GRPCServer) WatchFlag(req *flagspb.WatchFlagRequest, stream flagspb.Fla
 		}
 	}
 
-	sendEvents := func(ctx context.Context) error {
+	// Send the header now rather than with the first event so clients see
+	// the SDK config even on a quiet stream.
+	if md := s.sdkConfigMetadata(); md != nil {
+		if err := stream.SendHeader(md); err != nil {
+			return err
+		}
+	}
+
+	defer s.metrics.TrackProjectStream("grpc", projectID)()
+	defer recordStreamTime(stream.Context(), s.service, projectID, time.Now())
+
+	return s.followEvents(stream.Context(), drain, lastEventID, lastEventID > 0, listEventsSince, stream.Send)
+}
+
+// WatchProject streams a snapshot of every flag in the caller's project and
+// then each change after it. A resume token skips the snapshot and carries
+// on after the event it names.
+func (s *GRPCServer) WatchProject(req *flagspb.WatchProjectRequest, stream flagspb.FlagService_WatchProjectServer) error {
+	projectID, err := projectIDFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	release, ok := s.streamLimiter.acquire(stream.Context())
+	if !ok {
+		return status.Error(codes.ResourceExhausted, "too many open streams for this API key")
+	}
+	defer release()
+	drain, leave, ok := s.streamDrainer.join()
+	if !ok {
+		return errStreamShuttingDown
+	}
+	defer leave()
+
+	var lastEventID int64
+	resume := strings.TrimSpace(req.GetResumeToken())
+	if resume != "" {
+		if lastEventID, err = parseResumeToken(resume); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid resume_token")
+		}
+	}
+
+	if md := s.sdkConfigMetadata(); md != nil {
+		if err := stream.SendHeader(md); err != nil {
+			return err
+		}
+	}
+
+	defer s.metrics.TrackProjectStream("grpc", projectID)()
+	defer recordStreamTime(stream.Context(), s.service, projectID, time.Now())
+
+	if resume == "" {
+		snapshot, err := s.service.Snapshot(stream.Context(), projectID)
+		if err != nil {
+			return toGRPCError(err)
+		}
+		flags := make([]*flagspb.Flag, 0, len(snapshot.Flags))
+		for _, flag := range snapshot.Flags {
+			flags = append(flags, repositoryFlagToProto(flag))
+		}
+		lastEventID = snapshot.EventID
+		if err := stream.Send(&flagspb.WatchProjectEvent{
+			Snapshot:    &flagspb.ProjectSnapshot{Flags: flags},
+			ResumeToken: formatResumeToken(lastEventID),
+		}); err != nil {
+			return err
+		}
+	}
+
+	listEventsSince := func(ctx context.Context, eventID int64) ([]repository.FlagEvent, error) {
+		return s.service.ListEventsSince(ctx, projectID, eventID)
+	}
+	send := func(event *flagspb.WatchFlagEvent) error {
+		return stream.Send(&flagspb.WatchProjectEvent{
+			Change:      event,
+			ResumeToken: formatResumeToken(event.GetEventId()),
+		})
+	}
+	return s.followEvents(stream.Context(), drain, lastEventID, resume != "", listEventsSince, send)
+}
+
+// followEvents sends every event after lastEventID, a batch at a time, and
+// then each new one as it is published, until ctx is done or drain is
+// closed, when it sends a final RECONNECT event. replay reports whether the
+// first batch catches up a resumed stream, for the replay depth metric.
+func (s *GRPCServer) followEvents(ctx context.Context, drain <-chan struct{}, lastEventID int64, replay bool, listEventsSince func(context.Context, int64) ([]repository.FlagEvent, error), send func(*flagspb.WatchFlagEvent) error) error {
+	sendBatch := func(replay bool) error {
 		events, err := listEventsSince(ctx, lastEventID)
 		if err != nil {
 			return toGRPCError(err)
 		}
+		if replay {
+			s.metrics.ObserveReplayDepth("grpc", len(events))
+		}
 
+		sent := 0
+		defer func() { s.metrics.AddStreamEventsSent("grpc", sent) }()
 		for _, event := range events {
 			lastEventID = event.EventID
 			watchEvent, ok := repositoryEventToProto(event)
@@ -313,33 +552,97 @@ func (s *GRPCServer) WatchFlag(req *flagspb.WatchFlagRequest, stream flagspb.Fla
 				continue
 			}
 
-			if err := stream.Send(watchEvent); err != nil {
+			if err := send(watchEvent); err != nil {
 				return err
 			}
+			sent++
 		}
 
 		return nil
 	}
 
-	if err := sendEvents(stream.Context()); err != nil {
-		return err
+	// sendEvents sends batches until there are none left to catch up on.
+	sendEvents := func(replay bool) error {
+		for {
+			previous := lastEventID
+			if err := sendBatch(replay); err != nil || lastEventID == previous {
+				return err
+			}
+			replay = false
+		}
 	}
 
-	ticker := time.NewTicker(s.streamPollInterval)
-	defer ticker.Stop()
+	waiter := newEventWaiter(s.service, s.streamPollInterval)
+	defer waiter.stop()
+
+	if err := sendEvents(replay); err != nil {
+		return err
+	}
 
 	for {
 		select {
-		case <-stream.Context().Done():
+		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			if err := sendEvents(stream.Context()); err != nil {
+		case <-drain:
+			return send(reconnectEvent(lastEventID))
+		case <-waiter.C():
+			waiter.rearm()
+			if err := sendEvents(false); err != nil {
 				return err
 			}
 		}
 	}
 }
 
+// formatResumeToken returns the WatchProject resume token for the position
+// after eventID.
+func formatResumeToken(eventID int64) string {
+	return strconv.FormatInt(eventID, 10)
+}
+
+func parseResumeToken(token string) (int64, error) {
+	eventID, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || eventID < 0 {
+		return 0, errors.New("invalid resume token")
+	}
+	return eventID, nil
+}
+
+// invalidRulesStatus reports each invalid rule as a BadRequest field
+// violation on "rules[i]" (or "shadow_rules[i]"), and in the message for clients that do not read
+// status details.
+func invalidRulesStatus(rulesErr *service.RulesError) error {
+	st := status.New(codes.InvalidArgument, rulesErr.Error())
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(rulesErr.Rules))
+	for i, ruleErr := range rulesErr.Rules {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       rulesField(rulesErr, ruleErr.Index),
+			Description: ruleErr.Reason,
+		}
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// invalidVariantsStatus reports variants that fail their flag's schema like
+// [invalidRulesStatus], with a violation on "variants/<variant>/<path>".
+func invalidVariantsStatus(variantsErr *service.VariantsError) error {
+	st := status.New(codes.InvalidArgument, variantsErr.Error())
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(variantsErr.Errors))
+	for i, schemaErr := range variantsErr.Errors {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       "variants" + schemaErr.Path,
+			Description: schemaErr.Message,
+		}
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
 func toGRPCError(err error) error {
 	if err == nil {
 		return nil
@@ -349,17 +652,29 @@ func toGRPCError(err error) error {
 		return err
 	}
 
+	var rulesErr *service.RulesError
+	var variantsErr *service.VariantsError
 	switch {
+	case errors.As(err, &rulesErr):
+		return invalidRulesStatus(rulesErr)
 	case errors.Is(err, service.ErrInvalidRules):
 		return status.Error(codes.InvalidArgument, "invalid rules")
+	case errors.As(err, &variantsErr):
+		return invalidVariantsStatus(variantsErr)
 	case errors.Is(err, service.ErrInvalidVariants):
 		return status.Error(codes.InvalidArgument, "invalid variants")
-	case errors.Is(err, service.ErrFlagKeyRequired):
+	case errors.Is(err, service.ErrInvalidVariantsSchema), errors.Is(err, service.ErrInvalidTags), errors.Is(err, service.ErrInvalidOwner):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrFlagKeyRequired), errors.Is(err, service.ErrFlagKeyPolicyViolation):
 		return status.Error(codes.InvalidArgument, err.Error())
 	case errors.Is(err, service.ErrProjectIDRequired):
 		return status.Error(codes.InvalidArgument, err.Error())
 	case errors.Is(err, service.ErrFlagNotFound):
 		return status.Error(codes.NotFound, "flag not found")
+	case errors.Is(err, service.ErrAdminKeyRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrAPIKeyQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, "api key quota exceeded")
 	case errors.Is(err, context.Canceled):
 		return status.Error(codes.Canceled, "request canceled")
 	case errors.Is(err, context.DeadlineExceeded):
@@ -369,6 +684,10 @@ func toGRPCError(err error) error {
 	}
 }
 
+// keysetPageTokenPrefix marks a ListFlags page token holding the key of the
+// last flag of the previous page, rather than an offset.
+const keysetPageTokenPrefix = "k:"
+
 func parseListPageToken(pageToken string, maxOffset int) (int, error) {
 	pageToken = strings.TrimSpace(pageToken)
 	if pageToken == "" {
@@ -383,18 +702,31 @@ func parseListPageToken(pageToken string, maxOffset int) (int, error) {
 	return offset, nil
 }
 
-func protoFlagToRepository(flag *flagspb.Flag) repository.Flag {
+func protoFlagToRepository(flag *flagspb.Flag) (repository.Flag, error) {
 	if flag == nil {
-		return repository.Flag{}
+		return repository.Flag{}, nil
 	}
 
+	var expiresAt *time.Time
+	if raw := strings.TrimSpace(flag.GetExpiresAt()); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return repository.Flag{}, status.Error(codes.InvalidArgument, "expires_at must be an RFC 3339 timestamp")
+		}
+		expiresAt = &parsed
+	}
+
+	// UpdateFlag replaces every field, so a flag without tags clears them.
 	return repository.Flag{
 		Key:         flag.GetKey(),
 		Description: flag.GetDescription(),
 		Enabled:     flag.GetEnabled(),
 		Variants:    append(json.RawMessage(nil), flag.GetVariantsJson()...),
 		Rules:       append(json.RawMessage(nil), flag.GetRulesJson()...),
-	}
+		Tags:        append([]string{}, flag.GetTags()...),
+		Owner:       flag.GetOwner(),
+		ExpiresAt:   expiresAt,
+	}, nil
 }
 
 func repositoryFlagToProto(flag repository.Flag) *flagspb.Flag {
@@ -404,9 +736,19 @@ func repositoryFlagToProto(flag repository.Flag) *flagspb.Flag {
 		Enabled:      flag.Enabled,
 		VariantsJson: append([]byte(nil), flag.Variants...),
 		RulesJson:    append([]byte(nil), flag.Rules...),
+		Tags:         append([]string(nil), flag.Tags...),
+		Owner:        flag.Owner,
+		ExpiresAt:    formatExpiresAt(flag.ExpiresAt),
 	}
 }
 
+func formatExpiresAt(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.UTC().Format(time.RFC3339)
+}
+
 func decodeEvaluationContext(payload []byte) (core.EvaluationContext, error) {
 	if len(payload) == 0 {
 		return core.EvaluationContext{}, nil
@@ -420,6 +762,46 @@ func decodeEvaluationContext(payload []byte) (core.EvaluationContext, error) {
 	return evalContext, nil
 }
 
+func reasonToProto(reason core.Reason) flagspb.EvaluationReason {
+	switch reason {
+	case core.ReasonRuleMatch:
+		return flagspb.EvaluationReason_RULE_MATCH
+	case core.ReasonDefault:
+		return flagspb.EvaluationReason_DEFAULT
+	case core.ReasonDisabled:
+		return flagspb.EvaluationReason_DISABLED
+	case core.ReasonFlagNotFound:
+		return flagspb.EvaluationReason_FLAG_NOT_FOUND
+	case core.ReasonTargetMatch:
+		return flagspb.EvaluationReason_TARGET_MATCH
+	default:
+		return flagspb.EvaluationReason_EVALUATION_REASON_UNSPECIFIED
+	}
+}
+
+func ruleIndexToProto(ruleIndex *int) *int32 {
+	if ruleIndex == nil {
+		return nil
+	}
+	index := int32(*ruleIndex)
+	return &index
+}
+
+func contextWarningsToProto(warnings []service.ContextWarning) []*flagspb.ContextWarning {
+	if len(warnings) == 0 {
+		return nil
+	}
+	out := make([]*flagspb.ContextWarning, 0, len(warnings))
+	for _, warning := range warnings {
+		out = append(out, &flagspb.ContextWarning{
+			Attribute: warning.Attribute,
+			Kind:      warning.Kind,
+			Message:   warning.Message,
+		})
+	}
+	return out
+}
+
 func repositoryEventToProto(event repository.FlagEvent) (*flagspb.WatchFlagEvent, bool) {
 	watchEventType, ok := toProtoWatchEventType(event.EventType)
 	if !ok {
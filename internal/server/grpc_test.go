@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"slices"
 	"testing"
 	"time"
 
 	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
 	"github.com/matt-riley/flagz/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -79,6 +82,39 @@ func TestGRPCServerCreateFlag(t *testing.T) {
 		}
 	})
 
+	t.Run("reports each invalid rule as a field violation", func(t *testing.T) {
+		svc := &fakeService{
+			createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
+				return repository.Flag{}, &service.RulesError{Rules: []core.RuleError{
+					{Index: 1, Reason: "attribute is required"},
+				}}
+			},
+		}
+		grpcServer := NewGRPCServer(svc)
+
+		_, err := grpcServer.CreateFlag(ctxWithProject(), &flagspb.CreateFlagRequest{
+			Flag: &flagspb.Flag{Key: "new-ui"},
+		})
+		st := status.Convert(err)
+		if st.Code() != codes.InvalidArgument {
+			t.Fatalf("CreateFlag() code = %v, want %v", st.Code(), codes.InvalidArgument)
+		}
+		if want := "invalid rules: rule 1: attribute is required"; st.Message() != want {
+			t.Fatalf("CreateFlag() message = %q, want %q", st.Message(), want)
+		}
+		details := st.Details()
+		if len(details) != 1 {
+			t.Fatalf("CreateFlag() details = %v, want one BadRequest", details)
+		}
+		badRequest, ok := details[0].(*errdetails.BadRequest)
+		if !ok || len(badRequest.GetFieldViolations()) != 1 {
+			t.Fatalf("CreateFlag() details = %v, want one field violation", details)
+		}
+		if v := badRequest.GetFieldViolations()[0]; v.GetField() != "rules[1]" || v.GetDescription() != "attribute is required" {
+			t.Fatalf("field violation = %v, want rules[1]: attribute is required", v)
+		}
+	})
+
 	t.Run("maps invalid variants errors to invalid argument", func(t *testing.T) {
 		svc := &fakeService{
 			createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
@@ -182,6 +218,98 @@ func TestGRPCServerUpdateFlag(t *testing.T) {
 	})
 }
 
+func TestGRPCServerResolveIncludesReason(t *testing.T) {
+	ruleIndex := 2
+	svc := &fakeService{
+		resolveBooleanDetailFunc: func(_ context.Context, _ string, key string, _ core.EvaluationContext, _ bool) (service.ResolveResult, error) {
+			return service.ResolveResult{Key: key, Value: true, Reason: core.ReasonRuleMatch, RuleIndex: &ruleIndex}, nil
+		},
+		resolveBatchFunc: func(_ context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			return []service.ResolveResult{{
+				Key:      requests[0].Key,
+				Value:    false,
+				Reason:   core.ReasonFlagNotFound,
+				Warnings: []service.ContextWarning{{Attribute: "county", Kind: service.ContextWarningUnknownAttribute, Message: `unknown attribute "county"`}},
+			}}, nil
+		},
+	}
+	grpcServer := NewGRPCServer(svc)
+
+	resp, err := grpcServer.ResolveBoolean(ctxWithProject(), &flagspb.ResolveBooleanRequest{Key: "new-ui"})
+	if err != nil {
+		t.Fatalf("ResolveBoolean() error = %v", err)
+	}
+	if resp.GetReason() != flagspb.EvaluationReason_RULE_MATCH || resp.RuleIndex == nil || resp.GetRuleIndex() != 2 {
+		t.Fatalf("ResolveBoolean() = %v, want RULE_MATCH with rule_index 2", resp)
+	}
+
+	batch, err := grpcServer.ResolveBatch(ctxWithProject(), &flagspb.ResolveBatchRequest{
+		Requests: []*flagspb.ResolveBooleanRequest{{Key: "missing"}},
+	})
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
+	}
+	if got := batch.GetResults()[0]; got.GetReason() != flagspb.EvaluationReason_FLAG_NOT_FOUND || got.RuleIndex != nil {
+		t.Fatalf("ResolveBatch() result = %v, want FLAG_NOT_FOUND without rule_index", got)
+	}
+	if warnings := batch.GetResults()[0].GetWarnings(); len(warnings) != 1 || warnings[0].GetAttribute() != "county" || warnings[0].GetKind() != "unknown_attribute" {
+		t.Fatalf("ResolveBatch() warnings = %v, want the unknown county attribute", warnings)
+	}
+}
+
+func TestGRPCServerResolveAll(t *testing.T) {
+	svc := &fakeService{
+		resolveAllFunc: func(_ context.Context, _ string, evalContext core.EvaluationContext, _ string) ([]service.ResolveResult, error) {
+			warnings := []service.ContextWarning{{Attribute: "county", Kind: service.ContextWarningUnknownAttribute}}
+			return []service.ResolveResult{
+				{Key: "beta", Value: evalContext.Attributes["county"] == "US", Warnings: warnings},
+				{Key: "checkout", Value: false, Warnings: warnings},
+			}, nil
+		},
+	}
+	grpcServer := NewGRPCServer(svc)
+
+	resp, err := grpcServer.ResolveAll(ctxWithProject(), &flagspb.ResolveAllRequest{
+		ContextJson: []byte(`{"attributes":{"county":"US"}}`),
+	})
+	if err != nil {
+		t.Fatalf("ResolveAll() error = %v", err)
+	}
+	if values := resp.GetValues(); len(values) != 2 || !values["beta"] || values["checkout"] {
+		t.Fatalf("ResolveAll() values = %v, want beta on and checkout off", values)
+	}
+	if warnings := resp.GetWarnings(); len(warnings) != 1 || warnings[0].GetAttribute() != "county" {
+		t.Fatalf("ResolveAll() warnings = %v, want the county warning once", warnings)
+	}
+
+	_, err = grpcServer.ResolveAll(ctxWithProject(), &flagspb.ResolveAllRequest{ContextJson: []byte(`{`)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ResolveAll() with bad context code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestGRPCServerAPIKeyQuotaExceeded(t *testing.T) {
+	svc := &fakeService{
+		allowAPIKeyUsageFunc: func(_ context.Context, _, _ string, kind service.UsageKind) error {
+			if kind == service.UsageEvaluation {
+				return service.ErrAPIKeyQuotaExceeded
+			}
+			return nil
+		},
+		resolveBooleanDetailFunc: func(_ context.Context, _, _ string, _ core.EvaluationContext, _ bool) (service.ResolveResult, error) {
+			t.Fatal("ResolveBooleanDetail should not be called over quota")
+			return service.ResolveResult{}, nil
+		},
+	}
+	grpcServer := NewGRPCServer(svc)
+	ctx := middleware.NewContextWithAPIKeyID(ctxWithProject(), "key-1")
+
+	_, err := grpcServer.ResolveBoolean(ctx, &flagspb.ResolveBooleanRequest{Key: "new-ui"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("ResolveBoolean() code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+}
+
 func TestGRPCServerListFlagsPagination(t *testing.T) {
 	svc := &fakeService{
 		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
@@ -220,15 +348,15 @@ func TestGRPCServerListFlagsPagination(t *testing.T) {
 		if resp.GetFlags()[0].GetKey() != "a" || resp.GetFlags()[1].GetKey() != "b" {
 			t.Fatalf("ListFlags() keys = [%q %q], want [a b]", resp.GetFlags()[0].GetKey(), resp.GetFlags()[1].GetKey())
 		}
-		if resp.GetNextPageToken() != "2" {
-			t.Fatalf("ListFlags() next_page_token = %q, want %q", resp.GetNextPageToken(), "2")
+		if resp.GetNextPageToken() != "k:b" {
+			t.Fatalf("ListFlags() next_page_token = %q, want %q", resp.GetNextPageToken(), "k:b")
 		}
 	})
 
 	t.Run("uses page token to return next page", func(t *testing.T) {
 		resp, err := grpcServer.ListFlags(ctxWithProject(), &flagspb.ListFlagsRequest{
 			PageSize:  2,
-			PageToken: "2",
+			PageToken: "k:b",
 		})
 		if err != nil {
 			t.Fatalf("ListFlags() error = %v", err)
@@ -244,6 +372,19 @@ func TestGRPCServerListFlagsPagination(t *testing.T) {
 		}
 	})
 
+	t.Run("accepts offset page tokens from older servers", func(t *testing.T) {
+		resp, err := grpcServer.ListFlags(ctxWithProject(), &flagspb.ListFlagsRequest{
+			PageSize:  1,
+			PageToken: "1",
+		})
+		if err != nil {
+			t.Fatalf("ListFlags() error = %v", err)
+		}
+		if len(resp.GetFlags()) != 1 || resp.GetFlags()[0].GetKey() != "b" || resp.GetNextPageToken() != "k:b" {
+			t.Fatalf("ListFlags() = %v, want flag b and a keyset token after it", resp)
+		}
+	})
+
 	t.Run("rejects invalid page token", func(t *testing.T) {
 		_, err := grpcServer.ListFlags(ctxWithProject(), &flagspb.ListFlagsRequest{
 			PageSize:  1,
@@ -264,6 +405,63 @@ func TestGRPCServerListFlagsPagination(t *testing.T) {
 	})
 }
 
+func TestGRPCServerListFlagsByTag(t *testing.T) {
+	svc := &fakeService{
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			return []repository.Flag{
+				{Key: "a", Tags: []string{"checkout"}},
+				{Key: "b", Tags: []string{"marketing"}},
+				{Key: "c", Tags: []string{"checkout", "team:payments"}},
+			}, nil
+		},
+	}
+	grpcServer := NewGRPCServer(svc)
+
+	resp, err := grpcServer.ListFlags(ctxWithProject(), &flagspb.ListFlagsRequest{Tags: []string{"checkout"}, PageSize: 1})
+	if err != nil {
+		t.Fatalf("ListFlags() error = %v", err)
+	}
+	if len(resp.GetFlags()) != 1 || resp.GetFlags()[0].GetKey() != "a" || resp.GetNextPageToken() != "k:a" {
+		t.Fatalf("ListFlags() = %v, want flag a and a next page", resp)
+	}
+	resp, err = grpcServer.ListFlags(ctxWithProject(), &flagspb.ListFlagsRequest{Tags: []string{"checkout"}, PageSize: 1, PageToken: resp.GetNextPageToken()})
+	if err != nil {
+		t.Fatalf("ListFlags() page 2 error = %v", err)
+	}
+	if len(resp.GetFlags()) != 1 || resp.GetFlags()[0].GetKey() != "c" || resp.GetNextPageToken() != "" {
+		t.Fatalf("ListFlags() page 2 = %v, want only flag c", resp)
+	}
+	if got := resp.GetFlags()[0].GetTags(); !slices.Equal(got, []string{"checkout", "team:payments"}) {
+		t.Fatalf("tags = %v, want [checkout team:payments]", got)
+	}
+}
+
+func TestGRPCServerCreateFlagOwnershipFields(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			return flag, nil
+		},
+	}
+	grpcServer := NewGRPCServer(svc)
+
+	resp, err := grpcServer.CreateFlag(ctxWithProject(), &flagspb.CreateFlagRequest{
+		Flag: &flagspb.Flag{Key: "spring-sale", Owner: "growth@example.com", ExpiresAt: "2026-03-31T12:00:00+02:00"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if resp.GetFlag().GetOwner() != "growth@example.com" || resp.GetFlag().GetExpiresAt() != "2026-03-31T10:00:00Z" {
+		t.Fatalf("CreateFlag() = %v, want the owner and the expiry in UTC", resp.GetFlag())
+	}
+
+	_, err = grpcServer.CreateFlag(ctxWithProject(), &flagspb.CreateFlagRequest{
+		Flag: &flagspb.Flag{Key: "spring-sale", ExpiresAt: "next tuesday"},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("CreateFlag(bad expires_at) code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
 func TestGRPCServerWatchFlagStartsFromLastEventID(t *testing.T) {
 	sinceCalls := make([]int64, 0)
 	keyCalls := make([]string, 0)
@@ -382,10 +580,142 @@ func TestGRPCServerWatchFlagWithoutKeyUsesUnfilteredEvents(t *testing.T) {
 	}
 }
 
+func TestGRPCServerWatchFlagSendsSDKConfigHeader(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctxWithProject())
+	cancel()
+	stream := &fakeWatchFlagServer{ctx: ctx}
+
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			return nil, nil
+		},
+	}
+	grpcServer := NewGRPCServerWithOptions(svc, time.Hour, nil, WithGRPCSDKConfig(SDKConfig{
+		PollInterval: 2 * time.Minute,
+		MaxBatchSize: 50,
+	}))
+
+	if err := grpcServer.WatchFlag(&flagspb.WatchFlagRequest{}, stream); err != nil {
+		t.Fatalf("WatchFlag() error = %v", err)
+	}
+	got := stream.header.Get(SDKConfigMetadataKey)
+	want := `{"poll_interval_ms":120000,"max_batch_size":50}`
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("%s header = %v, want [%s]", SDKConfigMetadataKey, got, want)
+	}
+}
+
+func TestGRPCServerWatchProjectSendsSnapshotThenChanges(t *testing.T) {
+	var sinceCalls []int64
+	ctx, cancel := context.WithCancel(ctxWithProject())
+	stream := &fakeWatchProjectServer{ctx: ctx, cancel: cancel, stopAfter: 2}
+
+	svc := &fakeService{
+		snapshotFunc: func(_ context.Context, projectID string) (service.ProjectSnapshot, error) {
+			return service.ProjectSnapshot{
+				EventID: 7,
+				Flags:   []repository.Flag{{ProjectID: projectID, Key: "a", Enabled: true}, {ProjectID: projectID, Key: "b"}},
+			}, nil
+		},
+		listEventsSinceFunc: func(_ context.Context, _ string, eventID int64) ([]repository.FlagEvent, error) {
+			sinceCalls = append(sinceCalls, eventID)
+			if eventID != 7 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{
+				EventID:   8,
+				FlagKey:   "b",
+				EventType: service.EventTypeUpdated,
+				Payload:   json.RawMessage(`{"key":"b","enabled":true}`),
+			}}, nil
+		},
+	}
+	grpcServer := NewGRPCServerWithStreamPollInterval(svc, time.Hour)
+
+	if err := grpcServer.WatchProject(&flagspb.WatchProjectRequest{}, stream); err != nil {
+		t.Fatalf("WatchProject() error = %v", err)
+	}
+	if len(stream.events) != 2 {
+		t.Fatalf("WatchProject() sent %d messages, want 2", len(stream.events))
+	}
+	snapshot := stream.events[0]
+	if flags := snapshot.GetSnapshot().GetFlags(); len(flags) != 2 || flags[0].GetKey() != "a" || !flags[0].GetEnabled() || snapshot.GetChange() != nil || snapshot.GetResumeToken() != "7" {
+		t.Fatalf("first message = %v, want a snapshot of a and b with resume token 7", snapshot)
+	}
+	change := stream.events[1]
+	if change.GetSnapshot() != nil || change.GetChange().GetKey() != "b" || !change.GetChange().GetFlag().GetEnabled() || change.GetResumeToken() != "8" {
+		t.Fatalf("second message = %v, want the change to b with resume token 8", change)
+	}
+	if len(sinceCalls) == 0 || sinceCalls[0] != 7 {
+		t.Fatalf("ListEventsSince calls = %v, want the first after the snapshot's event 7", sinceCalls)
+	}
+}
+
+func TestGRPCServerWatchProjectResumesWithoutSnapshot(t *testing.T) {
+	var sinceCalls []int64
+	ctx, cancel := context.WithCancel(ctxWithProject())
+	stream := &fakeWatchProjectServer{ctx: ctx, cancel: cancel, stopAfter: 1}
+
+	svc := &fakeService{
+		snapshotFunc: func(context.Context, string) (service.ProjectSnapshot, error) {
+			t.Error("Snapshot called when resuming")
+			return service.ProjectSnapshot{}, nil
+		},
+		listEventsSinceFunc: func(_ context.Context, _ string, eventID int64) ([]repository.FlagEvent, error) {
+			sinceCalls = append(sinceCalls, eventID)
+			if eventID != 8 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{EventID: 9, FlagKey: "a", EventType: service.EventTypeDeleted}}, nil
+		},
+	}
+	grpcServer := NewGRPCServerWithStreamPollInterval(svc, time.Hour)
+
+	if err := grpcServer.WatchProject(&flagspb.WatchProjectRequest{ResumeToken: "8"}, stream); err != nil {
+		t.Fatalf("WatchProject() error = %v", err)
+	}
+	if len(sinceCalls) == 0 || sinceCalls[0] != 8 {
+		t.Fatalf("ListEventsSince calls = %v, want the first after event 8", sinceCalls)
+	}
+	if len(stream.events) != 1 || stream.events[0].GetChange().GetType() != flagspb.WatchFlagEventType_FLAG_DELETED || stream.events[0].GetResumeToken() != "9" {
+		t.Fatalf("WatchProject() sent %v, want the delete of a with resume token 9", stream.events)
+	}
+
+	for _, token := range []string{"abc", "-1"} {
+		err := grpcServer.WatchProject(&flagspb.WatchProjectRequest{ResumeToken: token}, &fakeWatchProjectServer{ctx: ctxWithProject()})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("WatchProject(resume_token %q) code = %v, want %v", token, status.Code(err), codes.InvalidArgument)
+		}
+	}
+}
+
+type fakeWatchProjectServer struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stopAfter int
+	events    []*flagspb.WatchProjectEvent
+}
+
+func (f *fakeWatchProjectServer) Send(event *flagspb.WatchProjectEvent) error {
+	f.events = append(f.events, event)
+	if len(f.events) == f.stopAfter {
+		f.cancel()
+	}
+	return nil
+}
+
+func (f *fakeWatchProjectServer) SetHeader(metadata.MD) error  { return nil }
+func (f *fakeWatchProjectServer) SendHeader(metadata.MD) error { return nil }
+func (f *fakeWatchProjectServer) SetTrailer(metadata.MD)       {}
+func (f *fakeWatchProjectServer) Context() context.Context     { return f.ctx }
+func (f *fakeWatchProjectServer) SendMsg(any) error            { return nil }
+func (f *fakeWatchProjectServer) RecvMsg(any) error            { return io.EOF }
+
 type fakeWatchFlagServer struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 	events []*flagspb.WatchFlagEvent
+	header metadata.MD
 }
 
 func (f *fakeWatchFlagServer) Send(event *flagspb.WatchFlagEvent) error {
@@ -397,11 +727,13 @@ func (f *fakeWatchFlagServer) Send(event *flagspb.WatchFlagEvent) error {
 	return nil
 }
 
-func (f *fakeWatchFlagServer) SetHeader(metadata.MD) error {
+func (f *fakeWatchFlagServer) SetHeader(md metadata.MD) error {
+	f.header = metadata.Join(f.header, md)
 	return nil
 }
 
-func (f *fakeWatchFlagServer) SendHeader(metadata.MD) error {
+func (f *fakeWatchFlagServer) SendHeader(md metadata.MD) error {
+	f.header = metadata.Join(f.header, md)
 	return nil
 }
 
@@ -418,3 +750,173 @@ func (f *fakeWatchFlagServer) SendMsg(any) error {
 func (f *fakeWatchFlagServer) RecvMsg(any) error {
 	return io.EOF
 }
+
+// grpcProjectCalls invokes every project-scoped RPC with ctx.
+func grpcProjectCalls(grpcServer *GRPCServer) map[string]func(ctx context.Context) error {
+	flag := &flagspb.Flag{Key: "new-ui"}
+	return map[string]func(ctx context.Context) error{
+		"CreateFlag": func(ctx context.Context) error {
+			_, err := grpcServer.CreateFlag(ctx, &flagspb.CreateFlagRequest{Flag: flag})
+			return err
+		},
+		"UpdateFlag": func(ctx context.Context) error {
+			_, err := grpcServer.UpdateFlag(ctx, &flagspb.UpdateFlagRequest{Flag: flag})
+			return err
+		},
+		"GetFlag": func(ctx context.Context) error {
+			_, err := grpcServer.GetFlag(ctx, &flagspb.GetFlagRequest{Key: "new-ui"})
+			return err
+		},
+		"ListFlags": func(ctx context.Context) error {
+			_, err := grpcServer.ListFlags(ctx, &flagspb.ListFlagsRequest{})
+			return err
+		},
+		"DeleteFlag": func(ctx context.Context) error {
+			_, err := grpcServer.DeleteFlag(ctx, &flagspb.DeleteFlagRequest{Key: "new-ui"})
+			return err
+		},
+		"ResolveBoolean": func(ctx context.Context) error {
+			_, err := grpcServer.ResolveBoolean(ctx, &flagspb.ResolveBooleanRequest{Key: "new-ui"})
+			return err
+		},
+		"ResolveBatch": func(ctx context.Context) error {
+			_, err := grpcServer.ResolveBatch(ctx, &flagspb.ResolveBatchRequest{
+				Requests: []*flagspb.ResolveBooleanRequest{{Key: "new-ui"}},
+			})
+			return err
+		},
+		"ResolveAll": func(ctx context.Context) error {
+			_, err := grpcServer.ResolveAll(ctx, &flagspb.ResolveAllRequest{})
+			return err
+		},
+		"WatchFlag": func(ctx context.Context) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			return grpcServer.WatchFlag(&flagspb.WatchFlagRequest{Key: "new-ui", LastEventId: 1}, &fakeWatchFlagServer{ctx: ctx})
+		},
+		"WatchProject": func(ctx context.Context) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			return grpcServer.WatchProject(&flagspb.WatchProjectRequest{}, &fakeWatchProjectServer{ctx: ctx})
+		},
+	}
+}
+
+func TestGRPCServerRequiresProject(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(context.Context, repository.Flag) (repository.Flag, error) {
+			t.Error("CreateFlag reached the service")
+			return repository.Flag{}, nil
+		},
+		updateFlagFunc: func(context.Context, repository.Flag) (repository.Flag, error) {
+			t.Error("UpdateFlag reached the service")
+			return repository.Flag{}, nil
+		},
+		getFlagFunc: func(context.Context, string, string) (repository.Flag, error) {
+			t.Error("GetFlag reached the service")
+			return repository.Flag{}, nil
+		},
+		listFlagsFunc: func(context.Context, string) ([]repository.Flag, error) {
+			t.Error("ListFlags reached the service")
+			return nil, nil
+		},
+		deleteFlagFunc: func(context.Context, string, string) error {
+			t.Error("DeleteFlag reached the service")
+			return nil
+		},
+		resolveBooleanDetailFunc: func(context.Context, string, string, core.EvaluationContext, bool) (service.ResolveResult, error) {
+			t.Error("ResolveBooleanDetail reached the service")
+			return service.ResolveResult{}, nil
+		},
+		resolveBatchFunc: func(context.Context, []service.ResolveRequest) ([]service.ResolveResult, error) {
+			t.Error("ResolveBatch reached the service")
+			return nil, nil
+		},
+		resolveAllFunc: func(context.Context, string, core.EvaluationContext, string) ([]service.ResolveResult, error) {
+			t.Error("ResolveAll reached the service")
+			return nil, nil
+		},
+		listEventsSinceForKeyFunc: func(context.Context, string, int64, string) ([]repository.FlagEvent, error) {
+			t.Error("ListEventsSinceForKey reached the service")
+			return nil, nil
+		},
+		snapshotFunc: func(context.Context, string) (service.ProjectSnapshot, error) {
+			t.Error("Snapshot reached the service")
+			return service.ProjectSnapshot{}, nil
+		},
+	}
+	calls := grpcProjectCalls(NewGRPCServerWithStreamPollInterval(svc, time.Hour))
+
+	contexts := map[string]context.Context{
+		"no project":    context.Background(),
+		"empty project": middleware.NewContextWithProjectID(context.Background(), ""),
+	}
+	for ctxName, ctx := range contexts {
+		for name, call := range calls {
+			t.Run(ctxName+"/"+name, func(t *testing.T) {
+				if err := call(ctx); status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("%s() code = %v, want %v", name, status.Code(err), codes.Unauthenticated)
+				}
+			})
+		}
+	}
+}
+
+func TestGRPCServerScopesCallsToProject(t *testing.T) {
+	var got []string
+	record := func(projectID string) { got = append(got, projectID) }
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			record(flag.ProjectID)
+			return flag, nil
+		},
+		updateFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			record(flag.ProjectID)
+			return flag, nil
+		},
+		getFlagFunc: func(_ context.Context, projectID, key string) (repository.Flag, error) {
+			record(projectID)
+			return repository.Flag{Key: key}, nil
+		},
+		listFlagsFunc: func(_ context.Context, projectID string) ([]repository.Flag, error) {
+			record(projectID)
+			return nil, nil
+		},
+		deleteFlagFunc: func(_ context.Context, projectID, _ string) error {
+			record(projectID)
+			return nil
+		},
+		resolveBooleanDetailFunc: func(_ context.Context, projectID, key string, _ core.EvaluationContext, _ bool) (service.ResolveResult, error) {
+			record(projectID)
+			return service.ResolveResult{Key: key}, nil
+		},
+		resolveBatchFunc: func(_ context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			results := make([]service.ResolveResult, len(requests))
+			for i, request := range requests {
+				record(request.ProjectID)
+				results[i].Key = request.Key
+			}
+			return results, nil
+		},
+		resolveAllFunc: func(_ context.Context, projectID string, _ core.EvaluationContext, _ string) ([]service.ResolveResult, error) {
+			record(projectID)
+			return nil, nil
+		},
+		listEventsSinceForKeyFunc: func(ctx context.Context, projectID string, _ int64, _ string) ([]repository.FlagEvent, error) {
+			record(projectID)
+			return nil, context.Canceled
+		},
+		snapshotFunc: func(_ context.Context, projectID string) (service.ProjectSnapshot, error) {
+			record(projectID)
+			return service.ProjectSnapshot{}, context.Canceled
+		},
+	}
+	ctx := middleware.NewContextWithProjectID(context.Background(), "acme")
+	for name, call := range grpcProjectCalls(NewGRPCServerWithStreamPollInterval(svc, time.Hour)) {
+		got = nil
+		_ = call(ctx)
+		if len(got) != 1 || got[0] != "acme" {
+			t.Errorf("%s() reached the service with projects %q, want [acme]", name, got)
+		}
+	}
+}
@@ -8,12 +8,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/instance"
+	"github.com/matt-riley/flagz/internal/jsonschema"
 	"github.com/matt-riley/flagz/internal/metrics"
 	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
@@ -23,6 +28,10 @@ import (
 const (
 	defaultStreamPollInterval = time.Second
 	maxJSONBodyBytes          = 1 << 20
+	maxEvaluateBodyBytes      = 256 << 10
+	maxImportBodyBytes        = 32 << 20
+	// maxStreamFilterKeys caps the key parameters of one stream request.
+	maxStreamFilterKeys = 100
 )
 
 var errJSONBodyTooLarge = errors.New("json request body too large")
@@ -33,27 +42,63 @@ type HTTPServer struct {
 	service            Service
 	metrics            *metrics.Metrics
 	metricsHandler     http.Handler
-	streamPollInterval time.Duration
+	streamPollInterval *PollInterval
 	maxJSONBodyBytes   int64
+	maxEvaluateBytes   int64
+	maxImportBytes     int64
+	readinessCheck     func(context.Context) service.Readiness
+	sdkConfigHeader    string
+	heartbeatInterval  time.Duration
+	keepaliveInterval  time.Duration
+	evaluateTimeout    time.Duration
+	importTimeout      time.Duration
+	streamWriteTimeout time.Duration
+	streamLimiter      *StreamLimiter
+	streamDrainer      *StreamDrainer
+	evaluationLimiter  *EvaluationLimiter
+	identity           instance.Identity
 }
 
 type evaluateJSONRequest struct {
 	Key          string                  `json:"key,omitempty"`
 	Context      core.EvaluationContext  `json:"context,omitempty"`
-	DefaultValue bool                    `json:"default_value,omitempty"`
+	Preset       string                  `json:"preset,omitempty"`
+	DefaultValue json.RawMessage         `json:"default_value,omitempty"`
 	Requests     []evaluateJSONBatchItem `json:"requests,omitempty"`
 }
 
 type evaluateJSONBatchItem struct {
 	Key          string                 `json:"key"`
 	Context      core.EvaluationContext `json:"context"`
-	DefaultValue bool                   `json:"default_value"`
+	Preset       string                 `json:"preset"`
+	DefaultValue json.RawMessage        `json:"default_value"`
 }
 
 type evaluateJSONResponse struct {
 	Results []service.ResolveResult `json:"results"`
 }
 
+type evaluateAllJSONRequest struct {
+	Context core.EvaluationContext `json:"context,omitempty"`
+	Preset  string                 `json:"preset,omitempty"`
+}
+
+type evaluateAllJSONResponse struct {
+	Values   map[string]bool          `json:"values"`
+	Warnings []service.ContextWarning `json:"warnings,omitempty"`
+}
+
+type proposalJSONRequest struct {
+	Action string          `json:"action,omitempty"`
+	Flag   json.RawMessage `json:"flag,omitempty"`
+}
+
+type staleFlagsResponse struct {
+	NotEvaluatedDays int                 `json:"not_evaluated_days"`
+	NotModifiedDays  int                 `json:"not_modified_days"`
+	Flags            []service.StaleFlag `json:"flags"`
+}
+
 type paginatedFlagsResponse struct {
 	Flags      []repository.Flag `json:"flags"`
 	NextCursor string            `json:"next_cursor,omitempty"`
@@ -78,6 +123,39 @@ func WithMaxJSONBodySize(size int64) HTTPOption {
 	}
 }
 
+// WithMaxEvaluateBodySize sets the maximum allowed request body size in
+// bytes for POST /v1/evaluate. Evaluation requests are small, so this is kept
+// well below the general limit. Defaults to 256KB if not set or if size <= 0.
+func WithMaxEvaluateBodySize(size int64) HTTPOption {
+	return func(s *HTTPServer) {
+		if size > 0 {
+			s.maxEvaluateBytes = size
+		}
+	}
+}
+
+// WithMaxImportBodySize sets the maximum allowed request body size in bytes
+// for POST /v1/flags/import and POST /v1/flags:batch. Imports are streamed,
+// so the body is never held in memory at once; batches are decoded whole but
+// limited to 500 flags. Defaults to 32MB if not set or if size <= 0.
+func WithMaxImportBodySize(size int64) HTTPOption {
+	return func(s *HTTPServer) {
+		if size > 0 {
+			s.maxImportBytes = size
+		}
+	}
+}
+
+// WithReadinessCheck sets the function consulted by GET /readyz. A non-nil
+// [service.Readiness.Err] marks the server not ready; it and the component
+// statuses are reported in the response body. Without a check, /readyz
+// always reports ready.
+func WithReadinessCheck(check func(context.Context) service.Readiness) HTTPOption {
+	return func(s *HTTPServer) {
+		s.readinessCheck = check
+	}
+}
+
 // NewHTTPHandlerWithStreamPollInterval returns an [http.Handler] wired with all
 // flagz routes using the specified stream poll interval for SSE.
 //
@@ -107,8 +185,13 @@ func NewHTTPHandlerWithOptions(svc Service, streamPollInterval time.Duration, m
 		service:            svc,
 		metrics:            m,
 		metricsHandler:     m.Handler(),
-		streamPollInterval: streamPollInterval,
+		streamPollInterval: NewPollInterval(streamPollInterval),
 		maxJSONBodyBytes:   maxJSONBodyBytes,
+		maxEvaluateBytes:   maxEvaluateBodyBytes,
+		maxImportBytes:     maxImportBodyBytes,
+		evaluateTimeout:    defaultEvaluateTimeout,
+		importTimeout:      defaultImportTimeout,
+		identity:           instance.New(""),
 	}
 
 	for _, opt := range opts {
@@ -118,19 +201,78 @@ func NewHTTPHandlerWithOptions(svc Service, streamPollInterval time.Duration, m
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /v1/flags", server.handleCreateFlag)
 	mux.HandleFunc("GET /v1/flags", server.handleListFlags)
+	mux.HandleFunc("POST /v1/flags:batch", withTimeout(server.importTimeout, server.handleBatchCreateFlags))
+	mux.HandleFunc("POST /v1/flags/import", withTimeout(server.importTimeout, server.handleImportFlags))
+	mux.HandleFunc("POST /v1/flags/from-template", server.handleCreateFlagFromTemplate)
+	mux.HandleFunc("GET /v1/flags/stale", server.handleStaleFlags)
+	mux.HandleFunc("GET /v1/projects/{id}/graph", server.handleProjectGraph)
 	mux.HandleFunc("GET /v1/flags/{key}", server.handleGetFlag)
 	mux.HandleFunc("PUT /v1/flags/{key}", server.handleUpdateFlag)
+	mux.HandleFunc("PATCH /v1/flags/{key}", server.handlePatchFlag)
 	mux.HandleFunc("DELETE /v1/flags/{key}", server.handleDeleteFlag)
-	mux.HandleFunc("POST /v1/evaluate", server.handleEvaluate)
+	mux.HandleFunc("POST /v1/flags/{key}/reshuffle", server.handleReshuffleFlag)
+	mux.HandleFunc("GET /v1/flags/{key}/bucket", server.handleFlagBucket)
+	mux.HandleFunc("GET /v1/flags/{key}/stats", server.handleFlagStats)
+	mux.HandleFunc("GET /v1/flags/{key}/history", server.handleFlagHistory)
+	mux.HandleFunc("POST /v1/flags/{key}/revert/{revision}", server.handleRevertFlag)
+	mux.HandleFunc("PUT /v1/flags/{key}/targets", server.handleSetFlagTargets)
+	mux.HandleFunc("POST /v1/flags/{key}/copy", server.handleCopyFlag)
+	mux.HandleFunc("POST /v1/flags/{key}/proposals", server.handleCreateProposal)
+	mux.HandleFunc("GET /v1/flags/{key}/proposals", server.handleListFlagProposals)
+	mux.HandleFunc("GET /v1/proposals", server.handleListProposals)
+	mux.HandleFunc("POST /v1/proposals/{id}/approve", server.handleApproveProposal)
+	mux.HandleFunc("POST /v1/proposals/{id}/reject", server.handleRejectProposal)
+	mux.HandleFunc("GET /v1/flag-templates", server.handleListFlagTemplates)
+	mux.HandleFunc("GET /v1/flag-defaults", server.handleGetFlagDefaults)
+	mux.HandleFunc("PUT /v1/flag-defaults", server.handleSetFlagDefaults)
+	mux.HandleFunc("GET /v1/flag-key-policy", server.handleGetFlagKeyPolicy)
+	mux.HandleFunc("PUT /v1/flag-key-policy", server.handleSetFlagKeyPolicy)
+	mux.HandleFunc("GET /v1/context-presets", server.handleListContextPresets)
+	mux.HandleFunc("GET /v1/context-presets/{name}", server.handleGetContextPreset)
+	mux.HandleFunc("PUT /v1/context-presets/{name}", server.handlePutContextPreset)
+	mux.HandleFunc("DELETE /v1/context-presets/{name}", server.handleDeleteContextPreset)
+	mux.HandleFunc("GET /v1/segments", server.handleListSegments)
+	mux.HandleFunc("GET /v1/segments/{name}", server.handleGetSegment)
+	mux.HandleFunc("PUT /v1/segments/{name}", server.handlePutSegment)
+	mux.HandleFunc("DELETE /v1/segments/{name}", server.handleDeleteSegment)
+	mux.HandleFunc("GET /v1/notifications/channels", server.handleListNotificationChannels)
+	mux.HandleFunc("POST /v1/notifications/channels", server.handleCreateNotificationChannel)
+	mux.HandleFunc("DELETE /v1/notifications/channels/{id}", server.handleDeleteNotificationChannel)
+	mux.HandleFunc("GET /v1/notifications", server.handleListNotifications)
+	mux.HandleFunc("GET /v1/webhooks/signing-secret", server.handleGetWebhookSigningSecret)
+	mux.HandleFunc("POST /v1/webhooks/signing-secret", server.handleRotateWebhookSigningSecret)
+	mux.HandleFunc("GET /v1/context-schema", server.handleGetContextSchema)
+	mux.HandleFunc("PUT /v1/context-schema", server.handleSetContextSchema)
+	mux.HandleFunc("GET /v1/context-enrichment", server.handleGetContextEnrichment)
+	mux.HandleFunc("PUT /v1/context-enrichment", server.handleSetContextEnrichment)
+	mux.HandleFunc("POST /v1/evaluate", withTimeout(server.evaluateTimeout, server.limitEvaluations(server.handleEvaluate)))
+	mux.HandleFunc("POST /v1/evaluate/all", withTimeout(server.evaluateTimeout, server.limitEvaluations(server.handleEvaluateAll)))
+	mux.HandleFunc("POST /v1/evaluate/explain", withTimeout(server.evaluateTimeout, server.limitEvaluations(server.handleExplain)))
+	mux.HandleFunc("GET /v1/sdk/config", server.handleSDKConfig)
 	mux.HandleFunc("GET /v1/stream", server.handleStream)
+	mux.HandleFunc("GET /v1/events", server.handleListEvents)
+	mux.HandleFunc("GET /v1/admin/stream", server.handleAdminStream)
+	mux.HandleFunc("GET /v1/admin/export/{dataset}", server.handleAdminExport)
+	mux.HandleFunc("GET /v1/admin/backup", server.handleAdminBackup)
+	mux.HandleFunc("POST /v1/admin/restore", server.handleAdminRestore)
 	mux.HandleFunc("POST /v1/api-keys", server.handleCreateAPIKey)
 	mux.HandleFunc("GET /v1/api-keys", server.handleListAPIKeys)
 	mux.HandleFunc("DELETE /v1/api-keys/{id}", server.handleDeleteAPIKey)
+	mux.HandleFunc("GET /v1/api-keys/rotation-policy", server.handleGetKeyRotationPolicy)
+	mux.HandleFunc("PUT /v1/api-keys/rotation-policy", server.handleSetKeyRotationPolicy)
+	mux.HandleFunc("GET /v1/api-keys/quota", server.handleGetAPIKeyQuota)
+	mux.HandleFunc("PUT /v1/api-keys/quota", server.handleSetAPIKeyQuota)
+	mux.HandleFunc("GET /v1/usage", server.handleUsage)
 	mux.HandleFunc("GET /v1/audit-log", server.handleListAuditLog)
+	mux.HandleFunc("GET /v1/openapi.json", server.handleOpenAPI)
+	mux.HandleFunc("GET /v1/schema", server.handleSchema)
+	mux.HandleFunc("GET /v1/info", server.handleInfo)
+	mux.HandleFunc("GET /v1/proto/descriptor", server.handleProtoDescriptor)
 	mux.HandleFunc("GET /healthz", server.handleHealthz)
+	mux.HandleFunc("GET /readyz", server.handleReadyz)
 	mux.HandleFunc("GET /metrics", server.handleMetrics)
 
-	return server.withMetrics(mux)
+	return server.withMetrics(server.withIdempotency(server.withUsage(mux)))
 }
 
 func (s *HTTPServer) withMetrics(next http.Handler) http.Handler {
@@ -143,6 +285,9 @@ func (s *HTTPServer) withMetrics(next http.Handler) http.Handler {
 		status := strconv.Itoa(rw.statusCode)
 		s.metrics.HTTPRequestsTotal.WithLabelValues(r.Method, route, status).Inc()
 		s.metrics.HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+		if rw.statusCode == http.StatusRequestEntityTooLarge {
+			s.metrics.HTTPBodyTooLarge.WithLabelValues(route).Inc()
+		}
 	})
 }
 
@@ -188,18 +333,18 @@ func routePattern(r *http.Request) string {
 func (s *HTTPServer) handleCreateFlag(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	var flag repository.Flag
 	if err := s.decodeJSONBody(w, r, &flag); err != nil {
-		writeJSONDecodeError(w, err)
+		writeJSONDecodeError(w, r, err)
 		return
 	}
 
 	if strings.TrimSpace(flag.Key) == "" {
-		writeJSONError(w, http.StatusBadRequest, "key is required")
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
 		return
 	}
 
@@ -208,7 +353,7 @@ func (s *HTTPServer) handleCreateFlag(w http.ResponseWriter, r *http.Request) {
 
 	created, err := s.service.CreateFlag(r.Context(), flag)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -218,19 +363,19 @@ func (s *HTTPServer) handleCreateFlag(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleGetFlag(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	key := strings.TrimSpace(r.PathValue("key"))
 	if key == "" {
-		writeJSONError(w, http.StatusBadRequest, "key is required")
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
 		return
 	}
 
 	flag, err := s.service.GetFlag(r.Context(), projectID, key)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -240,10 +385,12 @@ func (s *HTTPServer) handleGetFlag(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleListFlags(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
+	s.setSDKConfigHeader(w)
+
 	query := r.URL.Query()
 	cursor := strings.TrimSpace(query.Get("cursor"))
 	_, cursorProvided := query["cursor"]
@@ -253,12 +400,12 @@ func (s *HTTPServer) handleListFlags(w http.ResponseWriter, r *http.Request) {
 	if limitProvided {
 		l := strings.TrimSpace(query.Get("limit"))
 		if l == "" {
-			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			writeJSONError(w, r, http.StatusBadRequest, "limit must be a positive integer")
 			return
 		}
 		parsedLimit, err := strconv.Atoi(l)
 		if err != nil || parsedLimit < 1 {
-			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			writeJSONError(w, r, http.StatusBadRequest, "limit must be a positive integer")
 			return
 		}
 		limit = parsedLimit
@@ -267,15 +414,63 @@ func (s *HTTPServer) handleListFlags(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	flags, err := s.service.ListFlags(r.Context(), projectID)
+	switch query.Get("format") {
+	case "", "json":
+	case "ndjson":
+		s.writeFlagsNDJSON(w, r, projectID, query)
+		return
+	default:
+		writeJSONError(w, r, http.StatusBadRequest, "format must be json or ndjson")
+		return
+	}
+
+	_, filtered := query["references_attribute"]
+	paginated := cursorProvided || limitProvided
+
+	// Pages of the unfiltered listing are read from the repository one at
+	// a time rather than sliced from every flag of the project.
+	if paginated && !filtered {
+		flags, nextCursor, err := s.service.ListFlagsPage(r.Context(), repository.FlagPageQuery{
+			ProjectID: projectID,
+			AfterKey:  cursor,
+			Tags:      query["tag"],
+			Limit:     limit,
+		})
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, paginatedFlagsResponse{
+			Flags:      flags,
+			NextCursor: nextCursor,
+		})
+		return
+	}
+
+	var (
+		flags []repository.Flag
+		err   error
+	)
+	if filtered {
+		attr := strings.TrimSpace(query.Get("references_attribute"))
+		if attr == "" {
+			writeJSONError(w, r, http.StatusBadRequest, "references_attribute must not be empty")
+			return
+		}
+		flags, err = s.service.ListFlagsReferencingAttribute(r.Context(), projectID, attr)
+	} else {
+		flags, err = s.service.ListFlags(r.Context(), projectID)
+	}
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
+	// Repeated tag parameters narrow the list to flags carrying all of them.
+	flags = service.FilterFlagsByTags(flags, query["tag"])
 
 	// Apply cursor-based pagination when either parameter is provided.
-	if cursorProvided || limitProvided {
-		// Service.ListFlags returns flags sorted by key.
+	if paginated {
+		// Service.ListFlagsReferencingAttribute returns flags sorted by key.
 		if cursor != "" {
 			idx := sort.Search(len(flags), func(i int) bool { return flags[i].Key > cursor })
 			flags = flags[idx:]
@@ -306,24 +501,24 @@ func (s *HTTPServer) handleListFlags(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleUpdateFlag(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	key := strings.TrimSpace(r.PathValue("key"))
 	if key == "" {
-		writeJSONError(w, http.StatusBadRequest, "key is required")
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
 		return
 	}
 
 	var flag repository.Flag
 	if err := s.decodeJSONBody(w, r, &flag); err != nil {
-		writeJSONDecodeError(w, err)
+		writeJSONDecodeError(w, r, err)
 		return
 	}
 
 	if strings.TrimSpace(flag.Key) != "" && flag.Key != key {
-		writeJSONError(w, http.StatusBadRequest, "path key and body key must match")
+		writeJSONError(w, r, http.StatusBadRequest, "path key and body key must match")
 		return
 	}
 	flag.Key = key
@@ -331,7 +526,7 @@ func (s *HTTPServer) handleUpdateFlag(w http.ResponseWriter, r *http.Request) {
 
 	updated, err := s.service.UpdateFlag(r.Context(), flag)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -341,108 +536,499 @@ func (s *HTTPServer) handleUpdateFlag(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleDeleteFlag(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	key := strings.TrimSpace(r.PathValue("key"))
 	if key == "" {
-		writeJSONError(w, http.StatusBadRequest, "key is required")
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
 		return
 	}
 
 	if err := s.service.DeleteFlag(r.Context(), projectID, key); err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (s *HTTPServer) handleReshuffleFlag(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	flag, err := s.service.ReshuffleFlag(r.Context(), projectID, key)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
+
+func (s *HTTPServer) handleFlagBucket(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	targetingKey := r.URL.Query().Get("targeting_key")
+	if targetingKey == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "targeting_key is required")
+		return
+	}
+
+	assignment, err := s.service.BucketFor(r.Context(), projectID, key, targetingKey)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, assignment)
+}
+
+func (s *HTTPServer) handleFlagStats(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	stats, err := s.service.GetFlagStats(r.Context(), projectID, key)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleStaleFlags reports stale flags. The not_evaluated_days and
+// not_modified_days query parameters override the configured thresholds.
+func (s *HTTPServer) handleStaleFlags(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var thresholds service.StaleThresholds
+	for _, param := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"not_evaluated_days", &thresholds.NotEvaluatedFor},
+		{"not_modified_days", &thresholds.NotModifiedFor},
+	} {
+		value := strings.TrimSpace(r.URL.Query().Get(param.name))
+		if value == "" {
+			continue
+		}
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			writeJSONError(w, r, http.StatusBadRequest, param.name+" must be a positive integer")
+			return
+		}
+		*param.dst = time.Duration(days) * 24 * time.Hour
+	}
+
+	report, err := s.service.StaleFlags(r.Context(), projectID, thresholds)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, staleFlagsResponse{
+		NotEvaluatedDays: int(report.Thresholds.NotEvaluatedFor / (24 * time.Hour)),
+		NotModifiedDays:  int(report.Thresholds.NotModifiedFor / (24 * time.Hour)),
+		Flags:            report.Flags,
+	})
+}
+
+// handleProjectGraph returns the project's flags linked to their tags and
+// owners. The path names the project so the URL is stable across API keys,
+// but it must be the project of the key making the request.
+func (s *HTTPServer) handleProjectGraph(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if r.PathValue("id") != projectID {
+		writeServiceError(w, r, service.ErrProjectNotFound)
+		return
+	}
+
+	graph, err := s.service.ProjectGraph(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, graph)
+}
+
+func (s *HTTPServer) handleCreateProposal(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	var request proposalJSONRequest
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+	if request.Action == "" {
+		request.Action = repository.ProposalActionUpdate
+	}
+
+	proposal, err := s.service.ProposeFlagChange(r.Context(), repository.FlagProposal{
+		ProjectID: projectID,
+		FlagKey:   key,
+		Action:    request.Action,
+		Flag:      request.Flag,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, proposal)
+}
+
+func (s *HTTPServer) handleListFlagProposals(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSpace(r.PathValue("key"))
+	if key == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "key is required")
+		return
+	}
+	s.listProposals(w, r, key)
+}
+
+func (s *HTTPServer) handleListProposals(w http.ResponseWriter, r *http.Request) {
+	s.listProposals(w, r, "")
+}
+
+func (s *HTTPServer) listProposals(w http.ResponseWriter, r *http.Request, key string) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	status := strings.TrimSpace(r.URL.Query().Get("status"))
+	switch status {
+	case "", repository.ProposalStatusPending, repository.ProposalStatusApproved, repository.ProposalStatusRejected:
+	default:
+		writeJSONError(w, r, http.StatusBadRequest, "status must be pending, approved or rejected")
+		return
+	}
+
+	proposals, err := s.service.ListFlagProposals(r.Context(), projectID, key, status)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposals)
+}
+
+func (s *HTTPServer) handleApproveProposal(w http.ResponseWriter, r *http.Request) {
+	s.reviewProposal(w, r, s.service.ApproveFlagProposal)
+}
+
+func (s *HTTPServer) handleRejectProposal(w http.ResponseWriter, r *http.Request) {
+	s.reviewProposal(w, r, s.service.RejectFlagProposal)
+}
+
+func (s *HTTPServer) reviewProposal(w http.ResponseWriter, r *http.Request, review func(context.Context, string, string) (repository.FlagProposal, error)) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	proposal, err := review(r.Context(), projectID, id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proposal)
+}
+
+func (s *HTTPServer) handleGetFlagDefaults(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	defaults, err := s.service.GetFlagDefaults(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, defaults)
+}
+
+func (s *HTTPServer) handleSetFlagDefaults(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var defaults repository.FlagDefaults
+	if err := s.decodeJSONBody(w, r, &defaults); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetFlagDefaults(r.Context(), projectID, defaults)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *HTTPServer) handleGetFlagKeyPolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	policy, err := s.service.GetFlagKeyPolicy(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+func (s *HTTPServer) handleSetFlagKeyPolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var policy repository.FlagKeyPolicy
+	if err := s.decodeJSONBody(w, r, &policy); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetFlagKeyPolicy(r.Context(), projectID, policy)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
 func (s *HTTPServer) handleEvaluate(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	var request evaluateJSONRequest
-	if err := s.decodeJSONBody(w, r, &request); err != nil {
-		writeJSONDecodeError(w, err)
+	if err := s.decodeJSONBodyLimit(w, r, &request, s.maxEvaluateBytes); err != nil {
+		writeJSONDecodeError(w, r, err)
 		return
 	}
 
 	requests := make([]service.ResolveRequest, 0)
 	switch {
 	case len(request.Requests) > 0 && strings.TrimSpace(request.Key) != "":
-		writeJSONError(w, http.StatusBadRequest, "use either key or requests")
+		writeJSONError(w, r, http.StatusBadRequest, "use either key or requests")
 		return
 	case len(request.Requests) > 0:
 		requests = make([]service.ResolveRequest, 0, len(request.Requests))
 		for idx, item := range request.Requests {
 			if strings.TrimSpace(item.Key) == "" {
-				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("requests[%d].key is required", idx))
+				writeJSONError(w, r, http.StatusBadRequest, fmt.Sprintf("requests[%d].key is required", idx))
 				return
 			}
-			requests = append(requests, service.ResolveRequest{
-				ProjectID:    projectID,
-				Key:          item.Key,
-				Context:      item.Context,
-				DefaultValue: item.DefaultValue,
-			})
+			requests = append(requests, newResolveRequest(projectID, item.Key, item.Context, item.Preset, item.DefaultValue))
 		}
 	case strings.TrimSpace(request.Key) != "":
-		requests = append(requests, service.ResolveRequest{
-			ProjectID:    projectID,
-			Key:          request.Key,
-			Context:      request.Context,
-			DefaultValue: request.DefaultValue,
-		})
+		requests = append(requests, newResolveRequest(projectID, request.Key, request.Context, request.Preset, request.DefaultValue))
 	default:
-		writeJSONError(w, http.StatusBadRequest, "key or requests is required")
+		writeJSONError(w, r, http.StatusBadRequest, "key or requests is required")
 		return
 	}
 
-	results, err := s.service.ResolveBatch(r.Context(), requests)
+	results, err := s.service.ResolveBatch(service.NewContextWithRequestHeaders(r.Context(), r.Header.Get), requests)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
 	for _, result := range results {
-		s.metrics.RecordEvaluation(result.Value)
+		s.metrics.RecordEvaluation(projectID, result.Key, result.Value)
 	}
 
 	writeJSON(w, http.StatusOK, evaluateJSONResponse{Results: results})
 }
 
+// newResolveRequest builds the request for one flag of POST /v1/evaluate. A
+// boolean or null default_value asks for a boolean result; any other JSON
+// value asks for a typed result of its type.
+func newResolveRequest(projectID, key string, evalContext core.EvaluationContext, preset string, defaultValue json.RawMessage) service.ResolveRequest {
+	request := service.ResolveRequest{
+		ProjectID: projectID,
+		Key:       key,
+		Context:   evalContext,
+		Preset:    preset,
+	}
+	switch service.TypedValueType(defaultValue) {
+	case "":
+	case service.ValueTypeBoolean:
+		request.DefaultValue = bytes.Equal(bytes.TrimSpace(defaultValue), []byte("true"))
+	default:
+		request.TypedDefault = defaultValue
+	}
+	return request
+}
+
+// handleEvaluateAll evaluates every flag in the project against one context
+// and returns a map of key to value.
+func (s *HTTPServer) handleEvaluateAll(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request evaluateAllJSONRequest
+	if err := s.decodeJSONBodyLimit(w, r, &request, s.maxEvaluateBytes); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	results, err := s.service.ResolveAll(service.NewContextWithRequestHeaders(r.Context(), r.Header.Get), projectID, request.Context, request.Preset)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := evaluateAllJSONResponse{Values: make(map[string]bool, len(results))}
+	for _, result := range results {
+		s.metrics.RecordEvaluation(projectID, result.Key, result.Value)
+		response.Values[result.Key] = result.Value
+	}
+	if len(results) > 0 {
+		// Every result shares one context, and so its warnings.
+		response.Warnings = results[0].Warnings
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
 func (s *HTTPServer) handleStream(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	lastEventID, err := parseLastEventID(r.Header.Get("Last-Event-ID"))
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+		writeJSONError(w, r, http.StatusBadRequest, "invalid Last-Event-ID")
+		return
+	}
+
+	filter, err := parseStreamFilter(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	schemaVersion, err := parseEventSchemaVersion(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	release, ok := s.streamLimiter.acquire(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusTooManyRequests, "too many open streams for this API key")
 		return
 	}
+	defer release()
 
-	filterKey := strings.TrimSpace(r.URL.Query().Get("key"))
+	drain, leave, ok := s.streamDrainer.join()
+	if !ok {
+		writeShuttingDown(w, r)
+		return
+	}
+	defer leave()
 
-	rc := http.NewResponseController(w)
+	w, rc := s.streamWriter(w)
 
-	// listEvents selects the appropriate service method based on whether
-	// a per-key filter was requested.
+	// listEvents selects the appropriate service method based on the
+	// filter requested.
 	listEvents := func(ctx context.Context, eventID int64) ([]repository.FlagEvent, error) {
-		if filterKey != "" {
-			return s.service.ListEventsSinceForKey(ctx, projectID, eventID, filterKey)
+		switch {
+		case len(filter.Keys) == 0 && len(filter.Types) == 0:
+			return s.service.ListEventsSince(ctx, projectID, eventID)
+		case len(filter.Keys) == 1 && len(filter.Types) == 0:
+			return s.service.ListEventsSinceForKey(ctx, projectID, eventID, filter.Keys[0])
+		default:
+			return s.service.ListEventsSinceMatching(ctx, projectID, eventID, filter)
 		}
-		return s.service.ListEventsSince(ctx, projectID, eventID)
 	}
 
+	keepalive := newSSEKeepalive(s.keepaliveInterval)
+	defer keepalive.stop()
+
 	currentEventID := lastEventID
 	writeEvents := func(events []repository.FlagEvent) error {
+		sent := 0
+		defer func() { s.metrics.AddStreamEventsSent("sse", sent) }()
 		for _, event := range events {
 			currentEventID = event.EventID
 			eventName := toSSEEventName(event.EventType)
@@ -450,23 +1036,50 @@ func (s *HTTPServer) handleStream(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			payload := event.Payload
-			if len(payload) == 0 {
-				payload = []byte(`{}`)
+			payload, err := eventData(event, schemaVersion)
+			if err != nil {
+				return err
 			}
 
 			if err := writeSSEEvent(w, event.EventID, eventName, payload); err != nil {
 				return err
 			}
+			sent++
 			_ = rc.Flush()
+			keepalive.reset()
 		}
 
 		return nil
 	}
 
+	// follow writes events until there are none left to catch up on, as
+	// older history is listed a batch at a time. It reports whether the
+	// stream is still usable.
+	follow := func() bool {
+		for {
+			events, err := listEvents(r.Context(), currentEventID)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+					writeSSEError(w, rc, serviceErrorMessage(err))
+				}
+				return false
+			}
+			previous := currentEventID
+			if err := writeEvents(events); err != nil {
+				return false
+			}
+			if currentEventID == previous {
+				return true
+			}
+		}
+	}
+
+	waiter := newEventWaiter(s.service, s.streamPollInterval)
+	defer waiter.stop()
+
 	initialEvents, err := listEvents(r.Context(), currentEventID)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -474,33 +1087,58 @@ func (s *HTTPServer) handleStream(w http.ResponseWriter, r *http.Request) {
 	headers.Set("Content-Type", "text/event-stream")
 	headers.Set("Cache-Control", "no-cache")
 	headers.Set("Connection", "keep-alive")
+	headers.Set(SchemaVersionHeader, strconv.Itoa(schemaVersion))
+	s.setSDKConfigHeader(w)
 	w.WriteHeader(http.StatusOK)
+	_ = writeSSERetry(w)
 	_ = rc.Flush()
 
 	s.metrics.ActiveStreams.WithLabelValues("sse").Inc()
 	defer s.metrics.ActiveStreams.WithLabelValues("sse").Dec()
+	defer s.metrics.TrackProjectStream("sse", projectID)()
+	defer recordStreamTime(r.Context(), s.service, projectID, time.Now())
+	if lastEventID > 0 {
+		s.metrics.ObserveReplayDepth("sse", len(initialEvents))
+	}
 
 	if err := writeEvents(initialEvents); err != nil {
 		return
 	}
+	if len(initialEvents) > 0 && !follow() {
+		return
+	}
 
-	ticker := time.NewTicker(s.streamPollInterval)
-	defer ticker.Stop()
+	// A nil channel never fires, so heartbeats stay off unless configured.
+	var heartbeat <-chan time.Time
+	if s.heartbeatInterval > 0 {
+		heartbeatTicker := time.NewTicker(s.heartbeatInterval)
+		defer heartbeatTicker.Stop()
+		heartbeat = heartbeatTicker.C
+	}
 
 	for {
 		select {
 		case <-r.Context().Done():
 			return
-		case <-ticker.C:
-			events, err := listEvents(r.Context(), currentEventID)
-			if err != nil {
-				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-					return
-				}
-				writeSSEError(w, rc, serviceErrorMessage(err))
+		case <-drain:
+			_ = writeSSEReconnect(w, currentEventID)
+			_ = rc.Flush()
+			return
+		case now := <-heartbeat:
+			if err := writeSSEHeartbeat(w, currentEventID, now, s.identity); err != nil {
 				return
 			}
-			if err := writeEvents(events); err != nil {
+			_ = rc.Flush()
+			keepalive.reset()
+		case <-keepalive.C():
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			_ = rc.Flush()
+			keepalive.reset()
+		case <-waiter.C():
+			waiter.rearm()
+			if !follow() {
 				return
 			}
 		}
@@ -510,13 +1148,13 @@ func (s *HTTPServer) handleStream(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	keyID, secret, err := s.service.CreateAPIKey(r.Context(), projectID)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -531,13 +1169,13 @@ func (s *HTTPServer) handleCreateAPIKey(w http.ResponseWriter, r *http.Request)
 func (s *HTTPServer) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	keys, err := s.service.ListAPIKeys(r.Context(), projectID)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -547,18 +1185,18 @@ func (s *HTTPServer) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
 func (s *HTTPServer) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	keyID := strings.TrimSpace(r.PathValue("id"))
 	if keyID == "" {
-		writeJSONError(w, http.StatusBadRequest, "api key ID is required")
+		writeJSONError(w, r, http.StatusBadRequest, "api key ID is required")
 		return
 	}
 
 	if err := s.service.DeleteAPIKey(r.Context(), projectID, keyID); err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -567,7 +1205,7 @@ func (s *HTTPServer) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request)
 func (s *HTTPServer) handleListAuditLog(w http.ResponseWriter, r *http.Request) {
 	projectID, ok := middleware.ProjectIDFromContext(r.Context())
 	if !ok {
-		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
@@ -576,7 +1214,7 @@ func (s *HTTPServer) handleListAuditLog(w http.ResponseWriter, r *http.Request)
 	if v := r.URL.Query().Get("limit"); v != "" {
 		parsed, err := strconv.Atoi(v)
 		if err != nil || parsed <= 0 || parsed > 1000 {
-			writeJSONError(w, http.StatusBadRequest, "invalid limit parameter")
+			writeJSONError(w, r, http.StatusBadRequest, "invalid limit parameter")
 			return
 		}
 		limit = parsed
@@ -584,7 +1222,7 @@ func (s *HTTPServer) handleListAuditLog(w http.ResponseWriter, r *http.Request)
 	if v := r.URL.Query().Get("offset"); v != "" {
 		parsed, err := strconv.Atoi(v)
 		if err != nil || parsed < 0 {
-			writeJSONError(w, http.StatusBadRequest, "invalid offset parameter")
+			writeJSONError(w, r, http.StatusBadRequest, "invalid offset parameter")
 			return
 		}
 		offset = parsed
@@ -592,7 +1230,7 @@ func (s *HTTPServer) handleListAuditLog(w http.ResponseWriter, r *http.Request)
 
 	entries, err := s.service.ListAuditLog(r.Context(), projectID, limit, offset)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -602,6 +1240,31 @@ func (s *HTTPServer) handleHealthz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleReadyz reports whether this replica should receive traffic. Unlike
+// /healthz, it stays 503 during warm-up so load balancers hold evaluation
+// requests back until the flag cache is populated and being kept fresh, and
+// it turns 503 again once shutdown begins.
+func (s *HTTPServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.readinessCheck == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": service.StatusReady})
+		return
+	}
+
+	readiness := s.readinessCheck(r.Context())
+	body := struct {
+		Status     string                             `json:"status"`
+		Reason     string                             `json:"reason,omitempty"`
+		Components map[string]service.ComponentStatus `json:"components,omitempty"`
+	}{Status: service.StatusReady, Components: readiness.Components}
+	status := http.StatusOK
+	if readiness.Err != nil {
+		body.Status = service.StatusNotReady
+		body.Reason = readiness.Err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, body)
+}
+
 func (s *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	s.metricsHandler.ServeHTTP(w, r)
 }
@@ -620,6 +1283,42 @@ func parseLastEventID(value string) (int64, error) {
 	return eventID, nil
 }
 
+// parseStreamFilter reads the key parameters, which may repeat, and the
+// comma-separated types parameters of a stream request. Types are SSE event
+// names, which are translated to the event types stored.
+func parseStreamFilter(query url.Values) (repository.EventFilter, error) {
+	var filter repository.EventFilter
+	for _, key := range query["key"] {
+		key = strings.TrimSpace(key)
+		if key != "" && !slices.Contains(filter.Keys, key) {
+			filter.Keys = append(filter.Keys, key)
+		}
+	}
+	if len(filter.Keys) > maxStreamFilterKeys {
+		return repository.EventFilter{}, fmt.Errorf("too many key parameters: %d, the limit is %d", len(filter.Keys), maxStreamFilterKeys)
+	}
+
+	for _, types := range query["types"] {
+		for name := range strings.SplitSeq(types, ",") {
+			var eventType string
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "":
+				continue
+			case "update":
+				eventType = service.EventTypeUpdated
+			case "delete":
+				eventType = service.EventTypeDeleted
+			default:
+				return repository.EventFilter{}, fmt.Errorf("invalid event type %q: want update or delete", strings.TrimSpace(name))
+			}
+			if !slices.Contains(filter.Types, eventType) {
+				filter.Types = append(filter.Types, eventType)
+			}
+		}
+	}
+	return filter, nil
+}
+
 func toSSEEventName(eventType string) string {
 	switch strings.ToLower(strings.TrimSpace(eventType)) {
 	case "update", "updated":
@@ -631,23 +1330,194 @@ func toSSEEventName(eventType string) string {
 	}
 }
 
-func writeServiceError(w http.ResponseWriter, err error) {
+// rulesErrorResponse lists each invalid rule alongside the usual error
+// message, so clients can point at the offending rules. It is the legacy
+// form of an invalid-rules problem.
+type rulesErrorResponse struct {
+	Error      string           `json:"error"`
+	RequestID  string           `json:"request_id,omitempty"`
+	RuleErrors []core.RuleError `json:"rule_errors"`
+}
+
+// variantsErrorResponse is the legacy form of an invalid-variants problem
+// raised by a flag's variants schema.
+type variantsErrorResponse struct {
+	Error         string             `json:"error"`
+	RequestID     string             `json:"request_id,omitempty"`
+	VariantErrors []jsonschema.Error `json:"variant_errors"`
+}
+
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID, _ := middleware.RequestIDFromContext(r.Context())
+	var rulesErr *service.RulesError
+	if errors.As(err, &rulesErr) && middleware.LegacyErrorsFromContext(r.Context()) {
+		writeJSON(w, http.StatusBadRequest, rulesErrorResponse{Error: serviceErrorMessage(err), RequestID: requestID, RuleErrors: rulesErr.Rules})
+		return
+	}
+	var variantsErr *service.VariantsError
+	if errors.As(err, &variantsErr) && middleware.LegacyErrorsFromContext(r.Context()) {
+		writeJSON(w, http.StatusBadRequest, variantsErrorResponse{Error: serviceErrorMessage(err), RequestID: requestID, VariantErrors: variantsErr.Errors})
+		return
+	}
+	middleware.WriteProblem(w, r, serviceProblem(err))
+}
+
+// serviceProblem maps a service error to its problem details. Errors that
+// clients act on get their own problem type; the rest use the generic type
+// for their status.
+func serviceProblem(err error) middleware.Problem {
+	p := middleware.Problem{Detail: serviceErrorMessage(err)}
+	fieldError := func(field string) {
+		p.Errors = []middleware.FieldError{{Field: field, Detail: p.Detail}}
+	}
+
+	var rulesErr *service.RulesError
+	var variantsErr *service.VariantsError
 	switch {
-	case errors.Is(err, service.ErrInvalidRules), errors.Is(err, service.ErrInvalidVariants):
-		writeJSONError(w, http.StatusBadRequest, serviceErrorMessage(err))
-	case errors.Is(err, service.ErrFlagKeyRequired), errors.Is(err, service.ErrProjectIDRequired):
-		writeJSONError(w, http.StatusBadRequest, serviceErrorMessage(err))
+	case errors.As(err, &rulesErr):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-rules")
+		for _, ruleErr := range rulesErr.Rules {
+			p.Errors = append(p.Errors, middleware.FieldError{
+				Field:  rulesField(rulesErr, ruleErr.Index),
+				Detail: ruleErr.Reason,
+			})
+		}
+	case errors.Is(err, service.ErrInvalidRules):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-rules")
+	case errors.As(err, &variantsErr):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-variants")
+		for _, schemaErr := range variantsErr.Errors {
+			p.Errors = append(p.Errors, middleware.FieldError{
+				Field:  "variants" + schemaErr.Path,
+				Detail: schemaErr.Message,
+			})
+		}
+	case errors.Is(err, service.ErrInvalidVariants):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-variants")
+		fieldError("variants")
+	case errors.Is(err, service.ErrInvalidVariantsSchema):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-variants-schema")
+		fieldError("variants_schema")
+	case errors.Is(err, service.ErrInvalidTags):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-tags")
+		fieldError("tags")
+	case errors.Is(err, service.ErrInvalidOwner):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-owner")
+		fieldError("owner")
+	case errors.Is(err, service.ErrFlagKeyRequired):
+		p.Status = http.StatusBadRequest
+		fieldError("key")
+	case errors.Is(err, service.ErrFlagKeyPolicyViolation):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("flag-key-policy-violation")
+		fieldError("key")
+	case errors.Is(err, service.ErrInvalidFlagKeyPolicy):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-flag-key-policy")
+	case errors.Is(err, service.ErrTargetingKeyRequired):
+		p.Status = http.StatusBadRequest
+		fieldError("targeting_key")
+	case errors.Is(err, service.ErrProjectIDRequired), errors.Is(err, service.ErrAPIKeyIDRequired):
+		p.Status = http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidProposal):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-proposal")
+	case errors.Is(err, service.ErrInvalidTargets):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-targets")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidKeyRotationPolicy):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-key-rotation-policy")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidAPIKeyQuota):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-api-key-quota")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrAPIKeyQuotaExceeded):
+		p.Status, p.Type = http.StatusTooManyRequests, middleware.ProblemType("api-key-quota-exceeded")
+	case errors.Is(err, service.ErrInvalidContextPresetName):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-context-preset")
+		fieldError("name")
+	case errors.Is(err, service.ErrInvalidNotificationChannel):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-notification-channel")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidSegmentName):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-segment")
+		fieldError("name")
+	case errors.Is(err, service.ErrInvalidSegment):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-segment")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidContextSchema):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-context-schema")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidContextEnrichment):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-context-enrichment")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidFlagCopy):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-flag-copy")
+	case errors.Is(err, service.ErrInvalidFlagTemplateParameters):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-flag-template-parameters")
+		p.Detail = err.Error()
+		fieldError("parameters")
+	case errors.Is(err, service.ErrInvalidPatch):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-patch")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrInvalidEventQuery):
+		p.Status, p.Type = http.StatusBadRequest, middleware.ProblemType("invalid-event-query")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrSelfApproval):
+		p.Status, p.Type = http.StatusForbidden, middleware.ProblemType("self-approval")
+	case errors.Is(err, service.ErrActorRequired):
+		p.Status, p.Type = http.StatusForbidden, middleware.ProblemType("actor-required")
+	case errors.Is(err, service.ErrAdminKeyRequired):
+		p.Status, p.Type = http.StatusForbidden, middleware.ProblemType("admin-key-required")
+	case errors.Is(err, service.ErrProposalNotPending):
+		p.Status, p.Type = http.StatusConflict, middleware.ProblemType("proposal-not-pending")
+	case errors.Is(err, service.ErrFlagExists):
+		p.Status, p.Type = http.StatusConflict, middleware.ProblemType("flag-exists")
+	case errors.Is(err, service.ErrProjectNameTaken):
+		p.Status, p.Type = http.StatusConflict, middleware.ProblemType("project-name-taken")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrPatchTestFailed):
+		p.Status, p.Type = http.StatusConflict, middleware.ProblemType("patch-test-failed")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrSegmentInUse):
+		p.Status, p.Type = http.StatusConflict, middleware.ProblemType("segment-in-use")
+		p.Detail = err.Error()
+	case errors.Is(err, service.ErrProposalNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("proposal-not-found")
 	case errors.Is(err, service.ErrFlagNotFound):
-		writeJSONError(w, http.StatusNotFound, serviceErrorMessage(err))
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("flag-not-found")
 	case errors.Is(err, service.ErrAPIKeyNotFound):
-		writeJSONError(w, http.StatusNotFound, serviceErrorMessage(err))
-	case errors.Is(err, service.ErrAPIKeyIDRequired):
-		writeJSONError(w, http.StatusBadRequest, serviceErrorMessage(err))
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("api-key-not-found")
+	case errors.Is(err, service.ErrProjectNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("project-not-found")
+	case errors.Is(err, service.ErrContextPresetNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("context-preset-not-found")
+	case errors.Is(err, service.ErrNotificationChannelNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("notification-channel-not-found")
+	case errors.Is(err, service.ErrWebhookSigningSecretNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("webhook-signing-secret-not-found")
+	case errors.Is(err, service.ErrSegmentNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("segment-not-found")
+	case errors.Is(err, service.ErrFlagRevisionNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("flag-revision-not-found")
+	case errors.Is(err, service.ErrFlagTemplateNotFound):
+		p.Status, p.Type = http.StatusNotFound, middleware.ProblemType("flag-template-not-found")
+		fieldError("template")
 	case errors.Is(err, context.Canceled):
-		writeJSONError(w, http.StatusRequestTimeout, serviceErrorMessage(err))
+		p.Status = http.StatusRequestTimeout
+	case errors.Is(err, context.DeadlineExceeded):
+		p.Status, p.Detail = http.StatusServiceUnavailable, "request timed out"
 	default:
-		writeJSONError(w, http.StatusInternalServerError, serviceErrorMessage(err))
+		p.Status = http.StatusInternalServerError
+	}
+	return p
+}
+
+// rulesField returns the request field of the invalid rule at index, such
+// as "rules[2]" or, for shadow rules, "shadow_rules[2]".
+func rulesField(rulesErr *service.RulesError, index int) string {
+	field := "rules"
+	if rulesErr.Field != "" {
+		field = rulesErr.Field
 	}
+	return field + "[" + strconv.Itoa(index) + "]"
 }
 
 func serviceErrorMessage(err error) string {
@@ -656,16 +1526,67 @@ func serviceErrorMessage(err error) string {
 		return "invalid rules"
 	case errors.Is(err, service.ErrInvalidVariants):
 		return "invalid variants"
+	case errors.Is(err, service.ErrInvalidVariantsSchema):
+		// The wrapped error says which part of the schema is unusable.
+		return err.Error()
+	case errors.Is(err, service.ErrInvalidTags):
+		// The wrapped error names the offending tag.
+		return err.Error()
+	case errors.Is(err, service.ErrInvalidOwner):
+		return err.Error()
+	case errors.Is(err, service.ErrInvalidTargets):
+		return "invalid targets"
 	case errors.Is(err, service.ErrFlagKeyRequired):
 		return "flag key is required"
+	case errors.Is(err, service.ErrInvalidFlagCopy):
+		return "a flag cannot be copied onto itself"
+	case errors.Is(err, service.ErrFlagKeyPolicyViolation), errors.Is(err, service.ErrInvalidFlagKeyPolicy):
+		// The wrapped error names the key and the requirement it failed.
+		return err.Error()
 	case errors.Is(err, service.ErrProjectIDRequired):
 		return "project ID is required"
+	case errors.Is(err, service.ErrTargetingKeyRequired):
+		return "targeting key is required"
+	case errors.Is(err, service.ErrInvalidProposal):
+		return "invalid proposal"
+	case errors.Is(err, service.ErrSelfApproval):
+		return "proposals must be approved by a different actor"
+	case errors.Is(err, service.ErrActorRequired):
+		return "an authenticated API key or admin user is required"
+	case errors.Is(err, service.ErrAdminKeyRequired):
+		return "an admin-scoped api key is required"
+	case errors.Is(err, service.ErrProposalNotPending):
+		return "proposal is not pending"
+	case errors.Is(err, service.ErrProposalNotFound):
+		return "proposal not found"
 	case errors.Is(err, service.ErrFlagNotFound):
 		return "flag not found"
+	case errors.Is(err, service.ErrFlagExists):
+		return "flag already exists"
 	case errors.Is(err, service.ErrAPIKeyNotFound):
 		return "api key not found"
+	case errors.Is(err, service.ErrProjectNotFound):
+		return "project not found"
+	case errors.Is(err, service.ErrContextPresetNotFound):
+		return "context preset not found"
+	case errors.Is(err, service.ErrNotificationChannelNotFound):
+		return "notification channel not found"
+	case errors.Is(err, service.ErrWebhookSigningSecretNotFound):
+		return "webhook signing secret not found"
+	case errors.Is(err, service.ErrSegmentNotFound):
+		return "segment not found"
+	case errors.Is(err, service.ErrFlagRevisionNotFound):
+		return "flag revision not found"
+	case errors.Is(err, service.ErrFlagTemplateNotFound):
+		return "flag template not found"
+	case errors.Is(err, service.ErrInvalidContextPresetName):
+		return "invalid context preset name"
+	case errors.Is(err, service.ErrInvalidSegmentName):
+		return "invalid segment name"
 	case errors.Is(err, service.ErrAPIKeyIDRequired):
 		return "api key ID is required"
+	case errors.Is(err, service.ErrAPIKeyQuotaExceeded):
+		return "api key quota exceeded; it resets at the start of the next hour"
 	case errors.Is(err, context.Canceled):
 		return "request canceled"
 	default:
@@ -712,17 +1633,42 @@ func compactSSEPayload(payload []byte) []string {
 	return lines
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]string{"error": message})
+// writeJSONError writes a problem with the generic type for status.
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	middleware.WriteProblem(w, r, middleware.Problem{Status: status, Detail: message})
 }
 
-func writeJSONDecodeError(w http.ResponseWriter, err error) {
+func writeJSONDecodeError(w http.ResponseWriter, r *http.Request, err error) {
 	if errors.Is(err, errJSONBodyTooLarge) {
-		writeJSONError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		writeJSONError(w, r, http.StatusRequestTimeout, "timed out reading request body")
 		return
 	}
 
-	writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Status: http.StatusBadRequest,
+		Detail: "invalid JSON body",
+		Errors: jsonDecodeFieldErrors(err),
+	})
+}
+
+// jsonDecodeFieldErrors names the field a JSON decode error is about, when
+// the decoder says which one it is.
+func jsonDecodeFieldErrors(err error) []middleware.FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []middleware.FieldError{{Field: typeErr.Field, Detail: "unexpected JSON " + typeErr.Value}}
+	}
+	// DisallowUnknownFields reports `json: unknown field "name"`.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if name, err := strconv.Unquote(field); err == nil {
+			return []middleware.FieldError{{Field: name, Detail: "unknown field"}}
+		}
+	}
+	return nil
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -732,11 +1678,15 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 }
 
 func (s *HTTPServer) decodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	return s.decodeJSONBodyLimit(w, r, dst, s.maxJSONBodyBytes)
+}
+
+func (s *HTTPServer) decodeJSONBodyLimit(w http.ResponseWriter, r *http.Request, dst any, limit int64) error {
 	if r.Body == nil {
 		return io.EOF
 	}
 
-	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxJSONBodyBytes))
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
 	decoder.DisallowUnknownFields()
 
 	if err := decoder.Decode(dst); err != nil {
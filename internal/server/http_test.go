@@ -1,17 +1,25 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/instance"
+	"github.com/matt-riley/flagz/internal/jsonschema"
 	"github.com/matt-riley/flagz/internal/middleware"
 	"github.com/matt-riley/flagz/internal/repository"
 	"github.com/matt-riley/flagz/internal/service"
@@ -90,6 +98,911 @@ func TestHTTPHandlerListFlags(t *testing.T) {
 	}
 }
 
+func TestHTTPHandlerListFlagsReferencingAttribute(t *testing.T) {
+	var gotAttr string
+	svc := &fakeService{
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			t.Fatal("ListFlags should not be called when filtering by attribute")
+			return nil, nil
+		},
+		listFlagsByAttributeFunc: func(_ context.Context, _ string, attr string) ([]repository.Flag, error) {
+			gotAttr = attr
+			return []repository.Flag{{Key: "geo-banner"}}, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags?references_attribute=country", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotAttr != "country" {
+		t.Fatalf("attribute = %q, want %q", gotAttr, "country")
+	}
+	var got []repository.Flag
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(got) != 1 || got[0].Key != "geo-banner" {
+		t.Fatalf("response = %#v, want single geo-banner flag", got)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags?references_attribute=", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty attribute status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerListFlagsByTag(t *testing.T) {
+	svc := &fakeService{
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			return []repository.Flag{
+				{Key: "banner", Tags: []string{"marketing"}},
+				{Key: "checkout-v2", Tags: []string{"checkout", "team:payments"}},
+				{Key: "one-click", Tags: []string{"checkout"}},
+			}, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"?tag=checkout", []string{"checkout-v2", "one-click"}},
+		{"?tag=Checkout&tag=team:payments", []string{"checkout-v2"}},
+		{"?tag=unused", nil},
+	} {
+		req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags"+tc.query, nil))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tc.query, rec.Code, http.StatusOK)
+		}
+		var got []repository.Flag
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		var keys []string
+		for _, flag := range got {
+			keys = append(keys, flag.Key)
+		}
+		if !slices.Equal(keys, tc.want) {
+			t.Fatalf("%s: keys = %v, want %v", tc.query, keys, tc.want)
+		}
+	}
+}
+
+func TestHTTPHandlerCreateFlagInvalidTagsReturnsBadRequest(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
+			return repository.Flag{}, fmt.Errorf("%w: tag %q must start with a letter or digit", service.ErrInvalidTags, "-x")
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"new-ui","tags":["-x"]}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `invalid-tags`) || !strings.Contains(body, `"field":"tags"`) {
+		t.Fatalf("body = %q, want an invalid-tags problem on tags", body)
+	}
+}
+
+func TestHTTPHandlerCreateFlagOwnership(t *testing.T) {
+	var got repository.Flag
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			got = flag
+			if len(flag.Owner) > 20 {
+				return repository.Flag{}, fmt.Errorf("%w: owner is longer than 20 characters", service.ErrInvalidOwner)
+			}
+			return flag, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"spring-sale","owner":"growth@example.com","expires_at":"2026-03-31T00:00:00Z"}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if got.Owner != "growth@example.com" || got.ExpiresAt == nil || !got.ExpiresAt.Equal(time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("CreateFlag got %+v, want owner and expiry", got)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"spring-sale","owner":"`+strings.Repeat("a", 21)+`"}`)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `invalid-owner`) || !strings.Contains(body, `"field":"owner"`) {
+		t.Fatalf("body = %q, want an invalid-owner problem on owner", body)
+	}
+}
+
+func TestHTTPHandlerReshuffleAndBucket(t *testing.T) {
+	var reshuffled, gotTargetingKey string
+	svc := &fakeService{
+		reshuffleFlagFunc: func(_ context.Context, _ string, key string) (repository.Flag, error) {
+			reshuffled = key
+			return repository.Flag{Key: key, BucketingSalt: "new-salt"}, nil
+		},
+		bucketForFunc: func(_ context.Context, _ string, key, targetingKey string) (service.BucketAssignment, error) {
+			gotTargetingKey = targetingKey
+			return service.BucketAssignment{Key: key, TargetingKey: targetingKey, Bucket: 1234}, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/checkout/reshuffle", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reshuffle status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if reshuffled != "checkout" {
+		t.Fatalf("reshuffled key = %q, want checkout", reshuffled)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/checkout/bucket?targeting_key=user-42", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bucket status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotTargetingKey != "user-42" {
+		t.Fatalf("targeting key = %q, want user-42", gotTargetingKey)
+	}
+	var got service.BucketAssignment
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got.Bucket != 1234 {
+		t.Fatalf("bucket = %d, want 1234", got.Bucket)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/checkout/bucket", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing targeting_key status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerFlagStats(t *testing.T) {
+	lastEvaluated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeService{
+		getFlagStatsFunc: func(_ context.Context, _ string, key string) (repository.FlagStats, error) {
+			if key != "checkout" {
+				return repository.FlagStats{}, service.ErrFlagNotFound
+			}
+			return repository.FlagStats{FlagKey: key, Evaluations: 42, TrueEvaluations: 10, LastEvaluatedAt: &lastEvaluated}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/checkout/stats", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"key":"checkout","evaluations":42,"true_evaluations":10,"last_evaluated_at":"2026-03-01T12:00:00Z"}`; got != want {
+		t.Fatalf("stats body = %s, want %s", got, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/missing/stats", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing flag stats status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHTTPHandlerStaleFlags(t *testing.T) {
+	var gotThresholds service.StaleThresholds
+	svc := &fakeService{
+		staleFlagsFunc: func(_ context.Context, _ string, thresholds service.StaleThresholds) (service.StaleReport, error) {
+			gotThresholds = thresholds
+			return service.StaleReport{
+				Thresholds: service.StaleThresholds{NotEvaluatedFor: 14 * 24 * time.Hour, NotModifiedFor: 90 * 24 * time.Hour},
+				Flags: []service.StaleFlag{{
+					Key:              "legacy",
+					Reasons:          []string{service.StaleReasonNotEvaluated},
+					UpdatedAt:        time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+					CleanupCandidate: true,
+				}},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/stale?not_evaluated_days=14", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stale status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if gotThresholds.NotEvaluatedFor != 14*24*time.Hour || gotThresholds.NotModifiedFor != 0 {
+		t.Fatalf("thresholds = %+v, want 14 days unevaluated and default unmodified", gotThresholds)
+	}
+	want := `{"not_evaluated_days":14,"not_modified_days":90,"flags":[{"key":"legacy","reasons":["not_evaluated"],"evaluations":0,"last_evaluated_at":null,"updated_at":"2026-01-01T00:00:00Z","cleanup_candidate":true}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("stale body = %s, want %s", got, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/stale?not_modified_days=0", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid threshold status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerProjectGraph(t *testing.T) {
+	enabled := true
+	svc := &fakeService{
+		projectGraphFunc: func(_ context.Context, projectID string) (service.ProjectGraph, error) {
+			if projectID != "default" {
+				t.Fatalf("projectID = %q, want default", projectID)
+			}
+			return service.ProjectGraph{
+				Nodes: []service.GraphNode{
+					{ID: "flag:checkout", Kind: service.GraphNodeFlag, Label: "checkout", Enabled: &enabled},
+					{ID: "owner:team:payments", Kind: service.GraphNodeOwner, Label: "team:payments"},
+				},
+				Edges:   []service.GraphEdge{{From: "flag:checkout", To: "owner:team:payments", Kind: service.GraphEdgeOwnedBy}},
+				Orphans: []string{},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/projects/default/graph", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("graph status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	want := `{"nodes":[{"id":"flag:checkout","kind":"flag","label":"checkout","enabled":true},{"id":"owner:team:payments","kind":"owner","label":"team:payments"}],"edges":[{"from":"flag:checkout","to":"owner:team:payments","kind":"owned_by"}],"orphans":[]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("graph body = %s, want %s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/projects/other/graph", nil)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("other project status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHTTPHandlerFlagDefaults(t *testing.T) {
+	var stored repository.FlagDefaults
+	svc := &fakeService{
+		getFlagDefaultsFunc: func(_ context.Context, _ string) (repository.FlagDefaults, error) {
+			return stored, nil
+		},
+		setFlagDefaultsFunc: func(_ context.Context, _ string, defaults repository.FlagDefaults) (repository.FlagDefaults, error) {
+			if string(defaults.Rules) == `"bad"` {
+				return repository.FlagDefaults{}, service.ErrInvalidRules
+			}
+			stored = defaults
+			return defaults, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	body := `{"description_template":"Owned by checkout: {{key}}","rules":[{"attribute":"country","operator":"equals","value":"GB"}]}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/flag-defaults", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flag-defaults", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got repository.FlagDefaults
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got.DescriptionTemplate != "Owned by checkout: {{key}}" {
+		t.Fatalf("description_template = %q", got.DescriptionTemplate)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/flag-defaults", strings.NewReader(`{"rules":"bad"}`)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid rules status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerFlagKeyPolicy(t *testing.T) {
+	var stored repository.FlagKeyPolicy
+	svc := &fakeService{
+		getFlagKeyPolicyFunc: func(_ context.Context, _ string) (repository.FlagKeyPolicy, error) {
+			return stored, nil
+		},
+		setFlagKeyPolicyFunc: func(_ context.Context, _ string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error) {
+			if policy.MaxLength < 0 {
+				return repository.FlagKeyPolicy{}, fmt.Errorf("%w: max_length must not be negative", service.ErrInvalidFlagKeyPolicy)
+			}
+			stored = policy
+			return policy, nil
+		},
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			return repository.Flag{}, fmt.Errorf("%w: key %q must start with %q", service.ErrFlagKeyPolicyViolation, flag.Key, stored.Prefix)
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	body := `{"pattern":"[a-z-]+","max_length":40,"prefix":"checkout-"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/flag-key-policy", strings.NewReader(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flag-key-policy", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got repository.FlagKeyPolicy
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got != (repository.FlagKeyPolicy{Pattern: "[a-z-]+", MaxLength: 40, Prefix: "checkout-"}) {
+		t.Fatalf("policy = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/flag-key-policy", strings.NewReader(`{"max_length":-1}`))))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-flag-key-policy") {
+		t.Fatalf("invalid policy = %d %s, want 400 invalid-flag-key-policy", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"new-ui"}`))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("create status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if want := `must start with \"checkout-\"`; !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("create body = %s, want detail containing %s", rec.Body.String(), want)
+	}
+}
+
+func TestHTTPHandlerContextSchema(t *testing.T) {
+	var stored repository.ContextSchema
+	svc := &fakeService{
+		getContextSchemaFunc: func(_ context.Context, _ string) (repository.ContextSchema, error) {
+			return stored, nil
+		},
+		setContextSchemaFunc: func(_ context.Context, _ string, schema repository.ContextSchema) (repository.ContextSchema, error) {
+			for _, attr := range schema.Attributes {
+				if attr.Type == "text" {
+					return repository.ContextSchema{}, fmt.Errorf("%w: attribute %q has unknown type %q", service.ErrInvalidContextSchema, attr.Name, attr.Type)
+				}
+			}
+			stored = schema
+			return schema, nil
+		},
+		resolveBatchFunc: func(_ context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			return []service.ResolveResult{{
+				Key:      requests[0].Key,
+				Reason:   core.ReasonDefault,
+				Warnings: []service.ContextWarning{{Attribute: "county", Kind: service.ContextWarningUnknownAttribute, Message: `unknown attribute "county"; did you mean "country"?`}},
+			}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	body := `{"strict":true,"attributes":[{"name":"country","type":"string","description":"ISO 3166 code"}]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/context-schema", strings.NewReader(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/context-schema", nil)))
+	var got repository.ContextSchema
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if !got.Strict || len(got.Attributes) != 1 || got.Attributes[0].Name != "country" {
+		t.Fatalf("schema = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/context-schema", strings.NewReader(`{"attributes":[{"name":"age","type":"text"}]}`))))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-context-schema") {
+		t.Fatalf("invalid schema = %d %s, want 400 invalid-context-schema", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(`{"key":"checkout","context":{"attributes":{"county":"GB"}}}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("evaluate status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if want := `"warnings":[{"attribute":"county","kind":"unknown_attribute","message":"unknown attribute \"county\"; did you mean \"country\"?"}]`; !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("evaluate body = %s, want %s", rec.Body.String(), want)
+	}
+}
+
+func TestHTTPHandlerContextEnrichment(t *testing.T) {
+	var stored repository.ContextEnrichment
+	var gotHeader string
+	svc := &fakeService{
+		getContextEnrichmentFunc: func(_ context.Context, _ string) (repository.ContextEnrichment, error) {
+			return stored, nil
+		},
+		setContextEnrichmentFunc: func(_ context.Context, _ string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error) {
+			if _, ok := enrichment.Headers[""]; ok {
+				return repository.ContextEnrichment{}, fmt.Errorf("%w: attribute name is blank", service.ErrInvalidContextEnrichment)
+			}
+			stored = enrichment
+			return enrichment, nil
+		},
+		resolveBatchFunc: func(ctx context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			gotHeader = ""
+			if header, ok := service.RequestHeaderFromContext(ctx); ok {
+				gotHeader = header("X-Region")
+			}
+			return []service.ResolveResult{{Key: requests[0].Key, Reason: core.ReasonDefault}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	body := `{"attributes":{"environment":"prod"},"headers":{"region":"X-Region"},"api_key_id_attribute":"api_key"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/context-enrichment", strings.NewReader(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/context-enrichment", nil)))
+	var got repository.ContextEnrichment
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got.Attributes["environment"] != "prod" || got.Headers["region"] != "X-Region" || got.APIKeyIDAttribute != "api_key" {
+		t.Fatalf("enrichment = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/context-enrichment", strings.NewReader(`{"headers":{"":"X-Region"}}`))))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-context-enrichment") {
+		t.Fatalf("invalid enrichment = %d %s, want 400 invalid-context-enrichment", rec.Code, rec.Body.String())
+	}
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(`{"key":"checkout"}`)))
+	req.Header.Set("X-Region", "eu")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("evaluate status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotHeader != "eu" {
+		t.Fatalf("evaluate header = %q, want eu", gotHeader)
+	}
+}
+
+func TestHTTPHandlerFlagHistory(t *testing.T) {
+	var gotLimit, gotRevision int
+	svc := &fakeService{
+		listFlagRevisionsFunc: func(_ context.Context, _, key string, limit int) ([]repository.FlagRevision, error) {
+			gotLimit = limit
+			if key != "checkout" {
+				return nil, service.ErrFlagNotFound
+			}
+			return []repository.FlagRevision{
+				{Revision: 2, Flag: repository.Flag{Key: "checkout", Enabled: false}},
+				{Revision: 1, Flag: repository.Flag{Key: "checkout", Enabled: true}},
+			}, nil
+		},
+		revertFlagFunc: func(_ context.Context, _, key string, revision int) (repository.Flag, error) {
+			gotRevision = revision
+			if revision > 2 {
+				return repository.Flag{}, service.ErrFlagRevisionNotFound
+			}
+			return repository.Flag{Key: key, Enabled: true}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/checkout/history?limit=10", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("history status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var revisions []repository.FlagRevision
+	if err := json.Unmarshal(rec.Body.Bytes(), &revisions); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if gotLimit != 10 || len(revisions) != 2 || revisions[0].Revision != 2 || !revisions[1].Flag.Enabled {
+		t.Errorf("history = %+v with limit %d, want both revisions newest first with limit 10", revisions, gotLimit)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/checkout/revert/1", nil)))
+	if rec.Code != http.StatusOK || gotRevision != 1 || !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Errorf("revert = %d %s for revision %d, want 200 with the reverted flag", rec.Code, rec.Body.String(), gotRevision)
+	}
+
+	tests := []struct {
+		method, target string
+		want           int
+		wantType       string
+	}{
+		{http.MethodGet, "/v1/flags/missing/history", http.StatusNotFound, "flag-not-found"},
+		{http.MethodGet, "/v1/flags/checkout/history?limit=0", http.StatusBadRequest, "invalid-request"},
+		{http.MethodPost, "/v1/flags/checkout/revert/9", http.StatusNotFound, "flag-revision-not-found"},
+		{http.MethodPost, "/v1/flags/checkout/revert/latest", http.StatusBadRequest, "invalid-request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(tt.method, tt.target, nil)))
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), middleware.ProblemType(tt.wantType)) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerSetFlagTargets(t *testing.T) {
+	var got repository.FlagTargets
+	svc := &fakeService{
+		setFlagTargetsFunc: func(_ context.Context, _, key string, targets repository.FlagTargets) (repository.Flag, error) {
+			if key != "checkout" {
+				return repository.Flag{}, service.ErrFlagNotFound
+			}
+			if targets.Attribute == "" {
+				return repository.Flag{}, fmt.Errorf("%w: attribute is required", service.ErrInvalidTargets)
+			}
+			got = targets
+			return repository.Flag{Key: key, Enabled: true, Targets: targets}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	body := `{"attribute":"user_id","allow":["alice"],"deny":["mallory"]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/flags/checkout/targets", strings.NewReader(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusOK)
+	}
+	if got.Attribute != "user_id" || !slices.Equal(got.Allow, []string{"alice"}) || !slices.Equal(got.Deny, []string{"mallory"}) {
+		t.Errorf("targets = %+v, want the request body", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"targets":{"attribute":"user_id","allow":["alice"],"deny":["mallory"]}`) {
+		t.Errorf("body = %s, want the flag with its targets", rec.Body.String())
+	}
+
+	tests := []struct {
+		target, body string
+		want         int
+		wantType     string
+	}{
+		{"/v1/flags/missing/targets", body, http.StatusNotFound, "flag-not-found"},
+		{"/v1/flags/checkout/targets", `{"allow":["alice"]}`, http.StatusBadRequest, "invalid-targets"},
+		{"/v1/flags/checkout/targets", `{"allow":"alice"}`, http.StatusBadRequest, "invalid-request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.body, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))))
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), middleware.ProblemType(tt.wantType)) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerPatchFlag(t *testing.T) {
+	var gotType, gotPatch string
+	svc := &fakeService{
+		patchFlagFunc: func(_ context.Context, _, key, patchType string, patch json.RawMessage) (repository.Flag, error) {
+			switch {
+			case key != "checkout":
+				return repository.Flag{}, service.ErrFlagNotFound
+			case strings.Contains(string(patch), `"test"`):
+				return repository.Flag{}, fmt.Errorf("%w: value at \"/enabled\" differs", service.ErrPatchTestFailed)
+			case strings.Contains(string(patch), `"key"`):
+				return repository.Flag{}, fmt.Errorf("%w: key cannot be changed", service.ErrInvalidPatch)
+			}
+			gotType, gotPatch = patchType, string(patch)
+			return repository.Flag{Key: key, Enabled: true}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	patchReq := func(target, contentType, body string) *http.Request {
+		req := reqWithProject(httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body)))
+		req.Header.Set("Content-Type", contentType)
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, patchReq("/v1/flags/checkout", "application/merge-patch+json; charset=utf-8", `{"enabled":true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusOK)
+	}
+	if gotType != service.PatchTypeMerge || gotPatch != `{"enabled":true}` {
+		t.Errorf("PatchFlag(%q, %s), want the merge patch", gotType, gotPatch)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, patchReq("/v1/flags/checkout", service.PatchTypeJSON, `[{"op":"replace","path":"/enabled","value":true}]`))
+	if rec.Code != http.StatusOK || gotType != service.PatchTypeJSON {
+		t.Fatalf("JSON Patch response = %d %s, patch type %q", rec.Code, rec.Body.String(), gotType)
+	}
+
+	tests := []struct {
+		name, target, contentType, body string
+		want                            int
+		wantType                        string
+	}{
+		{"missing flag", "/v1/flags/missing", service.PatchTypeMerge, `{}`, http.StatusNotFound, "flag-not-found"},
+		{"plain JSON", "/v1/flags/checkout", "application/json", `{}`, http.StatusUnsupportedMediaType, "unsupported-media-type"},
+		{"malformed body", "/v1/flags/checkout", service.PatchTypeMerge, `{`, http.StatusBadRequest, "invalid-request"},
+		{"invalid patch", "/v1/flags/checkout", service.PatchTypeMerge, `{"key":"other"}`, http.StatusBadRequest, "invalid-patch"},
+		{"failed test", "/v1/flags/checkout", service.PatchTypeJSON, `[{"op":"test","path":"/enabled","value":false}]`, http.StatusConflict, "patch-test-failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, patchReq(tt.target, tt.contentType, tt.body))
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), middleware.ProblemType(tt.wantType)) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerCopyFlag(t *testing.T) {
+	var got service.CopyFlagRequest
+	svc := &fakeService{
+		copyFlagFunc: func(_ context.Context, req service.CopyFlagRequest) (repository.Flag, bool, error) {
+			got = req
+			if req.TargetKey == "taken" {
+				return repository.Flag{}, false, service.ErrFlagExists
+			}
+			return repository.Flag{ProjectID: req.TargetProjectID, Key: req.SourceKey}, !req.Overwrite, nil
+		},
+		authorizeAdminAPIKeyFunc: func(_ context.Context, keyID string) error {
+			if keyID != "admin-key" {
+				return service.ErrAdminKeyRequired
+			}
+			return nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	copyReq := func(body, keyID string) *http.Request {
+		req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/checkout/copy", strings.NewReader(body)))
+		return req.WithContext(middleware.NewContextWithAPIKeyID(req.Context(), keyID))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, copyReq(`{"target_project":"prod","include_rules":true}`, "admin-key"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusCreated)
+	}
+	want := service.CopyFlagRequest{SourceProjectID: "default", SourceKey: "checkout", TargetProjectID: "prod", IncludeRules: true}
+	if got != want {
+		t.Errorf("CopyFlag request = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name, body, keyID string
+		want              int
+		wantType          string
+	}{
+		{"overwrite", `{"target_project":"prod","overwrite":true}`, "admin-key", http.StatusOK, ""},
+		{"same project needs no admin key", `{"target_key":"checkout-v2"}`, "key", http.StatusCreated, ""},
+		{"other project needs admin key", `{"target_project":"prod"}`, "key", http.StatusForbidden, "admin-key-required"},
+		{"target exists", `{"target_key":"taken"}`, "key", http.StatusConflict, "flag-exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, copyReq(tt.body, tt.keyID))
+			if rec.Code != tt.want || (tt.wantType != "" && !strings.Contains(rec.Body.String(), middleware.ProblemType(tt.wantType))) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerFlagTemplates(t *testing.T) {
+	var got service.FlagFromTemplateRequest
+	svc := &fakeService{
+		createFromTemplateFunc: func(_ context.Context, req service.FlagFromTemplateRequest) (repository.Flag, error) {
+			got = req
+			switch req.Template {
+			case "kill-switch":
+				return repository.Flag{ProjectID: req.ProjectID, Key: req.Key, Enabled: true}, nil
+			case "percentage-rollout":
+				return repository.Flag{}, fmt.Errorf("%w: \"percentage\" must be between 0 and 100", service.ErrInvalidFlagTemplateParameters)
+			}
+			return repository.Flag{}, service.ErrFlagTemplateNotFound
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flag-templates", nil)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"kill-switch"`) {
+		t.Fatalf("GET /v1/flag-templates = %d %s, want 200 listing kill-switch", rec.Code, rec.Body.String())
+	}
+
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/from-template", strings.NewReader(body))))
+		return rec
+	}
+	rec = create(`{"template":"kill-switch","key":"search","owner":"team:search","parameters":{"x":"1"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusCreated)
+	}
+	if got.ProjectID != "default" || got.Key != "search" || got.Owner != "team:search" || got.Parameters["x"] != "1" || got.Enabled != nil {
+		t.Errorf("CreateFlagFromTemplate request = %+v", got)
+	}
+
+	tests := []struct {
+		name, body string
+		want       int
+		wantType   string
+	}{
+		{"missing key", `{"template":"kill-switch"}`, http.StatusBadRequest, "invalid-request"},
+		{"unknown template", `{"template":"nope","key":"a"}`, http.StatusNotFound, "flag-template-not-found"},
+		{"invalid parameters", `{"template":"percentage-rollout","key":"a","parameters":{"percentage":150}}`, http.StatusBadRequest, "invalid-flag-template-parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := create(tt.body)
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), middleware.ProblemType(tt.wantType)) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestHTTPHandlerContextPresets(t *testing.T) {
+	var stored repository.ContextPreset
+	var resolved []service.ResolveRequest
+	svc := &fakeService{
+		putContextPresetFunc: func(_ context.Context, preset repository.ContextPreset) (repository.ContextPreset, error) {
+			stored = preset
+			return preset, nil
+		},
+		getContextPresetFunc: func(_ context.Context, _, name string) (repository.ContextPreset, error) {
+			if name != stored.Name {
+				return repository.ContextPreset{}, service.ErrContextPresetNotFound
+			}
+			return stored, nil
+		},
+		resolveBatchFunc: func(_ context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			resolved = requests
+			return []service.ResolveResult{{Key: "checkout", Value: true}}, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	body := `{"description":"Free plan, France","context":{"attributes":{"country":"FR","plan":"free"}}}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/context-presets/EU%20free-tier%20user", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if stored.ProjectID != "default" || stored.Name != "EU free-tier user" || stored.Context.Attributes["plan"] != "free" {
+		t.Fatalf("stored preset = %+v", stored)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/context-presets/internal%20tester", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "context-preset-not-found") {
+		t.Fatalf("missing preset = %d %s, want 404 context-preset-not-found", rec.Code, rec.Body.String())
+	}
+
+	body = `{"requests":[{"key":"checkout","preset":"EU free-tier user","context":{"attributes":{"plan":"pro"}}}]}`
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("evaluate status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(resolved) != 1 || resolved[0].Preset != "EU free-tier user" || resolved[0].Context.Attributes["plan"] != "pro" {
+		t.Fatalf("resolve requests = %+v, want the preset name and inline context passed through", resolved)
+	}
+}
+
+func TestHTTPHandlerProposals(t *testing.T) {
+	var proposed repository.FlagProposal
+	var listedKey, listedStatus string
+	svc := &fakeService{
+		proposeFlagChangeFunc: func(_ context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error) {
+			proposed = proposal
+			proposal.ID = "prop-1"
+			proposal.Status = repository.ProposalStatusPending
+			return proposal, nil
+		},
+		listFlagProposalsFunc: func(_ context.Context, _ string, flagKey, status string) ([]repository.FlagProposal, error) {
+			listedKey, listedStatus = flagKey, status
+			return []repository.FlagProposal{{ID: "prop-1"}}, nil
+		},
+		approveFlagProposalFunc: func(_ context.Context, _, _ string) (repository.FlagProposal, error) {
+			return repository.FlagProposal{}, service.ErrSelfApproval
+		},
+		rejectFlagProposalFunc: func(_ context.Context, _, _ string) (repository.FlagProposal, error) {
+			return repository.FlagProposal{}, service.ErrProposalNotPending
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	body := `{"flag":{"enabled":true}}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/checkout/proposals", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("propose status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if proposed.FlagKey != "checkout" || proposed.Action != repository.ProposalActionUpdate {
+		t.Fatalf("proposal = %+v, want update of checkout", proposed)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags/checkout/proposals?status=pending", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if listedKey != "checkout" || listedStatus != "pending" {
+		t.Fatalf("list filters = %q/%q, want checkout/pending", listedKey, listedStatus)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/proposals?status=bogus", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad status filter = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/proposals/prop-1/approve", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("self-approve status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/proposals/prop-1/reject", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("reject reviewed status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
 func TestHTTPHandlerCreateFlagOversizedBody(t *testing.T) {
 	svc := &fakeService{
 		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
@@ -109,7 +1022,7 @@ func TestHTTPHandlerCreateFlagOversizedBody(t *testing.T) {
 	if rec.Code != http.StatusRequestEntityTooLarge {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
 	}
-	if !strings.Contains(rec.Body.String(), `"error":"request body too large"`) {
+	if !strings.Contains(rec.Body.String(), `"detail":"request body too large"`) {
 		t.Fatalf("body = %q, want request body too large error", rec.Body.String())
 	}
 }
@@ -129,11 +1042,64 @@ func TestHTTPHandlerCreateFlagInvalidRulesReturnsBadRequest(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
-	if !strings.Contains(rec.Body.String(), `"error":"invalid rules"`) {
+	if !strings.Contains(rec.Body.String(), `"detail":"invalid rules"`) {
 		t.Fatalf("body = %q, want invalid rules error", rec.Body.String())
 	}
 }
 
+func TestHTTPHandlerCreateFlagListsInvalidRules(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
+			return repository.Flag{}, &service.RulesError{Rules: []core.RuleError{
+				{Index: 0, Reason: `unknown operator "matches"`},
+				{Index: 2, Reason: "percentage must be a number between 0 and 100"},
+			}}
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"new-ui","rules":[]}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	want := `{"type":"urn:flagz:problem:invalid-rules","title":"Bad Request","status":400,"detail":"invalid rules","errors":[{"field":"rules[0]","detail":"unknown operator \"matches\""},{"field":"rules[2]","detail":"percentage must be a number between 0 and 100"}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+
+	// The legacy format keeps the original shape.
+	rec = httptest.NewRecorder()
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"new-ui","rules":[]}`)))
+	middleware.LegacyErrors(handler).ServeHTTP(rec, req)
+	want = `{"error":"invalid rules","rule_errors":[{"index":0,"reason":"unknown operator \"matches\""},{"index":2,"reason":"percentage must be a number between 0 and 100"}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("legacy body = %s, want %s", got, want)
+	}
+}
+
+func TestHTTPHandlerCreateFlagListsInvalidShadowRules(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
+			return repository.Flag{}, &service.RulesError{Field: "shadow_rules", Rules: []core.RuleError{
+				{Index: 1, Reason: `unknown operator "matches"`},
+			}}
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"new-ui","shadow_rules":[]}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	want := `"errors":[{"field":"shadow_rules[1]","detail":"unknown operator \"matches\""}]`
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("response = %d %s, want 400 with %s", rec.Code, rec.Body.String(), want)
+	}
+}
+
 func TestHTTPHandlerCreateFlagInvalidVariantsReturnsBadRequest(t *testing.T) {
 	svc := &fakeService{
 		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
@@ -149,11 +1115,191 @@ func TestHTTPHandlerCreateFlagInvalidVariantsReturnsBadRequest(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
-	if !strings.Contains(rec.Body.String(), `"error":"invalid variants"`) {
+	if !strings.Contains(rec.Body.String(), `"detail":"invalid variants"`) {
 		t.Fatalf("body = %q, want invalid variants error", rec.Body.String())
 	}
 }
 
+func TestHTTPHandlerCreateFlagListsVariantsSchemaErrors(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, _ repository.Flag) (repository.Flag, error) {
+			return repository.Flag{}, &service.VariantsError{Errors: []jsonschema.Error{
+				{Path: "/blue", Message: `missing required property "color"`},
+				{Path: "/red/size", Message: `must be one of ["s","m","l"]`},
+			}}
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"theme"}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	want := `{"type":"urn:flagz:problem:invalid-variants","title":"Bad Request","status":400,"detail":"invalid variants","errors":[{"field":"variants/blue","detail":"missing required property \"color\""},{"field":"variants/red/size","detail":"must be one of [\"s\",\"m\",\"l\"]"}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	req = reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"theme"}`)))
+	middleware.LegacyErrors(handler).ServeHTTP(rec, req)
+	want = `{"error":"invalid variants","variant_errors":[{"path":"/blue","message":"missing required property \"color\""},{"path":"/red/size","message":"must be one of [\"s\",\"m\",\"l\"]"}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("legacy body = %s, want %s", got, want)
+	}
+}
+
+func TestHTTPHandlerEvaluateTypedDefaults(t *testing.T) {
+	var got []service.ResolveRequest
+	svc := &fakeService{
+		resolveBatchFunc: func(_ context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
+			got = requests
+			results := make([]service.ResolveResult, len(requests))
+			for i, request := range requests {
+				results[i] = service.ResolveResult{Key: request.Key, Value: request.DefaultValue, Type: service.ValueTypeBoolean, Reason: core.ReasonFlagNotFound}
+				if request.TypedDefault != nil {
+					results[i].Type = service.TypedValueType(request.TypedDefault)
+					results[i].TypedValue = request.TypedDefault
+				}
+			}
+			return results, nil
+		},
+	}
+	handler := NewHTTPHandler(svc)
+
+	body := `{"requests":[
+		{"key":"a","default_value":true},
+		{"key":"b"},
+		{"key":"c","default_value":null},
+		{"key":"d","default_value":"light"},
+		{"key":"e","default_value":20},
+		{"key":"f","default_value":{"limit":5}}
+	]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	if len(got) != 6 || !got[0].DefaultValue || got[0].TypedDefault != nil || got[1].DefaultValue || got[1].TypedDefault != nil || got[2].TypedDefault != nil {
+		t.Fatalf("boolean requests = %+v", got)
+	}
+	for i, want := range []string{`"light"`, `20`, `{"limit":5}`} {
+		if string(got[3+i].TypedDefault) != want {
+			t.Errorf("requests[%d].TypedDefault = %s, want %s", 3+i, got[3+i].TypedDefault, want)
+		}
+	}
+	for _, want := range []string{
+		`{"key":"a","value":true,"type":"boolean","reason":"FLAG_NOT_FOUND"}`,
+		`"value":"light"`,
+		`"type":"string"`,
+		`"value":20`,
+		`"type":"number"`,
+		`"value":{"limit":5}`,
+		`"type":"json"`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("body = %s, want %s", rec.Body, want)
+		}
+	}
+}
+
+func TestHTTPHandlerExplain(t *testing.T) {
+	var gotKey, gotPreset string
+	var gotContext core.EvaluationContext
+	svc := &fakeService{
+		explainFlagFunc: func(_ context.Context, _, key string, evalContext core.EvaluationContext, preset string) (service.Explanation, error) {
+			gotKey, gotContext, gotPreset = key, evalContext, preset
+			if key == "missing" {
+				return service.Explanation{}, service.ErrFlagNotFound
+			}
+			ruleIndex := 0
+			return service.Explanation{
+				ResolveResult: service.ResolveResult{Key: key, Value: true, Type: service.ValueTypeBoolean, Reason: core.ReasonRuleMatch, RuleIndex: &ruleIndex},
+				Context:       evalContext,
+				Steps: []core.TraceStep{{
+					Kind:      core.StepRule,
+					RuleIndex: &ruleIndex,
+					Rule:      &core.Rule{Attribute: "country", Operator: core.OperatorEquals, Value: "NZ"},
+					Attribute: "country",
+					Value:     "NZ",
+					Present:   true,
+					Matched:   true,
+					Detail:    `"NZ" equals "NZ"`,
+				}},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate/explain", strings.NewReader(`{"key":"checkout","context":{"attributes":{"country":"NZ"}},"preset":"kiwi"}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	want := `{"key":"checkout","value":true,"type":"boolean","reason":"RULE_MATCH","rule_index":0,"context":{"attributes":{"country":"NZ"}},"steps":[{"kind":"rule","rule_index":0,"rule":{"attribute":"country","operator":"equals","value":"NZ"},"attribute":"country","value":"NZ","present":true,"matched":true,"detail":"\"NZ\" equals \"NZ\""}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+	if gotKey != "checkout" || gotPreset != "kiwi" || gotContext.Attributes["country"] != "NZ" {
+		t.Fatalf("ExplainFlag(%q, %v, %q), want the request's key, context and preset", gotKey, gotContext, gotPreset)
+	}
+
+	for body, status := range map[string]int{
+		`{"key":"missing"}`: http.StatusNotFound,
+		`{"context":{}}`:    http.StatusBadRequest,
+	} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate/explain", strings.NewReader(body))))
+		if rec.Code != status {
+			t.Errorf("%s status = %d, want %d", body, rec.Code, status)
+		}
+	}
+}
+
+func TestHTTPHandlerEvaluateAll(t *testing.T) {
+	var gotProject, gotPreset string
+	var gotContext core.EvaluationContext
+	svc := &fakeService{
+		resolveAllFunc: func(_ context.Context, projectID string, evalContext core.EvaluationContext, preset string) ([]service.ResolveResult, error) {
+			gotProject, gotContext, gotPreset = projectID, evalContext, preset
+			warnings := []service.ContextWarning{{Attribute: "county", Kind: service.ContextWarningUnknownAttribute, Message: `unknown attribute "county"`}}
+			return []service.ResolveResult{
+				{Key: "beta", Value: true, Reason: core.ReasonRuleMatch, Warnings: warnings},
+				{Key: "checkout", Value: false, Reason: core.ReasonDisabled, Warnings: warnings},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate/all", strings.NewReader(`{"context":{"attributes":{"county":"US"}},"preset":"beta-testers"}`)))
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	want := `{"values":{"beta":true,"checkout":false},"warnings":[{"attribute":"county","kind":"unknown_attribute","message":"unknown attribute \"county\""}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+	if gotProject != "default" || gotPreset != "beta-testers" || gotContext.Attributes["county"] != "US" {
+		t.Fatalf("ResolveAll(%q, %v, %q), want the default project, context and preset", gotProject, gotContext, gotPreset)
+	}
+
+	svc.resolveAllFunc = func(context.Context, string, core.EvaluationContext, string) ([]service.ResolveResult, error) {
+		return nil, service.ErrContextPresetNotFound
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate/all", strings.NewReader(`{"preset":"missing"}`))))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing preset status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
 func TestHTTPHandlerStreamReplaysFromLastEventID(t *testing.T) {
 	sinceCalls := make([]int64, 0)
 	svc := &fakeService{
@@ -222,73 +1368,325 @@ func TestHTTPHandlerStreamCompactsPayloadToSingleDataLine(t *testing.T) {
 		},
 	}
 
-	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
-	defer cancel()
-
-	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `data: {"key":"new-ui","enabled":true}`) {
+		t.Fatalf("stream body missing compact payload: %q", body)
+	}
+	if strings.Contains(body, "data: {\n") {
+		t.Fatalf("stream body should not contain multiline data payload: %q", body)
+	}
+}
+
+func TestHTTPHandlerStreamSchemaVersions(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, since int64) ([]repository.FlagEvent, error) {
+			if since != 0 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{
+				EventID:   1,
+				ProjectID: "proj1",
+				FlagKey:   "new-ui",
+				EventType: service.EventTypeUpdated,
+				Payload:   json.RawMessage(`{"key":"new-ui","enabled":true}`),
+			}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
+
+	for _, tt := range []struct {
+		query       string
+		wantVersion string
+		wantData    string
+	}{
+		{query: "", wantVersion: "1", wantData: `data: {"key":"new-ui","enabled":true}`},
+		{query: "?schema_version=1", wantVersion: "1", wantData: `data: {"key":"new-ui","enabled":true}`},
+		{query: "?schema_version=2", wantVersion: "2", wantData: `data: {"schema_version":2,"project_id":"proj1","key":"new-ui","flag":{"key":"new-ui","enabled":true}}`},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream"+tt.query, nil).WithContext(ctx))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		cancel()
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get(SchemaVersionHeader); got != tt.wantVersion {
+			t.Errorf("%q: %s = %q, want %q", tt.query, SchemaVersionHeader, got, tt.wantVersion)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, tt.wantData) {
+			t.Errorf("%q: stream body = %q, want %s", tt.query, body, tt.wantData)
+		}
+	}
+}
+
+func TestHTTPHandlerStreamInitialFetchErrorReturnsHTTPError(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			return nil, errors.New("backend failure")
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Content-Type"); got != middleware.ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %s", got, middleware.ProblemContentType)
+	}
+	if !strings.Contains(rec.Body.String(), `"detail":"internal server error"`) {
+		t.Fatalf("body = %q, want internal server error json", rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerStreamFlushesHeadersWithoutInitialEvents(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			return nil, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want %q", got, "text/event-stream")
+	}
+	if !rec.Flushed {
+		t.Fatal("stream should flush headers even without initial events")
+	}
+}
+
+func TestHTTPHandlerSDKConfig(t *testing.T) {
+	svc := &fakeService{
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			return nil, nil
+		},
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			return nil, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithSDKConfig(SDKConfig{
+		PollInterval:      time.Minute,
+		MaxBatchSize:      20,
+		HeartbeatInterval: 5 * time.Millisecond,
+	}))
+	want := `{"poll_interval_ms":60000,"max_batch_size":20,"heartbeat_interval_ms":5}`
+
 	rec := httptest.NewRecorder()
-	handler.ServeHTTP(rec, req)
-
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags", nil)))
 	if rec.Code != http.StatusOK {
-		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		t.Fatalf("list status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get(SDKConfigHeader); got != want {
+		t.Fatalf("list %s = %q, want %q", SDKConfigHeader, got, want)
 	}
 
-	body := rec.Body.String()
-	if !strings.Contains(body, `data: {"key":"new-ui","enabled":true}`) {
-		t.Fatalf("stream body missing compact payload: %q", body)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx)))
+	if got := rec.Header().Get(SDKConfigHeader); got != want {
+		t.Fatalf("stream %s = %q, want %q", SDKConfigHeader, got, want)
 	}
-	if strings.Contains(body, "data: {\n") {
-		t.Fatalf("stream body should not contain multiline data payload: %q", body)
+	if !strings.Contains(rec.Body.String(), "event: heartbeat\ndata: {\"event_id\":0,\"server_time\":") {
+		t.Fatalf("stream body missing heartbeat: %q", rec.Body.String())
 	}
 }
 
-func TestHTTPHandlerStreamInitialFetchErrorReturnsHTTPError(t *testing.T) {
+func TestHTTPHandlerStreamPushesAnnouncedEvents(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		events  []repository.FlagEvent
+		changed = make(chan struct{})
+	)
 	svc := &fakeService{
-		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
-			return nil, errors.New("backend failure")
+		listEventsSinceFunc: func(_ context.Context, _ string, since int64) ([]repository.FlagEvent, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var after []repository.FlagEvent
+			for _, event := range events {
+				if event.EventID > since {
+					after = append(after, event)
+				}
+			}
+			return after, nil
+		},
+		eventsChangedFunc: func() <-chan struct{} {
+			mu.Lock()
+			defer mu.Unlock()
+			return changed
 		},
 	}
+	// An hour-long poll interval: only the announcement can deliver the event.
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
 
-	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
-	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, repository.FlagEvent{
+			EventID:   1,
+			FlagKey:   "new-ui",
+			EventType: service.EventTypeUpdated,
+			Payload:   json.RawMessage(`{"key":"new-ui"}`),
+		})
+		close(changed)
+		changed = make(chan struct{})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
 	rec := httptest.NewRecorder()
-	handler.ServeHTTP(rec, req)
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx)))
 
-	if rec.Code != http.StatusInternalServerError {
-		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
-	}
-	if got := rec.Header().Get("Content-Type"); !strings.Contains(got, "application/json") {
-		t.Fatalf("Content-Type = %q, want application/json", got)
-	}
-	if !strings.Contains(rec.Body.String(), `"error":"internal server error"`) {
-		t.Fatalf("body = %q, want internal server error json", rec.Body.String())
+	if body := rec.Body.String(); !strings.Contains(body, "id: 1\nevent: update\n") {
+		t.Fatalf("stream body missing pushed event: %q", body)
 	}
 }
 
-func TestHTTPHandlerStreamFlushesHeadersWithoutInitialEvents(t *testing.T) {
+func TestHTTPHandlerStreamKeepaliveAndRetry(t *testing.T) {
 	svc := &fakeService{
 		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
 			return nil, nil
 		},
 	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithStreamKeepalive(5*time.Millisecond))
 
-	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
 	defer cancel()
-
-	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
 	rec := httptest.NewRecorder()
-	handler.ServeHTTP(rec, req)
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx)))
+
+	body := rec.Body.String()
+	retry, _, ok := strings.Cut(strings.TrimPrefix(body, "retry: "), "\n\n")
+	if !strings.HasPrefix(body, "retry: ") || !ok {
+		t.Fatalf("stream should open with a retry field: %q", body)
+	}
+	ms, err := strconv.Atoi(retry)
+	if err != nil || ms < int(sseRetryMin.Milliseconds()) || ms >= int((sseRetryMin+sseRetryJitter).Milliseconds()) {
+		t.Fatalf("retry = %q, want milliseconds in [%v, %v)", retry, sseRetryMin, sseRetryMin+sseRetryJitter)
+	}
+	if !strings.Contains(body, ": keepalive\n\n") {
+		t.Fatalf("idle stream body missing keepalive: %q", body)
+	}
+}
+
+func TestSSEKeepaliveDisabled(t *testing.T) {
+	keepalive := newSSEKeepalive(0)
+	defer keepalive.stop()
+	keepalive.reset()
+	if keepalive.C() != nil {
+		t.Fatal("disabled keepalive should have a nil channel")
+	}
+}
+
+func TestWriteSSEHeartbeat(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	id := instance.Identity{Region: "eu-west-1", Hostname: "flagz-0", Version: "v1.4.0", GitSHA: "abc123"}
+	if err := writeSSEHeartbeat(&buf, 42, now, id); err != nil {
+		t.Fatalf("writeSSEHeartbeat() error = %v", err)
+	}
+	want := "event: heartbeat\ndata: {\"event_id\":42,\"server_time\":\"2024-05-01T10:00:00Z\",\"instance\":{\"region\":\"eu-west-1\",\"hostname\":\"flagz-0\",\"version\":\"v1.4.0\",\"git_sha\":\"abc123\"}}\n\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("heartbeat = %q, want %q", got, want)
+	}
+}
+
+func TestHTTPHandlerInfo(t *testing.T) {
+	id := instance.Identity{Region: "eu-west-1", Hostname: "flagz-0", Version: "v1.4.0", GitSHA: "abc123"}
+	handler := NewHTTPHandlerWithStreamPollInterval(&fakeService{}, time.Hour, WithInstance(id))
 
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/info", nil)))
 	if rec.Code != http.StatusOK {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
-		t.Fatalf("Content-Type = %q, want %q", got, "text/event-stream")
+	var got instance.Identity
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got != id {
+		t.Fatalf("GET /v1/info = %s, %v, want %+v", rec.Body.String(), err, id)
 	}
-	if !rec.Flushed {
-		t.Fatal("stream should flush headers even without initial events")
+}
+
+func TestHTTPHandlerSDKRuleset(t *testing.T) {
+	generation := int64(7)
+	svc := &fakeService{
+		rulesetFunc: func(_ context.Context, projectID string) (service.Ruleset, error) {
+			defaultValue := false
+			return service.Ruleset{
+				ProjectID:  projectID,
+				Generation: generation,
+				Flags: []service.RulesetFlag{{
+					Key:     "checkout",
+					Enabled: true,
+					Default: &defaultValue,
+					Rules:   []core.Rule{{Attribute: "country", Operator: core.OperatorEquals, Value: "NZ"}},
+				}},
+			}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithSDKConfig(SDKConfig{PollInterval: time.Minute}))
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/sdk/config", nil))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusOK)
+	}
+	want := `{"project_id":"default","generation":7,"flags":[{"key":"checkout","enabled":true,"default":false,"rules":[{"attribute":"country","operator":"equals","value":"NZ"}]}]}`
+	if rec.Body.String() != want {
+		t.Errorf("body = %s, want %s", rec.Body.String(), want)
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || rec.Header().Get(SDKConfigHeader) == "" {
+		t.Fatalf("headers = %v, want a strong ETag and the SDK config hints", rec.Header())
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		if rec := get(header); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status = %d, want %d with no body", header, rec.Code, http.StatusNotModified)
+		}
+	}
+
+	generation++
+	rec = get(etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("after a change: status = %d ETag = %s, want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
 	}
 }
 
@@ -444,11 +1842,395 @@ func TestHTTPHandlerDeleteAPIKeyNotFound(t *testing.T) {
 	if rec.Code != http.StatusNotFound {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
 	}
-	if !strings.Contains(rec.Body.String(), `"error":"api key not found"`) {
+	if !strings.Contains(rec.Body.String(), `"detail":"api key not found"`) {
 		t.Fatalf("body = %q, want api key not found error", rec.Body.String())
 	}
 }
 
+func TestHTTPHandlerKeyRotationPolicy(t *testing.T) {
+	var stored repository.KeyRotationPolicy
+	svc := &fakeService{
+		getKeyRotationPolicyFunc: func(_ context.Context, _ string) (repository.KeyRotationPolicy, error) {
+			return stored, nil
+		},
+		setKeyRotationPolicyFunc: func(_ context.Context, _ string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error) {
+			if policy.RevokeAfter > 0 && policy.MaxAge == 0 {
+				return repository.KeyRotationPolicy{}, service.ErrInvalidKeyRotationPolicy
+			}
+			stored = policy
+			return policy, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/api-keys/rotation-policy", strings.NewReader(`{"max_age_seconds":7776000,"revoke_after_seconds":604800}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if want := (repository.KeyRotationPolicy{MaxAge: 90 * 24 * time.Hour, RevokeAfter: 7 * 24 * time.Hour}); stored != want {
+		t.Fatalf("stored policy = %+v, want %+v", stored, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/api-keys/rotation-policy", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"max_age_seconds":7776000,"revoke_after_seconds":604800}`; rec.Code != http.StatusOK || got != want {
+		t.Fatalf("GET = %d %s, want 200 %s", rec.Code, got, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/api-keys/rotation-policy", strings.NewReader(`{"revoke_after_seconds":60}`)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid policy status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerAPIKeyQuota(t *testing.T) {
+	var stored repository.APIKeyQuota
+	svc := &fakeService{
+		getAPIKeyQuotaFunc: func(_ context.Context, _ string) (repository.APIKeyQuota, error) {
+			return stored, nil
+		},
+		setAPIKeyQuotaFunc: func(_ context.Context, _ string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error) {
+			if quota.HourlyEvaluations < 0 || quota.HourlyMutations < 0 {
+				return repository.APIKeyQuota{}, service.ErrInvalidAPIKeyQuota
+			}
+			stored = quota
+			return quota, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/api-keys/quota", strings.NewReader(`{"hourly_evaluations":100000,"hourly_mutations":50}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if want := (repository.APIKeyQuota{HourlyEvaluations: 100000, HourlyMutations: 50}); stored != want {
+		t.Fatalf("stored quota = %+v, want %+v", stored, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/api-keys/quota", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"hourly_evaluations":100000,"hourly_mutations":50}`; rec.Code != http.StatusOK || got != want {
+		t.Fatalf("GET = %d %s, want 200 %s", rec.Code, got, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodPut, "/v1/api-keys/quota", strings.NewReader(`{"hourly_mutations":-1}`)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid quota status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerUsage(t *testing.T) {
+	hour := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var gotSince time.Time
+	svc := &fakeService{
+		getAPIKeyQuotaFunc: func(_ context.Context, _ string) (repository.APIKeyQuota, error) {
+			return repository.APIKeyQuota{HourlyMutations: 50}, nil
+		},
+		listAPIKeyUsageFunc: func(_ context.Context, _ string, since time.Time) ([]repository.APIKeyUsage, error) {
+			gotSince = since
+			return []repository.APIKeyUsage{{APIKeyID: "key-1", Hour: hour, Evaluations: 12, Mutations: 3, StreamSeconds: 600}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/usage?since=2026-03-01T09:30:00Z", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !gotSince.Equal(hour) {
+		t.Errorf("since = %v, want %v (truncated to the hour)", gotSince, hour)
+	}
+	want := `{"since":"2026-03-01T09:00:00Z","quota":{"hourly_evaluations":0,"hourly_mutations":50},"usage":[{"api_key_id":"key-1","hour":"2026-03-01T09:00:00Z","evaluations":12,"mutations":3,"stream_seconds":600}]}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+
+	req = reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/usage?since=yesterday", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerMetersAPIKeyUsage(t *testing.T) {
+	var kinds []service.UsageKind
+	svc := &fakeService{
+		allowAPIKeyUsageFunc: func(_ context.Context, projectID, apiKeyID string, kind service.UsageKind) error {
+			if projectID != "default" || apiKeyID != "key-1" {
+				t.Errorf("AllowAPIKeyUsage(%q, %q), want default, key-1", projectID, apiKeyID)
+			}
+			kinds = append(kinds, kind)
+			if kind == service.UsageMutation {
+				return service.ErrAPIKeyQuotaExceeded
+			}
+			return nil
+		},
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			return nil, nil
+		},
+		deleteFlagFunc: func(_ context.Context, _, _ string) error {
+			t.Fatal("DeleteFlag should not be called over quota")
+			return nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		ctx := middleware.NewContextWithAPIKeyID(ctxWithProject(), "key-1")
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	do(http.MethodPost, "/v1/evaluate", `{"key":"f"}`)
+	do(http.MethodGet, "/v1/flags", "")
+	rec := do(http.MethodDelete, "/v1/flags/f", "")
+
+	if want := []service.UsageKind{service.UsageEvaluation, service.UsageMutation}; !slices.Equal(kinds, want) {
+		t.Fatalf("metered kinds = %v, want %v", kinds, want)
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over-quota status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("Retry-After header missing")
+	}
+	if !strings.Contains(rec.Body.String(), "api-key-quota-exceeded") {
+		t.Errorf("body = %s, want api-key-quota-exceeded problem type", rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerNotificationChannels(t *testing.T) {
+	var created repository.NotificationChannel
+	svc := &fakeService{
+		createNotificationChannelFunc: func(_ context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error) {
+			if channel.Kind != "slack" && channel.Kind != "email" {
+				return repository.NotificationChannel{}, fmt.Errorf("%w: kind must be \"slack\" or \"email\"", service.ErrInvalidNotificationChannel)
+			}
+			created = channel
+			channel.ID = "chan-1"
+			return channel, nil
+		},
+		deleteNotificationChannelFunc: func(_ context.Context, _, id string) error {
+			if id != "chan-1" {
+				return service.ErrNotificationChannelNotFound
+			}
+			return nil
+		},
+		listNotificationsFunc: func(_ context.Context, _ string, limit int) ([]repository.Notification, error) {
+			if limit != 10 {
+				t.Errorf("limit = %d, want 10", limit)
+			}
+			return []repository.Notification{{ID: 7, ChannelID: "chan-1", EventID: 42, FlagKey: "checkout", EventType: "updated", Status: "failed", Attempts: 3, Error: "unexpected status 500"}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(method, target, strings.NewReader(body))))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/v1/notifications/channels", `{"kind":"slack","target":"https://hooks.slack.com/services/T0/B0/x","event_types":["deleted"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if created.ProjectID != "default" || created.Target != "https://hooks.slack.com/services/T0/B0/x" || !slices.Equal(created.EventTypes, []string{"deleted"}) {
+		t.Fatalf("created channel = %+v", created)
+	}
+
+	rec = do(http.MethodPost, "/v1/notifications/channels", `{"kind":"pager","target":"x"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-notification-channel") {
+		t.Fatalf("invalid kind = %d %s, want 400 invalid-notification-channel", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodDelete, "/v1/notifications/channels/chan-1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := do(http.MethodDelete, "/v1/notifications/channels/missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("DELETE missing status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = do(http.MethodGet, "/v1/notifications?limit=10", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"failed"`) || !strings.Contains(rec.Body.String(), `"attempts":3`) {
+		t.Fatalf("GET /v1/notifications = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/v1/notifications?limit=0", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid limit status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPHandlerWebhookSigningSecret(t *testing.T) {
+	var secret *repository.WebhookSigningSecret
+	svc := &fakeService{
+		webhookSigningSecretFunc: func(context.Context, string) (repository.WebhookSigningSecret, error) {
+			if secret == nil {
+				return repository.WebhookSigningSecret{}, service.ErrWebhookSigningSecretNotFound
+			}
+			return repository.WebhookSigningSecret{CreatedAt: secret.CreatedAt}, nil
+		},
+		rotateWebhookSigningSecretFunc: func(_ context.Context, projectID string) (repository.WebhookSigningSecret, bool, error) {
+			if projectID != "default" {
+				t.Errorf("projectID = %q, want default", projectID)
+			}
+			created := secret == nil
+			secret = &repository.WebhookSigningSecret{Secret: "whsec_new", CreatedAt: time.Unix(1700000000, 0).UTC()}
+			return *secret, created, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	do := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(method, "/v1/webhooks/signing-secret", nil)))
+		return rec
+	}
+
+	if rec := do(http.MethodGet); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "webhook-signing-secret-not-found") {
+		t.Fatalf("GET without a secret = %d %s, want 404 webhook-signing-secret-not-found", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost); rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"secret":"whsec_new"`) {
+		t.Fatalf("POST = %d %s, want 201 with the secret", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost); rec.Code != http.StatusOK {
+		t.Fatalf("POST again status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := do(http.MethodGet); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "whsec_") {
+		t.Fatalf("GET = %d %s, want 200 without the secret", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerListEvents(t *testing.T) {
+	var got repository.EventQuery
+	svc := &fakeService{
+		queryEventsFunc: func(_ context.Context, q repository.EventQuery) ([]repository.FlagEvent, int64, error) {
+			got = q
+			if !q.Since.IsZero() && !q.Since.Before(q.Until) {
+				return nil, 0, fmt.Errorf("%w: since must be before until", service.ErrInvalidEventQuery)
+			}
+			return []repository.FlagEvent{{EventID: 7, ProjectID: q.ProjectID, FlagKey: "checkout", EventType: service.EventTypeUpdated, Payload: json.RawMessage(`{}`), Actor: "api_key:k1"}}, 7, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	do := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodGet, target, nil)))
+		return rec
+	}
+
+	rec := do("/v1/events?key=checkout&key=search&types=delete&actor=api_key:k1&since=2026-01-06T00:00:00Z&until=2026-01-07T00:00:00Z&cursor=3&limit=10")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	want := repository.EventQuery{
+		ProjectID: "default",
+		Keys:      []string{"checkout", "search"},
+		Types:     []string{service.EventTypeDeleted},
+		Actor:     "api_key:k1",
+		Since:     time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+		Until:     time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC),
+		AfterID:   3,
+		Limit:     10,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("query = %+v, want %+v", got, want)
+	}
+	var resp struct {
+		Events []struct {
+			EventID int64  `json:"event_id"`
+			Actor   string `json:"actor"`
+		} `json:"events"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].EventID != 7 || resp.Events[0].Actor != "api_key:k1" || resp.NextCursor != "7" {
+		t.Fatalf("response = %s, want event 7 with its actor and next_cursor 7", rec.Body.String())
+	}
+
+	for _, target := range []string{
+		"/v1/events?since=yesterday",
+		"/v1/events?cursor=-1",
+		"/v1/events?limit=0",
+		"/v1/events?types=created",
+	} {
+		if rec := do(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want %d", target, rec.Code, http.StatusBadRequest)
+		}
+	}
+	rec = do("/v1/events?since=2026-01-07T00:00:00Z&until=2026-01-06T00:00:00Z")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-event-query") {
+		t.Fatalf("inverted range = %d %s, want 400 invalid-event-query", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerSegments(t *testing.T) {
+	var stored repository.Segment
+	svc := &fakeService{
+		putSegmentFunc: func(_ context.Context, segment repository.Segment) (repository.Segment, error) {
+			if segment.Name == "loop" {
+				return repository.Segment{}, fmt.Errorf("%w: segment cycle: loop -> loop", service.ErrInvalidSegment)
+			}
+			stored = segment
+			return segment, nil
+		},
+		getSegmentFunc: func(_ context.Context, _, name string) (repository.Segment, error) {
+			if name != stored.Name {
+				return repository.Segment{}, service.ErrSegmentNotFound
+			}
+			return stored, nil
+		},
+		deleteSegmentFunc: func(_ context.Context, _, name string) error {
+			return fmt.Errorf("%w: referenced by flag %q", service.ErrSegmentInUse, "checkout")
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(method, target, strings.NewReader(body))))
+		return rec
+	}
+
+	rec := do(http.MethodPut, "/v1/segments/beta-testers", `{"description":"Beta","rules":[{"attribute":"plan","operator":"equals","value":"pro"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if stored.ProjectID != "default" || stored.Name != "beta-testers" || len(stored.Rules) != 1 || stored.Rules[0].Attribute != "plan" {
+		t.Fatalf("stored segment = %+v", stored)
+	}
+
+	rec = do(http.MethodGet, "/v1/segments/beta-testers", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"beta-testers"`) {
+		t.Fatalf("GET = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/v1/segments/missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET missing status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = do(http.MethodPut, "/v1/segments/loop", `{"rules":[{"operator":"segment","value":"loop"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid-segment") || !strings.Contains(rec.Body.String(), "segment cycle") {
+		t.Fatalf("cyclic PUT = %d %s, want 400 invalid-segment", rec.Code, rec.Body.String())
+	}
+
+	rec = do(http.MethodDelete, "/v1/segments/beta-testers", "")
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "segment-in-use") {
+		t.Fatalf("DELETE in use = %d %s, want 409 segment-in-use", rec.Code, rec.Body.String())
+	}
+}
+
 func TestHTTPHandlerCreateAPIKeyUnauthorized(t *testing.T) {
 	svc := &fakeService{}
 
@@ -639,14 +2421,12 @@ func TestHTTPHandlerListFlagsPaginationWithInvalidLimit(t *testing.T) {
 				t.Fatal("expected ListFlags not to be called for invalid limit")
 			}
 
-			var got struct {
-				Error string `json:"error"`
-			}
+			var got middleware.Problem
 			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
 				t.Fatalf("unmarshal error response: %v", err)
 			}
-			if got.Error != "limit must be a positive integer" {
-				t.Fatalf("error = %q, want %q", got.Error, "limit must be a positive integer")
+			if got.Detail != "limit must be a positive integer" {
+				t.Fatalf("detail = %q, want %q", got.Detail, "limit must be a positive integer")
 			}
 		})
 	}
@@ -714,8 +2494,42 @@ func TestHTTPHandlerListFlagsPaginationCursorAndLimit(t *testing.T) {
 	if got.Flags[0].Key != "flag-0004" || got.Flags[1].Key != "flag-0005" {
 		t.Fatalf("got keys %q, %q; want flag-0004, flag-0005", got.Flags[0].Key, got.Flags[1].Key)
 	}
-	if got.NextCursor != "flag-0005" {
-		t.Fatalf("next_cursor = %q, want %q", got.NextCursor, "flag-0005")
+	if got.NextCursor != "flag-0005" {
+		t.Fatalf("next_cursor = %q, want %q", got.NextCursor, "flag-0005")
+	}
+}
+
+func TestHTTPHandlerListFlagsPaginationReadsPage(t *testing.T) {
+	var got repository.FlagPageQuery
+	svc := &fakeService{
+		listFlagsFunc: func(_ context.Context, _ string) ([]repository.Flag, error) {
+			t.Fatal("ListFlags should not be called when paginating")
+			return nil, nil
+		},
+		listFlagsPageFunc: func(_ context.Context, q repository.FlagPageQuery) ([]repository.Flag, string, error) {
+			got = q
+			return []repository.Flag{{Key: "flag-0004"}}, "flag-0004", nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond)
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/flags?cursor=flag-0003&limit=1&tag=beta&tag=ops", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	want := repository.FlagPageQuery{ProjectID: "default", AfterKey: "flag-0003", Tags: []string{"beta", "ops"}, Limit: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListFlagsPage() query = %+v, want %+v", got, want)
+	}
+	var page paginatedFlagsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(page.Flags) != 1 || page.NextCursor != "flag-0004" {
+		t.Fatalf("response = %+v, want flag-0004 and a next cursor", page)
 	}
 }
 
@@ -817,6 +2631,59 @@ func TestHTTPHandlerStreamWithKeyFilter(t *testing.T) {
 	}
 }
 
+func TestHTTPHandlerStreamWithKeysAndTypes(t *testing.T) {
+	var calledFilter repository.EventFilter
+	svc := &fakeService{
+		listEventsSinceMatchingFunc: func(_ context.Context, _ string, _ int64, filter repository.EventFilter) ([]repository.FlagEvent, error) {
+			calledFilter = filter
+			return []repository.FlagEvent{
+				{
+					EventID:   1,
+					FlagKey:   "b",
+					EventType: service.EventTypeDeleted,
+					Payload:   json.RawMessage(`{"key":"b"}`),
+				},
+			}, nil
+		},
+	}
+
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream?key=a&key=b&key=a&types=update,delete", nil).WithContext(ctx))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	want := repository.EventFilter{Keys: []string{"a", "b"}, Types: []string{service.EventTypeUpdated, service.EventTypeDeleted}}
+	if !slices.Equal(calledFilter.Keys, want.Keys) || !slices.Equal(calledFilter.Types, want.Types) {
+		t.Fatalf("ListEventsSinceMatching filter = %+v, want %+v", calledFilter, want)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "event: delete") {
+		t.Fatalf("stream body missing delete event: %q", body)
+	}
+}
+
+func TestHTTPHandlerStreamRejectsInvalidFilter(t *testing.T) {
+	tooManyKeys := make([]string, maxStreamFilterKeys+1)
+	for i := range tooManyKeys {
+		tooManyKeys[i] = fmt.Sprintf("key=k%d", i)
+	}
+
+	handler := NewHTTPHandler(&fakeService{})
+	for _, query := range []string{"types=created", "types=update,bogus", strings.Join(tooManyKeys, "&"), "schema_version=3", "schema_version=v2"} {
+		req := reqWithProject(httptest.NewRequest(http.MethodGet, "/v1/stream?"+query, nil))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("GET /v1/stream?%.40s status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
 func TestHTTPHandlerStreamWithoutKeyFilter(t *testing.T) {
 	var calledAll bool
 	svc := &fakeService{
@@ -843,19 +2710,84 @@ func TestHTTPHandlerStreamWithoutKeyFilter(t *testing.T) {
 }
 
 type fakeService struct {
-	createFlagFunc            func(ctx context.Context, flag repository.Flag) (repository.Flag, error)
-	updateFlagFunc            func(ctx context.Context, flag repository.Flag) (repository.Flag, error)
-	getFlagFunc               func(ctx context.Context, projectID, key string) (repository.Flag, error)
-	listFlagsFunc             func(ctx context.Context, projectID string) ([]repository.Flag, error)
-	deleteFlagFunc            func(ctx context.Context, projectID, key string) error
-	resolveBooleanFunc        func(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (bool, error)
-	resolveBatchFunc          func(ctx context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error)
-	listEventsSinceFunc       func(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error)
-	listEventsSinceForKeyFunc func(ctx context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error)
-	createAPIKeyFunc          func(ctx context.Context, projectID string) (string, string, error)
-	listAPIKeysFunc           func(ctx context.Context, projectID string) ([]repository.APIKeyMeta, error)
-	deleteAPIKeyFunc          func(ctx context.Context, projectID, keyID string) error
-	listAuditLogFunc          func(ctx context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error)
+	createFlagFunc              func(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+	createFlagsFunc             func(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error)
+	updateFlagFunc              func(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+	patchFlagFunc               func(ctx context.Context, projectID, key, patchType string, patch json.RawMessage) (repository.Flag, error)
+	getFlagFunc                 func(ctx context.Context, projectID, key string) (repository.Flag, error)
+	listFlagsFunc               func(ctx context.Context, projectID string) ([]repository.Flag, error)
+	listFlagsPageFunc           func(ctx context.Context, q repository.FlagPageQuery) ([]repository.Flag, string, error)
+	deleteFlagFunc              func(ctx context.Context, projectID, key string) error
+	listFlagsByAttributeFunc    func(ctx context.Context, projectID, attr string) ([]repository.Flag, error)
+	reshuffleFlagFunc           func(ctx context.Context, projectID, key string) (repository.Flag, error)
+	bucketForFunc               func(ctx context.Context, projectID, key, targetingKey string) (service.BucketAssignment, error)
+	getFlagStatsFunc            func(ctx context.Context, projectID, key string) (repository.FlagStats, error)
+	listFlagRevisionsFunc       func(ctx context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error)
+	revertFlagFunc              func(ctx context.Context, projectID, key string, revision int) (repository.Flag, error)
+	setFlagTargetsFunc          func(ctx context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error)
+	copyFlagFunc                func(ctx context.Context, req service.CopyFlagRequest) (repository.Flag, bool, error)
+	createFromTemplateFunc      func(ctx context.Context, req service.FlagFromTemplateRequest) (repository.Flag, error)
+	staleFlagsFunc              func(ctx context.Context, projectID string, thresholds service.StaleThresholds) (service.StaleReport, error)
+	projectGraphFunc            func(ctx context.Context, projectID string) (service.ProjectGraph, error)
+	rulesetFunc                 func(ctx context.Context, projectID string) (service.Ruleset, error)
+	getFlagDefaultsFunc         func(ctx context.Context, projectID string) (repository.FlagDefaults, error)
+	setFlagDefaultsFunc         func(ctx context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error)
+	getFlagKeyPolicyFunc        func(ctx context.Context, projectID string) (repository.FlagKeyPolicy, error)
+	getContextSchemaFunc        func(ctx context.Context, projectID string) (repository.ContextSchema, error)
+	setContextSchemaFunc        func(ctx context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error)
+	getContextEnrichmentFunc    func(ctx context.Context, projectID string) (repository.ContextEnrichment, error)
+	setContextEnrichmentFunc    func(ctx context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error)
+	setFlagKeyPolicyFunc        func(ctx context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error)
+	proposeFlagChangeFunc       func(ctx context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error)
+	listFlagProposalsFunc       func(ctx context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error)
+	approveFlagProposalFunc     func(ctx context.Context, projectID, id string) (repository.FlagProposal, error)
+	rejectFlagProposalFunc      func(ctx context.Context, projectID, id string) (repository.FlagProposal, error)
+	listContextPresetsFunc      func(ctx context.Context, projectID string) ([]repository.ContextPreset, error)
+	getContextPresetFunc        func(ctx context.Context, projectID, name string) (repository.ContextPreset, error)
+	putContextPresetFunc        func(ctx context.Context, preset repository.ContextPreset) (repository.ContextPreset, error)
+	deleteContextPresetFunc     func(ctx context.Context, projectID, name string) error
+	resolveBooleanFunc          func(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (bool, error)
+	resolveBooleanDetailFunc    func(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (service.ResolveResult, error)
+	resolveBatchFunc            func(ctx context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error)
+	resolveAllFunc              func(ctx context.Context, projectID string, evalContext core.EvaluationContext, preset string) ([]service.ResolveResult, error)
+	explainFlagFunc             func(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, preset string) (service.Explanation, error)
+	listEventsSinceFunc         func(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error)
+	listEventsSinceForKeyFunc   func(ctx context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error)
+	snapshotFunc                func(ctx context.Context, projectID string) (service.ProjectSnapshot, error)
+	listEventsSinceMatchingFunc func(ctx context.Context, projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, error)
+	queryEventsFunc             func(ctx context.Context, q repository.EventQuery) ([]repository.FlagEvent, int64, error)
+	authorizeAdminAPIKeyFunc    func(ctx context.Context, keyID string) error
+	listAllEventsSinceFunc      func(ctx context.Context, eventID int64) ([]repository.FlagEvent, error)
+	eventsChangedFunc           func() <-chan struct{}
+	listAuditLogRangeFunc       func(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error)
+	listEventsRangeFunc         func(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error)
+	backupFunc                  func(ctx context.Context, emit func(repository.BackupRecord) error) error
+	restoreBackupFunc           func(ctx context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error)
+	createAPIKeyFunc            func(ctx context.Context, projectID string) (string, string, error)
+	listAPIKeysFunc             func(ctx context.Context, projectID string) ([]repository.APIKeyMeta, error)
+	deleteAPIKeyFunc            func(ctx context.Context, projectID, keyID string) error
+	getKeyRotationPolicyFunc    func(ctx context.Context, projectID string) (repository.KeyRotationPolicy, error)
+	setKeyRotationPolicyFunc    func(ctx context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error)
+	allowAPIKeyUsageFunc        func(ctx context.Context, projectID, apiKeyID string, kind service.UsageKind) error
+	recordAPIKeyStreamTimeFunc  func(projectID, apiKeyID string, start, end time.Time)
+	listAPIKeyUsageFunc         func(ctx context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error)
+	getAPIKeyQuotaFunc          func(ctx context.Context, projectID string) (repository.APIKeyQuota, error)
+	setAPIKeyQuotaFunc          func(ctx context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error)
+	listSegmentsFunc            func(ctx context.Context, projectID string) ([]repository.Segment, error)
+	getSegmentFunc              func(ctx context.Context, projectID, name string) (repository.Segment, error)
+	putSegmentFunc              func(ctx context.Context, segment repository.Segment) (repository.Segment, error)
+	deleteSegmentFunc           func(ctx context.Context, projectID, name string) error
+	listAuditLogFunc            func(ctx context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error)
+	claimIdempotencyFunc        func(ctx context.Context, apiKeyID, key, fingerprint string) (repository.IdempotencyRecord, bool, error)
+	completeIdempotencyFunc     func(ctx context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error
+	releaseIdempotencyFunc      func(ctx context.Context, apiKeyID, key string) error
+
+	listNotificationChannelsFunc   func(ctx context.Context, projectID string) ([]repository.NotificationChannel, error)
+	createNotificationChannelFunc  func(ctx context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error)
+	deleteNotificationChannelFunc  func(ctx context.Context, projectID, id string) error
+	listNotificationsFunc          func(ctx context.Context, projectID string, limit int) ([]repository.Notification, error)
+	webhookSigningSecretFunc       func(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error)
+	rotateWebhookSigningSecretFunc func(ctx context.Context, projectID string) (repository.WebhookSigningSecret, bool, error)
 }
 
 func (f *fakeService) CreateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
@@ -865,6 +2797,13 @@ func (f *fakeService) CreateFlag(ctx context.Context, flag repository.Flag) (rep
 	return repository.Flag{}, errors.New("CreateFlag not implemented")
 }
 
+func (f *fakeService) CreateFlags(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error) {
+	if f.createFlagsFunc != nil {
+		return f.createFlagsFunc(ctx, flags)
+	}
+	return nil, errors.New("CreateFlags not implemented")
+}
+
 func (f *fakeService) UpdateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
 	if f.updateFlagFunc != nil {
 		return f.updateFlagFunc(ctx, flag)
@@ -872,6 +2811,13 @@ func (f *fakeService) UpdateFlag(ctx context.Context, flag repository.Flag) (rep
 	return repository.Flag{}, errors.New("UpdateFlag not implemented")
 }
 
+func (f *fakeService) PatchFlag(ctx context.Context, projectID, key, patchType string, patch json.RawMessage) (repository.Flag, error) {
+	if f.patchFlagFunc != nil {
+		return f.patchFlagFunc(ctx, projectID, key, patchType, patch)
+	}
+	return repository.Flag{}, errors.New("PatchFlag not implemented")
+}
+
 func (f *fakeService) GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error) {
 	if f.getFlagFunc != nil {
 		return f.getFlagFunc(ctx, projectID, key)
@@ -886,6 +2832,34 @@ func (f *fakeService) ListFlags(ctx context.Context, projectID string) ([]reposi
 	return nil, errors.New("ListFlags not implemented")
 }
 
+// ListFlagsPage pages the flags of listFlagsFunc, as the service does for
+// repositories that cannot read a page, unless listFlagsPageFunc is set.
+func (f *fakeService) ListFlagsPage(ctx context.Context, q repository.FlagPageQuery) ([]repository.Flag, string, error) {
+	if f.listFlagsPageFunc != nil {
+		return f.listFlagsPageFunc(ctx, q)
+	}
+	flags, err := f.ListFlags(ctx, q.ProjectID)
+	if err != nil {
+		return nil, "", err
+	}
+	page, next := pageFlags(flags, q)
+	return page, next, nil
+}
+
+// pageFlags returns the page of flags, which are sorted by key, that q
+// selects and the AfterKey of the next page.
+func pageFlags(flags []repository.Flag, q repository.FlagPageQuery) ([]repository.Flag, string) {
+	flags = service.FilterFlagsByTags(flags, q.Tags)
+	flags = flags[sort.Search(len(flags), func(i int) bool { return flags[i].Key > q.AfterKey }):]
+	if q.Limit == 0 {
+		q.Limit = 100
+	}
+	if len(flags) > q.Limit {
+		return flags[:q.Limit], flags[q.Limit-1].Key
+	}
+	return flags, ""
+}
+
 func (f *fakeService) DeleteFlag(ctx context.Context, projectID, key string) error {
 	if f.deleteFlagFunc != nil {
 		return f.deleteFlagFunc(ctx, projectID, key)
@@ -893,6 +2867,206 @@ func (f *fakeService) DeleteFlag(ctx context.Context, projectID, key string) err
 	return errors.New("DeleteFlag not implemented")
 }
 
+func (f *fakeService) ListFlagsReferencingAttribute(ctx context.Context, projectID, attr string) ([]repository.Flag, error) {
+	if f.listFlagsByAttributeFunc != nil {
+		return f.listFlagsByAttributeFunc(ctx, projectID, attr)
+	}
+	return nil, errors.New("ListFlagsReferencingAttribute not implemented")
+}
+
+func (f *fakeService) ReshuffleFlag(ctx context.Context, projectID, key string) (repository.Flag, error) {
+	if f.reshuffleFlagFunc != nil {
+		return f.reshuffleFlagFunc(ctx, projectID, key)
+	}
+	return repository.Flag{}, errors.New("ReshuffleFlag not implemented")
+}
+
+func (f *fakeService) BucketFor(ctx context.Context, projectID, key, targetingKey string) (service.BucketAssignment, error) {
+	if f.bucketForFunc != nil {
+		return f.bucketForFunc(ctx, projectID, key, targetingKey)
+	}
+	return service.BucketAssignment{}, errors.New("BucketFor not implemented")
+}
+
+func (f *fakeService) GetFlagStats(ctx context.Context, projectID, key string) (repository.FlagStats, error) {
+	if f.getFlagStatsFunc != nil {
+		return f.getFlagStatsFunc(ctx, projectID, key)
+	}
+	return repository.FlagStats{}, errors.New("GetFlagStats not implemented")
+}
+
+func (f *fakeService) ListFlagRevisions(ctx context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error) {
+	if f.listFlagRevisionsFunc != nil {
+		return f.listFlagRevisionsFunc(ctx, projectID, key, limit)
+	}
+	return nil, errors.New("ListFlagRevisions not implemented")
+}
+
+func (f *fakeService) RevertFlag(ctx context.Context, projectID, key string, revision int) (repository.Flag, error) {
+	if f.revertFlagFunc != nil {
+		return f.revertFlagFunc(ctx, projectID, key, revision)
+	}
+	return repository.Flag{}, errors.New("RevertFlag not implemented")
+}
+
+func (f *fakeService) SetFlagTargets(ctx context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error) {
+	if f.setFlagTargetsFunc != nil {
+		return f.setFlagTargetsFunc(ctx, projectID, key, targets)
+	}
+	return repository.Flag{}, errors.New("SetFlagTargets not implemented")
+}
+
+func (f *fakeService) CopyFlag(ctx context.Context, req service.CopyFlagRequest) (repository.Flag, bool, error) {
+	if f.copyFlagFunc != nil {
+		return f.copyFlagFunc(ctx, req)
+	}
+	return repository.Flag{}, false, errors.New("CopyFlag not implemented")
+}
+
+func (f *fakeService) FlagTemplates() []service.FlagTemplate {
+	return []service.FlagTemplate{{Name: "kill-switch", Title: "Ops kill switch"}}
+}
+
+func (f *fakeService) CreateFlagFromTemplate(ctx context.Context, req service.FlagFromTemplateRequest) (repository.Flag, error) {
+	if f.createFromTemplateFunc != nil {
+		return f.createFromTemplateFunc(ctx, req)
+	}
+	return repository.Flag{}, errors.New("CreateFlagFromTemplate not implemented")
+}
+
+func (f *fakeService) StaleFlags(ctx context.Context, projectID string, thresholds service.StaleThresholds) (service.StaleReport, error) {
+	if f.staleFlagsFunc != nil {
+		return f.staleFlagsFunc(ctx, projectID, thresholds)
+	}
+	return service.StaleReport{}, errors.New("StaleFlags not implemented")
+}
+
+func (f *fakeService) ProjectGraph(ctx context.Context, projectID string) (service.ProjectGraph, error) {
+	if f.projectGraphFunc != nil {
+		return f.projectGraphFunc(ctx, projectID)
+	}
+	return service.ProjectGraph{}, errors.New("ProjectGraph not implemented")
+}
+
+func (f *fakeService) Ruleset(ctx context.Context, projectID string) (service.Ruleset, error) {
+	if f.rulesetFunc != nil {
+		return f.rulesetFunc(ctx, projectID)
+	}
+	return service.Ruleset{}, errors.New("Ruleset not implemented")
+}
+
+func (f *fakeService) GetFlagDefaults(ctx context.Context, projectID string) (repository.FlagDefaults, error) {
+	if f.getFlagDefaultsFunc != nil {
+		return f.getFlagDefaultsFunc(ctx, projectID)
+	}
+	return repository.FlagDefaults{}, errors.New("GetFlagDefaults not implemented")
+}
+
+func (f *fakeService) SetFlagDefaults(ctx context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error) {
+	if f.setFlagDefaultsFunc != nil {
+		return f.setFlagDefaultsFunc(ctx, projectID, defaults)
+	}
+	return repository.FlagDefaults{}, errors.New("SetFlagDefaults not implemented")
+}
+
+func (f *fakeService) GetFlagKeyPolicy(ctx context.Context, projectID string) (repository.FlagKeyPolicy, error) {
+	if f.getFlagKeyPolicyFunc != nil {
+		return f.getFlagKeyPolicyFunc(ctx, projectID)
+	}
+	return repository.FlagKeyPolicy{}, errors.New("GetFlagKeyPolicy not implemented")
+}
+
+func (f *fakeService) SetFlagKeyPolicy(ctx context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error) {
+	if f.setFlagKeyPolicyFunc != nil {
+		return f.setFlagKeyPolicyFunc(ctx, projectID, policy)
+	}
+	return repository.FlagKeyPolicy{}, errors.New("SetFlagKeyPolicy not implemented")
+}
+
+func (f *fakeService) GetContextSchema(ctx context.Context, projectID string) (repository.ContextSchema, error) {
+	if f.getContextSchemaFunc != nil {
+		return f.getContextSchemaFunc(ctx, projectID)
+	}
+	return repository.ContextSchema{}, errors.New("GetContextSchema not implemented")
+}
+
+func (f *fakeService) SetContextSchema(ctx context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error) {
+	if f.setContextSchemaFunc != nil {
+		return f.setContextSchemaFunc(ctx, projectID, schema)
+	}
+	return repository.ContextSchema{}, errors.New("SetContextSchema not implemented")
+}
+
+func (f *fakeService) GetContextEnrichment(ctx context.Context, projectID string) (repository.ContextEnrichment, error) {
+	if f.getContextEnrichmentFunc != nil {
+		return f.getContextEnrichmentFunc(ctx, projectID)
+	}
+	return repository.ContextEnrichment{}, errors.New("GetContextEnrichment not implemented")
+}
+
+func (f *fakeService) SetContextEnrichment(ctx context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error) {
+	if f.setContextEnrichmentFunc != nil {
+		return f.setContextEnrichmentFunc(ctx, projectID, enrichment)
+	}
+	return repository.ContextEnrichment{}, errors.New("SetContextEnrichment not implemented")
+}
+
+func (f *fakeService) ListContextPresets(ctx context.Context, projectID string) ([]repository.ContextPreset, error) {
+	if f.listContextPresetsFunc != nil {
+		return f.listContextPresetsFunc(ctx, projectID)
+	}
+	return nil, errors.New("ListContextPresets not implemented")
+}
+
+func (f *fakeService) GetContextPreset(ctx context.Context, projectID, name string) (repository.ContextPreset, error) {
+	if f.getContextPresetFunc != nil {
+		return f.getContextPresetFunc(ctx, projectID, name)
+	}
+	return repository.ContextPreset{}, errors.New("GetContextPreset not implemented")
+}
+
+func (f *fakeService) PutContextPreset(ctx context.Context, preset repository.ContextPreset) (repository.ContextPreset, error) {
+	if f.putContextPresetFunc != nil {
+		return f.putContextPresetFunc(ctx, preset)
+	}
+	return repository.ContextPreset{}, errors.New("PutContextPreset not implemented")
+}
+
+func (f *fakeService) DeleteContextPreset(ctx context.Context, projectID, name string) error {
+	if f.deleteContextPresetFunc != nil {
+		return f.deleteContextPresetFunc(ctx, projectID, name)
+	}
+	return errors.New("DeleteContextPreset not implemented")
+}
+
+func (f *fakeService) ProposeFlagChange(ctx context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error) {
+	if f.proposeFlagChangeFunc != nil {
+		return f.proposeFlagChangeFunc(ctx, proposal)
+	}
+	return repository.FlagProposal{}, errors.New("ProposeFlagChange not implemented")
+}
+
+func (f *fakeService) ListFlagProposals(ctx context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error) {
+	if f.listFlagProposalsFunc != nil {
+		return f.listFlagProposalsFunc(ctx, projectID, flagKey, status)
+	}
+	return nil, errors.New("ListFlagProposals not implemented")
+}
+
+func (f *fakeService) ApproveFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error) {
+	if f.approveFlagProposalFunc != nil {
+		return f.approveFlagProposalFunc(ctx, projectID, id)
+	}
+	return repository.FlagProposal{}, errors.New("ApproveFlagProposal not implemented")
+}
+
+func (f *fakeService) RejectFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error) {
+	if f.rejectFlagProposalFunc != nil {
+		return f.rejectFlagProposalFunc(ctx, projectID, id)
+	}
+	return repository.FlagProposal{}, errors.New("RejectFlagProposal not implemented")
+}
+
 func (f *fakeService) ResolveBoolean(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (bool, error) {
 	if f.resolveBooleanFunc != nil {
 		return f.resolveBooleanFunc(ctx, projectID, key, evalContext, defaultValue)
@@ -900,6 +3074,14 @@ func (f *fakeService) ResolveBoolean(ctx context.Context, projectID, key string,
 	return false, errors.New("ResolveBoolean not implemented")
 }
 
+func (f *fakeService) ResolveBooleanDetail(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (service.ResolveResult, error) {
+	if f.resolveBooleanDetailFunc != nil {
+		return f.resolveBooleanDetailFunc(ctx, projectID, key, evalContext, defaultValue)
+	}
+	value, err := f.ResolveBoolean(ctx, projectID, key, evalContext, defaultValue)
+	return service.ResolveResult{Key: key, Value: value, Reason: core.ReasonDefault}, err
+}
+
 func (f *fakeService) ResolveBatch(ctx context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error) {
 	if f.resolveBatchFunc != nil {
 		return f.resolveBatchFunc(ctx, requests)
@@ -907,6 +3089,20 @@ func (f *fakeService) ResolveBatch(ctx context.Context, requests []service.Resol
 	return nil, errors.New("ResolveBatch not implemented")
 }
 
+func (f *fakeService) ResolveAll(ctx context.Context, projectID string, evalContext core.EvaluationContext, preset string) ([]service.ResolveResult, error) {
+	if f.resolveAllFunc != nil {
+		return f.resolveAllFunc(ctx, projectID, evalContext, preset)
+	}
+	return nil, errors.New("ResolveAll not implemented")
+}
+
+func (f *fakeService) ExplainFlag(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, preset string) (service.Explanation, error) {
+	if f.explainFlagFunc != nil {
+		return f.explainFlagFunc(ctx, projectID, key, evalContext, preset)
+	}
+	return service.Explanation{}, errors.New("ExplainFlag not implemented")
+}
+
 func (f *fakeService) ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
 	if f.listEventsSinceFunc != nil {
 		return f.listEventsSinceFunc(ctx, projectID, eventID)
@@ -921,6 +3117,77 @@ func (f *fakeService) ListEventsSinceForKey(ctx context.Context, projectID strin
 	return nil, errors.New("ListEventsSinceForKey not implemented")
 }
 
+func (f *fakeService) Snapshot(ctx context.Context, projectID string) (service.ProjectSnapshot, error) {
+	if f.snapshotFunc != nil {
+		return f.snapshotFunc(ctx, projectID)
+	}
+	return service.ProjectSnapshot{}, errors.New("Snapshot not implemented")
+}
+
+func (f *fakeService) ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, error) {
+	if f.listEventsSinceMatchingFunc != nil {
+		return f.listEventsSinceMatchingFunc(ctx, projectID, eventID, filter)
+	}
+	return nil, errors.New("ListEventsSinceMatching not implemented")
+}
+
+func (f *fakeService) AuthorizeAdminAPIKey(ctx context.Context, keyID string) error {
+	if f.authorizeAdminAPIKeyFunc != nil {
+		return f.authorizeAdminAPIKeyFunc(ctx, keyID)
+	}
+	return errors.New("AuthorizeAdminAPIKey not implemented")
+}
+
+func (f *fakeService) ListAllEventsSince(ctx context.Context, eventID int64) ([]repository.FlagEvent, error) {
+	if f.listAllEventsSinceFunc != nil {
+		return f.listAllEventsSinceFunc(ctx, eventID)
+	}
+	return nil, errors.New("ListAllEventsSince not implemented")
+}
+
+// EventsChanged returns nil unless overridden, so streams poll.
+func (f *fakeService) EventsChanged() <-chan struct{} {
+	if f.eventsChangedFunc != nil {
+		return f.eventsChangedFunc()
+	}
+	return nil
+}
+
+func (f *fakeService) ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error) {
+	if f.listAuditLogRangeFunc != nil {
+		return f.listAuditLogRangeFunc(ctx, afterID, from, to, limit)
+	}
+	return nil, errors.New("ListAuditLogRange not implemented")
+}
+
+func (f *fakeService) QueryEvents(ctx context.Context, q repository.EventQuery) ([]repository.FlagEvent, int64, error) {
+	if f.queryEventsFunc != nil {
+		return f.queryEventsFunc(ctx, q)
+	}
+	return nil, 0, errors.New("QueryEvents not implemented")
+}
+
+func (f *fakeService) ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error) {
+	if f.listEventsRangeFunc != nil {
+		return f.listEventsRangeFunc(ctx, afterEventID, from, to, limit)
+	}
+	return nil, errors.New("ListEventsRange not implemented")
+}
+
+func (f *fakeService) Backup(ctx context.Context, emit func(repository.BackupRecord) error) error {
+	if f.backupFunc != nil {
+		return f.backupFunc(ctx, emit)
+	}
+	return errors.New("Backup not implemented")
+}
+
+func (f *fakeService) RestoreBackup(ctx context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error) {
+	if f.restoreBackupFunc != nil {
+		return f.restoreBackupFunc(ctx, opts, next)
+	}
+	return repository.RestoreResult{}, errors.New("RestoreBackup not implemented")
+}
+
 func (f *fakeService) CreateAPIKey(ctx context.Context, projectID string) (string, string, error) {
 	if f.createAPIKeyFunc != nil {
 		return f.createAPIKeyFunc(ctx, projectID)
@@ -942,9 +3209,219 @@ func (f *fakeService) DeleteAPIKey(ctx context.Context, projectID, keyID string)
 	return errors.New("DeleteAPIKey not implemented")
 }
 
+func (f *fakeService) GetKeyRotationPolicy(ctx context.Context, projectID string) (repository.KeyRotationPolicy, error) {
+	if f.getKeyRotationPolicyFunc != nil {
+		return f.getKeyRotationPolicyFunc(ctx, projectID)
+	}
+	return repository.KeyRotationPolicy{}, errors.New("GetKeyRotationPolicy not implemented")
+}
+
+func (f *fakeService) SetKeyRotationPolicy(ctx context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error) {
+	if f.setKeyRotationPolicyFunc != nil {
+		return f.setKeyRotationPolicyFunc(ctx, projectID, policy)
+	}
+	return repository.KeyRotationPolicy{}, errors.New("SetKeyRotationPolicy not implemented")
+}
+
+func (f *fakeService) AllowAPIKeyUsage(ctx context.Context, projectID, apiKeyID string, kind service.UsageKind) error {
+	if f.allowAPIKeyUsageFunc != nil {
+		return f.allowAPIKeyUsageFunc(ctx, projectID, apiKeyID, kind)
+	}
+	return nil
+}
+
+func (f *fakeService) RecordAPIKeyStreamTime(projectID, apiKeyID string, start, end time.Time) {
+	if f.recordAPIKeyStreamTimeFunc != nil {
+		f.recordAPIKeyStreamTimeFunc(projectID, apiKeyID, start, end)
+	}
+}
+
+func (f *fakeService) ListAPIKeyUsage(ctx context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error) {
+	if f.listAPIKeyUsageFunc != nil {
+		return f.listAPIKeyUsageFunc(ctx, projectID, since)
+	}
+	return nil, errors.New("ListAPIKeyUsage not implemented")
+}
+
+func (f *fakeService) GetAPIKeyQuota(ctx context.Context, projectID string) (repository.APIKeyQuota, error) {
+	if f.getAPIKeyQuotaFunc != nil {
+		return f.getAPIKeyQuotaFunc(ctx, projectID)
+	}
+	return repository.APIKeyQuota{}, errors.New("GetAPIKeyQuota not implemented")
+}
+
+func (f *fakeService) SetAPIKeyQuota(ctx context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error) {
+	if f.setAPIKeyQuotaFunc != nil {
+		return f.setAPIKeyQuotaFunc(ctx, projectID, quota)
+	}
+	return repository.APIKeyQuota{}, errors.New("SetAPIKeyQuota not implemented")
+}
+
+func (f *fakeService) ListNotificationChannels(ctx context.Context, projectID string) ([]repository.NotificationChannel, error) {
+	if f.listNotificationChannelsFunc != nil {
+		return f.listNotificationChannelsFunc(ctx, projectID)
+	}
+	return nil, errors.New("ListNotificationChannels not implemented")
+}
+
+func (f *fakeService) CreateNotificationChannel(ctx context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error) {
+	if f.createNotificationChannelFunc != nil {
+		return f.createNotificationChannelFunc(ctx, channel)
+	}
+	return repository.NotificationChannel{}, errors.New("CreateNotificationChannel not implemented")
+}
+
+func (f *fakeService) DeleteNotificationChannel(ctx context.Context, projectID, id string) error {
+	if f.deleteNotificationChannelFunc != nil {
+		return f.deleteNotificationChannelFunc(ctx, projectID, id)
+	}
+	return errors.New("DeleteNotificationChannel not implemented")
+}
+
+func (f *fakeService) ListNotifications(ctx context.Context, projectID string, limit int) ([]repository.Notification, error) {
+	if f.listNotificationsFunc != nil {
+		return f.listNotificationsFunc(ctx, projectID, limit)
+	}
+	return nil, errors.New("ListNotifications not implemented")
+}
+
+func (f *fakeService) WebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error) {
+	if f.webhookSigningSecretFunc != nil {
+		return f.webhookSigningSecretFunc(ctx, projectID)
+	}
+	return repository.WebhookSigningSecret{}, errors.New("WebhookSigningSecret not implemented")
+}
+
+func (f *fakeService) RotateWebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, bool, error) {
+	if f.rotateWebhookSigningSecretFunc != nil {
+		return f.rotateWebhookSigningSecretFunc(ctx, projectID)
+	}
+	return repository.WebhookSigningSecret{}, false, errors.New("RotateWebhookSigningSecret not implemented")
+}
+
+func (f *fakeService) ListSegments(ctx context.Context, projectID string) ([]repository.Segment, error) {
+	if f.listSegmentsFunc != nil {
+		return f.listSegmentsFunc(ctx, projectID)
+	}
+	return nil, errors.New("ListSegments not implemented")
+}
+
+func (f *fakeService) GetSegment(ctx context.Context, projectID, name string) (repository.Segment, error) {
+	if f.getSegmentFunc != nil {
+		return f.getSegmentFunc(ctx, projectID, name)
+	}
+	return repository.Segment{}, errors.New("GetSegment not implemented")
+}
+
+func (f *fakeService) PutSegment(ctx context.Context, segment repository.Segment) (repository.Segment, error) {
+	if f.putSegmentFunc != nil {
+		return f.putSegmentFunc(ctx, segment)
+	}
+	return repository.Segment{}, errors.New("PutSegment not implemented")
+}
+
+func (f *fakeService) DeleteSegment(ctx context.Context, projectID, name string) error {
+	if f.deleteSegmentFunc != nil {
+		return f.deleteSegmentFunc(ctx, projectID, name)
+	}
+	return errors.New("DeleteSegment not implemented")
+}
+
 func (f *fakeService) ListAuditLog(ctx context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error) {
 	if f.listAuditLogFunc != nil {
 		return f.listAuditLogFunc(ctx, projectID, limit, offset)
 	}
 	return nil, errors.New("ListAuditLog not implemented")
 }
+
+func (f *fakeService) ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string) (repository.IdempotencyRecord, bool, error) {
+	if f.claimIdempotencyFunc != nil {
+		return f.claimIdempotencyFunc(ctx, apiKeyID, key, fingerprint)
+	}
+	return repository.IdempotencyRecord{}, false, errors.New("ClaimIdempotencyKey not implemented")
+}
+
+func (f *fakeService) CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error {
+	if f.completeIdempotencyFunc != nil {
+		return f.completeIdempotencyFunc(ctx, apiKeyID, key, record)
+	}
+	return errors.New("CompleteIdempotencyKey not implemented")
+}
+
+func (f *fakeService) ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error {
+	if f.releaseIdempotencyFunc != nil {
+		return f.releaseIdempotencyFunc(ctx, apiKeyID, key)
+	}
+	return errors.New("ReleaseIdempotencyKey not implemented")
+}
+
+func TestHTTPHandlerReadyz(t *testing.T) {
+	readiness := service.Readiness{
+		Err: service.ErrWarmingUp,
+		Components: map[string]service.ComponentStatus{
+			service.ComponentCache:        {Status: service.StatusReady},
+			service.ComponentInvalidation: {Status: service.StatusNotReady, Reason: service.ErrWarmingUp.Error()},
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(&fakeService{}, 5*time.Millisecond,
+		WithReadinessCheck(func(context.Context) service.Readiness { return readiness }),
+	)
+
+	var body struct {
+		Status     string                             `json:"status"`
+		Reason     string                             `json:"reason"`
+		Components map[string]service.ComponentStatus `json:"components"`
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("warming up status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Status != "not_ready" || body.Reason != "waiting for cache invalidation subscription" {
+		t.Fatalf("body = %q, want not_ready with readiness reason", rec.Body.String())
+	}
+	if got := body.Components["invalidation"].Status; got != "not_ready" {
+		t.Fatalf("invalidation component = %q, want not_ready", got)
+	}
+
+	readiness.Err = nil
+	readiness.Components[service.ComponentInvalidation] = service.ComponentStatus{Status: service.StatusReady}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ready status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body.Reason = ""
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Status != "ready" || body.Reason != "" || body.Components["cache"].Status != "ready" {
+		t.Fatalf("body = %q, want ready with component statuses", rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerInvalidJSONNamesField(t *testing.T) {
+	handler := NewHTTPHandlerWithStreamPollInterval(&fakeService{}, 5*time.Millisecond)
+
+	tests := []struct {
+		body, field string
+	}{
+		{body: `{"key":"new-ui","colour":"red"}`, field: "colour"},
+		{body: `{"key":"new-ui","enabled":"yes"}`, field: "enabled"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(tt.body))))
+
+		var got middleware.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unmarshal problem: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest || len(got.Errors) != 1 || got.Errors[0].Field != tt.field {
+			t.Errorf("body %s: status %d, problem %+v, want a field error for %q", tt.body, rec.Code, got, tt.field)
+		}
+	}
+}
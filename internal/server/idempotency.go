@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentResponseSize = 1 << 20
+)
+
+// withIdempotency makes POST, PUT, PATCH and DELETE requests carrying an
+// Idempotency-Key header safe to retry. The first request with a key is
+// handled and its response stored per API key; a retry with the same method,
+// path and body is answered with the stored response instead of being
+// applied again. Reusing a key for a different request is rejected with 422,
+// and a retry that arrives while the first request is still running gets
+// 409. Responses of 5xx, and responses too large to store, are not kept, so
+// those requests can be retried afresh.
+//
+// Evaluation requests change nothing and are passed through untouched.
+func (s *HTTPServer) withIdempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || !idempotentMethod(r.Method) || strings.HasPrefix(r.URL.Path, "/v1/evaluate") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		apiKeyID, ok := middleware.APIKeyIDFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			writeJSONError(w, r, http.StatusBadRequest, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+			return
+		}
+
+		// The body is read up front so that the fingerprint is known before
+		// the key is claimed; routes still apply their own, smaller limits
+		// when they decode it.
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxImportBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			writeJSONError(w, r, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+
+		record, claimed, err := s.service.ClaimIdempotencyKey(r.Context(), apiKeyID, key, fingerprint)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		if !claimed {
+			replayIdempotentResponse(w, r, record, fingerprint)
+			return
+		}
+
+		// The outcome is stored even if the client has gone away, since that
+		// is exactly when it will retry.
+		storeCtx := context.WithoutCancel(r.Context())
+		recorder := &idempotencyRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				_ = s.service.ReleaseIdempotencyKey(storeCtx, apiKeyID, key)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode == 0 || recorder.statusCode >= http.StatusInternalServerError || recorder.overflow {
+			return
+		}
+		err = s.service.CompleteIdempotencyKey(storeCtx, apiKeyID, key, repository.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  recorder.statusCode,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		completed = err == nil
+	})
+}
+
+// replayIdempotentResponse answers a request whose key is already held by
+// record.
+func replayIdempotentResponse(w http.ResponseWriter, r *http.Request, record repository.IdempotencyRecord, fingerprint string) {
+	switch {
+	case record.Fingerprint != fingerprint:
+		middleware.WriteProblem(w, r, middleware.Problem{
+			Status: http.StatusUnprocessableEntity,
+			Type:   middleware.ProblemType("idempotency-key-reused"),
+			Detail: "Idempotency-Key was already used for a different request",
+		})
+	case record.StatusCode == 0:
+		middleware.WriteProblem(w, r, middleware.Problem{
+			Status: http.StatusConflict,
+			Type:   middleware.ProblemType("idempotency-key-in-progress"),
+			Detail: "a request with this Idempotency-Key is still in progress",
+		})
+	default:
+		if record.ContentType != "" {
+			w.Header().Set("Content-Type", record.ContentType)
+		}
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.WriteHeader(record.StatusCode)
+		_, _ = w.Write(record.Body)
+	}
+}
+
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// validIdempotencyKey reports whether key is 1 to 255 printable ASCII
+// characters.
+func validIdempotencyKey(key string) bool {
+	if len(key) == 0 || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint hashes what makes a request the same request: its
+// method, path, query and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, r.URL.RequestURI())
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of it
+// to store, up to maxIdempotentResponseSize.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	overflow   bool
+}
+
+func (r *idempotencyRecorder) WriteHeader(code int) {
+	if r.statusCode == 0 {
+		r.statusCode = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if !r.overflow {
+		if r.body.Len()+len(b) > maxIdempotentResponseSize {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// fakeIdempotencyStore backs fakeService's idempotency methods with a map,
+// the way the repositories do with a table.
+type fakeIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]repository.IdempotencyRecord
+}
+
+func (f *fakeIdempotencyStore) install(svc *fakeService) {
+	f.records = make(map[string]repository.IdempotencyRecord)
+	svc.claimIdempotencyFunc = func(_ context.Context, apiKeyID, key, fingerprint string) (repository.IdempotencyRecord, bool, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if record, ok := f.records[apiKeyID+"/"+key]; ok {
+			return record, false, nil
+		}
+		f.records[apiKeyID+"/"+key] = repository.IdempotencyRecord{Fingerprint: fingerprint}
+		return repository.IdempotencyRecord{Fingerprint: fingerprint}, true, nil
+	}
+	svc.completeIdempotencyFunc = func(_ context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.records[apiKeyID+"/"+key] = record
+		return nil
+	}
+	svc.releaseIdempotencyFunc = func(_ context.Context, apiKeyID, key string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.records, apiKeyID+"/"+key)
+		return nil
+	}
+}
+
+func idempotentRequest(method, target, body, keyID, idempotencyKey string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	ctx := middleware.NewContextWithAPIKeyID(middleware.NewContextWithProjectID(req.Context(), "default"), keyID)
+	return req.WithContext(ctx)
+}
+
+func TestHTTPHandlerIdempotencyReplaysResponse(t *testing.T) {
+	creates := 0
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			creates++
+			return flag, nil
+		},
+	}
+	(&fakeIdempotencyStore{}).install(svc)
+	handler := NewHTTPHandler(svc)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"checkout"}`, "key-a", "create-checkout"))
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d; body %s", first.Code, http.StatusCreated, first.Body)
+	}
+	if got := first.Header().Get(idempotentReplayedHeader); got != "" {
+		t.Fatalf("first %s = %q, want empty", idempotentReplayedHeader, got)
+	}
+
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"checkout"}`, "key-a", "create-checkout"))
+	if retry.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want %d", retry.Code, http.StatusCreated)
+	}
+	if got := retry.Header().Get(idempotentReplayedHeader); got != "true" {
+		t.Fatalf("retry %s = %q, want true", idempotentReplayedHeader, got)
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry body = %s, want %s", retry.Body, first.Body)
+	}
+	if got := retry.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("retry Content-Type = %q, want application/json", got)
+	}
+	if creates != 1 {
+		t.Fatalf("CreateFlag called %d times, want 1", creates)
+	}
+
+	// Keys are scoped to the API key that used them.
+	other := httptest.NewRecorder()
+	handler.ServeHTTP(other, idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"checkout"}`, "key-b", "create-checkout"))
+	if other.Header().Get(idempotentReplayedHeader) != "" || creates != 2 {
+		t.Fatalf("request from another API key was replayed (creates = %d)", creates)
+	}
+}
+
+func TestHTTPHandlerIdempotencyRejectsReusedKey(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			return flag, nil
+		},
+	}
+	(&fakeIdempotencyStore{}).install(svc)
+	handler := NewHTTPHandler(svc)
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"checkout"}`, "key-a", "k1"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"search"}`, "key-a", "k1"))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(rec.Body.String(), middleware.ProblemType("idempotency-key-reused")) {
+		t.Fatalf("body = %s, want idempotency-key-reused problem", rec.Body)
+	}
+}
+
+func TestHTTPHandlerIdempotencyInProgress(t *testing.T) {
+	svc := &fakeService{
+		claimIdempotencyFunc: func(_ context.Context, _, _, fingerprint string) (repository.IdempotencyRecord, bool, error) {
+			return repository.IdempotencyRecord{Fingerprint: fingerprint}, false, nil
+		},
+	}
+	handler := NewHTTPHandler(svc)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest(http.MethodDelete, "/v1/flags/checkout", "", "key-a", "k1"))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), middleware.ProblemType("idempotency-key-in-progress")) {
+		t.Fatalf("body = %s, want idempotency-key-in-progress problem", rec.Body)
+	}
+}
+
+func TestHTTPHandlerIdempotencyReleasesServerErrors(t *testing.T) {
+	deletes := 0
+	svc := &fakeService{
+		deleteFlagFunc: func(context.Context, string, string) error {
+			deletes++
+			if deletes == 1 {
+				return errors.New("database unavailable")
+			}
+			return nil
+		},
+	}
+	(&fakeIdempotencyStore{}).install(svc)
+	handler := NewHTTPHandler(svc)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest(http.MethodDelete, "/v1/flags/checkout", "", "key-a", "k1"))
+	if first.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusInternalServerError)
+	}
+
+	retry := httptest.NewRecorder()
+	handler.ServeHTTP(retry, idempotentRequest(http.MethodDelete, "/v1/flags/checkout", "", "key-a", "k1"))
+	if retry.Code != http.StatusNoContent {
+		t.Fatalf("retry status = %d, want %d", retry.Code, http.StatusNoContent)
+	}
+	if deletes != 2 {
+		t.Fatalf("DeleteFlag called %d times, want 2", deletes)
+	}
+}
+
+func TestHTTPHandlerIdempotencyKeyValidation(t *testing.T) {
+	handler := NewHTTPHandler(&fakeService{})
+
+	for _, key := range []string{strings.Repeat("k", maxIdempotencyKeyLength+1), "bad\tkey", "clé"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/v1/flags", `{"key":"checkout"}`, "key-a", key))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Idempotency-Key %q: status = %d, want %d", key, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHTTPHandlerIdempotencyIgnoredWithoutAPIKey(t *testing.T) {
+	svc := &fakeService{
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			return flag, nil
+		},
+	}
+	handler := NewHTTPHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/flags", strings.NewReader(`{"key":"checkout"}`))
+	req.Header.Set(idempotencyKeyHeader, "k1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, reqWithProject(req))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+}
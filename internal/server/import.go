@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	// importFormField names the multipart part(s) holding NDJSON flags.
+	importFormField = "flags"
+	// maxImportErrors caps the per-line errors reported for one import so
+	// that a large, badly formed upload does not produce an equally large
+	// response.
+	maxImportErrors = 100
+)
+
+var errImportLineTooLong = errors.New("import line too long")
+
+type importLineError struct {
+	Line  int    `json:"line"`
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+type importFlagsResponse struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Errors  []importLineError `json:"errors,omitempty"`
+	// Error is set when the upload itself could not be read to the end.
+	// Flags on earlier lines have already been applied.
+	Error string `json:"error,omitempty"`
+}
+
+func (resp *importFlagsResponse) fail(line int, key, message string) {
+	resp.Failed++
+	if len(resp.Errors) < maxImportErrors {
+		resp.Errors = append(resp.Errors, importLineError{Line: line, Key: key, Error: message})
+	}
+}
+
+// handleImportFlags creates or updates flags from newline-delimited JSON, one
+// flag object per line. The body is either application/x-ndjson or
+// multipart/form-data with the NDJSON in "flags" parts. Lines are applied as
+// they are read, so memory use does not grow with the size of the upload;
+// a failing line is reported and skipped rather than aborting the import.
+func (s *HTTPServer) handleImportFlags(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != ndjsonContentType && mediaType != "multipart/form-data") {
+		writeJSONError(w, r, http.StatusUnsupportedMediaType, "content type must be "+ndjsonContentType+" or multipart/form-data")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxImportBytes)
+
+	var resp importFlagsResponse
+	line := 0
+	if mediaType == ndjsonContentType {
+		err = s.importNDJSON(r, projectID, r.Body, &line, &resp)
+	} else {
+		err = s.importMultipart(r, projectID, &line, &resp)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		status := http.StatusBadRequest
+		switch {
+		case errors.As(err, &maxBytesErr):
+			status = http.StatusRequestEntityTooLarge
+			resp.Error = "request body too large"
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			status = http.StatusRequestTimeout
+			resp.Error = "timed out reading request body"
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusServiceUnavailable
+			resp.Error = "request timed out"
+		case errors.Is(err, errImportLineTooLong):
+			resp.Error = fmt.Sprintf("line %d exceeds %d bytes", line+1, s.maxJSONBodyBytes)
+		default:
+			resp.Error = "invalid import body"
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *HTTPServer) importMultipart(r *http.Request, projectID string, line *int, resp *importFlagsResponse) error {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if part.FormName() == importFormField {
+			err = s.importNDJSON(r, projectID, part, line, resp)
+		}
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// importNDJSON applies each non-blank line of src as a flag. line counts
+// lines across all parts of a multipart upload. A single line may be at most
+// the general JSON body limit, the same as a POST /v1/flags body.
+func (s *HTTPServer) importNDJSON(r *http.Request, projectID string, src io.Reader, line *int, resp *importFlagsResponse) error {
+	scanner := bufio.NewScanner(src)
+	// The scanner only enforces its max when growing the buffer, so the
+	// initial buffer must not exceed it.
+	scanner.Buffer(make([]byte, 0, min(64<<10, s.maxJSONBodyBytes)), int(s.maxJSONBodyBytes))
+	for scanner.Scan() {
+		*line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var flag repository.Flag
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&flag); err != nil || decoder.More() {
+			resp.fail(*line, "", "invalid JSON")
+			continue
+		}
+		if strings.TrimSpace(flag.Key) == "" {
+			resp.fail(*line, "", "key is required")
+			continue
+		}
+		flag.ProjectID = projectID
+
+		created, err := s.upsertFlag(r, flag)
+		if err != nil {
+			if ctxErr := r.Context().Err(); ctxErr != nil {
+				return ctxErr
+			}
+			message := serviceErrorMessage(err)
+			var rulesErr *service.RulesError
+			var variantsErr *service.VariantsError
+			switch {
+			case errors.As(err, &rulesErr):
+				message = rulesErr.Error()
+			case errors.As(err, &variantsErr):
+				message = variantsErr.Error()
+			}
+			resp.fail(*line, flag.Key, message)
+			continue
+		}
+		if created {
+			resp.Created++
+		} else {
+			resp.Updated++
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return errImportLineTooLong
+	}
+	return scanner.Err()
+}
+
+func (s *HTTPServer) upsertFlag(r *http.Request, flag repository.Flag) (bool, error) {
+	_, err := s.service.GetFlag(r.Context(), flag.ProjectID, flag.Key)
+	switch {
+	case errors.Is(err, service.ErrFlagNotFound):
+		_, err = s.service.CreateFlag(r.Context(), flag)
+		return err == nil, err
+	case err != nil:
+		return false, err
+	default:
+		_, err = s.service.UpdateFlag(r.Context(), flag)
+		return false, err
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/metrics"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newImportService(stored map[string]repository.Flag) *fakeService {
+	return &fakeService{
+		getFlagFunc: func(_ context.Context, _, key string) (repository.Flag, error) {
+			flag, ok := stored[key]
+			if !ok {
+				return repository.Flag{}, service.ErrFlagNotFound
+			}
+			return flag, nil
+		},
+		createFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			if string(flag.Rules) == `"bogus"` {
+				return repository.Flag{}, service.ErrInvalidRules
+			}
+			stored[flag.Key] = flag
+			return flag, nil
+		},
+		updateFlagFunc: func(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+			stored[flag.Key] = flag
+			return flag, nil
+		},
+	}
+}
+
+func decodeImportResponse(t *testing.T, rec *httptest.ResponseRecorder) importFlagsResponse {
+	t.Helper()
+	var resp importFlagsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode import response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestHTTPHandlerImportFlagsNDJSON(t *testing.T) {
+	stored := map[string]repository.Flag{"dark-mode": {Key: "dark-mode", ProjectID: "default"}}
+	handler := NewHTTPHandlerWithStreamPollInterval(newImportService(stored), 5*time.Millisecond)
+
+	body := strings.Join([]string{
+		`{"key":"dark-mode","enabled":true}`,
+		``,
+		`{"key":"new-checkout","description":"New checkout"}`,
+		`{"key":"bad-rules","rules":"bogus"}`,
+		`{"enabled":true}`,
+		`{"key":`,
+	}, "\n")
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/import", strings.NewReader(body)))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	resp := decodeImportResponse(t, rec)
+	if resp.Created != 1 || resp.Updated != 1 || resp.Failed != 3 {
+		t.Fatalf("response = %+v, want 1 created, 1 updated, 3 failed", resp)
+	}
+	wantErrors := []importLineError{
+		{Line: 4, Key: "bad-rules", Error: "invalid rules"},
+		{Line: 5, Error: "key is required"},
+		{Line: 6, Error: "invalid JSON"},
+	}
+	for i, want := range wantErrors {
+		if i >= len(resp.Errors) || resp.Errors[i] != want {
+			t.Fatalf("errors = %+v, want %+v", resp.Errors, wantErrors)
+		}
+	}
+	if !stored["dark-mode"].Enabled {
+		t.Error("dark-mode was not updated")
+	}
+	if got := stored["new-checkout"]; got.ProjectID != "default" || got.Description != "New checkout" {
+		t.Errorf("new-checkout = %+v, want created in the caller's project", got)
+	}
+}
+
+func TestHTTPHandlerImportFlagsMultipart(t *testing.T) {
+	stored := map[string]repository.Flag{}
+	handler := NewHTTPHandlerWithStreamPollInterval(newImportService(stored), 5*time.Millisecond)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("comment", "ignored")
+	part, _ := mw.CreateFormFile("flags", "flags.ndjson")
+	part.Write([]byte("{\"key\":\"a\"}\n{\"key\":\"b\"}\n"))
+	part, _ = mw.CreateFormFile("flags", "more.ndjson")
+	part.Write([]byte(`{"key":"c"}`))
+	mw.Close()
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/import", &body))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if resp := decodeImportResponse(t, rec); resp.Created != 3 || resp.Failed != 0 {
+		t.Fatalf("response = %+v, want 3 created", resp)
+	}
+	if len(stored) != 3 {
+		t.Fatalf("stored = %v, want a, b and c", stored)
+	}
+}
+
+func TestHTTPHandlerImportFlagsLimits(t *testing.T) {
+	stored := map[string]repository.Flag{}
+	handler := NewHTTPHandlerWithStreamPollInterval(newImportService(stored), 5*time.Millisecond,
+		WithMaxJSONBodySize(64),
+		WithMaxImportBodySize(128),
+	)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/import", strings.NewReader(body)))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Lines are applied as they are read, so those before the limit stick.
+	rec := post(strings.Repeat("{\"key\":\"k\"}\n", 20))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if resp := decodeImportResponse(t, rec); resp.Created+resp.Updated == 0 || resp.Error != "request body too large" {
+		t.Fatalf("oversized body response = %+v, want partial import and error", resp)
+	}
+
+	rec = post(`{"key":"long","description":"` + strings.Repeat("a", 64) + `"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("long line status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if resp := decodeImportResponse(t, rec); resp.Error != "line 1 exceeds 64 bytes" {
+		t.Fatalf("long line error = %q", resp.Error)
+	}
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/import", strings.NewReader(`{"key":"a"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("json content type status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestHTTPHandlerEvaluateBodyLimit(t *testing.T) {
+	svc := &fakeService{
+		resolveBatchFunc: func(_ context.Context, _ []service.ResolveRequest) ([]service.ResolveResult, error) {
+			t.Fatal("ResolveBatch should not be called for oversized request bodies")
+			return nil, nil
+		},
+	}
+	m := metrics.New()
+	handler := NewHTTPHandlerWithOptions(svc, 5*time.Millisecond, m,
+		WithMaxEvaluateBodySize(32),
+	)
+
+	body := `{"key":"new-ui","context":{"targeting_key":"` + strings.Repeat("u", 32) + `"}}`
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if got := testutil.ToFloat64(m.HTTPBodyTooLarge.WithLabelValues("POST /v1/evaluate")); got != 1 {
+		t.Fatalf("body too large count = %v, want 1", got)
+	}
+}
+
+func TestHTTPHandlerEvaluateTimeout(t *testing.T) {
+	svc := &fakeService{
+		resolveBatchFunc: func(ctx context.Context, _ []service.ResolveRequest) ([]service.ResolveResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond,
+		WithEvaluateTimeout(10*time.Millisecond),
+	)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(`{"key":"new-ui"}`)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerImportTimeout(t *testing.T) {
+	svc := &fakeService{
+		getFlagFunc: func(ctx context.Context, _, _ string) (repository.Flag, error) {
+			<-ctx.Done()
+			return repository.Flag{}, ctx.Err()
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, 5*time.Millisecond,
+		WithImportTimeout(10*time.Millisecond),
+	)
+
+	req := reqWithProject(httptest.NewRequest(http.MethodPost, "/v1/flags/import", strings.NewReader(`{"key":"a"}`)))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if resp := decodeImportResponse(t, rec); resp.Error != "request timed out" {
+		t.Fatalf("error = %q, want request timed out", resp.Error)
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/instance"
+)
+
+// WithInstance sets the identity GET /v1/info reports and SSE heartbeats
+// carry. Defaults to [instance.New] without a region.
+func WithInstance(id instance.Identity) HTTPOption {
+	return func(s *HTTPServer) {
+		s.identity = id
+	}
+}
+
+// handleInfo reports which server instance answered, so a client seeing
+// stale flags can tell which replica, region and build it is pinned to.
+func (s *HTTPServer) handleInfo(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.identity)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// keyRotationPolicyJSON is the wire form of [repository.KeyRotationPolicy],
+// with durations in whole seconds.
+type keyRotationPolicyJSON struct {
+	MaxAgeSeconds      int64 `json:"max_age_seconds"`
+	RevokeAfterSeconds int64 `json:"revoke_after_seconds"`
+}
+
+func keyRotationPolicyToJSON(policy repository.KeyRotationPolicy) keyRotationPolicyJSON {
+	return keyRotationPolicyJSON{
+		MaxAgeSeconds:      int64(policy.MaxAge / time.Second),
+		RevokeAfterSeconds: int64(policy.RevokeAfter / time.Second),
+	}
+}
+
+func (s *HTTPServer) handleGetKeyRotationPolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	policy, err := s.service.GetKeyRotationPolicy(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keyRotationPolicyToJSON(policy))
+}
+
+func (s *HTTPServer) handleSetKeyRotationPolicy(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request keyRotationPolicyJSON
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetKeyRotationPolicy(r.Context(), projectID, repository.KeyRotationPolicy{
+		MaxAge:      time.Duration(request.MaxAgeSeconds) * time.Second,
+		RevokeAfter: time.Duration(request.RevokeAfterSeconds) * time.Second,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keyRotationPolicyToJSON(stored))
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// notificationChannelJSONRequest is the body of POST
+// /v1/notifications/channels.
+type notificationChannelJSONRequest struct {
+	Kind       string   `json:"kind"`
+	Target     string   `json:"target"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (s *HTTPServer) handleListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	channels, err := s.service.ListNotificationChannels(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, channels)
+}
+
+func (s *HTTPServer) handleCreateNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request notificationChannelJSONRequest
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	channel, err := s.service.CreateNotificationChannel(r.Context(), repository.NotificationChannel{
+		ProjectID:  projectID,
+		Kind:       request.Kind,
+		Target:     request.Target,
+		EventTypes: request.EventTypes,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, channel)
+}
+
+func (s *HTTPServer) handleDeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := s.service.DeleteNotificationChannel(r.Context(), projectID, r.PathValue("id")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *HTTPServer) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			writeJSONError(w, r, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = parsed
+	}
+
+	notifications, err := s.service.ListNotifications(r.Context(), projectID, limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notifications)
+}
+
+func (s *HTTPServer) handleGetWebhookSigningSecret(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	secret, err := s.service.WebhookSigningSecret(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, secret)
+}
+
+// handleRotateWebhookSigningSecret creates or replaces the project's webhook
+// signing secret and returns it, with 201 if the project had none.
+func (s *HTTPServer) handleRotateWebhookSigningSecret(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	secret, created, err := s.service.RotateWebhookSigningSecret(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, secret)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Per-route request timeouts. Evaluations answer from the cache and should
+// be quick, so a stuck one is cut off early; imports apply a flag per line
+// and may legitimately take minutes.
+const (
+	defaultEvaluateTimeout = 5 * time.Second
+	defaultImportTimeout   = 5 * time.Minute
+)
+
+// WithEvaluateTimeout bounds how long POST /v1/evaluate,
+// POST /v1/evaluate/all and POST /v1/evaluate/explain may take, including
+// reading the request body.
+// Defaults to 5s; zero or a negative timeout disables it.
+func WithEvaluateTimeout(timeout time.Duration) HTTPOption {
+	return func(s *HTTPServer) {
+		s.evaluateTimeout = timeout
+	}
+}
+
+// WithImportTimeout bounds how long POST /v1/flags/import and
+// POST /v1/flags:batch may take, including reading the request body. Lines
+// of an import applied before the timeout stay applied. Defaults to 5m; zero
+// or a negative timeout disables it.
+func WithImportTimeout(timeout time.Duration) HTTPOption {
+	return func(s *HTTPServer) {
+		s.importTimeout = timeout
+	}
+}
+
+// withTimeout cancels the request context after timeout and sets a read
+// deadline on the body, so a client trickling its upload cannot hold the
+// handler past it either. Handlers report an expired context as 503 and an
+// expired read as 408.
+func withTimeout(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		// Not every ResponseWriter supports read deadlines (httptest's does
+		// not); the context deadline still applies.
+		_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(timeout))
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+const (
+	// SDKConfigHeader is the HTTP response header carrying the server's
+	// [SDKConfig] on flag snapshot and stream responses.
+	SDKConfigHeader = "Flagz-SDK-Config"
+	// SDKConfigMetadataKey is the gRPC header metadata key carrying the
+	// server's [SDKConfig] on ListFlags, WatchFlag and WatchProject.
+	SDKConfigMetadataKey = "flagz-sdk-config"
+)
+
+// SDKConfig holds the behaviour hints sent to SDKs when they fetch a flag
+// snapshot or open a stream. Operators tune them centrally, for example to
+// slow down polling during an incident, and SDKs apply them on their next
+// request. A zero field is omitted, leaving the SDK's own setting in place.
+type SDKConfig struct {
+	// PollInterval is how often polling SDKs should refresh their flags.
+	PollInterval time.Duration
+	// MaxBatchSize caps the number of evaluations per batch request.
+	MaxBatchSize int
+	// HeartbeatInterval is how often the SSE stream sends a "heartbeat"
+	// event with its latest event ID and the server time. SDKs may treat a
+	// stream that stays silent for much longer than this as dead. Zero
+	// disables heartbeats.
+	HeartbeatInterval time.Duration
+}
+
+type sdkConfigJSON struct {
+	PollIntervalMS      int64 `json:"poll_interval_ms,omitempty"`
+	MaxBatchSize        int   `json:"max_batch_size,omitempty"`
+	HeartbeatIntervalMS int64 `json:"heartbeat_interval_ms,omitempty"`
+}
+
+// encode returns the JSON form of c sent to SDKs, or "" if c is empty.
+func (c SDKConfig) encode() string {
+	if c == (SDKConfig{}) {
+		return ""
+	}
+	payload, err := json.Marshal(sdkConfigJSON{
+		PollIntervalMS:      c.PollInterval.Milliseconds(),
+		MaxBatchSize:        c.MaxBatchSize,
+		HeartbeatIntervalMS: c.HeartbeatInterval.Milliseconds(),
+	})
+	if err != nil {
+		return ""
+	}
+	return string(payload)
+}
+
+// WithSDKConfig advertises cfg to SDKs on GET /v1/flags and GET /v1/stream
+// and enables SSE heartbeats at cfg.HeartbeatInterval.
+func WithSDKConfig(cfg SDKConfig) HTTPOption {
+	return func(s *HTTPServer) {
+		s.sdkConfigHeader = cfg.encode()
+		s.heartbeatInterval = cfg.HeartbeatInterval
+	}
+}
+
+func (s *HTTPServer) setSDKConfigHeader(w http.ResponseWriter) {
+	if s.sdkConfigHeader != "" {
+		w.Header().Set(SDKConfigHeader, s.sdkConfigHeader)
+	}
+}
+
+// handleSDKConfig serves GET /v1/sdk/config: the calling key's complete
+// ruleset, for SDKs that evaluate flags locally. The ETag is a hash of the
+// body, so SDKs can poll with If-None-Match and get 304 Not Modified until
+// something changes.
+func (s *HTTPServer) handleSDKConfig(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s.setSDKConfigHeader(w)
+
+	ruleset, err := s.service.Ruleset(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	body, err := json.Marshal(ruleset)
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. As the
+// header is only used for caching, weak validators compare equal to strong
+// ones.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GRPCOption configures optional GRPCServer parameters.
+type GRPCOption func(*GRPCServer)
+
+// WithGRPCSDKConfig advertises cfg to SDKs in the header metadata of
+// ListFlags, WatchFlag and WatchProject.
+func WithGRPCSDKConfig(cfg SDKConfig) GRPCOption {
+	return func(s *GRPCServer) {
+		s.sdkConfigHeader = cfg.encode()
+	}
+}
+
+// sdkConfigMetadata returns the header metadata advertising the SDK config,
+// or nil if none is configured.
+func (s *GRPCServer) sdkConfigMetadata() metadata.MD {
+	if s.sdkConfigHeader == "" {
+		return nil
+	}
+	return metadata.Pairs(SDKConfigMetadataKey, s.sdkConfigHeader)
+}
+
+// setUnarySDKConfigHeader attaches the SDK config to a unary response. It is
+// best-effort: outside a real gRPC call there is no stream to attach it to.
+func (s *GRPCServer) setUnarySDKConfigHeader(ctx context.Context) {
+	if md := s.sdkConfigMetadata(); md != nil {
+		_ = grpc.SetHeader(ctx, md)
+	}
+}
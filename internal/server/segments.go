@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// segmentJSONRequest is the body of PUT /v1/segments/{name}; the name comes
+// from the path.
+type segmentJSONRequest struct {
+	Description string      `json:"description"`
+	Rules       []core.Rule `json:"rules"`
+}
+
+func (s *HTTPServer) handleListSegments(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	segments, err := s.service.ListSegments(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segments)
+}
+
+func (s *HTTPServer) handleGetSegment(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	segment, err := s.service.GetSegment(r.Context(), projectID, r.PathValue("name"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, segment)
+}
+
+func (s *HTTPServer) handlePutSegment(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request segmentJSONRequest
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.PutSegment(r.Context(), repository.Segment{
+		ProjectID:   projectID,
+		Name:        r.PathValue("name"),
+		Description: request.Description,
+		Rules:       request.Rules,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *HTTPServer) handleDeleteSegment(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := s.service.DeleteSegment(r.Context(), projectID, r.PathValue("name")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,13 +3,15 @@
 // interface so that behavior stays consistent regardless of protocol.
 //
 // The HTTP layer serves a JSON REST API under /v1/*, SSE streaming at
-// GET /v1/stream, plus /healthz and /metrics endpoints. The gRPC layer
+// GET /v1/stream, plus /healthz, /readyz and /metrics endpoints. The gRPC layer
 // implements the FlagService proto, including server-streaming WatchFlag
 // with optional per-key filtering.
 package server
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/repository"
@@ -21,19 +23,100 @@ import (
 // [service.Service].
 type Service interface {
 	CreateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+	// CreateFlags creates every flag or none; rejected flags are reported in a [*service.BatchError].
+	CreateFlags(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error)
 	UpdateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error)
+	PatchFlag(ctx context.Context, projectID, key, patchType string, patch json.RawMessage) (repository.Flag, error)
 	GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error)
 	// ListFlags returns flags sorted by key.
 	ListFlags(ctx context.Context, projectID string) ([]repository.Flag, error)
+	// ListFlagsPage returns a page of flags sorted by key and the AfterKey
+	// of the next page, or "" on the last page.
+	ListFlagsPage(ctx context.Context, q repository.FlagPageQuery) ([]repository.Flag, string, error)
 	DeleteFlag(ctx context.Context, projectID, key string) error
+	// ListFlagsReferencingAttribute returns flags whose rules or targets reference attr, sorted by key.
+	ListFlagsReferencingAttribute(ctx context.Context, projectID, attr string) ([]repository.Flag, error)
+	ReshuffleFlag(ctx context.Context, projectID, key string) (repository.Flag, error)
+	BucketFor(ctx context.Context, projectID, key, targetingKey string) (service.BucketAssignment, error)
+	GetFlagStats(ctx context.Context, projectID, key string) (repository.FlagStats, error)
+	// ListFlagRevisions returns revisions newest first.
+	ListFlagRevisions(ctx context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error)
+	RevertFlag(ctx context.Context, projectID, key string, revision int) (repository.Flag, error)
+	SetFlagTargets(ctx context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error)
+	// CopyFlag reports whether the target flag was created rather than overwritten.
+	CopyFlag(ctx context.Context, req service.CopyFlagRequest) (repository.Flag, bool, error)
+	FlagTemplates() []service.FlagTemplate
+	CreateFlagFromTemplate(ctx context.Context, req service.FlagFromTemplateRequest) (repository.Flag, error)
+	StaleFlags(ctx context.Context, projectID string, thresholds service.StaleThresholds) (service.StaleReport, error)
+	ProjectGraph(ctx context.Context, projectID string) (service.ProjectGraph, error)
+	// Ruleset returns every flag of the project in its evaluable form, sorted by key.
+	Ruleset(ctx context.Context, projectID string) (service.Ruleset, error)
+	GetFlagDefaults(ctx context.Context, projectID string) (repository.FlagDefaults, error)
+	SetFlagDefaults(ctx context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error)
+	GetFlagKeyPolicy(ctx context.Context, projectID string) (repository.FlagKeyPolicy, error)
+	SetFlagKeyPolicy(ctx context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error)
+	ProposeFlagChange(ctx context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error)
+	ListFlagProposals(ctx context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error)
+	ApproveFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error)
+	RejectFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error)
+	ListContextPresets(ctx context.Context, projectID string) ([]repository.ContextPreset, error)
+	GetContextPreset(ctx context.Context, projectID, name string) (repository.ContextPreset, error)
+	PutContextPreset(ctx context.Context, preset repository.ContextPreset) (repository.ContextPreset, error)
+	DeleteContextPreset(ctx context.Context, projectID, name string) error
+	ListSegments(ctx context.Context, projectID string) ([]repository.Segment, error)
+	GetSegment(ctx context.Context, projectID, name string) (repository.Segment, error)
+	PutSegment(ctx context.Context, segment repository.Segment) (repository.Segment, error)
+	DeleteSegment(ctx context.Context, projectID, name string) error
+	ListNotificationChannels(ctx context.Context, projectID string) ([]repository.NotificationChannel, error)
+	CreateNotificationChannel(ctx context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, projectID, id string) error
+	ListNotifications(ctx context.Context, projectID string, limit int) ([]repository.Notification, error)
+	WebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error)
+	RotateWebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, bool, error)
+	GetContextSchema(ctx context.Context, projectID string) (repository.ContextSchema, error)
+	SetContextSchema(ctx context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error)
+	GetContextEnrichment(ctx context.Context, projectID string) (repository.ContextEnrichment, error)
+	SetContextEnrichment(ctx context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error)
 	ResolveBoolean(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (bool, error)
+	ResolveBooleanDetail(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (service.ResolveResult, error)
 	ResolveBatch(ctx context.Context, requests []service.ResolveRequest) ([]service.ResolveResult, error)
+	ResolveAll(ctx context.Context, projectID string, evalContext core.EvaluationContext, preset string) ([]service.ResolveResult, error)
+	ExplainFlag(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, preset string) (service.Explanation, error)
 	ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error)
 	ListEventsSinceForKey(ctx context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error)
+	// Snapshot returns a project's flags and the newest event they reflect.
+	Snapshot(ctx context.Context, projectID string) (service.ProjectSnapshot, error)
+	ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, error)
+	// QueryEvents returns a page of a project's event history and the
+	// cursor of the next page, or 0 on the last page.
+	QueryEvents(ctx context.Context, q repository.EventQuery) ([]repository.FlagEvent, int64, error)
+	// AuthorizeAdminAPIKey returns [service.ErrAdminKeyRequired] unless keyID is admin-scoped.
+	AuthorizeAdminAPIKey(ctx context.Context, keyID string) error
+	ListAllEventsSince(ctx context.Context, eventID int64) ([]repository.FlagEvent, error)
+	// EventsChanged returns a channel closed when new events can be listed,
+	// or nil if streams must poll.
+	EventsChanged() <-chan struct{}
+	ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error)
+	ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error)
+	Backup(ctx context.Context, emit func(repository.BackupRecord) error) error
+	RestoreBackup(ctx context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error)
 	CreateAPIKey(ctx context.Context, projectID string) (string, string, error)
 	ListAPIKeys(ctx context.Context, projectID string) ([]repository.APIKeyMeta, error)
 	DeleteAPIKey(ctx context.Context, projectID, keyID string) error
+	GetKeyRotationPolicy(ctx context.Context, projectID string) (repository.KeyRotationPolicy, error)
+	SetKeyRotationPolicy(ctx context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error)
+	// AllowAPIKeyUsage counts a request against the key's hourly quota, or
+	// returns [service.ErrAPIKeyQuotaExceeded].
+	AllowAPIKeyUsage(ctx context.Context, projectID, apiKeyID string, kind service.UsageKind) error
+	RecordAPIKeyStreamTime(projectID, apiKeyID string, start, end time.Time)
+	ListAPIKeyUsage(ctx context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error)
+	GetAPIKeyQuota(ctx context.Context, projectID string) (repository.APIKeyQuota, error)
+	SetAPIKeyQuota(ctx context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error)
 	ListAuditLog(ctx context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error)
+	// ClaimIdempotencyKey reports whether the caller owns key; otherwise it returns the record holding it.
+	ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string) (repository.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error
 }
 
 var _ Service = (*service.Service)(nil)
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// StreamDrainer ends open streams cleanly when the server shuts down. Each
+// stream sends its client a final reconnect event carrying the latest event
+// ID it reached and closes, so the client resumes on another replica rather
+// than seeing the connection cut. Share one drainer between the HTTP and
+// gRPC servers so [StreamDrainer.Drain] covers both transports.
+type StreamDrainer struct {
+	mu       sync.Mutex
+	draining bool
+	drain    chan struct{}
+	open     sync.WaitGroup
+}
+
+// NewStreamDrainer returns a [StreamDrainer] with no streams open.
+func NewStreamDrainer() *StreamDrainer {
+	return &StreamDrainer{drain: make(chan struct{})}
+}
+
+// join registers a new stream. It reports false once draining has begun.
+// Otherwise the stream must end once drain is closed and call leave when it
+// has. A nil drainer accepts every stream and never closes drain.
+func (d *StreamDrainer) join() (drain <-chan struct{}, leave func(), ok bool) {
+	if d == nil {
+		return nil, func() {}, true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, nil, false
+	}
+	d.open.Add(1)
+
+	var once sync.Once
+	return d.drain, func() { once.Do(d.open.Done) }, true
+}
+
+// Drain refuses new streams, tells every open stream to send its final
+// reconnect event and end, and waits until they all have or ctx is done.
+// It returns ctx's error if streams were still open. Calling it again only
+// waits.
+func (d *StreamDrainer) Drain(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		close(d.drain)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.open.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithStreamDrainer ends SSE streams with a "reconnect" event when the
+// drainer drains, and answers new streams with 503 from then on.
+func WithStreamDrainer(d *StreamDrainer) HTTPOption {
+	return func(s *HTTPServer) {
+		s.streamDrainer = d
+	}
+}
+
+// WithGRPCStreamDrainer ends WatchFlag, WatchProject and WatchAllProjects
+// streams with a RECONNECT event when the drainer drains, and fails new
+// streams with Unavailable from then on.
+func WithGRPCStreamDrainer(d *StreamDrainer) GRPCOption {
+	return func(s *GRPCServer) {
+		s.streamDrainer = d
+	}
+}
+
+// writeShuttingDown answers a stream opened after draining has begun.
+func writeShuttingDown(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	middleware.WriteProblem(w, r, middleware.Problem{
+		Status: http.StatusServiceUnavailable,
+		Type:   middleware.ProblemType("shutting-down"),
+		Detail: "server is shutting down; reconnect to another replica",
+	})
+}
+
+// sseReconnectJSON is the payload of a "reconnect" SSE event.
+type sseReconnectJSON struct {
+	EventID int64 `json:"event_id"`
+}
+
+// writeSSEReconnect writes the "reconnect" event that ends a draining
+// stream, carrying the ID of the last event the stream has reached. Like a
+// heartbeat it has no "id:" field, so it leaves the client's Last-Event-ID
+// alone.
+func writeSSEReconnect(w io.Writer, eventID int64) error {
+	payload, err := json.Marshal(sseReconnectJSON{EventID: eventID})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: reconnect\ndata: %s\n\n", payload)
+	return err
+}
+
+// errStreamShuttingDown fails a gRPC stream opened after draining has begun.
+var errStreamShuttingDown = status.Error(codes.Unavailable, "server is shutting down; reconnect to another replica")
+
+// reconnectEvent is the final event of a draining gRPC stream.
+func reconnectEvent(lastEventID int64) *flagspb.WatchFlagEvent {
+	return &flagspb.WatchFlagEvent{Type: flagspb.WatchFlagEventType_RECONNECT, EventId: lastEventID}
+}
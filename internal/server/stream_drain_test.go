@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamDrainer(t *testing.T) {
+	var none *StreamDrainer
+	if _, leave, ok := none.join(); !ok {
+		t.Fatal("nil drainer should accept every stream")
+	} else {
+		leave()
+	}
+	if err := none.Drain(context.Background()); err != nil {
+		t.Fatalf("nil drainer Drain() error = %v", err)
+	}
+
+	d := NewStreamDrainer()
+	drain, leave, ok := d.join()
+	if !ok {
+		t.Fatal("join() refused a stream before draining")
+	}
+
+	// An open stream keeps Drain waiting until its context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Drain(ctx); err == nil {
+		t.Fatal("Drain() should time out while a stream is open")
+	}
+	select {
+	case <-drain:
+	default:
+		t.Fatal("drain should be closed once draining begins")
+	}
+	if _, _, ok := d.join(); ok {
+		t.Fatal("join() should refuse streams while draining")
+	}
+
+	leave()
+	leave()
+	if err := d.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v, want nil once every stream has left", err)
+	}
+}
+
+func TestHTTPHandlerStreamDrain(t *testing.T) {
+	listed := make(chan struct{}, 1)
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, eventID int64) ([]repository.FlagEvent, error) {
+			select {
+			case listed <- struct{}{}:
+			default:
+			}
+			if eventID >= 7 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{
+				EventID:   7,
+				EventType: "updated",
+				FlagKey:   "new-ui",
+				Payload:   json.RawMessage(`{"key":"new-ui","enabled":true}`),
+			}}, nil
+		},
+	}
+	drainer := NewStreamDrainer()
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithStreamDrainer(drainer))
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctxWithProject()))
+	}()
+	<-listed
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := drainer.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	<-done
+
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "event: reconnect\ndata: {\"event_id\":7}\n\n") {
+		t.Fatalf("stream should end with a reconnect event for event 7, got %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctxWithProject()))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status after draining = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(rec.Body.String(), "shutting-down") {
+		t.Errorf("body = %s, want the shutting-down problem type", rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+}
+
+func TestGRPCServerWatchFlagDrain(t *testing.T) {
+	listed := make(chan struct{}, 1)
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, eventID int64) ([]repository.FlagEvent, error) {
+			select {
+			case listed <- struct{}{}:
+			default:
+			}
+			if eventID >= 7 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{
+				EventID:   7,
+				EventType: "updated",
+				FlagKey:   "new-ui",
+				Payload:   json.RawMessage(`{"key":"new-ui","enabled":true}`),
+			}}, nil
+		},
+	}
+	drainer := NewStreamDrainer()
+	grpcServer := NewGRPCServerWithOptions(svc, time.Hour, nil, WithGRPCStreamDrainer(drainer))
+
+	stream := &fakeWatchFlagServer{ctx: ctxWithProject()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- grpcServer.WatchFlag(&flagspb.WatchFlagRequest{}, stream)
+	}()
+	<-listed
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := drainer.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("WatchFlag() error = %v", err)
+	}
+
+	if len(stream.events) != 2 {
+		t.Fatalf("events = %v, want the update and a reconnect", stream.events)
+	}
+	if last := stream.events[1]; last.GetType() != flagspb.WatchFlagEventType_RECONNECT || last.GetEventId() != 7 {
+		t.Errorf("last event = %v, want RECONNECT at event 7", last)
+	}
+
+	err := grpcServer.WatchFlag(&flagspb.WatchFlagRequest{}, &fakeWatchFlagServer{ctx: ctxWithProject()})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("WatchFlag() after draining code = %v, want %v", status.Code(err), codes.Unavailable)
+	}
+}
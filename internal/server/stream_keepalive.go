@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/instance"
+)
+
+// The reconnection delay suggested to SSE clients is drawn from
+// [sseRetryMin, sseRetryMin+sseRetryJitter) for each stream, so clients that
+// were disconnected together, by a deploy for example, spread out their
+// reconnects instead of arriving at once.
+const (
+	sseRetryMin    = time.Second
+	sseRetryJitter = 4 * time.Second
+)
+
+// WithStreamKeepalive writes a ": keepalive" comment to SSE streams that have
+// been silent for interval, so load balancers and proxies with an idle
+// timeout do not drop them. Events and heartbeats both count as traffic.
+// Zero or a negative interval disables keepalives.
+func WithStreamKeepalive(interval time.Duration) HTTPOption {
+	return func(s *HTTPServer) {
+		s.keepaliveInterval = interval
+	}
+}
+
+// writeSSERetry writes the "retry:" field that sets how long an EventSource
+// waits before reconnecting after the stream drops.
+func writeSSERetry(w io.Writer) error {
+	delay := sseRetryMin + rand.N(sseRetryJitter)
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", delay.Milliseconds())
+	return err
+}
+
+// sseHeartbeatJSON is the payload of a "heartbeat" SSE event.
+type sseHeartbeatJSON struct {
+	EventID    int64             `json:"event_id"`
+	ServerTime time.Time         `json:"server_time"`
+	Instance   instance.Identity `json:"instance"`
+}
+
+// writeSSEHeartbeat writes a "heartbeat" event carrying the ID of the last
+// event the stream has reached, so a client can tell it is still connected
+// and how far behind the stream its own position is. The event has no "id:"
+// field, so it leaves the client's Last-Event-ID alone.
+func writeSSEHeartbeat(w io.Writer, eventID int64, now time.Time, id instance.Identity) error {
+	payload, err := json.Marshal(sseHeartbeatJSON{EventID: eventID, ServerTime: now.UTC(), Instance: id})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: heartbeat\ndata: %s\n\n", payload)
+	return err
+}
+
+// sseKeepalive times the silence on an SSE stream.
+type sseKeepalive struct {
+	timer    *time.Timer
+	interval time.Duration
+}
+
+func newSSEKeepalive(interval time.Duration) *sseKeepalive {
+	if interval <= 0 {
+		return &sseKeepalive{}
+	}
+	return &sseKeepalive{timer: time.NewTimer(interval), interval: interval}
+}
+
+// C fires once the stream has been silent for the interval. It is nil, and
+// so never fires, when keepalives are disabled.
+func (k *sseKeepalive) C() <-chan time.Time {
+	if k.timer == nil {
+		return nil
+	}
+	return k.timer.C
+}
+
+// reset starts a new silence window; call it after writing to the stream.
+func (k *sseKeepalive) reset() {
+	if k.timer != nil {
+		k.timer.Reset(k.interval)
+	}
+}
+
+func (k *sseKeepalive) stop() {
+	if k.timer != nil {
+		k.timer.Stop()
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+)
+
+// StreamLimiter caps how many streams one API key may hold open at once.
+// Share one limiter between the HTTP and gRPC servers so SSE streams and
+// gRPC watches count against the same allowance.
+type StreamLimiter struct {
+	mu   sync.Mutex
+	max  int
+	open map[string]int
+}
+
+// NewStreamLimiter returns a [StreamLimiter] allowing max concurrent
+// streams per API key. It returns nil, which allows every stream, when max
+// is zero or negative.
+func NewStreamLimiter(max int) *StreamLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &StreamLimiter{max: max, open: make(map[string]int)}
+}
+
+// acquire claims a stream slot for the API key in ctx. It reports false if
+// the key already has the maximum open; otherwise the caller must call
+// release once the stream ends. Requests without an API key, such as admin
+// sessions, are not limited.
+func (l *StreamLimiter) acquire(ctx context.Context) (release func(), ok bool) {
+	keyID, _ := middleware.APIKeyIDFromContext(ctx)
+	if l == nil || keyID == "" {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open[keyID] >= l.max {
+		return nil, false
+	}
+	l.open[keyID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.open[keyID]--; l.open[keyID] <= 0 {
+				delete(l.open, keyID)
+			}
+		})
+	}, true
+}
+
+// WithStreamLimiter limits the SSE streams each API key may hold open to
+// the limiter's maximum. Streams over it are answered with 429.
+func WithStreamLimiter(l *StreamLimiter) HTTPOption {
+	return func(s *HTTPServer) {
+		s.streamLimiter = l
+	}
+}
+
+// WithGRPCStreamLimiter limits the WatchFlag and WatchProject streams each
+// API key may hold open to the limiter's maximum. Streams over it fail with
+// ResourceExhausted.
+func WithGRPCStreamLimiter(l *StreamLimiter) GRPCOption {
+	return func(s *GRPCServer) {
+		s.streamLimiter = l
+	}
+}
+
+// WithStreamWriteTimeout bounds each write to an SSE stream. A client that
+// stops reading, and so lets the connection's send buffer fill, has its
+// stream closed after timeout instead of holding it open indefinitely.
+// Zero or a negative timeout disables the bound.
+func WithStreamWriteTimeout(timeout time.Duration) HTTPOption {
+	return func(s *HTTPServer) {
+		s.streamWriteTimeout = timeout
+	}
+}
+
+// deadlineWriter bounds each write and flush on an SSE stream with a write
+// deadline, cleared again afterwards so an idle stream is not closed by a
+// deadline that lapsed between events.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+// streamWriter returns the writer and response controller an SSE handler
+// writes through: w itself, or w wrapped to bound each write when a stream
+// write timeout is configured.
+func (s *HTTPServer) streamWriter(w http.ResponseWriter) (http.ResponseWriter, *http.ResponseController) {
+	rc := http.NewResponseController(w)
+	if s.streamWriteTimeout <= 0 {
+		return w, rc
+	}
+	dw := &deadlineWriter{ResponseWriter: w, rc: rc, timeout: s.streamWriteTimeout}
+	return dw, http.NewResponseController(dw)
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	_ = w.rc.SetWriteDeadline(time.Now().Add(w.timeout))
+	defer func() { _ = w.rc.SetWriteDeadline(time.Time{}) }()
+	return w.ResponseWriter.Write(p)
+}
+
+// FlushError is called by [http.ResponseController.Flush].
+func (w *deadlineWriter) FlushError() error {
+	_ = w.rc.SetWriteDeadline(time.Now().Add(w.timeout))
+	defer func() { _ = w.rc.SetWriteDeadline(time.Time{}) }()
+	return w.rc.Flush()
+}
+
+// Unwrap lets [http.ResponseController] reach the underlying writer.
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	flagspb "github.com/matt-riley/flagz/api/proto/v1"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	if NewStreamLimiter(0) != nil {
+		t.Fatal("NewStreamLimiter(0) should return nil")
+	}
+	var unlimited *StreamLimiter
+	if _, ok := unlimited.acquire(middleware.NewContextWithAPIKeyID(context.Background(), "key-1")); !ok {
+		t.Fatal("nil limiter should allow every stream")
+	}
+
+	l := NewStreamLimiter(2)
+	ctx := middleware.NewContextWithAPIKeyID(context.Background(), "key-1")
+	release1, ok1 := l.acquire(ctx)
+	_, ok2 := l.acquire(ctx)
+	if !ok1 || !ok2 {
+		t.Fatal("first two streams should be allowed")
+	}
+	if _, ok := l.acquire(ctx); ok {
+		t.Fatal("third stream should be refused")
+	}
+	if _, ok := l.acquire(middleware.NewContextWithAPIKeyID(context.Background(), "key-2")); !ok {
+		t.Fatal("another key should have its own allowance")
+	}
+	if _, ok := l.acquire(context.Background()); !ok {
+		t.Fatal("requests without an API key should not be limited")
+	}
+
+	release1()
+	release1()
+	if _, ok := l.acquire(ctx); !ok {
+		t.Fatal("stream should be allowed after one is released")
+	}
+	if _, ok := l.acquire(ctx); ok {
+		t.Fatal("releasing twice should free only one slot")
+	}
+}
+
+func TestHTTPHandlerStreamLimitPerAPIKey(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			return nil, nil
+		},
+	}
+	limiter := NewStreamLimiter(1)
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithStreamLimiter(limiter))
+
+	release, ok := limiter.acquire(middleware.NewContextWithAPIKeyID(context.Background(), "key-1"))
+	if !ok {
+		t.Fatal("acquire() refused the first stream")
+	}
+	defer release()
+
+	stream := func(keyID string) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithTimeout(middleware.NewContextWithAPIKeyID(ctxWithProject(), keyID), 20*time.Millisecond)
+		defer cancel()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
+		return rec
+	}
+
+	if rec := stream("key-1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec := stream("key-2"); rec.Code != http.StatusOK {
+		t.Fatalf("other key status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestGRPCServerWatchStreamLimitPerAPIKey(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, _ int64) ([]repository.FlagEvent, error) {
+			t.Fatal("ListEventsSince should not be called")
+			return nil, nil
+		},
+		snapshotFunc: func(_ context.Context, _ string) (service.ProjectSnapshot, error) {
+			t.Fatal("Snapshot should not be called")
+			return service.ProjectSnapshot{}, nil
+		},
+	}
+	limiter := NewStreamLimiter(1)
+	grpcServer := NewGRPCServerWithOptions(svc, time.Hour, nil, WithGRPCStreamLimiter(limiter))
+
+	ctx := middleware.NewContextWithAPIKeyID(ctxWithProject(), "key-1")
+	release, ok := limiter.acquire(ctx)
+	if !ok {
+		t.Fatal("acquire() refused the first stream")
+	}
+	defer release()
+
+	err := grpcServer.WatchFlag(&flagspb.WatchFlagRequest{}, &fakeWatchFlagServer{ctx: ctx})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("WatchFlag() code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+	err = grpcServer.WatchProject(&flagspb.WatchProjectRequest{}, &fakeWatchProjectServer{ctx: ctx})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("WatchProject() code = %v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+}
+
+// deadlineRecorder records the write deadlines set through an
+// [http.ResponseController].
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadlines []time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadlines = append(r.deadlines, deadline)
+	return nil
+}
+
+func TestHTTPHandlerStreamWriteTimeout(t *testing.T) {
+	svc := &fakeService{
+		listEventsSinceFunc: func(_ context.Context, _ string, eventID int64) ([]repository.FlagEvent, error) {
+			if eventID > 0 {
+				return nil, nil
+			}
+			return []repository.FlagEvent{{EventID: 1, EventType: "updated", FlagKey: "f", Payload: []byte(`{}`)}}, nil
+		},
+	}
+	handler := NewHTTPHandlerWithStreamPollInterval(svc, time.Hour, WithStreamWriteTimeout(time.Minute))
+
+	ctx, cancel := context.WithTimeout(ctxWithProject(), 20*time.Millisecond)
+	defer cancel()
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/stream", nil).WithContext(ctx))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(rec.deadlines) == 0 || len(rec.deadlines)%2 != 0 {
+		t.Fatalf("deadlines = %v, want set and cleared pairs", rec.deadlines)
+	}
+	for i := 0; i < len(rec.deadlines); i += 2 {
+		if rec.deadlines[i].IsZero() || !rec.deadlines[i+1].IsZero() {
+			t.Fatalf("deadlines[%d:%d] = %v, want a deadline then a cleared one", i, i+2, rec.deadlines[i:i+2])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+	"github.com/matt-riley/flagz/internal/service"
+)
+
+// defaultUsageWindow is how far back GET /v1/usage reports without since.
+const defaultUsageWindow = 24 * time.Hour
+
+type usageResponse struct {
+	Since time.Time                `json:"since"`
+	Quota repository.APIKeyQuota   `json:"quota"`
+	Usage []repository.APIKeyUsage `json:"usage"`
+}
+
+// usageKind classifies a request for API key usage metering: evaluations,
+// and writes other than evaluations. Everything else, including reads and
+// streams, is not counted as a request.
+func usageKind(r *http.Request) (service.UsageKind, bool) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/evaluate"):
+		return service.UsageEvaluation, true
+	case idempotentMethod(r.Method):
+		return service.UsageMutation, true
+	default:
+		return 0, false
+	}
+}
+
+// withUsage counts each evaluation and mutation against the caller's API
+// key, answering requests over the project's hourly quota with 429.
+func (s *HTTPServer) withUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind, metered := usageKind(r)
+		apiKeyID, hasKey := middleware.APIKeyIDFromContext(r.Context())
+		projectID, hasProject := middleware.ProjectIDFromContext(r.Context())
+		if !metered || !hasKey || !hasProject {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := s.service.AllowAPIKeyUsage(r.Context(), projectID, apiKeyID, kind); err != nil {
+			if errors.Is(err, service.ErrAPIKeyQuotaExceeded) {
+				w.Header().Set("Retry-After", strconv.Itoa(secondsUntilNextHour(time.Now())))
+			}
+			writeServiceError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// secondsUntilNextHour is when an hourly quota resets, rounded up.
+func secondsUntilNextHour(now time.Time) int {
+	next := now.Truncate(time.Hour).Add(time.Hour)
+	return int((next.Sub(now) + time.Second - 1) / time.Second)
+}
+
+// recordStreamTime counts the time since start against the API key in ctx;
+// defer it once a stream is open.
+func recordStreamTime(ctx context.Context, svc Service, projectID string, start time.Time) {
+	if apiKeyID, ok := middleware.APIKeyIDFromContext(ctx); ok {
+		svc.RecordAPIKeyStreamTime(projectID, apiKeyID, start, time.Now())
+	}
+}
+
+// allowUsage is the gRPC counterpart of withUsage.
+func (s *GRPCServer) allowUsage(ctx context.Context, projectID string, kind service.UsageKind) error {
+	apiKeyID, ok := middleware.APIKeyIDFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if err := s.service.AllowAPIKeyUsage(ctx, projectID, apiKeyID, kind); err != nil {
+		return toGRPCError(err)
+	}
+	return nil
+}
+
+func (s *HTTPServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	since := time.Now().UTC().Add(-defaultUsageWindow).Truncate(time.Hour)
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = parsed.UTC().Truncate(time.Hour)
+	}
+
+	quota, err := s.service.GetAPIKeyQuota(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	usage, err := s.service.ListAPIKeyUsage(r.Context(), projectID, since)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usageResponse{Since: since, Quota: quota, Usage: usage})
+}
+
+func (s *HTTPServer) handleGetAPIKeyQuota(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	quota, err := s.service.GetAPIKeyQuota(r.Context(), projectID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quota)
+}
+
+func (s *HTTPServer) handleSetAPIKeyQuota(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := middleware.ProjectIDFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request repository.APIKeyQuota
+	if err := s.decodeJSONBody(w, r, &request); err != nil {
+		writeJSONDecodeError(w, r, err)
+		return
+	}
+
+	stored, err := s.service.SetAPIKeyQuota(r.Context(), projectID, request)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stored)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+var (
+	// ErrAdminKeyRequired is returned when a key that is not admin-scoped
+	// asks for events across all projects.
+	ErrAdminKeyRequired = errors.New("admin-scoped api key required")
+
+	errAdminStreamNotSupported = errors.New("all-projects event stream not supported")
+)
+
+// AdminStreamRepository defines the storage behind the all-projects event
+// stream. It is optionally satisfied by [repository.PostgresRepository].
+type AdminStreamRepository interface {
+	GetAPIKeyScope(ctx context.Context, keyID string) (string, error)
+	ListAllEventsSince(ctx context.Context, eventID int64) ([]repository.FlagEvent, error)
+}
+
+// AuthorizeAdminAPIKey checks that keyID is a live, admin-scoped API key.
+// Returns [ErrAdminKeyRequired] otherwise.
+func (s *Service) AuthorizeAdminAPIKey(ctx context.Context, keyID string) error {
+	if strings.TrimSpace(keyID) == "" {
+		return ErrAdminKeyRequired
+	}
+	repo, ok := s.repo.(AdminStreamRepository)
+	if !ok {
+		return errAdminStreamNotSupported
+	}
+
+	scope, err := repo.GetAPIKeyScope(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAdminKeyRequired
+		}
+		return fmt.Errorf("get api key scope: %w", err)
+	}
+	if scope != repository.APIKeyScopeAdmin {
+		return ErrAdminKeyRequired
+	}
+	return nil
+}
+
+// ListAllEventsSince returns flag events of every project with IDs greater
+// than eventID. Callers must authorize the caller with
+// [Service.AuthorizeAdminAPIKey] first.
+func (s *Service) ListAllEventsSince(ctx context.Context, eventID int64) ([]repository.FlagEvent, error) {
+	repo, ok := s.repo.(AdminStreamRepository)
+	if !ok {
+		return nil, errAdminStreamNotSupported
+	}
+	if events, ok := s.recentEvents("", eventID, repository.EventFilter{}); ok {
+		return events, nil
+	}
+
+	events, err := repo.ListAllEventsSince(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("list all events since %d: %w", eventID, err)
+	}
+	return events, nil
+}
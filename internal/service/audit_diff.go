@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// auditDiffIgnored lists the flag fields left out of audit diffs: they
+// identify the flag, or record when and by whom it was written, which the
+// audit entry itself already does.
+var auditDiffIgnored = map[string]bool{
+	"key":        true,
+	"created_at": true,
+	"updated_at": true,
+	"created_by": true,
+	"updated_by": true,
+}
+
+// auditDiffRedacted lists the flag fields whose values are left out of
+// audit diffs. Variants may carry secrets, and are encrypted at rest when
+// the repository has a key provider, which the audit log is not.
+var auditDiffRedacted = map[string]bool{
+	"variants": true,
+}
+
+// FieldChange is one changed field in the details of a flag mutation's
+// audit log entry. Old or New is null when the field was unset. Fields in
+// auditDiffRedacted set only Changed.
+type FieldChange struct {
+	Old     json.RawMessage `json:"old,omitempty"`
+	New     json.RawMessage `json:"new,omitempty"`
+	Changed bool            `json:"changed,omitempty"`
+}
+
+// FlagChangeDetails is the [repository.AuditLogEntry.Details] of a flag
+// update: Changes maps the JSON name of each field that changed, such as
+// "rules" or "enabled", to its values before and after.
+type FlagChangeDetails struct {
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// flagChanges compares two versions of a flag field by field, as they are
+// encoded in API responses.
+func flagChanges(before, after repository.Flag) FlagChangeDetails {
+	details := FlagChangeDetails{Changes: make(map[string]FieldChange)}
+	old, err := flagFields(before)
+	if err != nil {
+		return details
+	}
+	updated, err := flagFields(after)
+	if err != nil {
+		return details
+	}
+
+	names := maps.Clone(old)
+	maps.Copy(names, updated)
+	for name := range names {
+		if auditDiffIgnored[name] || bytes.Equal(old[name], updated[name]) {
+			continue
+		}
+		if auditDiffRedacted[name] {
+			details.Changes[name] = FieldChange{Changed: true}
+			continue
+		}
+		details.Changes[name] = FieldChange{Old: nullIfMissing(old[name]), New: nullIfMissing(updated[name])}
+	}
+	return details
+}
+
+// flagFields encodes flag and returns its fields, each compacted so that
+// formatting differences do not count as changes.
+func flagFields(flag repository.Flag) (map[string]json.RawMessage, error) {
+	payload, err := json.Marshal(flag)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err == nil {
+			fields[name] = compact.Bytes()
+		}
+	}
+	return fields, nil
+}
+
+func nullIfMissing(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// insertFlagChangeAuditLog records a flag mutation together with what it
+// changed.
+func (s *Service) insertFlagChangeAuditLog(ctx context.Context, action string, before, after repository.Flag) {
+	details, err := json.Marshal(flagChanges(before, after))
+	if err != nil {
+		details = nil
+	}
+	s.insertAuditLogDetailsBestEffort(ctx, after.ProjectID, action, after.Key, details)
+}
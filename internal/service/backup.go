@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrProjectNameTaken is returned when a backup is restored without renaming
+// conflicts and one of its projects has the name of an existing project.
+var ErrProjectNameTaken = errors.New("project name is already taken")
+
+var errBackupNotSupported = errors.New("backup and restore not supported")
+
+// BackupRepository defines taking and restoring logical backups.
+// It is optionally satisfied by [repository.PostgresRepository].
+type BackupRepository interface {
+	Backup(ctx context.Context, emit func(repository.BackupRecord) error) error
+	Restore(ctx context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error)
+}
+
+// Backup passes every project, with its API key metadata, segments, flags
+// and audit log, to emit. It covers all projects, so callers must authorize
+// an admin key first.
+func (s *Service) Backup(ctx context.Context, emit func(repository.BackupRecord) error) error {
+	ctx, span := svcTracer.Start(ctx, "service.Backup")
+	defer span.End()
+
+	repo, ok := s.repo.(BackupRepository)
+	if !ok {
+		return errBackupNotSupported
+	}
+	if err := repo.Backup(ctx, emit); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+// RestoreBackup restores the records next returns as new projects and
+// reloads the cache, returning the IDs they were restored under. Nothing is
+// restored if any record fails. Callers must authorize an admin key first.
+func (s *Service) RestoreBackup(ctx context.Context, opts repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error) {
+	ctx, span := svcTracer.Start(ctx, "service.RestoreBackup")
+	defer span.End()
+
+	repo, ok := s.repo.(BackupRepository)
+	if !ok {
+		return repository.RestoreResult{}, errBackupNotSupported
+	}
+	result, err := repo.Restore(ctx, opts, next)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, repository.ErrProjectNameTaken) {
+			return repository.RestoreResult{}, fmt.Errorf("%w: %v", ErrProjectNameTaken, err)
+		}
+		return repository.RestoreResult{}, fmt.Errorf("restore backup: %w", err)
+	}
+
+	s.reloadCache(ctx)
+	for _, projectID := range result.Projects {
+		s.insertAuditLogBestEffort(ctx, projectID, "restore_backup", "")
+	}
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrTargetingKeyRequired is returned when a bucket lookup is made without a
+// targeting key.
+var ErrTargetingKeyRequired = errors.New("targeting key is required")
+
+// BucketAssignment describes where a targeting key lands in a flag's
+// percentage rollouts. Bucket is in [0, core.BucketCount).
+type BucketAssignment struct {
+	Key           string          `json:"key"`
+	TargetingKey  string          `json:"targeting_key"`
+	BucketingSalt string          `json:"bucketing_salt"`
+	Bucket        int             `json:"bucket"`
+	Rollouts      []RolloutBucket `json:"rollouts"`
+}
+
+// RolloutBucket reports whether the targeting key is inside a single
+// percentage rule. The rule only matches at evaluation time when Attribute
+// is present in the evaluation context and carries the same targeting key.
+type RolloutBucket struct {
+	RuleIndex  int     `json:"rule_index"`
+	Attribute  string  `json:"attribute"`
+	Percentage float64 `json:"percentage"`
+	InRollout  bool    `json:"in_rollout"`
+}
+
+// BucketFor computes the rollout bucket targetingKey falls into for a flag
+// and, for every percentage rule on the flag, whether that bucket is inside
+// the rollout. It answers "is user X in the rollout?" without needing to
+// reconstruct a full evaluation context.
+func (s *Service) BucketFor(ctx context.Context, projectID, key, targetingKey string) (BucketAssignment, error) {
+	ctx, span := svcTracer.Start(ctx, "service.BucketFor")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+	)
+
+	if targetingKey == "" {
+		return BucketAssignment{}, ErrTargetingKeyRequired
+	}
+
+	entry, err := s.flagForEvaluation(ctx, projectID, key)
+	if err != nil {
+		return BucketAssignment{}, err
+	}
+	flag, coreFlag := entry.flag, entry.core
+
+	bucket := core.Bucket(coreFlag, targetingKey)
+	assignment := BucketAssignment{
+		Key:           flag.Key,
+		TargetingKey:  targetingKey,
+		BucketingSalt: flag.BucketingSalt,
+		Bucket:        bucket,
+		Rollouts:      []RolloutBucket{},
+	}
+	for i, rule := range coreFlag.Rules {
+		percentage, ok := core.RolloutPercentage(rule)
+		if !ok {
+			continue
+		}
+		assignment.Rollouts = append(assignment.Rollouts, RolloutBucket{
+			RuleIndex:  i,
+			Attribute:  rule.Attribute,
+			Percentage: percentage,
+			InRollout:  core.BucketInRollout(bucket, percentage),
+		})
+	}
+
+	return assignment, nil
+}
+
+// ReshuffleFlag rotates a flag's bucketing salt so that every targeting key
+// is reassigned to a new percentage-rollout bucket. The updated flag is
+// cached and an "updated" event is published so streaming clients pick up
+// the new salt. Returns [ErrFlagNotFound] if the flag does not exist.
+func (s *Service) ReshuffleFlag(ctx context.Context, projectID, key string) (repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ReshuffleFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return repository.Flag{}, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(projectID) == "" {
+		return repository.Flag{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(BucketingRepository)
+	if !ok {
+		return repository.Flag{}, errReshuffleNotSupported
+	}
+
+	updated, err := repo.RotateBucketingSalt(ctx, projectID, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.deleteCachedFlag(projectID, key)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "flag not found")
+			return repository.Flag{}, ErrFlagNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "reshuffle flag failed")
+		return repository.Flag{}, fmt.Errorf("reshuffle flag: %w", err)
+	}
+
+	s.setCachedFlag(updated)
+	s.publishFlagEventBestEffort(ctx, EventTypeUpdated, updated)
+	s.insertAuditLogBestEffort(ctx, updated.ProjectID, "reshuffle", updated.Key)
+
+	return updated, nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// maxEnrichedAttributes caps the attributes a context enrichment may add.
+const maxEnrichedAttributes = 100
+
+var (
+	// ErrInvalidContextEnrichment is returned when a context enrichment has
+	// a blank or repeated attribute name, an invalid header name, or too
+	// many attributes.
+	ErrInvalidContextEnrichment = errors.New("invalid context enrichment")
+
+	errContextEnrichmentNotSupported = errors.New("context enrichment not supported")
+)
+
+// ContextEnrichmentRepository defines storage of per-project context
+// enrichment. It is optionally satisfied by [repository.PostgresRepository].
+type ContextEnrichmentRepository interface {
+	GetContextEnrichment(ctx context.Context, projectID string) (repository.ContextEnrichment, error)
+	SetContextEnrichment(ctx context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error)
+}
+
+type requestHeadersKey struct{}
+
+// NewContextWithRequestHeaders returns a copy of ctx carrying header, which
+// looks up a header, or gRPC metadata key, of the request being evaluated.
+// A project's [repository.ContextEnrichment.Headers] are read through it.
+func NewContextWithRequestHeaders(ctx context.Context, header func(name string) string) context.Context {
+	return context.WithValue(ctx, requestHeadersKey{}, header)
+}
+
+// RequestHeaderFromContext returns the header lookup stored by
+// [NewContextWithRequestHeaders], if any.
+func RequestHeaderFromContext(ctx context.Context) (func(name string) string, bool) {
+	header, ok := ctx.Value(requestHeadersKey{}).(func(string) string)
+	return header, ok
+}
+
+// GetContextEnrichment returns the attributes the server adds to a project's
+// evaluation contexts. When the repository does not store enrichment, an
+// empty one is returned. Returns [ErrProjectNotFound] if the project does
+// not exist.
+func (s *Service) GetContextEnrichment(ctx context.Context, projectID string) (repository.ContextEnrichment, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.ContextEnrichment{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(ContextEnrichmentRepository)
+	if !ok {
+		return emptyContextEnrichment(repository.ContextEnrichment{}), nil
+	}
+
+	enrichment, err := repo.GetContextEnrichment(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ContextEnrichment{}, ErrProjectNotFound
+		}
+		return repository.ContextEnrichment{}, fmt.Errorf("get context enrichment: %w", err)
+	}
+	return emptyContextEnrichment(enrichment), nil
+}
+
+// SetContextEnrichment validates and stores the attributes the server adds
+// to a project's evaluation contexts. Returns [ErrProjectNotFound] if the
+// project does not exist.
+func (s *Service) SetContextEnrichment(ctx context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetContextEnrichment")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.ContextEnrichment{}, ErrProjectIDRequired
+	}
+	enrichment, err := normalizeContextEnrichment(enrichment)
+	if err != nil {
+		return repository.ContextEnrichment{}, err
+	}
+
+	repo, ok := s.repo.(ContextEnrichmentRepository)
+	if !ok {
+		return repository.ContextEnrichment{}, errContextEnrichmentNotSupported
+	}
+
+	stored, err := repo.SetContextEnrichment(ctx, projectID, enrichment)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ContextEnrichment{}, ErrProjectNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set context enrichment failed")
+		return repository.ContextEnrichment{}, fmt.Errorf("set context enrichment: %w", err)
+	}
+
+	stored = emptyContextEnrichment(stored)
+	s.cacheContextEnrichment(projectID, stored)
+	s.insertAuditLogBestEffort(ctx, projectID, "update_context_enrichment", "")
+	return stored, nil
+}
+
+// emptyContextEnrichment replaces nil maps with empty ones, so they encode
+// as {} rather than null.
+func emptyContextEnrichment(enrichment repository.ContextEnrichment) repository.ContextEnrichment {
+	if enrichment.Attributes == nil {
+		enrichment.Attributes = map[string]any{}
+	}
+	if enrichment.Headers == nil {
+		enrichment.Headers = map[string]string{}
+	}
+	return enrichment
+}
+
+func normalizeContextEnrichment(enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error) {
+	enrichment.APIKeyIDAttribute = strings.TrimSpace(enrichment.APIKeyIDAttribute)
+	count := len(enrichment.Attributes) + len(enrichment.Headers)
+	if enrichment.APIKeyIDAttribute != "" {
+		count++
+	}
+	if count > maxEnrichedAttributes {
+		return repository.ContextEnrichment{}, fmt.Errorf("%w: %d attributes, the limit is %d", ErrInvalidContextEnrichment, count, maxEnrichedAttributes)
+	}
+
+	seen := make(map[string]bool, count)
+	checkName := func(name string) error {
+		switch {
+		case strings.TrimSpace(name) == "":
+			return fmt.Errorf("%w: attribute name is blank", ErrInvalidContextEnrichment)
+		case len(name) > maxContextAttributeNameLength:
+			return fmt.Errorf("%w: attribute name %q is longer than %d bytes", ErrInvalidContextEnrichment, name, maxContextAttributeNameLength)
+		case seen[name]:
+			return fmt.Errorf("%w: attribute %q is set more than once", ErrInvalidContextEnrichment, name)
+		}
+		seen[name] = true
+		return nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(enrichment.Attributes)) {
+		if err := checkName(name); err != nil {
+			return repository.ContextEnrichment{}, err
+		}
+	}
+	headers := make(map[string]string, len(enrichment.Headers))
+	for _, name := range slices.Sorted(maps.Keys(enrichment.Headers)) {
+		if err := checkName(name); err != nil {
+			return repository.ContextEnrichment{}, err
+		}
+		header := strings.TrimSpace(enrichment.Headers[name])
+		if !validHeaderName(header) {
+			return repository.ContextEnrichment{}, fmt.Errorf("%w: attribute %q names invalid header %q", ErrInvalidContextEnrichment, name, header)
+		}
+		headers[name] = textproto.CanonicalMIMEHeaderKey(header)
+	}
+	enrichment.Headers = headers
+	if enrichment.APIKeyIDAttribute != "" {
+		if err := checkName(enrichment.APIKeyIDAttribute); err != nil {
+			return repository.ContextEnrichment{}, err
+		}
+	}
+	return enrichment, nil
+}
+
+// contextEnrichment returns a project's context enrichment. Like context
+// schemas, it is cached until the next flag cache reload; a project whose
+// enrichment cannot be loaded is evaluated without it.
+func (s *Service) contextEnrichment(ctx context.Context, projectID string) repository.ContextEnrichment {
+	s.contextEnrichmentsMu.RLock()
+	enrichment, cached := s.contextEnrichments[projectID]
+	s.contextEnrichmentsMu.RUnlock()
+	if cached {
+		return enrichment
+	}
+
+	repo, ok := s.repo.(ContextEnrichmentRepository)
+	if !ok || strings.TrimSpace(projectID) == "" {
+		return repository.ContextEnrichment{}
+	}
+	enrichment, err := repo.GetContextEnrichment(ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.log.WarnContext(ctx, "load context enrichment failed", "project_id", projectID, "error", err)
+		}
+		return repository.ContextEnrichment{}
+	}
+	s.cacheContextEnrichment(projectID, enrichment)
+	return enrichment
+}
+
+func (s *Service) cacheContextEnrichment(projectID string, enrichment repository.ContextEnrichment) {
+	s.contextEnrichmentsMu.Lock()
+	defer s.contextEnrichmentsMu.Unlock()
+	if s.contextEnrichments == nil {
+		s.contextEnrichments = make(map[string]repository.ContextEnrichment)
+	}
+	s.contextEnrichments[projectID] = enrichment
+}
+
+func (s *Service) resetContextEnrichments() {
+	s.contextEnrichmentsMu.Lock()
+	defer s.contextEnrichmentsMu.Unlock()
+	s.contextEnrichments = nil
+}
+
+// enrichContext returns evalContext with the project's enrichment added
+// beneath it: an attribute evalContext already has is left alone.
+func (s *Service) enrichContext(ctx context.Context, projectID string, evalContext core.EvaluationContext) core.EvaluationContext {
+	enrichment := s.contextEnrichment(ctx, projectID)
+	if len(enrichment.Attributes) == 0 && len(enrichment.Headers) == 0 && enrichment.APIKeyIDAttribute == "" {
+		return evalContext
+	}
+
+	attributes := make(map[string]any, len(evalContext.Attributes)+len(enrichment.Attributes)+len(enrichment.Headers)+1)
+	maps.Copy(attributes, enrichment.Attributes)
+	if header, ok := RequestHeaderFromContext(ctx); ok {
+		for name, key := range enrichment.Headers {
+			if value := header(key); value != "" {
+				attributes[name] = value
+			}
+		}
+	}
+	if enrichment.APIKeyIDAttribute != "" {
+		if keyID, ok := middleware.APIKeyIDFromContext(ctx); ok && keyID != "" {
+			attributes[enrichment.APIKeyIDAttribute] = keyID
+		}
+	}
+	maps.Copy(attributes, evalContext.Attributes)
+	return core.EvaluationContext{Attributes: attributes}
+}
+
+// validHeaderName reports whether name is an HTTP header field name: a
+// non-empty token as defined by RFC 9110.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
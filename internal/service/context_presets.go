@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const maxContextPresetNameLength = 100
+
+var (
+	// ErrContextPresetNotFound is returned when a requested context preset
+	// does not exist.
+	ErrContextPresetNotFound = errors.New("context preset not found")
+	// ErrInvalidContextPresetName is returned when a preset name is blank,
+	// too long, or contains characters other than letters, digits, spaces,
+	// '-', '_' and '.'.
+	ErrInvalidContextPresetName = errors.New("invalid context preset name")
+)
+
+var errContextPresetsNotSupported = errors.New("context presets not supported")
+
+// ContextPresetRepository defines storage of named evaluation contexts.
+// It is optionally satisfied by [repository.PostgresRepository].
+type ContextPresetRepository interface {
+	ListContextPresets(ctx context.Context, projectID string) ([]repository.ContextPreset, error)
+	GetContextPreset(ctx context.Context, projectID, name string) (repository.ContextPreset, error)
+	PutContextPreset(ctx context.Context, preset repository.ContextPreset) (repository.ContextPreset, error)
+	DeleteContextPreset(ctx context.Context, projectID, name string) error
+}
+
+func (s *Service) contextPresetRepository() (ContextPresetRepository, error) {
+	repo, ok := s.repo.(ContextPresetRepository)
+	if !ok {
+		return nil, errContextPresetsNotSupported
+	}
+	return repo, nil
+}
+
+// ListContextPresets returns the context presets saved for a project,
+// ordered by name.
+func (s *Service) ListContextPresets(ctx context.Context, projectID string) ([]repository.ContextPreset, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	repo, err := s.contextPresetRepository()
+	if err != nil {
+		return nil, err
+	}
+	presets, err := repo.ListContextPresets(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list context presets: %w", err)
+	}
+	return presets, nil
+}
+
+// GetContextPreset returns a project's context preset by name. Returns
+// [ErrContextPresetNotFound] if it does not exist.
+func (s *Service) GetContextPreset(ctx context.Context, projectID, name string) (repository.ContextPreset, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.ContextPreset{}, ErrProjectIDRequired
+	}
+	repo, err := s.contextPresetRepository()
+	if err != nil {
+		return repository.ContextPreset{}, err
+	}
+	preset, err := repo.GetContextPreset(ctx, projectID, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ContextPreset{}, ErrContextPresetNotFound
+		}
+		return repository.ContextPreset{}, fmt.Errorf("get context preset: %w", err)
+	}
+	return preset, nil
+}
+
+// PutContextPreset creates a context preset or replaces the one with the
+// same name. Returns [ErrInvalidContextPresetName] if the name is not
+// acceptable.
+func (s *Service) PutContextPreset(ctx context.Context, preset repository.ContextPreset) (repository.ContextPreset, error) {
+	ctx, span := svcTracer.Start(ctx, "service.PutContextPreset")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", preset.ProjectID))
+
+	if strings.TrimSpace(preset.ProjectID) == "" {
+		return repository.ContextPreset{}, ErrProjectIDRequired
+	}
+	if !validContextPresetName(preset.Name) {
+		return repository.ContextPreset{}, ErrInvalidContextPresetName
+	}
+	repo, err := s.contextPresetRepository()
+	if err != nil {
+		return repository.ContextPreset{}, err
+	}
+
+	stored, err := repo.PutContextPreset(ctx, preset)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "put context preset failed")
+		return repository.ContextPreset{}, fmt.Errorf("put context preset: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, preset.ProjectID, "put_context_preset", "")
+	return stored, nil
+}
+
+// DeleteContextPreset removes a project's context preset. Returns
+// [ErrContextPresetNotFound] if it does not exist.
+func (s *Service) DeleteContextPreset(ctx context.Context, projectID, name string) error {
+	if strings.TrimSpace(projectID) == "" {
+		return ErrProjectIDRequired
+	}
+	repo, err := s.contextPresetRepository()
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteContextPreset(ctx, projectID, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrContextPresetNotFound
+		}
+		return fmt.Errorf("delete context preset: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "delete_context_preset", "")
+	return nil
+}
+
+// applyContextPreset returns evalContext with the attributes of the named
+// preset filled in where evalContext does not set them. Presets are looked
+// up once per project and name and memoised in seen.
+func (s *Service) applyContextPreset(ctx context.Context, projectID, name string, evalContext core.EvaluationContext, seen map[string]core.EvaluationContext) (core.EvaluationContext, error) {
+	cacheKey := projectID + "\x00" + name
+	presetContext, ok := seen[cacheKey]
+	if !ok {
+		preset, err := s.GetContextPreset(ctx, projectID, name)
+		if err != nil {
+			return core.EvaluationContext{}, err
+		}
+		presetContext = preset.Context
+		seen[cacheKey] = presetContext
+	}
+
+	merged := make(map[string]any, len(presetContext.Attributes)+len(evalContext.Attributes))
+	maps.Copy(merged, presetContext.Attributes)
+	maps.Copy(merged, evalContext.Attributes)
+	return core.EvaluationContext{Attributes: merged}, nil
+}
+
+func validContextPresetName(name string) bool {
+	if strings.TrimSpace(name) != name || name == "" || len(name) > maxContextPresetNameLength {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == ' ', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	maxContextAttributes          = 1000
+	maxContextAttributeNameLength = 200
+	// maxSuggestionDistance is how many single-character edits an unknown
+	// attribute may be from a registered one for it to be suggested.
+	maxSuggestionDistance = 2
+)
+
+// Kinds of [ContextWarning].
+const (
+	// ContextWarningUnknownAttribute means the attribute is not registered.
+	ContextWarningUnknownAttribute = "unknown_attribute"
+	// ContextWarningTypeMismatch means the attribute's value is not of its
+	// registered type.
+	ContextWarningTypeMismatch = "type_mismatch"
+)
+
+var (
+	// ErrInvalidContextSchema is returned when a context schema has a blank
+	// or repeated attribute name, an unknown attribute type, or too many
+	// attributes.
+	ErrInvalidContextSchema = errors.New("invalid context schema")
+
+	errContextSchemaNotSupported = errors.New("context schemas not supported")
+)
+
+// ContextSchemaRepository defines storage of per-project context schemas.
+// It is optionally satisfied by [repository.PostgresRepository].
+type ContextSchemaRepository interface {
+	GetContextSchema(ctx context.Context, projectID string) (repository.ContextSchema, error)
+	SetContextSchema(ctx context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error)
+}
+
+// ContextWarning reports an evaluation context attribute that does not match
+// the project's strict context schema. Evaluation goes ahead regardless; the
+// warning only points out why a rule might not have matched.
+type ContextWarning struct {
+	Attribute string `json:"attribute"`
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+}
+
+// WithContextWarningMetrics registers a callback invoked with the kind of
+// every [ContextWarning] produced during evaluation.
+func WithContextWarningMetrics(onWarning func(kind string)) Option {
+	return func(s *Service) {
+		s.onContextWarning = onWarning
+	}
+}
+
+// GetContextSchema returns a project's registry of evaluation context
+// attributes. When the repository does not store schemas, an empty,
+// non-strict schema is returned. Returns [ErrProjectNotFound] if the project
+// does not exist.
+func (s *Service) GetContextSchema(ctx context.Context, projectID string) (repository.ContextSchema, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.ContextSchema{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(ContextSchemaRepository)
+	if !ok {
+		return repository.ContextSchema{Attributes: []repository.ContextAttribute{}}, nil
+	}
+
+	schema, err := repo.GetContextSchema(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ContextSchema{}, ErrProjectNotFound
+		}
+		return repository.ContextSchema{}, fmt.Errorf("get context schema: %w", err)
+	}
+	if schema.Attributes == nil {
+		schema.Attributes = []repository.ContextAttribute{}
+	}
+	return schema, nil
+}
+
+// SetContextSchema validates and stores a project's registry of evaluation
+// context attributes. Returns [ErrProjectNotFound] if the project does not
+// exist.
+func (s *Service) SetContextSchema(ctx context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetContextSchema")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.ContextSchema{}, ErrProjectIDRequired
+	}
+	schema, err := normalizeContextSchema(schema)
+	if err != nil {
+		return repository.ContextSchema{}, err
+	}
+
+	repo, ok := s.repo.(ContextSchemaRepository)
+	if !ok {
+		return repository.ContextSchema{}, errContextSchemaNotSupported
+	}
+
+	stored, err := repo.SetContextSchema(ctx, projectID, schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ContextSchema{}, ErrProjectNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set context schema failed")
+		return repository.ContextSchema{}, fmt.Errorf("set context schema: %w", err)
+	}
+
+	s.cacheContextSchema(projectID, stored)
+	s.insertAuditLogBestEffort(ctx, projectID, "update_context_schema", "")
+	return stored, nil
+}
+
+func normalizeContextSchema(schema repository.ContextSchema) (repository.ContextSchema, error) {
+	if len(schema.Attributes) > maxContextAttributes {
+		return repository.ContextSchema{}, fmt.Errorf("%w: %d attributes, the limit is %d", ErrInvalidContextSchema, len(schema.Attributes), maxContextAttributes)
+	}
+	seen := make(map[string]bool, len(schema.Attributes))
+	attributes := make([]repository.ContextAttribute, 0, len(schema.Attributes))
+	for i, attr := range schema.Attributes {
+		attr.Name = strings.TrimSpace(attr.Name)
+		attr.Description = strings.TrimSpace(attr.Description)
+		switch {
+		case attr.Name == "":
+			return repository.ContextSchema{}, fmt.Errorf("%w: attributes[%d] has no name", ErrInvalidContextSchema, i)
+		case len(attr.Name) > maxContextAttributeNameLength:
+			return repository.ContextSchema{}, fmt.Errorf("%w: attributes[%d] name is longer than %d bytes", ErrInvalidContextSchema, i, maxContextAttributeNameLength)
+		case seen[attr.Name]:
+			return repository.ContextSchema{}, fmt.Errorf("%w: attribute %q is listed twice", ErrInvalidContextSchema, attr.Name)
+		}
+		switch attr.Type {
+		case repository.ContextAttributeString, repository.ContextAttributeNumber,
+			repository.ContextAttributeBoolean, repository.ContextAttributeArray:
+		default:
+			return repository.ContextSchema{}, fmt.Errorf("%w: attribute %q has unknown type %q", ErrInvalidContextSchema, attr.Name, attr.Type)
+		}
+		seen[attr.Name] = true
+		attributes = append(attributes, attr)
+	}
+	schema.Attributes = attributes
+	return schema, nil
+}
+
+// contextSchemaCache is what evaluation needs of a project's context schema.
+type contextSchemaCache struct {
+	// types holds the registered type of each attribute of a strict schema,
+	// and is nil when contexts are not checked.
+	types map[string]string
+	// sensitive holds the attributes whose values are redacted from logs.
+	sensitive map[string]bool
+}
+
+// strictContextTypes returns the registered type of each attribute of a
+// project whose schema is strict; ok is false when contexts are not checked.
+func (s *Service) strictContextTypes(ctx context.Context, projectID string) (types map[string]string, ok bool) {
+	types = s.cachedContextSchema(ctx, projectID).types
+	return types, types != nil
+}
+
+// cachedContextSchema returns what evaluation needs of a project's context
+// schema. Schemas are cached until the next flag cache reload, so a change
+// made on another replica is picked up within the cache resync interval.
+func (s *Service) cachedContextSchema(ctx context.Context, projectID string) contextSchemaCache {
+	s.contextSchemasMu.RLock()
+	cached, ok := s.contextSchemas[projectID]
+	s.contextSchemasMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	repo, isSchemaRepo := s.repo.(ContextSchemaRepository)
+	if !isSchemaRepo || strings.TrimSpace(projectID) == "" {
+		return contextSchemaCache{}
+	}
+	schema, err := repo.GetContextSchema(ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.log.WarnContext(ctx, "load context schema failed", "project_id", projectID, "error", err)
+		}
+		return contextSchemaCache{}
+	}
+	return s.cacheContextSchema(projectID, schema)
+}
+
+// cacheContextSchema records what evaluation needs of schema and returns
+// what it recorded.
+func (s *Service) cacheContextSchema(projectID string, schema repository.ContextSchema) contextSchemaCache {
+	var cached contextSchemaCache
+	if schema.Strict {
+		cached.types = make(map[string]string, len(schema.Attributes))
+	}
+	for _, attr := range schema.Attributes {
+		if cached.types != nil {
+			cached.types[attr.Name] = attr.Type
+		}
+		if attr.Sensitive {
+			if cached.sensitive == nil {
+				cached.sensitive = make(map[string]bool)
+			}
+			cached.sensitive[attr.Name] = true
+		}
+	}
+
+	s.contextSchemasMu.Lock()
+	defer s.contextSchemasMu.Unlock()
+	if s.contextSchemas == nil {
+		s.contextSchemas = make(map[string]contextSchemaCache)
+	}
+	s.contextSchemas[projectID] = cached
+	return cached
+}
+
+func (s *Service) resetContextSchemas() {
+	s.contextSchemasMu.Lock()
+	defer s.contextSchemasMu.Unlock()
+	s.contextSchemas = nil
+}
+
+// checkContext returns a warning, in attribute name order, for every
+// attribute of evalContext that breaks the project's strict context schema.
+func (s *Service) checkContext(ctx context.Context, projectID string, evalContext core.EvaluationContext) []ContextWarning {
+	types, ok := s.strictContextTypes(ctx, projectID)
+	if !ok || len(evalContext.Attributes) == 0 {
+		return nil
+	}
+
+	var warnings []ContextWarning
+	for _, name := range slices.Sorted(maps.Keys(evalContext.Attributes)) {
+		want, known := types[name]
+		if !known {
+			message := fmt.Sprintf("unknown attribute %q", name)
+			if suggestion := closestAttribute(name, types); suggestion != "" {
+				message += fmt.Sprintf("; did you mean %q?", suggestion)
+			}
+			warnings = append(warnings, ContextWarning{Attribute: name, Kind: ContextWarningUnknownAttribute, Message: message})
+			continue
+		}
+		if got := contextValueType(evalContext.Attributes[name]); got != want {
+			warnings = append(warnings, ContextWarning{
+				Attribute: name,
+				Kind:      ContextWarningTypeMismatch,
+				Message:   fmt.Sprintf("attribute %q is %s, want %s", name, got, want),
+			})
+		}
+	}
+	if s.onContextWarning != nil {
+		for _, warning := range warnings {
+			s.onContextWarning(warning.Kind)
+		}
+	}
+	return warnings
+}
+
+// contextValueType names the JSON type of an attribute value.
+func contextValueType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return repository.ContextAttributeString
+	case bool:
+		return repository.ContextAttributeBoolean
+	case json.Number:
+		return repository.ContextAttributeNumber
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return repository.ContextAttributeNumber
+	case reflect.Slice, reflect.Array:
+		return repository.ContextAttributeArray
+	default:
+		return "object"
+	}
+}
+
+// closestAttribute returns the registered attribute nearest to name, if one
+// is close enough to be a likely typo.
+func closestAttribute(name string, types map[string]string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, candidate := range slices.Sorted(maps.Keys(types)) {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// redactedValue replaces the values of sensitive attributes in logs.
+const redactedValue = "[REDACTED]"
+
+// loggableContext returns the attributes of evalContext with the values of
+// the project's sensitive attributes replaced by "[REDACTED]", for logging.
+func (s *Service) loggableContext(ctx context.Context, projectID string, evalContext core.EvaluationContext) map[string]any {
+	sensitive := s.cachedContextSchema(ctx, projectID).sensitive
+	attributes := make(map[string]any, len(evalContext.Attributes))
+	for name, value := range evalContext.Attributes {
+		if sensitive[name] {
+			value = redactedValue
+		}
+		attributes[name] = value
+	}
+	return attributes
+}
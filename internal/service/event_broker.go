@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	// maxBrokerEvents bounds the recent events held in memory for streams.
+	// Streams resuming from further back are served from the repository.
+	maxBrokerEvents = 10000
+	// defaultEventPollInterval is how often the broker looks for new events
+	// when no invalidation transport tells it about them.
+	defaultEventPollInterval = time.Second
+	brokerFetchTimeout       = 5 * time.Second
+)
+
+// EventFeedRepository is the storage the event broker follows. It is
+// optionally satisfied by [repository.PostgresRepository].
+type EventFeedRepository interface {
+	MaxEventID(ctx context.Context) (int64, error)
+	ListAllEventsSince(ctx context.Context, eventID int64) ([]repository.FlagEvent, error)
+}
+
+// WithEventPollInterval sets how often the event broker checks for new flag
+// events when there is no invalidation transport to announce them, such as a
+// LISTEN connection. With one, the broker checks on every announcement and
+// at the cache resync interval. Ignored if interval <= 0.
+func WithEventPollInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		if interval > 0 {
+			s.eventPollInterval = interval
+		}
+	}
+}
+
+// SetEventPollInterval changes how often the event broker checks for new
+// flag events, as [WithEventPollInterval] does at startup. It has no effect
+// when an invalidation transport announces events, or on repositories
+// without an event feed. Ignored if interval <= 0.
+func (s *Service) SetEventPollInterval(interval time.Duration) {
+	if interval <= 0 || s.events == nil || s.subscriber != nil {
+		return
+	}
+	s.events.setInterval(interval)
+}
+
+// eventBroker keeps the most recent flag events of every project in memory
+// so that streams do not each query the repository. A single goroutine
+// fetches new events whenever it is woken, by a local write or a change
+// announced by another replica, and then wakes every waiting stream.
+type eventBroker struct {
+	repo EventFeedRepository
+	log  *slog.Logger
+	wake chan struct{}
+	// interval passes a new poll interval to run.
+	interval chan time.Duration
+
+	mu sync.RWMutex
+	// events holds every event with an ID above floor, oldest first.
+	events  []repository.FlagEvent
+	floor   int64
+	changed chan struct{}
+}
+
+func newEventBroker(ctx context.Context, repo EventFeedRepository, log *slog.Logger) (*eventBroker, error) {
+	floor, err := repo.MaxEventID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("start event broker: %w", err)
+	}
+	return &eventBroker{
+		repo:     repo,
+		log:      log,
+		wake:     make(chan struct{}, 1),
+		interval: make(chan time.Duration, 1),
+		floor:    floor,
+		changed:  make(chan struct{}),
+	}, nil
+}
+
+// run fetches new events on every wake-up and every interval until ctx is
+// done.
+func (b *eventBroker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.wake:
+		case <-ticker.C:
+		case interval := <-b.interval:
+			ticker.Reset(interval)
+			continue
+		}
+		b.fetch(ctx)
+	}
+}
+
+// setInterval changes the interval run polls at. It never blocks; of
+// several changes made before run sees them, the last wins.
+func (b *eventBroker) setInterval(interval time.Duration) {
+	for {
+		select {
+		case b.interval <- interval:
+			return
+		default:
+		}
+		select {
+		case <-b.interval:
+		default:
+		}
+	}
+}
+
+// notify asks the broker to look for new events. It never blocks.
+func (b *eventBroker) notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *eventBroker) fetch(ctx context.Context) {
+	fetchCtx, cancel := context.WithTimeout(ctx, brokerFetchTimeout)
+	defer cancel()
+
+	b.mu.RLock()
+	cursor := b.floor
+	if n := len(b.events); n > 0 {
+		cursor = b.events[n-1].EventID
+	}
+	b.mu.RUnlock()
+
+	var fetched []repository.FlagEvent
+	for {
+		events, err := b.repo.ListAllEventsSince(fetchCtx, cursor)
+		if err != nil {
+			if ctx.Err() == nil {
+				b.log.Warn("event broker fetch failed", "error", err)
+			}
+			break
+		}
+		if len(events) == 0 {
+			break
+		}
+		fetched = append(fetched, events...)
+		cursor = events[len(events)-1].EventID
+	}
+	if len(fetched) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.events = append(b.events, fetched...)
+	if excess := len(b.events) - maxBrokerEvents; excess > 0 {
+		b.floor = b.events[excess-1].EventID
+		b.events = append(b.events[:0:0], b.events[excess:]...)
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// changes returns a channel that is closed the next time new events arrive.
+func (b *eventBroker) changes() <-chan struct{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.changed
+}
+
+// since returns the held events after eventID, limited to projectID and key
+// when they are not empty. ok is false if events after eventID have already
+// been dropped from memory and must be read from the repository.
+func (b *eventBroker) since(projectID string, eventID int64, filter repository.EventFilter) (events []repository.FlagEvent, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if eventID < b.floor {
+		return nil, false
+	}
+	start := sort.Search(len(b.events), func(i int) bool { return b.events[i].EventID > eventID })
+	events = make([]repository.FlagEvent, 0)
+	for _, event := range b.events[start:] {
+		if (projectID != "" && event.ProjectID != projectID) || !filter.Matches(event) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, true
+}
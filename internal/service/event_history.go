@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	defaultEventQueryLimit = 100
+	maxEventQueryLimit     = 1000
+)
+
+// ErrInvalidEventQuery is returned when an event history query has an empty
+// or inverted time range.
+var ErrInvalidEventQuery = errors.New("invalid event query")
+
+var errEventQueryNotSupported = errors.New("event history queries not supported")
+
+// EventQueryRepository defines querying a project's flag event history.
+// It is optionally satisfied by [repository.PostgresRepository] and
+// [repository.SQLiteRepository].
+type EventQueryRepository interface {
+	QueryEvents(ctx context.Context, q repository.EventQuery) ([]repository.FlagEvent, error)
+}
+
+// QueryEvents returns a page of the flag events of q.ProjectID that match q,
+// oldest first, for browsing what changed and who changed it. Unlike the
+// event streams it always reads the repository, so events are never dropped
+// from the history by the broker. A zero q.Limit returns 100 events and
+// larger limits are capped at 1000. next is the AfterID of the following
+// page, or 0 on the last page.
+func (s *Service) QueryEvents(ctx context.Context, q repository.EventQuery) (events []repository.FlagEvent, next int64, err error) {
+	ctx, span := svcTracer.Start(ctx, "service.QueryEvents")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", q.ProjectID))
+
+	if strings.TrimSpace(q.ProjectID) == "" {
+		return nil, 0, ErrProjectIDRequired
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return nil, 0, fmt.Errorf("%w: since must be before until", ErrInvalidEventQuery)
+	}
+	repo, ok := s.repo.(EventQueryRepository)
+	if !ok {
+		return nil, 0, errEventQueryNotSupported
+	}
+
+	switch {
+	case q.Limit <= 0:
+		q.Limit = defaultEventQueryLimit
+	case q.Limit > maxEventQueryLimit:
+		q.Limit = maxEventQueryLimit
+	}
+	limit := q.Limit
+	// One extra event tells whether there is another page.
+	q.Limit++
+	events, err = repo.QueryEvents(ctx, q)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, fmt.Errorf("query events: %w", err)
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		next = events[limit-1].EventID
+	}
+	return events, next, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// Explanation is the result of [Service.ExplainFlag]: the evaluation result,
+// the context it was evaluated against, and how the evaluator got there.
+type Explanation struct {
+	ResolveResult
+	// Context is the evaluated context, after the preset and the project's
+	// context enrichment were applied.
+	Context core.EvaluationContext `json:"context"`
+	Steps   []core.TraceStep       `json:"steps"`
+}
+
+// MarshalJSON encodes the explanation as its [ResolveResult] with the context
+// and steps added, since the embedded result's MarshalJSON would otherwise
+// be promoted and drop them.
+func (e Explanation) MarshalJSON() ([]byte, error) {
+	result, err := json.Marshal(e.ResolveResult)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := json.Marshal(struct {
+		Context core.EvaluationContext `json:"context"`
+		Steps   []core.TraceStep       `json:"steps"`
+	}{e.Context, e.Steps})
+	if err != nil {
+		return nil, err
+	}
+	return append(append(result[:len(result)-1], ','), trace[1:]...), nil
+}
+
+// ExplainFlag evaluates a flag like [Service.ResolveBooleanDetail], applying
+// preset first if it is set, and returns the result with a step-by-step
+// trace of the evaluation. It is meant for debugging: the evaluation is not
+// cached, does not count towards the flag's stats and does not run its
+// shadow rules. Returns [ErrFlagNotFound] if the flag does not exist, rather
+// than a default, and [ErrContextPresetNotFound] if the preset does not.
+func (s *Service) ExplainFlag(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, preset string) (Explanation, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ExplainFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return Explanation{}, ErrFlagKeyRequired
+	}
+	if preset != "" {
+		var err error
+		evalContext, err = s.applyContextPreset(ctx, projectID, preset, evalContext, make(map[string]core.EvaluationContext))
+		if err != nil {
+			return Explanation{}, err
+		}
+	}
+
+	entry, err := s.flagForEvaluation(ctx, projectID, key)
+	if err != nil {
+		return Explanation{}, err
+	}
+	coreFlag := entry.core
+	coreFlag.Segments = s.cachedSegments(projectID).core
+
+	warnings := s.checkContext(ctx, projectID, evalContext)
+	evalContext = s.enrichContext(ctx, projectID, evalContext)
+	evaluation, steps := core.ExplainFlag(coreFlag, evalContext)
+	span.SetAttributes(attribute.String("evaluation_reason", string(evaluation.Reason)))
+
+	explanation := Explanation{
+		ResolveResult: newResolveResult(key, evaluation),
+		Context:       evalContext,
+		Steps:         steps,
+	}
+	explanation.Warnings = warnings
+	return explanation, nil
+}
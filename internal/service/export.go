@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+var errExportNotSupported = errors.New("data export not supported")
+
+// ExportRepository defines the storage read by audit log and event exports.
+// It is optionally satisfied by [repository.PostgresRepository].
+type ExportRepository interface {
+	ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error)
+	ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error)
+}
+
+// ListAuditLogRange returns up to limit audit log entries of every project
+// created in [from, to) with IDs greater than afterID, ordered by ID. Like
+// the all-projects stream it is not scoped to a project, so callers must
+// authorize an admin key first.
+func (s *Service) ListAuditLogRange(ctx context.Context, afterID int64, from, to time.Time, limit int) ([]repository.AuditLogEntry, error) {
+	repo, ok := s.repo.(ExportRepository)
+	if !ok {
+		return nil, errExportNotSupported
+	}
+	entries, err := repo.ListAuditLogRange(ctx, afterID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log range: %w", err)
+	}
+	return entries, nil
+}
+
+// ListEventsRange returns up to limit flag events of every project created
+// in [from, to) with IDs greater than afterEventID, ordered by event ID.
+// Callers must authorize an admin key first.
+func (s *Service) ListEventsRange(ctx context.Context, afterEventID int64, from, to time.Time, limit int) ([]repository.FlagEvent, error) {
+	repo, ok := s.repo.(ExportRepository)
+	if !ok {
+		return nil, errExportNotSupported
+	}
+	events, err := repo.ListEventsRange(ctx, afterEventID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list events range: %w", err)
+	}
+	return events, nil
+}
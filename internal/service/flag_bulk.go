@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// maxBulkFlagKeys is the most flags one bulk update may change.
+const maxBulkFlagKeys = 1000
+
+// ErrInvalidBulkFlagUpdate is returned for a bulk update that names no
+// flags, too many, or no change to make, or that combines archiving or
+// restoring with another change.
+var ErrInvalidBulkFlagUpdate = errors.New("invalid bulk flag update")
+
+var errBulkUpdateNotSupported = errors.New("bulk flag updates not supported")
+
+// BulkFlagRepository defines transactional updates, archiving and restoring
+// of several flags of one project. It is optionally satisfied by
+// [repository.PostgresRepository] and [repository.SQLiteRepository].
+type BulkFlagRepository interface {
+	BulkUpdateFlags(ctx context.Context, projectID string, updates []repository.Flag, archiveKeys []string) ([]repository.Flag, error)
+	RestoreFlags(ctx context.Context, projectID string, keys []string) ([]repository.Flag, error)
+}
+
+// BulkFlagUpdate describes one change made to several flags of a project.
+type BulkFlagUpdate struct {
+	ProjectID string
+	Keys      []string
+	// Enabled, when set, enables or disables every flag.
+	Enabled *bool
+	// AddTags are added to the tags of every flag.
+	AddTags []string
+	// Archive archives every flag: it is no longer evaluated or listed,
+	// but keeps its key, revisions and stats until restored. It cannot be
+	// combined with other changes.
+	Archive bool
+	// Restore restores every flag, which must be archived. It cannot be
+	// combined with other changes.
+	Restore bool
+}
+
+// BulkFlagResult reports the flags a bulk update changed. Flags that
+// already matched the update are in no list.
+type BulkFlagResult struct {
+	Updated  []repository.Flag
+	Archived []string
+	Restored []repository.Flag
+}
+
+// BulkUpdateFlags applies update to every flag it names in one transaction:
+// either every flag changes or none does. Flags the update would not change
+// are left alone. Each flag that changes gets its own event and audit log
+// entry, and each updated flag a revision. Archived flags are announced as
+// deleted and restored flags as updated, so clients drop and pick them up
+// again.
+//
+// Returns [ErrInvalidBulkFlagUpdate] if update names no flags, more than
+// 1000, or nothing to change, [ErrInvalidTags] for a bad tag, and
+// [ErrFlagNotFound] naming the key if any flag does not exist or, when
+// restoring, is not archived.
+func (s *Service) BulkUpdateFlags(ctx context.Context, update BulkFlagUpdate) (BulkFlagResult, error) {
+	ctx, span := svcTracer.Start(ctx, "service.BulkUpdateFlags")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("project_id", update.ProjectID),
+		attribute.Int("flag_count", len(update.Keys)),
+	)
+
+	if strings.TrimSpace(update.ProjectID) == "" {
+		return BulkFlagResult{}, ErrProjectIDRequired
+	}
+	keys := slices.Clone(update.Keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	switch {
+	case len(keys) == 0:
+		return BulkFlagResult{}, fmt.Errorf("%w: no flags selected", ErrInvalidBulkFlagUpdate)
+	case len(keys) > maxBulkFlagKeys:
+		return BulkFlagResult{}, fmt.Errorf("%w: at most %d flags can be changed at once", ErrInvalidBulkFlagUpdate, maxBulkFlagKeys)
+	case update.Archive && (update.Restore || update.Enabled != nil || len(update.AddTags) > 0):
+		return BulkFlagResult{}, fmt.Errorf("%w: archive cannot be combined with other changes", ErrInvalidBulkFlagUpdate)
+	case update.Restore && (update.Enabled != nil || len(update.AddTags) > 0):
+		return BulkFlagResult{}, fmt.Errorf("%w: restore cannot be combined with other changes", ErrInvalidBulkFlagUpdate)
+	case !update.Archive && !update.Restore && update.Enabled == nil && len(update.AddTags) == 0:
+		return BulkFlagResult{}, fmt.Errorf("%w: no change given", ErrInvalidBulkFlagUpdate)
+	}
+	addTags, err := normalizeTags(update.AddTags)
+	if err != nil {
+		return BulkFlagResult{}, err
+	}
+
+	repo, ok := s.repo.(BulkFlagRepository)
+	if !ok {
+		return BulkFlagResult{}, errBulkUpdateNotSupported
+	}
+	if update.Restore {
+		restored, err := repo.RestoreFlags(ctx, update.ProjectID, keys)
+		if err != nil {
+			return BulkFlagResult{}, bulkFlagError(span, err)
+		}
+		for _, flag := range restored {
+			s.setCachedFlag(flag)
+			s.publishFlagEventBestEffort(ctx, EventTypeUpdated, flag)
+			s.insertAuditLogBestEffort(ctx, update.ProjectID, "restore", flag.Key)
+		}
+		return BulkFlagResult{Restored: restored}, nil
+	}
+
+	var (
+		current     = make(map[string]repository.Flag, len(keys))
+		updates     []repository.Flag
+		archiveKeys []string
+	)
+	for _, key := range keys {
+		flag, err := s.GetFlag(ctx, update.ProjectID, key)
+		if err != nil {
+			return BulkFlagResult{}, fmt.Errorf("flag %q: %w", key, err)
+		}
+		current[key] = flag
+		if update.Archive {
+			archiveKeys = append(archiveKeys, key)
+			continue
+		}
+
+		next := flag
+		if update.Enabled != nil {
+			next.Enabled = *update.Enabled
+		}
+		if len(addTags) > 0 {
+			next.Tags = append(slices.Clone(flag.Tags), addTags...)
+		}
+		if err := validateFlag(next); err != nil {
+			return BulkFlagResult{}, fmt.Errorf("flag %q: %w", key, err)
+		}
+		if next, err = completeFlagUpdate(ctx, flag, next); err != nil {
+			return BulkFlagResult{}, fmt.Errorf("flag %q: %w", key, err)
+		}
+		if next.Enabled == flag.Enabled && slices.Equal(next.Tags, flag.Tags) {
+			continue
+		}
+		updates = append(updates, next)
+	}
+	if len(updates) == 0 && len(archiveKeys) == 0 {
+		return BulkFlagResult{}, nil
+	}
+
+	updated, err := repo.BulkUpdateFlags(ctx, update.ProjectID, updates, archiveKeys)
+	if err != nil {
+		return BulkFlagResult{}, bulkFlagError(span, err)
+	}
+
+	for _, flag := range updated {
+		s.setCachedFlag(flag)
+		s.publishFlagEventBestEffort(ctx, EventTypeUpdated, flag)
+		s.insertFlagChangeAuditLog(ctx, "update", current[flag.Key], flag)
+	}
+	for _, key := range archiveKeys {
+		s.deleteCachedFlag(update.ProjectID, key)
+		s.publishFlagEventBestEffort(ctx, EventTypeDeleted, current[key])
+		s.insertAuditLogBestEffort(ctx, update.ProjectID, "archive", key)
+	}
+	return BulkFlagResult{Updated: updated, Archived: archiveKeys}, nil
+}
+
+// bulkFlagError records a failed bulk write on span and maps a missing flag
+// to [ErrFlagNotFound].
+func bulkFlagError(span trace.Span, err error) error {
+	span.RecordError(err)
+	if errors.Is(err, pgx.ErrNoRows) {
+		span.SetStatus(codes.Error, "flag not found")
+		return fmt.Errorf("%w: %v", ErrFlagNotFound, err)
+	}
+	span.SetStatus(codes.Error, "bulk update flags failed")
+	return fmt.Errorf("bulk update flags: %w", err)
+}
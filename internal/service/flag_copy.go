@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrInvalidFlagCopy is returned when a flag would be copied onto itself.
+var ErrInvalidFlagCopy = errors.New("a flag cannot be copied onto itself")
+
+// CopyFlagRequest describes a copy of one flag to another project, or to
+// another key in the same project.
+type CopyFlagRequest struct {
+	SourceProjectID string
+	SourceKey       string
+	TargetProjectID string
+	// TargetKey defaults to SourceKey.
+	TargetKey string
+	// IncludeRules copies the source's rules. Without it a new copy gets the
+	// target project's default rules and an existing flag keeps its own.
+	IncludeRules bool
+	// IncludeTargets copies the source's allow and deny lists.
+	IncludeTargets bool
+	// Overwrite updates the target flag if it already exists instead of
+	// failing with [ErrFlagExists].
+	Overwrite bool
+}
+
+// CopyFlag copies a flag's description, variants, variants schema, tags,
+// owner and expiry, and optionally its rules and targets, to another project
+// or key. This is how a flag is promoted from one environment's project to the
+// next.
+//
+// Copying never changes whether a flag is enabled: a new copy starts
+// disabled and an overwritten flag keeps its state, so promoting
+// configuration is kept apart from switching it on. The bucketing salt is
+// not copied either. A new copy goes through [Service.CreateFlag], so the
+// target project's defaults and key policy apply.
+//
+// The target project's audit log records a "copy" entry whose details name
+// the source flag and the revision copied. created reports whether the
+// target flag was new. Returns [ErrFlagNotFound] if the source does not
+// exist, [ErrProjectNotFound] if the target project does not, and
+// [ErrFlagExists] if the target flag does and req.Overwrite is false.
+func (s *Service) CopyFlag(ctx context.Context, req CopyFlagRequest) (flag repository.Flag, created bool, err error) {
+	ctx, span := svcTracer.Start(ctx, "service.CopyFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", req.SourceKey),
+		attribute.String("project_id", req.SourceProjectID),
+		attribute.String("target_project_id", req.TargetProjectID),
+	)
+
+	if strings.TrimSpace(req.SourceProjectID) == "" || strings.TrimSpace(req.TargetProjectID) == "" {
+		return repository.Flag{}, false, ErrProjectIDRequired
+	}
+	if strings.TrimSpace(req.SourceKey) == "" {
+		return repository.Flag{}, false, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(req.TargetKey) == "" {
+		req.TargetKey = req.SourceKey
+	}
+	if req.SourceProjectID == req.TargetProjectID && req.SourceKey == req.TargetKey {
+		return repository.Flag{}, false, ErrInvalidFlagCopy
+	}
+
+	source, err := s.GetFlag(ctx, req.SourceProjectID, req.SourceKey)
+	if err != nil {
+		return repository.Flag{}, false, err
+	}
+	if err := s.checkProjectLive(ctx, req.TargetProjectID); err != nil {
+		return repository.Flag{}, false, err
+	}
+
+	existing, err := s.GetFlag(ctx, req.TargetProjectID, req.TargetKey)
+	switch {
+	case err == nil:
+		if !req.Overwrite {
+			return repository.Flag{}, false, ErrFlagExists
+		}
+		existing.Description = source.Description
+		existing.Variants = source.Variants
+		existing.VariantsSchema = source.VariantsSchema
+		if existing.VariantsSchema == nil {
+			// Without the source's schema the target would keep its own.
+			existing.VariantsSchema = json.RawMessage("null")
+		}
+		existing.Tags = append([]string{}, source.Tags...)
+		existing.Owner = source.Owner
+		existing.ExpiresAt = source.ExpiresAt
+		if req.IncludeRules {
+			existing.Rules = source.Rules
+			existing.ShadowRules = source.ShadowRules
+			if existing.ShadowRules == nil {
+				existing.ShadowRules = json.RawMessage("null")
+			}
+		}
+		flag, err = s.UpdateFlag(ctx, existing)
+	case errors.Is(err, ErrFlagNotFound):
+		created = true
+		copied := repository.Flag{
+			ProjectID:      req.TargetProjectID,
+			Key:            req.TargetKey,
+			Description:    source.Description,
+			Variants:       source.Variants,
+			VariantsSchema: source.VariantsSchema,
+			Tags:           source.Tags,
+			Owner:          source.Owner,
+			ExpiresAt:      source.ExpiresAt,
+		}
+		if req.IncludeRules {
+			copied.Rules = source.Rules
+			copied.ShadowRules = source.ShadowRules
+		}
+		flag, err = s.CreateFlag(ctx, copied)
+	}
+	if err != nil {
+		return repository.Flag{}, false, err
+	}
+
+	if req.IncludeTargets && (!created || !source.Targets.IsZero()) {
+		flag, err = s.SetFlagTargets(ctx, flag.ProjectID, flag.Key, source.Targets)
+		if err != nil {
+			return repository.Flag{}, false, fmt.Errorf("copy targets: %w", err)
+		}
+	}
+
+	s.insertCopyAuditLog(ctx, flag, source, req)
+	return flag, created, nil
+}
+
+// checkProjectLive returns [ErrProjectNotFound] if projectID does not exist
+// or is deleted. Repositories without project lifecycle support are trusted.
+func (s *Service) checkProjectLive(ctx context.Context, projectID string) error {
+	repo, ok := s.repo.(ProjectLifecycleRepository)
+	if !ok {
+		return nil
+	}
+	project, err := repo.GetProject(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		return fmt.Errorf("get project: %w", err)
+	}
+	if project.DeletedAt != nil {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// insertCopyAuditLog records where a copied flag came from. The source's
+// updated_at identifies the revision that was copied.
+func (s *Service) insertCopyAuditLog(ctx context.Context, flag, source repository.Flag, req CopyFlagRequest) {
+	details, _ := json.Marshal(map[string]any{
+		"source_project_id": source.ProjectID,
+		"source_key":        source.Key,
+		"source_updated_at": source.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		"include_rules":     req.IncludeRules,
+		"include_targets":   req.IncludeTargets,
+	})
+	s.insertAuditLogDetailsBestEffort(ctx, flag.ProjectID, "copy", flag.Key, details)
+}
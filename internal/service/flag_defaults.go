@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrProjectNotFound is returned when a requested project does not exist.
+var ErrProjectNotFound = errors.New("project not found")
+
+var errFlagDefaultsNotSupported = errors.New("flag defaults not supported")
+
+// descriptionKeyPlaceholder is replaced with the flag key when a description
+// template is applied.
+const descriptionKeyPlaceholder = "{{key}}"
+
+// FlagDefaultsRepository defines storage of per-project flag defaults.
+// It is optionally satisfied by [repository.PostgresRepository].
+type FlagDefaultsRepository interface {
+	GetFlagDefaults(ctx context.Context, projectID string) (repository.FlagDefaults, error)
+	SetFlagDefaults(ctx context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error)
+}
+
+// GetFlagDefaults returns the defaults applied to flags created in a project.
+// Returns [ErrProjectNotFound] if the project does not exist. When the
+// repository does not store defaults, the zero value is returned.
+func (s *Service) GetFlagDefaults(ctx context.Context, projectID string) (repository.FlagDefaults, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.FlagDefaults{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(FlagDefaultsRepository)
+	if !ok {
+		return repository.FlagDefaults{}, nil
+	}
+
+	defaults, err := repo.GetFlagDefaults(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.FlagDefaults{}, ErrProjectNotFound
+		}
+		return repository.FlagDefaults{}, fmt.Errorf("get flag defaults: %w", err)
+	}
+	return defaults, nil
+}
+
+// SetFlagDefaults validates and stores the defaults applied to flags created
+// in a project. The rules skeleton must pass the same validation as flag
+// rules. Returns [ErrProjectNotFound] if the project does not exist.
+func (s *Service) SetFlagDefaults(ctx context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetFlagDefaults")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.FlagDefaults{}, ErrProjectIDRequired
+	}
+	if err := validateRules(defaults.Rules); err != nil {
+		return repository.FlagDefaults{}, err
+	}
+	if rulesUnset(defaults.Rules) {
+		defaults.Rules = nil
+	}
+
+	repo, ok := s.repo.(FlagDefaultsRepository)
+	if !ok {
+		return repository.FlagDefaults{}, errFlagDefaultsNotSupported
+	}
+
+	stored, err := repo.SetFlagDefaults(ctx, projectID, defaults)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.FlagDefaults{}, ErrProjectNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set flag defaults failed")
+		return repository.FlagDefaults{}, fmt.Errorf("set flag defaults: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "update_flag_defaults", "")
+	return stored, nil
+}
+
+// applyFlagDefaults fills in the description and rules of flag from its
+// project's defaults when the flag was created without them. Defaults are
+// looked up once per project and memoised in seen when it is non-nil.
+func (s *Service) applyFlagDefaults(ctx context.Context, flag repository.Flag, seen map[string]repository.FlagDefaults) (repository.Flag, error) {
+	repo, ok := s.repo.(FlagDefaultsRepository)
+	if !ok || strings.TrimSpace(flag.ProjectID) == "" {
+		return flag, nil
+	}
+
+	defaults, ok := seen[flag.ProjectID]
+	if !ok {
+		var err error
+		defaults, err = repo.GetFlagDefaults(ctx, flag.ProjectID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return repository.Flag{}, fmt.Errorf("load flag defaults: %w", err)
+		}
+		if seen != nil {
+			seen[flag.ProjectID] = defaults
+		}
+	}
+
+	if strings.TrimSpace(flag.Description) == "" && defaults.DescriptionTemplate != "" {
+		flag.Description = strings.ReplaceAll(defaults.DescriptionTemplate, descriptionKeyPlaceholder, flag.Key)
+	}
+	if rulesUnset(flag.Rules) && len(defaults.Rules) > 0 {
+		flag.Rules = defaults.Rules
+	}
+	return flag, nil
+}
+
+// rulesUnset reports whether a rules payload is absent, null or an empty
+// array. Malformed payloads are not unset; validation rejects them later.
+func rulesUnset(payload json.RawMessage) bool {
+	rules, err := parseRulesJSON(payload)
+	return err == nil && len(rules) == 0
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+var (
+	// ErrInvalidFlagKeyPolicy is returned when a flag key policy has a
+	// pattern that does not compile or a negative maximum length.
+	ErrInvalidFlagKeyPolicy = errors.New("invalid flag key policy")
+	// ErrFlagKeyPolicyViolation is returned when a new flag's key does not
+	// follow its project's flag key policy. The wrapping error says which
+	// requirement the key failed.
+	ErrFlagKeyPolicyViolation = errors.New("flag key violates project key policy")
+
+	errFlagKeyPolicyNotSupported = errors.New("flag key policies not supported")
+)
+
+// FlagKeyPolicyRepository defines storage of per-project flag key policies.
+// It is optionally satisfied by [repository.PostgresRepository].
+type FlagKeyPolicyRepository interface {
+	GetFlagKeyPolicy(ctx context.Context, projectID string) (repository.FlagKeyPolicy, error)
+	SetFlagKeyPolicy(ctx context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error)
+}
+
+// GetFlagKeyPolicy returns the naming policy for flags created in a project.
+// When the repository does not store policies, the zero policy, which allows
+// any key, is returned. Returns [ErrProjectNotFound] if the project does not
+// exist.
+func (s *Service) GetFlagKeyPolicy(ctx context.Context, projectID string) (repository.FlagKeyPolicy, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.FlagKeyPolicy{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(FlagKeyPolicyRepository)
+	if !ok {
+		return repository.FlagKeyPolicy{}, nil
+	}
+
+	policy, err := repo.GetFlagKeyPolicy(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.FlagKeyPolicy{}, ErrProjectNotFound
+		}
+		return repository.FlagKeyPolicy{}, fmt.Errorf("get flag key policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetFlagKeyPolicy validates and stores the naming policy for flags created
+// in a project. Existing flags are not checked against it. Returns
+// [ErrProjectNotFound] if the project does not exist.
+func (s *Service) SetFlagKeyPolicy(ctx context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetFlagKeyPolicy")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.FlagKeyPolicy{}, ErrProjectIDRequired
+	}
+	policy.Pattern = strings.TrimSpace(policy.Pattern)
+	if _, err := compileFlagKeyPolicy(policy); err != nil {
+		return repository.FlagKeyPolicy{}, err
+	}
+
+	repo, ok := s.repo.(FlagKeyPolicyRepository)
+	if !ok {
+		return repository.FlagKeyPolicy{}, errFlagKeyPolicyNotSupported
+	}
+
+	stored, err := repo.SetFlagKeyPolicy(ctx, projectID, policy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.FlagKeyPolicy{}, ErrProjectNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set flag key policy failed")
+		return repository.FlagKeyPolicy{}, fmt.Errorf("set flag key policy: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "update_flag_key_policy", "")
+	return stored, nil
+}
+
+// flagKeyRules is a [repository.FlagKeyPolicy] ready to check keys against.
+type flagKeyRules struct {
+	policy  repository.FlagKeyPolicy
+	pattern *regexp.Regexp
+}
+
+func compileFlagKeyPolicy(policy repository.FlagKeyPolicy) (flagKeyRules, error) {
+	if policy.MaxLength < 0 {
+		return flagKeyRules{}, fmt.Errorf("%w: max_length must not be negative", ErrInvalidFlagKeyPolicy)
+	}
+	rules := flagKeyRules{policy: policy}
+	if policy.Pattern != "" {
+		// Anchor the pattern so it describes the whole key, not a substring.
+		pattern, err := regexp.Compile(`^(?:` + policy.Pattern + `)$`)
+		if err != nil {
+			return flagKeyRules{}, fmt.Errorf("%w: pattern: %v", ErrInvalidFlagKeyPolicy, err)
+		}
+		rules.pattern = pattern
+	}
+	return rules, nil
+}
+
+// check returns an [ErrFlagKeyPolicyViolation] naming the first requirement
+// key fails, or nil.
+func (r flagKeyRules) check(key string) error {
+	if r.policy.Prefix != "" && !strings.HasPrefix(key, r.policy.Prefix) {
+		return fmt.Errorf("%w: key %q must start with %q", ErrFlagKeyPolicyViolation, key, r.policy.Prefix)
+	}
+	if r.policy.MaxLength > 0 && utf8.RuneCountInString(key) > r.policy.MaxLength {
+		return fmt.Errorf("%w: key %q is longer than %d characters", ErrFlagKeyPolicyViolation, key, r.policy.MaxLength)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q does not match pattern %q", ErrFlagKeyPolicyViolation, key, r.policy.Pattern)
+	}
+	return nil
+}
+
+// flagKeyRulesFor loads the key policy of a project. Policies are looked up
+// once per project and memoised in seen when it is non-nil. A project
+// without a policy, or one that does not exist, allows any key; creating the
+// flag reports a missing project.
+func (s *Service) flagKeyRulesFor(ctx context.Context, projectID string, seen map[string]flagKeyRules) (flagKeyRules, error) {
+	if rules, ok := seen[projectID]; ok {
+		return rules, nil
+	}
+	repo, ok := s.repo.(FlagKeyPolicyRepository)
+	if !ok || strings.TrimSpace(projectID) == "" {
+		return flagKeyRules{}, nil
+	}
+
+	policy, err := repo.GetFlagKeyPolicy(ctx, projectID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return flagKeyRules{}, fmt.Errorf("load flag key policy: %w", err)
+	}
+	rules, err := compileFlagKeyPolicy(policy)
+	if err != nil {
+		return flagKeyRules{}, fmt.Errorf("load flag key policy: %w", err)
+	}
+	if seen != nil {
+		seen[projectID] = rules
+	}
+	return rules, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// auditActionFlagExpired is recorded by [Service.RemindExpiredFlags] for each
+// flag whose expiry has passed.
+const auditActionFlagExpired = "flag_expired"
+
+const maxFlagOwnerLen = 200
+
+var (
+	// ErrInvalidOwner is returned when a flag's owner is too long or holds
+	// control characters.
+	ErrInvalidOwner = errors.New("invalid owner")
+
+	errFlagExpiryNotSupported = errors.New("flag expiry reminders not supported")
+)
+
+// FlagExpiryRepository claims flags whose expiry has passed so each is
+// reported once. It is optionally satisfied by
+// [repository.PostgresRepository] and [repository.SQLiteRepository].
+type FlagExpiryRepository interface {
+	ClaimExpiredFlags(ctx context.Context, now time.Time) ([]repository.Flag, error)
+}
+
+// validateOwner checks an owner that has already been trimmed.
+func validateOwner(owner string) error {
+	if len(owner) > maxFlagOwnerLen {
+		return fmt.Errorf("%w: owner is longer than %d characters", ErrInvalidOwner, maxFlagOwnerLen)
+	}
+	if strings.IndexFunc(owner, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: owner must not contain control characters", ErrInvalidOwner)
+	}
+	return nil
+}
+
+// RemindExpiredFlags finds the flags whose ExpiresAt has passed since the
+// previous run, logs a warning and records a "flag_expired" audit entry for
+// each, and returns them so the caller can notify their owners. Each expiry
+// is returned once across all replicas; moving a flag's ExpiresAt re-arms
+// it. Expired flags keep serving as they are: the reminder asks a person to
+// clean them up rather than changing them.
+func (s *Service) RemindExpiredFlags(ctx context.Context) ([]repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.RemindExpiredFlags")
+	defer span.End()
+
+	repo, ok := s.repo.(FlagExpiryRepository)
+	if !ok {
+		return nil, errFlagExpiryNotSupported
+	}
+
+	expired, err := repo.ClaimExpiredFlags(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("claim expired flags: %w", err)
+	}
+	span.SetAttributes(attribute.Int("expired_flags", len(expired)))
+	for _, flag := range expired {
+		s.log.WarnContext(ctx, "flag expired",
+			"project_id", flag.ProjectID,
+			"flag_key", flag.Key,
+			"owner", flag.Owner,
+			"expires_at", flag.ExpiresAt,
+		)
+		s.insertAuditLogBestEffort(ctx, flag.ProjectID, auditActionFlagExpired, flag.Key)
+	}
+	return expired, nil
+}
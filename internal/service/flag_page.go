@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	defaultFlagPageLimit = 100
+	maxFlagPageLimit     = 1000
+)
+
+// FlagPageRepository defines reading one page of a project's flags.
+// It is optionally satisfied by [repository.PostgresRepository] and
+// [repository.SQLiteRepository].
+type FlagPageRepository interface {
+	ListFlagsPage(ctx context.Context, q repository.FlagPageQuery) ([]repository.Flag, error)
+}
+
+// ListFlagsPage returns a page of the flags of q.ProjectID with keys after
+// q.AfterKey, ordered by key and narrowed to q.Tags. When the repository
+// supports it only the page is read from it, rather than every flag of the
+// project being copied from the cache. A zero q.Limit returns 100 flags and
+// larger limits are capped at 1000. next is the AfterKey of the following
+// page, or "" on the last page.
+func (s *Service) ListFlagsPage(ctx context.Context, q repository.FlagPageQuery) (flags []repository.Flag, next string, err error) {
+	ctx, span := svcTracer.Start(ctx, "service.ListFlagsPage")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", q.ProjectID))
+
+	if strings.TrimSpace(q.ProjectID) == "" {
+		return nil, "", ErrProjectIDRequired
+	}
+	switch {
+	case q.Limit <= 0:
+		q.Limit = defaultFlagPageLimit
+	case q.Limit > maxFlagPageLimit:
+		q.Limit = maxFlagPageLimit
+	}
+	if len(q.Tags) > 0 {
+		tags := make([]string, len(q.Tags))
+		for i, tag := range q.Tags {
+			tags[i] = NormalizeTag(tag)
+		}
+		q.Tags = tags
+	}
+	limit := q.Limit
+
+	if repo, ok := s.repo.(FlagPageRepository); ok {
+		// One extra flag tells whether there is another page.
+		q.Limit++
+		flags, err = repo.ListFlagsPage(ctx, q)
+		if err != nil {
+			span.RecordError(err)
+			return nil, "", fmt.Errorf("list flags page: %w", err)
+		}
+	} else {
+		flags, err = s.ListFlags(ctx, q.ProjectID)
+		if err != nil {
+			return nil, "", err
+		}
+		flags = FilterFlagsByTags(flags, q.Tags)
+		flags = flags[sort.Search(len(flags), func(i int) bool { return flags[i].Key > q.AfterKey }):]
+	}
+
+	if len(flags) > limit {
+		flags = flags[:limit]
+		next = flags[limit-1].Key
+	}
+	return flags, next, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/jsonpatch"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Flag patch formats accepted by [Service.PatchFlag], named by their media
+// types.
+const (
+	// PatchTypeMerge is a JSON Merge Patch (RFC 7396).
+	PatchTypeMerge = "application/merge-patch+json"
+	// PatchTypeJSON is a JSON Patch (RFC 6902).
+	PatchTypeJSON = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned when a flag patch is malformed, cannot be
+	// applied, or changes a field that updates cannot change.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPatchTestFailed is returned when a JSON Patch "test" operation does
+	// not match the flag, usually because it changed since the caller read
+	// it.
+	ErrPatchTestFailed = errors.New("patch test failed")
+
+	errFlagPatchNotSupported = errors.New("flag patches not supported")
+)
+
+// FlagPatchRepository defines changing a flag based on its current state in
+// one transaction. It is optionally satisfied by
+// [repository.PostgresRepository].
+type FlagPatchRepository interface {
+	UpdateFlagFunc(ctx context.Context, projectID, key string, update func(repository.Flag) (repository.Flag, error)) (repository.Flag, repository.Flag, error)
+}
+
+// PatchFlag applies patch, a document of patchType, to the JSON form of a
+// flag and saves the result as [Service.UpdateFlag] would. The flag stays
+// locked from reading it to saving it, so a concurrent change to a field the
+// patch leaves alone is never lost. Unlike an update, a field the patch
+// removes is cleared rather than kept. Returns [ErrInvalidPatch] if the
+// patch cannot be applied or changes the key, bucketing salt or targets,
+// [ErrPatchTestFailed] if one of its "test" operations fails, and
+// [ErrFlagNotFound] if the flag does not exist.
+func (s *Service) PatchFlag(ctx context.Context, projectID, key, patchType string, patch json.RawMessage) (repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.PatchFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+		attribute.String("patch_type", patchType),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return repository.Flag{}, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(projectID) == "" {
+		return repository.Flag{}, ErrProjectIDRequired
+	}
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch patchType {
+	case PatchTypeMerge:
+		apply = jsonpatch.Merge
+	case PatchTypeJSON:
+		apply = jsonpatch.Apply
+	default:
+		return repository.Flag{}, fmt.Errorf("%w: unknown patch type %q", ErrInvalidPatch, patchType)
+	}
+	repo, ok := s.repo.(FlagPatchRepository)
+	if !ok {
+		return repository.Flag{}, errFlagPatchNotSupported
+	}
+
+	// patchErr keeps the reason the patched flag was rejected, unwrapped by
+	// the repository.
+	var patchErr error
+	current, updated, err := repo.UpdateFlagFunc(ctx, projectID, key, func(current repository.Flag) (repository.Flag, error) {
+		flag, err := applyFlagPatch(current, apply, patch)
+		if err == nil {
+			err = validateFlag(flag)
+		}
+		if err == nil {
+			err = s.checkSegmentReferences(flag)
+		}
+		if err == nil {
+			flag, err = completeFlagUpdate(ctx, current, flag)
+		}
+		patchErr = err
+		return flag, err
+	})
+	if err != nil {
+		switch {
+		case patchErr != nil:
+			return repository.Flag{}, patchErr
+		case errors.Is(err, pgx.ErrNoRows):
+			s.deleteCachedFlag(projectID, key)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "flag not found")
+			return repository.Flag{}, ErrFlagNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "patch flag failed")
+		return repository.Flag{}, fmt.Errorf("patch flag: %w", err)
+	}
+
+	s.setCachedFlag(updated)
+	s.publishFlagEventBestEffort(ctx, EventTypeUpdated, updated)
+	s.insertFlagChangeAuditLog(ctx, "patch", current, updated)
+
+	return updated, nil
+}
+
+// applyFlagPatch applies patch to the JSON form of current and decodes the
+// result as the flag to save.
+func applyFlagPatch(current repository.Flag, apply func(doc, patch []byte) ([]byte, error), patch json.RawMessage) (repository.Flag, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return repository.Flag{}, fmt.Errorf("encode flag: %w", err)
+	}
+	patched, err := apply(doc, patch)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return repository.Flag{}, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		return repository.Flag{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return repository.Flag{}, fmt.Errorf("%w: the patched flag is not a JSON object", ErrInvalidPatch)
+	}
+	var flag repository.Flag
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&flag); err != nil {
+		return repository.Flag{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	flag.ProjectID = current.ProjectID
+
+	switch {
+	case flag.Key != current.Key:
+		return repository.Flag{}, fmt.Errorf("%w: key cannot be changed", ErrInvalidPatch)
+	case flag.BucketingSalt != current.BucketingSalt:
+		return repository.Flag{}, fmt.Errorf("%w: bucketing_salt cannot be changed; reshuffle the flag instead", ErrInvalidPatch)
+	case flag.Targets.Attribute != current.Targets.Attribute ||
+		!slices.Equal(flag.Targets.Allow, current.Targets.Allow) ||
+		!slices.Equal(flag.Targets.Deny, current.Targets.Deny):
+		return repository.Flag{}, fmt.Errorf("%w: targets cannot be changed by a patch", ErrInvalidPatch)
+	}
+
+	// An update keeps these when they are left out; a patch that removes
+	// them means to clear them.
+	if _, ok := fields["shadow_rules"]; !ok {
+		flag.ShadowRules = json.RawMessage("null")
+	}
+	if _, ok := fields["variants_schema"]; !ok {
+		flag.VariantsSchema = json.RawMessage("null")
+	}
+	if flag.Tags == nil {
+		flag.Tags = []string{}
+	}
+	return flag, nil
+}
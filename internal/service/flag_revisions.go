@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	defaultFlagHistoryLimit = 50
+	maxFlagHistoryLimit     = 1000
+)
+
+// ErrFlagRevisionNotFound is returned when a flag has no revision with the
+// requested number.
+var ErrFlagRevisionNotFound = errors.New("flag revision not found")
+
+var errFlagRevisionsNotSupported = errors.New("flag revisions not supported")
+
+// FlagRevisionRepository defines reads of the revisions recorded whenever a
+// flag is created or updated. It is optionally satisfied by
+// [repository.PostgresRepository].
+type FlagRevisionRepository interface {
+	ListFlagRevisions(ctx context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error)
+	GetFlagRevision(ctx context.Context, projectID, key string, revision int) (repository.FlagRevision, error)
+}
+
+func (s *Service) flagRevisionRepository() (FlagRevisionRepository, error) {
+	repo, ok := s.repo.(FlagRevisionRepository)
+	if !ok {
+		return nil, errFlagRevisionsNotSupported
+	}
+	return repo, nil
+}
+
+// ListFlagRevisions returns up to limit revisions of a flag, newest first.
+// A non-positive limit defaults to 50 and larger limits are capped at 1000.
+// Returns [ErrFlagNotFound] if the flag does not exist.
+func (s *Service) ListFlagRevisions(ctx context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ListFlagRevisions")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return nil, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	repo, err := s.flagRevisionRepository()
+	if err != nil {
+		return nil, err
+	}
+	// History is deleted with the flag, so an empty list would be ambiguous.
+	if _, err := s.GetFlag(ctx, projectID, key); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultFlagHistoryLimit
+	}
+	limit = min(limit, maxFlagHistoryLimit)
+	revisions, err := repo.ListFlagRevisions(ctx, projectID, key, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list flag revisions failed")
+		return nil, fmt.Errorf("list flag revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// RevertFlag restores the description, enabled state, variants and rules a
+// flag had at revision, as an ordinary update: the result is validated,
+// published and recorded as a new revision, and audited as "revert". The
+// bucketing salt, targets, tags, owner and expiry are left as they are.
+// Returns [ErrFlagRevisionNotFound] if the revision does not exist.
+func (s *Service) RevertFlag(ctx context.Context, projectID, key string, revision int) (repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.RevertFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+		attribute.Int("revision", revision),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return repository.Flag{}, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(projectID) == "" {
+		return repository.Flag{}, ErrProjectIDRequired
+	}
+	repo, err := s.flagRevisionRepository()
+	if err != nil {
+		return repository.Flag{}, err
+	}
+
+	rev, err := repo.GetFlagRevision(ctx, projectID, key, revision)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.Flag{}, ErrFlagRevisionNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "get flag revision failed")
+		return repository.Flag{}, fmt.Errorf("get flag revision: %w", err)
+	}
+
+	current, err := s.GetFlag(ctx, projectID, key)
+	if err != nil {
+		return repository.Flag{}, err
+	}
+	return s.updateFlag(ctx, repository.Flag{
+		ProjectID:   projectID,
+		Key:         key,
+		Description: rev.Flag.Description,
+		Enabled:     rev.Flag.Enabled,
+		Variants:    rev.Flag.Variants,
+		Rules:       rev.Flag.Rules,
+		Owner:       current.Owner,
+		ExpiresAt:   current.ExpiresAt,
+	}, "revert")
+}
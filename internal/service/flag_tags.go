@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrInvalidTags is returned when a flag's tags break the tag format or
+// there are too many of them.
+var ErrInvalidTags = errors.New("invalid tags")
+
+const (
+	maxFlagTags   = 32
+	maxFlagTagLen = 64
+)
+
+// tagPattern is the format of a tag after lowercasing: a letter or digit,
+// then letters, digits and - _ . : / such as "team:checkout" or "q3-launch".
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:/-]*$`)
+
+// normalizeTags trims and lowercases tags, drops duplicates and sorts them,
+// so tags compare and filter case-insensitively. nil stays nil, which tells
+// UpdateFlag to keep the current tags.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if len(tag) > maxFlagTagLen {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidTags, tag, maxFlagTagLen)
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: tag %q must start with a letter or digit and contain only letters, digits and - _ . : /", ErrInvalidTags, tag)
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > maxFlagTags {
+		return nil, fmt.Errorf("%w: a flag has at most %d tags", ErrInvalidTags, maxFlagTags)
+	}
+	return normalized, nil
+}
+
+// NormalizeTag returns tag as it is stored, for comparing a tag filter with
+// stored tags.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// FilterFlagsByTags returns the flags carrying every one of tags, in their
+// original order. With no tags it returns flags unchanged.
+func FilterFlagsByTags(flags []repository.Flag, tags []string) []repository.Flag {
+	if len(tags) == 0 {
+		return flags
+	}
+	filtered := make([]repository.Flag, 0, len(flags))
+	for _, flag := range flags {
+		if hasAllTags(flag.Tags, tags) {
+			filtered = append(filtered, flag)
+		}
+	}
+	return filtered
+}
+
+func hasAllTags(flagTags, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(flagTags, NormalizeTag(tag)) {
+			return false
+		}
+	}
+	return true
+}
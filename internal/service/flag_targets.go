@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// maxFlagTargets bounds each of a flag's target lists. Targets are checked
+// linearly on every evaluation, so larger audiences belong in a rule.
+const maxFlagTargets = 10000
+
+// ErrInvalidTargets is returned when a flag's targets are malformed: an
+// identifier is blank, a list is too long, or lists are given without the
+// attribute they are matched against.
+var ErrInvalidTargets = errors.New("invalid targets")
+
+var errFlagTargetsNotSupported = errors.New("flag targets not supported")
+
+// FlagTargetRepository defines writes of a flag's individual targets. It is
+// optionally satisfied by [repository.PostgresRepository].
+type FlagTargetRepository interface {
+	SetFlagTargets(ctx context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error)
+}
+
+// SetFlagTargets replaces the identifiers a flag is individually switched on
+// (allow) or off (deny) for, ahead of its rules. Identifiers are trimmed and
+// de-duplicated; with both lists empty the flag has no targets. The updated
+// flag is cached, published and audited as "set_targets", with the old and
+// new targets in the entry's details. Returns
+// [ErrFlagNotFound] if the flag does not exist.
+func (s *Service) SetFlagTargets(ctx context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetFlagTargets")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", key),
+		attribute.String("project_id", projectID),
+	)
+
+	if strings.TrimSpace(key) == "" {
+		return repository.Flag{}, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(projectID) == "" {
+		return repository.Flag{}, ErrProjectIDRequired
+	}
+	targets, err := normalizeFlagTargets(targets)
+	if err != nil {
+		return repository.Flag{}, err
+	}
+
+	repo, ok := s.repo.(FlagTargetRepository)
+	if !ok {
+		return repository.Flag{}, errFlagTargetsNotSupported
+	}
+	current, err := s.GetFlag(ctx, projectID, key)
+	if err != nil {
+		return repository.Flag{}, err
+	}
+
+	updated, err := repo.SetFlagTargets(ctx, projectID, key, targets)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.deleteCachedFlag(projectID, key)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "flag not found")
+			return repository.Flag{}, ErrFlagNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set flag targets failed")
+		return repository.Flag{}, fmt.Errorf("set flag targets: %w", err)
+	}
+
+	s.setCachedFlag(updated)
+	s.publishFlagEventBestEffort(ctx, EventTypeUpdated, updated)
+	s.insertFlagChangeAuditLog(ctx, "set_targets", current, updated)
+
+	return updated, nil
+}
+
+func normalizeFlagTargets(targets repository.FlagTargets) (repository.FlagTargets, error) {
+	allow, err := normalizeTargetList("allow", targets.Allow)
+	if err != nil {
+		return repository.FlagTargets{}, err
+	}
+	deny, err := normalizeTargetList("deny", targets.Deny)
+	if err != nil {
+		return repository.FlagTargets{}, err
+	}
+	if len(allow) == 0 && len(deny) == 0 {
+		return repository.FlagTargets{}, nil
+	}
+
+	attr := strings.TrimSpace(targets.Attribute)
+	if attr == "" {
+		return repository.FlagTargets{}, fmt.Errorf("%w: attribute is required", ErrInvalidTargets)
+	}
+	return repository.FlagTargets{Attribute: attr, Allow: allow, Deny: deny}, nil
+}
+
+func normalizeTargetList(name string, ids []string) ([]string, error) {
+	if len(ids) > maxFlagTargets {
+		return nil, fmt.Errorf("%w: %s has %d identifiers, the limit is %d", ErrInvalidTargets, name, len(ids), maxFlagTargets)
+	}
+	seen := make(map[string]bool, len(ids))
+	normalized := make([]string, 0, len(ids))
+	for i, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return nil, fmt.Errorf("%w: %s[%d] is blank", ErrInvalidTargets, name, i)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+	return normalized, nil
+}
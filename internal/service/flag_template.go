@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Types of a [FlagTemplateParameter].
+const (
+	FlagTemplateParameterString = "string"
+	// FlagTemplateParameterPercentage is a number from 0 to 100.
+	FlagTemplateParameterPercentage = "percentage"
+	// FlagTemplateParameterList is a list of strings, which may also be given
+	// as one comma-separated string.
+	FlagTemplateParameterList = "list"
+)
+
+// templateFlagLifetime is how long flags from templates for temporary
+// changes, such as rollouts, live before their expiry reminder.
+const templateFlagLifetime = 90 * 24 * time.Hour
+
+var (
+	// ErrFlagTemplateNotFound is returned when a flag is created from a
+	// template that does not exist.
+	ErrFlagTemplateNotFound = errors.New("flag template not found")
+	// ErrInvalidFlagTemplateParameters is returned when a template parameter
+	// is missing, unknown or of the wrong type.
+	ErrInvalidFlagTemplateParameters = errors.New("invalid flag template parameters")
+)
+
+// FlagTemplate is a common kind of flag, such as a percentage rollout, that
+// [Service.CreateFlagFromTemplate] stamps out with its rules, variants and
+// metadata already in place.
+type FlagTemplate struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Enabled is whether flags from the template start enabled.
+	Enabled    bool                    `json:"enabled"`
+	Parameters []FlagTemplateParameter `json:"parameters"`
+
+	build func(params map[string]any) repository.Flag
+}
+
+// FlagTemplateParameter is a value a [FlagTemplate] fills in. Parameters
+// without a Default are required.
+type FlagTemplateParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Default     any    `json:"default,omitempty"`
+}
+
+// flagTemplates are the built-in templates, in the order they are listed.
+var flagTemplates = []FlagTemplate{
+	{
+		Name:        "percentage-rollout",
+		Title:       "Percentage rollout",
+		Description: "Releases a feature to a percentage of targeting keys, to be raised step by step. Expires after 90 days.",
+		Parameters: []FlagTemplateParameter{
+			{Name: "attribute", Type: FlagTemplateParameterString, Description: "Context attribute to bucket on", Default: "user_id"},
+			{Name: "percentage", Type: FlagTemplateParameterPercentage, Description: "Percentage of targeting keys that get the feature, from 0 to 100", Default: float64(10)},
+		},
+		build: func(params map[string]any) repository.Flag {
+			return repository.Flag{
+				Variants:  mustMarshal(map[string]any{"default": false}),
+				Rules:     mustMarshal([]core.Rule{{Attribute: params["attribute"].(string), Operator: core.OperatorPercentage, Value: params["percentage"]}}),
+				Tags:      []string{"rollout"},
+				ExpiresAt: templateExpiry(),
+			}
+		},
+	},
+	{
+		Name:        "kill-switch",
+		Title:       "Ops kill switch",
+		Description: "Keeps a feature on for everyone and turns it off for everyone when the flag is disabled, for shedding it during an incident.",
+		Enabled:     true,
+		build: func(map[string]any) repository.Flag {
+			return repository.Flag{
+				Variants: mustMarshal(map[string]any{"default": true}),
+				Rules:    json.RawMessage("[]"),
+				Tags:     []string{"ops", "kill-switch"},
+			}
+		},
+	},
+	{
+		Name:        "beta-allowlist",
+		Title:       "Beta allowlist",
+		Description: "Turns a feature on for a fixed list of users, such as beta testers. Expires after 90 days.",
+		Parameters: []FlagTemplateParameter{
+			{Name: "attribute", Type: FlagTemplateParameterString, Description: "Context attribute identifying users", Default: "user_id"},
+			{Name: "values", Type: FlagTemplateParameterList, Description: "Values of the attribute that get the feature"},
+		},
+		build: func(params map[string]any) repository.Flag {
+			return repository.Flag{
+				Variants:  mustMarshal(map[string]any{"default": false}),
+				Rules:     mustMarshal([]core.Rule{{Attribute: params["attribute"].(string), Operator: core.OperatorIn, Value: params["values"]}}),
+				Tags:      []string{"beta"},
+				ExpiresAt: templateExpiry(),
+			}
+		},
+	},
+}
+
+// FlagFromTemplateRequest describes a flag to create from a [FlagTemplate].
+// Description, Owner and Tags replace the template's when they are set, and
+// Enabled its initial state.
+type FlagFromTemplateRequest struct {
+	ProjectID   string
+	Template    string
+	Key         string
+	Description string
+	Enabled     *bool
+	Owner       string
+	Tags        []string
+	// Parameters holds a value for each of the template's parameters,
+	// falling back to their defaults.
+	Parameters map[string]any
+}
+
+// FlagTemplates returns the built-in flag templates.
+func (s *Service) FlagTemplates() []FlagTemplate {
+	return slices.Clone(flagTemplates)
+}
+
+// CreateFlagFromTemplate creates a flag from the template req names. It goes
+// through [Service.CreateFlag], so the project's defaults and key policy
+// apply as to any new flag. Returns [ErrFlagTemplateNotFound] if the template
+// does not exist and [ErrInvalidFlagTemplateParameters] if req's parameters
+// do not fit it.
+func (s *Service) CreateFlagFromTemplate(ctx context.Context, req FlagFromTemplateRequest) (repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.CreateFlagFromTemplate")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", req.Key),
+		attribute.String("project_id", req.ProjectID),
+		attribute.String("flag_template", req.Template),
+	)
+
+	template, ok := findFlagTemplate(req.Template)
+	if !ok {
+		return repository.Flag{}, fmt.Errorf("%w: %q", ErrFlagTemplateNotFound, req.Template)
+	}
+	params, err := template.resolveParameters(req.Parameters)
+	if err != nil {
+		return repository.Flag{}, err
+	}
+
+	flag := template.build(params)
+	flag.ProjectID = req.ProjectID
+	flag.Key = req.Key
+	flag.Enabled = template.Enabled
+	if desc := strings.TrimSpace(req.Description); desc != "" {
+		flag.Description = desc
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	flag.Owner = req.Owner
+	if req.Tags != nil {
+		flag.Tags = req.Tags
+	}
+	return s.CreateFlag(ctx, flag)
+}
+
+func findFlagTemplate(name string) (FlagTemplate, bool) {
+	for _, template := range flagTemplates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+	return FlagTemplate{}, false
+}
+
+// resolveParameters checks params against the template's parameters, fills
+// in defaults and converts each value to its parameter's type: a string, a
+// float64 percentage or a []string.
+func (t FlagTemplate) resolveParameters(params map[string]any) (map[string]any, error) {
+	resolved := make(map[string]any, len(t.Parameters))
+	for _, param := range t.Parameters {
+		value, ok := params[param.Name]
+		if !ok || value == nil {
+			if param.Default == nil {
+				return nil, fmt.Errorf("%w: %q is required", ErrInvalidFlagTemplateParameters, param.Name)
+			}
+			value = param.Default
+		}
+		converted, err := param.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q %v", ErrInvalidFlagTemplateParameters, param.Name, err)
+		}
+		resolved[param.Name] = converted
+	}
+	for name := range params {
+		if _, ok := resolved[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown parameter %q", ErrInvalidFlagTemplateParameters, name)
+		}
+	}
+	return resolved, nil
+}
+
+// convert converts value to the parameter's type, accepting strings for
+// percentages and lists so that form input can be passed as it is.
+func (p FlagTemplateParameter) convert(value any) (any, error) {
+	switch p.Type {
+	case FlagTemplateParameterString:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, errors.New("must be a non-empty string")
+		}
+		return strings.TrimSpace(s), nil
+	case FlagTemplateParameterPercentage:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, errors.New("must be a number")
+			}
+			n = parsed
+		default:
+			return nil, errors.New("must be a number")
+		}
+		if n < 0 || n > 100 {
+			return nil, errors.New("must be between 0 and 100")
+		}
+		return n, nil
+	case FlagTemplateParameterList:
+		var items []string
+		switch v := value.(type) {
+		case string:
+			items = strings.Split(v, ",")
+		case []string:
+			items = v
+		case []any:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, errors.New("must be a list of strings")
+				}
+				items = append(items, s)
+			}
+		default:
+			return nil, errors.New("must be a list of strings")
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(list) == 0 {
+			return nil, errors.New("must not be empty")
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("has unknown type %q", p.Type)
+}
+
+func templateExpiry() *time.Time {
+	expiresAt := time.Now().UTC().Add(templateFlagLifetime).Truncate(time.Second)
+	return &expiresAt
+}
+
+func mustMarshal(v any) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Kinds of [GraphNode].
+const (
+	GraphNodeFlag  = "flag"
+	GraphNodeTag   = "tag"
+	GraphNodeOwner = "owner"
+)
+
+// Kinds of [GraphEdge].
+const (
+	// GraphEdgeTagged links a flag to each of its tags.
+	GraphEdgeTagged = "tagged"
+	// GraphEdgeOwnedBy links a flag to its owner.
+	GraphEdgeOwnedBy = "owned_by"
+)
+
+// GraphNode is a flag, tag or owner in a [ProjectGraph]. IDs are the kind
+// and the name joined by a colon, such as "flag:checkout" or
+// "owner:team:payments".
+type GraphNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	// Enabled is only set on flag nodes.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Orphaned marks a flag with neither tags nor an owner.
+	Orphaned bool `json:"orphaned,omitempty"`
+}
+
+// GraphEdge links two [GraphNode] IDs.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// ProjectGraph is a project's flags as a graph for visualization. Nodes
+// list the flags sorted by key, then the tags and the owners, each sorted
+// by name. Orphans lists the keys of the orphaned flags.
+type ProjectGraph struct {
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+	Orphans []string    `json:"orphans"`
+}
+
+// ProjectGraph returns the flags of a project linked to their tags and
+// owners, so flags sharing a team or feature area cluster together and
+// flags nobody is responsible for stand out.
+func (s *Service) ProjectGraph(ctx context.Context, projectID string) (ProjectGraph, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ProjectGraph")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return ProjectGraph{}, ErrProjectIDRequired
+	}
+	flags, err := s.ListFlags(ctx, projectID)
+	if err != nil {
+		return ProjectGraph{}, err
+	}
+
+	graph := ProjectGraph{Nodes: make([]GraphNode, 0, len(flags)), Edges: make([]GraphEdge, 0), Orphans: make([]string, 0)}
+	tags := make(map[string]bool)
+	owners := make(map[string]bool)
+	for _, flag := range flags {
+		flagID := GraphNodeFlag + ":" + flag.Key
+		enabled := flag.Enabled
+		node := GraphNode{ID: flagID, Kind: GraphNodeFlag, Label: flag.Key, Enabled: &enabled}
+
+		for _, tag := range flag.Tags {
+			tags[tag] = true
+			graph.Edges = append(graph.Edges, GraphEdge{From: flagID, To: GraphNodeTag + ":" + tag, Kind: GraphEdgeTagged})
+		}
+		if flag.Owner != "" {
+			owners[flag.Owner] = true
+			graph.Edges = append(graph.Edges, GraphEdge{From: flagID, To: GraphNodeOwner + ":" + flag.Owner, Kind: GraphEdgeOwnedBy})
+		}
+		if len(flag.Tags) == 0 && flag.Owner == "" {
+			node.Orphaned = true
+			graph.Orphans = append(graph.Orphans, flag.Key)
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, group := range []struct {
+		kind  string
+		names map[string]bool
+	}{
+		{GraphNodeTag, tags},
+		{GraphNodeOwner, owners},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(group.names)) {
+			graph.Nodes = append(graph.Nodes, GraphNode{ID: group.kind + ":" + name, Kind: group.kind, Label: name})
+		}
+	}
+	return graph, nil
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Flag read sources reported to the callback given to [WithHedgedReads].
+const (
+	FlagSourcePrimary = "primary"
+	FlagSourceReplica = "replica"
+)
+
+const (
+	defaultHedgeDelay = 10 * time.Millisecond
+	// hedgeFailureThreshold consecutive errors open a source's circuit for
+	// hedgeOpenDuration, during which it is tried only as a last resort.
+	hedgeFailureThreshold = 3
+	hedgeOpenDuration     = 30 * time.Second
+	// hedgeLatencyWeight is the weight of each new sample in a source's
+	// moving average latency.
+	hedgeLatencyWeight = 0.2
+)
+
+// FlagReader reads a single flag. It is satisfied by
+// [repository.PostgresRepository], including one connected to a read replica.
+type FlagReader interface {
+	GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error)
+}
+
+// WithHedgedReads serves [Service.GetFlag] cache misses from both the
+// repository and replica. The source that has recently been fastest is asked
+// first; if it has not answered after delay, the other is asked too and the
+// first answer wins. A source that fails repeatedly is skipped for a while.
+// A replica's "not found" is never trusted, since the flag may simply not
+// have replicated yet; the primary is asked instead.
+//
+// onRead, if not nil, is called with the source of every answer and whether
+// it came from the hedged (second) request. A nil replica is a no-op, and a
+// delay <= 0 uses the 10ms default.
+func WithHedgedReads(replica FlagReader, delay time.Duration, onRead func(source string, hedgeWon bool)) Option {
+	return func(s *Service) {
+		if replica == nil {
+			return
+		}
+		if delay <= 0 {
+			delay = defaultHedgeDelay
+		}
+		s.hedgedReplica = replica
+		s.hedgeDelay = delay
+		s.onHedgedRead = onRead
+	}
+}
+
+// readSource is one place flags can be read from, with the health and
+// latency estimates used to order sources.
+type readSource struct {
+	name   string
+	reader FlagReader
+
+	mu        sync.Mutex
+	latency   time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+func (src *readSource) state(now time.Time) (healthy bool, latency time.Duration) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	return !now.Before(src.openUntil), src.latency
+}
+
+func (src *readSource) observeLatency(elapsed time.Duration) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.latency == 0 {
+		src.latency = elapsed
+		return
+	}
+	src.latency += time.Duration(hedgeLatencyWeight * float64(elapsed-src.latency))
+}
+
+func (src *readSource) recordSuccess(elapsed time.Duration) {
+	src.observeLatency(elapsed)
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.failures = 0
+	src.openUntil = time.Time{}
+}
+
+func (src *readSource) recordFailure(now time.Time) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.failures++
+	if src.failures >= hedgeFailureThreshold {
+		src.openUntil = now.Add(hedgeOpenDuration)
+	}
+}
+
+// hedgedReader races a primary and a replica [FlagReader].
+type hedgedReader struct {
+	sources []*readSource
+	delay   time.Duration
+	onRead  func(source string, hedgeWon bool)
+	now     func() time.Time
+}
+
+func newHedgedReader(primary, replica FlagReader, delay time.Duration, onRead func(string, bool)) *hedgedReader {
+	return &hedgedReader{
+		sources: []*readSource{
+			{name: FlagSourcePrimary, reader: primary},
+			{name: FlagSourceReplica, reader: replica},
+		},
+		delay:  delay,
+		onRead: onRead,
+		now:    time.Now,
+	}
+}
+
+// order returns the sources healthy first, then fastest first. Sources with
+// no latency estimate yet sort as fastest so that each gets measured.
+func (h *hedgedReader) order(now time.Time) ([]*readSource, []bool) {
+	type ranked struct {
+		src     *readSource
+		healthy bool
+		latency time.Duration
+	}
+	ranks := make([]ranked, len(h.sources))
+	for i, src := range h.sources {
+		healthy, latency := src.state(now)
+		ranks[i] = ranked{src: src, healthy: healthy, latency: latency}
+	}
+	slices.SortStableFunc(ranks, func(a, b ranked) int {
+		if a.healthy != b.healthy {
+			if a.healthy {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.latency, b.latency)
+	})
+
+	sources := make([]*readSource, len(ranks))
+	healthy := make([]bool, len(ranks))
+	for i, r := range ranks {
+		sources[i], healthy[i] = r.src, r.healthy
+	}
+	return sources, healthy
+}
+
+type hedgedResult struct {
+	index int
+	flag  repository.Flag
+	err   error
+}
+
+// GetFlag implements [FlagReader]. Errors are those of the primary where it
+// answered, so pgx.ErrNoRows keeps its meaning for callers.
+func (h *hedgedReader) GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error) {
+	sources, healthy := h.order(h.now())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgedResult, len(sources))
+	launched := 0
+	launch := func() {
+		index, src := launched, sources[launched]
+		launched++
+		go func() {
+			start := time.Now()
+			flag, err := src.reader.GetFlag(ctx, projectID, key)
+			elapsed := time.Since(start)
+			switch {
+			case err == nil, errors.Is(err, pgx.ErrNoRows):
+				src.recordSuccess(elapsed)
+			case ctx.Err() != nil:
+				// Lost the race: its latency is at least elapsed.
+				src.observeLatency(elapsed)
+			default:
+				src.recordFailure(h.now())
+			}
+			results <- hedgedResult{index: index, flag: flag, err: err}
+		}()
+	}
+
+	launch()
+	var hedge <-chan time.Time
+	if len(sources) > 1 && healthy[1] {
+		timer := time.NewTimer(h.delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	var lastErr error
+	pending, hedgeIndex := 1, -1
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+			hedgeIndex = launched
+			launch()
+			pending++
+		case res := <-results:
+			pending--
+			src := sources[res.index]
+			if res.err == nil || (src.name == FlagSourcePrimary && errors.Is(res.err, pgx.ErrNoRows)) {
+				if h.onRead != nil {
+					h.onRead(src.name, res.index == hedgeIndex)
+				}
+				return res.flag, res.err
+			}
+			if lastErr == nil || src.name == FlagSourcePrimary {
+				lastErr = res.err
+			}
+			// Failed fast: fall back to the next source without waiting
+			// out the hedge delay.
+			if launched < len(sources) {
+				hedge = nil
+				launch()
+				pending++
+			}
+			if pending == 0 {
+				return repository.Flag{}, lastErr
+			}
+		case <-ctx.Done():
+			return repository.Flag{}, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// defaultIdempotencyTTL is how long the response to a request made with an
+// Idempotency-Key is replayed to retries.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyRepository defines storage of the responses to requests made
+// with an Idempotency-Key header. It is optionally satisfied by
+// [repository.PostgresRepository] and [repository.SQLiteRepository].
+type IdempotencyRepository interface {
+	ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string, now, expiredBefore time.Time) (repository.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+// WithIdempotencyTTL sets how long the response to a request made with an
+// Idempotency-Key is replayed before the key can be reused. Defaults to 24
+// hours if not set or if ttl <= 0.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.idempotencyTTL = ttl
+		}
+	}
+}
+
+// ClaimIdempotencyKey reserves key for the API key apiKeyID so that the
+// caller handles the request identified by fingerprint. It reports true if
+// the caller owns the key; otherwise it returns the live record holding it,
+// whose StatusCode is 0 while that request is still in progress.
+//
+// When the repository does not store idempotency keys every claim succeeds,
+// so requests are handled as if no key had been sent.
+func (s *Service) ClaimIdempotencyKey(ctx context.Context, apiKeyID, key, fingerprint string) (repository.IdempotencyRecord, bool, error) {
+	repo, ok := s.repo.(IdempotencyRepository)
+	if !ok {
+		return repository.IdempotencyRecord{Fingerprint: fingerprint}, true, nil
+	}
+
+	now := time.Now()
+	record, claimed, err := repo.ClaimIdempotencyKey(ctx, apiKeyID, key, fingerprint, now, now.Add(-s.idempotencyTTL))
+	if err != nil {
+		return repository.IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	return record, claimed, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request that claimed
+// key so that retries replay it.
+func (s *Service) CompleteIdempotencyKey(ctx context.Context, apiKeyID, key string, record repository.IdempotencyRecord) error {
+	repo, ok := s.repo.(IdempotencyRepository)
+	if !ok {
+		return nil
+	}
+
+	if err := repo.CompleteIdempotencyKey(ctx, apiKeyID, key, record); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the claim on key without storing a response,
+// so a retry handles the request again.
+func (s *Service) ReleaseIdempotencyKey(ctx context.Context, apiKeyID, key string) error {
+	repo, ok := s.repo.(IdempotencyRepository)
+	if !ok {
+		return nil
+	}
+
+	if err := repo.ReleaseIdempotencyKey(ctx, apiKeyID, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys removes stored responses older than the idempotency
+// TTL and returns how many were removed.
+func (s *Service) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	repo, ok := s.repo.(IdempotencyRepository)
+	if !ok {
+		return 0, nil
+	}
+
+	purged, err := repo.PurgeIdempotencyKeys(ctx, time.Now().Add(-s.idempotencyTTL))
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Audit log actions recorded by [Service.EnforceKeyRotation], named like the
+// admin UI's own API key actions.
+const (
+	auditActionAPIKeyRotationOverdue = "api_key_rotation_overdue"
+	auditActionAPIKeyAutoRevoke      = "api_key_auto_revoke"
+)
+
+var (
+	// ErrInvalidKeyRotationPolicy is returned when a key rotation policy has
+	// a negative duration, or a revocation grace period without a maximum
+	// key age.
+	ErrInvalidKeyRotationPolicy = errors.New("invalid key rotation policy")
+
+	errKeyRotationNotSupported = errors.New("key rotation policies not supported")
+)
+
+// KeyRotationRepository defines storage of per-project API key rotation
+// policies and the bookkeeping for overdue keys. It is optionally satisfied
+// by [repository.PostgresRepository].
+type KeyRotationRepository interface {
+	GetKeyRotationPolicy(ctx context.Context, projectID string) (repository.KeyRotationPolicy, error)
+	SetKeyRotationPolicy(ctx context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error)
+	MarkOverdueAPIKeys(ctx context.Context, now time.Time) ([]repository.APIKeyMeta, error)
+	RevokeOverdueAPIKeys(ctx context.Context, now time.Time) ([]repository.APIKeyMeta, error)
+}
+
+// KeyRotationResult lists the keys changed by one [Service.EnforceKeyRotation]
+// run.
+type KeyRotationResult struct {
+	// Overdue holds keys that passed their project's maximum age since the
+	// previous run.
+	Overdue []repository.APIKeyMeta
+	// Revoked holds keys revoked because their grace period ended.
+	Revoked []repository.APIKeyMeta
+}
+
+// GetKeyRotationPolicy returns a project's API key rotation policy. When the
+// repository does not store policies, the zero (disabled) policy is
+// returned. Returns [ErrProjectNotFound] if the project does not exist.
+func (s *Service) GetKeyRotationPolicy(ctx context.Context, projectID string) (repository.KeyRotationPolicy, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.KeyRotationPolicy{}, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(KeyRotationRepository)
+	if !ok {
+		return repository.KeyRotationPolicy{}, nil
+	}
+
+	policy, err := repo.GetKeyRotationPolicy(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.KeyRotationPolicy{}, ErrProjectNotFound
+		}
+		return repository.KeyRotationPolicy{}, fmt.Errorf("get key rotation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetKeyRotationPolicy validates and stores a project's API key rotation
+// policy. A zero MaxAge disables the policy. Returns [ErrProjectNotFound] if
+// the project does not exist.
+func (s *Service) SetKeyRotationPolicy(ctx context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetKeyRotationPolicy")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.KeyRotationPolicy{}, ErrProjectIDRequired
+	}
+	if policy.MaxAge < 0 || policy.RevokeAfter < 0 {
+		return repository.KeyRotationPolicy{}, fmt.Errorf("%w: durations must not be negative", ErrInvalidKeyRotationPolicy)
+	}
+	if policy.RevokeAfter > 0 && policy.MaxAge == 0 {
+		return repository.KeyRotationPolicy{}, fmt.Errorf("%w: a revocation grace period requires a maximum key age", ErrInvalidKeyRotationPolicy)
+	}
+
+	repo, ok := s.repo.(KeyRotationRepository)
+	if !ok {
+		return repository.KeyRotationPolicy{}, errKeyRotationNotSupported
+	}
+
+	updated, err := repo.SetKeyRotationPolicy(ctx, projectID, policy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.KeyRotationPolicy{}, ErrProjectNotFound
+		}
+		return repository.KeyRotationPolicy{}, fmt.Errorf("set key rotation policy: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "update_key_rotation_policy", "")
+	return updated, nil
+}
+
+// EnforceKeyRotation marks keys that have become overdue under their
+// project's rotation policy and revokes those whose grace period has ended.
+// Each newly overdue or revoked key is logged and recorded in its project's
+// audit log, so operators are told once per key. It is safe to run on every
+// replica: a key is only marked or revoked by one of them.
+func (s *Service) EnforceKeyRotation(ctx context.Context) (KeyRotationResult, error) {
+	ctx, span := svcTracer.Start(ctx, "service.EnforceKeyRotation")
+	defer span.End()
+
+	repo, ok := s.repo.(KeyRotationRepository)
+	if !ok {
+		return KeyRotationResult{}, errKeyRotationNotSupported
+	}
+
+	now := time.Now()
+	overdue, err := repo.MarkOverdueAPIKeys(ctx, now)
+	if err != nil {
+		return KeyRotationResult{}, fmt.Errorf("mark overdue api keys: %w", err)
+	}
+	for _, key := range overdue {
+		attrs := []any{"project_id", key.ProjectID, "api_key_id", key.ID, "rotate_by", key.RotateBy}
+		if key.RevokeAt != nil {
+			attrs = append(attrs, "revoke_at", key.RevokeAt)
+		}
+		s.log.WarnContext(ctx, "api key overdue for rotation", attrs...)
+		s.insertKeyAuditLog(ctx, key, auditActionAPIKeyRotationOverdue)
+	}
+
+	revoked, err := repo.RevokeOverdueAPIKeys(ctx, now)
+	if err != nil {
+		return KeyRotationResult{Overdue: overdue}, fmt.Errorf("revoke overdue api keys: %w", err)
+	}
+	for _, key := range revoked {
+		s.log.WarnContext(ctx, "api key revoked by rotation policy", "project_id", key.ProjectID, "api_key_id", key.ID)
+		s.insertKeyAuditLog(ctx, key, auditActionAPIKeyAutoRevoke)
+	}
+
+	return KeyRotationResult{Overdue: overdue, Revoked: revoked}, nil
+}
+
+// insertKeyAuditLog records a policy action on key. The key is named in the
+// details, like admin key actions, since the audit entry's API key ID is
+// reserved for the caller that performed the action.
+func (s *Service) insertKeyAuditLog(ctx context.Context, key repository.APIKeyMeta, action string) {
+	details, _ := json.Marshal(map[string]any{
+		"api_key_id": key.ID,
+		"rotate_by":  key.RotateBy,
+		"revoke_at":  key.RevokeAt,
+	})
+	requestID, _ := middleware.RequestIDFromContext(ctx)
+	bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bestEffortTimeout)
+	defer cancel()
+	_ = s.repo.InsertAuditLog(bgCtx, repository.AuditLogEntry{
+		ProjectID: key.ProjectID,
+		Action:    action,
+		Details:   details,
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// WithEvaluationCache remembers each evaluation result for ttl, so that
+// evaluating the same flag against an identical context again, as
+// server-side callers often do, skips the evaluator. Results are keyed by
+// project, flag and a hash of the context after enrichment, and are dropped
+// as soon as the flag, the project's segments or the whole cache change. At
+// most maxEntries results are kept; when that many are held the cache is
+// emptied and starts over. A ttl or maxEntries <= 0 leaves caching off,
+// which is the default.
+//
+// A result served from the cache still counts towards the flag's stats, but
+// the flag's shadow rules are only evaluated on misses.
+func WithEvaluationCache(ttl time.Duration, maxEntries int) Option {
+	return func(s *Service) {
+		if ttl <= 0 || maxEntries <= 0 {
+			s.memo = nil
+			return
+		}
+		s.memo = newEvaluationMemo(ttl, maxEntries)
+	}
+}
+
+// WithEvaluationCacheMetrics registers a callback invoked on every lookup in
+// the evaluation cache, with whether it was a hit. It has no effect unless
+// [WithEvaluationCache] enables the cache.
+func WithEvaluationCacheMetrics(onLookup func(hit bool)) Option {
+	return func(s *Service) {
+		s.onMemoLookup = onLookup
+	}
+}
+
+// evaluationMemo is the cache behind [WithEvaluationCache]:
+// map[projectID]map[flagKey]map[context hash]entry. An entry also holds the
+// encoded context, so a hash collision is a miss rather than a wrong answer.
+type evaluationMemo struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]map[string]map[uint64]memoEntry
+	size    int
+	// generation advances on every invalidation, so a result computed from
+	// a flag that changed meanwhile is not stored.
+	generation uint64
+}
+
+type memoEntry struct {
+	context    string
+	evaluation core.Evaluation
+	variants   json.RawMessage
+	expires    time.Time
+}
+
+// memoKey identifies one evaluation in an [evaluationMemo].
+type memoKey struct {
+	projectID  string
+	flagKey    string
+	context    string
+	hash       uint64
+	generation uint64
+}
+
+func newEvaluationMemo(ttl time.Duration, maxEntries int) *evaluationMemo {
+	return &evaluationMemo{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]map[string]map[uint64]memoEntry),
+	}
+}
+
+// lookup returns the remembered result of evaluating the flag against
+// evalContext, if there is one, and the key to store a new result under.
+// ok is false when the context cannot be encoded, in which case nothing is
+// cached.
+func (m *evaluationMemo) lookup(projectID, flagKey string, evalContext core.EvaluationContext) (entry memoEntry, hit bool, key memoKey, ok bool) {
+	// Maps encode with sorted keys, so equal contexts encode identically.
+	encoded, err := json.Marshal(evalContext.Attributes)
+	if err != nil {
+		return memoEntry{}, false, memoKey{}, false
+	}
+	key = memoKey{projectID: projectID, flagKey: flagKey, context: string(encoded), hash: xxhash.Sum64(encoded)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key.generation = m.generation
+	entry, found := m.entries[projectID][flagKey][key.hash]
+	if !found || entry.context != key.context || !m.now().Before(entry.expires) {
+		return memoEntry{}, false, key, true
+	}
+	return entry, true, key, true
+}
+
+// store remembers a result under key unless the cache was invalidated
+// since key was looked up.
+func (m *evaluationMemo) store(key memoKey, evaluation core.Evaluation, variants json.RawMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key.generation != m.generation {
+		return
+	}
+
+	if _, exists := m.entries[key.projectID][key.flagKey][key.hash]; !exists {
+		if m.size >= m.maxEntries {
+			m.resetLocked()
+		}
+		m.size++
+	}
+
+	byFlag, ok := m.entries[key.projectID]
+	if !ok {
+		byFlag = make(map[string]map[uint64]memoEntry)
+		m.entries[key.projectID] = byFlag
+	}
+	byHash, ok := byFlag[key.flagKey]
+	if !ok {
+		byHash = make(map[uint64]memoEntry)
+		byFlag[key.flagKey] = byHash
+	}
+	byHash[key.hash] = memoEntry{context: key.context, evaluation: evaluation, variants: variants, expires: m.now().Add(m.ttl)}
+}
+
+// invalidateFlag drops the results for one flag. Like the other
+// invalidations, it does nothing on a nil memo.
+func (m *evaluationMemo) invalidateFlag(projectID, flagKey string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	m.size -= len(m.entries[projectID][flagKey])
+	delete(m.entries[projectID], flagKey)
+}
+
+// invalidateProject drops the results for every flag of a project.
+func (m *evaluationMemo) invalidateProject(projectID string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	for _, byHash := range m.entries[projectID] {
+		m.size -= len(byHash)
+	}
+	delete(m.entries, projectID)
+}
+
+// reset drops every result.
+func (m *evaluationMemo) reset() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	m.resetLocked()
+}
+
+func (m *evaluationMemo) resetLocked() {
+	m.entries = make(map[string]map[string]map[uint64]memoEntry)
+	m.size = 0
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// Notification channel kinds.
+const (
+	NotificationKindSlack = "slack"
+	NotificationKindEmail = "email"
+	// NotificationKindWebhook channels receive signed JSON deliveries; see
+	// [Service.RotateWebhookSigningSecret].
+	NotificationKindWebhook = "webhook"
+)
+
+// Notification delivery statuses.
+const (
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+const (
+	// notificationQueueSize events may wait for delivery; further events are
+	// not announced until the queue drains.
+	notificationQueueSize = 256
+	notificationWorkers   = 4
+	// notificationAttempts is how often a delivery is tried, waiting
+	// defaultNotificationRetryDelay and then twice as long between tries.
+	notificationAttempts          = 3
+	defaultNotificationRetryDelay = 2 * time.Second
+	notificationTimeout           = 10 * time.Second
+	maxNotificationHistory        = 1000
+)
+
+var (
+	// ErrInvalidNotificationChannel is returned when a channel's kind,
+	// target or event types are not acceptable.
+	ErrInvalidNotificationChannel = errors.New("invalid notification channel")
+	// ErrNotificationChannelNotFound is returned when a requested
+	// notification channel does not exist.
+	ErrNotificationChannelNotFound = errors.New("notification channel not found")
+
+	errNotificationsNotSupported = errors.New("notifications not supported")
+)
+
+// NotificationRepository defines storage of notification channels and their
+// delivery history. It is optionally satisfied by
+// [repository.PostgresRepository].
+type NotificationRepository interface {
+	ListNotificationChannels(ctx context.Context, projectID string) ([]repository.NotificationChannel, error)
+	CreateNotificationChannel(ctx context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, projectID, id string) error
+	InsertNotification(ctx context.Context, notification repository.Notification) error
+	ListNotifications(ctx context.Context, projectID string, limit int) ([]repository.Notification, error)
+}
+
+// NotificationSender delivers a flag event to a channel in one attempt. It
+// is satisfied by notify.Router.
+type NotificationSender interface {
+	SendNotification(ctx context.Context, channel repository.NotificationChannel, event repository.FlagEvent) error
+}
+
+// WithNotificationSender announces every published flag event on the
+// project's notification channels through sender. Delivery happens in the
+// background after the event is stored, with retries, and each outcome is
+// recorded in the notification history. It has no effect unless the
+// repository implements [NotificationRepository]. A nil sender is a no-op.
+func WithNotificationSender(sender NotificationSender) Option {
+	return func(s *Service) {
+		s.notifier = sender
+	}
+}
+
+func (s *Service) notificationRepository() (NotificationRepository, error) {
+	repo, ok := s.repo.(NotificationRepository)
+	if !ok {
+		return nil, errNotificationsNotSupported
+	}
+	return repo, nil
+}
+
+// startNotifier starts the delivery workers if notifications are enabled.
+func (s *Service) startNotifier(ctx context.Context) {
+	repo, err := s.notificationRepository()
+	if s.notifier == nil || err != nil {
+		return
+	}
+	s.notifications = make(chan repository.FlagEvent, notificationQueueSize)
+	for range notificationWorkers {
+		go s.runNotifier(ctx, repo)
+	}
+}
+
+// enqueueNotification queues a stored event for delivery without waiting.
+func (s *Service) enqueueNotification(ctx context.Context, event repository.FlagEvent) {
+	if s.notifications == nil {
+		return
+	}
+	select {
+	case s.notifications <- event:
+	default:
+		s.log.WarnContext(ctx, "notification queue full, event not announced", "project_id", event.ProjectID, "event_id", event.EventID)
+	}
+}
+
+func (s *Service) runNotifier(ctx context.Context, repo NotificationRepository) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.notifications:
+			s.notify(ctx, repo, event)
+		}
+	}
+}
+
+// notify delivers event to each of its project's channels that wants it.
+func (s *Service) notify(ctx context.Context, repo NotificationRepository, event repository.FlagEvent) {
+	channels, err := repo.ListNotificationChannels(ctx, event.ProjectID)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Warn("list notification channels failed", "project_id", event.ProjectID, "error", err)
+		}
+		return
+	}
+
+	var signingSecrets []string
+	for _, channel := range channels {
+		if len(channel.EventTypes) > 0 && !slices.Contains(channel.EventTypes, event.EventType) {
+			continue
+		}
+		if channel.Kind == NotificationKindWebhook {
+			if signingSecrets == nil {
+				if signingSecrets, err = s.webhookSigningSecrets(ctx, event.ProjectID); err != nil && ctx.Err() == nil {
+					s.log.Warn("get webhook signing secret failed", "project_id", event.ProjectID, "error", err)
+				}
+			}
+			channel.SigningSecrets = signingSecrets
+		}
+		notification := s.deliverNotification(ctx, channel, event)
+		if ctx.Err() != nil {
+			return
+		}
+		if notification.Status == NotificationFailed {
+			s.log.Warn("notification failed", "project_id", event.ProjectID, "channel_id", channel.ID, "event_id", event.EventID, "error", notification.Error)
+		}
+
+		recordCtx, cancel := context.WithTimeout(ctx, bestEffortTimeout)
+		if err := repo.InsertNotification(recordCtx, notification); err != nil && ctx.Err() == nil {
+			s.log.Warn("record notification failed", "project_id", event.ProjectID, "channel_id", channel.ID, "error", err)
+		}
+		cancel()
+	}
+}
+
+// deliverNotification sends event to channel, retrying with backoff, and
+// returns the outcome.
+func (s *Service) deliverNotification(ctx context.Context, channel repository.NotificationChannel, event repository.FlagEvent) repository.Notification {
+	notification := repository.Notification{
+		ProjectID: event.ProjectID,
+		ChannelID: channel.ID,
+		EventID:   event.EventID,
+		FlagKey:   event.FlagKey,
+		EventType: event.EventType,
+		Status:    NotificationFailed,
+	}
+
+	delay := s.notificationRetryDelay
+	for attempt := 1; attempt <= notificationAttempts; attempt++ {
+		notification.Attempts = attempt
+		sendCtx, cancel := context.WithTimeout(ctx, notificationTimeout)
+		err := s.notifier.SendNotification(sendCtx, channel, event)
+		cancel()
+		if err == nil {
+			notification.Status, notification.Error = NotificationSent, ""
+			return notification
+		}
+		notification.Error = notificationError(channel, err)
+
+		if attempt == notificationAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return notification
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return notification
+}
+
+// ListNotificationChannels returns a project's notification channels,
+// oldest first. The targets of Slack and webhook channels are redacted to
+// their scheme and host, because their URLs often carry credentials; they
+// are only returned in full by [Service.CreateNotificationChannel].
+func (s *Service) ListNotificationChannels(ctx context.Context, projectID string) ([]repository.NotificationChannel, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	repo, err := s.notificationRepository()
+	if err != nil {
+		return nil, err
+	}
+	channels, err := repo.ListNotificationChannels(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list notification channels: %w", err)
+	}
+	for i := range channels {
+		channels[i].Target = redactNotificationTarget(channels[i])
+	}
+	return channels, nil
+}
+
+// CreateNotificationChannel validates and stores a notification channel.
+// Returns [ErrInvalidNotificationChannel] if the channel is not acceptable,
+// including a webhook channel for a project without a webhook signing
+// secret.
+func (s *Service) CreateNotificationChannel(ctx context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error) {
+	ctx, span := svcTracer.Start(ctx, "service.CreateNotificationChannel")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", channel.ProjectID))
+
+	if strings.TrimSpace(channel.ProjectID) == "" {
+		return repository.NotificationChannel{}, ErrProjectIDRequired
+	}
+	channel.Target = strings.TrimSpace(channel.Target)
+	if err := validateNotificationChannel(channel); err != nil {
+		return repository.NotificationChannel{}, err
+	}
+	repo, err := s.notificationRepository()
+	if err != nil {
+		return repository.NotificationChannel{}, err
+	}
+	if channel.Kind == NotificationKindWebhook {
+		if _, err := s.currentWebhookSigningSecret(ctx, channel.ProjectID); err != nil {
+			if errors.Is(err, ErrWebhookSigningSecretNotFound) {
+				return repository.NotificationChannel{}, fmt.Errorf("%w: create a webhook signing secret first", ErrInvalidNotificationChannel)
+			}
+			return repository.NotificationChannel{}, err
+		}
+	}
+
+	stored, err := repo.CreateNotificationChannel(ctx, channel)
+	if err != nil {
+		return repository.NotificationChannel{}, fmt.Errorf("create notification channel: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, channel.ProjectID, "create_notification_channel", "")
+	return stored, nil
+}
+
+// DeleteNotificationChannel removes a project's notification channel,
+// keeping its delivery history. Returns [ErrNotificationChannelNotFound] if
+// it does not exist.
+func (s *Service) DeleteNotificationChannel(ctx context.Context, projectID, id string) error {
+	if strings.TrimSpace(projectID) == "" {
+		return ErrProjectIDRequired
+	}
+	repo, err := s.notificationRepository()
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteNotificationChannel(ctx, projectID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotificationChannelNotFound
+		}
+		return fmt.Errorf("delete notification channel: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "delete_notification_channel", "")
+	return nil
+}
+
+// ListNotifications returns up to limit of a project's most recent
+// notification deliveries, newest first. limit is capped at 1000.
+func (s *Service) ListNotifications(ctx context.Context, projectID string, limit int) ([]repository.Notification, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	repo, err := s.notificationRepository()
+	if err != nil {
+		return nil, err
+	}
+	limit = min(max(limit, 1), maxNotificationHistory)
+	notifications, err := repo.ListNotifications(ctx, projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// redactNotificationTarget returns the target of channel with all but the
+// scheme and host of a Slack or webhook URL replaced by "…".
+func redactNotificationTarget(channel repository.NotificationChannel) string {
+	if channel.Kind != NotificationKindSlack && channel.Kind != NotificationKindWebhook {
+		return channel.Target
+	}
+	u, err := url.Parse(channel.Target)
+	if err != nil || u.Host == "" {
+		return "…"
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
+
+// notificationError returns the text of err, a failed delivery to channel,
+// as it is recorded: with the channel's target redacted, because errors such
+// as a refused connection quote the URL they were sending to.
+func notificationError(channel repository.NotificationChannel, err error) string {
+	msg := err.Error()
+	redacted := redactNotificationTarget(channel)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.URL != "" {
+		msg = strings.ReplaceAll(msg, urlErr.URL, redacted)
+	}
+	if channel.Target != "" {
+		msg = strings.ReplaceAll(msg, channel.Target, redacted)
+	}
+	return msg
+}
+
+func validateNotificationChannel(channel repository.NotificationChannel) error {
+	switch channel.Kind {
+	case NotificationKindSlack:
+		u, err := url.Parse(channel.Target)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: target must be an https Slack webhook URL", ErrInvalidNotificationChannel)
+		}
+	case NotificationKindEmail:
+		if _, err := mail.ParseAddress(channel.Target); err != nil {
+			return fmt.Errorf("%w: target must be an email address", ErrInvalidNotificationChannel)
+		}
+	case NotificationKindWebhook:
+		u, err := url.Parse(channel.Target)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: target must be an http or https URL", ErrInvalidNotificationChannel)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q, %q or %q", ErrInvalidNotificationChannel, NotificationKindSlack, NotificationKindEmail, NotificationKindWebhook)
+	}
+	for _, eventType := range channel.EventTypes {
+		if eventType != EventTypeUpdated && eventType != EventTypeDeleted {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidNotificationChannel, eventType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// defaultProjectRetention is how long a deleted project can be restored
+// before it is purged.
+const defaultProjectRetention = 30 * 24 * time.Hour
+
+var (
+	// ErrProjectNotDeleted is returned when restoring a project that is not
+	// deleted.
+	ErrProjectNotDeleted = errors.New("project is not deleted")
+	// ErrProjectRestoreExpired is returned when restoring a project deleted
+	// longer ago than the retention window.
+	ErrProjectRestoreExpired = errors.New("project retention window has expired")
+
+	errProjectLifecycleNotSupported = errors.New("project deletion not supported")
+)
+
+// ProjectLifecycleRepository defines soft-deletion, restore and purge of
+// projects. It is optionally satisfied by [repository.PostgresRepository].
+type ProjectLifecycleRepository interface {
+	GetProject(ctx context.Context, id string) (repository.Project, error)
+	DeleteProject(ctx context.Context, id string) error
+	RestoreProject(ctx context.Context, id string, deletedAfter time.Time) error
+	PurgeDeletedProjects(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// WithProjectRetention sets how long a deleted project can be restored before
+// [Service.PurgeDeletedProjects] removes it for good. Defaults to 30 days if
+// not set or if retention <= 0.
+func WithProjectRetention(retention time.Duration) Option {
+	return func(s *Service) {
+		if retention > 0 {
+			s.projectRetention = retention
+		}
+	}
+}
+
+// ProjectRetention returns how long a deleted project can be restored.
+func (s *Service) ProjectRetention() time.Duration {
+	return s.projectRetention
+}
+
+// DeleteProject soft-deletes a project. Its API keys are revoked, its
+// dashboard tokens deleted and its flags dropped from the cache, so they can
+// no longer be evaluated. Other replicas stop serving the flags on their next
+// cache resync; the revoked keys and tokens already lock clients out in the
+// meantime. Returns
+// [ErrProjectNotFound] if the project does not exist or is already deleted.
+func (s *Service) DeleteProject(ctx context.Context, projectID string) error {
+	ctx, span := svcTracer.Start(ctx, "service.DeleteProject")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(ProjectLifecycleRepository)
+	if !ok {
+		return errProjectLifecycleNotSupported
+	}
+
+	if err := repo.DeleteProject(ctx, projectID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delete project failed")
+		return fmt.Errorf("delete project: %w", err)
+	}
+
+	s.deleteCachedProject(projectID)
+	s.insertAuditLogBestEffort(ctx, projectID, "delete_project", "")
+	return nil
+}
+
+// RestoreProject undoes [Service.DeleteProject] while the project is within
+// the retention window and reloads the cache so its flags are served again.
+// API keys revoked and dashboard tokens deleted by the deletion stay gone;
+// new ones must be issued.
+// Returns [ErrProjectNotFound], [ErrProjectNotDeleted] or
+// [ErrProjectRestoreExpired] when the project cannot be restored.
+func (s *Service) RestoreProject(ctx context.Context, projectID string) error {
+	ctx, span := svcTracer.Start(ctx, "service.RestoreProject")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(ProjectLifecycleRepository)
+	if !ok {
+		return errProjectLifecycleNotSupported
+	}
+
+	project, err := repo.GetProject(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		return fmt.Errorf("get project: %w", err)
+	}
+	if project.DeletedAt == nil {
+		return ErrProjectNotDeleted
+	}
+
+	cutoff := time.Now().Add(-s.projectRetention)
+	if !project.DeletedAt.After(cutoff) {
+		return ErrProjectRestoreExpired
+	}
+
+	if err := repo.RestoreProject(ctx, projectID, cutoff); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Restored or purged concurrently.
+			return ErrProjectNotDeleted
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "restore project failed")
+		return fmt.Errorf("restore project: %w", err)
+	}
+
+	s.reloadCache(ctx)
+	s.insertAuditLogBestEffort(ctx, projectID, "restore_project", "")
+	return nil
+}
+
+// PurgeDeletedProjects permanently removes projects deleted longer ago than
+// the retention window and returns how many were removed.
+func (s *Service) PurgeDeletedProjects(ctx context.Context) (int64, error) {
+	repo, ok := s.repo.(ProjectLifecycleRepository)
+	if !ok {
+		return 0, errProjectLifecycleNotSupported
+	}
+
+	purged, err := repo.PurgeDeletedProjects(ctx, time.Now().Add(-s.projectRetention))
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted projects: %w", err)
+	}
+	return purged, nil
+}
+
+func (s *Service) deleteCachedProject(projectID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.cache[projectID] {
+		s.rulesIndex.remove(entry.flag)
+	}
+	delete(s.cache, projectID)
+	delete(s.segments, projectID)
+	s.memo.invalidateProject(projectID)
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+var (
+	// ErrProposalNotFound is returned when a requested flag proposal does not
+	// exist.
+	ErrProposalNotFound = errors.New("proposal not found")
+	// ErrProposalNotPending is returned when approving or rejecting a
+	// proposal that has already been reviewed.
+	ErrProposalNotPending = errors.New("proposal is not pending")
+	// ErrInvalidProposal is returned when a proposal has an unknown action or
+	// an update proposal carries no flag.
+	ErrInvalidProposal = errors.New("invalid proposal")
+	// ErrSelfApproval is returned when the proposer of a change tries to
+	// approve it. Approval requires a different API key or admin user.
+	ErrSelfApproval = errors.New("proposals must be approved by a different actor")
+	// ErrActorRequired is returned when a proposal is made or reviewed from
+	// a context with no API key or admin user to attribute it to.
+	ErrActorRequired = errors.New("an authenticated API key or admin user is required")
+
+	errProposalsNotSupported = errors.New("flag proposals not supported")
+)
+
+// ProposalRepository defines persistence for flag change proposals.
+// It is optionally satisfied by [repository.PostgresRepository].
+type ProposalRepository interface {
+	CreateFlagProposal(ctx context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error)
+	GetFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error)
+	ListFlagProposals(ctx context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error)
+	ReviewFlagProposal(ctx context.Context, projectID, id, status, reviewerAPIKeyID, reviewerAdminUserID string) (repository.FlagProposal, error)
+	ReopenFlagProposal(ctx context.Context, projectID, id string) error
+}
+
+// ProposeFlagChange records a pending update or delete of an existing flag.
+// For updates, proposal.Flag must hold a valid flag body; its key and project
+// are forced to match the proposal. The proposer is taken from ctx. Nothing
+// changes until another actor calls [Service.ApproveFlagProposal].
+func (s *Service) ProposeFlagChange(ctx context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ProposeFlagChange")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", proposal.FlagKey),
+		attribute.String("project_id", proposal.ProjectID),
+	)
+
+	if strings.TrimSpace(proposal.FlagKey) == "" {
+		return repository.FlagProposal{}, ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(proposal.ProjectID) == "" {
+		return repository.FlagProposal{}, ErrProjectIDRequired
+	}
+
+	apiKeyID, adminUserID := actorFromContext(ctx)
+	if apiKeyID == "" && adminUserID == "" {
+		return repository.FlagProposal{}, ErrActorRequired
+	}
+	proposal.ProposedByAPIKeyID = apiKeyID
+	proposal.ProposedByAdminUserID = adminUserID
+
+	switch proposal.Action {
+	case repository.ProposalActionUpdate:
+		flag, err := proposedFlag(proposal)
+		if err != nil {
+			return repository.FlagProposal{}, err
+		}
+		if err := validateFlag(flag); err != nil {
+			return repository.FlagProposal{}, err
+		}
+	case repository.ProposalActionDelete:
+		proposal.Flag = nil
+	default:
+		return repository.FlagProposal{}, fmt.Errorf("%w: unknown action %q", ErrInvalidProposal, proposal.Action)
+	}
+
+	repo, ok := s.repo.(ProposalRepository)
+	if !ok {
+		return repository.FlagProposal{}, errProposalsNotSupported
+	}
+
+	if _, err := s.GetFlag(ctx, proposal.ProjectID, proposal.FlagKey); err != nil {
+		return repository.FlagProposal{}, err
+	}
+
+	created, err := repo.CreateFlagProposal(ctx, proposal)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create proposal failed")
+		return repository.FlagProposal{}, fmt.Errorf("create proposal: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, created.ProjectID, "propose_"+created.Action, created.FlagKey)
+	return created, nil
+}
+
+// ListFlagProposals returns a project's proposals, newest first, optionally
+// narrowed to a flag key and status.
+func (s *Service) ListFlagProposals(ctx context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+
+	repo, ok := s.repo.(ProposalRepository)
+	if !ok {
+		return nil, errProposalsNotSupported
+	}
+
+	proposals, err := repo.ListFlagProposals(ctx, projectID, flagKey, status)
+	if err != nil {
+		return nil, fmt.Errorf("list proposals: %w", err)
+	}
+	return proposals, nil
+}
+
+// ApproveFlagProposal approves a pending proposal and applies it through
+// [Service.UpdateFlag] or [Service.DeleteFlag], so caches, events and audit
+// entries behave exactly as for a direct change. The approver, taken from
+// ctx, must differ from the proposer. If the change cannot be applied the
+// proposal is returned to pending and the error is returned.
+func (s *Service) ApproveFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ApproveFlagProposal")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("project_id", projectID),
+		attribute.String("proposal_id", id),
+	)
+
+	repo, proposal, err := s.reviewableProposal(ctx, projectID, id)
+	if err != nil {
+		return repository.FlagProposal{}, err
+	}
+	if isProposer(ctx, proposal) {
+		return repository.FlagProposal{}, ErrSelfApproval
+	}
+
+	approved, err := s.reviewProposal(ctx, repo, proposal, repository.ProposalStatusApproved)
+	if err != nil {
+		return repository.FlagProposal{}, err
+	}
+
+	var applyErr error
+	switch approved.Action {
+	case repository.ProposalActionUpdate:
+		var flag repository.Flag
+		if flag, applyErr = proposedFlag(approved); applyErr == nil {
+			_, applyErr = s.UpdateFlag(ctx, flag)
+		}
+	case repository.ProposalActionDelete:
+		applyErr = s.DeleteFlag(ctx, approved.ProjectID, approved.FlagKey)
+	default:
+		applyErr = fmt.Errorf("%w: unknown action %q", ErrInvalidProposal, approved.Action)
+	}
+	if applyErr != nil {
+		reopenCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bestEffortTimeout)
+		defer cancel()
+		if err := repo.ReopenFlagProposal(reopenCtx, approved.ProjectID, approved.ID); err != nil {
+			s.log.WarnContext(ctx, "reopen flag proposal failed", "proposal_id", approved.ID, "error", err)
+		}
+		span.RecordError(applyErr)
+		span.SetStatus(codes.Error, "apply proposal failed")
+		return repository.FlagProposal{}, applyErr
+	}
+
+	s.insertAuditLogBestEffort(ctx, approved.ProjectID, "approve_"+approved.Action, approved.FlagKey)
+	return approved, nil
+}
+
+// RejectFlagProposal closes a pending proposal without applying it. Any
+// actor, including the proposer, may reject a proposal.
+func (s *Service) RejectFlagProposal(ctx context.Context, projectID, id string) (repository.FlagProposal, error) {
+	ctx, span := svcTracer.Start(ctx, "service.RejectFlagProposal")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("project_id", projectID),
+		attribute.String("proposal_id", id),
+	)
+
+	repo, proposal, err := s.reviewableProposal(ctx, projectID, id)
+	if err != nil {
+		return repository.FlagProposal{}, err
+	}
+
+	rejected, err := s.reviewProposal(ctx, repo, proposal, repository.ProposalStatusRejected)
+	if err != nil {
+		return repository.FlagProposal{}, err
+	}
+
+	s.insertAuditLogBestEffort(ctx, rejected.ProjectID, "reject_"+rejected.Action, rejected.FlagKey)
+	return rejected, nil
+}
+
+// reviewableProposal loads a proposal for review and checks that it is
+// pending and that ctx identifies a reviewer.
+func (s *Service) reviewableProposal(ctx context.Context, projectID, id string) (ProposalRepository, repository.FlagProposal, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, repository.FlagProposal{}, ErrProjectIDRequired
+	}
+	if apiKeyID, adminUserID := actorFromContext(ctx); apiKeyID == "" && adminUserID == "" {
+		return nil, repository.FlagProposal{}, ErrActorRequired
+	}
+
+	repo, ok := s.repo.(ProposalRepository)
+	if !ok {
+		return nil, repository.FlagProposal{}, errProposalsNotSupported
+	}
+
+	proposal, err := repo.GetFlagProposal(ctx, projectID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.FlagProposal{}, ErrProposalNotFound
+		}
+		return nil, repository.FlagProposal{}, fmt.Errorf("get proposal: %w", err)
+	}
+	if proposal.Status != repository.ProposalStatusPending {
+		return nil, repository.FlagProposal{}, ErrProposalNotPending
+	}
+	return repo, proposal, nil
+}
+
+func (s *Service) reviewProposal(ctx context.Context, repo ProposalRepository, proposal repository.FlagProposal, status string) (repository.FlagProposal, error) {
+	apiKeyID, adminUserID := actorFromContext(ctx)
+	reviewed, err := repo.ReviewFlagProposal(ctx, proposal.ProjectID, proposal.ID, status, apiKeyID, adminUserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Another reviewer got there first.
+			return repository.FlagProposal{}, ErrProposalNotPending
+		}
+		return repository.FlagProposal{}, fmt.Errorf("review proposal: %w", err)
+	}
+	return reviewed, nil
+}
+
+// proposedFlag decodes the flag body of an update proposal, forcing its key
+// and project to those of the proposal.
+func proposedFlag(proposal repository.FlagProposal) (repository.Flag, error) {
+	if len(proposal.Flag) == 0 || string(proposal.Flag) == "null" {
+		return repository.Flag{}, fmt.Errorf("%w: update proposals require a flag", ErrInvalidProposal)
+	}
+
+	var flag repository.Flag
+	if err := json.Unmarshal(proposal.Flag, &flag); err != nil {
+		return repository.Flag{}, fmt.Errorf("%w: %v", ErrInvalidProposal, err)
+	}
+	if flag.Key != "" && flag.Key != proposal.FlagKey {
+		return repository.Flag{}, fmt.Errorf("%w: flag key must match the proposal", ErrInvalidProposal)
+	}
+	flag.Key = proposal.FlagKey
+	flag.ProjectID = proposal.ProjectID
+	return flag, nil
+}
+
+func actorFromContext(ctx context.Context) (apiKeyID, adminUserID string) {
+	actor := middleware.ActorFromContext(ctx)
+	return actor.APIKeyID, actor.AdminUserID
+}
+
+func isProposer(ctx context.Context, proposal repository.FlagProposal) bool {
+	apiKeyID, adminUserID := actorFromContext(ctx)
+	return (apiKeyID != "" && apiKeyID == proposal.ProposedByAPIKeyID) ||
+		(adminUserID != "" && adminUserID == proposal.ProposedByAdminUserID)
+}
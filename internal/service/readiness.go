@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultWarmupTimeout bounds how long [Service.Ready] waits for the cache
+// invalidation subscription before reporting ready anyway.
+const defaultWarmupTimeout = 30 * time.Second
+
+// readinessPingTimeout bounds the database ping made by [Service.Readiness].
+const readinessPingTimeout = 2 * time.Second
+
+// Readiness components reported by [Service.Readiness].
+const (
+	ComponentCache        = "cache"
+	ComponentDatabase     = "database"
+	ComponentInvalidation = "invalidation"
+)
+
+// Statuses of a [ComponentStatus].
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+var (
+	// ErrCacheNotLoaded is reported by [Service.Ready] before the initial
+	// flag cache load has completed.
+	ErrCacheNotLoaded = errors.New("flag cache not loaded")
+	// ErrWarmingUp is reported by [Service.Ready] while the service is still
+	// waiting for its first cache invalidation subscription.
+	ErrWarmingUp = errors.New("waiting for cache invalidation subscription")
+	// ErrDatabaseUnavailable is reported by [Service.Ready] until the
+	// repository has answered a ping.
+	ErrDatabaseUnavailable = errors.New("database unavailable")
+	// ErrShuttingDown is reported by [Service.Ready] once
+	// [Service.BeginShutdown] has been called.
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// listenStatusReporter is optionally implemented by invalidation subscribers
+// whose subscription connects asynchronously.
+type listenStatusReporter interface {
+	Listening() bool
+}
+
+// pinger is optionally implemented by repositories that can verify their
+// database connection, such as [repository.PostgresRepository].
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ComponentStatus is the state of one dependency checked by
+// [Service.Readiness].
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Readiness is the result of [Service.Readiness]. Err is nil when the
+// service is ready; Components holds the current state of every dependency
+// the service has, keyed by [ComponentCache], [ComponentDatabase] and
+// [ComponentInvalidation].
+type Readiness struct {
+	Err        error
+	Components map[string]ComponentStatus
+}
+
+// WithWarmupTimeout sets how long [Service.Ready] waits for the cache
+// invalidation subscription to be established before reporting ready
+// regardless, so a replica is never held out of rotation indefinitely by a
+// broken LISTEN connection. A timeout of zero stops it waiting for the
+// subscription at all. Defaults to 30 seconds; negative values are ignored.
+func WithWarmupTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		if timeout >= 0 {
+			s.warmupTimeout = timeout
+		}
+	}
+}
+
+// Ready reports whether the service should receive evaluation traffic. It
+// is [Service.Readiness] without the component statuses.
+func (s *Service) Ready(ctx context.Context) error {
+	return s.Readiness(ctx).Err
+}
+
+// Readiness checks the flag cache, the database connection and the
+// invalidation subscription. Until all three have been ready at once, Err
+// is [ErrCacheNotLoaded], [ErrDatabaseUnavailable] or [ErrWarmingUp]; the
+// subscription stops being waited for once the warm-up timeout elapses.
+// Once ready, the service stays ready, since later database or subscription
+// drops are survived by serving from the cache and by the periodic resync,
+// until [Service.BeginShutdown] makes Err [ErrShuttingDown]. Components
+// always reflect the current state.
+func (s *Service) Readiness(ctx context.Context) Readiness {
+	components := make(map[string]ComponentStatus, 3)
+	var gate error
+	fail := func(component string, err error) {
+		components[component] = ComponentStatus{Status: StatusNotReady, Reason: err.Error()}
+	}
+
+	components[ComponentCache] = ComponentStatus{Status: StatusReady}
+	if !s.cacheLoaded.Load() {
+		fail(ComponentCache, ErrCacheNotLoaded)
+		gate = ErrCacheNotLoaded
+	}
+
+	if p, ok := s.repo.(pinger); ok {
+		components[ComponentDatabase] = ComponentStatus{Status: StatusReady}
+		pingCtx, cancel := context.WithTimeout(ctx, readinessPingTimeout)
+		err := p.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			fail(ComponentDatabase, err)
+			if gate == nil {
+				gate = ErrDatabaseUnavailable
+			}
+		}
+	}
+
+	if reporter, ok := s.subscriber.(listenStatusReporter); ok {
+		components[ComponentInvalidation] = ComponentStatus{Status: StatusReady}
+		if !reporter.Listening() {
+			fail(ComponentInvalidation, ErrWarmingUp)
+			if gate == nil && time.Since(s.startedAt) < s.warmupTimeout {
+				gate = ErrWarmingUp
+			}
+		}
+	}
+
+	switch {
+	case s.draining.Load():
+		return Readiness{Err: ErrShuttingDown, Components: components}
+	case s.ready.Load():
+		return Readiness{Components: components}
+	case gate != nil:
+		return Readiness{Err: gate, Components: components}
+	}
+	s.ready.Store(true)
+	return Readiness{Components: components}
+}
+
+// BeginShutdown makes the service report [ErrShuttingDown] from then on, so
+// load balancers stop routing to it before its listeners are closed.
+func (s *Service) BeginShutdown() {
+	s.draining.Store(true)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// rulesIndex is an inverted index from evaluation-context attribute names to
+// the keys of flags whose rules or targets reference them, partitioned by
+// project: map[projectID]map[attribute]set[flagKey]. It is maintained
+// alongside the flag cache and guarded by the same [Service.mu].
+type rulesIndex map[string]map[string]map[string]struct{}
+
+// add indexes every attribute referenced by flag's rules and targets. Flags
+// whose rules fail to parse are skipped; they cannot be evaluated either.
+func (idx rulesIndex) add(flag repository.Flag) {
+	for _, attr := range ruleAttributes(flag) {
+		byAttr, ok := idx[flag.ProjectID]
+		if !ok {
+			byAttr = make(map[string]map[string]struct{})
+			idx[flag.ProjectID] = byAttr
+		}
+		keys, ok := byAttr[attr]
+		if !ok {
+			keys = make(map[string]struct{})
+			byAttr[attr] = keys
+		}
+		keys[flag.Key] = struct{}{}
+	}
+}
+
+// remove drops flag from every attribute its rules reference, pruning empty
+// entries so the index does not grow without bound as rules change.
+func (idx rulesIndex) remove(flag repository.Flag) {
+	byAttr, ok := idx[flag.ProjectID]
+	if !ok {
+		return
+	}
+	for _, attr := range ruleAttributes(flag) {
+		keys, ok := byAttr[attr]
+		if !ok {
+			continue
+		}
+		delete(keys, flag.Key)
+		if len(keys) == 0 {
+			delete(byAttr, attr)
+		}
+	}
+	if len(byAttr) == 0 {
+		delete(idx, flag.ProjectID)
+	}
+}
+
+func ruleAttributes(flag repository.Flag) []string {
+	rules, err := parseRulesJSON(flag.Rules)
+	if err != nil {
+		return nil
+	}
+	attrs := make([]string, 0, len(rules)+1)
+	for _, rule := range rules {
+		if rule.Operator == core.OperatorSegment {
+			continue
+		}
+		attrs = append(attrs, rule.Attribute)
+	}
+	if flag.Targets.Attribute != "" {
+		attrs = append(attrs, flag.Targets.Attribute)
+	}
+	return attrs
+}
+
+// ListFlagsReferencingAttribute returns the flags in a project whose rules
+// or targets reference the named context attribute, sorted by key. It is served from the
+// in-memory index and is intended for impact analysis before changing the
+// shape of evaluation contexts.
+func (s *Service) ListFlagsReferencingAttribute(ctx context.Context, projectID, attr string) ([]repository.Flag, error) {
+	_, span := svcTracer.Start(ctx, "service.ListFlagsReferencingAttribute")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("project_id", projectID),
+		attribute.String("attribute", attr),
+	)
+
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+
+	s.mu.RLock()
+	keys := s.rulesIndex[projectID][attr]
+	flags := make([]repository.Flag, 0, len(keys))
+	for key := range keys {
+		if entry, ok := s.cache[projectID][key]; ok {
+			flags = append(flags, entry.flag)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Key < flags[j].Key
+	})
+
+	return flags, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+var errRulesetNotSupported = errors.New("ruleset not supported")
+
+// EventGenerationRepository reports how far a project's flag event log has
+// advanced. It is optionally satisfied by [repository.PostgresRepository].
+type EventGenerationRepository interface {
+	LatestEventID(ctx context.Context, projectID string) (int64, error)
+}
+
+// Ruleset is everything an SDK needs to evaluate a project's flags locally,
+// with the same semantics as [core.EvaluateFlagDetail].
+type Ruleset struct {
+	ProjectID string `json:"project_id"`
+	// Generation is the ID of the project's newest flag event. Every flag
+	// change records an event, so a higher generation means a newer ruleset.
+	Generation int64         `json:"generation"`
+	Flags      []RulesetFlag `json:"flags"`
+	// Segments are the segments flag rules may reference, sorted by name.
+	// Their percentage rules bucket as described on [core.Segment].
+	Segments []core.Segment `json:"segments,omitempty"`
+}
+
+// RulesetFlag is the evaluable form of a flag. Default is the boolean
+// "default" variant, if any, which is returned when no target or rule
+// matches; percentage rules hash the targeting key with BucketingSalt.
+type RulesetFlag struct {
+	Key           string          `json:"key"`
+	Enabled       bool            `json:"enabled"`
+	Default       *bool           `json:"default,omitempty"`
+	Variants      json.RawMessage `json:"variants,omitempty"`
+	Rules         []core.Rule     `json:"rules,omitempty"`
+	Targets       core.Targets    `json:"targets,omitzero"`
+	BucketingSalt string          `json:"bucketing_salt,omitempty"`
+}
+
+// Ruleset returns a project's flags, sorted by key, in the form served to
+// SDKs that evaluate locally. The generation is read before the flags, so a
+// ruleset is never labelled newer than the flags it contains.
+func (s *Service) Ruleset(ctx context.Context, projectID string) (Ruleset, error) {
+	ctx, span := svcTracer.Start(ctx, "service.Ruleset")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return Ruleset{}, ErrProjectIDRequired
+	}
+	repo, ok := s.repo.(EventGenerationRepository)
+	if !ok {
+		return Ruleset{}, errRulesetNotSupported
+	}
+
+	generation, err := repo.LatestEventID(ctx, projectID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "latest event id failed")
+		return Ruleset{}, fmt.Errorf("ruleset generation: %w", err)
+	}
+	entries := s.cachedFlagEntries(projectID)
+
+	ruleset := Ruleset{
+		ProjectID:  projectID,
+		Generation: generation,
+		Flags:      make([]RulesetFlag, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		flag, coreFlag := entry.flag, entry.core
+		if entry.err != nil {
+			return Ruleset{}, fmt.Errorf("ruleset flag %q: %w", flag.Key, entry.err)
+		}
+		rulesetFlag := RulesetFlag{
+			Key:           flag.Key,
+			Enabled:       flag.Enabled,
+			Default:       coreFlag.DefaultValue,
+			Rules:         coreFlag.Rules,
+			BucketingSalt: coreFlag.BucketingSalt,
+		}
+		if !flag.Targets.IsZero() {
+			rulesetFlag.Targets = coreFlag.Targets
+		}
+		if variants := bytes.TrimSpace(flag.Variants); len(variants) > 0 && string(variants) != "null" {
+			rulesetFlag.Variants = variants
+		}
+		ruleset.Flags = append(ruleset.Flags, rulesetFlag)
+	}
+	segments := s.cachedSegments(projectID).core
+	for _, name := range slices.Sorted(maps.Keys(segments)) {
+		ruleset.Segments = append(ruleset.Segments, segments[name])
+	}
+	span.SetAttributes(attribute.Int64("generation", generation), attribute.Int("flag_count", len(ruleset.Flags)))
+
+	return ruleset, nil
+}
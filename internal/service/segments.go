@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/core"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const maxSegmentNameLength = 100
+
+var (
+	// ErrSegmentNotFound is returned when a requested segment does not
+	// exist.
+	ErrSegmentNotFound = errors.New("segment not found")
+	// ErrInvalidSegmentName is returned when a segment name is blank, too
+	// long, or contains characters other than letters, digits, '-', '_'
+	// and '.'.
+	ErrInvalidSegmentName = errors.New("invalid segment name")
+	// ErrInvalidSegment is returned when a segment's references to other
+	// segments are unknown, cyclic or nested too deeply.
+	ErrInvalidSegment = errors.New("invalid segment")
+	// ErrSegmentInUse is returned when deleting a segment that a flag or
+	// another segment still references.
+	ErrSegmentInUse = errors.New("segment in use")
+)
+
+var errSegmentsNotSupported = errors.New("segments not supported")
+
+// SegmentRepository defines storage of named, reusable rule sets. It is
+// optionally satisfied by [repository.PostgresRepository].
+type SegmentRepository interface {
+	ListAllSegments(ctx context.Context) ([]repository.Segment, error)
+	PutSegment(ctx context.Context, segment repository.Segment) (repository.Segment, error)
+	DeleteSegment(ctx context.Context, projectID, name string) error
+}
+
+// projectSegments is one project's cached segments, both as stored and in
+// the form the evaluator uses. It is replaced, never modified, so
+// evaluations can keep using it without holding [Service.mu].
+type projectSegments struct {
+	stored map[string]repository.Segment
+	core   map[string]core.Segment
+}
+
+func newProjectSegments(segments map[string]repository.Segment) projectSegments {
+	cached := projectSegments{stored: segments, core: make(map[string]core.Segment, len(segments))}
+	for name, segment := range segments {
+		cached.core[name] = core.Segment{Name: name, Rules: segment.Rules}
+	}
+	return cached
+}
+
+func (s *Service) segmentRepository() (SegmentRepository, error) {
+	repo, ok := s.repo.(SegmentRepository)
+	if !ok {
+		return nil, errSegmentsNotSupported
+	}
+	return repo, nil
+}
+
+// loadSegments reads every project's segments for the cache, or none if
+// the repository does not store segments.
+func (s *Service) loadSegments(ctx context.Context) (map[string]projectSegments, error) {
+	next := make(map[string]projectSegments)
+	repo, err := s.segmentRepository()
+	if err != nil {
+		return next, nil
+	}
+	segments, err := repo.ListAllSegments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load segments: %w", err)
+	}
+
+	byProject := make(map[string]map[string]repository.Segment)
+	for _, segment := range segments {
+		if _, ok := byProject[segment.ProjectID]; !ok {
+			byProject[segment.ProjectID] = make(map[string]repository.Segment)
+		}
+		byProject[segment.ProjectID][segment.Name] = segment
+	}
+	for projectID, stored := range byProject {
+		next[projectID] = newProjectSegments(stored)
+	}
+	return next, nil
+}
+
+// cachedSegments returns a project's cached segments, which must not be
+// modified.
+func (s *Service) cachedSegments(projectID string) projectSegments {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.segments[projectID]
+}
+
+// setCachedSegments replaces a project's cached segments.
+func (s *Service) setCachedSegments(projectID string, stored map[string]repository.Segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memo.invalidateProject(projectID)
+	if len(stored) == 0 {
+		delete(s.segments, projectID)
+		return
+	}
+	s.segments[projectID] = newProjectSegments(stored)
+}
+
+// ListSegments returns a project's segments, ordered by name.
+func (s *Service) ListSegments(ctx context.Context, projectID string) ([]repository.Segment, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	if _, err := s.segmentRepository(); err != nil {
+		return nil, err
+	}
+
+	stored := s.cachedSegments(projectID).stored
+	segments := make([]repository.Segment, 0, len(stored))
+	for _, name := range slices.Sorted(maps.Keys(stored)) {
+		segments = append(segments, stored[name])
+	}
+	return segments, nil
+}
+
+// GetSegment returns a project's segment by name. Returns
+// [ErrSegmentNotFound] if it does not exist.
+func (s *Service) GetSegment(ctx context.Context, projectID, name string) (repository.Segment, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.Segment{}, ErrProjectIDRequired
+	}
+	if _, err := s.segmentRepository(); err != nil {
+		return repository.Segment{}, err
+	}
+
+	segment, ok := s.cachedSegments(projectID).stored[name]
+	if !ok {
+		return repository.Segment{}, ErrSegmentNotFound
+	}
+	return segment, nil
+}
+
+// PutSegment creates a segment or replaces the one with the same name.
+// Returns [ErrInvalidSegmentName] if the name is not acceptable, a
+// [*RulesError] if a rule is invalid, and [ErrInvalidSegment] if the
+// segments it references are unknown, cyclic or nested deeper than
+// [core.MaxSegmentDepth].
+func (s *Service) PutSegment(ctx context.Context, segment repository.Segment) (repository.Segment, error) {
+	ctx, span := svcTracer.Start(ctx, "service.PutSegment")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", segment.ProjectID))
+
+	if strings.TrimSpace(segment.ProjectID) == "" {
+		return repository.Segment{}, ErrProjectIDRequired
+	}
+	if !validSegmentName(segment.Name) {
+		return repository.Segment{}, ErrInvalidSegmentName
+	}
+	if segment.Rules == nil {
+		segment.Rules = make([]core.Rule, 0)
+	}
+	if ruleErrs := core.ValidateRules(segment.Rules); len(ruleErrs) > 0 {
+		return repository.Segment{}, &RulesError{Rules: ruleErrs}
+	}
+	repo, err := s.segmentRepository()
+	if err != nil {
+		return repository.Segment{}, err
+	}
+
+	next := maps.Clone(s.cachedSegments(segment.ProjectID).core)
+	if next == nil {
+		next = make(map[string]core.Segment)
+	}
+	next[segment.Name] = core.Segment{Name: segment.Name, Rules: segment.Rules}
+	if err := core.ValidateSegments(next); err != nil {
+		return repository.Segment{}, fmt.Errorf("%w: %v", ErrInvalidSegment, err)
+	}
+
+	stored, err := repo.PutSegment(ctx, segment)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "put segment failed")
+		return repository.Segment{}, fmt.Errorf("put segment: %w", err)
+	}
+
+	segments := maps.Clone(s.cachedSegments(segment.ProjectID).stored)
+	if segments == nil {
+		segments = make(map[string]repository.Segment)
+	}
+	segments[stored.Name] = stored
+	s.setCachedSegments(segment.ProjectID, segments)
+
+	s.insertAuditLogBestEffort(ctx, segment.ProjectID, "put_segment", "")
+	return stored, nil
+}
+
+// DeleteSegment removes a project's segment. Returns [ErrSegmentNotFound]
+// if it does not exist and [ErrSegmentInUse] if a flag's rules or shadow
+// rules, or another segment, still reference it.
+func (s *Service) DeleteSegment(ctx context.Context, projectID, name string) error {
+	if strings.TrimSpace(projectID) == "" {
+		return ErrProjectIDRequired
+	}
+	repo, err := s.segmentRepository()
+	if err != nil {
+		return err
+	}
+	cached := s.cachedSegments(projectID)
+	if _, ok := cached.stored[name]; !ok {
+		return ErrSegmentNotFound
+	}
+	if err := s.checkSegmentUnused(ctx, projectID, name, cached); err != nil {
+		return err
+	}
+
+	if err := repo.DeleteSegment(ctx, projectID, name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSegmentNotFound
+		}
+		return fmt.Errorf("delete segment: %w", err)
+	}
+
+	segments := maps.Clone(cached.stored)
+	delete(segments, name)
+	s.setCachedSegments(projectID, segments)
+
+	s.insertAuditLogBestEffort(ctx, projectID, "delete_segment", "")
+	return nil
+}
+
+// checkSegmentUnused returns [ErrSegmentInUse] if a cached flag or another
+// of the project's segments references the named segment.
+func (s *Service) checkSegmentUnused(ctx context.Context, projectID, name string, cached projectSegments) error {
+	for _, other := range slices.Sorted(maps.Keys(cached.core)) {
+		if other != name && slices.Contains(segmentReferences(cached.core[other].Rules), name) {
+			return fmt.Errorf("%w: referenced by segment %q", ErrSegmentInUse, other)
+		}
+	}
+
+	flags, err := s.ListFlags(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	for _, flag := range flags {
+		for _, payload := range []json.RawMessage{flag.Rules, flag.ShadowRules} {
+			rules, err := parseRulesJSON(normalizeShadowRules(payload))
+			if err != nil {
+				continue
+			}
+			if slices.Contains(segmentReferences(rules), name) {
+				return fmt.Errorf("%w: referenced by flag %q", ErrSegmentInUse, flag.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSegmentReferences returns a [*RulesError] for each rule or shadow
+// rule of flag that references a segment its project does not have. It
+// expects rules that already passed [validateFlag].
+func (s *Service) checkSegmentReferences(flag repository.Flag) error {
+	segments := s.cachedSegments(flag.ProjectID).core
+	for _, field := range []struct {
+		name    string
+		payload json.RawMessage
+	}{
+		{"", flag.Rules},
+		{"shadow_rules", normalizeShadowRules(flag.ShadowRules)},
+	} {
+		rules, err := parseRulesJSON(field.payload)
+		if err != nil {
+			return err
+		}
+		var ruleErrs []core.RuleError
+		for i, rule := range rules {
+			if name, ok := core.SegmentName(rule); ok {
+				if _, exists := segments[name]; !exists {
+					ruleErrs = append(ruleErrs, core.RuleError{Index: i, Reason: fmt.Sprintf("unknown segment %q", name)})
+				}
+			}
+		}
+		if len(ruleErrs) > 0 {
+			return &RulesError{Field: field.name, Rules: ruleErrs}
+		}
+	}
+	return nil
+}
+
+// segmentReferences returns the names of the segments rules reference.
+func segmentReferences(rules []core.Rule) []string {
+	var names []string
+	for _, rule := range rules {
+		if name, ok := core.SegmentName(rule); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func validSegmentName(name string) bool {
+	if name == "" || len(name) > maxSegmentNameLength {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -12,15 +12,18 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/middleware"
@@ -42,7 +45,8 @@ const (
 var (
 	// ErrFlagNotFound is returned when a requested flag does not exist.
 	ErrFlagNotFound = errors.New("flag not found")
-	// ErrInvalidRules is returned when flag rules JSON is malformed.
+	// ErrInvalidRules is returned when flag rules JSON is malformed or a rule
+	// fails validation, in which case the error is a [*RulesError].
 	ErrInvalidRules = errors.New("invalid rules")
 	// ErrInvalidVariants is returned when flag variants JSON is malformed.
 	ErrInvalidVariants = errors.New("invalid variants")
@@ -54,8 +58,13 @@ var (
 	ErrAPIKeyNotFound = errors.New("api key not found")
 	// ErrAPIKeyIDRequired is returned when an API key ID is empty or blank.
 	ErrAPIKeyIDRequired = errors.New("api key ID is required")
+	// ErrFlagExists is returned for a flag in a batch whose key is already in
+	// use, either by an existing flag or by an earlier flag in the batch.
+	ErrFlagExists = errors.New("flag already exists")
 
 	errAPIKeyManagementNotSupported = errors.New("api key management not supported")
+	errBatchCreateNotSupported      = errors.New("batch flag creation not supported")
+	errReshuffleNotSupported        = errors.New("bucketing salt rotation not supported")
 )
 
 // Repository defines the persistence operations required by [Service].
@@ -81,23 +90,75 @@ type APIKeyRepository interface {
 	DeleteAPIKey(ctx context.Context, projectID, keyID string) error
 }
 
-type cacheInvalidationSubscriber interface {
-	SubscribeFlagInvalidation(ctx context.Context) (<-chan struct{}, error)
+// FilteredEventRepository defines listing the flag events that pass an
+// [repository.EventFilter]. It is optionally satisfied by
+// [repository.PostgresRepository].
+type FilteredEventRepository interface {
+	ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, error)
+}
+
+// BatchFlagRepository defines transactional multi-flag creation.
+// It is optionally satisfied by [repository.PostgresRepository].
+type BatchFlagRepository interface {
+	CreateFlags(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error)
+}
+
+// BucketingRepository defines rotation of a flag's percentage-rollout salt.
+// It is optionally satisfied by [repository.PostgresRepository].
+type BucketingRepository interface {
+	RotateBucketingSalt(ctx context.Context, projectID, key string) (repository.Flag, error)
+}
+
+// InvalidationSubscriber delivers the flags changed on another replica. A
+// [repository.FlagInvalidation] naming a flag refreshes just that flag in the
+// cache; any other prompts a full reload. [repository.PostgresRepository]
+// implements it with LISTEN/NOTIFY and is used unless another transport is
+// configured with [WithInvalidationTransport].
+type InvalidationSubscriber interface {
+	SubscribeFlagInvalidation(ctx context.Context) (<-chan repository.FlagInvalidation, error)
+}
+
+// invalidationPublisher is implemented by invalidation transports that need
+// writers to announce changes explicitly, rather than relying on the
+// repository's own notifications.
+type invalidationPublisher interface {
+	PublishFlagInvalidation(ctx context.Context, event repository.FlagEvent) error
 }
 
 // ResolveRequest represents a single flag evaluation request, pairing a flag
 // key with an evaluation context and a default value to fall back on.
+// Preset optionally names a saved context preset of the project; its
+// attributes are used wherever Context does not set them.
 type ResolveRequest struct {
 	ProjectID    string
 	Key          string
 	Context      core.EvaluationContext
 	DefaultValue bool
+	Preset       string
+	// TypedDefault, when set to a string, number, object or array, asks for
+	// a typed result instead of a boolean one; see [TypedValueType].
+	TypedDefault json.RawMessage
 }
 
-// ResolveResult holds the evaluated boolean result for a single flag key.
+// ResolveResult holds the evaluated boolean result for a single flag key,
+// along with the reason it was chosen. RuleIndex is set only when Reason is
+// [core.ReasonRuleMatch]; Variant is set only when the value came from the
+// flag's variants default, or from its "on" or "off" variant for a typed
+// result.
+//
+// A typed result still carries the boolean outcome in Value, but is
+// marshalled with TypedValue as its "value".
 type ResolveResult struct {
-	Key   string `json:"key"`
-	Value bool   `json:"value"`
+	Key        string          `json:"key"`
+	Value      bool            `json:"value"`
+	Type       string          `json:"type"`
+	TypedValue json.RawMessage `json:"-"`
+	Reason     core.Reason     `json:"reason"`
+	RuleIndex  *int            `json:"rule_index,omitempty"`
+	Variant    string          `json:"variant,omitempty"`
+	// Warnings lists the context attributes that break the project's strict
+	// context schema.
+	Warnings []ContextWarning `json:"warnings,omitempty"`
 }
 
 // Service is the central feature-flag service. It manages flag CRUD operations,
@@ -107,12 +168,73 @@ type Service struct {
 	repo                Repository
 	log                 *slog.Logger
 	mu                  sync.RWMutex
-	cache               map[string]map[string]repository.Flag // map[projectID]map[key]Flag
+	cache               map[string]map[string]cachedFlag // map[projectID]map[key]
+	rulesIndex          rulesIndex
+	segments            map[string]projectSegments // map[projectID]
 	cacheResyncInterval time.Duration
 	onCacheLoad         func()
 	onInvalidation      func()
 	onCacheReset        func()
 	onCacheUpdate       func(projectID string, size float64)
+	onCacheReloaded     func(duration time.Duration)
+	onCacheReloadFailed func()
+	onCacheDelta        func(projectID string, delta float64)
+	onEventPublished    func(eventType string)
+	subscriber          InvalidationSubscriber
+	publisher           invalidationPublisher
+	events              *eventBroker
+	eventPollInterval   time.Duration
+	projectRetention    time.Duration
+	idempotencyTTL      time.Duration
+
+	// flagReader serves GetFlag cache misses: the repository itself, or a
+	// hedgedReader when a replica is configured.
+	flagReader    FlagReader
+	hedgedReplica FlagReader
+	hedgeDelay    time.Duration
+	onHedgedRead  func(source string, hedgeWon bool)
+
+	statsRepo          FlagStatsRepository
+	statsFlushInterval time.Duration
+	statsMu            sync.Mutex
+	pendingStats       map[statsKey]pendingStats
+	staleThresholds    StaleThresholds
+
+	// API key usage; flushedUsage holds the stored current-hour totals seen
+	// at the last flush, for quota checks.
+	usageRepo    UsageRepository
+	usageMu      sync.Mutex
+	pendingUsage map[usageKey]usageCounts
+	flushedUsage map[usageKey]usageCounts
+	quotas       map[string]cachedAPIKeyQuota
+
+	// Flag change notifications; notifications is nil unless they are
+	// enabled.
+	notifier               NotificationSender
+	notifications          chan repository.FlagEvent
+	notificationRetryDelay time.Duration
+
+	startedAt     time.Time
+	warmupTimeout time.Duration
+	cacheLoaded   atomic.Bool
+	ready         atomic.Bool
+	draining      atomic.Bool
+
+	// contextSchemas caches, per project, what evaluation needs of its
+	// context schema.
+	contextSchemasMu sync.RWMutex
+	contextSchemas   map[string]contextSchemaCache
+	onContextWarning func(kind string)
+
+	// contextEnrichments caches each project's context enrichment.
+	contextEnrichmentsMu sync.RWMutex
+	contextEnrichments   map[string]repository.ContextEnrichment
+
+	onShadowEvaluation func(projectID, key string, agree bool)
+
+	// memo remembers recent evaluation results; nil when disabled.
+	memo         *evaluationMemo
+	onMemoLookup func(hit bool)
 }
 
 // Option configures optional [Service] parameters.
@@ -142,6 +264,41 @@ func WithCacheMetrics(onLoad, onInvalidation, onCacheReset func(), onCacheUpdate
 	}
 }
 
+// WithCacheReloadMetrics registers callbacks invoked when
+// [Service.LoadCache] finishes: onReloaded with how long a successful reload
+// took, onFailed when a reload fails, and onDelta with how much each
+// project's flag count changed, including projects left without flags. A
+// nil callback is ignored.
+func WithCacheReloadMetrics(onReloaded func(duration time.Duration), onFailed func(), onDelta func(projectID string, delta float64)) Option {
+	return func(s *Service) {
+		s.onCacheReloaded = onReloaded
+		s.onCacheReloadFailed = onFailed
+		s.onCacheDelta = onDelta
+	}
+}
+
+// WithEventMetrics registers a callback invoked after each flag event is
+// successfully published. A nil callback is ignored.
+func WithEventMetrics(onPublished func(eventType string)) Option {
+	return func(s *Service) {
+		s.onEventPublished = onPublished
+	}
+}
+
+// WithInvalidationTransport replaces the repository's own cache invalidation
+// subscription with transport. If transport can also publish invalidations,
+// the service announces every flag event through it after the event is
+// stored, so that other replicas subscribed to the same transport reload.
+func WithInvalidationTransport(transport InvalidationSubscriber) Option {
+	return func(s *Service) {
+		if transport == nil {
+			return
+		}
+		s.subscriber = transport
+		s.publisher, _ = transport.(invalidationPublisher)
+	}
+}
+
 // WithCacheResyncInterval sets the periodic safety-net cache refresh interval.
 // Defaults to 1 minute if not set or if interval <= 0.
 func WithCacheResyncInterval(interval time.Duration) Option {
@@ -163,25 +320,77 @@ func New(ctx context.Context, repo Repository, opts ...Option) (*Service, error)
 	svc := &Service{
 		repo:                repo,
 		log:                 slog.Default(),
-		cache:               make(map[string]map[string]repository.Flag),
+		cache:               make(map[string]map[string]cachedFlag),
+		rulesIndex:          make(rulesIndex),
+		segments:            make(map[string]projectSegments),
 		cacheResyncInterval: defaultCacheResyncInterval,
+		projectRetention:    defaultProjectRetention,
+		idempotencyTTL:      defaultIdempotencyTTL,
+		statsFlushInterval:  defaultStatsFlushInterval,
+		pendingStats:        make(map[statsKey]pendingStats),
+		pendingUsage:        make(map[usageKey]usageCounts),
+		flushedUsage:        make(map[usageKey]usageCounts),
+		quotas:              make(map[string]cachedAPIKeyQuota),
+		staleThresholds: StaleThresholds{
+			NotEvaluatedFor: defaultStaleNotEvaluatedFor,
+			NotModifiedFor:  defaultStaleNotModifiedFor,
+		},
+		startedAt:         time.Now(),
+		warmupTimeout:     defaultWarmupTimeout,
+		eventPollInterval: defaultEventPollInterval,
+
+		notificationRetryDelay: defaultNotificationRetryDelay,
 	}
 	for _, opt := range opts {
 		opt(svc)
 	}
+	svc.flagReader = repo
+	if svc.hedgedReplica != nil {
+		svc.flagReader = newHedgedReader(repo, svc.hedgedReplica, svc.hedgeDelay, svc.onHedgedRead)
+	}
 
 	if err := svc.LoadCache(ctx); err != nil {
 		return nil, err
 	}
+	svc.cacheLoaded.Store(true)
 	svc.log.Info("flag cache loaded", "flags", svc.cacheSize())
 
-	if subscriber, ok := repo.(cacheInvalidationSubscriber); ok {
-		if err := svc.startCacheInvalidationListener(ctx, subscriber); err != nil {
+	if svc.subscriber == nil {
+		if subscriber, ok := repo.(InvalidationSubscriber); ok {
+			svc.subscriber = subscriber
+		}
+	}
+	if feed, ok := repo.(EventFeedRepository); ok {
+		broker, err := newEventBroker(ctx, feed, svc.log)
+		if err != nil {
+			return nil, err
+		}
+		svc.events = broker
+		// Announced changes wake the broker; the resync interval is only a
+		// safety net for announcements that were lost.
+		interval := svc.eventPollInterval
+		if svc.subscriber != nil {
+			interval = svc.cacheResyncInterval
+		}
+		go broker.run(ctx, interval)
+	}
+	if svc.subscriber != nil {
+		if err := svc.startCacheInvalidationListener(ctx, svc.subscriber); err != nil {
 			return nil, err
 		}
 		svc.log.Info("cache invalidation listener started")
 	}
 
+	if statsRepo, ok := repo.(FlagStatsRepository); ok {
+		svc.statsRepo = statsRepo
+		go svc.runStatsFlusher(ctx)
+	}
+	if usageRepo, ok := repo.(UsageRepository); ok {
+		svc.usageRepo = usageRepo
+		go svc.runUsageFlusher(ctx)
+	}
+	svc.startNotifier(ctx)
+
 	return svc, nil
 }
 
@@ -189,22 +398,39 @@ func New(ctx context.Context, repo Repository, opts ...Option) (*Service, error)
 // repository. It is called during startup and periodically to ensure
 // consistency.
 func (s *Service) LoadCache(ctx context.Context) error {
+	start := time.Now()
 	flags, err := s.repo.ListFlags(ctx)
 	if err != nil {
+		if s.onCacheReloadFailed != nil {
+			s.onCacheReloadFailed()
+		}
 		return fmt.Errorf("load flags: %w", err)
 	}
+	nextSegments, err := s.loadSegments(ctx)
+	if err != nil {
+		if s.onCacheReloadFailed != nil {
+			s.onCacheReloadFailed()
+		}
+		return err
+	}
 
-	next := make(map[string]map[string]repository.Flag)
+	next := make(map[string]map[string]cachedFlag)
+	nextIndex := make(rulesIndex)
 	for _, flag := range flags {
 		if _, ok := next[flag.ProjectID]; !ok {
-			next[flag.ProjectID] = make(map[string]repository.Flag)
+			next[flag.ProjectID] = make(map[string]cachedFlag)
 		}
-		next[flag.ProjectID][flag.Key] = flag
+		next[flag.ProjectID][flag.Key] = newCachedFlag(flag)
+		nextIndex.add(flag)
 	}
 
 	s.mu.Lock()
+	previous := s.cache
 	s.cache = next
+	s.rulesIndex = nextIndex
+	s.segments = nextSegments
 	s.mu.Unlock()
+	s.memo.reset()
 
 	if s.onCacheLoad != nil {
 		s.onCacheLoad()
@@ -217,12 +443,27 @@ func (s *Service) LoadCache(ctx context.Context) error {
 			s.onCacheUpdate(pid, float64(len(m)))
 		}
 	}
+	if s.onCacheDelta != nil {
+		for pid, m := range next {
+			s.onCacheDelta(pid, float64(len(m)-len(previous[pid])))
+		}
+		for pid, m := range previous {
+			if _, ok := next[pid]; !ok {
+				s.onCacheDelta(pid, -float64(len(m)))
+			}
+		}
+	}
+	if s.onCacheReloaded != nil {
+		s.onCacheReloaded(time.Since(start))
+	}
 
 	return nil
 }
 
-// CreateFlag validates and persists a new flag, updates the cache, and
-// publishes an "updated" event on a best-effort basis.
+// CreateFlag fills in the project's flag defaults, validates and persists a
+// new flag, updates the cache, and publishes an "updated" event on a
+// best-effort basis. A key that breaks the project's flag key policy is
+// rejected with [ErrFlagKeyPolicyViolation].
 func (s *Service) CreateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
 	ctx, span := svcTracer.Start(ctx, "service.CreateFlag")
 	defer span.End()
@@ -231,16 +472,26 @@ func (s *Service) CreateFlag(ctx context.Context, flag repository.Flag) (reposit
 		attribute.String("project_id", flag.ProjectID),
 	)
 
-	if strings.TrimSpace(flag.Key) == "" {
-		return repository.Flag{}, ErrFlagKeyRequired
+	flag, err := s.applyFlagDefaults(ctx, flag, nil)
+	if err != nil {
+		return repository.Flag{}, err
 	}
-	if strings.TrimSpace(flag.ProjectID) == "" {
-		return repository.Flag{}, ErrProjectIDRequired
+	if err := validateFlag(flag); err != nil {
+		return repository.Flag{}, err
 	}
-	if _, err := parseRulesJSON(flag.Rules); err != nil {
+	if err := s.checkSegmentReferences(flag); err != nil {
 		return repository.Flag{}, err
 	}
-	if err := parseVariantsJSON(flag.Variants); err != nil {
+	flag.VariantsSchema = normalizeVariantsSchema(flag.VariantsSchema)
+	flag.ShadowRules = normalizeShadowRules(flag.ShadowRules)
+	flag.Tags, _ = normalizeTags(flag.Tags)
+	flag.Owner = strings.TrimSpace(flag.Owner)
+	flag.CreatedBy = middleware.ActorFromContext(ctx).String()
+	keyRules, err := s.flagKeyRulesFor(ctx, flag.ProjectID, nil)
+	if err != nil {
+		return repository.Flag{}, err
+	}
+	if err := keyRules.check(flag.Key); err != nil {
 		return repository.Flag{}, err
 	}
 
@@ -258,10 +509,92 @@ func (s *Service) CreateFlag(ctx context.Context, flag repository.Flag) (reposit
 	return created, nil
 }
 
+// CreateFlags fills in project flag defaults and validates every flag up
+// front, then creates them all in a single repository transaction; either
+// every flag is created or none are. If any flag is invalid, or its key is
+// taken, the error is a [*BatchError] listing every such flag by its
+// zero-based index.
+func (s *Service) CreateFlags(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error) {
+	ctx, span := svcTracer.Start(ctx, "service.CreateFlags")
+	defer span.End()
+	span.SetAttributes(attribute.Int("flag_count", len(flags)))
+
+	flags = slices.Clone(flags)
+	defaultsByProject := make(map[string]repository.FlagDefaults)
+	keyRulesByProject := make(map[string]flagKeyRules)
+	seen := make(map[[2]string]bool, len(flags))
+	var batchErr BatchError
+	for i, flag := range flags {
+		flag, err := s.applyFlagDefaults(ctx, flag, defaultsByProject)
+		if err != nil {
+			return nil, fmt.Errorf("flag %d: %w", i, err)
+		}
+		keyRules, err := s.flagKeyRulesFor(ctx, flag.ProjectID, keyRulesByProject)
+		if err != nil {
+			return nil, fmt.Errorf("flag %d: %w", i, err)
+		}
+		err = validateFlag(flag)
+		if err == nil {
+			err = s.checkSegmentReferences(flag)
+		}
+		if err == nil {
+			err = keyRules.check(flag.Key)
+		}
+		if err == nil {
+			id := [2]string{flag.ProjectID, flag.Key}
+			if _, ok := s.getCachedFlag(flag.ProjectID, flag.Key); ok || seen[id] {
+				err = ErrFlagExists
+			}
+			seen[id] = true
+		}
+		if err != nil {
+			batchErr.Items = append(batchErr.Items, BatchItemError{Index: i, Key: flag.Key, Err: err})
+			continue
+		}
+		flag.VariantsSchema = normalizeVariantsSchema(flag.VariantsSchema)
+		flag.ShadowRules = normalizeShadowRules(flag.ShadowRules)
+		flag.Tags, _ = normalizeTags(flag.Tags)
+		flag.Owner = strings.TrimSpace(flag.Owner)
+		flag.CreatedBy = middleware.ActorFromContext(ctx).String()
+		flags[i] = flag
+	}
+	if len(batchErr.Items) > 0 {
+		return nil, &batchErr
+	}
+
+	repo, ok := s.repo.(BatchFlagRepository)
+	if !ok {
+		return nil, errBatchCreateNotSupported
+	}
+
+	created, err := repo.CreateFlags(ctx, flags)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create flags failed")
+		return nil, fmt.Errorf("create flags: %w", err)
+	}
+
+	for _, flag := range created {
+		s.setCachedFlag(flag)
+		s.publishFlagEventBestEffort(ctx, EventTypeUpdated, flag)
+		s.insertAuditLogBestEffort(ctx, flag.ProjectID, "create", flag.Key)
+	}
+
+	return created, nil
+}
+
 // UpdateFlag validates and persists changes to an existing flag. Returns
-// [ErrFlagNotFound] if the flag does not exist. On success, the cache is
-// updated and an "updated" event is published.
+// [ErrFlagNotFound] if the flag does not exist. A flag without a
+// VariantsSchema or ShadowRules keeps the one it has; send JSON null to
+// remove it. On success, the cache is updated and an "updated" event is
+// published.
 func (s *Service) UpdateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
+	return s.updateFlag(ctx, flag, "update")
+}
+
+// updateFlag implements [Service.UpdateFlag], recording the change in the
+// audit log under action.
+func (s *Service) updateFlag(ctx context.Context, flag repository.Flag, action string) (repository.Flag, error) {
 	ctx, span := svcTracer.Start(ctx, "service.UpdateFlag")
 	defer span.End()
 	span.SetAttributes(
@@ -269,16 +602,18 @@ func (s *Service) UpdateFlag(ctx context.Context, flag repository.Flag) (reposit
 		attribute.String("project_id", flag.ProjectID),
 	)
 
-	if strings.TrimSpace(flag.Key) == "" {
-		return repository.Flag{}, ErrFlagKeyRequired
+	if err := validateFlag(flag); err != nil {
+		return repository.Flag{}, err
 	}
-	if strings.TrimSpace(flag.ProjectID) == "" {
-		return repository.Flag{}, ErrProjectIDRequired
+	if err := s.checkSegmentReferences(flag); err != nil {
+		return repository.Flag{}, err
 	}
-	if _, err := parseRulesJSON(flag.Rules); err != nil {
+	current, err := s.GetFlag(ctx, flag.ProjectID, flag.Key)
+	if err != nil {
 		return repository.Flag{}, err
 	}
-	if err := parseVariantsJSON(flag.Variants); err != nil {
+	flag, err = completeFlagUpdate(ctx, current, flag)
+	if err != nil {
 		return repository.Flag{}, err
 	}
 
@@ -297,11 +632,34 @@ func (s *Service) UpdateFlag(ctx context.Context, flag repository.Flag) (reposit
 
 	s.setCachedFlag(updated)
 	s.publishFlagEventBestEffort(ctx, EventTypeUpdated, updated)
-	s.insertAuditLogBestEffort(ctx, updated.ProjectID, "update", updated.Key)
+	s.insertFlagChangeAuditLog(ctx, action, current, updated)
 
 	return updated, nil
 }
 
+// completeFlagUpdate prepares flag, which is to replace current, for
+// storage: it keeps the shadow rules and variants schema flag leaves out,
+// normalizes the rest and records the actor making the change. flag must
+// already have passed [validateFlag].
+func completeFlagUpdate(ctx context.Context, current, flag repository.Flag) (repository.Flag, error) {
+	if flag.ShadowRules == nil {
+		flag.ShadowRules = current.ShadowRules
+	}
+	if flag.VariantsSchema == nil {
+		// The new variants must still satisfy the schema being kept.
+		flag.VariantsSchema = current.VariantsSchema
+		if err := validateVariantsSchema(flag); err != nil {
+			return repository.Flag{}, err
+		}
+	}
+	flag.VariantsSchema = normalizeVariantsSchema(flag.VariantsSchema)
+	flag.ShadowRules = normalizeShadowRules(flag.ShadowRules)
+	flag.Tags, _ = normalizeTags(flag.Tags)
+	flag.Owner = strings.TrimSpace(flag.Owner)
+	flag.UpdatedBy = middleware.ActorFromContext(ctx).String()
+	return flag, nil
+}
+
 // GetFlag returns a flag by projectID and key, serving from the in-memory cache when
 // available and falling back to the repository. Returns [ErrFlagNotFound]
 // if the flag does not exist.
@@ -324,7 +682,7 @@ func (s *Service) GetFlag(ctx context.Context, projectID, key string) (repositor
 		return flag, nil
 	}
 
-	flag, err := s.repo.GetFlag(ctx, projectID, key)
+	flag, err := s.flagReader.GetFlag(ctx, projectID, key)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			span.RecordError(err)
@@ -349,23 +707,11 @@ func (s *Service) ListFlags(ctx context.Context, projectID string) ([]repository
 	if strings.TrimSpace(projectID) == "" {
 		return nil, ErrProjectIDRequired
 	}
-	s.mu.RLock()
-	projectFlags, ok := s.cache[projectID]
-	if !ok {
-		s.mu.RUnlock()
-		return []repository.Flag{}, nil
-	}
-
-	flags := make([]repository.Flag, 0, len(projectFlags))
-	for _, flag := range projectFlags {
-		flags = append(flags, flag)
+	entries := s.cachedFlagEntries(projectID)
+	flags := make([]repository.Flag, 0, len(entries))
+	for _, entry := range entries {
+		flags = append(flags, entry.flag)
 	}
-	s.mu.RUnlock()
-
-	sort.Slice(flags, func(i, j int) bool {
-		return flags[i].Key < flags[j].Key
-	})
-
 	return flags, nil
 }
 
@@ -408,6 +754,16 @@ func (s *Service) DeleteFlag(ctx context.Context, projectID, key string) error {
 // a boolean result. If the flag is not found, the provided default value is
 // returned without error.
 func (s *Service) ResolveBoolean(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (bool, error) {
+	result, err := s.ResolveBooleanDetail(ctx, projectID, key, evalContext, defaultValue)
+	return result.Value, err
+}
+
+// ResolveBooleanDetail evaluates a flag like [Service.ResolveBoolean] and also
+// reports the evaluation reason, matched rule index, and variant. A missing
+// flag yields defaultValue with [core.ReasonFlagNotFound]. If the project's
+// context schema is strict, the result also warns about unknown or mistyped
+// context attributes.
+func (s *Service) ResolveBooleanDetail(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (ResolveResult, error) {
 	ctx, span := svcTracer.Start(ctx, "service.EvaluateFlag")
 	defer span.End()
 	span.SetAttributes(
@@ -415,47 +771,187 @@ func (s *Service) ResolveBoolean(ctx context.Context, projectID, key string, eva
 		attribute.String("project_id", projectID),
 	)
 
-	flag, err := s.GetFlag(ctx, projectID, key)
+	result, _, err := s.resolveDetail(ctx, projectID, key, evalContext, defaultValue)
+	return result, err
+}
+
+// resolveDetail is [Service.ResolveBooleanDetail], also returning the
+// evaluated flag's variants, or nil if the flag does not exist.
+func (s *Service) resolveDetail(ctx context.Context, projectID, key string, evalContext core.EvaluationContext, defaultValue bool) (ResolveResult, json.RawMessage, error) {
+	span := trace.SpanFromContext(ctx)
+	warnings := s.checkContext(ctx, projectID, evalContext)
+	if len(warnings) > 0 {
+		span.SetAttributes(attribute.Int("context_warnings", len(warnings)))
+	}
+	fallback := ResolveResult{Key: key, Value: defaultValue, Type: ValueTypeBoolean, Reason: core.ReasonFlagNotFound, Warnings: warnings}
+	evalContext = s.enrichContext(ctx, projectID, evalContext)
+
+	var cacheKey memoKey
+	memoize := false
+	if s.memo != nil {
+		var entry memoEntry
+		var hit bool
+		entry, hit, cacheKey, memoize = s.memo.lookup(projectID, key, evalContext)
+		if s.onMemoLookup != nil {
+			s.onMemoLookup(hit)
+		}
+		if hit {
+			s.recordEvaluation(projectID, key, entry.evaluation.Value)
+			span.SetAttributes(attribute.String("evaluation_reason", string(entry.evaluation.Reason)), attribute.Bool("evaluation_cached", true))
+			result := newResolveResult(key, entry.evaluation)
+			result.Warnings = warnings
+			return result, entry.variants, nil
+		}
+	}
+
+	entry, err := s.flagForEvaluation(ctx, projectID, key)
 	if err != nil {
 		if errors.Is(err, ErrFlagNotFound) {
-			return defaultValue, nil
+			return fallback, nil, nil
 		}
-		return defaultValue, err
-	}
+		return fallback, nil, err
+	}
+	flag, coreFlag := entry.flag, entry.core
+	coreFlag.Segments = s.cachedSegments(projectID).core
+
+	evaluation := core.EvaluateFlagDetail(coreFlag, evalContext)
+	if memoize {
+		s.memo.store(cacheKey, evaluation, flag.Variants)
+	}
+	s.recordEvaluation(projectID, key, evaluation.Value)
+	s.evaluateShadow(ctx, entry, coreFlag, evalContext, evaluation)
+	span.SetAttributes(attribute.String("evaluation_reason", string(evaluation.Reason)))
+	if s.log.Enabled(ctx, slog.LevelDebug) {
+		requestID, _ := middleware.RequestIDFromContext(ctx)
+		s.log.DebugContext(ctx, "flag evaluated",
+			"request_id", requestID,
+			"project_id", projectID,
+			"flag_key", key,
+			"reason", evaluation.Reason,
+			"attributes", s.loggableContext(ctx, projectID, evalContext),
+		)
+	}
+
+	result := newResolveResult(key, evaluation)
+	result.Warnings = warnings
+	return result, flag.Variants, nil
+}
 
+// PreviewFlag validates flag as [Service.UpdateFlag] would and evaluates it
+// against evalContext, without saving it or counting the evaluation, so an
+// edit can be tried out before it is made.
+func (s *Service) PreviewFlag(ctx context.Context, flag repository.Flag, evalContext core.EvaluationContext) (ResolveResult, error) {
+	_, span := svcTracer.Start(ctx, "service.PreviewFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", flag.Key),
+		attribute.String("project_id", flag.ProjectID),
+	)
+
+	if err := validateFlag(flag); err != nil {
+		return ResolveResult{}, err
+	}
+	if err := s.checkSegmentReferences(flag); err != nil {
+		return ResolveResult{}, err
+	}
 	coreFlag, err := repositoryFlagToCore(flag)
 	if err != nil {
-		return defaultValue, fmt.Errorf("decode flag %q rules: %w", key, err)
+		return ResolveResult{}, err
 	}
+	coreFlag.Segments = s.cachedSegments(flag.ProjectID).core
+	return newResolveResult(flag.Key, core.EvaluateFlagDetail(coreFlag, evalContext)), nil
+}
 
-	return core.EvaluateFlag(coreFlag, evalContext), nil
+func newResolveResult(key string, evaluation core.Evaluation) ResolveResult {
+	result := ResolveResult{
+		Key:     key,
+		Value:   evaluation.Value,
+		Type:    ValueTypeBoolean,
+		Reason:  evaluation.Reason,
+		Variant: evaluation.Variant,
+	}
+	if evaluation.Reason == core.ReasonRuleMatch {
+		ruleIndex := evaluation.RuleIndex
+		result.RuleIndex = &ruleIndex
+	}
+	return result
 }
 
-// ResolveBatch evaluates multiple flags in a single call, returning results
-// in the same order as the requests.
+// ResolveBatch evaluates multiple flags in a single call, returning detailed
+// results in the same order as the requests. Returns
+// [ErrContextPresetNotFound] if a request names a preset that does not exist.
 func (s *Service) ResolveBatch(ctx context.Context, requests []ResolveRequest) ([]ResolveResult, error) {
 	results := make([]ResolveResult, 0, len(requests))
+	var presets map[string]core.EvaluationContext
 	for _, request := range requests {
-		value, err := s.ResolveBoolean(ctx, request.ProjectID, request.Key, request.Context, request.DefaultValue)
+		evalContext := request.Context
+		if request.Preset != "" {
+			if presets == nil {
+				presets = make(map[string]core.EvaluationContext)
+			}
+			var err error
+			evalContext, err = s.applyContextPreset(ctx, request.ProjectID, request.Preset, evalContext, presets)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if request.TypedDefault != nil {
+			result, err := s.resolveTyped(ctx, request, evalContext)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+			continue
+		}
+		result, err := s.ResolveBooleanDetail(ctx, request.ProjectID, request.Key, evalContext, request.DefaultValue)
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, ResolveResult{
-			Key:   request.Key,
-			Value: value,
-		})
+		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// ResolveAll evaluates every flag in projectID against one context, applying
+// preset first if it is set, and returns the results sorted by key. Each
+// evaluation counts towards its flag's stats, as with [Service.ResolveBatch].
+func (s *Service) ResolveAll(ctx context.Context, projectID string, evalContext core.EvaluationContext, preset string) ([]ResolveResult, error) {
+	ctx, span := svcTracer.Start(ctx, "service.ResolveAll")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	flags, err := s.ListFlags(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("flag_count", len(flags)))
+
+	requests := make([]ResolveRequest, len(flags))
+	for i, flag := range flags {
+		requests[i] = ResolveRequest{
+			ProjectID: projectID,
+			Key:       flag.Key,
+			Context:   evalContext,
+			Preset:    preset,
+		}
+	}
+	return s.ResolveBatch(ctx, requests)
+}
+
 // ListEventsSince returns flag events with IDs greater than eventID, used by
-// streaming consumers to poll for updates.
+// streaming consumers to follow updates. Recent events are served from the
+// event broker; older ones, and all of them without a broker, come from the
+// repository a batch at a time.
 func (s *Service) ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
 	if strings.TrimSpace(projectID) == "" {
 		return nil, ErrProjectIDRequired
 	}
+	if events, ok := s.recentEvents(projectID, eventID, repository.EventFilter{}); ok {
+		return events, nil
+	}
 	events, err := s.repo.ListEventsSince(ctx, projectID, eventID)
 	if err != nil {
 		return nil, fmt.Errorf("list events since %d: %w", eventID, err)
@@ -531,6 +1027,9 @@ func (s *Service) ListEventsSinceForKey(ctx context.Context, projectID string, e
 	if strings.TrimSpace(key) == "" {
 		return nil, ErrFlagKeyRequired
 	}
+	if events, ok := s.recentEvents(projectID, eventID, repository.EventFilter{Keys: []string{key}}); ok {
+		return events, nil
+	}
 
 	events, err := s.repo.ListEventsSinceForKey(ctx, projectID, eventID, key)
 	if err != nil {
@@ -540,27 +1039,149 @@ func (s *Service) ListEventsSinceForKey(ctx context.Context, projectID string, e
 	return events, nil
 }
 
+// ListEventsSinceMatching returns the flag events of a project with IDs
+// greater than eventID that pass filter, so a stream watching a few flags or
+// event types is only sent those. Recent events come from the event broker
+// and older ones from a filtered repository query.
+func (s *Service) ListEventsSinceMatching(ctx context.Context, projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	if events, ok := s.recentEvents(projectID, eventID, filter); ok {
+		return events, nil
+	}
+
+	if repo, ok := s.repo.(FilteredEventRepository); ok {
+		events, err := repo.ListEventsSinceMatching(ctx, projectID, eventID, filter)
+		if err != nil {
+			return nil, fmt.Errorf("list matching events since %d: %w", eventID, err)
+		}
+		return events, nil
+	}
+
+	// Without a filtered query, skip whole batches that match nothing so the
+	// caller is not handed an empty batch while events remain.
+	for {
+		batch, err := s.repo.ListEventsSince(ctx, projectID, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("list matching events since %d: %w", eventID, err)
+		}
+		events := make([]repository.FlagEvent, 0, len(batch))
+		for _, event := range batch {
+			if filter.Matches(event) {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 0 || len(batch) == 0 {
+			return events, nil
+		}
+		eventID = batch[len(batch)-1].EventID
+	}
+}
+
+// recentEvents returns the events after eventID held by the event broker. ok
+// is false if there is no broker or it no longer holds all of them.
+func (s *Service) recentEvents(projectID string, eventID int64, filter repository.EventFilter) ([]repository.FlagEvent, bool) {
+	if s.events == nil {
+		return nil, false
+	}
+	return s.events.since(projectID, eventID, filter)
+}
+
+// cachedFlag is a flag in the cache together with its decoded form, so that
+// evaluating it does not parse its rules and variants again. An entry is
+// replaced whenever its flag is, which keeps the two in step.
+type cachedFlag struct {
+	flag repository.Flag
+	core core.Flag
+	// err is the error decoding the flag's rules, if any; such a flag
+	// cannot be evaluated.
+	err error
+	// shadowRules are the flag's decoded shadow rules. hasShadow is false
+	// when the flag has none, or they cannot be decoded and are skipped.
+	shadowRules []core.Rule
+	hasShadow   bool
+}
+
+func newCachedFlag(flag repository.Flag) cachedFlag {
+	coreFlag, err := repositoryFlagToCore(flag)
+	entry := cachedFlag{flag: flag, core: coreFlag, err: err}
+	if flag.ShadowRules != nil {
+		if rules, err := parseRulesJSON(flag.ShadowRules); err == nil {
+			entry.shadowRules, entry.hasShadow = rules, true
+		}
+	}
+	return entry
+}
+
 func (s *Service) getCachedFlag(projectID, key string) (repository.Flag, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	if projectFlags, ok := s.cache[projectID]; ok {
-		if flag, ok := projectFlags[key]; ok {
-			return flag, true
+		if entry, ok := projectFlags[key]; ok {
+			return entry.flag, true
 		}
 	}
 
 	return repository.Flag{}, false
 }
 
+// flagForEvaluation returns a flag with its decoded form. A cached flag is
+// returned as decoded when it was cached; any other is fetched with
+// [Service.GetFlag] and decoded now. The decoded rules are shared with the
+// cache and must not be modified.
+func (s *Service) flagForEvaluation(ctx context.Context, projectID, key string) (cachedFlag, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[projectID][key]
+	s.mu.RUnlock()
+
+	if !ok {
+		flag, err := s.GetFlag(ctx, projectID, key)
+		if err != nil {
+			return cachedFlag{}, err
+		}
+		entry = newCachedFlag(flag)
+	}
+	if entry.err != nil {
+		return cachedFlag{}, fmt.Errorf("decode flag %q rules: %w", key, entry.err)
+	}
+	return entry, nil
+}
+
+// cachedFlagEntries returns the cached flags of a project with their
+// decoded forms, sorted by key. As with [Service.flagForEvaluation], the
+// decoded rules must not be modified.
+func (s *Service) cachedFlagEntries(projectID string) []cachedFlag {
+	s.mu.RLock()
+	projectFlags := s.cache[projectID]
+	entries := make([]cachedFlag, 0, len(projectFlags))
+	for _, entry := range projectFlags {
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].flag.Key < entries[j].flag.Key
+	})
+	return entries
+}
+
 func (s *Service) setCachedFlag(flag repository.Flag) {
+	entry := newCachedFlag(flag)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, ok := s.cache[flag.ProjectID]; !ok {
-		s.cache[flag.ProjectID] = make(map[string]repository.Flag)
+		s.cache[flag.ProjectID] = make(map[string]cachedFlag)
 	}
-	s.cache[flag.ProjectID][flag.Key] = flag
+	if previous, ok := s.cache[flag.ProjectID][flag.Key]; ok {
+		s.rulesIndex.remove(previous.flag)
+	}
+	s.cache[flag.ProjectID][flag.Key] = entry
+	s.rulesIndex.add(flag)
+	s.memo.invalidateFlag(flag.ProjectID, flag.Key)
 }
 
 func (s *Service) deleteCachedFlag(projectID, key string) {
@@ -568,11 +1189,15 @@ func (s *Service) deleteCachedFlag(projectID, key string) {
 	defer s.mu.Unlock()
 
 	if projectFlags, ok := s.cache[projectID]; ok {
+		if previous, ok := projectFlags[key]; ok {
+			s.rulesIndex.remove(previous.flag)
+		}
 		delete(projectFlags, key)
 		if len(projectFlags) == 0 {
 			delete(s.cache, projectID)
 		}
 	}
+	s.memo.invalidateFlag(projectID, key)
 }
 
 func (s *Service) cacheSize() int {
@@ -586,7 +1211,7 @@ func (s *Service) cacheSize() int {
 	return n
 }
 
-func (s *Service) startCacheInvalidationListener(ctx context.Context, subscriber cacheInvalidationSubscriber) error {
+func (s *Service) startCacheInvalidationListener(ctx context.Context, subscriber InvalidationSubscriber) error {
 	invalidations, err := subscriber.SubscribeFlagInvalidation(ctx)
 	if err != nil {
 		return fmt.Errorf("subscribe cache invalidation: %w", err)
@@ -611,7 +1236,7 @@ func (s *Service) startCacheInvalidationListener(ctx context.Context, subscriber
 					}
 				}
 				s.reloadCache(ctx)
-			case _, ok := <-invalidations:
+			case invalidation, ok := <-invalidations:
 				if !ok {
 					next, err := subscriber.SubscribeFlagInvalidation(ctx)
 					if err != nil {
@@ -623,11 +1248,12 @@ func (s *Service) startCacheInvalidationListener(ctx context.Context, subscriber
 					s.log.Info("cache invalidation resubscribed after channel close")
 					continue
 				}
-				s.log.Debug("cache invalidation received")
+				s.log.Debug("cache invalidation received", "project_id", invalidation.ProjectID, "flag_key", invalidation.FlagKey)
 				if s.onInvalidation != nil {
 					s.onInvalidation()
 				}
-				s.reloadCache(ctx)
+				s.applyInvalidation(ctx, invalidation)
+				s.notifyEventBroker()
 			}
 		}
 	}()
@@ -642,12 +1268,43 @@ func (s *Service) publishFlagEventBestEffort(ctx context.Context, eventType stri
 	_ = s.publishFlagEvent(publishCtx, eventType, flag)
 }
 
+// applyInvalidation brings the cache up to date with one announced change.
+// The flag it names is read again, or dropped if it was deleted; a change
+// that names no flag, or whose flag cannot be read, reloads the whole cache.
+// The periodic resync still reloads everything, catching any change missed.
+func (s *Service) applyInvalidation(ctx context.Context, invalidation repository.FlagInvalidation) {
+	if !invalidation.Targeted() {
+		s.reloadCache(ctx)
+		return
+	}
+	if invalidation.EventType == EventTypeDeleted {
+		s.deleteCachedFlag(invalidation.ProjectID, invalidation.FlagKey)
+		return
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, cacheReloadTimeout)
+	defer cancel()
+	flag, err := s.repo.GetFlag(readCtx, invalidation.ProjectID, invalidation.FlagKey)
+	switch {
+	case err == nil:
+		s.setCachedFlag(flag)
+	case errors.Is(err, pgx.ErrNoRows):
+		// Deleted since, or its project was.
+		s.deleteCachedFlag(invalidation.ProjectID, invalidation.FlagKey)
+	default:
+		s.log.WarnContext(ctx, "cache flag refresh failed, reloading", "project_id", invalidation.ProjectID, "flag_key", invalidation.FlagKey, "error", err)
+		s.reloadCache(ctx)
+	}
+}
+
 func (s *Service) reloadCache(ctx context.Context) {
 	reloadCtx, cancel := context.WithTimeout(ctx, cacheReloadTimeout)
 	defer cancel()
 	if err := s.LoadCache(reloadCtx); err != nil {
-		s.log.Error("cache reload failed", "error", err)
+		s.log.ErrorContext(ctx, "cache reload failed", "error", err)
 	} else {
+		s.resetContextSchemas()
+		s.resetContextEnrichments()
 		s.log.Debug("cache reloaded", "flags", s.cacheSize())
 	}
 }
@@ -658,19 +1315,177 @@ func (s *Service) publishFlagEvent(ctx context.Context, eventType string, flag r
 		return fmt.Errorf("marshal %s event payload: %w", eventType, err)
 	}
 
-	_, err = s.repo.PublishFlagEvent(ctx, repository.FlagEvent{
+	event, err := s.repo.PublishFlagEvent(ctx, repository.FlagEvent{
 		ProjectID: flag.ProjectID,
 		FlagKey:   flag.Key,
 		EventType: eventType,
 		Payload:   payload,
+		Actor:     middleware.ActorFromContext(ctx).String(),
 	})
 	if err != nil {
 		return fmt.Errorf("publish %s event: %w", eventType, err)
 	}
 
+	if s.publisher != nil {
+		// The event is already stored, so a failed announcement only delays
+		// other replicas until their next periodic resync.
+		if err := s.publisher.PublishFlagInvalidation(ctx, event); err != nil {
+			s.log.WarnContext(ctx, "publish cache invalidation failed", "event_type", eventType, "error", err)
+		}
+	}
+
+	s.notifyEventBroker()
+	s.enqueueNotification(ctx, event)
+	if s.onEventPublished != nil {
+		s.onEventPublished(eventType)
+	}
+
 	return nil
 }
 
+// EventsChanged returns a channel that is closed once new flag events can be
+// read with [Service.ListEventsSince] and its variants, so streams can wait
+// for changes rather than poll. It returns nil, which never fires, when the
+// repository cannot feed the event broker; streams must then poll.
+func (s *Service) EventsChanged() <-chan struct{} {
+	if s.events == nil {
+		return nil
+	}
+	return s.events.changes()
+}
+
+func (s *Service) notifyEventBroker() {
+	if s.events != nil {
+		s.events.notify()
+	}
+}
+
+func validateFlag(flag repository.Flag) error {
+	if strings.TrimSpace(flag.Key) == "" {
+		return ErrFlagKeyRequired
+	}
+	if strings.TrimSpace(flag.ProjectID) == "" {
+		return ErrProjectIDRequired
+	}
+	if err := validateRules(flag.Rules); err != nil {
+		return err
+	}
+	if err := validateShadowRules(flag.ShadowRules); err != nil {
+		return err
+	}
+	if err := parseVariantsJSON(flag.Variants); err != nil {
+		return err
+	}
+	if _, err := normalizeTags(flag.Tags); err != nil {
+		return err
+	}
+	if err := validateOwner(strings.TrimSpace(flag.Owner)); err != nil {
+		return err
+	}
+	return validateVariantsSchema(flag)
+}
+
+// BatchItemError is the reason one flag of a batch was rejected.
+type BatchItemError struct {
+	// Index is the flag's zero-based position in the batch.
+	Index int
+	Key   string
+	Err   error
+}
+
+func (e BatchItemError) Error() string {
+	return fmt.Sprintf("flag %d: %v", e.Index, e.Err)
+}
+
+func (e BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by [Service.CreateFlags] when one or more flags are
+// rejected, in which case none are created. It matches every item's error
+// with [errors.Is] and [errors.As].
+type BatchError struct {
+	Items []BatchItemError
+}
+
+func (e *BatchError) Error() string {
+	reasons := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		reasons[i] = item.Error()
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Items))
+	for i, item := range e.Items {
+		errs[i] = item
+	}
+	return errs
+}
+
+// RulesError is returned when one or more flag rules fail validation. It
+// wraps [ErrInvalidRules] and lists every invalid rule, so callers can
+// report all problems at once.
+type RulesError struct {
+	// Field is "shadow_rules" when the rules are a flag's shadow rules and
+	// empty otherwise.
+	Field string
+	Rules []core.RuleError
+}
+
+func (e *RulesError) Error() string {
+	reasons := make([]string, len(e.Rules))
+	for i, ruleErr := range e.Rules {
+		reasons[i] = ruleErr.Error()
+	}
+	if e.Field != "" {
+		return ErrInvalidRules.Error() + ": " + e.Field + ": " + strings.Join(reasons, "; ")
+	}
+	return ErrInvalidRules.Error() + ": " + strings.Join(reasons, "; ")
+}
+
+func (e *RulesError) Unwrap() error {
+	return ErrInvalidRules
+}
+
+// validateRules decodes payload as a list of rules and checks each with
+// [core.ValidateRules]. A payload that is not a JSON array is rejected
+// outright; otherwise every invalid element is reported in a [RulesError].
+func validateRules(payload json.RawMessage) error {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(payload, &elements); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	var ruleErrs []core.RuleError
+	rules := make([]core.Rule, 0, len(elements))
+	indexes := make([]int, 0, len(elements))
+	for i, element := range elements {
+		var rule core.Rule
+		if err := json.Unmarshal(element, &rule); err != nil {
+			ruleErrs = append(ruleErrs, core.RuleError{Index: i, Reason: "rule must be an object with attribute, operator and value"})
+			continue
+		}
+		rules = append(rules, rule)
+		indexes = append(indexes, i)
+	}
+	for _, ruleErr := range core.ValidateRules(rules) {
+		ruleErr.Index = indexes[ruleErr.Index]
+		ruleErrs = append(ruleErrs, ruleErr)
+	}
+	if len(ruleErrs) == 0 {
+		return nil
+	}
+
+	sort.Slice(ruleErrs, func(i, j int) bool { return ruleErrs[i].Index < ruleErrs[j].Index })
+	return &RulesError{Rules: ruleErrs}
+}
+
 func repositoryFlagToCore(flag repository.Flag) (core.Flag, error) {
 	rules, err := parseRulesJSON(flag.Rules)
 	if err != nil {
@@ -678,10 +1493,16 @@ func repositoryFlagToCore(flag repository.Flag) (core.Flag, error) {
 	}
 
 	return core.Flag{
-		Key:          flag.Key,
-		Disabled:     !flag.Enabled,
-		DefaultValue: parseBooleanDefaultFromVariants(flag.Variants),
-		Rules:        rules,
+		Key:           flag.Key,
+		Disabled:      !flag.Enabled,
+		DefaultValue:  parseBooleanDefaultFromVariants(flag.Variants),
+		Rules:         rules,
+		BucketingSalt: flag.BucketingSalt,
+		Targets: core.Targets{
+			Attribute: flag.Targets.Attribute,
+			Allow:     flag.Targets.Allow,
+			Deny:      flag.Targets.Deny,
+		},
 	}, nil
 }
 
@@ -738,15 +1559,21 @@ func (s *Service) ListAuditLog(ctx context.Context, projectID string, limit, off
 }
 
 func (s *Service) insertAuditLogBestEffort(ctx context.Context, projectID, action, flagKey string) {
-	apiKeyID, _ := middleware.APIKeyIDFromContext(ctx)
-	adminUserID, _ := middleware.AdminUserIDFromContext(ctx)
+	s.insertAuditLogDetailsBestEffort(ctx, projectID, action, flagKey, nil)
+}
+
+func (s *Service) insertAuditLogDetailsBestEffort(ctx context.Context, projectID, action, flagKey string, details json.RawMessage) {
+	actor := middleware.ActorFromContext(ctx)
+	requestID, _ := middleware.RequestIDFromContext(ctx)
 	bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), bestEffortTimeout)
 	defer cancel()
 	_ = s.repo.InsertAuditLog(bgCtx, repository.AuditLogEntry{
 		ProjectID:   projectID,
-		APIKeyID:    apiKeyID,
-		AdminUserID: adminUserID,
+		APIKeyID:    actor.APIKeyID,
+		AdminUserID: actor.AdminUserID,
 		Action:      action,
 		FlagKey:     flagKey,
+		Details:     details,
+		RequestID:   requestID,
 	})
 }
@@ -62,3 +62,39 @@ for b.Loop() {
 _, _ = svc.ResolveBoolean(ctx, "default", "feature-rollout", evalCtx, false)
 }
 }
+
+// BenchmarkResolveBoolean_ManyRules evaluates a cached flag whose rules
+// would be costly to decode on every evaluation.
+func BenchmarkResolveBoolean_ManyRules(b *testing.B) {
+	ctx := context.Background()
+	rules := make([]core.Rule, 20)
+	for i := range rules {
+		rules[i] = core.Rule{Attribute: fmt.Sprintf("attr-%d", i), Operator: core.OperatorIn, Value: []string{"a", "b", "c"}}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		b.Fatal(err)
+	}
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "feature-many-rules",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     rulesJSON,
+	})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		b.Fatalf("New() error = %v", err)
+	}
+
+	evalCtx := core.EvaluationContext{
+		Attributes: map[string]any{"attr-19": "c"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = svc.ResolveBoolean(ctx, "default", "feature-many-rules", evalCtx, false)
+	}
+}
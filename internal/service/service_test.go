@@ -5,13 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/matt-riley/flagz/internal/core"
 	"github.com/matt-riley/flagz/internal/middleware"
+	"github.com/matt-riley/flagz/internal/notify"
 	"github.com/matt-riley/flagz/internal/repository"
 )
 
@@ -93,6 +100,12 @@ func TestServiceCRUDAndEvaluation(t *testing.T) {
 	if len(batch) != 2 || !batch[0].Value || !batch[1].Value {
 		t.Fatalf("ResolveBatch() = %#v, want [{new-ui true} {unknown true}]", batch)
 	}
+	if batch[0].Reason != core.ReasonRuleMatch || batch[0].RuleIndex == nil || *batch[0].RuleIndex != 0 {
+		t.Fatalf("ResolveBatch()[0] = %#v, want RULE_MATCH at rule 0", batch[0])
+	}
+	if batch[1].Reason != core.ReasonFlagNotFound || batch[1].RuleIndex != nil {
+		t.Fatalf("ResolveBatch()[1] = %#v, want FLAG_NOT_FOUND", batch[1])
+	}
 
 	flag.Description = "updated rollout"
 	if _, err := svc.UpdateFlag(ctx, flag); err != nil {
@@ -228,6 +241,54 @@ func TestServiceUpdateFlagEvictsStaleCacheOnNotFound(t *testing.T) {
 	}
 }
 
+func TestServiceResolveUsesRulesDecodedWithCachedFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "new-ui",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"US"}]`),
+	})
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "broken",
+		Enabled:   true,
+		Rules:     json.RawMessage(`{"not":"a list"}`),
+	})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if entry := svc.cache["default"]["new-ui"]; entry.err != nil || len(entry.core.Rules) != 1 {
+		t.Fatalf("cached entry = %+v, want one decoded rule", entry)
+	}
+	us := core.EvaluationContext{Attributes: map[string]any{"country": "US"}}
+	if value, err := svc.ResolveBoolean(ctx, "default", "new-ui", us, false); err != nil || !value {
+		t.Fatalf("ResolveBoolean() = %v, %v, want true", value, err)
+	}
+
+	// Updating the flag replaces its decoded rules along with it.
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID: "default",
+		Key:       "new-ui",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"CA"}]`),
+	}); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if value, err := svc.ResolveBoolean(ctx, "default", "new-ui", us, false); err != nil || value {
+		t.Fatalf("ResolveBoolean() after update = %v, %v, want false", value, err)
+	}
+
+	if _, err := svc.ResolveBoolean(ctx, "default", "broken", us, false); !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("ResolveBoolean() of undecodable rules error = %v, want %v", err, ErrInvalidRules)
+	}
+}
+
 func TestServiceDeleteFlagEvictsStaleCacheOnNotFound(t *testing.T) {
 	ctx := context.Background()
 	repo := newFakeServiceRepository()
@@ -389,6 +450,285 @@ func TestServiceRejectsInvalidVariants(t *testing.T) {
 	})
 }
 
+func TestServiceResolveAll(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "beta",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"US"}]`),
+	})
+	repo.setFlag(repository.Flag{ProjectID: "default", Key: "archived"})
+	repo.setFlag(repository.Flag{ProjectID: "other", Key: "elsewhere", Enabled: true})
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	results, err := svc.ResolveAll(ctx, "default", core.EvaluationContext{
+		Attributes: map[string]any{"country": "US"},
+	}, "")
+	if err != nil {
+		t.Fatalf("ResolveAll() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Key != "archived" || results[1].Key != "beta" {
+		t.Fatalf("ResolveAll() = %#v, want archived and beta in key order", results)
+	}
+	if results[0].Value || results[0].Reason != core.ReasonDisabled {
+		t.Errorf("ResolveAll() archived = %#v, want disabled", results[0])
+	}
+	if !results[1].Value || results[1].Reason != core.ReasonRuleMatch {
+		t.Errorf("ResolveAll() beta = %#v, want a rule match", results[1])
+	}
+
+	if _, err := svc.ResolveAll(ctx, "", core.EvaluationContext{}, ""); !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("ResolveAll() without project error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+func TestServiceResolveBatchTyped(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "checkout-theme",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false,"on":"dark","off":"light"}`),
+		Rules:     json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`),
+	})
+	repo.setFlag(repository.Flag{
+		ProjectID: "default",
+		Key:       "page-size",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"on":50}`),
+	})
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	pro := core.EvaluationContext{Attributes: map[string]any{"plan": "pro"}}
+	results, err := svc.ResolveBatch(ctx, []ResolveRequest{
+		{ProjectID: "default", Key: "checkout-theme", Context: pro, TypedDefault: json.RawMessage(`"system"`)},
+		{ProjectID: "default", Key: "checkout-theme", TypedDefault: json.RawMessage(`"system"`)},
+		{ProjectID: "default", Key: "checkout-theme", Context: pro, TypedDefault: json.RawMessage(`{"theme":"system"}`)},
+		{ProjectID: "default", Key: "page-size", TypedDefault: json.RawMessage(`20`)},
+		{ProjectID: "default", Key: "page-size", Context: pro, TypedDefault: json.RawMessage(`20`)},
+		{ProjectID: "default", Key: "missing", TypedDefault: json.RawMessage(`[1,2]`)},
+		{ProjectID: "default", Key: "checkout-theme", Context: pro},
+	})
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
+	}
+
+	want := []struct {
+		typ, value, variant string
+		reason              core.Reason
+	}{
+		{ValueTypeString, `"dark"`, VariantOn, core.ReasonRuleMatch},
+		{ValueTypeString, `"light"`, VariantOff, core.ReasonDefault},
+		{ValueTypeJSON, `{"theme":"system"}`, "", core.ReasonTypeMismatch},
+		// No rules, so the flag evaluates to true.
+		{ValueTypeNumber, `50`, VariantOn, core.ReasonDefault},
+		{ValueTypeNumber, `50`, VariantOn, core.ReasonDefault},
+		{ValueTypeJSON, `[1,2]`, "", core.ReasonFlagNotFound},
+		{ValueTypeBoolean, `true`, "", core.ReasonRuleMatch},
+	}
+	for i, w := range want {
+		got := results[i]
+		encoded, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("results[%d]: Marshal() error = %v", i, err)
+		}
+		var body struct {
+			Value json.RawMessage `json:"value"`
+			Type  string          `json:"type"`
+		}
+		if err := json.Unmarshal(encoded, &body); err != nil {
+			t.Fatalf("results[%d]: Unmarshal() error = %v", i, err)
+		}
+		if body.Type != w.typ || string(body.Value) != w.value || got.Variant != w.variant || got.Reason != w.reason {
+			t.Errorf("results[%d] = %s (variant %q, reason %s), want type %s value %s variant %q reason %s",
+				i, encoded, got.Variant, got.Reason, w.typ, w.value, w.variant, w.reason)
+		}
+	}
+}
+
+func TestTypedValueType(t *testing.T) {
+	for value, want := range map[string]string{
+		`true`:     ValueTypeBoolean,
+		` false`:   ValueTypeBoolean,
+		`"on"`:     ValueTypeString,
+		`-1.5e3`:   ValueTypeNumber,
+		`{"a":1}`:  ValueTypeJSON,
+		`[]`:       ValueTypeJSON,
+		`null`:     "",
+		``:         "",
+		`{invalid`: "",
+	} {
+		if got := TypedValueType(json.RawMessage(value)); got != want {
+			t.Errorf("TypedValueType(%s) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestServiceValidatesVariantsSchema(t *testing.T) {
+	ctx := context.Background()
+	schema := json.RawMessage(`{"type":"object","required":["color","size"],"properties":{"size":{"enum":["s","m","l"]}}}`)
+
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = svc.CreateFlag(ctx, repository.Flag{
+		ProjectID:      "default",
+		Key:            "theme",
+		Variants:       json.RawMessage(`{"red":{"color":"red","size":"xl"},"blue":{"size":"m"}}`),
+		VariantsSchema: schema,
+	})
+	var variantsErr *VariantsError
+	if !errors.As(err, &variantsErr) || !errors.Is(err, ErrInvalidVariants) {
+		t.Fatalf("CreateFlag() error = %v, want *VariantsError", err)
+	}
+	var paths []string
+	for _, schemaErr := range variantsErr.Errors {
+		paths = append(paths, schemaErr.Path)
+	}
+	if want := []string{"/blue", "/red/size"}; !slices.Equal(paths, want) {
+		t.Fatalf("error paths = %v, want %v (%v)", paths, want, err)
+	}
+
+	created, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID:      "default",
+		Key:            "theme",
+		Variants:       json.RawMessage(`{"red":{"color":"red","size":"l"}}`),
+		VariantsSchema: schema,
+	})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if len(created.VariantsSchema) == 0 {
+		t.Fatal("CreateFlag() dropped the variants schema")
+	}
+
+	// An update without a schema keeps, and is checked against, the stored one.
+	_, err = svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID: "default",
+		Key:       "theme",
+		Variants:  json.RawMessage(`{"red":{"color":"red"}}`),
+	})
+	if !errors.As(err, &variantsErr) {
+		t.Fatalf("UpdateFlag() error = %v, want *VariantsError", err)
+	}
+	updated, err := svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID: "default",
+		Key:       "theme",
+		Variants:  json.RawMessage(`{"red":{"color":"red","size":"s"}}`),
+	})
+	if err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if len(updated.VariantsSchema) == 0 {
+		t.Fatal("UpdateFlag() without a schema dropped the stored one")
+	}
+
+	// null removes the schema.
+	updated, err = svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID:      "default",
+		Key:            "theme",
+		Variants:       json.RawMessage(`{"red":"red"}`),
+		VariantsSchema: json.RawMessage(`null`),
+	})
+	if err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if updated.VariantsSchema != nil {
+		t.Fatalf("VariantsSchema = %s, want none", updated.VariantsSchema)
+	}
+
+	_, err = svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID:      "default",
+		Key:            "theme",
+		Variants:       json.RawMessage(`{"red":"red"}`),
+		VariantsSchema: json.RawMessage(`{"$ref":"#/defs/variant"}`),
+	})
+	if !errors.Is(err, ErrInvalidVariantsSchema) {
+		t.Fatalf("UpdateFlag() error = %v, want %v", err, ErrInvalidVariantsSchema)
+	}
+}
+
+func TestServiceNormalizesTags(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	created, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "default",
+		Key:       "checkout-v2",
+		Tags:      []string{"Team:Payments", " checkout ", "checkout"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if want := []string{"checkout", "team:payments"}; !slices.Equal(created.Tags, want) {
+		t.Fatalf("Tags = %v, want %v", created.Tags, want)
+	}
+
+	for _, tags := range [][]string{
+		{"two words"},
+		{"-leading-dash"},
+		{""},
+		{strings.Repeat("a", 65)},
+	} {
+		_, err := svc.UpdateFlag(ctx, repository.Flag{ProjectID: "default", Key: "checkout-v2", Tags: tags})
+		if !errors.Is(err, ErrInvalidTags) {
+			t.Fatalf("UpdateFlag(tags %q) error = %v, want ErrInvalidTags", tags, err)
+		}
+	}
+	tooMany := make([]string, maxFlagTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{ProjectID: "default", Key: "checkout-v2", Tags: tooMany}); !errors.Is(err, ErrInvalidTags) {
+		t.Fatalf("UpdateFlag(%d tags) error = %v, want ErrInvalidTags", len(tooMany), err)
+	}
+}
+
+func TestFilterFlagsByTags(t *testing.T) {
+	flags := []repository.Flag{
+		{Key: "a", Tags: []string{"checkout", "team:payments"}},
+		{Key: "b", Tags: []string{"checkout"}},
+		{Key: "c"},
+	}
+	keys := func(flags []repository.Flag) []string {
+		var keys []string
+		for _, flag := range flags {
+			keys = append(keys, flag.Key)
+		}
+		return keys
+	}
+
+	if got := keys(FilterFlagsByTags(flags, nil)); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("no tags = %v, want every flag", got)
+	}
+	if got := keys(FilterFlagsByTags(flags, []string{"Checkout"})); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("checkout = %v, want [a b]", got)
+	}
+	if got := keys(FilterFlagsByTags(flags, []string{"checkout", "team:payments"})); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("checkout and team:payments = %v, want [a]", got)
+	}
+	if got := FilterFlagsByTags(flags, []string{"missing"}); len(got) != 0 {
+		t.Fatalf("missing = %v, want none", keys(got))
+	}
+}
+
 func TestServiceMutationPublishesWithDetachedContext(t *testing.T) {
 	repo := newFakeServiceRepository()
 	repo.requirePublishActiveContext = true
@@ -563,10 +903,110 @@ func TestServiceAuditLogRecordedOnMutations(t *testing.T) {
 	}
 }
 
+func TestServiceAuditLogRecordsFlagChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	flag := repository.Flag{
+		ProjectID:   "proj1",
+		Key:         "checkout",
+		Description: "new checkout",
+		Variants:    json.RawMessage(`{"default": false}`),
+		Rules:       json.RawMessage(`[]`),
+	}
+	if _, err := svc.CreateFlag(ctx, flag); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	flag.Enabled = true
+	flag.Variants = json.RawMessage(`{"default":false,"on":{"token":"s3cret"}}`)
+	flag.Rules = json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`)
+	flag.Owner = "team:payments"
+	if _, err := svc.UpdateFlag(ctx, flag); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+
+	repo.mu.RLock()
+	entry := repo.auditLogs[len(repo.auditLogs)-1]
+	repo.mu.RUnlock()
+	if entry.Action != "update" {
+		t.Fatalf("Action = %q, want update", entry.Action)
+	}
+	if strings.Contains(string(entry.Details), "s3cret") {
+		t.Fatalf("details = %s, want variant values left out", entry.Details)
+	}
+	var details FlagChangeDetails
+	if err := json.Unmarshal(entry.Details, &details); err != nil {
+		t.Fatalf("decode details %s: %v", entry.Details, err)
+	}
+	want := map[string]FieldChange{
+		"enabled":  {Old: json.RawMessage(`false`), New: json.RawMessage(`true`)},
+		"rules":    {Old: json.RawMessage(`[]`), New: json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`)},
+		"owner":    {Old: json.RawMessage(`null`), New: json.RawMessage(`"team:payments"`)},
+		"variants": {Changed: true},
+	}
+	if len(details.Changes) != len(want) {
+		t.Fatalf("changes = %s, want enabled, rules, owner and variants only", entry.Details)
+	}
+	for name, change := range want {
+		got := details.Changes[name]
+		if string(got.Old) != string(change.Old) || string(got.New) != string(change.New) || got.Changed != change.Changed {
+			t.Errorf("changes[%q] = %+v, want %+v", name, got, change)
+		}
+	}
+}
+
+func TestServiceRecordsActorOnFlagsAndEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	keyCtx := middleware.NewContextWithAPIKeyID(ctx, "key-1")
+	flag := repository.Flag{ProjectID: "proj1", Key: "checkout", CreatedBy: "api_key:forged", UpdatedBy: "api_key:forged"}
+	created, err := svc.CreateFlag(keyCtx, flag)
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if created.CreatedBy != "api_key:key-1" || created.UpdatedBy != "api_key:key-1" {
+		t.Fatalf("created by %q, updated by %q, want api_key:key-1 for both", created.CreatedBy, created.UpdatedBy)
+	}
+
+	adminCtx := middleware.NewContextWithAdminUserID(ctx, "user-1")
+	flag.Enabled = true
+	updated, err := svc.UpdateFlag(adminCtx, flag)
+	if err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if updated.CreatedBy != "api_key:key-1" || updated.UpdatedBy != "admin_user:user-1" {
+		t.Fatalf("created by %q, updated by %q, want api_key:key-1 and admin_user:user-1", updated.CreatedBy, updated.UpdatedBy)
+	}
+
+	if err := svc.DeleteFlag(keyCtx, "proj1", "checkout"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	var actors []string
+	for _, event := range repo.events {
+		actors = append(actors, event.Actor)
+	}
+	if want := []string{"api_key:key-1", "admin_user:user-1", "api_key:key-1"}; !slices.Equal(actors, want) {
+		t.Fatalf("event actors = %v, want %v", actors, want)
+	}
+}
+
 func TestServiceAuditLogIncludesActorIDsFromContext(t *testing.T) {
 	ctx := middleware.NewContextWithProjectID(context.Background(), "proj1")
 	ctx = middleware.NewContextWithAPIKeyID(ctx, "api-key-1")
 	ctx = middleware.NewContextWithAdminUserID(ctx, "admin-user-1")
+	ctx = middleware.NewContextWithRequestID(ctx, "req-1")
 	repo := newFakeServiceRepository()
 
 	svc, err := New(ctx, repo)
@@ -599,6 +1039,9 @@ func TestServiceAuditLogIncludesActorIDsFromContext(t *testing.T) {
 	if repo.auditLogs[0].AdminUserID != "admin-user-1" {
 		t.Fatalf("audit log AdminUserID = %q, want %q", repo.auditLogs[0].AdminUserID, "admin-user-1")
 	}
+	if repo.auditLogs[0].RequestID != "req-1" {
+		t.Fatalf("audit log RequestID = %q, want %q", repo.auditLogs[0].RequestID, "req-1")
+	}
 }
 
 func TestServiceMutationSucceedsWhenAuditLogFails(t *testing.T) {
@@ -629,49 +1072,1516 @@ func TestServiceMutationSucceedsWhenAuditLogFails(t *testing.T) {
 	}
 }
 
-func TestServiceResubscribesAfterInvalidationChannelClose(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	repo := newResubscribingFakeServiceRepository()
-	initial := repository.Flag{
-		ProjectID:   "default",
-		Key:         "new-ui",
-		Description: "initial rollout",
-		Enabled:     false,
-		Variants:    json.RawMessage(`{}`),
-		Rules:       json.RawMessage(`[]`),
-	}
-	repo.setFlag(initial)
+func TestServiceCreateFlagsIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "existing"})
 
 	svc, err := New(ctx, repo)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	updated := initial
-	updated.Description = "updated remotely"
-	updated.Enabled = true
-	repo.setFlag(updated)
-
-	stale, err := svc.GetFlag(ctx, "default", initial.Key)
-	if err != nil {
-		t.Fatalf("GetFlag() error = %v", err)
+	_, err = svc.CreateFlags(ctx, []repository.Flag{
+		{ProjectID: "proj1", Key: "ok"},
+		{ProjectID: "proj1", Key: "bad", Rules: json.RawMessage(`{`)},
+	})
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("CreateFlags() error = %v, want ErrInvalidRules", err)
 	}
-	if stale.Description != initial.Description {
-		t.Fatalf("GetFlag().Description = %q, want stale %q before invalidation", stale.Description, initial.Description)
+	if !strings.Contains(err.Error(), "flag 1") {
+		t.Fatalf("CreateFlags() error = %q, want row index", err)
+	}
+	if _, err := svc.GetFlag(ctx, "proj1", "ok"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("GetFlag(ok) error = %v, want ErrFlagNotFound after rejected batch", err)
 	}
 
-	repo.closeInvalidationChannel()
-	waitForCondition(t, time.Second, func() bool {
-		return repo.subscriptionCalls() >= 2
+	_, err = svc.CreateFlags(ctx, []repository.Flag{
+		{ProjectID: "proj1", Key: "ok"},
+		{ProjectID: "proj1", Key: "existing"},
+		{ProjectID: "proj1", Key: "bad", Rules: json.RawMessage(`[{"attribute":"plan"}]`)},
+		{ProjectID: "proj1", Key: "ok"},
 	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("CreateFlags() error = %v, want *BatchError", err)
+	}
+	var gotIndexes []int
+	for _, item := range batchErr.Items {
+		gotIndexes = append(gotIndexes, item.Index)
+	}
+	if !slices.Equal(gotIndexes, []int{1, 2, 3}) {
+		t.Fatalf("BatchError indexes = %v, want [1 2 3]", gotIndexes)
+	}
+	if !errors.Is(batchErr.Items[0].Err, ErrFlagExists) || !errors.Is(batchErr.Items[2].Err, ErrFlagExists) {
+		t.Fatalf("BatchError items = %v, want ErrFlagExists for existing and repeated keys", batchErr.Items)
+	}
+	var rulesErr *RulesError
+	if !errors.As(batchErr.Items[1].Err, &rulesErr) {
+		t.Fatalf("BatchError item 2 = %v, want *RulesError", batchErr.Items[1].Err)
+	}
 
-	repo.notifyInvalidation()
-	waitForCondition(t, time.Second, func() bool {
-		flag, err := svc.GetFlag(ctx, "default", initial.Key)
-		return err == nil && flag.Description == updated.Description && flag.Enabled == updated.Enabled
+	created, err := svc.CreateFlags(ctx, []repository.Flag{
+		{ProjectID: "proj1", Key: "a", Enabled: true},
+		{ProjectID: "proj1", Key: "b"},
 	})
+	if err != nil {
+		t.Fatalf("CreateFlags() error = %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("len(CreateFlags()) = %d, want 2", len(created))
+	}
+	if flag, err := svc.GetFlag(ctx, "proj1", "a"); err != nil || !flag.Enabled {
+		t.Fatalf("GetFlag(a) = %+v, %v", flag, err)
+	}
+	if len(repo.events) != 2 || len(repo.auditLogs) != 2 {
+		t.Fatalf("events = %d, audit logs = %d, want 2 each", len(repo.events), len(repo.auditLogs))
+	}
+}
+
+func TestServiceCreateFlagAppliesProjectDefaults(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := svc.SetFlagDefaults(ctx, "proj1", repository.FlagDefaults{
+		Rules: json.RawMessage(`[{"attribute":"user_id","operator":"percentage","value":150}]`),
+	}); !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("SetFlagDefaults() error = %v, want ErrInvalidRules", err)
+	}
+
+	skeleton := json.RawMessage(`[{"attribute":"user_id","operator":"percentage","value":0}]`)
+	if _, err := svc.SetFlagDefaults(ctx, "proj1", repository.FlagDefaults{
+		DescriptionTemplate: "Rollout for {{key}}",
+		Rules:               skeleton,
+	}); err != nil {
+		t.Fatalf("SetFlagDefaults() error = %v", err)
+	}
+
+	created, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout", Rules: json.RawMessage(`[]`)})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if created.Description != "Rollout for checkout" {
+		t.Fatalf("Description = %q, want templated description", created.Description)
+	}
+	if string(created.Rules) != string(skeleton) {
+		t.Fatalf("Rules = %s, want default skeleton", created.Rules)
+	}
+
+	batch, err := svc.CreateFlags(ctx, []repository.Flag{
+		{ProjectID: "proj1", Key: "explicit", Description: "kept", Rules: json.RawMessage(`[{"attribute":"country","operator":"equals","value":"GB"}]`)},
+		{ProjectID: "proj2", Key: "other"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlags() error = %v", err)
+	}
+	if batch[0].Description != "kept" || string(batch[0].Rules) == string(skeleton) {
+		t.Fatalf("explicit flag = %+v, want caller values kept", batch[0])
+	}
+	if batch[1].Description != "" {
+		t.Fatalf("other project Description = %q, want empty", batch[1].Description)
+	}
+}
+
+func TestServiceCreateFlagEnforcesKeyPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, policy := range []repository.FlagKeyPolicy{
+		{Pattern: "[a-z"},
+		{MaxLength: -1},
+	} {
+		if _, err := svc.SetFlagKeyPolicy(ctx, "proj1", policy); !errors.Is(err, ErrInvalidFlagKeyPolicy) {
+			t.Fatalf("SetFlagKeyPolicy(%+v) error = %v, want ErrInvalidFlagKeyPolicy", policy, err)
+		}
+	}
+	if _, err := svc.SetFlagKeyPolicy(ctx, "proj1", repository.FlagKeyPolicy{
+		Pattern:   `[a-z]+-[a-z]+-[a-z-]+`,
+		MaxLength: 24,
+		Prefix:    "checkout-",
+	}); err != nil {
+		t.Fatalf("SetFlagKeyPolicy() error = %v", err)
+	}
+
+	for _, key := range []string{
+		"search-new-ranking",                  // wrong prefix
+		"checkout-payments-apple-pay-express", // too long
+		"checkout-Payments-wallet",            // fails the pattern
+		"xcheckout-payments-wallet-",          // pattern is anchored
+	} {
+		_, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: key})
+		if !errors.Is(err, ErrFlagKeyPolicyViolation) {
+			t.Errorf("CreateFlag(%q) error = %v, want ErrFlagKeyPolicyViolation", key, err)
+		}
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout-payments-wallet"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj2", Key: "anything_goes"}); err != nil {
+		t.Fatalf("CreateFlag() in project without a policy error = %v", err)
+	}
+
+	_, err = svc.CreateFlags(ctx, []repository.Flag{
+		{ProjectID: "proj1", Key: "checkout-cart-badge"},
+		{ProjectID: "proj1", Key: "cart-badge"},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Items) != 1 || batchErr.Items[0].Index != 1 ||
+		!errors.Is(batchErr.Items[0].Err, ErrFlagKeyPolicyViolation) {
+		t.Fatalf("CreateFlags() error = %v, want a policy violation for flag 1", err)
+	}
+}
+
+func TestServiceStrictContextSchemaWarns(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true})
+
+	var warned []string
+	svc, err := New(ctx, repo, WithContextWarningMetrics(func(kind string) { warned = append(warned, kind) }))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, schema := range []repository.ContextSchema{
+		{Attributes: []repository.ContextAttribute{{Name: " ", Type: "string"}}},
+		{Attributes: []repository.ContextAttribute{{Name: "country", Type: "text"}}},
+		{Attributes: []repository.ContextAttribute{{Name: "country", Type: "string"}, {Name: "country", Type: "string"}}},
+	} {
+		if _, err := svc.SetContextSchema(ctx, "proj1", schema); !errors.Is(err, ErrInvalidContextSchema) {
+			t.Fatalf("SetContextSchema(%+v) error = %v, want ErrInvalidContextSchema", schema, err)
+		}
+	}
+
+	evalContext := core.EvaluationContext{Attributes: map[string]any{
+		"county": "GB",
+		"age":    "42",
+		"plan":   "pro",
+	}}
+	schema := repository.ContextSchema{Attributes: []repository.ContextAttribute{
+		{Name: "country", Type: repository.ContextAttributeString, Description: "ISO 3166 code"},
+		{Name: "age", Type: repository.ContextAttributeNumber},
+		{Name: "plan", Type: repository.ContextAttributeString},
+	}}
+	if _, err := svc.SetContextSchema(ctx, "proj1", schema); err != nil {
+		t.Fatalf("SetContextSchema() error = %v", err)
+	}
+	result, err := svc.ResolveBooleanDetail(ctx, "proj1", "checkout", evalContext, false)
+	if err != nil || len(result.Warnings) != 0 {
+		t.Fatalf("non-strict ResolveBooleanDetail() = %+v, %v, want no warnings", result, err)
+	}
+
+	schema.Strict = true
+	if _, err := svc.SetContextSchema(ctx, "proj1", schema); err != nil {
+		t.Fatalf("SetContextSchema() error = %v", err)
+	}
+	for _, key := range []string{"checkout", "missing"} {
+		result, err := svc.ResolveBooleanDetail(ctx, "proj1", key, evalContext, false)
+		if err != nil {
+			t.Fatalf("ResolveBooleanDetail(%q) error = %v", key, err)
+		}
+		want := []ContextWarning{
+			{Attribute: "age", Kind: ContextWarningTypeMismatch, Message: `attribute "age" is string, want number`},
+			{Attribute: "county", Kind: ContextWarningUnknownAttribute, Message: `unknown attribute "county"; did you mean "country"?`},
+		}
+		if !slices.Equal(result.Warnings, want) {
+			t.Fatalf("ResolveBooleanDetail(%q) warnings = %+v, want %+v", key, result.Warnings, want)
+		}
+	}
+	if want := []string{ContextWarningTypeMismatch, ContextWarningUnknownAttribute, ContextWarningTypeMismatch, ContextWarningUnknownAttribute}; !slices.Equal(warned, want) {
+		t.Fatalf("warning metrics = %v, want %v", warned, want)
+	}
+
+	valid := core.EvaluationContext{Attributes: map[string]any{"country": "GB", "age": float64(42)}}
+	if result, _ := svc.ResolveBooleanDetail(ctx, "proj1", "checkout", valid, false); len(result.Warnings) != 0 {
+		t.Fatalf("valid context warnings = %+v, want none", result.Warnings)
+	}
+	if result, _ := svc.ResolveBooleanDetail(ctx, "proj2", "checkout", evalContext, false); len(result.Warnings) != 0 {
+		t.Fatalf("project without a schema warnings = %+v, want none", result.Warnings)
+	}
+}
+
+func TestServiceRedactsSensitiveContextAttributes(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true})
+
+	var buf strings.Builder
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	svc, err := New(ctx, repo, WithLogger(log))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := svc.SetContextSchema(ctx, "proj1", repository.ContextSchema{Attributes: []repository.ContextAttribute{
+		{Name: "email", Type: repository.ContextAttributeString, Sensitive: true},
+		{Name: "country", Type: repository.ContextAttributeString},
+	}}); err != nil {
+		t.Fatalf("SetContextSchema() error = %v", err)
+	}
+
+	evalContext := core.EvaluationContext{Attributes: map[string]any{"email": "ada@example.com", "country": "GB"}}
+	if _, err := svc.ResolveBooleanDetail(ctx, "proj1", "checkout", evalContext, false); err != nil {
+		t.Fatalf("ResolveBooleanDetail() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"attributes":{"country":"GB","email":"[REDACTED]"}`) {
+		t.Fatalf("expected the evaluation logged with email redacted, got: %s", out)
+	}
+	if strings.Contains(out, "ada@example.com") {
+		t.Fatalf("sensitive attribute logged: %s", out)
+	}
+}
+
+func TestServiceShadowRules(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	var shadows []bool
+	svc, err := New(ctx, repo, WithShadowEvaluationMetrics(func(projectID, key string, agree bool) {
+		if projectID != "proj1" || key != "eu-launch" {
+			t.Errorf("shadow evaluation of %s/%s, want proj1/eu-launch", projectID, key)
+		}
+		shadows = append(shadows, agree)
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = svc.CreateFlag(ctx, repository.Flag{
+		ProjectID:   "proj1",
+		Key:         "eu-launch",
+		ShadowRules: json.RawMessage(`[{"attribute":"country","operator":"matches","value":"FR"}]`),
+	})
+	var rulesErr *RulesError
+	if !errors.As(err, &rulesErr) || rulesErr.Field != "shadow_rules" {
+		t.Fatalf("CreateFlag(invalid shadow rules) error = %v, want a RulesError on shadow_rules", err)
+	}
+
+	if _, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID:   "proj1",
+		Key:         "eu-launch",
+		Enabled:     true,
+		Variants:    json.RawMessage(`{"default":false}`),
+		Rules:       json.RawMessage(`[{"attribute":"country","operator":"equals","value":"FR"}]`),
+		ShadowRules: json.RawMessage(`[{"attribute":"country","operator":"in","value":["FR","DE"]}]`),
+	}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+
+	for _, tc := range []struct {
+		country string
+		want    bool
+	}{{"FR", true}, {"DE", false}, {"GB", false}} {
+		result, err := svc.ResolveBooleanDetail(ctx, "proj1", "eu-launch", core.EvaluationContext{Attributes: map[string]any{"country": tc.country}}, false)
+		if err != nil {
+			t.Fatalf("ResolveBooleanDetail(%s) error = %v", tc.country, err)
+		}
+		// The shadow rules never change the result.
+		if result.Value != tc.want {
+			t.Errorf("ResolveBooleanDetail(%s) = %v, want %v", tc.country, result.Value, tc.want)
+		}
+	}
+	if want := []bool{true, false, true}; !slices.Equal(shadows, want) {
+		t.Fatalf("shadow agreement = %v, want %v", shadows, want)
+	}
+
+	// Updates without shadow rules keep them; JSON null removes them.
+	flag, err := svc.GetFlag(ctx, "proj1", "eu-launch")
+	if err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	flag.ShadowRules = nil
+	updated, err := svc.UpdateFlag(ctx, flag)
+	if err != nil || updated.ShadowRules == nil {
+		t.Fatalf("UpdateFlag(no shadow rules) = %s, %v; want them kept", updated.ShadowRules, err)
+	}
+	flag.ShadowRules = json.RawMessage("null")
+	if updated, err = svc.UpdateFlag(ctx, flag); err != nil || updated.ShadowRules != nil {
+		t.Fatalf("UpdateFlag(null shadow rules) = %s, %v; want them removed", updated.ShadowRules, err)
+	}
+	shadows = nil
+	if _, err := svc.ResolveBooleanDetail(ctx, "proj1", "eu-launch", core.EvaluationContext{Attributes: map[string]any{"country": "DE"}}, false); err != nil {
+		t.Fatalf("ResolveBooleanDetail() error = %v", err)
+	}
+	if len(shadows) != 0 {
+		t.Fatalf("shadow evaluations without shadow rules = %v, want none", shadows)
+	}
+}
+
+func TestServiceContextEnrichment(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "eu-prod",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"environment","operator":"equals","value":"prod"}]`),
+	})
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "eu-only",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"region","operator":"equals","value":"eu"}]`),
+	})
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "internal-key",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"api_key","operator":"equals","value":"key-1"}]`),
+	})
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, enrichment := range []repository.ContextEnrichment{
+		{Attributes: map[string]any{" ": "x"}},
+		{Attributes: map[string]any{"region": "eu"}, Headers: map[string]string{"region": "X-Region"}},
+		{Headers: map[string]string{"region": "X Region"}},
+		{Attributes: map[string]any{"api_key": "x"}, APIKeyIDAttribute: "api_key"},
+	} {
+		if _, err := svc.SetContextEnrichment(ctx, "proj1", enrichment); !errors.Is(err, ErrInvalidContextEnrichment) {
+			t.Fatalf("SetContextEnrichment(%+v) error = %v, want ErrInvalidContextEnrichment", enrichment, err)
+		}
+	}
+
+	stored, err := svc.SetContextEnrichment(ctx, "proj1", repository.ContextEnrichment{
+		Attributes:        map[string]any{"environment": "prod"},
+		Headers:           map[string]string{"region": "x-region"},
+		APIKeyIDAttribute: "api_key",
+	})
+	if err != nil {
+		t.Fatalf("SetContextEnrichment() error = %v", err)
+	}
+	if stored.Headers["region"] != "X-Region" {
+		t.Fatalf("stored headers = %v, want canonical X-Region", stored.Headers)
+	}
+
+	headers := map[string]string{"X-Region": "eu"}
+	reqCtx := NewContextWithRequestHeaders(middleware.NewContextWithAPIKeyID(ctx, "key-1"), func(name string) string { return headers[name] })
+	results, err := svc.ResolveBatch(reqCtx, []ResolveRequest{
+		{ProjectID: "proj1", Key: "eu-prod"},
+		{ProjectID: "proj1", Key: "eu-only"},
+		{ProjectID: "proj1", Key: "internal-key"},
+		// The client's own attributes win over enrichment.
+		{ProjectID: "proj1", Key: "eu-prod", Context: core.EvaluationContext{Attributes: map[string]any{"environment": "staging"}}},
+		{ProjectID: "proj1", Key: "eu-only", Context: core.EvaluationContext{Attributes: map[string]any{"region": "us"}}},
+	})
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
+	}
+	for i, want := range []bool{true, true, true, false, false} {
+		if results[i].Value != want {
+			t.Errorf("results[%d] = %+v, want value %v", i, results[i], want)
+		}
+	}
+
+	// Without the header or key, nothing is added for them.
+	if result, _ := svc.ResolveBooleanDetail(ctx, "proj1", "eu-only", core.EvaluationContext{}, false); result.Value {
+		t.Errorf("eu-only without header = %+v, want false", result)
+	}
+	if result, _ := svc.ResolveBooleanDetail(ctx, "proj1", "internal-key", core.EvaluationContext{}, false); result.Value {
+		t.Errorf("internal-key without API key = %+v, want false", result)
+	}
+
+	got, err := svc.GetContextEnrichment(ctx, "proj2")
+	if err != nil || got.Attributes == nil || got.Headers == nil {
+		t.Fatalf("GetContextEnrichment(unconfigured) = %+v, %v, want empty maps", got, err)
+	}
+}
+
+func TestServiceFlagProposalRequiresSecondApprover(t *testing.T) {
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout"})
+
+	svc, err := New(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	alice := middleware.NewContextWithAPIKeyID(context.Background(), "alice")
+	bob := middleware.NewContextWithAdminUserID(context.Background(), "bob")
+
+	if _, err := svc.ProposeFlagChange(context.Background(), repository.FlagProposal{
+		ProjectID: "proj1", FlagKey: "checkout", Action: repository.ProposalActionDelete,
+	}); !errors.Is(err, ErrActorRequired) {
+		t.Fatalf("anonymous ProposeFlagChange() error = %v, want ErrActorRequired", err)
+	}
+	if _, err := svc.ProposeFlagChange(alice, repository.FlagProposal{
+		ProjectID: "proj1", FlagKey: "checkout", Action: repository.ProposalActionUpdate,
+	}); !errors.Is(err, ErrInvalidProposal) {
+		t.Fatalf("ProposeFlagChange() without flag error = %v, want ErrInvalidProposal", err)
+	}
+
+	proposal, err := svc.ProposeFlagChange(alice, repository.FlagProposal{
+		ProjectID: "proj1",
+		FlagKey:   "checkout",
+		Action:    repository.ProposalActionUpdate,
+		Flag:      json.RawMessage(`{"enabled":true,"description":"go live"}`),
+	})
+	if err != nil {
+		t.Fatalf("ProposeFlagChange() error = %v", err)
+	}
+	if flag, _ := svc.GetFlag(context.Background(), "proj1", "checkout"); flag.Enabled {
+		t.Fatal("flag changed before approval")
+	}
+
+	if _, err := svc.ApproveFlagProposal(alice, "proj1", proposal.ID); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("self ApproveFlagProposal() error = %v, want ErrSelfApproval", err)
+	}
+
+	approved, err := svc.ApproveFlagProposal(bob, "proj1", proposal.ID)
+	if err != nil {
+		t.Fatalf("ApproveFlagProposal() error = %v", err)
+	}
+	if approved.Status != repository.ProposalStatusApproved || approved.ReviewedByAdminUserID != "bob" {
+		t.Fatalf("approved = %+v", approved)
+	}
+	flag, err := svc.GetFlag(context.Background(), "proj1", "checkout")
+	if err != nil || !flag.Enabled || flag.Description != "go live" {
+		t.Fatalf("GetFlag() after approval = %+v, %v", flag, err)
+	}
+
+	if _, err := svc.RejectFlagProposal(bob, "proj1", proposal.ID); !errors.Is(err, ErrProposalNotPending) {
+		t.Fatalf("RejectFlagProposal() on approved error = %v, want ErrProposalNotPending", err)
+	}
+	if _, err := svc.ApproveFlagProposal(bob, "proj1", "missing"); !errors.Is(err, ErrProposalNotFound) {
+		t.Fatalf("ApproveFlagProposal(missing) error = %v, want ErrProposalNotFound", err)
+	}
+}
+
+func TestServiceDeleteAndRestoreProject(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true})
+	repo.setFlag(repository.Flag{ProjectID: "proj2", Key: "search", Enabled: true})
+
+	svc, err := New(ctx, repo, WithProjectRetention(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := svc.RestoreProject(ctx, "proj1"); !errors.Is(err, ErrProjectNotDeleted) {
+		t.Fatalf("RestoreProject() on live project error = %v, want ErrProjectNotDeleted", err)
+	}
+	if err := svc.DeleteProject(ctx, "proj1"); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if err := svc.DeleteProject(ctx, "proj1"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("second DeleteProject() error = %v, want ErrProjectNotFound", err)
+	}
+
+	if _, err := svc.GetFlag(ctx, "proj1", "checkout"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("GetFlag() on deleted project error = %v, want ErrFlagNotFound", err)
+	}
+	if enabled, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{}, false); err != nil || enabled {
+		t.Fatalf("ResolveBoolean() on deleted project = %v, %v, want default", enabled, err)
+	}
+	if flags, _ := svc.ListFlags(ctx, "proj1"); len(flags) != 0 {
+		t.Fatalf("ListFlags() on deleted project = %v, want none", flags)
+	}
+	if _, err := svc.GetFlag(ctx, "proj2", "search"); err != nil {
+		t.Fatalf("GetFlag() on other project error = %v", err)
+	}
+
+	if err := svc.RestoreProject(ctx, "proj1"); err != nil {
+		t.Fatalf("RestoreProject() error = %v", err)
+	}
+	if enabled, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{}, false); err != nil || !enabled {
+		t.Fatalf("ResolveBoolean() after restore = %v, %v", enabled, err)
+	}
+
+	actions := make([]string, 0, len(repo.auditLogs))
+	for _, entry := range repo.auditLogs {
+		actions = append(actions, entry.Action)
+	}
+	if got := strings.Join(actions, ","); got != "delete_project,restore_project" {
+		t.Fatalf("audit actions = %q", got)
+	}
+}
+
+func TestServiceRestoreProjectAfterRetention(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout"})
+
+	svc, err := New(ctx, repo, WithProjectRetention(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	repo.deletedAt["proj1"] = time.Now().Add(-2 * time.Hour)
+
+	if err := svc.RestoreProject(ctx, "proj1"); !errors.Is(err, ErrProjectRestoreExpired) {
+		t.Fatalf("RestoreProject() error = %v, want ErrProjectRestoreExpired", err)
+	}
+	purged, err := svc.PurgeDeletedProjects(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedProjects() = %d, %v, want 1", purged, err)
+	}
+	if err := svc.RestoreProject(ctx, "proj1"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("RestoreProject() after purge error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestServiceFlagStatsCountsEvaluations(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true})
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "unused"})
+
+	svc, err := New(ctx, repo, WithStatsFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for range 2 {
+		if _, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{}, false); err != nil {
+			t.Fatalf("ResolveBoolean() error = %v", err)
+		}
+	}
+	// Missing flags are not counted.
+	if _, err := svc.ResolveBoolean(ctx, "proj1", "missing", core.EvaluationContext{}, false); err != nil {
+		t.Fatalf("ResolveBoolean(missing) error = %v", err)
+	}
+
+	stats, err := svc.GetFlagStats(ctx, "proj1", "checkout")
+	if err != nil || stats.Evaluations != 2 || stats.TrueEvaluations != 2 || stats.LastEvaluatedAt == nil {
+		t.Fatalf("GetFlagStats() before flush = %+v, %v, want 2 true evaluations", stats, err)
+	}
+
+	repo.flagStatsErr = errors.New("db down")
+	if err := svc.FlushStats(ctx); err == nil {
+		t.Fatal("FlushStats() error = nil, want failure")
+	}
+	repo.flagStatsErr = nil
+	if err := svc.FlushStats(ctx); err != nil {
+		t.Fatalf("FlushStats() error = %v", err)
+	}
+	if got := repo.flagStats["proj1/checkout"]; got.Evaluations != 2 || got.TrueEvaluations != 2 {
+		t.Fatalf("stored stats = %+v, want 2 true evaluations (kept across failed flush)", got)
+	}
+
+	if _, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{}, false); err != nil {
+		t.Fatalf("ResolveBoolean() error = %v", err)
+	}
+	all, err := svc.ListFlagStats(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("ListFlagStats() error = %v", err)
+	}
+	if got := all["checkout"].Evaluations; got != 3 {
+		t.Fatalf("ListFlagStats()[checkout] = %d, want 3", got)
+	}
+	if _, ok := all["unused"]; ok {
+		t.Fatal("never-evaluated flag should have no stats")
+	}
+
+	unused, err := svc.GetFlagStats(ctx, "proj1", "unused")
+	if err != nil || unused.Evaluations != 0 || unused.LastEvaluatedAt != nil {
+		t.Fatalf("GetFlagStats(unused) = %+v, %v, want zero", unused, err)
+	}
+	if _, err := svc.GetFlagStats(ctx, "proj1", "missing"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("GetFlagStats(missing) error = %v, want ErrFlagNotFound", err)
+	}
+}
+
+func TestServiceEvaluationCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true, Variants: json.RawMessage(`{"default":false}`), Rules: json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`)})
+
+	var hits, misses int
+	svc, err := New(ctx, repo,
+		WithEvaluationCache(time.Minute, 10),
+		WithEvaluationCacheMetrics(func(hit bool) {
+			if hit {
+				hits++
+			} else {
+				misses++
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Unix(1_000, 0)
+	svc.memo.now = func() time.Time { return now }
+
+	resolve := func(plan string) bool {
+		t.Helper()
+		got, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{Attributes: map[string]any{"plan": plan}}, false)
+		if err != nil {
+			t.Fatalf("ResolveBoolean(%q) error = %v", plan, err)
+		}
+		return got
+	}
+
+	if !resolve("pro") || !resolve("pro") || resolve("free") {
+		t.Fatal("ResolveBoolean() = wrong values, want true, true, false")
+	}
+	if hits != 1 || misses != 2 {
+		t.Fatalf("hits, misses = %d, %d, want 1, 2", hits, misses)
+	}
+	stats, err := svc.GetFlagStats(ctx, "proj1", "checkout")
+	if err != nil || stats.Evaluations != 3 {
+		t.Fatalf("GetFlagStats() = %+v, %v, want cached results counted", stats, err)
+	}
+
+	// Updating the flag drops its cached results.
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: false}); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if resolve("pro") {
+		t.Fatal("ResolveBoolean() after disabling = true, want a fresh evaluation")
+	}
+	if hits != 1 || misses != 3 {
+		t.Fatalf("hits, misses after update = %d, %d, want 1, 3", hits, misses)
+	}
+
+	// Results expire after the TTL.
+	resolve("pro")
+	now = now.Add(time.Minute)
+	resolve("pro")
+	if hits != 2 || misses != 4 {
+		t.Fatalf("hits, misses after expiry = %d, %d, want 2, 4", hits, misses)
+	}
+
+	// A full cache starts over.
+	for i := range 12 {
+		resolve(fmt.Sprintf("plan-%d", i))
+	}
+	if size := svc.memo.size; size > 10 {
+		t.Fatalf("memo size = %d, want at most 10", size)
+	}
+}
+
+func TestServiceProjectGraph(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true, Tags: []string{"payments", "web"}, Owner: "team:payments"})
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "banner", Tags: []string{"web"}})
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "legacy"})
+	repo.setFlag(repository.Flag{ProjectID: "proj2", Key: "elsewhere", Owner: "team:other"})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	graph, err := svc.ProjectGraph(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("ProjectGraph() error = %v", err)
+	}
+
+	var ids []string
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	wantIDs := []string{"flag:banner", "flag:checkout", "flag:legacy", "tag:payments", "tag:web", "owner:team:payments"}
+	if !slices.Equal(ids, wantIDs) {
+		t.Fatalf("node IDs = %v, want %v", ids, wantIDs)
+	}
+	if enabled := graph.Nodes[1].Enabled; enabled == nil || !*enabled {
+		t.Fatalf("checkout enabled = %v, want true", enabled)
+	}
+	if !graph.Nodes[2].Orphaned || graph.Nodes[0].Orphaned {
+		t.Fatalf("orphaned = %v/%v, want only legacy", graph.Nodes[0].Orphaned, graph.Nodes[2].Orphaned)
+	}
+	if !slices.Equal(graph.Orphans, []string{"legacy"}) {
+		t.Fatalf("Orphans = %v, want [legacy]", graph.Orphans)
+	}
+	wantEdges := []GraphEdge{
+		{From: "flag:banner", To: "tag:web", Kind: GraphEdgeTagged},
+		{From: "flag:checkout", To: "tag:payments", Kind: GraphEdgeTagged},
+		{From: "flag:checkout", To: "tag:web", Kind: GraphEdgeTagged},
+		{From: "flag:checkout", To: "owner:team:payments", Kind: GraphEdgeOwnedBy},
+	}
+	if !slices.Equal(graph.Edges, wantEdges) {
+		t.Fatalf("Edges = %v, want %v", graph.Edges, wantEdges)
+	}
+
+	if _, err := svc.ProjectGraph(ctx, " "); !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("ProjectGraph(blank) error = %v, want ErrProjectIDRequired", err)
+	}
+}
+
+func TestServiceStaleFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	targeting := json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`)
+	defaultOff := json.RawMessage(`{"default":false}`)
+	// Evaluated recently, targeted and recently changed: not stale.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "active", Enabled: true, Variants: defaultOff, Rules: targeting, CreatedAt: old, UpdatedAt: recent})
+	// Never evaluated since it was created long ago.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "dead", Enabled: true, Variants: defaultOff, Rules: targeting, CreatedAt: old, UpdatedAt: old})
+	// Fully rolled out and untouched: hard-code it.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "launched", Enabled: true, CreatedAt: old, UpdatedAt: old})
+	// New and always on, but too young to be a cleanup candidate.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "fresh", Enabled: true, CreatedAt: recent, UpdatedAt: recent})
+
+	svc, err := New(ctx, repo, WithStatsFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, key := range []string{"active", "launched"} {
+		if _, err := svc.ResolveBoolean(ctx, "proj1", key, core.EvaluationContext{}, false); err != nil {
+			t.Fatalf("ResolveBoolean(%s) error = %v", key, err)
+		}
+	}
+
+	report, err := svc.StaleFlags(ctx, "proj1", StaleThresholds{})
+	if err != nil {
+		t.Fatalf("StaleFlags() error = %v", err)
+	}
+	if report.Thresholds.NotEvaluatedFor != defaultStaleNotEvaluatedFor || report.Thresholds.NotModifiedFor != defaultStaleNotModifiedFor {
+		t.Fatalf("thresholds = %+v, want defaults", report.Thresholds)
+	}
+
+	got := make(map[string]StaleFlag)
+	for _, flag := range report.Flags {
+		got[flag.Key] = flag
+	}
+	if _, ok := got["active"]; ok {
+		t.Errorf("active flag reported as stale: %+v", got["active"])
+	}
+	if dead := got["dead"]; !dead.CleanupCandidate || !slices.Equal(dead.Reasons, []string{StaleReasonNotEvaluated, StaleReasonNotModified}) {
+		t.Errorf("dead = %+v, want unevaluated cleanup candidate", dead)
+	}
+	if launched := got["launched"]; !launched.CleanupCandidate || launched.Evaluations != 1 ||
+		!slices.Equal(launched.Reasons, []string{StaleReasonNotModified, StaleReasonAlwaysOn}) ||
+		launched.Suggestion != "Always true and unchanged for 90 days; replace checks with true and delete it." {
+		t.Errorf("launched = %+v, want always-on cleanup candidate", launched)
+	}
+	if fresh := got["fresh"]; fresh.CleanupCandidate || !slices.Equal(fresh.Reasons, []string{StaleReasonAlwaysOn}) {
+		t.Errorf("fresh = %+v, want always on without cleanup", fresh)
+	}
+
+	// A tighter threshold turns the recently changed flag stale too.
+	report, err = svc.StaleFlags(ctx, "proj1", StaleThresholds{NotModifiedFor: time.Minute})
+	if err != nil {
+		t.Fatalf("StaleFlags(1m) error = %v", err)
+	}
+	if len(report.Flags) != 4 {
+		t.Fatalf("StaleFlags(1m) = %+v, want all 4 flags", report.Flags)
+	}
+}
+
+func TestServiceReshuffleFlagRotatesBuckets(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID:     "proj1",
+		Key:           "checkout",
+		Enabled:       true,
+		Variants:      json.RawMessage(`{"default":false}`),
+		Rules:         json.RawMessage(`[{"attribute":"user_id","operator":"percentage","value":50}]`),
+		BucketingSalt: "initial",
+	})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	before, err := svc.BucketFor(ctx, "proj1", "checkout", "user-42")
+	if err != nil {
+		t.Fatalf("BucketFor() error = %v", err)
+	}
+	again, err := svc.BucketFor(ctx, "proj1", "checkout", "user-42")
+	if err != nil {
+		t.Fatalf("BucketFor() error = %v", err)
+	}
+	if before.Bucket != again.Bucket {
+		t.Fatalf("bucket changed between lookups: %d != %d", before.Bucket, again.Bucket)
+	}
+	if len(before.Rollouts) != 1 || before.Rollouts[0].Attribute != "user_id" || before.Rollouts[0].Percentage != 50 {
+		t.Fatalf("rollouts = %+v, want single 50%% user_id rollout", before.Rollouts)
+	}
+	resolved, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{
+		Attributes: map[string]any{"user_id": "user-42"},
+	}, false)
+	if err != nil {
+		t.Fatalf("ResolveBoolean() error = %v", err)
+	}
+	if resolved != before.Rollouts[0].InRollout {
+		t.Fatalf("ResolveBoolean() = %v, want in_rollout %v", resolved, before.Rollouts[0].InRollout)
+	}
+
+	flag, err := svc.ReshuffleFlag(ctx, "proj1", "checkout")
+	if err != nil {
+		t.Fatalf("ReshuffleFlag() error = %v", err)
+	}
+	if flag.BucketingSalt == "initial" {
+		t.Fatal("ReshuffleFlag() did not rotate the salt")
+	}
+	after, err := svc.BucketFor(ctx, "proj1", "checkout", "user-42")
+	if err != nil {
+		t.Fatalf("BucketFor() error = %v", err)
+	}
+	if after.BucketingSalt != flag.BucketingSalt {
+		t.Fatalf("BucketFor() salt = %q, want cached %q", after.BucketingSalt, flag.BucketingSalt)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != EventTypeUpdated {
+		t.Fatalf("events = %+v, want one updated event", repo.events)
+	}
+
+	if _, err := svc.ReshuffleFlag(ctx, "proj1", "missing"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("ReshuffleFlag(missing) error = %v, want ErrFlagNotFound", err)
+	}
+	if _, err := svc.BucketFor(ctx, "proj1", "checkout", ""); !errors.Is(err, ErrTargetingKeyRequired) {
+		t.Fatalf("BucketFor(\"\") error = %v, want ErrTargetingKeyRequired", err)
+	}
+}
+
+func TestServiceRejectsInvalidRolloutPercentage(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "rollout",
+		Rules:     json.RawMessage(`[{"attribute":"user_id","operator":"percentage","value":150}]`),
+	})
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("CreateFlag() error = %v, want ErrInvalidRules", err)
+	}
+}
+
+func TestServiceReportsEachInvalidRule(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Rules: json.RawMessage(`[
+			{"attribute":"plan","operator":"equals","value":"pro"},
+			{"attribute":"country","operator":"matches","value":"G.*"},
+			"plan",
+			{"attribute":"customAttr","operator":"in","value":[]}
+		]`),
+	})
+	var rulesErr *RulesError
+	if !errors.As(err, &rulesErr) || !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("CreateFlag() error = %v, want *RulesError wrapping ErrInvalidRules", err)
+	}
+
+	want := []core.RuleError{
+		{Index: 1, Reason: `unknown operator "matches"`},
+		{Index: 2, Reason: "rule must be an object with attribute, operator and value"},
+		{Index: 3, Reason: "in value must be a non-empty list of strings, numbers or booleans"},
+	}
+	if !slices.Equal(rulesErr.Rules, want) {
+		t.Fatalf("RulesError.Rules = %+v, want %+v", rulesErr.Rules, want)
+	}
+}
+
+func TestServiceUsesConfiguredInvalidationTransport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newNotifyingFakeServiceRepository()
+	transport := &fakeInvalidationTransport{invalidations: make(chan repository.FlagInvalidation, 1)}
+	svc, err := New(ctx, repo, WithInvalidationTransport(transport))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	created, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "remote"})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if got := transport.publishedKeys(); len(got) != 1 || got[0] != created.Key {
+		t.Fatalf("published invalidations = %v, want [%s]", got, created.Key)
+	}
+
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "from-other-replica"})
+	transport.invalidations <- repository.FlagInvalidation{}
+	waitForCondition(t, time.Second, func() bool {
+		_, err := svc.GetFlag(ctx, "proj1", "from-other-replica")
+		return err == nil
+	})
+}
+
+func TestServiceReadyWaitsForInvalidationSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	plain, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := plain.Ready(ctx); err != nil {
+		t.Fatalf("Ready() without a listener = %v, want nil", err)
+	}
+
+	repo := &listeningFakeServiceRepository{fakeServiceRepository: newFakeServiceRepository()}
+	svc, err := New(ctx, repo, WithWarmupTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := svc.Ready(ctx); !errors.Is(err, ErrWarmingUp) {
+		t.Fatalf("Ready() before LISTEN = %v, want ErrWarmingUp", err)
+	}
+
+	repo.listening.Store(true)
+	if err := svc.Ready(ctx); err != nil {
+		t.Fatalf("Ready() after LISTEN = %v, want nil", err)
+	}
+	repo.listening.Store(false)
+	if err := svc.Ready(ctx); err != nil {
+		t.Fatalf("Ready() after LISTEN dropped = %v, want nil (readiness is latched)", err)
+	}
+
+	noWait, err := New(ctx, &listeningFakeServiceRepository{fakeServiceRepository: newFakeServiceRepository()}, WithWarmupTimeout(0))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := noWait.Ready(ctx); err != nil {
+		t.Fatalf("Ready() with zero warm-up timeout = %v, want nil", err)
+	}
+}
+
+func TestServiceReadinessComponentsAndShutdown(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &pingingFakeServiceRepository{
+		listeningFakeServiceRepository: &listeningFakeServiceRepository{fakeServiceRepository: newFakeServiceRepository()},
+	}
+	repo.pingErr = errors.New("connection refused")
+	svc, err := New(ctx, repo, WithWarmupTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	repo.listening.Store(true)
+
+	readiness := svc.Readiness(ctx)
+	if !errors.Is(readiness.Err, ErrDatabaseUnavailable) {
+		t.Fatalf("Readiness().Err with failing ping = %v, want ErrDatabaseUnavailable", readiness.Err)
+	}
+	want := map[string]string{
+		ComponentCache:        StatusReady,
+		ComponentDatabase:     StatusNotReady,
+		ComponentInvalidation: StatusReady,
+	}
+	for component, status := range want {
+		if got := readiness.Components[component].Status; got != status {
+			t.Errorf("component %q status = %q, want %q", component, got, status)
+		}
+	}
+	if reason := readiness.Components[ComponentDatabase].Reason; !strings.Contains(reason, "connection refused") {
+		t.Errorf("database reason = %q, want ping error", reason)
+	}
+
+	repo.pingErr = nil
+	if err := svc.Ready(ctx); err != nil {
+		t.Fatalf("Ready() after ping succeeds = %v, want nil", err)
+	}
+
+	svc.BeginShutdown()
+	readiness = svc.Readiness(ctx)
+	if !errors.Is(readiness.Err, ErrShuttingDown) {
+		t.Fatalf("Readiness().Err after BeginShutdown = %v, want ErrShuttingDown", readiness.Err)
+	}
+	if got := readiness.Components[ComponentDatabase].Status; got != StatusReady {
+		t.Errorf("database status after BeginShutdown = %q, want %q", got, StatusReady)
+	}
+}
+
+func TestServiceSetFlagTargets(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"NZ"}]`),
+	})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	flag, err := svc.SetFlagTargets(ctx, "proj1", "checkout", repository.FlagTargets{
+		Attribute: " user_id ",
+		Allow:     []string{"alice", " alice", "bob"},
+		Deny:      []string{"mallory"},
+	})
+	if err != nil {
+		t.Fatalf("SetFlagTargets() error = %v", err)
+	}
+	if flag.Targets.Attribute != "user_id" || !slices.Equal(flag.Targets.Allow, []string{"alice", "bob"}) {
+		t.Fatalf("targets = %+v, want trimmed and de-duplicated", flag.Targets)
+	}
+
+	evaluate := func(attrs map[string]any) bool {
+		t.Helper()
+		value, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{Attributes: attrs}, false)
+		if err != nil {
+			t.Fatalf("ResolveBoolean() error = %v", err)
+		}
+		return value
+	}
+	if !evaluate(map[string]any{"user_id": "alice"}) {
+		t.Error("allowed identifier evaluated to false")
+	}
+	if evaluate(map[string]any{"user_id": "mallory", "country": "NZ"}) {
+		t.Error("denied identifier evaluated to true despite a matching rule")
+	}
+	if !evaluate(map[string]any{"user_id": "carol", "country": "NZ"}) {
+		t.Error("unlisted identifier did not fall through to rules")
+	}
+	if flags, _ := svc.ListFlagsReferencingAttribute(ctx, "proj1", "user_id"); len(flags) != 1 {
+		t.Errorf("flags referencing user_id = %+v, want checkout", flags)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != EventTypeUpdated {
+		t.Errorf("events = %+v, want one updated event", repo.events)
+	}
+
+	cleared, err := svc.SetFlagTargets(ctx, "proj1", "checkout", repository.FlagTargets{Attribute: "user_id"})
+	if err != nil {
+		t.Fatalf("SetFlagTargets(empty) error = %v", err)
+	}
+	if !cleared.Targets.IsZero() {
+		t.Errorf("targets = %+v, want none", cleared.Targets)
+	}
+
+	for name, targets := range map[string]repository.FlagTargets{
+		"missing attribute": {Allow: []string{"alice"}},
+		"blank identifier":  {Attribute: "user_id", Deny: []string{" "}},
+		"too many":          {Attribute: "user_id", Allow: make([]string, maxFlagTargets+1)},
+	} {
+		if _, err := svc.SetFlagTargets(ctx, "proj1", "checkout", targets); !errors.Is(err, ErrInvalidTargets) {
+			t.Errorf("SetFlagTargets(%s) error = %v, want ErrInvalidTargets", name, err)
+		}
+	}
+	if _, err := svc.SetFlagTargets(ctx, "proj1", "missing", repository.FlagTargets{}); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("SetFlagTargets(missing) error = %v, want ErrFlagNotFound", err)
+	}
+}
+
+func TestServicePatchFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID:   "proj1",
+		Key:         "checkout",
+		Description: "New checkout",
+		Variants:    json.RawMessage(`{"on":"blue","off":"grey"}`),
+		Rules:       json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`),
+		ShadowRules: json.RawMessage(`[]`),
+		Tags:        []string{"payments"},
+	})
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	flag, err := svc.PatchFlag(ctx, "proj1", "checkout", PatchTypeMerge, json.RawMessage(`{"enabled":true,"variants":{"off":null}}`))
+	if err != nil {
+		t.Fatalf("PatchFlag(merge) error = %v", err)
+	}
+	if !flag.Enabled || flag.Description != "New checkout" || string(flag.Variants) != `{"on":"blue"}` || !strings.Contains(string(flag.Rules), `"pro"`) {
+		t.Fatalf("PatchFlag(merge) = %+v, want enabled with the other fields kept", flag)
+	}
+	if cached, _ := svc.GetFlag(ctx, "proj1", "checkout"); !cached.Enabled {
+		t.Fatal("cached flag not updated")
+	}
+
+	flag, err = svc.PatchFlag(ctx, "proj1", "checkout", PatchTypeJSON, json.RawMessage(`[
+		{"op":"test","path":"/enabled","value":true},
+		{"op":"add","path":"/rules/-","value":{"attribute":"country","operator":"in","value":["GB"]}},
+		{"op":"remove","path":"/shadow_rules"},
+		{"op":"remove","path":"/tags"}
+	]`))
+	if err != nil {
+		t.Fatalf("PatchFlag(json) error = %v", err)
+	}
+	if !strings.Contains(string(flag.Rules), `"country"`) || flag.ShadowRules != nil || len(flag.Tags) != 0 {
+		t.Fatalf("PatchFlag(json) = %+v, want a rule added and shadow rules and tags removed", flag)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		patchType string
+		patch     string
+		want      error
+	}{
+		{"failed test", "checkout", PatchTypeJSON, `[{"op":"test","path":"/enabled","value":false},{"op":"replace","path":"/enabled","value":false}]`, ErrPatchTestFailed},
+		{"key change", "checkout", PatchTypeMerge, `{"key":"other"}`, ErrInvalidPatch},
+		{"targets change", "checkout", PatchTypeMerge, `{"targets":{"attribute":"user_id","allow":["alice"]}}`, ErrInvalidPatch},
+		{"unknown field", "checkout", PatchTypeMerge, `{"enabeld":false}`, ErrInvalidPatch},
+		{"not an object", "checkout", PatchTypeMerge, `[]`, ErrInvalidPatch},
+		{"unknown patch type", "checkout", "application/json", `{}`, ErrInvalidPatch},
+		{"invalid rules", "checkout", PatchTypeMerge, `{"rules":[{"attribute":"plan","operator":"matches","value":"x"}]}`, ErrInvalidRules},
+		{"missing flag", "missing", PatchTypeMerge, `{"enabled":false}`, ErrFlagNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.PatchFlag(ctx, "proj1", tt.key, tt.patchType, json.RawMessage(tt.patch)); !errors.Is(err, tt.want) {
+				t.Fatalf("PatchFlag() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if stored, _ := repo.GetFlag(ctx, "proj1", "checkout"); !stored.Enabled {
+		t.Fatal("rejected patches changed the stored flag")
+	}
+}
+
+func TestServiceCreateFlagFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	rollout, err := svc.CreateFlagFromTemplate(ctx, FlagFromTemplateRequest{
+		ProjectID:  "proj1",
+		Template:   "percentage-rollout",
+		Key:        "new-search",
+		Owner:      "team:search",
+		Parameters: map[string]any{"percentage": "25"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlagFromTemplate(percentage-rollout) error = %v", err)
+	}
+	if string(rollout.Rules) != `[{"attribute":"user_id","operator":"percentage","value":25}]` {
+		t.Errorf("rollout rules = %s, want a 25%% rollout on user_id", rollout.Rules)
+	}
+	if rollout.Enabled || rollout.ExpiresAt == nil || rollout.Owner != "team:search" || !slices.Equal(rollout.Tags, []string{"rollout"}) {
+		t.Errorf("rollout = %+v, want a disabled, expiring flag tagged rollout", rollout)
+	}
+
+	disabled := false
+	kill, err := svc.CreateFlagFromTemplate(ctx, FlagFromTemplateRequest{ProjectID: "proj1", Template: "kill-switch", Key: "search-kill", Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("CreateFlagFromTemplate(kill-switch) error = %v", err)
+	}
+	if kill.Enabled || string(kill.Variants) != `{"default":true}` {
+		t.Errorf("kill switch = %+v, want disabled by request with a true default", kill)
+	}
+
+	beta, err := svc.CreateFlagFromTemplate(ctx, FlagFromTemplateRequest{
+		ProjectID:  "proj1",
+		Template:   "beta-allowlist",
+		Key:        "beta-search",
+		Parameters: map[string]any{"attribute": "email", "values": "a@example.com, b@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFlagFromTemplate(beta-allowlist) error = %v", err)
+	}
+	if string(beta.Rules) != `[{"attribute":"email","operator":"in","value":["a@example.com","b@example.com"]}]` {
+		t.Errorf("beta rules = %s", beta.Rules)
+	}
+
+	for _, req := range []FlagFromTemplateRequest{
+		{Template: "beta-allowlist", Key: "b"},
+		{Template: "percentage-rollout", Key: "p", Parameters: map[string]any{"percentage": float64(101)}},
+		{Template: "percentage-rollout", Key: "p", Parameters: map[string]any{"percent": float64(5)}},
+		{Template: "kill-switch", Key: "k", Parameters: map[string]any{"attribute": "user_id"}},
+	} {
+		req.ProjectID = "proj1"
+		if _, err := svc.CreateFlagFromTemplate(ctx, req); !errors.Is(err, ErrInvalidFlagTemplateParameters) {
+			t.Errorf("CreateFlagFromTemplate(%+v) error = %v, want ErrInvalidFlagTemplateParameters", req, err)
+		}
+	}
+	if _, err := svc.CreateFlagFromTemplate(ctx, FlagFromTemplateRequest{ProjectID: "proj1", Template: "nope", Key: "n"}); !errors.Is(err, ErrFlagTemplateNotFound) {
+		t.Errorf("unknown template error = %v, want ErrFlagTemplateNotFound", err)
+	}
+}
+
+func TestServiceCopyFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID:   "staging",
+		Key:         "checkout",
+		Description: "New checkout",
+		Enabled:     true,
+		Variants:    json.RawMessage(`{"default":false}`),
+		Rules:       json.RawMessage(`[{"attribute":"country","operator":"equals","value":"NZ"}]`),
+		Targets:     repository.FlagTargets{Attribute: "user_id", Allow: []string{"alice"}},
+	})
+	repo.setFlag(repository.Flag{ProjectID: "prod", Key: "other", Rules: json.RawMessage(`[]`)})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := CopyFlagRequest{SourceProjectID: "staging", SourceKey: "checkout", TargetProjectID: "prod", IncludeRules: true}
+	copied, created, err := svc.CopyFlag(ctx, req)
+	if err != nil {
+		t.Fatalf("CopyFlag() error = %v", err)
+	}
+	if !created || copied.ProjectID != "prod" || copied.Description != "New checkout" || copied.Enabled {
+		t.Fatalf("copy = %+v (created %v), want a new, disabled flag in prod", copied, created)
+	}
+	if string(copied.Rules) != `[{"attribute":"country","operator":"equals","value":"NZ"}]` || !copied.Targets.IsZero() {
+		t.Errorf("copy rules = %s, targets = %+v, want rules only", copied.Rules, copied.Targets)
+	}
+	last := repo.auditLogs[len(repo.auditLogs)-1]
+	if last.Action != "copy" || last.ProjectID != "prod" || !strings.Contains(string(last.Details), `"source_project_id":"staging"`) {
+		t.Errorf("audit entry = %+v, want a copy entry naming the source", last)
+	}
+
+	if _, _, err := svc.CopyFlag(ctx, req); !errors.Is(err, ErrFlagExists) {
+		t.Fatalf("second CopyFlag() error = %v, want ErrFlagExists", err)
+	}
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{ProjectID: "prod", Key: "checkout", Enabled: true, Rules: json.RawMessage(`[]`)}); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	req.IncludeRules, req.IncludeTargets, req.Overwrite = false, true, true
+	promoted, created, err := svc.CopyFlag(ctx, req)
+	if err != nil {
+		t.Fatalf("overwriting CopyFlag() error = %v", err)
+	}
+	if created || !promoted.Enabled || string(promoted.Rules) != `[]` || !slices.Equal(promoted.Targets.Allow, []string{"alice"}) {
+		t.Errorf("promoted = %+v (created %v), want targets copied onto the enabled flag and its rules kept", promoted, created)
+	}
+
+	tests := []struct {
+		name string
+		req  CopyFlagRequest
+		want error
+	}{
+		{"onto itself", CopyFlagRequest{SourceProjectID: "staging", SourceKey: "checkout", TargetProjectID: "staging"}, ErrInvalidFlagCopy},
+		{"missing source", CopyFlagRequest{SourceProjectID: "staging", SourceKey: "missing", TargetProjectID: "prod"}, ErrFlagNotFound},
+		{"missing project", CopyFlagRequest{SourceProjectID: "staging", SourceKey: "checkout", TargetProjectID: "nope"}, ErrProjectNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.CopyFlag(ctx, tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("CopyFlag() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceListFlagsReferencingAttribute(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "geo-banner",
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"AU"}]`),
+	})
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj2",
+		Key:       "other-project",
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"AU"}]`),
+	})
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	keys := func(attr string) []string {
+		t.Helper()
+		flags, err := svc.ListFlagsReferencingAttribute(ctx, "proj1", attr)
+		if err != nil {
+			t.Fatalf("ListFlagsReferencingAttribute(%q) error = %v", attr, err)
+		}
+		out := make([]string, 0, len(flags))
+		for _, f := range flags {
+			out = append(out, f.Key)
+		}
+		return out
+	}
+
+	if got := keys("country"); len(got) != 1 || got[0] != "geo-banner" {
+		t.Fatalf("country flags after load = %v, want [geo-banner]", got)
+	}
+
+	if _, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "au-plans",
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"AU"},{"attribute":"plan","operator":"in","value":["pro"]}]`),
+	}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if got := keys("country"); len(got) != 2 || got[0] != "au-plans" || got[1] != "geo-banner" {
+		t.Fatalf("country flags after create = %v, want [au-plans geo-banner]", got)
+	}
+
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "geo-banner",
+		Rules:     json.RawMessage(`[{"attribute":"region","operator":"equals","value":"apac"}]`),
+	}); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if got := keys("country"); len(got) != 1 || got[0] != "au-plans" {
+		t.Fatalf("country flags after update = %v, want [au-plans]", got)
+	}
+	if got := keys("region"); len(got) != 1 || got[0] != "geo-banner" {
+		t.Fatalf("region flags after update = %v, want [geo-banner]", got)
+	}
+
+	if err := svc.DeleteFlag(ctx, "proj1", "au-plans"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
+	}
+	if got := keys("plan"); len(got) != 0 {
+		t.Fatalf("plan flags after delete = %v, want none", got)
+	}
+
+	if _, err := svc.ListFlagsReferencingAttribute(ctx, " ", "country"); !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("ListFlagsReferencingAttribute() error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+func TestServiceResubscribesAfterInvalidationChannelClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := newResubscribingFakeServiceRepository()
+	initial := repository.Flag{
+		ProjectID:   "default",
+		Key:         "new-ui",
+		Description: "initial rollout",
+		Enabled:     false,
+		Variants:    json.RawMessage(`{}`),
+		Rules:       json.RawMessage(`[]`),
+	}
+	repo.setFlag(initial)
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	updated := initial
+	updated.Description = "updated remotely"
+	updated.Enabled = true
+	repo.setFlag(updated)
+
+	stale, err := svc.GetFlag(ctx, "default", initial.Key)
+	if err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if stale.Description != initial.Description {
+		t.Fatalf("GetFlag().Description = %q, want stale %q before invalidation", stale.Description, initial.Description)
+	}
+
+	repo.closeInvalidationChannel()
+	waitForCondition(t, time.Second, func() bool {
+		return repo.subscriptionCalls() >= 2
+	})
+
+	repo.notifyInvalidation()
+	waitForCondition(t, time.Second, func() bool {
+		flag, err := svc.GetFlag(ctx, "default", initial.Key)
+		return err == nil && flag.Description == updated.Description && flag.Enabled == updated.Enabled
+	})
+}
+
+func TestServiceRuleset(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	empty, err := svc.Ruleset(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("Ruleset() error = %v", err)
+	}
+	if empty.Generation != 0 || len(empty.Flags) != 0 {
+		t.Fatalf("Ruleset() = %+v, want generation 0 and no flags", empty)
+	}
+
+	if _, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":true}`),
+		Rules:     json.RawMessage(`[{"attribute":"country","operator":"equals","value":"NZ"}]`),
+	}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "banner", Variants: json.RawMessage(`null`)}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if _, err := svc.SetFlagTargets(ctx, "proj1", "checkout", repository.FlagTargets{Attribute: "user_id", Deny: []string{"mallory"}}); err != nil {
+		t.Fatalf("SetFlagTargets() error = %v", err)
+	}
+
+	ruleset, err := svc.Ruleset(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("Ruleset() error = %v", err)
+	}
+	if ruleset.Generation != 3 {
+		t.Errorf("Generation = %d, want 3 after three changes", ruleset.Generation)
+	}
+	if len(ruleset.Flags) != 2 || ruleset.Flags[0].Key != "banner" || ruleset.Flags[1].Key != "checkout" {
+		t.Fatalf("Flags = %+v, want banner and checkout sorted by key", ruleset.Flags)
+	}
+	banner, checkout := ruleset.Flags[0], ruleset.Flags[1]
+	if banner.Variants != nil || banner.Default != nil || banner.Enabled {
+		t.Errorf("banner = %+v, want a disabled flag without variants", banner)
+	}
+	if checkout.Default == nil || !*checkout.Default || len(checkout.Rules) != 1 || !slices.Equal(checkout.Targets.Deny, []string{"mallory"}) {
+		t.Errorf("checkout = %+v, want default, rule and deny target", checkout)
+	}
+
+	if _, err := svc.Ruleset(ctx, " "); !errors.Is(err, ErrProjectIDRequired) {
+		t.Errorf("Ruleset(blank) error = %v, want ErrProjectIDRequired", err)
+	}
+}
+
+func TestServiceSnapshot(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, key := range []string{"checkout", "banner"} {
+		if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: key}); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", key, err)
+		}
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj2", Key: "other"}); err != nil {
+		t.Fatalf("CreateFlag(other) error = %v", err)
+	}
+
+	snapshot, err := svc.Snapshot(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.EventID != 2 {
+		t.Errorf("EventID = %d, want 2, the newest proj1 event", snapshot.EventID)
+	}
+	if len(snapshot.Flags) != 2 || snapshot.Flags[0].Key != "banner" || snapshot.Flags[1].Key != "checkout" {
+		t.Fatalf("Flags = %+v, want banner and checkout sorted by key", snapshot.Flags)
+	}
+
+	if _, err := svc.Snapshot(ctx, " "); !errors.Is(err, ErrProjectIDRequired) {
+		t.Errorf("Snapshot(blank) error = %v, want ErrProjectIDRequired", err)
+	}
 }
 
 type fakeServiceRepository struct {
@@ -680,591 +2590,2938 @@ type fakeServiceRepository struct {
 	events      []repository.FlagEvent
 	nextEventID int64
 	publishErr  error
+	listErr     error
+
+	auditLogs []repository.AuditLogEntry
+	auditErr  error
+
+	flagDefaults map[string]repository.FlagDefaults
+	proposals    []repository.FlagProposal
+	deletedAt    map[string]time.Time
+	archived     map[string]repository.Flag
+	flagStats    map[string]repository.FlagStats
+	flagStatsErr error
+
+	flagKeyPolicies map[string]repository.FlagKeyPolicy
+	contextSchemas  map[string]repository.ContextSchema
+
+	contextEnrichments map[string]repository.ContextEnrichment
+
+	requirePublishActiveContext bool
+	publishCtxErr               error
+	publishCtxHasDeadline       bool
+}
+
+func newFakeServiceRepository() *fakeServiceRepository {
+	return &fakeServiceRepository{
+		flags:        make(map[string]map[string]repository.Flag),
+		flagDefaults: make(map[string]repository.FlagDefaults),
+		deletedAt:    make(map[string]time.Time),
+		archived:     make(map[string]repository.Flag),
+		flagStats:    make(map[string]repository.FlagStats),
+
+		flagKeyPolicies: make(map[string]repository.FlagKeyPolicy),
+		contextSchemas:  make(map[string]repository.ContextSchema),
+
+		contextEnrichments: make(map[string]repository.ContextEnrichment),
+	}
+}
+
+func (f *fakeServiceRepository) AddFlagStats(_ context.Context, stats []repository.FlagStats) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flagStatsErr != nil {
+		return f.flagStatsErr
+	}
+	for _, s := range stats {
+		stored := f.flagStats[s.ProjectID+"/"+s.FlagKey]
+		stored.ProjectID, stored.FlagKey = s.ProjectID, s.FlagKey
+		stored.Evaluations += s.Evaluations
+		stored.TrueEvaluations += s.TrueEvaluations
+		stored.LastEvaluatedAt = s.LastEvaluatedAt
+		f.flagStats[s.ProjectID+"/"+s.FlagKey] = stored
+	}
+	return nil
+}
+
+func (f *fakeServiceRepository) GetFlagStats(_ context.Context, projectID, key string) (repository.FlagStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	stats, ok := f.flagStats[projectID+"/"+key]
+	if !ok {
+		return repository.FlagStats{}, pgx.ErrNoRows
+	}
+	return stats, nil
+}
+
+func (f *fakeServiceRepository) ListFlagStats(_ context.Context, projectID string) ([]repository.FlagStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var result []repository.FlagStats
+	for _, stats := range f.flagStats {
+		if stats.ProjectID == projectID {
+			result = append(result, stats)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeServiceRepository) GetProject(_ context.Context, id string) (repository.Project, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	project := repository.Project{ID: id}
+	if deletedAt, ok := f.deletedAt[id]; ok {
+		project.DeletedAt = &deletedAt
+	} else if _, ok := f.flags[id]; !ok {
+		return repository.Project{}, pgx.ErrNoRows
+	}
+	return project, nil
+}
+
+func (f *fakeServiceRepository) DeleteProject(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.deletedAt[id]; ok {
+		return pgx.ErrNoRows
+	}
+	if _, ok := f.flags[id]; !ok {
+		return pgx.ErrNoRows
+	}
+	f.deletedAt[id] = time.Now()
+	return nil
+}
+
+func (f *fakeServiceRepository) RestoreProject(_ context.Context, id string, deletedAfter time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deletedAt, ok := f.deletedAt[id]
+	if !ok || !deletedAt.After(deletedAfter) {
+		return pgx.ErrNoRows
+	}
+	delete(f.deletedAt, id)
+	return nil
+}
+
+func (f *fakeServiceRepository) PurgeDeletedProjects(_ context.Context, deletedBefore time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var purged int64
+	for id, deletedAt := range f.deletedAt {
+		if !deletedAt.After(deletedBefore) {
+			delete(f.deletedAt, id)
+			delete(f.flags, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (f *fakeServiceRepository) GetFlagDefaults(_ context.Context, projectID string) (repository.FlagDefaults, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flagDefaults[projectID], nil
+}
+
+func (f *fakeServiceRepository) SetFlagDefaults(_ context.Context, projectID string, defaults repository.FlagDefaults) (repository.FlagDefaults, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flagDefaults[projectID] = defaults
+	return defaults, nil
+}
+
+func (f *fakeServiceRepository) GetFlagKeyPolicy(_ context.Context, projectID string) (repository.FlagKeyPolicy, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flagKeyPolicies[projectID], nil
+}
+
+func (f *fakeServiceRepository) SetFlagKeyPolicy(_ context.Context, projectID string, policy repository.FlagKeyPolicy) (repository.FlagKeyPolicy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flagKeyPolicies[projectID] = policy
+	return policy, nil
+}
+
+func (f *fakeServiceRepository) GetContextSchema(_ context.Context, projectID string) (repository.ContextSchema, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.contextSchemas[projectID], nil
+}
+
+func (f *fakeServiceRepository) SetContextSchema(_ context.Context, projectID string, schema repository.ContextSchema) (repository.ContextSchema, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contextSchemas[projectID] = schema
+	return schema, nil
+}
+
+func (f *fakeServiceRepository) GetContextEnrichment(_ context.Context, projectID string) (repository.ContextEnrichment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.contextEnrichments[projectID], nil
+}
+
+func (f *fakeServiceRepository) SetContextEnrichment(_ context.Context, projectID string, enrichment repository.ContextEnrichment) (repository.ContextEnrichment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contextEnrichments[projectID] = enrichment
+	return enrichment, nil
+}
+
+func (f *fakeServiceRepository) InsertAuditLog(_ context.Context, entry repository.AuditLogEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.auditErr != nil {
+		return f.auditErr
+	}
+	f.auditLogs = append(f.auditLogs, entry)
+	return nil
+}
+
+func (f *fakeServiceRepository) ListAuditLog(_ context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var result []repository.AuditLogEntry
+	for _, e := range f.auditLogs {
+		if e.ProjectID == projectID {
+			result = append(result, e)
+		}
+	}
+	if offset > len(result) {
+		return nil, nil
+	}
+	result = result[offset:]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (f *fakeServiceRepository) CreateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.flags[flag.ProjectID]; !ok {
+		f.flags[flag.ProjectID] = make(map[string]repository.Flag)
+	}
+	flag.UpdatedBy = flag.CreatedBy
+	f.flags[flag.ProjectID][flag.Key] = flag
+	return flag, nil
+}
+
+func (f *fakeServiceRepository) CreateFlags(ctx context.Context, flags []repository.Flag) ([]repository.Flag, error) {
+	f.mu.RLock()
+	for _, flag := range flags {
+		if _, exists := f.flags[flag.ProjectID][flag.Key]; exists {
+			f.mu.RUnlock()
+			return nil, fmt.Errorf("create flag %q: duplicate key", flag.Key)
+		}
+	}
+	f.mu.RUnlock()
+
+	created := make([]repository.Flag, 0, len(flags))
+	for _, flag := range flags {
+		c, _ := f.CreateFlag(ctx, flag)
+		created = append(created, c)
+	}
+	return created, nil
+}
+
+func (f *fakeServiceRepository) UpdateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	projectFlags, ok := f.flags[flag.ProjectID]
+	if !ok {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	current, ok := projectFlags[flag.Key]
+	if !ok {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	flag.CreatedBy = current.CreatedBy
+	f.flags[flag.ProjectID][flag.Key] = flag
+	return flag, nil
+}
+
+func (f *fakeServiceRepository) UpdateFlagFunc(_ context.Context, projectID, key string, update func(repository.Flag) (repository.Flag, error)) (repository.Flag, repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, ok := f.flags[projectID][key]
+	if !ok {
+		return repository.Flag{}, repository.Flag{}, pgx.ErrNoRows
+	}
+	flag, err := update(current)
+	if err != nil {
+		return repository.Flag{}, repository.Flag{}, fmt.Errorf("update flag: %w", err)
+	}
+	flag.ProjectID, flag.Key = projectID, key
+	flag.BucketingSalt, flag.Targets, flag.CreatedBy = current.BucketingSalt, current.Targets, current.CreatedBy
+	f.flags[projectID][key] = flag
+	return current, flag, nil
+}
+
+func (f *fakeServiceRepository) RotateBucketingSalt(_ context.Context, projectID, key string) (repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	flag, ok := f.flags[projectID][key]
+	if !ok {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	flag.BucketingSalt += "-reshuffled"
+	f.flags[projectID][key] = flag
+	return flag, nil
+}
+
+func (f *fakeServiceRepository) SetFlagTargets(_ context.Context, projectID, key string, targets repository.FlagTargets) (repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	flag, ok := f.flags[projectID][key]
+	if !ok {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	flag.Targets = targets
+	f.flags[projectID][key] = flag
+	return flag, nil
+}
+
+func (f *fakeServiceRepository) GetFlag(_ context.Context, projectID, key string) (repository.Flag, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	projectFlags, ok := f.flags[projectID]
+	if _, deleted := f.deletedAt[projectID]; !ok || deleted {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	flag, ok := projectFlags[key]
+	if !ok {
+		return repository.Flag{}, pgx.ErrNoRows
+	}
+	return flag, nil
+}
+
+func (f *fakeServiceRepository) ListFlags(_ context.Context) ([]repository.Flag, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	var flags []repository.Flag
+	for projectID, projectFlags := range f.flags {
+		if _, deleted := f.deletedAt[projectID]; deleted {
+			continue
+		}
+		for _, flag := range projectFlags {
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+func (f *fakeServiceRepository) DeleteFlag(_ context.Context, projectID, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	projectFlags, ok := f.flags[projectID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	if _, ok := projectFlags[key]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(f.flags[projectID], key)
+	return nil
+}
+
+func (f *fakeServiceRepository) BulkUpdateFlags(_ context.Context, projectID string, updates []repository.Flag, archiveKeys []string) ([]repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, flag := range updates {
+		if _, ok := f.flags[projectID][flag.Key]; !ok {
+			return nil, fmt.Errorf("update flag %q: %w", flag.Key, pgx.ErrNoRows)
+		}
+	}
+	for _, key := range archiveKeys {
+		if _, ok := f.flags[projectID][key]; !ok {
+			return nil, fmt.Errorf("archive flag %q: %w", key, pgx.ErrNoRows)
+		}
+	}
+	updated := make([]repository.Flag, 0, len(updates))
+	for _, flag := range updates {
+		flag.ProjectID = projectID
+		f.flags[projectID][flag.Key] = flag
+		updated = append(updated, flag)
+	}
+	for _, key := range archiveKeys {
+		f.archived[projectID+"/"+key] = f.flags[projectID][key]
+		delete(f.flags[projectID], key)
+	}
+	return updated, nil
+}
+
+func (f *fakeServiceRepository) RestoreFlags(_ context.Context, projectID string, keys []string) ([]repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := f.archived[projectID+"/"+key]; !ok {
+			return nil, fmt.Errorf("restore flag %q: %w", key, pgx.ErrNoRows)
+		}
+	}
+	restored := make([]repository.Flag, 0, len(keys))
+	for _, key := range keys {
+		flag := f.archived[projectID+"/"+key]
+		delete(f.archived, projectID+"/"+key)
+		f.flags[projectID][key] = flag
+		restored = append(restored, flag)
+	}
+	return restored, nil
+}
+
+func (f *fakeServiceRepository) CreateFlagProposal(_ context.Context, proposal repository.FlagProposal) (repository.FlagProposal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	proposal.ID = fmt.Sprintf("prop-%d", len(f.proposals)+1)
+	proposal.Status = repository.ProposalStatusPending
+	f.proposals = append(f.proposals, proposal)
+	return proposal, nil
+}
+
+func (f *fakeServiceRepository) GetFlagProposal(_ context.Context, projectID, id string) (repository.FlagProposal, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, p := range f.proposals {
+		if p.ProjectID == projectID && p.ID == id {
+			return p, nil
+		}
+	}
+	return repository.FlagProposal{}, pgx.ErrNoRows
+}
+
+func (f *fakeServiceRepository) ListFlagProposals(_ context.Context, projectID, flagKey, status string) ([]repository.FlagProposal, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var result []repository.FlagProposal
+	for _, p := range f.proposals {
+		if p.ProjectID == projectID && (flagKey == "" || p.FlagKey == flagKey) && (status == "" || p.Status == status) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeServiceRepository) ReviewFlagProposal(_ context.Context, projectID, id, status, reviewerAPIKeyID, reviewerAdminUserID string) (repository.FlagProposal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, p := range f.proposals {
+		if p.ProjectID == projectID && p.ID == id && p.Status == repository.ProposalStatusPending {
+			p.Status = status
+			p.ReviewedByAPIKeyID = reviewerAPIKeyID
+			p.ReviewedByAdminUserID = reviewerAdminUserID
+			f.proposals[i] = p
+			return p, nil
+		}
+	}
+	return repository.FlagProposal{}, pgx.ErrNoRows
+}
+
+func (f *fakeServiceRepository) ReopenFlagProposal(_ context.Context, projectID, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, p := range f.proposals {
+		if p.ProjectID == projectID && p.ID == id && p.Status == repository.ProposalStatusApproved {
+			p.Status = repository.ProposalStatusPending
+			p.ReviewedByAPIKeyID, p.ReviewedByAdminUserID = "", ""
+			f.proposals[i] = p
+		}
+	}
+	return nil
+}
+
+func (f *fakeServiceRepository) ListEventsSince(_ context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	events := make([]repository.FlagEvent, 0, len(f.events))
+	for _, event := range f.events {
+		if event.EventID > eventID && event.ProjectID == projectID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeServiceRepository) ListEventsSinceForKey(_ context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	events := make([]repository.FlagEvent, 0, len(f.events))
+	for _, event := range f.events {
+		if event.EventID > eventID && event.ProjectID == projectID && event.FlagKey == key {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeServiceRepository) LatestEventID(_ context.Context, projectID string) (int64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var latest int64
+	for _, event := range f.events {
+		if event.ProjectID == projectID {
+			latest = event.EventID
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeServiceRepository) PublishFlagEvent(ctx context.Context, event repository.FlagEvent) (repository.FlagEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.publishCtxErr = ctx.Err()
+	_, f.publishCtxHasDeadline = ctx.Deadline()
+
+	if f.requirePublishActiveContext && f.publishCtxErr != nil {
+		return repository.FlagEvent{}, f.publishCtxErr
+	}
+
+	if f.publishErr != nil {
+		return repository.FlagEvent{}, f.publishErr
+	}
+
+	f.nextEventID++
+	event.EventID = f.nextEventID
+	f.events = append(f.events, event)
+	return event, nil
+}
+
+func (f *fakeServiceRepository) setFlag(flag repository.Flag) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.flags[flag.ProjectID]; !ok {
+		f.flags[flag.ProjectID] = make(map[string]repository.Flag)
+	}
+	f.flags[flag.ProjectID][flag.Key] = flag
+}
+
+func (f *fakeServiceRepository) removeFlag(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// remove from all projects for simplicity in tests or just default
+	for pid := range f.flags {
+		delete(f.flags[pid], key)
+	}
+}
+
+type notifyingFakeServiceRepository struct {
+	*fakeServiceRepository
+	invalidations chan repository.FlagInvalidation
+}
+
+func newNotifyingFakeServiceRepository() *notifyingFakeServiceRepository {
+	return &notifyingFakeServiceRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		invalidations:         make(chan repository.FlagInvalidation, 1),
+	}
+}
+
+func (f *notifyingFakeServiceRepository) SubscribeFlagInvalidation(_ context.Context) (<-chan repository.FlagInvalidation, error) {
+	return f.invalidations, nil
+}
+
+func (f *notifyingFakeServiceRepository) notifyInvalidation() {
+	select {
+	case f.invalidations <- repository.FlagInvalidation{}:
+	default:
+	}
+}
+
+type fakeInvalidationTransport struct {
+	invalidations chan repository.FlagInvalidation
+
+	mu        sync.Mutex
+	published []repository.FlagEvent
+}
+
+func (f *fakeInvalidationTransport) SubscribeFlagInvalidation(_ context.Context) (<-chan repository.FlagInvalidation, error) {
+	return f.invalidations, nil
+}
+
+func (f *fakeInvalidationTransport) PublishFlagInvalidation(_ context.Context, event repository.FlagEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published = append(f.published, event)
+	return nil
+}
+
+func (f *fakeInvalidationTransport) publishedKeys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.published))
+	for _, event := range f.published {
+		keys = append(keys, event.FlagKey)
+	}
+	return keys
+}
+
+type pingingFakeServiceRepository struct {
+	*listeningFakeServiceRepository
+	pingErr error
+}
+
+func (f *pingingFakeServiceRepository) Ping(_ context.Context) error {
+	return f.pingErr
+}
+
+type listeningFakeServiceRepository struct {
+	*fakeServiceRepository
+	listening atomic.Bool
+}
+
+func (f *listeningFakeServiceRepository) SubscribeFlagInvalidation(_ context.Context) (<-chan repository.FlagInvalidation, error) {
+	return make(chan repository.FlagInvalidation), nil
+}
+
+func (f *listeningFakeServiceRepository) Listening() bool {
+	return f.listening.Load()
+}
+
+type resubscribingFakeServiceRepository struct {
+	*fakeServiceRepository
+	invalidationMu sync.Mutex
+	invalidations  chan repository.FlagInvalidation
+	subscriptions  int
+}
+
+func newResubscribingFakeServiceRepository() *resubscribingFakeServiceRepository {
+	return &resubscribingFakeServiceRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		invalidations:         make(chan repository.FlagInvalidation, 1),
+	}
+}
+
+func (f *resubscribingFakeServiceRepository) SubscribeFlagInvalidation(_ context.Context) (<-chan repository.FlagInvalidation, error) {
+	f.invalidationMu.Lock()
+	defer f.invalidationMu.Unlock()
+
+	if f.invalidations == nil {
+		f.invalidations = make(chan repository.FlagInvalidation, 1)
+	}
+	f.subscriptions++
+	return f.invalidations, nil
+}
+
+func (f *resubscribingFakeServiceRepository) closeInvalidationChannel() {
+	f.invalidationMu.Lock()
+	ch := f.invalidations
+	f.invalidations = nil
+	f.invalidationMu.Unlock()
+
+	if ch != nil {
+		close(ch)
+	}
+}
+
+func (f *resubscribingFakeServiceRepository) notifyInvalidation() {
+	f.invalidationMu.Lock()
+	ch := f.invalidations
+	f.invalidationMu.Unlock()
+	if ch == nil {
+		return
+	}
+
+	select {
+	case ch <- repository.FlagInvalidation{}:
+	default:
+	}
+}
+
+func (f *resubscribingFakeServiceRepository) subscriptionCalls() int {
+	f.invalidationMu.Lock()
+	defer f.invalidationMu.Unlock()
+	return f.subscriptions
+}
+
+func TestWithCacheMetrics_LoadCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-1", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-2", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+	repo.setFlag(repository.Flag{ProjectID: "proj-b", Key: "flag-3", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+
+	var mu sync.Mutex
+	var seq int
+	var loadSeq, resetSeq int
+	type updateCall struct {
+		seq       int
+		projectID string
+		size      float64
+	}
+	var updates []updateCall
+
+	onLoad := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		loadSeq = seq
+	}
+	onInvalidation := func() {}
+	onCacheReset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		resetSeq = seq
+	}
+	onCacheUpdate := func(projectID string, size float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		updates = append(updates, updateCall{seq: seq, projectID: projectID, size: size})
+	}
+
+	_, err := New(ctx, repo, WithCacheMetrics(onLoad, onInvalidation, onCacheReset, onCacheUpdate))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if loadSeq == 0 {
+		t.Fatal("onCacheLoad was not called")
+	}
+	if resetSeq == 0 {
+		t.Fatal("onCacheReset was not called")
+	}
+	if len(updates) != 2 {
+		t.Fatalf("onCacheUpdate calls = %d, want 2", len(updates))
+	}
+
+	// Verify onCacheLoad was called before onCacheReset.
+	if loadSeq >= resetSeq {
+		t.Fatalf("onCacheLoad (seq=%d) not called before onCacheReset (seq=%d)", loadSeq, resetSeq)
+	}
+
+	// Verify onCacheReset was called before any onCacheUpdate.
+	for _, u := range updates {
+		if u.seq <= resetSeq {
+			t.Fatalf("onCacheUpdate (seq=%d) called before onCacheReset (seq=%d)", u.seq, resetSeq)
+		}
+	}
+
+	// Verify correct project IDs and sizes.
+	got := make(map[string]float64)
+	for _, u := range updates {
+		got[u.projectID] = u.size
+	}
+	if got["proj-a"] != 2 {
+		t.Fatalf("onCacheUpdate(proj-a) size = %v, want 2", got["proj-a"])
+	}
+	if got["proj-b"] != 1 {
+		t.Fatalf("onCacheUpdate(proj-b) size = %v, want 1", got["proj-b"])
+	}
+}
+
+func TestWithCacheReloadMetrics(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-1", Enabled: true})
+	repo.setFlag(repository.Flag{ProjectID: "proj-b", Key: "flag-2", Enabled: true})
+
+	var reloads, failures int
+	deltas := make(map[string]float64)
+	svc, err := New(ctx, repo, WithCacheReloadMetrics(
+		func(duration time.Duration) {
+			if duration < 0 {
+				t.Errorf("reload duration = %v, want >= 0", duration)
+			}
+			reloads++
+		},
+		func() { failures++ },
+		func(projectID string, delta float64) { deltas[projectID] = delta },
+	))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if reloads != 1 || deltas["proj-a"] != 1 || deltas["proj-b"] != 1 {
+		t.Fatalf("after startup: reloads = %d, deltas = %v; want 1 reload adding one flag per project", reloads, deltas)
+	}
+
+	// proj-a gains a flag behind the service's back and proj-b loses its only one.
+	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-3", Enabled: true})
+	if err := repo.DeleteFlag(ctx, "proj-b", "flag-2"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
+	}
+	clear(deltas)
+	if err := svc.LoadCache(ctx); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	if reloads != 2 || deltas["proj-a"] != 1 || deltas["proj-b"] != -1 {
+		t.Fatalf("after reload: reloads = %d, deltas = %v; want proj-a +1 and proj-b -1", reloads, deltas)
+	}
+
+	repo.listErr = errors.New("connection refused")
+	if err := svc.LoadCache(ctx); err == nil {
+		t.Fatal("LoadCache() error = nil, want the list error")
+	}
+	if failures != 1 || reloads != 2 {
+		t.Fatalf("after failed reload: failures = %d, reloads = %d; want 1 and 2", failures, reloads)
+	}
+}
+
+func TestWithCacheMetrics_NilCallbacks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "default", Key: "flag-1", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+
+	// No WithCacheMetrics option — all callbacks are nil.
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v, want nil (nil callbacks should not panic)", err)
+	}
+
+	// Explicit second LoadCache to confirm nil callbacks remain safe.
+	if err := svc.LoadCache(ctx); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+}
+
+func TestWithCacheMetrics_ResetBeforeUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "p1", Key: "a", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+	repo.setFlag(repository.Flag{ProjectID: "p2", Key: "b", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+	repo.setFlag(repository.Flag{ProjectID: "p2", Key: "c", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
+
+	var mu sync.Mutex
+	var order []string
+
+	onLoad := func() {}
+	onInvalidation := func() {}
+	onCacheReset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "reset")
+	}
+	onCacheUpdate := func(projectID string, size float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "update:"+projectID)
+	}
+
+	svc, err := New(ctx, repo, WithCacheMetrics(onLoad, onInvalidation, onCacheReset, onCacheUpdate))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Clear and reload to observe ordering a second time.
+	mu.Lock()
+	order = nil
+	mu.Unlock()
+
+	if err := svc.LoadCache(ctx); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(order) < 3 {
+		t.Fatalf("callback invocations = %d, want >= 3 (1 reset + 2 updates)", len(order))
+	}
+	if order[0] != "reset" {
+		t.Fatalf("first callback = %q, want %q", order[0], "reset")
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] == "reset" {
+			t.Fatalf("unexpected second reset at position %d", i)
+		}
+	}
+}
+
+func TestWithCacheMetrics_InvalidationCallback(t *testing.T) {
+	ctx := context.Background()
+	repo := newNotifyingFakeServiceRepository()
+
+	var mu sync.Mutex
+	var invalidationCalls int
+
+	svc, err := New(ctx, repo, WithCacheMetrics(
+		func() {},
+		func() {
+			mu.Lock()
+			defer mu.Unlock()
+			invalidationCalls++
+		},
+		func() {},
+		func(string, float64) {},
+	))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_ = svc
+
+	repo.notifyInvalidation()
+
+	waitForCondition(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return invalidationCalls > 0
+	})
+}
+
+func TestTargetedInvalidationRefreshesOneFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := newNotifyingFakeServiceRepository()
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: false})
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "banner", Enabled: false})
+
+	var loads atomic.Int32
+	svc, err := New(ctx, repo, WithCacheMetrics(func() { loads.Add(1) }, nil, nil, nil))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	enabled := func(key string) bool {
+		flag, ok := svc.getCachedFlag("proj1", key)
+		return ok && flag.Enabled
+	}
+	cached := func(key string) bool {
+		_, ok := svc.getCachedFlag("proj1", key)
+		return ok
+	}
+
+	// Another replica enables both flags but announces only checkout.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true})
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "banner", Enabled: true})
+	repo.invalidations <- repository.FlagInvalidation{ProjectID: "proj1", FlagKey: "checkout", EventType: EventTypeUpdated}
+	waitForCondition(t, time.Second, func() bool { return enabled("checkout") })
+	if enabled("banner") {
+		t.Fatal("banner refreshed by an invalidation naming checkout")
+	}
+
+	repo.invalidations <- repository.FlagInvalidation{ProjectID: "proj1", FlagKey: "checkout", EventType: EventTypeDeleted}
+	waitForCondition(t, time.Second, func() bool { return !cached("checkout") })
+
+	// A flag that is gone by the time it is read is dropped too.
+	repo.removeFlag("banner")
+	repo.invalidations <- repository.FlagInvalidation{ProjectID: "proj1", FlagKey: "banner", EventType: EventTypeUpdated}
+	waitForCondition(t, time.Second, func() bool { return !cached("banner") })
+	if got := loads.Load(); got != 1 {
+		t.Fatalf("full cache loads = %d, want only the startup load", got)
+	}
+
+	// An invalidation naming no flag reloads everything.
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "banner", Enabled: true})
+	repo.invalidations <- repository.FlagInvalidation{}
+	waitForCondition(t, time.Second, func() bool { return enabled("banner") })
+	if got := loads.Load(); got != 2 {
+		t.Fatalf("full cache loads = %d, want 2", got)
+	}
+}
+
+func waitForCondition(t *testing.T, timeout time.Duration, check func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if check() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fakeAPIKeyRepository embeds fakeServiceRepository and implements [APIKeyRepository].
+type fakeAPIKeyRepository struct {
+	*fakeServiceRepository
+	keys      map[string][]repository.APIKeyMeta // projectID -> keys
+	nextKeyID int
+}
+
+func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
+	return &fakeAPIKeyRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		keys:                  make(map[string][]repository.APIKeyMeta),
+	}
+}
+
+func (f *fakeAPIKeyRepository) CreateAPIKey(_ context.Context, projectID string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextKeyID++
+	id := fmt.Sprintf("key-%d", f.nextKeyID)
+	f.keys[projectID] = append(f.keys[projectID], repository.APIKeyMeta{
+		ID:        id,
+		ProjectID: projectID,
+		CreatedAt: time.Now(),
+	})
+	return id, "raw-secret", nil
+}
+
+func (f *fakeAPIKeyRepository) ListAPIKeys(_ context.Context, projectID string) ([]repository.APIKeyMeta, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.keys[projectID], nil
+}
+
+func (f *fakeAPIKeyRepository) DeleteAPIKey(_ context.Context, projectID, keyID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := f.keys[projectID]
+	for i, k := range keys {
+		if k.ID == keyID {
+			f.keys[projectID] = append(keys[:i], keys[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func TestCreateAPIKey_ProjectIDValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, _, err = svc.CreateAPIKey(ctx, "")
+	if !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("CreateAPIKey('') error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+func TestCreateAPIKey_Success(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	id, secret, err := svc.CreateAPIKey(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if id == "" {
+		t.Fatal("CreateAPIKey() returned empty id")
+	}
+	if secret == "" {
+		t.Fatal("CreateAPIKey() returned empty secret")
+	}
+}
+
+func TestListAPIKeys_ProjectIDValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	_, err = svc.ListAPIKeys(ctx, "")
+	if !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("ListAPIKeys('') error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+func TestDeleteAPIKey_ProjectIDValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = svc.DeleteAPIKey(ctx, "", "some-key")
+	if !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("DeleteAPIKey('', ...) error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+func TestDeleteAPIKey_KeyIDValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = svc.DeleteAPIKey(ctx, "proj-1", "")
+	if !errors.Is(err, ErrAPIKeyIDRequired) {
+		t.Fatalf("DeleteAPIKey(..., '') error = %v, want %v", err, ErrAPIKeyIDRequired)
+	}
+}
+
+func TestDeleteAPIKey_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = svc.DeleteAPIKey(ctx, "proj-1", "nonexistent")
+	if !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("DeleteAPIKey(nonexistent) error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+}
+
+// fakeKeyRotationRepository embeds fakeAPIKeyRepository and implements
+// [KeyRotationRepository] over its in-memory keys.
+type fakeKeyRotationRepository struct {
+	*fakeAPIKeyRepository
+	policies map[string]repository.KeyRotationPolicy
+	marked   map[string]bool
+}
+
+func newFakeKeyRotationRepository() *fakeKeyRotationRepository {
+	return &fakeKeyRotationRepository{
+		fakeAPIKeyRepository: newFakeAPIKeyRepository(),
+		policies:             make(map[string]repository.KeyRotationPolicy),
+		marked:               make(map[string]bool),
+	}
+}
+
+func (f *fakeKeyRotationRepository) GetKeyRotationPolicy(_ context.Context, projectID string) (repository.KeyRotationPolicy, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.policies[projectID], nil
+}
+
+func (f *fakeKeyRotationRepository) SetKeyRotationPolicy(_ context.Context, projectID string, policy repository.KeyRotationPolicy) (repository.KeyRotationPolicy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.policies[projectID] = policy
+	return policy, nil
+}
+
+func (f *fakeKeyRotationRepository) MarkOverdueAPIKeys(_ context.Context, now time.Time) ([]repository.APIKeyMeta, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var marked []repository.APIKeyMeta
+	for projectID, keys := range f.keys {
+		policy := f.policies[projectID]
+		for _, key := range keys {
+			rotateBy, ok := policy.RotateBy(key.CreatedAt)
+			if !ok || now.Before(rotateBy) || f.marked[key.ID] {
+				continue
+			}
+			f.marked[key.ID] = true
+			key.RotateBy = &rotateBy
+			marked = append(marked, key)
+		}
+	}
+	return marked, nil
+}
+
+func (f *fakeKeyRotationRepository) RevokeOverdueAPIKeys(_ context.Context, now time.Time) ([]repository.APIKeyMeta, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var revoked []repository.APIKeyMeta
+	for projectID, keys := range f.keys {
+		policy := f.policies[projectID]
+		kept := keys[:0]
+		for _, key := range keys {
+			if revokeAt, ok := policy.RevokeAt(key.CreatedAt); ok && !now.Before(revokeAt) {
+				key.RevokeAt = &revokeAt
+				revoked = append(revoked, key)
+				continue
+			}
+			kept = append(kept, key)
+		}
+		f.keys[projectID] = kept
+	}
+	return revoked, nil
+}
+
+func TestServiceSetKeyRotationPolicyValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeKeyRotationRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, policy := range []repository.KeyRotationPolicy{
+		{MaxAge: -time.Hour},
+		{MaxAge: time.Hour, RevokeAfter: -time.Hour},
+		{RevokeAfter: time.Hour},
+	} {
+		if _, err := svc.SetKeyRotationPolicy(ctx, "proj1", policy); !errors.Is(err, ErrInvalidKeyRotationPolicy) {
+			t.Errorf("SetKeyRotationPolicy(%+v) error = %v, want ErrInvalidKeyRotationPolicy", policy, err)
+		}
+	}
+
+	want := repository.KeyRotationPolicy{MaxAge: 90 * 24 * time.Hour, RevokeAfter: 7 * 24 * time.Hour}
+	if _, err := svc.SetKeyRotationPolicy(ctx, "proj1", want); err != nil {
+		t.Fatalf("SetKeyRotationPolicy() error = %v", err)
+	}
+	if got, err := svc.GetKeyRotationPolicy(ctx, "proj1"); err != nil || got != want {
+		t.Fatalf("GetKeyRotationPolicy() = %+v, %v, want %+v", got, err, want)
+	}
+}
+
+func TestServiceEnforceKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeKeyRotationRepository()
+	now := time.Now()
+	repo.keys["proj1"] = []repository.APIKeyMeta{
+		{ID: "fresh", ProjectID: "proj1", CreatedAt: now.Add(-time.Hour)},
+		{ID: "overdue", ProjectID: "proj1", CreatedAt: now.Add(-26 * time.Hour)},
+		{ID: "expired", ProjectID: "proj1", CreatedAt: now.Add(-50 * time.Hour)},
+	}
+	repo.keys["proj2"] = []repository.APIKeyMeta{
+		{ID: "no-policy", ProjectID: "proj2", CreatedAt: now.Add(-1000 * time.Hour)},
+	}
+	repo.policies["proj1"] = repository.KeyRotationPolicy{MaxAge: 24 * time.Hour, RevokeAfter: 24 * time.Hour}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := svc.EnforceKeyRotation(ctx)
+	if err != nil {
+		t.Fatalf("EnforceKeyRotation() error = %v", err)
+	}
+	keyIDs := func(keys []repository.APIKeyMeta) []string {
+		ids := make([]string, len(keys))
+		for i, key := range keys {
+			ids[i] = key.ID
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	if got := keyIDs(result.Overdue); !slices.Equal(got, []string{"expired", "overdue"}) {
+		t.Fatalf("Overdue = %v, want [expired overdue]", got)
+	}
+	if got := keyIDs(result.Revoked); !slices.Equal(got, []string{"expired"}) {
+		t.Fatalf("Revoked = %v, want [expired]", got)
+	}
+
+	var actions []string
+	for _, entry := range repo.auditLogs {
+		actions = append(actions, entry.Action)
+	}
+	slices.Sort(actions)
+	want := []string{"api_key_auto_revoke", "api_key_rotation_overdue", "api_key_rotation_overdue"}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+
+	// Keys are reported once; a second run finds nothing new.
+	result, err = svc.EnforceKeyRotation(ctx)
+	if err != nil || len(result.Overdue) != 0 || len(result.Revoked) != 0 {
+		t.Fatalf("second EnforceKeyRotation() = %+v, %v, want no changes", result, err)
+	}
+}
+
+// fakeFlagExpiryRepository embeds fakeServiceRepository and implements
+// [FlagExpiryRepository] over its in-memory flags.
+type fakeFlagExpiryRepository struct {
+	*fakeServiceRepository
+	notified map[string]bool
+}
+
+func (f *fakeFlagExpiryRepository) ClaimExpiredFlags(_ context.Context, now time.Time) ([]repository.Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []repository.Flag
+	for _, flags := range f.flags {
+		for _, flag := range flags {
+			id := flag.ProjectID + "/" + flag.Key
+			if flag.ExpiresAt == nil || flag.ExpiresAt.After(now) || f.notified[id] {
+				continue
+			}
+			f.notified[id] = true
+			claimed = append(claimed, flag)
+		}
+	}
+	return claimed, nil
+}
+
+func TestServiceRemindExpiredFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeFlagExpiryRepository{fakeServiceRepository: newFakeServiceRepository(), notified: make(map[string]bool)}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, flag := range []repository.Flag{
+		{ProjectID: "proj1", Key: "spring-sale", Owner: " growth@example.com ", ExpiresAt: &past},
+		{ProjectID: "proj1", Key: "summer-sale", ExpiresAt: &future},
+		{ProjectID: "proj1", Key: "checkout-v2"},
+	} {
+		if _, err := svc.CreateFlag(ctx, flag); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", flag.Key, err)
+		}
+	}
+
+	expired, err := svc.RemindExpiredFlags(ctx)
+	if err != nil {
+		t.Fatalf("RemindExpiredFlags() error = %v", err)
+	}
+	if len(expired) != 1 || expired[0].Key != "spring-sale" || expired[0].Owner != "growth@example.com" {
+		t.Fatalf("expired = %+v, want only spring-sale with its trimmed owner", expired)
+	}
+	last := repo.auditLogs[len(repo.auditLogs)-1]
+	if last.Action != auditActionFlagExpired || last.FlagKey != "spring-sale" {
+		t.Fatalf("last audit log = %+v, want a flag_expired entry for spring-sale", last)
+	}
+
+	// Each expiry is reported once.
+	if expired, err := svc.RemindExpiredFlags(ctx); err != nil || len(expired) != 0 {
+		t.Fatalf("second RemindExpiredFlags() = %+v, %v, want nothing", expired, err)
+	}
+}
+
+func TestServiceRemindExpiredFlagsNotSupported(t *testing.T) {
+	svc, err := New(context.Background(), newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := svc.RemindExpiredFlags(context.Background()); !errors.Is(err, errFlagExpiryNotSupported) {
+		t.Fatalf("RemindExpiredFlags() error = %v, want errFlagExpiryNotSupported", err)
+	}
+}
+
+func TestServiceRejectsInvalidOwner(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, owner := range []string{strings.Repeat("a", maxFlagOwnerLen+1), "team\npayments"} {
+		_, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "default", Key: "checkout-v2", Owner: owner})
+		if !errors.Is(err, ErrInvalidOwner) {
+			t.Fatalf("CreateFlag(owner %q) error = %v, want ErrInvalidOwner", owner, err)
+		}
+	}
+}
+
+type flagReaderFunc func(ctx context.Context, projectID, key string) (repository.Flag, error)
+
+func (f flagReaderFunc) GetFlag(ctx context.Context, projectID, key string) (repository.Flag, error) {
+	return f(ctx, projectID, key)
+}
+
+func slowFlagReader(delay time.Duration, flag repository.Flag, err error) flagReaderFunc {
+	return func(ctx context.Context, _, _ string) (repository.Flag, error) {
+		select {
+		case <-time.After(delay):
+			return flag, err
+		case <-ctx.Done():
+			return repository.Flag{}, ctx.Err()
+		}
+	}
+}
+
+func TestHedgedReaderGetFlag(t *testing.T) {
+	ctx := context.Background()
+	primaryFlag := repository.Flag{Key: "checkout", Description: "primary"}
+	replicaFlag := repository.Flag{Key: "checkout", Description: "replica"}
+
+	tests := []struct {
+		name         string
+		primary      flagReaderFunc
+		replica      flagReaderFunc
+		want         string
+		wantErr      error
+		wantSource   string
+		wantHedgeWon bool
+	}{
+		{
+			name:       "fast primary answers alone",
+			primary:    slowFlagReader(0, primaryFlag, nil),
+			replica:    slowFlagReader(time.Second, replicaFlag, nil),
+			want:       "primary",
+			wantSource: FlagSourcePrimary,
+		},
+		{
+			name:         "replica wins the hedge against a slow primary",
+			primary:      slowFlagReader(time.Second, primaryFlag, nil),
+			replica:      slowFlagReader(0, replicaFlag, nil),
+			want:         "replica",
+			wantSource:   FlagSourceReplica,
+			wantHedgeWon: true,
+		},
+		{
+			name:       "replica answers when the primary fails",
+			primary:    slowFlagReader(0, repository.Flag{}, errors.New("primary down")),
+			replica:    slowFlagReader(0, replicaFlag, nil),
+			want:       "replica",
+			wantSource: FlagSourceReplica,
+		},
+		{
+			name:       "replica not found defers to the primary",
+			primary:    slowFlagReader(50*time.Millisecond, primaryFlag, nil),
+			replica:    slowFlagReader(0, repository.Flag{}, pgx.ErrNoRows),
+			want:       "primary",
+			wantSource: FlagSourcePrimary,
+		},
+		{
+			name:       "primary not found is final",
+			primary:    slowFlagReader(0, repository.Flag{}, pgx.ErrNoRows),
+			replica:    slowFlagReader(time.Second, replicaFlag, nil),
+			wantErr:    pgx.ErrNoRows,
+			wantSource: FlagSourcePrimary,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSource string
+			var gotHedgeWon bool
+			reader := newHedgedReader(tt.primary, tt.replica, 5*time.Millisecond, func(source string, hedgeWon bool) {
+				gotSource, gotHedgeWon = source, hedgeWon
+			})
+
+			flag, err := reader.GetFlag(ctx, "proj1", "checkout")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetFlag() error = %v, want %v", err, tt.wantErr)
+			}
+			if flag.Description != tt.want {
+				t.Errorf("GetFlag() answered by %q, want %q", flag.Description, tt.want)
+			}
+			if gotSource != tt.wantSource || gotHedgeWon != tt.wantHedgeWon {
+				t.Errorf("onRead(%q, %v), want (%q, %v)", gotSource, gotHedgeWon, tt.wantSource, tt.wantHedgeWon)
+			}
+		})
+	}
+}
+
+func TestHedgedReaderSkipsFailingSource(t *testing.T) {
+	ctx := context.Background()
+	var replicaCalls atomic.Int32
+	replica := flagReaderFunc(func(context.Context, string, string) (repository.Flag, error) {
+		replicaCalls.Add(1)
+		return repository.Flag{}, errors.New("replica down")
+	})
+	primary := slowFlagReader(20*time.Millisecond, repository.Flag{Key: "checkout"}, nil)
+	reader := newHedgedReader(primary, replica, time.Millisecond, nil)
+
+	for range hedgeFailureThreshold {
+		if _, err := reader.GetFlag(ctx, "proj1", "checkout"); err != nil {
+			t.Fatalf("GetFlag() error = %v", err)
+		}
+	}
+	calls := replicaCalls.Load()
+	if calls != hedgeFailureThreshold {
+		t.Fatalf("replica calls = %d, want %d", calls, hedgeFailureThreshold)
+	}
+
+	if _, err := reader.GetFlag(ctx, "proj1", "checkout"); err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if got := replicaCalls.Load(); got != calls {
+		t.Fatalf("replica calls = %d after its circuit opened, want %d", got, calls)
+	}
+
+	reader.now = func() time.Time { return time.Now().Add(hedgeOpenDuration) }
+	if _, err := reader.GetFlag(ctx, "proj1", "checkout"); err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if got := replicaCalls.Load(); got != calls+1 {
+		t.Fatalf("replica calls = %d after cool-down, want %d", got, calls+1)
+	}
+}
+
+func TestServiceGetFlagHedgesCacheMisses(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+
+	var sources []string
+	replica := slowFlagReader(time.Second, repository.Flag{ProjectID: "proj1", Key: "late", Description: "replica"}, nil)
+	svc, err := New(ctx, repo, WithHedgedReads(replica, time.Millisecond, func(source string, _ bool) {
+		sources = append(sources, source)
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	repo.setFlag(repository.Flag{ProjectID: "proj1", Key: "late", Description: "primary"})
+
+	flag, err := svc.GetFlag(ctx, "proj1", "late")
+	if err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if flag.Description != "primary" || !slices.Equal(sources, []string{FlagSourcePrimary}) {
+		t.Fatalf("GetFlag() = %+v from %v, want the primary's flag", flag, sources)
+	}
+
+	if _, err := svc.GetFlag(ctx, "proj1", "missing"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("GetFlag(missing) error = %v, want ErrFlagNotFound", err)
+	}
+	if _, err := svc.GetFlag(ctx, "proj1", "late"); err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("reads = %v, want the repeated GetFlag served from cache", sources)
+	}
+}
+
+// fakeAdminStreamRepository implements [AdminStreamRepository] over a
+// fakeServiceRepository's event log.
+type fakeAdminStreamRepository struct {
+	*fakeServiceRepository
+	scopes map[string]string // keyID -> scope
+}
+
+func (f *fakeAdminStreamRepository) GetAPIKeyScope(_ context.Context, keyID string) (string, error) {
+	scope, ok := f.scopes[keyID]
+	if !ok {
+		return "", fmt.Errorf("get api key scope: %w", pgx.ErrNoRows)
+	}
+	return scope, nil
+}
+
+func (f *fakeAdminStreamRepository) ListAllEventsSince(_ context.Context, eventID int64) ([]repository.FlagEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var events []repository.FlagEvent
+	for _, event := range f.events {
+		if event.EventID > eventID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestServiceAdminStream(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAdminStreamRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		scopes: map[string]string{
+			"admin-key":   repository.APIKeyScopeAdmin,
+			"project-key": repository.APIKeyScopeProject,
+		},
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := svc.AuthorizeAdminAPIKey(ctx, "admin-key"); err != nil {
+		t.Fatalf("AuthorizeAdminAPIKey(admin-key) error = %v", err)
+	}
+	for _, keyID := range []string{"project-key", "revoked-key", ""} {
+		if err := svc.AuthorizeAdminAPIKey(ctx, keyID); !errors.Is(err, ErrAdminKeyRequired) {
+			t.Errorf("AuthorizeAdminAPIKey(%q) error = %v, want ErrAdminKeyRequired", keyID, err)
+		}
+	}
+
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "a"}); err != nil {
+		t.Fatalf("CreateFlag(proj1) error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj2", Key: "b"}); err != nil {
+		t.Fatalf("CreateFlag(proj2) error = %v", err)
+	}
+	events, err := svc.ListAllEventsSince(ctx, 0)
+	if err != nil {
+		t.Fatalf("ListAllEventsSince() error = %v", err)
+	}
+	if len(events) != 2 || events[0].ProjectID != "proj1" || events[1].ProjectID != "proj2" {
+		t.Fatalf("ListAllEventsSince() = %+v, want one event from each project", events)
+	}
+
+	plain, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := plain.AuthorizeAdminAPIKey(ctx, "admin-key"); err == nil || errors.Is(err, ErrAdminKeyRequired) {
+		t.Errorf("AuthorizeAdminAPIKey() without support error = %v, want not-supported error", err)
+	}
+}
+
+// fakeContextPresetRepository adds in-memory [ContextPresetRepository]
+// storage to a fakeServiceRepository.
+type fakeContextPresetRepository struct {
+	*fakeServiceRepository
+	presets map[string]repository.ContextPreset // projectID + "/" + name
+}
+
+func (f *fakeContextPresetRepository) ListContextPresets(_ context.Context, projectID string) ([]repository.ContextPreset, error) {
+	presets := make([]repository.ContextPreset, 0)
+	for _, preset := range f.presets {
+		if preset.ProjectID == projectID {
+			presets = append(presets, preset)
+		}
+	}
+	slices.SortFunc(presets, func(a, b repository.ContextPreset) int { return strings.Compare(a.Name, b.Name) })
+	return presets, nil
+}
+
+func (f *fakeContextPresetRepository) GetContextPreset(_ context.Context, projectID, name string) (repository.ContextPreset, error) {
+	preset, ok := f.presets[projectID+"/"+name]
+	if !ok {
+		return repository.ContextPreset{}, fmt.Errorf("get context preset: %w", pgx.ErrNoRows)
+	}
+	return preset, nil
+}
+
+func (f *fakeContextPresetRepository) PutContextPreset(_ context.Context, preset repository.ContextPreset) (repository.ContextPreset, error) {
+	f.presets[preset.ProjectID+"/"+preset.Name] = preset
+	return preset, nil
+}
+
+func (f *fakeContextPresetRepository) DeleteContextPreset(_ context.Context, projectID, name string) error {
+	if _, ok := f.presets[projectID+"/"+name]; !ok {
+		return fmt.Errorf("delete context preset: %w", pgx.ErrNoRows)
+	}
+	delete(f.presets, projectID+"/"+name)
+	return nil
+}
+
+func TestServiceContextPresets(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeContextPresetRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		presets:               make(map[string]repository.ContextPreset),
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, name := range []string{"", " padded", "a/b", strings.Repeat("x", 101)} {
+		if _, err := svc.PutContextPreset(ctx, repository.ContextPreset{ProjectID: "proj1", Name: name}); !errors.Is(err, ErrInvalidContextPresetName) {
+			t.Errorf("PutContextPreset(%q) error = %v, want ErrInvalidContextPresetName", name, err)
+		}
+	}
+	if _, err := svc.PutContextPreset(ctx, repository.ContextPreset{
+		ProjectID: "proj1",
+		Name:      "EU free-tier user",
+		Context:   core.EvaluationContext{Attributes: map[string]any{"country": "FR", "plan": "free"}},
+	}); err != nil {
+		t.Fatalf("PutContextPreset() error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "eu-free",
+		Enabled:   true,
+		Rules:     json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"free"},{"attribute":"country","operator":"in","value":["FR","DE"]}]`),
+	}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+
+	results, err := svc.ResolveBatch(ctx, []ResolveRequest{
+		{ProjectID: "proj1", Key: "eu-free", Preset: "EU free-tier user"},
+		{ProjectID: "proj1", Key: "eu-free", Preset: "EU free-tier user", Context: core.EvaluationContext{Attributes: map[string]any{"plan": "pro", "country": "US"}}},
+	})
+	if err != nil {
+		t.Fatalf("ResolveBatch() error = %v", err)
+	}
+	if results[0].Reason != core.ReasonRuleMatch {
+		t.Errorf("preset result = %+v, want a rule match", results[0])
+	}
+	if results[1].Reason == core.ReasonRuleMatch {
+		t.Errorf("overridden result = %+v, want inline attributes to win", results[1])
+	}
+
+	if _, err := svc.ResolveBatch(ctx, []ResolveRequest{{ProjectID: "proj2", Key: "eu-free", Preset: "EU free-tier user"}}); !errors.Is(err, ErrContextPresetNotFound) {
+		t.Errorf("ResolveBatch() with another project's preset error = %v, want ErrContextPresetNotFound", err)
+	}
+	if err := svc.DeleteContextPreset(ctx, "proj1", "EU free-tier user"); err != nil {
+		t.Fatalf("DeleteContextPreset() error = %v", err)
+	}
+	if err := svc.DeleteContextPreset(ctx, "proj1", "EU free-tier user"); !errors.Is(err, ErrContextPresetNotFound) {
+		t.Errorf("second DeleteContextPreset() error = %v, want ErrContextPresetNotFound", err)
+	}
+}
+
+// fakeFlagRevisionRepository records a revision of every flag created or
+// updated through a fakeServiceRepository, as the Postgres repository does.
+type fakeFlagRevisionRepository struct {
+	*fakeServiceRepository
+	revisions map[string][]repository.FlagRevision // projectID + "/" + key, oldest first
+}
 
-	auditLogs []repository.AuditLogEntry
-	auditErr  error
+func (f *fakeFlagRevisionRepository) record(flag repository.Flag) {
+	id := flag.ProjectID + "/" + flag.Key
+	f.revisions[id] = append(f.revisions[id], repository.FlagRevision{Revision: len(f.revisions[id]) + 1, Flag: flag})
+}
 
-	requirePublishActiveContext bool
-	publishCtxErr               error
-	publishCtxHasDeadline       bool
+func (f *fakeFlagRevisionRepository) CreateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
+	created, err := f.fakeServiceRepository.CreateFlag(ctx, flag)
+	if err == nil {
+		f.record(created)
+	}
+	return created, err
 }
 
-func newFakeServiceRepository() *fakeServiceRepository {
-	return &fakeServiceRepository{
-		flags: make(map[string]map[string]repository.Flag),
+func (f *fakeFlagRevisionRepository) UpdateFlag(ctx context.Context, flag repository.Flag) (repository.Flag, error) {
+	updated, err := f.fakeServiceRepository.UpdateFlag(ctx, flag)
+	if err == nil {
+		f.record(updated)
 	}
+	return updated, err
 }
 
-func (f *fakeServiceRepository) InsertAuditLog(_ context.Context, entry repository.AuditLogEntry) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	if f.auditErr != nil {
-		return f.auditErr
+func (f *fakeFlagRevisionRepository) ListFlagRevisions(_ context.Context, projectID, key string, limit int) ([]repository.FlagRevision, error) {
+	revisions := slices.Clone(f.revisions[projectID+"/"+key])
+	slices.Reverse(revisions)
+	return revisions[:min(limit, len(revisions))], nil
+}
+
+func (f *fakeFlagRevisionRepository) GetFlagRevision(_ context.Context, projectID, key string, revision int) (repository.FlagRevision, error) {
+	revisions := f.revisions[projectID+"/"+key]
+	if revision < 1 || revision > len(revisions) {
+		return repository.FlagRevision{}, fmt.Errorf("get flag revision: %w", pgx.ErrNoRows)
 	}
-	f.auditLogs = append(f.auditLogs, entry)
-	return nil
+	return revisions[revision-1], nil
 }
 
-func (f *fakeServiceRepository) ListAuditLog(_ context.Context, projectID string, limit, offset int) ([]repository.AuditLogEntry, error) {
+func TestServiceFlagRevisions(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeFlagRevisionRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		revisions:             make(map[string][]repository.FlagRevision),
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	goodRules := json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"}]`)
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: true, Rules: goodRules}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if _, err := svc.UpdateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout", Enabled: false, Rules: json.RawMessage(`[]`)}); err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+
+	history, err := svc.ListFlagRevisions(ctx, "proj1", "checkout", 0)
+	if err != nil {
+		t.Fatalf("ListFlagRevisions() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Revision != 2 || history[0].Flag.Enabled {
+		t.Fatalf("history = %+v, want revisions 2 then 1", history)
+	}
+	if _, err := svc.ListFlagRevisions(ctx, "proj1", "missing", 0); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("ListFlagRevisions(missing) error = %v, want ErrFlagNotFound", err)
+	}
+
+	reverted, err := svc.RevertFlag(ctx, "proj1", "checkout", 1)
+	if err != nil {
+		t.Fatalf("RevertFlag() error = %v", err)
+	}
+	if !reverted.Enabled || string(reverted.Rules) != string(goodRules) {
+		t.Errorf("reverted flag = %+v, want revision 1", reverted)
+	}
+	if cached, _ := svc.GetFlag(ctx, "proj1", "checkout"); !cached.Enabled {
+		t.Error("cache still serves the pre-revert flag")
+	}
+	if got := len(repo.revisions["proj1/checkout"]); got != 3 {
+		t.Errorf("revisions after revert = %d, want 3", got)
+	}
+	if last := repo.auditLogs[len(repo.auditLogs)-1]; last.Action != "revert" || last.FlagKey != "checkout" {
+		t.Errorf("last audit entry = %+v, want a revert of checkout", last)
+	}
+
+	if _, err := svc.RevertFlag(ctx, "proj1", "checkout", 9); !errors.Is(err, ErrFlagRevisionNotFound) {
+		t.Errorf("RevertFlag(9) error = %v, want ErrFlagRevisionNotFound", err)
+	}
+
+	plain, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := plain.RevertFlag(ctx, "proj1", "checkout", 1); !errors.Is(err, errFlagRevisionsNotSupported) {
+		t.Errorf("RevertFlag() without revision storage error = %v, want errFlagRevisionsNotSupported", err)
+	}
+}
+
+func TestServicePreviewFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	stored := repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[]`),
+	}
+	repo.setFlag(stored)
+
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	draft := stored
+	draft.Rules = json.RawMessage(`[{"attribute":"country","operator":"in","value":["NZ","AU"]}]`)
+	result, err := svc.PreviewFlag(ctx, draft, core.EvaluationContext{Attributes: map[string]any{"country": "NZ"}})
+	if err != nil {
+		t.Fatalf("PreviewFlag() error = %v", err)
+	}
+	if !result.Value || result.Reason != core.ReasonRuleMatch || result.RuleIndex == nil || *result.RuleIndex != 0 {
+		t.Fatalf("PreviewFlag() = %+v, want rule 0 to match", result)
+	}
+
+	flag, err := svc.GetFlag(ctx, "proj1", "checkout")
+	if err != nil {
+		t.Fatalf("GetFlag() error = %v", err)
+	}
+	if string(flag.Rules) != `[]` {
+		t.Fatalf("stored rules = %s, preview must not save the draft", flag.Rules)
+	}
+
+	draft.Rules = json.RawMessage(`[{"attribute":"country","operator":"like","value":"NZ"}]`)
+	_, err = svc.PreviewFlag(ctx, draft, core.EvaluationContext{})
+	var rulesErr *RulesError
+	if !errors.As(err, &rulesErr) || len(rulesErr.Rules) != 1 {
+		t.Fatalf("PreviewFlag() error = %v, want RulesError for the unknown operator", err)
+	}
+}
+
+func TestServiceExplainFlag(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeContextPresetRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		presets:               make(map[string]repository.ContextPreset),
+	}
+	repo.setFlag(repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"attribute":"plan","operator":"equals","value":"pro"},{"attribute":"country","operator":"in","value":["NZ","AU"]}]`),
+	})
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := svc.PutContextPreset(ctx, repository.ContextPreset{
+		ProjectID: "proj1",
+		Name:      "kiwi",
+		Context:   core.EvaluationContext{Attributes: map[string]any{"country": "NZ"}},
+	}); err != nil {
+		t.Fatalf("PutContextPreset() error = %v", err)
+	}
+
+	explanation, err := svc.ExplainFlag(ctx, "proj1", "checkout", core.EvaluationContext{Attributes: map[string]any{"plan": "free"}}, "kiwi")
+	if err != nil {
+		t.Fatalf("ExplainFlag() error = %v", err)
+	}
+	if !explanation.Value || explanation.Reason != core.ReasonRuleMatch || explanation.RuleIndex == nil || *explanation.RuleIndex != 1 {
+		t.Fatalf("ExplainFlag() = %+v, want rule 1 to match", explanation.ResolveResult)
+	}
+	if got := explanation.Context.Attributes; got["country"] != "NZ" || got["plan"] != "free" {
+		t.Errorf("explained context = %v, want the preset merged in", got)
+	}
+	if len(explanation.Steps) != 2 || explanation.Steps[0].Matched || !explanation.Steps[1].Matched {
+		t.Errorf("steps = %+v, want rule 0 failing and rule 1 matching", explanation.Steps)
+	}
+
+	if _, err := svc.ExplainFlag(ctx, "proj1", "missing", core.EvaluationContext{}, ""); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("ExplainFlag(missing) error = %v, want ErrFlagNotFound", err)
+	}
+	if _, err := svc.ExplainFlag(ctx, "proj1", "checkout", core.EvaluationContext{}, "nobody"); !errors.Is(err, ErrContextPresetNotFound) {
+		t.Errorf("ExplainFlag(unknown preset) error = %v, want ErrContextPresetNotFound", err)
+	}
+}
+
+// fakeEventFeedRepository feeds the event broker from a fakeServiceRepository
+// and counts how often streams reach the repository for events.
+type fakeEventFeedRepository struct {
+	*fakeServiceRepository
+	projectReads atomic.Int64
+}
+
+func (f *fakeEventFeedRepository) MaxEventID(context.Context) (int64, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	var result []repository.AuditLogEntry
-	for _, e := range f.auditLogs {
-		if e.ProjectID == projectID {
-			result = append(result, e)
+	return f.nextEventID, nil
+}
+
+func (f *fakeEventFeedRepository) ListAllEventsSince(_ context.Context, eventID int64) ([]repository.FlagEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var events []repository.FlagEvent
+	for _, event := range f.events {
+		if event.EventID > eventID {
+			events = append(events, event)
 		}
 	}
-	if offset > len(result) {
-		return nil, nil
+	return events, nil
+}
+
+func (f *fakeEventFeedRepository) ListEventsSince(ctx context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
+	f.projectReads.Add(1)
+	return f.fakeServiceRepository.ListEventsSince(ctx, projectID, eventID)
+}
+
+func TestServiceEventBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := &fakeEventFeedRepository{fakeServiceRepository: newFakeServiceRepository()}
+	if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: "proj1", FlagKey: "old", EventType: EventTypeUpdated}); err != nil {
+		t.Fatal(err)
 	}
-	result = result[offset:]
-	if limit > 0 && limit < len(result) {
-		result = result[:limit]
+	svc, err := New(ctx, repo, WithEventPollInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	changed := svc.EventsChanged()
+	if changed == nil {
+		t.Fatal("EventsChanged() = nil, want a channel when the repository feeds the broker")
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "a"}); err != nil {
+		t.Fatalf("CreateFlag(proj1) error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj2", Key: "b"}); err != nil {
+		t.Fatalf("CreateFlag(proj2) error = %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("EventsChanged() channel not closed after a write")
+	}
+	// Both writes may take more than one fetch to arrive.
+	deadline := time.Now().Add(time.Second)
+	for {
+		events, err := svc.ListEventsSince(ctx, "proj2", 1)
+		if err != nil {
+			t.Fatalf("ListEventsSince() error = %v", err)
+		}
+		if len(events) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ListEventsSince(proj2) = %+v, want the proj2 event", events)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	events, err := svc.ListEventsSince(ctx, "proj1", 1)
+	if err != nil {
+		t.Fatalf("ListEventsSince() error = %v", err)
+	}
+	if len(events) != 1 || events[0].FlagKey != "a" {
+		t.Fatalf("ListEventsSince(proj1, 1) = %+v, want only the event for a", events)
+	}
+	if n := repo.projectReads.Load(); n != 0 {
+		t.Fatalf("repository read %d times, want recent events served from memory", n)
+	}
+
+	// The event before the broker started is only in the repository.
+	events, err = svc.ListEventsSince(ctx, "proj1", 0)
+	if err != nil {
+		t.Fatalf("ListEventsSince() error = %v", err)
+	}
+	if len(events) != 2 || events[0].FlagKey != "old" {
+		t.Fatalf("ListEventsSince(proj1, 0) = %+v, want old and a", events)
+	}
+	if n := repo.projectReads.Load(); n != 1 {
+		t.Fatalf("repository read %d times, want 1 for history", n)
+	}
+
+	keyed, err := svc.ListEventsSinceForKey(ctx, "proj1", 1, "b")
+	if err != nil || len(keyed) != 0 {
+		t.Fatalf("ListEventsSinceForKey(proj1, b) = %+v, %v, want none", keyed, err)
+	}
+
+	matched, err := svc.ListEventsSinceMatching(ctx, "proj1", 1, repository.EventFilter{Keys: []string{"a", "b"}, Types: []string{EventTypeUpdated}})
+	if err != nil || len(matched) != 1 || matched[0].FlagKey != "a" {
+		t.Fatalf("ListEventsSinceMatching(proj1, a b) = %+v, %v, want the event for a", matched, err)
+	}
+	if n := repo.projectReads.Load(); n != 1 {
+		t.Fatalf("repository read %d times, want matching recent events served from memory", n)
+	}
+}
+
+func TestServiceListEventsSinceMatchingFiltersWithoutRepositorySupport(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	for _, event := range []struct{ key, eventType string }{
+		{"a", EventTypeUpdated}, {"b", EventTypeUpdated}, {"a", EventTypeDeleted},
+	} {
+		if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: "proj1", FlagKey: event.key, EventType: event.eventType}); err != nil {
+			t.Fatalf("PublishFlagEvent() error = %v", err)
+		}
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	events, err := svc.ListEventsSinceMatching(ctx, "proj1", 0, repository.EventFilter{Keys: []string{"a"}, Types: []string{EventTypeDeleted}})
+	if err != nil {
+		t.Fatalf("ListEventsSinceMatching() error = %v", err)
+	}
+	if len(events) != 1 || events[0].FlagKey != "a" || events[0].EventType != EventTypeDeleted {
+		t.Fatalf("ListEventsSinceMatching() = %+v, want the delete of a", events)
+	}
+	if _, err := svc.ListEventsSinceMatching(ctx, " ", 0, repository.EventFilter{}); !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("ListEventsSinceMatching(blank project) error = %v, want %v", err, ErrProjectIDRequired)
+	}
+}
+
+// fakeEventQueryRepository answers event history queries from a
+// fakeServiceRepository's events.
+type fakeEventQueryRepository struct {
+	*fakeServiceRepository
+	queries []repository.EventQuery
+}
+
+func (f *fakeEventQueryRepository) QueryEvents(_ context.Context, q repository.EventQuery) ([]repository.FlagEvent, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	f.queries = append(f.queries, q)
+
+	events := make([]repository.FlagEvent, 0)
+	for _, event := range f.events {
+		if event.EventID <= q.AfterID || event.ProjectID != q.ProjectID ||
+			!(repository.EventFilter{Keys: q.Keys, Types: q.Types}).Matches(event) ||
+			(q.Actor != "" && event.Actor != q.Actor) {
+			continue
+		}
+		if len(events) == q.Limit {
+			break
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func TestServiceQueryEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeEventQueryRepository{fakeServiceRepository: newFakeServiceRepository()}
+	for _, event := range []struct{ key, actor string }{
+		{"a", "api_key:k1"}, {"b", "api_key:k1"}, {"a", "admin:u1"}, {"a", "api_key:k1"},
+	} {
+		if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: "proj1", FlagKey: event.key, EventType: EventTypeUpdated, Actor: event.actor}); err != nil {
+			t.Fatalf("PublishFlagEvent() error = %v", err)
+		}
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	q := repository.EventQuery{ProjectID: "proj1", Keys: []string{"a"}, Actor: "api_key:k1", Limit: 1}
+	events, next, err := svc.QueryEvents(ctx, q)
+	if err != nil || len(events) != 1 || events[0].EventID != 1 || next != 1 {
+		t.Fatalf("QueryEvents(limit 1) = %+v, %d, %v, want event 1 and a next page after it", events, next, err)
+	}
+	q.AfterID = next
+	events, next, err = svc.QueryEvents(ctx, q)
+	if err != nil || len(events) != 1 || events[0].EventID != 4 || next != 0 {
+		t.Fatalf("QueryEvents(after 1) = %+v, %d, %v, want event 4 and no next page", events, next, err)
+	}
+
+	if _, _, err := svc.QueryEvents(ctx, repository.EventQuery{ProjectID: "proj1"}); err != nil {
+		t.Fatalf("QueryEvents() error = %v", err)
+	}
+	if got := repo.queries[len(repo.queries)-1].Limit; got != defaultEventQueryLimit+1 {
+		t.Fatalf("repository limit = %d, want the default limit plus one", got)
+	}
+	if _, _, err := svc.QueryEvents(ctx, repository.EventQuery{ProjectID: "proj1", Limit: 5000}); err != nil {
+		t.Fatalf("QueryEvents(limit 5000) error = %v", err)
+	}
+	if got := repo.queries[len(repo.queries)-1].Limit; got != maxEventQueryLimit+1 {
+		t.Fatalf("repository limit = %d, want the maximum limit plus one", got)
+	}
+
+	now := time.Now()
+	if _, _, err := svc.QueryEvents(ctx, repository.EventQuery{ProjectID: "proj1", Since: now, Until: now}); !errors.Is(err, ErrInvalidEventQuery) {
+		t.Fatalf("QueryEvents(empty range) error = %v, want ErrInvalidEventQuery", err)
+	}
+	if _, _, err := svc.QueryEvents(ctx, repository.EventQuery{}); !errors.Is(err, ErrProjectIDRequired) {
+		t.Fatalf("QueryEvents(no project) error = %v, want ErrProjectIDRequired", err)
+	}
+}
+
+// fakeFlagPageRepository reads pages of a fakeServiceRepository's flags.
+type fakeFlagPageRepository struct {
+	*fakeServiceRepository
+	queries []repository.FlagPageQuery
+}
+
+func (f *fakeFlagPageRepository) ListFlagsPage(ctx context.Context, q repository.FlagPageQuery) ([]repository.Flag, error) {
+	f.queries = append(f.queries, q)
+	flags, err := f.ListFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(flags, func(a, b repository.Flag) int { return strings.Compare(a.Key, b.Key) })
+	page := make([]repository.Flag, 0)
+	for _, flag := range FilterFlagsByTags(flags, q.Tags) {
+		if flag.ProjectID == q.ProjectID && flag.Key > q.AfterKey && len(page) < q.Limit {
+			page = append(page, flag)
+		}
+	}
+	return page, nil
+}
+
+func TestServiceListFlagsPage(t *testing.T) {
+	ctx := context.Background()
+	base := newFakeServiceRepository()
+	for _, flag := range []repository.Flag{
+		{ProjectID: "proj1", Key: "c", Tags: []string{"beta"}},
+		{ProjectID: "proj1", Key: "a", Tags: []string{"beta"}},
+		{ProjectID: "proj1", Key: "b"},
+		{ProjectID: "proj2", Key: "z", Tags: []string{"beta"}},
+	} {
+		if _, err := base.CreateFlag(ctx, flag); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", flag.Key, err)
+		}
+	}
+	paged := &fakeFlagPageRepository{fakeServiceRepository: base}
+
+	for name, repo := range map[string]Repository{"page repository": paged, "cache": base} {
+		t.Run(name, func(t *testing.T) {
+			svc, err := New(ctx, repo)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			q := repository.FlagPageQuery{ProjectID: "proj1", Limit: 2}
+			flags, next, err := svc.ListFlagsPage(ctx, q)
+			if err != nil || len(flags) != 2 || flags[0].Key != "a" || flags[1].Key != "b" || next != "b" {
+				t.Fatalf("ListFlagsPage(limit 2) = %+v, %q, %v, want a and b and a next page after b", flags, next, err)
+			}
+			q.AfterKey = next
+			flags, next, err = svc.ListFlagsPage(ctx, q)
+			if err != nil || len(flags) != 1 || flags[0].Key != "c" || next != "" {
+				t.Fatalf("ListFlagsPage(after b) = %+v, %q, %v, want c and no next page", flags, next, err)
+			}
+			flags, _, err = svc.ListFlagsPage(ctx, repository.FlagPageQuery{ProjectID: "proj1", Tags: []string{" BETA "}})
+			if err != nil || len(flags) != 2 || flags[0].Key != "a" || flags[1].Key != "c" {
+				t.Fatalf("ListFlagsPage(BETA) = %+v, %v, want a and c", flags, err)
+			}
+			if _, _, err := svc.ListFlagsPage(ctx, repository.FlagPageQuery{}); !errors.Is(err, ErrProjectIDRequired) {
+				t.Fatalf("ListFlagsPage(no project) error = %v, want ErrProjectIDRequired", err)
+			}
+		})
+	}
+
+	if got := paged.queries[0].Limit; got != 3 {
+		t.Fatalf("repository limit = %d, want the page size plus one", got)
+	}
+	if got := paged.queries[2].Limit; got != defaultFlagPageLimit+1 {
+		t.Fatalf("repository limit = %d, want the default limit plus one", got)
+	}
+}
+
+func TestServiceBulkUpdateFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeServiceRepository()
+	for _, flag := range []repository.Flag{
+		{ProjectID: "proj1", Key: "a", Tags: []string{"beta"}},
+		{ProjectID: "proj1", Key: "b", Enabled: true},
+		{ProjectID: "proj1", Key: "c"},
+	} {
+		if _, err := repo.CreateFlag(ctx, flag); err != nil {
+			t.Fatalf("CreateFlag(%s) error = %v", flag.Key, err)
+		}
+	}
+	svc, err := New(ctx, repo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	enabled := true
+	for name, update := range map[string]BulkFlagUpdate{
+		"no flags":         {ProjectID: "proj1", Enabled: &enabled},
+		"no change":        {ProjectID: "proj1", Keys: []string{"a"}},
+		"archive and more": {ProjectID: "proj1", Keys: []string{"a"}, Archive: true, Enabled: &enabled},
+		"restore and more": {ProjectID: "proj1", Keys: []string{"a"}, Restore: true, AddTags: []string{"ops"}},
+		"too many flags":   {ProjectID: "proj1", Keys: make([]string, maxBulkFlagKeys+1), Enabled: &enabled},
+	} {
+		if name == "too many flags" {
+			for i := range update.Keys {
+				update.Keys[i] = fmt.Sprintf("flag-%d", i)
+			}
+		}
+		if _, err := svc.BulkUpdateFlags(ctx, update); !errors.Is(err, ErrInvalidBulkFlagUpdate) {
+			t.Errorf("BulkUpdateFlags(%s) error = %v, want ErrInvalidBulkFlagUpdate", name, err)
+		}
+	}
+	if _, err := svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"a"}, AddTags: []string{"bad tag"}}); !errors.Is(err, ErrInvalidTags) {
+		t.Errorf("BulkUpdateFlags(bad tag) error = %v, want ErrInvalidTags", err)
+	}
+	if _, err := svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"a", "missing"}, Enabled: &enabled}); !errors.Is(err, ErrFlagNotFound) || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("BulkUpdateFlags(missing) error = %v, want ErrFlagNotFound naming the key", err)
+	}
+	if flag, _ := svc.GetFlag(ctx, "proj1", "a"); flag.Enabled {
+		t.Fatal("a failed bulk update should change no flag")
+	}
+
+	// b is already enabled but still gains the tag.
+	result, err := svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"c", "a", "b", "a"}, Enabled: &enabled, AddTags: []string{"Ops"}})
+	if err != nil {
+		t.Fatalf("BulkUpdateFlags(enable) error = %v", err)
+	}
+	if len(result.Updated) != 3 || len(result.Archived) != 0 {
+		t.Fatalf("BulkUpdateFlags(enable) = %+v, want a, b and c updated", result)
+	}
+	a, _ := svc.GetFlag(ctx, "proj1", "a")
+	if !a.Enabled || !slices.Equal(a.Tags, []string{"beta", "ops"}) {
+		t.Fatalf("GetFlag(a) = %+v, want it enabled and tagged beta and ops", a)
+	}
+
+	result, err = svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"a", "b"}, Enabled: &enabled})
+	if err != nil || len(result.Updated) != 0 {
+		t.Fatalf("BulkUpdateFlags(unchanged) = %+v, %v, want no flags updated", result, err)
+	}
+
+	result, err = svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"a", "c"}, Archive: true})
+	if err != nil || !slices.Equal(result.Archived, []string{"a", "c"}) {
+		t.Fatalf("BulkUpdateFlags(archive) = %+v, %v, want a and c archived", result, err)
+	}
+	if _, err := svc.GetFlag(ctx, "proj1", "c"); !errors.Is(err, ErrFlagNotFound) {
+		t.Fatalf("GetFlag(c) error = %v, want ErrFlagNotFound", err)
+	}
+
+	if _, err := svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"c", "b"}, Restore: true}); !errors.Is(err, ErrFlagNotFound) || !strings.Contains(err.Error(), `"b"`) {
+		t.Fatalf("BulkUpdateFlags(restore live flag) error = %v, want ErrFlagNotFound naming b", err)
+	}
+	result, err = svc.BulkUpdateFlags(ctx, BulkFlagUpdate{ProjectID: "proj1", Keys: []string{"c"}, Restore: true})
+	if err != nil || len(result.Restored) != 1 {
+		t.Fatalf("BulkUpdateFlags(restore) = %+v, %v, want c restored", result, err)
+	}
+	if c, err := svc.GetFlag(ctx, "proj1", "c"); err != nil || !c.Enabled {
+		t.Fatalf("GetFlag(c) = %+v, %v, want it back as it was archived", c, err)
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	var types []string
+	for _, event := range repo.events {
+		types = append(types, event.FlagKey+":"+event.EventType)
+	}
+	want := []string{"a:" + EventTypeUpdated, "b:" + EventTypeUpdated, "c:" + EventTypeUpdated, "a:" + EventTypeDeleted, "c:" + EventTypeDeleted, "c:" + EventTypeUpdated}
+	if !slices.Equal(types, want) {
+		t.Fatalf("events = %v, want %v", types, want)
 	}
-	return result, nil
 }
 
-func (f *fakeServiceRepository) CreateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+func TestServiceSetEventPollInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	if _, ok := f.flags[flag.ProjectID]; !ok {
-		f.flags[flag.ProjectID] = make(map[string]repository.Flag)
+	repo := &fakeEventFeedRepository{fakeServiceRepository: newFakeServiceRepository()}
+	svc, err := New(ctx, repo, WithEventPollInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	changed := svc.EventsChanged()
+	// Published behind the service's back, so only a poll finds it.
+	if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: "proj1", FlagKey: "a", EventType: EventTypeUpdated}); err != nil {
+		t.Fatal(err)
+	}
+	svc.SetEventPollInterval(5 * time.Millisecond)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("EventsChanged() channel not closed at the new poll interval")
 	}
-	f.flags[flag.ProjectID][flag.Key] = flag
-	return flag, nil
 }
 
-func (f *fakeServiceRepository) UpdateFlag(_ context.Context, flag repository.Flag) (repository.Flag, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+func TestEventBrokerDropsOldestEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeEventFeedRepository{fakeServiceRepository: newFakeServiceRepository()}
+	broker, err := newEventBroker(ctx, repo, slog.Default())
+	if err != nil {
+		t.Fatalf("newEventBroker() error = %v", err)
+	}
+	for range maxBrokerEvents + 5 {
+		if _, err := repo.PublishFlagEvent(ctx, repository.FlagEvent{ProjectID: "proj1", FlagKey: "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	broker.fetch(ctx)
 
-	projectFlags, ok := f.flags[flag.ProjectID]
-	if !ok {
-		return repository.Flag{}, pgx.ErrNoRows
+	if _, ok := broker.since("proj1", 4, repository.EventFilter{}); ok {
+		t.Fatal("since(4) should fall back to the repository once event 5 is dropped")
 	}
-	if _, ok := projectFlags[flag.Key]; !ok {
-		return repository.Flag{}, pgx.ErrNoRows
+	events, ok := broker.since("proj1", 5, repository.EventFilter{})
+	if !ok || len(events) != maxBrokerEvents {
+		t.Fatalf("since(5) = %d events, %v, want %d from memory", len(events), ok, maxBrokerEvents)
+	}
+	if events[0].EventID != 6 {
+		t.Fatalf("oldest held event = %d, want 6", events[0].EventID)
 	}
-	f.flags[flag.ProjectID][flag.Key] = flag
-	return flag, nil
 }
 
-func (f *fakeServiceRepository) GetFlag(_ context.Context, projectID, key string) (repository.Flag, error) {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
-
-	projectFlags, ok := f.flags[projectID]
-	if !ok {
-		return repository.Flag{}, pgx.ErrNoRows
+func TestServiceEventsChangedWithoutBroker(t *testing.T) {
+	svc, err := New(context.Background(), newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
 	}
-	flag, ok := projectFlags[key]
-	if !ok {
-		return repository.Flag{}, pgx.ErrNoRows
+	if svc.EventsChanged() != nil {
+		t.Fatal("EventsChanged() should be nil when streams must poll")
 	}
-	return flag, nil
 }
 
-func (f *fakeServiceRepository) ListFlags(_ context.Context) ([]repository.Flag, error) {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
+// fakeUsageRepository embeds fakeServiceRepository and implements
+// [UsageRepository] in memory.
+type fakeUsageRepository struct {
+	*fakeServiceRepository
+	usage    map[usageKey]repository.APIKeyUsage
+	quotas   map[string]repository.APIKeyQuota
+	usageErr error
+}
 
-	var flags []repository.Flag
-	for _, projectFlags := range f.flags {
-		for _, flag := range projectFlags {
-			flags = append(flags, flag)
-		}
+func newFakeUsageRepository() *fakeUsageRepository {
+	return &fakeUsageRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		usage:                 make(map[usageKey]repository.APIKeyUsage),
+		quotas:                make(map[string]repository.APIKeyQuota),
 	}
-	return flags, nil
 }
 
-func (f *fakeServiceRepository) DeleteFlag(_ context.Context, projectID, key string) error {
+func (f *fakeUsageRepository) AddAPIKeyUsage(_ context.Context, usage []repository.APIKeyUsage) ([]repository.APIKeyUsage, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-
-	projectFlags, ok := f.flags[projectID]
-	if !ok {
-		return pgx.ErrNoRows
-	}
-	if _, ok := projectFlags[key]; !ok {
-		return pgx.ErrNoRows
-	}
-	delete(f.flags[projectID], key)
-	return nil
+	if f.usageErr != nil {
+		return nil, f.usageErr
+	}
+	totals := make([]repository.APIKeyUsage, 0, len(usage))
+	for _, u := range usage {
+		k := usageKey{u.ProjectID, u.APIKeyID, u.Hour}
+		stored := f.usage[k]
+		stored.ProjectID, stored.APIKeyID, stored.Hour = u.ProjectID, u.APIKeyID, u.Hour
+		stored.Evaluations += u.Evaluations
+		stored.Mutations += u.Mutations
+		stored.StreamSeconds += u.StreamSeconds
+		f.usage[k] = stored
+		totals = append(totals, stored)
+	}
+	return totals, nil
 }
 
-func (f *fakeServiceRepository) ListEventsSince(_ context.Context, projectID string, eventID int64) ([]repository.FlagEvent, error) {
+func (f *fakeUsageRepository) ListAPIKeyUsage(_ context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-
-	events := make([]repository.FlagEvent, 0, len(f.events))
-	for _, event := range f.events {
-		if event.EventID > eventID && event.ProjectID == projectID {
-			events = append(events, event)
+	var usage []repository.APIKeyUsage
+	for k, u := range f.usage {
+		if k.projectID == projectID && !k.hour.Before(since.Truncate(time.Hour)) {
+			usage = append(usage, u)
 		}
 	}
-	return events, nil
+	return usage, nil
 }
 
-func (f *fakeServiceRepository) ListEventsSinceForKey(_ context.Context, projectID string, eventID int64, key string) ([]repository.FlagEvent, error) {
+func (f *fakeUsageRepository) GetAPIKeyQuota(_ context.Context, projectID string) (repository.APIKeyQuota, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-
-	events := make([]repository.FlagEvent, 0, len(f.events))
-	for _, event := range f.events {
-		if event.EventID > eventID && event.ProjectID == projectID && event.FlagKey == key {
-			events = append(events, event)
-		}
-	}
-	return events, nil
+	return f.quotas[projectID], nil
 }
 
-func (f *fakeServiceRepository) PublishFlagEvent(ctx context.Context, event repository.FlagEvent) (repository.FlagEvent, error) {
+func (f *fakeUsageRepository) SetAPIKeyQuota(_ context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	f.quotas[projectID] = quota
+	return quota, nil
+}
 
-	f.publishCtxErr = ctx.Err()
-	_, f.publishCtxHasDeadline = ctx.Deadline()
+func TestServiceAPIKeyUsageQuota(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeUsageRepository()
+	svc, err := New(ctx, repo, WithStatsFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
 
-	if f.requirePublishActiveContext && f.publishCtxErr != nil {
-		return repository.FlagEvent{}, f.publishCtxErr
+	if _, err := svc.SetAPIKeyQuota(ctx, "proj1", repository.APIKeyQuota{HourlyMutations: -1}); !errors.Is(err, ErrInvalidAPIKeyQuota) {
+		t.Fatalf("SetAPIKeyQuota(negative) error = %v, want ErrInvalidAPIKeyQuota", err)
+	}
+	if _, err := svc.SetAPIKeyQuota(ctx, "proj1", repository.APIKeyQuota{HourlyMutations: 2}); err != nil {
+		t.Fatalf("SetAPIKeyQuota() error = %v", err)
 	}
 
-	if f.publishErr != nil {
-		return repository.FlagEvent{}, f.publishErr
+	for i := range 2 {
+		if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-1", UsageMutation); err != nil {
+			t.Fatalf("mutation %d: AllowAPIKeyUsage() error = %v", i, err)
+		}
+	}
+	if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-1", UsageMutation); !errors.Is(err, ErrAPIKeyQuotaExceeded) {
+		t.Fatalf("third mutation error = %v, want ErrAPIKeyQuotaExceeded", err)
+	}
+	if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-2", UsageMutation); err != nil {
+		t.Fatalf("other key: AllowAPIKeyUsage() error = %v", err)
+	}
+	if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-1", UsageEvaluation); err != nil {
+		t.Fatalf("evaluations are unlimited: AllowAPIKeyUsage() error = %v", err)
 	}
 
-	f.nextEventID++
-	event.EventID = f.nextEventID
-	f.events = append(f.events, event)
-	return event, nil
-}
+	repo.usageErr = errors.New("db down")
+	if err := svc.FlushUsage(ctx); err == nil {
+		t.Fatal("FlushUsage() error = nil, want failure")
+	}
+	repo.usageErr = nil
+	if err := svc.FlushUsage(ctx); err != nil {
+		t.Fatalf("FlushUsage() error = %v", err)
+	}
+	// The flushed totals still count against the quota.
+	if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-1", UsageMutation); !errors.Is(err, ErrAPIKeyQuotaExceeded) {
+		t.Fatalf("after flush error = %v, want ErrAPIKeyQuotaExceeded", err)
+	}
 
-func (f *fakeServiceRepository) setFlag(flag repository.Flag) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	if _, ok := f.flags[flag.ProjectID]; !ok {
-		f.flags[flag.ProjectID] = make(map[string]repository.Flag)
+	hour := time.Now().UTC().Truncate(time.Hour)
+	svc.RecordAPIKeyStreamTime("proj1", "key-2", hour, hour.Add(90*time.Second))
+	usage, err := svc.ListAPIKeyUsage(ctx, "proj1", hour.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListAPIKeyUsage() error = %v", err)
+	}
+	var key1, key2 repository.APIKeyUsage
+	for _, u := range usage {
+		switch u.APIKeyID {
+		case "key-1":
+			key1.Evaluations += u.Evaluations
+			key1.Mutations += u.Mutations
+		case "key-2":
+			key2.Mutations += u.Mutations
+			key2.StreamSeconds += u.StreamSeconds
+		}
+	}
+	if key1.Evaluations != 1 || key1.Mutations != 2 {
+		t.Errorf("key-1 usage = %+v, want 1 evaluation and 2 mutations (kept across failed flush)", key1)
+	}
+	if key2.Mutations != 1 || key2.StreamSeconds != 90 {
+		t.Errorf("key-2 usage = %+v, want 1 mutation and 90 stream seconds", key2)
 	}
-	f.flags[flag.ProjectID][flag.Key] = flag
 }
 
-func (f *fakeServiceRepository) removeFlag(key string) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	// remove from all projects for simplicity in tests or just default
-	for pid := range f.flags {
-		delete(f.flags[pid], key)
+func TestServiceAPIKeyUsageWithoutRepositorySupport(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := svc.AllowAPIKeyUsage(ctx, "proj1", "key-1", UsageMutation); err != nil {
+		t.Fatalf("AllowAPIKeyUsage() error = %v, want nil", err)
+	}
+	if quota, err := svc.GetAPIKeyQuota(ctx, "proj1"); err != nil || quota != (repository.APIKeyQuota{}) {
+		t.Fatalf("GetAPIKeyQuota() = %+v, %v, want unlimited", quota, err)
 	}
 }
 
-type notifyingFakeServiceRepository struct {
+// fakeNotificationRepository embeds fakeServiceRepository and implements
+// [NotificationRepository] and [WebhookSigningRepository] in memory.
+type fakeNotificationRepository struct {
 	*fakeServiceRepository
-	invalidations chan struct{}
+	channels       []repository.NotificationChannel
+	notifications  chan repository.Notification
+	recorded       []repository.Notification
+	signingSecrets map[string]repository.WebhookSigningSecret
 }
 
-func newNotifyingFakeServiceRepository() *notifyingFakeServiceRepository {
-	return &notifyingFakeServiceRepository{
+func newFakeNotificationRepository() *fakeNotificationRepository {
+	return &fakeNotificationRepository{
 		fakeServiceRepository: newFakeServiceRepository(),
-		invalidations:         make(chan struct{}, 1),
+		notifications:         make(chan repository.Notification, 16),
 	}
 }
 
-func (f *notifyingFakeServiceRepository) SubscribeFlagInvalidation(_ context.Context) (<-chan struct{}, error) {
-	return f.invalidations, nil
-}
-
-func (f *notifyingFakeServiceRepository) notifyInvalidation() {
-	select {
-	case f.invalidations <- struct{}{}:
-	default:
+func (f *fakeNotificationRepository) ListNotificationChannels(_ context.Context, projectID string) ([]repository.NotificationChannel, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var channels []repository.NotificationChannel
+	for _, c := range f.channels {
+		if c.ProjectID == projectID {
+			channels = append(channels, c)
+		}
 	}
+	return channels, nil
 }
 
-type resubscribingFakeServiceRepository struct {
-	*fakeServiceRepository
-	invalidationMu sync.Mutex
-	invalidations  chan struct{}
-	subscriptions  int
+func (f *fakeNotificationRepository) CreateNotificationChannel(_ context.Context, channel repository.NotificationChannel) (repository.NotificationChannel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	channel.ID = fmt.Sprintf("chan-%d", len(f.channels)+1)
+	f.channels = append(f.channels, channel)
+	return channel, nil
 }
 
-func newResubscribingFakeServiceRepository() *resubscribingFakeServiceRepository {
-	return &resubscribingFakeServiceRepository{
-		fakeServiceRepository: newFakeServiceRepository(),
-		invalidations:         make(chan struct{}, 1),
+func (f *fakeNotificationRepository) DeleteNotificationChannel(_ context.Context, projectID, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, c := range f.channels {
+		if c.ProjectID == projectID && c.ID == id {
+			f.channels = slices.Delete(f.channels, i, i+1)
+			return nil
+		}
 	}
+	return pgx.ErrNoRows
 }
 
-func (f *resubscribingFakeServiceRepository) SubscribeFlagInvalidation(_ context.Context) (<-chan struct{}, error) {
-	f.invalidationMu.Lock()
-	defer f.invalidationMu.Unlock()
-
-	if f.invalidations == nil {
-		f.invalidations = make(chan struct{}, 1)
-	}
-	f.subscriptions++
-	return f.invalidations, nil
+func (f *fakeNotificationRepository) InsertNotification(_ context.Context, notification repository.Notification) error {
+	f.mu.Lock()
+	f.recorded = append(f.recorded, notification)
+	f.mu.Unlock()
+	f.notifications <- notification
+	return nil
 }
 
-func (f *resubscribingFakeServiceRepository) closeInvalidationChannel() {
-	f.invalidationMu.Lock()
-	ch := f.invalidations
-	f.invalidations = nil
-	f.invalidationMu.Unlock()
+func (f *fakeNotificationRepository) ListNotifications(context.Context, string, int) ([]repository.Notification, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return slices.Clone(f.recorded), nil
+}
 
-	if ch != nil {
-		close(ch)
+func (f *fakeNotificationRepository) GetWebhookSigningSecret(_ context.Context, projectID string) (repository.WebhookSigningSecret, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	secret, ok := f.signingSecrets[projectID]
+	if !ok {
+		return repository.WebhookSigningSecret{}, fmt.Errorf("get webhook signing secret: %w", pgx.ErrNoRows)
 	}
+	return secret, nil
 }
 
-func (f *resubscribingFakeServiceRepository) notifyInvalidation() {
-	f.invalidationMu.Lock()
-	ch := f.invalidations
-	f.invalidationMu.Unlock()
-	if ch == nil {
-		return
+func (f *fakeNotificationRepository) RotateWebhookSigningSecret(_ context.Context, projectID, secret string, overlap time.Duration) (repository.WebhookSigningSecret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.signingSecrets == nil {
+		f.signingSecrets = make(map[string]repository.WebhookSigningSecret)
 	}
-
-	select {
-	case ch <- struct{}{}:
-	default:
+	rotated := repository.WebhookSigningSecret{ProjectID: projectID, Secret: secret, CreatedAt: time.Now()}
+	if old, ok := f.signingSecrets[projectID]; ok {
+		expiresAt := time.Now().Add(overlap)
+		rotated.PreviousSecret, rotated.PreviousExpiresAt = old.Secret, &expiresAt
 	}
+	f.signingSecrets[projectID] = rotated
+	return rotated, nil
 }
 
-func (f *resubscribingFakeServiceRepository) subscriptionCalls() int {
-	f.invalidationMu.Lock()
-	defer f.invalidationMu.Unlock()
-	return f.subscriptions
+// fakeNotificationSender fails the first failures deliveries to each
+// channel target.
+type fakeNotificationSender struct {
+	mu       sync.Mutex
+	failures int
+	attempts map[string]int
+	sent     []repository.FlagEvent
+	// secrets holds the signing secrets of the last delivery to each
+	// channel target.
+	secrets map[string][]string
 }
 
-func TestWithCacheMetrics_LoadCache(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeServiceRepository()
-	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-1", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-	repo.setFlag(repository.Flag{ProjectID: "proj-a", Key: "flag-2", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-	repo.setFlag(repository.Flag{ProjectID: "proj-b", Key: "flag-3", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-
-	var mu sync.Mutex
-	var seq int
-	var loadSeq, resetSeq int
-	type updateCall struct {
-		seq       int
-		projectID string
-		size      float64
-	}
-	var updates []updateCall
-
-	onLoad := func() {
-		mu.Lock()
-		defer mu.Unlock()
-		seq++
-		loadSeq = seq
-	}
-	onInvalidation := func() {}
-	onCacheReset := func() {
-		mu.Lock()
-		defer mu.Unlock()
-		seq++
-		resetSeq = seq
+func (f *fakeNotificationSender) SendNotification(_ context.Context, channel repository.NotificationChannel, event repository.FlagEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts[channel.Target]++
+	if f.secrets != nil {
+		f.secrets[channel.Target] = channel.SigningSecrets
 	}
-	onCacheUpdate := func(projectID string, size float64) {
-		mu.Lock()
-		defer mu.Unlock()
-		seq++
-		updates = append(updates, updateCall{seq: seq, projectID: projectID, size: size})
+	if f.attempts[channel.Target] <= f.failures {
+		return errors.New("webhook unavailable")
 	}
+	f.sent = append(f.sent, event)
+	return nil
+}
 
-	_, err := New(ctx, repo, WithCacheMetrics(onLoad, onInvalidation, onCacheReset, onCacheUpdate))
+func TestServiceNotifiesChannelsWithRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := newFakeNotificationRepository()
+	sender := &fakeNotificationSender{failures: 1, attempts: make(map[string]int)}
+	svc, err := New(ctx, repo, WithNotificationSender(sender), func(s *Service) {
+		s.notificationRetryDelay = time.Millisecond
+	})
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
-	if loadSeq == 0 {
-		t.Fatal("onCacheLoad was not called")
+	for _, channel := range []repository.NotificationChannel{
+		{ProjectID: "proj1", Kind: NotificationKindSlack, Target: "https://hooks.slack.com/services/T0/B0/x"},
+		{ProjectID: "proj1", Kind: NotificationKindEmail, Target: "ops@example.com", EventTypes: []string{EventTypeDeleted}},
+	} {
+		created, err := svc.CreateNotificationChannel(ctx, channel)
+		if err != nil {
+			t.Fatalf("CreateNotificationChannel(%s) error = %v", channel.Kind, err)
+		}
+		if created.Target != channel.Target {
+			t.Fatalf("created target = %q, want %q in full", created.Target, channel.Target)
+		}
 	}
-	if resetSeq == 0 {
-		t.Fatal("onCacheReset was not called")
+	channels, err := svc.ListNotificationChannels(ctx, "proj1")
+	if err != nil {
+		t.Fatalf("ListNotificationChannels() error = %v", err)
 	}
-	if len(updates) != 2 {
-		t.Fatalf("onCacheUpdate calls = %d, want 2", len(updates))
+	if len(channels) != 2 || channels[0].Target != "https://hooks.slack.com/…" || channels[1].Target != "ops@example.com" {
+		t.Fatalf("channels = %+v, want the Slack URL redacted and the email kept", channels)
 	}
 
-	// Verify onCacheLoad was called before onCacheReset.
-	if loadSeq >= resetSeq {
-		t.Fatalf("onCacheLoad (seq=%d) not called before onCacheReset (seq=%d)", loadSeq, resetSeq)
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
 	}
-
-	// Verify onCacheReset was called before any onCacheUpdate.
-	for _, u := range updates {
-		if u.seq <= resetSeq {
-			t.Fatalf("onCacheUpdate (seq=%d) called before onCacheReset (seq=%d)", u.seq, resetSeq)
+
+	select {
+	case n := <-repo.notifications:
+		if n.ChannelID != "chan-1" || n.FlagKey != "checkout" || n.EventType != EventTypeUpdated || n.Status != NotificationSent || n.Attempts != 2 {
+			t.Fatalf("notification = %+v, want sent to chan-1 on the second attempt", n)
 		}
+	case <-time.After(time.Second):
+		t.Fatal("no notification recorded")
+	}
+	select {
+	case n := <-repo.notifications:
+		t.Fatalf("unexpected notification %+v: the email channel only wants deletions", n)
+	case <-time.After(20 * time.Millisecond):
 	}
 
-	// Verify correct project IDs and sizes.
-	got := make(map[string]float64)
-	for _, u := range updates {
-		got[u.projectID] = u.size
+	sender.mu.Lock()
+	sender.failures = notificationAttempts
+	sender.mu.Unlock()
+	if err := svc.DeleteFlag(ctx, "proj1", "checkout"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
 	}
-	if got["proj-a"] != 2 {
-		t.Fatalf("onCacheUpdate(proj-a) size = %v, want 2", got["proj-a"])
+	got := map[string]repository.Notification{}
+	for range 2 {
+		select {
+		case n := <-repo.notifications:
+			got[n.ChannelID] = n
+		case <-time.After(time.Second):
+			t.Fatalf("got %d notifications for the deletion, want 2", len(got))
+		}
 	}
-	if got["proj-b"] != 1 {
-		t.Fatalf("onCacheUpdate(proj-b) size = %v, want 1", got["proj-b"])
+	if n := got["chan-1"]; n.Status != NotificationSent {
+		t.Errorf("slack notification = %+v, want sent", n)
+	}
+	if n := got["chan-2"]; n.Status != NotificationFailed || n.Attempts != notificationAttempts || n.Error != "webhook unavailable" {
+		t.Errorf("email notification = %+v, want failed after %d attempts", n, notificationAttempts)
 	}
 }
 
-func TestWithCacheMetrics_NilCallbacks(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeServiceRepository()
-	repo.setFlag(repository.Flag{ProjectID: "default", Key: "flag-1", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-
-	// No WithCacheMetrics option — all callbacks are nil.
-	svc, err := New(ctx, repo)
+func TestServiceRedactsTargetsInNotificationErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := newFakeNotificationRepository()
+	svc, err := New(ctx, repo, WithNotificationSender(notify.NewRouter(nil, nil)), func(s *Service) {
+		s.notificationRetryDelay = time.Millisecond
+	})
 	if err != nil {
-		t.Fatalf("New() error = %v, want nil (nil callbacks should not panic)", err)
+		t.Fatalf("New() error = %v", err)
 	}
 
-	// Explicit second LoadCache to confirm nil callbacks remain safe.
-	if err := svc.LoadCache(ctx); err != nil {
-		t.Fatalf("LoadCache() error = %v", err)
+	// Nothing listens on port 1, so every attempt fails to connect.
+	channel := repository.NotificationChannel{ProjectID: "proj1", Kind: NotificationKindSlack, Target: "https://127.0.0.1:1/services/T000/B000/s3cretpath"}
+	if _, err := svc.CreateNotificationChannel(ctx, channel); err != nil {
+		t.Fatalf("CreateNotificationChannel() error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	select {
+	case <-repo.notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification recorded")
 	}
-}
-
-func TestWithCacheMetrics_ResetBeforeUpdate(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeServiceRepository()
-	repo.setFlag(repository.Flag{ProjectID: "p1", Key: "a", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-	repo.setFlag(repository.Flag{ProjectID: "p2", Key: "b", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-	repo.setFlag(repository.Flag{ProjectID: "p2", Key: "c", Enabled: true, Variants: json.RawMessage(`{}`), Rules: json.RawMessage(`[]`)})
-
-	var mu sync.Mutex
-	var order []string
 
-	onLoad := func() {}
-	onInvalidation := func() {}
-	onCacheReset := func() {
-		mu.Lock()
-		defer mu.Unlock()
-		order = append(order, "reset")
+	notifications, err := svc.ListNotifications(ctx, "proj1", 10)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("ListNotifications() = %+v, %v, want one notification", notifications, err)
 	}
-	onCacheUpdate := func(projectID string, size float64) {
-		mu.Lock()
-		defer mu.Unlock()
-		order = append(order, "update:"+projectID)
+	n := notifications[0]
+	if n.Status != NotificationFailed || n.Error == "" || strings.Contains(n.Error, "s3cretpath") {
+		t.Fatalf("notification = %+v, want a failure whose error leaves out the URL path", n)
+	}
+	if !strings.Contains(n.Error, "https://127.0.0.1:1/…") {
+		t.Errorf("error = %q, want the redacted URL", n.Error)
 	}
+}
 
-	svc, err := New(ctx, repo, WithCacheMetrics(onLoad, onInvalidation, onCacheReset, onCacheUpdate))
+func TestServiceCreateNotificationChannelValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, err := New(ctx, newFakeNotificationRepository())
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	// Clear and reload to observe ordering a second time.
-	mu.Lock()
-	order = nil
-	mu.Unlock()
-
-	if err := svc.LoadCache(ctx); err != nil {
-		t.Fatalf("LoadCache() error = %v", err)
+	for name, channel := range map[string]repository.NotificationChannel{
+		"unknown kind":     {Kind: "pager", Target: "x"},
+		"http slack url":   {Kind: NotificationKindSlack, Target: "http://hooks.slack.com/services/x"},
+		"bad email":        {Kind: NotificationKindEmail, Target: "ops"},
+		"unknown event":    {Kind: NotificationKindEmail, Target: "ops@example.com", EventTypes: []string{"created"}},
+		"blank slack host": {Kind: NotificationKindSlack, Target: "https://"},
+		"webhook scheme":   {Kind: NotificationKindWebhook, Target: "ftp://example.com/hooks"},
+	} {
+		channel.ProjectID = "proj1"
+		if _, err := svc.CreateNotificationChannel(ctx, channel); !errors.Is(err, ErrInvalidNotificationChannel) {
+			t.Errorf("%s: error = %v, want ErrInvalidNotificationChannel", name, err)
+		}
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
-	if len(order) < 3 {
-		t.Fatalf("callback invocations = %d, want >= 3 (1 reset + 2 updates)", len(order))
+	if err := svc.DeleteNotificationChannel(ctx, "proj1", "missing"); !errors.Is(err, ErrNotificationChannelNotFound) {
+		t.Errorf("DeleteNotificationChannel(missing) error = %v, want ErrNotificationChannelNotFound", err)
 	}
-	if order[0] != "reset" {
-		t.Fatalf("first callback = %q, want %q", order[0], "reset")
+
+	plain, err := New(ctx, newFakeServiceRepository())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
 	}
-	for i := 1; i < len(order); i++ {
-		if order[i] == "reset" {
-			t.Fatalf("unexpected second reset at position %d", i)
-		}
+	if _, err := plain.ListNotificationChannels(ctx, "proj1"); !errors.Is(err, errNotificationsNotSupported) {
+		t.Errorf("ListNotificationChannels() without support error = %v, want errNotificationsNotSupported", err)
 	}
 }
 
-func TestWithCacheMetrics_InvalidationCallback(t *testing.T) {
-	ctx := context.Background()
-	repo := newNotifyingFakeServiceRepository()
-
-	var mu sync.Mutex
-	var invalidationCalls int
-
-	svc, err := New(ctx, repo, WithCacheMetrics(
-		func() {},
-		func() {
-			mu.Lock()
-			defer mu.Unlock()
-			invalidationCalls++
-		},
-		func() {},
-		func(string, float64) {},
-	))
+func TestServiceWebhookSigningSecret(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := newFakeNotificationRepository()
+	sender := &fakeNotificationSender{attempts: make(map[string]int), secrets: make(map[string][]string)}
+	svc, err := New(ctx, repo, WithNotificationSender(sender))
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
-	_ = svc
-
-	repo.notifyInvalidation()
 
-	waitForCondition(t, time.Second, func() bool {
-		mu.Lock()
-		defer mu.Unlock()
-		return invalidationCalls > 0
-	})
-}
+	channel := repository.NotificationChannel{ProjectID: "proj1", Kind: NotificationKindWebhook, Target: "https://example.com/hooks/flagz"}
+	if _, err := svc.CreateNotificationChannel(ctx, channel); !errors.Is(err, ErrInvalidNotificationChannel) {
+		t.Fatalf("CreateNotificationChannel(webhook) without a secret error = %v, want ErrInvalidNotificationChannel", err)
+	}
+	if _, err := svc.WebhookSigningSecret(ctx, "proj1"); !errors.Is(err, ErrWebhookSigningSecretNotFound) {
+		t.Fatalf("WebhookSigningSecret() error = %v, want ErrWebhookSigningSecretNotFound", err)
+	}
 
-func waitForCondition(t *testing.T, timeout time.Duration, check func() bool) {
-	t.Helper()
+	first, created, err := svc.RotateWebhookSigningSecret(ctx, "proj1")
+	if err != nil || !created || !strings.HasPrefix(first.Secret, webhookSecretPrefix) {
+		t.Fatalf("RotateWebhookSigningSecret() = %+v, %t, %v, want a new secret", first, created, err)
+	}
+	second, created, err := svc.RotateWebhookSigningSecret(ctx, "proj1")
+	if err != nil || created || second.Secret == first.Secret || second.PreviousSecret != "" || second.PreviousExpiresAt == nil {
+		t.Fatalf("RotateWebhookSigningSecret() again = %+v, %t, %v, want a replacement without the old secret", second, created, err)
+	}
+	meta, err := svc.WebhookSigningSecret(ctx, "proj1")
+	if err != nil || meta.Secret != "" || meta.PreviousExpiresAt == nil {
+		t.Fatalf("WebhookSigningSecret() = %+v, %v, want metadata only", meta, err)
+	}
 
-	deadline := time.Now().Add(timeout)
-	for {
-		if check() {
-			return
-		}
-		if time.Now().After(deadline) {
-			t.Fatal("condition not met before timeout")
+	if _, err := svc.CreateNotificationChannel(ctx, channel); err != nil {
+		t.Fatalf("CreateNotificationChannel(webhook) error = %v", err)
+	}
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "checkout"}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	select {
+	case n := <-repo.notifications:
+		if n.Status != NotificationSent {
+			t.Fatalf("notification = %+v, want sent", n)
 		}
-		time.Sleep(10 * time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("no notification recorded")
+	}
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if got := sender.secrets[channel.Target]; !slices.Equal(got, []string{second.Secret, first.Secret}) {
+		t.Errorf("signing secrets = %q, want the new and the rotated secret", got)
 	}
 }
 
-// fakeAPIKeyRepository embeds fakeServiceRepository and implements [APIKeyRepository].
-type fakeAPIKeyRepository struct {
+// fakeSegmentRepository adds in-memory [SegmentRepository] storage to a
+// fakeServiceRepository.
+type fakeSegmentRepository struct {
 	*fakeServiceRepository
-	keys      map[string][]repository.APIKeyMeta // projectID -> keys
-	nextKeyID int
-}
-
-func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
-	return &fakeAPIKeyRepository{
-		fakeServiceRepository: newFakeServiceRepository(),
-		keys:                  make(map[string][]repository.APIKeyMeta),
-	}
+	segments map[string]repository.Segment // projectID + "/" + name
 }
 
-func (f *fakeAPIKeyRepository) CreateAPIKey(_ context.Context, projectID string) (string, string, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	f.nextKeyID++
-	id := fmt.Sprintf("key-%d", f.nextKeyID)
-	f.keys[projectID] = append(f.keys[projectID], repository.APIKeyMeta{
-		ID:        id,
-		ProjectID: projectID,
-		CreatedAt: time.Now(),
-	})
-	return id, "raw-secret", nil
+func (f *fakeSegmentRepository) ListAllSegments(context.Context) ([]repository.Segment, error) {
+	return slices.Collect(maps.Values(f.segments)), nil
 }
 
-func (f *fakeAPIKeyRepository) ListAPIKeys(_ context.Context, projectID string) ([]repository.APIKeyMeta, error) {
-	f.mu.RLock()
-	defer f.mu.RUnlock()
-	return f.keys[projectID], nil
+func (f *fakeSegmentRepository) PutSegment(_ context.Context, segment repository.Segment) (repository.Segment, error) {
+	f.segments[segment.ProjectID+"/"+segment.Name] = segment
+	return segment, nil
 }
 
-func (f *fakeAPIKeyRepository) DeleteAPIKey(_ context.Context, projectID, keyID string) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	keys := f.keys[projectID]
-	for i, k := range keys {
-		if k.ID == keyID {
-			f.keys[projectID] = append(keys[:i], keys[i+1:]...)
-			return nil
-		}
+func (f *fakeSegmentRepository) DeleteSegment(_ context.Context, projectID, name string) error {
+	if _, ok := f.segments[projectID+"/"+name]; !ok {
+		return fmt.Errorf("delete segment: %w", pgx.ErrNoRows)
 	}
-	return pgx.ErrNoRows
+	delete(f.segments, projectID+"/"+name)
+	return nil
 }
 
-func TestCreateAPIKey_ProjectIDValidation(t *testing.T) {
+func TestServiceSegments(t *testing.T) {
 	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
+	repo := &fakeSegmentRepository{
+		fakeServiceRepository: newFakeServiceRepository(),
+		segments: map[string]repository.Segment{
+			"proj1/staff": {ProjectID: "proj1", Name: "staff", Rules: []core.Rule{{Attribute: "email", Operator: core.OperatorIn, Value: []any{"a@example.com"}}}},
+		},
+	}
 	svc, err := New(ctx, repo)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	_, _, err = svc.CreateAPIKey(ctx, "")
-	if !errors.Is(err, ErrProjectIDRequired) {
-		t.Fatalf("CreateAPIKey('') error = %v, want %v", err, ErrProjectIDRequired)
+	if _, err := svc.PutSegment(ctx, repository.Segment{ProjectID: "proj1", Name: "beta testers"}); !errors.Is(err, ErrInvalidSegmentName) {
+		t.Errorf("PutSegment(invalid name) error = %v, want ErrInvalidSegmentName", err)
+	}
+	if _, err := svc.PutSegment(ctx, repository.Segment{ProjectID: "proj1", Name: "beta", Rules: []core.Rule{{Operator: core.OperatorSegment, Value: "missing"}}}); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("PutSegment(unknown reference) error = %v, want ErrInvalidSegment", err)
+	}
+	if _, err := svc.PutSegment(ctx, repository.Segment{ProjectID: "proj1", Name: "staff", Rules: []core.Rule{{Operator: core.OperatorSegment, Value: "staff"}}}); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("PutSegment(cycle) error = %v, want ErrInvalidSegment", err)
+	}
+	if _, err := svc.PutSegment(ctx, repository.Segment{ProjectID: "proj1", Name: "beta", Rules: []core.Rule{{Attribute: "plan", Operator: "regex"}}}); !errors.Is(err, ErrInvalidRules) {
+		t.Errorf("PutSegment(invalid rule) error = %v, want ErrInvalidRules", err)
 	}
-}
 
-func TestCreateAPIKey_Success(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
-	svc, err := New(ctx, repo)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+	beta := repository.Segment{ProjectID: "proj1", Name: "beta", Rules: []core.Rule{
+		{Operator: core.OperatorSegment, Value: "staff"},
+		{Attribute: "plan", Operator: core.OperatorEquals, Value: "pro"},
+	}}
+	if _, err := svc.PutSegment(ctx, beta); err != nil {
+		t.Fatalf("PutSegment() error = %v", err)
+	}
+	segments, err := svc.ListSegments(ctx, "proj1")
+	if err != nil || len(segments) != 2 || segments[0].Name != "beta" || segments[1].Name != "staff" {
+		t.Fatalf("ListSegments() = %+v, %v, want beta and staff", segments, err)
 	}
 
-	id, secret, err := svc.CreateAPIKey(ctx, "proj-1")
-	if err != nil {
-		t.Fatalf("CreateAPIKey() error = %v", err)
+	if _, err := svc.CreateFlag(ctx, repository.Flag{ProjectID: "proj1", Key: "typo", Rules: json.RawMessage(`[{"operator":"segment","value":"btea"}]`)}); !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("CreateFlag(unknown segment) error = %v, want ErrInvalidRules", err)
 	}
-	if id == "" {
-		t.Fatal("CreateAPIKey() returned empty id")
+	if _, err := svc.CreateFlag(ctx, repository.Flag{
+		ProjectID: "proj1",
+		Key:       "checkout",
+		Enabled:   true,
+		Variants:  json.RawMessage(`{"default":false}`),
+		Rules:     json.RawMessage(`[{"operator":"segment","value":"beta"}]`),
+	}); err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
 	}
-	if secret == "" {
-		t.Fatal("CreateAPIKey() returned empty secret")
+
+	for _, tt := range []struct {
+		attributes map[string]any
+		want       bool
+	}{
+		{map[string]any{"email": "a@example.com"}, true},
+		{map[string]any{"plan": "pro"}, true},
+		{map[string]any{"plan": "free"}, false},
+	} {
+		got, err := svc.ResolveBoolean(ctx, "proj1", "checkout", core.EvaluationContext{Attributes: tt.attributes}, false)
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveBoolean(%v) = %v, %v, want %v", tt.attributes, got, err, tt.want)
+		}
 	}
-}
 
-func TestListAPIKeys_ProjectIDValidation(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
-	svc, err := New(ctx, repo)
+	if err := svc.DeleteSegment(ctx, "proj1", "staff"); !errors.Is(err, ErrSegmentInUse) {
+		t.Errorf("DeleteSegment(referenced by segment) error = %v, want ErrSegmentInUse", err)
+	}
+	if err := svc.DeleteSegment(ctx, "proj1", "beta"); !errors.Is(err, ErrSegmentInUse) {
+		t.Errorf("DeleteSegment(referenced by flag) error = %v, want ErrSegmentInUse", err)
+	}
+	if err := svc.DeleteFlag(ctx, "proj1", "checkout"); err != nil {
+		t.Fatalf("DeleteFlag() error = %v", err)
+	}
+	if err := svc.DeleteSegment(ctx, "proj1", "beta"); err != nil {
+		t.Fatalf("DeleteSegment() error = %v", err)
+	}
+	if _, err := svc.GetSegment(ctx, "proj1", "beta"); !errors.Is(err, ErrSegmentNotFound) {
+		t.Errorf("GetSegment(deleted) error = %v, want ErrSegmentNotFound", err)
+	}
+	if err := svc.DeleteSegment(ctx, "proj1", "beta"); !errors.Is(err, ErrSegmentNotFound) {
+		t.Errorf("DeleteSegment(missing) error = %v, want ErrSegmentNotFound", err)
+	}
+
+	plain, err := New(ctx, newFakeServiceRepository())
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
-
-	_, err = svc.ListAPIKeys(ctx, "")
-	if !errors.Is(err, ErrProjectIDRequired) {
-		t.Fatalf("ListAPIKeys('') error = %v, want %v", err, ErrProjectIDRequired)
+	if _, err := plain.ListSegments(ctx, "proj1"); !errors.Is(err, errSegmentsNotSupported) {
+		t.Errorf("ListSegments() without support error = %v, want errSegmentsNotSupported", err)
 	}
 }
 
-func TestDeleteAPIKey_ProjectIDValidation(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
-	svc, err := New(ctx, repo)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+type fakeBackupRepository struct {
+	*fakeServiceRepository
+	restoreErr error
+}
+
+func (f *fakeBackupRepository) Backup(_ context.Context, emit func(repository.BackupRecord) error) error {
+	flags, _ := f.ListFlags(context.Background())
+	for _, flag := range flags {
+		if err := emit(repository.BackupRecord{Kind: repository.BackupKindFlag, ProjectID: flag.ProjectID, Flag: &flag}); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	err = svc.DeleteAPIKey(ctx, "", "some-key")
-	if !errors.Is(err, ErrProjectIDRequired) {
-		t.Fatalf("DeleteAPIKey('', ...) error = %v, want %v", err, ErrProjectIDRequired)
+func (f *fakeBackupRepository) Restore(_ context.Context, _ repository.RestoreOptions, next func() (repository.BackupRecord, error)) (repository.RestoreResult, error) {
+	if f.restoreErr != nil {
+		return repository.RestoreResult{}, f.restoreErr
+	}
+	result := repository.RestoreResult{Projects: map[string]string{"old": "restored"}}
+	for {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return repository.RestoreResult{}, err
+		}
+		flag := *record.Flag
+		flag.ProjectID = "restored"
+		f.mu.Lock()
+		if f.flags[flag.ProjectID] == nil {
+			f.flags[flag.ProjectID] = make(map[string]repository.Flag)
+		}
+		f.flags[flag.ProjectID][flag.Key] = flag
+		f.mu.Unlock()
+		result.Flags++
 	}
 }
 
-func TestDeleteAPIKey_KeyIDValidation(t *testing.T) {
+func TestServiceRestoreBackup(t *testing.T) {
 	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
+	repo := &fakeBackupRepository{fakeServiceRepository: newFakeServiceRepository()}
 	svc, err := New(ctx, repo)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	err = svc.DeleteAPIKey(ctx, "proj-1", "")
-	if !errors.Is(err, ErrAPIKeyIDRequired) {
-		t.Fatalf("DeleteAPIKey(..., '') error = %v, want %v", err, ErrAPIKeyIDRequired)
+	records := []repository.BackupRecord{{Kind: repository.BackupKindFlag, ProjectID: "old", Flag: &repository.Flag{Key: "checkout", Enabled: true}}}
+	next := func() (repository.BackupRecord, error) {
+		if len(records) == 0 {
+			return repository.BackupRecord{}, io.EOF
+		}
+		record := records[0]
+		records = records[1:]
+		return record, nil
+	}
+	result, err := svc.RestoreBackup(ctx, repository.RestoreOptions{}, next)
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if result.Flags != 1 || result.Projects["old"] != "restored" {
+		t.Fatalf("result = %+v", result)
+	}
+	if flag, err := svc.GetFlag(ctx, "restored", "checkout"); err != nil || !flag.Enabled {
+		t.Fatalf("GetFlag() = %+v, %v, want the restored flag from the reloaded cache", flag, err)
+	}
+	if len(repo.auditLogs) != 1 || repo.auditLogs[0].Action != "restore_backup" || repo.auditLogs[0].ProjectID != "restored" {
+		t.Fatalf("audit log = %+v, want one restore_backup entry for the restored project", repo.auditLogs)
 	}
-}
 
-func TestDeleteAPIKey_NotFound(t *testing.T) {
-	ctx := context.Background()
-	repo := newFakeAPIKeyRepository()
-	svc, err := New(ctx, repo)
+	repo.restoreErr = fmt.Errorf("restore project %q: %w", "Checkout", repository.ErrProjectNameTaken)
+	if _, err := svc.RestoreBackup(ctx, repository.RestoreOptions{}, next); !errors.Is(err, ErrProjectNameTaken) {
+		t.Fatalf("RestoreBackup() error = %v, want ErrProjectNameTaken", err)
+	}
+
+	plain, err := New(ctx, newFakeServiceRepository())
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
-
-	err = svc.DeleteAPIKey(ctx, "proj-1", "nonexistent")
-	if !errors.Is(err, ErrAPIKeyNotFound) {
-		t.Fatalf("DeleteAPIKey(nonexistent) error = %v, want %v", err, ErrAPIKeyNotFound)
+	if err := plain.Backup(ctx, func(repository.BackupRecord) error { return nil }); !errors.Is(err, errBackupNotSupported) {
+		t.Fatalf("Backup() error = %v, want errBackupNotSupported", err)
 	}
 }
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// WithShadowEvaluationMetrics registers a callback invoked each time a
+// flag's shadow rules are evaluated, with whether they produced the same
+// value as the live rules.
+func WithShadowEvaluationMetrics(onShadow func(projectID, key string, agree bool)) Option {
+	return func(s *Service) {
+		s.onShadowEvaluation = onShadow
+	}
+}
+
+// normalizeShadowRules returns the shadow rules to store: nil when JSON null
+// or nothing was sent. An empty list is kept, since it answers what the flag
+// would serve without any rules.
+func normalizeShadowRules(rules json.RawMessage) json.RawMessage {
+	if trimmed := bytes.TrimSpace(rules); len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil
+	}
+	return rules
+}
+
+// validateShadowRules checks shadow rules as [validateRules] checks rules,
+// reporting problems under the shadow_rules field.
+func validateShadowRules(payload json.RawMessage) error {
+	err := validateRules(normalizeShadowRules(payload))
+	var rulesErr *RulesError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &rulesErr):
+		rulesErr.Field = "shadow_rules"
+		return rulesErr
+	default:
+		return fmt.Errorf("shadow_rules: %w", err)
+	}
+}
+
+// evaluateShadow evaluates coreFlag, the decoded form of entry's flag, with
+// the flag's shadow rules in place of its rules and reports whether the
+// result agrees with live. Evaluations decided before the rules are reached,
+// by the flag being disabled or by a target, are skipped: the shadow rules
+// could not have changed them.
+func (s *Service) evaluateShadow(ctx context.Context, entry cachedFlag, coreFlag core.Flag, evalContext core.EvaluationContext, live core.Evaluation) {
+	if !entry.hasShadow || (live.Reason != core.ReasonRuleMatch && live.Reason != core.ReasonDefault) {
+		return
+	}
+	coreFlag.Rules = entry.shadowRules
+	shadow := core.EvaluateFlagDetail(coreFlag, evalContext)
+	agree := shadow.Value == live.Value
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("shadow_agree", agree))
+	if s.onShadowEvaluation != nil {
+		s.onShadowEvaluation(entry.flag.ProjectID, entry.flag.Key, agree)
+	}
+}
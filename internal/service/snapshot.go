@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+var errSnapshotNotSupported = errors.New("project snapshot not supported")
+
+// ProjectSnapshot is a project's flags together with the flag event they
+// are current as of.
+type ProjectSnapshot struct {
+	// EventID is the ID of the project's newest flag event when the snapshot
+	// was taken. Following the events after it picks up every later change.
+	EventID int64
+	Flags   []repository.Flag
+}
+
+// Snapshot returns a project's flags, sorted by key, and the ID of its
+// newest flag event. As in [Service.Ruleset], the event ID is read before
+// the flags: a change made in between may be in the snapshot and also
+// follow it as an event, but is never missing from both.
+func (s *Service) Snapshot(ctx context.Context, projectID string) (ProjectSnapshot, error) {
+	ctx, span := svcTracer.Start(ctx, "service.Snapshot")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return ProjectSnapshot{}, ErrProjectIDRequired
+	}
+	repo, ok := s.repo.(EventGenerationRepository)
+	if !ok {
+		return ProjectSnapshot{}, errSnapshotNotSupported
+	}
+
+	eventID, err := repo.LatestEventID(ctx, projectID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "latest event id failed")
+		return ProjectSnapshot{}, fmt.Errorf("snapshot event id: %w", err)
+	}
+	flags, err := s.ListFlags(ctx, projectID)
+	if err != nil {
+		return ProjectSnapshot{}, err
+	}
+	span.SetAttributes(attribute.Int64("event_id", eventID), attribute.Int("flag_count", len(flags)))
+
+	return ProjectSnapshot{EventID: eventID, Flags: flags}, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+const (
+	defaultStaleNotEvaluatedFor = 30 * 24 * time.Hour
+	defaultStaleNotModifiedFor  = 90 * 24 * time.Hour
+)
+
+// Reasons a flag appears in a [StaleReport].
+const (
+	// StaleReasonNotEvaluated means the flag has not been evaluated within
+	// the threshold. Flags younger than the threshold are never reported.
+	StaleReasonNotEvaluated = "not_evaluated"
+	// StaleReasonNotModified means the flag has not been changed within the
+	// threshold.
+	StaleReasonNotModified = "not_modified"
+	// StaleReasonAlwaysOn and StaleReasonAlwaysOff mean the flag's
+	// configuration yields the same value for every context; see
+	// [core.ConstantValue].
+	StaleReasonAlwaysOn  = "always_on"
+	StaleReasonAlwaysOff = "always_off"
+)
+
+// StaleThresholds sets how long a flag may go without being evaluated or
+// modified before it is reported as stale. Zero fields use the service's
+// configured thresholds.
+type StaleThresholds struct {
+	NotEvaluatedFor time.Duration
+	NotModifiedFor  time.Duration
+}
+
+// StaleFlag is a flag that meets at least one staleness criterion.
+// CleanupCandidate is set when the flag can most likely be removed: it has
+// not been evaluated within the threshold, or it has evaluated to the same
+// value without modification for the whole modification threshold.
+type StaleFlag struct {
+	Key              string     `json:"key"`
+	Reasons          []string   `json:"reasons"`
+	Evaluations      int64      `json:"evaluations"`
+	LastEvaluatedAt  *time.Time `json:"last_evaluated_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	CleanupCandidate bool       `json:"cleanup_candidate"`
+	Suggestion       string     `json:"suggestion,omitempty"`
+}
+
+// StaleReport lists a project's stale flags, sorted by key, together with the
+// thresholds that were applied.
+type StaleReport struct {
+	Thresholds StaleThresholds
+	Flags      []StaleFlag
+}
+
+// WithStaleThresholds sets the default thresholds used by
+// [Service.StaleFlags]. Fields <= 0 keep their defaults of 30 days without
+// evaluation and 90 days without modification.
+func WithStaleThresholds(thresholds StaleThresholds) Option {
+	return func(s *Service) {
+		if thresholds.NotEvaluatedFor > 0 {
+			s.staleThresholds.NotEvaluatedFor = thresholds.NotEvaluatedFor
+		}
+		if thresholds.NotModifiedFor > 0 {
+			s.staleThresholds.NotModifiedFor = thresholds.NotModifiedFor
+		}
+	}
+}
+
+// StaleFlags reports the flags in a project that have not been evaluated or
+// modified within the thresholds, or that always evaluate to the same value.
+// The evaluation criterion is skipped when the repository does not record
+// evaluation stats.
+func (s *Service) StaleFlags(ctx context.Context, projectID string, thresholds StaleThresholds) (StaleReport, error) {
+	ctx, span := svcTracer.Start(ctx, "service.StaleFlags")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return StaleReport{}, ErrProjectIDRequired
+	}
+	if thresholds.NotEvaluatedFor <= 0 {
+		thresholds.NotEvaluatedFor = s.staleThresholds.NotEvaluatedFor
+	}
+	if thresholds.NotModifiedFor <= 0 {
+		thresholds.NotModifiedFor = s.staleThresholds.NotModifiedFor
+	}
+
+	entries := s.cachedFlagEntries(projectID)
+	stats, err := s.ListFlagStats(ctx, projectID)
+	trackEvaluations := true
+	if errors.Is(err, errFlagStatsNotSupported) {
+		trackEvaluations = false
+	} else if err != nil {
+		return StaleReport{}, err
+	}
+
+	now := time.Now()
+	report := StaleReport{Thresholds: thresholds, Flags: make([]StaleFlag, 0)}
+	for _, entry := range entries {
+		flag := entry.flag
+		stale := StaleFlag{Key: flag.Key, UpdatedAt: flag.UpdatedAt}
+		flagStats := stats[flag.Key]
+		stale.Evaluations = flagStats.Evaluations
+		stale.LastEvaluatedAt = flagStats.LastEvaluatedAt
+
+		unused := false
+		if trackEvaluations {
+			lastUsed := flag.CreatedAt
+			if flagStats.LastEvaluatedAt != nil && flagStats.LastEvaluatedAt.After(lastUsed) {
+				lastUsed = *flagStats.LastEvaluatedAt
+			}
+			unused = now.Sub(lastUsed) >= thresholds.NotEvaluatedFor
+		}
+		unchanged := now.Sub(flag.UpdatedAt) >= thresholds.NotModifiedFor
+
+		if unused {
+			stale.Reasons = append(stale.Reasons, StaleReasonNotEvaluated)
+		}
+		if unchanged {
+			stale.Reasons = append(stale.Reasons, StaleReasonNotModified)
+		}
+
+		constant, isConstant := false, false
+		if entry.err == nil {
+			constant, isConstant = core.ConstantValue(entry.core)
+		}
+		if isConstant {
+			if constant {
+				stale.Reasons = append(stale.Reasons, StaleReasonAlwaysOn)
+			} else {
+				stale.Reasons = append(stale.Reasons, StaleReasonAlwaysOff)
+			}
+		}
+
+		switch {
+		case unused:
+			stale.CleanupCandidate = true
+			stale.Suggestion = fmt.Sprintf("Not evaluated for %s; delete it once no code references it.", formatStaleDuration(thresholds.NotEvaluatedFor))
+		case isConstant && unchanged:
+			stale.CleanupCandidate = true
+			stale.Suggestion = fmt.Sprintf("Always %t and unchanged for %s; replace checks with %t and delete it.", constant, formatStaleDuration(thresholds.NotModifiedFor), constant)
+		}
+
+		if len(stale.Reasons) > 0 {
+			report.Flags = append(report.Flags, stale)
+		}
+	}
+	return report, nil
+}
+
+func formatStaleDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		if days := int(d / (24 * time.Hour)); days != 1 {
+			return fmt.Sprintf("%d days", days)
+		}
+		return "1 day"
+	}
+	return d.String()
+}
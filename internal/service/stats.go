@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+const (
+	defaultStatsFlushInterval = 10 * time.Second
+	statsFlushTimeout         = 5 * time.Second
+)
+
+var errFlagStatsNotSupported = errors.New("flag stats not supported")
+
+// FlagStatsRepository defines persistence for flag evaluation counters.
+// It is optionally satisfied by [repository.PostgresRepository]. When the
+// repository implements it, [Service] counts evaluations in memory and
+// flushes them on an interval, so evaluation never waits on the database.
+type FlagStatsRepository interface {
+	AddFlagStats(ctx context.Context, stats []repository.FlagStats) error
+	GetFlagStats(ctx context.Context, projectID, key string) (repository.FlagStats, error)
+	ListFlagStats(ctx context.Context, projectID string) ([]repository.FlagStats, error)
+}
+
+type statsKey struct {
+	projectID string
+	key       string
+}
+
+type pendingStats struct {
+	evaluations     int64
+	trueEvaluations int64
+	lastEvaluatedAt time.Time
+}
+
+// WithStatsFlushInterval sets how often evaluation counters are written to
+// the repository. Defaults to 10 seconds if not set or if interval <= 0.
+func WithStatsFlushInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		if interval > 0 {
+			s.statsFlushInterval = interval
+		}
+	}
+}
+
+// recordEvaluation counts one evaluation of an existing flag that served
+// value.
+func (s *Service) recordEvaluation(projectID, key string, value bool) {
+	if s.statsRepo == nil {
+		return
+	}
+	now := time.Now()
+
+	s.statsMu.Lock()
+	p := s.pendingStats[statsKey{projectID, key}]
+	p.evaluations++
+	if value {
+		p.trueEvaluations++
+	}
+	p.lastEvaluatedAt = now
+	s.pendingStats[statsKey{projectID, key}] = p
+	s.statsMu.Unlock()
+}
+
+// FlushStats writes the evaluation counters gathered since the last flush to
+// the repository. If the write fails the counters are kept for the next
+// flush. It is called periodically and should be called once more on
+// shutdown so the final interval is not lost.
+func (s *Service) FlushStats(ctx context.Context) error {
+	if s.statsRepo == nil {
+		return nil
+	}
+
+	s.statsMu.Lock()
+	pending := s.pendingStats
+	s.pendingStats = make(map[statsKey]pendingStats)
+	s.statsMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	stats := make([]repository.FlagStats, 0, len(pending))
+	for k, p := range pending {
+		lastEvaluatedAt := p.lastEvaluatedAt
+		stats = append(stats, repository.FlagStats{
+			ProjectID:       k.projectID,
+			FlagKey:         k.key,
+			Evaluations:     p.evaluations,
+			TrueEvaluations: p.trueEvaluations,
+			LastEvaluatedAt: &lastEvaluatedAt,
+		})
+	}
+
+	if err := s.statsRepo.AddFlagStats(ctx, stats); err != nil {
+		s.statsMu.Lock()
+		for k, p := range pending {
+			s.pendingStats[k] = mergePendingStats(s.pendingStats[k], p)
+		}
+		s.statsMu.Unlock()
+		return fmt.Errorf("flush flag stats: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) runStatsFlusher(ctx context.Context) {
+	ticker := time.NewTicker(s.statsFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flushCtx, cancel := context.WithTimeout(ctx, statsFlushTimeout)
+			if err := s.FlushStats(flushCtx); err != nil && ctx.Err() == nil {
+				s.log.Warn("flag stats flush failed", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// GetFlagStats returns how often a flag has been evaluated and when it was
+// last evaluated, including evaluations not yet flushed. Returns
+// [ErrFlagNotFound] if the flag does not exist.
+func (s *Service) GetFlagStats(ctx context.Context, projectID, key string) (repository.FlagStats, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.FlagStats{}, ErrProjectIDRequired
+	}
+	if strings.TrimSpace(key) == "" {
+		return repository.FlagStats{}, ErrFlagKeyRequired
+	}
+	if s.statsRepo == nil {
+		return repository.FlagStats{}, errFlagStatsNotSupported
+	}
+
+	if _, err := s.GetFlag(ctx, projectID, key); err != nil {
+		return repository.FlagStats{}, err
+	}
+
+	stats, err := s.statsRepo.GetFlagStats(ctx, projectID, key)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return repository.FlagStats{}, fmt.Errorf("get flag stats: %w", err)
+	}
+	stats.ProjectID = projectID
+	stats.FlagKey = key
+
+	s.statsMu.Lock()
+	p, ok := s.pendingStats[statsKey{projectID, key}]
+	s.statsMu.Unlock()
+	if ok {
+		stats = addPendingStats(stats, p)
+	}
+	return stats, nil
+}
+
+// ListFlagStats returns the stats of every evaluated flag in a project keyed
+// by flag key, including evaluations not yet flushed. Flags that were never
+// evaluated are absent.
+func (s *Service) ListFlagStats(ctx context.Context, projectID string) (map[string]repository.FlagStats, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	if s.statsRepo == nil {
+		return nil, errFlagStatsNotSupported
+	}
+
+	stored, err := s.statsRepo.ListFlagStats(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list flag stats: %w", err)
+	}
+
+	byKey := make(map[string]repository.FlagStats, len(stored))
+	for _, stats := range stored {
+		byKey[stats.FlagKey] = stats
+	}
+
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	for k, p := range s.pendingStats {
+		if k.projectID != projectID {
+			continue
+		}
+		stats := byKey[k.key]
+		stats.ProjectID = projectID
+		stats.FlagKey = k.key
+		byKey[k.key] = addPendingStats(stats, p)
+	}
+	return byKey, nil
+}
+
+func addPendingStats(stats repository.FlagStats, p pendingStats) repository.FlagStats {
+	stats.Evaluations += p.evaluations
+	stats.TrueEvaluations += p.trueEvaluations
+	if stats.LastEvaluatedAt == nil || p.lastEvaluatedAt.After(*stats.LastEvaluatedAt) {
+		lastEvaluatedAt := p.lastEvaluatedAt
+		stats.LastEvaluatedAt = &lastEvaluatedAt
+	}
+	return stats
+}
+
+func mergePendingStats(a, b pendingStats) pendingStats {
+	a.evaluations += b.evaluations
+	a.trueEvaluations += b.trueEvaluations
+	if b.lastEvaluatedAt.After(a.lastEvaluatedAt) {
+		a.lastEvaluatedAt = b.lastEvaluatedAt
+	}
+	return a
+}
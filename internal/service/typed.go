@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/core"
+)
+
+// Value types reported in [ResolveResult.Type].
+const (
+	ValueTypeBoolean = "boolean"
+	ValueTypeString  = "string"
+	ValueTypeNumber  = "number"
+	// ValueTypeJSON covers JSON objects and arrays.
+	ValueTypeJSON = "json"
+)
+
+// Variants holding a flag's typed values. A typed evaluation returns the
+// "on" variant when the flag evaluates to true and the "off" variant when it
+// evaluates to false, so rules, targets and rollouts choose between them as
+// they do between true and false.
+const (
+	VariantOn  = "on"
+	VariantOff = "off"
+)
+
+// TypedValueType returns the [ResolveResult.Type] of a JSON value, or ""
+// for null and invalid JSON.
+func TypedValueType(value json.RawMessage) string {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || !json.Valid(value) {
+		return ""
+	}
+	switch value[0] {
+	case 't', 'f':
+		return ValueTypeBoolean
+	case '"':
+		return ValueTypeString
+	case '{', '[':
+		return ValueTypeJSON
+	case 'n':
+		return ""
+	default:
+		return ValueTypeNumber
+	}
+}
+
+// resolveTyped evaluates request as [Service.ResolveBooleanDetail] does and
+// replaces the boolean outcome with the flag's "on" or "off" variant. The
+// caller's TypedDefault is returned instead when the flag does not exist or
+// lacks that variant, and, with [core.ReasonTypeMismatch], when the variant
+// is of another type than the default.
+func (s *Service) resolveTyped(ctx context.Context, request ResolveRequest, evalContext core.EvaluationContext) (ResolveResult, error) {
+	ctx, span := svcTracer.Start(ctx, "service.EvaluateFlag")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("flag_key", request.Key),
+		attribute.String("project_id", request.ProjectID),
+		attribute.String("value_type", TypedValueType(request.TypedDefault)),
+	)
+
+	result, variants, err := s.resolveDetail(ctx, request.ProjectID, request.Key, evalContext, false)
+	if err != nil {
+		return ResolveResult{}, err
+	}
+	result.Type = TypedValueType(request.TypedDefault)
+	result.TypedValue = request.TypedDefault
+	result.Variant = ""
+	if variants == nil {
+		return result, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(variants, &values); err != nil {
+		return result, nil
+	}
+	name := VariantOff
+	if result.Value {
+		name = VariantOn
+	}
+	value, ok := values[name]
+	if !ok {
+		return result, nil
+	}
+	if TypedValueType(value) != result.Type {
+		result.Reason = core.ReasonTypeMismatch
+		result.RuleIndex = nil
+		return result, nil
+	}
+	result.TypedValue = value
+	result.Variant = name
+	return result, nil
+}
+
+// MarshalJSON reports TypedValue as the value of a typed result.
+func (r ResolveResult) MarshalJSON() ([]byte, error) {
+	type plain ResolveResult
+	if r.TypedValue == nil {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Value json.RawMessage `json:"value"`
+	}{plain(r), r.TypedValue})
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// apiKeyQuotaCacheTTL is how long a project's quota is reused before it is
+// read again, so a quota set on another replica applies within a minute.
+const apiKeyQuotaCacheTTL = time.Minute
+
+var (
+	// ErrAPIKeyQuotaExceeded is returned by [Service.AllowAPIKeyUsage] when
+	// the API key has used up its hourly quota.
+	ErrAPIKeyQuotaExceeded = errors.New("api key quota exceeded")
+	// ErrInvalidAPIKeyQuota is returned when a quota is negative.
+	ErrInvalidAPIKeyQuota = errors.New("invalid api key quota")
+
+	errUsageNotSupported = errors.New("api key usage not supported")
+)
+
+// UsageKind is what an API key request counts towards.
+type UsageKind int
+
+// Kinds of metered requests.
+const (
+	UsageEvaluation UsageKind = iota + 1
+	UsageMutation
+)
+
+// UsageRepository defines persistence for hourly API key usage counters and
+// per-project quotas. It is optionally satisfied by
+// [repository.PostgresRepository]. When the repository implements it,
+// [Service] counts usage in memory and flushes it with the flag stats, so
+// requests never wait on the database to be counted.
+type UsageRepository interface {
+	AddAPIKeyUsage(ctx context.Context, usage []repository.APIKeyUsage) ([]repository.APIKeyUsage, error)
+	ListAPIKeyUsage(ctx context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error)
+	GetAPIKeyQuota(ctx context.Context, projectID string) (repository.APIKeyQuota, error)
+	SetAPIKeyQuota(ctx context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error)
+}
+
+type usageKey struct {
+	projectID string
+	apiKeyID  string
+	hour      time.Time
+}
+
+type usageCounts struct {
+	evaluations int64
+	mutations   int64
+	streamTime  time.Duration
+}
+
+func (c usageCounts) count(kind UsageKind) int64 {
+	if kind == UsageMutation {
+		return c.mutations
+	}
+	return c.evaluations
+}
+
+type cachedAPIKeyQuota struct {
+	quota    repository.APIKeyQuota
+	loadedAt time.Time
+}
+
+func quotaLimit(quota repository.APIKeyQuota, kind UsageKind) int64 {
+	if kind == UsageMutation {
+		return quota.HourlyMutations
+	}
+	return quota.HourlyEvaluations
+}
+
+// AllowAPIKeyUsage counts one request of kind made with an API key, unless
+// the key has reached its project's hourly quota for kind, in which case
+// it returns [ErrAPIKeyQuotaExceeded] and counts nothing. Usage is shared
+// between replicas when it is flushed, so a key can overshoot its quota by
+// what other replicas served since their last flush. Requests are always
+// allowed when the repository does not store usage.
+func (s *Service) AllowAPIKeyUsage(ctx context.Context, projectID, apiKeyID string, kind UsageKind) error {
+	if s.usageRepo == nil || projectID == "" || apiKeyID == "" {
+		return nil
+	}
+
+	// A quota that cannot be read is not enforced; failing every request
+	// of the project would be worse than briefly serving over quota.
+	quota, err := s.apiKeyQuota(ctx, projectID)
+	if err != nil {
+		s.log.WarnContext(ctx, "api key quota lookup failed", "project_id", projectID, "error", err)
+	}
+	limit := quotaLimit(quota, kind)
+
+	key := usageKey{projectID, apiKeyID, time.Now().UTC().Truncate(time.Hour)}
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	p := s.pendingUsage[key]
+	if limit > 0 && s.flushedUsage[key].count(kind)+p.count(kind) >= limit {
+		return ErrAPIKeyQuotaExceeded
+	}
+	if kind == UsageMutation {
+		p.mutations++
+	} else {
+		p.evaluations++
+	}
+	s.pendingUsage[key] = p
+	return nil
+}
+
+// RecordAPIKeyStreamTime counts a stream held open with an API key from
+// start to end, splitting the time between the hours it spans.
+func (s *Service) RecordAPIKeyStreamTime(projectID, apiKeyID string, start, end time.Time) {
+	if s.usageRepo == nil || projectID == "" || apiKeyID == "" || !end.After(start) {
+		return
+	}
+
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	for start.Before(end) {
+		hour := start.UTC().Truncate(time.Hour)
+		next := hour.Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		key := usageKey{projectID, apiKeyID, hour}
+		p := s.pendingUsage[key]
+		p.streamTime += next.Sub(start)
+		s.pendingUsage[key] = p
+		start = next
+	}
+}
+
+// FlushUsage writes the API key usage counted since the last flush to the
+// repository. If the write fails the counts are kept for the next flush.
+// Like [Service.FlushStats], it runs periodically and should be called once
+// more on shutdown.
+func (s *Service) FlushUsage(ctx context.Context) error {
+	if s.usageRepo == nil {
+		return nil
+	}
+
+	s.usageMu.Lock()
+	pending := s.pendingUsage
+	s.pendingUsage = make(map[usageKey]usageCounts)
+	usage := make([]repository.APIKeyUsage, 0, len(pending))
+	for k, p := range pending {
+		// Stream time is stored in whole seconds; the remainder waits for
+		// the next flush.
+		seconds := int64(p.streamTime / time.Second)
+		if rest := p.streamTime % time.Second; rest > 0 {
+			s.pendingUsage[k] = usageCounts{streamTime: rest}
+		}
+		if p.evaluations == 0 && p.mutations == 0 && seconds == 0 {
+			continue
+		}
+		usage = append(usage, repository.APIKeyUsage{
+			ProjectID:     k.projectID,
+			APIKeyID:      k.apiKeyID,
+			Hour:          k.hour,
+			Evaluations:   p.evaluations,
+			Mutations:     p.mutations,
+			StreamSeconds: seconds,
+		})
+	}
+	s.usageMu.Unlock()
+
+	if len(usage) == 0 {
+		return nil
+	}
+
+	totals, err := s.usageRepo.AddAPIKeyUsage(ctx, usage)
+	if err != nil {
+		s.usageMu.Lock()
+		for _, u := range usage {
+			k := usageKey{u.ProjectID, u.APIKeyID, u.Hour}
+			p := s.pendingUsage[k]
+			p.evaluations += u.Evaluations
+			p.mutations += u.Mutations
+			p.streamTime += time.Duration(u.StreamSeconds) * time.Second
+			s.pendingUsage[k] = p
+		}
+		s.usageMu.Unlock()
+		return fmt.Errorf("flush api key usage: %w", err)
+	}
+
+	// The totals include what other replicas flushed, which is what quota
+	// checks compare against until the next flush.
+	currentHour := time.Now().UTC().Truncate(time.Hour)
+	s.usageMu.Lock()
+	for k := range s.flushedUsage {
+		if k.hour.Before(currentHour) {
+			delete(s.flushedUsage, k)
+		}
+	}
+	for _, u := range totals {
+		if u.Hour.Before(currentHour) {
+			continue
+		}
+		s.flushedUsage[usageKey{u.ProjectID, u.APIKeyID, u.Hour}] = usageCounts{
+			evaluations: u.Evaluations,
+			mutations:   u.Mutations,
+		}
+	}
+	s.usageMu.Unlock()
+	return nil
+}
+
+func (s *Service) runUsageFlusher(ctx context.Context) {
+	ticker := time.NewTicker(s.statsFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flushCtx, cancel := context.WithTimeout(ctx, statsFlushTimeout)
+			if err := s.FlushUsage(flushCtx); err != nil && ctx.Err() == nil {
+				s.log.Warn("api key usage flush failed", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// ListAPIKeyUsage returns a project's hourly usage per API key from the
+// hour containing since onwards, including usage not yet flushed, ordered
+// by hour and then API key ID.
+func (s *Service) ListAPIKeyUsage(ctx context.Context, projectID string, since time.Time) ([]repository.APIKeyUsage, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return nil, ErrProjectIDRequired
+	}
+	if s.usageRepo == nil {
+		return nil, errUsageNotSupported
+	}
+
+	stored, err := s.usageRepo.ListAPIKeyUsage(ctx, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("list api key usage: %w", err)
+	}
+
+	byKey := make(map[usageKey]repository.APIKeyUsage, len(stored))
+	for _, u := range stored {
+		byKey[usageKey{u.ProjectID, u.APIKeyID, u.Hour}] = u
+	}
+
+	sinceHour := since.UTC().Truncate(time.Hour)
+	s.usageMu.Lock()
+	for k, p := range s.pendingUsage {
+		if k.projectID != projectID || k.hour.Before(sinceHour) {
+			continue
+		}
+		u := byKey[k]
+		u.ProjectID, u.APIKeyID, u.Hour = k.projectID, k.apiKeyID, k.hour
+		u.Evaluations += p.evaluations
+		u.Mutations += p.mutations
+		u.StreamSeconds += int64(p.streamTime / time.Second)
+		byKey[k] = u
+	}
+	s.usageMu.Unlock()
+
+	usage := make([]repository.APIKeyUsage, 0, len(byKey))
+	for _, u := range byKey {
+		usage = append(usage, u)
+	}
+	slices.SortFunc(usage, func(a, b repository.APIKeyUsage) int {
+		return cmp.Or(a.Hour.Compare(b.Hour), cmp.Compare(a.APIKeyID, b.APIKeyID))
+	})
+	return usage, nil
+}
+
+// GetAPIKeyQuota returns a project's hourly API key quota. When the
+// repository does not store quotas, the zero (unlimited) quota is returned.
+// Returns [ErrProjectNotFound] if the project does not exist.
+func (s *Service) GetAPIKeyQuota(ctx context.Context, projectID string) (repository.APIKeyQuota, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.APIKeyQuota{}, ErrProjectIDRequired
+	}
+	if s.usageRepo == nil {
+		return repository.APIKeyQuota{}, nil
+	}
+
+	quota, err := s.usageRepo.GetAPIKeyQuota(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.APIKeyQuota{}, ErrProjectNotFound
+		}
+		return repository.APIKeyQuota{}, fmt.Errorf("get api key quota: %w", err)
+	}
+	return quota, nil
+}
+
+// SetAPIKeyQuota validates and stores a project's hourly API key quota. A
+// zero limit is unlimited. Returns [ErrProjectNotFound] if the project does
+// not exist.
+func (s *Service) SetAPIKeyQuota(ctx context.Context, projectID string, quota repository.APIKeyQuota) (repository.APIKeyQuota, error) {
+	ctx, span := svcTracer.Start(ctx, "service.SetAPIKeyQuota")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.APIKeyQuota{}, ErrProjectIDRequired
+	}
+	if quota.HourlyEvaluations < 0 || quota.HourlyMutations < 0 {
+		return repository.APIKeyQuota{}, fmt.Errorf("%w: limits must not be negative", ErrInvalidAPIKeyQuota)
+	}
+	if s.usageRepo == nil {
+		return repository.APIKeyQuota{}, errUsageNotSupported
+	}
+
+	stored, err := s.usageRepo.SetAPIKeyQuota(ctx, projectID, quota)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.APIKeyQuota{}, ErrProjectNotFound
+		}
+		return repository.APIKeyQuota{}, fmt.Errorf("set api key quota: %w", err)
+	}
+
+	s.usageMu.Lock()
+	s.quotas[projectID] = cachedAPIKeyQuota{quota: stored, loadedAt: time.Now()}
+	s.usageMu.Unlock()
+
+	s.insertAuditLogBestEffort(ctx, projectID, "update_api_key_quota", "")
+	return stored, nil
+}
+
+// apiKeyQuota returns a project's quota, read from the repository at most
+// once per apiKeyQuotaCacheTTL.
+func (s *Service) apiKeyQuota(ctx context.Context, projectID string) (repository.APIKeyQuota, error) {
+	s.usageMu.Lock()
+	cached, ok := s.quotas[projectID]
+	s.usageMu.Unlock()
+	if ok && time.Since(cached.loadedAt) < apiKeyQuotaCacheTTL {
+		return cached.quota, nil
+	}
+
+	quota, err := s.usageRepo.GetAPIKeyQuota(ctx, projectID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return cached.quota, err
+	}
+
+	s.usageMu.Lock()
+	s.quotas[projectID] = cachedAPIKeyQuota{quota: quota, loadedAt: time.Now()}
+	s.usageMu.Unlock()
+	return quota, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/matt-riley/flagz/internal/jsonschema"
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// ErrInvalidVariantsSchema is returned when a flag's variants schema is not a
+// JSON Schema the service can use.
+var ErrInvalidVariantsSchema = errors.New("invalid variants schema")
+
+// VariantsError is returned when a flag's variants do not satisfy its
+// variants schema. It wraps [ErrInvalidVariants] and lists every violation.
+type VariantsError struct {
+	// Errors have JSON Pointer paths into the variants object, so the first
+	// segment names the variant, as in "/red/color".
+	Errors []jsonschema.Error
+}
+
+func (e *VariantsError) Error() string {
+	reasons := make([]string, len(e.Errors))
+	for i, schemaErr := range e.Errors {
+		reasons[i] = schemaErr.Error()
+	}
+	return ErrInvalidVariants.Error() + ": " + strings.Join(reasons, "; ")
+}
+
+func (e *VariantsError) Unwrap() error {
+	return ErrInvalidVariants
+}
+
+// normalizeVariantsSchema returns the schema to store. The schemas that
+// accept anything, null, {} and true, clear it.
+func normalizeVariantsSchema(schema json.RawMessage) json.RawMessage {
+	switch string(bytes.TrimSpace(schema)) {
+	case "", "null", "{}", "true":
+		return nil
+	}
+	return schema
+}
+
+// validateVariantsSchema checks each of the flag's variant values against its
+// variants schema, if it has one.
+func validateVariantsSchema(flag repository.Flag) error {
+	raw := normalizeVariantsSchema(flag.VariantsSchema)
+	if raw == nil {
+		return nil
+	}
+	schema, err := jsonschema.Compile(raw)
+	if err != nil {
+		reason := strings.TrimPrefix(err.Error(), jsonschema.ErrInvalidSchema.Error()+": ")
+		return fmt.Errorf("%w: %s", ErrInvalidVariantsSchema, reason)
+	}
+
+	if len(flag.Variants) == 0 || string(bytes.TrimSpace(flag.Variants)) == "null" {
+		return nil
+	}
+	var variants map[string]any
+	if err := json.Unmarshal(flag.Variants, &variants); err != nil {
+		return &VariantsError{Errors: []jsonschema.Error{{Message: "variants must be an object of named values when a schema is set"}}}
+	}
+
+	var schemaErrs []jsonschema.Error
+	for _, name := range slices.Sorted(maps.Keys(variants)) {
+		for _, schemaErr := range schema.Validate(variants[name]) {
+			schemaErr.Path = "/" + jsonschema.EscapePointer(name) + schemaErr.Path
+			schemaErrs = append(schemaErrs, schemaErr)
+		}
+	}
+	if len(schemaErrs) > 0 {
+		return &VariantsError{Errors: schemaErrs}
+	}
+	return nil
+}
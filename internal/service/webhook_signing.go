@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/matt-riley/flagz/internal/repository"
+)
+
+// webhookSecretOverlap is how long deliveries stay signed with a rotated
+// secret as well as its replacement.
+const webhookSecretOverlap = 24 * time.Hour
+
+// webhookSecretPrefix starts every webhook signing secret, so a leaked one
+// is easy to recognize.
+const webhookSecretPrefix = "whsec_"
+
+// ErrWebhookSigningSecretNotFound is returned when a project has no webhook
+// signing secret.
+var ErrWebhookSigningSecretNotFound = errors.New("webhook signing secret not found")
+
+// WebhookSigningRepository defines storage of the secrets webhook deliveries
+// are signed with. It is optionally satisfied by
+// [repository.PostgresRepository].
+type WebhookSigningRepository interface {
+	GetWebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error)
+	RotateWebhookSigningSecret(ctx context.Context, projectID, secret string, overlap time.Duration) (repository.WebhookSigningSecret, error)
+}
+
+func (s *Service) webhookSigningRepository() (WebhookSigningRepository, error) {
+	repo, ok := s.repo.(WebhookSigningRepository)
+	if !ok {
+		return nil, errNotificationsNotSupported
+	}
+	return repo, nil
+}
+
+// WebhookSigningSecret returns when a project's webhook signing secret was
+// created and until when the secret it replaced is still used, without the
+// secrets themselves. Returns [ErrWebhookSigningSecretNotFound] if the
+// project has none.
+func (s *Service) WebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error) {
+	if strings.TrimSpace(projectID) == "" {
+		return repository.WebhookSigningSecret{}, ErrProjectIDRequired
+	}
+	secret, err := s.currentWebhookSigningSecret(ctx, projectID)
+	if err != nil {
+		return repository.WebhookSigningSecret{}, err
+	}
+	secret.Secret, secret.PreviousSecret = "", ""
+	return secret, nil
+}
+
+// RotateWebhookSigningSecret generates a new webhook signing secret for a
+// project and returns it; this is the only time it is returned. Deliveries
+// are signed with both the new secret and the one it replaces for 24 hours,
+// so receivers can switch over without rejecting any. The second return
+// value reports whether the project had no secret before.
+func (s *Service) RotateWebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, bool, error) {
+	ctx, span := svcTracer.Start(ctx, "service.RotateWebhookSigningSecret")
+	defer span.End()
+	span.SetAttributes(attribute.String("project_id", projectID))
+
+	if strings.TrimSpace(projectID) == "" {
+		return repository.WebhookSigningSecret{}, false, ErrProjectIDRequired
+	}
+	repo, err := s.webhookSigningRepository()
+	if err != nil {
+		return repository.WebhookSigningSecret{}, false, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return repository.WebhookSigningSecret{}, false, fmt.Errorf("generate webhook signing secret: %w", err)
+	}
+	secret, err := repo.RotateWebhookSigningSecret(ctx, projectID, webhookSecretPrefix+hex.EncodeToString(b), webhookSecretOverlap)
+	if err != nil {
+		span.RecordError(err)
+		return repository.WebhookSigningSecret{}, false, fmt.Errorf("rotate webhook signing secret: %w", err)
+	}
+
+	s.insertAuditLogBestEffort(ctx, projectID, "rotate_webhook_signing_secret", "")
+	created := secret.PreviousSecret == ""
+	secret.PreviousSecret = ""
+	return secret, created, nil
+}
+
+func (s *Service) currentWebhookSigningSecret(ctx context.Context, projectID string) (repository.WebhookSigningSecret, error) {
+	repo, err := s.webhookSigningRepository()
+	if err != nil {
+		return repository.WebhookSigningSecret{}, err
+	}
+	secret, err := repo.GetWebhookSigningSecret(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.WebhookSigningSecret{}, ErrWebhookSigningSecretNotFound
+		}
+		return repository.WebhookSigningSecret{}, fmt.Errorf("get webhook signing secret: %w", err)
+	}
+	return secret, nil
+}
+
+// webhookSigningSecrets returns the secrets a project's webhook deliveries
+// are signed with now, newest first.
+func (s *Service) webhookSigningSecrets(ctx context.Context, projectID string) ([]string, error) {
+	secret, err := s.currentWebhookSigningSecret(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	secrets := []string{secret.Secret}
+	if secret.PreviousSecret != "" && secret.PreviousExpiresAt != nil && time.Now().Before(*secret.PreviousExpiresAt) {
+		secrets = append(secrets, secret.PreviousSecret)
+	}
+	return secrets, nil
+}
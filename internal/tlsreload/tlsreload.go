@@ -0,0 +1,189 @@
+// Package tlsreload serves TLS from certificate files that can be replaced
+// while the server runs.
+//
+// A [Reloader] loads a certificate, its key and optionally a client CA
+// bundle, and hands out [tls.Config] values that pick up the files' current
+// contents for every new connection. Files are reloaded on SIGHUP and
+// whenever their modification times change, so certificates renewed by
+// cert-manager, certbot or similar tools take effect without a restart.
+// Connections that are already established keep the certificate they were
+// made with.
+package tlsreload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Reloader holds the current certificate and client CA pool.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	log          *slog.Logger
+
+	current atomic.Pointer[loaded]
+}
+
+// loaded is one generation of the files' contents.
+type loaded struct {
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+}
+
+// Option configures a [Reloader].
+type Option func(*Reloader)
+
+// WithLogger sets the logger reloads are reported to.
+func WithLogger(log *slog.Logger) Option {
+	return func(r *Reloader) {
+		r.log = log
+	}
+}
+
+// New loads certFile and keyFile, and clientCAFile when it is not empty, and
+// returns a Reloader serving them. With a client CA, clients must present a
+// certificate it signed (mutual TLS).
+func New(certFile, keyFile, clientCAFile string, opts ...Option) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		log:          slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MutualTLS reports whether clients must present a certificate.
+func (r *Reloader) MutualTLS() bool {
+	return r.clientCAFile != ""
+}
+
+// Config returns a server configuration that uses the files' contents as of
+// each handshake. nextProtos are the ALPN protocols to offer, such as "h2"
+// and "http/1.1".
+func (r *Reloader) Config(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			current := r.current.Load()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*current.cert},
+			}
+			if current.clientCA != nil {
+				config.ClientCAs = current.clientCA
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
+
+// Reload reads the files again. If any of them cannot be loaded, the
+// previous certificate and CA pool stay in use and the error is returned.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	next := &loaded{cert: &cert, modTimes: modTimes}
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("read TLS client CA: %w", err)
+		}
+		next.clientCA = x509.NewCertPool()
+		if !next.clientCA.AppendCertsFromPEM(pem) {
+			return errors.New("TLS client CA file contains no PEM certificates")
+		}
+	}
+	r.current.Store(next)
+	return nil
+}
+
+// Run reloads the files on SIGHUP, and whenever a file's modification time
+// has changed at the end of an interval, until ctx is done. Failed reloads
+// are logged and retried at the next trigger.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reloadAndLog(ctx, "signal")
+		case <-ticker.C:
+			if r.changed() {
+				r.reloadAndLog(ctx, "file change")
+			}
+		}
+	}
+}
+
+func (r *Reloader) reloadAndLog(ctx context.Context, trigger string) {
+	if err := r.Reload(); err != nil {
+		r.log.ErrorContext(ctx, "TLS reload failed; keeping the previous certificate", "trigger", trigger, "error", err)
+		return
+	}
+	r.log.InfoContext(ctx, "TLS certificate reloaded", "trigger", trigger)
+}
+
+// changed reports whether any file's modification time differs from when it
+// was last loaded. A file that cannot be read counts as changed, so the
+// failure is reported by the reload.
+func (r *Reloader) changed() bool {
+	modTimes, err := r.modTimes()
+	if err != nil {
+		return true
+	}
+	for i, modTime := range r.current.Load().modTimes {
+		if !modTime.Equal(modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) modTimes() ([]time.Time, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
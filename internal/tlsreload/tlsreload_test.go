@@ -0,0 +1,215 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and key signed by parent, or self-signed when
+// parent is nil.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if keyFile != "" {
+		if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// handshake connects a client to a server using serverConfig and returns the
+// certificate the server presented.
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- tls.Server(conn, serverConfig).Handshake()
+	}()
+	client, err := tls.Dial("tcp", lis.Addr().String(), clientConfig)
+	if err == nil {
+		defer client.Close()
+	}
+	if serr := <-serverErr; err == nil {
+		err = serr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := newTestCert(t, "flagz.test", nil)
+	first.write(t, certFile, keyFile)
+
+	reloader, err := New(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	serverConfig := reloader.Config("h2")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(first.cert)
+	clientConfig := &tls.Config{ServerName: "flagz.test", RootCAs: roots}
+	got, err := handshake(t, serverConfig, clientConfig)
+	if err != nil {
+		t.Fatalf("handshake error = %v", err)
+	}
+	if !got.Equal(first.cert) {
+		t.Fatal("server did not present the loaded certificate")
+	}
+
+	second := newTestCert(t, "flagz.test", nil)
+	second.write(t, certFile, keyFile)
+	// Some filesystems keep coarse modification times.
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if !reloader.changed() {
+		t.Fatal("changed() = false after the certificate was replaced")
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	roots.AddCert(second.cert)
+	got, err = handshake(t, serverConfig, clientConfig)
+	if err != nil {
+		t.Fatalf("handshake after reload error = %v", err)
+	}
+	if !got.Equal(second.cert) {
+		t.Fatal("server did not present the reloaded certificate")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("Reload() of a broken key succeeded")
+	}
+	if got, err = handshake(t, serverConfig, clientConfig); err != nil || !got.Equal(second.cert) {
+		t.Fatalf("after a failed reload got %v, %v; want the previous certificate", got, err)
+	}
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, "flagz test CA", nil)
+	ca.write(t, caFile, "")
+	server := newTestCert(t, "flagz.test", ca)
+	server.write(t, certFile, keyFile)
+
+	reloader, err := New(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !reloader.MutualTLS() {
+		t.Fatal("MutualTLS() = false with a client CA")
+	}
+	serverConfig := reloader.Config()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tests := []struct {
+		name    string
+		client  *testCert
+		wantErr bool
+	}{
+		{name: "no client certificate", wantErr: true},
+		{name: "certificate from another CA", client: newTestCert(t, "stranger", nil), wantErr: true},
+		{name: "certificate from the client CA", client: newTestCert(t, "client", ca)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConfig := &tls.Config{ServerName: "flagz.test", RootCAs: roots}
+			if tt.client != nil {
+				clientConfig.Certificates = []tls.Certificate{tt.client.tlsCertificate()}
+			}
+			_, err := handshake(t, serverConfig, clientConfig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), ""); err == nil {
+		t.Fatal("New() with missing files succeeded")
+	}
+}
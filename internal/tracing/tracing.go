@@ -12,11 +12,14 @@ import (
 	"strings"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/matt-riley/flagz/internal/instance"
 )
 
 const defaultServiceName = "flagz"
@@ -25,9 +28,9 @@ const defaultServiceName = "flagz"
 // exporter. If OTEL_EXPORTER_OTLP_ENDPOINT is not set, tracing is disabled and
 // a no-op shutdown function is returned.
 //
-// The returned function should be called on server shutdown to flush pending
-// spans.
-func Init(ctx context.Context) (shutdown func(context.Context) error, err error) {
+// Spans carry id as resource attributes; see [NewResource]. The returned
+// function should be called on server shutdown to flush pending spans.
+func Init(ctx context.Context, id instance.Identity) (shutdown func(context.Context) error, err error) {
 	endpoint := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
 	if endpoint == "" {
 		return func(context.Context) error { return nil }, nil
@@ -36,16 +39,9 @@ func Init(ctx context.Context) (shutdown func(context.Context) error, err error)
 		return nil, err
 	}
 
-	serviceName := serviceNameFromEnv()
-
-	res, err := resource.Merge(
-		resource.Default(),
-		resource.NewSchemaless(
-			semconv.ServiceName(serviceName),
-		),
-	)
+	res, err := NewResource(id)
 	if err != nil {
-		return nil, fmt.Errorf("create resource: %w", err)
+		return nil, err
 	}
 
 	exporter, err := otlptracehttp.New(ctx)
@@ -67,6 +63,30 @@ func Init(ctx context.Context) (shutdown func(context.Context) error, err error)
 	return tp.Shutdown, nil
 }
 
+// NewResource returns the OpenTelemetry resource describing this server,
+// named by OTEL_SERVICE_NAME (default "flagz") and identified by id's
+// hostname, version, git SHA and, when set, region. It is shared by trace and
+// log export so both signals carry the same service identity.
+func NewResource(id instance.Identity) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(serviceNameFromEnv()),
+		semconv.ServiceVersion(id.Version),
+		semconv.HostName(id.Hostname),
+		attribute.String("flagz.git_sha", id.GitSHA),
+	}
+	if id.Region != "" {
+		attrs = append(attrs, semconv.CloudRegion(id.Region))
+	}
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attrs...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+	return res, nil
+}
+
 func serviceNameFromEnv() string {
 	serviceName := strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME"))
 	if serviceName == "" {
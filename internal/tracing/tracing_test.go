@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/matt-riley/flagz/internal/instance"
 )
 
 func TestInit_NoEndpointReturnsNoop(t *testing.T) {
@@ -18,7 +21,7 @@ func TestInit_NoEndpointReturnsNoop(t *testing.T) {
 
 	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "   ")
 
-	shutdown, err := Init(context.Background())
+	shutdown, err := Init(context.Background(), instance.Identity{})
 	if err != nil {
 		t.Fatalf("Init() error = %v, want nil", err)
 	}
@@ -41,7 +44,7 @@ func TestInit_WithEndpointInitializesTracerProvider(t *testing.T) {
 	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4318")
 	t.Setenv("OTEL_SERVICE_NAME", "flagz-test")
 
-	shutdown, err := Init(context.Background())
+	shutdown, err := Init(context.Background(), instance.Identity{})
 	if err != nil {
 		t.Fatalf("Init() error = %v, want nil", err)
 	}
@@ -83,7 +86,7 @@ func TestInit_InvalidExporterConfigReturnsError(t *testing.T) {
 
 	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://[::1")
 
-	shutdown, err := Init(context.Background())
+	shutdown, err := Init(context.Background(), instance.Identity{})
 	if err == nil {
 		t.Fatal("Init() error = nil, want non-nil")
 	}
@@ -107,3 +110,29 @@ func restoreOpenTelemetryGlobals(t *testing.T) {
 		otel.SetTextMapPropagator(originalPropagator)
 	})
 }
+
+func TestNewResourceCarriesInstanceIdentity(t *testing.T) {
+	res, err := NewResource(instance.Identity{Region: "eu-west-1", Hostname: "flagz-0", Version: "v1.4.0", GitSHA: "abc123"})
+	if err != nil {
+		t.Fatalf("NewResource() error = %v", err)
+	}
+	want := map[string]string{
+		"cloud.region":    "eu-west-1",
+		"host.name":       "flagz-0",
+		"service.version": "v1.4.0",
+		"flagz.git_sha":   "abc123",
+	}
+	for key, value := range want {
+		if got, ok := res.Set().Value(attribute.Key(key)); !ok || got.AsString() != value {
+			t.Errorf("resource %s = %q, want %q", key, got.AsString(), value)
+		}
+	}
+
+	res, err = NewResource(instance.Identity{Hostname: "flagz-0"})
+	if err != nil {
+		t.Fatalf("NewResource() error = %v", err)
+	}
+	if _, ok := res.Set().Value("cloud.region"); ok {
+		t.Error("resource has cloud.region without a region")
+	}
+}
@@ -0,0 +1,102 @@
+// Package webhook delivers JSON events to an HTTP endpoint configured by the
+// operator, such as a chat integration or an incident tool, optionally
+// signed so the receiver can check where they came from.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header of a signed delivery. Its value is
+// "t=<unix seconds>,v1=<signature>", with a v1 signature for each secret:
+// the hex HMAC-SHA256, keyed with the secret, of the timestamp, a "." and
+// the request body. Receivers should reject deliveries whose timestamp is
+// too far from their own clock, so that a captured delivery cannot be
+// replayed later.
+const SignatureHeader = "Flagz-Signature"
+
+// defaultTimeout bounds each delivery when the caller's HTTP client has no
+// timeout of its own.
+const defaultTimeout = 10 * time.Second
+
+// Sender POSTs events as JSON to one URL.
+type Sender struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewSender returns a Sender for the http or https URL rawURL. A nil
+// httpClient uses one with a 10 second timeout.
+func NewSender(rawURL string, httpClient *http.Client) (*Sender, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Sender{url: u.String(), httpClient: httpClient}, nil
+}
+
+// Send delivers event, encoded as JSON, in a single attempt. Any response
+// other than 2xx is an error.
+func (s *Sender) Send(ctx context.Context, event any) error {
+	return s.SendSigned(ctx, event)
+}
+
+// SendSigned is [Sender.Send] with a [SignatureHeader] signed with each of
+// secrets at the time of sending, so a retried delivery carries a fresh
+// timestamp. Without secrets the delivery is unsigned.
+func (s *Sender) SendSigned(ctx context.Context, event any, secrets ...string) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flagz-webhook")
+	if len(secrets) > 0 {
+		req.Header.Set(SignatureHeader, Sign(body, time.Now(), secrets...))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("send webhook: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Sign returns the [SignatureHeader] value of body sent at t, with a
+// signature for each of secrets.
+func Sign(body []byte, t time.Time, secrets ...string) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	var b strings.Builder
+	b.WriteString("t=" + timestamp)
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		b.WriteString(",v1=" + hex.EncodeToString(mac.Sum(nil)))
+	}
+	return b.String()
+}
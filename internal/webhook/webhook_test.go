@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSenderSend(t *testing.T) {
+	var got map[string]string
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if got["type"] == "rejected" {
+			http.Error(w, "no thanks", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sender, err := NewSender(srv.URL+"/hooks/flagz", nil)
+	if err != nil {
+		t.Fatalf("NewSender() error = %v", err)
+	}
+	if err := sender.Send(context.Background(), map[string]string{"type": "flag.expired"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got["type"] != "flag.expired" || contentType != "application/json" {
+		t.Fatalf("received %v as %q, want the event as JSON", got, contentType)
+	}
+
+	err = sender.Send(context.Background(), map[string]string{"type": "rejected"})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "no thanks") {
+		t.Fatalf("Send() error = %v, want the status and body", err)
+	}
+}
+
+func TestNewSenderRejectsInvalidURLs(t *testing.T) {
+	for _, rawURL := range []string{"", "hooks.example.com/flagz", "ftp://hooks.example.com", "https://"} {
+		if _, err := NewSender(rawURL, nil); err == nil {
+			t.Errorf("NewSender(%q) error = nil, want an error", rawURL)
+		}
+	}
+}
+
+func TestSenderSendSigned(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sender, err := NewSender(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewSender() error = %v", err)
+	}
+	if err := sender.SendSigned(context.Background(), map[string]string{"type": "flag.updated"}, "new-secret", "old-secret"); err != nil {
+		t.Fatalf("SendSigned() error = %v", err)
+	}
+
+	timestamp, _, ok := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+	if !ok {
+		t.Fatalf("%s = %q, want a timestamp and signatures", SignatureHeader, signature)
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp %q: %v", timestamp, err)
+	}
+	if want := Sign(body, time.Unix(unix, 0), "new-secret", "old-secret"); signature != want {
+		t.Fatalf("%s = %q, want %q", SignatureHeader, signature, want)
+	}
+
+	mac := hmac.New(sha256.New, []byte("old-secret"))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !strings.HasSuffix(signature, ",v1="+hex.EncodeToString(mac.Sum(nil))) {
+		t.Errorf("%s = %q, want the old secret's signature last", SignatureHeader, signature)
+	}
+}
+
+func TestSenderSendIsUnsigned(t *testing.T) {
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sender, err := NewSender(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewSender() error = %v", err)
+	}
+	if err := sender.Send(context.Background(), map[string]string{}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if signature != "" {
+		t.Errorf("%s = %q, want none", SignatureHeader, signature)
+	}
+}
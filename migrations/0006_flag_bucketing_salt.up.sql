@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE flags
+    ADD COLUMN bucketing_salt TEXT NOT NULL DEFAULT replace(gen_random_uuid()::text, '-', '');
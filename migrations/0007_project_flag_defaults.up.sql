@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE projects
+    ADD COLUMN flag_defaults JSONB NOT NULL DEFAULT '{}'::jsonb;
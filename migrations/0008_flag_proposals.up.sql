@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE flag_proposals (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  flag_key TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('update', 'delete')),
+  flag JSONB,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  proposed_by_api_key_id TEXT NOT NULL DEFAULT '',
+  proposed_by_admin_user_id TEXT NOT NULL DEFAULT '',
+  reviewed_by_api_key_id TEXT NOT NULL DEFAULT '',
+  reviewed_by_admin_user_id TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  reviewed_at TIMESTAMPTZ
+);
+CREATE INDEX idx_flag_proposals_project_status ON flag_proposals (project_id, status, created_at DESC);
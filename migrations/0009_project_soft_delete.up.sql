@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE projects
+    ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE flag_stats (
+  project_id UUID NOT NULL,
+  flag_key TEXT NOT NULL,
+  evaluations BIGINT NOT NULL DEFAULT 0,
+  last_evaluated_at TIMESTAMPTZ,
+  PRIMARY KEY (project_id, flag_key),
+  FOREIGN KEY (project_id, flag_key) REFERENCES flags(project_id, key) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN rotation_overdue_at;
+
+ALTER TABLE projects
+    DROP COLUMN api_key_revoke_after_seconds,
+    DROP COLUMN api_key_max_age_seconds;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE projects
+    ADD COLUMN api_key_max_age_seconds BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN api_key_revoke_after_seconds BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE api_keys
+    ADD COLUMN rotation_overdue_at TIMESTAMPTZ;
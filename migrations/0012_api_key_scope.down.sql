@@ -0,0 +1,2 @@
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN scope;
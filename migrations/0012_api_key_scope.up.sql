@@ -0,0 +1,4 @@
+-- +goose Up
+ALTER TABLE api_keys
+    ADD COLUMN scope TEXT NOT NULL DEFAULT 'project'
+        CHECK (scope IN ('project', 'admin'));
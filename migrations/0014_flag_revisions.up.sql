@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE flag_revisions (
+  project_id UUID NOT NULL,
+  flag_key TEXT NOT NULL,
+  revision INTEGER NOT NULL,
+  flag JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (project_id, flag_key, revision),
+  FOREIGN KEY (project_id, flag_key) REFERENCES flags(project_id, key) ON DELETE CASCADE
+);
+
+-- Existing flags start their history at their current state.
+INSERT INTO flag_revisions (project_id, flag_key, revision, flag, created_at)
+SELECT project_id, key, 1, jsonb_build_object(
+         'key', key,
+         'description', description,
+         'enabled', enabled,
+         'variants', variants,
+         'rules', rules,
+         'bucketing_salt', bucketing_salt,
+         'created_at', created_at,
+         'updated_at', updated_at
+       ), updated_at
+FROM flags;
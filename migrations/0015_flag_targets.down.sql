@@ -0,0 +1,5 @@
+-- +goose Down
+ALTER TABLE flags
+    DROP COLUMN target_attribute,
+    DROP COLUMN allow_targets,
+    DROP COLUMN deny_targets;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE flags
+    ADD COLUMN target_attribute TEXT NOT NULL DEFAULT '',
+    ADD COLUMN allow_targets TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN deny_targets TEXT[] NOT NULL DEFAULT '{}';
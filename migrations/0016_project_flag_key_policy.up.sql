@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE projects
+    ADD COLUMN flag_key_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
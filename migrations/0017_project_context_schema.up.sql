@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE projects
+    ADD COLUMN context_schema JSONB NOT NULL DEFAULT '{}'::jsonb;
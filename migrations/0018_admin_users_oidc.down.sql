@@ -0,0 +1,3 @@
+-- +goose Down
+DELETE FROM admin_users WHERE oidc_subject IS NOT NULL;
+ALTER TABLE admin_users DROP COLUMN oidc_subject;
@@ -0,0 +1,4 @@
+-- +goose Up
+-- OIDC users are matched on the ID token's subject and have no password.
+ALTER TABLE admin_users
+    ADD COLUMN oidc_subject TEXT UNIQUE;
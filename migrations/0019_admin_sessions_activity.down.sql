@@ -0,0 +1,8 @@
+-- +goose Down
+DROP INDEX admin_sessions_admin_user_id_idx;
+ALTER TABLE admin_sessions
+    DROP COLUMN id,
+    DROP COLUMN role,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN user_agent,
+    DROP COLUMN remote_addr;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- id identifies a session in the "active sessions" page without exposing
+-- id_hash. role is the role the session was issued for; a session whose
+-- user's role has changed since is rotated on its next request.
+ALTER TABLE admin_sessions
+    ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE,
+    ADD COLUMN role TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN remote_addr TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX admin_sessions_admin_user_id_idx ON admin_sessions (admin_user_id);
@@ -0,0 +1,2 @@
+-- +goose Down
+ALTER TABLE flags DROP COLUMN variants_schema;
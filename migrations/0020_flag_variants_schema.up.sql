@@ -0,0 +1,4 @@
+-- +goose Up
+-- variants_schema is a JSON Schema each of the flag's variant values must
+-- satisfy. NULL means the variants are unconstrained.
+ALTER TABLE flags ADD COLUMN variants_schema JSONB;
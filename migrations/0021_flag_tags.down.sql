@@ -0,0 +1,3 @@
+-- +goose Down
+DROP INDEX IF EXISTS idx_flags_tags;
+ALTER TABLE flags DROP COLUMN tags;
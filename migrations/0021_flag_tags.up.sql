@@ -0,0 +1,5 @@
+-- +goose Up
+-- tags group flags by team or feature area; they are lowercase, sorted and
+-- unique, which the service enforces.
+ALTER TABLE flags ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX idx_flags_tags ON flags USING GIN (tags);
@@ -0,0 +1,6 @@
+-- +goose Down
+DROP INDEX IF EXISTS idx_flags_expiry_pending;
+ALTER TABLE flags
+    DROP COLUMN owner,
+    DROP COLUMN expires_at,
+    DROP COLUMN expiry_notified_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- owner names who is responsible for a flag. expires_at marks a temporary
+-- flag; expiry_notified_at records that its expiry reminder went out, so
+-- each expiry is reported once however many replicas look for it.
+ALTER TABLE flags
+    ADD COLUMN owner TEXT NOT NULL DEFAULT '',
+    ADD COLUMN expires_at TIMESTAMPTZ,
+    ADD COLUMN expiry_notified_at TIMESTAMPTZ;
+CREATE INDEX idx_flags_expiry_pending ON flags (expires_at)
+    WHERE expires_at IS NOT NULL AND expiry_notified_at IS NULL;
@@ -0,0 +1,3 @@
+-- +goose Down
+ALTER TABLE flag_stats
+    DROP COLUMN true_evaluations;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- true_evaluations counts the evaluations that served true, so the share of
+-- a rollout actually served can be compared with its configured percentage.
+ALTER TABLE flag_stats
+    ADD COLUMN true_evaluations BIGINT NOT NULL DEFAULT 0;
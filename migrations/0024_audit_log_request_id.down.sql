@@ -0,0 +1,3 @@
+-- +goose Down
+ALTER TABLE audit_log
+    DROP COLUMN request_id;
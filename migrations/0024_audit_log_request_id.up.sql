@@ -0,0 +1,6 @@
+-- +goose Up
+-- request_id is the X-Request-ID of the API request that caused the entry,
+-- so an entry can be matched with server logs and traces. Empty for entries
+-- written by the admin portal and background jobs.
+ALTER TABLE audit_log
+    ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- +goose Down
+ALTER TABLE api_keys
+    DROP COLUMN last_used_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- last_used_at is when the key last authenticated a request. It is written
+-- at most once per key every few minutes, so it may lag by that much. NULL
+-- means the key has not been used since this column was added.
+ALTER TABLE api_keys
+    ADD COLUMN last_used_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- idempotency_keys remembers the response to each mutating request made with
+-- an Idempotency-Key header, so a retry of the request is answered with the
+-- same response instead of being applied twice. status_code is NULL while the
+-- first request is still being handled. Rows are purged once past their TTL.
+CREATE TABLE idempotency_keys (
+    api_key_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL DEFAULT '',
+    status_code INTEGER,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (api_key_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
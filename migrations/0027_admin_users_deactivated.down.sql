@@ -0,0 +1,3 @@
+-- +goose Down
+ALTER TABLE admin_users
+    DROP COLUMN deactivated_at;
@@ -0,0 +1,5 @@
+-- +goose Up
+-- deactivated_at is set when an admin deactivates the user. Deactivated users
+-- cannot sign in and their sessions stop working; the row is kept so audit
+-- log entries still name them.
+ALTER TABLE admin_users ADD COLUMN deactivated_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- +goose Down
+ALTER TABLE projects DROP COLUMN context_enrichment;
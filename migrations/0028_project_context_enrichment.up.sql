@@ -0,0 +1,5 @@
+-- +goose Up
+-- context_enrichment holds attributes the server adds to every evaluation
+-- context of the project, beneath those the client sends.
+ALTER TABLE projects
+    ADD COLUMN context_enrichment JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,4 @@
+-- +goose Up
+-- shadow_rules is a rule set evaluated alongside rules on live traffic,
+-- counting agreement without changing results. NULL means none.
+ALTER TABLE flags ADD COLUMN shadow_rules JSONB;
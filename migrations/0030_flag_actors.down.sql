@@ -0,0 +1,5 @@
+-- +goose Down
+ALTER TABLE flag_events DROP COLUMN actor;
+ALTER TABLE flags
+    DROP COLUMN updated_by,
+    DROP COLUMN created_by;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- created_by and updated_by name the actor behind a flag's creation and its
+-- latest update, and actor the one behind each event, as "api_key:<id>" or
+-- "admin_user:<id>". Empty means unknown, as for rows written before this.
+ALTER TABLE flags
+    ADD COLUMN created_by TEXT NOT NULL DEFAULT '',
+    ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE flag_events ADD COLUMN actor TEXT NOT NULL DEFAULT '';
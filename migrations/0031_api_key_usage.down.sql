@@ -0,0 +1,6 @@
+-- +goose Down
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS api_key_hourly_mutation_quota,
+    DROP COLUMN IF EXISTS api_key_hourly_evaluation_quota;
+
+DROP TABLE IF EXISTS api_key_usage;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- api_key_usage counts the requests each API key made in each hour. Every
+-- replica adds its own counts on a short interval, so the rows are totals
+-- across replicas. stream_seconds is streaming time, counted when a stream
+-- closes.
+CREATE TABLE api_key_usage (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    api_key_id TEXT NOT NULL,
+    hour TIMESTAMPTZ NOT NULL,
+    evaluations BIGINT NOT NULL DEFAULT 0,
+    mutations BIGINT NOT NULL DEFAULT 0,
+    stream_seconds BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, hour, api_key_id)
+);
+
+-- Hourly limits applied to each of the project's API keys; 0 is unlimited.
+ALTER TABLE projects
+    ADD COLUMN api_key_hourly_evaluation_quota BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN api_key_hourly_mutation_quota BIGINT NOT NULL DEFAULT 0;
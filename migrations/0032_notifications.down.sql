@@ -0,0 +1,3 @@
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_channels;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- notification_channels are where a project's flag changes are announced:
+-- a Slack incoming webhook URL or an email address. An empty event_types
+-- announces every event type.
+CREATE TABLE notification_channels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('slack', 'email')),
+    target TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_notification_channels_project ON notification_channels (project_id, created_at);
+
+-- notifications records each delivery, successful or not, after retries.
+-- channel_id is kept when the channel is deleted so history survives it.
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL,
+    event_id BIGINT NOT NULL,
+    flag_key TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('sent', 'failed')),
+    attempts INT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_notifications_project ON notifications (project_id, id DESC);
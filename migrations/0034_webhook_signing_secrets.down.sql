@@ -0,0 +1,6 @@
+-- +goose Down
+DROP TABLE IF EXISTS webhook_signing_secrets;
+DELETE FROM notification_channels WHERE kind = 'webhook';
+ALTER TABLE notification_channels DROP CONSTRAINT notification_channels_kind_check;
+ALTER TABLE notification_channels ADD CONSTRAINT notification_channels_kind_check
+    CHECK (kind IN ('slack', 'email'));
@@ -0,0 +1,16 @@
+-- +goose Up
+-- webhook channels POST each flag event as JSON, signed with the project's
+-- webhook signing secret.
+ALTER TABLE notification_channels DROP CONSTRAINT notification_channels_kind_check;
+ALTER TABLE notification_channels ADD CONSTRAINT notification_channels_kind_check
+    CHECK (kind IN ('slack', 'email', 'webhook'));
+
+-- webhook_signing_secrets holds each project's current signing secret and,
+-- until previous_expires_at, the one a rotation replaced.
+CREATE TABLE webhook_signing_secrets (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    previous_secret TEXT NOT NULL DEFAULT '',
+    previous_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- dashboard_tokens are short-lived, read-only HTTP API tokens minted in the
+-- admin portal. Expired tokens are rejected, and deleted a day after they
+-- expire whenever another token is minted.
+CREATE TABLE dashboard_tokens (
+    id TEXT PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    created_by UUID REFERENCES admin_users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX dashboard_tokens_expires_at_idx ON dashboard_tokens (expires_at);
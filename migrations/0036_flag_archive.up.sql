@@ -0,0 +1,5 @@
+-- +goose Up
+-- archived_at marks a flag archived by a bulk action in the admin portal.
+-- Archived flags are neither evaluated nor listed, but keep their key,
+-- revisions and stats until they are restored.
+ALTER TABLE flags ADD COLUMN archived_at TIMESTAMPTZ;